@@ -1,17 +1,50 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"time"
 
+	"mcloud/internal/agent"
+	"mcloud/internal/cert"
 	"mcloud/internal/config"
+	"mcloud/internal/constant"
+	"mcloud/internal/tracing"
 )
 
 func main() {
-	cfg, err := config.Load("config/config.yaml")
+	configPath := flag.String("config", config.DefaultConfigPath, "path to the mcloud config file")
+	managerURL := flag.String("manager-url", "", "mcloudd URL (overrides agent.manager_url)")
+	version := flag.Bool("version", false, "print the version and exit")
+	flag.Parse()
+	if *version {
+		fmt.Println(constant.AppVersion)
+		return
+	}
+	config.SetPath(*configPath)
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *managerURL != "" {
+		cfg.Agent.ManagerURL = *managerURL
+	}
+
+	// Continue the traces of mcloudd's calls and commands (see config.Tracing)
+	shutdownTracing, err := tracing.Setup(context.Background(), "mcloud-agent", cfg.Tracing)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer shutdownTracing(context.Background())
+
+	manager, err := agent.NewManagerClient(cfg)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -22,16 +55,86 @@ func main() {
 		"Node": nodeName,
 	}
 
-	body, _ := json.Marshal(req)
+	if err := manager.Post(context.Background(), "/register", req); err != nil {
+		log.Println("failed to register with manager:", err)
+	}
+
+	go agent.ReportMetrics(context.Background(), manager)
 
-	_, err = http.Post(
-		cfg.Agent.ManagerURL+"/register",
-		"application/json",
-		bytes.NewBuffer(body),
-	)
+	// Notice when lxd, microceph or microovn recover after their breaker opened
+	go agent.ProbeBreakers(context.Background())
 
+	// Hold the command stream open so mcloudd can push commands (preflight,
+	// service restarts, logs, config) to this node
+	go func() {
+		if err := agent.WatchCommands(context.Background(), cfg, nodeName); err != nil {
+			log.Println("command stream disabled:", err)
+		}
+	}()
+
+	// Run workload lifecycle hooks for instances on this node
+	go agent.WatchLifecycle(context.Background(), manager, cfg.Hooks)
+
+	// Bring the node back if it was drained before the last shutdown, then
+	// guard the next shutdown so workloads are moved off first
+	go func() {
+		if err := agent.RestoreAfterBoot(context.Background(), manager); err != nil {
+			log.Println("failed to restore node after drain:", err)
+		}
+	}()
+
+	drainTimeout := agent.DefaultDrainTimeout
+	if cfg.Agent.DrainTimeoutSeconds > 0 {
+		drainTimeout = time.Duration(cfg.Agent.DrainTimeoutSeconds) * time.Second
+	}
+	go func() {
+		if err := agent.HandleShutdown(context.Background(), manager, drainTimeout); err != nil {
+			log.Println("shutdown drain hook disabled:", err)
+		}
+	}()
+
+	log.Fatal(serve(cfg, agent.NewManagerPeer(manager)))
+}
+
+// serve starts the agent API. Only mcloudd is accepted: its certificate must be
+// signed by the cluster CA and admitted by peer (see agent.ManagerPeer).
+func serve(cfg *config.Config, peer *agent.ManagerPeer) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/join", agent.JoinHandler)
+	mux.HandleFunc("/services/{service}/restart", agent.RestartServiceHandler)
+	mux.HandleFunc("PUT /timesync", agent.ApplyTimeSyncHandler)
+	mux.HandleFunc("GET /timesync", agent.TimeSyncStatusHandler)
+	mux.HandleFunc("/benchmark", agent.BenchmarkHandler)
+	mux.HandleFunc("/benchmark/sink", agent.BenchmarkSinkHandler)
+	mux.HandleFunc("GET /health", agent.HealthHandler)
+
+	// Warm copy of the manager database when this node is the standby
+	standbyPath := cfg.Standby.Path
+	if standbyPath == "" {
+		standbyPath = agent.DefaultStandbyPath
+	}
+	mux.HandleFunc("PUT /standby/snapshot", agent.StandbySnapshotHandler(standbyPath))
+	mux.HandleFunc("GET /standby", agent.StandbyStatusHandler(standbyPath))
+
+	caBytes, err := cert.ReadPEM(cfg.Security.CACertPath)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	caPool := x509.NewCertPool()
+	caPool.AppendCertsFromPEM(caBytes)
+
+	server := &http.Server{
+		Addr:    fmt.Sprintf(":%d", cfg.Agent.Port),
+		Handler: tracing.Handler(mux, "mcloud-agent"),
+		TLSConfig: &tls.Config{
+			ClientAuth: tls.RequireAndVerifyClientCert,
+			ClientCAs:  caPool,
+			MinVersion: tls.VersionTLS12,
+			// Runs after chain verification, so a refused caller fails the handshake
+			VerifyConnection: peer.VerifyConnection,
+		},
 	}
 
+	log.Println("mcloud-agent listening on", server.Addr)
+	return server.ListenAndServeTLS(cfg.Security.ServerCertPath, cfg.Security.ServerKeyPath)
 }
@@ -0,0 +1,67 @@
+package mcloudctl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"mcloud/internal/alert"
+	"mcloud/internal/config"
+
+	"github.com/urfave/cli/v2"
+)
+
+// AlertsListCommand is the CLI command handler for 'mcloudctl alerts list'.
+// Fetches GET /alerts and prints the alert history, newest first: the alerts
+// raised by the rules in alerts.rules, firing or resolved.
+//
+// CLI Usage:
+//   mcloudctl alerts list [--status firing] [--rule "node offline > 5m"] [--subject node-2] [--limit 50]
+//
+// Example Output:
+//   ID  STATUS    SEVERITY  RULE               SUBJECT  FIRED                RESOLVED             MAILS  MESSAGE
+//   13  firing    critical  node offline > 5m  node-2   2026-10-16 09:07:00  -                    1      Node node-2 has been offline since 2026-10-16 09:01:00: its agent is not connected
+//   12  resolved  critical  ceph HEALTH_ERR    ceph     2026-10-16 08:30:00  2026-10-16 08:41:00  2      Ceph is HEALTH_ERR: OSD_DOWN: 2 osds down
+func AlertsListCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{"limit": {strconv.Itoa(c.Int("limit"))}}
+	for _, name := range []string{"status", "rule", "subject", "severity"} {
+		if v := c.String(name); v != "" {
+			query.Set(name, v)
+		}
+	}
+	var resp alert.ListAlertsResponse
+	if err := client.do(ctx, http.MethodGet, "/alerts?"+query.Encode(), nil, &resp); err != nil {
+		return err
+	}
+
+	return printResult(c, resp, func(tw io.Writer) {
+		if len(resp.Items) == 0 {
+			fmt.Fprintln(tw, "No alerts")
+			return
+		}
+		fmt.Fprintln(tw, "ID\tSTATUS\tSEVERITY\tRULE\tSUBJECT\tFIRED\tRESOLVED\tMAILS\tMESSAGE")
+		for _, a := range resp.Items {
+			resolved := "-"
+			if a.ResolvedAt != nil {
+				resolved = a.ResolvedAt.Local().Format(time.DateTime)
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%d\t%s\n", a.ID, a.Status, a.Severity, a.Rule, a.Subject,
+				a.FiredAt.Local().Format(time.DateTime), resolved, a.Notifications, a.Message)
+		}
+	})
+}
@@ -0,0 +1,118 @@
+package mcloudctl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"mcloud/internal/apply"
+	"mcloud/internal/config"
+	"mcloud/pkg/logger"
+
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// readManifest reads a ClusterSpec manifest from a YAML file ("-" for stdin).
+// It is decoded generically and re-encoded as JSON, the form the API takes,
+// so every key of the manifest reaches mcloudd (which rejects unknown ones).
+func readManifest(path string) (*apply.Manifest, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var doc any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	var m apply.Manifest
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &m, nil
+}
+
+// printChanges writes a plan as a table to tw.
+func printChanges(tw io.Writer, changes []apply.Change) {
+	if len(changes) == 0 {
+		fmt.Fprintln(tw, "No changes")
+		return
+	}
+	fmt.Fprintln(tw, "ACTION\tKIND\tNAME\tDETAIL")
+	for _, c := range changes {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", c.Action, c.Kind, c.Name, c.Detail)
+	}
+}
+
+// ApplyCommand is the CLI command handler for 'mcloudctl apply'.
+// Sends a ClusterSpec manifest to POST /apply: mcloudd diffs it against the
+// cluster and converges on it in an "apply" operation, which the command
+// waits for. With --dry-run it only prints the plan; with --prune flavors,
+// security groups and volumes missing from the manifest are deleted.
+//
+// CLI Usage:
+//   mcloudctl apply -f cluster.yaml [--dry-run] [--prune] [--no-wait]
+//
+// Example Output:
+//   ACTION  KIND         NAME               DETAIL
+//   create  flavor       small              1 cpus, 1024 MiB, 10 GiB
+//   create  workload     web-1              container ubuntu:24.04
+//   update  workload     web-1              attach security groups web
+//   create  floating_ip  next free address  workload web-1
+//   running      0%  create flavor small
+//   ...
+//   [INFO] 2026-01-02 10:30:45 Applied 4 changes
+//
+// Example Output (Error):
+//   Error: MC2401 ManifestConflict: manifest changes fields that cannot be changed in place: workload db-1: image ubuntu:22.04 -> ubuntu:24.04
+func ApplyCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	m, err := readManifest(c.String("file"))
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	req := apply.Request{Manifest: *m, DryRun: c.Bool("dry-run"), Prune: c.Bool("prune")}
+	var result apply.Result
+	if err := client.do(ctx, http.MethodPost, "/apply", req, &result); err != nil {
+		return err
+	}
+	err = printResult(c, result, func(tw io.Writer) { printChanges(tw, result.Changes) })
+	if err != nil || result.Operation == nil {
+		return err
+	}
+
+	if c.Bool("no-wait") {
+		logger.Info("Applying %d changes, operation %s", len(result.Changes), result.Operation.ID)
+		return nil
+	}
+	if _, err := waitOperation(ctx, client, result.Operation.ID, 0); err != nil {
+		return err
+	}
+	logger.Info("Applied %d changes", len(result.Changes))
+	return nil
+}
@@ -0,0 +1,145 @@
+package mcloudctl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"mcloud/internal/audit"
+	"mcloud/internal/config"
+
+	"github.com/urfave/cli/v2"
+)
+
+// AuditListCommand is the CLI command handler for 'mcloudctl audit list'.
+// Fetches GET /audit and prints the entries as a table.
+//
+// CLI Usage:
+//   mcloudctl audit list [--since 1h] [--limit 100]
+//
+// Example Output:
+//   TIME                  ACTOR         ACTION                          TARGET                        STATUS  RESULT
+//   2026-01-03 10:30:45   mcloud-admin  POST /workloads/{id}/clone      /workloads/1234/clone         201     success
+func AuditListCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	if since := c.String("since"); since != "" {
+		query.Set("since", since)
+	}
+	query.Set("limit", strconv.Itoa(c.Int("limit")))
+
+	var entries []audit.Entry
+	if err := client.do(ctx, http.MethodGet, "/audit?"+query.Encode(), nil, &entries); err != nil {
+		return err
+	}
+
+	return printResult(c, entries, func(tw io.Writer) {
+		fmt.Fprintln(tw, "TIME\tACTOR\tACTION\tTARGET\tSTATUS\tRESULT")
+		for _, e := range entries {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\n",
+				e.CreatedAt.Local().Format(time.DateTime), e.Actor, e.Action, e.Target, e.StatusCode, e.Result)
+		}
+	})
+}
+
+// AuditSessionsCommand is the CLI command handler for 'mcloudctl audit sessions'.
+// Fetches GET /audit/exec-sessions and prints the sessions as a table, newest first.
+// EXIT is empty while a session runs and "error" if it could not be run to completion.
+//
+// CLI Usage:
+//   mcloudctl audit sessions [--actor <identity>] [--target /workloads/<id>] [--limit 100]
+//
+// Example Output:
+//   ID                                    STARTED              ACTOR         INSTANCE  COMMAND  EXIT  TRANSCRIPT
+//   0b7e1c2a-4f3d-4c1e-9a8b-2d6f0e5a7c31  2026-01-03 10:30:45  mcloud-admin  web-1     bash -l  0     18.2 KiB (truncated)
+func AuditSessionsCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	if actor := c.String("actor"); actor != "" {
+		query.Set("actor", actor)
+	}
+	if target := c.String("target"); target != "" {
+		query.Set("target", target)
+	}
+	query.Set("limit", strconv.Itoa(c.Int("limit")))
+
+	var resp audit.ListExecSessionsResponse
+	if err := client.do(ctx, http.MethodGet, "/audit/exec-sessions?"+query.Encode(), nil, &resp); err != nil {
+		return err
+	}
+
+	return printResult(c, resp, func(tw io.Writer) {
+		fmt.Fprintln(tw, "ID\tSTARTED\tACTOR\tINSTANCE\tCOMMAND\tEXIT\tTRANSCRIPT")
+		for _, s := range resp.Items {
+			exit := ""
+			switch {
+			case s.ExitCode != nil:
+				exit = strconv.Itoa(*s.ExitCode)
+			case s.Error != nil:
+				exit = "error"
+			}
+			transcript := "-"
+			if s.Recorded {
+				transcript = fmt.Sprintf("%.1f KiB", float64(s.TranscriptBytes)/1024)
+				if s.Truncated {
+					transcript += " (truncated)"
+				}
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				s.ID, s.StartedAt.Local().Format(time.DateTime), s.Actor, s.Instance,
+				strings.Join(s.Command, " "), exit, transcript)
+		}
+	})
+}
+
+// AuditTranscriptCommand is the CLI command handler for 'mcloudctl audit transcript'.
+// Writes the transcript of an exec session (GET /audit/exec-sessions/{id}/transcript)
+// to stdout in asciicast v2 format, e.g. for `asciinema play`.
+//
+// CLI Usage:
+//   mcloudctl audit transcript <session-id> > session.cast
+func AuditTranscriptCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	id := c.Args().First()
+	if id == "" {
+		return usageErrorf("session id is required")
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	return client.download(ctx, "/audit/exec-sessions/"+id+"/transcript", os.Stdout)
+}
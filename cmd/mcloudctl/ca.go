@@ -0,0 +1,251 @@
+package mcloudctl
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"database/sql"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"mcloud/internal/cert"
+	"mcloud/internal/config"
+	"mcloud/internal/constant"
+	"mcloud/internal/database"
+	"mcloud/internal/state"
+	"mcloud/pkg/logger"
+	"mcloud/pkg/utils"
+
+	"github.com/urfave/cli/v2"
+)
+
+// CAInfo describes a CA of the cluster for 'mcloudctl ca list' and 'ca rotate'.
+//
+// Example JSON:
+//   {"id": "5f1c...", "kind": "intermediate", "subject": "MCloud Cluster Intermediate CA", "serial": "3a9f...", "not_after": "2031-10-16T09:00:00Z", "retired_at": null}
+type CAInfo struct {
+	ID        string     `json:"id"`
+	Kind      string     `json:"kind"`
+	Subject   string     `json:"subject"`
+	Serial    string     `json:"serial"`
+	NotAfter  time.Time  `json:"not_after"`
+	RetiredAt *time.Time `json:"retired_at"`
+}
+
+func newCAInfo(ca *database.CertificateAuthority) (*CAInfo, error) {
+	block, _ := pem.Decode([]byte(ca.CertPEM))
+	if block == nil {
+		return nil, fmt.Errorf("CA %s: invalid certificate PEM", ca.ID)
+	}
+	c, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("CA %s: %w", ca.ID, err)
+	}
+	return &CAInfo{
+		ID:        ca.ID,
+		Kind:      ca.Kind,
+		Subject:   c.Subject.CommonName,
+		Serial:    fmt.Sprintf("%x", c.SerialNumber),
+		NotAfter:  c.NotAfter.UTC(),
+		RetiredAt: ca.RetiredAt,
+	}, nil
+}
+
+// openClusterDB loads this node's config and state and connects to the
+// manager database, for the ca commands that run on the leader. It returns the
+// config, the cluster ID and the connection.
+func openClusterDB() (*config.Config, string, *sql.DB, error) {
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return nil, "", nil, err
+	}
+	st, err := state.LoadState(cfg.StatePath)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("load state %s (run on the leader): %w", cfg.StatePath, err)
+	}
+	conn, err := database.Connect(cfg.Database.DBPath)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	return cfg, st.Cluster.ID, conn, nil
+}
+
+// CAListCommand is the CLI command handler for 'mcloudctl ca list'.
+// Lists the cluster's root CA and its intermediate CAs, retired ones included,
+// from the manager database on this node.
+//
+// CLI Usage:
+//   mcloudctl ca list
+//
+// Example Output:
+//   KIND          SUBJECT                         SERIAL    NOT AFTER            STATUS
+//   root          MCloud Cluster CA               1b2c...   2036-01-02 10:30:45  active
+//   intermediate  MCloud Cluster Intermediate CA  3a9f...   2031-01-02 10:30:45  retired 2026-10-16 09:00:00
+//   intermediate  MCloud Cluster Intermediate CA  7d41...   2031-10-16 09:00:00  active
+func CAListCommand(c *cli.Context) error {
+	_, clusterID, conn, err := openClusterDB()
+	if err != nil {
+		return err
+	}
+	defer database.Close(conn)
+
+	cas, err := database.NewCertificateAuthorityRepository(conn).ListByCluster(c.Context, clusterID)
+	if err != nil {
+		return err
+	}
+	infos := make([]*CAInfo, 0, len(cas))
+	for i := range cas {
+		info, err := newCAInfo(&cas[i])
+		if err != nil {
+			return err
+		}
+		infos = append(infos, info)
+	}
+
+	return printResult(c, infos, func(tw io.Writer) {
+		fmt.Fprintln(tw, "KIND\tSUBJECT\tSERIAL\tNOT AFTER\tSTATUS")
+		for _, ca := range infos {
+			status := "active"
+			if ca.RetiredAt != nil {
+				status = "retired " + ca.RetiredAt.Local().Format(time.DateTime)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", ca.Kind, ca.Subject, ca.Serial, ca.NotAfter.Local().Format(time.DateTime), status)
+		}
+	})
+}
+
+// CARotateCommand is the CLI command handler for 'mcloudctl ca rotate'.
+// Replaces the cluster's intermediate CA with a new one signed by the same
+// root, then re-issues this node's server, admin client and node certificates
+// from it. The root, and so every node's trust, is untouched: certificates the
+// retired intermediate issued bundle it and stay valid until they expire. On a
+// cluster initialized without an intermediate, this adds one.
+//
+// Command Flow:
+//   Step 1: Load the config, the state (cluster ID) and the root CA; run on the leader
+//   Step 2: Generate the new intermediate next to the current one
+//   Step 3: Retire the current intermediate in the database and record the new one
+//   Step 4: Put the new intermediate in place and re-issue this node's certificates
+//
+// CLI Usage:
+//   mcloudctl ca rotate
+//
+// Example Output:
+//   [INFO] 2026-10-16 09:00:00 Rotated the intermediate CA (serial 7d41..., valid until 2031-10-16 09:00:00)
+//   [INFO] 2026-10-16 09:00:00 Re-issued the server, admin client and node certificates; restart mcloudd to serve the new server certificate
+func CARotateCommand(c *cli.Context) error {
+	// Step 1: Config, cluster and root CA
+	cfg, clusterID, conn, err := openClusterDB()
+	if err != nil {
+		return err
+	}
+	defer database.Close(conn)
+	rootCert, rootKey, err := cert.LoadCA(cfg.Security.CACertPath, cfg.Security.CAKeyPath)
+	if err != nil {
+		return fmt.Errorf("load root CA (run on the leader): %w", err)
+	}
+
+	addedPaths := cfg.Security.IntermediateCertPath == ""
+	if addedPaths {
+		cfg.Security.IntermediateCertPath = filepath.Join(cfg.Security.CertDir, "intermediate.crt")
+		cfg.Security.IntermediateKeyPath = filepath.Join(cfg.Security.CertDir, "intermediate.key")
+	}
+
+	// Step 2: The new intermediate, beside the current one until it is recorded
+	certPath, keyPath := cfg.Security.IntermediateCertPath+".new", cfg.Security.IntermediateKeyPath+".new"
+	interCert, interKey, err := cert.GenerateIntermediateCA(rootCert, rootKey, certPath, keyPath)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(certPath)
+	defer os.Remove(keyPath)
+
+	// Step 3: Retire the current intermediate and record the new one
+	rootPEM, err := cert.ReadPEM(cfg.Security.CACertPath)
+	if err != nil {
+		return err
+	}
+	if err := recordIntermediate(c.Context, conn, clusterID, string(rootPEM), cert.EncodeCertPEM(interCert)); err != nil {
+		return err
+	}
+
+	// Step 4: Put it in place and re-issue this node's certificates
+	if err := os.Rename(certPath, cfg.Security.IntermediateCertPath); err != nil {
+		return err
+	}
+	if err := os.Rename(keyPath, cfg.Security.IntermediateKeyPath); err != nil {
+		return err
+	}
+	if addedPaths {
+		if err := config.SaveConfig(cfg); err != nil {
+			return err
+		}
+	}
+	if err := reissueCerts(cfg, interCert, interKey); err != nil {
+		return err
+	}
+
+	info, err := newCAInfo(&database.CertificateAuthority{Kind: database.CAKindIntermediate, CertPEM: cert.EncodeCertPEM(interCert)})
+	if err != nil {
+		return err
+	}
+	if err := printResult(c, info, nil); err != nil {
+		return err
+	}
+	logger.Info("Rotated the intermediate CA (serial %s, valid until %s)", info.Serial, info.NotAfter.Local().Format(time.DateTime))
+	logger.Info("Re-issued the server, admin client and node certificates; restart mcloudd to serve the new server certificate")
+	return nil
+}
+
+// recordIntermediate retires the cluster's intermediate CAs and records a new
+// one, in one transaction. The root is recorded too if it is missing, as on
+// clusters initialized before intermediates. Only certificates are recorded;
+// the keys stay on the leader's filesystem.
+func recordIntermediate(ctx context.Context, conn *sql.DB, clusterID, rootPEM, certPEM string) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	repo := database.NewCertificateAuthorityRepositoryTx(tx)
+	if _, err := repo.GetByCluster(ctx, clusterID); errors.Is(err, sql.ErrNoRows) {
+		err = repo.Create(ctx, &database.CertificateAuthority{ID: utils.GenerateUUID(), ClusterID: clusterID, Kind: database.CAKindRoot, CertPEM: rootPEM})
+		if err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+	if err := repo.RetireIntermediates(ctx, clusterID, time.Now().UTC()); err != nil {
+		return err
+	}
+	err = repo.Create(ctx, &database.CertificateAuthority{
+		ID:        utils.GenerateUUID(),
+		ClusterID: clusterID,
+		Kind:      database.CAKindIntermediate,
+		CertPEM:   certPEM,
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// reissueCerts issues this node's server, admin client and node certificates
+// from a new intermediate CA, with the SANs mcloudd gives the server
+// certificate (see ensureCertificates in internal/app).
+func reissueCerts(cfg *config.Config, ca *x509.Certificate, caKey *rsa.PrivateKey) error {
+	if err := cert.IssueServerCert(cfg, ca, caKey); err != nil {
+		return err
+	}
+	if err := cert.GenerateClientCert(ca, caKey, constant.AdminClientCommonName, cfg.Security.ClientCertPath, cfg.Security.ClientKeyPath); err != nil {
+		return err
+	}
+	return cert.IssueNodeCert(cfg, ca, caKey)
+}
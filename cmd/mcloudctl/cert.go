@@ -0,0 +1,253 @@
+package mcloudctl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"mcloud/internal/certificate"
+	"mcloud/internal/config"
+	"mcloud/internal/database"
+	"mcloud/internal/revocation"
+	"mcloud/pkg/logger"
+
+	"github.com/urfave/cli/v2"
+)
+
+// CertRevokeCommand is the CLI command handler for 'mcloudctl cert revoke'.
+// Sends POST /certs/revoke for a certificate given as a PEM file or by its
+// serial number in hex; mcloudd refuses it on the HTTP and gRPC APIs from
+// then on and lists it in GET /certs/crl.
+//
+// CLI Usage:
+//   mcloudctl cert revoke <serial|cert-file> [--reason "key of node3 leaked"]
+//
+// Example Output:
+//   [INFO] 2026-10-16 09:00:00 Revoked certificate 3a9f0c12d4e5b6a7 (node3)
+//
+// Example Output (Error):
+//   Error: MC1111 InvalidRevocation: invalid revocation: serial "xyz" is not hex
+func CertRevokeCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	arg := c.Args().First()
+	if arg == "" {
+		return usageErrorf("serial number or certificate file is required")
+	}
+	req := revocation.RevokeRequest{Reason: c.String("reason")}
+	if data, err := os.ReadFile(arg); err == nil {
+		req.Certificate = string(data)
+	} else {
+		req.Serial = arg
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	var revoked database.RevokedSerial
+	if err := client.do(ctx, http.MethodPost, "/certs/revoke", req, &revoked); err != nil {
+		return err
+	}
+	if err := printResult(c, revoked, nil); err != nil {
+		return err
+	}
+	if revoked.CommonName != "" {
+		logger.Info("Revoked certificate %s (%s)", revoked.Serial, revoked.CommonName)
+	} else {
+		logger.Info("Revoked certificate %s", revoked.Serial)
+	}
+	return nil
+}
+
+// CertRevokedCommand is the CLI command handler for 'mcloudctl cert revoked'.
+// Fetches GET /certs/revoked and prints the revoked certificates, oldest first.
+//
+// CLI Usage:
+//   mcloudctl cert revoked
+//
+// Example Output:
+//   SERIAL            COMMON NAME  REVOKED AT           BY     REASON
+//   3a9f0c12d4e5b6a7  node3        2026-10-16 09:00:00  admin  key of node3 leaked
+func CertRevokedCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	var revoked []database.RevokedSerial
+	if err := client.do(ctx, http.MethodGet, "/certs/revoked", nil, &revoked); err != nil {
+		return err
+	}
+
+	return printResult(c, revoked, func(tw io.Writer) {
+		fmt.Fprintln(tw, "SERIAL\tCOMMON NAME\tREVOKED AT\tBY\tREASON")
+		for _, r := range revoked {
+			by := "-"
+			if r.RevokedBy != nil && *r.RevokedBy != "" {
+				by = *r.RevokedBy
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", r.Serial, r.CommonName, r.RevokedAt.Local().Format(time.DateTime), by, r.Reason)
+		}
+	})
+}
+
+// CertListCommand is the CLI command handler for 'mcloudctl cert list'.
+// Fetches GET /certs: the cluster's CAs and the server, admin client and node
+// certificates of the node mcloudd runs on, with their expiry, so certificates
+// about to expire stand out.
+//
+// CLI Usage:
+//   mcloudctl cert list
+//
+// Example Output:
+//   ID        KIND          SUBJECT                         NOT AFTER            STATUS
+//   5f1c...   root          MCloud Cluster CA               2036-01-02 10:30:45  valid
+//   8e20...   intermediate  MCloud Cluster Intermediate CA  2031-01-02 10:30:45  valid
+//   server    server        mcloud-server                   2036-01-02 10:30:45  valid
+//   client    client        mcloud-admin                    2026-11-01 10:30:45  expiring
+//   node      node          node1                           2036-01-02 10:30:45  valid
+func CertListCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	var certs []certificate.Certificate
+	if err := client.do(ctx, http.MethodGet, "/certs", nil, &certs); err != nil {
+		return err
+	}
+
+	return printResult(c, certs, func(tw io.Writer) {
+		fmt.Fprintln(tw, "ID\tKIND\tSUBJECT\tNOT AFTER\tSTATUS")
+		for _, cert := range certs {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", cert.ID, cert.Kind, cert.Subject, cert.NotAfter.Local().Format(time.DateTime), cert.Status)
+		}
+	})
+}
+
+// CertInspectCommand is the CLI command handler for 'mcloudctl cert inspect'.
+// Fetches GET /certs/{id} and prints the certificate's details and PEM.
+//
+// CLI Usage:
+//   mcloudctl cert inspect <id>
+//
+// Example Output:
+//   ID:          server
+//   Kind:        server
+//   Subject:     mcloud-server
+//   Issuer:      MCloud Cluster Intermediate CA
+//   Serial:      3a9f0c12d4e5b6a7
+//   Valid:       2026-01-02 10:30:45 - 2036-01-02 10:30:45 (valid)
+//   DNS names:   node1, mcloud.example.com
+//   IPs:         10.0.0.11
+//   Path:        /etc/mcloud/certs/server.crt
+//   -----BEGIN CERTIFICATE-----
+//   ...
+func CertInspectCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	id := c.Args().First()
+	if id == "" {
+		return usageErrorf("certificate ID is required (see mcloudctl cert list)")
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	var cert certificate.Certificate
+	if err := client.do(ctx, http.MethodGet, "/certs/"+url.PathEscape(id), nil, &cert); err != nil {
+		return err
+	}
+
+	return printResult(c, cert, func(tw io.Writer) {
+		fmt.Fprintf(tw, "ID:\t%s\n", cert.ID)
+		fmt.Fprintf(tw, "Kind:\t%s\n", cert.Kind)
+		fmt.Fprintf(tw, "Subject:\t%s\n", cert.Subject)
+		fmt.Fprintf(tw, "Issuer:\t%s\n", cert.Issuer)
+		fmt.Fprintf(tw, "Serial:\t%s\n", cert.Serial)
+		fmt.Fprintf(tw, "Valid:\t%s - %s (%s)\n", cert.NotBefore.Local().Format(time.DateTime), cert.NotAfter.Local().Format(time.DateTime), cert.Status)
+		if len(cert.DNSNames) > 0 {
+			fmt.Fprintf(tw, "DNS names:\t%s\n", strings.Join(cert.DNSNames, ", "))
+		}
+		if len(cert.IPAddresses) > 0 {
+			fmt.Fprintf(tw, "IPs:\t%s\n", strings.Join(cert.IPAddresses, ", "))
+		}
+		if cert.Path != "" {
+			fmt.Fprintf(tw, "Path:\t%s\n", cert.Path)
+		}
+		fmt.Fprint(tw, cert.PEM)
+	})
+}
+
+// CertRenewCommand is the CLI command handler for 'mcloudctl cert renew'.
+// Sends POST /certs/{id}/renew to re-issue the server, admin client or node
+// certificate of the node mcloudd runs on from the issuing CA.
+//
+// CLI Usage:
+//   mcloudctl cert renew <server|client|node>
+//
+// Example Output:
+//   [INFO] 2026-10-16 09:00:00 Renewed certificate server (serial 7d41..., valid until 2036-10-16 09:00:00); restart mcloudd to serve it
+//
+// Example Output (Error):
+//   Error: MC1113 CertNotRenewable: certificate cannot be renewed by mcloudd: root CA 5f1c...: rotate the intermediate with mcloudctl ca rotate
+func CertRenewCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	id := c.Args().First()
+	if id == "" {
+		return usageErrorf("certificate ID is required: server, client or node")
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	var cert certificate.Certificate
+	if err := client.do(ctx, http.MethodPost, "/certs/"+url.PathEscape(id)+"/renew", nil, &cert); err != nil {
+		return err
+	}
+	if err := printResult(c, cert, nil); err != nil {
+		return err
+	}
+	msg := fmt.Sprintf("Renewed certificate %s (serial %s, valid until %s)", cert.ID, cert.Serial, cert.NotAfter.Local().Format(time.DateTime))
+	if cert.ID == certificate.IDServer {
+		msg += "; restart mcloudd to serve it"
+	}
+	logger.Info("%s", msg)
+	return nil
+}
@@ -0,0 +1,142 @@
+package mcloudctl
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"mcloud/internal/cert"
+	"mcloud/internal/config"
+	"mcloud/internal/state"
+	"mcloud/internal/tracing"
+	"mcloud/pkg/api"
+	"mcloud/pkg/client"
+	"mcloud/pkg/reason"
+	"mcloud/pkg/utils"
+)
+
+// apiClient talks to the mcloudd REST API over HTTPS.
+// It trusts only the cluster CA and presents the local client certificate
+// (written by `mcloudctl init`) so mutating endpoints accept the request.
+type apiClient struct {
+	baseURL string
+	http    *http.Client
+	api     *client.Client // shares http, so timeouts set on it apply
+}
+
+// newAPIClient builds an apiClient from the local configuration.
+//
+// Parameters:
+//   - cfg: Configuration containing the manager address and certificate paths
+//
+// Returns:
+//   - *apiClient: Client ready to call https://<http_host>:<http_port>
+//   - error: If the CA or client certificate cannot be loaded
+func newAPIClient(cfg *config.Config) (*apiClient, error) {
+	caBytes, err := cert.ReadPEM(cfg.Security.CACertPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+	}
+	caPool := x509.NewCertPool()
+	if !caPool.AppendCertsFromPEM(caBytes) {
+		return nil, fmt.Errorf("invalid CA certificate: %s", cfg.Security.CACertPath)
+	}
+
+	clientCert, err := tls.LoadX509KeyPair(cfg.Security.ClientCertPath, cfg.Security.ClientKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load client certificate: %w", err)
+	}
+
+	return newTLSAPIClient("https://" + utils.HostPort(cfg.Manager.HttpHost, cfg.Manager.HttpPort), caPool, clientCert), nil
+}
+
+// newBundleAPIClient builds an apiClient from the manager URL and PEM
+// certificates of a re-join bundle, for when the files on disk are gone.
+func newBundleAPIClient(b *state.RejoinBundle) (*apiClient, error) {
+	caPool := x509.NewCertPool()
+	if !caPool.AppendCertsFromPEM([]byte(b.CACert)) {
+		return nil, fmt.Errorf("invalid CA certificate in re-join bundle")
+	}
+	clientCert, err := tls.X509KeyPair([]byte(b.ClientCert), []byte(b.ClientKey))
+	if err != nil {
+		return nil, fmt.Errorf("invalid client certificate in re-join bundle: %w", err)
+	}
+	return newTLSAPIClient(b.ManagerURL, caPool, clientCert), nil
+}
+
+func newTLSAPIClient(baseURL string, caPool *x509.CertPool, clientCert tls.Certificate) *apiClient {
+	c := &apiClient{
+		baseURL: baseURL,
+		http: &http.Client{
+			Timeout: 30 * time.Second,
+			Transport: tracing.Transport(&http.Transport{
+				TLSClientConfig: &tls.Config{
+					RootCAs:      caPool,
+					Certificates: []tls.Certificate{clientCert},
+					MinVersion:   tls.VersionTLS12,
+				},
+			}),
+		},
+	}
+	// New only fails loading certificates, which an HTTPClient skips
+	c.api, _ = client.New(client.Config{BaseURL: baseURL, HTTPClient: c.http})
+	return c
+}
+
+// do sends a JSON request to the API and decodes the JSON response into out (if non-nil).
+// Non-2xx responses are returned as *client.APIError with the server's message.
+func (c *apiClient) do(ctx context.Context, method string, path string, body any, out any) error {
+	return c.api.Do(ctx, method, path, body, out)
+}
+
+// download copies the body of GET path to w, for responses that are not JSON
+// (e.g. exec transcripts). Errors are returned as by do.
+func (c *apiClient) download(ctx context.Context, path string, w io.Writer) error {
+	return c.api.Download(ctx, path, w)
+}
+
+// upgrade sends a JSON request asking to switch the connection to protocol
+// (e.g. execstream.Protocol) and returns the raw connection once the server
+// answers 101 Switching Protocols, with the response headers.
+// The client timeout does not apply; the connection lives until it is closed.
+func (c *apiClient) upgrade(ctx context.Context, path string, protocol string, body any) (io.ReadWriteCloser, http.Header, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+api.Path(path), bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set(api.VersionHeader, api.Version)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", protocol)
+
+	hc := *c.http
+	hc.Timeout = 0
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(resp.Body)
+		return nil, nil, fmt.Errorf("POST %s: %s: %w", path, resp.Status, reason.FromResponse(resp.StatusCode, resp.Header, msg))
+	}
+	// net/http hands over the connection as the body of a 101 response
+	conn, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		resp.Body.Close()
+		return nil, nil, fmt.Errorf("POST %s: connection cannot be upgraded", path)
+	}
+	return conn, resp.Header, nil
+}
@@ -0,0 +1,213 @@
+package mcloudctl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+
+	"mcloud/internal/clusterconfig"
+	"mcloud/internal/config"
+	"mcloud/pkg/client"
+	"mcloud/pkg/utils"
+
+	"github.com/urfave/cli/v2"
+)
+
+// configKeyPath is the API path of a cluster config key.
+func configKeyPath(namespace, key string) string {
+	return "/config/" + url.PathEscape(namespace) + "/" + url.PathEscape(key)
+}
+
+// configArgs returns the namespace and key arguments of a config subcommand.
+func configArgs(c *cli.Context) (string, string, error) {
+	if c.NArg() < 2 {
+		return "", "", usageErrorf("namespace and key are required")
+	}
+	return c.Args().Get(0), c.Args().Get(1), nil
+}
+
+// ConfigListCommand is the CLI command handler for 'mcloudctl config list'.
+// Fetches GET /config (or /config/{namespace}) and prints the keys as a table.
+//
+// CLI Usage:
+//   mcloudctl config list [namespace]
+//
+// Example Output:
+//   NAMESPACE  KEY            VALUE  VERSION  UPDATED
+//   agent      drain_timeout  5m     2        2026-01-03 10:30:45
+func ConfigListCommand(c *cli.Context) error {
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	api, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	path := "/config"
+	if ns := c.Args().First(); ns != "" {
+		path += "/" + url.PathEscape(ns)
+	}
+	var entries []clusterconfig.Entry
+	if err := api.do(context.Background(), http.MethodGet, path, nil, &entries); err != nil {
+		return err
+	}
+
+	return printResult(c, entries, func(tw io.Writer) {
+		fmt.Fprintln(tw, "NAMESPACE\tKEY\tVALUE\tVERSION\tUPDATED")
+		for _, e := range entries {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n", e.Namespace, e.Key, e.Value, e.Version, e.UpdatedAt.Local().Format(time.DateTime))
+		}
+	})
+}
+
+// ConfigGetCommand is the CLI command handler for 'mcloudctl config get'.
+// Prints the value of one key.
+//
+// CLI Usage:
+//   mcloudctl config get <namespace> <key>
+func ConfigGetCommand(c *cli.Context) error {
+	namespace, key, err := configArgs(c)
+	if err != nil {
+		return err
+	}
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	api, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	var e clusterconfig.Entry
+	if err := api.do(context.Background(), http.MethodGet, configKeyPath(namespace, key), nil, &e); err != nil {
+		return err
+	}
+	return printResult(c, e, func(tw io.Writer) { fmt.Fprintln(tw, e.Value) })
+}
+
+// ConfigSetCommand is the CLI command handler for 'mcloudctl config set'.
+// Sends PUT /config/{namespace}/{key}. With --version the write only succeeds if
+// the key is still at that version (0: only if it does not exist yet).
+//
+// CLI Usage:
+//   mcloudctl config set <namespace> <key> <value> [--version N]
+//
+// Example Output:
+//   agent/drain_timeout = 5m (version 3)
+//
+// Example Output (Error - Stale Version):
+//   Returns: error("PUT /config/agent/drain_timeout: 409 Conflict: config key was changed by someone else")
+func ConfigSetCommand(c *cli.Context) error {
+	namespace, key, err := configArgs(c)
+	if err != nil {
+		return err
+	}
+	if c.NArg() < 3 {
+		return usageErrorf("value is required")
+	}
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	api, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	req := clusterconfig.SetRequest{Value: c.Args().Get(2)}
+	if c.IsSet("version") {
+		v := c.Int64("version")
+		req.Version = &v
+	}
+	var e clusterconfig.Entry
+	if err := api.do(context.Background(), http.MethodPut, configKeyPath(namespace, key), req, &e); err != nil {
+		return err
+	}
+	return printResult(c, e, func(tw io.Writer) {
+		fmt.Fprintf(tw, "%s/%s = %s (version %d)\n", e.Namespace, e.Key, e.Value, e.Version)
+	})
+}
+
+// ConfigDeleteCommand is the CLI command handler for 'mcloudctl config delete'.
+//
+// CLI Usage:
+//   mcloudctl config delete <namespace> <key> [--version N]
+func ConfigDeleteCommand(c *cli.Context) error {
+	namespace, key, err := configArgs(c)
+	if err != nil {
+		return err
+	}
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	api, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	path := configKeyPath(namespace, key)
+	if c.IsSet("version") {
+		path += "?version=" + strconv.FormatInt(c.Int64("version"), 10)
+	}
+	if err := api.do(context.Background(), http.MethodDelete, path, nil, nil); err != nil {
+		return err
+	}
+	fmt.Printf("Deleted %s/%s\n", namespace, key)
+	return nil
+}
+
+// ConfigWatchCommand is the CLI command handler for 'mcloudctl config watch'.
+// Tails GET /config/watch until interrupted, reconnecting if the connection drops.
+//
+// CLI Usage:
+//   mcloudctl config watch [namespace]
+//
+// Example Output:
+//   2026-01-03 10:30:45  agent/drain_timeout = 5m (version 3)
+//   2026-01-03 10:31:02  agent/drain_timeout deleted
+func ConfigWatchCommand(c *cli.Context) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	stream, err := client.New(client.Config{
+		BaseURL:    "https://" + utils.HostPort(cfg.Manager.HttpHost, cfg.Manager.HttpPort),
+		CACertPath: cfg.Security.CACertPath,
+		CertPath:   cfg.Security.ClientCertPath,
+		KeyPath:    cfg.Security.ClientKeyPath,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = stream.WatchConfig(ctx, c.Args().First(), func(ch client.ConfigChange) error {
+		if outputFormat(c) != outputTable {
+			return printDocument(c, ch)
+		}
+		at := ch.ChangedAt.Local().Format(time.DateTime)
+		if ch.Deleted || ch.Value == nil {
+			fmt.Printf("%s  %s/%s deleted\n", at, ch.Namespace, ch.Key)
+			return nil
+		}
+		fmt.Printf("%s  %s/%s = %s (version %d)\n", at, ch.Namespace, ch.Key, *ch.Value, ch.Version)
+		return nil
+	})
+	if ctx.Err() != nil {
+		// Interrupted by the user
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,170 @@
+package mcloudctl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"mcloud/internal/config"
+	"mcloud/internal/workload"
+	"mcloud/pkg/execstream"
+	"mcloud/pkg/logger"
+
+	"github.com/urfave/cli/v2"
+	"golang.org/x/term"
+)
+
+// detachPrefix starts the console detach sequence, Ctrl+a q.
+const detachPrefix = 0x01
+
+// ConsoleCommand is the CLI command handler for 'mcloudctl console'.
+// Attaches to the console of the workload's instance over
+// POST /workloads/{id}/console, e.g. to watch a VM boot or log in when its
+// network is broken. Press Ctrl+a q to detach (Ctrl+a Ctrl+a sends Ctrl+a).
+// The session is recorded in the audit log like an exec of "console".
+//
+// CLI Usage:
+//   mcloudctl console <workload-id>
+func ConsoleCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	id := c.Args().First()
+	if id == "" {
+		return usageErrorf("workload id is required")
+	}
+	var req workload.ConsoleRequest
+	req.Width, req.Height = terminalSize()
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	conn, header, err := client.upgrade(ctx, "/workloads/"+id+"/console", execstream.Protocol, req)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	logger.Debug("Console session %s", header.Get("X-Mcloud-Exec-Session"))
+
+	fmt.Fprintln(os.Stderr, "Attached to the console; press Ctrl+a q to detach")
+	if _, err := runTerminal(conn, true); err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stderr)
+	return nil
+}
+
+// runTerminal runs an execstream session on a terminal: stdin is put in raw
+// mode and sent as is, window size changes are sent as Resize frames, and
+// Stdout is written to stdout until the Exit frame, whose code is returned.
+// With detach, Ctrl+a q ends the input (a console has no end of its own).
+func runTerminal(conn io.ReadWriter, detach bool) (int, error) {
+	if fd := int(os.Stdin.Fd()); term.IsTerminal(fd) {
+		state, err := term.MakeRaw(fd)
+		if err != nil {
+			return -1, err
+		}
+		defer term.Restore(fd, state)
+	}
+
+	stdin := execstream.NewWriters(conn, execstream.Stdin)[0]
+
+	winch := make(chan os.Signal, 1)
+	signal.Notify(winch, syscall.SIGWINCH)
+	defer signal.Stop(winch)
+	go func() {
+		for range winch {
+			if width, height := terminalSize(); width > 0 {
+				stdin.WriteResize(width, height)
+			}
+		}
+	}()
+
+	go func() {
+		var keys consoleDetach
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if n > 0 {
+				data, detached := buf[:n], false
+				if detach {
+					data, detached = keys.filter(data)
+				}
+				if _, err := stdin.Write(data); err != nil {
+					return
+				}
+				if detached {
+					break
+				}
+			}
+			if err != nil {
+				break
+			}
+		}
+		stdin.WriteEOF()
+	}()
+
+	for {
+		typ, payload, err := execstream.ReadFrame(conn)
+		if err != nil {
+			return -1, fmt.Errorf("session ended without an exit code: %w", err)
+		}
+		switch typ {
+		case execstream.Stdout:
+			os.Stdout.Write(payload)
+		case execstream.Stderr:
+			os.Stderr.Write(payload)
+		case execstream.Exit:
+			return execstream.ExitCode(payload)
+		}
+	}
+}
+
+// consoleDetach finds the detach sequence, Ctrl+a q, in console input.
+type consoleDetach struct {
+	pending bool // the last byte seen was Ctrl+a
+}
+
+// filter returns the part of p to send and whether the detach sequence was typed.
+func (d *consoleDetach) filter(p []byte) ([]byte, bool) {
+	out := make([]byte, 0, len(p)+1)
+	for _, b := range p {
+		switch {
+		case d.pending && b == 'q':
+			return out, true
+		case d.pending:
+			d.pending = false
+			out = append(out, detachPrefix)
+			if b != detachPrefix {
+				out = append(out, b)
+			}
+		case b == detachPrefix:
+			d.pending = true
+		default:
+			out = append(out, b)
+		}
+	}
+	return out, false
+}
+
+func isTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// terminalSize returns the size of the local window, or 0, 0 when stdout is
+// not a terminal.
+func terminalSize() (int, int) {
+	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		return 0, 0
+	}
+	return width, height
+}
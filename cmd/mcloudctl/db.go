@@ -0,0 +1,131 @@
+package mcloudctl
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"mcloud/internal/config"
+	"mcloud/internal/database"
+	"mcloud/pkg/logger"
+
+	"github.com/urfave/cli/v2"
+)
+
+// openDatabase opens the manager database at --db-path, or at database.db_path
+// from the config file, without migrating it.
+func openDatabase(c *cli.Context) (*database.Database, error) {
+	path := c.String("db-path")
+	if path == "" {
+		cfg, err := config.GetConfig()
+		if err != nil {
+			return nil, err
+		}
+		path = cfg.Database.DBPath
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("database %s: %w", path, err)
+	}
+	return database.Open(path)
+}
+
+// DBStatusCommand is the CLI command handler for 'mcloudctl db status'.
+// Lists the migrations built into this mcloudctl and whether each one is applied.
+//
+// CLI Usage:
+//   mcloudctl db status [--db-path /var/lib/mcloud/mcloud.db]
+//
+// Example Output:
+//   MIGRATION                      STATE    APPLIED              REVERSIBLE
+//   001_init.sql                   applied  2026-01-02 09:12:03  no
+//   016_kv_store_versions.sql      applied  2026-01-03 10:30:45  yes
+//   017_workload_priority.sql      pending  -                    yes
+func DBStatusCommand(c *cli.Context) error {
+	db, err := openDatabase(c)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrations, err := db.Status()
+	if err != nil {
+		return err
+	}
+
+	return printResult(c, migrations, func(tw io.Writer) {
+		fmt.Fprintln(tw, "MIGRATION\tSTATE\tAPPLIED\tREVERSIBLE")
+		for _, m := range migrations {
+			applied := "-"
+			if m.AppliedAt != nil {
+				applied = m.AppliedAt.Local().Format(time.DateTime)
+			}
+			reversible := "no"
+			if m.Reversible {
+				reversible = "yes"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", m.Filename, m.State, applied, reversible)
+		}
+	})
+}
+
+// DBMigrateCommand is the CLI command handler for 'mcloudctl db migrate'.
+// Applies pending migrations, the same way mcloudd does when it starts.
+//
+// CLI Usage:
+//   mcloudctl db migrate
+//
+// Example Output:
+//   Applied migration: 017_workload_priority.sql
+//   Migration completed successfully
+func DBMigrateCommand(c *cli.Context) error {
+	db, err := openDatabase(c)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return db.Migrate()
+}
+
+// DBRollbackCommand is the CLI command handler for 'mcloudctl db rollback'.
+// Reverts the last --steps migrations. Stop mcloudd first and start the version
+// that matches the rolled back schema afterwards; a newer mcloudd would apply the
+// migrations again on start. Requires --force because reverted tables and columns
+// are dropped with their data.
+//
+// CLI Usage:
+//   mcloudctl db rollback --steps 2 --force
+//
+// Example Output:
+//   Rolled back migration: 017_workload_priority.sql
+//   Rolled back migration: 016_kv_store_versions.sql
+//   [INFO] 2026-01-03 10:30:45 Rolled back 2 migrations
+//
+// Example Output (Error - No --force):
+//   Returns: error("rollback drops the tables and columns added by 1 migration(s); re-run with --force")
+func DBRollbackCommand(c *cli.Context) error {
+	steps := c.Int("steps")
+	if steps <= 0 {
+		return usageErrorf("--steps must be at least 1")
+	}
+	if !c.Bool("force") {
+		return fmt.Errorf("rollback drops the tables and columns added by %d migration(s); re-run with --force", steps)
+	}
+
+	db, err := openDatabase(c)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	reverted, err := db.Rollback(steps)
+	if err != nil {
+		return err
+	}
+	if err := printResult(c, reverted, nil); err != nil {
+		return err
+	}
+	logger.Info("Rolled back %d migrations", len(reverted))
+	return nil
+}
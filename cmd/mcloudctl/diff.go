@@ -0,0 +1,93 @@
+package mcloudctl
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"mcloud/internal/cluster"
+	"mcloud/internal/config"
+
+	"github.com/urfave/cli/v2"
+)
+
+// DiffCommand is the CLI command handler for 'mcloudctl diff'.
+// Compares a cluster state manifest (e.g. committed to git) with the live one
+// from GET /cluster/state.yaml and prints the drift per resource:
+//   - declared in the file but not live
+//   + live but not in the file
+//   ~ in both with different fields (file value => live value)
+// Like diff(1), it exits 1 when there is drift, so it can gate a CI job.
+//
+// CLI Usage:
+//   mcloudctl diff --against cluster-state.yaml
+//
+// Example Output:
+//   ~ workload prod/web-1
+//       image: ubuntu:24.04 => ubuntu:22.04
+//       env.LOG_LEVEL: debug => (unset)
+//   - config agent/drain_timeout
+//   + secret old-token
+func DiffCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	data, err := os.ReadFile(c.String("against"))
+	if err != nil {
+		return err
+	}
+	want, err := cluster.ParseState(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", c.String("against"), err)
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	var live bytes.Buffer
+	if err := client.download(ctx, "/cluster/state.yaml", &live); err != nil {
+		return err
+	}
+	have, err := cluster.ParseState(live.Bytes())
+	if err != nil {
+		return err
+	}
+
+	diffs := cluster.DiffState(want, have)
+	err = printResult(c, diffs, func(tw io.Writer) {
+		if len(diffs) == 0 {
+			fmt.Fprintln(tw, "No drift")
+		}
+		for _, d := range diffs {
+			switch d.Change {
+			case "missing":
+				fmt.Fprintf(tw, "- %s\n", d.Resource)
+			case "extra":
+				fmt.Fprintf(tw, "+ %s\n", d.Resource)
+			default:
+				fmt.Fprintf(tw, "~ %s\n", d.Resource)
+				for _, f := range d.Fields {
+					fmt.Fprintf(tw, "    %s: %s => %s\n", f.Path, orUnset(f.Want), orUnset(f.Have))
+				}
+			}
+		}
+	})
+	if err != nil || len(diffs) == 0 {
+		return err
+	}
+	return cli.Exit("", 1)
+}
+
+func orUnset(v string) string {
+	if v == "" {
+		return "(unset)"
+	}
+	return v
+}
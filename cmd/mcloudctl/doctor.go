@@ -0,0 +1,88 @@
+package mcloudctl
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"mcloud/internal/config"
+	"mcloud/internal/doctor"
+	"mcloud/pkg/logger"
+
+	"github.com/urfave/cli/v2"
+)
+
+// DoctorCommand is the CLI command handler for 'mcloudctl doctor'.
+// Cross-checks state.yaml, the manager database, the certificates on disk and
+// (unless --offline) the live LXD, Ceph and OVN clusters of this node, and
+// prints every inconsistency with its remedy (see package doctor). With --fix
+// the safe remedies are applied: rewriting a state.yaml that is behind the
+// database and tightening private key permissions. Fails when errors remain,
+// so it can gate scripts.
+//
+// CLI Usage:
+//   mcloudctl doctor [--fix] [--offline]
+//
+// Example Output:
+//   CHECK        SEVERITY  MESSAGE
+//   database     ok        /var/lib/mcloud/mcloud.db is intact and migrated
+//   state        error     the database holds cluster prod but state.yaml does not say it is initialized
+//                          → rewrite state.yaml from the database (--fix)
+//   cert.server  warn      mcloud-server expires on 2026-11-02 09:00:00
+//                          → renew it with mcloudctl cert renew server
+//   lxd          ok        3 cluster members match the nodes
+//   Error: doctor found 1 error and 1 warning
+//
+// Example Output (--fix):
+//   state        fixed     the database holds cluster prod but state.yaml does not say it is initialized
+//   [INFO] 2026-10-16 10:30:45 1 finding fixed
+func DoctorCommand(c *cli.Context) error {
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+
+	report := doctor.Run(context.Background(), cfg, doctor.Options{Fix: c.Bool("fix"), Live: !c.Bool("offline")})
+	err = printResult(c, report, func(tw io.Writer) {
+		fmt.Fprintln(tw, "CHECK\tSEVERITY\tMESSAGE")
+		for _, f := range report.Findings {
+			severity := f.Severity
+			if f.Fixed {
+				severity = "fixed"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", f.Check, severity, f.Message)
+			if f.Remedy != "" && !f.Fixed {
+				remedy := f.Remedy
+				if f.Fixable {
+					remedy += " (--fix)"
+				}
+				fmt.Fprintf(tw, "\t\t→ %s\n", remedy)
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	fixed := 0
+	for _, f := range report.Findings {
+		if f.Fixed {
+			fixed++
+		}
+	}
+	if fixed > 0 {
+		logger.Info("%d %s fixed", fixed, plural(fixed, "finding"))
+	}
+	if report.Errors > 0 {
+		return fmt.Errorf("doctor found %d %s and %d %s", report.Errors, plural(report.Errors, "error"), report.Warnings, plural(report.Warnings, "warning"))
+	}
+	return nil
+}
+
+// plural returns word, with an s unless n is 1.
+func plural(n int, word string) string {
+	if n == 1 {
+		return word
+	}
+	return word + "s"
+}
@@ -0,0 +1,73 @@
+package mcloudctl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"mcloud/internal/config"
+	"mcloud/internal/reconcile"
+
+	"github.com/urfave/cli/v2"
+)
+
+// DriftCommand is the CLI command handler for 'mcloudctl drift'.
+// Fetches GET /drift (or runs a check with POST /drift/check) and prints the
+// drift between the database and LXD, OVN and Ceph found by the reconciler.
+//
+// CLI Usage:
+//   mcloudctl drift [--check]
+//
+// Example Output:
+//   Checked 2026-10-16T09:00:00Z
+//   KIND      TYPE                   RESOURCE    POLICY  HEALABLE  SINCE                 MESSAGE
+//   volume    drift.volume_orphan    remote/old  report  no        2026-10-16T08:55:00Z  volume remote/old is not recorded
+//   workload  drift.instance_state   web-1       report  yes       2026-10-16T09:00:00Z  instance web-1 is Stopped, recorded as running
+func DriftCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	var status reconcile.Status
+	if c.Bool("check") {
+		err = client.do(ctx, http.MethodPost, "/drift/check", nil, &status)
+	} else {
+		err = client.do(ctx, http.MethodGet, "/drift", nil, &status)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, e := range status.Errors {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", e)
+	}
+	return printResult(c, status, func(tw io.Writer) {
+		if status.CheckedAt == nil {
+			fmt.Fprintln(tw, "Not checked yet (run with --check)")
+			return
+		}
+		fmt.Fprintf(tw, "Checked %s\n", status.CheckedAt.Format(time.RFC3339))
+		if len(status.Drift) == 0 {
+			fmt.Fprintln(tw, "No drift")
+			return
+		}
+		fmt.Fprintln(tw, "KIND\tTYPE\tRESOURCE\tPOLICY\tHEALABLE\tSINCE\tMESSAGE")
+		for _, d := range status.Drift {
+			healable := "no"
+			if d.Healable {
+				healable = "yes"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", d.Kind, d.Type, d.Resource, d.Policy, healable, d.DetectedAt.Format(time.RFC3339), d.Message)
+		}
+	})
+}
@@ -0,0 +1,176 @@
+package mcloudctl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"mcloud/internal/cluster"
+	"mcloud/internal/config"
+	"mcloud/internal/constant"
+	"mcloud/internal/database"
+	"mcloud/internal/installer"
+	"mcloud/pkg/utils"
+	"mcloud/services/microceph"
+
+	"gopkg.in/yaml.v3"
+)
+
+// dryRunInit prints what `mcloudctl init --dry-run` would do on this host once
+// validation and preflight passed: the config file it writes, then each step of
+// the bootstrap pipeline, with the LXD preseed and the MicroOVN and MicroCeph
+// commands it runs. Nothing is written; the cluster name is checked against the
+// database only if one exists. Steps an interrupted init completed are shown
+// as skipped, unless clean starts over.
+//
+// Example Output:
+//   Dry run of init of cluster prod; nothing is changed
+//
+//   Write /etc/mcloud/config.yaml:
+//     manager:
+//       http_host: 10.0.0.5
+//       ...
+//
+//   [1/8] certs
+//     generate the CA /var/lib/mcloud/certs/ca.crt and intermediate /var/lib/mcloud/certs/intermediate.crt
+//     ...
+//   [3/8] lxd
+//     $ lxd init --preseed <<'EOF'
+//   config:
+//     core.https_address: 10.0.0.5:8443
+//   ...
+//   EOF
+//   [4/8] ovn
+//     $ microovn init
+//   [5/8] ceph
+//     $ microceph init
+//     $ microceph disk add /dev/sdb --wipe
+//   ...
+func dryRunInit(ctx context.Context, name string, host utils.HostInfo, opts *InitOptions, prev *cluster.PipelineState, clean bool) error {
+	cfg := newConfig(host, opts)
+	if prev == nil || clean {
+		if err := checkClusterNameLength(name); err != nil {
+			return err
+		}
+		if _, err := os.Stat(cfg.Database.DBPath); err == nil {
+			conn, err := database.Connect(cfg.Database.DBPath)
+			if err != nil {
+				return fmt.Errorf("failed to connect to database: %w", err)
+			}
+			err = validateClusterName(ctx, name, conn)
+			database.Close(conn)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Dry run of init of cluster %s; nothing is changed\n\n", name)
+	fmt.Printf("Write %s:\n%s\n", opts.ConfigPath, indentLines(string(data), "  "))
+
+	var completed []string
+	switch {
+	case prev != nil && clean:
+		fmt.Printf("Undo the partial init of cluster %s (%s) and start over\n\n", prev.Data["cluster_name"], failedAt(prev))
+	case prev != nil:
+		fmt.Printf("Resume the init of cluster %s (%s)\n\n", name, failedAt(prev))
+		completed = prev.Completed
+	}
+
+	steps := dryRunBootstrapSteps(os.Stdout, name, host, cfg, cluster.CephDisks(cfg.Storage, host.Hostname))
+	for i, s := range steps {
+		fmt.Printf("[%d/%d] %s\n", i+1, len(steps), s.Name)
+		if slices.Contains(completed, s.Name) {
+			fmt.Println("  skipped: completed by the interrupted init")
+			continue
+		}
+		if err := s.Execute(ctx); err != nil {
+			return fmt.Errorf("step %s: %w", s.Name, err)
+		}
+	}
+	fmt.Println("\nRe-run without --dry-run to initialize the cluster")
+	return nil
+}
+
+// dryRunBootstrapSteps mirrors bootstrapPipeline with steps that print what
+// they would do to w.
+func dryRunBootstrapSteps(w io.Writer, name string, host utils.HostInfo, cfg config.Config, disks microceph.DiskConfig) []cluster.Step {
+	say := func(format string, args ...any) func(context.Context) error {
+		return func(context.Context) error {
+			fmt.Fprintf(w, "  "+format+"\n", args...)
+			return nil
+		}
+	}
+	sec := cfg.Security
+	steps := []cluster.Step{
+		{
+			Name: "certs",
+			Execute: say("generate the CA %s and intermediate %s\n  issue the server certificate %s for %v %v\n  issue the client certificate %s and node certificate %s",
+				sec.CACertPath, sec.IntermediateCertPath, sec.ServerCertPath,
+				append([]string{host.Hostname}, sec.ServerNames...), host.IPs, sec.ClientCertPath, sec.NodeCertPath),
+		},
+		{
+			Name:    "database",
+			Execute: say("create cluster %s with leader node %s (%s) in %s", name, host.Hostname, host.IPs[0], cfg.Database.DBPath),
+		},
+		{
+			Name:    "mcloudd",
+			Execute: say("install and start mcloudd (%s) with --config %s", installer.DetectServiceManager().Name(), cfg.ConfigPath),
+		},
+		{
+			Name:    "state",
+			Execute: say("write %s", cfg.StatePath),
+		},
+		{
+			Name:    "rejoin-bundle",
+			Execute: say("write %s", constant.DefaultRejoinBundlePath),
+		},
+	}
+	return slices.Insert(steps, 2, cluster.DryRunComponents(w, components).BootstrapSteps(name, host.IPs[0].String(), disks)...)
+}
+
+// dryRunJoin prints what `mcloudctl join --dry-run` would do once its checks
+// passed: the MicroCeph commands joining Ceph with the node's disks, if
+// cephToken is set, and the mcloud-agent service it installs.
+//
+// Example Output:
+//   Dry run of join; nothing is changed
+//
+//   Join Ceph
+//     $ microceph join <ceph-token>
+//     $ microceph disk add /dev/nvme1n1
+//   Install mcloud-agent
+//     install and start mcloud-agent (systemd) with --config /etc/mcloud/config.yaml --manager-url https://10.0.0.5:9028
+func dryRunJoin(ctx context.Context, configPath, managerURL, cephToken string, disks microceph.DiskConfig) error {
+	fmt.Printf("Dry run of join; nothing is changed\n\n")
+	if cephToken != "" {
+		fmt.Println("Join Ceph")
+		ceph := cluster.DryRunComponents(os.Stdout, components).Ceph
+		if err := ceph.Join(ctx, microceph.JoinConfig{Token: cephToken, Disks: disks}); err != nil {
+			return err
+		}
+	}
+	fmt.Println("Install mcloud-agent")
+	fmt.Printf("  install and start mcloud-agent (%s) with --config %s --manager-url %s\n",
+		installer.DetectServiceManager().Name(), configPath, managerURL)
+	fmt.Println("\nRe-run without --dry-run to join the cluster")
+	return nil
+}
+
+// indentLines prefixes every line of s with prefix.
+func indentLines(s, prefix string) string {
+	lines := strings.SplitAfter(s, "\n")
+	for i, l := range lines {
+		if l != "" {
+			lines[i] = prefix + l
+		}
+	}
+	return strings.Join(lines, "")
+}
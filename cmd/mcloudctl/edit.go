@@ -0,0 +1,258 @@
+package mcloudctl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+
+	"mcloud/internal/config"
+	"mcloud/internal/workload"
+	"mcloud/pkg/logger"
+
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultEditor is used when $EDITOR is not set.
+const defaultEditor = "vi"
+
+// workloadEditDoc is the editable view of a workload shown by 'mcloudctl edit workload'.
+// Field names match workload.UpdateRequest so the diff can be sent as a merge patch.
+type workloadEditDoc struct {
+	Priority string             `yaml:"priority" json:"priority"`
+	Env      map[string]string  `yaml:"env" json:"env"`
+	Secrets  map[string]string  `yaml:"secrets" json:"secrets"`
+	Hooks    []workloadHookEdit `yaml:"hooks" json:"hooks"`
+}
+
+type workloadHookEdit struct {
+	Event   string `yaml:"event" json:"event"`
+	Webhook string `yaml:"webhook,omitempty" json:"webhook,omitempty"`
+	Command string `yaml:"command,omitempty" json:"command,omitempty"`
+}
+
+// normalize replaces nil collections with empty ones, so clearing a section
+// in the editor is diffed as removing its entries rather than as "not set".
+func (d *workloadEditDoc) normalize() {
+	if d.Env == nil {
+		d.Env = map[string]string{}
+	}
+	if d.Secrets == nil {
+		d.Secrets = map[string]string{}
+	}
+	if d.Hooks == nil {
+		d.Hooks = []workloadHookEdit{}
+	}
+}
+
+// EditWorkloadCommand is the CLI command handler for 'mcloudctl edit workload'.
+// Fetches GET /workloads/{id}, opens the editable fields as YAML in $EDITOR and
+// sends only the changed fields as a merge patch to PATCH /workloads/{id}.
+// If the patch is rejected, the edited file is kept so the changes are not lost.
+//
+// CLI Usage:
+//   mcloudctl edit workload <workload-id>
+//
+// Example Output:
+//   Updated workload web-1 (550e8400-e29b-41d4-a716-446655440000)
+func EditWorkloadCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	id := c.Args().First()
+	if id == "" {
+		return usageErrorf("workload id is required")
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	var current workload.WorkloadDetail
+	if err := client.do(ctx, http.MethodGet, "/workloads/"+id, nil, &current); err != nil {
+		return err
+	}
+
+	original := workloadEditDoc{Priority: current.Priority, Env: current.Env, Secrets: current.Secrets}
+	for _, h := range current.Hooks {
+		hook := workloadHookEdit{Event: h.Event}
+		if h.WebhookURL != nil {
+			hook.Webhook = *h.WebhookURL
+		}
+		if h.Command != nil {
+			hook.Command = *h.Command
+		}
+		original.Hooks = append(original.Hooks, hook)
+	}
+	original.normalize()
+
+	header := fmt.Sprintf(`# Editing workload %s (%s).
+# Only priority (low, normal, high), env, secrets (variable: secret name)
+# and hooks can be changed.
+# Lines beginning with '#' are ignored; an empty file cancels the edit.
+`, current.Name, current.ID)
+
+	var edited workloadEditDoc
+	path, changed, err := editYAML(header, &original, &edited)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		logger.Info("Edit cancelled, no changes made")
+		return nil
+	}
+	edited.normalize()
+
+	patch, err := mergePatch(&original, &edited)
+	if err != nil {
+		return err
+	}
+	if len(patch) == 0 {
+		os.Remove(path)
+		logger.Info("Edit cancelled, no changes made")
+		return nil
+	}
+
+	if err := client.do(ctx, http.MethodPatch, "/workloads/"+id, patch, nil); err != nil {
+		return fmt.Errorf("%w\nyour changes were saved to %s", err, path)
+	}
+	os.Remove(path)
+
+	logger.Info("Updated workload %s (%s)", current.Name, current.ID)
+	return nil
+}
+
+// editYAML writes doc as YAML below header to a temporary file, opens it in
+// $EDITOR and decodes the result into edited, rejecting unknown fields.
+// changed is false when the file was saved unchanged or emptied; the file is
+// then removed. Otherwise the caller removes it once the edit was applied.
+func editYAML(header string, doc any, edited any) (path string, changed bool, err error) {
+	data, err := yaml.Marshal(doc)
+	if err != nil {
+		return "", false, err
+	}
+	original := append([]byte(header), data...)
+
+	f, err := os.CreateTemp("", "mcloudctl-edit-*.yaml")
+	if err != nil {
+		return "", false, err
+	}
+	path = f.Name()
+	_, err = f.Write(original)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", false, err
+	}
+
+	editor := os.Getenv("EDITOR")
+	if editor == "" {
+		editor = defaultEditor
+	}
+	// $EDITOR may carry arguments, e.g. "code --wait"
+	argv := append(strings.Fields(editor), path)
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		os.Remove(path)
+		return "", false, fmt.Errorf("editor %s failed: %w", editor, err)
+	}
+
+	saved, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, err
+	}
+	if bytes.Equal(saved, original) || isBlankYAML(saved) {
+		os.Remove(path)
+		return "", false, nil
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(saved))
+	dec.KnownFields(true)
+	if err := dec.Decode(edited); err != nil && !errors.Is(err, io.EOF) {
+		return "", false, fmt.Errorf("invalid YAML (your changes were saved to %s): %w", path, err)
+	}
+	return path, true, nil
+}
+
+// isBlankYAML reports whether data holds nothing but comments and whitespace.
+func isBlankYAML(data []byte) bool {
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return false
+		}
+	}
+	return true
+}
+
+// mergePatch returns the JSON merge patch (RFC 7386) that turns original into
+// edited: changed fields with their new value, removed object keys as null.
+// Both values are compared by their JSON encoding.
+//
+// Example:
+//   original: {"env": {"A": "1", "B": "2"}}
+//   edited:   {"env": {"A": "1", "C": "3"}}
+//   patch:    {"env": {"B": null, "C": "3"}}
+func mergePatch(original any, edited any) (map[string]any, error) {
+	var a, b map[string]any
+	if err := roundTripJSON(original, &a); err != nil {
+		return nil, err
+	}
+	if err := roundTripJSON(edited, &b); err != nil {
+		return nil, err
+	}
+	return diffObjects(a, b), nil
+}
+
+func roundTripJSON(in any, out any) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+func diffObjects(a map[string]any, b map[string]any) map[string]any {
+	patch := map[string]any{}
+	for k := range a {
+		if _, ok := b[k]; !ok {
+			patch[k] = nil
+		}
+	}
+	for k, bv := range b {
+		av, ok := a[k]
+		if !ok {
+			patch[k] = bv
+			continue
+		}
+		aObj, aIsObj := av.(map[string]any)
+		bObj, bIsObj := bv.(map[string]any)
+		if aIsObj && bIsObj {
+			if sub := diffObjects(aObj, bObj); len(sub) > 0 {
+				patch[k] = sub
+			}
+			continue
+		}
+		if !reflect.DeepEqual(av, bv) {
+			patch[k] = bv
+		}
+	}
+	return patch
+}
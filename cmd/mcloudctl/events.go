@@ -0,0 +1,151 @@
+package mcloudctl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"mcloud/internal/config"
+	"mcloud/internal/database"
+	"mcloud/pkg/client"
+	"mcloud/pkg/utils"
+
+	"github.com/urfave/cli/v2"
+)
+
+// EventsCommand is the CLI command handler for 'mcloudctl events'.
+// Prints recent events from GET /events; with --follow it then tails
+// GET /events/stream until interrupted, reconnecting if the connection drops.
+// Following with --output json or yaml prints every event as its own document.
+//
+// CLI Usage:
+//   mcloudctl events [--limit 20] [--follow]
+//
+// Example Output:
+//   TIME                 TYPE                    SEVERITY  MESSAGE
+//   2026-01-03 10:30:45  image.import.progress   info      Image ubuntu-vm: 40% (1200/3000 bytes)
+func EventsCommand(c *cli.Context) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	api, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	var events []database.Event
+	if err := api.do(ctx, http.MethodGet, "/events?limit="+strconv.Itoa(c.Int("limit")), nil, &events); err != nil {
+		return err
+	}
+
+	// Newest first from the API; print oldest first so --follow continues downwards
+	slices.Reverse(events)
+	follow := c.Bool("follow")
+	if follow && outputFormat(c) != outputTable {
+		// A stream of documents, one per event, rather than a list
+		for _, e := range events {
+			if err := printDocument(c, e); err != nil {
+				return err
+			}
+		}
+	} else {
+		err := printResult(c, events, func(tw io.Writer) {
+			fmt.Fprintln(tw, "TIME\tTYPE\tSEVERITY\tMESSAGE")
+			for _, e := range events {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", e.CreatedAt.Local().Format(time.DateTime), e.Type, e.Severity, e.Message)
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if !follow {
+		return nil
+	}
+
+	stream, err := client.New(client.Config{
+		BaseURL:    "https://" + utils.HostPort(cfg.Manager.HttpHost, cfg.Manager.HttpPort),
+		CACertPath: cfg.Security.CACertPath,
+		CertPath:   cfg.Security.ClientCertPath,
+		KeyPath:    cfg.Security.ClientKeyPath,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = stream.WatchEvents(ctx, func(e client.Event) error {
+		if outputFormat(c) != outputTable {
+			return printDocument(c, e)
+		}
+		fmt.Printf("%s  %s  %s  %s\n", e.CreatedAt.Local().Format(time.DateTime), e.Type, e.Severity, e.Message)
+		return nil
+	})
+	if ctx.Err() != nil {
+		// Interrupted by the user
+		return nil
+	}
+	return err
+}
+
+// EventsSearchCommand is the CLI command handler for 'mcloudctl events search'.
+// Sends GET /events with a full-text query and field filters and prints the
+// matching events, oldest first. --type also takes a family of types, e.g.
+// node.*, and --severity a minimum severity.
+//
+// CLI Usage:
+//   mcloudctl events search "ceph degraded" [--since 24h] [--until 1h] [--node node-2] [--type ceph.health] [--severity warning] [--limit 50]
+//
+// Example Output:
+//   TIME                 TYPE          SEVERITY  MESSAGE
+//   2026-01-03 10:30:45  node.offline  warning   Node node-2 is offline: its agent disconnected
+func EventsSearchCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	api, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	query.Set("q", strings.Join(c.Args().Slice(), " "))
+	for _, name := range []string{"since", "until", "node", "type", "severity"} {
+		if v := c.String(name); v != "" {
+			query.Set(name, v)
+		}
+	}
+	query.Set("limit", strconv.Itoa(c.Int("limit")))
+
+	var events []database.Event
+	if err := api.do(ctx, http.MethodGet, "/events?"+query.Encode(), nil, &events); err != nil {
+		return err
+	}
+	slices.Reverse(events)
+
+	return printResult(c, events, func(tw io.Writer) {
+		if len(events) == 0 {
+			fmt.Fprintln(tw, "No matching events")
+			return
+		}
+		fmt.Fprintln(tw, "TIME\tTYPE\tSEVERITY\tMESSAGE")
+		for _, e := range events {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", e.CreatedAt.Local().Format(time.DateTime), e.Type, e.Severity, e.Message)
+		}
+	})
+}
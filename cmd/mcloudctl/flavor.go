@@ -0,0 +1,129 @@
+package mcloudctl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"mcloud/internal/config"
+	"mcloud/internal/database"
+	"mcloud/internal/flavor"
+	"mcloud/pkg/logger"
+
+	"github.com/urfave/cli/v2"
+)
+
+// FlavorCreateCommand is the CLI command handler for 'mcloudctl flavor create'.
+// Sends POST /flavors to mcloudd using the local client certificate.
+//
+// CLI Usage:
+//   mcloudctl flavor create <name> --cpus 8 --memory-mb 16384 --disk-gb 80
+//
+// Example Output:
+//   [INFO] 2026-01-02 10:30:45 Created flavor xlarge (8 CPUs, 16384 MiB memory, 80 GiB disk)
+//
+// Example Output (Error):
+//   Error: MC2001 FlavorExists: a flavor with this name already exists: small
+func FlavorCreateCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	name := c.Args().First()
+	if name == "" {
+		return usageErrorf("flavor name is required")
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	req := flavor.CreateRequest{
+		Name:     name,
+		CPUs:     c.Int("cpus"),
+		MemoryMB: c.Int("memory-mb"),
+		DiskGB:   c.Int("disk-gb"),
+	}
+	var f database.Flavor
+	if err := client.do(ctx, http.MethodPost, "/flavors", req, &f); err != nil {
+		return err
+	}
+	if err := printResult(c, f, nil); err != nil {
+		return err
+	}
+	logger.Info("Created flavor %s (%d CPUs, %d MiB memory, %d GiB disk)", f.Name, f.CPUs, f.MemoryMB, f.DiskGB)
+	return nil
+}
+
+// FlavorListCommand is the CLI command handler for 'mcloudctl flavor list'.
+// Fetches GET /flavors and prints the flavors as a table, smallest first.
+//
+// CLI Usage:
+//   mcloudctl flavor list
+//
+// Example Output:
+//   NAME    CPUS  MEMORY    DISK    PROFILE
+//   small   1     1024MiB   10GiB   flavor-small
+//   medium  2     4096MiB   20GiB   flavor-medium
+//   large   4     8192MiB   40GiB   flavor-large
+func FlavorListCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	var flavors []database.Flavor
+	if err := client.do(ctx, http.MethodGet, "/flavors", nil, &flavors); err != nil {
+		return err
+	}
+
+	return printResult(c, flavors, func(tw io.Writer) {
+		fmt.Fprintln(tw, "NAME\tCPUS\tMEMORY\tDISK\tPROFILE")
+		for _, f := range flavors {
+			fmt.Fprintf(tw, "%s\t%d\t%dMiB\t%s\t%s\n", f.Name, f.CPUs, f.MemoryMB, flavor.RootSize(&f), flavor.ProfileName(f.Name))
+		}
+	})
+}
+
+// FlavorDeleteCommand is the CLI command handler for 'mcloudctl flavor delete'.
+// Sends DELETE /flavors/{name}; flavors used by workloads cannot be deleted.
+//
+// CLI Usage:
+//   mcloudctl flavor delete <name>
+//
+// Example Output (Error):
+//   Error: MC2002 FlavorInUse: flavor is used by workloads: medium is used by 3 workloads
+func FlavorDeleteCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	name := c.Args().First()
+	if name == "" {
+		return usageErrorf("flavor name is required")
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	if err := client.do(ctx, http.MethodDelete, "/flavors/"+url.PathEscape(name), nil, nil); err != nil {
+		return err
+	}
+	logger.Info("Deleted flavor %s", name)
+	return nil
+}
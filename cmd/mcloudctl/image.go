@@ -0,0 +1,221 @@
+package mcloudctl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"mcloud/internal/config"
+	"mcloud/internal/database"
+	"mcloud/internal/image"
+	"mcloud/pkg/logger"
+
+	"github.com/urfave/cli/v2"
+)
+
+// ImageImportCommand is the CLI command handler for 'mcloudctl image import'.
+// Sends POST /images/import to mcloudd and, with --wait, polls the import until it finishes.
+//
+// CLI Usage:
+//   mcloudctl image import <url> --alias <alias> [--sha256 <hex>] [--limit-kbps <n>] [--wait]
+//
+// Example Output (--wait):
+//   Image import 1b2c... started
+//   downloading  42% (1.2 GiB / 2.9 GiB)
+//   Image ubuntu-24.04-vm imported
+func ImageImportCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	source := c.Args().First()
+	if source == "" {
+		return usageErrorf("image url is required")
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	req := image.ImportRequest{
+		URL:            source,
+		Alias:          c.String("alias"),
+		SHA256:         c.String("sha256"),
+		MaxBytesPerSec: c.Int64("limit-kbps") * 1024,
+	}
+	var imp database.ImageImport
+	if err := client.do(ctx, http.MethodPost, "/images/import", req, &imp); err != nil {
+		return err
+	}
+	if err := printResult(c, imp, nil); err != nil {
+		return err
+	}
+	logger.Info("Image import %s started", imp.ID)
+
+	if !c.Bool("wait") {
+		return nil
+	}
+
+	for {
+		time.Sleep(2 * time.Second)
+		if err := client.do(ctx, http.MethodGet, "/images/imports/"+imp.ID, nil, &imp); err != nil {
+			return err
+		}
+
+		switch imp.Status {
+		case image.StatusCompleted:
+			logger.Info("Image %s imported", imp.Alias)
+			return nil
+		case image.StatusFailed:
+			if imp.Error != nil {
+				return fmt.Errorf("image import failed: %s", *imp.Error)
+			}
+			return fmt.Errorf("image import failed")
+		}
+
+		if imp.BytesTotal > 0 {
+			fmt.Fprintf(os.Stderr, "%-12s %3d%% (%s / %s)\n", imp.Status, imp.BytesDone*100/imp.BytesTotal,
+				formatBytes(imp.BytesDone), formatBytes(imp.BytesTotal))
+		} else {
+			fmt.Fprintf(os.Stderr, "%-12s %s\n", imp.Status, formatBytes(imp.BytesDone))
+		}
+	}
+}
+
+// formatBytes renders a byte count using binary units, e.g. 1.5 GiB.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// ImageBuildCommand is the CLI command handler for 'mcloudctl image build'.
+// Reads the provisioning script (a file, or - for stdin) and sends POST /images/builds.
+// The build runs as an operation; with --wait the command polls it until it finishes.
+//
+// CLI Usage:
+//   mcloudctl image build --alias <alias> --base <image> --script <file|-> --node <node> [--vm] [--wait]
+//
+// Example Output (--wait):
+//   Image build 3e9a... started, operation 7f1c...
+//   running     10%  Launching builder mcloud-build-3e9a1b2c from ubuntu:24.04
+//   running     30%  Running provisioning script
+//   running     80%  Publishing image web-golden
+//   Image web-golden built (fingerprint 5f2a0c...)
+func ImageBuildCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	var script []byte
+	var err error
+	if path := c.String("script"); path == "-" {
+		script, err = io.ReadAll(os.Stdin)
+	} else {
+		script, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return fmt.Errorf("read script: %w", err)
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	req := image.BuildRequest{
+		Alias:     c.String("alias"),
+		BaseImage: c.String("base"),
+		Script:    string(script),
+		Node:      c.String("node"),
+		VM:        c.Bool("vm"),
+	}
+	var result image.BuildResult
+	if err := client.do(ctx, http.MethodPost, "/images/builds", req, &result); err != nil {
+		return err
+	}
+	if err := printResult(c, result, nil); err != nil {
+		return err
+	}
+	logger.Info("Image build %s started, operation %s", result.Build.ID, result.Operation.ID)
+
+	if !c.Bool("wait") {
+		return nil
+	}
+	if _, err := waitOperation(ctx, client, result.Operation.ID, 0); err != nil {
+		return err
+	}
+
+	var build database.ImageBuild
+	if err := client.do(ctx, http.MethodGet, "/images/builds/"+result.Build.ID, nil, &build); err != nil {
+		return err
+	}
+	fingerprint := "-"
+	if build.Fingerprint != nil {
+		fingerprint = *build.Fingerprint
+	}
+	logger.Info("Image %s built (fingerprint %s)", build.Alias, fingerprint)
+	return nil
+}
+
+// ImageBuildListCommand is the CLI command handler for 'mcloudctl image builds'.
+// Fetches GET /images/builds and prints the builds with their provenance, newest first.
+//
+// CLI Usage:
+//   mcloudctl image builds [--alias <alias>] [--limit 20]
+//
+// Example Output:
+//   ID                                    ALIAS       BASE          NODE      STATUS     SCRIPT        FINGERPRINT   CREATED
+//   3e9a1b2c-...                          web-golden  ubuntu:24.04  660e...   completed  9f86d081884c  5f2a0c1d9e3b  2026-10-16 10:30:45
+func ImageBuildListCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{"limit": {strconv.Itoa(c.Int("limit"))}}
+	if alias := c.String("alias"); alias != "" {
+		query.Set("alias", alias)
+	}
+	var resp image.ListBuildsResponse
+	if err := client.do(ctx, http.MethodGet, "/images/builds?"+query.Encode(), nil, &resp); err != nil {
+		return err
+	}
+
+	short := func(s *string) string {
+		if s == nil {
+			return "-"
+		}
+		return (*s)[:min(12, len(*s))]
+	}
+	return printResult(c, resp, func(tw io.Writer) {
+		fmt.Fprintln(tw, "ID\tALIAS\tBASE\tNODE\tSTATUS\tSCRIPT\tFINGERPRINT\tCREATED")
+		for _, b := range resp.Items {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", b.ID, b.Alias, b.BaseImage, b.NodeID, b.Status,
+				short(&b.ScriptSHA256), short(b.Fingerprint), b.CreatedAt.Local().Format(time.DateTime))
+		}
+	})
+}
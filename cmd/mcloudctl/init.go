@@ -3,23 +3,39 @@ package mcloudctl
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
 
+	"mcloud/internal/auth"
 	"mcloud/internal/cert"
+	"mcloud/internal/cluster"
 	"mcloud/internal/config"
 	"mcloud/internal/constant"
 	"mcloud/internal/database"
 	"mcloud/internal/installer"
+	"mcloud/internal/network"
+	"mcloud/internal/preflight"
 	"mcloud/internal/state"
 	"mcloud/pkg/logger"
 	"mcloud/pkg/utils"
-	"mcloud/services/lxd"
 	"mcloud/services/microceph"
-	"mcloud/services/microovn"
 
 	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
 )
 
+// components are the LXD, Ceph and OVN clusters that init and join bootstrap on
+// this host.
+var components = cluster.ShellComponents()
+
 // InitRequest represents the request structure for cluster initialization.
 // This structure matches the server's expected API request format.
 //
@@ -104,8 +120,8 @@ type InitResponse struct {
 //   Returns: error("a cluster with the name 'test-cluster' already exists")
 func validateClusterName(ctx context.Context, name string, conn *sql.DB) error {
 	// Check 1: Validate minimum name length
-	if len(name) < 3 {
-		return fmt.Errorf("cluster name must be at least 3 characters")
+	if err := checkClusterNameLength(name); err != nil {
+		return err
 	}
 
 	// Check 2: Verify no cluster with the same name already exists
@@ -121,22 +137,287 @@ func validateClusterName(ctx context.Context, name string, conn *sql.DB) error {
 	return nil
 }
 
+// checkClusterNameLength rejects cluster names shorter than 3 characters.
+func checkClusterNameLength(name string) error {
+	if len(name) < 3 {
+		return fmt.Errorf("cluster name must be at least 3 characters")
+	}
+	return nil
+}
+
+// InitOptions are the node settings `mcloudctl init` writes to the config file.
+// They are read from an optional preseed file (--preseed) and overridden by
+// any command-line flag that is set explicitly.
+//
+// Example preseed (YAML):
+//   name: production-cluster
+//   advertise_interface: eth1
+//   http_port: 9028
+//   grpc_port: 9030
+//   db_path: /var/lib/mcloud/mcloud.db
+//   cert_dir: /var/lib/mcloud/certs
+//   ceph_disks: [/dev/sdb, /dev/sdc]
+//   ceph_node_disks: {node3: [/dev/nvme1n1]}
+//   ceph_wipe: true
+//   external_pool: [203.0.113.16/28]
+//   server_names: [mcloud.example.com]
+//   snap_channels: {lxd: 5.21/stable}
+type InitOptions struct {
+	Name               string `yaml:"name"`
+	ConfigPath         string `yaml:"config_path"`
+	AdvertiseInterface string `yaml:"advertise_interface"`
+	AdvertiseAddress   string `yaml:"advertise_address"`
+	HTTPPort           int    `yaml:"http_port"`
+	GRPCPort           int    `yaml:"grpc_port"`
+	DBPath             string `yaml:"db_path"`
+	CertDir            string `yaml:"cert_dir"`
+
+	// FQDNs clients reach mcloudd by, added to the server certificate's SANs
+	// with the hostname and addresses (see config.Security)
+	ServerNames []string `yaml:"server_names"`
+
+	// The storage profile written to the config (see config.Storage): Ceph
+	// OSDs of every node, unless CephNodeDisks assigns a node its own. With no
+	// disks every available block device is used, falling back to
+	// CephLoopCount loop files (test clusters only)
+	CephDisks      []string            `yaml:"ceph_disks"`
+	CephNodeDisks  map[string][]string `yaml:"ceph_node_disks"`
+	CephWipe       bool                `yaml:"ceph_wipe"`
+	CephEncrypt    bool                `yaml:"ceph_encrypt"`
+	CephLoopCount  int                 `yaml:"ceph_loop_count"`
+	CephLoopSizeGB int                 `yaml:"ceph_loop_size_gb"`
+
+	// CIDRs of the uplink network floating IPs are allocated from (see
+	// config.Network); empty disables floating IPs
+	ExternalPool []string `yaml:"external_pool"`
+
+	// Channels --install-deps installs missing snaps from, by snap name;
+	// unset snaps use installer.DefaultSnapChannels
+	SnapChannels map[string]string `yaml:"snap_channels"`
+}
+
+// storage converts the Ceph init options to the cluster's storage profile.
+func (o *InitOptions) storage() config.Storage {
+	return config.Storage{
+		Disks:      o.CephDisks,
+		Nodes:      o.CephNodeDisks,
+		Wipe:       o.CephWipe,
+		Encrypt:    o.CephEncrypt,
+		LoopCount:  o.CephLoopCount,
+		LoopSizeGB: o.CephLoopSizeGB,
+	}
+}
+
+// preflightOptions returns what preflight must verify for these init options:
+// the ports and the Ceph disks the storage profile assigns to this host.
+func (o *InitOptions) preflightOptions() preflight.Options {
+	hostname, _ := os.Hostname()
+	opts := cluster.DiskPreflight(cluster.CephDisks(o.storage(), hostname))
+	opts.Ports = []int{o.HTTPPort, o.GRPCPort}
+	return opts
+}
+
+// snapChannels returns the channel every required snap is pinned to, for the
+// config file.
+func (o *InitOptions) snapChannels() map[string]string {
+	channels := make(map[string]string, len(preflight.RequiredSnaps))
+	for snap := range preflight.RequiredSnaps {
+		channels[snap] = installer.SnapChannel(snap, o.SnapChannels)
+	}
+	return channels
+}
+
+// loadInitOptions builds the init settings from defaults, the preseed file and flags, in that order.
+// With --interactive, or in a terminal without a cluster name, it then asks for
+// the settings still missing (see runInitWizard).
+//
+// Parameters:
+//   - c: CLI context containing parsed command-line flags
+//
+// Returns:
+//   - The merged options
+//   - error if the preseed file cannot be read or no cluster name is given
+//
+// Example Input:
+//   $ mcloudctl init --preseed /root/mcloud.yaml --http-port 8443
+//   /root/mcloud.yaml: {name: prod, http_port: 9028, advertise_interface: eth1}
+//
+// Example Output:
+//   InitOptions{Name: "prod", HTTPPort: 8443, AdvertiseInterface: "eth1", GRPCPort: 9030, ...}
+func loadInitOptions(c *cli.Context) (*InitOptions, error) {
+	opts, err := mergeInitOptions(c)
+	if err != nil {
+		return nil, err
+	}
+	if c.Bool("interactive") || (opts.Name == "" && !c.Bool("resume") && isTerminal(os.Stdin)) {
+		if err := runInitWizard(newPrompter(os.Stdin, os.Stdout), opts); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Name == "" {
+		return nil, usageErrorf("cluster name is required (--name or name in the preseed)")
+	}
+	if err := cluster.ValidateStorage(opts.storage()); err != nil {
+		return nil, err
+	}
+	if _, err := network.ParsePool(opts.ExternalPool); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
+
+// mergeInitOptions is loadInitOptions without requiring a cluster name, for
+// commands such as preflight that take a subset of the init flags.
+func mergeInitOptions(c *cli.Context) (*InitOptions, error) {
+	opts := &InitOptions{
+		ConfigPath: constant.DefaultConfigPath,
+		HTTPPort:   9028,
+		GRPCPort:   9030,
+		DBPath:     constant.DefaultDBPath,
+		CertDir:    constant.DefaultCertDir,
+	}
+
+	if preseed := c.String("preseed"); preseed != "" {
+		data, err := os.ReadFile(preseed)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read preseed: %w", err)
+		}
+		if err := yaml.Unmarshal(data, opts); err != nil {
+			return nil, fmt.Errorf("failed to parse preseed: %w", err)
+		}
+	}
+
+	// Explicit flags win over the preseed
+	if c.IsSet("name") {
+		opts.Name = c.String("name")
+	}
+	if c.IsSet("config") {
+		opts.ConfigPath = c.String("config")
+	}
+	if c.IsSet("advertise-interface") {
+		opts.AdvertiseInterface = c.String("advertise-interface")
+	}
+	if c.IsSet("advertise-address") {
+		opts.AdvertiseAddress = c.String("advertise-address")
+	}
+	if c.IsSet("http-port") {
+		opts.HTTPPort = c.Int("http-port")
+	}
+	if c.IsSet("grpc-port") {
+		opts.GRPCPort = c.Int("grpc-port")
+	}
+	if c.IsSet("db-path") {
+		opts.DBPath = c.String("db-path")
+	}
+	if c.IsSet("cert-dir") {
+		opts.CertDir = c.String("cert-dir")
+	}
+	if c.IsSet("ceph-disk") {
+		opts.CephDisks = c.StringSlice("ceph-disk")
+	}
+	for _, assign := range c.StringSlice("ceph-node-disk") {
+		hostname, disk, ok := strings.Cut(assign, "=")
+		if !ok || hostname == "" || disk == "" {
+			return nil, fmt.Errorf("invalid --ceph-node-disk %q, want <hostname>=<disk>", assign)
+		}
+		if opts.CephNodeDisks == nil {
+			opts.CephNodeDisks = map[string][]string{}
+		}
+		opts.CephNodeDisks[hostname] = append(opts.CephNodeDisks[hostname], disk)
+	}
+	if c.IsSet("ceph-wipe") {
+		opts.CephWipe = c.Bool("ceph-wipe")
+	}
+	if c.IsSet("ceph-encrypt") {
+		opts.CephEncrypt = c.Bool("ceph-encrypt")
+	}
+	if c.IsSet("ceph-loop-count") {
+		opts.CephLoopCount = c.Int("ceph-loop-count")
+	}
+	if c.IsSet("ceph-loop-size") {
+		opts.CephLoopSizeGB = c.Int("ceph-loop-size")
+	}
+	if c.IsSet("external-pool") {
+		opts.ExternalPool = c.StringSlice("external-pool")
+	}
+	if c.IsSet("server-name") {
+		opts.ServerNames = c.StringSlice("server-name")
+	}
+	for _, pin := range c.StringSlice("snap-channel") {
+		snap, channel, ok := strings.Cut(pin, "=")
+		if !ok || snap == "" || channel == "" {
+			return nil, fmt.Errorf("invalid --snap-channel %q, want <snap>=<channel>", pin)
+		}
+		if opts.SnapChannels == nil {
+			opts.SnapChannels = map[string]string{}
+		}
+		opts.SnapChannels[snap] = channel
+	}
+
+	return opts, nil
+}
+
+// advertiseIP picks the address this node advertises to the rest of the cluster:
+// an explicit IPv4 or IPv6 address, which must be on one of the host's
+// interfaces, else the address of the chosen interface (IPv4 first), else the
+// first detected address. init and join share it.
+//
+// Example Input:
+//   address: "", iface: "eth1"
+//   eth1: 10.0.0.5/24
+//
+// Example Output:
+//   Returns: (10.0.0.5, nil)
+func advertiseIP(address, iface string, detected []net.IP) (net.IP, error) {
+	switch {
+	case address != "":
+		ip := net.ParseIP(address)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid advertise address %q", address)
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			ip = ip4
+		}
+		if err := utils.CheckAdvertiseAddress(ip, 0); err != nil {
+			return nil, err
+		}
+		return ip, nil
+	case iface != "":
+		return utils.InterfaceIP(iface)
+	case len(detected) > 0:
+		return detected[0], nil
+	}
+	return nil, fmt.Errorf("no IP address found to advertise")
+}
+
+// checkAdvertiseReachable verifies, as part of preflight, that the LXD cluster
+// port can be served on the advertised address.
+func checkAdvertiseReachable(ip net.IP) error {
+	if err := utils.CheckAdvertiseAddress(ip, preflight.LXDPort); err != nil {
+		return fmt.Errorf("advertise address %s: %w", ip, err)
+	}
+	return nil
+}
+
 // writeConfig creates and saves the mcloud configuration file.
 // Generates configuration for both manager (HTTP/gRPC) and agent components,
-// using the host's detected IP address.
+// using the host's advertise address (host.IPs[0]) and the init options.
+// The certificate and database directories are created as well.
 //
 // Parameters:
-//   - host: Detected host information containing hostname and IP addresses
+//   - host: Detected host information; IPs[0] is the advertise address
+//   - opts: Init options (ports, paths, advertise interface)
 //
 // Returns:
-//   - nil if config is saved successfully
-//   - error if file write fails
+//   - The written configuration
+//   - error if a directory or the file cannot be written
 //
 // Example Input:
 //   host: HostInfo{
 //     Hostname: "node1",
 //     IPs: [192.168.1.10],
 //   }
+//   opts: InitOptions{ConfigPath: "/etc/mcloud/config.yaml", HTTPPort: 9028, GRPCPort: 9030, ...}
 //
 // Example Output (Success):
 //   Console log: "Wrote config file to /etc/mcloud/config.yaml"
@@ -146,44 +427,116 @@ func validateClusterName(ctx context.Context, name string, conn *sql.DB) error {
 //       http_port: 9028
 //       grpc_host: 192.168.1.10
 //       grpc_port: 9030
+//       advertise_interface: eth1
 //     agent:
-//       manager_url: http://192.168.1.10:9030
+//       manager_url: https://192.168.1.10:9028
+//       port: 9032
+//       drain_timeout_seconds: 300
 //     database:
-//       db_path: mcloud.db
+//       db_path: /var/lib/mcloud/mcloud.db
+//     config_path: /etc/mcloud/config.yaml
+//     state_path: /var/lib/mcloud/state.yaml
+//     security:
+//       cert_dir: /var/lib/mcloud/certs
+//       ca_cert_path: /var/lib/mcloud/certs/ca.crt
+//       ...
+//     time_sync:
+//       managed: false
+//       max_offset_ms: 500
+//     storage:
+//       disks: [/dev/sdb]
+//       nodes: {node3: [/dev/nvme1n1]}
 //
 // Example Output (Error):
 //   Returns: error("open /etc/mcloud/config.yaml: permission denied")
-func writeConfig(host utils.HostInfo) error {
+func writeConfig(host utils.HostInfo, opts *InitOptions) (*config.Config, error) {
+	cfg := newConfig(host, opts)
+
+	// Certificates and the database are written later in bootstrap
+	if err := os.MkdirAll(opts.CertDir, 0700); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(opts.DBPath), 0755); err != nil {
+		return nil, err
+	}
+
+	// Write configuration to YAML file
+	config.SetPath(opts.ConfigPath)
+	if err := config.SaveConfig(&cfg); err != nil {
+		return nil, err
+	}
+	logger.Info("Wrote config file to %s\n", config.Path())
+	return &cfg, nil
+}
+
+// newConfig builds the configuration writeConfig saves for the init options,
+// advertising host.IPs[0].
+func newConfig(host utils.HostInfo, opts *InitOptions) config.Config {
+	ip := host.IPs[0].String()
+
 	// Create configuration structure with manager and agent settings
-	cfg := config.Config{
+	return config.Config{
 		Manager: config.Manager{
-			HttpHost: host.IPs[0].String(),
-			HttpPort: 9028,
-			GrpcHost: host.IPs[0].String(),
-			GrpcPort: 9030,
+			HttpHost:           ip,
+			HttpPort:           opts.HTTPPort,
+			GrpcHost:           ip,
+			GrpcPort:           opts.GRPCPort,
+			AdvertiseInterface: opts.AdvertiseInterface,
+			AdvertiseAddress:   opts.AdvertiseAddress,
 		},
 		Agent: config.Agent{
-			ManagerURL: fmt.Sprintf("http://%s:9030", host.IPs[0].String()),
+			ManagerURL: "https://" + utils.HostPort(ip, opts.HTTPPort),
+			Port:       9032,
+
+			DrainTimeoutSeconds: 300,
 		},
 		Database: config.Database{
-			DBPath: "mcloud.db",
+			DBPath: opts.DBPath,
 		},
-		ConfigPath: constant.DefaultConfigPath,
+		ConfigPath: opts.ConfigPath,
 		StatePath:  constant.DefaultStatePath,
+		Security: config.Security{
+			CertDir:              opts.CertDir,
+			CACertPath:           filepath.Join(opts.CertDir, "ca.crt"),
+			CAKeyPath:            filepath.Join(opts.CertDir, "ca.key"),
+			IntermediateCertPath: filepath.Join(opts.CertDir, "intermediate.crt"),
+			IntermediateKeyPath:  filepath.Join(opts.CertDir, "intermediate.key"),
+			ServerCertPath:       filepath.Join(opts.CertDir, "server.crt"),
+			ServerKeyPath:        filepath.Join(opts.CertDir, "server.key"),
+			ClientCertPath:       filepath.Join(opts.CertDir, "client.crt"),
+			ClientKeyPath:        filepath.Join(opts.CertDir, "client.key"),
+			NodeCertPath:         filepath.Join(opts.CertDir, "node.crt"),
+			NodeKeyPath:          filepath.Join(opts.CertDir, "node.key"),
+			SecretsKeyPath:       filepath.Join(opts.CertDir, "secrets.key"),
+			ServerNames:          opts.ServerNames,
+		},
+		TimeSync: config.TimeSync{
+			MaxOffsetMs: 500,
+		},
+		Log: config.Log{
+			Level: "info",
+			File: config.LogFile{
+				Path:       constant.DefaultLogPath,
+				MaxSizeMB:  50,
+				MaxAgeDays: 7,
+				MaxBackups: 5,
+			},
+		},
+		Snaps: config.Snaps{
+			Channels: opts.snapChannels(),
+		},
+		Storage: opts.storage(),
+		Network: config.Network{
+			ExternalPool: opts.ExternalPool,
+		},
 	}
-
-	// Write configuration to YAML file
-	if err := config.SaveConfig(&cfg); err != nil {
-		return err
-	}
-	logger.Info("Wrote config file to %s\n", cfg.ConfigPath)
-	return nil
 }
 
 // writeState creates and saves the cluster state file.
 // The state file persists node identity, cluster membership, and initialization status.
 //
 // Parameters:
+//   - path: State file (cfg.StatePath)
 //   - name: Cluster name
 //   - host: Detected host information
 //   - nodeId: UUID for this node
@@ -194,13 +547,14 @@ func writeConfig(host utils.HostInfo) error {
 //   - error if file write fails
 //
 // Example Input:
+//   path: "/var/lib/mcloud/state.yaml"
 //   name: "production-cluster"
 //   host: HostInfo{Hostname: "node1", IPs: [192.168.1.10]}
 //   nodeId: "550e8400-e29b-41d4-a716-446655440000"
 //   clusterId: "660e8400-e29b-41d4-a716-446655440001"
 //
 // Example Output (Success):
-//   Console log: "Wrote state file to /etc/mcloud/config.yaml"
+//   Console log: "Wrote state file to /var/lib/mcloud/state.yaml"
 //   File created: /var/lib/mcloud/state.yaml with content:
 //     version: "0.1.0"
 //     node:
@@ -217,8 +571,8 @@ func writeConfig(host utils.HostInfo) error {
 //
 // Example Output (Error):
 //   Returns: error("open /var/lib/mcloud/state.yaml: permission denied")
-func writeState(name string, host utils.HostInfo, nodeId string, clusterId string) error {
-	state := state.State{
+func writeState(path string, name string, host utils.HostInfo, nodeId string, clusterId string) error {
+	st := state.State{
 		Version: constant.AppVersion,
 		Node: state.Node{
 			ID:        nodeId,
@@ -229,7 +583,7 @@ func writeState(name string, host utils.HostInfo, nodeId string, clusterId strin
 		Cluster: state.Cluster{
 			ID:               clusterId,
 			Name:             name,
-			AdvertiseAddr: fmt.Sprintf("%s:7443", host.IPs[0].String()),
+			AdvertiseAddr: utils.HostPort(host.IPs[0].String(), 7443),
 		},
 		Flags: state.Flags{
 			Initialized: true,
@@ -237,15 +591,19 @@ func writeState(name string, host utils.HostInfo, nodeId string, clusterId strin
 	}
 
 	// Save state to file
-	if _, err := state.SaveState(state); err != nil {
+	if err := state.SaveState(path, st); err != nil {
 		return err
 	}
-	logger.Info("Wrote state file to %s\n", config.DefaultConfigPath)
+	logger.Info("Wrote state file to %s\n", path)
 	return nil
 }
 
-// generateCert generates the Certificate Authority (CA) and server certificates.
-// The CA is used to sign server certificates for secure gRPC communication.
+// generateCert generates the Certificate Authority (CA), server, admin client and node certificates.
+// The root CA signs the cluster intermediate CA, which signs the server certificates for
+// secure gRPC/HTTPS communication, the client certificate mcloudctl presents to the
+// mTLS-protected REST API, and the node certificate mcloud-agent presents so mcloudd
+// knows which node is reporting. Each issued certificate file bundles the intermediate,
+// so peers only need the root; the intermediate can be rotated (mcloudctl ca rotate).
 //
 // Parameters:
 //   - cfg: Configuration containing certificate file paths
@@ -260,41 +618,64 @@ func writeState(name string, host utils.HostInfo, nodeId string, clusterId strin
 //     Security: Security{
 //       CACertPath: "/etc/mcloud/ca.crt",
 //       CAKeyPath: "/etc/mcloud/ca.key",
+//       IntermediateCertPath: "/etc/mcloud/intermediate.crt",
+//       IntermediateKeyPath: "/etc/mcloud/intermediate.key",
 //       ServerCertPath: "/etc/mcloud/server.crt",
 //       ServerKeyPath: "/etc/mcloud/server.key",
+//       ClientCertPath: "/etc/mcloud/client.crt",
+//       ClientKeyPath: "/etc/mcloud/client.key",
+//       NodeCertPath: "/etc/mcloud/node.crt",
+//       NodeKeyPath: "/etc/mcloud/node.key",
 //     }
 //   }
-//   host: HostInfo{IPs: [192.168.1.10]}
+//   host: HostInfo{Hostname: "node1", IPs: [192.168.1.10]}
 //
 // Example Output (Success):
 //   Console logs:
 //     "Generated CA certificate"
+//     "Generated intermediate CA certificate"
 //     "Generated server certificate"
+//     "Generated client certificate"
+//     "Generated node certificate"
 //   Files created:
 //     /etc/mcloud/ca.crt (4096-bit RSA CA certificate, 10 years validity)
 //     /etc/mcloud/ca.key (4096-bit RSA private key)
 //     /etc/mcloud/server.crt (2048-bit RSA server certificate, 1 year validity)
 //     /etc/mcloud/server.key (2048-bit RSA private key)
+//     /etc/mcloud/client.crt (2048-bit RSA client certificate, 1 year validity)
+//     /etc/mcloud/client.key (2048-bit RSA private key)
+//     /etc/mcloud/node.crt (2048-bit RSA client certificate, 1 year validity)
+//     /etc/mcloud/node.key (2048-bit RSA private key)
 //   Certificate details:
 //     CA Subject: CN=mcloud-ca
 //     Server Subject: CN=192.168.1.10
-//     Server SAN: IP:192.168.1.10
+//     Server SAN: DNS:node1, DNS:mcloud.example.com (server_names), IP:192.168.1.10, IP:fd00::10 (every address of the host)
+//     Node Subject: CN=node1
+//     Node SAN: IP:192.168.1.10, IP:fd00::10
 //
 // Example Output (Error):
 //   Returns: error("failed to create CA certificate: permission denied")
 func generateCert(cfg config.Config, host utils.HostInfo) error {
 	// Generate CA certificate and private key
-	caCert, caKey, err := cert.GenerateCAV2(cfg.Security.CACertPath, cfg.Security.CAKeyPath)
+	rootCert, rootKey, err := cert.GenerateCAV2(cfg.Security.CACertPath, cfg.Security.CAKeyPath)
 	if err != nil {
 		return err
 	}
 	logger.Info("Generated CA certificate")
 
+	// The intermediate signs everything below, so rotating it leaves the root alone
+	caCert, caKey, err := cert.GenerateIntermediateCA(rootCert, rootKey, cfg.Security.IntermediateCertPath, cfg.Security.IntermediateKeyPath)
+	if err != nil {
+		return err
+	}
+	logger.Info("Generated intermediate CA certificate")
+
 	// Generate server certificate signed by the CA
 	err = cert.GenerateServerCert(
 		caCert,
 		caKey,
-		host.IPs[0].String(),
+		append([]string{host.Hostname}, cfg.Security.ServerNames...),
+		host.IPs,
 		cfg.Security.ServerCertPath,
 		cfg.Security.ServerKeyPath,
 	)
@@ -302,22 +683,49 @@ func generateCert(cfg config.Config, host utils.HostInfo) error {
 		return err
 	}
 	logger.Info("Generated server certificate")
+
+	// Generate the admin client certificate used by mcloudctl to call the REST API
+	err = cert.GenerateClientCert(
+		caCert,
+		caKey,
+		constant.AdminClientCommonName,
+		cfg.Security.ClientCertPath,
+		cfg.Security.ClientKeyPath,
+	)
+	if err != nil {
+		return err
+	}
+	logger.Info("Generated client certificate")
+
+	// Generate this node's certificate; mcloudd binds agent reports to its CN
+	err = cert.GenerateNodeCert(
+		caCert,
+		caKey,
+		host.Hostname,
+		host.IPs,
+		cfg.Security.NodeCertPath,
+		cfg.Security.NodeKeyPath,
+	)
+	if err != nil {
+		return err
+	}
+	logger.Info("Generated node certificate")
 	return nil
 }
 
-// bootstrapDatabase initializes the database and creates initial cluster and node records.
-// Connects to the database, runs migrations, and inserts the first cluster and leader node.
+// bootstrapDatabase creates the initial cluster and leader node records.
+// It is safe to re-run when resuming: an existing cluster record is left as is.
 //
 // Parameters:
 //   - ctx: Context for cancellation and timeout
+//   - conn: Database connection opened by InitCommand
 //   - name: Cluster name
 //   - clusterId: UUID for the cluster
 //   - nodeId: UUID for this node
 //   - host: Host information containing hostname and IP
 //
 // Returns:
-//   - *sql.DB: Database connection if successful
-//   - error: If database connection or record creation fails
+//   - error: If record creation fails
 //
 // Example Input:
 //   name: "production-cluster"
@@ -326,8 +734,7 @@ func generateCert(cfg config.Config, host utils.HostInfo) error {
 //   host: HostInfo{Hostname: "node1", IPs: [192.168.1.10]}
 //
 // Example Output (Success):
-//   Console log: "Database connected and migrated"
-//                "Created initial cluster and node records in database"
+//   Console log: "Created initial cluster and node records in database"
 //   Database records created:
 //     clusters table:
 //       - id: "660e8400-e29b-41d4-a716-446655440001"
@@ -340,71 +747,215 @@ func generateCert(cfg config.Config, host utils.HostInfo) error {
 //       - ip: "192.168.1.10"
 //       - role: "leader"
 //       - status: "online"
-//   Returns: (*sql.DB, nil)
-//
-// Example Output (Error):
-//   Returns: (nil, error("unable to open database file: permission denied"))
-func bootstrapDatabase(ctx context.Context, name string, clusterId string, nodeId string, host utils.HostInfo) (*sql.DB, error) {
-	// Step 1: Connect to database and run migrations
-	conn, err := database.Connect()
+//   Returns: nil
+func bootstrapDatabase(ctx context.Context, conn *sql.DB, name string, clusterId string, nodeId string, host utils.HostInfo, cfg config.Config) error {
+	// The records are written in one transaction, so a failed step leaves none
+	// of them behind for the resumed init to trip over
+	tx, err := conn.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	logger.Info("Database connected and migrated")
+	defer tx.Rollback()
 
-	// Step 2: Initialize repositories
-	clusterRepo := database.NewClusterRepository(conn)
-	nodeRepo := database.NewNodeRepository(conn)
+	clusterRepo := database.NewClusterRepositoryTx(tx)
+	nodeRepo := database.NewNodeRepositoryTx(tx)
+
+	// Step 1: Skip if an earlier, interrupted init already created the records
+	if existing, err := clusterRepo.GetByID(ctx, clusterId); err == nil && existing != nil {
+		return nil
+	}
 
-	// Step 3: Create cluster record
+	// Step 2: Create cluster record
 	cluster := &database.Cluster{
 		ID:    clusterId,
 		Name:  name,
 		State: "active",
 	}
-	
+
 	if err := clusterRepo.Create(ctx, cluster); err != nil {
-		return nil, err
+		return err
 	}
 
-	// Step 4: Create leader node record
+	// Step 3: Create leader node record
 	node := &database.Node{
-		ID:				 nodeId,
-		ClusterID:  clusterId,
-		Hostname:   host.Hostname,
-		IP:         host.IPs[0].String(),
-		Role:       "leader",
-		Status:     "online",
+		ID:        nodeId,
+		ClusterID: clusterId,
+		Hostname:  host.Hostname,
+		IP:        host.IPs[0].String(),
+		Role:      "leader",
+		Status:    "online",
 	}
 
 	if err := nodeRepo.Create(ctx, node); err != nil {
-		return nil, err
+		return err
+	}
+
+	// Step 4: Record the root and intermediate CAs generated by the certs step
+	if err := storeCAs(ctx, tx, clusterId, cfg); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
 	}
 	logger.Info("Created initial cluster and node records in database")
-	return conn, nil
+	return nil
+}
+
+// storeCAs records the certificates of the cluster's root and intermediate CAs
+// in the database. Their keys stay in security.ca_key_path and
+// security.intermediate_key_path on the leader.
+func storeCAs(ctx context.Context, tx *sql.Tx, clusterId string, cfg config.Config) error {
+	rootPEM, err := cert.ReadPEM(cfg.Security.CACertPath)
+	if err != nil {
+		return err
+	}
+	interPEM, err := cert.ReadPEM(cfg.Security.IntermediateCertPath)
+	if err != nil {
+		return err
+	}
+
+	repo := database.NewCertificateAuthorityRepositoryTx(tx)
+	for _, ca := range []*database.CertificateAuthority{
+		{ID: utils.GenerateUUID(), ClusterID: clusterId, Kind: database.CAKindRoot, CertPEM: string(rootPEM)},
+		{ID: utils.GenerateUUID(), ClusterID: clusterId, Kind: database.CAKindIntermediate, CertPEM: string(interPEM)},
+	} {
+		if err := repo.Create(ctx, ca); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// joinTokenTTL is how long the join string printed by init is accepted.
+const joinTokenTTL = 24 * time.Hour
+
+// issueJoinToken records a bootstrap token for the cluster and returns it as a
+// join string (see auth.JoinToken) for mcloudctl join --token.
+//
+// Example Output:
+//   Returns: "eyJhZGRyZXNzIjoiaHR0cHM6Ly8xOTIuMTY4LjEuMTA6OTAyOCIsImNhX2Zpbmdlcn..."
+func issueJoinToken(ctx context.Context, conn *sql.DB, clusterId string, cfg config.Config) (string, error) {
+	caPEM, err := cert.ReadPEM(cfg.Security.CACertPath)
+	if err != nil {
+		return "", err
+	}
+	fingerprint, err := auth.CAFingerprint(caPEM)
+	if err != nil {
+		return "", err
+	}
+
+	token := &auth.JoinToken{
+		Address:       cfg.Agent.ManagerURL,
+		CAFingerprint: fingerprint,
+		Token:         auth.GenerateJoinToken(clusterId),
+		ExpiresAt:     time.Now().UTC().Add(joinTokenTTL).Truncate(time.Second),
+	}
+	err = database.NewBootstrapTokenRepository(conn).Create(ctx, &database.BootstrapToken{
+		Token:     token.Token,
+		ClusterID: clusterId,
+		ExpiresAt: token.ExpiresAt,
+	})
+	if err != nil {
+		return "", err
+	}
+	return token.Encode(), nil
+}
+
+// removeBootstrapRecords deletes the records created by bootstrapDatabase (rollback).
+func removeBootstrapRecords(ctx context.Context, conn *sql.DB, clusterId string, nodeId string) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	caRepo := database.NewCertificateAuthorityRepositoryTx(tx)
+	cas, err := caRepo.ListByCluster(ctx, clusterId)
+	if err != nil {
+		return err
+	}
+	for _, ca := range cas {
+		if err := caRepo.DeleteByID(ctx, ca.ID); err != nil {
+			return err
+		}
+	}
+	if err := database.NewNodeRepositoryTx(tx).DeleteByID(ctx, nodeId); err != nil {
+		return err
+	}
+	if err := database.NewClusterRepositoryTx(tx).DeleteByID(ctx, clusterId); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// removeCerts deletes the certificates created by generateCert (rollback).
+func removeCerts(cfg config.Config) error {
+	paths := []string{
+		cfg.Security.CACertPath,
+		cfg.Security.CAKeyPath,
+		cfg.Security.IntermediateCertPath,
+		cfg.Security.IntermediateKeyPath,
+		cfg.Security.ServerCertPath,
+		cfg.Security.ServerKeyPath,
+		cfg.Security.ClientCertPath,
+		cfg.Security.ClientKeyPath,
+		cfg.Security.NodeCertPath,
+		cfg.Security.NodeKeyPath,
+	}
+	for _, p := range paths {
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// setClusterState updates the state column of the cluster record, if it exists.
+func setClusterState(ctx context.Context, conn *sql.DB, clusterId string, state string) error {
+	repo := database.NewClusterRepository(conn)
+	c, err := repo.GetByID(ctx, clusterId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	c.State = state
+	return repo.UpdateByID(ctx, c)
 }
 
 // bootstrap initializes all mcloud infrastructure components.
-// Orchestrates the setup of certificates, database, LXD, networking, storage, and systemd service.
+// Runs the setup of certificates, database, LXD, networking, storage, the systemd
+// service, the state file and the re-join bundle as a cluster.Pipeline, so progress
+// is recorded in the kv_store and a failed init can be resumed or rolled back.
 //
-// The function performs the following steps:
-//   1. Generate CA and server certificates for secure communication
-//   2. Initialize database and create cluster/node records
-//   3. Bootstrap LXD control plane
-//   4. Setup OVN networking
-//   5. Setup Ceph storage
-//   6. Install and start mcloudd as systemd service
+// The pipeline runs the following steps:
+//   1. certs:    Generate CA and server certificates (rollback: remove them)
+//   2. database: Create cluster/node records (rollback: delete them)
+//   3. lxd:      Bootstrap LXD control plane (cannot be rolled back)
+//   4. ovn:      Setup OVN networking (cannot be rolled back)
+//   5. ceph:     Setup Ceph storage (cannot be rolled back)
+//   6. mcloudd:  Install and start mcloudd as systemd service (rollback: uninstall)
+//   7. state:    Write the cluster state file (rollback: remove it)
+//   8. rejoin-bundle: Write the node's encrypted re-join bundle (rollback: remove it)
+//
+// Steps completed by an earlier run are skipped. On failure the cluster record is
+// marked "partial", unless rollbackOnFailure undid every completed step.
 //
 // Parameters:
 //   - ctx: Context for cancellation and timeout
+//   - pr: Progress display the steps are reported to (see bootstrapStepTitles)
+//   - conn: Database connection holding the cluster records and pipeline state
 //   - name: Cluster name
 //   - host: Host information
 //   - nodeId: UUID for this node
 //   - clusterId: UUID for the cluster
 //   - cfg: Configuration
+//   - disks: Disks to add as Ceph OSDs (this node's share of the storage profile)
+//   - rollbackOnFailure: Undo completed steps when a step fails
 //
 // Returns:
-//   - result: Currently nil, reserved for future use
+//   - *cluster.PipelineState: Final pipeline state
 //   - error: If any bootstrap step fails
 //
 // Example Input:
@@ -414,87 +965,213 @@ func bootstrapDatabase(ctx context.Context, name string, clusterId string, nodeI
 //   clusterId: "660e8400-e29b-41d4-a716-446655440001"
 //   cfg: Config{...}
 //
-// Example Output (Success):
-//   Console logs:
+// Example Output (Success, on a terminal):
+//   Console:
 //     "Bootstrapping mcloud components..."
 //     "Generated CA certificate"
-//     "Generated server certificate"
-//     "Database connected and migrated"
-//     "Created initial cluster and node records in database"
-//     "LXD cluster initialized"
-//     "OVN initialized"
-//     "Ceph cluster initialized"
-//     "✅ mcloudd installed and started"
+//     "✔ [1/8] Generating certificates (0.4s)"
+//     ...
+//     "✔ [8/8] Writing the re-join bundle (0.1s)"
 //     "mcloud components bootstrapped successfully"
-//   Side effects:
-//     - Certificates created in /etc/mcloud/
-//     - Database initialized with cluster and node records
-//     - LXD cluster created with name "production-cluster"
-//     - OVN networking configured
-//     - Ceph storage pool configured
-//     - mcloudd systemd service running
-//   Returns: (nil, nil)
-//
-// Example Output (Error - LXD Bootstrap Failed):
-//   Returns: (nil, error("failed to initialize LXD cluster: connection refused"))
-func bootstrap(ctx context.Context, name string, host utils.HostInfo, nodeId string, clusterId string, cfg config.Config) (result any, err error) {
+//   Without a terminal each step is logged as "[1/8] Generating certificates..."
+//   and "[1/8] Generating certificates: done".
+//   Returns: (&PipelineState{Status: "completed", ...}, nil)
+//
+// Example Output (Error - Ceph Bootstrap Failed):
+//   Console:
+//     "✖ [5/8] Bootstrapping Ceph and adding its disks: no available disks for Ceph; ..."
+//     "Cluster is partially initialized; fix the problem and re-run init to resume, or with --clean to start over"
+//   Returns: (&PipelineState{Status: "partial", FailedStep: "ceph", ...}, error("step ceph failed: ..."))
+func bootstrap(ctx context.Context, pr *progress, conn *sql.DB, name string, host utils.HostInfo, nodeId string, clusterId string, cfg config.Config, disks microceph.DiskConfig, rollbackOnFailure bool) (*cluster.PipelineState, error) {
 	logger.Info("Bootstrapping mcloud components...")
 
-	// Step 1: Generate CA and server certificates
-	if err := generateCert(cfg, host); err != nil {
-		return nil, err
-	}
+	pipeline := bootstrapPipeline(conn, name, host, nodeId, clusterId, cfg, disks)
+	pipeline.RollbackOnFailure = rollbackOnFailure
+	pipeline.OnProgress = pr.Pipeline(bootstrapStepTitles(disks))
 
-	// Step 2: Initialize database and create initial records
-	_, err = bootstrapDatabase(ctx, name, clusterId, nodeId, host)
+	st, err := pipeline.Run(ctx, map[string]string{
+		"cluster_name": name,
+		"cluster_id":   clusterId,
+		"node_id":      nodeId,
+	})
 	if err != nil {
-		return nil, err
+		if st != nil && st.Status == cluster.PipelinePartial {
+			// ctx is already cancelled when init was interrupted, so record the state
+			// with a context of its own
+			stateCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			stateErr := setClusterState(stateCtx, conn, clusterId, "partial")
+			cancel()
+			if stateErr != nil {
+				logger.Warn("Failed to mark cluster as partially initialized: %v", stateErr)
+			}
+			logger.Error("Cluster is partially initialized; fix the problem and re-run init to resume, or with --clean to start over")
+		}
+		return st, err
 	}
 
-	// Step 3: Initialize LXD control plane
-	lxdConfig := lxd.BootstrapConfig{
-		ClusterName: name,
-		Address:     host.IPs[0].String(),
+	// A resumed init finds the cluster marked partial by the failed run
+	if err := setClusterState(ctx, conn, clusterId, "active"); err != nil {
+		return st, err
 	}
-	if err := lxd.Bootstrap(lxdConfig); err != nil {
-		return nil, err
+	logger.Info("mcloud components bootstrapped successfully")
+	return st, nil
+}
+
+// bootstrapStepTitles describes the steps of bootstrapPipeline as progress
+// shows them while they run.
+func bootstrapStepTitles(disks microceph.DiskConfig) map[string]string {
+	ceph := "Bootstrapping Ceph and adding its disks"
+	if len(disks.Paths) > 0 {
+		ceph += " " + strings.Join(disks.Paths, ", ")
+	}
+	return map[string]string{
+		"certs":         "Generating certificates",
+		"database":      "Creating the cluster and node records",
+		"lxd":           "Bootstrapping LXD",
+		"ovn":           "Bootstrapping OVN",
+		"ceph":          ceph,
+		"mcloudd":       "Installing mcloudd",
+		"state":         "Writing the state file",
+		"rejoin-bundle": "Writing the re-join bundle",
 	}
+}
 
-	// Step 4: Setup OVN networking
-	if err := microovn.Bootstrap(); err != nil {
-		return nil, err
+// bootstrapPipeline builds the cluster.BootstrapPipeline run by bootstrap. It is
+// also built to undo an interrupted init (see cleanPartialInit), with the IDs
+// that init stored in the pipeline state.
+func bootstrapPipeline(conn *sql.DB, name string, host utils.HostInfo, nodeId string, clusterId string, cfg config.Config, disks microceph.DiskConfig) *cluster.Pipeline {
+	steps := []cluster.Step{
+		{
+			Name:     "certs",
+			Execute:  func(ctx context.Context) error { return generateCert(cfg, host) },
+			Rollback: func(ctx context.Context) error { return removeCerts(cfg) },
+		},
+		{
+			Name: "database",
+			Execute: func(ctx context.Context) error {
+				return bootstrapDatabase(ctx, conn, name, clusterId, nodeId, host, cfg)
+			},
+			Rollback: func(ctx context.Context) error {
+				return removeBootstrapRecords(ctx, conn, clusterId, nodeId)
+			},
+		},
+		{
+			Name:     "mcloudd",
+			Execute:  func(ctx context.Context) error { return installer.Init(config.Path()) },
+			Rollback: func(ctx context.Context) error { return installer.Uninstall(installer.UninstallOptions{KeepBinary: true}) },
+		},
+		{
+			Name:    "state",
+			Execute: func(ctx context.Context) error { return writeState(cfg.StatePath, name, host, nodeId, clusterId) },
+			Rollback: func(ctx context.Context) error {
+				if err := os.Remove(cfg.StatePath); err != nil && !os.IsNotExist(err) {
+					return err
+				}
+				return nil
+			},
+		},
+		{
+			Name: "rejoin-bundle",
+			Execute: func(ctx context.Context) error {
+				return writeRejoinBundle(ctx, conn, cfg, name, host, nodeId, clusterId)
+			},
+			Rollback: func(ctx context.Context) error {
+				if err := os.Remove(constant.DefaultRejoinBundlePath); err != nil && !os.IsNotExist(err) {
+					return err
+				}
+				return nil
+			},
+		},
 	}
-	
-	// Step 5: Setup Ceph storage
-	cephConfig := microceph.BootstrapConfig{
-		Disk: constant.DefaultCephDisk,
+
+	// LXD, OVN and Ceph come after the database and before mcloudd
+	steps = slices.Insert(steps, 2, components.BootstrapSteps(name, host.IPs[0].String(), disks)...)
+	return cluster.NewPipeline(conn, cluster.BootstrapPipeline, steps...)
+}
+
+// loadPartialInit returns the bootstrap pipeline state left by an init that
+// failed or was killed, or nil if the database at dbPath holds none (or does not
+// exist yet).
+func loadPartialInit(ctx context.Context, dbPath string) (*cluster.PipelineState, error) {
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		return nil, nil
 	}
-	if err := microceph.Bootstrap(cephConfig); err != nil {
-		return nil, err
+	conn, err := database.Connect(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
+	defer database.Close(conn)
 
-	// Step 6: Install mcloudd as systemd service and start it
-	if err := installer.Init(); err != nil {
+	st, err := cluster.LoadPipelineState(ctx, conn, cluster.BootstrapPipeline)
+	if err != nil || st == nil {
 		return nil, err
 	}
-	logger.Info("mcloud components bootstrapped successfully")
+	if st.Status != cluster.PipelinePartial && st.Status != cluster.PipelineRunning {
+		return nil, nil
+	}
+	return st, nil
+}
 
-	return nil, nil
+// failedAt describes where an interrupted init stopped.
+func failedAt(st *cluster.PipelineState) string {
+	if st.FailedStep == "" {
+		return "interrupted while running"
+	}
+	return "failed at step " + st.FailedStep
+}
+
+// cleanPartialInit undoes the steps of an interrupted init (see --clean): its
+// certificates, database records, mcloudd service, state file and re-join bundle
+// are removed. LXD, OVN and Ceph are left bootstrapped; the next run finds them
+// done and keeps them.
+func cleanPartialInit(ctx context.Context, pr *progress, conn *sql.DB, prev *cluster.PipelineState, host utils.HostInfo, cfg config.Config) error {
+	logger.Info("Undoing the partial init of cluster %s (%s)", prev.Data["cluster_name"], failedAt(prev))
+	pipeline := bootstrapPipeline(conn, prev.Data["cluster_name"], host, prev.Data["node_id"], prev.Data["cluster_id"], cfg, microceph.DiskConfig{})
+	pipeline.OnProgress = pr.Pipeline(bootstrapStepTitles(microceph.DiskConfig{}))
+	if err := pipeline.Reset(ctx); err != nil {
+		return fmt.Errorf("failed to clean up the partial init: %w", err)
+	}
+	return nil
 }
 
 // InitCommand is the CLI command handler for 'mcloudctl init'.
 // Initializes a new mcloud cluster on the current node, setting it up as the cluster leader.
 //
 // Command Flow:
-//   Step 1: Load configuration and connect to database
-//   Step 2: Detect host information (hostname, IP addresses)
-//   Step 3: Validate cluster name (length and uniqueness)
-//   Step 4: Write configuration file
-//   Step 5: Bootstrap all mcloud components (certs, DB, LXD, OVN, Ceph, mcloudd)
-//   Step 6: Write cluster state file
+//   Step 1: Merge preseed and flags, detect host information and the advertise address
+//   Step 2: Write configuration file (default /etc/mcloud/config.yaml)
+//   Step 3: Connect to database and resume an interrupted init, or validate cluster name (length and uniqueness)
+//   Step 4: Bootstrap all mcloud components (certs, DB, LXD, OVN, Ceph, mcloudd)
+//   Step 5: Write cluster state file and the node's re-join bundle
+//   Step 6: Print a join string for the other nodes (valid 24h)
 //
 // CLI Usage:
-//   mcloudctl init --name <cluster-name>
+//   mcloudctl init --name <cluster-name> [--advertise-interface eth1 | --advertise-address 10.0.0.5] [--http-port 9028]
+//     [--grpc-port 9030] [--db-path <file>] [--cert-dir <dir>] [--config <file>]
+//     [--ceph-disk /dev/sdb --ceph-disk /dev/sdc] [--ceph-node-disk node3=/dev/nvme1n1]
+//     [--ceph-wipe] [--ceph-encrypt] [--ceph-loop-count 3] [--external-pool 203.0.113.16/28]
+//     [--install-deps] [--snap-channel lxd=5.21/stable] [--server-name mcloud.example.com]
+//   mcloudctl init --preseed <file>
+//   mcloudctl init --interactive   (also without --name in a terminal)
+//   mcloudctl init --name <cluster-name> --clean
+//   mcloudctl init --name <cluster-name> --dry-run
+//
+// --dry-run validates the options and runs preflight (without installing snaps
+// for --install-deps), then prints the config file, the LXD preseed and the
+// MicroOVN and MicroCeph commands init would run (see dryRunInit) and stops
+// before anything is written.
+//
+// Re-running init after it failed or was killed resumes the interrupted init from
+// the step that did not complete (see bootstrap), reusing its cluster and node
+// IDs and skipping preflight. --clean instead undoes the completed steps that can
+// be undone and starts over; LXD, OVN and Ceph are kept and found done.
+//
+// An explicit advertise address must be on one of the host's interfaces, and
+// preflight checks that the LXD cluster port (8443) can be served on it.
+//
+// Each check and bootstrap step is shown as it runs (see progress): on a
+// terminal as a spinner that turns into a checkmark, with the step's log lines
+// above it; when the output is piped, as a log line when it starts and ends.
 //
 // Parameters:
 //   - c: CLI context containing parsed command-line flags
@@ -509,9 +1186,8 @@ func bootstrap(ctx context.Context, name string, host utils.HostInfo, nodeId str
 // Example Output (Success):
 //   Console logs:
 //     [INFO] 2026-01-02 10:30:45 Initializing mcloud cluster: production-cluster
-//     [INFO] 2026-01-02 10:30:45 Loaded config: {...}
-//     [INFO] 2026-01-02 10:30:45 Database initialized and migrated
 //     [INFO] 2026-01-02 10:30:45 Wrote config file to /etc/mcloud/config.yaml
+//     [INFO] 2026-01-02 10:30:45 Database initialized and migrated
 //     [INFO] 2026-01-02 10:30:45 Bootstrapping mcloud components...
 //     [INFO] 2026-01-02 10:30:46 Generated CA certificate
 //     [INFO] 2026-01-02 10:30:46 Generated server certificate
@@ -524,12 +1200,24 @@ func bootstrap(ctx context.Context, name string, host utils.HostInfo, nodeId str
 //     [INFO] 2026-01-02 10:30:50 mcloud components bootstrapped successfully
 //     [INFO] 2026-01-02 10:30:50 Wrote state file to /var/lib/mcloud/state.yaml
 //     [INFO] 2026-01-02 10:30:50 mcloud initialized successfully
+//     [INFO] 2026-01-02 10:30:50 Join other nodes (until 2026-01-03 10:30:50) with:
+//       mcloudctl join --token eyJhZGRyZXNzIjoiaHR0cHM6Ly8xOTIuMTY4LjEuMTA6OTAyOCIsImNhX2Zpbmdlcn...
 //   Returns: nil
 //
 // Example Output (Error - Not Root):
 //   [ERROR] 2026-01-02 10:30:45 must run as root
 //   Returns: error("must run as root")
 //
+// Example Output (Re-run After Ceph Failed):
+//   [INFO] 2026-01-02 10:35:12 Resuming init of cluster production-cluster (failed at step ceph)
+//   – [1/8] Generating certificates (already done)
+//   ...
+//   ✔ [5/8] Bootstrapping Ceph and adding its disks (1m12s)
+//
+// Example Output (Error - Another Cluster Partially Initialized):
+//   Returns: error("cluster 'production-cluster' is partially initialized (failed at step ceph); re-run init
+//     with --name production-cluster to resume it, or with --clean to start over")
+//
 // Example Output (Error - Cluster Name Exists):
 //   [ERROR] 2026-01-02 10:30:45 a cluster with the name 'production-cluster' already exists
 //   Returns: error("a cluster with the name 'production-cluster' already exists")
@@ -537,62 +1225,122 @@ func bootstrap(ctx context.Context, name string, host utils.HostInfo, nodeId str
 // Side Effects:
 //   - Creates /etc/mcloud/config.yaml
 //   - Creates /var/lib/mcloud/state.yaml
-//   - Initializes mcloud.db with cluster and node records
-//   - Generates TLS certificates in /etc/mcloud/
+//   - Creates /etc/mcloud/rejoin.bundle (see mcloudctl rejoin)
+//   - Initializes /var/lib/mcloud/mcloud.db with cluster and node records
+//   - Generates TLS certificates in /var/lib/mcloud/certs/
 //   - Configures LXD, OVN, and Ceph
-//   - Installs and starts mcloudd.service
+//   - Installs and starts mcloudd.service (ExecStart=mcloudd --config /etc/mcloud/config.yaml)
 func InitCommand(c *cli.Context) error {
-	ctx := context.Background()
+	// Ctrl-C cancels the running step (e.g. a hung lxd init) so its failure is
+	// recorded and init can be resumed
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	// Extract cluster name from CLI flag
-	clusterName := c.String("name")
+	// Step 1a: Merge the preseed file and flags into the init options
+	opts, err := loadInitOptions(c)
+	if err != nil {
+		return err
+	}
+	clusterName := opts.Name
 	logger.Info("Initializing mcloud cluster: %s\n", clusterName)
 
-	// Step 1a: Load configuration from YAML file
-	cfg, err := config.GetConfig()
+	// The steps below are shown with a spinner and checkmarks on a terminal
+	pr := newProgress()
+	defer pr.Close()
+
+	// Step 1c: Find an interrupted init to resume (or clean up with --clean)
+	prev, err := loadPartialInit(ctx, opts.DBPath)
 	if err != nil {
-		logger.Error("Failed to load config: %v", err)
+		return err
+	}
+	switch {
+	case c.Bool("resume") && c.Bool("clean"):
+		return usageErrorf("--resume and --clean cannot be used together")
+	case prev == nil && c.Bool("resume"):
+		return fmt.Errorf("there is no partially initialized cluster to resume")
+	case prev != nil && !c.Bool("clean") && prev.Data["cluster_name"] != clusterName:
+		return fmt.Errorf("cluster '%s' is partially initialized (%s); re-run init with --name %s to resume it, or with --clean to start over",
+			prev.Data["cluster_name"], failedAt(prev), prev.Data["cluster_name"])
 	}
-	logger.Info("Loaded config: %v", cfg)
 
-	// Step 1b: Initialize database connection and run migrations
-	conn, err := database.Connect()
-	if err != nil {
-		logger.Error("Failed to connect to database: %v", err)
+	// Step 1d: Preflight; an interrupted init has already claimed the ports it checks
+	if prev == nil && !c.Bool("skip-preflight") {
+		err := pr.Step("Running preflight checks", func() error {
+			return runPreflight(ctx, opts.preflightOptions(), opts.SnapChannels, c.Bool("install-deps") && !c.Bool("dry-run"))
+		})
+		if err != nil {
+			return err
+		}
 	}
-	logger.Info("Database initialized and migrated")
 
-	// Step 2: Detect host information (hostname, IP addresses, memory, etc.)
+	// Step 1b: Detect host information (hostname, IP addresses, memory, etc.)
 	host, err := utils.DetectHost()
 	if err != nil {
 		return err
 	}
 
-	// Step 3: Validate cluster name (minimum length and uniqueness)
-	if err := validateClusterName(ctx, clusterName, conn); err != nil {
+	// The rest of init advertises host.IPs[0], so put the chosen address first
+	ip, err := advertiseIP(opts.AdvertiseAddress, opts.AdvertiseInterface, host.IPs)
+	if err != nil {
 		return err
 	}
+	if prev == nil && !c.Bool("skip-preflight") {
+		if err := pr.Step("Checking "+ip.String()+" can serve the LXD cluster port", func() error { return checkAdvertiseReachable(ip) }); err != nil {
+			return err
+		}
+	}
+	host.IPs = append([]net.IP{ip}, host.IPs...)
+
+	// Step 1e: With --dry-run, print what the steps below would do instead
+	if c.Bool("dry-run") {
+		return dryRunInit(ctx, clusterName, *host, opts, prev, c.Bool("clean"))
+	}
 
-	// Step 4: Write configuration file with detected settings
-	if err := writeConfig(*host); err != nil {
+	// Step 2: Write configuration file; the database and certificates below use its paths
+	cfg, err := writeConfig(*host, opts)
+	if err != nil {
 		return err
 	}
 
+	// Step 3a: Initialize database connection and run migrations
+	conn, err := database.Connect(cfg.Database.DBPath)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	logger.Info("Database initialized and migrated")
+
 	// Generate unique identifiers for node and cluster
 	nodeId := utils.GenerateUUID()
 	clusterId := utils.GenerateUUID()
 
-	// Step 5: Bootstrap all mcloud infrastructure components
-	_, err = bootstrap(ctx, clusterName, *host, nodeId, clusterId, *cfg)
-	if err != nil {
+	// Step 3b: Resume the interrupted init, or validate cluster name (minimum length and uniqueness)
+	if prev != nil && c.Bool("clean") {
+		if err := cleanPartialInit(ctx, pr, conn, prev, *host, *cfg); err != nil {
+			return err
+		}
+		prev = nil
+	}
+	if prev != nil {
+		nodeId = prev.Data["node_id"]
+		clusterId = prev.Data["cluster_id"]
+		logger.Info("Resuming init of cluster %s (%s)", clusterName, failedAt(prev))
+	} else if err := pr.Step("Validating cluster name "+clusterName, func() error { return validateClusterName(ctx, clusterName, conn) }); err != nil {
 		return err
 	}
 
-	// Step 6: Write cluster state file with node and cluster information
-	if err := writeState(clusterName, *host, nodeId, clusterId); err != nil {
+	// Step 4: Bootstrap all mcloud infrastructure components and write the state file
+	_, err = bootstrap(ctx, pr, conn, clusterName, *host, nodeId, clusterId, *cfg, cluster.CephDisks(cfg.Storage, host.Hostname), c.Bool("rollback-on-failure"))
+	if err != nil {
 		return err
 	}
 
 	logger.Info("mcloud initialized successfully")
+
+	// Step 6: Hand out a join string
+	joinToken, err := issueJoinToken(ctx, conn, clusterId, *cfg)
+	if err != nil {
+		return fmt.Errorf("failed to issue join token: %w", err)
+	}
+	logger.Info("Join other nodes (until %s) with:\n  mcloudctl join --token %s", time.Now().Add(joinTokenTTL).Format(time.DateTime), joinToken)
 	return nil
 }
@@ -1,28 +1,305 @@
 package mcloudctl
 
 import (
-	"flag"
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"mcloud/internal/auth"
+	"mcloud/internal/cert"
+	"mcloud/internal/cluster"
+	"mcloud/internal/config"
+	"mcloud/internal/constant"
+	"mcloud/internal/installer"
+	"mcloud/internal/preflight"
+	"mcloud/internal/tracing"
+	"mcloud/internal/version"
+	"mcloud/pkg/client"
+	"mcloud/pkg/logger"
+	"mcloud/pkg/utils"
+	"mcloud/services/microceph"
+
+	"github.com/urfave/cli/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
 )
 
-// JoinCommand handles the 'mcloud join' command
-func JoinCommand(args []string) error {
-	fs := flag.NewFlagSet("join", flag.ExitOnError)
-	token := fs.String("token", "", "Bootstrap token from init")
-	serverURL := fs.String("server", "http://localhost:8080", "mcloudd server URL")
+// pinClusterCA fetches the cluster CA from the manager named by the join token,
+// accepting it only if it matches the token's fingerprint (see
+// auth.FetchPinnedCA), and stores it at security.ca_cert_path, where the agent
+// and mcloudctl read it for every later connection. A CA already there must be
+// the same one. With dryRun the CA is checked but not stored.
+func pinClusterCA(ctx context.Context, cfg *config.Config, token *auth.JoinToken, dryRun bool) error {
+	path := cfg.Security.CACertPath
+	if path == "" {
+		return fmt.Errorf("security.ca_cert_path is not set in %s", config.Path())
+	}
+
+	caPEM, err := auth.FetchPinnedCA(ctx, token.Address, token.CAFingerprint)
+	if err != nil {
+		return err
+	}
+
+	existing, err := cert.ReadPEM(path)
+	switch {
+	case err == nil:
+		if err := token.VerifyCA(existing); err != nil {
+			return fmt.Errorf("%s holds another cluster's CA: %w", path, err)
+		}
+		return nil
+	case !os.IsNotExist(err):
+		return err
+	}
+	if dryRun {
+		logger.Info("Would pin CA %s of %s in %s", token.CAFingerprint, token.Address, path)
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, caPEM, 0o644); err != nil {
+		return err
+	}
+	logger.Info("Pinned CA %s of %s in %s", token.CAFingerprint, token.Address, path)
+	return nil
+}
+
+// checkManagerVersion refuses to join a manager whose mcloudd has another major
+// version than this mcloudctl and the mcloud-agent shipped with it: the
+// manager would turn the agent away once it connects. Managers too old to
+// serve GET /version are let through.
+func checkManagerVersion(ctx context.Context, cfg *config.Config, managerURL string) error {
+	c, err := client.New(client.Config{BaseURL: managerURL, CACertPath: cfg.Security.CACertPath})
+	if err != nil {
+		return err
+	}
+	info, err := c.Version(ctx)
+	var apiErr *client.APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("get version of %s: %w", managerURL, err)
+	}
+	if err := version.Compatible("manager "+managerURL, info.Version); err != nil {
+		major, _ := version.Major(info.Version)
+		return fmt.Errorf("%w; install mcloud %d.x on this node, or upgrade the managers first (mcloudctl upgrade --version v%s)",
+			err, major, strings.TrimPrefix(constant.AppVersion, "v"))
+	}
+	return nil
+}
+
+// JoinCommand is the CLI command handler for 'mcloudctl join'.
+// Installs mcloud-agent on this node as a service pointed at the manager, the way
+// init installs mcloudd on the leader. It does not exchange a bootstrap token:
+// the node's config and certificates must already be in place, and LXD and OVN
+// membership is set up separately. With --ceph-token the node also joins Ceph,
+// adding the disks the storage profile (storage in the config) assigns to it,
+// or those given with --ceph-disk.
+//
+// --token takes the join string printed by init (see auth.JoinToken): it names
+// the manager, so --server is not needed, and pins the cluster's CA. The CA the
+// manager presents is checked against it and kept at security.ca_cert_path
+// (see pinClusterCA), so the node does not need the CA file beforehand.
+//
+// Command Flow:
+//   Step 1: Load the node's config (certificates, agent port, storage profile)
+//   Step 1a: Check the join string and pin the manager's CA, if --token is given,
+//            then check the manager runs the same major version (see checkManagerVersion)
+//   Step 1b: Pick the advertise address: --advertise-address, else the address of
+//            --advertise-interface (both saved to the config), else the first detected
+//   Step 1c: Run the preflight checks init runs, unless --skip-preflight is given,
+//            and check the LXD cluster port (8443) can be served on the advertise address
+//   Step 2: Join Ceph with the node's disks, if --ceph-token is given
+//   Step 3: Install and start mcloud-agent with --manager-url from --server or agent.manager_url
+//
+// CLI Usage:
+//   mcloudctl join [--token <join-string> | --server https://192.168.1.10:9028] [--config /etc/mcloud/config.yaml]
+//     [--ceph-token <token>] [--ceph-disk /dev/sdc] [--skip-preflight] [--install-deps]
+//     [--advertise-interface eth1 | --advertise-address 10.0.0.6] [--dry-run]
+//
+// --dry-run runs every check of step 1 but stores neither the CA nor the
+// advertise flags, and skips the version check while the CA is not pinned yet.
+// It then prints the MicroCeph commands and the agent service steps 2 and 3
+// would run (see dryRunJoin).
+//
+// Each step is shown as it runs (see progress): on a terminal as a spinner that
+// turns into a checkmark, else as a log line when it starts and ends.
+//
+// Example Output (--ceph-token, storage.nodes: {node3: [/dev/nvme1n1]}, on a terminal):
+//   ✔ Running preflight checks (2.1s)
+//   ⠹ Joining Ceph and adding its disks /dev/nvme1n1
+//
+// Example Output:
+//   ✔ copied mcloud-agent → /usr/local/bin/mcloud-agent
+//   ✅ mcloud-agent installed and started (systemd)
+//   ✔ Installing mcloud-agent (1.3s)
+//   [INFO] 2026-01-02 10:30:45 mcloud-agent is reporting to https://192.168.1.10:9028
+//
+// Example Output (Error - Address Not On This Host):
+//   Returns: error("address 10.9.9.9 is not on any active interface of this host (have [10.0.0.6])")
+//
+// Example Output (Error - Incompatible Manager):
+//   Returns: error("MC2700 IncompatibleVersion: manager https://192.168.1.10:9028 runs 2.0.0, this binary is 1.4.2
+//     (major versions 2 and 1 differ); install mcloud 2.x on this node, or upgrade the managers first (mcloudctl upgrade --version v1.4.2)")
+//
+// Example Output (Error - No Manager URL):
+//   Returns: error("--token or --server is required when agent.manager_url is not set in /etc/mcloud/config.yaml")
+//
+// Example Output (--token):
+//   [INFO] 2026-01-02 10:30:40 Pinned CA 9f86d081... of https://192.168.1.10:9028 in /var/lib/mcloud/certs/ca.crt
+//
+// Example Output (Error - Token Of Another Cluster):
+//   Returns: error("MC1024 CAPinMismatch: manager CA does not match the pinned fingerprint: https://192.168.1.10:9028 presented CA [3b1f...], want 9f86...")
+func JoinCommand(c *cli.Context) (err error) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Step 1: Load the node's config
+	configPath := c.String("config")
+	config.SetPath(configPath)
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("load config %s: %w", configPath, err)
+	}
 
-	if err := fs.Parse(args); err != nil {
+	// Trace the join as one span, with the manager calls, preflight and the Ceph
+	// join below it (exported only when tracing is configured)
+	shutdownTracing, err := tracing.Setup(ctx, "mcloudctl", cfg.Tracing)
+	if err != nil {
 		return err
 	}
+	defer shutdownTracing(context.WithoutCancel(ctx))
+	ctx, span := otel.Tracer("mcloud/cmd/mcloudctl").Start(ctx, "mcloudctl join")
+	defer func() {
+		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
+	managerURL := c.String("server")
+	dryRun := c.Bool("dry-run")
 
-	if *token == "" {
-		return fmt.Errorf("--token is required")
+	// The steps below are shown with a spinner and checkmarks on a terminal
+	pr := newProgress()
+	defer pr.Close()
+
+	// Step 1a: The join string names the manager and pins the cluster's CA
+	if s := c.String("token"); s != "" {
+		token, err := auth.ParseJoinToken(s)
+		if err != nil {
+			return err
+		}
+		if token.Expired(time.Now()) {
+			return fmt.Errorf("%w: expired at %s", auth.ErrJoinTokenExpired, token.ExpiresAt.Local().Format(time.DateTime))
+		}
+		if managerURL != "" && managerURL != token.Address {
+			return fmt.Errorf("--server %s does not match the join token's manager %s", managerURL, token.Address)
+		}
+		if err := pr.Step("Pinning the CA of "+token.Address, func() error { return pinClusterCA(ctx, cfg, token, dryRun) }); err != nil {
+			return err
+		}
+		managerURL = token.Address
 	}
 
-	// TODO: Implement join functionality
-	fmt.Printf("Join command not yet implemented.\n")
-	fmt.Printf("Token: %s\n", *token)
-	fmt.Printf("Server: %s\n", *serverURL)
-	
+	if managerURL == "" {
+		managerURL = cfg.Agent.ManagerURL
+	}
+	if managerURL == "" {
+		return usageErrorf("--token or --server is required when agent.manager_url is not set in %s", configPath)
+	}
+	// A dry run has not pinned the CA the version check connects with
+	if _, err := os.Stat(cfg.Security.CACertPath); dryRun && os.IsNotExist(err) {
+		logger.Info("Skipping the version check of %s until its CA is pinned", managerURL)
+	} else if err := pr.Step("Checking the version of "+managerURL, func() error { return checkManagerVersion(ctx, cfg, managerURL) }); err != nil {
+		return err
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return err
+	}
+	disks := cluster.CephDisks(cfg.Storage, hostname)
+	if c.IsSet("ceph-disk") {
+		disks.Paths = c.StringSlice("ceph-disk")
+	}
+	cephToken := c.String("ceph-token")
+
+	// Step 1b: The address other nodes reach this one on; flags override the config
+	if c.IsSet("advertise-interface") {
+		cfg.Manager.AdvertiseInterface = c.String("advertise-interface")
+	}
+	if c.IsSet("advertise-address") {
+		cfg.Manager.AdvertiseAddress = c.String("advertise-address")
+	}
+	ip, err := advertiseIP(cfg.Manager.AdvertiseAddress, cfg.Manager.AdvertiseInterface, utils.GetAllIPs())
+	if err != nil {
+		return err
+	}
+
+	// Step 1c: Preflight, before anything is installed. The node runs no manager,
+	// so only the agent port must be free; Ceph disks are checked when it joins Ceph.
+	if !c.Bool("skip-preflight") {
+		opts := preflight.Options{LoopOSDs: true}
+		if cephToken != "" {
+			opts = cluster.DiskPreflight(disks)
+		}
+		if cfg.Agent.Port > 0 {
+			opts.Ports = []int{cfg.Agent.Port}
+		}
+		err := pr.Step("Running preflight checks", func() error {
+			return runPreflight(ctx, opts, cfg.Snaps.Channels, c.Bool("install-deps") && !dryRun)
+		})
+		if err != nil {
+			return err
+		}
+		if err := pr.Step("Checking "+ip.String()+" can serve the LXD cluster port", func() error { return checkAdvertiseReachable(ip) }); err != nil {
+			return err
+		}
+	}
+	if (c.IsSet("advertise-interface") || c.IsSet("advertise-address")) && !dryRun {
+		if err := config.SaveConfig(cfg); err != nil {
+			return fmt.Errorf("save config %s: %w", configPath, err)
+		}
+	}
+	logger.Info("Advertising %s to the other nodes", ip)
+	if dryRun {
+		return dryRunJoin(ctx, configPath, managerURL, cephToken, disks)
+	}
+
+	// Step 2: Join Ceph
+	if cephToken != "" {
+		title := "Joining Ceph and adding its disks"
+		if len(disks.Paths) > 0 {
+			title += " " + strings.Join(disks.Paths, ", ")
+		}
+		err := pr.Step(title, func() error {
+			return components.Ceph.Join(ctx, microceph.JoinConfig{Token: cephToken, Disks: disks})
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Step 3: Install the agent and start it
+	err = pr.Step("Installing mcloud-agent", func() error {
+		if err := installer.InstallAgent(configPath, managerURL); err != nil {
+			return fmt.Errorf("failed to install mcloud-agent: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	logger.Info("mcloud-agent is reporting to %s", managerURL)
 	return nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,128 @@
+package mcloudctl
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"mcloud/internal/config"
+	"mcloud/internal/node"
+	"mcloud/internal/workload"
+	"mcloud/pkg/logger"
+
+	"github.com/urfave/cli/v2"
+)
+
+// LaunchCommand is the CLI command handler for 'mcloudctl launch'.
+// Sends POST /workloads to mcloudd using the local client certificate. The
+// files given with --user-data and --network-config are passed to cloud-init
+// in the instance. The launch runs as an operation; with --wait the command
+// polls it until it finishes.
+//
+// CLI Usage:
+//   mcloudctl launch <image> <name> [--vm] [--node <node-id>] [--selector KEY=VALUE ...] [--toleration KEY[=VALUE][:EFFECT] ...]
+//     [--storage-pool <pool>] [--priority low|normal|high] [--flavor medium] [--project team-a] [--env KEY=VALUE ...] [--secret KEY=<secret-name> ...]
+//     [--user-data cloud-init.yaml] [--network-config network.yaml] [--wait]
+//
+// Example Output:
+//   [INFO] 2026-01-02 10:30:45 Launching workload web-1 (550e8400-...), operation 7c9e6679-...
+//
+// Example Output (Error):
+//   Error: MC1203 InvalidWorkload: invalid workload request: user_data is not valid YAML: yaml: line 2: did not find expected ',' or ']'
+//   Error: MC1208 NoEligibleNode: no online node matches the node selector and tolerates its taints
+//   Error: MC2504 QuotaExceeded: project quota exceeded: project team-a: cpus 6 used + 4 requested exceeds the quota of 8
+func LaunchCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	if c.NArg() != 2 {
+		return usageErrorf("image and name are required")
+	}
+	req := workload.CreateRequest{
+		Image:       c.Args().Get(0),
+		Name:        c.Args().Get(1),
+		Kind:        "container",
+		NodeID:      c.String("node"),
+		StoragePool: c.String("storage-pool"),
+		Priority:    c.String("priority"),
+		Flavor:      c.String("flavor"),
+		Project:     c.String("project"),
+	}
+	if c.Bool("vm") {
+		req.Kind = "vm"
+	}
+
+	var err error
+	if req.Env, err = parseKeyValues("env", c.StringSlice("env")); err != nil {
+		return err
+	}
+	if req.Secrets, err = parseKeyValues("secret", c.StringSlice("secret")); err != nil {
+		return err
+	}
+	if req.NodeSelector, err = parseKeyValues("selector", c.StringSlice("selector")); err != nil {
+		return err
+	}
+	for _, v := range c.StringSlice("toleration") {
+		taint, effect, _ := strings.Cut(v, ":")
+		key, value, _ := strings.Cut(taint, "=")
+		req.Tolerations = append(req.Tolerations, node.Toleration{Key: key, Value: value, Effect: effect})
+	}
+	if path := c.String("user-data"); path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		req.UserData = string(b)
+	}
+	if path := c.String("network-config"); path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		req.NetworkConfig = string(b)
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	var result workload.AsyncResult
+	if err := client.do(ctx, http.MethodPost, "/workloads", req, &result); err != nil {
+		return err
+	}
+	if err := printResult(c, result, nil); err != nil {
+		return err
+	}
+	logger.Info("Launching workload %s (%s), operation %s", req.Name, result.Workload.ID, result.Operation.ID)
+
+	if !c.Bool("wait") {
+		return nil
+	}
+	if _, err := waitOperation(ctx, client, result.Operation.ID, 0); err != nil {
+		return err
+	}
+	logger.Info("Launched workload %s (%s)", req.Name, result.Workload.ID)
+	return nil
+}
+
+// parseKeyValues parses repeated --flag KEY=VALUE values into a map (nil when empty).
+func parseKeyValues(flag string, values []string) (map[string]string, error) {
+	var m map[string]string
+	for _, kv := range values {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid --%s %q, expected KEY=VALUE", flag, kv)
+		}
+		if m == nil {
+			m = map[string]string{}
+		}
+		m[k] = v
+	}
+	return m, nil
+}
@@ -0,0 +1,152 @@
+package mcloudctl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"mcloud/internal/config"
+	"mcloud/internal/database"
+	"mcloud/internal/loadbalancer"
+	"mcloud/pkg/logger"
+
+	"github.com/urfave/cli/v2"
+)
+
+// formatSelectors is the inverse of loadbalancer.ParseSelector, e.g. default/web-*.
+func formatSelectors(selectors []database.LoadBalancerSelector) string {
+	parts := make([]string, len(selectors))
+	for i, sel := range selectors {
+		parts[i] = sel.Project + "/" + sel.Name
+	}
+	return strings.Join(parts, ",")
+}
+
+// LBListCommand is the CLI command handler for 'mcloudctl lb list'.
+// Fetches GET /load-balancers and prints the load balancers as a table.
+//
+// CLI Usage:
+//   mcloudctl lb list
+//
+// Example Output:
+//   NAME  VIP           PORT      TARGET  SELECTORS      BACKENDS
+//   web   203.0.113.10  tcp/80    8080    default/web-*  10.154.12.5,10.154.12.9
+//   dns   203.0.113.11  udp/53    53      infra/dns-*    -
+func LBListCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	var items []database.LoadBalancer
+	if err := client.do(ctx, http.MethodGet, "/load-balancers", nil, &items); err != nil {
+		return err
+	}
+
+	return printResult(c, items, func(tw io.Writer) {
+		fmt.Fprintln(tw, "NAME\tVIP\tPORT\tTARGET\tSELECTORS\tBACKENDS")
+		for _, lb := range items {
+			backends := strings.Join(lb.Backends, ",")
+			if backends == "" {
+				backends = "-"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s/%d\t%d\t%s\t%s\n", lb.Name, lb.VIP, lb.Protocol, lb.Port, lb.TargetPort, formatSelectors(lb.Selectors), backends)
+		}
+	})
+}
+
+// LBCreateCommand is the CLI command handler for 'mcloudctl lb create'.
+// Sends POST /load-balancers. Selectors are [project/]name, where name may be
+// a glob; mcloudd keeps the backends in sync with the running workloads they
+// match. The caller must be a member of the project of every selector.
+//
+// CLI Usage:
+//   mcloudctl lb create <name> --vip 203.0.113.10 --port 80 [--target-port 8080] [--protocol udp] [--project team-a] --selector web-* [--selector team-b/api ...]
+//
+// Example Output:
+//   [INFO] 2026-01-02 10:30:45 Created load balancer web on 203.0.113.10 tcp/80 with 2 backends
+//
+// Example Output (Error):
+//   Error: MC2801 LoadBalancerExists: load balancer already exists: 203.0.113.10 is the vip of web
+func LBCreateCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	name := c.Args().First()
+	if name == "" {
+		return usageErrorf("load balancer name is required")
+	}
+	if len(c.StringSlice("selector")) == 0 {
+		return usageErrorf("at least one --selector is required")
+	}
+	req := loadbalancer.CreateRequest{
+		Name:       name,
+		Project:    c.String("project"),
+		VIP:        c.String("vip"),
+		Protocol:   c.String("protocol"),
+		Port:       c.Int("port"),
+		TargetPort: c.Int("target-port"),
+	}
+	for _, s := range c.StringSlice("selector") {
+		req.Selectors = append(req.Selectors, loadbalancer.ParseSelector(s))
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	var lb database.LoadBalancer
+	if err := client.do(ctx, http.MethodPost, "/load-balancers", req, &lb); err != nil {
+		return err
+	}
+	if err := printResult(c, lb, nil); err != nil {
+		return err
+	}
+	logger.Info("Created load balancer %s on %s %s/%d with %d backends", lb.Name, lb.VIP, lb.Protocol, lb.Port, len(lb.Backends))
+	return nil
+}
+
+// LBDeleteCommand is the CLI command handler for 'mcloudctl lb delete'.
+// Sends DELETE /load-balancers/{name}, removing its OVN load balancer.
+//
+// CLI Usage:
+//   mcloudctl lb delete <name>
+//
+// Example Output (Error):
+//   Error: MC2800 LoadBalancerNotFound: load balancer not found: web
+func LBDeleteCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	name := c.Args().First()
+	if name == "" {
+		return usageErrorf("load balancer name is required")
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	if err := client.do(ctx, http.MethodDelete, "/load-balancers/"+url.PathEscape(name), nil, nil); err != nil {
+		return err
+	}
+	logger.Info("Deleted load balancer %s", name)
+	return nil
+}
@@ -0,0 +1,53 @@
+package mcloudctl
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+
+	"mcloud/internal/config"
+
+	"github.com/urfave/cli/v2"
+)
+
+// LogsCommand is the CLI command handler for 'mcloudctl logs'.
+// Streams GET /nodes/{id}/logs: the node's agent tails the journal of the
+// service and mcloudd passes the lines through, so no SSH to the node is
+// needed. With --follow it keeps streaming until interrupted.
+//
+// CLI Usage:
+//   mcloudctl logs <node-id> --service lxd|microceph|microovn|mcloudd|agent [--since 1h] [--follow]
+//
+// Example Output:
+//   2026-10-16T09:12:03+0000 node2 lxd.daemon[2211]: Starting LXD
+func LogsCommand(c *cli.Context) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	nodeID := c.Args().First()
+	if nodeID == "" {
+		return usageErrorf("node id is required")
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	q := url.Values{}
+	q.Set("service", c.String("service"))
+	q.Set("since", c.String("since"))
+	q.Set("follow", strconv.FormatBool(c.Bool("follow")))
+	err = client.download(ctx, "/nodes/"+nodeID+"/logs?"+q.Encode(), os.Stdout)
+	if ctx.Err() != nil {
+		// Interrupted by the user
+		return nil
+	}
+	return err
+}
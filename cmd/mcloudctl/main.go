@@ -1,7 +1,7 @@
 package mcloudctl
 
 import (
-	"mcloud/pkg/logger"
+	"mcloud/internal/constant"
 	"os"
 
 	"github.com/urfave/cli/v2"
@@ -36,19 +36,1496 @@ func main() {
 				Usage:  "Initialize a new mcloud cluster",
 				Flags: []cli.Flag{
 					&cli.StringFlag{
-						Name:     "name",
-						Aliases:  []string{"n"},
-						Usage:    "Cluster name",
-						Required: true,
+						Name:    "name",
+						Aliases: []string{"n"},
+						Usage:   "Cluster name (required unless set in the preseed)",
+					},
+					&cli.StringFlag{
+						Name:  "preseed",
+						Usage: "YAML file with init settings; flags override it",
+					},
+					&cli.BoolFlag{
+						Name:    "interactive",
+						Aliases: []string{"i"},
+						Usage:   "Prompt for the settings not given (default in a terminal without --name)",
+					},
+					&cli.StringFlag{
+						Name:  "config",
+						Usage: "Config file to generate",
+						Value: constant.DefaultConfigPath,
+					},
+					&cli.StringFlag{
+						Name:    "advertise-interface",
+						Aliases: []string{"interface"},
+						Usage:   "Network interface whose address is advertised to other nodes",
+					},
+					&cli.StringFlag{
+						Name:  "advertise-address",
+						Usage: "IPv4 or IPv6 address advertised to other nodes (overrides --advertise-interface)",
+					},
+					&cli.IntFlag{
+						Name:  "http-port",
+						Usage: "REST API port",
+						Value: 9028,
+					},
+					&cli.IntFlag{
+						Name:  "grpc-port",
+						Usage: "gRPC port",
+						Value: 9030,
+					},
+					&cli.StringFlag{
+						Name:  "db-path",
+						Usage: "SQLite database file",
+						Value: constant.DefaultDBPath,
+					},
+					&cli.StringFlag{
+						Name:  "cert-dir",
+						Usage: "Directory for the cluster CA and certificates",
+						Value: constant.DefaultCertDir,
+					},
+					&cli.StringSliceFlag{
+						Name:  "ceph-disk",
+						Usage: "Block device every node uses as a Ceph OSD (repeatable; default: all available disks)",
+					},
+					&cli.StringSliceFlag{
+						Name:  "ceph-node-disk",
+						Usage: "Ceph OSD of one node as <hostname>=<disk>, replacing --ceph-disk on that node (repeatable)",
+					},
+					&cli.BoolFlag{
+						Name:  "ceph-wipe",
+						Usage: "Wipe Ceph disks before adding them",
+					},
+					&cli.BoolFlag{
+						Name:  "ceph-encrypt",
+						Usage: "Encrypt Ceph OSDs",
+					},
+					&cli.IntFlag{
+						Name:  "ceph-loop-count",
+						Usage: "Loop-file OSDs to create when no disk is available (test clusters)",
+					},
+					&cli.IntFlag{
+						Name:  "ceph-loop-size",
+						Usage: "Size of each loop-file OSD in GB",
+						Value: 4,
+					},
+					&cli.StringSliceFlag{
+						Name:  "external-pool",
+						Usage: "CIDR on the uplink network floating IPs are allocated from (repeatable; default: floating IPs disabled)",
+					},
+					&cli.BoolFlag{
+						Name:  "resume",
+						Usage: "Fail unless there is a partially initialized cluster to continue (init resumes one anyway)",
+					},
+					&cli.BoolFlag{
+						Name:  "clean",
+						Usage: "Undo the steps a partially initialized cluster completed and start over",
+					},
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "Validate and run preflight, then print the config, LXD preseed and MicroOVN/MicroCeph commands init would run without changing anything",
+					},
+					&cli.BoolFlag{
+						Name:  "rollback-on-failure",
+						Usage: "Undo completed steps if a step fails instead of leaving them for the next init to resume",
+					},
+					&cli.BoolFlag{
+						Name:  "skip-preflight",
+						Usage: "Do not run the preflight checks first",
+					},
+					&cli.BoolFlag{
+						Name:  "install-deps",
+						Usage: "Install missing lxd, microceph and microovn snaps found by preflight",
+					},
+					&cli.StringSliceFlag{
+						Name:  "snap-channel",
+						Usage: "Channel to install a snap from, as <snap>=<channel> (repeatable)",
+					},
+					&cli.StringSliceFlag{
+						Name:  "server-name",
+						Usage: "FQDN clients reach mcloudd by, added to its TLS certificate (repeatable)",
 					},
 				},
 				Action: InitCommand, // See cmd/mcloudctl/init.go for full logic
 			},
+			{
+				Name:  "preflight",
+				Usage: "Check whether this host is ready for init",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "preseed",
+						Usage: "YAML file with init settings; flags override it",
+					},
+					&cli.IntFlag{
+						Name:  "http-port",
+						Usage: "REST API port",
+						Value: 9028,
+					},
+					&cli.IntFlag{
+						Name:  "grpc-port",
+						Usage: "gRPC port",
+						Value: 9030,
+					},
+					&cli.StringSliceFlag{
+						Name:  "ceph-disk",
+						Usage: "Block device to use as a Ceph OSD (repeatable; default: all available disks)",
+					},
+					&cli.IntFlag{
+						Name:  "ceph-loop-count",
+						Usage: "Loop-file OSDs to create when no disk is available (test clusters)",
+					},
+					&cli.BoolFlag{
+						Name:  "json",
+						Usage: "Print the report as JSON (same as --output json)",
+					},
+				},
+				Action: PreflightCommand, // See cmd/mcloudctl/preflight.go
+			},
+			{
+				Name:  "join",
+				Usage: "Install and start mcloud-agent on this node, reporting to the manager",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "token",
+						Usage: "Join string printed by init; names the manager and pins the cluster's CA",
+					},
+					&cli.StringFlag{
+						Name:  "server",
+						Usage: "mcloudd URL the agent reports to (default: the join string's, else agent.manager_url from the config)",
+					},
+					&cli.StringFlag{
+						Name:  "ceph-token",
+						Usage: "MicroCeph join token (microceph cluster add <hostname> on a member); joins Ceph with this node's disks",
+					},
+					&cli.StringSliceFlag{
+						Name:  "ceph-disk",
+						Usage: "Block device to use as a Ceph OSD (repeatable; default: the node's disks in the storage profile)",
+					},
+					&cli.StringFlag{
+						Name:    "advertise-interface",
+						Aliases: []string{"interface"},
+						Usage:   "Network interface whose address is advertised to other nodes",
+					},
+					&cli.StringFlag{
+						Name:  "advertise-address",
+						Usage: "IPv4 or IPv6 address advertised to other nodes (overrides --advertise-interface)",
+					},
+					&cli.StringFlag{
+						Name:  "config",
+						Usage: "Config file of this node",
+						Value: constant.DefaultConfigPath,
+					},
+					&cli.BoolFlag{
+						Name:  "skip-preflight",
+						Usage: "Do not run the preflight checks first",
+					},
+					&cli.BoolFlag{
+						Name:  "install-deps",
+						Usage: "Install missing lxd, microceph and microovn snaps found by preflight",
+					},
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "Validate and run preflight, then print the MicroCeph commands and agent service join would set up without changing anything",
+					},
+				},
+				Action: JoinCommand, // See cmd/mcloudctl/join.go
+			},
+			{
+				Name:  "rejoin",
+				Usage: "Re-join the cluster after losing the node's state, using its re-join bundle",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "bundle",
+						Usage: "Encrypted re-join bundle written at init",
+						Value: constant.DefaultRejoinBundlePath,
+					},
+					&cli.BoolFlag{
+						Name:  "force",
+						Usage: "Re-join even though a state file exists",
+					},
+				},
+				Action: RejoinCommand, // See cmd/mcloudctl/rejoin.go
+			},
+			{
+				Name:  "uninstall",
+				Usage: "Stop mcloudd and mcloud-agent and remove their services and binaries",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "purge",
+						Usage: "Also delete " + constant.DefaultDataDir + " (state, database, certificates, images)",
+					},
+				},
+				Action: UninstallCommand, // See cmd/mcloudctl/uninstall.go
+			},
+			{
+				Name:  "reset",
+				Usage: "Wipe this node's state, database and certificates so init can run again",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "force",
+						Usage: "Confirm deleting the node's cluster data",
+					},
+				},
+				Action: ResetCommand, // See cmd/mcloudctl/uninstall.go
+			},
+			{
+				Name:  "doctor",
+				Usage: "Cross-check state.yaml, the database, certificates and LXD, Ceph and OVN, and suggest fixes",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "fix",
+						Usage: "Apply the safe fixes: rewrite state.yaml from the database, tighten private key permissions",
+					},
+					&cli.BoolFlag{
+						Name:  "offline",
+						Usage: "Skip the live LXD, Ceph and OVN checks",
+					},
+				},
+				Action: DoctorCommand, // See cmd/mcloudctl/doctor.go
+			},
+			{
+				Name:  "upgrade",
+				Usage: "Upgrade mcloudd and mcloud-agent on this host, rolling back if the new version is not healthy",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "version",
+						Usage:    "Release to upgrade to, e.g. v0.2.0",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:  "component",
+						Usage: "Binary to upgrade: mcloudd, mcloud-agent or all (the ones installed here)",
+						Value: "all",
+					},
+					&cli.StringFlag{
+						Name:  "file",
+						Usage: "Local binary to install instead of downloading from upgrade.release_url (needs --component and --sha256)",
+					},
+					&cli.StringFlag{
+						Name:  "sha256",
+						Usage: "Expected SHA-256 of --file",
+					},
+					&cli.BoolFlag{
+						Name:  "insecure-skip-signature",
+						Usage: "Download a release without verifying SHA256SUMS.sig when upgrade.public_key_path is not set",
+					},
+				},
+				Action: UpgradeCommand, // See cmd/mcloudctl/upgrade.go
+			},
+			{
+				Name:   "version",
+				Usage:  "Show the version, commit and build date of mcloudctl and mcloudd",
+				Action: VersionCommand, // See cmd/mcloudctl/version.go
+			},
+			{
+				Name:  "ca",
+				Usage: "Inspect and rotate the cluster's intermediate CA (on the leader)",
+				Subcommands: []*cli.Command{
+					{
+						Name:   "list",
+						Usage:  "List the root CA and the intermediate CAs, retired ones included",
+						Action: CAListCommand, // See cmd/mcloudctl/ca.go
+					},
+					{
+						Name:   "rotate",
+						Usage:  "Replace the intermediate CA and re-issue this node's certificates; the root is kept",
+						Action: CARotateCommand, // See cmd/mcloudctl/ca.go
+					},
+				},
+			},
+			{
+				Name:  "cert",
+				Usage: "Inspect, renew and revoke certificates",
+				Subcommands: []*cli.Command{
+					{
+						Name:   "list",
+						Usage:  "List the CAs and this node's server, client and node certificates with their expiry",
+						Action: CertListCommand, // See cmd/mcloudctl/cert.go
+					},
+					{
+						Name:      "inspect",
+						Usage:     "Show a certificate's details and PEM",
+						ArgsUsage: "<id>",
+						Action:    CertInspectCommand, // See cmd/mcloudctl/cert.go
+					},
+					{
+						Name:      "renew",
+						Usage:     "Re-issue this node's server, client or node certificate from the issuing CA",
+						ArgsUsage: "<server|client|node>",
+						Action:    CertRenewCommand, // See cmd/mcloudctl/cert.go
+					},
+					{
+						Name:      "revoke",
+						Usage:     "Revoke a certificate given as a PEM file or by serial number (hex)",
+						ArgsUsage: "<serial|cert-file>",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "reason",
+								Usage: "Why the certificate is revoked, e.g. \"key leaked\"",
+							},
+						},
+						Action: CertRevokeCommand, // See cmd/mcloudctl/cert.go
+					},
+					{
+						Name:   "revoked",
+						Usage:  "List the certificates revoked by serial number",
+						Action: CertRevokedCommand, // See cmd/mcloudctl/cert.go
+					},
+				},
+			},
+			{
+				Name:  "db",
+				Usage: "Inspect and migrate the manager database on this node",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "db-path",
+						Usage: "SQLite database file (default: database.db_path from the config)",
+					},
+				},
+				Subcommands: []*cli.Command{
+					{
+						Name:   "status",
+						Usage:  "List migrations and whether they are applied",
+						Action: DBStatusCommand, // See cmd/mcloudctl/db.go
+					},
+					{
+						Name:   "migrate",
+						Usage:  "Apply pending migrations",
+						Action: DBMigrateCommand, // See cmd/mcloudctl/db.go
+					},
+					{
+						Name:  "rollback",
+						Usage: "Revert the most recent migrations (stop mcloudd first)",
+						Flags: []cli.Flag{
+							&cli.IntFlag{
+								Name:  "steps",
+								Usage: "Number of migrations to revert",
+								Value: 1,
+							},
+							&cli.BoolFlag{
+								Name:  "force",
+								Usage: "Confirm dropping the tables and columns the migrations added",
+							},
+						},
+						Action: DBRollbackCommand, // See cmd/mcloudctl/db.go
+					},
+				},
+			},
+			{
+				Name:      "launch",
+				Usage:     "Launch a new workload, optionally configured by cloud-init",
+				ArgsUsage: "<image> <name>",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "vm",
+						Usage: "Launch a virtual machine instead of a container",
+					},
+					&cli.StringFlag{
+						Name:  "node",
+						Usage: "Node ID to place the workload on (default: chosen by the scheduler)",
+					},
+					&cli.StringSliceFlag{
+						Name:  "selector",
+						Usage: "Only place the workload on nodes with label KEY=VALUE (repeatable; ignored with --node)",
+					},
+					&cli.StringSliceFlag{
+						Name:  "toleration",
+						Usage: "Tolerate node taint KEY[=VALUE][:EFFECT] (repeatable; any value and effect when omitted)",
+					},
+					&cli.StringFlag{
+						Name:  "storage-pool",
+						Usage: "Storage pool of the root disk (default: the default profile's pool)",
+					},
+					&cli.StringFlag{
+						Name:  "priority",
+						Usage: "Priority class: low, normal or high (default: normal)",
+					},
+					&cli.StringFlag{
+						Name:  "flavor",
+						Usage: "Flavor sizing CPUs, memory and root disk (see mcloudctl flavor list)",
+					},
+					&cli.StringFlag{
+						Name:  "project",
+						Usage: "Project the workload belongs to and counts against (default: default)",
+					},
+					&cli.StringSliceFlag{
+						Name:  "env",
+						Usage: "Environment variable KEY=VALUE (repeatable)",
+					},
+					&cli.StringSliceFlag{
+						Name:  "secret",
+						Usage: "Environment variable KEY=<secret-name> taken from a secret (repeatable)",
+					},
+					&cli.StringFlag{
+						Name:  "user-data",
+						Usage: "cloud-init user-data file, applied as user.user-data",
+					},
+					&cli.StringFlag{
+						Name:  "network-config",
+						Usage: "cloud-init network-config file, applied as user.network-config",
+					},
+					&cli.BoolFlag{
+						Name:  "wait",
+						Usage: "Wait for the launch operation to finish and show progress",
+					},
+				},
+				Action: LaunchCommand, // See cmd/mcloudctl/launch.go
+			},
+			{
+				Name:      "exec",
+				Usage:     "Run a command in a workload (recorded in the audit log)",
+				ArgsUsage: "<workload-id> -- <command> [args...]",
+				Flags: []cli.Flag{
+					&cli.StringSliceFlag{
+						Name:  "env",
+						Usage: "Extra environment variable KEY=VALUE (repeatable)",
+					},
+					&cli.BoolFlag{
+						Name:    "tty",
+						Aliases: []string{"t"},
+						Usage:   "Run the command on a terminal (default when stdin and stdout are terminals)",
+					},
+				},
+				Action: WorkloadExecCommand, // See cmd/mcloudctl/workload.go
+			},
+			{
+				Name:      "console",
+				Usage:     "Attach to a workload's console (Ctrl+a q detaches)",
+				ArgsUsage: "<workload-id>",
+				Action:    ConsoleCommand, // See cmd/mcloudctl/console.go
+			},
+			{
+				Name:  "flavor",
+				Usage: "Manage flavors (named workload sizes)",
+				Subcommands: []*cli.Command{
+					{
+						Name:   "list",
+						Usage:  "List flavors",
+						Action: FlavorListCommand, // See cmd/mcloudctl/flavor.go
+					},
+					{
+						Name:      "create",
+						Usage:     "Create a flavor",
+						ArgsUsage: "<name>",
+						Flags: []cli.Flag{
+							&cli.IntFlag{
+								Name:     "cpus",
+								Usage:    "CPU limit (limits.cpu)",
+								Required: true,
+							},
+							&cli.IntFlag{
+								Name:     "memory-mb",
+								Usage:    "Memory limit in MiB (limits.memory)",
+								Required: true,
+							},
+							&cli.IntFlag{
+								Name:     "disk-gb",
+								Usage:    "Root disk size in GiB",
+								Required: true,
+							},
+						},
+						Action: FlavorCreateCommand, // See cmd/mcloudctl/flavor.go
+					},
+					{
+						Name:      "delete",
+						Usage:     "Delete a flavor no workload uses",
+						ArgsUsage: "<name>",
+						Action:    FlavorDeleteCommand, // See cmd/mcloudctl/flavor.go
+					},
+				},
+			},
+			{
+				Name:  "project",
+				Usage: "Manage projects (tenants) and their quotas",
+				Subcommands: []*cli.Command{
+					{
+						Name:   "list",
+						Usage:  "List projects with their usage and quotas",
+						Action: ProjectListCommand, // See cmd/mcloudctl/project.go
+					},
+					{
+						Name:      "create",
+						Usage:     "Create a project",
+						ArgsUsage: "<name>",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "description",
+								Usage: "Free-form description",
+							},
+							&cli.StringFlag{
+								Name:  "cpus",
+								Usage: "CPU limit, or \"unlimited\"",
+							},
+							&cli.StringFlag{
+								Name:  "memory-mb",
+								Usage: "Memory limit in MiB, or \"unlimited\"",
+							},
+							&cli.StringFlag{
+								Name:  "instances",
+								Usage: "Instance count limit, or \"unlimited\"",
+							},
+							&cli.StringFlag{
+								Name:  "disk-gb",
+								Usage: "Root disk limit in GiB, or \"unlimited\"",
+							},
+						},
+						Action: ProjectCreateCommand, // See cmd/mcloudctl/project.go
+					},
+					{
+						Name:      "delete",
+						Usage:     "Delete a project that has no workloads",
+						ArgsUsage: "<name>",
+						Action:    ProjectDeleteCommand, // See cmd/mcloudctl/project.go
+					},
+					{
+						Name:      "bindings",
+						Usage:     "List the identities with a role in a project",
+						ArgsUsage: "<name>",
+						Action:    ProjectBindingsCommand, // See cmd/mcloudctl/project.go
+					},
+					{
+						Name:      "bind",
+						Usage:     "Give a client identity a role in a project",
+						ArgsUsage: "<name> <identity>",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "role",
+								Usage: "Role: member (launch and manage workloads) or admin (also quotas and bindings)",
+								Value: "member",
+							},
+						},
+						Action: ProjectBindCommand, // See cmd/mcloudctl/project.go
+					},
+					{
+						Name:      "unbind",
+						Usage:     "Remove a client identity's role in a project",
+						ArgsUsage: "<name> <identity>",
+						Action:    ProjectUnbindCommand, // See cmd/mcloudctl/project.go
+					},
+					{
+						Name:  "quota",
+						Usage: "Manage project quotas",
+						Subcommands: []*cli.Command{
+							{
+								Name:      "set",
+								Usage:     "Change a project's limits; limits not given are kept",
+								ArgsUsage: "<name>",
+								Flags: []cli.Flag{
+									&cli.StringFlag{
+										Name:  "cpus",
+										Usage: "CPU limit, or \"unlimited\"",
+									},
+									&cli.StringFlag{
+										Name:  "memory-mb",
+										Usage: "Memory limit in MiB, or \"unlimited\"",
+									},
+									&cli.StringFlag{
+										Name:  "instances",
+										Usage: "Instance count limit, or \"unlimited\"",
+									},
+									&cli.StringFlag{
+										Name:  "disk-gb",
+										Usage: "Root disk limit in GiB, or \"unlimited\"",
+									},
+								},
+								Action: ProjectQuotaSetCommand, // See cmd/mcloudctl/project.go
+							},
+						},
+					},
+				},
+			},
+			{
+				Name:  "volume",
+				Usage: "Manage Ceph-backed storage volumes",
+				Subcommands: []*cli.Command{
+					{
+						Name:   "list",
+						Usage:  "List volumes and the workloads they are attached to",
+						Action: VolumeListCommand, // See cmd/mcloudctl/volume.go
+					},
+					{
+						Name:      "create",
+						Usage:     "Create a volume",
+						ArgsUsage: "<name>",
+						Flags: []cli.Flag{
+							&cli.IntFlag{
+								Name:     "size-gb",
+								Usage:    "Size in GiB",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:  "pool",
+								Usage: "Ceph-backed storage pool (default: the default profile's pool)",
+							},
+						},
+						Action: VolumeCreateCommand, // See cmd/mcloudctl/volume.go
+					},
+					{
+						Name:      "attach",
+						Usage:     "Attach a volume to a workload",
+						ArgsUsage: "<volume-id> <workload-id>",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "path",
+								Usage:    "Mount path in the workload, e.g. /var/lib/postgresql",
+								Required: true,
+							},
+						},
+						Action: VolumeAttachCommand, // See cmd/mcloudctl/volume.go
+					},
+					{
+						Name:      "detach",
+						Usage:     "Detach a volume from its workload",
+						ArgsUsage: "<volume-id>",
+						Action:    VolumeDetachCommand, // See cmd/mcloudctl/volume.go
+					},
+					{
+						Name:      "delete",
+						Usage:     "Delete a detached volume and its data",
+						ArgsUsage: "<volume-id>",
+						Action:    VolumeDeleteCommand, // See cmd/mcloudctl/volume.go
+					},
+				},
+			},
+			{
+				Name:  "network",
+				Usage: "Expose workloads outside the cluster with floating IPs",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "expose",
+						Usage:     "Forward a floating IP from the external pool to a workload",
+						ArgsUsage: "<workload-id>",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "address",
+								Usage: "Floating IP to use (default: the first free address of the pool)",
+							},
+							&cli.StringFlag{
+								Name:  "dns-name",
+								Usage: "Also publish <name>.<dns zone> for the address",
+							},
+						},
+						Action: NetworkExposeCommand, // See cmd/mcloudctl/network.go
+					},
+					{
+						Name:   "list",
+						Usage:  "List allocated floating IPs",
+						Action: NetworkListCommand, // See cmd/mcloudctl/network.go
+					},
+					{
+						Name:      "release",
+						Usage:     "Remove a floating IP's forward and DNS record and free the address",
+						ArgsUsage: "<address>",
+						Action:    NetworkReleaseCommand, // See cmd/mcloudctl/network.go
+					},
+				},
+			},
+			{
+				Name:  "lb",
+				Usage: "Manage load balancers spreading a VIP over workloads",
+				Subcommands: []*cli.Command{
+					{
+						Name:   "list",
+						Usage:  "List load balancers with their backends",
+						Action: LBListCommand, // See cmd/mcloudctl/lb.go
+					},
+					{
+						Name:      "create",
+						Usage:     "Create a load balancer",
+						ArgsUsage: "<name>",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "vip",
+								Usage:    "Address to balance, routed to the OVN network's uplink",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:  "protocol",
+								Usage: "tcp or udp",
+								Value: "tcp",
+							},
+							&cli.IntFlag{
+								Name:     "port",
+								Usage:    "Port to listen on",
+								Required: true,
+							},
+							&cli.IntFlag{
+								Name:  "target-port",
+								Usage: "Port of the workloads (default: --port)",
+							},
+							&cli.StringSliceFlag{
+								Name:  "selector",
+								Usage: "Backend workloads [project/]name, where name may be a glob, e.g. web-* (repeatable)",
+							},
+							&cli.StringFlag{
+								Name:  "project",
+								Usage: "Project of selectors without one (default: default)",
+							},
+						},
+						Action: LBCreateCommand, // See cmd/mcloudctl/lb.go
+					},
+					{
+						Name:      "delete",
+						Usage:     "Delete a load balancer",
+						ArgsUsage: "<name>",
+						Action:    LBDeleteCommand, // See cmd/mcloudctl/lb.go
+					},
+				},
+			},
+			{
+				Name:  "secgroup",
+				Usage: "Manage security groups, the firewall of workloads",
+				Subcommands: []*cli.Command{
+					{
+						Name:   "list",
+						Usage:  "List security groups",
+						Action: SecGroupListCommand, // See cmd/mcloudctl/secgroup.go
+					},
+					{
+						Name:      "show",
+						Usage:     "Show a security group's rules and workloads",
+						ArgsUsage: "<name>",
+						Action:    SecGroupShowCommand, // See cmd/mcloudctl/secgroup.go
+					},
+					{
+						Name:      "create",
+						Usage:     "Create a security group",
+						ArgsUsage: "<name>",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "description",
+								Usage: "Description of the group",
+							},
+							&cli.StringSliceFlag{
+								Name:  "rule",
+								Usage: "Allow rule direction[:protocol[:port[:cidr]]], e.g. ingress:tcp:443:0.0.0.0/0 (repeatable)",
+							},
+						},
+						Action: SecGroupCreateCommand, // See cmd/mcloudctl/secgroup.go
+					},
+					{
+						Name:      "rules",
+						Usage:     "Replace the rules of a security group",
+						ArgsUsage: "<name>",
+						Flags: []cli.Flag{
+							&cli.StringSliceFlag{
+								Name:  "rule",
+								Usage: "Allow rule direction[:protocol[:port[:cidr]]], e.g. ingress:tcp:443:0.0.0.0/0 (repeatable)",
+							},
+						},
+						Action: SecGroupRulesCommand, // See cmd/mcloudctl/secgroup.go
+					},
+					{
+						Name:      "delete",
+						Usage:     "Delete a security group no workload uses",
+						ArgsUsage: "<name>",
+						Action:    SecGroupDeleteCommand, // See cmd/mcloudctl/secgroup.go
+					},
+					{
+						Name:      "attach",
+						Usage:     "Attach a security group to a workload",
+						ArgsUsage: "<name> <workload-id>",
+						Action:    SecGroupAttachCommand, // See cmd/mcloudctl/secgroup.go
+					},
+					{
+						Name:      "detach",
+						Usage:     "Detach a security group from a workload",
+						ArgsUsage: "<name> <workload-id>",
+						Action:    SecGroupDetachCommand, // See cmd/mcloudctl/secgroup.go
+					},
+				},
+			},
+			{
+				Name:  "workload",
+				Usage: "Manage workloads",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "list",
+						Usage: "List workloads with their IP addresses",
+						Flags: []cli.Flag{
+							&cli.IntFlag{
+								Name:  "limit",
+								Usage: "Maximum number of workloads to show",
+								Value: 100,
+							},
+							&cli.StringFlag{
+								Name:  "sort",
+								Usage: "Sort by name, status, kind, priority or created_at (prefix - for descending)",
+							},
+							&cli.StringFlag{
+								Name:  "status",
+								Usage: "Only show workloads with this status",
+							},
+							&cli.StringFlag{
+								Name:  "node",
+								Usage: "Only show workloads on this node ID",
+							},
+							&cli.StringFlag{
+								Name:  "project",
+								Usage: "Only show workloads in this project",
+							},
+						},
+						Action: WorkloadListCommand, // See cmd/mcloudctl/workload.go
+					},
+					{
+						Name:      "describe",
+						Usage:     "Show a workload's details, addresses and environment",
+						ArgsUsage: "<workload-id>",
+						Action:    WorkloadDescribeCommand, // See cmd/mcloudctl/workload.go
+					},
+					{
+						Name:      "clone",
+						Usage:     "Clone a workload under a new name",
+						ArgsUsage: "<workload-id>",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "name",
+								Usage:    "Name of the new workload",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:  "snapshot",
+								Usage: "Clone from this snapshot of the source",
+							},
+							&cli.StringFlag{
+								Name:  "target",
+								Usage: "Node ID to place the clone on",
+							},
+							&cli.BoolFlag{
+								Name:  "wait",
+								Usage: "Wait for the clone operation to finish and show progress",
+							},
+						},
+						Action: WorkloadCloneCommand, // See cmd/mcloudctl/workload.go
+					},
+					{
+						Name:      "exec",
+						Usage:     "Run a command in a workload (recorded in the audit log)",
+						ArgsUsage: "<workload-id> -- <command> [args...]",
+						Flags: []cli.Flag{
+							&cli.StringSliceFlag{
+								Name:  "env",
+								Usage: "Extra environment variable KEY=VALUE (repeatable)",
+							},
+							&cli.BoolFlag{
+								Name:    "tty",
+								Aliases: []string{"t"},
+								Usage:   "Run the command on a terminal (default when stdin and stdout are terminals)",
+							},
+						},
+						Action: WorkloadExecCommand, // See cmd/mcloudctl/workload.go
+					},
+					{
+						Name:  "schedule",
+						Usage: "Stop and start a workload at fixed times (manager local time)",
+						Subcommands: []*cli.Command{
+							{
+								Name:      "set",
+								Usage:     "Set or replace the workload's power schedule",
+								ArgsUsage: "<workload-id>",
+								Flags: []cli.Flag{
+									&cli.StringFlag{
+										Name:  "stop",
+										Usage: "Stop the workload at this time (HH:MM)",
+									},
+									&cli.StringFlag{
+										Name:  "start",
+										Usage: "Start the workload at this time (HH:MM)",
+									},
+									&cli.StringFlag{
+										Name:  "days",
+										Usage: "Only on these weekdays, e.g. mon-fri or sat,sun (default: every day)",
+									},
+								},
+								Action: WorkloadScheduleSetCommand, // See cmd/mcloudctl/schedule.go
+							},
+							{
+								Name:      "clear",
+								Usage:     "Remove the workload's power schedule",
+								ArgsUsage: "<workload-id>",
+								Action:    WorkloadScheduleClearCommand, // See cmd/mcloudctl/schedule.go
+							},
+							{
+								Name:      "skip",
+								Usage:     "Skip the next scheduled stop or start",
+								ArgsUsage: "<workload-id>",
+								Action:    WorkloadScheduleSkipCommand, // See cmd/mcloudctl/schedule.go
+							},
+							{
+								Name:      "override",
+								Usage:     "Suspend the schedule for a while, e.g. to keep a VM running overnight",
+								ArgsUsage: "<workload-id>",
+								Flags: []cli.Flag{
+									&cli.DurationFlag{
+										Name:     "for",
+										Usage:    "How long to suspend the schedule (0 resumes it)",
+										Required: true,
+									},
+								},
+								Action: WorkloadScheduleOverrideCommand, // See cmd/mcloudctl/schedule.go
+							},
+						},
+					},
+				},
+			},
+			{
+				Name:  "edit",
+				Usage: "Edit a resource in $EDITOR and apply only the changed fields",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "workload",
+						Usage:     "Edit a workload's env, secret references and hooks",
+						ArgsUsage: "<workload-id>",
+						Action:    EditWorkloadCommand, // See cmd/mcloudctl/edit.go
+					},
+				},
+			},
+			{
+				Name:  "diff",
+				Usage: "Show drift between a cluster state manifest (e.g. from git) and the live cluster",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "against",
+						Usage:    "Manifest file written from GET /cluster/state.yaml",
+						Required: true,
+					},
+				},
+				Action: DiffCommand, // See cmd/mcloudctl/diff.go
+			},
+			{
+				Name:  "apply",
+				Usage: "Converge the cluster on a ClusterSpec manifest of flavors, security groups, workloads and volumes",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "file",
+						Aliases:  []string{"f"},
+						Usage:    "Manifest file (- for stdin)",
+						Required: true,
+					},
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "Only print the changes",
+					},
+					&cli.BoolFlag{
+						Name:  "prune",
+						Usage: "Also delete flavors, security groups and volumes the manifest does not mention",
+					},
+					&cli.BoolFlag{
+						Name:  "no-wait",
+						Usage: "Do not wait for the apply operation to finish",
+					},
+				},
+				Action: ApplyCommand, // See cmd/mcloudctl/apply.go
+			},
+			{
+				Name:  "drift",
+				Usage: "Show drift between the database and LXD, OVN and Ceph found by the reconciler",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "check",
+						Usage: "Run a check now instead of showing the last one",
+					},
+				},
+				Action: DriftCommand, // See cmd/mcloudctl/drift.go
+			},
+			{
+				Name:      "logs",
+				Usage:     "Stream the logs of a service on a node through its agent",
+				ArgsUsage: "<node-id>",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "service",
+						Usage:    "Service to show: lxd, microceph, microovn, mcloudd or agent",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:  "since",
+						Usage: "How far back to start (e.g. 30m)",
+						Value: "1h",
+					},
+					&cli.BoolFlag{
+						Name:    "follow",
+						Aliases: []string{"f"},
+						Usage:   "Keep streaming new lines",
+					},
+				},
+				Action: LogsCommand, // See cmd/mcloudctl/logs.go
+			},
+			{
+				Name:  "operation",
+				Usage: "Inspect long-running operations",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "list",
+						Usage: "List operations, newest first",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "status",
+								Usage: "Only show operations with this status (pending, running, succeeded, failed)",
+							},
+							&cli.IntFlag{
+								Name:  "limit",
+								Usage: "Maximum number of operations to show",
+								Value: 20,
+							},
+						},
+						Action: OperationListCommand, // See cmd/mcloudctl/operation.go
+					},
+					{
+						Name:      "show",
+						Usage:     "Show an operation's status, progress and result",
+						ArgsUsage: "<operation-id>",
+						Action:    OperationShowCommand, // See cmd/mcloudctl/operation.go
+					},
+					{
+						Name:      "wait",
+						Usage:     "Wait for an operation to finish",
+						ArgsUsage: "<operation-id>",
+						Flags: []cli.Flag{
+							&cli.DurationFlag{
+								Name:  "timeout",
+								Usage: "Give up after this long (0 = wait forever)",
+							},
+						},
+						Action: OperationWaitCommand, // See cmd/mcloudctl/operation.go
+					},
+				},
+			},
+			{
+				Name:  "storage",
+				Usage: "Manage Ceph storage on this node",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "add-disk",
+						Usage:     "Add disks to Ceph as OSDs (default: all available disks)",
+						ArgsUsage: "[<device>...]",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:  "wipe",
+								Usage: "Wipe the disks before adding them",
+							},
+							&cli.BoolFlag{
+								Name:  "encrypt",
+								Usage: "Encrypt the OSDs",
+							},
+							&cli.IntFlag{
+								Name:  "loop-count",
+								Usage: "Loop-file OSDs to create when no disk is available (test clusters)",
+							},
+							&cli.IntFlag{
+								Name:  "loop-size",
+								Usage: "Size of each loop-file OSD in GB",
+								Value: 4,
+							},
+						},
+						Action: StorageAddDiskCommand, // See cmd/mcloudctl/storage.go
+					},
+				},
+			},
+			{
+				Name:  "maintenance",
+				Usage: "Manage the weekly maintenance window that refreshes the cluster's snaps",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "window",
+						Usage: "Manage the maintenance window",
+						Subcommands: []*cli.Command{
+							{
+								Name:   "show",
+								Usage:  "Show the maintenance window and its next opening",
+								Action: MaintenanceWindowShowCommand, // See cmd/mcloudctl/maintenance.go
+							},
+							{
+								Name:  "set",
+								Usage: "Set the weekly maintenance window",
+								Flags: []cli.Flag{
+									&cli.StringFlag{
+										Name:     "day",
+										Usage:    "Weekday the window opens: sun, mon, tue, wed, thu, fri or sat",
+										Required: true,
+									},
+									&cli.StringFlag{
+										Name:     "start",
+										Usage:    "Time the window opens, HH:MM in the manager's local time",
+										Required: true,
+									},
+									&cli.DurationFlag{
+										Name:     "duration",
+										Usage:    "How long the window stays open, e.g. 4h (at most 24h); no node is started after it closes",
+										Required: true,
+									},
+									&cli.StringFlag{
+										Name:  "snaps",
+										Usage: "Comma-separated snaps to refresh, in order (default lxd,microceph,microovn)",
+									},
+								},
+								Action: MaintenanceWindowSetCommand, // See cmd/mcloudctl/maintenance.go
+							},
+							{
+								Name:   "delete",
+								Usage:  "Delete the maintenance window",
+								Action: MaintenanceWindowDeleteCommand, // See cmd/mcloudctl/maintenance.go
+							},
+						},
+					},
+					{
+						Name:  "run",
+						Usage: "Refresh the snaps now, one node at a time, outside the window",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "snaps",
+								Usage: "Comma-separated snaps to refresh, in order (default: the window's snaps)",
+							},
+							&cli.BoolFlag{
+								Name:  "wait",
+								Usage: "Wait for the rollout to finish and show progress",
+							},
+						},
+						Action: MaintenanceRunCommand, // See cmd/mcloudctl/maintenance.go
+					},
+				},
+			},
+			{
+				Name:  "node",
+				Usage: "Manage cluster nodes",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "benchmark",
+						Usage:     "Benchmark a node's CPU, disk and network for placement weighting",
+						ArgsUsage: "<node-id>",
+						Action:    NodeBenchmarkCommand, // See cmd/mcloudctl/node.go
+					},
+					{
+						Name:      "remove",
+						Usage:     "Remove a drained node from LXD, Ceph and OVN, revoke its certificates and forget it",
+						ArgsUsage: "<node-id>",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:  "force",
+								Usage: "Remove a dead node: skip safety checks, keep going when a step fails, mark its workloads failed",
+							},
+						},
+						Action: NodeRemoveCommand, // See cmd/mcloudctl/node.go
+					},
+					{
+						Name:      "label",
+						Usage:     "Set (KEY=VALUE) or remove (KEY-) labels of a node matched by workload node selectors, and list its labels and taints",
+						ArgsUsage: "<node-id> [KEY=VALUE ...] [KEY- ...]",
+						Action:    NodeLabelCommand, // See cmd/mcloudctl/node.go
+					},
+					{
+						Name:      "taint",
+						Usage:     "Set (KEY[=VALUE][:NoSchedule|PreferNoSchedule]) or remove (KEY-) taints keeping workloads without a toleration off a node",
+						ArgsUsage: "<node-id> [KEY[=VALUE][:EFFECT] ...] [KEY- ...]",
+						Action:    NodeTaintCommand, // See cmd/mcloudctl/node.go
+					},
+					{
+						Name:   "agents",
+						Usage:  "List the agents with their command stream open",
+						Action: NodeAgentsCommand, // See cmd/mcloudctl/node.go
+					},
+					{
+						Name:      "command",
+						Usage:     "Push a command (preflight, restart_service, collect_logs, apply_config, task) to a node's agent",
+						ArgsUsage: "<node-id> <type>",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "service",
+								Usage: "Service of restart_service and collect_logs (lxd, microceph or microovn)",
+							},
+							&cli.StringFlag{
+								Name:  "task",
+								Usage: "Task of a task command: ceph_status, restart_lxd or rotate_logs",
+							},
+							&cli.StringFlag{
+								Name:  "payload",
+								Usage: "JSON payload, e.g. for apply_config (overrides --task and --service)",
+							},
+							&cli.BoolFlag{
+								Name:  "no-wait",
+								Usage: "Do not wait for the agent's result",
+							},
+						},
+						Action: NodeCommandCommand, // See cmd/mcloudctl/node.go
+					},
+				},
+			},
+			{
+				Name:  "image",
+				Usage: "Manage images",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "import",
+						Usage:     "Import an image from a URL into LXD",
+						ArgsUsage: "<url>",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "alias",
+								Usage:    "Alias of the imported image",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:  "sha256",
+								Usage: "Expected SHA-256 checksum of the downloaded file",
+							},
+							&cli.Int64Flag{
+								Name:  "limit-kbps",
+								Usage: "Maximum download rate in KiB/s (0 = unlimited)",
+							},
+							&cli.BoolFlag{
+								Name:  "wait",
+								Usage: "Wait for the import to finish and show progress",
+							},
+						},
+						Action: ImageImportCommand, // See cmd/mcloudctl/image.go
+					},
+					{
+						Name:  "build",
+						Usage: "Build an image from a base image and a provisioning script",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "alias",
+								Usage:    "Alias of the built image (replaces an existing image with this alias)",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:     "base",
+								Usage:    "Base image, e.g. ubuntu:24.04",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:     "script",
+								Usage:    "Provisioning script file, run with sh -e (- for stdin)",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:     "node",
+								Usage:    "Build node ID or hostname",
+								Required: true,
+							},
+							&cli.BoolFlag{
+								Name:  "vm",
+								Usage: "Build a virtual machine image",
+							},
+							&cli.BoolFlag{
+								Name:  "wait",
+								Usage: "Wait for the build to finish and show progress",
+							},
+						},
+						Action: ImageBuildCommand, // See cmd/mcloudctl/image.go
+					},
+					{
+						Name:  "builds",
+						Usage: "List image builds with their provenance",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "alias",
+								Usage: "Only show builds of this alias",
+							},
+							&cli.IntFlag{
+								Name:  "limit",
+								Usage: "Maximum number of builds to show",
+								Value: 20,
+							},
+						},
+						Action: ImageBuildListCommand, // See cmd/mcloudctl/image.go
+					},
+				},
+			},
+			{
+				Name:  "alerts",
+				Usage: "Inspect alerts raised by the alert rules",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "list",
+						Usage: "List firing and resolved alerts, newest first",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "status",
+								Usage: "Only show alerts with this status (firing, resolved)",
+							},
+							&cli.StringFlag{
+								Name:  "rule",
+								Usage: "Only show alerts of this rule (e.g. \"ceph HEALTH_ERR\")",
+							},
+							&cli.StringFlag{
+								Name:  "subject",
+								Usage: "Only show alerts about this subject (a hostname, certificate ID or ceph)",
+							},
+							&cli.StringFlag{
+								Name:  "severity",
+								Usage: "Only show alerts of this severity (warning, error, critical)",
+							},
+							&cli.IntFlag{
+								Name:  "limit",
+								Usage: "Maximum number of alerts to show",
+								Value: 50,
+							},
+						},
+						Action: AlertsListCommand, // See cmd/mcloudctl/alerts.go
+					},
+				},
+			},
+			{
+				Name:  "events",
+				Usage: "Show cluster events",
+				Flags: []cli.Flag{
+					&cli.IntFlag{
+						Name:  "limit",
+						Usage: "Number of recent events to show",
+						Value: 20,
+					},
+					&cli.BoolFlag{
+						Name:    "follow",
+						Aliases: []string{"f"},
+						Usage:   "Keep streaming new events",
+					},
+				},
+				Action: EventsCommand, // See cmd/mcloudctl/events.go
+				Subcommands: []*cli.Command{
+					{
+						Name:      "search",
+						Usage:     "Search past events by text, node, type, severity and time",
+						ArgsUsage: "[words...]",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "since",
+								Usage: "Only show events newer than this duration (e.g. 24h) or RFC 3339 time",
+							},
+							&cli.StringFlag{
+								Name:  "until",
+								Usage: "Only show events older than this duration (e.g. 1h) or RFC 3339 time",
+							},
+							&cli.StringFlag{
+								Name:  "node",
+								Usage: "Only show events of this node (ID or hostname)",
+							},
+							&cli.StringFlag{
+								Name:  "type",
+								Usage: "Only show events of this type (e.g. node.drained) or family (e.g. node.*)",
+							},
+							&cli.StringFlag{
+								Name:  "severity",
+								Usage: "Only show events of at least this severity: info, warning, error or critical",
+							},
+							&cli.IntFlag{
+								Name:  "limit",
+								Usage: "Maximum number of events",
+								Value: 50,
+							},
+						},
+						Action: EventsSearchCommand, // See cmd/mcloudctl/events.go
+					},
+				},
+			},
+			{
+				Name:  "config",
+				Usage: "Read and change cluster-wide configuration",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "list",
+						Usage:     "List config keys",
+						ArgsUsage: "[namespace]",
+						Action:    ConfigListCommand, // See cmd/mcloudctl/config.go
+					},
+					{
+						Name:      "get",
+						Usage:     "Print the value of a config key",
+						ArgsUsage: "<namespace> <key>",
+						Action:    ConfigGetCommand, // See cmd/mcloudctl/config.go
+					},
+					{
+						Name:      "set",
+						Usage:     "Set a config key",
+						ArgsUsage: "<namespace> <key> <value>",
+						Flags: []cli.Flag{
+							&cli.Int64Flag{
+								Name:  "version",
+								Usage: "Only write if the key is at this version (0 = only if it does not exist)",
+							},
+						},
+						Action: ConfigSetCommand, // See cmd/mcloudctl/config.go
+					},
+					{
+						Name:      "delete",
+						Usage:     "Delete a config key",
+						ArgsUsage: "<namespace> <key>",
+						Flags: []cli.Flag{
+							&cli.Int64Flag{
+								Name:  "version",
+								Usage: "Only delete if the key is at this version",
+							},
+						},
+						Action: ConfigDeleteCommand, // See cmd/mcloudctl/config.go
+					},
+					{
+						Name:      "watch",
+						Usage:     "Stream config changes",
+						ArgsUsage: "[namespace]",
+						Action:    ConfigWatchCommand, // See cmd/mcloudctl/config.go
+					},
+				},
+			},
+			{
+				Name:  "audit",
+				Usage: "Inspect the audit log of mutating operations",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "list",
+						Usage: "List audit entries",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "since",
+								Usage: "Only show entries newer than this duration (e.g. 1h) or RFC 3339 time",
+							},
+							&cli.IntFlag{
+								Name:  "limit",
+								Usage: "Maximum number of entries",
+								Value: 100,
+							},
+						},
+						Action: AuditListCommand, // See cmd/mcloudctl/audit.go
+					},
+					{
+						Name:  "sessions",
+						Usage: "List exec sessions",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "actor",
+								Usage: "Only show sessions of this identity",
+							},
+							&cli.StringFlag{
+								Name:  "target",
+								Usage: "Only show sessions into this resource (e.g. /workloads/<id>)",
+							},
+							&cli.IntFlag{
+								Name:  "limit",
+								Usage: "Maximum number of sessions",
+								Value: 100,
+							},
+						},
+						Action: AuditSessionsCommand, // See cmd/mcloudctl/audit.go
+					},
+					{
+						Name:      "transcript",
+						Usage:     "Print the recorded transcript of an exec session (asciicast v2)",
+						ArgsUsage: "<session-id>",
+						Action:    AuditTranscriptCommand, // See cmd/mcloudctl/audit.go
+					},
+				},
+			},
 		},
 	}
 
-	// Run the CLI app and handle errors
+	var inv invocation
+	setupOutput(app, &inv)
+
+	// Run the CLI app and handle errors. The reason code leads the message so
+	// scripts can match on it (e.g. "MC1200 WorkloadNotFound: ..."); with
+	// --output json or yaml the error is printed structured. The exit code
+	// tells usage errors, not found and server errors apart (see exitCode).
 	if err := app.Run(os.Args); err != nil {
-		logger.Error("%v", err)
+		code := exitCode(err, inv.ran)
+		printError(inv.format, err, code)
+		os.Exit(code)
 	}
 }
@@ -0,0 +1,192 @@
+package mcloudctl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"mcloud/internal/config"
+	"mcloud/internal/database"
+	"mcloud/internal/maintenance"
+	"mcloud/pkg/logger"
+
+	"github.com/urfave/cli/v2"
+)
+
+// MaintenanceWindowShowCommand is the CLI command handler for 'mcloudctl maintenance window show'.
+// Fetches GET /cluster/maintenance.
+//
+// CLI Usage:
+//   mcloudctl maintenance window show
+//
+// Example Output:
+//   Window:        sun 02:00 for 4h0m0s
+//   Snaps:         lxd, microceph, microovn
+//   Next opening:  2026-10-18T02:00:00+02:00
+//   Last run:      2026-10-11T02:00:00Z
+//
+// Example Output (Error):
+//   Error: MC2600 MaintenanceWindowNotFound: cluster has no maintenance window
+func MaintenanceWindowShowCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	var window maintenance.Window
+	if err := client.do(ctx, http.MethodGet, "/cluster/maintenance", nil, &window); err != nil {
+		return err
+	}
+
+	return printResult(c, window, func(tw io.Writer) {
+		fmt.Fprintf(tw, "Window:\t%s %s for %s\n", window.Day, window.StartAt, time.Duration(window.DurationMinutes)*time.Minute)
+		fmt.Fprintf(tw, "Snaps:\t%s\n", strings.ReplaceAll(window.Snaps, ",", ", "))
+		fmt.Fprintf(tw, "Next opening:\t%s\n", window.NextOpeningAt.Format(time.RFC3339))
+		if window.LastRunAt != nil {
+			fmt.Fprintf(tw, "Last run:\t%s\n", window.LastRunAt.Format(time.RFC3339))
+		}
+	})
+}
+
+// MaintenanceWindowSetCommand is the CLI command handler for 'mcloudctl maintenance window set'.
+// Sends PUT /cluster/maintenance. Every week, when the window opens, mcloudd
+// refreshes the snaps one node at a time (cordon, refresh, health-check,
+// uncordon); a window that is open right now first runs next week.
+//
+// CLI Usage:
+//   mcloudctl maintenance window set --day sun --start 02:00 --duration 4h [--snaps lxd,microovn]
+//
+// Example Output:
+//   [INFO] 2026-10-16 10:30:45 Maintenance window set: sun 02:00 for 4h0m0s, next opening 2026-10-18T02:00:00+02:00
+//
+// Example Output (Error):
+//   Error: MC2601 InvalidMaintenanceWindow: invalid maintenance window: day must be one of sun, mon, tue, wed, thu, fri, sat
+func MaintenanceWindowSetCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	duration := c.Duration("duration")
+	if duration < time.Minute {
+		return usageErrorf("--duration must be at least 1m, got %s", duration)
+	}
+	req := maintenance.WindowRequest{
+		Day:             c.String("day"),
+		Start:           c.String("start"),
+		DurationMinutes: int(duration.Minutes()),
+	}
+	if snaps := c.String("snaps"); snaps != "" {
+		req.Snaps = strings.Split(snaps, ",")
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	var window maintenance.Window
+	if err := client.do(ctx, http.MethodPut, "/cluster/maintenance", req, &window); err != nil {
+		return err
+	}
+	if err := printResult(c, window, nil); err != nil {
+		return err
+	}
+	logger.Info("Maintenance window set: %s %s for %s, next opening %s", window.Day, window.StartAt,
+		time.Duration(window.DurationMinutes)*time.Minute, window.NextOpeningAt.Format(time.RFC3339))
+	return nil
+}
+
+// MaintenanceWindowDeleteCommand is the CLI command handler for 'mcloudctl maintenance window delete'.
+// Sends DELETE /cluster/maintenance; a rollout in progress keeps running.
+//
+// CLI Usage:
+//   mcloudctl maintenance window delete
+func MaintenanceWindowDeleteCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	if err := client.do(ctx, http.MethodDelete, "/cluster/maintenance", nil, nil); err != nil {
+		return err
+	}
+	logger.Info("Maintenance window deleted")
+	return nil
+}
+
+// MaintenanceRunCommand is the CLI command handler for 'mcloudctl maintenance run'.
+// Sends POST /cluster/maintenance/run to refresh the snaps now, outside the
+// window; with --wait it follows the operation until every node is done.
+//
+// CLI Usage:
+//   mcloudctl maintenance run [--snaps lxd] [--wait]
+//
+// Example Output:
+//   running      0%  node2: Cordoning
+//   running      8%  node2: Refreshing lxd
+//   running     50%  node1: Cordoning
+//   ...
+//   [INFO] 2026-10-16 10:52:10 Maintenance done: 2 nodes refreshed, 0 skipped
+//
+// Example Output (Error):
+//   Error: MC2602 MaintenanceRunning: maintenance is already running: cluster c1
+func MaintenanceRunCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	var req maintenance.RunRequest
+	if snaps := c.String("snaps"); snaps != "" {
+		req.Snaps = strings.Split(snaps, ",")
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	var op database.Operation
+	if err := client.do(ctx, http.MethodPost, "/cluster/maintenance/run", req, &op); err != nil {
+		return err
+	}
+	if !c.Bool("wait") {
+		logger.Info("Maintenance started, operation %s", op.ID)
+		return nil
+	}
+
+	done, err := waitOperation(ctx, client, op.ID, 0)
+	if err != nil {
+		return err
+	}
+	var result maintenance.Result
+	if len(done.Result) > 0 {
+		if err := json.Unmarshal(done.Result, &result); err != nil {
+			return err
+		}
+	}
+	if err := printResult(c, result, nil); err != nil {
+		return err
+	}
+	logger.Info("Maintenance done: %d nodes refreshed, %d skipped", len(result.Refreshed), len(result.Skipped))
+	return nil
+}
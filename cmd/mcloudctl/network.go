@@ -0,0 +1,125 @@
+package mcloudctl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"mcloud/internal/config"
+	"mcloud/internal/database"
+	"mcloud/internal/network"
+	"mcloud/pkg/logger"
+
+	"github.com/urfave/cli/v2"
+)
+
+// NetworkExposeCommand is the CLI command handler for 'mcloudctl network expose'.
+// Sends POST /network/floating-ips: mcloudd allocates a floating IP from
+// network.external_pool and forwards it to the workload over OVN.
+//
+// CLI Usage:
+//   mcloudctl network expose <workload-id> [--address 203.0.113.20] [--dns-name web]
+//
+// Example Output:
+//   [INFO] 2026-01-02 10:30:45 Exposed workload 550e8400-... at 203.0.113.17 (-> 10.154.12.5), web.apps.example.com
+//
+// Example Output (Error):
+//   Error: MC2203 FloatingIPsExhausted: no free address in the external pool
+func NetworkExposeCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	id := c.Args().First()
+	if id == "" {
+		return usageErrorf("workload id is required")
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	req := network.ExposeRequest{WorkloadID: id, Address: c.String("address"), DNSName: c.String("dns-name")}
+	var exposure network.Exposure
+	if err := client.do(ctx, http.MethodPost, "/network/floating-ips", req, &exposure); err != nil {
+		return err
+	}
+	if err := printResult(c, exposure, nil); err != nil {
+		return err
+	}
+	message := fmt.Sprintf("Exposed workload %s at %s (-> %s)", id, exposure.Address, exposure.TargetAddress)
+	if exposure.FQDN != "" {
+		message += ", " + exposure.FQDN
+	}
+	logger.Info("%s", message)
+	return nil
+}
+
+// NetworkListCommand is the CLI command handler for 'mcloudctl network list'.
+// Fetches GET /network/floating-ips and prints the allocations as a table.
+//
+// CLI Usage:
+//   mcloudctl network list
+//
+// Example Output:
+//   ADDRESS       WORKLOAD      TARGET       NETWORK
+//   203.0.113.17  550e8400-...  10.154.12.5  default
+func NetworkListCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	var items []database.FloatingIP
+	if err := client.do(ctx, http.MethodGet, "/network/floating-ips", nil, &items); err != nil {
+		return err
+	}
+
+	return printResult(c, items, func(tw io.Writer) {
+		fmt.Fprintln(tw, "ADDRESS\tWORKLOAD\tTARGET\tNETWORK")
+		for _, f := range items {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", f.Address, f.WorkloadID, f.TargetAddress, f.Network)
+		}
+	})
+}
+
+// NetworkReleaseCommand is the CLI command handler for 'mcloudctl network release'.
+// Sends DELETE /network/floating-ips/{address}, which removes the forward and
+// any DNS record of the address and returns it to the pool.
+//
+// CLI Usage:
+//   mcloudctl network release <address>
+func NetworkReleaseCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	address := c.Args().First()
+	if address == "" {
+		return usageErrorf("floating ip address is required")
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	if err := client.do(ctx, http.MethodDelete, "/network/floating-ips/"+url.PathEscape(address), nil, nil); err != nil {
+		return err
+	}
+	logger.Info("Released floating IP %s", address)
+	return nil
+}
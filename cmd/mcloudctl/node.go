@@ -0,0 +1,329 @@
+package mcloudctl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"mcloud/internal/agent"
+	"mcloud/internal/command"
+	"mcloud/internal/config"
+	"mcloud/internal/database"
+	"mcloud/internal/node"
+	"mcloud/pkg/logger"
+
+	"github.com/urfave/cli/v2"
+)
+
+// NodeBenchmarkCommand is the CLI command handler for 'mcloudctl node benchmark'.
+// Sends POST /nodes/{id}/benchmark and prints the scores stored as node attributes.
+//
+// CLI Usage:
+//   mcloudctl node benchmark <node-id>
+//
+// Example Output:
+//   CPU:     1843.2 MB/s (sha256, all cores)
+//   Disk:    412.7 MB/s (sequential write)
+//   Network: 112.4 MB/s (manager → node)
+func NodeBenchmarkCommand(c *cli.Context) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
+	defer cancel()
+
+	id := c.Args().First()
+	if id == "" {
+		return usageErrorf("node id is required")
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+	// The benchmark runs for several seconds on the node
+	client.http.Timeout = 3 * time.Minute
+
+	var result agent.BenchmarkResult
+	if err := client.do(ctx, http.MethodPost, "/nodes/"+id+"/benchmark", nil, &result); err != nil {
+		return err
+	}
+
+	return printResult(c, result, func(tw io.Writer) {
+		fmt.Fprintf(tw, "CPU:     %.1f MB/s (sha256, all cores)\n", result.CPUScore)
+		fmt.Fprintf(tw, "Disk:    %.1f MB/s (sequential write)\n", result.DiskWriteMBps)
+		fmt.Fprintf(tw, "Network: %.1f MB/s (manager → node)\n", result.NetworkMBps)
+	})
+}
+
+// NodeRemoveCommand is the CLI command handler for 'mcloudctl node remove'.
+// Sends DELETE /nodes/{id} and prints the outcome of every removal step.
+// Without --force the node must have been drained.
+//
+// CLI Usage:
+//   mcloudctl node remove <node-id> [--force]
+//
+// Example Output:
+//   lxd       done
+//   ceph      done    removed OSDs [4 5]
+//   ovn       done
+//   database  done    certificates revoked, rows deleted
+//   Node node3 removed
+//
+// Example Output (Error):
+//   Error: MC1107 NodeRemoveRefused: node node3 still has workloads web-1; drain it first or use force
+func NodeRemoveCommand(c *cli.Context) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 31*time.Minute)
+	defer cancel()
+
+	id := c.Args().First()
+	if id == "" {
+		return usageErrorf("node id is required")
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+	// Removing OSDs waits for Ceph to move their data
+	client.http.Timeout = 31 * time.Minute
+
+	path := "/nodes/" + id
+	if c.Bool("force") {
+		path += "?force=true"
+	}
+	var result node.RemoveResult
+	if err := client.do(ctx, http.MethodDelete, path, nil, &result); err != nil {
+		return err
+	}
+
+	return printResult(c, result, func(tw io.Writer) {
+		for _, step := range result.Steps {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", step.Name, step.Status, step.Detail)
+		}
+		fmt.Fprintf(tw, "Node %s removed\n", result.Hostname)
+		if len(result.DetachedWorkloads) > 0 {
+			fmt.Fprintf(tw, "Workloads marked failed: %s\n", strings.Join(result.DetachedWorkloads, ", "))
+		}
+	})
+}
+
+// NodeLabelCommand is the CLI command handler for 'mcloudctl node label'.
+// Sends PUT /nodes/{id}/labels/{key} for every KEY=VALUE and DELETE
+// /nodes/{id}/labels/{key} for every KEY-, then prints the node's labels and
+// taints from GET /nodes/{id}/labels. Without changes it only prints them.
+//
+// CLI Usage:
+//   mcloudctl node label <node-id> [KEY=VALUE ...] [KEY- ...]
+//
+// Example Output:
+//   KIND   KEY   VALUE  EFFECT
+//   label  disk  ssd
+//   label  zone  a
+//   taint  gpu   true   NoSchedule
+func NodeLabelCommand(c *cli.Context) error {
+	return editNodeLabels(c, "labels", func(arg string) (string, any, error) {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok || key == "" {
+			return "", nil, usageErrorf("invalid label %q, expected KEY=VALUE or KEY-", arg)
+		}
+		return key, node.SetLabelRequest{Value: value}, nil
+	})
+}
+
+// NodeTaintCommand is the CLI command handler for 'mcloudctl node taint'.
+// Sends PUT /nodes/{id}/taints/{key} for every KEY[=VALUE][:EFFECT] (the
+// effect defaults to NoSchedule) and DELETE /nodes/{id}/taints/{key} for
+// every KEY-, then prints the node's labels and taints. Workloads already on
+// the node stay there.
+//
+// CLI Usage:
+//   mcloudctl node taint <node-id> [KEY[=VALUE][:NoSchedule|PreferNoSchedule] ...] [KEY- ...]
+//
+// Example Output:
+//   KIND   KEY   VALUE  EFFECT
+//   label  zone  a
+//   taint  gpu   true   NoSchedule
+//   taint  spot         PreferNoSchedule
+func NodeTaintCommand(c *cli.Context) error {
+	return editNodeLabels(c, "taints", func(arg string) (string, any, error) {
+		taint, effect, _ := strings.Cut(arg, ":")
+		key, value, _ := strings.Cut(taint, "=")
+		if key == "" {
+			return "", nil, usageErrorf("invalid taint %q, expected KEY[=VALUE][:EFFECT] or KEY-", arg)
+		}
+		return key, node.SetTaintRequest{Value: value, Effect: effect}, nil
+	})
+}
+
+// editNodeLabels applies the KEY- removals and the settings given after the
+// node ID to the node's labels or taints (kind, as in the API path), then
+// prints all of the node's labels and taints. parse turns a setting into its
+// key and request body.
+func editNodeLabels(c *cli.Context, kind string, parse func(arg string) (string, any, error)) error {
+	ctx := context.Background()
+
+	id := c.Args().First()
+	if id == "" {
+		return usageErrorf("node id is required")
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	for _, arg := range c.Args().Tail() {
+		if key, ok := strings.CutSuffix(arg, "-"); ok && !strings.ContainsAny(arg, "=:") {
+			if err := client.do(ctx, http.MethodDelete, "/nodes/"+id+"/"+kind+"/"+url.PathEscape(key), nil, nil); err != nil {
+				return err
+			}
+			continue
+		}
+		key, req, err := parse(arg)
+		if err != nil {
+			return err
+		}
+		if err := client.do(ctx, http.MethodPut, "/nodes/"+id+"/"+kind+"/"+url.PathEscape(key), req, nil); err != nil {
+			return err
+		}
+	}
+
+	var labels node.Labels
+	if err := client.do(ctx, http.MethodGet, "/nodes/"+id+"/labels", nil, &labels); err != nil {
+		return err
+	}
+	return printResult(c, labels, func(tw io.Writer) {
+		fmt.Fprintln(tw, "KIND\tKEY\tVALUE\tEFFECT")
+		for _, k := range slices.Sorted(maps.Keys(labels.Labels)) {
+			fmt.Fprintf(tw, "label\t%s\t%s\t\n", k, labels.Labels[k])
+		}
+		for _, t := range labels.Taints {
+			fmt.Fprintf(tw, "taint\t%s\t%s\t%s\n", t.Key, t.Value, t.Effect)
+		}
+	})
+}
+
+// NodeAgentsCommand is the CLI command handler for 'mcloudctl node agents'.
+// Fetches GET /agents and prints the agents with their command stream open.
+//
+// CLI Usage:
+//   mcloudctl node agents
+//
+// Example Output:
+//   NODE   CONNECTED             RUNNING
+//   node2  2026-10-16T09:00:00Z  1
+func NodeAgentsCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	var agents []command.Agent
+	if err := client.do(ctx, http.MethodGet, "/agents", nil, &agents); err != nil {
+		return err
+	}
+
+	return printResult(c, agents, func(tw io.Writer) {
+		fmt.Fprintln(tw, "NODE\tCONNECTED\tRUNNING")
+		for _, a := range agents {
+			fmt.Fprintf(tw, "%s\t%s\t%d\n", a.Node, a.ConnectedAt.Format(time.RFC3339), a.Running)
+		}
+	})
+}
+
+// NodeCommandCommand is the CLI command handler for 'mcloudctl node command'.
+// Sends POST /nodes/{id}/commands, which pushes the command down the node
+// agent's command stream, and waits for its result unless --no-wait is set.
+// The result is printed as JSON; collect_logs and task print the output.
+//
+// CLI Usage:
+//   mcloudctl node command <node-id> preflight
+//   mcloudctl node command <node-id> restart_service --service lxd
+//   mcloudctl node command <node-id> collect_logs --service microceph
+//   mcloudctl node command <node-id> apply_config --payload '{"timesync": {"servers": ["ntp.example.com"]}}'
+//   mcloudctl node command <node-id> task --task ceph_status
+//
+// Example Output:
+//   running     50%  Running on node2
+//   {"service":"lxd","healthy_before":false,"healthy_after":true,"output":"lxd restarted"}
+func NodeCommandCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	id, cmdType := c.Args().Get(0), c.Args().Get(1)
+	if id == "" || cmdType == "" {
+		return usageErrorf("node id and command type are required")
+	}
+	req := command.Request{Type: cmdType}
+	switch {
+	case c.String("payload") != "":
+		req.Payload = json.RawMessage(c.String("payload"))
+	case c.String("task") != "":
+		req.Payload, _ = json.Marshal(command.TaskPayload{Task: c.String("task")})
+	case c.String("service") != "":
+		req.Payload, _ = json.Marshal(agent.ServiceCommand{Service: c.String("service")})
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	var op database.Operation
+	if err := client.do(ctx, http.MethodPost, "/nodes/"+id+"/commands", req, &op); err != nil {
+		return err
+	}
+	if c.Bool("no-wait") {
+		if err := printResult(c, op, nil); err != nil {
+			return err
+		}
+		logger.Info("Command %s sent, operation %s", cmdType, op.ID)
+		return nil
+	}
+
+	done, err := waitOperation(ctx, client, op.ID, 0)
+	if err != nil {
+		return err
+	}
+	if outputFormat(c) != outputTable {
+		return printResult(c, done.Result, nil)
+	}
+	switch cmdType {
+	case command.TypeCollectLogs, command.TypeTask:
+		var out struct {
+			Output string `json:"output"` // agent.LogsResult, agent.TaskResult
+		}
+		if err := json.Unmarshal(done.Result, &out); err == nil {
+			fmt.Print(out.Output)
+			return nil
+		}
+	}
+	fmt.Println(string(done.Result))
+	return nil
+}
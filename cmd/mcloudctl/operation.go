@@ -0,0 +1,182 @@
+package mcloudctl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"mcloud/internal/config"
+	"mcloud/internal/database"
+	"mcloud/internal/operation"
+	"mcloud/pkg/logger"
+
+	"github.com/urfave/cli/v2"
+)
+
+// operationPollInterval is how often waitOperation polls GET /operations/{id}.
+const operationPollInterval = 2 * time.Second
+
+// waitOperation polls an operation until it finishes or timeout passes (0 = no limit),
+// printing each progress change to stderr. It returns the final operation, or an error if it failed.
+func waitOperation(ctx context.Context, client *apiClient, id string, timeout time.Duration) (*database.Operation, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	lastMessage := ""
+	for {
+		var op database.Operation
+		if err := client.do(ctx, http.MethodGet, "/operations/"+id, nil, &op); err != nil {
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("timed out waiting for operation %s", id)
+			}
+			return nil, err
+		}
+
+		if op.Message != nil && *op.Message != lastMessage {
+			lastMessage = *op.Message
+			fmt.Fprintf(os.Stderr, "%-10s %3d%%  %s\n", op.Status, op.Progress, lastMessage)
+		}
+
+		switch op.Status {
+		case operation.StatusSucceeded:
+			return &op, nil
+		case operation.StatusFailed:
+			if op.Error != nil {
+				return &op, fmt.Errorf("operation %s failed: %s", op.ID, *op.Error)
+			}
+			return &op, fmt.Errorf("operation %s failed", op.ID)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("timed out waiting for operation %s", id)
+		case <-time.After(operationPollInterval):
+		}
+	}
+}
+
+// OperationWaitCommand is the CLI command handler for 'mcloudctl operation wait'.
+// Polls GET /operations/{id} until the operation succeeds or fails.
+//
+// CLI Usage:
+//   mcloudctl operation wait <operation-id> [--timeout 10m]
+//
+// Example Output:
+//   running     10%  Launching instance web-1
+//   running     90%  Recording workload status
+//   Operation 7f1c... succeeded
+func OperationWaitCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	id := c.Args().First()
+	if id == "" {
+		return usageErrorf("operation id is required")
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	op, err := waitOperation(ctx, client, id, c.Duration("timeout"))
+	if err != nil {
+		return err
+	}
+	if err := printResult(c, op, nil); err != nil {
+		return err
+	}
+	logger.Info("Operation %s succeeded", op.ID)
+	return nil
+}
+
+// OperationShowCommand is the CLI command handler for 'mcloudctl operation show'.
+// Fetches GET /operations/{id} and prints it as JSON.
+//
+// CLI Usage:
+//   mcloudctl operation show <operation-id>
+func OperationShowCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	id := c.Args().First()
+	if id == "" {
+		return usageErrorf("operation id is required")
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	var op database.Operation
+	if err := client.do(ctx, http.MethodGet, "/operations/"+id, nil, &op); err != nil {
+		return err
+	}
+
+	return printResult(c, op, func(tw io.Writer) {
+		enc := json.NewEncoder(tw)
+		enc.SetIndent("", "  ")
+		enc.Encode(op)
+	})
+}
+
+// OperationListCommand is the CLI command handler for 'mcloudctl operation list'.
+// Fetches GET /operations and prints the operations as a table, newest first.
+//
+// CLI Usage:
+//   mcloudctl operation list [--status running] [--limit 20]
+//
+// Example Output:
+//   ID                                    TYPE             STATUS     PROGRESS  CREATED              MESSAGE
+//   7f1c0e2a-9a4b-4f0e-8a39-3c1d2e4f5a6b  workload.create  succeeded  100%      2026-01-03 10:30:45  Recording workload status
+func OperationListCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{"limit": {strconv.Itoa(c.Int("limit"))}}
+	if status := c.String("status"); status != "" {
+		query.Set("status", status)
+	}
+	var resp operation.ListOperationsResponse
+	if err := client.do(ctx, http.MethodGet, "/operations?"+query.Encode(), nil, &resp); err != nil {
+		return err
+	}
+
+	return printResult(c, resp, func(tw io.Writer) {
+		fmt.Fprintln(tw, "ID\tTYPE\tSTATUS\tPROGRESS\tCREATED\tMESSAGE")
+		for _, op := range resp.Items {
+			message := "-"
+			if op.Error != nil {
+				message = *op.Error
+			} else if op.Message != nil {
+				message = *op.Message
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%d%%\t%s\t%s\n", op.ID, op.Type, op.Status, op.Progress,
+				op.CreatedAt.Local().Format(time.DateTime), message)
+		}
+	})
+}
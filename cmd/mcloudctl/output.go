@@ -0,0 +1,221 @@
+package mcloudctl
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"text/tabwriter"
+
+	"mcloud/pkg/client"
+	"mcloud/pkg/logger"
+	"mcloud/pkg/reason"
+
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Formats of the global --output flag.
+const (
+	outputTable = "table" // human-readable tables (default)
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+var outputFormats = []string{outputTable, outputJSON, outputYAML}
+
+// Exit codes of mcloudctl, so scripts can tell failures apart. Commands that
+// mirror another status (exec, diff) exit with their own code.
+const (
+	exitFailure  = 1 // any other error
+	exitUsage    = 2 // bad flags or arguments
+	exitNotFound = 3 // the server answered 404
+	exitServer   = 4 // the server failed (5xx) or could not be reached
+)
+
+// outputFlag is added to the app and to every command, so --output can be
+// given before or after the command name. It has no value unless given, so
+// outputFormat can find where it was.
+func outputFlag() cli.Flag {
+	return &cli.StringFlag{
+		Name:        "output",
+		Aliases:     []string{"o"},
+		Usage:       "Output format: table, json or yaml",
+		DefaultText: outputTable,
+	}
+}
+
+// outputFormat returns the --output given to the command or any command
+// above it, table if none.
+func outputFormat(c *cli.Context) string {
+	if c.Bool("json") { // older flag of preflight
+		return outputJSON
+	}
+	for _, ctx := range c.Lineage() {
+		if format := ctx.String("output"); format != "" {
+			return format
+		}
+	}
+	return outputTable
+}
+
+// invocation is what main learns of the command that ran, to report its
+// error (see setupOutput).
+type invocation struct {
+	ran    bool   // the action started; urfave/cli rejects bad flags before
+	format string // --output
+}
+
+// setupOutput adds --output to the app and every command. Before an action
+// runs, the format is checked and recorded in inv; for json and yaml, info
+// logs are silenced so stdout carries only the document.
+func setupOutput(app *cli.App, inv *invocation) {
+	app.Flags = append(app.Flags, outputFlag())
+	app.Before = func(c *cli.Context) error {
+		inv.format = outputFormat(c)
+		return nil
+	}
+	setupCommands(app.Commands, inv)
+}
+
+func setupCommands(cmds []*cli.Command, inv *invocation) {
+	for _, cmd := range cmds {
+		cmd.Flags = append(cmd.Flags, outputFlag())
+		if len(cmd.Subcommands) > 0 {
+			setupCommands(cmd.Subcommands, inv)
+			continue
+		}
+		cmd.Before = func(c *cli.Context) error {
+			inv.ran, inv.format = true, outputFormat(c)
+			if !slices.Contains(outputFormats, inv.format) {
+				return usageErrorf("--output must be one of table, json, yaml (got %q)", inv.format)
+			}
+			if inv.format != outputTable {
+				logger.SetLevel(logger.LevelWarn)
+			}
+			return nil
+		}
+	}
+}
+
+// printResult prints v as JSON or YAML when --output asks for it, else calls
+// table to write the human-readable form. table writes to a tabwriter, so
+// tab-separated columns line up; commands that only log what they did pass
+// nil.
+//
+// Example Output (--output json):
+//   [
+//     {"id": "n1", "hostname": "node1", ...}
+//   ]
+func printResult(c *cli.Context, v any, table func(w io.Writer)) error {
+	switch outputFormat(c) {
+	case outputJSON:
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case outputYAML:
+		return writeYAML(os.Stdout, v)
+	}
+	if table == nil {
+		return nil
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	table(tw)
+	return tw.Flush()
+}
+
+// printDocument prints v as one document of a stream, for commands that
+// follow: a line of JSON, or a YAML document led by ---.
+func printDocument(c *cli.Context, v any) error {
+	if outputFormat(c) == outputYAML {
+		fmt.Println("---")
+		return writeYAML(os.Stdout, v)
+	}
+	return json.NewEncoder(os.Stdout).Encode(v)
+}
+
+// writeYAML writes v as YAML with the field names of its JSON encoding, which
+// the API types define; key order is kept.
+func writeYAML(w io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	blockStyle(&doc)
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// blockStyle drops the flow style JSON parses into, so the YAML reads as YAML.
+func blockStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, child := range n.Content {
+		blockStyle(child)
+	}
+}
+
+// usageError is an error in the flags or arguments of a command.
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string { return e.msg }
+
+func usageErrorf(format string, args ...any) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+// exitCode maps the error a command failed with to the exit code of mcloudctl.
+// ran tells whether the command's action started.
+func exitCode(err error, ran bool) int {
+	var apiErr *client.APIError
+	var urlErr *url.Error
+	var usageErr *usageError
+	switch {
+	case !ran, errors.As(err, &usageErr):
+		return exitUsage
+	case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound:
+		return exitNotFound
+	case errors.As(err, &apiErr) && apiErr.StatusCode >= 500, errors.As(err, &urlErr):
+		return exitServer
+	}
+	return exitFailure
+}
+
+// errorOutput is how an error is printed with --output json or yaml.
+//
+// Example JSON:
+//   {"code": "MC1200", "reason": "WorkloadNotFound", "message": "GET /workloads/abc: 404 Not Found: workload not found", "exit_code": 3}
+type errorOutput struct {
+	Code     reason.Code `json:"code"`
+	Reason   string      `json:"reason"`
+	Message  string      `json:"message"`
+	ExitCode int         `json:"exit_code"`
+}
+
+// printError prints err to stderr in the format of --output: a line led by
+// the reason code for tables, else a structured errorOutput.
+func printError(format string, err error, code int) {
+	rc := reason.CodeOf(err, reason.Internal)
+	out := &errorOutput{Code: rc, Reason: rc.Name(), Message: err.Error(), ExitCode: code}
+	switch format {
+	case outputJSON:
+		json.NewEncoder(os.Stderr).Encode(out)
+	case outputYAML:
+		writeYAML(os.Stderr, out)
+	default:
+		logger.Error("%s", reason.Format(err))
+	}
+}
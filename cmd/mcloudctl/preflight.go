@@ -0,0 +1,84 @@
+package mcloudctl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"mcloud/internal/installer"
+	"mcloud/internal/preflight"
+	"mcloud/pkg/logger"
+
+	"github.com/urfave/cli/v2"
+)
+
+// PreflightCommand is the CLI command handler for 'mcloudctl preflight'.
+// Checks whether this host can run `mcloudctl init` with the same flags or
+// preseed, without changing anything. Exits non-zero if any check fails.
+// init and join run the same checks first unless --skip-preflight is given.
+//
+// CLI Usage:
+//   mcloudctl preflight [--preseed <file>] [--http-port 9028] [--grpc-port 9030] [--ceph-disk /dev/sdb] [--ceph-loop-count 3] [--output json]
+//
+// Example Output:
+//   CHECK           STATUS  MESSAGE
+//   snap lxd        pass    lxd, lxc found
+//   snap microovn   fail    microovn is not installed (microovn not found)
+//   kernel rbd      warn    module rbd is not loaded
+//   port 8443       pass    free
+//   memory          pass    16022 MB (minimum 4096 MB)
+func PreflightCommand(c *cli.Context) error {
+	opts, err := mergeInitOptions(c)
+	if err != nil {
+		return err
+	}
+
+	report := preflight.Run(context.Background(), preflight.Checks(opts.preflightOptions()))
+
+	if err := printResult(c, report, func(tw io.Writer) { writePreflightReport(tw, report) }); err != nil {
+		return err
+	}
+	return report.Err()
+}
+
+// printPreflightReport prints a preflight report as a table.
+func printPreflightReport(report *preflight.Report) {
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	writePreflightReport(tw, report)
+	tw.Flush()
+}
+
+func writePreflightReport(tw io.Writer, report *preflight.Report) {
+	fmt.Fprintln(tw, "CHECK\tSTATUS\tMESSAGE")
+	for _, r := range report.Results {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", r.Check, r.Status, r.Message)
+	}
+}
+
+// runPreflight runs the preflight checks before init or join changes anything.
+// With installDeps, missing snaps are installed from their pinned channels and
+// the checks run again. Failures are printed as a table.
+func runPreflight(ctx context.Context, opts preflight.Options, snapChannels map[string]string, installDeps bool) error {
+	checks := preflight.Checks(opts)
+	report := preflight.Run(ctx, checks)
+
+	// Install missing snaps and check again
+	if missing := report.MissingSnaps(); len(missing) > 0 && installDeps {
+		if err := installer.InstallSnaps(ctx, missing, snapChannels, logger.Info); err != nil {
+			return err
+		}
+		report = preflight.Run(ctx, checks)
+	}
+
+	if err := report.Err(); err != nil {
+		printPreflightReport(report)
+		if missing := report.MissingSnaps(); len(missing) > 0 && !installDeps {
+			logger.Info("Re-run with --install-deps to install %s", strings.Join(missing, ", "))
+		}
+		return err
+	}
+	logger.Info("Preflight checks passed")
+	return nil
+}
@@ -0,0 +1,232 @@
+package mcloudctl
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"mcloud/internal/cluster"
+	"mcloud/internal/installer"
+	"mcloud/pkg/logger"
+)
+
+// spinnerInterval is how often the spinner of a running step turns.
+const spinnerInterval = 100 * time.Millisecond
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// stdout is where init and join print tables such as the preflight report;
+// while a progress display runs they are printed above its status line.
+var stdout io.Writer = os.Stdout
+
+// progress renders the steps of init and join. On a terminal the running step
+// is a spinner that turns into a checkmark (or a cross) when it ends, and log
+// lines are printed above it; otherwise every step is logged as it starts and
+// ends. Close must be called when the command is done.
+//
+// Example Output (Terminal):
+//   ✔ Running preflight checks (1.2s)
+//   ✔ [1/8] Generating certificates (0.4s)
+//   ⠹ [3/8] Bootstrapping LXD
+//
+// Example Output (Non-Terminal):
+//   [INFO] 2026-01-02 10:30:45 [3/8] Bootstrapping LXD...
+//   [INFO] 2026-01-02 10:30:47 [3/8] Bootstrapping LXD: done
+type progress struct {
+	out io.Writer
+	tty bool
+
+	mu      sync.Mutex
+	title   string // running step, "" between steps
+	started time.Time
+	frame   int
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func newProgress() *progress {
+	p := &progress{out: os.Stdout, tty: isTerminal(os.Stdout)}
+	if p.tty {
+		p.stop, p.done = make(chan struct{}), make(chan struct{})
+		stdout = &progressWriter{p: p, w: os.Stdout}
+		logger.SetOutput(stdout, &progressWriter{p: p, w: os.Stderr})
+		installer.SetOutput(stdout)
+		go p.spin()
+	}
+	return p
+}
+
+// Close stops the spinner and hands the terminal back to the logger.
+func (p *progress) Close() {
+	if !p.tty {
+		return
+	}
+	close(p.stop)
+	<-p.done
+	p.mu.Lock()
+	p.clear()
+	p.mu.Unlock()
+	stdout = os.Stdout
+	logger.SetOutput(os.Stdout, os.Stderr)
+	installer.SetOutput(os.Stdout)
+}
+
+func (p *progress) spin() {
+	defer close(p.done)
+	ticker := time.NewTicker(spinnerInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.mu.Lock()
+			p.frame++
+			p.draw()
+			p.mu.Unlock()
+		}
+	}
+}
+
+// draw and clear redraw and erase the status line; p.mu must be held.
+func (p *progress) draw() {
+	if p.tty && p.title != "" {
+		fmt.Fprintf(p.out, "\r\033[K%s %s", spinnerFrames[p.frame%len(spinnerFrames)], p.title)
+	}
+}
+
+func (p *progress) clear() {
+	if p.tty && p.title != "" {
+		fmt.Fprint(p.out, "\r\033[K")
+	}
+}
+
+// Start shows title as the running step.
+func (p *progress) Start(title string) {
+	p.mu.Lock()
+	p.clear()
+	p.title, p.started = title, time.Now()
+	p.draw()
+	p.mu.Unlock()
+	if !p.tty {
+		logger.Info("%s...", title)
+	}
+}
+
+// Done marks the running step finished.
+func (p *progress) Done() {
+	p.end(nil)
+}
+
+// Fail marks the running step failed with err.
+func (p *progress) Fail(err error) {
+	p.end(err)
+}
+
+func (p *progress) end(err error) {
+	p.mu.Lock()
+	title := p.title
+	p.clear()
+	p.title = ""
+	if p.tty {
+		if err != nil {
+			fmt.Fprintf(p.out, "✖ %s: %v\n", title, err)
+		} else {
+			fmt.Fprintf(p.out, "✔ %s (%s)\n", title, time.Since(p.started).Round(100*time.Millisecond))
+		}
+	}
+	p.mu.Unlock()
+
+	switch {
+	case p.tty:
+	case err != nil:
+		logger.Error("%s: failed: %v", title, err)
+	default:
+		logger.Info("%s: done", title)
+	}
+}
+
+// Note prints a step that did not run as one, e.g. one skipped on resume.
+func (p *progress) Note(mark, title, detail string) {
+	if !p.tty {
+		logger.Info("%s: %s", title, detail)
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clear()
+	fmt.Fprintf(p.out, "%s %s (%s)\n", mark, title, detail)
+	p.draw()
+}
+
+// Step runs fn as a step titled title.
+func (p *progress) Step(title string, fn func() error) error {
+	p.Start(title)
+	if err := fn(); err != nil {
+		p.Fail(err)
+		return err
+	}
+	p.Done()
+	return nil
+}
+
+func (p *progress) running() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.title
+}
+
+// Pipeline reports the progress of a cluster.Pipeline, titling its steps with
+// titles (the step name if missing).
+func (p *progress) Pipeline(titles map[string]string) func(cluster.Progress) {
+	return func(pr cluster.Progress) {
+		title := titles[pr.Step]
+		if title == "" {
+			title = pr.Step
+		}
+		title = fmt.Sprintf("[%d/%d] %s", pr.Index, pr.Total, title)
+		switch pr.Status {
+		case cluster.StepRunning:
+			p.Start(title)
+		case cluster.StepDone:
+			p.Done()
+		case cluster.StepFailed:
+			// A failed rollback is reported without a running step
+			if p.running() == "" {
+				p.Start(title)
+			}
+			p.Fail(pr.Err)
+		case cluster.StepSkipped:
+			p.Note("–", title, "already done")
+		case cluster.StepRolledBack:
+			p.Note("↩", title, "rolled back")
+		}
+	}
+}
+
+// progressWriter prints lines written to w above the status line of p,
+// holding back a partial line until it is complete.
+type progressWriter struct {
+	p       *progress
+	w       io.Writer
+	partial []byte
+}
+
+func (pw *progressWriter) Write(b []byte) (int, error) {
+	pw.p.mu.Lock()
+	defer pw.p.mu.Unlock()
+	pw.partial = append(pw.partial, b...)
+	i := bytes.LastIndexByte(pw.partial, '\n')
+	if i < 0 {
+		return len(b), nil
+	}
+	pw.p.clear()
+	_, err := pw.w.Write(pw.partial[:i+1])
+	pw.partial = append(pw.partial[:0], pw.partial[i+1:]...)
+	pw.p.draw()
+	return len(b), err
+}
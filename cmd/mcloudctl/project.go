@@ -0,0 +1,341 @@
+package mcloudctl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"mcloud/internal/config"
+	"mcloud/internal/database"
+	"mcloud/internal/project"
+	"mcloud/pkg/logger"
+
+	"github.com/urfave/cli/v2"
+)
+
+// ProjectCreateCommand is the CLI command handler for 'mcloudctl project create'.
+// Sends POST /projects to mcloudd using the local client certificate. Limits
+// that are not given are unlimited.
+//
+// CLI Usage:
+//   mcloudctl project create <name> [--description "Team A"] [--cpus 16] [--memory-mb 32768]
+//     [--instances 10] [--disk-gb 500]
+//
+// Example Output:
+//   [INFO] 2026-01-02 10:30:45 Created project team-a (16 CPUs, unlimited memory, 10 instances, unlimited disk)
+//
+// Example Output (Error):
+//   Error: MC2501 ProjectExists: a project with this name already exists: team-a
+func ProjectCreateCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	name := c.Args().First()
+	if name == "" {
+		return usageErrorf("project name is required")
+	}
+	req := project.CreateRequest{Name: name, Description: c.String("description")}
+	if err := quotaFromFlags(c, &req.Quota); err != nil {
+		return err
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	var p project.ProjectDetail
+	if err := client.do(ctx, http.MethodPost, "/projects", req, &p); err != nil {
+		return err
+	}
+	if err := printResult(c, p, nil); err != nil {
+		return err
+	}
+	logger.Info("Created project %s (%s CPUs, %s memory, %s instances, %s disk)", p.Name,
+		formatLimit(p.MaxCPUs, ""), formatLimit(p.MaxMemoryMB, "MiB"), formatLimit(p.MaxInstances, ""), formatLimit(p.MaxDiskGB, "GiB"))
+	return nil
+}
+
+// ProjectListCommand is the CLI command handler for 'mcloudctl project list'.
+// Fetches GET /projects and prints each project's usage against its quota.
+//
+// CLI Usage:
+//   mcloudctl project list
+//
+// Example Output:
+//   NAME     CPUS     MEMORY                INSTANCES  DISK
+//   default  6/-      12288MiB/-            4/-        80GiB/-
+//   team-a   2/16     4096MiB/32768MiB      1/10       20GiB/-
+func ProjectListCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	var projects []project.ProjectDetail
+	if err := client.do(ctx, http.MethodGet, "/projects", nil, &projects); err != nil {
+		return err
+	}
+
+	return printResult(c, projects, func(tw io.Writer) {
+		fmt.Fprintln(tw, "NAME\tCPUS\tMEMORY\tINSTANCES\tDISK")
+		for _, p := range projects {
+			fmt.Fprintf(tw, "%s\t%d/%s\t%dMiB/%s\t%d/%s\t%dGiB/%s\n", p.Name,
+				p.Usage.CPUs, formatQuota(p.MaxCPUs, ""),
+				p.Usage.MemoryMB, formatQuota(p.MaxMemoryMB, "MiB"),
+				p.Usage.Instances, formatQuota(p.MaxInstances, ""),
+				p.Usage.DiskGB, formatQuota(p.MaxDiskGB, "GiB"))
+		}
+	})
+}
+
+// ProjectQuotaSetCommand is the CLI command handler for 'mcloudctl project quota set'.
+// Fetches GET /projects/{name}, applies the given limits and sends the result
+// with PUT /projects/{name}/quota, so limits that are not given are kept.
+//
+// CLI Usage:
+//   mcloudctl project quota set <name> [--cpus 16|unlimited] [--memory-mb 32768|unlimited]
+//     [--instances 10|unlimited] [--disk-gb 500|unlimited]
+//
+// Example Output:
+//   [INFO] 2026-01-02 10:30:45 Set quota of project team-a: 32 CPUs, 32768MiB memory, 10 instances, unlimited disk
+//
+// Example Output (Error):
+//   Error: MC2500 ProjectNotFound: project not found: team-b
+func ProjectQuotaSetCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	name := c.Args().First()
+	if name == "" {
+		return usageErrorf("project name is required")
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	path := "/projects/" + url.PathEscape(name)
+	var p project.ProjectDetail
+	if err := client.do(ctx, http.MethodGet, path, nil, &p); err != nil {
+		return err
+	}
+	q := project.Quota{CPUs: p.MaxCPUs, MemoryMB: p.MaxMemoryMB, Instances: p.MaxInstances, DiskGB: p.MaxDiskGB}
+	if err := quotaFromFlags(c, &q); err != nil {
+		return err
+	}
+
+	if err := client.do(ctx, http.MethodPut, path+"/quota", q, &p); err != nil {
+		return err
+	}
+	if err := printResult(c, p, nil); err != nil {
+		return err
+	}
+	logger.Info("Set quota of project %s: %s CPUs, %s memory, %s instances, %s disk", p.Name,
+		formatLimit(p.MaxCPUs, ""), formatLimit(p.MaxMemoryMB, "MiB"), formatLimit(p.MaxInstances, ""), formatLimit(p.MaxDiskGB, "GiB"))
+	return nil
+}
+
+// ProjectDeleteCommand is the CLI command handler for 'mcloudctl project delete'.
+// Sends DELETE /projects/{name}; projects with workloads cannot be deleted.
+//
+// CLI Usage:
+//   mcloudctl project delete <name>
+//
+// Example Output (Error):
+//   Error: MC2502 ProjectInUse: project is in use: team-a has 3 workloads
+func ProjectDeleteCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	name := c.Args().First()
+	if name == "" {
+		return usageErrorf("project name is required")
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	if err := client.do(ctx, http.MethodDelete, "/projects/"+url.PathEscape(name), nil, nil); err != nil {
+		return err
+	}
+	logger.Info("Deleted project %s", name)
+	return nil
+}
+
+// ProjectBindingsCommand is the CLI command handler for 'mcloudctl project bindings'.
+// Fetches GET /projects/{name}/bindings. A project without bindings is open
+// to every client.
+//
+// CLI Usage:
+//   mcloudctl project bindings <name>
+//
+// Example Output:
+//   IDENTITY   ROLE    CREATED
+//   alice      admin   2026-01-02T10:30:45Z
+//   ci-runner  member  2026-01-03T08:12:00Z
+func ProjectBindingsCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	name := c.Args().First()
+	if name == "" {
+		return usageErrorf("project name is required")
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	var bindings []database.ProjectBinding
+	if err := client.do(ctx, http.MethodGet, "/projects/"+url.PathEscape(name)+"/bindings", nil, &bindings); err != nil {
+		return err
+	}
+
+	return printResult(c, bindings, func(tw io.Writer) {
+		fmt.Fprintln(tw, "IDENTITY\tROLE\tCREATED")
+		for _, b := range bindings {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", b.Identity, b.Role, b.CreatedAt.Format(time.RFC3339))
+		}
+	})
+}
+
+// ProjectBindCommand is the CLI command handler for 'mcloudctl project bind'.
+// Sends PUT /projects/{name}/bindings/{identity}; the identity is the common
+// name of a client certificate. Only admins of the project may change its
+// bindings.
+//
+// CLI Usage:
+//   mcloudctl project bind <name> <identity> [--role member|admin]
+//
+// Example Output:
+//   [INFO] 2026-01-02 10:30:45 Bound ci-runner to project team-a as member
+//
+// Example Output (Error):
+//   Error: MC2505 ProjectDenied: not allowed in this project: ci-runner is member of team-a, admin required
+func ProjectBindCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	name, identity := c.Args().Get(0), c.Args().Get(1)
+	if name == "" || identity == "" {
+		return usageErrorf("project name and identity are required")
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	var b database.ProjectBinding
+	path := "/projects/" + url.PathEscape(name) + "/bindings/" + url.PathEscape(identity)
+	if err := client.do(ctx, http.MethodPut, path, project.BindingRequest{Role: c.String("role")}, &b); err != nil {
+		return err
+	}
+	if err := printResult(c, b, nil); err != nil {
+		return err
+	}
+	logger.Info("Bound %s to project %s as %s", b.Identity, b.Project, b.Role)
+	return nil
+}
+
+// ProjectUnbindCommand is the CLI command handler for 'mcloudctl project unbind'.
+// Sends DELETE /projects/{name}/bindings/{identity}.
+//
+// CLI Usage:
+//   mcloudctl project unbind <name> <identity>
+//
+// Example Output:
+//   [INFO] 2026-01-02 10:30:45 Unbound ci-runner from project team-a
+func ProjectUnbindCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	name, identity := c.Args().Get(0), c.Args().Get(1)
+	if name == "" || identity == "" {
+		return usageErrorf("project name and identity are required")
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	path := "/projects/" + url.PathEscape(name) + "/bindings/" + url.PathEscape(identity)
+	if err := client.do(ctx, http.MethodDelete, path, nil, nil); err != nil {
+		return err
+	}
+	logger.Info("Unbound %s from project %s", identity, name)
+	return nil
+}
+
+// quotaFromFlags overwrites the limits of q given with --cpus, --memory-mb,
+// --instances and --disk-gb; "unlimited" clears a limit.
+func quotaFromFlags(c *cli.Context, q *project.Quota) error {
+	for flag, limit := range map[string]**int{
+		"cpus": &q.CPUs, "memory-mb": &q.MemoryMB, "instances": &q.Instances, "disk-gb": &q.DiskGB,
+	} {
+		if !c.IsSet(flag) {
+			continue
+		}
+		v := c.String(flag)
+		if v == "unlimited" {
+			*limit = nil
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return usageErrorf("--%s must be a non-negative number or unlimited, got %q", flag, v)
+		}
+		*limit = &n
+	}
+	return nil
+}
+
+// formatLimit renders a quota limit for messages, e.g. "16", "32768MiB" or "unlimited".
+func formatLimit(limit *int, unit string) string {
+	if limit == nil {
+		return "unlimited"
+	}
+	return strconv.Itoa(*limit) + unit
+}
+
+// formatQuota renders a quota limit for tables, "-" when unlimited.
+func formatQuota(limit *int, unit string) string {
+	if limit == nil {
+		return "-"
+	}
+	return strconv.Itoa(*limit) + unit
+}
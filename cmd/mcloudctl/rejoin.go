@@ -0,0 +1,208 @@
+package mcloudctl
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"mcloud/internal/config"
+	"mcloud/internal/constant"
+	"mcloud/internal/node"
+	"mcloud/internal/state"
+	"mcloud/pkg/logger"
+	"mcloud/pkg/utils"
+
+	"github.com/urfave/cli/v2"
+)
+
+// writeRejoinBundle issues this node's re-join credential and saves it, with the
+// manager address and certificates, as the node's encrypted re-join bundle.
+//
+// Example Output:
+//   Console log: "Wrote re-join bundle to /etc/mcloud/rejoin.bundle"
+func writeRejoinBundle(ctx context.Context, conn *sql.DB, cfg config.Config, name string, host utils.HostInfo, nodeId string, clusterId string) error {
+	caCert, err := os.ReadFile(cfg.Security.CACertPath)
+	if err != nil {
+		return err
+	}
+	clientCert, err := os.ReadFile(cfg.Security.ClientCertPath)
+	if err != nil {
+		return err
+	}
+	clientKey, err := os.ReadFile(cfg.Security.ClientKeyPath)
+	if err != nil {
+		return err
+	}
+	nodeCert, err := os.ReadFile(cfg.Security.NodeCertPath)
+	if err != nil {
+		return err
+	}
+	nodeKey, err := os.ReadFile(cfg.Security.NodeKeyPath)
+	if err != nil {
+		return err
+	}
+
+	secret, err := node.IssueRejoinSecret(ctx, conn, cfg.Security.SecretsKeyPath, nodeId)
+	if err != nil {
+		return fmt.Errorf("failed to issue re-join credential: %w", err)
+	}
+
+	bundle := &state.RejoinBundle{
+		ClusterID:   clusterId,
+		ClusterName: name,
+		NodeID:      nodeId,
+		Hostname:    host.Hostname,
+		ManagerURL:  cfg.Agent.ManagerURL,
+		CACert:      string(caCert),
+		ClientCert:  string(clientCert),
+		ClientKey:   string(clientKey),
+		NodeCert:    string(nodeCert),
+		NodeKey:     string(nodeKey),
+		Secret:      secret,
+		IssuedAt:    time.Now(),
+	}
+	if err := state.SaveRejoinBundle(constant.DefaultRejoinBundlePath, bundle); err != nil {
+		return err
+	}
+	logger.Info("Wrote re-join bundle to %s", constant.DefaultRejoinBundlePath)
+	return nil
+}
+
+// RejoinCommand is the CLI command handler for 'mcloudctl rejoin'.
+// Re-establishes cluster membership of a node that lost its state file (or
+// certificates) but kept its OS, using the encrypted re-join bundle written when
+// the node was set up. No token has to be minted on the leader.
+//
+// Command Flow:
+//   Step 1: Decrypt the bundle (fails on any other machine) and check the hostname
+//   Step 2: POST /nodes/rejoin signed with the secret (see auth.RejoinMAC); the manager rotates the secret
+//   Step 3: Save the bundle with the new secret
+//   Step 4: Restore missing CA and client certificates from the bundle
+//   Step 5: Rewrite the state file from the node record
+//
+// CLI Usage:
+//   mcloudctl rejoin [--bundle /etc/mcloud/rejoin.bundle] [--force]
+//
+// Example Output:
+//   [INFO] 2026-01-02 10:30:45 Re-joining cluster production-cluster as node2 (660e8400-...)
+//   [INFO] 2026-01-02 10:30:45 Restored /var/lib/mcloud/certs/ca.crt
+//   [INFO] 2026-01-02 10:30:45 Wrote state file to /var/lib/mcloud/state.yaml
+//   [INFO] 2026-01-02 10:30:45 Re-joined cluster production-cluster
+//
+// Example Output (Error - Secret Already Used):
+//   Returns: error("POST /nodes/rejoin: 403 Forbidden: re-join credential rejected")
+func RejoinCommand(c *cli.Context) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if os.Geteuid() != 0 {
+		return fmt.Errorf("must run as root")
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(cfg.StatePath); err == nil && !c.Bool("force") {
+		return fmt.Errorf("state file %s exists; this node has not lost its membership (use --force to rewrite it)", cfg.StatePath)
+	}
+
+	// Step 1: Decrypt the bundle and check it belongs to this host
+	bundle, err := state.LoadRejoinBundle(c.String("bundle"))
+	if err != nil {
+		return err
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return err
+	}
+	if hostname != bundle.Hostname {
+		return fmt.Errorf("re-join bundle is for host %s, not %s", bundle.Hostname, hostname)
+	}
+	logger.Info("Re-joining cluster %s as %s (%s)", bundle.ClusterName, bundle.Hostname, bundle.NodeID)
+
+	// Step 2: Prove membership to the manager
+	client, err := newBundleAPIClient(bundle)
+	if err != nil {
+		return err
+	}
+	req, err := node.SignRejoinRequest(bundle.NodeID, bundle.Hostname, bundle.Secret)
+	if err != nil {
+		return err
+	}
+	var resp node.RejoinResponse
+	if err := client.do(ctx, http.MethodPost, "/nodes/rejoin", req, &resp); err != nil {
+		return err
+	}
+
+	// Step 3: The old secret is spent; keep the new one before anything else can fail
+	bundle.Secret = resp.Secret
+	bundle.ClusterName = resp.ClusterName
+	bundle.IssuedAt = time.Now()
+	if err := state.SaveRejoinBundle(c.String("bundle"), bundle); err != nil {
+		return fmt.Errorf("re-joined, but failed to save the rotated credential: %w", err)
+	}
+
+	// Step 4: Restore certificates that were lost with the node's state
+	files := map[string]string{
+		cfg.Security.CACertPath:     bundle.CACert,
+		cfg.Security.ClientCertPath: bundle.ClientCert,
+		cfg.Security.ClientKeyPath:  bundle.ClientKey,
+	}
+	if bundle.NodeCert != "" && cfg.Security.NodeCertPath != "" {
+		files[cfg.Security.NodeCertPath] = bundle.NodeCert
+		files[cfg.Security.NodeKeyPath] = bundle.NodeKey
+	}
+	for path, pem := range files {
+		if err := restoreFile(path, pem); err != nil {
+			return err
+		}
+	}
+
+	// Step 5: Rebuild the state file from the node record
+	s := state.State{
+		Version: constant.AppVersion,
+		Node: state.Node{
+			ID:            resp.Node.ID,
+			Hostname:      resp.Node.Hostname,
+			IP:            resp.Node.IP,
+			Role:          resp.Node.Role,
+			Status:        resp.Node.Status,
+			InitializedAt: resp.Node.JoinedAt,
+		},
+		Cluster: state.Cluster{
+			ID:            resp.Node.ClusterID,
+			Name:          resp.ClusterName,
+			AdvertiseAddr: utils.HostPort(resp.Node.IP, 7443),
+		},
+		Flags: state.Flags{
+			Initialized: true,
+		},
+	}
+	if err := state.SaveState(cfg.StatePath, s); err != nil {
+		return err
+	}
+	logger.Info("Wrote state file to %s", cfg.StatePath)
+
+	logger.Info("Re-joined cluster %s", resp.ClusterName)
+	return nil
+}
+
+// restoreFile writes content to path (root-only) unless the file already exists.
+func restoreFile(path string, content string) error {
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		return err
+	}
+	logger.Info("Restored %s", path)
+	return nil
+}
@@ -0,0 +1,156 @@
+package mcloudctl
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"mcloud/internal/config"
+	"mcloud/internal/database"
+	"mcloud/internal/workload"
+	"mcloud/pkg/logger"
+
+	"github.com/urfave/cli/v2"
+)
+
+// formatSchedule describes a power schedule on one line.
+//
+// Example Output:
+//   "stop 01:00, start 07:00 (mon,tue,wed,thu,fri); skipping until 2026-01-04 01:00"
+func formatSchedule(s *database.WorkloadSchedule) string {
+	var parts []string
+	if s.StopAt != nil {
+		parts = append(parts, "stop "+*s.StopAt)
+	}
+	if s.StartAt != nil {
+		parts = append(parts, "start "+*s.StartAt)
+	}
+	out := strings.Join(parts, ", ")
+	if s.Days != "" {
+		out += " (" + s.Days + ")"
+	}
+	if s.SkipUntil != nil && s.SkipUntil.After(time.Now()) {
+		out += "; skipping until " + s.SkipUntil.Local().Format("2006-01-02 15:04")
+	}
+	return out
+}
+
+// scheduleCommand resolves the workload ID argument and API client shared by
+// the schedule subcommands.
+func scheduleCommand(c *cli.Context) (string, *apiClient, error) {
+	id := c.Args().First()
+	if id == "" {
+		return "", nil, usageErrorf("workload id is required")
+	}
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return "", nil, err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return "", nil, err
+	}
+	return id, client, nil
+}
+
+// WorkloadScheduleSetCommand is the CLI command handler for 'mcloudctl workload schedule set'.
+// Sends PUT /workloads/{id}/schedule. Times are the manager's local time.
+//
+// CLI Usage:
+//   mcloudctl workload schedule set <workload-id> [--stop 01:00] [--start 07:00] [--days mon-fri]
+//
+// Example Output:
+//   [INFO] 2026-01-03 10:30:45 Workload 550e8400-... scheduled: stop 01:00, start 07:00 (mon,tue,wed,thu,fri)
+func WorkloadScheduleSetCommand(c *cli.Context) error {
+	id, client, err := scheduleCommand(c)
+	if err != nil {
+		return err
+	}
+
+	req := workload.ScheduleRequest{
+		Stop:  c.String("stop"),
+		Start: c.String("start"),
+		Days:  c.String("days"),
+	}
+	var result database.WorkloadSchedule
+	if err := client.do(context.Background(), http.MethodPut, "/workloads/"+id+"/schedule", req, &result); err != nil {
+		return err
+	}
+	if err := printResult(c, result, nil); err != nil {
+		return err
+	}
+	logger.Info("Workload %s scheduled: %s", id, formatSchedule(&result))
+	return nil
+}
+
+// WorkloadScheduleClearCommand is the CLI command handler for 'mcloudctl workload schedule clear'.
+// Sends DELETE /workloads/{id}/schedule; the workload stays in its current state.
+//
+// CLI Usage:
+//   mcloudctl workload schedule clear <workload-id>
+func WorkloadScheduleClearCommand(c *cli.Context) error {
+	id, client, err := scheduleCommand(c)
+	if err != nil {
+		return err
+	}
+
+	if err := client.do(context.Background(), http.MethodDelete, "/workloads/"+id+"/schedule", nil, nil); err != nil {
+		return err
+	}
+	logger.Info("Removed the schedule of workload %s", id)
+	return nil
+}
+
+// WorkloadScheduleSkipCommand is the CLI command handler for 'mcloudctl workload schedule skip'.
+// Sends POST /workloads/{id}/schedule/skip so the next scheduled action does not run.
+//
+// CLI Usage:
+//   mcloudctl workload schedule skip <workload-id>
+//
+// Example Output:
+//   [INFO] 2026-01-03 10:30:45 Workload 550e8400-...: stop 01:00, start 07:00; skipping until 2026-01-04 01:00
+func WorkloadScheduleSkipCommand(c *cli.Context) error {
+	id, client, err := scheduleCommand(c)
+	if err != nil {
+		return err
+	}
+
+	var result database.WorkloadSchedule
+	if err := client.do(context.Background(), http.MethodPost, "/workloads/"+id+"/schedule/skip", nil, &result); err != nil {
+		return err
+	}
+	if err := printResult(c, result, nil); err != nil {
+		return err
+	}
+	logger.Info("Workload %s: %s", id, formatSchedule(&result))
+	return nil
+}
+
+// WorkloadScheduleOverrideCommand is the CLI command handler for 'mcloudctl workload schedule override'.
+// Sends POST /workloads/{id}/schedule/override to suspend the schedule for --for,
+// e.g. to keep a VM up overnight; --for 0 resumes the schedule.
+//
+// CLI Usage:
+//   mcloudctl workload schedule override <workload-id> --for 12h
+func WorkloadScheduleOverrideCommand(c *cli.Context) error {
+	id, client, err := scheduleCommand(c)
+	if err != nil {
+		return err
+	}
+
+	req := workload.OverrideRequest{DurationSeconds: int(c.Duration("for").Seconds())}
+	var result database.WorkloadSchedule
+	if err := client.do(context.Background(), http.MethodPost, "/workloads/"+id+"/schedule/override", req, &result); err != nil {
+		return err
+	}
+	if err := printResult(c, result, nil); err != nil {
+		return err
+	}
+	if req.DurationSeconds == 0 {
+		logger.Info("Workload %s follows its schedule again: %s", id, formatSchedule(&result))
+		return nil
+	}
+	logger.Info("Workload %s: %s", id, formatSchedule(&result))
+	return nil
+}
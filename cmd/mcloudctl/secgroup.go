@@ -0,0 +1,300 @@
+package mcloudctl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"mcloud/internal/config"
+	"mcloud/internal/database"
+	"mcloud/internal/securitygroup"
+	"mcloud/pkg/logger"
+
+	"github.com/urfave/cli/v2"
+)
+
+// parseRuleFlags parses the --rule flags of secgroup create and secgroup rules.
+func parseRuleFlags(c *cli.Context) ([]database.SecurityGroupRule, error) {
+	rules := []database.SecurityGroupRule{}
+	for _, s := range c.StringSlice("rule") {
+		rule, err := securitygroup.ParseRule(s)
+		if err != nil {
+			return nil, fmt.Errorf("--rule %s: %w", s, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// formatRule is the inverse of securitygroup.ParseRule, e.g. ingress:tcp:443:0.0.0.0/0.
+func formatRule(r database.SecurityGroupRule) string {
+	parts := []string{r.Direction, r.Protocol, r.Port, r.CIDR}
+	if parts[1] == "" {
+		parts[1] = "any"
+	}
+	for len(parts) > 1 && parts[len(parts)-1] == "" {
+		parts = parts[:len(parts)-1]
+	}
+	return strings.Join(parts, ":")
+}
+
+// SecGroupListCommand is the CLI command handler for 'mcloudctl secgroup list'.
+// Fetches GET /security-groups and prints the groups as a table.
+//
+// CLI Usage:
+//   mcloudctl secgroup list
+//
+// Example Output:
+//   NAME  RULES  WORKLOADS  DESCRIPTION
+//   web   2      3          HTTP(S) from anywhere
+func SecGroupListCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	var items []database.SecurityGroup
+	if err := client.do(ctx, http.MethodGet, "/security-groups", nil, &items); err != nil {
+		return err
+	}
+
+	return printResult(c, items, func(tw io.Writer) {
+		fmt.Fprintln(tw, "NAME\tRULES\tWORKLOADS\tDESCRIPTION")
+		for _, g := range items {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%s\n", g.Name, len(g.Rules), len(g.Workloads), g.Description)
+		}
+	})
+}
+
+// SecGroupShowCommand is the CLI command handler for 'mcloudctl secgroup show'.
+// Fetches GET /security-groups/{name} and prints its rules and workloads.
+//
+// CLI Usage:
+//   mcloudctl secgroup show <name>
+//
+// Example Output:
+//   Name:        web
+//   Description: HTTP(S) from anywhere
+//   Rules:
+//     ingress:tcp:80
+//     ingress:tcp:443
+//   Workloads:
+//     550e8400-e29b-41d4-a716-446655440000
+func SecGroupShowCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	name := c.Args().First()
+	if name == "" {
+		return usageErrorf("security group name is required")
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	var g database.SecurityGroup
+	if err := client.do(ctx, http.MethodGet, "/security-groups/"+url.PathEscape(name), nil, &g); err != nil {
+		return err
+	}
+
+	return printResult(c, g, func(tw io.Writer) {
+		fmt.Fprintf(tw, "Name:        %s\n", g.Name)
+		fmt.Fprintf(tw, "Description: %s\n", g.Description)
+		fmt.Fprintln(tw, "Rules:")
+		for _, r := range g.Rules {
+			fmt.Fprintf(tw, "  %s\n", formatRule(r))
+		}
+		fmt.Fprintln(tw, "Workloads:")
+		for _, id := range g.Workloads {
+			fmt.Fprintf(tw, "  %s\n", id)
+		}
+	})
+}
+
+// SecGroupCreateCommand is the CLI command handler for 'mcloudctl secgroup create'.
+// Sends POST /security-groups. Rules are direction[:protocol[:port[:cidr]]].
+//
+// CLI Usage:
+//   mcloudctl secgroup create <name> [--description text] [--rule ingress:tcp:443 ...]
+//
+// Example Output:
+//   [INFO] 2026-01-02 10:30:45 Created security group web with 2 rules
+//
+// Example Output (Error):
+//   Error: --rule ingress:tcp:http: MC2303 InvalidSecurityGroup: invalid security group: port must be a port or range of 1-65535, got "http"
+func SecGroupCreateCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	name := c.Args().First()
+	if name == "" {
+		return usageErrorf("security group name is required")
+	}
+	rules, err := parseRuleFlags(c)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	req := securitygroup.CreateRequest{Name: name, Description: c.String("description"), Rules: rules}
+	var g database.SecurityGroup
+	if err := client.do(ctx, http.MethodPost, "/security-groups", req, &g); err != nil {
+		return err
+	}
+	if err := printResult(c, g, nil); err != nil {
+		return err
+	}
+	logger.Info("Created security group %s with %d rules", g.Name, len(g.Rules))
+	return nil
+}
+
+// SecGroupRulesCommand is the CLI command handler for 'mcloudctl secgroup rules'.
+// Sends PUT /security-groups/{name}/rules, replacing all rules of the group;
+// no --rule leaves the group allowing nothing.
+//
+// CLI Usage:
+//   mcloudctl secgroup rules <name> --rule ingress:tcp:443 --rule egress
+func SecGroupRulesCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	name := c.Args().First()
+	if name == "" {
+		return usageErrorf("security group name is required")
+	}
+	rules, err := parseRuleFlags(c)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	var g database.SecurityGroup
+	if err := client.do(ctx, http.MethodPut, "/security-groups/"+url.PathEscape(name)+"/rules", securitygroup.RulesRequest{Rules: rules}, &g); err != nil {
+		return err
+	}
+	if err := printResult(c, g, nil); err != nil {
+		return err
+	}
+	logger.Info("Security group %s now has %d rules", g.Name, len(g.Rules))
+	return nil
+}
+
+// SecGroupDeleteCommand is the CLI command handler for 'mcloudctl secgroup delete'.
+// Sends DELETE /security-groups/{name}; groups attached to workloads are refused.
+//
+// CLI Usage:
+//   mcloudctl secgroup delete <name>
+//
+// Example Output (Error):
+//   Error: MC2302 SecurityGroupInUse: security group is attached to workloads: web is attached to 3 workloads
+func SecGroupDeleteCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	name := c.Args().First()
+	if name == "" {
+		return usageErrorf("security group name is required")
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	if err := client.do(ctx, http.MethodDelete, "/security-groups/"+url.PathEscape(name), nil, nil); err != nil {
+		return err
+	}
+	logger.Info("Deleted security group %s", name)
+	return nil
+}
+
+// SecGroupAttachCommand is the CLI command handler for 'mcloudctl secgroup attach'.
+// Sends POST /workloads/{id}/security-groups.
+//
+// CLI Usage:
+//   mcloudctl secgroup attach <name> <workload-id>
+//
+// Example Output:
+//   [INFO] 2026-01-02 10:30:45 Workload 550e8400-... security groups: ssh, web
+func SecGroupAttachCommand(c *cli.Context) error {
+	return changeSecGroupAttachment(c, true)
+}
+
+// SecGroupDetachCommand is the CLI command handler for 'mcloudctl secgroup detach'.
+// Sends DELETE /workloads/{id}/security-groups/{name}. A workload without
+// groups is no longer filtered.
+//
+// CLI Usage:
+//   mcloudctl secgroup detach <name> <workload-id>
+func SecGroupDetachCommand(c *cli.Context) error {
+	return changeSecGroupAttachment(c, false)
+}
+
+func changeSecGroupAttachment(c *cli.Context, attach bool) error {
+	ctx := context.Background()
+
+	name, id := c.Args().Get(0), c.Args().Get(1)
+	if name == "" || id == "" {
+		return usageErrorf("security group name and workload id are required")
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	path := "/workloads/" + url.PathEscape(id) + "/security-groups"
+	var groups []string
+	if attach {
+		err = client.do(ctx, http.MethodPost, path, securitygroup.AttachRequest{Name: name}, &groups)
+	} else {
+		err = client.do(ctx, http.MethodDelete, path+"/"+url.PathEscape(name), nil, &groups)
+	}
+	if err != nil {
+		return err
+	}
+	if err := printResult(c, groups, nil); err != nil {
+		return err
+	}
+	if len(groups) == 0 {
+		logger.Info("Workload %s has no security groups", id)
+		return nil
+	}
+	logger.Info("Workload %s security groups: %s", id, strings.Join(groups, ", "))
+	return nil
+}
@@ -0,0 +1,58 @@
+package mcloudctl
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"mcloud/pkg/logger"
+	"mcloud/services/microceph"
+
+	"github.com/urfave/cli/v2"
+)
+
+// StorageAddDiskCommand is the CLI command handler for 'mcloudctl storage add-disk'.
+// Adds disks on the local node to Ceph as OSDs. Without device arguments every
+// available disk (unmounted, unpartitioned, no filesystem) is added.
+//
+// CLI Usage:
+//   mcloudctl storage add-disk [<device>...] [--wipe] [--encrypt] [--loop-count 3 --loop-size 4]
+//
+// Example Input:
+//   $ sudo mcloudctl storage add-disk /dev/sdc /dev/sdd --wipe
+//
+// Example Output:
+//   [INFO] 2026-01-03 10:30:45 Added disks to Ceph: [/dev/sdc /dev/sdd]
+func StorageAddDiskCommand(c *cli.Context) error {
+	if os.Geteuid() != 0 {
+		return fmt.Errorf("must run as root")
+	}
+
+	cfg := microceph.DiskConfig{
+		Paths:      c.Args().Slice(),
+		Wipe:       c.Bool("wipe"),
+		Encrypt:    c.Bool("encrypt"),
+		LoopSizeGB: c.Int("loop-size"),
+		LoopCount:  c.Int("loop-count"),
+	}
+
+	if len(cfg.Paths) == 0 {
+		disks, err := microceph.AvailableDisks()
+		if err != nil {
+			return err
+		}
+		for _, d := range disks {
+			cfg.Paths = append(cfg.Paths, d.Path)
+		}
+	}
+
+	if err := microceph.AddDisks(context.Background(), cfg); err != nil {
+		return err
+	}
+	if len(cfg.Paths) == 0 {
+		logger.Info("Added %d loop-file OSDs to Ceph", cfg.LoopCount)
+	} else {
+		logger.Info("Added disks to Ceph: %v", cfg.Paths)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package mcloudctl
+
+import (
+	"fmt"
+
+	"mcloud/internal/config"
+	"mcloud/internal/constant"
+	"mcloud/internal/installer"
+	"mcloud/pkg/logger"
+
+	"github.com/urfave/cli/v2"
+)
+
+// UninstallCommand is the CLI command handler for 'mcloudctl uninstall'.
+// Stops and disables mcloudd and mcloud-agent and removes their unit files and
+// binaries. With --purge the data directory (state, database, certificates,
+// images) is deleted as well.
+//
+// CLI Usage:
+//   mcloudctl uninstall [--purge]
+//
+// Example Output:
+//   ✔ removed /etc/systemd/system/mcloudd.service
+//   ✔ removed /usr/local/bin/mcloudd
+//   ✔ removed /etc/systemd/system/mcloud-agent.service
+//   ✔ removed /usr/local/bin/mcloud-agent
+//   [INFO] 2026-01-02 10:30:45 mcloudd uninstalled
+func UninstallCommand(c *cli.Context) error {
+	if err := installer.Uninstall(installer.UninstallOptions{
+		Purge:   c.Bool("purge"),
+		DataDir: constant.DefaultDataDir,
+	}); err != nil {
+		return err
+	}
+	if err := installer.UninstallAgent(); err != nil {
+		return err
+	}
+	logger.Info("mcloudd uninstalled")
+	return nil
+}
+
+// ResetCommand is the CLI command handler for 'mcloudctl reset'.
+// Wipes this node's mcloud state (state file, database, certificates, re-join
+// bundle) and stops mcloudd, keeping the binaries and config so `mcloudctl init`
+// can run again. Requires --force because the cluster records cannot be recovered.
+//
+// CLI Usage:
+//   mcloudctl reset --force
+//
+// Example Output (Error - No --force):
+//   Returns: error("reset deletes the state file, database and certificates of this node; re-run with --force")
+func ResetCommand(c *cli.Context) error {
+	if !c.Bool("force") {
+		return fmt.Errorf("reset deletes the state file, database and certificates of this node; re-run with --force")
+	}
+
+	// Fall back to the default paths when the config is already gone
+	cfg, err := config.GetConfig()
+	if err != nil {
+		logger.Warn("Cannot read config (%v); resetting default paths", err)
+		cfg = &config.Config{
+			StatePath: constant.DefaultStatePath,
+			Database:  config.Database{DBPath: constant.DefaultDBPath},
+			Security:  config.Security{CertDir: constant.DefaultCertDir},
+		}
+	}
+
+	if err := installer.Reset(cfg); err != nil {
+		return err
+	}
+	logger.Info("Node reset; run mcloudctl init to set it up again")
+	return nil
+}
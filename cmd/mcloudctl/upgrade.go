@@ -0,0 +1,185 @@
+package mcloudctl
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"mcloud/internal/command"
+	"mcloud/internal/config"
+	"mcloud/internal/installer"
+	"mcloud/internal/upgrade"
+	"mcloud/pkg/logger"
+
+	"github.com/urfave/cli/v2"
+)
+
+// upgradeHealthTimeout is how long a restarted service has to pass its
+// health checks before the upgrade is rolled back.
+const upgradeHealthTimeout = 2 * time.Minute
+
+// UpgradeCommand is the CLI command handler for 'mcloudctl upgrade'.
+// Upgrades mcloudd and mcloud-agent on this host to a release: each binary is
+// downloaded from upgrade.release_url (or taken from --file), verified against
+// SHA256SUMS and its signature by upgrade.public_key_path (or --sha256; an
+// unsigned download needs --insecure-skip-signature), checked to report the
+// version, swapped in atomically and restarted. A service that does not pass
+// its health checks within 2 minutes (mcloudd: /healthz reports the version
+// and /readyz passes; agent: its command stream reconnects) is rolled back to
+// the previous binary and restarted. Run it on every node, managers first.
+//
+// CLI Usage:
+//   mcloudctl upgrade --version v0.2.0 [--component mcloudd|mcloud-agent|all]
+//   mcloudctl upgrade --version v0.2.0 --component mcloudd --file ./mcloudd --sha256 9f2c...
+//
+// Example Output:
+//   ✔ copied mcloudd → /usr/local/bin/mcloudd.new
+//   ✔ replaced /usr/local/bin/mcloudd (previous kept as /usr/local/bin/mcloudd.prev)
+//   [INFO] 2026-10-16 10:30:52 mcloudd upgraded to v0.2.0
+//   ✔ copied mcloud-agent → /usr/local/bin/mcloud-agent.new
+//   ✔ replaced /usr/local/bin/mcloud-agent (previous kept as /usr/local/bin/mcloud-agent.prev)
+//   [INFO] 2026-10-16 10:30:58 mcloud-agent upgraded to v0.2.0
+//
+// Example Output (Error):
+//   ✔ restored /usr/local/bin/mcloudd from /usr/local/bin/mcloudd.prev
+//   Error: mcloudd v0.2.0 failed its health checks and was rolled back: readyz: database: migration locked
+func UpgradeCommand(c *cli.Context) error {
+	ctx := context.Background()
+	version := upgrade.Tag(c.String("version"))
+
+	var binaries []string
+	switch component := c.String("component"); component {
+	case "all":
+		for _, b := range []string{"mcloudd", "mcloud-agent"} {
+			if installer.IsInstalled(b) {
+				binaries = append(binaries, b)
+			}
+		}
+		if len(binaries) == 0 {
+			return fmt.Errorf("neither mcloudd nor mcloud-agent is installed on this host")
+		}
+	case "mcloudd", "mcloud-agent":
+		binaries = []string{component}
+	default:
+		return usageErrorf("--component must be mcloudd, mcloud-agent or all, got %q", component)
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	var paths map[string]string
+	if file := c.String("file"); file != "" {
+		if len(binaries) != 1 || c.String("sha256") == "" {
+			return usageErrorf("--file needs --component mcloudd or mcloud-agent and --sha256")
+		}
+		if err := upgrade.VerifyFile(file, c.String("sha256")); err != nil {
+			return err
+		}
+		paths = map[string]string{binaries[0]: file}
+	} else {
+		dir, err := os.MkdirTemp("", "mcloud-upgrade-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+		if paths, err = upgrade.Download(ctx, cfg.Upgrade, version, binaries, dir, c.Bool("insecure-skip-signature")); err != nil {
+			return err
+		}
+	}
+	// Check every binary before swapping any, so a bad release changes nothing
+	for _, b := range binaries {
+		if err := upgrade.CheckVersion(ctx, paths[b], version); err != nil {
+			return err
+		}
+	}
+
+	// mcloudd first: the agent's health check asks it whether the agent reconnected
+	for _, b := range binaries {
+		if err := upgradeBinary(ctx, client, b, paths[b], version); err != nil {
+			return err
+		}
+		logger.Info("%s upgraded to %s", b, version)
+	}
+	return nil
+}
+
+// upgradeBinary swaps in and restarts one binary, and rolls it back when the
+// restarted service does not pass its health checks.
+func upgradeBinary(ctx context.Context, client *apiClient, binary, path, version string) error {
+	if err := installer.ReplaceBinary(binary, path); err != nil {
+		return err
+	}
+	restartedAt := time.Now()
+	err := installer.RestartService(binary)
+	if err == nil {
+		err = waitUpgradeHealthy(ctx, client, binary, version, restartedAt)
+	}
+	if err == nil {
+		return nil
+	}
+
+	if rerr := installer.RollbackBinary(binary); rerr != nil {
+		return fmt.Errorf("%s %s failed: %v; rollback failed: %w", binary, version, err, rerr)
+	}
+	if rerr := installer.RestartService(binary); rerr != nil {
+		return fmt.Errorf("%s %s failed: %v; restarting the previous binary failed: %w", binary, version, err, rerr)
+	}
+	return fmt.Errorf("%s %s failed its health checks and was rolled back: %w", binary, version, err)
+}
+
+// waitUpgradeHealthy polls the restarted service until it is healthy or
+// upgradeHealthTimeout passes, and returns the last failed check.
+func waitUpgradeHealthy(ctx context.Context, client *apiClient, binary, version string, restartedAt time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, upgradeHealthTimeout)
+	defer cancel()
+	hostname, _ := os.Hostname()
+
+	for {
+		err := checkUpgradeHealth(ctx, client, binary, version, hostname, restartedAt)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
+
+func checkUpgradeHealth(ctx context.Context, client *apiClient, binary, version, hostname string, restartedAt time.Time) error {
+	if binary == "mcloud-agent" {
+		var agents []command.Agent
+		if err := client.do(ctx, http.MethodGet, "/agents", nil, &agents); err != nil {
+			return err
+		}
+		for _, a := range agents {
+			if a.Node == hostname && a.ConnectedAt.After(restartedAt) {
+				return nil
+			}
+		}
+		return fmt.Errorf("agent of %s has not reconnected to mcloudd", hostname)
+	}
+
+	var status struct {
+		Version string `json:"version"`
+	}
+	if err := client.do(ctx, http.MethodGet, "/healthz", nil, &status); err != nil {
+		return fmt.Errorf("healthz: %w", err)
+	}
+	if upgrade.Tag(status.Version) != version {
+		return fmt.Errorf("mcloudd still reports version %s", status.Version)
+	}
+	if err := client.do(ctx, http.MethodGet, "/readyz", nil, nil); err != nil {
+		return fmt.Errorf("readyz: %w", err)
+	}
+	return nil
+}
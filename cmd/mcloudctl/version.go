@@ -0,0 +1,72 @@
+package mcloudctl
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"mcloud/internal/config"
+	"mcloud/internal/version"
+	"mcloud/pkg/client"
+	"mcloud/pkg/logger"
+
+	"github.com/urfave/cli/v2"
+)
+
+// versionReport is what 'mcloudctl version' prints: the build of mcloudctl
+// and, when the manager can be reached, of mcloudd.
+type versionReport struct {
+	Client version.Info        `json:"client"`
+	Server *client.VersionInfo `json:"server,omitempty"`
+}
+
+// VersionCommand is the CLI command handler for 'mcloudctl version'.
+// Prints the build of mcloudctl and fetches GET /version for the manager's.
+// A manager that cannot be reached or runs another major version is reported
+// as a warning; the client build is printed either way.
+//
+// CLI Usage:
+//
+//	mcloudctl version
+//
+// Example Output:
+//
+//	Client:      0.2.0 (commit 3f9c2ab, built 2026-10-16T08:12:00Z, go1.24.4)
+//	Server:      0.2.0 (commit 3f9c2ab, built 2026-10-16T08:12:00Z, go1.24.4)
+//	API version: 1
+//
+// Example Output (Manager Unreachable):
+//
+//	Client:      0.2.0 (commit 3f9c2ab, built 2026-10-16T08:12:00Z, go1.24.4)
+//	[WARN] 2026-10-16 10:30:45 Cannot get the server version: open /etc/mcloud/config.yaml: no such file or directory
+func VersionCommand(c *cli.Context) error {
+	report := versionReport{Client: version.Current()}
+	if info, err := serverVersion(context.Background()); err != nil {
+		logger.Warn("Cannot get the server version: %v", err)
+	} else {
+		report.Server = info
+		if err := version.Compatible("mcloudd", info.Version); err != nil {
+			logger.Warn("%v; upgrade one to match the other", err)
+		}
+	}
+
+	return printResult(c, report, func(tw io.Writer) {
+		fmt.Fprintf(tw, "Client:\t%s (commit %s, built %s, %s)\n", report.Client.Version, report.Client.Commit, report.Client.BuildDate, report.Client.GoVersion)
+		if s := report.Server; s != nil {
+			fmt.Fprintf(tw, "Server:\t%s (commit %s, built %s, %s)\n", s.Version, s.Commit, s.BuildDate, s.GoVersion)
+			fmt.Fprintf(tw, "API version:\t%s\n", s.APIVersion)
+		}
+	})
+}
+
+func serverVersion(ctx context.Context) (*client.VersionInfo, error) {
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+	c, err := newAPIClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return c.api.Version(ctx)
+}
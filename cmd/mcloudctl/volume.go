@@ -0,0 +1,198 @@
+package mcloudctl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"mcloud/internal/config"
+	"mcloud/internal/database"
+	"mcloud/internal/volume"
+	"mcloud/pkg/logger"
+
+	"github.com/urfave/cli/v2"
+)
+
+// VolumeCreateCommand is the CLI command handler for 'mcloudctl volume create'.
+// Sends POST /volumes to mcloudd using the local client certificate.
+//
+// CLI Usage:
+//   mcloudctl volume create <name> --size-gb 50 [--pool remote]
+//
+// Example Output:
+//   [INFO] 2026-01-02 10:30:45 Created volume remote/pgdata (50 GiB), id 7d1f0c2e-...
+//
+// Example Output (Error):
+//   Error: MC2103 InvalidVolume: invalid volume request: pool local is not Ceph-backed (driver zfs)
+func VolumeCreateCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	name := c.Args().First()
+	if name == "" {
+		return usageErrorf("volume name is required")
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	req := volume.CreateRequest{Name: name, Pool: c.String("pool"), SizeGB: c.Int("size-gb")}
+	var v database.Volume
+	if err := client.do(ctx, http.MethodPost, "/volumes", req, &v); err != nil {
+		return err
+	}
+	if err := printResult(c, v, nil); err != nil {
+		return err
+	}
+	logger.Info("Created volume %s/%s (%d GiB), id %s", v.Pool, v.Name, v.SizeGB, v.ID)
+	return nil
+}
+
+// VolumeListCommand is the CLI command handler for 'mcloudctl volume list'.
+// Fetches GET /volumes and prints the volumes as a table.
+//
+// CLI Usage:
+//   mcloudctl volume list
+//
+// Example Output:
+//   ID            POOL    NAME     SIZE   WORKLOAD      PATH
+//   7d1f0c2e-...  remote  pgdata   50GiB  550e8400-...  /var/lib/postgresql
+//   91ab44d0-...  remote  scratch  10GiB  -             -
+func VolumeListCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	var volumes []database.Volume
+	if err := client.do(ctx, http.MethodGet, "/volumes", nil, &volumes); err != nil {
+		return err
+	}
+
+	return printResult(c, volumes, func(tw io.Writer) {
+		fmt.Fprintln(tw, "ID\tPOOL\tNAME\tSIZE\tWORKLOAD\tPATH")
+		for _, v := range volumes {
+			workloadID, mountPath := "-", "-"
+			if v.WorkloadID != nil {
+				workloadID = *v.WorkloadID
+			}
+			if v.MountPath != nil {
+				mountPath = *v.MountPath
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%dGiB\t%s\t%s\n", v.ID, v.Pool, v.Name, v.SizeGB, workloadID, mountPath)
+		}
+	})
+}
+
+// VolumeAttachCommand is the CLI command handler for 'mcloudctl volume attach'.
+// Sends POST /volumes/{id}/attach; the volume is mounted in the workload at --path.
+//
+// CLI Usage:
+//   mcloudctl volume attach <volume-id> <workload-id> --path /var/lib/postgresql
+//
+// Example Output (Error):
+//   Error: MC2102 VolumeInUse: volume is attached to a workload: pgdata is attached to workload 550e8400-...
+func VolumeAttachCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	id, workloadID := c.Args().Get(0), c.Args().Get(1)
+	if id == "" || workloadID == "" {
+		return usageErrorf("volume id and workload id are required")
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	req := volume.AttachRequest{WorkloadID: workloadID, Path: c.String("path")}
+	var v database.Volume
+	if err := client.do(ctx, http.MethodPost, "/volumes/"+url.PathEscape(id)+"/attach", req, &v); err != nil {
+		return err
+	}
+	if err := printResult(c, v, nil); err != nil {
+		return err
+	}
+	logger.Info("Attached volume %s/%s to workload %s at %s", v.Pool, v.Name, workloadID, *v.MountPath)
+	return nil
+}
+
+// VolumeDetachCommand is the CLI command handler for 'mcloudctl volume detach'.
+// Sends POST /volumes/{id}/detach.
+//
+// CLI Usage:
+//   mcloudctl volume detach <volume-id>
+func VolumeDetachCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	id := c.Args().First()
+	if id == "" {
+		return usageErrorf("volume id is required")
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	var v database.Volume
+	if err := client.do(ctx, http.MethodPost, "/volumes/"+url.PathEscape(id)+"/detach", nil, &v); err != nil {
+		return err
+	}
+	if err := printResult(c, v, nil); err != nil {
+		return err
+	}
+	logger.Info("Detached volume %s/%s", v.Pool, v.Name)
+	return nil
+}
+
+// VolumeDeleteCommand is the CLI command handler for 'mcloudctl volume delete'.
+// Sends DELETE /volumes/{id}; attached volumes must be detached first. The
+// volume's data is gone for good.
+//
+// CLI Usage:
+//   mcloudctl volume delete <volume-id>
+func VolumeDeleteCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	id := c.Args().First()
+	if id == "" {
+		return usageErrorf("volume id is required")
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	if err := client.do(ctx, http.MethodDelete, "/volumes/"+url.PathEscape(id), nil, nil); err != nil {
+		return err
+	}
+	logger.Info("Deleted volume %s", id)
+	return nil
+}
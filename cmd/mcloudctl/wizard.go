@@ -0,0 +1,264 @@
+package mcloudctl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	"mcloud/internal/network"
+	"mcloud/pkg/utils"
+	"mcloud/services/microceph"
+)
+
+// prompter asks questions on a terminal and reads the answers.
+type prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+func newPrompter(in io.Reader, out io.Writer) *prompter {
+	return &prompter{in: bufio.NewReader(in), out: out}
+}
+
+// ask prints question and reads one line, def when it is empty, until check
+// accepts it. Rejected answers are explained and asked again.
+//
+// Example Output:
+//   Cluster name: ab
+//     cluster name must be at least 3 characters
+//   Cluster name: prod
+func (p *prompter) ask(question, def string, check func(string) error) (string, error) {
+	for {
+		if def != "" {
+			fmt.Fprintf(p.out, "%s [%s]: ", question, def)
+		} else {
+			fmt.Fprintf(p.out, "%s: ", question)
+		}
+		line, err := p.in.ReadString('\n')
+		if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+			fmt.Fprintln(p.out)
+			return "", errors.New("init cancelled: no answer")
+		}
+		answer := strings.TrimSpace(line)
+		if answer == "" {
+			answer = def
+		}
+		if err := check(answer); err != nil {
+			fmt.Fprintf(p.out, "  %v\n", err)
+			continue
+		}
+		return answer, nil
+	}
+}
+
+// confirm asks a yes/no question; anything but y or yes is no.
+func (p *prompter) confirm(question string) (bool, error) {
+	answer, err := p.ask(question+" [y/N]", "", func(string) error { return nil })
+	if err != nil {
+		return false, err
+	}
+	answer = strings.ToLower(answer)
+	return answer == "y" || answer == "yes", nil
+}
+
+// runInitWizard asks for the init settings not given by flags or the preseed:
+// the cluster name, the address to advertise, the Ceph disks and the floating
+// IP range of the uplink network. Each answer is checked before the next
+// question; the settings are then summarised for confirmation.
+//
+// Example Output:
+//   Cluster name: prod
+//   Addresses of this node:
+//     1) 10.0.0.11
+//     2) 192.168.1.20
+//   Address to advertise (number or IP) [1]: 2
+//   Available disks:
+//     1) /dev/sdb  100.0 GiB  QEMU HARDDISK
+//   Ceph disks (numbers or paths, comma-separated; empty for all) []: 1
+//   Floating IP range on the uplink network (CIDR; empty to disable) []: 203.0.113.16/28
+//
+//   Cluster name:  prod
+//   Advertise:     192.168.1.20
+//   Ceph disks:    /dev/sdb
+//   Floating IPs:  203.0.113.16/28
+//   Config file:   /etc/mcloud/config.yaml
+//   Initialize the cluster with these settings? [y/N]: y
+func runInitWizard(p *prompter, opts *InitOptions) error {
+	if opts.Name == "" {
+		name, err := p.ask("Cluster name", "", checkClusterNameLength)
+		if err != nil {
+			return err
+		}
+		opts.Name = name
+	}
+
+	if opts.AdvertiseAddress == "" && opts.AdvertiseInterface == "" {
+		addr, err := askAdvertiseAddress(p)
+		if err != nil {
+			return err
+		}
+		opts.AdvertiseAddress = addr
+	}
+
+	if len(opts.CephDisks) == 0 && opts.CephLoopCount == 0 {
+		if err := askCephDisks(p, opts); err != nil {
+			return err
+		}
+	}
+
+	if len(opts.ExternalPool) == 0 {
+		pool, err := p.ask("Floating IP range on the uplink network (CIDR; empty to disable)", "", func(s string) error {
+			if s == "" {
+				return nil
+			}
+			_, err := network.ParsePool([]string{s})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if pool != "" {
+			opts.ExternalPool = []string{pool}
+		}
+	}
+
+	return confirmInit(p, opts)
+}
+
+// askAdvertiseAddress lists the addresses of this node and asks for one,
+// by number or as an IP.
+func askAdvertiseAddress(p *prompter) (string, error) {
+	ips := utils.GetAllIPs()
+	def := ""
+	if len(ips) > 0 {
+		fmt.Fprintln(p.out, "Addresses of this node:")
+		for i, ip := range ips {
+			fmt.Fprintf(p.out, "  %d) %s\n", i+1, ip)
+		}
+		def = "1"
+	}
+	answer, err := p.ask("Address to advertise (number or IP)", def, func(s string) error {
+		_, err := pickAddress(s, ips)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return pickAddress(answer, ips)
+}
+
+// pickAddress resolves an answer to askAdvertiseAddress: the number of a listed
+// address, or an IP address of this host.
+func pickAddress(answer string, ips []net.IP) (string, error) {
+	if n, err := strconv.Atoi(answer); err == nil {
+		if n < 1 || n > len(ips) {
+			return "", fmt.Errorf("pick a number between 1 and %d", len(ips))
+		}
+		return ips[n-1].String(), nil
+	}
+	ip, err := advertiseIP(answer, "", nil)
+	if err != nil {
+		return "", err
+	}
+	return ip.String(), nil
+}
+
+// askCephDisks lists the disks Ceph may use and asks which to use; with none,
+// it asks how many loop files to create instead.
+func askCephDisks(p *prompter, opts *InitOptions) error {
+	disks, err := microceph.AvailableDisks()
+	if err != nil {
+		return err
+	}
+	if len(disks) == 0 {
+		fmt.Fprintln(p.out, "No disk is available for Ceph; loop files can stand in on test clusters.")
+		answer, err := p.ask("Number of loop-file OSDs", "3", func(s string) error {
+			if n, err := strconv.Atoi(s); err != nil || n < 1 {
+				return fmt.Errorf("enter a number of at least 1")
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		opts.CephLoopCount, _ = strconv.Atoi(answer)
+		return nil
+	}
+
+	fmt.Fprintln(p.out, "Available disks:")
+	for i, d := range disks {
+		fmt.Fprintf(p.out, "  %d) %s  %s  %s\n", i+1, d.Path, formatBytes(int64(d.SizeBytes)), d.Model)
+	}
+	answer, err := p.ask("Ceph disks (numbers or paths, comma-separated; empty for all)", "", func(s string) error {
+		_, err := pickDisks(s, disks)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	opts.CephDisks, _ = pickDisks(answer, disks)
+	return nil
+}
+
+// pickDisks resolves an answer to askCephDisks to disk paths; empty picks none,
+// which leaves Ceph every available disk.
+func pickDisks(answer string, disks []microceph.BlockDevice) ([]string, error) {
+	var paths []string
+	for _, field := range strings.Split(answer, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if n, err := strconv.Atoi(field); err == nil {
+			if n < 1 || n > len(disks) {
+				return nil, fmt.Errorf("pick numbers between 1 and %d", len(disks))
+			}
+			paths = append(paths, disks[n-1].Path)
+			continue
+		}
+		if !strings.HasPrefix(field, "/dev/") {
+			return nil, fmt.Errorf("invalid disk %q: expected a number or a /dev path", field)
+		}
+		paths = append(paths, field)
+	}
+	return paths, nil
+}
+
+// confirmInit prints the settings init will use and asks to go on.
+func confirmInit(p *prompter, opts *InitOptions) error {
+	advertise := opts.AdvertiseAddress
+	if advertise == "" {
+		advertise = "interface " + opts.AdvertiseInterface
+	}
+	disks := strings.Join(opts.CephDisks, ", ")
+	switch {
+	case disks != "":
+	case opts.CephLoopCount > 0:
+		disks = fmt.Sprintf("%d loop files if no disk is available", opts.CephLoopCount)
+	default:
+		disks = "all available"
+	}
+	pool := strings.Join(opts.ExternalPool, ", ")
+	if pool == "" {
+		pool = "disabled"
+	}
+
+	fmt.Fprintln(p.out)
+	fmt.Fprintf(p.out, "Cluster name:  %s\n", opts.Name)
+	fmt.Fprintf(p.out, "Advertise:     %s\n", advertise)
+	fmt.Fprintf(p.out, "Ceph disks:    %s\n", disks)
+	fmt.Fprintf(p.out, "Floating IPs:  %s\n", pool)
+	fmt.Fprintf(p.out, "Config file:   %s\n", opts.ConfigPath)
+	ok, err := p.confirm("Initialize the cluster with these settings?")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("init cancelled")
+	}
+	return nil
+}
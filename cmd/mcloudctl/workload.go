@@ -0,0 +1,317 @@
+package mcloudctl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"mcloud/internal/config"
+	"mcloud/internal/workload"
+	"mcloud/pkg/execstream"
+	"mcloud/pkg/logger"
+
+	"github.com/urfave/cli/v2"
+)
+
+// WorkloadCloneCommand is the CLI command handler for 'mcloudctl workload clone'.
+// Sends POST /workloads/{id}/clone to mcloudd using the local client certificate.
+// The copy runs as an operation; with --wait the command polls it until it finishes.
+//
+// CLI Usage:
+//   mcloudctl workload clone <workload-id> --name <new-name> [--snapshot <snap>] [--target <node-id>] [--wait]
+func WorkloadCloneCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	id := c.Args().First()
+	if id == "" {
+		return usageErrorf("workload id is required")
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	req := workload.CloneRequest{
+		Name:         c.String("name"),
+		Snapshot:     c.String("snapshot"),
+		TargetNodeID: c.String("target"),
+	}
+	var result workload.AsyncResult
+	if err := client.do(ctx, http.MethodPost, "/workloads/"+id+"/clone", req, &result); err != nil {
+		return err
+	}
+	if err := printResult(c, result, nil); err != nil {
+		return err
+	}
+	logger.Info("Cloning workload %s → %s (%s), operation %s", id, req.Name, result.Workload.ID, result.Operation.ID)
+
+	if !c.Bool("wait") {
+		return nil
+	}
+	if _, err := waitOperation(ctx, client, result.Operation.ID, 0); err != nil {
+		return err
+	}
+	logger.Info("Cloned workload %s → %s (%s)", id, req.Name, result.Workload.ID)
+	return nil
+}
+
+// formatAddresses joins a workload's IP addresses for display; "-" when none are known yet.
+func formatAddresses(addresses []string) string {
+	if len(addresses) == 0 {
+		return "-"
+	}
+	return strings.Join(addresses, ", ")
+}
+
+// WorkloadListCommand is the CLI command handler for 'mcloudctl workload list'.
+// Fetches GET /workloads and prints the workloads as a table.
+//
+// CLI Usage:
+//   mcloudctl workload list [--limit 100] [--sort -created_at] [--status running] [--node <node-id>]
+//     [--project team-a]
+//
+// Example Output:
+//   ID                                    NAME   KIND       STATUS   ADDRESSES
+//   550e8400-e29b-41d4-a716-446655440000  web-1  container  running  10.10.0.12, fd42:1::12
+func WorkloadListCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{"limit": {strconv.Itoa(c.Int("limit"))}}
+	for _, name := range []string{"sort", "status", "node", "project"} {
+		if v := c.String(name); v != "" {
+			query.Set(name, v)
+		}
+	}
+
+	var resp workload.ListWorkloadsResponse
+	if err := client.do(ctx, http.MethodGet, "/workloads?"+query.Encode(), nil, &resp); err != nil {
+		return err
+	}
+
+	return printResult(c, resp, func(tw io.Writer) {
+		fmt.Fprintln(tw, "ID\tNAME\tKIND\tSTATUS\tADDRESSES")
+		for _, w := range resp.Items {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", w.ID, w.Name, w.Kind, w.Status, formatAddresses(w.Addresses))
+		}
+	})
+}
+
+// WorkloadDescribeCommand is the CLI command handler for 'mcloudctl workload describe'.
+// Fetches GET /workloads/{id} and prints the workload's details.
+//
+// CLI Usage:
+//   mcloudctl workload describe <workload-id>
+//
+// Example Output:
+//   ID:          550e8400-e29b-41d4-a716-446655440000
+//   Name:        web-1
+//   Kind:        container
+//   Status:      running
+//   Image:       ubuntu:24.04
+//   Priority:    normal
+//   Flavor:      medium
+//   Project:     team-a
+//   Node:        660e8400-e29b-41d4-a716-446655440001
+//   Addresses:   10.10.0.12, fd42:1::12
+//   Created:     2026-01-03 10:30:45
+//   Cloud-init:  sha256 5f2a0c3e9b...
+//   Schedule:    stop 01:00, start 07:00 (mon,tue,wed,thu,fri)
+//   Env:
+//     APP_ENV=production
+//     DB_PASSWORD=<secret db-password>
+func WorkloadDescribeCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	id := c.Args().First()
+	if id == "" {
+		return usageErrorf("workload id is required")
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	var w workload.WorkloadDetail
+	if err := client.do(ctx, http.MethodGet, "/workloads/"+id, nil, &w); err != nil {
+		return err
+	}
+
+	node := "-"
+	if w.NodeID != nil {
+		node = *w.NodeID
+	}
+
+	return printResult(c, w, func(tw io.Writer) {
+		fmt.Fprintf(tw, "ID:\t%s\n", w.ID)
+		fmt.Fprintf(tw, "Name:\t%s\n", w.Name)
+		fmt.Fprintf(tw, "Kind:\t%s\n", w.Kind)
+		fmt.Fprintf(tw, "Status:\t%s\n", w.Status)
+		fmt.Fprintf(tw, "Image:\t%s\n", w.Image)
+		priority := w.Priority
+		if w.PreemptedAt != nil {
+			priority += " (preempted " + w.PreemptedAt.Local().Format(time.DateTime) + ")"
+		}
+		fmt.Fprintf(tw, "Priority:\t%s\n", priority)
+		if w.Flavor != nil {
+			fmt.Fprintf(tw, "Flavor:\t%s\n", *w.Flavor)
+		}
+		fmt.Fprintf(tw, "Project:\t%s\n", w.Project)
+		fmt.Fprintf(tw, "Node:\t%s\n", node)
+		fmt.Fprintf(tw, "Addresses:\t%s\n", formatAddresses(w.Addresses))
+		fmt.Fprintf(tw, "Created:\t%s\n", w.CreatedAt.Local().Format(time.DateTime))
+		if w.CloudInitHash != nil {
+			fmt.Fprintf(tw, "Cloud-init:\tsha256 %s\n", *w.CloudInitHash)
+		}
+		if w.Schedule != nil {
+			fmt.Fprintf(tw, "Schedule:\t%s\n", formatSchedule(w.Schedule))
+		}
+
+		env := make([]string, 0, len(w.Env)+len(w.Secrets))
+		for k, v := range w.Env {
+			env = append(env, k+"="+v)
+		}
+		for k, name := range w.Secrets {
+			env = append(env, fmt.Sprintf("%s=<secret %s>", k, name))
+		}
+		if len(env) > 0 {
+			sort.Strings(env)
+			fmt.Fprintln(tw, "Env:")
+			for _, e := range env {
+				fmt.Fprintln(tw, "  "+e)
+			}
+		}
+	})
+}
+
+// WorkloadExecCommand is the CLI command handler for 'mcloudctl exec' and
+// 'mcloudctl workload exec'. Runs a command in the workload's instance over
+// POST /workloads/{id}/exec, streaming stdin, stdout and stderr, and exits with
+// the command's exit code. With --tty (the default when stdin and stdout are
+// terminals) the command gets a terminal of the local window's size that
+// follows its resizes. The session (and, when enabled on the server, its
+// transcript) is recorded in the audit log; see 'mcloudctl audit sessions'.
+//
+// CLI Usage:
+//   mcloudctl exec <workload-id> [--env KEY=VALUE ...] [--tty] -- <command> [args...]
+//
+// Example:
+//   mcloudctl exec 550e8400-... -- sh -c 'tail -n 100 /var/log/nginx/error.log'
+//   mcloudctl exec 550e8400-... -- bash -l
+func WorkloadExecCommand(c *cli.Context) error {
+	ctx := context.Background()
+
+	id := c.Args().First()
+	if id == "" {
+		return usageErrorf("workload id is required")
+	}
+	req := workload.ExecRequest{Command: c.Args().Tail()}
+	if len(req.Command) == 0 {
+		return usageErrorf("command is required")
+	}
+	for _, kv := range c.StringSlice("env") {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			return fmt.Errorf("invalid --env %q, expected KEY=VALUE", kv)
+		}
+		if req.Env == nil {
+			req.Env = map[string]string{}
+		}
+		req.Env[k] = v
+	}
+	req.TTY = c.Bool("tty")
+	if !c.IsSet("tty") {
+		req.TTY = isTerminal(os.Stdin) && isTerminal(os.Stdout)
+	}
+	if req.TTY {
+		req.Width, req.Height = terminalSize()
+		if _, ok := req.Env["TERM"]; !ok && os.Getenv("TERM") != "" {
+			if req.Env == nil {
+				req.Env = map[string]string{}
+			}
+			req.Env["TERM"] = os.Getenv("TERM")
+		}
+	}
+
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return err
+	}
+	client, err := newAPIClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	conn, header, err := client.upgrade(ctx, "/workloads/"+id+"/exec", execstream.Protocol, req)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	logger.Debug("Exec session %s", header.Get("X-Mcloud-Exec-Session"))
+
+	if req.TTY {
+		code, err := runTerminal(conn, false)
+		if err != nil {
+			return err
+		}
+		if code != 0 {
+			return cli.Exit("", code)
+		}
+		return nil
+	}
+
+	go func() {
+		stdin := execstream.NewWriters(conn, execstream.Stdin)[0]
+		if _, err := io.Copy(stdin, os.Stdin); err != nil {
+			return
+		}
+		execstream.WriteFrame(conn, execstream.StdinEOF, nil)
+	}()
+
+	for {
+		typ, payload, err := execstream.ReadFrame(conn)
+		if err != nil {
+			return fmt.Errorf("exec session ended without an exit code: %w", err)
+		}
+		switch typ {
+		case execstream.Stdout:
+			os.Stdout.Write(payload)
+		case execstream.Stderr:
+			os.Stderr.Write(payload)
+		case execstream.Exit:
+			code, err := execstream.ExitCode(payload)
+			if err != nil {
+				return err
+			}
+			if code != 0 {
+				return cli.Exit("", code)
+			}
+			return nil
+		}
+	}
+}
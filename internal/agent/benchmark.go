@@ -0,0 +1,104 @@
+package agent
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// cpuBenchmarkDuration is how long every core hashes data.
+	cpuBenchmarkDuration = 3 * time.Second
+
+	// diskBenchmarkSize is the amount of data written (and fsynced) by the disk benchmark.
+	diskBenchmarkSize = 256 << 20
+
+	// benchmarkDir is where the disk benchmark writes its scratch file
+	// (the same filesystem LXD storage and mcloud state usually live on).
+	benchmarkDir = "/var/lib/mcloud"
+)
+
+// BenchmarkResult holds the scores of a node benchmark. Higher is faster.
+type BenchmarkResult struct {
+	CPUScore      float64 `json:"cpu_score"`       // MB/s of SHA-256 across all cores
+	DiskWriteMBps float64 `json:"disk_write_mbps"` // sequential write throughput with fsync
+	NetworkMBps   float64 `json:"network_mbps"`    // manager → node throughput (filled in by mcloudd)
+}
+
+// RunBenchmark runs the CPU and disk benchmarks. It takes a few seconds and
+// briefly loads every core, so it should not be run on a busy node.
+func RunBenchmark() (*BenchmarkResult, error) {
+	result := &BenchmarkResult{CPUScore: benchmarkCPU()}
+
+	disk, err := benchmarkDisk()
+	if err != nil {
+		return nil, err
+	}
+	result.DiskWriteMBps = disk
+
+	return result, nil
+}
+
+// benchmarkCPU hashes a buffer on every core for cpuBenchmarkDuration.
+func benchmarkCPU() float64 {
+	buf := make([]byte, 1<<20)
+	rand.Read(buf)
+
+	var total atomic.Int64
+	deadline := time.Now().Add(cpuBenchmarkDuration)
+
+	var wg sync.WaitGroup
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for time.Now().Before(deadline) {
+				sha256.Sum256(buf)
+				total.Add(int64(len(buf)))
+			}
+		}()
+	}
+	wg.Wait()
+
+	return float64(total.Load()) / (1 << 20) / cpuBenchmarkDuration.Seconds()
+}
+
+// benchmarkDisk writes diskBenchmarkSize bytes to a scratch file and syncs it to disk.
+func benchmarkDisk() (float64, error) {
+	if err := os.MkdirAll(benchmarkDir, 0700); err != nil {
+		return 0, err
+	}
+	f, err := os.CreateTemp(benchmarkDir, "benchmark-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	buf := make([]byte, 4<<20)
+	rand.Read(buf)
+
+	start := time.Now()
+	for written := 0; written < diskBenchmarkSize; written += len(buf) {
+		if _, err := f.Write(buf); err != nil {
+			return 0, err
+		}
+	}
+	if err := f.Sync(); err != nil {
+		return 0, err
+	}
+	elapsed := time.Since(start).Seconds()
+
+	return float64(diskBenchmarkSize) / (1 << 20) / elapsed, nil
+}
+
+// drain reads r to the end and returns the number of bytes read.
+// It backs the network benchmark sink.
+func drain(r io.Reader) (int64, error) {
+	return io.Copy(io.Discard, r)
+}
@@ -0,0 +1,201 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"mcloud/internal/cert"
+	"mcloud/internal/config"
+	"mcloud/internal/tracing"
+	"mcloud/pkg/reason"
+	"mcloud/pkg/utils"
+)
+
+// Client is used by mcloudd to call the agent running on a node.
+// Calls are made over mutual TLS using the cluster CA and the local client certificate.
+type Client struct {
+	baseURL string
+	http    *http.Client
+}
+
+// NewClient creates a client for the agent listening on the given node address.
+func NewClient(cfg *config.Config, nodeAddress string) (*Client, error) {
+	caBytes, err := cert.ReadPEM(cfg.Security.CACertPath)
+	if err != nil {
+		return nil, err
+	}
+	caPool := x509.NewCertPool()
+	caPool.AppendCertsFromPEM(caBytes)
+
+	clientCert, err := tls.LoadX509KeyPair(cfg.Security.ClientCertPath, cfg.Security.ClientKeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		baseURL: "https://" + utils.HostPort(nodeAddress, cfg.Agent.Port),
+		http: &http.Client{
+			// Restarts wait for post-restart health checks on the agent
+			Timeout: 2 * time.Minute,
+			Transport: tracing.Transport(&http.Transport{
+				TLSClientConfig: &tls.Config{
+					RootCAs:      caPool,
+					Certificates: []tls.Certificate{clientCert},
+					MinVersion:   tls.VersionTLS12,
+				},
+			}),
+		},
+	}, nil
+}
+
+// RestartService asks the agent to restart an allowlisted service.
+func (c *Client) RestartService(ctx context.Context, service string) (*ServiceRestartResult, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/services/"+service+"/restart", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusBadRequest {
+		msg, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("%w: %s", ErrActionNotAllowed, reason.FromResponse(resp.StatusCode, resp.Header, msg).Message)
+	}
+
+	var result ServiceRestartResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("agent returned %s: %w", resp.Status, err)
+	}
+	if resp.StatusCode >= 300 {
+		return &result, fmt.Errorf("agent failed to restart %s: %s", service, resp.Status)
+	}
+	return &result, nil
+}
+
+// ApplyTimeSync pushes the chrony configuration to the agent.
+// It returns true if the node's configuration changed.
+func (c *Client) ApplyTimeSync(ctx context.Context, cfg *TimeSyncConfig) (bool, error) {
+	body, err := json.Marshal(cfg)
+	if err != nil {
+		return false, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, c.baseURL+"/timesync", bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(resp.Body)
+		return false, fmt.Errorf("agent failed to apply time sync: %s: %w", resp.Status, reason.FromResponse(resp.StatusCode, resp.Header, msg))
+	}
+
+	var result struct {
+		Changed bool `json:"changed"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return false, err
+	}
+	return result.Changed, nil
+}
+
+// GetTimeSyncStatus returns the node's clock state as reported by chrony.
+func (c *Client) GetTimeSyncStatus(ctx context.Context) (*TimeSyncStatus, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/timesync", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("agent failed to report time sync status: %s: %w", resp.Status, reason.FromResponse(resp.StatusCode, resp.Header, msg))
+	}
+
+	var status TimeSyncStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// RunBenchmark runs the CPU and disk benchmarks on the node.
+func (c *Client) RunBenchmark(ctx context.Context) (*BenchmarkResult, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/benchmark", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("agent failed to run benchmark: %s: %w", resp.Status, reason.FromResponse(resp.StatusCode, resp.Header, msg))
+	}
+
+	var result BenchmarkResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// MeasureNetwork uploads size bytes to the agent and returns the throughput in MB/s.
+func (c *Client) MeasureNetwork(ctx context.Context, size int64) (float64, error) {
+	body := io.LimitReader(zeroReader{}, size)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/benchmark/sink", body)
+	if err != nil {
+		return 0, err
+	}
+	req.ContentLength = size
+
+	start := time.Now()
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	elapsed := time.Since(start).Seconds()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(resp.Body)
+		return 0, fmt.Errorf("agent failed to receive benchmark payload: %s: %w", resp.Status, reason.FromResponse(resp.StatusCode, resp.Header, msg))
+	}
+	return float64(size) / (1 << 20) / elapsed, nil
+}
+
+// zeroReader is an endless stream of zero bytes.
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
@@ -0,0 +1,252 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"mcloud/internal/command"
+	"mcloud/internal/config"
+	"mcloud/internal/preflight"
+	"mcloud/internal/tracing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+const (
+	commandRetryBase = time.Second // first delay before reopening a broken command stream
+	commandRetryMax  = time.Minute
+)
+
+// ServiceCommand is the payload of restart_service, collect_logs and
+// refresh_snap commands.
+//
+// Example JSON:
+//   {"service": "microceph"}
+type ServiceCommand struct {
+	Service string `json:"service"`
+}
+
+// ApplyConfigCommand is the payload of an apply_config command; each section
+// that is set is applied.
+//
+// Example JSON:
+//   {"timesync": {"servers": ["ntp.example.com"]}}
+type ApplyConfigCommand struct {
+	TimeSync *TimeSyncConfig `json:"timesync,omitempty"`
+}
+
+// tasks maps every task of command.Tasks to the allowlisted action it runs.
+var tasks = map[string]string{
+	"ceph_status": "ceph:status",
+	"restart_lxd": "restart:lxd",
+	"rotate_logs": "logs:rotate",
+}
+
+// logUnits maps every service of command.LogServices to its systemd unit.
+var logUnits = map[string]string{
+	"lxd":       "snap.lxd.daemon",
+	"microceph": "snap.microceph.daemon",
+	"microovn":  "snap.microovn.daemon",
+	"mcloudd":   "mcloudd",
+	"agent":     "mcloud-agent",
+}
+
+// TaskResult is the result of a task command.
+type TaskResult struct {
+	Task   string `json:"task"`
+	Output string `json:"output"`
+}
+
+// LogsResult is the result of a collect_logs command.
+type LogsResult struct {
+	Service string `json:"service"`
+	Output  string `json:"output"` // the most recent lines of the service's snap logs
+}
+
+// ApplyConfigResult is the result of an apply_config command.
+type ApplyConfigResult struct {
+	TimeSyncChanged bool `json:"timesync_changed"`
+}
+
+// managerGRPCAddress is the host of agent.manager_url on manager.grpc_port.
+func managerGRPCAddress(cfg *config.Config) (string, error) {
+	u, err := url.Parse(cfg.Agent.ManagerURL)
+	if err != nil || u.Hostname() == "" {
+		return "", fmt.Errorf("invalid agent.manager_url %q", cfg.Agent.ManagerURL)
+	}
+	return net.JoinHostPort(u.Hostname(), strconv.Itoa(cfg.Manager.GrpcPort)), nil
+}
+
+// WatchCommands holds a command stream open to mcloudd's gRPC API and runs
+// the commands mcloudd pushes down it (see RunCommand). A broken stream is
+// reopened with exponential backoff until ctx is cancelled.
+func WatchCommands(ctx context.Context, cfg *config.Config, node string) error {
+	addr, err := managerGRPCAddress(cfg)
+	if err != nil {
+		return err
+	}
+	tlsConfig, err := managerTLSConfig(cfg)
+	if err != nil {
+		return err
+	}
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)), tracing.DialOption())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	backoff := commandRetryBase
+	for {
+		opened := time.Now()
+		err := command.Watch(ctx, conn, node, RunCommand)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if time.Since(opened) > commandRetryMax {
+			backoff = commandRetryBase // the stream was up for a while, this is a fresh failure
+		}
+		log.Printf("command stream to %s closed: %v; reopening in %s", addr, err, backoff)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, commandRetryMax)
+	}
+}
+
+// RunCommand runs a command pushed by mcloudd and returns its result:
+//   preflight        the preflight checks of the node (ports used by the
+//                    running cluster, e.g. 8443, report as taken)
+//   restart_service  RestartService of the payload's service
+//   collect_logs     the recent snap logs of the payload's service
+//   apply_config     the sections of an ApplyConfigCommand
+//   task             the allowlisted action of a predefined task
+//   refresh_snap     RefreshSnap of the payload's service
+//   logs             streams the journal of the payload's service to out
+func RunCommand(ctx context.Context, cmd *command.Command, out io.Writer) (any, error) {
+	log.Printf("running command %s (%s)", cmd.ID, cmd.Type)
+	switch cmd.Type {
+	case command.TypePreflight:
+		return preflight.Run(ctx, preflight.Checks(preflight.Options{})), nil
+
+	case command.TypeRestartService:
+		var p ServiceCommand
+		if err := decodePayload(cmd, &p); err != nil {
+			return nil, err
+		}
+		return RestartService(ctx, p.Service)
+
+	case command.TypeCollectLogs:
+		var p ServiceCommand
+		if err := decodePayload(cmd, &p); err != nil {
+			return nil, err
+		}
+		output, err := Execute(ctx, "logs:"+p.Service)
+		if err != nil {
+			return nil, err
+		}
+		return &LogsResult{Service: p.Service, Output: output}, nil
+
+	case command.TypeApplyConfig:
+		var p ApplyConfigCommand
+		if err := decodePayload(cmd, &p); err != nil {
+			return nil, err
+		}
+		var result ApplyConfigResult
+		if p.TimeSync != nil {
+			changed, err := ApplyTimeSync(ctx, p.TimeSync)
+			if err != nil {
+				return nil, err
+			}
+			result.TimeSyncChanged = changed
+		}
+		return &result, nil
+
+	case command.TypeRefreshSnap:
+		var p ServiceCommand
+		if err := decodePayload(cmd, &p); err != nil {
+			return nil, err
+		}
+		return RefreshSnap(ctx, p.Service)
+
+	case command.TypeTask:
+		var p command.TaskPayload
+		if err := decodePayload(cmd, &p); err != nil {
+			return nil, err
+		}
+		action, ok := tasks[p.Task]
+		if !ok {
+			return nil, fmt.Errorf("%w: task %s", ErrActionNotAllowed, p.Task)
+		}
+		output, err := Execute(ctx, action)
+		if err != nil {
+			return nil, err
+		}
+		return &TaskResult{Task: p.Task, Output: output}, nil
+
+	case command.TypeLogs:
+		var p command.LogsPayload
+		if err := decodePayload(cmd, &p); err != nil {
+			return nil, err
+		}
+		return nil, StreamLogs(ctx, &p, out)
+
+	default:
+		return nil, fmt.Errorf("%w: command %s", ErrActionNotAllowed, cmd.Type)
+	}
+}
+
+// StreamLogs writes the journal of a service since p.Since to out, then, when
+// following, new lines until ctx is cancelled.
+func StreamLogs(ctx context.Context, p *command.LogsPayload, out io.Writer) error {
+	unit, ok := logUnits[p.Service]
+	if !ok {
+		return fmt.Errorf("%w: logs of %s", ErrActionNotAllowed, p.Service)
+	}
+	since := command.DefaultLogsSince
+	if p.Since != "" {
+		d, err := time.ParseDuration(p.Since)
+		if err != nil {
+			return fmt.Errorf("invalid since %q: %w", p.Since, err)
+		}
+		since = d
+	}
+
+	args := []string{"-u", unit, "--since", time.Now().Add(-since).Format(time.DateTime), "--no-pager", "-o", "short-iso"}
+	if p.Follow {
+		args = append(args, "--follow")
+	}
+	c := exec.CommandContext(ctx, "journalctl", args...)
+	var stderr bytes.Buffer
+	c.Stdout, c.Stderr = out, &stderr
+	if err := c.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil // cancelled, e.g. the client stopped following
+		}
+		return fmt.Errorf("journalctl -u %s: %w: %s", unit, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+func decodePayload(cmd *command.Command, v any) error {
+	if cmd.Payload == "" {
+		return fmt.Errorf("command %s needs a payload", cmd.Type)
+	}
+	if err := json.Unmarshal([]byte(cmd.Payload), v); err != nil {
+		return fmt.Errorf("invalid %s payload: %w", cmd.Type, err)
+	}
+	return nil
+}
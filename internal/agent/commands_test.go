@@ -0,0 +1,56 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+
+	"mcloud/internal/command"
+)
+
+func TestTasksAllowlisted(t *testing.T) {
+	for task := range command.Tasks {
+		action, ok := tasks[task]
+		if !ok {
+			t.Errorf("task %s has no action", task)
+			continue
+		}
+		if _, ok := allowedActions[action]; !ok {
+			t.Errorf("task %s runs %s, which is not allowlisted", task, action)
+		}
+	}
+	for task := range tasks {
+		if _, ok := command.Tasks[task]; !ok {
+			t.Errorf("task %s is not in command.Tasks", task)
+		}
+	}
+}
+
+func TestLogUnits(t *testing.T) {
+	for _, service := range command.LogServices {
+		if logUnits[service] == "" {
+			t.Errorf("service %s has no unit", service)
+		}
+	}
+	for service := range logUnits {
+		if !slices.Contains(command.LogServices, service) {
+			t.Errorf("service %s is not in command.LogServices", service)
+		}
+	}
+}
+
+func TestRunCommandRejects(t *testing.T) {
+	cmds := []*command.Command{
+		{Type: command.TypeTask, Payload: `{"task": "rm -rf /"}`},
+		{Type: command.TypeCollectLogs, Payload: `{"service": "sshd"}`},
+		{Type: command.TypeLogs, Payload: `{"service": "sshd"}`},
+		{Type: "shell", Payload: `{"command": "id"}`},
+	}
+	for _, cmd := range cmds {
+		if _, err := RunCommand(context.Background(), cmd, io.Discard); !errors.Is(err, ErrActionNotAllowed) {
+			t.Errorf("%s %s: got %v, want ErrActionNotAllowed", cmd.Type, cmd.Payload, err)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package agent
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"mcloud/internal/config"
+	"mcloud/pkg/reason"
+)
+
+const (
+	// defaultDeliveryConcurrency applies when hooks.concurrency is not set.
+	defaultDeliveryConcurrency = 2
+
+	// defaultDeliveryQueueSize applies when hooks.queue_size is not set.
+	defaultDeliveryQueueSize = 100
+
+	// defaultDeliveryAttempts applies when hooks.max_attempts is not set.
+	defaultDeliveryAttempts = 5
+
+	// deliveryRetryBase is the pause after the first failed attempt; it doubles
+	// with every further failure up to deliveryRetryMax.
+	deliveryRetryBase = 2 * time.Second
+	deliveryRetryMax  = time.Minute
+)
+
+// ErrDeliveryQueueFull is the error of a delivery dead-lettered because its
+// endpoint already had hooks.queue_size deliveries waiting.
+var ErrDeliveryQueueFull = reason.New(reason.RateLimited, "webhook delivery queue is full")
+
+// DeliveryStats describes the webhook delivery queue of this agent.
+//
+// Example JSON:
+//   {"queued": 12, "in_flight": 2, "endpoints": 1, "delivered": 340, "retried": 57, "dead_lettered": 3}
+type DeliveryStats struct {
+	Queued       int    `json:"queued"`    // waiting, across endpoints
+	InFlight     int    `json:"in_flight"` // being delivered or waiting to retry
+	Endpoints    int    `json:"endpoints"` // endpoints with deliveries queued or in flight
+	Delivered    uint64 `json:"delivered"`
+	Retried      uint64 `json:"retried"`
+	DeadLettered uint64 `json:"dead_lettered"`
+}
+
+// delivery is one webhook call. done receives the outcome once it was
+// delivered, or once it was given up on (dead is then true).
+type delivery struct {
+	url     string
+	payload HookPayload
+	done    func(output string, attempts int, err error, dead bool)
+}
+
+// WebhookQueue delivers hook webhooks. Deliveries are queued per endpoint and
+// a few workers per endpoint send them, so a slow or dead URL only holds up
+// its own deliveries, never lifecycle event processing or other endpoints.
+// Failed deliveries are retried with exponential backoff; those that keep
+// failing, or that find their endpoint's queue full, become dead letters.
+type WebhookQueue struct {
+	concurrency int
+	queueSize   int
+	attempts    int
+	timeout     time.Duration // per attempt
+	retryBase   time.Duration
+	retryMax    time.Duration
+	send        func(ctx context.Context, target string, payload HookPayload) (string, error)
+
+	mu        sync.Mutex
+	endpoints map[string]*endpointQueue
+	delivered uint64
+	retried   uint64
+	dead      uint64
+}
+
+type endpointQueue struct {
+	pending  []*delivery
+	workers  int
+	inFlight int
+}
+
+// NewWebhookQueue builds a queue from the hooks config; timeout bounds one attempt.
+func NewWebhookQueue(cfg config.Hooks, timeout time.Duration) *WebhookQueue {
+	q := &WebhookQueue{
+		concurrency: defaultDeliveryConcurrency,
+		queueSize:   defaultDeliveryQueueSize,
+		attempts:    defaultDeliveryAttempts,
+		timeout:     timeout,
+		retryBase:   deliveryRetryBase,
+		retryMax:    deliveryRetryMax,
+		send:        callWebhook,
+		endpoints:   map[string]*endpointQueue{},
+	}
+	if cfg.Concurrency > 0 {
+		q.concurrency = cfg.Concurrency
+	}
+	if cfg.QueueSize > 0 {
+		q.queueSize = cfg.QueueSize
+	}
+	if cfg.MaxAttempts > 0 {
+		q.attempts = cfg.MaxAttempts
+	}
+	return q
+}
+
+// endpointKey groups deliveries by scheme and host, e.g. "https://alerts.example.com".
+func endpointKey(target string) string {
+	u, err := url.Parse(target)
+	if err != nil {
+		return target
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+// Submit queues a delivery and returns at once. If the endpoint's queue is
+// full the delivery is dead-lettered without an attempt.
+func (q *WebhookQueue) Submit(ctx context.Context, d *delivery) {
+	key := endpointKey(d.url)
+
+	q.mu.Lock()
+	ep := q.endpoints[key]
+	if ep == nil {
+		ep = &endpointQueue{}
+		q.endpoints[key] = ep
+	}
+	if len(ep.pending) >= q.queueSize {
+		q.dead++
+		q.mu.Unlock()
+		d.done("", 0, ErrDeliveryQueueFull, true)
+		return
+	}
+	ep.pending = append(ep.pending, d)
+	if ep.workers < q.concurrency {
+		ep.workers++
+		go q.work(ctx, key, ep)
+	}
+	q.mu.Unlock()
+}
+
+// work delivers an endpoint's queue until it is empty, then exits.
+func (q *WebhookQueue) work(ctx context.Context, key string, ep *endpointQueue) {
+	for {
+		q.mu.Lock()
+		if len(ep.pending) == 0 || ctx.Err() != nil {
+			ep.workers--
+			if ep.workers == 0 && len(ep.pending) == 0 {
+				delete(q.endpoints, key)
+			}
+			q.mu.Unlock()
+			return
+		}
+		d := ep.pending[0]
+		ep.pending[0] = nil
+		ep.pending = ep.pending[1:]
+		ep.inFlight++
+		q.mu.Unlock()
+
+		output, attempts, err := q.deliver(ctx, d)
+
+		q.mu.Lock()
+		ep.inFlight--
+		if err != nil {
+			q.dead++
+		} else {
+			q.delivered++
+		}
+		q.mu.Unlock()
+		d.done(output, attempts, err, err != nil)
+	}
+}
+
+// deliver sends d until it succeeds or runs out of attempts.
+func (q *WebhookQueue) deliver(ctx context.Context, d *delivery) (string, int, error) {
+	backoff := q.retryBase
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, q.timeout)
+		output, err := q.send(attemptCtx, d.url, d.payload)
+		cancel()
+		if err == nil || attempt >= q.attempts {
+			return output, attempt, err
+		}
+
+		q.mu.Lock()
+		q.retried++
+		q.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return output, attempt, err
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, q.retryMax)
+	}
+}
+
+// Stats returns the queue's current depth and counters.
+func (q *WebhookQueue) Stats() DeliveryStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	stats := DeliveryStats{
+		Endpoints:    len(q.endpoints),
+		Delivered:    q.delivered,
+		Retried:      q.retried,
+		DeadLettered: q.dead,
+	}
+	for _, ep := range q.endpoints {
+		stats.Queued += len(ep.pending)
+		stats.InFlight += ep.inFlight
+	}
+	return stats
+}
+
+// webhooks is the queue of WatchLifecycle, read by metrics reports and /health.
+var webhooks atomic.Pointer[WebhookQueue]
+
+// WebhookStats returns the stats of the agent's webhook queue (zero before
+// WatchLifecycle started).
+func WebhookStats() DeliveryStats {
+	if q := webhooks.Load(); q != nil {
+		return q.Stats()
+	}
+	return DeliveryStats{}
+}
@@ -0,0 +1,116 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"mcloud/internal/config"
+)
+
+// outcome is what a delivery's done callback received.
+type outcome struct {
+	attempts int
+	err      error
+	dead     bool
+}
+
+func testQueue(cfg config.Hooks, send func(ctx context.Context, target string, payload HookPayload) (string, error)) *WebhookQueue {
+	q := NewWebhookQueue(cfg, time.Second)
+	q.retryBase, q.retryMax = time.Millisecond, 4*time.Millisecond
+	q.send = send
+	return q
+}
+
+func submit(q *WebhookQueue, target string, wg *sync.WaitGroup, out chan<- outcome) {
+	wg.Add(1)
+	q.Submit(context.Background(), &delivery{url: target, done: func(_ string, attempts int, err error, dead bool) {
+		out <- outcome{attempts, err, dead}
+		wg.Done()
+	}})
+}
+
+func TestWebhookQueueRetriesThenDeadLetters(t *testing.T) {
+	var calls atomic.Int32
+	q := testQueue(config.Hooks{MaxAttempts: 3}, func(ctx context.Context, target string, payload HookPayload) (string, error) {
+		if target == "https://flaky.example.com/hook" && calls.Add(1) == 2 {
+			return "ok", nil
+		}
+		return "", errors.New("connection refused")
+	})
+
+	var wg sync.WaitGroup
+	flaky, dead := make(chan outcome, 1), make(chan outcome, 1)
+	submit(q, "https://flaky.example.com/hook", &wg, flaky)
+	wg.Wait()
+	submit(q, "https://dead.example.com/hook", &wg, dead)
+	wg.Wait()
+
+	if got := <-flaky; got.attempts != 2 || got.err != nil || got.dead {
+		t.Errorf("flaky endpoint: %+v, want delivered on attempt 2", got)
+	}
+	if got := <-dead; got.attempts != 3 || got.err == nil || !got.dead {
+		t.Errorf("dead endpoint: %+v, want dead letter after 3 attempts", got)
+	}
+	stats := q.Stats()
+	if stats.Delivered != 1 || stats.DeadLettered != 1 || stats.Retried != 3 || stats.Endpoints != 0 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestWebhookQueuePerEndpointLimits(t *testing.T) {
+	release := make(chan struct{})
+	var inFlight, maxInFlight atomic.Int32
+	q := testQueue(config.Hooks{Concurrency: 2, QueueSize: 3}, func(ctx context.Context, target string, payload HookPayload) (string, error) {
+		if target == "https://other.example.com/hook" {
+			return "", nil
+		}
+		n := inFlight.Add(1)
+		for {
+			m := maxInFlight.Load()
+			if n <= m || maxInFlight.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		<-release
+		inFlight.Add(-1)
+		return "", nil
+	})
+
+	// 2 in flight and 3 waiting fill the slow endpoint; the 6th is dead-lettered at once
+	var wg sync.WaitGroup
+	slow := make(chan outcome, 6)
+	submit(q, "https://slow.example.com/a", &wg, slow)
+	submit(q, "https://slow.example.com/a", &wg, slow)
+	for inFlight.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	for range 3 {
+		submit(q, "https://slow.example.com/a", &wg, slow)
+	}
+	submit(q, "https://slow.example.com/b", &wg, slow)
+	if got := <-slow; !got.dead || !errors.Is(got.err, ErrDeliveryQueueFull) || got.attempts != 0 {
+		t.Errorf("overflow delivery: %+v, want dead letter with ErrDeliveryQueueFull", got)
+	}
+	if stats := q.Stats(); stats.Queued != 3 || stats.InFlight != 2 {
+		t.Errorf("stats = %+v, want 3 queued and 2 in flight", stats)
+	}
+
+	// Other endpoints are not held up by the slow one
+	other := make(chan outcome, 1)
+	var otherWG sync.WaitGroup
+	submit(q, "https://other.example.com/hook", &otherWG, other)
+	otherWG.Wait()
+	if got := <-other; got.dead {
+		t.Errorf("other endpoint: %+v, want delivered", got)
+	}
+
+	close(release)
+	wg.Wait()
+	if m := maxInFlight.Load(); m != 2 {
+		t.Errorf("max deliveries in flight to one endpoint = %d, want 2", m)
+	}
+}
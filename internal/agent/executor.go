@@ -0,0 +1,168 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"mcloud/pkg/commander"
+	"mcloud/pkg/reason"
+)
+
+var (
+	ErrActionNotAllowed = reason.New(reason.ActionNotAllowed, "action not allowed")
+	ErrServiceUnhealthy = reason.New(reason.ServiceUnhealthy, "service is unhealthy")
+)
+
+// allowedActions is the allowlist of commands the agent may run on behalf of the manager.
+// Requests can only name an action; arbitrary command lines are never accepted.
+var allowedActions = map[string][]string{
+	"restart:lxd":       {"snap", "restart", "lxd"},
+	"restart:microceph": {"snap", "restart", "microceph"},
+	"restart:microovn":  {"snap", "restart", "microovn"},
+
+	"refresh:lxd":       {"snap", "refresh", "lxd"},
+	"refresh:microceph": {"snap", "refresh", "microceph"},
+	"refresh:microovn":  {"snap", "refresh", "microovn"},
+
+	"health:lxd":       {"lxc", "info"},
+	"health:microceph": {"microceph", "status"},
+	"health:microovn":  {"microovn", "status"},
+
+	"logs:lxd":       {"snap", "logs", "-n=" + logLines, "lxd"},
+	"logs:microceph": {"snap", "logs", "-n=" + logLines, "microceph"},
+	"logs:microovn":  {"snap", "logs", "-n=" + logLines, "microovn"},
+
+	"ceph:status": {"microceph.ceph", "status"},
+	"logs:rotate": {"logrotate", "--force", "/etc/logrotate.conf"},
+
+	"timesync:restart":  {"systemctl", "restart", "chrony"},
+	"timesync:tracking": {"chronyc", "-c", "tracking"},
+}
+
+// logLines is how many recent log lines a logs action returns.
+const logLines = "500"
+
+const (
+	healthRetries    = 10              // post-restart health check attempts
+	healthRetryDelay = 3 * time.Second // delay between attempts
+)
+
+type ServiceRestartResult struct {
+	Service       string `json:"service"`
+	HealthyBefore bool   `json:"healthy_before"`
+	HealthyAfter  bool   `json:"healthy_after"`
+	Output        string `json:"output"`
+}
+
+// SnapRefreshResult is the result of a refresh_snap command. Output is what
+// snap printed, e.g. "lxd (5.21/stable) 5.21.3-c5ae129 from Canonical✓ refreshed"
+// or "snap \"lxd\" has no updates available".
+type SnapRefreshResult struct {
+	Snap          string `json:"snap"`
+	HealthyBefore bool   `json:"healthy_before"`
+	HealthyAfter  bool   `json:"healthy_after"`
+	Output        string `json:"output"`
+}
+
+// Execute runs an allowlisted action and returns its output.
+// The command is killed if ctx is cancelled (e.g. mcloudd dropped the request).
+func Execute(ctx context.Context, action string) (string, error) {
+	argv, ok := allowedActions[action]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrActionNotAllowed, action)
+	}
+	return commander.ExecCommandContext(ctx, argv[0], argv[1:]...)
+}
+
+// IsRestartable reports whether the service can be restarted through the agent.
+func IsRestartable(service string) bool {
+	_, ok := allowedActions["restart:"+service]
+	return ok
+}
+
+// IsRefreshable reports whether the snap can be refreshed through the agent.
+func IsRefreshable(snap string) bool {
+	_, ok := allowedActions["refresh:"+snap]
+	return ok
+}
+
+// CheckServiceHealth runs the health check action for the given service.
+func CheckServiceHealth(ctx context.Context, service string) error {
+	if _, err := Execute(ctx, "health:"+service); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrServiceUnhealthy, service, err)
+	}
+	return nil
+}
+
+// RestartService restarts a snap-managed service with health checks around it.
+// The pre-check is informational (a stuck service is the reason to restart it);
+// the post-check is retried until the service reports healthy or attempts run out.
+func RestartService(ctx context.Context, service string) (*ServiceRestartResult, error) {
+	if !IsRestartable(service) {
+		return nil, fmt.Errorf("%w: restart %s", ErrActionNotAllowed, service)
+	}
+
+	result := &ServiceRestartResult{Service: service}
+
+	// 1. Pre-restart health check
+	result.HealthyBefore = CheckServiceHealth(ctx, service) == nil
+
+	// 2. Restart
+	output, err := Execute(ctx, "restart:"+service)
+	result.Output = output
+	if err != nil {
+		return result, fmt.Errorf("failed to restart %s: %w", service, err)
+	}
+
+	// 3. Post-restart health check
+	for i := 0; i < healthRetries; i++ {
+		if err = CheckServiceHealth(ctx, service); err == nil {
+			result.HealthyAfter = true
+			return result, nil
+		}
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		case <-time.After(healthRetryDelay):
+		}
+	}
+
+	return result, err
+}
+
+// RefreshSnap refreshes a snap on its tracked channel with the same health
+// checks around it as RestartService. A refresh that leaves the service
+// unhealthy returns the result together with ErrServiceUnhealthy.
+func RefreshSnap(ctx context.Context, snap string) (*SnapRefreshResult, error) {
+	if !IsRefreshable(snap) {
+		return nil, fmt.Errorf("%w: refresh %s", ErrActionNotAllowed, snap)
+	}
+
+	result := &SnapRefreshResult{Snap: snap}
+
+	// 1. Pre-refresh health check
+	result.HealthyBefore = CheckServiceHealth(ctx, snap) == nil
+
+	// 2. Refresh (a snap without updates is not an error)
+	output, err := Execute(ctx, "refresh:"+snap)
+	result.Output = output
+	if err != nil {
+		return result, fmt.Errorf("failed to refresh %s: %w", snap, err)
+	}
+
+	// 3. Post-refresh health check
+	for i := 0; i < healthRetries; i++ {
+		if err = CheckServiceHealth(ctx, snap); err == nil {
+			result.HealthyAfter = true
+			return result, nil
+		}
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		case <-time.After(healthRetryDelay):
+		}
+	}
+
+	return result, err
+}
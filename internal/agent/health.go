@@ -0,0 +1,51 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"time"
+
+	"mcloud/pkg/commander"
+)
+
+// HealthStatus is the agent's view of the components it drives on this node,
+// and of its webhook delivery queue.
+//
+// Example JSON:
+//   {"hostname": "node-2", "breakers": [{"component": "lxd", "state": "closed", "failures": 0}, ...],
+//    "webhooks": {"queued": 0, "in_flight": 1, "endpoints": 1, "delivered": 340, "retried": 57, "dead_lettered": 3}}
+type HealthStatus struct {
+	Hostname string                    `json:"hostname"`
+	Breakers []commander.BreakerStatus `json:"breakers"`
+	Webhooks DeliveryStats             `json:"webhooks"`
+}
+
+// HealthHandler handles GET /health on the agent.
+func HealthHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	hostname, _ := os.Hostname()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(HealthStatus{Hostname: hostname, Breakers: commander.Breakers(), Webhooks: WebhookStats()})
+}
+
+// ProbeBreakers probes components whose circuit breaker is open until ctx is
+// cancelled, so a breaker closes soon after its component recovers. The next
+// metrics report carries the change to mcloudd, which clears the node condition.
+func ProbeBreakers(ctx context.Context) {
+	ticker := time.NewTicker(commander.BreakerCooldown)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			commander.ProbeBreakers(ctx)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"mcloud/internal/config"
+)
+
+// Workload lifecycle hook events.
+const (
+	HookOnStart   = "on-start"
+	HookOnStop    = "on-stop"
+	HookOnFailure = "on-failure" // the instance stopped without an API request (crash or power-off inside)
+)
+
+const (
+	// defaultHookTimeout bounds one hook run when hooks.timeout_seconds is not set.
+	defaultHookTimeout = 30 * time.Second
+
+	// hookOutputLimit caps the command output or webhook response reported to mcloudd.
+	hookOutputLimit = 4096
+
+	// lifecycleRestartDelay is the pause before re-running a monitor that exited.
+	lifecycleRestartDelay = 5 * time.Second
+)
+
+// Hook is a workload lifecycle hook as returned by GET /workloads/hooks on mcloudd.
+type Hook struct {
+	ID         string  `json:"id"`
+	WorkloadID string  `json:"workload_id"`
+	Event      string  `json:"event"`
+	WebhookURL *string `json:"webhook_url,omitempty"`
+	Command    *string `json:"command,omitempty"`
+}
+
+// HookPayload is the JSON body POSTed to webhook hooks.
+type HookPayload struct {
+	WorkloadID string    `json:"workload_id"`
+	Instance   string    `json:"instance"`
+	Event      string    `json:"event"`
+	Node       string    `json:"node"`
+	Time       time.Time `json:"time"`
+}
+
+// HookResult is reported to POST /workloads/hooks/results after a hook ran.
+// A webhook that could not be delivered is reported as a dead letter, with
+// its URL and payload so it can be inspected and sent again.
+type HookResult struct {
+	HookID     string       `json:"hook_id"`
+	WorkloadID string       `json:"workload_id"`
+	Instance   string       `json:"instance"`
+	Event      string       `json:"event"`
+	Node       string       `json:"node"`
+	Success    bool         `json:"success"`
+	Output     string       `json:"output,omitempty"`
+	Error      string       `json:"error,omitempty"`
+	DurationMs int64        `json:"duration_ms"`
+	Attempts   int          `json:"attempts,omitempty"` // webhook deliveries tried
+	DeadLetter bool         `json:"dead_letter,omitempty"`
+	WebhookURL string       `json:"webhook_url,omitempty"` // set on dead letters
+	Payload    *HookPayload `json:"payload,omitempty"`     // set on dead letters
+}
+
+// lifecycleEvent is one line of `lxc monitor --type=lifecycle --format=json`.
+type lifecycleEvent struct {
+	Location string `json:"location"`
+	Metadata struct {
+		Action    string          `json:"action"`
+		Source    string          `json:"source"` // e.g. /1.0/instances/web-1
+		Requestor json.RawMessage `json:"requestor"`
+	} `json:"metadata"`
+}
+
+// hookEvent maps an LXD lifecycle action to a hook event ("" if none applies).
+// A stop that no API client requested is treated as a failure.
+func hookEvent(e *lifecycleEvent) string {
+	switch e.Metadata.Action {
+	case "instance-started":
+		return HookOnStart
+	case "instance-shutdown":
+		return HookOnStop
+	case "instance-stopped":
+		if len(e.Metadata.Requestor) == 0 || string(e.Metadata.Requestor) == "null" {
+			return HookOnFailure
+		}
+		return HookOnStop
+	}
+	return ""
+}
+
+// WatchLifecycle follows LXD lifecycle events for instances on this node and runs
+// the matching workload hooks, reporting every result to mcloudd. Webhooks go
+// through a WebhookQueue. The monitor is restarted if it exits; WatchLifecycle
+// returns when ctx is cancelled.
+func WatchLifecycle(ctx context.Context, manager *ManagerClient, cfg config.Hooks) {
+	hostname, _ := os.Hostname()
+	timeout := defaultHookTimeout
+	if cfg.TimeoutSeconds > 0 {
+		timeout = time.Duration(cfg.TimeoutSeconds) * time.Second
+	}
+	queue := NewWebhookQueue(cfg, timeout)
+	webhooks.Store(queue)
+
+	for ctx.Err() == nil {
+		if err := monitorLifecycle(ctx, func(e *lifecycleEvent) {
+			if e.Location != "" && e.Location != hostname {
+				return
+			}
+			event := hookEvent(e)
+			instance := strings.TrimPrefix(e.Metadata.Source, "/1.0/instances/")
+			if event == "" || instance == e.Metadata.Source {
+				return
+			}
+			// Hooks may be slow (webhooks, commands); don't hold up the event stream
+			go runHooks(ctx, manager, queue, cfg.Commands, timeout, hostname, instance, event)
+		}); err != nil && ctx.Err() == nil {
+			log.Println("lifecycle monitor stopped:", err)
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(lifecycleRestartDelay):
+		}
+	}
+}
+
+func monitorLifecycle(ctx context.Context, handle func(*lifecycleEvent)) error {
+	monitor := exec.CommandContext(ctx, "lxc", "monitor", "--type=lifecycle", "--format=json")
+	stdout, err := monitor.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := monitor.Start(); err != nil {
+		return fmt.Errorf("failed to watch lifecycle events: %w", err)
+	}
+	defer monitor.Wait()
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e lifecycleEvent
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		handle(&e)
+	}
+	return scanner.Err()
+}
+
+// runHooks fetches the hooks for an instance event from mcloudd and runs them in
+// order. Webhooks are queued for delivery and reported once delivered or given up on.
+func runHooks(ctx context.Context, manager *ManagerClient, queue *WebhookQueue, commands map[string][]string, timeout time.Duration, node string, instance string, event string) {
+	var hooks []Hook
+	query := url.Values{"instance": {instance}, "event": {event}}
+	if err := manager.Get(ctx, "/workloads/hooks?"+query.Encode(), &hooks); err != nil {
+		log.Printf("failed to fetch %s hooks for %s: %v", event, instance, err)
+		return
+	}
+
+	for _, hook := range hooks {
+		result := HookResult{
+			HookID:     hook.ID,
+			WorkloadID: hook.WorkloadID,
+			Instance:   instance,
+			Event:      event,
+			Node:       node,
+		}
+		start := time.Now()
+		payload := HookPayload{
+			WorkloadID: hook.WorkloadID,
+			Instance:   instance,
+			Event:      event,
+			Node:       node,
+			Time:       start.UTC(),
+		}
+
+		if hook.WebhookURL != nil {
+			queue.Submit(ctx, &delivery{
+				url:     *hook.WebhookURL,
+				payload: payload,
+				done: func(output string, attempts int, err error, dead bool) {
+					result.Attempts = attempts
+					if dead {
+						result.DeadLetter = true
+						result.WebhookURL = *hook.WebhookURL
+						result.Payload = &payload
+					}
+					reportHook(ctx, manager, result, start, output, err)
+				},
+			})
+			continue
+		}
+
+		hookCtx, cancel := context.WithTimeout(ctx, timeout)
+		output, err := runHook(hookCtx, commands, &hook, payload)
+		cancel()
+		reportHook(ctx, manager, result, start, output, err)
+	}
+}
+
+// reportHook completes a hook result with its outcome and posts it to mcloudd.
+func reportHook(ctx context.Context, manager *ManagerClient, result HookResult, start time.Time, output string, err error) {
+	result.DurationMs = time.Since(start).Milliseconds()
+	result.Success = err == nil
+	if len(output) > hookOutputLimit {
+		output = output[:hookOutputLimit]
+	}
+	result.Output = output
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	if err := manager.Post(ctx, "/workloads/hooks/results", result); err != nil {
+		log.Printf("failed to report hook %s result: %v", result.HookID, err)
+	}
+}
+
+func runHook(ctx context.Context, commands map[string][]string, hook *Hook, payload HookPayload) (string, error) {
+	switch {
+	case hook.Command != nil:
+		// The manager only stores the name; the command line comes from this node's allowlist
+		argv, ok := commands[*hook.Command]
+		if !ok || len(argv) == 0 {
+			return "", fmt.Errorf("%w: hook command %s", ErrActionNotAllowed, *hook.Command)
+		}
+		args := append([]string{"exec", payload.Instance, "--"}, argv...)
+		output, err := exec.CommandContext(ctx, "lxc", args...).CombinedOutput()
+		return string(output), err
+	default:
+		return "", fmt.Errorf("hook %s has neither a webhook nor a command", hook.ID)
+	}
+}
+
+func callWebhook(ctx context.Context, target string, payload HookPayload) (string, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "mcloud-agent")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var body bytes.Buffer
+	body.ReadFrom(io.LimitReader(resp.Body, hookOutputLimit))
+	if resp.StatusCode >= 300 {
+		return body.String(), fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return body.String(), nil
+}
@@ -0,0 +1,182 @@
+package agent
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"mcloud/internal/cert"
+	"mcloud/internal/config"
+	"mcloud/internal/tracing"
+	"mcloud/pkg/client"
+	"mcloud/pkg/commander"
+	"mcloud/pkg/utils"
+)
+
+const (
+	// MetricsInterval is how often the agent samples and reports host metrics.
+	MetricsInterval = 30 * time.Second
+
+	// metricsDiskPath is the filesystem whose usage is reported.
+	metricsDiskPath = "/"
+
+	// defaultManagerTimeout applies to manager calls whose context has no deadline.
+	defaultManagerTimeout = 10 * time.Second
+)
+
+// MetricsReport is the payload the agent sends to mcloudd on every sample.
+// The node is identified by hostname, which is unique within a cluster.
+// Breakers carries the circuit breaker states mcloudd turns into node conditions.
+// HookQueueDepth is the number of webhook deliveries waiting (see WebhookQueue).
+type MetricsReport struct {
+	Hostname         string    `json:"hostname"`
+	CPUCount         int       `json:"cpu_count"`
+	Load1            float64   `json:"load1"`
+	Load5            float64   `json:"load5"`
+	Load15           float64   `json:"load15"`
+	MemoryUsedMB     int       `json:"memory_used_mb"`
+	MemoryTotalMB    int       `json:"memory_total_mb"`
+	DiskUsedBytes    uint64    `json:"disk_used_bytes"`
+	DiskTotalBytes   uint64    `json:"disk_total_bytes"`
+	NetRxBytesPerSec uint64    `json:"net_rx_bytes_per_sec"`
+	NetTxBytesPerSec uint64    `json:"net_tx_bytes_per_sec"`
+	CollectedAt      time.Time `json:"collected_at"`
+	HookQueueDepth   int       `json:"hook_queue_depth"`
+
+	Breakers []commander.BreakerStatus `json:"breakers,omitempty"`
+}
+
+// ManagerClient is used by the agent to call mcloudd.
+// Calls are made over mutual TLS using the cluster CA and the local client certificate.
+type ManagerClient struct {
+	api *client.Client
+}
+
+// NewManagerClient creates a client for the mcloudd REST API at cfg.Agent.ManagerURL.
+// It presents the node certificate, whose CN mcloudd checks against the hostname in
+// reports; configs written before node certificates existed fall back to the client
+// certificate.
+func NewManagerClient(cfg *config.Config) (*ManagerClient, error) {
+	tlsConfig, err := managerTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	api, err := client.New(client.Config{
+		BaseURL: cfg.Agent.ManagerURL,
+		HTTPClient: &http.Client{
+			Transport: tracing.Transport(&http.Transport{
+				TLSClientConfig: tlsConfig,
+			}),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ManagerClient{api: api}, nil
+}
+
+// managerTLSConfig trusts the cluster CA and presents the node certificate (or
+// the client certificate of older configs) to mcloudd.
+func managerTLSConfig(cfg *config.Config) (*tls.Config, error) {
+	caBytes, err := cert.ReadPEM(cfg.Security.CACertPath)
+	if err != nil {
+		return nil, err
+	}
+	caPool := x509.NewCertPool()
+	caPool.AppendCertsFromPEM(caBytes)
+
+	certPath, keyPath := cfg.Security.NodeCertPath, cfg.Security.NodeKeyPath
+	if certPath == "" {
+		certPath, keyPath = cfg.Security.ClientCertPath, cfg.Security.ClientKeyPath
+	}
+	clientCert, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		RootCAs:      caPool,
+		Certificates: []tls.Certificate{clientCert},
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// Post sends body as JSON to the given path and returns an error for non-2xx responses.
+// Calls without a deadline on ctx time out after defaultManagerTimeout.
+func (c *ManagerClient) Post(ctx context.Context, path string, body any) error {
+	return c.do(ctx, http.MethodPost, path, body, nil)
+}
+
+// Get fetches path and decodes the JSON response into out.
+// Calls without a deadline on ctx time out after defaultManagerTimeout.
+func (c *ManagerClient) Get(ctx context.Context, path string, out any) error {
+	return c.do(ctx, http.MethodGet, path, nil, out)
+}
+
+func (c *ManagerClient) do(ctx context.Context, method string, path string, body any, out any) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultManagerTimeout)
+		defer cancel()
+	}
+
+	return c.api.Do(ctx, method, path, body, out)
+}
+
+// ReportMetrics samples host metrics every MetricsInterval and posts them to mcloudd
+// until ctx is cancelled. Network throughput is computed from consecutive samples,
+// so the first report is sent one interval after start.
+// Failed reports are logged and dropped; mcloudd only keeps a rolling window anyway.
+func ReportMetrics(ctx context.Context, client *ManagerClient) {
+	hostname, _ := os.Hostname()
+
+	prev, err := utils.SampleHostMetrics(metricsDiskPath)
+	if err != nil {
+		log.Println("failed to sample host metrics:", err)
+	}
+
+	ticker := time.NewTicker(MetricsInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		cur, err := utils.SampleHostMetrics(metricsDiskPath)
+		if err != nil {
+			log.Println("failed to sample host metrics:", err)
+			continue
+		}
+
+		rx, tx := utils.NetworkThroughput(prev, cur)
+		prev = cur
+
+		report := MetricsReport{
+			Hostname:         hostname,
+			CPUCount:         cur.CPU,
+			Load1:            cur.Load1,
+			Load5:            cur.Load5,
+			Load15:           cur.Load15,
+			MemoryUsedMB:     cur.MemoryUsedMB,
+			MemoryTotalMB:    cur.MemoryTotalMB,
+			DiskUsedBytes:    cur.DiskUsedBytes,
+			DiskTotalBytes:   cur.DiskTotalBytes,
+			NetRxBytesPerSec: rx,
+			NetTxBytesPerSec: tx,
+			CollectedAt:      cur.SampledAt.UTC(),
+			HookQueueDepth:   WebhookStats().Queued,
+			Breakers:         commander.Breakers(),
+		}
+		if err := client.Post(ctx, "/nodes/metrics", report); err != nil {
+			log.Println("failed to report host metrics:", err)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package agent
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"sync"
+	"time"
+
+	"mcloud/internal/auth"
+	"mcloud/internal/constant"
+)
+
+const (
+	// revokedRefresh is how often the revoked serials are fetched from mcloudd.
+	revokedRefresh = time.Minute
+
+	// revokedMaxAge is how long the last fetched serials are used while mcloudd
+	// cannot be reached; after that callers are refused.
+	revokedMaxAge = 10 * time.Minute
+)
+
+// ManagerPeer admits callers of the agent API: only mcloudd, which calls
+// agents with the cluster admin certificate (constant.AdminClientCommonName),
+// and only while that certificate is not revoked. The agent has no database,
+// so the serials revoked with POST /certs/revoke are fetched from mcloudd's
+// GET /certs/revoked, the same list auth.CheckRevoked reads.
+type ManagerPeer struct {
+	fetch func(ctx context.Context) ([]string, error)
+	now   func() time.Time
+
+	mu      sync.Mutex
+	serials map[string]bool
+	fetched time.Time
+}
+
+func NewManagerPeer(manager *ManagerClient) *ManagerPeer {
+	return &ManagerPeer{
+		fetch: func(ctx context.Context) ([]string, error) {
+			var revoked []struct {
+				Serial string `json:"serial"`
+			}
+			if err := manager.Get(ctx, "/certs/revoked", &revoked); err != nil {
+				return nil, err
+			}
+			serials := make([]string, 0, len(revoked))
+			for _, r := range revoked {
+				serials = append(serials, r.Serial)
+			}
+			return serials, nil
+		},
+		now: time.Now,
+	}
+}
+
+// VerifyConnection is a tls.Config.VerifyConnection for the agent API. It
+// runs after the client certificate was verified against the cluster CA.
+//
+// Example Output (Error):
+//   node certificate of node2  =>  node2 is not the cluster manager
+//   revoked admin certificate  =>  ErrCertRevoked: serial 3a9f...
+func (p *ManagerPeer) VerifyConnection(cs tls.ConnectionState) error {
+	if len(cs.VerifiedChains) == 0 {
+		return fmt.Errorf("client certificate required")
+	}
+	leaf := cs.VerifiedChains[0][0]
+	if leaf.Subject.CommonName != constant.AdminClientCommonName {
+		return fmt.Errorf("%s is not the cluster manager", leaf.Subject.CommonName)
+	}
+
+	revoked, err := p.revoked(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to check revoked certificates: %w", err)
+	}
+	serial := fmt.Sprintf("%x", leaf.SerialNumber)
+	if revoked[serial] {
+		return fmt.Errorf("%w: serial %s", auth.ErrCertRevoked, serial)
+	}
+	return nil
+}
+
+// revoked returns the revoked serials, fetching them again once they are
+// older than revokedRefresh.
+func (p *ManagerPeer) revoked(ctx context.Context) (map[string]bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.now()
+	if p.serials != nil && now.Sub(p.fetched) < revokedRefresh {
+		return p.serials, nil
+	}
+
+	list, err := p.fetch(ctx)
+	if err != nil {
+		if p.serials != nil && now.Sub(p.fetched) < revokedMaxAge {
+			return p.serials, nil
+		}
+		return nil, err
+	}
+	p.serials = make(map[string]bool, len(list))
+	for _, serial := range list {
+		p.serials[serial] = true
+	}
+	p.fetched = now
+	return p.serials, nil
+}
@@ -0,0 +1,68 @@
+package agent
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"mcloud/internal/auth"
+	"mcloud/internal/constant"
+)
+
+func TestManagerPeer(t *testing.T) {
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	var fetches int
+	var down bool
+	revoked := []string{"3a9f"}
+	p := &ManagerPeer{
+		fetch: func(context.Context) ([]string, error) {
+			fetches++
+			if down {
+				return nil, errors.New("connection refused")
+			}
+			return revoked, nil
+		},
+		now: func() time.Time { return now },
+	}
+	conn := func(cn string, serial int64) tls.ConnectionState {
+		leaf := &x509.Certificate{Subject: pkix.Name{CommonName: cn}, SerialNumber: big.NewInt(serial)}
+		return tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{leaf}}}
+	}
+
+	if err := p.VerifyConnection(conn(constant.AdminClientCommonName, 0x1b2c)); err != nil {
+		t.Fatalf("manager: got %v", err)
+	}
+	// A node certificate is signed by the same CA, but is not the manager
+	if err := p.VerifyConnection(conn("node2", 0x1b2d)); err == nil {
+		t.Error("node certificate accepted")
+	}
+	if err := p.VerifyConnection(conn(constant.AdminClientCommonName, 0x3a9f)); !errors.Is(err, auth.ErrCertRevoked) {
+		t.Errorf("revoked: got %v, want ErrCertRevoked", err)
+	}
+	if fetches != 1 {
+		t.Errorf("%d fetches within a minute, want 1", fetches)
+	}
+
+	// Revocations are picked up on the next refresh
+	revoked = []string{"3a9f", "1b2c"}
+	now = now.Add(revokedRefresh)
+	if err := p.VerifyConnection(conn(constant.AdminClientCommonName, 0x1b2c)); !errors.Is(err, auth.ErrCertRevoked) {
+		t.Errorf("revoked after refresh: got %v, want ErrCertRevoked", err)
+	}
+
+	// While mcloudd is unreachable the last list is used, until it is too old
+	down = true
+	now = now.Add(revokedRefresh)
+	if err := p.VerifyConnection(conn(constant.AdminClientCommonName, 0x4d5e)); err != nil {
+		t.Errorf("manager unreachable: got %v", err)
+	}
+	now = now.Add(revokedMaxAge)
+	if err := p.VerifyConnection(conn(constant.AdminClientCommonName, 0x4d5e)); err == nil {
+		t.Error("accepted with a stale revocation list")
+	}
+}
@@ -0,0 +1,106 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"mcloud/pkg/reason"
+)
+
+// RestartServiceHandler handles POST /services/{service}/restart on the agent.
+func RestartServiceHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	result, err := RestartService(r.Context(), r.PathValue("service"))
+	if errors.Is(err, ErrActionNotAllowed) {
+		reason.HTTPError(w, err, 400)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		// Still return the result so the manager can see the health check outcome
+		w.WriteHeader(http.StatusBadGateway)
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+// ApplyTimeSyncHandler handles PUT /timesync on the agent.
+func ApplyTimeSyncHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var cfg TimeSyncConfig
+	if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+		reason.HTTPError(w, err, 400)
+		return
+	}
+
+	changed, err := ApplyTimeSync(r.Context(), &cfg)
+	if err != nil {
+		if errors.Is(err, ErrInvalidTimeSync) {
+			reason.HTTPError(w, err, 400)
+			return
+		}
+		reason.HTTPError(w, err, 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"changed": changed})
+}
+
+// TimeSyncStatusHandler handles GET /timesync on the agent.
+func TimeSyncStatusHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	status, err := GetTimeSyncStatus(r.Context())
+	if err != nil {
+		reason.HTTPError(w, err, 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// BenchmarkHandler handles POST /benchmark on the agent.
+func BenchmarkHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	result, err := RunBenchmark()
+	if err != nil {
+		reason.HTTPError(w, err, 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// BenchmarkSinkHandler handles POST /benchmark/sink on the agent.
+// It discards the request body; mcloudd times the upload to measure network throughput.
+func BenchmarkSinkHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if _, err := drain(r.Body); err != nil {
+		reason.HTTPError(w, err, 400)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,164 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// drainedMarkerPath records that the node was drained before shutting down,
+	// so the agent restores it in LXD on the next boot.
+	drainedMarkerPath = "/var/lib/mcloud/drained"
+
+	// logindDropInPath raises logind's limit on how long delay inhibitors may hold up shutdown.
+	logindDropInPath = "/etc/systemd/logind.conf.d/mcloud-agent.conf"
+
+	// DefaultDrainTimeout is used when agent.drain_timeout_seconds is not set.
+	DefaultDrainTimeout = 5 * time.Minute
+)
+
+// prepareForShutdownMatch selects logind's PrepareForShutdown signal on the system bus.
+const prepareForShutdownMatch = "type='signal',interface='org.freedesktop.login1.Manager',member='PrepareForShutdown'"
+
+// ensureInhibitDelay makes sure logind lets delay inhibitors hold shutdown for at least max.
+// The default (5s) is far too short to migrate workloads.
+func ensureInhibitDelay(max time.Duration) error {
+	data := fmt.Sprintf("# Managed by mcloud-agent\n[Login]\nInhibitDelayMaxSec=%d\n", int(max.Seconds()))
+
+	if current, err := os.ReadFile(logindDropInPath); err == nil && string(current) == data {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(logindDropInPath), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(logindDropInPath, []byte(data), 0644); err != nil {
+		return err
+	}
+
+	// logind re-reads its configuration on SIGHUP
+	return exec.Command("systemctl", "kill", "--signal=SIGHUP", "systemd-logind").Run()
+}
+
+// inhibitLock is a logind "delay" inhibitor lock on shutdown, held by a
+// systemd-inhibit child process for as long as it runs.
+type inhibitLock struct {
+	cmd *exec.Cmd
+}
+
+func takeInhibitLock() (*inhibitLock, error) {
+	cmd := exec.Command("systemd-inhibit",
+		"--what=shutdown",
+		"--mode=delay",
+		"--who=mcloud-agent",
+		"--why=Moving workloads to other nodes",
+		"sleep", "infinity",
+	)
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to take shutdown inhibitor lock: %w", err)
+	}
+	return &inhibitLock{cmd: cmd}, nil
+}
+
+func (l *inhibitLock) release() {
+	if l == nil {
+		return
+	}
+	l.cmd.Process.Kill()
+	l.cmd.Wait()
+}
+
+// HandleShutdown holds a shutdown inhibitor lock and, when logind announces a
+// shutdown or reboot, asks mcloudd to drain this node before releasing the lock.
+// The drain is bounded by timeout, so a dead manager cannot block shutdown for
+// longer than that. It runs until ctx is cancelled.
+func HandleShutdown(ctx context.Context, manager *ManagerClient, timeout time.Duration) error {
+	if err := ensureInhibitDelay(timeout + 30*time.Second); err != nil {
+		log.Println("failed to raise logind InhibitDelayMaxSec:", err)
+	}
+
+	lock, err := takeInhibitLock()
+	if err != nil {
+		return err
+	}
+	defer func() { lock.release() }()
+
+	monitor := exec.CommandContext(ctx, "busctl", "monitor", "--system", "--json=short", "--match", prepareForShutdownMatch)
+	stdout, err := monitor.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := monitor.Start(); err != nil {
+		return fmt.Errorf("failed to watch for shutdown: %w", err)
+	}
+	defer monitor.Wait()
+
+	hostname, _ := os.Hostname()
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var msg struct {
+			Member  string `json:"member"`
+			Payload struct {
+				Data []bool `json:"data"`
+			} `json:"payload"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil || msg.Member != "PrepareForShutdown" || len(msg.Payload.Data) == 0 {
+			continue
+		}
+
+		if !msg.Payload.Data[0] {
+			// Shutdown was cancelled: hold the lock again for the next one
+			if lock == nil {
+				if lock, err = takeInhibitLock(); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		log.Println("shutdown requested, draining node")
+		drainCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := manager.Post(drainCtx, "/nodes/drain", map[string]any{
+			"hostname":        hostname,
+			"timeout_seconds": int(timeout.Seconds()),
+		})
+		cancel()
+		if err != nil {
+			log.Println("failed to drain node, shutting down anyway:", err)
+		} else {
+			log.Println("node drained")
+		}
+
+		// Restore on the next boot even if the drain only partly finished
+		if err := os.WriteFile(drainedMarkerPath, nil, 0600); err != nil {
+			log.Println("failed to write drain marker:", err)
+		}
+
+		lock.release()
+		lock = nil
+	}
+
+	return scanner.Err()
+}
+
+// RestoreAfterBoot asks mcloudd to restore this node if it was drained before the last shutdown.
+func RestoreAfterBoot(ctx context.Context, manager *ManagerClient) error {
+	if _, err := os.Stat(drainedMarkerPath); os.IsNotExist(err) {
+		return nil
+	}
+
+	hostname, _ := os.Hostname()
+	restoreCtx, cancel := context.WithTimeout(ctx, DefaultDrainTimeout)
+	defer cancel()
+	if err := manager.Post(restoreCtx, "/nodes/restore", map[string]string{"hostname": hostname}); err != nil {
+		return err
+	}
+	return os.Remove(drainedMarkerPath)
+}
@@ -0,0 +1,195 @@
+package agent
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"mcloud/pkg/reason"
+)
+
+// DefaultStandbyPath is where a standby node keeps the manager database copy.
+const DefaultStandbyPath = "/var/lib/mcloud/standby/mcloud.db"
+
+// standbyChecksumHeader carries the SHA-256 of the uncompressed snapshot.
+const standbyChecksumHeader = "X-Mcloud-Snapshot-Sha256"
+
+// StandbyStatus describes the latest database snapshot a standby node holds.
+type StandbyStatus struct {
+	Path       string    `json:"path"`
+	SHA256     string    `json:"sha256"`
+	Size       int64     `json:"size"`
+	ReceivedAt time.Time `json:"received_at"`
+}
+
+// StandbySnapshotHandler handles PUT /standby/snapshot on the agent: it stores a
+// gzip-compressed SQLite snapshot shipped by the leader at path, replacing the
+// previous copy only once the new one is complete and its checksum matches.
+func StandbySnapshotHandler(path string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		want := r.Header.Get(standbyChecksumHeader)
+		if want == "" {
+			reason.HTTPErrorf(w, 400, "%s is required", standbyChecksumHeader)
+			return
+		}
+
+		status, err := receiveSnapshot(path, r.Body, want)
+		if err != nil {
+			reason.HTTPError(w, err, 400)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(status)
+	}
+}
+
+// StandbyStatusHandler handles GET /standby on the agent.
+func StandbyStatusHandler(path string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		data, err := os.ReadFile(path + ".json")
+		if os.IsNotExist(err) {
+			reason.HTTPErrorf(w, 404, "no snapshot received")
+			return
+		}
+		if err != nil {
+			reason.HTTPError(w, err, 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	}
+}
+
+// receiveSnapshot decompresses body next to path, checks it against the
+// expected checksum and renames it into place, then records its status in
+// path.json.
+func receiveSnapshot(path string, body io.Reader, wantSHA256 string) (*StandbyStatus, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+
+	zr, err := gzip.NewReader(body)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot is not gzip-compressed: %w", err)
+	}
+	defer zr.Close()
+
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp)
+
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(f, h), zr)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("receive snapshot: %w", err)
+	}
+
+	got := hex.EncodeToString(h.Sum(nil))
+	if !strings.EqualFold(got, wantSHA256) {
+		return nil, fmt.Errorf("snapshot checksum mismatch: got %s, want %s", got, wantSHA256)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return nil, err
+	}
+
+	status := &StandbyStatus{Path: path, SHA256: got, Size: size, ReceivedAt: time.Now().UTC()}
+	meta, err := json.Marshal(status)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path+".json", meta, 0600); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// ShipStandbySnapshot sends the SQLite snapshot at snapshotPath to the agent,
+// gzip-compressed, and returns what the standby stored.
+func (c *Client) ShipStandbySnapshot(ctx context.Context, snapshotPath string) (*StandbyStatus, error) {
+	sum, err := fileSHA256(snapshotPath)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(snapshotPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// Compress while uploading instead of buffering the whole snapshot
+	pr, pw := io.Pipe()
+	go func() {
+		zw := gzip.NewWriter(pw)
+		_, err := io.Copy(zw, f)
+		if err == nil {
+			err = zw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, c.baseURL+"/standby/snapshot", pr)
+	if err != nil {
+		pr.Close()
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/gzip")
+	req.Header.Set(standbyChecksumHeader, sum)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("agent failed to store standby snapshot: %s: %w", resp.Status, reason.FromResponse(resp.StatusCode, resp.Header, msg))
+	}
+
+	var status StandbyStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
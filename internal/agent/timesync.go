@@ -0,0 +1,111 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"mcloud/pkg/reason"
+)
+
+// chronyConfPath is a drop-in read by the stock chrony.conf on Ubuntu/Debian
+// (confdir /etc/chrony/conf.d), so the distribution config stays untouched.
+const chronyConfPath = "/etc/chrony/conf.d/mcloud.conf"
+
+var ErrInvalidTimeSync = reason.New(reason.InvalidTimeSync, "invalid time sync config")
+
+// TimeSyncConfig is the chrony configuration mcloudd pushes to a node.
+type TimeSyncConfig struct {
+	Servers      []string `json:"servers"`       // NTP servers to sync from; empty keeps the distribution pools
+	Allow        []string `json:"allow"`         // clients allowed to sync from this node (leader only)
+	LocalStratum bool     `json:"local_stratum"` // keep serving time to members if upstream is unreachable
+}
+
+// TimeSyncStatus is the node's current clock state as reported by chrony.
+type TimeSyncStatus struct {
+	ReferenceID   string  `json:"reference_id"`
+	Stratum       int     `json:"stratum"`
+	OffsetSeconds float64 `json:"offset_seconds"` // difference between system time and NTP time
+	LeapStatus    string  `json:"leap_status"`
+	Synchronized  bool    `json:"synchronized"`
+}
+
+// renderChronyConf builds the drop-in file contents from the config.
+func renderChronyConf(cfg *TimeSyncConfig) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString("# Managed by mcloud. Local changes will be overwritten.\n")
+	for _, s := range cfg.Servers {
+		if s == "" || strings.ContainsAny(s, " \t\n") {
+			return nil, fmt.Errorf("%w: server %q", ErrInvalidTimeSync, s)
+		}
+		fmt.Fprintf(&b, "server %s iburst\n", s)
+	}
+	for _, a := range cfg.Allow {
+		if a == "" || strings.ContainsAny(a, " \t\n") {
+			return nil, fmt.Errorf("%w: allow %q", ErrInvalidTimeSync, a)
+		}
+		fmt.Fprintf(&b, "allow %s\n", a)
+	}
+	if cfg.LocalStratum {
+		b.WriteString("local stratum 10\n")
+	}
+	return b.Bytes(), nil
+}
+
+// ApplyTimeSync writes the chrony drop-in and restarts chrony if the file changed.
+//
+// Returns:
+//   - bool: true if the configuration changed and chrony was restarted
+//   - error: if the config is invalid or chrony could not be restarted
+func ApplyTimeSync(ctx context.Context, cfg *TimeSyncConfig) (bool, error) {
+	data, err := renderChronyConf(cfg)
+	if err != nil {
+		return false, err
+	}
+
+	if current, err := os.ReadFile(chronyConfPath); err == nil && bytes.Equal(current, data) {
+		return false, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(chronyConfPath), 0755); err != nil {
+		return false, err
+	}
+	if err := os.WriteFile(chronyConfPath, data, 0644); err != nil {
+		return false, err
+	}
+
+	if _, err := Execute(ctx, "timesync:restart"); err != nil {
+		return true, fmt.Errorf("failed to restart chrony: %w", err)
+	}
+	return true, nil
+}
+
+// GetTimeSyncStatus parses `chronyc -c tracking`, e.g.
+// C0A8010A,192.168.1.10,3,1767436245.123,0.000012345,...,Normal
+func GetTimeSyncStatus(ctx context.Context) (*TimeSyncStatus, error) {
+	output, err := Execute(ctx, "timesync:tracking")
+	if err != nil {
+		return nil, err
+	}
+
+	fields := strings.Split(strings.TrimSpace(output), ",")
+	if len(fields) < 14 {
+		return nil, fmt.Errorf("unexpected chronyc output: %q", output)
+	}
+
+	status := &TimeSyncStatus{
+		ReferenceID: fields[1],
+		LeapStatus:  fields[13],
+	}
+	status.Stratum, _ = strconv.Atoi(fields[2])
+	if status.OffsetSeconds, err = strconv.ParseFloat(fields[4], 64); err != nil {
+		return nil, fmt.Errorf("unexpected chronyc offset: %q", fields[4])
+	}
+	status.Synchronized = status.LeapStatus != "Not synchronised" && status.Stratum > 0
+
+	return status, nil
+}
@@ -0,0 +1,59 @@
+package alert
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"mcloud/internal/database"
+	"mcloud/pkg/reason"
+	"mcloud/pkg/utils"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(s *Service) *Handler {
+	return &Handler{service: s}
+}
+
+// ListAlertsResponse is one page of GET /alerts.
+type ListAlertsResponse struct {
+	Items      []database.Alert `json:"items"`
+	NextOffset int              `json:"next_offset,omitempty"` // 0 when there are no more pages
+}
+
+// ListAlerts handles GET /alerts?limit=100&offset=0, the alert history, newest
+// first. Optional parameters:
+//   sort                              fired_at, severity or rule; "-" prefix for descending
+//   status, rule, subject, severity   exact-match filters, e.g. status=firing
+func (h *Handler) ListAlerts(w http.ResponseWriter, r *http.Request) {
+	limit, offset, err := utils.ParsePage(r)
+	if err != nil {
+		reason.HTTPError(w, err, 400)
+		return
+	}
+
+	sort, filter := utils.ParseListQuery(r, "status", "rule", "subject", "severity")
+	alerts, err := h.service.List(r.Context(), database.ListOptions{Limit: limit, Offset: offset, Sort: sort, Filter: filter})
+	if errors.Is(err, database.ErrInvalidListOption) {
+		reason.HTTPError(w, err, 400)
+		return
+	}
+	if err != nil {
+		reason.HTTPError(w, err, 500)
+		return
+	}
+
+	resp := ListAlertsResponse{Items: alerts}
+	if resp.Items == nil {
+		resp.Items = []database.Alert{}
+	}
+	if len(alerts) == limit {
+		resp.NextOffset = offset + limit
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,10 @@
+package alert
+
+import (
+	"net/http"
+)
+
+// InitModule registers the alert routes served by handler.
+func InitModule(mux *http.ServeMux, handler *Handler) {
+	mux.HandleFunc("GET /alerts", handler.ListAlerts)
+}
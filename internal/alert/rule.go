@@ -0,0 +1,101 @@
+package alert
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"mcloud/pkg/reason"
+	"mcloud/services/microceph"
+)
+
+// ErrInvalidRule is returned by ParseRule for a rule it does not understand.
+var ErrInvalidRule = reason.New(reason.InvalidRequest, "invalid alert rule")
+
+// Rule kinds.
+const (
+	KindNodeOffline = "node offline" // a node's agent is disconnected for longer than Threshold
+	KindCeph        = "ceph"         // Ceph health is Health or worse
+	KindCAExpiry    = "ca expires"   // a cluster CA expires within Threshold
+	KindCertExpiry  = "cert expires" // a server, client or node certificate expires within Threshold
+)
+
+// DefaultRules are evaluated when alerts.rules is empty.
+var DefaultRules = []string{"node offline > 5m", "ceph HEALTH_ERR", "ca expires < 30d"}
+
+// Rule is one parsed alert rule.
+type Rule struct {
+	Expr      string // normalized rule, e.g. "node offline > 5m"; alerts are recorded under it
+	Kind      string
+	Threshold time.Duration // node offline and expiry rules
+	Health    string        // ceph rules: HEALTH_WARN or HEALTH_ERR
+}
+
+// ParseRule parses an alert rule:
+//   node offline > <duration>
+//   ceph HEALTH_WARN | HEALTH_ERR
+//   ca expires < <duration>
+//   cert expires < <duration>
+// Durations are Go durations (90s, 5m, 12h) or whole days (30d).
+//
+// Example Input:
+//   ParseRule("ca  expires < 30d")
+//
+// Example Output:
+//   &Rule{Expr: "ca expires < 30d", Kind: "ca expires", Threshold: 720h}
+//
+// Example Output (Error):
+//   ParseRule("ceph HEALTH_OK")  =>  ErrInvalidRule: "ceph HEALTH_OK": ceph rules take HEALTH_WARN or HEALTH_ERR
+func ParseRule(expr string) (*Rule, error) {
+	fields := strings.Fields(expr)
+	fail := func(format string, args ...any) (*Rule, error) {
+		return nil, fmt.Errorf("%w: %q: %s", ErrInvalidRule, expr, fmt.Sprintf(format, args...))
+	}
+	if len(fields) == 0 {
+		return fail("empty rule")
+	}
+
+	switch {
+	case fields[0] == "ceph":
+		if len(fields) != 2 {
+			return fail("want ceph HEALTH_WARN or ceph HEALTH_ERR")
+		}
+		health := strings.ToUpper(fields[1])
+		if health != microceph.HealthWarn && health != microceph.HealthErr {
+			return fail("ceph rules take %s or %s", microceph.HealthWarn, microceph.HealthErr)
+		}
+		return &Rule{Expr: "ceph " + health, Kind: KindCeph, Health: health}, nil
+
+	case len(fields) >= 2 && strings.Join(fields[:2], " ") == KindNodeOffline:
+		return parseThreshold(expr, fields, KindNodeOffline, ">")
+
+	case len(fields) >= 2 && (strings.Join(fields[:2], " ") == KindCAExpiry || strings.Join(fields[:2], " ") == KindCertExpiry):
+		return parseThreshold(expr, fields, strings.Join(fields[:2], " "), "<")
+	}
+	return fail("unknown condition, want node offline, ceph, ca expires or cert expires")
+}
+
+// parseThreshold parses the "<op> <duration>" that ends a rule of kind.
+func parseThreshold(expr string, fields []string, kind string, op string) (*Rule, error) {
+	if len(fields) != 4 || fields[2] != op {
+		return nil, fmt.Errorf("%w: %q: want %s %s <duration>", ErrInvalidRule, expr, kind, op)
+	}
+	d, err := parseDuration(fields[3])
+	if err != nil || d <= 0 {
+		return nil, fmt.Errorf("%w: %q: invalid duration %q", ErrInvalidRule, expr, fields[3])
+	}
+	return &Rule{Expr: strings.Join(fields, " "), Kind: kind, Threshold: d}, nil
+}
+
+// parseDuration accepts Go durations and whole days, e.g. 30d.
+func parseDuration(v string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(v, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(v)
+}
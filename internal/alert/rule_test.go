@@ -0,0 +1,35 @@
+package alert
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		expr string
+		want Rule
+	}{
+		{"node offline > 5m", Rule{Expr: "node offline > 5m", Kind: KindNodeOffline, Threshold: 5 * time.Minute}},
+		{"ceph health_err", Rule{Expr: "ceph HEALTH_ERR", Kind: KindCeph, Health: "HEALTH_ERR"}},
+		{"ca  expires <  30d", Rule{Expr: "ca expires < 30d", Kind: KindCAExpiry, Threshold: 30 * 24 * time.Hour}},
+		{"cert expires < 36h", Rule{Expr: "cert expires < 36h", Kind: KindCertExpiry, Threshold: 36 * time.Hour}},
+	}
+	for _, tt := range tests {
+		got, err := ParseRule(tt.expr)
+		if err != nil {
+			t.Errorf("ParseRule(%q): %v", tt.expr, err)
+			continue
+		}
+		if *got != tt.want {
+			t.Errorf("ParseRule(%q) = %+v, want %+v", tt.expr, *got, tt.want)
+		}
+	}
+
+	for _, expr := range []string{"", "ceph HEALTH_OK", "ceph", "node offline < 5m", "node offline > soon", "ca expires < -1d", "disk full"} {
+		if _, err := ParseRule(expr); !errors.Is(err, ErrInvalidRule) {
+			t.Errorf("ParseRule(%q): got %v, want ErrInvalidRule", expr, err)
+		}
+	}
+}
@@ -0,0 +1,340 @@
+// Package alert raises alerts from the rules in alerts.rules, e.g. "node
+// offline > 5m", "ceph HEALTH_ERR" or "ca expires < 30d". Every run of the
+// alerts job evaluates each rule; a rule that holds for a subject (a node, a
+// certificate, the Ceph cluster) opens an alert, which stays firing until the
+// condition clears. Alerts are deduplicated per rule and subject, recorded in
+// the alert history with an alert.fired or alert.resolved event, and mailed
+// through SMTP when configured - at most once per cooldown while an alert keeps
+// firing or flapping, and once more when it resolves.
+package alert
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"mcloud/internal/certificate"
+	"mcloud/internal/command"
+	"mcloud/internal/config"
+	"mcloud/internal/database"
+	"mcloud/internal/event"
+	"mcloud/pkg/logger"
+	"mcloud/services/microceph"
+)
+
+var log = logger.Named("alert")
+
+const (
+	// Interval is how often the rules are evaluated.
+	Interval = time.Minute
+
+	// DefaultCooldown is used when alerts.cooldown_minutes is not set.
+	DefaultCooldown = time.Hour
+)
+
+// condition is a rule holding for one subject.
+type condition struct {
+	subject  string
+	severity string
+	message  string
+}
+
+type Service struct {
+	db       *sql.DB
+	rules    []*Rule
+	cooldown time.Duration
+	notifier Notifier // nil when alerts are not mailed
+
+	agents func() []command.Agent
+	certs  *certificate.Service
+	ceph   func() (*microceph.ClusterStatus, error)
+
+	run     sync.Mutex           // serializes runs
+	offline map[string]time.Time // hostname -> first run its agent was seen disconnected, guarded by run
+	failing map[string]string    // rule -> last evaluation error, guarded by run
+}
+
+// NewService returns the alert service for cfg.Alerts. Invalid rules and an
+// incomplete SMTP config are logged and skipped rather than failing startup.
+func NewService(db *sql.DB, cfg *config.Config, hub *command.Hub, certs *certificate.Service) *Service {
+	exprs := cfg.Alerts.Rules
+	if len(exprs) == 0 {
+		exprs = DefaultRules
+	}
+	var rules []*Rule
+	for _, expr := range exprs {
+		r, err := ParseRule(expr)
+		if err != nil {
+			log.Warn("Ignoring alert rule: %v", err)
+			continue
+		}
+		rules = append(rules, r)
+	}
+
+	s := &Service{
+		db:       db,
+		rules:    rules,
+		cooldown: DefaultCooldown,
+		agents:   hub.Agents,
+		certs:    certs,
+		ceph:     microceph.Status,
+		offline:  map[string]time.Time{},
+		failing:  map[string]string{},
+	}
+	if cfg.Alerts.CooldownMinutes > 0 {
+		s.cooldown = time.Duration(cfg.Alerts.CooldownMinutes) * time.Minute
+	}
+	if cfg.Alerts.SMTP.Host != "" {
+		n, err := NewSMTPNotifier(cfg.Alerts.SMTP)
+		if err != nil {
+			log.Warn("Alerts will not be mailed: %v", err)
+		} else {
+			s.notifier = n
+		}
+	}
+	return s
+}
+
+// List returns the alert history, newest first by default.
+//
+// Example Input:
+//   List(ctx, database.ListOptions{Limit: 50, Filter: map[string]string{"status": "firing"}})
+func (s *Service) List(ctx context.Context, opts database.ListOptions) ([]database.Alert, error) {
+	return database.NewAlertRepository(s.db).List(ctx, opts)
+}
+
+// Evaluate is the alerts job: it checks every rule, opens alerts for new
+// conditions, resolves alerts whose condition cleared and sends the mails that
+// are due. Alerts of a rule that could not be checked (e.g. Ceph did not
+// answer) are left as they are; those of rules no longer configured resolve.
+func (s *Service) Evaluate(ctx context.Context) error {
+	s.run.Lock()
+	defer s.run.Unlock()
+
+	now := time.Now()
+	firing := map[string]map[string]condition{} // rule -> subject -> condition
+	checked := map[string]bool{}
+	for _, r := range s.rules {
+		checked[r.Expr] = true
+		conds, err := s.check(ctx, r, now)
+		if err != nil {
+			// Only log when the error changes; a missing Ceph fails every minute
+			if s.failing[r.Expr] != err.Error() {
+				log.Warn("Failed to evaluate alert rule %q: %v", r.Expr, err)
+				s.failing[r.Expr] = err.Error()
+			}
+			continue
+		}
+		delete(s.failing, r.Expr)
+		firing[r.Expr] = map[string]condition{}
+		for _, c := range conds {
+			firing[r.Expr][c.subject] = c
+		}
+	}
+
+	repo := database.NewAlertRepository(s.db)
+	open, err := repo.ListFiring(ctx)
+	if err != nil {
+		return err
+	}
+	for i := range open {
+		a := &open[i]
+		conds, ok := firing[a.Rule]
+		if !ok && checked[a.Rule] {
+			continue
+		}
+		if c, ok := conds[a.Subject]; ok {
+			delete(conds, a.Subject)
+			if c.message != a.Message {
+				a.Message = c.message
+				if err := repo.SetMessage(ctx, a.ID, a.Message); err != nil {
+					return err
+				}
+			}
+			s.notifyDue(ctx, a, now)
+			continue
+		}
+
+		if err := repo.Resolve(ctx, a.ID); err != nil {
+			return err
+		}
+		a.Status, a.ResolvedAt = database.AlertResolved, &now
+		log.Info("Alert %q resolved for %s", a.Rule, a.Subject)
+		s.event(ctx, event.AlertResolvedEvent(a))
+		if a.Notifications > 0 {
+			s.notify(ctx, a, now)
+		}
+	}
+
+	for _, r := range s.rules {
+		conds := firing[r.Expr]
+		subjects := make([]string, 0, len(conds))
+		for subject := range conds {
+			subjects = append(subjects, subject)
+		}
+		slices.Sort(subjects)
+		for _, subject := range subjects {
+			c := conds[subject]
+			a := &database.Alert{Rule: r.Expr, Subject: subject, Severity: c.severity, Message: c.message}
+			if err := repo.Create(ctx, a); err != nil {
+				return err
+			}
+			log.Warn("Alert %q fired for %s: %s", a.Rule, a.Subject, a.Message)
+			s.event(ctx, event.AlertFiredEvent(a))
+			s.notifyDue(ctx, a, now)
+		}
+	}
+	return nil
+}
+
+// check returns the subjects rule r holds for.
+func (s *Service) check(ctx context.Context, r *Rule, now time.Time) ([]condition, error) {
+	switch r.Kind {
+	case KindNodeOffline:
+		return s.checkNodes(ctx, r, now)
+	case KindCeph:
+		return s.checkCeph(r)
+	case KindCAExpiry, KindCertExpiry:
+		return s.checkCerts(ctx, r, now)
+	}
+	return nil, fmt.Errorf("unknown rule kind %q", r.Kind)
+}
+
+// checkNodes reports the nodes whose agent has not been connected since a run
+// more than r.Threshold ago. The time a node went offline is only known to
+// this process, so after a restart offline nodes alert r.Threshold later.
+func (s *Service) checkNodes(ctx context.Context, r *Rule, now time.Time) ([]condition, error) {
+	clusters, err := database.NewClusterRepository(s.db).List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	connected := map[string]bool{}
+	for _, a := range s.agents() {
+		connected[a.Node] = true
+	}
+
+	var conds []condition
+	offline := map[string]time.Time{}
+	for _, c := range clusters {
+		nodes, err := database.NewNodeRepository(s.db).ListByCluster(ctx, c.ID, database.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range nodes {
+			if connected[n.Hostname] {
+				continue
+			}
+			since, ok := s.offline[n.Hostname]
+			if !ok {
+				since = now
+			}
+			offline[n.Hostname] = since
+			if now.Sub(since) > r.Threshold {
+				conds = append(conds, condition{
+					subject:  n.Hostname,
+					severity: database.SeverityCritical,
+					message:  fmt.Sprintf("Node %s has been offline since %s: its agent is not connected", n.Hostname, since.Format(time.DateTime)),
+				})
+			}
+		}
+	}
+	s.offline = offline
+	return conds, nil
+}
+
+// healthRank orders Ceph health states from best to worst.
+var healthRank = map[string]int{microceph.HealthOK: 0, microceph.HealthWarn: 1, microceph.HealthErr: 2}
+
+// checkCeph reports the Ceph cluster when its health is r.Health or worse.
+func (s *Service) checkCeph(r *Rule) ([]condition, error) {
+	st, err := s.ceph()
+	if err != nil {
+		return nil, err
+	}
+	if healthRank[st.Health] < healthRank[r.Health] {
+		return nil, nil
+	}
+	severity := database.SeverityWarning
+	if st.Health == microceph.HealthErr {
+		severity = database.SeverityCritical
+	}
+	message := "Ceph is " + st.Health
+	if len(st.Checks) > 0 {
+		message += ": " + strings.Join(st.Checks, "; ")
+	}
+	return []condition{{subject: "ceph", severity: severity, message: message}}, nil
+}
+
+// checkCerts reports the CAs (ca expires) or other certificates (cert expires)
+// that expire within r.Threshold. Retired and revoked ones are not in use.
+func (s *Service) checkCerts(ctx context.Context, r *Rule, now time.Time) ([]condition, error) {
+	certs, err := s.certs.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var conds []condition
+	for _, c := range certs {
+		isCA := c.Kind == database.CAKindRoot || c.Kind == database.CAKindIntermediate
+		if isCA != (r.Kind == KindCAExpiry) || c.Status == certificate.StatusRetired || c.Status == certificate.StatusRevoked {
+			continue
+		}
+		left := c.NotAfter.Sub(now)
+		if left >= r.Threshold {
+			continue
+		}
+		cond := condition{
+			subject:  c.ID,
+			severity: database.SeverityWarning,
+			message:  fmt.Sprintf("The %s certificate %s expires on %s", c.Kind, c.Subject, c.NotAfter.Local().Format(time.DateTime)),
+		}
+		if left <= 0 {
+			cond.severity = database.SeverityCritical
+			cond.message = fmt.Sprintf("The %s certificate %s expired on %s", c.Kind, c.Subject, c.NotAfter.Local().Format(time.DateTime))
+		}
+		conds = append(conds, cond)
+	}
+	return conds, nil
+}
+
+// notifyDue mails a unless a mail about its rule and subject went out less
+// than a cooldown ago.
+func (s *Service) notifyDue(ctx context.Context, a *database.Alert, now time.Time) {
+	if s.notifier == nil {
+		return
+	}
+	last, err := database.NewAlertRepository(s.db).LastNotifiedAt(ctx, a.Rule, a.Subject)
+	if err != nil {
+		log.Error("Failed to read notifications of alert %d: %v", a.ID, err)
+		return
+	}
+	if last != nil && now.Sub(*last) < s.cooldown {
+		return
+	}
+	s.notify(ctx, a, now)
+}
+
+// notify mails a and records it. A failed mail about a firing alert is retried
+// on the next run.
+func (s *Service) notify(ctx context.Context, a *database.Alert, now time.Time) {
+	if s.notifier == nil {
+		return
+	}
+	if err := s.notifier.Notify(ctx, a); err != nil {
+		log.Error("Failed to send alert %d: %v", a.ID, err)
+		return
+	}
+	if err := database.NewAlertRepository(s.db).MarkNotified(ctx, a.ID, now); err != nil {
+		log.Error("Failed to record notification of alert %d: %v", a.ID, err)
+	}
+}
+
+func (s *Service) event(ctx context.Context, e *database.Event) {
+	if err := database.NewEventRepository(s.db).Create(ctx, e); err != nil {
+		log.Error("Failed to record event %s: %v", e.Type, err)
+	}
+}
@@ -0,0 +1,103 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"mcloud/internal/certificate"
+	"mcloud/internal/command"
+	"mcloud/internal/config"
+	"mcloud/internal/database"
+	"mcloud/internal/operation"
+	"mcloud/services/microceph"
+)
+
+// mailbox is a Notifier that keeps what it is sent.
+type mailbox struct {
+	sent []string // "<status> <rule> <subject>"
+}
+
+func (m *mailbox) Notify(ctx context.Context, a *database.Alert) error {
+	m.sent = append(m.sent, a.Status+" "+a.Rule+" "+a.Subject)
+	return nil
+}
+
+func TestEvaluate(t *testing.T) {
+	ctx := context.Background()
+	db, err := database.Connect(filepath.Join(t.TempDir(), "mcloud.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close(db)
+	if _, err := db.Exec(`INSERT INTO clusters (id, name, state) VALUES ('c1', 'test', 'active')`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO nodes (id, cluster_id, hostname, ip, role, status) VALUES ('n1', 'c1', 'node-1', '10.0.0.1', 'leader', 'online')`); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{Alerts: config.Alerts{Rules: []string{"node offline > 5m", "ceph HEALTH_WARN"}}}
+	s := NewService(db, cfg, command.NewHub(db, operation.NewService(db, cfg)), certificate.NewService(db, cfg))
+	mail := &mailbox{}
+	s.notifier = mail
+	var agents []command.Agent
+	s.agents = func() []command.Agent { return agents }
+	health, cephErr := microceph.HealthErr, error(nil)
+	s.ceph = func() (*microceph.ClusterStatus, error) {
+		return &microceph.ClusterStatus{Health: health, Checks: []string{"OSD_DOWN: 2 osds down"}}, cephErr
+	}
+
+	evaluate := func(wantMails int) []database.Alert {
+		t.Helper()
+		if err := s.Evaluate(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if len(mail.sent) != wantMails {
+			t.Fatalf("got mails %q, want %d", mail.sent, wantMails)
+		}
+		firing, err := database.NewAlertRepository(db).ListFiring(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return firing
+	}
+
+	// Ceph fires at once; the node is offline but not for 5m yet
+	if firing := evaluate(1); len(firing) != 1 || firing[0].Subject != "ceph" || firing[0].Severity != database.SeverityCritical {
+		t.Fatalf("first run: firing %+v", firing)
+	}
+
+	// Still firing: deduplicated and within the cooldown, so no new mail
+	s.offline["node-1"] = time.Now().Add(-10 * time.Minute)
+	if firing := evaluate(2); len(firing) != 2 || mail.sent[1] != "firing node offline > 5m node-1" {
+		t.Fatalf("second run: firing %+v, mails %q", firing, mail.sent)
+	}
+
+	// A rule that cannot be checked leaves its alerts alone
+	cephErr = errors.New("microceph is not installed")
+	if firing := evaluate(2); len(firing) != 2 {
+		t.Fatalf("ceph unavailable: firing %+v", firing)
+	}
+
+	// Both conditions clear
+	health, cephErr = microceph.HealthOK, nil
+	agents = []command.Agent{{Node: "node-1"}}
+	if firing := evaluate(4); len(firing) != 0 || mail.sent[2] != "resolved ceph HEALTH_WARN ceph" {
+		t.Fatalf("cleared: firing %+v, mails %q", firing, mail.sent)
+	}
+
+	// Flapping within the cooldown opens a new alert without mailing it again
+	health = microceph.HealthWarn
+	firing := evaluate(4)
+	if len(firing) != 1 || firing[0].Severity != database.SeverityWarning || firing[0].Notifications != 0 {
+		t.Fatalf("flap: firing %+v", firing)
+	}
+
+	history, err := s.List(ctx, database.ListOptions{Filter: map[string]string{"status": database.AlertResolved}})
+	if err != nil || len(history) != 2 {
+		t.Fatalf("resolved alerts: got %+v, %v", history, err)
+	}
+}
@@ -0,0 +1,101 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"mcloud/internal/config"
+	"mcloud/internal/database"
+)
+
+// defaultSMTPPort is the submission port, used when smtp.port is not set.
+const defaultSMTPPort = 587
+
+// Notifier delivers an alert that started firing, is still firing or resolved.
+type Notifier interface {
+	Notify(ctx context.Context, a *database.Alert) error
+}
+
+// SMTPNotifier mails alerts to the recipients of the alerts.smtp config.
+type SMTPNotifier struct {
+	cfg      config.SMTP
+	password string
+}
+
+// NewSMTPNotifier returns a notifier for cfg, reading the password from
+// cfg.PasswordPath when a username is set.
+func NewSMTPNotifier(cfg config.SMTP) (*SMTPNotifier, error) {
+	if cfg.Host == "" || cfg.From == "" || len(cfg.To) == 0 {
+		return nil, errors.New("alerts.smtp needs host, from and to")
+	}
+	n := &SMTPNotifier{cfg: cfg}
+	if cfg.Username != "" {
+		data, err := os.ReadFile(cfg.PasswordPath)
+		if err != nil {
+			return nil, fmt.Errorf("read SMTP password: %w", err)
+		}
+		n.password = strings.TrimSpace(string(data))
+	}
+	if n.cfg.Port == 0 {
+		n.cfg.Port = defaultSMTPPort
+	}
+	return n, nil
+}
+
+// Notify sends one mail about a. The connection is upgraded with STARTTLS
+// when the server offers it; credentials are only sent over TLS (or to localhost).
+func (n *SMTPNotifier) Notify(ctx context.Context, a *database.Alert) error {
+	var auth smtp.Auth
+	if n.cfg.Username != "" {
+		auth = smtp.PlainAuth("", n.cfg.Username, n.password, n.cfg.Host)
+	}
+	addr := net.JoinHostPort(n.cfg.Host, strconv.Itoa(n.cfg.Port))
+	if err := smtp.SendMail(addr, auth, n.cfg.From, n.cfg.To, message(n.cfg.From, n.cfg.To, a)); err != nil {
+		return fmt.Errorf("mail alert %d via %s: %w", a.ID, addr, err)
+	}
+	return nil
+}
+
+// message formats the mail about a.
+//
+// Example Output:
+//   Subject: [mcloud] FIRING critical: node offline > 5m on node-2
+//
+//   Node node-2 has been offline since 2026-10-16 09:02:00
+//
+//   Rule:      node offline > 5m
+//   Subject:   node-2
+//   Severity:  critical
+//   Fired at:  2026-10-16 09:07:00
+func message(from string, to []string, a *database.Alert) []byte {
+	status := "FIRING " + a.Severity
+	if a.Status == database.AlertResolved {
+		status = "RESOLVED"
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&b, "Subject: [mcloud] %s: %s on %s\r\n", status, a.Rule, a.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+
+	fmt.Fprintf(&b, "%s\r\n\r\n", a.Message)
+	fmt.Fprintf(&b, "Rule:      %s\r\n", a.Rule)
+	fmt.Fprintf(&b, "Subject:   %s\r\n", a.Subject)
+	fmt.Fprintf(&b, "Severity:  %s\r\n", a.Severity)
+	fmt.Fprintf(&b, "Fired at:  %s\r\n", a.FiredAt.Local().Format(time.DateTime))
+	if a.ResolvedAt != nil {
+		fmt.Fprintf(&b, "Resolved:  %s\r\n", a.ResolvedAt.Local().Format(time.DateTime))
+	}
+	return b.Bytes()
+}
@@ -0,0 +1,548 @@
+// Package app wires mcloudd together. New constructs every dependency explicitly
+// (database, services, handlers, HTTP and gRPC servers, background jobs) from a
+// config value, so the same wiring can run as the daemon, in dev mode against a
+// scratch database, or inside an e2e harness with parts swapped out or disabled.
+package app
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"mcloud/internal/agent"
+	"mcloud/internal/alert"
+	"mcloud/internal/apply"
+	"mcloud/internal/audit"
+	"mcloud/internal/auth"
+	"mcloud/internal/cert"
+	"mcloud/internal/certificate"
+	"mcloud/internal/cluster"
+	"mcloud/internal/clusterconfig"
+	"mcloud/internal/command"
+	"mcloud/internal/config"
+	"mcloud/internal/database"
+	"mcloud/internal/dns"
+	"mcloud/internal/event"
+	"mcloud/internal/flavor"
+	"mcloud/internal/grpc"
+	"mcloud/internal/health"
+	"mcloud/internal/image"
+	"mcloud/internal/job"
+	"mcloud/internal/loadbalancer"
+	"mcloud/internal/maintenance"
+	"mcloud/internal/network"
+	"mcloud/internal/node"
+	"mcloud/internal/openapi"
+	"mcloud/internal/operation"
+	"mcloud/internal/project"
+	"mcloud/internal/reconcile"
+	"mcloud/internal/revocation"
+	"mcloud/internal/secret"
+	"mcloud/internal/securitygroup"
+	"mcloud/internal/standby"
+	"mcloud/internal/state"
+	"mcloud/internal/timesync"
+	"mcloud/internal/tracing"
+	"mcloud/internal/usage"
+	"mcloud/internal/volume"
+	"mcloud/internal/workload"
+	"mcloud/pkg/api"
+	"mcloud/pkg/commander"
+	"mcloud/pkg/logger"
+	"mcloud/pkg/sdnotify"
+	"mcloud/pkg/utils"
+)
+
+var log = logger.Named("app")
+
+// Options selects how New wires the daemon. Only Config is required.
+type Options struct {
+	Config *config.Config
+
+	// DB is used instead of connecting to Config.Database.DBPath, e.g. an
+	// in-memory database already migrated by a test harness
+	DB *sql.DB
+
+	DisableGRPC bool // e.g. an e2e harness that only drives the REST API
+	DisableJobs bool // no background jobs (gc, alerts, time sync, usage, address sync, schedules, maintenance windows, standby, drift, LXD status events)
+}
+
+// App is a fully wired mcloudd. Services are exported so harnesses can call
+// them directly instead of going through HTTP.
+type App struct {
+	Config *config.Config
+	DB     *sql.DB
+
+	Alerts         *alert.Service
+	Apply          *apply.Service
+	Certificates   *certificate.Service
+	Cluster        *cluster.Service
+	ClusterConfig  *clusterconfig.Service
+	ClusterDNS     *dns.Server // nil unless cluster_dns.enabled
+	Commands       *command.Hub
+	DNS            *dns.Service
+	Events         *event.Service
+	Flavors        *flavor.Service
+	ExecSessions   *audit.Sessions
+	Health         *health.Service
+	Images         *image.Service
+	LoadBalancers  *loadbalancer.Service
+	Maintenance    *maintenance.Service
+	Network        *network.Service
+	Nodes          *node.Service
+	Operations     *operation.Service
+	Projects       *project.Service
+	Reconciler     *reconcile.Service
+	Revocations    *revocation.Service
+	Secrets        *secret.Service
+	SecurityGroups *securitygroup.Service
+	State          *state.StateManager
+	TimeSync       *timesync.Service
+	Usage          *usage.Service
+	Volumes        *volume.Service
+	Workloads      *workload.Service
+
+	// Handler is the REST API with its middleware (usage metering, audit log,
+	// client certificate check, tracing)
+	Handler http.Handler
+
+	meter *usage.Meter
+	jobs  *job.Runner
+	opts  Options
+}
+
+// LoadConfig reads the config file at path and makes it the file later
+// config.Load calls (e.g. from mcloudctl helpers) use.
+func LoadConfig(path string) (*config.Config, error) {
+	config.SetPath(path)
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("load config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// ConfigureLogging applies the log section of cfg (levels and log file).
+// Problems are logged and otherwise ignored; logging to stdout still works.
+func ConfigureLogging(cfg *config.Config) {
+	if err := logger.Configure(cfg.Log.Level, cfg.Log.Modules); err != nil {
+		logger.Warn("Invalid log configuration: %v", err)
+	}
+	if err := logger.SetFile(logger.FileOptions{
+		Path:       cfg.Log.File.Path,
+		MaxSizeMB:  cfg.Log.File.MaxSizeMB,
+		MaxAge:     time.Duration(cfg.Log.File.MaxAgeDays) * 24 * time.Hour,
+		MaxBackups: cfg.Log.File.MaxBackups,
+	}); err != nil {
+		logger.Warn("Failed to open log file %s: %v", cfg.Log.File.Path, err)
+	}
+}
+
+// New builds an App: it connects to (and migrates) the database unless one is
+// given, then constructs every service and registers the REST routes. Nothing
+// is started until Run.
+//
+// Example Input:
+//   New(Options{Config: cfg})
+//
+// Example Output (Error - Migration Locked):
+//   Returns: (nil, error("connect to database: ...")) wrapping database.ErrMigrationLocked
+func New(opts Options) (*App, error) {
+	if opts.Config == nil {
+		return nil, errors.New("app: config is required")
+	}
+	cfg := opts.Config
+
+	db := opts.DB
+	if db == nil {
+		var err error
+		if db, err = database.Connect(cfg.Database.DBPath); err != nil {
+			return nil, fmt.Errorf("connect to database: %w", err)
+		}
+	}
+
+	a := &App{
+		Config: cfg,
+		DB:     db,
+		opts:   opts,
+	}
+
+	// Services, in dependency order
+	a.State = state.NewStateManager(state.NewFileStore(cfg.StatePath))
+	a.State.OnChange(func(old, new state.State) {
+		if old.Node.Role != new.Node.Role || old.Node.Status != new.Node.Status {
+			log.Info("Node %s is now %s (%s)", new.Node.Hostname, new.Node.Role, new.Node.Status)
+		}
+	})
+	a.Secrets = secret.NewService(db, cfg.Security.SecretsKeyPath)
+	a.Operations = operation.NewService(db, cfg)
+	a.ExecSessions = audit.NewSessions(db, cfg.Audit.ExecRecording)
+	a.Flavors = flavor.NewService(db)
+	a.Projects = project.NewService(db, cfg)
+	a.Revocations = revocation.NewService(db, cfg)
+	a.Certificates = certificate.NewService(db, cfg)
+	a.Health = health.NewService(db, a.State)
+	a.Workloads = workload.NewService(db, cfg, a.Secrets, a.Flavors, a.Projects, a.Operations, a.ExecSessions)
+	a.Cluster = cluster.NewService(db, cluster.ShellComponents())
+	a.Commands = command.NewHub(db, a.Operations)
+	a.ClusterConfig = clusterconfig.NewService(db)
+	a.DNS = dns.NewService(db, cfg.DNS)
+	a.ClusterDNS = dns.NewServer(db, cfg)
+	a.Network = network.NewService(db, cfg.Network, a.DNS)
+	a.SecurityGroups = securitygroup.NewService(db, cfg.Network, a.Projects)
+	a.LoadBalancers = loadbalancer.NewService(db, cfg.Network, a.Projects)
+	a.Events = event.NewService(db)
+	a.Images = image.NewService(db, a.Operations)
+	a.Nodes = node.NewService(db, cfg)
+	a.Maintenance = maintenance.NewService(db, a.Nodes, a.Commands, a.Operations)
+	a.TimeSync = timesync.NewService(db, cfg)
+	a.Usage = usage.NewService(db)
+	a.Volumes = volume.NewService(db, a.Projects)
+	a.Reconciler = reconcile.NewService(db, cfg)
+	a.Alerts = alert.NewService(db, cfg, a.Commands, a.Certificates)
+	a.Apply = apply.NewService(db, a.Flavors, a.SecurityGroups, a.Volumes, a.Network, a.Workloads, a.Operations)
+
+	a.meter = usage.NewMeter(db)
+	a.Handler = api.Versioned(a.meter.Middleware(auth.RequireClientCert(auth.RejectRevoked(db, audit.Middleware(db, tracing.Handler(audit.RecordRoute(a.routes()), "mcloudd"))))))
+
+	if !opts.DisableJobs {
+		a.jobs = job.NewRunner(db)
+		a.jobs.Register("gc", time.Hour, job.GarbageCollect(db))
+		a.jobs.Register("alerts", alert.Interval, a.Alerts.Evaluate)
+		a.jobs.Register("load-balancers", time.Minute, a.LoadBalancers.Sync)
+		a.jobs.Register("maintenance", maintenance.Interval, a.Maintenance.ApplyWindows)
+		a.jobs.Register("partitions", 24*time.Hour, job.CompactPartitions(db, cfg.Database))
+		a.jobs.Register("config-changes", time.Hour, a.ClusterConfig.PruneChanges)
+		a.jobs.Register("exec-sessions", time.Hour, a.ExecSessions.Prune)
+		a.jobs.Register("reconcile", reconcile.Interval(cfg), a.Reconciler.Run)
+		a.jobs.Register("security-groups", 5*time.Minute, a.SecurityGroups.Reconcile)
+		a.jobs.Register("timesync", 5*time.Minute, a.TimeSync.Reconcile)
+		a.jobs.Register("usage", usage.CollectInterval, a.Usage.Collect)
+		a.jobs.Register("workload-addresses", time.Minute, a.Workloads.SyncAddresses)
+		a.jobs.Register("workload-schedules", workload.ScheduleInterval, a.Workloads.ApplySchedules)
+		if cfg.Standby.Node != "" {
+			a.jobs.Register("standby", standby.Interval(cfg), standby.NewShipper(db, cfg).Ship)
+		}
+	}
+	return a, nil
+}
+
+// routes registers every module's REST routes on a new mux. Routes are
+// registered without the version prefix, which api.Versioned strips.
+func (a *App) routes() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	// Register liveness and readiness routes (e.g., /healthz, /readyz)
+	health.InitModule(mux, health.NewHandler(a.Health))
+
+	// Register the OpenAPI document route (e.g., /openapi.json)
+	openapi.InitModule(mux, openapi.NewHandler())
+
+	// Register cluster-related HTTP routes (e.g., /cluster/status)
+	cluster.InitModule(mux, cluster.NewHandler(a.Cluster))
+
+	// Register cluster config routes (e.g., /config/{namespace}/{key}, /config/watch)
+	clusterconfig.InitModule(mux, clusterconfig.NewHandler(a.ClusterConfig))
+
+	// Register operation routes (e.g., /operations/{id})
+	operation.InitModule(mux, operation.NewHandler(a.Operations))
+
+	// Register workload routes (e.g., /workloads, /workloads/{id}/clone)
+	workload.InitModule(mux, workload.NewHandler(a.Workloads))
+
+	// Register flavor routes (e.g., /flavors, /flavors/{name})
+	flavor.InitModule(mux, flavor.NewHandler(a.Flavors))
+
+	// Register project routes (e.g., /projects, /projects/{name}/quota)
+	project.InitModule(mux, project.NewHandler(a.Projects))
+
+	// Register volume routes (e.g., /volumes, /volumes/{id}/attach)
+	volume.InitModule(mux, volume.NewHandler(a.Volumes))
+
+	// Register secrets backend routes (e.g., /secrets)
+	secret.InitModule(mux, secret.NewHandler(a.Secrets))
+
+	// Register floating IP DNS record routes (e.g., /dns/records)
+	dns.InitModule(mux, dns.NewHandler(a.DNS))
+
+	// Register floating IP routes (e.g., /network/floating-ips)
+	network.InitModule(mux, network.NewHandler(a.Network))
+
+	// Register security group routes (e.g., /security-groups, /workloads/{id}/security-groups)
+	securitygroup.InitModule(mux, securitygroup.NewHandler(a.SecurityGroups))
+
+	// Register load balancer routes (e.g., /load-balancers, /load-balancers/{name})
+	loadbalancer.InitModule(mux, loadbalancer.NewHandler(a.LoadBalancers))
+
+	// Register manifest apply routes (e.g., /apply)
+	apply.InitModule(mux, apply.NewHandler(a.Apply))
+
+	// Register drift routes (e.g., /drift, /drift/check)
+	reconcile.InitModule(mux, reconcile.NewHandler(a.Reconciler))
+
+	// Register maintenance window routes (e.g., /cluster/maintenance, /cluster/maintenance/run)
+	maintenance.InitModule(mux, maintenance.NewHandler(a.Maintenance))
+
+	// Register node routes (e.g., /nodes/{id}/services/{service}/restart)
+	node.InitModule(mux, node.NewHandler(a.Nodes))
+
+	// Register certificate routes (e.g., /certs, /certs/{id}/renew)
+	certificate.InitModule(mux, certificate.NewHandler(a.Certificates))
+
+	// Register certificate revocation routes (e.g., /certs/revoke, /certs/crl)
+	revocation.InitModule(mux, revocation.NewHandler(a.Revocations))
+
+	// Register agent command routes (e.g., /agents, /nodes/{id}/commands)
+	command.InitModule(mux, command.NewHandler(a.Commands))
+
+	// Register image import and build routes (e.g., /images/import, /images/builds)
+	image.InitModule(mux, image.NewHandler(a.Images))
+
+	// Register time sync status routes (e.g., /timesync)
+	timesync.InitModule(mux, timesync.NewHandler(a.TimeSync))
+
+	// Register alert history routes (e.g., /alerts)
+	alert.InitModule(mux, alert.NewHandler(a.Alerts))
+
+	// Register event routes (e.g., /events, /events/stream)
+	event.InitModule(mux, event.NewHandler(a.Events))
+
+	// Register background job status routes (e.g., /jobs)
+	job.InitModule(mux, job.NewHandler(a.DB))
+
+	// Register audit log routes (e.g., /audit, /audit/exec-sessions)
+	audit.InitModule(mux, audit.NewHandler(a.DB, a.ExecSessions))
+
+	// Register usage metering routes (e.g., /usage)
+	usage.InitModule(mux, usage.NewHandler(a.Usage))
+
+	return mux
+}
+
+// Run recovers work interrupted by the last shutdown, starts the HTTPS, gRPC
+// and cluster DNS servers and background jobs, and blocks until ctx is cancelled. It returns an
+// error if the servers cannot be set up.
+func (a *App) Run(ctx context.Context) error {
+	// Operations started before a restart can no longer finish
+	if err := a.Operations.FailInterrupted(ctx); err != nil {
+		log.Error("Failed to mark interrupted operations: %v", err)
+	}
+	// Continue downloads interrupted by a previous shutdown
+	if err := a.Images.ResumeUnfinished(ctx); err != nil {
+		log.Error("Failed to resume image imports: %v", err)
+	}
+
+	// Load (or generate on first start) the CA and server certificate shared by HTTP and gRPC
+	if err := ensureCertificates(a.Config); err != nil {
+		return fmt.Errorf("prepare certificates: %w", err)
+	}
+
+	server, err := a.httpServer()
+	if err != nil {
+		return err
+	}
+
+	go a.meter.Run(ctx)
+	go a.watchLogLevel(ctx)
+	a.watchBreakers(ctx)
+	if a.jobs != nil {
+		logger.Info("Starting background jobs")
+		go a.jobs.Start(ctx)
+		go a.Workloads.WatchStatus(ctx)
+	}
+	if !a.opts.DisableGRPC {
+		go a.runGRPC()
+	}
+	if a.ClusterDNS != nil {
+		go func() {
+			if err := a.ClusterDNS.Serve(ctx); err != nil {
+				log.Error("%v", err)
+			}
+		}()
+	}
+
+	logger.Info("Starting HTTPS server on %s", server.Addr)
+	ln, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		return fmt.Errorf("HTTP server: %w", err)
+	}
+	errc := make(chan error, 1)
+	go func() {
+		errc <- server.ServeTLS(ln, "", "")
+	}()
+
+	// Under systemd (Type=notify) the unit is started once the API listens
+	if _, err := sdnotify.Notify(sdnotify.Ready); err != nil {
+		log.Warn("Failed to notify systemd: %v", err)
+	}
+	go a.Health.Watchdog(ctx)
+
+	select {
+	case err := <-errc:
+		return fmt.Errorf("HTTP server: %w", err)
+	case <-ctx.Done():
+	}
+
+	sdnotify.Notify(sdnotify.Stopping)
+	logger.Info("Shutting down HTTP server...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Error("HTTP server Shutdown: %v", err)
+	}
+	return nil
+}
+
+// watchLogLevel applies log/level from the cluster config (e.g. `mcloudctl config
+// set log level debug`) at start and whenever it changes, without a restart.
+// Deleting the key restores log.level from the config file.
+func (a *App) watchLogLevel(ctx context.Context) {
+	apply := func(value *string) {
+		level := a.Config.Log.Level
+		if value != nil {
+			level = *value
+		}
+		if level == "" {
+			level = "info"
+		}
+		if err := logger.Configure(level, a.Config.Log.Modules); err != nil {
+			log.Warn("Invalid log level %q in cluster config: %v", level, err)
+			return
+		}
+		log.Info("Log level set to %s from cluster config", level)
+	}
+
+	if e, err := a.ClusterConfig.Get(ctx, "log", "level"); err == nil {
+		apply(&e.Value)
+	}
+	err := a.ClusterConfig.Watch(ctx, "log", func(c clusterconfig.Change) error {
+		if c.Key == "level" {
+			apply(c.Value)
+		}
+		return nil
+	})
+	if err != nil && ctx.Err() == nil {
+		log.Error("Stopped watching log config: %v", err)
+	}
+}
+
+// watchBreakers turns the circuit breakers of the lxc, microceph and microovn
+// commands mcloudd runs into conditions of its own node, and probes open
+// breakers so they close once the component recovers.
+func (a *App) watchBreakers(ctx context.Context) {
+	hostname, _ := os.Hostname()
+	commander.OnBreakerChange(func(st commander.BreakerStatus) {
+		err := a.Nodes.ApplyBreakers(context.WithoutCancel(ctx), hostname, []commander.BreakerStatus{st})
+		if err != nil && !errors.Is(err, node.ErrNodeNotFound) {
+			log.Error("Failed to update conditions of node %s: %v", hostname, err)
+		}
+	})
+	go agent.ProbeBreakers(ctx)
+}
+
+// httpServer builds the REST API server. Client certificates are verified when
+// presented; mutating endpoints require one and every mutating call (accepted
+// or rejected) is written to the audit log.
+func (a *App) httpServer() (*http.Server, error) {
+	caBytes, err := cert.ReadPEM(a.Config.Security.CACertPath)
+	if err != nil {
+		return nil, fmt.Errorf("read CA certificate: %w", err)
+	}
+	caPool := x509.NewCertPool()
+	caPool.AppendCertsFromPEM(caBytes)
+
+	// The CA is sent after the server certificate so that a joining node can
+	// pin it against its join string (see auth.FetchPinnedCA)
+	serverCert, err := tls.LoadX509KeyPair(a.Config.Security.ServerCertPath, a.Config.Security.ServerKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("load server certificate: %w", err)
+	}
+	if block, _ := pem.Decode(caBytes); block != nil {
+		serverCert.Certificate = append(serverCert.Certificate, block.Bytes)
+	}
+
+	return &http.Server{
+		Addr:         utils.HostPort(a.Config.Manager.HttpHost, a.Config.Manager.HttpPort),
+		Handler:      a.Handler,
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 10 * time.Second,
+		TLSConfig: &tls.Config{
+			Certificates: []tls.Certificate{serverCert},
+			ClientAuth:   tls.VerifyClientCertIfGiven,
+			ClientCAs:    caPool,
+			MinVersion:   tls.VersionTLS12,
+		},
+	}, nil
+}
+
+// runGRPC serves the gRPC API (the agents' command streams) with mutual TLS
+// until the process exits.
+func (a *App) runGRPC() {
+	log := logger.Named("grpc")
+	addr := utils.HostPort(a.Config.Manager.GrpcHost, a.Config.Manager.GrpcPort)
+
+	log.Info("Starting gRPC server on %s", addr)
+	if err := grpc.StartGRPCServer(
+		addr,
+		a.Config.Security.CACertPath,
+		a.Config.Security.ServerCertPath,
+		a.Config.Security.ServerKeyPath,
+		a.Commands.Register,
+		func(leaf *x509.Certificate) error {
+			return auth.CheckRevoked(context.Background(), a.DB, leaf)
+		},
+	); err != nil {
+		log.Error("gRPC server error: %v", err)
+	}
+}
+
+// ensureCertificates makes sure the CA and server certificate exist before the servers start.
+// The CA written by `mcloudctl init` is reused; it is only generated when missing so that
+// client certificates issued by init keep validating across daemon restarts.
+// With security.intermediate_cert_path set, the intermediate CA signs the server
+// certificate, and is generated from the root when missing.
+// The server certificate is regenerated when it lacks the hostname, an FQDN of
+// security.server_names or the advertise address, e.g. after the address changed.
+func ensureCertificates(cfg *config.Config) error {
+	caCert, caKey, err := cert.LoadCA(cfg.Security.CACertPath, cfg.Security.CAKeyPath)
+	if err != nil {
+		// Generate CA certificate and key
+		caCert, caKey, err = cert.GenerateCAV2(cfg.Security.CACertPath, cfg.Security.CAKeyPath)
+		if err != nil {
+			return err
+		}
+		logger.Info("Generated CA certificate")
+	}
+	if cfg.Security.IntermediateCertPath != "" {
+		interCert, interKey, err := cert.LoadCA(cfg.Security.IntermediateCertPath, cfg.Security.IntermediateKeyPath)
+		if err != nil {
+			interCert, interKey, err = cert.GenerateIntermediateCA(caCert, caKey, cfg.Security.IntermediateCertPath, cfg.Security.IntermediateKeyPath)
+			if err != nil {
+				return err
+			}
+			logger.Info("Generated intermediate CA certificate")
+		}
+		caCert, caKey = interCert, interKey
+	}
+
+	names, advertise := cert.ServerNames(cfg), cert.AdvertiseIP(cfg)
+	if _, err := os.Stat(cfg.Security.ServerCertPath); err == nil {
+		covered, err := cert.CoversSANs(cfg.Security.ServerCertPath, names, []net.IP{advertise})
+		if err != nil || covered {
+			return err
+		}
+		logger.Info("Server certificate does not cover %v and %s; regenerating it", names, advertise)
+	}
+
+	// Generate server certificate signed by CA, valid for the host's names and
+	// its IPv4 and IPv6 addresses
+	return cert.IssueServerCert(cfg, caCert, caKey)
+}
@@ -0,0 +1,64 @@
+package apply
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"mcloud/internal/auth"
+	"mcloud/internal/operation"
+	"mcloud/pkg/reason"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(s *Service) *Handler {
+	return &Handler{service: s}
+}
+
+// writeError maps service errors to HTTP status codes.
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrInvalid):
+		reason.HTTPError(w, err, 400)
+	case errors.Is(err, ErrConflict):
+		reason.HTTPError(w, err, 409)
+	default:
+		reason.HTTPError(w, err, 500)
+	}
+}
+
+// ApplyManifest plans a manifest. A dry run, or a manifest the cluster
+// already matches, is answered with the plan (200); otherwise with the plan
+// and the operation applying it (202).
+func (h *Handler) ApplyManifest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req Request
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields() // a misspelled manifest key would silently be ignored
+	if err := dec.Decode(&req); err != nil {
+		reason.HTTPError(w, fmt.Errorf("%w: %v", ErrInvalid, err), 400)
+		return
+	}
+
+	actor := auth.ClientIdentity(r)
+	result, err := h.service.Apply(r.Context(), &req, &actor)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if result.Operation != nil {
+		operation.WriteAccepted(w, result.Operation, result)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
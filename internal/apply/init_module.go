@@ -0,0 +1,10 @@
+package apply
+
+import (
+	"net/http"
+)
+
+// InitModule registers the manifest apply route served by handler.
+func InitModule(mux *http.ServeMux, handler *Handler) {
+	mux.HandleFunc("POST /apply", handler.ApplyManifest)
+}
@@ -0,0 +1,824 @@
+// Package apply converges the cluster on a manifest: a declarative
+// description of the flavors, security groups, volumes and workloads a user
+// wants, kept in git and applied with mcloudctl apply -f. Plan diffs the
+// manifest against the database and returns the changes that would make the
+// cluster match it; Apply makes them through the services that own each kind
+// of object, in dependency order, in a background "apply" operation.
+//
+// Applying the same manifest twice changes nothing the second time. Objects
+// the manifest does not mention are left alone unless Prune is set; workloads
+// are never deleted, since mcloud has no workload deletion.
+package apply
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	"mcloud/internal/database"
+	"mcloud/internal/flavor"
+	"mcloud/internal/network"
+	"mcloud/internal/operation"
+	"mcloud/internal/securitygroup"
+	"mcloud/internal/volume"
+	"mcloud/internal/workload"
+	"mcloud/pkg/logger"
+	"mcloud/pkg/reason"
+)
+
+var log = logger.Named("apply")
+
+var (
+	ErrInvalid  = reason.New(reason.InvalidManifest, "invalid manifest")
+	ErrConflict = reason.New(reason.ManifestConflict, "manifest changes fields that cannot be changed in place")
+)
+
+// SpecKind is the kind of a manifest. Unlike the ClusterState exported by
+// GET /cluster/state.yaml, which only records what exists, a ClusterSpec is
+// applied.
+const SpecKind = "ClusterSpec"
+
+// Change kinds and actions.
+const (
+	KindFlavor        = "flavor"
+	KindSecurityGroup = "security_group"
+	KindWorkload      = "workload"
+	KindVolume        = "volume"
+	KindFloatingIP    = "floating_ip"
+
+	ActionCreate   = "create"
+	ActionUpdate   = "update"
+	ActionDelete   = "delete"
+	ActionConflict = "conflict"
+)
+
+// Manifest is the desired state of the cluster. Each section uses the request
+// type of the owning API, so a manifest entry reads like the body of its
+// create request.
+//
+// Example YAML (mcloudctl apply -f cluster.yaml):
+//   kind: ClusterSpec
+//   flavors:
+//     - {name: small, cpus: 1, memory_mb: 1024, disk_gb: 10}
+//   security_groups:
+//     - name: web
+//       rules:
+//         - {direction: ingress, protocol: tcp, port: "443"}
+//         - {direction: egress}
+//   workloads:
+//     - name: web-1
+//       image: ubuntu:24.04
+//       flavor: small
+//       env: {APP_ENV: production}
+//       security_groups: [web]
+//       floating_ip: {dns_name: web}
+//   volumes:
+//     - {name: web-data, size_gb: 20, workload: web-1, mount_path: /srv}
+type Manifest struct {
+	Kind           string                        `json:"kind"`
+	Flavors        []flavor.CreateRequest        `json:"flavors,omitempty"`
+	SecurityGroups []securitygroup.CreateRequest `json:"security_groups,omitempty"`
+	Workloads      []WorkloadSpec                `json:"workloads,omitempty"`
+	Volumes        []VolumeSpec                  `json:"volumes,omitempty"`
+}
+
+// WorkloadSpec is a workload of a manifest. Its environment, secrets, hooks,
+// priority, security groups and floating IP are converged; kind, image,
+// flavor and cloud-init config cannot change after launch, and node_id and
+// storage_pool only apply at launch.
+type WorkloadSpec struct {
+	workload.CreateRequest
+	SecurityGroups []string        `json:"security_groups,omitempty"`
+	FloatingIP     *FloatingIPSpec `json:"floating_ip,omitempty"` // nil releases the workload's floating IPs
+}
+
+// FloatingIPSpec exposes a workload with a floating IP. DNSName is only
+// published when the address is allocated.
+type FloatingIPSpec struct {
+	Address string `json:"address,omitempty"` // empty = any free address of the pool
+	DNSName string `json:"dns_name,omitempty"`
+}
+
+// VolumeSpec is a volume of a manifest, optionally attached to one of the
+// manifest's workloads. Volumes cannot be resized.
+type VolumeSpec struct {
+	volume.CreateRequest
+	Workload  string `json:"workload,omitempty"` // name of a workload of the manifest
+	MountPath string `json:"mount_path,omitempty"`
+}
+
+// Request applies a manifest. DryRun only returns the plan; Prune also
+// deletes the flavors, security groups and volumes the manifest does not
+// mention.
+type Request struct {
+	Manifest Manifest `json:"manifest"`
+	DryRun   bool     `json:"dry_run,omitempty"`
+	Prune    bool     `json:"prune,omitempty"`
+}
+
+// Change is one step of a plan.
+//
+// Example JSON:
+//   {"kind": "workload", "name": "web-1", "action": "update", "detail": "env APP_ENV, priority high"}
+//   {"kind": "workload", "name": "db-1", "action": "conflict", "detail": "image ubuntu:22.04 -> ubuntu:24.04"}
+type Change struct {
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	Action string `json:"action"`
+	Detail string `json:"detail,omitempty"`
+
+	run func(ctx context.Context) error
+}
+
+// Result is the answer to an apply request: the plan, and the operation
+// carrying it out unless it was a dry run or there was nothing to do.
+type Result struct {
+	Operation *database.Operation `json:"operation,omitempty"`
+	Changes   []Change            `json:"changes"`
+}
+
+type Service struct {
+	db             *sql.DB
+	flavors        *flavor.Service
+	securityGroups *securitygroup.Service
+	volumes        *volume.Service
+	network        *network.Service
+	workloads      *workload.Service
+	operations     *operation.Service
+
+	mu sync.Mutex // one apply at a time
+}
+
+func NewService(db *sql.DB, flavors *flavor.Service, securityGroups *securitygroup.Service, volumes *volume.Service, network *network.Service, workloads *workload.Service, operations *operation.Service) *Service {
+	return &Service{
+		db:             db,
+		flavors:        flavors,
+		securityGroups: securityGroups,
+		volumes:        volumes,
+		network:        network,
+		workloads:      workloads,
+		operations:     operations,
+	}
+}
+
+// Apply plans a manifest and, unless req.DryRun, starts the "apply" operation
+// that makes the changes, stopping at the first one that fails. Plans with
+// conflicts are refused before anything changes.
+//
+// Example Output (Error):
+//   Workload image changed            =>  ErrConflict: workload db-1: image ubuntu:22.04 -> ubuntu:24.04
+//   Volume of an undeclared workload  =>  ErrInvalid: volume pgdata: workload db-9 is not in the manifest
+func (s *Service) Apply(ctx context.Context, req *Request, actor *string) (*Result, error) {
+	changes, err := s.Plan(ctx, &req.Manifest, req.Prune, actor)
+	if err != nil {
+		return nil, err
+	}
+	var conflicts []string
+	for _, c := range changes {
+		if c.Action == ActionConflict {
+			conflicts = append(conflicts, fmt.Sprintf("%s %s: %s", c.Kind, c.Name, c.Detail))
+		}
+	}
+	if len(conflicts) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrConflict, strings.Join(conflicts, "; "))
+	}
+	if req.DryRun || len(changes) == 0 {
+		return &Result{Changes: changes}, nil
+	}
+
+	op, err := s.operations.Start(ctx, "apply", "", actor,
+		func(ctx context.Context, report operation.Reporter) (any, error) {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			for i, c := range changes {
+				report(100*i/len(changes), fmt.Sprintf("%s %s %s", c.Action, strings.ReplaceAll(c.Kind, "_", " "), c.Name))
+				if err := c.run(ctx); err != nil {
+					return nil, fmt.Errorf("%s %s %s: %w", c.Action, c.Kind, c.Name, err)
+				}
+				log.Info("Applied: %s %s %s %s", c.Action, c.Kind, c.Name, c.Detail)
+			}
+			return changes, nil
+		})
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Operation: op, Changes: changes}, nil
+}
+
+// Plan validates a manifest and returns the changes that would make the
+// cluster match it, in the order Apply makes them: flavors, security groups,
+// workloads, volumes, security group attachments, floating IPs, then with
+// prune the deletions.
+func (s *Service) Plan(ctx context.Context, m *Manifest, prune bool, actor *string) ([]Change, error) {
+	if err := s.validate(ctx, m); err != nil {
+		return nil, err
+	}
+
+	steps := []func(context.Context, *Manifest, *string) ([]Change, error){
+		s.planFlavors,
+		s.planSecurityGroups,
+		s.planWorkloads,
+		s.planVolumes,
+		s.planAttachments,
+		s.planFloatingIPs,
+	}
+	if prune {
+		steps = append(steps, s.planPrune)
+	}
+	var changes []Change
+	for _, step := range steps {
+		c, err := step(ctx, m, actor)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, c...)
+	}
+	if changes == nil {
+		changes = []Change{}
+	}
+	return changes, nil
+}
+
+// validate checks references between the sections of a manifest, and
+// normalizes its security group rules so they compare with stored ones.
+func (s *Service) validate(ctx context.Context, m *Manifest) error {
+	if m.Kind != SpecKind {
+		return fmt.Errorf("%w: kind is %q, want %q", ErrInvalid, m.Kind, SpecKind)
+	}
+
+	flavors := map[string]bool{}
+	for _, f := range m.Flavors {
+		if f.Name == "" || flavors[f.Name] {
+			return fmt.Errorf("%w: flavor names must be set and unique, got %q", ErrInvalid, f.Name)
+		}
+		flavors[f.Name] = true
+	}
+
+	groups := map[string]bool{}
+	for i := range m.SecurityGroups {
+		g := &m.SecurityGroups[i]
+		if g.Name == "" || groups[g.Name] {
+			return fmt.Errorf("%w: security group names must be set and unique, got %q", ErrInvalid, g.Name)
+		}
+		groups[g.Name] = true
+		rules, err := securitygroup.NormalizeRules(g.Rules)
+		if err != nil {
+			return fmt.Errorf("%w: security group %s: %v", ErrInvalid, g.Name, err)
+		}
+		g.Rules = rules
+	}
+
+	workloads := map[string]bool{}
+	for _, w := range m.Workloads {
+		if w.Name == "" || workloads[w.Name] {
+			return fmt.Errorf("%w: workload names must be set and unique, got %q", ErrInvalid, w.Name)
+		}
+		workloads[w.Name] = true
+		if w.Flavor != "" && !flavors[w.Flavor] {
+			if _, err := s.flavors.Get(ctx, w.Flavor); err != nil {
+				return fmt.Errorf("%w: workload %s: %v", ErrInvalid, w.Name, err)
+			}
+		}
+		for _, g := range w.SecurityGroups {
+			if groups[g] {
+				continue
+			}
+			if _, err := s.securityGroups.Get(ctx, g); err != nil {
+				return fmt.Errorf("%w: workload %s: %v", ErrInvalid, w.Name, err)
+			}
+		}
+	}
+
+	volumes := map[string]bool{}
+	for _, v := range m.Volumes {
+		key := v.Pool + "/" + v.Name
+		if v.Name == "" || volumes[key] {
+			return fmt.Errorf("%w: volume names must be set and unique per pool, got %q", ErrInvalid, v.Name)
+		}
+		volumes[key] = true
+		if v.Workload != "" && !workloads[v.Workload] {
+			return fmt.Errorf("%w: volume %s: workload %s is not in the manifest", ErrInvalid, v.Name, v.Workload)
+		}
+		if (v.Workload == "") != (v.MountPath == "") {
+			return fmt.Errorf("%w: volume %s: workload and mount_path go together", ErrInvalid, v.Name)
+		}
+	}
+	return nil
+}
+
+
+func (s *Service) planFlavors(ctx context.Context, m *Manifest, actor *string) ([]Change, error) {
+	existing, err := s.flavors.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	current := map[string]database.Flavor{}
+	for _, f := range existing {
+		current[f.Name] = f
+	}
+
+	var changes []Change
+	for _, f := range m.Flavors {
+		cur, ok := current[f.Name]
+		if !ok {
+			req := f
+			changes = append(changes, Change{Kind: KindFlavor, Name: f.Name, Action: ActionCreate,
+				Detail: fmt.Sprintf("%d cpus, %d MiB, %d GiB", f.CPUs, f.MemoryMB, f.DiskGB),
+				run: func(ctx context.Context) error {
+					_, err := s.flavors.Create(ctx, &req, actor)
+					return err
+				}})
+			continue
+		}
+		if cur.CPUs != f.CPUs || cur.MemoryMB != f.MemoryMB || cur.DiskGB != f.DiskGB {
+			changes = append(changes, Change{Kind: KindFlavor, Name: f.Name, Action: ActionConflict,
+				Detail: fmt.Sprintf("flavors cannot be changed (%d cpus, %d MiB, %d GiB -> %d cpus, %d MiB, %d GiB)",
+					cur.CPUs, cur.MemoryMB, cur.DiskGB, f.CPUs, f.MemoryMB, f.DiskGB)})
+		}
+	}
+	return changes, nil
+}
+
+func (s *Service) planSecurityGroups(ctx context.Context, m *Manifest, actor *string) ([]Change, error) {
+	var changes []Change
+	for _, g := range m.SecurityGroups {
+		cur, err := s.securityGroups.Get(ctx, g.Name)
+		switch {
+		case errors.Is(err, securitygroup.ErrNotFound):
+			req := g
+			changes = append(changes, Change{Kind: KindSecurityGroup, Name: g.Name, Action: ActionCreate,
+				Detail: fmt.Sprintf("%d rules", len(g.Rules)),
+				run: func(ctx context.Context) error {
+					_, err := s.securityGroups.Create(ctx, &req, actor)
+					return err
+				}})
+		case err != nil:
+			return nil, err
+		case !slices.Equal(cur.Rules, g.Rules):
+			req := &securitygroup.RulesRequest{Rules: g.Rules}
+			name := g.Name
+			changes = append(changes, Change{Kind: KindSecurityGroup, Name: g.Name, Action: ActionUpdate,
+				Detail: fmt.Sprintf("rules (%d -> %d)", len(cur.Rules), len(g.Rules)),
+				run: func(ctx context.Context) error {
+					_, err := s.securityGroups.ReplaceRules(ctx, name, req, actor)
+					return err
+				}})
+		}
+	}
+	return changes, nil
+}
+
+func (s *Service) planWorkloads(ctx context.Context, m *Manifest, actor *string) ([]Change, error) {
+	var changes []Change
+	for _, spec := range m.Workloads {
+		w, err := s.workloadByName(ctx, spec.Name)
+		if err != nil {
+			return nil, err
+		}
+		if w == nil {
+			req := spec.CreateRequest
+			changes = append(changes, Change{Kind: KindWorkload, Name: spec.Name, Action: ActionCreate,
+				Detail: cmp.Or(spec.Kind, "container") + " " + spec.Image,
+				run: func(ctx context.Context) error {
+					result, err := s.workloads.CreateWorkload(ctx, &req, actor)
+					if err != nil {
+						return err
+					}
+					// Later changes need the instance
+					_, err = s.operations.Wait(ctx, result.Operation.ID)
+					return err
+				}})
+			continue
+		}
+
+		current, err := s.workloads.GetWorkload(ctx, w.ID)
+		if err != nil {
+			return nil, err
+		}
+		if conflicts := workloadConflicts(current, &spec); len(conflicts) > 0 {
+			changes = append(changes, Change{Kind: KindWorkload, Name: spec.Name, Action: ActionConflict, Detail: strings.Join(conflicts, ", ")})
+			continue
+		}
+		if req, detail := workloadPatch(current, &spec); req != nil {
+			id := w.ID
+			changes = append(changes, Change{Kind: KindWorkload, Name: spec.Name, Action: ActionUpdate, Detail: detail,
+				run: func(ctx context.Context) error {
+					_, err := s.workloads.UpdateWorkload(ctx, id, req)
+					return err
+				}})
+		}
+	}
+	return changes, nil
+}
+
+// workloadConflicts lists the differences between a workload and its spec
+// that would need a new instance.
+func workloadConflicts(current *workload.WorkloadDetail, spec *WorkloadSpec) []string {
+	var conflicts []string
+	if kind := cmp.Or(spec.Kind, "container"); kind != current.Kind {
+		conflicts = append(conflicts, fmt.Sprintf("kind %s -> %s", current.Kind, kind))
+	}
+	if spec.Image != current.Image {
+		conflicts = append(conflicts, fmt.Sprintf("image %s -> %s", current.Image, spec.Image))
+	}
+	if flavor := deref(current.Flavor); spec.Flavor != flavor {
+		conflicts = append(conflicts, fmt.Sprintf("flavor %q -> %q", flavor, spec.Flavor))
+	}
+	if project := cmp.Or(spec.Project, database.DefaultProject); project != current.Project {
+		conflicts = append(conflicts, fmt.Sprintf("project %s -> %s", current.Project, project))
+	}
+	if deref(workload.CloudInitHash(spec.UserData, spec.NetworkConfig)) != deref(current.CloudInitHash) {
+		conflicts = append(conflicts, "cloud-init config")
+	}
+	return conflicts
+}
+
+// workloadPatch returns the update that gives a workload the environment,
+// secrets, priority and hooks of its spec, and a summary of it; nil if it
+// already has them.
+func workloadPatch(current *workload.WorkloadDetail, spec *WorkloadSpec) (*workload.UpdateRequest, string) {
+	req := &workload.UpdateRequest{Env: patch(current.Env, spec.Env), Secrets: patch(current.Secrets, spec.Secrets)}
+	var detail []string
+	for _, k := range slices.Sorted(maps.Keys(req.Env)) {
+		detail = append(detail, "env "+k)
+	}
+	for _, k := range slices.Sorted(maps.Keys(req.Secrets)) {
+		detail = append(detail, "secret "+k)
+	}
+	if priority := cmp.Or(spec.Priority, database.PriorityNormal); priority != current.Priority {
+		req.Priority = &priority
+		detail = append(detail, "priority "+priority)
+	}
+	if !hooksEqual(current.Hooks, spec.Hooks) {
+		hooks := append([]workload.HookSpec{}, spec.Hooks...)
+		req.Hooks = &hooks
+		detail = append(detail, "hooks")
+	}
+	if len(detail) == 0 {
+		return nil, ""
+	}
+	return req, strings.Join(detail, ", ")
+}
+
+// patch returns the merge patch from current to desired variables.
+func patch(current map[string]string, desired map[string]string) map[string]*string {
+	p := map[string]*string{}
+	for k, v := range desired {
+		if cur, ok := current[k]; !ok || cur != v {
+			p[k] = &v
+		}
+	}
+	for k := range current {
+		if _, ok := desired[k]; !ok {
+			p[k] = nil
+		}
+	}
+	return p
+}
+
+// hooksEqual compares hooks regardless of order.
+func hooksEqual(current []database.WorkloadHook, desired []workload.HookSpec) bool {
+	var a, b []string
+	for _, h := range current {
+		a = append(a, h.Event+"\x00"+deref(h.WebhookURL)+"\x00"+deref(h.Command))
+	}
+	for _, h := range desired {
+		b = append(b, h.Event+"\x00"+h.Webhook+"\x00"+h.Command)
+	}
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
+
+func (s *Service) planVolumes(ctx context.Context, m *Manifest, actor *string) ([]Change, error) {
+	existing, err := s.volumes.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	matched, err := matchVolumes(existing, m.Volumes)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	for i, spec := range m.Volumes {
+		mountPath := ""
+		if spec.MountPath != "" {
+			mountPath = path.Clean(spec.MountPath)
+		}
+		attachment := ""
+		if spec.Workload != "" {
+			attachment = fmt.Sprintf("%s at %s", spec.Workload, mountPath)
+		}
+
+		cur := matched[i]
+		if cur == nil {
+			req := spec.CreateRequest
+			detail := fmt.Sprintf("%d GiB", spec.SizeGB)
+			if attachment != "" {
+				detail += ", attached to " + attachment
+			}
+			changes = append(changes, Change{Kind: KindVolume, Name: spec.Name, Action: ActionCreate, Detail: detail,
+				run: func(ctx context.Context) error {
+					v, err := s.volumes.Create(ctx, &req, actor)
+					if err != nil || spec.Workload == "" {
+						return err
+					}
+					return s.attachVolume(ctx, v.ID, spec.Workload, mountPath, actor)
+				}})
+			continue
+		}
+		if cur.SizeGB != spec.SizeGB {
+			changes = append(changes, Change{Kind: KindVolume, Name: spec.Name, Action: ActionConflict,
+				Detail: fmt.Sprintf("volumes cannot be resized (%d GiB -> %d GiB)", cur.SizeGB, spec.SizeGB)})
+			continue
+		}
+
+		currentAttachment := ""
+		if cur.WorkloadID != nil {
+			w, err := database.NewWorkloadRepository(s.db).GetByID(ctx, *cur.WorkloadID)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return nil, err
+			}
+			name := *cur.WorkloadID // the workload is gone
+			if w != nil {
+				name = w.Name
+			}
+			currentAttachment = fmt.Sprintf("%s at %s", name, deref(cur.MountPath))
+		}
+		if currentAttachment == attachment {
+			continue
+		}
+
+		var detail string
+		switch {
+		case attachment == "":
+			detail = "detach from " + currentAttachment
+		case currentAttachment == "":
+			detail = "attach to " + attachment
+		default:
+			detail = fmt.Sprintf("move from %s to %s", currentAttachment, attachment)
+		}
+		id, attached := cur.ID, cur.WorkloadID != nil
+		changes = append(changes, Change{Kind: KindVolume, Name: spec.Name, Action: ActionUpdate, Detail: detail,
+			run: func(ctx context.Context) error {
+				if attached {
+					if _, err := s.volumes.Detach(ctx, id, actor); err != nil {
+						return err
+					}
+				}
+				if spec.Workload == "" {
+					return nil
+				}
+				return s.attachVolume(ctx, id, spec.Workload, mountPath, actor)
+			}})
+	}
+	return changes, nil
+}
+
+// matchVolumes pairs the volumes of a manifest with existing volumes: by pool
+// and name, or by name alone for specs without a pool.
+func matchVolumes(existing []database.Volume, specs []VolumeSpec) ([]*database.Volume, error) {
+	matched := make([]*database.Volume, len(specs))
+	for i, spec := range specs {
+		var found []string
+		for j := range existing {
+			v := &existing[j]
+			if v.Name == spec.Name && (spec.Pool == "" || v.Pool == spec.Pool) {
+				matched[i] = v
+				found = append(found, v.Pool)
+			}
+		}
+		if len(found) > 1 {
+			return nil, fmt.Errorf("%w: volume %s exists in pools %s; set its pool", ErrInvalid, spec.Name, strings.Join(found, ", "))
+		}
+	}
+	return matched, nil
+}
+
+func (s *Service) attachVolume(ctx context.Context, id string, workloadName string, mountPath string, actor *string) error {
+	workloadID, err := s.workloadID(ctx, workloadName)
+	if err != nil {
+		return err
+	}
+	_, err = s.volumes.Attach(ctx, id, &volume.AttachRequest{WorkloadID: workloadID, Path: mountPath}, actor)
+	return err
+}
+
+// planAttachments gives every workload of the manifest exactly the security
+// groups of its spec.
+func (s *Service) planAttachments(ctx context.Context, m *Manifest, actor *string) ([]Change, error) {
+	var changes []Change
+	for _, spec := range m.Workloads {
+		var current []string
+		w, err := s.workloadByName(ctx, spec.Name)
+		if err != nil {
+			return nil, err
+		}
+		if w != nil {
+			if current, err = s.securityGroups.GroupsOf(ctx, w.ID); err != nil {
+				return nil, err
+			}
+		}
+
+		var attach, detach []string
+		for _, g := range spec.SecurityGroups {
+			if !slices.Contains(current, g) && !slices.Contains(attach, g) {
+				attach = append(attach, g)
+			}
+		}
+		for _, g := range current {
+			if !slices.Contains(spec.SecurityGroups, g) {
+				detach = append(detach, g)
+			}
+		}
+		if len(attach) == 0 && len(detach) == 0 {
+			continue
+		}
+
+		var detail []string
+		if len(attach) > 0 {
+			detail = append(detail, "attach security groups "+strings.Join(attach, ", "))
+		}
+		if len(detach) > 0 {
+			detail = append(detail, "detach security groups "+strings.Join(detach, ", "))
+		}
+		name := spec.Name
+		changes = append(changes, Change{Kind: KindWorkload, Name: spec.Name, Action: ActionUpdate, Detail: strings.Join(detail, "; "),
+			run: func(ctx context.Context) error {
+				id, err := s.workloadID(ctx, name)
+				if err != nil {
+					return err
+				}
+				for _, g := range attach {
+					if _, err := s.securityGroups.Attach(ctx, id, g); err != nil {
+						return err
+					}
+				}
+				for _, g := range detach {
+					if _, err := s.securityGroups.Detach(ctx, id, g); err != nil {
+						return err
+					}
+				}
+				return nil
+			}})
+	}
+	return changes, nil
+}
+
+// planFloatingIPs gives every workload of the manifest one floating IP if
+// its spec has floating_ip, and none otherwise.
+func (s *Service) planFloatingIPs(ctx context.Context, m *Manifest, actor *string) ([]Change, error) {
+	repo := database.NewFloatingIPRepository(s.db)
+	var changes []Change
+	for _, spec := range m.Workloads {
+		var current []database.FloatingIP
+		w, err := s.workloadByName(ctx, spec.Name)
+		if err != nil {
+			return nil, err
+		}
+		if w != nil {
+			if current, err = repo.ListByWorkload(ctx, w.ID); err != nil {
+				return nil, err
+			}
+		}
+
+		keep := -1
+		if spec.FloatingIP != nil {
+			for i, f := range current {
+				if spec.FloatingIP.Address == "" || f.Address == spec.FloatingIP.Address {
+					keep = i
+					break
+				}
+			}
+		}
+		for i, f := range current {
+			if i == keep {
+				continue
+			}
+			address := f.Address
+			changes = append(changes, Change{Kind: KindFloatingIP, Name: address, Action: ActionDelete, Detail: "workload " + spec.Name,
+				run: func(ctx context.Context) error { return s.network.Release(ctx, address) }})
+		}
+		if spec.FloatingIP == nil || keep >= 0 {
+			continue
+		}
+
+		req := network.ExposeRequest{Address: spec.FloatingIP.Address, DNSName: spec.FloatingIP.DNSName}
+		name := spec.Name
+		changes = append(changes, Change{Kind: KindFloatingIP, Name: cmp.Or(req.Address, "next free address"), Action: ActionCreate, Detail: "workload " + spec.Name,
+			run: func(ctx context.Context) error {
+				id, err := s.workloadID(ctx, name)
+				if err != nil {
+					return err
+				}
+				req.WorkloadID = id
+				_, err = s.network.Expose(ctx, &req, actor)
+				return err
+			}})
+	}
+	return changes, nil
+}
+
+// planPrune deletes the volumes, security groups and flavors the manifest
+// does not mention, in that order: a volume may be attached to a workload,
+// and a flavor may still be used by a workload outside the manifest, in which
+// case its deletion fails.
+func (s *Service) planPrune(ctx context.Context, m *Manifest, actor *string) ([]Change, error) {
+	var changes []Change
+
+	volumes, err := s.volumes.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	matched, err := matchVolumes(volumes, m.Volumes)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range volumes {
+		if slices.ContainsFunc(matched, func(cur *database.Volume) bool { return cur != nil && cur.ID == v.ID }) {
+			continue
+		}
+		id, attached := v.ID, v.WorkloadID != nil
+		detail := "pool " + v.Pool
+		if attached {
+			detail += ", detached first"
+		}
+		changes = append(changes, Change{Kind: KindVolume, Name: v.Name, Action: ActionDelete, Detail: detail,
+			run: func(ctx context.Context) error {
+				if attached {
+					if _, err := s.volumes.Detach(ctx, id, actor); err != nil {
+						return err
+					}
+				}
+				return s.volumes.Delete(ctx, id)
+			}})
+	}
+
+	groups, err := s.securityGroups.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range groups {
+		if slices.ContainsFunc(m.SecurityGroups, func(d securitygroup.CreateRequest) bool { return d.Name == g.Name }) {
+			continue
+		}
+		name := g.Name
+		changes = append(changes, Change{Kind: KindSecurityGroup, Name: name, Action: ActionDelete,
+			run: func(ctx context.Context) error { return s.securityGroups.Delete(ctx, name) }})
+	}
+
+	flavors, err := s.flavors.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range flavors {
+		if slices.ContainsFunc(m.Flavors, func(d flavor.CreateRequest) bool { return d.Name == f.Name }) {
+			continue
+		}
+		name := f.Name
+		changes = append(changes, Change{Kind: KindFlavor, Name: name, Action: ActionDelete,
+			run: func(ctx context.Context) error { return s.flavors.Delete(ctx, name) }})
+	}
+	return changes, nil
+}
+
+// workloadByName returns the workload with this name, nil if there is none.
+func (s *Service) workloadByName(ctx context.Context, name string) (*database.Workload, error) {
+	clusters, err := database.NewClusterRepository(s.db).List(ctx)
+	if err != nil || len(clusters) == 0 {
+		return nil, err
+	}
+	w, err := database.NewWorkloadRepository(s.db).GetByName(ctx, clusters[0].ID, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return w, err
+}
+
+// workloadID resolves a workload name when a change runs, after the workload
+// may have been created by an earlier change.
+func (s *Service) workloadID(ctx context.Context, name string) (string, error) {
+	w, err := s.workloadByName(ctx, name)
+	if err == nil && w == nil {
+		err = fmt.Errorf("%w: %s", workload.ErrWorkloadNotFound, name)
+	}
+	if err != nil {
+		return "", err
+	}
+	return w.ID, nil
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,162 @@
+package apply
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"mcloud/internal/audit"
+	"mcloud/internal/config"
+	"mcloud/internal/database"
+	"mcloud/internal/dns"
+	"mcloud/internal/flavor"
+	"mcloud/internal/network"
+	"mcloud/internal/operation"
+	"mcloud/internal/project"
+	"mcloud/internal/secret"
+	"mcloud/internal/securitygroup"
+	"mcloud/internal/volume"
+	"mcloud/internal/workload"
+)
+
+func newTestService(t *testing.T) (*Service, *sql.DB) {
+	t.Helper()
+	db, err := database.Connect(filepath.Join(t.TempDir(), "mcloud.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close(db) })
+
+	cfg := &config.Config{}
+	ops := operation.NewService(db, cfg)
+	flavors := flavor.NewService(db)
+	workloads := workload.NewService(db, cfg, secret.NewService(db, ""), flavors, project.NewService(db, cfg), ops, audit.NewSessions(db, cfg.Audit.ExecRecording))
+	return NewService(db, flavors, securitygroup.NewService(db, cfg.Network, project.NewService(db, cfg)), volume.NewService(db, project.NewService(db, cfg)),
+		network.NewService(db, cfg.Network, dns.NewService(db, cfg.DNS)), workloads, ops), db
+}
+
+// summary renders a plan as "action kind name: detail" lines.
+func summary(changes []Change) []string {
+	var out []string
+	for _, c := range changes {
+		out = append(out, strings.TrimSuffix(c.Action+" "+c.Kind+" "+c.Name+": "+c.Detail, ": "))
+	}
+	return out
+}
+
+func TestPlanValidation(t *testing.T) {
+	s, _ := newTestService(t)
+	ctx := context.Background()
+
+	web := WorkloadSpec{CreateRequest: workload.CreateRequest{Name: "web-1", Image: "ubuntu:24.04"}}
+	manifests := map[string]Manifest{
+		"wrong kind":            {Kind: "ClusterState"},
+		"duplicate workload":    {Kind: SpecKind, Workloads: []WorkloadSpec{web, web}},
+		"unknown flavor":        {Kind: SpecKind, Workloads: []WorkloadSpec{{CreateRequest: workload.CreateRequest{Name: "db-1", Flavor: "huge"}}}},
+		"unknown group":         {Kind: SpecKind, Workloads: []WorkloadSpec{{CreateRequest: web.CreateRequest, SecurityGroups: []string{"ssh"}}}},
+		"invalid rule":          {Kind: SpecKind, SecurityGroups: []securitygroup.CreateRequest{{Name: "web", Rules: []database.SecurityGroupRule{{Direction: "in"}}}}},
+		"undeclared workload":   {Kind: SpecKind, Volumes: []VolumeSpec{{CreateRequest: volume.CreateRequest{Name: "data", SizeGB: 10}, Workload: "db-1", MountPath: "/data"}}},
+		"path without workload": {Kind: SpecKind, Volumes: []VolumeSpec{{CreateRequest: volume.CreateRequest{Name: "data", SizeGB: 10}, MountPath: "/data"}}},
+	}
+	for name, m := range manifests {
+		if _, err := s.Plan(ctx, &m, false, nil); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: got %v, want ErrInvalid", name, err)
+		}
+	}
+}
+
+func TestPlan(t *testing.T) {
+	s, db := newTestService(t)
+	ctx := context.Background()
+
+	staging, small, remote := "staging", "small", "remote"
+	steps := []error{
+		database.NewClusterRepository(db).Create(ctx, &database.Cluster{ID: "c-1", Name: "prod", State: "active"}),
+		database.NewWorkloadRepository(db).Create(ctx, &database.Workload{ID: "w-1", ClusterID: "c-1", Name: "web-1", Kind: "container", Status: "running", Image: "ubuntu:24.04", Priority: "normal", Flavor: &small}),
+		database.NewWorkloadEnvRepository(db).Create(ctx, &database.WorkloadEnv{WorkloadID: "w-1", Key: "APP_ENV", Value: &staging}),
+		database.NewSecurityGroupRepository(db).Create(ctx, &database.SecurityGroup{Name: "old"}),
+		database.NewSecurityGroupRepository(db).Attach(ctx, "w-1", "old"),
+		database.NewVolumeRepository(db).Create(ctx, &database.Volume{ID: "v-1", Name: "data", Pool: remote, SizeGB: 10}),
+		database.NewFloatingIPRepository(db).Create(ctx, &database.FloatingIP{Address: "203.0.113.17", WorkloadID: "w-1", Network: "default", TargetAddress: "10.0.0.5"}),
+	}
+	for _, err := range steps {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := Manifest{
+		Kind: SpecKind,
+		Flavors: []flavor.CreateRequest{
+			{Name: "small", CPUs: 1, MemoryMB: 1024, DiskGB: 10},
+			{Name: "xlarge", CPUs: 8, MemoryMB: 16384, DiskGB: 80},
+		},
+		SecurityGroups: []securitygroup.CreateRequest{
+			{Name: "web", Rules: []database.SecurityGroupRule{{Direction: "ingress", Protocol: "tcp", Port: "443"}}},
+		},
+		Workloads: []WorkloadSpec{
+			{CreateRequest: workload.CreateRequest{Name: "web-1", Image: "ubuntu:24.04", Flavor: "small", Env: map[string]string{"APP_ENV": "production"}}, SecurityGroups: []string{"web"}},
+			{CreateRequest: workload.CreateRequest{Name: "db-1", Image: "ubuntu:24.04", Flavor: "xlarge"}, FloatingIP: &FloatingIPSpec{}},
+		},
+		Volumes: []VolumeSpec{
+			{CreateRequest: volume.CreateRequest{Name: "data", SizeGB: 10}, Workload: "db-1", MountPath: "/var/lib/db/"},
+		},
+	}
+	changes, err := s.Plan(ctx, &m, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"create flavor xlarge: 8 cpus, 16384 MiB, 80 GiB",
+		"create security_group web: 1 rules",
+		"update workload web-1: env APP_ENV",
+		"create workload db-1: container ubuntu:24.04",
+		"update volume data: attach to db-1 at /var/lib/db",
+		"update workload web-1: attach security groups web; detach security groups old",
+		"delete floating_ip 203.0.113.17: workload web-1",
+		"create floating_ip next free address: workload db-1",
+		"delete security_group old",
+		"delete flavor medium", // seeded by migration 025
+		"delete flavor large",
+	}
+	if got := summary(changes); !slices.Equal(got, want) {
+		t.Fatalf("plan:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// A manifest of what exists plans nothing, even with prune
+	current := Manifest{
+		Kind: SpecKind,
+		Flavors: []flavor.CreateRequest{
+			{Name: "small", CPUs: 1, MemoryMB: 1024, DiskGB: 10},
+			{Name: "medium", CPUs: 2, MemoryMB: 4096, DiskGB: 20},
+			{Name: "large", CPUs: 4, MemoryMB: 8192, DiskGB: 40},
+		},
+		SecurityGroups: []securitygroup.CreateRequest{{Name: "old"}},
+		Workloads: []WorkloadSpec{
+			{CreateRequest: workload.CreateRequest{Name: "web-1", Kind: "container", Image: "ubuntu:24.04", Flavor: "small", Priority: "normal", Env: map[string]string{"APP_ENV": "staging"}},
+				SecurityGroups: []string{"old"}, FloatingIP: &FloatingIPSpec{Address: "203.0.113.17"}},
+		},
+		Volumes: []VolumeSpec{{CreateRequest: volume.CreateRequest{Name: "data", Pool: "remote", SizeGB: 10}}},
+	}
+	if changes, err := s.Plan(ctx, &current, true, nil); err != nil || len(changes) != 0 {
+		t.Fatalf("plan of the current state: got %v, %v", summary(changes), err)
+	}
+
+	// Changes that need a new instance or volume are refused as a whole
+	m.Workloads[0].Image = "ubuntu:22.04"
+	m.Volumes[0].SizeGB = 20
+	if _, err := s.Apply(ctx, &Request{Manifest: m}, nil); !errors.Is(err, ErrConflict) ||
+		!strings.Contains(err.Error(), "image ubuntu:24.04 -> ubuntu:22.04") || !strings.Contains(err.Error(), "cannot be resized") {
+		t.Fatalf("conflicts: got %v, want ErrConflict", err)
+	}
+
+	// A dry run starts no operation
+	result, err := s.Apply(ctx, &Request{Manifest: current, DryRun: true}, nil)
+	if err != nil || result.Operation != nil || len(result.Changes) != 0 {
+		t.Fatalf("dry run: got %+v, %v", result, err)
+	}
+}
@@ -0,0 +1,187 @@
+package audit
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"mcloud/internal/auth"
+	"mcloud/internal/database"
+	"mcloud/pkg/reason"
+	"mcloud/pkg/utils"
+)
+
+const defaultListLimit = 100
+
+type Handler struct {
+	db       *sql.DB
+	sessions *Sessions
+}
+
+type Entry struct {
+	ID          int64     `json:"id"`
+	Actor       string    `json:"actor"`
+	Action      string    `json:"action"`
+	Target      string    `json:"target"`
+	PayloadHash *string   `json:"payload_hash,omitempty"`
+	StatusCode  int       `json:"status_code"`
+	Result      string    `json:"result"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func NewHandler(db *sql.DB, sessions *Sessions) *Handler {
+	return &Handler{db: db, sessions: sessions}
+}
+
+// ListAudit handles GET /audit?since=1h&limit=100.
+// since accepts a Go duration (relative to now) or an RFC 3339 timestamp.
+func (h *Handler) ListAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	since, err := ParseSince(r.URL.Query().Get("since"))
+	if err != nil {
+		reason.HTTPError(w, err, 400)
+		return
+	}
+
+	limit := defaultListLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			reason.HTTPErrorf(w, 400, "invalid limit")
+			return
+		}
+	}
+
+	// The limit is unbounded, so entries are encoded as they are read
+	stream := utils.NewJSONStream(w, "")
+	err = database.NewAuditRepository(h.db).EachSince(r.Context(), since, limit, func(e *database.AuditEntry) error {
+		return stream.Write(Entry{
+			ID:          e.ID,
+			Actor:       e.Actor,
+			Action:      e.Action,
+			Target:      e.Target,
+			PayloadHash: e.PayloadHash,
+			StatusCode:  e.StatusCode,
+			Result:      e.Result,
+			CreatedAt:   e.CreatedAt,
+		})
+	})
+	if err != nil {
+		if !stream.Started() {
+			reason.HTTPError(w, err, 500)
+		}
+		return
+	}
+	stream.Close(nil)
+}
+
+// ListExecSessionsResponse is one page of GET /audit/exec-sessions.
+type ListExecSessionsResponse struct {
+	Items      []database.ExecSession `json:"items"`
+	NextOffset int                    `json:"next_offset,omitempty"` // 0 when there are no more pages
+}
+
+// ListExecSessions handles GET /audit/exec-sessions?limit=100&offset=0, newest first.
+// Like the other exec-session routes it is limited to the cluster admin, as
+// transcripts hold everything typed into the session. Optional parameters:
+//   sort                     started_at or actor; "-" prefix for descending
+//   actor, target, instance  exact-match filters (target is e.g. /workloads/<id>)
+func (h *Handler) ListExecSessions(w http.ResponseWriter, r *http.Request) {
+	if !auth.RequireAdmin(w, r) {
+		return
+	}
+
+	limit, offset, err := utils.ParsePage(r)
+	if err != nil {
+		reason.HTTPError(w, err, 400)
+		return
+	}
+
+	sort, filter := utils.ParseListQuery(r, "actor", "target", "instance")
+	sessions, err := h.sessions.List(r.Context(), database.ListOptions{Limit: limit, Offset: offset, Sort: sort, Filter: filter})
+	if errors.Is(err, database.ErrInvalidListOption) {
+		reason.HTTPError(w, err, 400)
+		return
+	}
+	if err != nil {
+		reason.HTTPError(w, err, 500)
+		return
+	}
+
+	resp := ListExecSessionsResponse{Items: sessions}
+	if resp.Items == nil {
+		resp.Items = []database.ExecSession{}
+	}
+	if len(sessions) == limit {
+		resp.NextOffset = offset + limit
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// GetExecSession handles GET /audit/exec-sessions/{id}, the target of the
+// audit entry of an exec request.
+func (h *Handler) GetExecSession(w http.ResponseWriter, r *http.Request) {
+	if !auth.RequireAdmin(w, r) {
+		return
+	}
+
+	session, err := h.sessions.Get(r.Context(), r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, ErrSessionNotFound) {
+			reason.HTTPError(w, err, 404)
+			return
+		}
+		reason.HTTPError(w, err, 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(session)
+}
+
+// GetExecTranscript handles GET /audit/exec-sessions/{id}/transcript. The
+// transcript is an asciicast v2 file (replay with `asciinema play`); 404 if
+// the session was not recorded.
+func (h *Handler) GetExecTranscript(w http.ResponseWriter, r *http.Request) {
+	if !auth.RequireAdmin(w, r) {
+		return
+	}
+
+	transcript, err := h.sessions.Transcript(r.Context(), r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, ErrSessionNotFound) {
+			reason.HTTPError(w, err, 404)
+			return
+		}
+		reason.HTTPError(w, err, 500)
+		return
+	}
+	if transcript == nil {
+		reason.HTTPErrorf(w, 404, "exec session %s was not recorded", r.PathValue("id"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-asciicast")
+	w.Write(transcript)
+}
+
+// ParseSince converts a "since" filter into an absolute time.
+// An empty value means "from the beginning".
+func ParseSince(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(v); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
@@ -0,0 +1,15 @@
+package audit
+
+import (
+	"net/http"
+)
+
+// InitModule registers the audit routes served by handler.
+func InitModule(mux *http.ServeMux, handler *Handler) {
+	mux.HandleFunc("/audit", handler.ListAudit)
+
+	// Exec sessions, the targets of exec audit entries
+	mux.HandleFunc("GET /audit/exec-sessions", handler.ListExecSessions)
+	mux.HandleFunc("GET /audit/exec-sessions/{id}", handler.GetExecSession)
+	mux.HandleFunc("GET /audit/exec-sessions/{id}/transcript", handler.GetExecTranscript)
+}
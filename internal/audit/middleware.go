@@ -0,0 +1,183 @@
+// Package audit records every mutating API call (who did what to which resource,
+// and with what result) and exposes the records via GET /audit.
+package audit
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"hash"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"mcloud/internal/auth"
+	"mcloud/internal/database"
+	"mcloud/pkg/logger"
+	"mcloud/pkg/reason"
+)
+
+// MaxBodyBytes bounds the body of a mutating request; no API request comes
+// close, and larger ones are cut off with 413 by http.MaxBytesReader.
+const MaxBodyBytes = 8 << 20
+
+// unauditedPaths are high-frequency agent reports rather than operator actions.
+var unauditedPaths = map[string]bool{
+	"/nodes/metrics": true,
+}
+
+// statusRecorder captures the status code written by the wrapped handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	s.status = code
+	s.ResponseWriter.WriteHeader(code)
+}
+
+// Hijack records the switch of protocols of an upgraded connection (e.g. exec
+// sessions), which the handler then answers on the raw connection.
+func (s *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(s.ResponseWriter).Hijack()
+	if err == nil {
+		s.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer (e.g. for flushing).
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// targetKey holds the audit target of a request in its context (see SetTarget).
+type targetKey struct{}
+
+// SetTarget replaces the target recorded in the audit entry of r, by default
+// its path. Handlers use it to point the entry at a record of what the request
+// did, e.g. /audit/exec-sessions/<id> for an exec session.
+func SetTarget(r *http.Request, target string) {
+	if p, ok := r.Context().Value(targetKey{}).(*string); ok {
+		*p = target
+	}
+}
+
+// routeKey holds the route the mux matched in a request's context (see RecordRoute).
+type routeKey struct{}
+
+// RecordRoute wraps the mux so that Middleware learns the route it matched.
+// The mux sets r.Pattern on the request it is given, and handlers between
+// Middleware and the mux (e.g. tracing) pass it a copy made by WithContext,
+// so Middleware's own request never has the pattern set.
+//
+// Example:
+//   audit.Middleware(db, tracing.Handler(audit.RecordRoute(mux), "mcloudd"))
+func RecordRoute(mux http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mux.ServeHTTP(w, r)
+		if p, ok := r.Context().Value(routeKey{}).(*string); ok {
+			*p = r.Pattern
+		}
+	})
+}
+
+// hashingBody hashes a request body as the handler reads it.
+type hashingBody struct {
+	io.Reader
+	io.Closer
+	hash hash.Hash
+	read int64
+}
+
+func (b *hashingBody) Read(p []byte) (int, error) {
+	n, err := b.Reader.Read(p)
+	b.read += int64(n)
+	return n, err
+}
+
+// Middleware records an audit entry for every mutating request (anything but GET, HEAD, OPTIONS).
+// The request body is hashed (SHA-256) as it streams to the handler rather than stored, so secrets
+// in payloads never reach the log, and is limited to MaxBodyBytes. Mutating requests without a
+// verified client certificate are refused before anything is read or recorded; RequireClientCert
+// refuses them as well, this keeps anonymous callers from filling the log if the two are reordered.
+func Middleware(db *sql.DB, next http.Handler) http.Handler {
+	auditRepo := database.NewAuditRepository(db)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+		actor := auth.ClientIdentity(r)
+		if actor == "" {
+			reason.HTTPErrorf(w, http.StatusUnauthorized, "client certificate required")
+			return
+		}
+		if unauditedPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var body *hashingBody
+		if r.Body != nil {
+			limited := http.MaxBytesReader(w, r.Body, MaxBodyBytes)
+			h := sha256.New()
+			body = &hashingBody{Reader: io.TeeReader(limited, h), Closer: limited, hash: h}
+			r.Body = body
+		}
+
+		target, pattern := r.URL.Path, ""
+		ctx := context.WithValue(r.Context(), targetKey{}, &target)
+		r = r.WithContext(context.WithValue(ctx, routeKey{}, &pattern))
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		// Hash what the handler left unread too, unless the connection was
+		// taken over (exec sessions)
+		var payloadHash *string
+		if body != nil {
+			if rec.status != http.StatusSwitchingProtocols {
+				io.Copy(io.Discard, body)
+			}
+			if body.read > 0 {
+				h := hex.EncodeToString(body.hash.Sum(nil))
+				payloadHash = &h
+			}
+		}
+
+		// The pattern is filled in by the mux, e.g. "/workloads/{id}/clone",
+		// through RecordRoute or directly when nothing replaced r
+		if pattern == "" {
+			pattern = r.Pattern
+		}
+		action := r.Method + " " + r.URL.Path
+		if pattern != "" {
+			action = r.Method + " " + strings.TrimPrefix(pattern, r.Method+" ")
+		}
+
+		result := "success"
+		if rec.status >= 400 {
+			result = "failure"
+		}
+
+		entry := &database.AuditEntry{
+			Actor:       actor,
+			Action:      action,
+			Target:      target,
+			PayloadHash: payloadHash,
+			StatusCode:  rec.status,
+			Result:      result,
+		}
+		// Record even if the client went away mid-request
+		if err := auditRepo.Create(context.Background(), entry); err != nil {
+			logger.Error("failed to write audit entry for %s: %v", action, err)
+		}
+	})
+}
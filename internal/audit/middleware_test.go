@@ -0,0 +1,121 @@
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"mcloud/internal/database"
+)
+
+// withClientCert makes r look like it came with a verified client certificate for cn.
+func withClientCert(r *http.Request, cn string) *http.Request {
+	leaf := &x509.Certificate{Subject: pkix.Name{CommonName: cn}}
+	r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{leaf}}}
+	return r
+}
+
+func TestMiddleware(t *testing.T) {
+	ctx := context.Background()
+	db, err := database.Connect(filepath.Join(t.TempDir(), "mcloud.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close(db)
+
+	// The handler reads only the first byte; the rest is hashed all the same
+	var served int
+	handler := Middleware(db, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served++
+		r.Body.Read(make([]byte, 1))
+		if _, err := io.ReadAll(r.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		}
+	}))
+
+	body := `{"name": "web-1"}`
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, withClientCert(httptest.NewRequest(http.MethodPost, "/workloads", strings.NewReader(body)), "admin"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d", w.Code)
+	}
+
+	// Anonymous callers are refused before the handler runs or anything is recorded
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/workloads", strings.NewReader(body)))
+	if w.Code != http.StatusUnauthorized || served != 1 {
+		t.Errorf("anonymous POST: status %d, handler ran %d times", w.Code, served)
+	}
+
+	// Oversized bodies are cut off
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, withClientCert(httptest.NewRequest(http.MethodPut, "/config/a/b", strings.NewReader(strings.Repeat("x", MaxBodyBytes+1))), "admin"))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized PUT: status %d, want 413", w.Code)
+	}
+
+	entries, err := database.NewAuditRepository(db).ListSince(ctx, time.Time{}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("audit entries = %+v, want the POST and the oversized PUT", entries)
+	}
+	sum := sha256.Sum256([]byte(body))
+	var post *database.AuditEntry
+	for i := range entries {
+		if entries[i].Action == "POST /workloads" {
+			post = &entries[i]
+		}
+	}
+	if post == nil || post.Actor != "admin" || post.PayloadHash == nil || *post.PayloadHash != hex.EncodeToString(sum[:]) {
+		t.Errorf("POST entry = %+v, want actor admin and the body's SHA-256", post)
+	}
+}
+
+func TestMiddlewareRoute(t *testing.T) {
+	ctx := context.Background()
+	db, err := database.Connect(filepath.Join(t.TempDir(), "mcloud.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close(db)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /workloads/{id}/clone", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/audit", func(w http.ResponseWriter, r *http.Request) {})
+	// Like otelhttp, hand the mux a copy of the request
+	type spanKey struct{}
+	copying := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		RecordRoute(mux).ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), spanKey{}, "span")))
+	})
+	handler := Middleware(db, copying)
+
+	for _, path := range []string{"/workloads/550e8400/clone", "/audit", "/nowhere"} {
+		handler.ServeHTTP(httptest.NewRecorder(), withClientCert(httptest.NewRequest(http.MethodPost, path, nil), "admin"))
+	}
+	entries, err := database.NewAuditRepository(db).ListSince(ctx, time.Time{}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actions []string
+	for _, e := range entries {
+		actions = append(actions, e.Action)
+	}
+	slices.Sort(actions)
+	want := []string{"POST /audit", "POST /nowhere", "POST /workloads/{id}/clone"}
+	if !slices.Equal(actions, want) {
+		t.Errorf("actions = %v, want %v", actions, want)
+	}
+}
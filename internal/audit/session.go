@@ -0,0 +1,252 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"mcloud/internal/config"
+	"mcloud/internal/database"
+	"mcloud/pkg/logger"
+	"mcloud/pkg/reason"
+	"mcloud/pkg/utils"
+)
+
+const (
+	// DefaultTranscriptBytes applies when audit.exec_recording.max_bytes is not set.
+	DefaultTranscriptBytes = 1 << 20
+
+	// DefaultSessionRetention applies when audit.exec_recording.retention_days is not set.
+	DefaultSessionRetention = 90 * 24 * time.Hour
+)
+
+var ErrSessionNotFound = reason.New(reason.ExecSessionNotFound, "exec session not found")
+
+// Sessions records exec sessions: metadata always, transcripts when
+// audit.exec_recording is enabled.
+type Sessions struct {
+	db        *sql.DB
+	record    bool
+	maxBytes  int
+	retention time.Duration
+}
+
+func NewSessions(db *sql.DB, cfg config.ExecRecording) *Sessions {
+	s := &Sessions{
+		db:        db,
+		record:    cfg.Enabled,
+		maxBytes:  DefaultTranscriptBytes,
+		retention: DefaultSessionRetention,
+	}
+	if cfg.MaxBytes > 0 {
+		s.maxBytes = cfg.MaxBytes
+	}
+	if cfg.RetentionDays > 0 {
+		s.retention = time.Duration(cfg.RetentionDays) * 24 * time.Hour
+	}
+	return s
+}
+
+// Session is an exec session in progress. Wrap its streams with Input and
+// Output so they are recorded, and call End once the command has exited.
+type Session struct {
+	*database.ExecSession
+
+	sessions *Sessions
+	rec      *Recorder // nil when recording is disabled
+}
+
+// Start records the start of an exec session by actor into instance, the
+// resource at target (e.g. /workloads/<id>). The caller links the request's
+// audit entry to the session with SetTarget.
+//
+// Example Output:
+//   &Session{ExecSession: {ID: "0b7e...", Target: "/workloads/550e8400-...", Instance: "web-1",
+//     Actor: "admin", Command: ["bash"]}}
+func (s *Sessions) Start(ctx context.Context, target string, instance string, actor string, command []string) (*Session, error) {
+	es := &database.ExecSession{
+		ID:        utils.GenerateUUID(),
+		Target:    target,
+		Instance:  instance,
+		Actor:     actor,
+		Command:   command,
+		StartedAt: time.Now(),
+	}
+	if err := database.NewExecSessionRepository(s.db).Create(ctx, es); err != nil {
+		return nil, err
+	}
+
+	session := &Session{ExecSession: es, sessions: s}
+	if s.record {
+		session.rec = NewRecorder(s.maxBytes, es.StartedAt, command)
+	}
+	return session, nil
+}
+
+// Input returns r, recording what is read from it as session input.
+func (s *Session) Input(r io.Reader) io.Reader {
+	if s.rec == nil {
+		return r
+	}
+	return io.TeeReader(r, s.rec.Stream("i"))
+}
+
+// Output returns w, recording what is written to it as session output.
+func (s *Session) Output(w io.Writer) io.Writer {
+	if s.rec == nil {
+		return w
+	}
+	return io.MultiWriter(s.rec.Stream("o"), w)
+}
+
+// End records how the session ended: the command's exit code, or runErr if it
+// could not be run to completion.
+func (s *Session) End(exitCode int, runErr error) {
+	var code *int
+	var errMsg *string
+	if runErr != nil {
+		msg := runErr.Error()
+		errMsg = &msg
+	} else {
+		code = &exitCode
+	}
+
+	var transcript []byte
+	var truncated bool
+	if s.rec != nil {
+		transcript, truncated = s.rec.Bytes()
+	}
+
+	// The request may be gone by now (client disconnected), so use a fresh context
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := database.NewExecSessionRepository(s.sessions.db).Finish(ctx, s.ID, code, errMsg, transcript, truncated); err != nil {
+		logger.Error("failed to record end of exec session %s: %v", s.ID, err)
+	}
+}
+
+// Get returns a session's metadata.
+func (s *Sessions) Get(ctx context.Context, id string) (*database.ExecSession, error) {
+	es, err := database.NewExecSessionRepository(s.db).GetByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSessionNotFound
+	}
+	return es, err
+}
+
+// Transcript returns a session's transcript in asciicast v2 format, or nil if
+// it was not recorded.
+func (s *Sessions) Transcript(ctx context.Context, id string) ([]byte, error) {
+	transcript, err := database.NewExecSessionRepository(s.db).GetTranscript(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSessionNotFound
+	}
+	return transcript, err
+}
+
+// List returns sessions, newest first unless opts sorts otherwise.
+func (s *Sessions) List(ctx context.Context, opts database.ListOptions) ([]database.ExecSession, error) {
+	return database.NewExecSessionRepository(s.db).List(ctx, opts)
+}
+
+// Prune deletes sessions older than the retention period. It is run as a job.
+func (s *Sessions) Prune(ctx context.Context) error {
+	return database.NewExecSessionRepository(s.db).DeleteStartedBefore(ctx, time.Now().Add(-s.retention))
+}
+
+// Recorder builds an asciicast v2 transcript (https://docs.asciinema.org/manual/asciicast/v2/):
+// a JSON header line, then one [seconds, "i"|"o", data] line per read or write.
+// Events past the size limit are dropped and the transcript is marked truncated.
+//
+// Example Output:
+//   {"version":2,"width":80,"height":24,"timestamp":1792146645,"command":"bash"}
+//   [0.412,"i","ls\n"]
+//   [0.431,"o","etc  home  var\n"]
+type Recorder struct {
+	mu        sync.Mutex
+	start     time.Time
+	buf       []byte
+	max       int
+	truncated bool
+}
+
+func NewRecorder(maxBytes int, start time.Time, command []string) *Recorder {
+	header, _ := json.Marshal(map[string]any{
+		"version":   2,
+		"width":     80,
+		"height":    24,
+		"timestamp": start.Unix(),
+		"command":   joinCommand(command),
+	})
+	return &Recorder{start: start, buf: append(header, '\n'), max: maxBytes}
+}
+
+// Stream returns a writer recording everything written to it as events of
+// the given type ("i" for input, "o" for output). It never fails.
+func (r *Recorder) Stream(kind string) io.Writer {
+	return recorderStream{r: r, kind: kind}
+}
+
+type recorderStream struct {
+	r    *Recorder
+	kind string
+}
+
+func (s recorderStream) Write(p []byte) (int, error) {
+	s.r.add(s.kind, p)
+	return len(p), nil
+}
+
+func (r *Recorder) add(kind string, p []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.truncated {
+		return
+	}
+
+	elapsed := time.Since(r.start).Seconds()
+	data, _ := json.Marshal(string(p))
+	line := fmt.Appendf(nil, "[%.3f,%q,%s]\n", elapsed, kind, data)
+	if len(r.buf)+len(line) > r.max {
+		r.truncated = true
+		return
+	}
+	r.buf = append(r.buf, line...)
+}
+
+// Bytes returns the transcript so far and whether events were dropped.
+func (r *Recorder) Bytes() ([]byte, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]byte(nil), r.buf...), r.truncated
+}
+
+// joinCommand renders a command for the transcript header, quoting arguments with spaces.
+func joinCommand(command []string) string {
+	var out []byte
+	for i, arg := range command {
+		if i > 0 {
+			out = append(out, ' ')
+		}
+		if arg == "" || containsSpace(arg) {
+			out = fmt.Appendf(out, "%q", arg)
+		} else {
+			out = append(out, arg...)
+		}
+	}
+	return string(out)
+}
+
+func containsSpace(s string) bool {
+	for _, c := range s {
+		if c == ' ' || c == '\t' || c == '\n' {
+			return true
+		}
+	}
+	return false
+}
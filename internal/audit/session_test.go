@@ -0,0 +1,115 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"mcloud/internal/config"
+	"mcloud/internal/database"
+)
+
+func TestRecorder(t *testing.T) {
+	rec := NewRecorder(200, time.Now(), []string{"sh", "-c", "echo hi"})
+	rec.Stream("i").Write([]byte("ls\n"))
+	rec.Stream("o").Write([]byte("a  b\n"))
+
+	transcript, truncated := rec.Bytes()
+	if truncated {
+		t.Fatal("truncated below the limit")
+	}
+	lines := bytes.Split(bytes.TrimSpace(transcript), []byte("\n"))
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want header and 2 events:\n%s", len(lines), transcript)
+	}
+
+	var header map[string]any
+	if err := json.Unmarshal(lines[0], &header); err != nil {
+		t.Fatal(err)
+	}
+	if header["version"] != 2.0 || header["command"] != `sh -c "echo hi"` {
+		t.Errorf("header = %v", header)
+	}
+	for i, want := range []struct{ kind, data string }{{"i", "ls\n"}, {"o", "a  b\n"}} {
+		var event []any
+		if err := json.Unmarshal(lines[i+1], &event); err != nil {
+			t.Fatal(err)
+		}
+		if len(event) != 3 || event[1] != want.kind || event[2] != want.data {
+			t.Errorf("event %d = %v, want [_, %q, %q]", i, event, want.kind, want.data)
+		}
+	}
+
+	// Events past the limit are dropped whole, and so is everything after them
+	rec.Stream("o").Write(bytes.Repeat([]byte("x"), 200))
+	rec.Stream("o").Write([]byte("y"))
+	after, truncated := rec.Bytes()
+	if !truncated || !bytes.Equal(after, transcript) {
+		t.Errorf("after limit: truncated %v, transcript grew from %d to %d bytes", truncated, len(transcript), len(after))
+	}
+}
+
+func TestExecSession(t *testing.T) {
+	ctx := context.Background()
+	db, err := database.Connect(filepath.Join(t.TempDir(), "mcloud.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close(db)
+	sessions := NewSessions(db, config.ExecRecording{Enabled: true})
+
+	// The exec request's audit entry points at the session it started
+	var session *Session
+	handler := Middleware(db, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		if session, err = sessions.Start(r.Context(), "/workloads/w1", "web-1", "admin", []string{"bash"}); err != nil {
+			t.Fatal(err)
+		}
+		SetTarget(r, "/audit/exec-sessions/"+session.ID)
+
+		var out bytes.Buffer
+		io.Copy(session.Output(&out), session.Input(strings.NewReader("exit 3\n")))
+		session.End(3, nil)
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), withClientCert(httptest.NewRequest(http.MethodPost, "/workloads/w1/exec", nil), "admin"))
+
+	entries, err := database.NewAuditRepository(db).ListSince(ctx, time.Time{}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Target != "/audit/exec-sessions/"+session.ID {
+		t.Fatalf("audit entries = %+v, want one targeting session %s", entries, session.ID)
+	}
+
+	got, err := sessions.Get(ctx, session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ExitCode == nil || *got.ExitCode != 3 || got.EndedAt == nil || !got.Recorded || got.Truncated {
+		t.Errorf("session = %+v, want ended with exit code 3 and a full transcript", got)
+	}
+	transcript, err := sessions.Transcript(ctx, session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(transcript, []byte(`"i","exit 3\n"]`)) || !bytes.Contains(transcript, []byte(`"o","exit 3\n"]`)) {
+		t.Errorf("transcript lacks input or output events:\n%s", transcript)
+	}
+
+	// Sessions past retention are pruned with their transcripts
+	sessions.retention = -time.Minute
+	if err := sessions.Prune(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sessions.Get(ctx, session.ID); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Get after prune = %v, want ErrSessionNotFound", err)
+	}
+}
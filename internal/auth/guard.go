@@ -0,0 +1,189 @@
+package auth
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"mcloud/pkg/reason"
+)
+
+var ErrTooManyAttempts = reason.New(reason.RateLimited, "too many attempts")
+
+// AttemptGuard limits how fast a client may guess a secret, e.g. on the
+// re-join endpoint that accepts callers without a client certificate. Each
+// client address gets at most rate attempts a minute, and is locked out for
+// lockout after maxFailures failures in a row. Failures are forgotten once a
+// client has been idle for lockout.
+type AttemptGuard struct {
+	rate        int
+	maxFailures int
+	lockout     time.Duration
+	now         func() time.Time
+
+	mu        sync.Mutex
+	clients   map[string]*clientAttempts // by client address
+	lastPrune time.Time
+}
+
+type clientAttempts struct {
+	window      time.Time // start of the current one-minute window
+	count       int       // attempts in the window
+	failures    int       // failures in a row
+	lastSeen    time.Time
+	lockedUntil time.Time
+}
+
+const (
+	// pruneAbove is the number of tracked clients above which idle ones are
+	// forgotten, at most once a minute.
+	pruneAbove = 1024
+
+	// maxClients bounds the tracked clients: when a scan from many addresses
+	// fills the map with active ones, those not locked out are forgotten.
+	maxClients = 64 * 1024
+)
+
+func NewAttemptGuard(rate, maxFailures int, lockout time.Duration) *AttemptGuard {
+	return &AttemptGuard{
+		rate:        rate,
+		maxFailures: maxFailures,
+		lockout:     lockout,
+		now:         time.Now,
+		clients:     map[string]*clientAttempts{},
+	}
+}
+
+// Allow counts an attempt by client. It returns ErrTooManyAttempts and how
+// long to wait when the client is locked out or over its rate.
+//
+// Example Output (Error):
+//   6th failure in a row  =>  (15m0s, ErrTooManyAttempts: locked out after 5 failed attempts)
+func (g *AttemptGuard) Allow(client string) (time.Duration, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := g.now()
+	if (len(g.clients) > pruneAbove && now.Sub(g.lastPrune) >= time.Minute) || len(g.clients) >= maxClients {
+		g.prune(now)
+	}
+
+	a := g.client(client, now)
+	if now.Before(a.lockedUntil) {
+		return a.lockedUntil.Sub(now), fmt.Errorf("%w: locked out after %d failed attempts", ErrTooManyAttempts, g.maxFailures)
+	}
+	if now.Sub(a.window) >= time.Minute {
+		a.window, a.count = now, 0
+	}
+	if a.count >= g.rate {
+		return a.window.Add(time.Minute).Sub(now), fmt.Errorf("%w: at most %d a minute", ErrTooManyAttempts, g.rate)
+	}
+	a.count++
+	return 0, nil
+}
+
+// Fail records a failed attempt by client and reports whether it is now
+// locked out.
+func (g *AttemptGuard) Fail(client string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	a := g.client(client, g.now())
+	a.failures++
+	if a.failures < g.maxFailures {
+		return false
+	}
+	a.failures, a.lockedUntil = 0, g.now().Add(g.lockout)
+	return true
+}
+
+// Succeed resets the failures of client.
+func (g *AttemptGuard) Succeed(client string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if a := g.clients[client]; a != nil {
+		a.failures = 0
+	}
+}
+
+// client returns the attempts of client, seen at now.
+func (g *AttemptGuard) client(client string, now time.Time) *clientAttempts {
+	a := g.clients[client]
+	if a == nil {
+		a = &clientAttempts{window: now}
+		g.clients[client] = a
+	}
+	a.lastSeen = now
+	return a
+}
+
+// prune forgets the clients that are not locked out and were idle for the
+// lockout, or, if that leaves maxClients, every client not locked out.
+func (g *AttemptGuard) prune(now time.Time) {
+	g.lastPrune = now
+	for client, a := range g.clients {
+		if !now.Before(a.lockedUntil) && now.Sub(a.lastSeen) >= g.lockout {
+			delete(g.clients, client)
+		}
+	}
+	if len(g.clients) < maxClients {
+		return
+	}
+	for client, a := range g.clients {
+		if !now.Before(a.lockedUntil) {
+			delete(g.clients, client)
+		}
+	}
+}
+
+// ClientAddr returns the IP address a request came from, the key of
+// AttemptGuard. mcloudd is reached directly, so no proxy header is trusted.
+func ClientAddr(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// NonceCache remembers the nonces of signed requests for as long as their
+// timestamp is accepted, so a captured request cannot be replayed. Callers
+// check the signature first, so only signed nonces are remembered.
+type NonceCache struct {
+	window time.Duration // how far a request's timestamp may be from now
+	now    func() time.Time
+
+	mu        sync.Mutex
+	seen      map[string]time.Time // nonce -> when it may be forgotten
+	nextPrune time.Time
+}
+
+func NewNonceCache(window time.Duration) *NonceCache {
+	return &NonceCache{window: window, now: time.Now, seen: map[string]time.Time{}}
+}
+
+// Use reports whether a request with nonce and timestamp ts is fresh: ts is
+// within the window of now and the nonce was not used before. The nonce is
+// then remembered.
+func (c *NonceCache) Use(nonce string, ts time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	if nonce == "" || ts.Before(now.Add(-c.window)) || ts.After(now.Add(c.window)) {
+		return false
+	}
+	// Expired nonces are dropped once per window rather than on every use
+	if !now.Before(c.nextPrune) {
+		for n, expires := range c.seen {
+			if now.After(expires) {
+				delete(c.seen, n)
+			}
+		}
+		c.nextPrune = now.Add(c.window)
+	}
+	if _, ok := c.seen[nonce]; ok {
+		return false
+	}
+	c.seen[nonce] = ts.Add(c.window)
+	return true
+}
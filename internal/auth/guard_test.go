@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestAttemptGuard(t *testing.T) {
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	g := NewAttemptGuard(3, 2, 15*time.Minute)
+	g.now = func() time.Time { return now }
+
+	// Rate: three attempts a minute
+	for i := 0; i < 3; i++ {
+		if _, err := g.Allow("10.0.0.1"); err != nil {
+			t.Fatalf("attempt %d: %v", i+1, err)
+		}
+	}
+	if wait, err := g.Allow("10.0.0.1"); !errors.Is(err, ErrTooManyAttempts) || wait != time.Minute {
+		t.Errorf("4th attempt: got %s, %v; want 1m0s, ErrTooManyAttempts", wait, err)
+	}
+	if _, err := g.Allow("10.0.0.2"); err != nil {
+		t.Errorf("other client: %v", err)
+	}
+
+	// Lockout: two failures in a row; a success in between resets them
+	now = now.Add(time.Minute)
+	g.Fail("10.0.0.1")
+	g.Succeed("10.0.0.1")
+	if g.Fail("10.0.0.1") {
+		t.Error("locked out after a success reset the failures")
+	}
+	if !g.Fail("10.0.0.1") {
+		t.Error("not locked out after 2 failures in a row")
+	}
+	if wait, err := g.Allow("10.0.0.1"); !errors.Is(err, ErrTooManyAttempts) || wait != 15*time.Minute {
+		t.Errorf("locked out: got %s, %v", wait, err)
+	}
+	now = now.Add(15 * time.Minute)
+	if _, err := g.Allow("10.0.0.1"); err != nil {
+		t.Errorf("after the lockout: %v", err)
+	}
+}
+
+func TestNonceCacheAndRejoinMAC(t *testing.T) {
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	c := NewNonceCache(5 * time.Minute)
+	c.now = func() time.Time { return now }
+
+	if !c.Use("n1", now.Add(-time.Minute)) {
+		t.Error("fresh nonce rejected")
+	}
+	if c.Use("n1", now) {
+		t.Error("replayed nonce accepted")
+	}
+	if c.Use("n2", now.Add(-10*time.Minute)) || c.Use("n3", now.Add(10*time.Minute)) {
+		t.Error("timestamp outside the window accepted")
+	}
+
+	mac := RejoinMAC("s3cret", "node-1", "n1", now.Unix())
+	if !VerifyRejoinMAC("s3cret", "node-1", "n1", now.Unix(), mac) {
+		t.Error("valid MAC rejected")
+	}
+	if VerifyRejoinMAC("s3cret", "node-2", "n1", now.Unix(), mac) || VerifyRejoinMAC("guess", "node-1", "n1", now.Unix(), mac) {
+		t.Error("MAC accepted for another node or secret")
+	}
+	// The stored hash does not sign requests
+	if VerifyRejoinMAC(HashRejoinSecret("s3cret"), "node-1", "n1", now.Unix(), mac) {
+		t.Error("MAC accepted with the hash of the secret")
+	}
+
+	// Expired nonces are forgotten
+	now = now.Add(11 * time.Minute)
+	c.Use("n4", now)
+	if len(c.seen) != 1 {
+		t.Errorf("%d nonces remembered, want 1", len(c.seen))
+	}
+}
+
+func TestAttemptGuardPrune(t *testing.T) {
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	g := NewAttemptGuard(3, 2, 15*time.Minute)
+	g.now = func() time.Time { return now }
+
+	// A scan from many addresses, each failing once
+	for i := 0; i <= pruneAbove; i++ {
+		client := fmt.Sprintf("10.0.%d.%d", i/256, i%256)
+		g.Allow(client)
+		g.Fail(client)
+	}
+	g.Fail("10.1.0.1")
+	g.Fail("10.1.0.1") // locked out
+	if len(g.clients) != pruneAbove+2 {
+		t.Fatalf("%d clients tracked, want %d", len(g.clients), pruneAbove+2)
+	}
+
+	// Once idle for the lockout, clients with failures are forgotten too
+	now = now.Add(15 * time.Minute)
+	g.Fail("10.1.0.1")
+	g.Fail("10.1.0.1")
+	now = now.Add(time.Minute)
+	g.Allow("10.2.0.1")
+	if len(g.clients) != 2 {
+		t.Errorf("%d clients tracked after pruning, want the locked out and the new one", len(g.clients))
+	}
+}
@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"net/http"
+
+	"mcloud/internal/constant"
+	"mcloud/pkg/reason"
+)
+
+// RequireClientCert wraps an HTTP handler so that mutating requests must present
+// a client certificate signed by the cluster CA.
+// Read-only requests (GET, HEAD, OPTIONS) are let through without a certificate.
+//
+// The TLS listener is expected to run with tls.VerifyClientCertIfGiven, so any
+// certificate that reaches this point has already been verified against the CA;
+// the middleware only needs to check that one was presented.
+func RequireClientCert(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			reason.HTTPErrorf(w, http.StatusUnauthorized, "client certificate required")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ClientIdentity returns the common name of the verified client certificate, or "" if none.
+func ClientIdentity(r *http.Request) string {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+		return ""
+	}
+	return r.TLS.VerifiedChains[0][0].Subject.CommonName
+}
+
+// RequireAdmin writes an error and returns false unless the request carries a
+// verified client certificate of the cluster admin (constant.AdminClientCommonName,
+// the certificate mcloudctl gets at init). Handlers of read-only routes that
+// expose sensitive data call it themselves, since RequireClientCert lets GET
+// requests through without a certificate.
+//
+// Example:
+//   if !auth.RequireAdmin(w, r) {
+//       return
+//   }
+func RequireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	switch identity := ClientIdentity(r); identity {
+	case constant.AdminClientCommonName:
+		return true
+	case "":
+		reason.HTTPError(w, reason.New(reason.Unauthenticated, "client certificate required"), http.StatusUnauthorized)
+	default:
+		reason.HTTPError(w, reason.New(reason.Forbidden, identity+" is not the cluster admin"), http.StatusForbidden)
+	}
+	return false
+}
@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"mcloud/internal/constant"
+)
+
+func TestRequireAdmin(t *testing.T) {
+	tests := []struct {
+		name   string
+		cn     string // "" = no client certificate
+		status int
+	}{
+		{"admin", constant.AdminClientCommonName, http.StatusOK},
+		{"node certificate", "node2", http.StatusForbidden},
+		{"no certificate", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/audit/exec-sessions", nil)
+			if tt.cn != "" {
+				leaf := &x509.Certificate{Subject: pkix.Name{CommonName: tt.cn}}
+				r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{leaf}}}
+			}
+			w := httptest.NewRecorder()
+			if ok := RequireAdmin(w, r); ok != (tt.status == http.StatusOK) {
+				t.Errorf("RequireAdmin = %v", ok)
+			}
+			if w.Code != tt.status {
+				t.Errorf("status %d, want %d", w.Code, tt.status)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// testManager starts a TLS server whose certificate (for 127.0.0.1) is signed
+// by a new CA, presented after it like mcloudd does. It returns the server and
+// the CA's fingerprint.
+func testManager(t *testing.T) (*httptest.Server, string) {
+	t.Helper()
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "MCloud Root CA"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, _ := x509.ParseCertificate(caDER)
+
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "mcloudd"},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewUnstartedServer(http.NotFoundHandler())
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der, caDER}, PrivateKey: key}}}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	sum := sha256.Sum256(caDER)
+	return srv, hex.EncodeToString(sum[:])
+}
+
+func TestFetchPinnedCA(t *testing.T) {
+	srv, fp := testManager(t)
+	ctx := context.Background()
+
+	caPEM, err := FetchPinnedCA(ctx, srv.URL, fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := CAFingerprint(caPEM); err != nil || got != fp {
+		t.Fatalf("fingerprint of the returned CA: got %s (%v), want %s", got, err, fp)
+	}
+	if block, _ := pem.Decode(caPEM); block == nil {
+		t.Fatal("returned CA is not PEM")
+	}
+
+	// A manager of another cluster
+	_, other := testManager(t)
+	if _, err := FetchPinnedCA(ctx, srv.URL, other); !errors.Is(err, ErrCAPinMismatch) {
+		t.Fatalf("other fingerprint: got %v, want ErrCAPinMismatch", err)
+	}
+}
@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+// GenerateRejoinSecret returns a random secret a node re-joins the cluster with.
+func GenerateRejoinSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashRejoinSecret returns the hex SHA-256 of a re-join secret, which the
+// manager stores to tell which secret a credential holds. The secret is
+// random, so a plain hash is enough.
+func HashRejoinSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// RejoinMAC signs a re-join request: the hex HMAC-SHA256 of the node ID, a
+// nonce and the request's Unix time, keyed with the node's secret. The secret
+// itself never travels; the manager keeps it sealed with its secrets key, so
+// the hash in the database is not enough to sign a request.
+//
+// Example Output:
+//   RejoinMAC("q3Xk2...", "660e8400-...", "Hq1...", 1792141200)  =>  "5b0c..."
+func RejoinMAC(secret, nodeID, nonce string, ts int64) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%s\n%s\n%d", nodeID, nonce, ts)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyRejoinMAC reports whether mac signs the request (see RejoinMAC), in
+// constant time.
+func VerifyRejoinMAC(secret, nodeID, nonce string, ts int64, mac string) bool {
+	return hmac.Equal([]byte(mac), []byte(RejoinMAC(secret, nodeID, nonce, ts)))
+}
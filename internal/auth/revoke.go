@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"context"
+	"crypto/x509"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"mcloud/internal/database"
+	"mcloud/pkg/reason"
+)
+
+var ErrCertRevoked = reason.New(reason.CertRevoked, "client certificate has been revoked")
+
+// RejectRevoked wraps an HTTP handler so that any request presenting a revoked
+// client certificate is refused, read-only ones included (see CheckRevoked).
+func RejectRevoked(db *sql.DB, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		err := CheckRevoked(r.Context(), db, r.TLS.VerifiedChains[0][0])
+		switch {
+		case errors.Is(err, ErrCertRevoked):
+			reason.HTTPError(w, err, http.StatusUnauthorized)
+			return
+		case err != nil:
+			reason.HTTPError(w, err, 500)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// CheckRevoked returns ErrCertRevoked if a verified client certificate has
+// been revoked. Certificates are revoked by common name when a node is
+// removed; those issued afterwards (NotBefore after the revocation) are
+// accepted again. A single certificate is revoked by its serial number
+// (POST /certs/revoke).
+//
+// Example Output (Error):
+//   serial 3a9f... revoked  =>  ErrCertRevoked: serial 3a9f...
+func CheckRevoked(ctx context.Context, db *sql.DB, leaf *x509.Certificate) error {
+	revoked := database.NewRevokedCertificateRepository(db)
+
+	serial := fmt.Sprintf("%x", leaf.SerialNumber)
+	_, err := revoked.GetSerial(ctx, serial)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		return err
+	default:
+		return fmt.Errorf("%w: serial %s", ErrCertRevoked, serial)
+	}
+
+	c, err := revoked.Get(ctx, leaf.Subject.CommonName)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		return err
+	case leaf.NotBefore.Before(c.RevokedAt):
+		return fmt.Errorf("%w: %s", ErrCertRevoked, leaf.Subject.CommonName)
+	}
+	return nil
+}
@@ -2,8 +2,23 @@ package auth
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
 	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"net/url"
+	"time"
+
+	"mcloud/pkg/reason"
+)
+
+var (
+	ErrJoinTokenInvalid = reason.New(reason.TokenInvalid, "join token is invalid")
+	ErrJoinTokenExpired = reason.New(reason.TokenExpired, "join token has expired")
 )
 
 // GenerateJoinToken generates a secure bootstrap token for joining nodes
@@ -21,3 +36,87 @@ func GenerateJoinToken(clusterID string) string {
 	// Format: mcloud-<clusterID-prefix>-<random>
 	return fmt.Sprintf("mcloud-%s-%s", clusterID[:8], tokenRandom[:16])
 }
+
+// JoinToken is everything a node needs to join a cluster, handed to it as one
+// string (see Encode): where the manager is, which CA the cluster's
+// certificates must chain to, and the bootstrap token.
+//
+// Example JSON (before encoding):
+//   {"address": "https://192.168.1.10:9028",
+//    "ca_fingerprint": "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+//    "token": "mcloud-550e8400-q2Xh3kD9aLm0pZrT", "expires_at": "2026-01-03T10:30:45Z"}
+type JoinToken struct {
+	Address       string    `json:"address"`        // mcloudd URL
+	CAFingerprint string    `json:"ca_fingerprint"` // hex SHA-256 of the CA certificate (DER), see CAFingerprint
+	Token         string    `json:"token"`          // bootstrap token (bootstrap_tokens.token)
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+// Encode returns the join string: the token as URL-safe base64 JSON, which
+// survives copy-paste and shell quoting.
+func (t *JoinToken) Encode() string {
+	b, _ := json.Marshal(t)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// ParseJoinToken decodes and validates a join string made by Encode. It does
+// not check expiry; see Expired.
+//
+// Example Output (Error):
+//   "abc"                        =>  ErrJoinTokenInvalid: not base64-encoded JSON
+//   Address "http://10.0.0.1"    =>  ErrJoinTokenInvalid: address must be an https URL
+func ParseJoinToken(s string) (*JoinToken, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: not base64-encoded JSON", ErrJoinTokenInvalid)
+	}
+	var t JoinToken
+	if err := json.Unmarshal(b, &t); err != nil {
+		return nil, fmt.Errorf("%w: not base64-encoded JSON", ErrJoinTokenInvalid)
+	}
+
+	u, err := url.Parse(t.Address)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("%w: address must be an https URL, got %q", ErrJoinTokenInvalid, t.Address)
+	}
+	if fp, err := hex.DecodeString(t.CAFingerprint); err != nil || len(fp) != sha256.Size {
+		return nil, fmt.Errorf("%w: CA fingerprint must be a hex SHA-256", ErrJoinTokenInvalid)
+	}
+	if t.Token == "" {
+		return nil, fmt.Errorf("%w: token is empty", ErrJoinTokenInvalid)
+	}
+	return &t, nil
+}
+
+// Expired reports whether the token has expired at now. A token without an
+// expiry never does.
+func (t *JoinToken) Expired(now time.Time) bool {
+	return !t.ExpiresAt.IsZero() && now.After(t.ExpiresAt)
+}
+
+// VerifyCA checks that caPEM is the CA certificate the token was issued for,
+// i.e. that the node is set up for the same cluster.
+func (t *JoinToken) VerifyCA(caPEM []byte) error {
+	fp, err := CAFingerprint(caPEM)
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare([]byte(fp), []byte(t.CAFingerprint)) != 1 {
+		return fmt.Errorf("%w: CA certificate fingerprint %s does not match the token's %s", ErrJoinTokenInvalid, fp, t.CAFingerprint)
+	}
+	return nil
+}
+
+// CAFingerprint returns the hex SHA-256 of a PEM certificate's DER bytes, the
+// same value as openssl x509 -noout -fingerprint -sha256 without the colons.
+func CAFingerprint(certPEM []byte) (string, error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return "", fmt.Errorf("no PEM certificate found")
+	}
+	if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(block.Bytes)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func testCA(t *testing.T, name string) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestJoinToken(t *testing.T) {
+	ca := testCA(t, "cluster A")
+	fp, err := CAFingerprint(ca)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := &JoinToken{
+		Address:       "https://192.168.1.10:9028",
+		CAFingerprint: fp,
+		Token:         GenerateJoinToken("550e8400-e29b-41d4-a716-446655440000"),
+		ExpiresAt:     time.Date(2026, 1, 3, 10, 30, 45, 0, time.UTC),
+	}
+	out, err := ParseJoinToken(in.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *out != *in {
+		t.Fatalf("round trip: got %+v, want %+v", out, in)
+	}
+
+	if err := out.VerifyCA(ca); err != nil {
+		t.Fatalf("own CA: %v", err)
+	}
+	if err := out.VerifyCA(testCA(t, "cluster B")); !errors.Is(err, ErrJoinTokenInvalid) {
+		t.Fatalf("other CA: got %v, want ErrJoinTokenInvalid", err)
+	}
+
+	if out.Expired(in.ExpiresAt.Add(-time.Minute)) || !out.Expired(in.ExpiresAt.Add(time.Minute)) {
+		t.Fatal("expiry not honoured")
+	}
+}
+
+func TestParseJoinTokenInvalid(t *testing.T) {
+	fp, err := CAFingerprint(testCA(t, "cluster A"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := JoinToken{Address: "https://10.0.0.1:9028", CAFingerprint: fp, Token: "mcloud-550e8400-abc"}
+
+	cases := map[string]string{
+		"not base64":  "not a join string!",
+		"not JSON":    "bm90IGpzb24",
+		"http":        (&JoinToken{Address: "http://10.0.0.1:9028", CAFingerprint: fp, Token: valid.Token}).Encode(),
+		"no host":     (&JoinToken{Address: "https://", CAFingerprint: fp, Token: valid.Token}).Encode(),
+		"short hash":  (&JoinToken{Address: valid.Address, CAFingerprint: fp[:32], Token: valid.Token}).Encode(),
+		"empty token": (&JoinToken{Address: valid.Address, CAFingerprint: fp}).Encode(),
+	}
+	for name, s := range cases {
+		if _, err := ParseJoinToken(s); !errors.Is(err, ErrJoinTokenInvalid) {
+			t.Errorf("%s: got %v, want ErrJoinTokenInvalid", name, err)
+		}
+	}
+	if _, err := ParseJoinToken(valid.Encode()); err != nil {
+		t.Errorf("valid: %v", err)
+	}
+}
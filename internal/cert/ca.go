@@ -6,8 +6,10 @@ import (
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/pem"
+	"fmt"
 	"math/big"
 	"os"
+	"strings"
 	"time"
 
 	"mcloud/internal/constant"
@@ -18,8 +20,14 @@ import (
 // typ:  PEM block type (e.g., "CERTIFICATE", "RSA PRIVATE KEY")
 // bytes: DER-encoded bytes to encode as PEM
 func writePEM(path, typ string, bytes []byte) {
-	f, _ := os.Create(path) // create or truncate the file
+	// Private keys are readable by their owner only
+	mode := os.FileMode(0644)
+	if strings.HasSuffix(typ, "PRIVATE KEY") {
+		mode = 0600
+	}
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode) // create or truncate the file
 	defer f.Close()
+	f.Chmod(mode) // an existing file keeps its mode otherwise
 	pem.Encode(f, &pem.Block{Type: typ, Bytes: bytes}) // write PEM block
 }
 
@@ -169,3 +177,44 @@ func GenerateCAV2(certPath string, keyPath string) (*x509.Certificate, *rsa.Priv
 	// Note: The cert template is returned, not the parsed certificate
 	return cert, key, nil
 }
+
+// LoadCA reads a PEM-encoded CA certificate and RSA private key from disk
+// and parses them so they can be used to sign other certificates.
+//
+// Parameters:
+//   certPath - File path of the CA certificate PEM
+//   keyPath  - File path of the CA private key PEM
+//
+// Returns:
+//   - *x509.Certificate: Parsed CA certificate
+//   - *rsa.PrivateKey: Parsed CA private key
+//   - error: If either file is missing or cannot be decoded
+func LoadCA(certPath string, keyPath string) (*x509.Certificate, *rsa.PrivateKey, error) {
+	certBytes, err := ReadPEM(certPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	certBlock, _ := pem.Decode(certBytes)
+	if certBlock == nil {
+		return nil, nil, fmt.Errorf("invalid CA certificate PEM: %s", certPath)
+	}
+	caCert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyBytes, err := ReadPEM(keyPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyBlock, _ := pem.Decode(keyBytes)
+	if keyBlock == nil {
+		return nil, nil, fmt.Errorf("invalid CA key PEM: %s", keyPath)
+	}
+	caKey, err := x509.ParsePKCS1PrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return caCert, caKey, nil
+}
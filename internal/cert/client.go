@@ -0,0 +1,98 @@
+package cert
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"time"
+
+	"mcloud/internal/constant"
+)
+
+// GenerateClientCert generates a client certificate signed by the given CA and writes it to files.
+// Client certificates authenticate mcloudctl (and other callers) against the mTLS-protected REST API.
+//
+// Parameters:
+//   ca         - The CA certificate used to sign the client certificate
+//   caKey      - The CA's private key
+//   commonName - Identity of the client (e.g., "admin", a node hostname)
+//   certPath   - File path to write the client certificate PEM
+//   keyPath    - File path to write the client private key PEM
+//
+// Returns:
+//   error - If any error occurs during key generation, certificate creation, or file writing
+func GenerateClientCert(
+	ca *x509.Certificate,
+	caKey *rsa.PrivateKey,
+	commonName string,
+	certPath string,
+	keyPath string,
+) error {
+	return generateClientCert(ca, caKey, commonName, nil, certPath, keyPath)
+}
+
+// GenerateNodeCert generates a node's certificate: a client certificate whose
+// CN is the node's hostname, as GenerateClientCert, that also carries the node's
+// IPv4 and IPv6 addresses as IP SANs so peers can verify it by address.
+//
+// Example Input:
+//   hostname: "node1", ips: [192.168.1.10, fd00::10]
+//
+// Example Output:
+//   Subject: CN=node1, SAN: IP:192.168.1.10, IP:fd00::10
+func GenerateNodeCert(
+	ca *x509.Certificate,
+	caKey *rsa.PrivateKey,
+	hostname string,
+	ips []net.IP,
+	certPath string,
+	keyPath string,
+) error {
+	return generateClientCert(ca, caKey, hostname, ips, certPath, keyPath)
+}
+
+func generateClientCert(
+	ca *x509.Certificate,
+	caKey *rsa.PrivateKey,
+	commonName string,
+	ips []net.IP,
+	certPath string,
+	keyPath string,
+) error {
+	// Generate a new 2048-bit RSA private key for the client
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return err
+	}
+	// Generate a random serial number for the certificate
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+
+	// Create a certificate template for the client
+	cert := &x509.Certificate{
+		SerialNumber: serial, // unique serial number
+		Subject: pkix.Name{
+			Organization: []string{constant.OrganizationName},
+			CommonName:   commonName, // identity of the caller
+		},
+		NotBefore:   time.Now(),                           // valid from now
+		NotAfter:    time.Now().Add(365 * 24 * time.Hour), // valid for 1 year
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}, // for client authentication
+		IPAddresses: sanIPs(ips),
+	}
+
+	// Create the certificate, signed by the CA
+	der, err := x509.CreateCertificate(rand.Reader, cert, ca, &key.PublicKey, caKey)
+	if err != nil {
+		return err
+	}
+
+	// Write the certificate (with the CA when it is an intermediate) and private key to files in PEM format
+	writeCertChain(certPath, der, ca)
+	writePEM(keyPath, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key))
+
+	return nil
+}
@@ -0,0 +1,39 @@
+package cert
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"math/big"
+	"time"
+)
+
+// crlValidity is how long a CRL is current (its NextUpdate). mcloudd signs a
+// fresh one on each request, so clients should fetch it at least this often.
+const crlValidity = 24 * time.Hour
+
+// CreateCRL signs a certificate revocation list with a CA and returns it in
+// DER form.
+//
+// Parameters:
+//   ca      - The CA that issued the revoked certificates; it must have the CRL sign key usage
+//   caKey   - The CA's private key
+//   revoked - The revoked certificates: serial number and revocation time
+//   number  - The CRL number, which must grow with every CRL the CA issues
+//
+// Returns:
+//   - The CRL, DER encoded (application/pkix-crl)
+//   - error if signing fails
+//
+// Example Output:
+//   Issuer: CN=MCloud Cluster Intermediate CA, 2 entries, next update in 24h
+func CreateCRL(ca *x509.Certificate, caKey *rsa.PrivateKey, revoked []x509.RevocationListEntry, number *big.Int) ([]byte, error) {
+	now := time.Now()
+	template := &x509.RevocationList{
+		RevokedCertificateEntries: revoked,
+		Number:                    number,
+		ThisUpdate:                now,
+		NextUpdate:                now.Add(crlValidity),
+	}
+	return x509.CreateRevocationList(rand.Reader, template, ca, caKey)
+}
@@ -0,0 +1,98 @@
+package cert
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"time"
+
+	"mcloud/internal/constant"
+)
+
+// intermediateValidity is how long an intermediate CA is valid: half the root's
+// 10 years, so it can be rotated well before the root expires.
+const intermediateValidity = 5 * 365 * 24 * time.Hour
+
+// GenerateIntermediateCA generates the cluster intermediate CA, signed by the
+// root CA, and writes it to files. The intermediate issues the server, client
+// and node certificates, so it can be rotated without touching the root that
+// every node trusts. It may not sign further CAs.
+//
+// Parameters:
+//   root     - The root CA certificate
+//   rootKey  - The root CA's private key
+//   certPath - File path to write the intermediate certificate PEM
+//   keyPath  - File path to write the intermediate private key PEM
+//
+// Returns:
+//   - The parsed intermediate certificate, ready to sign (its Raw bytes are set,
+//     so certificates it issues bundle it, see writeCertChain)
+//   - Its private key
+//   - error if key generation or signing fails
+//
+// Example Output:
+//   Subject: CN=MCloud Cluster Intermediate CA, Issuer: CN=MCloud Cluster CA, valid 5 years
+func GenerateIntermediateCA(root *x509.Certificate, rootKey *rsa.PrivateKey, certPath string, keyPath string) (*x509.Certificate, *rsa.PrivateKey, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 4096)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			Organization: []string{constant.OrganizationName},
+			CommonName:   constant.IntermediateCACommonName,
+		},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(intermediateValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		MaxPathLenZero:        true, // signs only leaf certificates
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, root, &key.PublicKey, rootKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	writePEM(certPath, "CERTIFICATE", der)
+	writePEM(keyPath, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key))
+	return cert, key, nil
+}
+
+// isIntermediate reports whether ca is a parsed CA certificate issued by
+// another CA, rather than a self-signed root.
+func isIntermediate(ca *x509.Certificate) bool {
+	return len(ca.Raw) > 0 && !bytes.Equal(ca.RawIssuer, ca.RawSubject)
+}
+
+// writeCertChain writes a certificate signed by ca to path, followed by ca when
+// it is an intermediate, so peers that only trust the root can verify it.
+func writeCertChain(path string, der []byte, ca *x509.Certificate) {
+	if !isIntermediate(ca) {
+		writePEM(path, "CERTIFICATE", der)
+		return
+	}
+	f, _ := os.Create(path)
+	defer f.Close()
+	pem.Encode(f, &pem.Block{Type: "CERTIFICATE", Bytes: der})
+	pem.Encode(f, &pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw})
+}
+
+// EncodeCertPEM returns the PEM of a parsed certificate, e.g. to store a CA in
+// the database.
+func EncodeCertPEM(c *x509.Certificate) string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.Raw}))
+}
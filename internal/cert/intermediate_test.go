@@ -0,0 +1,61 @@
+package cert
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIntermediateChain(t *testing.T) {
+	dir := t.TempDir()
+	path := func(name string) string { return filepath.Join(dir, name) }
+	root, rootKey, err := GenerateCAV2(path("ca.crt"), path("ca.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootPEM, _ := os.ReadFile(path("ca.crt"))
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(rootPEM)
+
+	// A node certificate of the first intermediate must still verify after the
+	// intermediate is rotated, with only the root trusted
+	for _, name := range []string{"first", "second"} {
+		inter, interKey, err := GenerateIntermediateCA(root, rootKey, path(name+"-inter.crt"), path(name+"-inter.key"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := GenerateClientCert(inter, interKey, "node1", path(name+".crt"), path(name+".key")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"first", "second"} {
+		pair, err := tls.LoadX509KeyPair(path(name+".crt"), path(name+".key"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pair.Certificate) != 2 {
+			t.Fatalf("%s: %d certificates in the file, want the leaf and the intermediate", name, len(pair.Certificate))
+		}
+		leaf, _ := x509.ParseCertificate(pair.Certificate[0])
+		inter, _ := x509.ParseCertificate(pair.Certificate[1])
+		intermediates := x509.NewCertPool()
+		intermediates.AddCert(inter)
+		_, err = leaf.Verify(x509.VerifyOptions{Roots: roots, Intermediates: intermediates, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}})
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+
+	// The CA keys are readable by their owner only
+	for _, name := range []string{"ca.key", "first-inter.key", "second-inter.key"} {
+		fi, err := os.Stat(path(name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Mode().Perm() != 0600 {
+			t.Errorf("%s: mode %v, want 0600", name, fi.Mode().Perm())
+		}
+	}
+}
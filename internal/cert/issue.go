@@ -0,0 +1,55 @@
+package cert
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"os"
+
+	"mcloud/internal/config"
+	"mcloud/pkg/utils"
+)
+
+// IssuingCA loads the CA that issues the cluster's certificates: the
+// intermediate when security.intermediate_cert_path is set, else the root.
+func IssuingCA(cfg *config.Config) (*x509.Certificate, *rsa.PrivateKey, error) {
+	if cfg.Security.IntermediateCertPath != "" {
+		return LoadCA(cfg.Security.IntermediateCertPath, cfg.Security.IntermediateKeyPath)
+	}
+	return LoadCA(cfg.Security.CACertPath, cfg.Security.CAKeyPath)
+}
+
+// IssueServerCert issues this node's server certificate to
+// security.server_cert_path, for its DNS names (see ServerNames), the advertise
+// address and every IPv4 and IPv6 address of the host.
+func IssueServerCert(cfg *config.Config, ca *x509.Certificate, caKey *rsa.PrivateKey) error {
+	ips := append([]net.IP{AdvertiseIP(cfg)}, utils.GetAllIPs()...)
+	return GenerateServerCert(ca, caKey, ServerNames(cfg), ips, cfg.Security.ServerCertPath, cfg.Security.ServerKeyPath)
+}
+
+// IssueNodeCert issues this node's certificate (CN = hostname), presented by
+// mcloud-agent, to security.node_cert_path.
+func IssueNodeCert(cfg *config.Config, ca *x509.Certificate, caKey *rsa.PrivateKey) error {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return err
+	}
+	ips := append([]net.IP{AdvertiseIP(cfg)}, utils.GetAllIPs()...)
+	return GenerateNodeCert(ca, caKey, hostname, ips, cfg.Security.NodeCertPath, cfg.Security.NodeKeyPath)
+}
+
+// ParseCertFile parses the first certificate of a PEM file: the leaf of a
+// chain written by writeCertChain.
+func ParseCertFile(path string) (*x509.Certificate, error) {
+	data, err := ReadPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("invalid certificate PEM: %s", path)
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
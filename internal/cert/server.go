@@ -7,17 +7,24 @@ import (
 	"crypto/x509/pkix"
 	"math/big"
 	"net"
+	"os"
+	"slices"
+	"strings"
 	"time"
 
+	"mcloud/internal/config"
 	"mcloud/internal/constant"
+	"mcloud/pkg/utils"
 )
 
 // GenerateServerCert generates a server certificate signed by the given CA and writes it to files.
+// Clients may connect by any of the DNS names or addresses, so all are SANs.
 //
 // Parameters:
 //   ca      - The CA certificate used to sign the server certificate
 //   caKey   - The CA's private key
-//   addr    - The server's IP address (used as the certificate's IP SAN)
+//   names   - DNS names of the server: its hostname and FQDNs (the certificate's DNS SANs)
+//   ips     - The server's IPv4 and IPv6 addresses (the certificate's IP SANs)
 //   certPath - File path to write the server certificate PEM
 //   keyPath  - File path to write the server private key PEM
 //
@@ -26,7 +33,8 @@ import (
 func GenerateServerCert(
 	ca *x509.Certificate,
 	caKey *rsa.PrivateKey,
-	addr string,
+	names []string,
+	ips []net.IP,
 	certPath string, 
 	keyPath string,
 ) error {
@@ -45,7 +53,8 @@ func GenerateServerCert(
 		NotAfter:    time.Now().Add(365 * 24 * time.Hour * 10), // valid for 10 years
 		KeyUsage:    x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment, // allowed usages
 		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}, // for server authentication
-		IPAddresses: []net.IP{net.ParseIP(addr)}, // set IP SAN
+		DNSNames:    sanNames(names), // set DNS SANs
+		IPAddresses: sanIPs(ips),     // set IP SANs
 	}
 
 	// Create the certificate, signed by the CA
@@ -54,9 +63,87 @@ func GenerateServerCert(
 		return err
 	}
 
-	// Write the certificate and private key to files in PEM format
-	writePEM(certPath, "CERTIFICATE", der)
+	// Write the certificate (with the CA when it is an intermediate) and private key to files in PEM format
+	writeCertChain(certPath, der, ca)
 	writePEM(keyPath, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key))
 
 	return nil
 }
+
+// sanIPs returns ips without nil or repeated entries, IPv4 addresses in their
+// 4-byte form, for the IP SANs of a certificate.
+func sanIPs(ips []net.IP) []net.IP {
+	var out []net.IP
+	for _, ip := range ips {
+		if ip == nil {
+			continue
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			ip = ip4
+		}
+		if !slices.ContainsFunc(out, ip.Equal) {
+			out = append(out, ip)
+		}
+	}
+	return out
+}
+
+// sanNames returns names lower-cased, without empty or repeated entries.
+func sanNames(names []string) []string {
+	var out []string
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSuffix(name, "."))
+		if name != "" && !slices.Contains(out, name) {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// ServerNames returns the DNS names the server certificate must carry: the
+// host's name and the FQDNs of security.server_names.
+//
+// Example Output:
+//   ServerNames(cfg) => ["node1", "mcloud.example.com"]
+func ServerNames(cfg *config.Config) []string {
+	hostname, _ := os.Hostname()
+	return sanNames(append([]string{hostname}, cfg.Security.ServerNames...))
+}
+
+// AdvertiseIP returns the address mcloudd is reached on: manager.advertise_address,
+// else the address of manager.advertise_interface, else manager.http_host. It is
+// nil when none of them is an IP.
+func AdvertiseIP(cfg *config.Config) net.IP {
+	if ip := net.ParseIP(cfg.Manager.AdvertiseAddress); ip != nil {
+		return ip
+	}
+	if cfg.Manager.AdvertiseInterface != "" {
+		if ip, err := utils.InterfaceIP(cfg.Manager.AdvertiseInterface); err == nil {
+			return ip
+		}
+	}
+	return net.ParseIP(cfg.Manager.HttpHost)
+}
+
+// CoversSANs reports whether the certificate at certPath lists every one of
+// names and ips as a SAN, so clients using any of them pass verification.
+//
+// Example Output:
+//   certificate for [node1, 10.0.0.5], ips [10.0.0.6]  =>  (false, nil)
+func CoversSANs(certPath string, names []string, ips []net.IP) (bool, error) {
+	c, err := ParseCertFile(certPath)
+	if err != nil {
+		return false, err
+	}
+	for _, name := range sanNames(names) {
+		if !slices.Contains(c.DNSNames, name) {
+			return false, nil
+		}
+	}
+	for _, ip := range sanIPs(ips) {
+		if !slices.ContainsFunc(c.IPAddresses, ip.Equal) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
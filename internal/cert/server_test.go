@@ -0,0 +1,35 @@
+package cert
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+)
+
+func TestServerCertSANs(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey, err := GenerateCAV2(filepath.Join(dir, "ca.crt"), filepath.Join(dir, "ca.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPath := filepath.Join(dir, "server.crt")
+	ips := []net.IP{net.ParseIP("10.0.0.5"), net.ParseIP("fd00::5"), net.ParseIP("10.0.0.5"), nil}
+	if err := GenerateServerCert(ca, caKey, []string{"Node1", "mcloud.example.com.", ""}, ips, certPath, filepath.Join(dir, "server.key")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		names []string
+		ips   []net.IP
+		want  bool
+	}{
+		{[]string{"node1", "mcloud.example.com"}, []net.IP{net.ParseIP("10.0.0.5"), net.ParseIP("fd00::5")}, true},
+		{nil, []net.IP{net.ParseIP("10.0.0.6")}, false}, // the advertise address changed
+		{[]string{"api.example.com"}, nil, false},
+	} {
+		got, err := CoversSANs(certPath, tt.names, tt.ips)
+		if err != nil || got != tt.want {
+			t.Errorf("CoversSANs(%v, %v): got %v, %v, want %v", tt.names, tt.ips, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package certificate
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"mcloud/pkg/reason"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(s *Service) *Handler {
+	return &Handler{service: s}
+}
+
+// ListCertificates handles GET /certs: the cluster's CAs and the server, admin
+// client and node certificates of this node, with their expiry.
+func (h *Handler) ListCertificates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	certs, err := h.service.List(r.Context())
+	if err != nil {
+		reason.HTTPError(w, err, 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(certs)
+}
+
+// GetCertificate handles GET /certs/{id}: one certificate with its SANs and
+// PEM. The id is server, client, node or the ID of a CA.
+func (h *Handler) GetCertificate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	c, err := h.service.Get(r.Context(), r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, ErrCertNotFound) {
+			reason.HTTPError(w, err, 404)
+			return
+		}
+		reason.HTTPError(w, err, 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c)
+}
+
+// RenewCertificate handles POST /certs/{id}/renew: re-issues the server,
+// admin client or node certificate of this node from the issuing CA. CAs
+// cannot be renewed (409).
+func (h *Handler) RenewCertificate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	c, err := h.service.Renew(r.Context(), r.PathValue("id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrCertNotFound):
+			reason.HTTPError(w, err, 404)
+		case errors.Is(err, ErrCertNotRenewable):
+			reason.HTTPError(w, err, 409)
+		default:
+			reason.HTTPError(w, err, 500)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c)
+}
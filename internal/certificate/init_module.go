@@ -0,0 +1,12 @@
+package certificate
+
+import (
+	"net/http"
+)
+
+// InitModule registers the certificate routes served by handler.
+func InitModule(mux *http.ServeMux, handler *Handler) {
+	mux.HandleFunc("GET /certs", handler.ListCertificates)
+	mux.HandleFunc("GET /certs/{id}", handler.GetCertificate)
+	mux.HandleFunc("POST /certs/{id}/renew", handler.RenewCertificate)
+}
@@ -0,0 +1,256 @@
+// Package certificate lists the certificates of the cluster with their expiry
+// and renews those mcloudd issues: the cluster CAs recorded in the database,
+// and the server, admin client and node certificates of the node mcloudd runs
+// on. Renewing re-issues a certificate from the issuing CA with the same
+// subject and SANs, so operators need not touch the files on disk.
+package certificate
+
+import (
+	"context"
+	"crypto/x509"
+	"database/sql"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"mcloud/internal/auth"
+	"mcloud/internal/cert"
+	"mcloud/internal/config"
+	"mcloud/internal/constant"
+	"mcloud/internal/database"
+	"mcloud/internal/event"
+	"mcloud/pkg/logger"
+	"mcloud/pkg/reason"
+)
+
+var log = logger.Named("certificate")
+
+var (
+	ErrCertNotFound     = reason.New(reason.CertNotFound, "certificate not found")
+	ErrCertNotRenewable = reason.New(reason.CertNotRenewable, "certificate cannot be renewed by mcloudd")
+)
+
+// IDs of the certificates of the node mcloudd runs on; CAs are identified by
+// their database ID.
+const (
+	IDServer = "server"
+	IDClient = "client"
+	IDNode   = "node"
+)
+
+// Statuses of a certificate.
+const (
+	StatusValid    = "valid"
+	StatusExpiring = "expiring" // expires within expiringWithin
+	StatusExpired  = "expired"
+	StatusRetired  = "retired" // an intermediate CA replaced by 'mcloudctl ca rotate'
+	StatusRevoked  = "revoked"
+)
+
+// expiringWithin is how close to its expiry a certificate is reported as
+// expiring.
+const expiringWithin = 30 * 24 * time.Hour
+
+// Certificate describes one certificate. PEM is only set by Get.
+//
+// Example JSON:
+//   {"id": "server", "kind": "server", "subject": "mcloud-server", "issuer": "MCloud Cluster Intermediate CA", "serial": "3a9f...", "not_before": "2026-01-02T10:30:45Z", "not_after": "2036-01-02T10:30:45Z", "status": "valid", "dns_names": ["node1"], "ip_addresses": ["10.0.0.11"], "path": "/etc/mcloud/certs/server.crt"}
+type Certificate struct {
+	ID          string    `json:"id"`
+	Kind        string    `json:"kind"` // root, intermediate, server, client, node
+	Subject     string    `json:"subject"`
+	Issuer      string    `json:"issuer"`
+	Serial      string    `json:"serial"`
+	NotBefore   time.Time `json:"not_before"`
+	NotAfter    time.Time `json:"not_after"`
+	Status      string    `json:"status"`
+	DNSNames    []string  `json:"dns_names,omitempty"`
+	IPAddresses []string  `json:"ip_addresses,omitempty"`
+	Path        string    `json:"path,omitempty"` // file of a certificate of this node
+	PEM         string    `json:"pem,omitempty"`
+}
+
+type Service struct {
+	db  *sql.DB
+	cfg *config.Config
+}
+
+func NewService(db *sql.DB, cfg *config.Config) *Service {
+	return &Service{db: db, cfg: cfg}
+}
+
+// List returns the cluster's CAs, retired intermediates included, then the
+// server, admin client and node certificates of this node. Certificates of
+// this node whose file is missing are left out.
+//
+// Example Output:
+//   [{ID: "5f1c...", Kind: "root", Status: "valid"}, ..., {ID: "node", Kind: "node", Status: "expiring"}]
+func (s *Service) List(ctx context.Context) ([]Certificate, error) {
+	certs, err := s.all(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range certs {
+		certs[i].PEM = ""
+	}
+	return certs, nil
+}
+
+// Get returns one certificate, with its PEM.
+//
+// Example Output (Error):
+//   "nope"  =>  ErrCertNotFound: nope
+func (s *Service) Get(ctx context.Context, id string) (*Certificate, error) {
+	certs, err := s.all(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range certs {
+		if certs[i].ID == id {
+			return &certs[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrCertNotFound, id)
+}
+
+// Renew re-issues a certificate of this node from the issuing CA, with the
+// same subject and the SANs mcloudd would give it now. mcloudd serves a
+// renewed server certificate after a restart. CAs are not renewed: the
+// intermediate is replaced with 'mcloudctl ca rotate'.
+//
+// Example Output (Error):
+//   "5f1c..." (the root CA)  =>  ErrCertNotRenewable: root CA 5f1c...: rotate the intermediate with mcloudctl ca rotate
+func (s *Service) Renew(ctx context.Context, id string) (*Certificate, error) {
+	switch id {
+	case IDServer, IDClient, IDNode:
+	default:
+		c, err := s.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %s CA %s: rotate the intermediate with mcloudctl ca rotate", ErrCertNotRenewable, c.Kind, id)
+	}
+
+	ca, caKey, err := cert.IssuingCA(s.cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%w: no CA key on this node: %v", ErrCertNotRenewable, err)
+	}
+	sec := s.cfg.Security
+	switch id {
+	case IDServer:
+		err = cert.IssueServerCert(s.cfg, ca, caKey)
+	case IDClient:
+		err = cert.GenerateClientCert(ca, caKey, constant.AdminClientCommonName, sec.ClientCertPath, sec.ClientKeyPath)
+	case IDNode:
+		err = cert.IssueNodeCert(s.cfg, ca, caKey)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	e := event.CertRenewedEvent(c.ID, c.Serial, c.NotAfter)
+	if clusters, err := database.NewClusterRepository(s.db).List(ctx); err == nil && len(clusters) > 0 {
+		e.ClusterID = &clusters[0].ID
+	}
+	if err := database.NewEventRepository(s.db).Create(ctx, e); err != nil {
+		log.Error("Failed to record event %s: %v", e.Type, err)
+	}
+	return c, nil
+}
+
+// all returns every certificate List shows, with its PEM.
+func (s *Service) all(ctx context.Context) ([]Certificate, error) {
+	var certs []Certificate
+
+	clusters, err := database.NewClusterRepository(s.db).List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	caRepo := database.NewCertificateAuthorityRepository(s.db)
+	for _, cl := range clusters {
+		cas, err := caRepo.ListByCluster(ctx, cl.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, ca := range cas {
+			block, _ := pem.Decode([]byte(ca.CertPEM))
+			if block == nil {
+				return nil, fmt.Errorf("CA %s: invalid certificate PEM", ca.ID)
+			}
+			parsed, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("CA %s: %w", ca.ID, err)
+			}
+			c := newCertificate(ca.ID, ca.Kind, parsed)
+			c.PEM = ca.CertPEM
+			if ca.RetiredAt != nil {
+				c.Status = StatusRetired
+			}
+			certs = append(certs, c)
+		}
+	}
+
+	sec := s.cfg.Security
+	for _, local := range []struct{ id, path string }{
+		{IDServer, sec.ServerCertPath},
+		{IDClient, sec.ClientCertPath},
+		{IDNode, sec.NodeCertPath},
+	} {
+		if local.path == "" {
+			continue
+		}
+		parsed, err := cert.ParseCertFile(local.path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		c := newCertificate(local.id, local.id, parsed)
+		c.Path = local.path
+		c.PEM = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: parsed.Raw}))
+		switch err := auth.CheckRevoked(ctx, s.db, parsed); {
+		case errors.Is(err, auth.ErrCertRevoked):
+			c.Status = StatusRevoked
+		case err != nil:
+			return nil, err
+		}
+		certs = append(certs, c)
+	}
+	return certs, nil
+}
+
+func newCertificate(id, kind string, c *x509.Certificate) Certificate {
+	out := Certificate{
+		ID:        id,
+		Kind:      kind,
+		Subject:   c.Subject.CommonName,
+		Issuer:    c.Issuer.CommonName,
+		Serial:    fmt.Sprintf("%x", c.SerialNumber),
+		NotBefore: c.NotBefore.UTC(),
+		NotAfter:  c.NotAfter.UTC(),
+		Status:    status(c.NotAfter, time.Now()),
+		DNSNames:  c.DNSNames,
+	}
+	for _, ip := range c.IPAddresses {
+		out.IPAddresses = append(out.IPAddresses, ip.String())
+	}
+	return out
+}
+
+// status is the status of a certificate by its expiry alone.
+func status(notAfter, now time.Time) string {
+	switch {
+	case now.After(notAfter):
+		return StatusExpired
+	case notAfter.Sub(now) < expiringWithin:
+		return StatusExpiring
+	}
+	return StatusValid
+}
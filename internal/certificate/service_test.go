@@ -0,0 +1,99 @@
+package certificate
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"mcloud/internal/cert"
+	"mcloud/internal/config"
+	"mcloud/internal/database"
+)
+
+func TestListAndRenew(t *testing.T) {
+	dir := t.TempDir()
+	path := func(name string) string { return filepath.Join(dir, name) }
+	db, err := database.Connect(path("mcloud.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close(db)
+	ctx := context.Background()
+
+	cfg := &config.Config{}
+	cfg.Security.CACertPath, cfg.Security.CAKeyPath = path("ca.crt"), path("ca.key")
+	cfg.Security.ServerCertPath, cfg.Security.ServerKeyPath = path("server.crt"), path("server.key")
+	cfg.Security.ClientCertPath, cfg.Security.ClientKeyPath = path("client.crt"), path("client.key")
+	cfg.Security.NodeCertPath, cfg.Security.NodeKeyPath = path("node.crt"), path("node.key")
+	root, rootKey, err := cert.GenerateCAV2(cfg.Security.CACertPath, cfg.Security.CAKeyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cert.IssueServerCert(cfg, root, rootKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.NewClusterRepository(db).Create(ctx, &database.Cluster{ID: "c1", Name: "test", State: "active"}); err != nil {
+		t.Fatal(err)
+	}
+	rootPEM, err := cert.ReadPEM(cfg.Security.CACertPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = database.NewCertificateAuthorityRepository(db).Create(ctx, &database.CertificateAuthority{ID: "ca1", ClusterID: "c1", Kind: database.CAKindRoot, CertPEM: string(rootPEM)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewService(db, cfg)
+
+	// The client and node certificates were not issued, so are left out
+	certs, err := s.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(certs) != 2 || certs[0].ID != "ca1" || certs[0].Kind != "root" || certs[1].ID != IDServer {
+		t.Fatalf("list: got %+v", certs)
+	}
+	if certs[1].Status != StatusValid || certs[1].PEM != "" || certs[1].Path != cfg.Security.ServerCertPath {
+		t.Errorf("server: got %+v", certs[1])
+	}
+
+	old, err := s.Get(ctx, IDServer)
+	if err != nil || old.PEM == "" {
+		t.Fatalf("get server: got %+v, %v", old, err)
+	}
+	renewed, err := s.Renew(ctx, IDServer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renewed.Serial == old.Serial {
+		t.Errorf("renew server: serial %s unchanged", renewed.Serial)
+	}
+	if _, err := s.Renew(ctx, IDNode); err != nil {
+		t.Errorf("renew node: %v", err)
+	}
+
+	if _, err := s.Renew(ctx, "ca1"); !errors.Is(err, ErrCertNotRenewable) {
+		t.Errorf("renew CA: got %v, want ErrCertNotRenewable", err)
+	}
+	if _, err := s.Get(ctx, "nope"); !errors.Is(err, ErrCertNotFound) {
+		t.Errorf("get nope: got %v, want ErrCertNotFound", err)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		notAfter time.Time
+		want     string
+	}{
+		{now.AddDate(1, 0, 0), StatusValid},
+		{now.AddDate(0, 0, 10), StatusExpiring},
+		{now.Add(-time.Hour), StatusExpired},
+	} {
+		if got := status(tt.notAfter, now); got != tt.want {
+			t.Errorf("status(%s): got %s, want %s", tt.notAfter, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package cluster
+
+import (
+	"context"
+
+	"mcloud/services/lxd"
+	"mcloud/services/microceph"
+	"mcloud/services/microovn"
+)
+
+// Cepher is the MicroCeph cluster as the cluster service drives it.
+type Cepher interface {
+	// Bootstrap initializes MicroCeph on the first node and adds its disks.
+	Bootstrap(ctx context.Context, cfg microceph.BootstrapConfig) error
+	// Join makes this node join MicroCeph with the token from a member and adds its disks.
+	Join(ctx context.Context, cfg microceph.JoinConfig) error
+	// Status summarizes the Ceph cluster health.
+	Status(ctx context.Context) (*microceph.ClusterStatus, error)
+	// Bootstrapped reports whether MicroCeph is already initialized on this node.
+	Bootstrapped(ctx context.Context) bool
+	// OSDs lists the OSDs of the cluster.
+	OSDs(ctx context.Context) ([]microceph.OSD, error)
+	// RemoveMember removes a node from MicroCeph, returning the IDs of the OSDs removed with it.
+	RemoveMember(ctx context.Context, member string, force bool) ([]int, error)
+}
+
+// OVNer is the MicroOVN cluster as the cluster service drives it.
+type OVNer interface {
+	// Bootstrap initializes MicroOVN on the first node.
+	Bootstrap(ctx context.Context) error
+	// Join makes this node join MicroOVN with the token from a member.
+	Join(ctx context.Context, token string) (string, error)
+	// Status fails when this node is not a member or its daemon is down.
+	Status(ctx context.Context) (string, error)
+	// RemoveMember removes a node from MicroOVN.
+	RemoveMember(ctx context.Context, member string, force bool) error
+}
+
+// LXDer is the LXD cluster as the cluster service drives it.
+type LXDer interface {
+	// Bootstrap initializes the LXD cluster on the first node. It does nothing
+	// when LXD is already clustered.
+	Bootstrap(ctx context.Context, cfg lxd.BootstrapConfig) error
+	// ClusterMembers lists the members of the LXD cluster.
+	ClusterMembers(ctx context.Context) ([]lxd.ClusterMember, error)
+	// Clustered reports whether clustering is enabled on the local LXD server.
+	Clustered(ctx context.Context) (bool, error)
+	// RemoveMember removes a member from the LXD cluster.
+	RemoveMember(ctx context.Context, member string, force bool) error
+}
+
+// Components are the clustered services under mcloud.
+type Components struct {
+	Ceph Cepher
+	OVN  OVNer
+	LXD  LXDer
+}
+
+// ShellComponents returns the Components driving the snaps installed on this
+// host through their CLIs (see services/lxd, microceph and microovn).
+func ShellComponents() Components {
+	return Components{Ceph: ShellCeph{}, OVN: ShellOVN{}, LXD: ShellLXD{}}
+}
+
+// ShellCeph is the Cepher running the microceph CLI.
+type ShellCeph struct{}
+
+func (ShellCeph) Bootstrap(ctx context.Context, cfg microceph.BootstrapConfig) error {
+	return microceph.Bootstrap(ctx, cfg)
+}
+
+func (ShellCeph) Join(ctx context.Context, cfg microceph.JoinConfig) error {
+	return microceph.Join(ctx, cfg)
+}
+
+func (ShellCeph) Status(ctx context.Context) (*microceph.ClusterStatus, error) {
+	return microceph.Status()
+}
+
+func (ShellCeph) Bootstrapped(ctx context.Context) bool {
+	return microceph.Bootstrapped(ctx)
+}
+
+func (ShellCeph) OSDs(ctx context.Context) ([]microceph.OSD, error) {
+	return microceph.OSDs(ctx)
+}
+
+func (ShellCeph) RemoveMember(ctx context.Context, member string, force bool) ([]int, error) {
+	return microceph.RemoveMember(ctx, member, force)
+}
+
+// ShellOVN is the OVNer running the microovn CLI.
+type ShellOVN struct{}
+
+func (ShellOVN) Bootstrap(ctx context.Context) error {
+	return microovn.Bootstrap(ctx)
+}
+
+func (ShellOVN) Join(ctx context.Context, token string) (string, error) {
+	return microovn.Join(ctx, token)
+}
+
+func (ShellOVN) Status(ctx context.Context) (string, error) {
+	return microovn.Status(ctx)
+}
+
+func (ShellOVN) RemoveMember(ctx context.Context, member string, force bool) error {
+	return microovn.RemoveMember(ctx, member, force)
+}
+
+// ShellLXD is the LXDer running the lxc and lxd CLIs.
+type ShellLXD struct{}
+
+func (ShellLXD) Bootstrap(ctx context.Context, cfg lxd.BootstrapConfig) error {
+	return lxd.Bootstrap(ctx, cfg)
+}
+
+func (ShellLXD) ClusterMembers(ctx context.Context) ([]lxd.ClusterMember, error) {
+	return lxd.ClusterMembers(ctx)
+}
+
+func (ShellLXD) Clustered(ctx context.Context) (bool, error) {
+	return lxd.Clustered(ctx)
+}
+
+func (ShellLXD) RemoveMember(ctx context.Context, member string, force bool) error {
+	return lxd.RemoveMember(ctx, member, force)
+}
+
+// BootstrapSteps are the steps of the BootstrapPipeline that cluster LXD, OVN
+// and Ceph on the first node, in that order. None is undone on rollback: a
+// re-run finds the component bootstrapped and keeps it.
+func (c Components) BootstrapSteps(name, address string, disks microceph.DiskConfig) []Step {
+	return []Step{
+		{
+			Name: "lxd",
+			Execute: func(ctx context.Context) error {
+				return c.LXD.Bootstrap(ctx, lxd.BootstrapConfig{ClusterName: name, Address: address})
+			},
+		},
+		{
+			Name:    "ovn",
+			Execute: func(ctx context.Context) error { return c.OVN.Bootstrap(ctx) },
+		},
+		{
+			Name: "ceph",
+			Execute: func(ctx context.Context) error {
+				return c.Ceph.Bootstrap(ctx, microceph.BootstrapConfig{Disks: disks})
+			},
+		},
+	}
+}
@@ -0,0 +1,133 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"mcloud/services/lxd"
+	"mcloud/services/microceph"
+)
+
+// DryRunComponents returns Components that write the commands and configs c
+// would run to w instead of running them, for `--dry-run`. Queries (Status,
+// Bootstrapped, Clustered, ClusterMembers, OSDs) still go to c, so what is
+// already bootstrapped is reported as skipped.
+func DryRunComponents(w io.Writer, c Components) Components {
+	return Components{Ceph: dryRunCeph{Cepher: c.Ceph, w: w}, OVN: dryRunOVN{OVNer: c.OVN, w: w}, LXD: dryRunLXD{LXDer: c.LXD, w: w}}
+}
+
+// printCommand writes a command as it would be typed in a shell.
+func printCommand(w io.Writer, name string, args ...string) {
+	fmt.Fprintf(w, "  $ %s\n", strings.Join(append([]string{name}, args...), " "))
+}
+
+type dryRunCeph struct {
+	Cepher
+	w io.Writer
+}
+
+func (c dryRunCeph) Bootstrap(ctx context.Context, cfg microceph.BootstrapConfig) error {
+	if c.Cepher.Bootstrapped(ctx) {
+		fmt.Fprintln(c.w, "  MicroCeph is already bootstrapped; microceph init is skipped")
+	} else {
+		printCommand(c.w, "microceph", "init")
+	}
+	return c.addDisks(cfg.Disks)
+}
+
+func (c dryRunCeph) Join(ctx context.Context, cfg microceph.JoinConfig) error {
+	printCommand(c.w, "microceph", "join", "<ceph-token>")
+	return c.addDisks(cfg.Disks)
+}
+
+func (c dryRunCeph) addDisks(disks microceph.DiskConfig) error {
+	args, err := microceph.DiskAddArgs(disks)
+	if err != nil {
+		return err
+	}
+	printCommand(c.w, "microceph", args...)
+	return nil
+}
+
+func (c dryRunCeph) RemoveMember(ctx context.Context, member string, force bool) ([]int, error) {
+	osds, err := c.Cepher.OSDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var removed []int
+	for _, osd := range osds {
+		if osd.Location != member {
+			continue
+		}
+		args := []string{"disk", "remove", fmt.Sprintf("osd.%d", osd.ID)}
+		if force {
+			args = append(args, "--bypass-safety-checks")
+		}
+		printCommand(c.w, "microceph", args...)
+		removed = append(removed, osd.ID)
+	}
+	args := []string{"cluster", "remove", member}
+	if force {
+		args = append(args, "--force")
+	}
+	printCommand(c.w, "microceph", args...)
+	return removed, nil
+}
+
+type dryRunOVN struct {
+	OVNer
+	w io.Writer
+}
+
+func (o dryRunOVN) Bootstrap(ctx context.Context) error {
+	// Status fails until this node is a member
+	if _, err := o.OVNer.Status(ctx); err == nil {
+		fmt.Fprintln(o.w, "  MicroOVN is already bootstrapped; microovn init is skipped")
+		return nil
+	}
+	printCommand(o.w, "microovn", "init")
+	return nil
+}
+
+func (o dryRunOVN) Join(ctx context.Context, token string) (string, error) {
+	printCommand(o.w, "microovn", "join", "<ovn-token>")
+	return "", nil
+}
+
+func (o dryRunOVN) RemoveMember(ctx context.Context, member string, force bool) error {
+	args := []string{"cluster", "remove", member}
+	if force {
+		args = append(args, "--force")
+	}
+	printCommand(o.w, "microovn", args...)
+	return nil
+}
+
+type dryRunLXD struct {
+	LXDer
+	w io.Writer
+}
+
+func (l dryRunLXD) Bootstrap(ctx context.Context, cfg lxd.BootstrapConfig) error {
+	if clustered, err := l.LXDer.Clustered(ctx); err == nil && clustered {
+		fmt.Fprintln(l.w, "  LXD is already clustered; lxd init is skipped")
+		return nil
+	}
+	preseed, err := lxd.InitPreseed(cfg)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(l.w, "  $ lxd init --preseed <<'EOF'\n%sEOF\n", preseed)
+	return nil
+}
+
+func (l dryRunLXD) RemoveMember(ctx context.Context, member string, force bool) error {
+	args := []string{"cluster", "remove", member}
+	if force {
+		args = append(args, "--force", "--yes")
+	}
+	printCommand(l.w, "lxc", args...)
+	return nil
+}
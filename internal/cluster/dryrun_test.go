@@ -0,0 +1,98 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"mcloud/services/microceph"
+)
+
+func TestDryRunBootstrapSteps(t *testing.T) {
+	c, ceph, ovn, l := MockComponents()
+	ovn.StatusFunc = func() (string, error) { return "", errors.New("not a member") }
+
+	var out strings.Builder
+	disks := microceph.DiskConfig{Paths: []string{"/dev/sdb", "/dev/sdc"}, Wipe: true}
+	for _, s := range DryRunComponents(&out, c).BootstrapSteps("prod", "10.0.0.5", disks) {
+		if err := s.Execute(context.Background()); err != nil {
+			t.Fatalf("%s: %v", s.Name, err)
+		}
+	}
+
+	for _, want := range []string{
+		"$ lxd init --preseed <<'EOF'\n",
+		"  core.https_address: 10.0.0.5:8443\n",
+		"  server_name: prod\n",
+		"$ microovn init\n",
+		"$ microceph init\n",
+		"$ microceph disk add /dev/sdb /dev/sdc --wipe\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("dry run output lacks %q:\n%s", want, out.String())
+		}
+	}
+	// Nothing is run, only queried
+	for _, calls := range [][]string{ceph.Calls(), ovn.Calls(), l.Calls()} {
+		for _, call := range calls {
+			if strings.HasPrefix(call, "Bootstrap ") || call == "Bootstrap" {
+				t.Errorf("dry run called %s", call)
+			}
+		}
+	}
+}
+
+func TestDryRunBootstrapped(t *testing.T) {
+	c, ceph, _, l := MockComponents()
+	ceph.BootstrappedFunc = func() bool { return true }
+	l.ClusteredFunc = func() (bool, error) { return true, nil }
+
+	var out strings.Builder
+	for _, s := range DryRunComponents(&out, c).BootstrapSteps("prod", "10.0.0.5", microceph.DiskConfig{}) {
+		if err := s.Execute(context.Background()); err != nil {
+			t.Fatalf("%s: %v", s.Name, err)
+		}
+	}
+	for _, want := range []string{
+		"LXD is already clustered; lxd init is skipped\n",
+		"MicroOVN is already bootstrapped; microovn init is skipped\n",
+		"MicroCeph is already bootstrapped; microceph init is skipped\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("dry run output lacks %q:\n%s", want, out.String())
+		}
+	}
+	for _, skipped := range []string{"lxd init --preseed", "$ microovn init", "$ microceph init"} {
+		if strings.Contains(out.String(), skipped) {
+			t.Errorf("dry run output has %q:\n%s", skipped, out.String())
+		}
+	}
+}
+
+func TestDryRunRemoveMember(t *testing.T) {
+	c, ceph, _, _ := MockComponents()
+	ceph.OSDsFunc = func() ([]microceph.OSD, error) {
+		return []microceph.OSD{{ID: 1, Location: "node1"}, {ID: 2, Location: "node2"}, {ID: 3, Location: "node2"}}, nil
+	}
+
+	var out strings.Builder
+	removed, err := DryRunComponents(&out, c).Ceph.RemoveMember(context.Background(), "node2", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 2 || removed[0] != 2 || removed[1] != 3 {
+		t.Errorf("removed OSDs %v, want [2 3]", removed)
+	}
+	want := "  $ microceph disk remove osd.2 --bypass-safety-checks\n" +
+		"  $ microceph disk remove osd.3 --bypass-safety-checks\n" +
+		"  $ microceph cluster remove node2 --force\n"
+	if out.String() != want {
+		t.Errorf("dry run output:\n%s\nwant:\n%s", out.String(), want)
+	}
+	for _, call := range ceph.Calls() {
+		if strings.HasPrefix(call, "RemoveMember") {
+			t.Errorf("dry run called %s", call)
+		}
+	}
+}
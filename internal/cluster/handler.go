@@ -3,6 +3,8 @@ package cluster
 import (
 	"encoding/json"
 	"net/http"
+
+	"mcloud/pkg/reason"
 )
 
 type Handler struct {
@@ -25,13 +27,13 @@ func (h *Handler) InitCluster(w http.ResponseWriter, r *http.Request) {
 
 	var req InitRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), 400)
+		reason.HTTPError(w, err, 400)
 		return
 	}
 
 	// result, err := h.service.InitCluster(r.Context(), &req)
 	// if err != nil {
-	// 	http.Error(w, err.Error(), 409)
+	// 	reason.HTTPError(w, err, 409)
 	// 	return
 	// }
 
@@ -39,3 +41,38 @@ func (h *Handler) InitCluster(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(SuccessResponse{Success: true})
 
 }
+
+func (h *Handler) Status(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	result, err := h.service.Status(r.Context())
+	if err != nil {
+		reason.HTTPError(w, err, 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// State handles GET /cluster/state.yaml: the canonical manifest of every
+// user-defined resource (see State), for committing to git and for
+// `mcloudctl diff --against`.
+func (h *Handler) State(w http.ResponseWriter, r *http.Request) {
+	state, err := h.service.State(r.Context())
+	if err != nil {
+		reason.HTTPError(w, err, 500)
+		return
+	}
+	data, err := state.Encode()
+	if err != nil {
+		reason.HTTPError(w, err, 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Write(data)
+}
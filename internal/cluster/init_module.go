@@ -1,13 +1,12 @@
 package cluster
 
 import (
-	"database/sql"
 	"net/http"
 )
 
-func InitModule(mux *http.ServeMux, db *sql.DB) {
-	// Initialize services and handlers here
-	handler := NewHandler(NewService(db))
-
+// InitModule registers the cluster routes served by handler.
+func InitModule(mux *http.ServeMux, handler *Handler) {
 	mux.HandleFunc("/cluster/init", handler.InitCluster)
+	mux.HandleFunc("/cluster/status", handler.Status)
+	mux.HandleFunc("GET /cluster/state.yaml", handler.State)
 }
@@ -0,0 +1,179 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"mcloud/services/lxd"
+	"mcloud/services/microceph"
+)
+
+// MockComponents returns Components backed by mocks that succeed and record
+// their calls, for tests.
+func MockComponents() (Components, *MockCeph, *MockOVN, *MockLXD) {
+	ceph, ovn, l := &MockCeph{}, &MockOVN{}, &MockLXD{}
+	return Components{Ceph: ceph, OVN: ovn, LXD: l}, ceph, ovn, l
+}
+
+// mockCalls records the calls made to a mock, e.g. "RemoveMember node2".
+type mockCalls struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (m *mockCalls) record(format string, args ...any) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = append(m.calls, fmt.Sprintf(format, args...))
+}
+
+// Calls returns the calls made so far, oldest first.
+func (m *mockCalls) Calls() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.calls...)
+}
+
+// MockCeph is a Cepher for tests. A method whose func is unset succeeds
+// (Status reports HEALTH_OK; MicroCeph is not bootstrapped and has no OSDs).
+type MockCeph struct {
+	mockCalls
+	BootstrapFunc    func(cfg microceph.BootstrapConfig) error
+	JoinFunc         func(cfg microceph.JoinConfig) error
+	StatusFunc       func() (*microceph.ClusterStatus, error)
+	BootstrappedFunc func() bool
+	OSDsFunc         func() ([]microceph.OSD, error)
+	RemoveMemberFunc func(member string, force bool) ([]int, error)
+}
+
+func (m *MockCeph) Bootstrap(ctx context.Context, cfg microceph.BootstrapConfig) error {
+	m.record("Bootstrap %v", cfg.Disks.Paths)
+	if m.BootstrapFunc != nil {
+		return m.BootstrapFunc(cfg)
+	}
+	return nil
+}
+
+func (m *MockCeph) Join(ctx context.Context, cfg microceph.JoinConfig) error {
+	m.record("Join %v", cfg.Disks.Paths)
+	if m.JoinFunc != nil {
+		return m.JoinFunc(cfg)
+	}
+	return nil
+}
+
+func (m *MockCeph) Status(ctx context.Context) (*microceph.ClusterStatus, error) {
+	m.record("Status")
+	if m.StatusFunc != nil {
+		return m.StatusFunc()
+	}
+	return &microceph.ClusterStatus{Health: microceph.HealthOK}, nil
+}
+
+func (m *MockCeph) Bootstrapped(ctx context.Context) bool {
+	m.record("Bootstrapped")
+	if m.BootstrappedFunc != nil {
+		return m.BootstrappedFunc()
+	}
+	return false
+}
+
+func (m *MockCeph) OSDs(ctx context.Context) ([]microceph.OSD, error) {
+	m.record("OSDs")
+	if m.OSDsFunc != nil {
+		return m.OSDsFunc()
+	}
+	return nil, nil
+}
+
+func (m *MockCeph) RemoveMember(ctx context.Context, member string, force bool) ([]int, error) {
+	m.record("RemoveMember %s", member)
+	if m.RemoveMemberFunc != nil {
+		return m.RemoveMemberFunc(member, force)
+	}
+	return nil, nil
+}
+
+// MockOVN is an OVNer for tests. A method whose func is unset succeeds.
+type MockOVN struct {
+	mockCalls
+	BootstrapFunc    func() error
+	JoinFunc         func(token string) (string, error)
+	StatusFunc       func() (string, error)
+	RemoveMemberFunc func(member string, force bool) error
+}
+
+func (m *MockOVN) Bootstrap(ctx context.Context) error {
+	m.record("Bootstrap")
+	if m.BootstrapFunc != nil {
+		return m.BootstrapFunc()
+	}
+	return nil
+}
+
+func (m *MockOVN) Join(ctx context.Context, token string) (string, error) {
+	m.record("Join")
+	if m.JoinFunc != nil {
+		return m.JoinFunc(token)
+	}
+	return "", nil
+}
+
+func (m *MockOVN) Status(ctx context.Context) (string, error) {
+	m.record("Status")
+	if m.StatusFunc != nil {
+		return m.StatusFunc()
+	}
+	return "", nil
+}
+
+func (m *MockOVN) RemoveMember(ctx context.Context, member string, force bool) error {
+	m.record("RemoveMember %s", member)
+	if m.RemoveMemberFunc != nil {
+		return m.RemoveMemberFunc(member, force)
+	}
+	return nil
+}
+
+// MockLXD is an LXDer for tests. A method whose func is unset succeeds (LXD
+// is not clustered).
+type MockLXD struct {
+	mockCalls
+	BootstrapFunc      func(cfg lxd.BootstrapConfig) error
+	ClusterMembersFunc func() ([]lxd.ClusterMember, error)
+	ClusteredFunc      func() (bool, error)
+	RemoveMemberFunc   func(member string, force bool) error
+}
+
+func (m *MockLXD) Bootstrap(ctx context.Context, cfg lxd.BootstrapConfig) error {
+	m.record("Bootstrap %s %s", cfg.ClusterName, cfg.Address)
+	if m.BootstrapFunc != nil {
+		return m.BootstrapFunc(cfg)
+	}
+	return nil
+}
+
+func (m *MockLXD) ClusterMembers(ctx context.Context) ([]lxd.ClusterMember, error) {
+	m.record("ClusterMembers")
+	if m.ClusterMembersFunc != nil {
+		return m.ClusterMembersFunc()
+	}
+	return nil, nil
+}
+
+func (m *MockLXD) Clustered(ctx context.Context) (bool, error) {
+	m.record("Clustered")
+	if m.ClusteredFunc != nil {
+		return m.ClusteredFunc()
+	}
+	return false, nil
+}
+
+func (m *MockLXD) RemoveMember(ctx context.Context, member string, force bool) error {
+	m.record("RemoveMember %s", member)
+	if m.RemoveMemberFunc != nil {
+		return m.RemoveMemberFunc(member, force)
+	}
+	return nil
+}
@@ -0,0 +1,283 @@
+package cluster
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"mcloud/internal/database"
+)
+
+// Pipeline statuses, as stored in the kv_store.
+const (
+	PipelineRunning    = "running"
+	PipelinePartial    = "partial"     // a step failed; completed steps are kept so the run can be resumed
+	PipelineRolledBack = "rolled_back" // a step failed and every completed step was undone
+	PipelineCompleted  = "completed"
+)
+
+// Progress statuses reported for each step.
+const (
+	StepRunning    = "running"
+	StepDone       = "done"
+	StepSkipped    = "skipped" // already completed by an earlier run
+	StepFailed     = "failed"
+	StepRolledBack = "rolled_back"
+)
+
+// BootstrapPipeline is the name of the pipeline run by `mcloudctl init`.
+const BootstrapPipeline = "bootstrap"
+
+// Step is one unit of a Pipeline. Execute must be safe to re-run after a partial
+// failure. Rollback undoes Execute and is nil for steps that cannot be undone.
+type Step struct {
+	Name     string
+	Execute  func(ctx context.Context) error
+	Rollback func(ctx context.Context) error
+}
+
+// Progress is reported before and after every step.
+type Progress struct {
+	Step   string
+	Index  int // 1-based
+	Total  int
+	Status string
+	Err    error
+}
+
+// PipelineState is the persisted progress of a pipeline.
+type PipelineState struct {
+	Status     string            `json:"status"`
+	Completed  []string          `json:"completed"`
+	FailedStep string            `json:"failed_step,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	Data       map[string]string `json:"data,omitempty"` // caller values needed to resume, e.g. generated IDs
+	UpdatedAt  time.Time         `json:"updated_at"`
+}
+
+func (s *PipelineState) completed(step string) bool {
+	for _, name := range s.Completed {
+		if name == step {
+			return true
+		}
+	}
+	return false
+}
+
+// Pipeline runs steps in order and records each completed step in the kv_store,
+// so a failed run can either be resumed (skipping completed steps) or rolled back.
+type Pipeline struct {
+	db    *sql.DB
+	name  string
+	steps []Step
+
+	// RollbackOnFailure undoes the completed steps (newest first) when a step
+	// fails, instead of leaving them in place for a resume.
+	RollbackOnFailure bool
+
+	// OnProgress, if set, is called as steps start, finish, fail or are rolled back.
+	OnProgress func(Progress)
+}
+
+func NewPipeline(db *sql.DB, name string, steps ...Step) *Pipeline {
+	return &Pipeline{db: db, name: name, steps: steps}
+}
+
+func pipelineKey(name string) string {
+	return "pipeline." + name
+}
+
+// LoadPipelineState returns the stored state of the named pipeline, or nil if it never ran.
+func LoadPipelineState(ctx context.Context, db *sql.DB, name string) (*PipelineState, error) {
+	kv, err := database.NewKVStoreRepository(db).Get(ctx, pipelineKey(name))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var st PipelineState
+	if err := json.Unmarshal([]byte(kv.Value), &st); err != nil {
+		return nil, fmt.Errorf("invalid state for pipeline %s: %w", name, err)
+	}
+	return &st, nil
+}
+
+func (p *Pipeline) save(ctx context.Context, st *PipelineState) error {
+	st.UpdatedAt = time.Now().UTC()
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	return database.NewKVStoreRepository(p.db).Set(ctx, pipelineKey(p.name), string(data))
+}
+
+func (p *Pipeline) report(progress Progress) {
+	if p.OnProgress != nil {
+		p.OnProgress(progress)
+	}
+}
+
+// Run executes the steps, skipping those a previous partial run completed.
+// data is stored with the state so a resumed run can reuse it; nil keeps the stored data.
+//
+// Example Input:
+//   p := NewPipeline(db, "bootstrap", certs, database, lxd, ovn, ceph, service)
+//   p.Run(ctx, map[string]string{"cluster_id": "660e8400-..."})   // ceph fails
+//
+// Example Output:
+//   &PipelineState{Status: "partial", Completed: ["certs", "database", "lxd", "ovn"],
+//     FailedStep: "ceph", Error: "no available disks for Ceph; ..."}, error("step ceph failed: ...")
+//
+// Running it again after fixing the cause continues with "ceph".
+func (p *Pipeline) Run(ctx context.Context, data map[string]string) (*PipelineState, error) {
+	st, err := LoadPipelineState(ctx, p.db, p.name)
+	if err != nil {
+		return nil, err
+	}
+	if st == nil || st.Status == PipelineRolledBack || st.Status == PipelineCompleted {
+		st = &PipelineState{Completed: []string{}}
+	}
+	st.Status = PipelineRunning
+	st.FailedStep = ""
+	st.Error = ""
+	if data != nil {
+		st.Data = data
+	}
+	if err := p.save(ctx, st); err != nil {
+		return nil, err
+	}
+
+	total := len(p.steps)
+	for i, step := range p.steps {
+		progress := Progress{Step: step.Name, Index: i + 1, Total: total}
+
+		if st.completed(step.Name) {
+			progress.Status = StepSkipped
+			p.report(progress)
+			continue
+		}
+
+		progress.Status = StepRunning
+		p.report(progress)
+
+		if err := step.Execute(ctx); err != nil {
+			progress.Status = StepFailed
+			progress.Err = err
+			p.report(progress)
+			return p.fail(ctx, st, i, fmt.Errorf("step %s failed: %w", step.Name, err))
+		}
+
+		st.Completed = append(st.Completed, step.Name)
+		if err := p.save(ctx, st); err != nil {
+			return st, err
+		}
+		progress.Status = StepDone
+		p.report(progress)
+	}
+
+	st.Status = PipelineCompleted
+	return st, p.save(ctx, st)
+}
+
+// fail records the failure and, if configured, rolls back. The failed step is
+// rolled back too, as it may have been partly applied.
+func (p *Pipeline) fail(ctx context.Context, st *PipelineState, failed int, stepErr error) (*PipelineState, error) {
+	st.Status = PipelinePartial
+	st.FailedStep = p.steps[failed].Name
+	st.Error = stepErr.Error()
+
+	if p.RollbackOnFailure {
+		if p.rollback(ctx, st, failed) {
+			st.Status = PipelineRolledBack
+		}
+	}
+
+	if err := p.save(ctx, st); err != nil {
+		return st, errors.Join(stepErr, err)
+	}
+	return st, stepErr
+}
+
+// rollback undoes steps[0..failed] newest first and reports whether everything was undone.
+// Steps without a Rollback are left in place, so the state stays partial and resumable.
+func (p *Pipeline) rollback(ctx context.Context, st *PipelineState, failed int) bool {
+	clean := true
+	for i := failed; i >= 0; i-- {
+		step := p.steps[i]
+		if i != failed && !st.completed(step.Name) {
+			continue
+		}
+		progress := Progress{Step: step.Name, Index: i + 1, Total: len(p.steps)}
+
+		if step.Rollback == nil {
+			if i != failed {
+				clean = false
+			}
+			continue
+		}
+		if err := step.Rollback(ctx); err != nil {
+			clean = false
+			progress.Status = StepFailed
+			progress.Err = fmt.Errorf("rollback: %w", err)
+			p.report(progress)
+			continue
+		}
+
+		remaining := []string{}
+		for _, name := range st.Completed {
+			if name != step.Name {
+				remaining = append(remaining, name)
+			}
+		}
+		st.Completed = remaining
+		progress.Status = StepRolledBack
+		p.report(progress)
+	}
+	return clean
+}
+
+// Reset undoes the steps a partial run completed (newest first) and forgets the
+// run, so the next Run starts from the first step. Steps without a Rollback are
+// left in place; their Execute must cope with finding them done. A pipeline that
+// never ran, completed or was rolled back is left alone.
+//
+// Example Input:
+//   // stored: {Status: "partial", Completed: ["certs", "database", "lxd"], FailedStep: "ovn"}
+//   p.Reset(ctx)
+//
+// Example Output:
+//   Rolls back "database" and "certs", deletes the stored state; returns nil
+func (p *Pipeline) Reset(ctx context.Context) error {
+	st, err := LoadPipelineState(ctx, p.db, p.name)
+	if err != nil {
+		return err
+	}
+	if st == nil || (st.Status != PipelinePartial && st.Status != PipelineRunning) {
+		return nil
+	}
+
+	// The first step not completed failed, or was cut off in a run that died,
+	// and may have been partly applied
+	failed := 0
+	for failed < len(p.steps)-1 && st.completed(p.steps[failed].Name) {
+		failed++
+	}
+	p.rollback(ctx, st, failed)
+	for _, name := range st.Completed {
+		for _, step := range p.steps {
+			if step.Name == name && step.Rollback != nil {
+				// Keep the state so the reset can be retried after fixing the rollback
+				if err := p.save(ctx, st); err != nil {
+					return err
+				}
+				return fmt.Errorf("failed to roll back step %s", name)
+			}
+		}
+	}
+	return database.NewKVStoreRepository(p.db).Delete(ctx, pipelineKey(p.name))
+}
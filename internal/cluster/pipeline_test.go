@@ -0,0 +1,86 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"mcloud/internal/database"
+)
+
+// recorder builds steps that log their executions and rollbacks; the step named
+// by fail returns an error while fail is set.
+type recorder struct {
+	log  []string
+	fail string
+}
+
+func (r *recorder) step(name string, undoable bool) Step {
+	s := Step{
+		Name: name,
+		Execute: func(ctx context.Context) error {
+			r.log = append(r.log, name)
+			if name == r.fail {
+				return errors.New("boom")
+			}
+			return nil
+		},
+	}
+	if undoable {
+		s.Rollback = func(ctx context.Context) error {
+			r.log = append(r.log, "undo "+name)
+			return nil
+		}
+	}
+	return s
+}
+
+func TestPipelineResetAndResume(t *testing.T) {
+	ctx := context.Background()
+	db, err := database.Connect(filepath.Join(t.TempDir(), "mcloud.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close(db)
+
+	r := &recorder{fail: "ceph"}
+	p := NewPipeline(db, BootstrapPipeline, r.step("certs", true), r.step("lxd", false), r.step("ceph", true), r.step("state", true))
+
+	st, err := p.Run(ctx, map[string]string{"cluster_id": "c1"})
+	if err == nil || st.Status != PipelinePartial || st.FailedStep != "ceph" {
+		t.Fatalf("first run: got %+v, %v", st, err)
+	}
+
+	// Resuming skips the completed steps and keeps the stored data
+	r.log, r.fail = nil, ""
+	if st, err = p.Run(ctx, nil); err != nil || st.Status != PipelineCompleted || st.Data["cluster_id"] != "c1" {
+		t.Fatalf("resume: got %+v, %v", st, err)
+	}
+	if want := []string{"ceph", "state"}; !reflect.DeepEqual(r.log, want) {
+		t.Fatalf("resume ran %v, want %v", r.log, want)
+	}
+
+	// Reset leaves a completed pipeline alone
+	r.log = nil
+	if err := p.Reset(ctx); err != nil || len(r.log) != 0 {
+		t.Fatalf("reset completed: ran %v, %v", r.log, err)
+	}
+
+	// Reset undoes a partial run, including the failed step, and forgets it
+	r.fail = "state"
+	if _, err := p.Run(ctx, nil); err == nil {
+		t.Fatal("second run: want failure")
+	}
+	r.log = nil
+	if err := p.Reset(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"undo state", "undo ceph", "undo certs"}; !reflect.DeepEqual(r.log, want) {
+		t.Fatalf("reset ran %v, want %v", r.log, want)
+	}
+	if st, err := LoadPipelineState(ctx, db, BootstrapPipeline); err != nil || st != nil {
+		t.Fatalf("state after reset: got %+v, %v", st, err)
+	}
+}
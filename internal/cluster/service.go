@@ -1,20 +1,22 @@
 package cluster
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"strings"
 
 	"mcloud/internal/database"
+	"mcloud/internal/node"
+	"mcloud/internal/preflight"
 	"mcloud/pkg/commander"
+	"mcloud/services/microceph"
 	// "mcloud/services/lxd"
 )
 
-const (
-	Disk = "/dev/sdb" // disk used for microceph
-)
-
 type Service struct {
-	db        *sql.DB
+	db         *sql.DB
+	components Components
 	// lxdClient lxd.Client
 }
 
@@ -29,16 +31,41 @@ type InitResult struct {
 	Leader    *database.Node `json:"leader"`
 }
 
-func NewService(db *sql.DB) *Service {
+// StatusResult is the state of every cluster known to this manager, plus Ceph health.
+// Ceph is nil (and CephError set) when the Ceph status cannot be read.
+// Breakers are the circuit breakers of the commands this manager runs; those of
+// other nodes show up as node conditions.
+type StatusResult struct {
+	Clusters  []ClusterStatus           `json:"clusters"`
+	Ceph      *microceph.ClusterStatus  `json:"ceph"`
+	CephError string                    `json:"ceph_error,omitempty"`
+	Bootstrap *PipelineState            `json:"bootstrap,omitempty"` // progress of `mcloudctl init`
+	Breakers  []commander.BreakerStatus `json:"breakers"`
+}
+
+// ClusterStatus is a cluster with its nodes. Conditions maps a node ID to the
+// conditions set on it (e.g. "microceph_unavailable": reason); healthy nodes are left out.
+type ClusterStatus struct {
+	database.Cluster
+	Nodes      []database.Node              `json:"nodes"`
+	Conditions map[string]map[string]string `json:"conditions,omitempty"`
+}
+
+// NewService returns the cluster service driving LXD, MicroCeph and MicroOVN
+// through components, e.g. ShellComponents() or MockComponents() in tests.
+func NewService(db *sql.DB, components Components) *Service {
 	// Create LXD client
 	// lxdClient := lxd.NewClient()
 	return &Service{
-		db:        db,
+		db:         db,
+		components: components,
 		// lxdClient: lxdClient,
 	}
 }
 
-func validateInitRequest(req *InitRequest) error {
+// validateInitRequest checks an init request and runs the preflight checks for
+// the node's Ceph disks (see CephDisks).
+func validateInitRequest(ctx context.Context, req *InitRequest, disks microceph.DiskConfig) error {
 	// Basic validation
 	if req.Name == "" {
 		return errors.New("cluster name is required")
@@ -47,35 +74,13 @@ func validateInitRequest(req *InitRequest) error {
 		return errors.New("advertise address is required")
 	}
 
-	// check snap lxd, microceph, microovn installed
-	cmds := []string{
-		"lxd",
-		"lxc",
-		"microceph",
-		"microovn",
-	}
-	for _, c := range cmds {
-		if err := commander.CheckCommandExists(c); err != nil {
-			return err
-		}
-	}
-
-	// check port 8443 available
-	if err := commander.CheckPortAvailable(8443); err != nil {
-		return err
-	}
-
-	// check disk exists
-	if err := commander.CheckDiskExists(Disk); err != nil {
-		return err
-	}
-	
-	return nil
+	// Snaps, LXD port, disk, time sync and CPU/RAM
+	return preflight.Run(ctx, preflight.Checks(DiskPreflight(disks))).Err()
 }
 
 // func (s *Service) InitCluster(ctx context.Context, req *InitRequest) (*InitResult, error) {
 // 	// 1. Validate
-// 	if err := validateInitRequest(req); err != nil {
+// 	if err := validateInitRequest(ctx, req); err != nil {
 // 		return nil, err
 // 	}
 
@@ -282,4 +287,52 @@ func validateInitRequest(req *InitRequest) error {
 // 		ClusterID: clusterID,
 // 		Token:     token,
 // 	}, nil
-// }
\ No newline at end of file
+// }
+
+// Status returns the clusters with their nodes and the Ceph health summary.
+func (s *Service) Status(ctx context.Context) (*StatusResult, error) {
+	clusters, err := database.NewClusterRepository(s.db).List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &StatusResult{Clusters: []ClusterStatus{}}
+	for _, c := range clusters {
+		nodes, err := database.NewNodeRepository(s.db).ListByCluster(ctx, c.ID, database.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		if nodes == nil {
+			nodes = []database.Node{}
+		}
+		status := ClusterStatus{Cluster: c, Nodes: nodes, Conditions: map[string]map[string]string{}}
+		for _, n := range nodes {
+			attrs, err := database.NewNodeAttributeRepository(s.db).ListByNode(ctx, n.ID)
+			if err != nil {
+				return nil, err
+			}
+			for _, a := range attrs {
+				if name, ok := strings.CutPrefix(a.Key, node.ConditionPrefix); ok {
+					if status.Conditions[n.ID] == nil {
+						status.Conditions[n.ID] = map[string]string{}
+					}
+					status.Conditions[n.ID][name] = a.Value
+				}
+			}
+		}
+		result.Clusters = append(result.Clusters, status)
+	}
+	result.Breakers = commander.Breakers()
+
+	if result.Bootstrap, err = LoadPipelineState(ctx, s.db, BootstrapPipeline); err != nil {
+		return nil, err
+	}
+
+	ceph, err := s.components.Ceph.Status(ctx)
+	if err != nil {
+		result.CephError = err.Error()
+	} else {
+		result.Ceph = ceph
+	}
+	return result, nil
+}
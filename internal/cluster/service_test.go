@@ -0,0 +1,86 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"mcloud/internal/database"
+	"mcloud/services/microceph"
+)
+
+func TestStatus(t *testing.T) {
+	ctx := context.Background()
+	db, err := database.Connect(filepath.Join(t.TempDir(), "mcloud.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close(db)
+	if err := database.NewClusterRepository(db).Create(ctx, &database.Cluster{ID: "c1", Name: "prod", State: "active"}); err != nil {
+		t.Fatal(err)
+	}
+
+	components, ceph, _, _ := MockComponents()
+	ceph.StatusFunc = func() (*microceph.ClusterStatus, error) {
+		return &microceph.ClusterStatus{Health: microceph.HealthWarn, OSDCount: 3, OSDsUp: 2}, nil
+	}
+	svc := NewService(db, components)
+	st, err := svc.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(st.Clusters) != 1 || st.Clusters[0].Name != "prod" || len(st.Clusters[0].Nodes) != 0 {
+		t.Errorf("clusters = %+v", st.Clusters)
+	}
+	if st.Ceph == nil || st.Ceph.Health != microceph.HealthWarn || st.CephError != "" {
+		t.Errorf("ceph = %+v, %q", st.Ceph, st.CephError)
+	}
+
+	// An unreachable Ceph is reported, not fatal
+	ceph.StatusFunc = func() (*microceph.ClusterStatus, error) { return nil, errors.New("microceph is not running") }
+	if st, err = svc.Status(ctx); err != nil || st.Ceph != nil || st.CephError != "microceph is not running" {
+		t.Errorf("Status with Ceph down = %+v, %v", st, err)
+	}
+	if calls := ceph.Calls(); len(calls) != 2 {
+		t.Errorf("ceph calls = %v, want 2 Status", calls)
+	}
+}
+
+func TestBootstrapSteps(t *testing.T) {
+	ctx := context.Background()
+	db, err := database.Connect(filepath.Join(t.TempDir(), "mcloud.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close(db)
+
+	components, ceph, ovn, lxd := MockComponents()
+	ceph.BootstrapFunc = func(microceph.BootstrapConfig) error { return errors.New("no available disks") }
+	disks := microceph.DiskConfig{Paths: []string{"/dev/sdb"}}
+	p := NewPipeline(db, BootstrapPipeline, components.BootstrapSteps("prod", "10.0.0.1", disks)...)
+
+	st, err := p.Run(ctx, nil)
+	if err == nil || st.FailedStep != "ceph" {
+		t.Fatalf("Run = %+v, %v; want ceph to fail", st, err)
+	}
+	if got := lxd.Calls(); !slices.Equal(got, []string{"Bootstrap prod 10.0.0.1"}) {
+		t.Errorf("lxd calls = %v", got)
+	}
+	if got := ovn.Calls(); !slices.Equal(got, []string{"Bootstrap"}) {
+		t.Errorf("ovn calls = %v", got)
+	}
+
+	// Resuming retries only Ceph
+	ceph.BootstrapFunc = nil
+	if st, err = p.Run(ctx, nil); err != nil || st.Status != PipelineCompleted {
+		t.Fatalf("resume = %+v, %v", st, err)
+	}
+	if got := ceph.Calls(); !slices.Equal(got, []string{"Bootstrap [/dev/sdb]", "Bootstrap [/dev/sdb]"}) {
+		t.Errorf("ceph calls = %v", got)
+	}
+	if len(lxd.Calls()) != 1 || len(ovn.Calls()) != 1 {
+		t.Errorf("resume re-ran lxd %v or ovn %v", lxd.Calls(), ovn.Calls())
+	}
+}
@@ -0,0 +1,374 @@
+package cluster
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"mcloud/internal/database"
+
+	"gopkg.in/yaml.v3"
+)
+
+// StateKind is the kind of the manifest written by GET /cluster/state.yaml.
+const StateKind = "ClusterState"
+
+// State is the canonical manifest of every user-defined resource of the
+// clusters this manager knows: what an operator declared, never what the
+// cluster decided (IDs, status, placement, addresses and timestamps are left
+// out). Lists are sorted and maps are written in key order, so the same
+// resources always give the same bytes and the file can be kept in git.
+//
+// Secret values are never exported; only the names of the secrets that exist.
+//
+// Example YAML:
+//   kind: ClusterState
+//   config:
+//     - {namespace: log, key: level, value: debug}
+//   secrets:
+//     - db-password
+//   workloads:
+//     - cluster: prod
+//       name: web-1
+//       kind: container
+//       image: ubuntu:24.04
+//       priority: high
+//       env: {APP_ENV: production}
+//       secrets: {DB_PASSWORD: db-password}
+//       schedule: {stop_at: "01:00", start_at: "07:00", days: mon,tue,wed,thu,fri}
+//   dns_records:
+//     - {fqdn: web.example.com, type: A, value: 203.0.113.10}
+type State struct {
+	Kind       string           `yaml:"kind"`
+	Config     []StateConfig    `yaml:"config"`
+	Secrets    []string         `yaml:"secrets"`
+	Workloads  []StateWorkload  `yaml:"workloads"`
+	DNSRecords []StateDNSRecord `yaml:"dns_records"`
+}
+
+type StateConfig struct {
+	Namespace string `yaml:"namespace"`
+	Key       string `yaml:"key"`
+	Value     string `yaml:"value"`
+}
+
+type StateWorkload struct {
+	Cluster  string            `yaml:"cluster"`
+	Name     string            `yaml:"name"`
+	Kind     string            `yaml:"kind"`
+	Image    string            `yaml:"image"`
+	Priority string            `yaml:"priority"`
+	Env      map[string]string `yaml:"env,omitempty"`
+	Secrets  map[string]string `yaml:"secrets,omitempty"` // variable -> secret name
+	Hooks    []StateHook       `yaml:"hooks,omitempty"`
+	Schedule *StateSchedule    `yaml:"schedule,omitempty"`
+}
+
+type StateHook struct {
+	Event   string `yaml:"event"`
+	Webhook string `yaml:"webhook,omitempty"`
+	Command string `yaml:"command,omitempty"`
+}
+
+// StateSchedule is a power schedule without its runtime part (skips, last action).
+type StateSchedule struct {
+	StopAt  string `yaml:"stop_at,omitempty"`
+	StartAt string `yaml:"start_at,omitempty"`
+	Days    string `yaml:"days,omitempty"`
+}
+
+type StateDNSRecord struct {
+	FQDN  string `yaml:"fqdn"`
+	Type  string `yaml:"type"`
+	Value string `yaml:"value"`
+}
+
+// configKeyPrefix is where clusterconfig stores its keys in kv_store
+// ("config/<namespace>/<key>"); other kv_store entries are internal state.
+const configKeyPrefix = "config/"
+
+// State reads the manifest of the live clusters.
+func (s *Service) State(ctx context.Context) (*State, error) {
+	state := &State{Kind: StateKind}
+
+	kvs, err := database.NewKVStoreRepository(s.db).ListPrefix(ctx, configKeyPrefix)
+	if err != nil {
+		return nil, err
+	}
+	for _, kv := range kvs {
+		namespace, key, _ := strings.Cut(strings.TrimPrefix(kv.Key, configKeyPrefix), "/")
+		state.Config = append(state.Config, StateConfig{Namespace: namespace, Key: key, Value: kv.Value})
+	}
+
+	secrets, err := database.NewSecretRepository(s.db).List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, secret := range secrets {
+		state.Secrets = append(state.Secrets, secret.Name)
+	}
+
+	clusters, err := database.NewClusterRepository(s.db).List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	clusterNames := make(map[string]string, len(clusters))
+	for _, c := range clusters {
+		clusterNames[c.ID] = c.Name
+	}
+	workloads, err := database.NewWorkloadRepository(s.db).List(ctx, database.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range workloads {
+		w, err := s.stateWorkload(ctx, &workloads[i], clusterNames[workloads[i].ClusterID])
+		if err != nil {
+			return nil, err
+		}
+		state.Workloads = append(state.Workloads, *w)
+	}
+
+	records, err := database.NewDNSRecordRepository(s.db).List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, rec := range records {
+		state.DNSRecords = append(state.DNSRecords, StateDNSRecord{FQDN: rec.FQDN, Type: rec.Type, Value: rec.Value})
+	}
+
+	state.Sort()
+	return state, nil
+}
+
+func (s *Service) stateWorkload(ctx context.Context, w *database.Workload, cluster string) (*StateWorkload, error) {
+	sw := &StateWorkload{Cluster: cluster, Name: w.Name, Kind: w.Kind, Image: w.Image, Priority: w.Priority}
+
+	env, err := database.NewWorkloadEnvRepository(s.db).ListByWorkload(ctx, w.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range env {
+		switch {
+		case e.SecretName != nil:
+			if sw.Secrets == nil {
+				sw.Secrets = map[string]string{}
+			}
+			sw.Secrets[e.Key] = *e.SecretName
+		case e.Value != nil:
+			if sw.Env == nil {
+				sw.Env = map[string]string{}
+			}
+			sw.Env[e.Key] = *e.Value
+		}
+	}
+
+	hooks, err := database.NewWorkloadHookRepository(s.db).ListByWorkload(ctx, w.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range hooks {
+		hook := StateHook{Event: h.Event}
+		if h.WebhookURL != nil {
+			hook.Webhook = *h.WebhookURL
+		}
+		if h.Command != nil {
+			hook.Command = *h.Command
+		}
+		sw.Hooks = append(sw.Hooks, hook)
+	}
+
+	schedule, err := database.NewWorkloadScheduleRepository(s.db).GetByWorkload(ctx, w.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if schedule != nil {
+		sw.Schedule = &StateSchedule{Days: schedule.Days}
+		if schedule.StopAt != nil {
+			sw.Schedule.StopAt = *schedule.StopAt
+		}
+		if schedule.StartAt != nil {
+			sw.Schedule.StartAt = *schedule.StartAt
+		}
+	}
+	return sw, nil
+}
+
+// Sort puts every list of the manifest in its canonical order.
+func (st *State) Sort() {
+	sort.Slice(st.Config, func(i, j int) bool {
+		a, b := st.Config[i], st.Config[j]
+		return a.Namespace < b.Namespace || a.Namespace == b.Namespace && a.Key < b.Key
+	})
+	sort.Strings(st.Secrets)
+	sort.Slice(st.Workloads, func(i, j int) bool {
+		a, b := st.Workloads[i], st.Workloads[j]
+		return a.Cluster < b.Cluster || a.Cluster == b.Cluster && a.Name < b.Name
+	})
+	for _, w := range st.Workloads {
+		sort.Slice(w.Hooks, func(i, j int) bool {
+			a, b := w.Hooks[i], w.Hooks[j]
+			return a.Event+"\x00"+a.Webhook+"\x00"+a.Command < b.Event+"\x00"+b.Webhook+"\x00"+b.Command
+		})
+	}
+	sort.Slice(st.DNSRecords, func(i, j int) bool {
+		a, b := st.DNSRecords[i], st.DNSRecords[j]
+		return a.FQDN < b.FQDN || a.FQDN == b.FQDN && a.Type < b.Type
+	})
+}
+
+// Encode writes the manifest as YAML with a two-space indent.
+func (st *State) Encode() ([]byte, error) {
+	var b strings.Builder
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(st); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return []byte(b.String()), nil
+}
+
+// ParseState reads a manifest written by Encode (or by hand) and sorts it.
+func ParseState(data []byte) (*State, error) {
+	var st State
+	if err := yaml.Unmarshal(data, &st); err != nil {
+		return nil, err
+	}
+	if st.Kind != StateKind {
+		return nil, fmt.Errorf("not a cluster state manifest: kind is %q, want %q", st.Kind, StateKind)
+	}
+	st.Sort()
+	return &st, nil
+}
+
+// StateDiff is one resource that differs between a desired manifest and the
+// live one. Change is "missing" (declared but not live), "extra" (live but not
+// declared) or "changed", with the differing fields.
+type StateDiff struct {
+	Resource string      `json:"resource"` // e.g. "workload prod/web-1"
+	Change   string      `json:"change"`
+	Fields   []FieldDiff `json:"fields,omitempty"`
+}
+
+type FieldDiff struct {
+	Path string `json:"path"` // e.g. "env.APP_ENV"
+	Want string `json:"want"` // "" when the field is not declared
+	Have string `json:"have"` // "" when the field is not live
+}
+
+// DiffState compares a desired manifest (e.g. from git) with the live one and
+// returns the drift, in resource order. Both are expected to be sorted.
+//
+// Example Output:
+//   [{Resource: "workload prod/web-1", Change: "changed",
+//     Fields: [{Path: "image", Want: "ubuntu:24.04", Have: "ubuntu:22.04"}]},
+//    {Resource: "secret old-token", Change: "extra"}]
+func DiffState(want, have *State) []StateDiff {
+	wantRes, haveRes := want.resources(), have.resources()
+
+	names := make([]string, 0, len(wantRes)+len(haveRes))
+	for name := range wantRes {
+		names = append(names, name)
+	}
+	for name := range haveRes {
+		if _, ok := wantRes[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var diffs []StateDiff
+	for _, name := range names {
+		w, inWant := wantRes[name]
+		h, inHave := haveRes[name]
+		switch {
+		case !inHave:
+			diffs = append(diffs, StateDiff{Resource: name, Change: "missing"})
+		case !inWant:
+			diffs = append(diffs, StateDiff{Resource: name, Change: "extra"})
+		default:
+			if fields := diffFields(w, h); len(fields) > 0 {
+				diffs = append(diffs, StateDiff{Resource: name, Change: "changed", Fields: fields})
+			}
+		}
+	}
+	return diffs
+}
+
+// resources indexes the manifest by resource identity.
+func (st *State) resources() map[string]any {
+	res := map[string]any{}
+	for _, c := range st.Config {
+		res["config "+c.Namespace+"/"+c.Key] = c
+	}
+	for _, name := range st.Secrets {
+		res["secret "+name] = struct{}{}
+	}
+	for _, w := range st.Workloads {
+		res["workload "+w.Cluster+"/"+w.Name] = w
+	}
+	for _, r := range st.DNSRecords {
+		res["dns_record "+r.FQDN+" "+r.Type] = r
+	}
+	return res
+}
+
+// diffFields compares two resources field by field, as flattened YAML paths.
+func diffFields(want, have any) []FieldDiff {
+	wantFields, haveFields := flatten(want), flatten(have)
+
+	paths := make([]string, 0, len(wantFields)+len(haveFields))
+	for path := range wantFields {
+		paths = append(paths, path)
+	}
+	for path := range haveFields {
+		if _, ok := wantFields[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	var fields []FieldDiff
+	for _, path := range paths {
+		if wantFields[path] != haveFields[path] {
+			fields = append(fields, FieldDiff{Path: path, Want: wantFields[path], Have: haveFields[path]})
+		}
+	}
+	return fields
+}
+
+// flatten turns a resource into "path: value" pairs, e.g. "env.APP_ENV" or
+// "hooks[0].event", by way of its YAML form.
+func flatten(v any) map[string]string {
+	data, _ := yaml.Marshal(v)
+	var tree any
+	yaml.Unmarshal(data, &tree)
+
+	out := map[string]string{}
+	var walk func(prefix string, v any)
+	walk = func(prefix string, v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			for k, child := range v {
+				if prefix != "" {
+					k = prefix + "." + k
+				}
+				walk(k, child)
+			}
+		case []any:
+			for i, child := range v {
+				walk(fmt.Sprintf("%s[%d]", prefix, i), child)
+			}
+		case nil:
+		default:
+			out[prefix] = fmt.Sprint(v)
+		}
+	}
+	walk("", tree)
+	return out
+}
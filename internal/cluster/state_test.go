@@ -0,0 +1,108 @@
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"mcloud/internal/database"
+)
+
+func TestStateRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	db, err := database.Connect(filepath.Join(t.TempDir(), "mcloud.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close(db)
+
+	debug, prod := "debug", "production"
+	steps := []error{
+		database.NewClusterRepository(db).Create(ctx, &database.Cluster{ID: "c1", Name: "prod", State: "active"}),
+		database.NewSecretRepository(db).Upsert(ctx, &database.Secret{Name: "db-password", ValueEncrypted: "x"}),
+		database.NewKVStoreRepository(db).Set(ctx, "config/log/level", debug),
+		database.NewKVStoreRepository(db).Set(ctx, "bootstrap/pipeline", "{}"), // internal, not config
+		database.NewWorkloadRepository(db).Create(ctx, &database.Workload{ID: "w2", ClusterID: "c1", Name: "web-2", Kind: "container", Status: "running", Image: "ubuntu:24.04", Priority: "normal"}),
+		database.NewWorkloadRepository(db).Create(ctx, &database.Workload{ID: "w1", ClusterID: "c1", Name: "web-1", Kind: "vm", Status: "stopped", Image: "ubuntu:24.04", Priority: "high"}),
+		database.NewWorkloadEnvRepository(db).Create(ctx, &database.WorkloadEnv{WorkloadID: "w1", Key: "APP_ENV", Value: &prod}),
+	}
+	for _, err := range steps {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	components, _, _, _ := MockComponents()
+	svc := NewService(db, components)
+	live, err := svc.State(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &State{
+		Kind:    StateKind,
+		Config:  []StateConfig{{Namespace: "log", Key: "level", Value: "debug"}},
+		Secrets: []string{"db-password"},
+		Workloads: []StateWorkload{
+			{Cluster: "prod", Name: "web-1", Kind: "vm", Image: "ubuntu:24.04", Priority: "high", Env: map[string]string{"APP_ENV": "production"}},
+			{Cluster: "prod", Name: "web-2", Kind: "container", Image: "ubuntu:24.04", Priority: "normal"},
+		},
+	}
+	if !reflect.DeepEqual(live, want) {
+		t.Fatalf("State =\n%+v\nwant\n%+v", live, want)
+	}
+
+	// Encoding is deterministic and parses back to the same manifest
+	first, err := live.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _ := svc.State(ctx)
+	second, _ := again.Encode()
+	if !bytes.Equal(first, second) {
+		t.Errorf("two dumps differ:\n%s\n---\n%s", first, second)
+	}
+	parsed, err := ParseState(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diffs := DiffState(parsed, live); len(diffs) != 0 {
+		t.Errorf("DiffState of a dump with itself = %+v", diffs)
+	}
+}
+
+func TestDiffState(t *testing.T) {
+	want := &State{
+		Kind:    StateKind,
+		Config:  []StateConfig{{Namespace: "agent", Key: "drain_timeout", Value: "5m"}},
+		Secrets: []string{"db-password"},
+		Workloads: []StateWorkload{
+			{Cluster: "prod", Name: "web-1", Image: "ubuntu:24.04", Priority: "high", Env: map[string]string{"LOG_LEVEL": "debug"}},
+		},
+	}
+	have := &State{
+		Kind:    StateKind,
+		Secrets: []string{"db-password", "old-token"},
+		Workloads: []StateWorkload{
+			{Cluster: "prod", Name: "web-1", Image: "ubuntu:22.04", Priority: "high"},
+		},
+	}
+
+	got := DiffState(want, have)
+	expected := []StateDiff{
+		{Resource: "config agent/drain_timeout", Change: "missing"},
+		{Resource: "secret old-token", Change: "extra"},
+		{Resource: "workload prod/web-1", Change: "changed", Fields: []FieldDiff{
+			{Path: "env.LOG_LEVEL", Want: "debug"},
+			{Path: "image", Want: "ubuntu:24.04", Have: "ubuntu:22.04"},
+		}},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("DiffState =\n%+v\nwant\n%+v", got, expected)
+	}
+
+	if _, err := ParseState([]byte("kind: Workload\n")); err == nil {
+		t.Error("ParseState accepted a manifest of another kind")
+	}
+}
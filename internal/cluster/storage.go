@@ -0,0 +1,74 @@
+package cluster
+
+import (
+	"fmt"
+	"strings"
+
+	"mcloud/internal/config"
+	"mcloud/internal/preflight"
+	"mcloud/services/microceph"
+)
+
+// CephDisks returns the OSDs a node adds under the storage profile: the disks
+// assigned to its hostname in profile.Nodes if any, otherwise profile.Disks.
+// No disks means every available disk on the node.
+//
+// Example Input:
+//   CephDisks(config.Storage{Disks: []string{"/dev/sdb"}, Nodes: map[string][]string{"node2": {"/dev/nvme1n1"}}}, "node2")
+//
+// Example Output:
+//   microceph.DiskConfig{Paths: []string{"/dev/nvme1n1"}}
+func CephDisks(profile config.Storage, hostname string) microceph.DiskConfig {
+	paths := profile.Disks
+	if disks, ok := profile.Nodes[hostname]; ok {
+		paths = disks
+	}
+	return microceph.DiskConfig{
+		Paths:      paths,
+		Wipe:       profile.Wipe,
+		Encrypt:    profile.Encrypt,
+		LoopSizeGB: profile.LoopSizeGB,
+		LoopCount:  profile.LoopCount,
+	}
+}
+
+// DiskPreflight returns the preflight options that check a node's Ceph disks.
+func DiskPreflight(disks microceph.DiskConfig) preflight.Options {
+	return preflight.Options{Disks: disks.Paths, LoopOSDs: disks.LoopCount > 0}
+}
+
+// ValidateStorage checks a storage profile before it is written to the config.
+//
+// Example Output (Error):
+//   Nodes: {"node2": ["sdc"]}  =>  error("storage: disk sdc of node2 is not a /dev path")
+func ValidateStorage(profile config.Storage) error {
+	check := func(owner string, disks []string) error {
+		seen := map[string]bool{}
+		for _, d := range disks {
+			if !strings.HasPrefix(d, "/dev/") {
+				return fmt.Errorf("storage: disk %s of %s is not a /dev path", d, owner)
+			}
+			if seen[d] {
+				return fmt.Errorf("storage: disk %s of %s is listed twice", d, owner)
+			}
+			seen[d] = true
+		}
+		return nil
+	}
+
+	if err := check("every node", profile.Disks); err != nil {
+		return err
+	}
+	for hostname, disks := range profile.Nodes {
+		if hostname == "" {
+			return fmt.Errorf("storage: per-node disks need a hostname")
+		}
+		if err := check(hostname, disks); err != nil {
+			return err
+		}
+	}
+	if profile.LoopCount < 0 || profile.LoopSizeGB < 0 {
+		return fmt.Errorf("storage: loop_count and loop_size_gb must not be negative")
+	}
+	return nil
+}
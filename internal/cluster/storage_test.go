@@ -0,0 +1,56 @@
+package cluster
+
+import (
+	"reflect"
+	"testing"
+
+	"mcloud/internal/config"
+)
+
+func TestCephDisks(t *testing.T) {
+	profile := config.Storage{
+		Disks:     []string{"/dev/sdb", "/dev/sdc"},
+		Nodes:     map[string][]string{"node3": {"/dev/nvme1n1"}, "node4": {}},
+		Wipe:      true,
+		LoopCount: 3,
+	}
+
+	tests := []struct {
+		hostname string
+		want     []string
+	}{
+		{"node1", []string{"/dev/sdb", "/dev/sdc"}},
+		{"node3", []string{"/dev/nvme1n1"}},
+		{"node4", []string{}}, // assigned no disks: auto-discover
+	}
+	for _, tt := range tests {
+		got := CephDisks(profile, tt.hostname)
+		if !reflect.DeepEqual(got.Paths, tt.want) || !got.Wipe || got.LoopCount != 3 {
+			t.Errorf("CephDisks(%s) = %+v, want paths %v", tt.hostname, got, tt.want)
+		}
+	}
+
+	if opts := DiskPreflight(CephDisks(profile, "node3")); !reflect.DeepEqual(opts.Disks, []string{"/dev/nvme1n1"}) || !opts.LoopOSDs {
+		t.Errorf("DiskPreflight = %+v", opts)
+	}
+}
+
+func TestValidateStorage(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile config.Storage
+		ok      bool
+	}{
+		{"empty", config.Storage{}, true},
+		{"disks", config.Storage{Disks: []string{"/dev/sdb"}, Nodes: map[string][]string{"node2": {"/dev/sdc"}}}, true},
+		{"relative disk", config.Storage{Disks: []string{"sdb"}}, false},
+		{"duplicate node disk", config.Storage{Nodes: map[string][]string{"node2": {"/dev/sdc", "/dev/sdc"}}}, false},
+		{"no hostname", config.Storage{Nodes: map[string][]string{"": {"/dev/sdc"}}}, false},
+		{"negative loops", config.Storage{LoopCount: -1}, false},
+	}
+	for _, tt := range tests {
+		if err := ValidateStorage(tt.profile); (err == nil) != tt.ok {
+			t.Errorf("%s: ValidateStorage = %v, want ok=%v", tt.name, err, tt.ok)
+		}
+	}
+}
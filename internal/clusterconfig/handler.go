@@ -0,0 +1,207 @@
+package clusterconfig
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"mcloud/pkg/reason"
+)
+
+// keepAliveInterval keeps idle watch streams open through proxies and load balancers.
+const keepAliveInterval = 15 * time.Second
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(s *Service) *Handler {
+	return &Handler{service: s}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidKey):
+		reason.HTTPError(w, err, 400)
+	case errors.Is(err, ErrNotFound):
+		reason.HTTPError(w, err, 404)
+	case errors.Is(err, ErrVersionConflict):
+		reason.HTTPError(w, err, 409)
+	default:
+		reason.HTTPError(w, err, 500)
+	}
+}
+
+// ListConfig handles GET /config and GET /config/{namespace}.
+func (h *Handler) ListConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	entries, err := h.service.List(r.Context(), r.PathValue("namespace"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+// GetConfig handles GET /config/{namespace}/{key}.
+func (h *Handler) GetConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	entry, err := h.service.Get(r.Context(), r.PathValue("namespace"), r.PathValue("key"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entry)
+}
+
+// SetConfig handles PUT /config/{namespace}/{key} (see SetRequest).
+// A stale version is answered with 409 Conflict.
+func (h *Handler) SetConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req SetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		reason.HTTPError(w, err, 400)
+		return
+	}
+
+	entry, err := h.service.Set(r.Context(), r.PathValue("namespace"), r.PathValue("key"), &req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entry)
+}
+
+// DeleteConfig handles DELETE /config/{namespace}/{key}[?version=N].
+func (h *Handler) DeleteConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var version *int64
+	if v := r.URL.Query().Get("version"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n <= 0 {
+			reason.HTTPErrorf(w, 400, "invalid version")
+			return
+		}
+		version = &n
+	}
+
+	if err := h.service.Delete(r.Context(), r.PathValue("namespace"), r.PathValue("key"), version); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// WatchConfig handles GET /config/watch[?namespace=agent]: config changes are
+// pushed as Server-Sent Events until the client disconnects. Clients resuming
+// after a disconnect send the last revision as Last-Event-ID; new clients only
+// receive changes made after they connected.
+func (h *Handler) WatchConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	ctx := r.Context()
+
+	namespace := r.URL.Query().Get("namespace")
+	if namespace != "" {
+		if err := validateName("namespace", namespace); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+
+	var revision int64
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			reason.HTTPErrorf(w, 400, "invalid Last-Event-ID")
+			return
+		}
+		revision = n
+	} else {
+		n, err := h.service.LatestRevision(ctx)
+		if err != nil {
+			reason.HTTPError(w, err, 500)
+			return
+		}
+		revision = n
+	}
+
+	// The server's write timeout is meant for regular requests, not streams
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		reason.HTTPError(w, err, 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	poll := time.NewTicker(pollInterval)
+	defer poll.Stop()
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case <-poll.C:
+			changes, err := h.service.ChangesAfter(ctx, namespace, revision)
+			if err != nil {
+				return
+			}
+			for _, c := range changes {
+				data, err := json.Marshal(c)
+				if err != nil {
+					return
+				}
+				if _, err := fmt.Fprintf(w, "id: %d\nevent: config.changed\ndata: %s\n\n", c.Revision, data); err != nil {
+					return
+				}
+				revision = c.Revision
+			}
+			if len(changes) == 0 {
+				continue
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,15 @@
+package clusterconfig
+
+import (
+	"net/http"
+)
+
+// InitModule registers the cluster config routes served by handler.
+func InitModule(mux *http.ServeMux, handler *Handler) {
+	mux.HandleFunc("GET /config", handler.ListConfig)
+	mux.HandleFunc("GET /config/watch", handler.WatchConfig)
+	mux.HandleFunc("GET /config/{namespace}", handler.ListConfig)
+	mux.HandleFunc("GET /config/{namespace}/{key}", handler.GetConfig)
+	mux.HandleFunc("PUT /config/{namespace}/{key}", handler.SetConfig)
+	mux.HandleFunc("DELETE /config/{namespace}/{key}", handler.DeleteConfig)
+}
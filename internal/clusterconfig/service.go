@@ -0,0 +1,375 @@
+// Package clusterconfig is the cluster-wide configuration store: namespaced keys
+// in the kv_store table with a version per key for optimistic concurrency, typed
+// getters, and a change log that mcloudd components (Watch) and agents
+// (GET /config/watch) follow to pick up new values without a restart.
+package clusterconfig
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"mcloud/internal/database"
+	"mcloud/pkg/reason"
+)
+
+const (
+	// keyPrefix keeps config keys apart from internal kv_store entries such as
+	// init pipeline progress; a config key is stored as config/<namespace>/<key>.
+	keyPrefix = "config/"
+
+	// watchBatchSize is the maximum number of changes read per poll.
+	watchBatchSize = 100
+
+	// pollInterval is how often watchers check the change log. Every manager
+	// writes to the same table, so polling it sees changes made anywhere.
+	pollInterval = time.Second
+
+	// ChangeRetention is how long the change log is kept. A watcher that falls
+	// further behind should re-read the values it cares about.
+	ChangeRetention = 24 * time.Hour
+)
+
+var (
+	ErrNotFound        = reason.New(reason.ConfigKeyNotFound, "config key not found")
+	ErrVersionConflict = reason.New(reason.ConfigVersionConflict, "config key was changed by someone else")
+	ErrInvalidKey      = reason.New(reason.InvalidConfigKey, "invalid config key")
+)
+
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// Entry is the current value of a config key.
+type Entry struct {
+	Namespace string    `json:"namespace"`
+	Key       string    `json:"key"`
+	Value     string    `json:"value"`
+	Version   int64     `json:"version"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Change is one write or deletion of a config key, in revision order.
+type Change struct {
+	Revision  int64     `json:"revision"`
+	Namespace string    `json:"namespace"`
+	Key       string    `json:"key"`
+	Value     *string   `json:"value,omitempty"` // nil when deleted
+	Version   int64     `json:"version"`
+	Deleted   bool      `json:"deleted"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// SetRequest writes a config value. Version makes the write conditional: 0
+// creates the key only if it does not exist, n updates it only if it is still
+// at version n; without Version the write is unconditional.
+//
+// Example JSON:
+//   {"value": "30s", "version": 3}
+type SetRequest struct {
+	Value   string `json:"value"`
+	Version *int64 `json:"version,omitempty"`
+}
+
+type Service struct {
+	db *sql.DB
+}
+
+func NewService(db *sql.DB) *Service {
+	return &Service{db: db}
+}
+
+// validateName checks a namespace or key. "watch" is reserved because
+// /config/watch would shadow a namespace of that name.
+func validateName(kind, name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("%w: %s %q may only contain letters, digits, '_', '.' and '-'", ErrInvalidKey, kind, name)
+	}
+	if kind == "namespace" && name == "watch" {
+		return fmt.Errorf("%w: namespace %q is reserved", ErrInvalidKey, name)
+	}
+	return nil
+}
+
+func storageKey(namespace, key string) string {
+	return keyPrefix + namespace + "/" + key
+}
+
+// namespacePrefix is the kv_store prefix of a namespace ("" = every namespace).
+func namespacePrefix(namespace string) string {
+	if namespace == "" {
+		return keyPrefix
+	}
+	return keyPrefix + namespace + "/"
+}
+
+// splitKey turns a kv_store key back into namespace and key.
+func splitKey(stored string) (string, string) {
+	namespace, key, _ := strings.Cut(strings.TrimPrefix(stored, keyPrefix), "/")
+	return namespace, key
+}
+
+func toEntry(kv *database.KV) *Entry {
+	namespace, key := splitKey(kv.Key)
+	return &Entry{Namespace: namespace, Key: key, Value: kv.Value, Version: kv.Version, UpdatedAt: kv.UpdatedAt}
+}
+
+// Get returns the current value of a key.
+//
+// Example Output:
+//   Entry{Namespace: "agent", Key: "drain_timeout", Value: "5m", Version: 2}
+func (s *Service) Get(ctx context.Context, namespace, key string) (*Entry, error) {
+	if err := validateName("namespace", namespace); err != nil {
+		return nil, err
+	}
+	if err := validateName("key", key); err != nil {
+		return nil, err
+	}
+
+	kv, err := database.NewKVStoreRepository(s.db).Get(ctx, storageKey(namespace, key))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return toEntry(kv), nil
+}
+
+// List returns the keys of a namespace, or of every namespace when it is empty.
+func (s *Service) List(ctx context.Context, namespace string) ([]Entry, error) {
+	if namespace != "" {
+		if err := validateName("namespace", namespace); err != nil {
+			return nil, err
+		}
+	}
+
+	kvs, err := database.NewKVStoreRepository(s.db).ListPrefix(ctx, namespacePrefix(namespace))
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]Entry, 0, len(kvs))
+	for i := range kvs {
+		entries = append(entries, *toEntry(&kvs[i]))
+	}
+	return entries, nil
+}
+
+// Set writes a key and records the change for watchers.
+//
+// Example Input:
+//   Set(ctx, "agent", "drain_timeout", &SetRequest{Value: "5m", Version: &two})
+//
+// Example Output (Error - Stale Version):
+//   Returns: (nil, ErrVersionConflict)
+func (s *Service) Set(ctx context.Context, namespace, key string, req *SetRequest) (*Entry, error) {
+	if err := validateName("namespace", namespace); err != nil {
+		return nil, err
+	}
+	if err := validateName("key", key); err != nil {
+		return nil, err
+	}
+	stored := storageKey(namespace, key)
+
+	var kv *database.KV
+	err := database.WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		repo := database.NewKVStoreRepositoryTx(tx)
+
+		expected, err := s.expectedVersion(ctx, repo, stored, req.Version)
+		if err != nil {
+			return err
+		}
+		ok, err := repo.CompareAndSet(ctx, stored, req.Value, expected)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrVersionConflict
+		}
+		kv, err = repo.Get(ctx, stored)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toEntry(kv), nil
+}
+
+// Delete removes a key, only at the given version if one is passed.
+func (s *Service) Delete(ctx context.Context, namespace, key string, version *int64) error {
+	if err := validateName("namespace", namespace); err != nil {
+		return err
+	}
+	if err := validateName("key", key); err != nil {
+		return err
+	}
+	stored := storageKey(namespace, key)
+
+	return database.WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		repo := database.NewKVStoreRepositoryTx(tx)
+
+		current, err := repo.Get(ctx, stored)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		expected := current.Version
+		if version != nil {
+			expected = *version
+		}
+		ok, err := repo.CompareAndDelete(ctx, stored, expected)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrVersionConflict
+		}
+		return nil
+	})
+}
+
+// expectedVersion is the version a write must find: the requested one, or
+// for unconditional writes whatever is stored now (0 if nothing).
+func (s *Service) expectedVersion(ctx context.Context, repo *database.KVStoreRepository, stored string, requested *int64) (int64, error) {
+	if requested != nil {
+		if *requested < 0 {
+			return 0, fmt.Errorf("%w: version must not be negative", ErrInvalidKey)
+		}
+		return *requested, nil
+	}
+	current, err := repo.Get(ctx, stored)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return current.Version, nil
+}
+
+// lookup returns the raw value of a key and whether it is set.
+func (s *Service) lookup(ctx context.Context, namespace, key string) (string, bool, error) {
+	e, err := s.Get(ctx, namespace, key)
+	if errors.Is(err, ErrNotFound) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return e.Value, true, nil
+}
+
+// Bool returns a key parsed with strconv.ParseBool, or def when it is not set.
+func (s *Service) Bool(ctx context.Context, namespace, key string, def bool) (bool, error) {
+	v, ok, err := s.lookup(ctx, namespace, key)
+	if err != nil || !ok {
+		return def, err
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return def, fmt.Errorf("config %s/%s: %q is not a bool", namespace, key, v)
+	}
+	return b, nil
+}
+
+// Int returns a key parsed as a base-10 integer, or def when it is not set.
+func (s *Service) Int(ctx context.Context, namespace, key string, def int) (int, error) {
+	v, ok, err := s.lookup(ctx, namespace, key)
+	if err != nil || !ok {
+		return def, err
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return def, fmt.Errorf("config %s/%s: %q is not an integer", namespace, key, v)
+	}
+	return n, nil
+}
+
+// Duration returns a key parsed with time.ParseDuration (e.g. "90s", "5m"), or
+// def when it is not set.
+func (s *Service) Duration(ctx context.Context, namespace, key string, def time.Duration) (time.Duration, error) {
+	v, ok, err := s.lookup(ctx, namespace, key)
+	if err != nil || !ok {
+		return def, err
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return def, fmt.Errorf("config %s/%s: %q is not a duration", namespace, key, v)
+	}
+	return d, nil
+}
+
+// ChangesAfter returns up to watchBatchSize changes in a namespace (every
+// namespace when empty) newer than revision, oldest first.
+func (s *Service) ChangesAfter(ctx context.Context, namespace string, revision int64) ([]Change, error) {
+	kvChanges, err := database.NewKVStoreRepository(s.db).ListChangesAfter(ctx, namespacePrefix(namespace), revision, watchBatchSize)
+	if err != nil {
+		return nil, err
+	}
+	changes := make([]Change, 0, len(kvChanges))
+	for _, c := range kvChanges {
+		ns, key := splitKey(c.Key)
+		changes = append(changes, Change{
+			Revision:  c.Revision,
+			Namespace: ns,
+			Key:       key,
+			Value:     c.Value,
+			Version:   c.Version,
+			Deleted:   c.Deleted,
+			ChangedAt: c.ChangedAt,
+		})
+	}
+	return changes, nil
+}
+
+// LatestRevision returns the newest change revision, so a watch can start with new changes only.
+func (s *Service) LatestRevision(ctx context.Context) (int64, error) {
+	return database.NewKVStoreRepository(s.db).LatestRevision(ctx)
+}
+
+// Watch calls fn for every change in a namespace (every namespace when empty)
+// made after it was called, until ctx is cancelled or fn returns an error.
+// This is how mcloudd components follow config without a restart:
+//
+//   go cfgService.Watch(ctx, "timesync", func(c clusterconfig.Change) error {
+//       if c.Key == "max_offset_ms" { ... }
+//       return nil
+//   })
+func (s *Service) Watch(ctx context.Context, namespace string, fn func(Change) error) error {
+	revision, err := s.LatestRevision(ctx)
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		changes, err := s.ChangesAfter(ctx, namespace, revision)
+		if err != nil {
+			// Transient database errors should not end the watch
+			continue
+		}
+		for _, c := range changes {
+			if err := fn(c); err != nil {
+				return err
+			}
+			revision = c.Revision
+		}
+	}
+}
+
+// PruneChanges is the periodic job that drops change log entries older than ChangeRetention.
+func (s *Service) PruneChanges(ctx context.Context) error {
+	return database.NewKVStoreRepository(s.db).DeleteChangesBefore(ctx, time.Now().Add(-ChangeRetention))
+}
@@ -0,0 +1,117 @@
+package command
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"mcloud/internal/audit"
+	"mcloud/internal/auth"
+	"mcloud/internal/operation"
+	"mcloud/pkg/reason"
+)
+
+type Handler struct {
+	hub *Hub
+}
+
+func NewHandler(h *Hub) *Handler {
+	return &Handler{hub: h}
+}
+
+// writeError maps hub errors to HTTP status codes.
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrInvalid):
+		reason.HTTPError(w, err, 400)
+	case errors.Is(err, ErrNodeNotFound):
+		reason.HTTPError(w, err, 404)
+	case errors.Is(err, ErrNotConnected):
+		reason.HTTPError(w, err, 409)
+	default:
+		reason.HTTPError(w, err, 500)
+	}
+}
+
+// ListAgents returns the agents with their command stream open.
+func (h *Handler) ListAgents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.hub.Agents())
+}
+
+// SendCommand pushes a command to a node's agent and answers 202 with the
+// operation that tracks it, which the request's audit entry points at.
+func (h *Handler) SendCommand(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		reason.HTTPError(w, err, 400)
+		return
+	}
+
+	actor := auth.ClientIdentity(r)
+	op, err := h.hub.Send(r.Context(), r.PathValue("id"), &req, &actor)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	audit.SetTarget(r, "/operations/"+op.ID)
+	operation.WriteAccepted(w, op, op)
+}
+
+// streamWriter flushes every write to the client and remembers whether
+// anything was written, after which errors can no longer change the status.
+type streamWriter struct {
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	written bool
+}
+
+func (s *streamWriter) Write(p []byte) (int, error) {
+	s.written = true
+	n, err := s.w.Write(p)
+	if err == nil {
+		err = s.rc.Flush()
+	}
+	return n, err
+}
+
+// StreamLogs streams the logs of a service on a node as plain text, fetched
+// by the node's agent from the service's journal.
+//
+// Query parameters:
+//   service  lxd, microceph, microovn, mcloudd or agent (required)
+//   since    how far back to start, e.g. 30m (default 1h)
+//   follow   true to keep streaming new lines until the client disconnects
+func (h *Handler) StreamLogs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	p := &LogsPayload{Service: q.Get("service"), Since: q.Get("since"), Follow: q.Get("follow") == "true"}
+
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{}) // the server's write timeout would cut long streams
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	out := &streamWriter{w: w, rc: rc}
+	if err := h.hub.Stream(r.Context(), r.PathValue("id"), p, out); err != nil {
+		if !out.written {
+			writeError(w, err)
+			return
+		}
+		fmt.Fprintf(out, "\nerror: %v\n", err)
+	}
+}
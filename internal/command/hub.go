@@ -0,0 +1,417 @@
+package command
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"mcloud/internal/database"
+	"mcloud/internal/event"
+	"mcloud/internal/operation"
+	"mcloud/internal/version"
+	"mcloud/pkg/logger"
+	"mcloud/pkg/reason"
+	"mcloud/pkg/utils"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var log = logger.Named("command")
+
+// anonymousActor is audited for tasks sent without a client identity (as in
+// the audit middleware).
+const anonymousActor = "anonymous"
+
+var (
+	ErrNodeNotFound = reason.New(reason.NodeNotFound, "node not found")
+	ErrNotConnected = reason.New(reason.AgentNotConnected, "agent is not connected")
+	ErrInvalid      = reason.New(reason.InvalidCommand, "invalid command")
+)
+
+// Request asks for a command to be run on a node.
+//
+// Example JSON:
+//   {"type": "restart_service", "payload": {"service": "lxd"}}
+type Request struct {
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// Agent is an agent with its command stream open.
+//
+// Example JSON:
+//   {"node": "node2", "connected_at": "2026-10-16T09:00:00Z", "running": 1}
+type Agent struct {
+	Node        string    `json:"node"`
+	Version     string    `json:"version,omitempty"` // of mcloud-agent; empty for agents too old to report it
+	ConnectedAt time.Time `json:"connected_at"`
+	Running     int       `json:"running"` // commands sent and not answered yet
+}
+
+// agentStream is the open WatchCommands stream of one agent.
+type agentStream struct {
+	node        string
+	version     string
+	connectedAt time.Time
+	stream      grpc.ServerStream
+	done        chan struct{} // closed when the stream ends or is replaced
+
+	sendMu sync.Mutex // SendMsg must not be called concurrently
+
+	mu      sync.Mutex
+	pending map[string]*pendingCommand // by command ID
+	closed  bool
+}
+
+// pendingCommand receives the replies to a command until it is forgotten.
+type pendingCommand struct {
+	replies chan *AgentMessage
+	gone    chan struct{} // closed by forget
+}
+
+func (st *agentStream) send(cmd *Command) error {
+	st.sendMu.Lock()
+	defer st.sendMu.Unlock()
+	return st.stream.SendMsg(cmd)
+}
+
+// expect registers a command before it is sent so its replies are not lost.
+func (st *agentStream) expect(id string) chan *AgentMessage {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	pc := &pendingCommand{replies: make(chan *AgentMessage, 16), gone: make(chan struct{})}
+	st.pending[id] = pc
+	return pc.replies
+}
+
+func (st *agentStream) forget(id string) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if pc, ok := st.pending[id]; ok {
+		delete(st.pending, id)
+		close(pc.gone)
+	}
+}
+
+// deliver hands a reply to the command waiting for it. Output can outpace
+// the reader, so it blocks until the reply is taken or the command forgotten.
+func (st *agentStream) deliver(m *AgentMessage) {
+	st.mu.Lock()
+	pc, ok := st.pending[m.CommandID]
+	st.mu.Unlock()
+	if !ok {
+		if m.Type != MessageOutput { // output may still arrive after a cancel
+			log.Warn("Agent on %s answered unknown command %s", st.node, m.CommandID)
+		}
+		return
+	}
+	select {
+	case pc.replies <- m:
+	case <-pc.gone:
+	case <-st.done:
+	}
+}
+
+func (st *agentStream) close() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if !st.closed {
+		st.closed = true
+		close(st.done)
+	}
+}
+
+// Hub holds the command streams of the connected agents and sends commands
+// down them.
+type Hub struct {
+	db  *sql.DB
+	ops *operation.Service
+
+	mu      sync.Mutex
+	streams map[string]*agentStream // by node hostname
+}
+
+func NewHub(db *sql.DB, ops *operation.Service) *Hub {
+	return &Hub{db: db, ops: ops, streams: map[string]*agentStream{}}
+}
+
+// Register adds the AgentService to a gRPC server.
+func (h *Hub) Register(s *grpc.Server) {
+	s.RegisterService(&serviceDesc, h)
+}
+
+// watch serves one agent's WatchCommands stream until it ends. A second
+// stream from the same node replaces the first. An agent of another major
+// version than mcloudd is turned away.
+func (h *Hub) watch(stream grpc.ServerStream) error {
+	var hello AgentMessage
+	if err := stream.RecvMsg(&hello); err != nil {
+		return err
+	}
+	if hello.Type != MessageHello || hello.NodeName == "" {
+		return status.Error(codes.InvalidArgument, "the first message must be a hello with the node name")
+	}
+	if err := version.Compatible("mcloud-agent on "+hello.NodeName, hello.Version); err != nil {
+		log.Warn("Rejected agent on %s: %v", hello.NodeName, err)
+		return status.Error(codes.FailedPrecondition, err.Error()+"; upgrade it with mcloudctl upgrade on the node")
+	}
+
+	st := &agentStream{
+		node:        hello.NodeName,
+		version:     hello.Version,
+		connectedAt: time.Now().UTC(),
+		stream:      stream,
+		done:        make(chan struct{}),
+		pending:     map[string]*pendingCommand{},
+	}
+	h.mu.Lock()
+	old, reconnected := h.streams[st.node]
+	if reconnected {
+		old.close()
+	}
+	h.streams[st.node] = st
+	h.mu.Unlock()
+	log.Info("Agent on %s connected", st.node)
+	if !reconnected {
+		h.nodeEvent(stream.Context(), st.node, event.NodeOnlineEvent)
+	}
+
+	defer func() {
+		h.mu.Lock()
+		current := h.streams[st.node] == st
+		if current {
+			delete(h.streams, st.node)
+		}
+		h.mu.Unlock()
+		st.close()
+		log.Info("Agent on %s disconnected", st.node)
+		// A stream replaced by a newer one does not take its node offline
+		if current {
+			h.nodeEvent(context.WithoutCancel(stream.Context()), st.node, event.NodeOfflineEvent)
+		}
+	}()
+
+	for {
+		var m AgentMessage
+		err := stream.RecvMsg(&m)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		select {
+		case <-st.done:
+			return nil // replaced by a newer stream
+		default:
+		}
+		st.deliver(&m)
+	}
+}
+
+// nodeEvent records the event newEvent builds for the node with the given
+// hostname; agents of nodes without a record are not reported.
+func (h *Hub) nodeEvent(ctx context.Context, hostname string, newEvent func(*database.Node) *database.Event) {
+	n, err := database.NewNodeRepository(h.db).GetByHostname(ctx, hostname)
+	if err != nil {
+		return
+	}
+	e := newEvent(n)
+	if err := database.NewEventRepository(h.db).Create(ctx, e); err != nil {
+		log.Error("Failed to record event %s: %v", e.Type, err)
+	}
+}
+
+func (h *Hub) stream(node string) *agentStream {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.streams[node]
+}
+
+// Agents returns the connected agents by node.
+func (h *Hub) Agents() []Agent {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	agents := make([]Agent, 0, len(h.streams))
+	for _, st := range h.streams {
+		st.mu.Lock()
+		agents = append(agents, Agent{Node: st.node, Version: st.version, ConnectedAt: st.connectedAt, Running: len(st.pending)})
+		st.mu.Unlock()
+	}
+	sort.Slice(agents, func(i, j int) bool { return agents[i].Node < agents[j].Node })
+	return agents
+}
+
+// Send starts an operation that runs a command on a node's agent. The
+// operation reports the agent's ack as progress and succeeds or fails with
+// the agent's result; it fails if the agent disconnects before answering.
+//
+// Example Output (Error):
+//   Agent of node2 not connected  =>  ErrNotConnected: node2
+func (h *Hub) Send(ctx context.Context, nodeID string, req *Request, actor *string) (*database.Operation, error) {
+	if !slices.Contains(Types, req.Type) {
+		return nil, fmt.Errorf("%w: unknown type %q", ErrInvalid, req.Type)
+	}
+	if len(req.Payload) > 0 && !json.Valid(req.Payload) {
+		return nil, fmt.Errorf("%w: payload is not JSON", ErrInvalid)
+	}
+	var task TaskPayload
+	if req.Type == TypeTask {
+		if err := json.Unmarshal(req.Payload, &task); err != nil || Tasks[task.Task] == "" {
+			return nil, fmt.Errorf("%w: task must be one of %s", ErrInvalid, strings.Join(slices.Sorted(maps.Keys(Tasks)), ", "))
+		}
+	}
+
+	n, err := database.NewNodeRepository(h.db).GetByID(ctx, nodeID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNodeNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if h.stream(n.Hostname) == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotConnected, n.Hostname)
+	}
+
+	cmd := &Command{ID: utils.GenerateUUID(), Type: req.Type, Payload: string(req.Payload)}
+	return h.ops.Start(ctx, "agent."+req.Type, "/nodes/"+n.ID, actor, func(ctx context.Context, report operation.Reporter) (any, error) {
+		result, err := h.run(ctx, n.Hostname, cmd, report, io.Discard)
+		if req.Type == TypeTask {
+			h.auditTask(ctx, n, task.Task, actor, err)
+		}
+		return result, err
+	})
+}
+
+// Run runs a command on the agent of node (a hostname) as part of the
+// caller's own work, e.g. a step of a larger operation, and decodes the
+// agent's result into result. The agent's ack is passed to report.
+//
+// Example Output (Error):
+//   Agent of node2 not connected  =>  ErrNotConnected: node2
+func (h *Hub) Run(ctx context.Context, node string, req *Request, report operation.Reporter, result any) error {
+	if !slices.Contains(Types, req.Type) {
+		return fmt.Errorf("%w: unknown type %q", ErrInvalid, req.Type)
+	}
+	cmd := &Command{ID: utils.GenerateUUID(), Type: req.Type, Payload: string(req.Payload)}
+	raw, err := h.run(ctx, node, cmd, report, io.Discard)
+	if err != nil || raw == nil || result == nil {
+		return err
+	}
+	return json.Unmarshal(raw.(json.RawMessage), result)
+}
+
+// auditTask records a task execution in the audit log: action "TASK <name>"
+// on the node, with status 200 when the agent ran it and 502 when it failed.
+func (h *Hub) auditTask(ctx context.Context, n *database.Node, task string, actor *string, runErr error) {
+	entry := &database.AuditEntry{
+		Actor:      anonymousActor,
+		Action:     "TASK " + task,
+		Target:     "/nodes/" + n.ID,
+		StatusCode: http.StatusOK,
+		Result:     "success",
+	}
+	if actor != nil && *actor != "" {
+		entry.Actor = *actor
+	}
+	if runErr != nil {
+		entry.StatusCode, entry.Result = http.StatusBadGateway, "failure"
+	}
+	// Record even if the operation timed out
+	if err := database.NewAuditRepository(h.db).Create(context.WithoutCancel(ctx), entry); err != nil {
+		log.Error("Failed to audit task %s on %s: %v", task, n.Hostname, err)
+	}
+}
+
+// Stream tails the logs of a service on a node's agent into w until the
+// agent has sent them all or, when following, until ctx is cancelled. No
+// operation tracks it: the logs go straight to the caller.
+//
+// Example Output (Error):
+//   service "nginx"  =>  ErrInvalid: service must be one of [lxd ...]
+func (h *Hub) Stream(ctx context.Context, nodeID string, p *LogsPayload, w io.Writer) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	n, err := database.NewNodeRepository(h.db).GetByID(ctx, nodeID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNodeNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if h.stream(n.Hostname) == nil {
+		return fmt.Errorf("%w: %s", ErrNotConnected, n.Hostname)
+	}
+
+	payload, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	cmd := &Command{ID: utils.GenerateUUID(), Type: TypeLogs, Payload: string(payload)}
+	_, err = h.run(ctx, n.Hostname, cmd, func(int, string) {}, w)
+	if ctx.Err() != nil {
+		return nil // the caller went away or stopped following
+	}
+	return err
+}
+
+// run sends cmd to the agent of node, copies its output to out and waits for
+// its result. If ctx is cancelled first the agent is told to cancel the
+// command.
+func (h *Hub) run(ctx context.Context, node string, cmd *Command, report operation.Reporter, out io.Writer) (any, error) {
+	st := h.stream(node)
+	if st == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNotConnected, node)
+	}
+	replies := st.expect(cmd.ID)
+	defer st.forget(cmd.ID)
+
+	if err := st.send(cmd); err != nil {
+		return nil, fmt.Errorf("failed to send command to %s: %w", node, err)
+	}
+	report(10, "Sent to "+node)
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := st.send(&Command{ID: cmd.ID, Type: TypeCancel}); err != nil {
+				log.Warn("Failed to cancel command %s on %s: %v", cmd.ID, node, err)
+			}
+			return nil, ctx.Err()
+		case <-st.done:
+			return nil, fmt.Errorf("agent on %s disconnected before answering", node)
+		case m := <-replies:
+			switch m.Type {
+			case MessageAck:
+				report(50, "Running on "+node)
+			case MessageOutput:
+				if _, err := io.WriteString(out, m.Data); err != nil {
+					// The reader is gone; stop the command rather than buffer its output
+					st.send(&Command{ID: cmd.ID, Type: TypeCancel})
+					return nil, err
+				}
+			case MessageResult:
+				if !m.Success {
+					return nil, errors.New(m.Error)
+				}
+				if m.Result == "" {
+					return nil, nil
+				}
+				return json.RawMessage(m.Result), nil
+			}
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"mcloud/internal/config"
+	"mcloud/internal/database"
+	"mcloud/internal/operation"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// serve starts a hub on an in-memory gRPC server with node1 recorded and
+// returns a client connection to it.
+func serve(t *testing.T) (*Hub, *grpc.ClientConn) {
+	t.Helper()
+	db, err := database.Connect(filepath.Join(t.TempDir(), "mcloud.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close(db) })
+	if err := database.NewNodeRepository(db).Create(context.Background(), &database.Node{ID: "n1", ClusterID: "c1", Hostname: "node1", IP: "10.0.0.1", Role: "worker", Status: "online"}); err != nil {
+		t.Fatal(err)
+	}
+
+	hub := NewHub(db, operation.NewService(db, &config.Config{}))
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	hub.Register(server)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return hub, conn
+}
+
+// connected waits until the agent of node has its stream open.
+func connected(t *testing.T, hub *Hub, node string) {
+	t.Helper()
+	for range 100 {
+		if hub.stream(node) != nil {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("agent on %s did not connect", node)
+}
+
+func TestSend(t *testing.T) {
+	hub, conn := serve(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if _, err := hub.Send(ctx, "n1", &Request{Type: TypePreflight}, nil); !errors.Is(err, ErrNotConnected) {
+		t.Fatalf("before connecting: got %v, want ErrNotConnected", err)
+	}
+
+	go Watch(ctx, conn, "node1", func(ctx context.Context, cmd *Command, _ io.Writer) (any, error) {
+		if cmd.Type == TypeCollectLogs {
+			return nil, errors.New("no such service")
+		}
+		return map[string]string{"type": cmd.Type, "payload": cmd.Payload}, nil
+	})
+	connected(t, hub, "node1")
+
+	op, err := hub.Send(ctx, "n1", &Request{Type: TypeRestartService, Payload: []byte(`{"service":"lxd"}`)}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if op.Type != "agent.restart_service" || op.Resource == nil || *op.Resource != "/nodes/n1" {
+		t.Errorf("operation: got type %s resource %v", op.Type, op.Resource)
+	}
+	done, err := hub.ops.Wait(ctx, op.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if done.Status != operation.StatusSucceeded || string(done.Result) != `{"payload":"{\"service\":\"lxd\"}","type":"restart_service"}` {
+		t.Errorf("result: got %s %s", done.Status, done.Result)
+	}
+
+	op, err = hub.Send(ctx, "n1", &Request{Type: TypeCollectLogs, Payload: []byte(`{"service":"nope"}`)}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if done, _ := hub.ops.Wait(ctx, op.ID); done.Status != operation.StatusFailed || done.Error == nil || *done.Error != "no such service" {
+		t.Errorf("failed command: got %s %v", done.Status, done.Error)
+	}
+
+	actor := "admin"
+	op, err = hub.Send(ctx, "n1", &Request{Type: TypeTask, Payload: []byte(`{"task":"ceph_status"}`)}, &actor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hub.ops.Wait(ctx, op.ID)
+	entries, err := database.NewAuditRepository(hub.db).ListSince(ctx, time.Time{}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Actor != "admin" || entries[0].Action != "TASK ceph_status" || entries[0].Target != "/nodes/n1" || entries[0].Result != "success" {
+		t.Errorf("audit: got %+v", entries)
+	}
+
+	if agents := hub.Agents(); len(agents) != 1 || agents[0].Node != "node1" || agents[0].Running != 0 {
+		t.Errorf("agents: got %+v", agents)
+	}
+}
+
+func TestSendErrors(t *testing.T) {
+	hub, _ := serve(t)
+	ctx := context.Background()
+	if _, err := hub.Send(ctx, "n1", &Request{Type: "reboot"}, nil); !errors.Is(err, ErrInvalid) {
+		t.Errorf("unknown type: got %v, want ErrInvalid", err)
+	}
+	if _, err := hub.Send(ctx, "n1", &Request{Type: TypeApplyConfig, Payload: []byte(`{`)}, nil); !errors.Is(err, ErrInvalid) {
+		t.Errorf("bad payload: got %v, want ErrInvalid", err)
+	}
+	for _, payload := range []string{``, `{"task":"shell"}`, `{"task":"ceph_status; reboot"}`} {
+		if _, err := hub.Send(ctx, "n1", &Request{Type: TypeTask, Payload: []byte(payload)}, nil); !errors.Is(err, ErrInvalid) {
+			t.Errorf("task payload %q: got %v, want ErrInvalid", payload, err)
+		}
+	}
+	if _, err := hub.Send(ctx, "n9", &Request{Type: TypePreflight}, nil); !errors.Is(err, ErrNodeNotFound) {
+		t.Errorf("unknown node: got %v, want ErrNodeNotFound", err)
+	}
+}
+
+func TestStream(t *testing.T) {
+	hub, conn := serve(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cancelled := make(chan struct{})
+	go Watch(ctx, conn, "node1", func(ctx context.Context, cmd *Command, out io.Writer) (any, error) {
+		var p LogsPayload
+		json.Unmarshal([]byte(cmd.Payload), &p)
+		fmt.Fprintf(out, "%s since %s\n", p.Service, p.Since)
+		if !p.Follow {
+			return nil, nil
+		}
+		<-ctx.Done()
+		close(cancelled)
+		return nil, ctx.Err()
+	})
+	connected(t, hub, "node1")
+
+	var out strings.Builder
+	if err := hub.Stream(ctx, "n1", &LogsPayload{Service: "lxd", Since: "2h"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "lxd since 2h\n" {
+		t.Errorf("output: got %q", out.String())
+	}
+
+	// Following stops, and cancels the command on the agent, with the context
+	followCtx, stop := context.WithCancel(ctx)
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(hub.Stream(followCtx, "n1", &LogsPayload{Service: "mcloudd", Follow: true}, pw))
+	}()
+	line := make([]byte, 64)
+	n, _ := pr.Read(line)
+	if string(line[:n]) != "mcloudd since \n" {
+		t.Errorf("follow output: got %q", line[:n])
+	}
+	stop()
+	if _, err := io.ReadAll(pr); err != nil {
+		t.Errorf("follow: got %v after cancelling", err)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Error("the agent did not cancel the command")
+	}
+
+	for _, p := range []LogsPayload{{Service: "sshd"}, {Service: "lxd", Since: "yesterday"}, {Service: "lxd", Since: "-1h"}} {
+		if err := hub.Stream(ctx, "n1", &p, io.Discard); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%+v: got %v, want ErrInvalid", p, err)
+		}
+	}
+	if err := hub.Stream(ctx, "n9", &LogsPayload{Service: "lxd"}, io.Discard); !errors.Is(err, ErrNodeNotFound) {
+		t.Errorf("unknown node: got %v, want ErrNodeNotFound", err)
+	}
+}
@@ -0,0 +1,12 @@
+package command
+
+import (
+	"net/http"
+)
+
+// InitModule registers the agent command routes served by handler.
+func InitModule(mux *http.ServeMux, handler *Handler) {
+	mux.HandleFunc("GET /agents", handler.ListAgents)
+	mux.HandleFunc("POST /nodes/{id}/commands", handler.SendCommand)
+	mux.HandleFunc("GET /nodes/{id}/logs", handler.StreamLogs)
+}
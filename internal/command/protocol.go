@@ -0,0 +1,263 @@
+// Package command is the channel mcloudd uses to push commands to agents. Each
+// agent holds a long-lived WatchCommands stream open to mcloudd's gRPC API
+// (see proto/cluster/v1/cluster.proto); mcloudd sends a command down the
+// stream of the node it targets and the agent answers with an ack when it
+// starts and a result when it is done. Every command is tracked by an
+// operation, so acks and results end up in the operations table.
+//
+// Messages are encoded as JSON (gRPC content subtype "json") so that neither
+// side needs generated protobuf code.
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"time"
+
+	"mcloud/internal/constant"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+)
+
+// Command types an agent runs. The payload of each is documented in the
+// agent package (see agent.RunCommand).
+const (
+	TypePreflight      = "preflight"       // run the preflight checks, result: preflight.Report
+	TypeRestartService = "restart_service" // payload {"service": "lxd"}
+	TypeCollectLogs    = "collect_logs"    // payload {"service": "lxd"}
+	TypeApplyConfig    = "apply_config"    // payload {"timesync": {...}}
+	TypeTask           = "task"            // payload {"task": "ceph_status"}, one of Tasks
+	TypeRefreshSnap    = "refresh_snap"    // payload {"service": "lxd"}, result: agent.SnapRefreshResult
+)
+
+// Types lists every command type.
+var Types = []string{TypePreflight, TypeRestartService, TypeCollectLogs, TypeApplyConfig, TypeTask, TypeRefreshSnap}
+
+// Tasks are the predefined tasks a task command can run, with what each does.
+// Agents map every task to a fixed command line; there is no way to run
+// anything else.
+var Tasks = map[string]string{
+	"ceph_status": "gather the status of the Ceph cluster (ceph status)",
+	"restart_lxd": "restart the LXD snap",
+	"rotate_logs": "rotate the node's logs now (logrotate --force)",
+}
+
+// Commands that are not run through an operation, so not in Types (see
+// Hub.Stream).
+const (
+	TypeLogs   = "logs"   // payload LogsPayload, output: the log lines
+	TypeCancel = "cancel" // cancels the running command with the same ID
+)
+
+// LogServices are the services whose logs a logs command tails, each from its
+// systemd unit's journal.
+var LogServices = []string{"lxd", "microceph", "microovn", "mcloudd", "agent"}
+
+// DefaultLogsSince applies to logs commands without a since.
+const DefaultLogsSince = time.Hour
+
+// LogsPayload is the payload of a logs command.
+//
+// Example JSON:
+//   {"service": "lxd", "since": "1h", "follow": true}
+type LogsPayload struct {
+	Service string `json:"service"`
+	Since   string `json:"since,omitempty"`  // how far back to start, e.g. 30m (default 1h)
+	Follow  bool   `json:"follow,omitempty"` // keep streaming new lines until cancelled
+}
+
+// Validate checks the service and since of a logs command.
+func (p *LogsPayload) Validate() error {
+	if !slices.Contains(LogServices, p.Service) {
+		return fmt.Errorf("%w: service must be one of %v", ErrInvalid, LogServices)
+	}
+	if p.Since == "" {
+		return nil
+	}
+	if d, err := time.ParseDuration(p.Since); err != nil || d <= 0 {
+		return fmt.Errorf("%w: since must be a positive duration such as 30m or 2h", ErrInvalid)
+	}
+	return nil
+}
+
+// TaskPayload is the payload of a task command.
+//
+// Example JSON:
+//   {"task": "ceph_status"}
+type TaskPayload struct {
+	Task string `json:"task"`
+}
+
+// AgentMessage types.
+const (
+	MessageHello  = "hello"
+	MessageAck    = "ack"
+	MessageResult = "result"
+	MessageOutput = "output" // a chunk of a command's output, before its result
+)
+
+// AgentMessage is sent by an agent: a hello with its node name and version
+// when the stream opens, then an ack, any output and a result for every
+// command it receives.
+//
+// Example JSON:
+//   {"type": "result", "command_id": "7f1c...", "success": true, "result": "{\"changed\":true}"}
+type AgentMessage struct {
+	Type      string `json:"type"`
+	NodeName  string `json:"node_name,omitempty"`
+	Version   string `json:"version,omitempty"` // of mcloud-agent, in the hello
+	CommandID string `json:"command_id,omitempty"`
+	Success   bool   `json:"success,omitempty"`
+	Result    string `json:"result,omitempty"` // JSON output of the command
+	Error     string `json:"error,omitempty"`
+	Data      string `json:"data,omitempty"` // output
+}
+
+// Command is pushed by mcloudd to an agent.
+//
+// Example JSON:
+//   {"id": "7f1c...", "type": "restart_service", "payload": "{\"service\":\"lxd\"}"}
+type Command struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Payload string `json:"payload,omitempty"`
+}
+
+// codecName is the gRPC content subtype of the stream.
+const codecName = "json"
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+func (jsonCodec) Name() string                       { return codecName }
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
+
+// watcher is implemented by Hub; grpc.ServiceDesc needs an interface type.
+type watcher interface {
+	watch(stream grpc.ServerStream) error
+}
+
+const watchMethod = "/mcloud.cluster.v1.AgentService/WatchCommands"
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: "mcloud.cluster.v1.AgentService",
+	HandlerType: (*watcher)(nil),
+	Streams: []grpc.StreamDesc{{
+		StreamName:    "WatchCommands",
+		Handler:       func(srv any, stream grpc.ServerStream) error { return srv.(watcher).watch(stream) },
+		ServerStreams: true,
+		ClientStreams: true,
+	}},
+	Metadata: "proto/cluster/v1/cluster.proto",
+}
+
+// Runner runs a command on the agent and returns its result, which is sent
+// back as JSON. What it writes to out is streamed back as it is written.
+type Runner func(ctx context.Context, cmd *Command, out io.Writer) (any, error)
+
+// outputChunk is the most output one output message carries.
+const outputChunk = 32 << 10
+
+// outputWriter sends what is written to it as output messages of a command.
+type outputWriter struct {
+	id   string
+	send func(*AgentMessage) error
+}
+
+func (w *outputWriter) Write(p []byte) (int, error) {
+	for written := 0; written < len(p); {
+		n := min(len(p)-written, outputChunk)
+		if err := w.send(&AgentMessage{Type: MessageOutput, CommandID: w.id, Data: string(p[written : written+n])}); err != nil {
+			return written, err
+		}
+		written += n
+	}
+	return len(p), nil
+}
+
+// Watch opens the WatchCommands stream as node and runs every command mcloudd
+// pushes with run, each in its own goroutine: it acks the command, runs it and
+// sends the result. A cancel command cancels the running command with its ID.
+// Watch returns when the stream breaks or ctx is cancelled; commands still
+// running are cancelled with it.
+func Watch(ctx context.Context, conn grpc.ClientConnInterface, node string, run Runner) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	ctx, cancel := context.WithCancel(ctx) // cancelled before waiting for the commands
+	defer cancel()
+
+	stream, err := conn.NewStream(ctx, &serviceDesc.Streams[0], watchMethod, grpc.CallContentSubtype(codecName))
+	if err != nil {
+		return err
+	}
+
+	var mu sync.Mutex // SendMsg must not be called concurrently
+	send := func(m *AgentMessage) error {
+		mu.Lock()
+		defer mu.Unlock()
+		return stream.SendMsg(m)
+	}
+	if err := send(&AgentMessage{Type: MessageHello, NodeName: node, Version: constant.AppVersion}); err != nil {
+		return err
+	}
+
+	var runningMu sync.Mutex
+	running := map[string]context.CancelFunc{}
+	for {
+		var cmd Command
+		if err := stream.RecvMsg(&cmd); err != nil {
+			return err
+		}
+		if cmd.Type == TypeCancel {
+			runningMu.Lock()
+			if cancelCmd, ok := running[cmd.ID]; ok {
+				cancelCmd()
+			}
+			runningMu.Unlock()
+			continue
+		}
+		if err := send(&AgentMessage{Type: MessageAck, CommandID: cmd.ID}); err != nil {
+			return err
+		}
+
+		cmdCtx, cancelCmd := context.WithCancel(ctx)
+		runningMu.Lock()
+		running[cmd.ID] = cancelCmd
+		runningMu.Unlock()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				runningMu.Lock()
+				delete(running, cmd.ID)
+				runningMu.Unlock()
+				cancelCmd()
+			}()
+			reply := &AgentMessage{Type: MessageResult, CommandID: cmd.ID, Success: true}
+			result, err := run(cmdCtx, &cmd, &outputWriter{id: cmd.ID, send: send})
+			if err == nil && result != nil {
+				var data []byte
+				if data, err = json.Marshal(result); err != nil {
+					err = fmt.Errorf("failed to encode result: %w", err)
+				}
+				reply.Result = string(data)
+			}
+			if err != nil {
+				reply.Success, reply.Result, reply.Error = false, "", err.Error()
+			}
+			if err := send(reply); err != nil && ctx.Err() == nil {
+				log.Warn("Failed to report result of command %s: %v", cmd.ID, err)
+			}
+		}()
+	}
+}
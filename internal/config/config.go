@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 
 	"gopkg.in/yaml.v3"
 )
@@ -11,21 +12,172 @@ type Manager struct {
 	HttpPort int    `yaml:"http_port"`
 	GrpcHost string `yaml:"grpc_host"`
 	GrpcPort int    `yaml:"grpc_port"`
+
+	AdvertiseInterface string `yaml:"advertise_interface"` // interface whose address is advertised to other nodes
+	AdvertiseAddress   string `yaml:"advertise_address"`   // address advertised to other nodes; overrides advertise_interface
+
+	OperationTimeoutSeconds int `yaml:"operation_timeout_seconds"` // background operations are cancelled after this long (default 1800)
 }
 
 type Agent struct {
 	ManagerURL string `yaml:"manager_url"`
+	Port       int    `yaml:"port"`
+
+	DrainTimeoutSeconds int `yaml:"drain_timeout_seconds"` // how long shutdown waits for workloads to move off the node
 }
 
 type Database struct {
 	DBPath string `yaml:"db_path"`
+
+	Partitions Partitions `yaml:"partitions"`
+}
+
+// Partitions controls the monthly partitions of the events and audit log tables.
+type Partitions struct {
+	KeepMonths int    `yaml:"keep_months"` // finished months kept queryable in the database (default 12)
+	ArchiveDir string `yaml:"archive_dir"` // older months are written here as <table>-YYYY-MM.db, then dropped (default: archive/ next to db_path)
 }
 
 type Security struct {
+	CertDir        string `yaml:"cert_dir"` // directory holding the files below
 	CACertPath     string `yaml:"ca_cert_path"`
 	CAKeyPath      string `yaml:"ca_key_path"`
+	// The cluster intermediate CA, signed by the root above; it issues the
+	// server, client and node certificates. Unset on clusters made before it,
+	// whose root issues them directly
+	IntermediateCertPath string `yaml:"intermediate_cert_path"`
+	IntermediateKeyPath  string `yaml:"intermediate_key_path"`
 	ServerCertPath string `yaml:"server_cert_path"`
 	ServerKeyPath  string `yaml:"server_key_path"`
+	ClientCertPath string `yaml:"client_cert_path"`
+	ClientKeyPath  string `yaml:"client_key_path"`
+	NodeCertPath   string `yaml:"node_cert_path"` // this node's certificate (CN = hostname), presented by mcloud-agent to mcloudd
+	NodeKeyPath    string `yaml:"node_key_path"`
+	SecretsKeyPath string `yaml:"secrets_key_path"`
+
+	ServerNames []string `yaml:"server_names"` // FQDNs clients reach mcloudd by, added to the server certificate with the hostname
+}
+
+type TimeSync struct {
+	Managed     bool   `yaml:"managed"`       // configure chrony on all nodes
+	Upstream    string `yaml:"upstream"`      // NTP server for all nodes; empty = members follow the leader
+	MaxOffsetMs int    `yaml:"max_offset_ms"` // clock skew above this raises an event (default 500)
+}
+
+type Scheduler struct {
+	WeightBenchmark      bool `yaml:"weight_benchmark"`       // place unpinned workloads on the fastest benchmarked node
+	PreemptMemoryPercent int  `yaml:"preempt_memory_percent"` // stop low-priority workloads at this node memory use (0 = 95, -1 = never)
+}
+
+type Log struct {
+	Level   string            `yaml:"level"`   // debug, info, warn or error (MCLOUD_LOG_LEVEL overrides)
+	Modules map[string]string `yaml:"modules"` // per-module overrides, e.g. grpc: debug
+	File    LogFile           `yaml:"file"`
+}
+
+type LogFile struct {
+	Path       string `yaml:"path"`         // e.g. /var/log/mcloud/mcloudd.log; empty = stdout/stderr only
+	MaxSizeMB  int    `yaml:"max_size_mb"`  // rotate at this size (default 50)
+	MaxAgeDays int    `yaml:"max_age_days"` // also rotate after this many days (0 = size only)
+	MaxBackups int    `yaml:"max_backups"`  // rotated files to keep (default 5)
+}
+
+type DNS struct {
+	Provider string `yaml:"provider"` // cloudflare or rfc2136; empty = no DNS records for floating IPs
+	Zone     string `yaml:"zone"`     // records are created as <name>.<zone>, e.g. apps.example.com
+	TTL      int    `yaml:"ttl"`      // record TTL in seconds (default 300)
+
+	Cloudflare DNSCloudflare `yaml:"cloudflare"`
+	RFC2136    DNSRFC2136    `yaml:"rfc2136"`
+}
+
+type DNSCloudflare struct {
+	ZoneID       string `yaml:"zone_id"`
+	APITokenPath string `yaml:"api_token_path"` // file holding an API token with DNS edit permission
+}
+
+type DNSRFC2136 struct {
+	Server  string `yaml:"server"`   // authoritative server accepting dynamic updates, e.g. 10.0.0.53
+	KeyFile string `yaml:"key_file"` // TSIG key file passed to nsupdate -k
+}
+
+// ClusterDNS is the DNS server mcloudd runs for workloads. It answers
+// <workload>.<project>.<domain> with the workload's addresses and forwards
+// every other name to the upstream resolvers. Queries from other clients are
+// refused, so it is not an open resolver.
+type ClusterDNS struct {
+	Enabled   bool     `yaml:"enabled"`
+	Domain    string   `yaml:"domain"`    // default mcloud.local
+	Listen    string   `yaml:"listen"`    // UDP and TCP address (default <manager.http_host>:53)
+	Upstreams []string `yaml:"upstreams"` // resolvers for other names, e.g. [1.1.1.1]; default the host's
+	TTL       int      `yaml:"ttl"`       // of the answers for workloads, in seconds (default 30)
+	Clients   []string `yaml:"clients"`   // CIDRs allowed to query, e.g. [10.10.0.0/24]; default the OVN network's subnets and uplink address, and loopback
+}
+
+// Network is how workloads are reached from outside the cluster.
+type Network struct {
+	OVNNetwork   string   `yaml:"ovn_network"`   // LXD OVN network of the workloads, where forwards are created (default default)
+	ExternalPool []string `yaml:"external_pool"` // CIDRs floating IPs are allocated from, e.g. [203.0.113.16/28]; empty = floating IPs disabled
+}
+
+type Hooks struct {
+	// Commands an on-start hook may run inside an instance, by name, e.g.
+	// warm-cache: ["/usr/local/bin/warm-cache", "--all"]. Hooks can only name one.
+	Commands       map[string][]string `yaml:"commands"`
+	TimeoutSeconds int                 `yaml:"timeout_seconds"` // per hook run (default 30)
+
+	// Webhook delivery, per endpoint (scheme and host of the URL)
+	Concurrency int `yaml:"concurrency"`  // deliveries in flight (default 2)
+	QueueSize   int `yaml:"queue_size"`   // deliveries waiting before new ones are dead-lettered (default 100)
+	MaxAttempts int `yaml:"max_attempts"` // tries per delivery, with exponential backoff (default 5)
+}
+
+type Standby struct {
+	Node            string `yaml:"node"`             // hostname of the node keeping a warm copy of the database; empty = disabled
+	IntervalSeconds int    `yaml:"interval_seconds"` // how often a snapshot is shipped (default 10)
+	Path            string `yaml:"path"`             // where the standby's agent keeps the copy (default /var/lib/mcloud/standby/mcloud.db)
+}
+
+type Snaps struct {
+	// Channels pins the snap channel each dependency is installed from by
+	// init --install-deps, e.g. lxd: 5.21/stable
+	Channels map[string]string `yaml:"channels"`
+}
+
+// Storage is the cluster's Ceph storage profile: the block devices each node
+// adds as OSDs at init and join.
+type Storage struct {
+	Disks      []string            `yaml:"disks"`        // on every node, e.g. [/dev/sdb, /dev/sdc]; empty = every available disk
+	Nodes      map[string][]string `yaml:"nodes"`        // per-node disks by hostname, overriding disks
+	Wipe       bool                `yaml:"wipe"`         // wipe disks before adding them
+	Encrypt    bool                `yaml:"encrypt"`      // encrypt the OSDs
+	LoopCount  int                 `yaml:"loop_count"`   // loop-file OSDs when a node has no disk (test clusters only)
+	LoopSizeGB int                 `yaml:"loop_size_gb"` // size of each loop-file OSD (default 4)
+}
+
+type Audit struct {
+	ExecRecording ExecRecording `yaml:"exec_recording"`
+}
+
+type ExecRecording struct {
+	Enabled       bool `yaml:"enabled"`        // store transcripts of exec sessions (session metadata is always kept)
+	MaxBytes      int  `yaml:"max_bytes"`      // per transcript; later output is dropped (default 1048576)
+	RetentionDays int  `yaml:"retention_days"` // sessions and transcripts are deleted after this many days (default 90)
+}
+
+// Reconcile configures the drift reconciler. Each resource kind has a policy:
+// report (the default) only records drift as events, heal also repairs it.
+type Reconcile struct {
+	IntervalSeconds int    `yaml:"interval_seconds"` // how often LXD, OVN and Ceph are compared with the database (default 300)
+	Workloads       string `yaml:"workloads"`        // heal = start or stop instances to the recorded power state
+	Volumes         string `yaml:"volumes"`          // heal = recreate missing volumes (empty) and restore attachments
+	Network         string `yaml:"network"`          // heal = recreate missing or retargeted floating IP forwards
+}
+
+// Upgrade is where `mcloudctl upgrade` gets release binaries from.
+type Upgrade struct {
+	ReleaseURL    string `yaml:"release_url"`     // <release_url>/<version>/ holds mcloudd-linux-<arch>, mcloud-agent-linux-<arch> and SHA256SUMS
+	PublicKeyPath string `yaml:"public_key_path"` // ed25519 public key (PEM); SHA256SUMS.sig must be its signature of SHA256SUMS. Downloads without it need --insecure-skip-signature
 }
 
 type Config struct {
@@ -38,14 +190,84 @@ type Config struct {
 	StatePath  string `yaml:"state_path"`
 
 	Security Security `yaml:"security"`
+
+	TimeSync TimeSync `yaml:"time_sync"`
+
+	Scheduler Scheduler `yaml:"scheduler"`
+
+	Log Log `yaml:"log"`
+
+	DNS DNS `yaml:"dns"`
+
+	ClusterDNS ClusterDNS `yaml:"cluster_dns"`
+
+	Network Network `yaml:"network"`
+
+	Hooks Hooks `yaml:"hooks"`
+
+	Snaps Snaps `yaml:"snaps"`
+
+	Storage Storage `yaml:"storage"`
+
+	Standby Standby `yaml:"standby"`
+
+	Audit Audit `yaml:"audit"`
+
+	Reconcile Reconcile `yaml:"reconcile"`
+
+	Upgrade Upgrade `yaml:"upgrade"`
+
+	Alerts Alerts `yaml:"alerts"`
+
+	Tracing Tracing `yaml:"tracing"`
 }
 
 const (
 	DefaultConfigPath = "/etc/mcloud/config.yaml"
 )
 
+// path is the config file read by Load and written by SaveConfig.
+var path = DefaultConfigPath
+
+// SetPath changes the config file used by Load and SaveConfig, e.g. from a --config flag.
+func SetPath(p string) {
+	if p != "" {
+		path = p
+	}
+}
+
+// Path returns the config file in use.
+func Path() string {
+	return path
+}
+
+// Alerts configures alerting: the rules are evaluated every minute, and alerts
+// that start or stop firing are mailed to smtp.to.
+type Alerts struct {
+	Rules           []string `yaml:"rules"`            // e.g. "node offline > 5m", "ceph HEALTH_ERR", "ca expires < 30d" (the defaults), "cert expires < 14d"
+	CooldownMinutes int      `yaml:"cooldown_minutes"` // an alert is mailed at most this often while it keeps firing or flapping (default 60)
+	SMTP            SMTP     `yaml:"smtp"`
+}
+
+type SMTP struct {
+	Host         string   `yaml:"host"`          // empty = alerts are recorded but not mailed
+	Port         int      `yaml:"port"`          // default 587; STARTTLS is used when the server offers it
+	Username     string   `yaml:"username"`      // empty = no authentication
+	PasswordPath string   `yaml:"password_path"` // file holding the password of username
+	From         string   `yaml:"from"`          // e.g. mcloud@example.com
+	To           []string `yaml:"to"`
+}
+
+// Tracing exports OpenTelemetry traces of mcloudd, mcloud-agent and mcloudctl
+// join over OTLP/gRPC. The standard OTEL_EXPORTER_OTLP_* variables also apply.
+type Tracing struct {
+	Endpoint    string  `yaml:"endpoint"`     // OTLP collector, e.g. otel-collector:4317; empty = OTEL_EXPORTER_OTLP_ENDPOINT, else tracing is off
+	Insecure    bool    `yaml:"insecure"`     // send without TLS, e.g. to a collector on localhost
+	SampleRatio float64 `yaml:"sample_ratio"` // fraction of new traces recorded (default 1); traces started by a caller follow its decision
+}
+
 func Load() (*Config, error) {
-	data, err := os.ReadFile(DefaultConfigPath)
+	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
@@ -68,9 +290,12 @@ func SaveConfig(cfg *Config) error {
 		return err
 	}
 
-	if err := os.WriteFile(DefaultConfigPath, data, 0644); err != nil {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
 		return err
 	}
 
 	return nil
-}
\ No newline at end of file
+}
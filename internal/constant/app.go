@@ -1,21 +1,52 @@
 package constant
 
+// Build information of the running binary, set by release builds with
+//   -ldflags "-X mcloud/internal/constant.AppVersion=0.2.0
+//             -X mcloud/internal/constant.AppCommit=$(git rev-parse --short HEAD)
+//             -X mcloud/internal/constant.AppBuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+var (
+	AppVersion   = "0.1.0"
+	AppCommit    = "unknown"
+	AppBuildDate = "unknown"
+)
+
 const (
 	// AppName is the name of the application
 	AppName = "mcloud"
 	AppServerName = "mcloud-server"
 
-	// AppVersion is the current version of the application
-	AppVersion = "0.1.0"
-
 	// OrganizationName is the name of the organization
 	OrganizationName = "MCloud"
 
 	// RootCACommonName is the common name for the root CA certificate
 	RootCACommonName = "MCloud Cluster CA"
 
+	// IntermediateCACommonName is the common name of the cluster intermediate CA,
+	// which the root CA signs and which issues server, client and node certificates
+	IntermediateCACommonName = "MCloud Cluster Intermediate CA"
+
+	// AdminClientCommonName is the common name of the client certificate issued to mcloudctl at init
+	AdminClientCommonName = "mcloud-admin"
+
+	// DefaultDataDir holds the state file, database, certificates and image downloads
+	DefaultDataDir = "/var/lib/mcloud"
+
 	DefaultConfigPath = "/etc/mcloud/config.yaml"
 	DefaultStatePath  = "/var/lib/mcloud/state.yaml"
+	DefaultDBPath     = "/var/lib/mcloud/mcloud.db"
+
+	// DefaultRejoinBundlePath is the node's encrypted re-join bundle; it lives next
+	// to the config so it survives losing /var/lib/mcloud
+	DefaultRejoinBundlePath = "/etc/mcloud/rejoin.bundle"
+
+	// DefaultCertDir holds the cluster CA, server and client certificates
+	DefaultCertDir = "/var/lib/mcloud/certs"
+
+	// DefaultLogPath is the mcloudd log file written next to journald output
+	DefaultLogPath = "/var/log/mcloud/mcloudd.log"
+
+	// DefaultImageDownloadDir holds partially downloaded images until they are imported into LXD
+	DefaultImageDownloadDir = "/var/lib/mcloud/images"
 )
 
 type NodeRole string
@@ -24,8 +55,3 @@ const (
 	RoleLeader NodeRole = "leader"
 	RoleMember NodeRole = "member"
 )
-
-const (
-	// DefaultCephDisk is the default disk for mcloud
-	DefaultCephDisk = "/dev/sdb"
-)
\ No newline at end of file
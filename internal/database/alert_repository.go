@@ -0,0 +1,134 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Alert statuses.
+const (
+	AlertFiring   = "firing"
+	AlertResolved = "resolved"
+)
+
+// Alert is one stretch of time an alert rule fired for a subject (see migration 035).
+//
+// Example JSON:
+//   {"id": 12, "rule": "node offline > 5m", "subject": "node-2", "severity": "critical",
+//    "message": "Node node-2 has been offline for 7m", "status": "firing", "notifications": 1,
+//    "notified_at": "2026-10-16T09:07:00Z", "fired_at": "2026-10-16T09:07:00Z"}
+type Alert struct {
+	ID            int64      `json:"id"`
+	Rule          string     `json:"rule"`
+	Subject       string     `json:"subject"`
+	Severity      string     `json:"severity"`
+	Message       string     `json:"message"`
+	Status        string     `json:"status"`
+	Notifications int        `json:"notifications"` // mails sent about it
+	NotifiedAt    *time.Time `json:"notified_at,omitempty"`
+	FiredAt       time.Time  `json:"fired_at"`
+	ResolvedAt    *time.Time `json:"resolved_at,omitempty"`
+}
+
+type AlertRepository struct {
+	exec sqlExecutor
+}
+
+func NewAlertRepository(db *sql.DB) *AlertRepository {
+	return &AlertRepository{exec: executor(db)}
+}
+
+const alertColumns = `id, rule, subject, severity, message, status, notifications, notified_at, fired_at, resolved_at`
+
+func scanAlert(row rowScanner, a *Alert) error {
+	return row.Scan(&a.ID, &a.Rule, &a.Subject, &a.Severity, &a.Message, &a.Status, &a.Notifications, &a.NotifiedAt, &a.FiredAt, &a.ResolvedAt)
+}
+
+// Create records a firing alert and sets its ID and FiredAt.
+func (r *AlertRepository) Create(ctx context.Context, a *Alert) error {
+	a.Status = AlertFiring
+	a.FiredAt = time.Now().UTC()
+	res, err := r.exec.ExecContext(ctx, `
+INSERT INTO alerts (rule, subject, severity, message, status, fired_at)
+VALUES (?, ?, ?, ?, ?, ?)
+`, a.Rule, a.Subject, a.Severity, a.Message, a.Status, a.FiredAt)
+	if err != nil {
+		return err
+	}
+	a.ID, err = res.LastInsertId()
+	return err
+}
+
+// ListFiring returns the alerts that are firing, oldest first.
+func (r *AlertRepository) ListFiring(ctx context.Context) ([]Alert, error) {
+	return collect(ctx, r.exec, `SELECT `+alertColumns+` FROM alerts WHERE status = ? ORDER BY fired_at, id`, []any{AlertFiring}, scanAlert)
+}
+
+// List returns alerts, newest first by default.
+//
+// Example Input:
+//   List(ctx, ListOptions{Limit: 50, Filter: map[string]string{"status": "firing"}})
+func (r *AlertRepository) List(ctx context.Context, opts ListOptions) ([]Alert, error) {
+	query, args, err := alertList.build(opts, nil)
+	if err != nil {
+		return nil, err
+	}
+	return collect(ctx, r.exec, query, args, scanAlert)
+}
+
+var alertList = listQuery{
+	selectSQL:   `SELECT ` + alertColumns + ` FROM alerts`,
+	defaultSort: "fired_at DESC, id DESC",
+	sortable:    map[string]string{"fired_at": "fired_at", "severity": "severity", "rule": "rule"},
+	filterable:  map[string]string{"status": "status", "rule": "rule", "subject": "subject", "severity": "severity"},
+}
+
+// SetMessage updates the message of a firing alert, e.g. a growing offline time.
+func (r *AlertRepository) SetMessage(ctx context.Context, id int64, message string) error {
+	_, err := r.exec.ExecContext(ctx, `UPDATE alerts SET message = ? WHERE id = ?`, message, id)
+	return err
+}
+
+// Resolve marks a firing alert resolved.
+func (r *AlertRepository) Resolve(ctx context.Context, id int64) error {
+	_, err := r.exec.ExecContext(ctx, `
+UPDATE alerts SET status = ?, resolved_at = ?
+WHERE id = ? AND status = ?
+`, AlertResolved, time.Now().UTC(), id, AlertFiring)
+	return err
+}
+
+// MarkNotified records a mail sent about an alert at at.
+func (r *AlertRepository) MarkNotified(ctx context.Context, id int64, at time.Time) error {
+	_, err := r.exec.ExecContext(ctx, `
+UPDATE alerts SET notifications = notifications + 1, notified_at = ?
+WHERE id = ?
+`, at.UTC(), id)
+	return err
+}
+
+// LastNotifiedAt returns when a mail was last sent about any alert of rule and
+// subject, or nil if none ever was.
+func (r *AlertRepository) LastNotifiedAt(ctx context.Context, rule string, subject string) (*time.Time, error) {
+	var at time.Time
+	err := r.exec.QueryRowContext(ctx, `
+SELECT notified_at FROM alerts
+WHERE rule = ? AND subject = ? AND notified_at IS NOT NULL
+ORDER BY notified_at DESC LIMIT 1
+`, rule, subject).Scan(&at)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &at, nil
+}
+
+// DeleteResolvedBefore removes alerts resolved before cutoff.
+func (r *AlertRepository) DeleteResolvedBefore(ctx context.Context, cutoff time.Time) error {
+	_, err := r.exec.ExecContext(ctx, `DELETE FROM alerts WHERE status = ? AND resolved_at < ?`, AlertResolved, cutoff.UTC())
+	return err
+}
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type AuditEntry struct {
+	ID          int64
+	Actor       string
+	Action      string
+	Target      string
+	PayloadHash *string
+	StatusCode  int
+	Result      string
+	CreatedAt   time.Time
+}
+
+type AuditRepository struct {
+	exec sqlExecutor
+}
+
+func NewAuditRepository(db *sql.DB) *AuditRepository {
+	return &AuditRepository{exec: executor(db)}
+}
+
+func (r *AuditRepository) Create(ctx context.Context, a *AuditEntry) error {
+	_, err := r.exec.ExecContext(ctx, `
+INSERT INTO audit_log (actor, action, target, payload_hash, status_code, result, created_at)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+`, a.Actor, a.Action, a.Target, a.PayloadHash, a.StatusCode, a.Result, time.Now().UTC())
+	return err
+}
+
+// ListSince returns audit entries created at or after since, newest first,
+// including the monthly partitions still in the database (see partition.go).
+func (r *AuditRepository) ListSince(ctx context.Context, since time.Time, limit int) ([]AuditEntry, error) {
+	var items []AuditEntry
+	err := r.EachSince(ctx, since, limit, func(a *AuditEntry) error {
+		items = append(items, *a)
+		return nil
+	})
+	return items, err
+}
+
+// EachSince is ListSince calling fn for every entry as it is read from the cursor.
+func (r *AuditRepository) EachSince(ctx context.Context, since time.Time, limit int, fn func(*AuditEntry) error) error {
+	const columns = `id, actor, action, target, payload_hash, status_code, result, created_at`
+	src, err := partitionSource(ctx, r.exec, "audit_log", columns, since, time.Time{})
+	if err != nil {
+		return err
+	}
+	rows, err := r.exec.QueryContext(ctx, `
+SELECT `+columns+`
+FROM `+src+` WHERE created_at >= ?
+ORDER BY id DESC LIMIT ?
+`, since.UTC(), limit)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a AuditEntry
+		if err := rows.Scan(
+			&a.ID, &a.Actor, &a.Action, &a.Target,
+			&a.PayloadHash, &a.StatusCode, &a.Result, &a.CreatedAt,
+		); err != nil {
+			return err
+		}
+		if err := fn(&a); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
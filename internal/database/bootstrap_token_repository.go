@@ -22,7 +22,7 @@ type BootstrapTokenRepository struct {
 }
 
 func NewBootstrapTokenRepository(db *sql.DB) *BootstrapTokenRepository {
-	return &BootstrapTokenRepository{exec: db}
+	return &BootstrapTokenRepository{exec: executor(db)}
 }
 
 func NewBootstrapTokenRepositoryTx(tx *sql.Tx) *BootstrapTokenRepository {
@@ -48,47 +48,48 @@ func (r *BootstrapTokenRepository) Delete(ctx context.Context, token string) err
 	return err
 }
 
-func (r *BootstrapTokenRepository) Get(ctx context.Context, token string) (*BootstrapToken, error) {
-	row := r.exec.QueryRowContext(ctx, `SELECT token, cluster_id, expires_at, used,
-	created_at, create_user_id, updated_at, update_user_id
-	FROM bootstrap_tokens WHERE token = ?
-	`, token)
+func (r *BootstrapTokenRepository) DeleteExpired(ctx context.Context, now time.Time) error {
+	_, err := r.exec.ExecContext(ctx, `DELETE FROM bootstrap_tokens WHERE expires_at < ?`, now)
+	return err
+}
 
+func (r *BootstrapTokenRepository) Get(ctx context.Context, token string) (*BootstrapToken, error) {
 	var t BootstrapToken
-	var usedInt int
-	if err := row.Scan(
-		&t.Token, &t.ClusterID, &t.ExpiresAt, &usedInt,
-		&t.CreatedAt, &t.CreateUserID, &t.UpdatedAt, &t.UpdateUserID,
-	); err != nil {
+	if err := scanBootstrapToken(r.exec.QueryRowContext(ctx, bootstrapTokenList.selectSQL+` WHERE token = ?`, token), &t); err != nil {
 		return nil, err
 	}
-	t.Used = usedInt == 1
 	return &t, nil
 }
 
-func (r *BootstrapTokenRepository) ListByCluster(ctx context.Context, clusterID string) ([]BootstrapToken, error) {
-	rows, err := r.exec.QueryContext(ctx, `
-		SELECT token, cluster_id, expires_at, used,
-		created_at, create_user_id, updated_at, update_user_id
-		FROM bootstrap_tokens WHERE cluster_id = ?
-		`, clusterID)
+// ListByCluster returns the join tokens of a cluster, soonest to expire first.
+//
+// Example Input:
+//   ListByCluster(ctx, "c1", ListOptions{Filter: map[string]string{"used": "0"}})
+func (r *BootstrapTokenRepository) ListByCluster(ctx context.Context, clusterID string, opts ListOptions) ([]BootstrapToken, error) {
+	query, args, err := bootstrapTokenList.build(opts, []string{"cluster_id = ?"}, clusterID)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	return collect(ctx, r.exec, query, args, scanBootstrapToken)
+}
+
+var bootstrapTokenList = listQuery{
+	selectSQL: `SELECT token, cluster_id, expires_at, used,
+created_at, create_user_id, updated_at, update_user_id
+FROM bootstrap_tokens`,
+	defaultSort: "expires_at, token",
+	sortable:    map[string]string{"expires_at": "expires_at", "created_at": "created_at"},
+	filterable:  map[string]string{"used": "used"},
+}
 
-	var items []BootstrapToken
-	for rows.Next() {
-		var t BootstrapToken
-		var usedInt int
-		if err := rows.Scan(
-			&t.Token, &t.ClusterID, &t.ExpiresAt, &usedInt,
-			&t.CreatedAt, &t.CreateUserID, &t.UpdatedAt, &t.UpdateUserID,
-		); err != nil {
-			return nil, err
-		}
-		t.Used = usedInt == 1
-		items = append(items, t)
+func scanBootstrapToken(row rowScanner, t *BootstrapToken) error {
+	var usedInt int
+	if err := row.Scan(
+		&t.Token, &t.ClusterID, &t.ExpiresAt, &usedInt,
+		&t.CreatedAt, &t.CreateUserID, &t.UpdatedAt, &t.UpdateUserID,
+	); err != nil {
+		return err
 	}
-	return items, nil
+	t.Used = usedInt == 1
+	return nil
 }
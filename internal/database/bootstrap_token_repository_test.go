@@ -0,0 +1,67 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBootstrapTokenRepository(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	c := testCluster(t, db, "prod")
+	repo := NewBootstrapTokenRepository(db)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	for _, tok := range []*BootstrapToken{
+		{Token: "later", ClusterID: c.ID, ExpiresAt: now.Add(2 * time.Hour)},
+		{Token: "soon", ClusterID: c.ID, ExpiresAt: now.Add(time.Hour)},
+		{Token: "expired", ClusterID: c.ID, ExpiresAt: now.Add(-time.Hour)},
+	} {
+		if err := repo.Create(ctx, tok); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := repo.Get(ctx, "soon")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ClusterID != c.ID || got.Used || !got.ExpiresAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("Get(soon) = %+v", got)
+	}
+	if err := repo.MarkUsed(ctx, "soon"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := repo.Get(ctx, "soon"); err != nil || !got.Used {
+		t.Errorf("Get(soon) after MarkUsed = %+v, %v", got, err)
+	}
+
+	// Soonest to expire first; the used filter compares the stored 0/1
+	tokens, err := repo.ListByCluster(ctx, c.ID, ListOptions{Filter: map[string]string{"used": "0"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != 2 || tokens[0].Token != "expired" || tokens[1].Token != "later" {
+		t.Errorf("ListByCluster(used=0) = %+v", tokens)
+	}
+
+	if err := repo.DeleteExpired(ctx, now); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Get(ctx, "expired"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Get(expired) after DeleteExpired error = %v, want sql.ErrNoRows", err)
+	}
+	if err := repo.Delete(ctx, "later"); err != nil {
+		t.Fatal(err)
+	}
+	tokens, err = repo.ListByCluster(ctx, c.ID, ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != 1 || tokens[0].Token != "soon" {
+		t.Errorf("ListByCluster after deletes = %+v", tokens)
+	}
+}
@@ -6,11 +6,19 @@ import (
 	"time"
 )
 
+// Kinds of CertificateAuthority.
+const (
+	CAKindRoot         = "root"         // self-signed; its key is not stored
+	CAKindIntermediate = "intermediate" // signed by the root; issues the cluster's certificates; its key is not stored
+)
+
 type CertificateAuthority struct {
 	ID           string
 	ClusterID    string
+	Kind         string
 	CertPEM      string
 	KeyPEM       string
+	RetiredAt    *time.Time // set when a rotation replaced the intermediate
 	CreatedAt    time.Time
 	CreateUserID *string
 	UpdatedAt    time.Time
@@ -22,7 +30,7 @@ type CertificateAuthorityRepository struct {
 }
 
 func NewCertificateAuthorityRepository(db *sql.DB) *CertificateAuthorityRepository {
-	return &CertificateAuthorityRepository{exec: db}
+	return &CertificateAuthorityRepository{exec: executor(db)}
 }
 
 func NewCertificateAuthorityRepositoryTx(tx *sql.Tx) *CertificateAuthorityRepository {
@@ -31,29 +39,60 @@ func NewCertificateAuthorityRepositoryTx(tx *sql.Tx) *CertificateAuthorityReposi
 
 func (r *CertificateAuthorityRepository) Create(ctx context.Context, ca *CertificateAuthority) error {
 	_, err := r.exec.ExecContext(ctx, `
-INSERT INTO certificate_authorities (id, cluster_id, cert_pem, key_pem, create_user_id)
-VALUES (?, ?, ?, ?, ?)
-`, ca.ID, ca.ClusterID, ca.CertPEM, ca.KeyPEM, ca.CreateUserID)
+INSERT INTO certificate_authorities (id, cluster_id, kind, cert_pem, key_pem, create_user_id)
+VALUES (?, ?, ?, ?, ?, ?)
+`, ca.ID, ca.ClusterID, ca.Kind, ca.CertPEM, ca.KeyPEM, ca.CreateUserID)
 	return err
 }
 
-func (r *CertificateAuthorityRepository) GetByCluster(ctx context.Context, clusterID string) (*CertificateAuthority, error) {
-	row := r.exec.QueryRowContext(ctx, `
-SELECT id, cluster_id, cert_pem, key_pem,
+const caSelect = `SELECT id, cluster_id, kind, cert_pem, key_pem, retired_at,
 created_at, create_user_id, updated_at, update_user_id
-FROM certificate_authorities WHERE cluster_id = ?
-`, clusterID)
+FROM certificate_authorities`
 
-	var ca CertificateAuthority
-	if err := row.Scan(
-		&ca.ID, &ca.ClusterID, &ca.CertPEM, &ca.KeyPEM,
+func scanCA(row rowScanner, ca *CertificateAuthority) error {
+	return row.Scan(
+		&ca.ID, &ca.ClusterID, &ca.Kind, &ca.CertPEM, &ca.KeyPEM, &ca.RetiredAt,
 		&ca.CreatedAt, &ca.CreateUserID, &ca.UpdatedAt, &ca.UpdateUserID,
-	); err != nil {
+	)
+}
+
+// GetByCluster returns the root CA of a cluster.
+func (r *CertificateAuthorityRepository) GetByCluster(ctx context.Context, clusterID string) (*CertificateAuthority, error) {
+	var ca CertificateAuthority
+	if err := scanCA(r.exec.QueryRowContext(ctx, caSelect+` WHERE cluster_id = ? AND kind = ?`, clusterID, CAKindRoot), &ca); err != nil {
+		return nil, err
+	}
+	return &ca, nil
+}
+
+// GetActiveIntermediate returns the intermediate CA of a cluster that is not
+// retired; sql.ErrNoRows if the cluster has none.
+func (r *CertificateAuthorityRepository) GetActiveIntermediate(ctx context.Context, clusterID string) (*CertificateAuthority, error) {
+	var ca CertificateAuthority
+	err := scanCA(r.exec.QueryRowContext(ctx, caSelect+`
+WHERE cluster_id = ? AND kind = ? AND retired_at IS NULL
+ORDER BY created_at DESC LIMIT 1`, clusterID, CAKindIntermediate), &ca)
+	if err != nil {
 		return nil, err
 	}
 	return &ca, nil
 }
 
+// ListByCluster returns the CAs of a cluster, the root first, then the
+// intermediates from oldest to newest.
+func (r *CertificateAuthorityRepository) ListByCluster(ctx context.Context, clusterID string) ([]CertificateAuthority, error) {
+	return collect(ctx, r.exec, caSelect+` WHERE cluster_id = ? ORDER BY kind = 'intermediate', created_at, rowid`, []any{clusterID}, scanCA)
+}
+
+// RetireIntermediates marks the cluster's active intermediate CAs retired.
+func (r *CertificateAuthorityRepository) RetireIntermediates(ctx context.Context, clusterID string, at time.Time) error {
+	_, err := r.exec.ExecContext(ctx, `
+UPDATE certificate_authorities SET retired_at = ?, updated_at = CURRENT_TIMESTAMP
+WHERE cluster_id = ? AND kind = ? AND retired_at IS NULL
+`, at, clusterID, CAKindIntermediate)
+	return err
+}
+
 func (r *CertificateAuthorityRepository) DeleteByID(ctx context.Context, id string) error {
 	_, err := r.exec.ExecContext(ctx, `DELETE FROM certificate_authorities WHERE id = ?`, id)
 	return err
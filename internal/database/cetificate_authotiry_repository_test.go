@@ -0,0 +1,77 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCertificateAuthorityRepository(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	c := testCluster(t, db, "prod")
+	repo := NewCertificateAuthorityRepository(db)
+
+	if _, err := repo.GetActiveIntermediate(ctx, c.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetActiveIntermediate without one error = %v, want sql.ErrNoRows", err)
+	}
+	for _, ca := range []*CertificateAuthority{
+		{ID: "root", ClusterID: c.ID, Kind: CAKindRoot, CertPEM: "root PEM"},
+		{ID: "int-1", ClusterID: c.ID, Kind: CAKindIntermediate, CertPEM: "int-1 PEM", KeyPEM: "int-1 key"},
+	} {
+		if err := repo.Create(ctx, ca); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	root, err := repo.GetByCluster(ctx, c.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root.ID != "root" || root.KeyPEM != "" || root.RetiredAt != nil {
+		t.Errorf("GetByCluster = %+v, want the root CA without its key", root)
+	}
+	active, err := repo.GetActiveIntermediate(ctx, c.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if active.ID != "int-1" || active.KeyPEM != "int-1 key" {
+		t.Errorf("GetActiveIntermediate = %+v", active)
+	}
+
+	// A rotation retires the active intermediate, then adds its successor
+	retired := time.Now().UTC().Truncate(time.Second)
+	if err := repo.RetireIntermediates(ctx, c.ID, retired); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Create(ctx, &CertificateAuthority{ID: "int-2", ClusterID: c.ID, Kind: CAKindIntermediate, CertPEM: "int-2 PEM"}); err != nil {
+		t.Fatal(err)
+	}
+	if active, err := repo.GetActiveIntermediate(ctx, c.ID); err != nil || active.ID != "int-2" {
+		t.Errorf("GetActiveIntermediate after rotation = %+v, %v; want int-2", active, err)
+	}
+
+	cas, err := repo.ListByCluster(ctx, c.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, ca := range cas {
+		ids = append(ids, ca.ID)
+	}
+	if len(cas) != 3 || ids[0] != "root" || ids[1] != "int-1" || ids[2] != "int-2" {
+		t.Fatalf("ListByCluster = %v, want root, int-1, int-2", ids)
+	}
+	if cas[1].RetiredAt == nil || !cas[1].RetiredAt.Equal(retired) || cas[2].RetiredAt != nil {
+		t.Errorf("retired at = %v, %v; want %s, nil", cas[1].RetiredAt, cas[2].RetiredAt, retired)
+	}
+
+	if err := repo.DeleteByID(ctx, "int-1"); err != nil {
+		t.Fatal(err)
+	}
+	if cas, err := repo.ListByCluster(ctx, c.ID); err != nil || len(cas) != 2 {
+		t.Errorf("ListByCluster after delete = %d CAs, %v; want 2", len(cas), err)
+	}
+}
@@ -7,13 +7,13 @@ import (
 )
 
 type Cluster struct {
-	ID           string
-	Name         string
-	State        string
-	CreatedAt    time.Time
-	CreateUserID *string
-	UpdatedAt    time.Time
-	UpdateUserID *string
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	State        string    `json:"state"`
+	CreatedAt    time.Time `json:"created_at"`
+	CreateUserID *string   `json:"create_user_id"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	UpdateUserID *string   `json:"update_user_id"`
 }
 
 type ClusterRepository struct {
@@ -27,7 +27,7 @@ type sqlExecutor interface {
 }
 
 func NewClusterRepository(db *sql.DB) *ClusterRepository {
-	return &ClusterRepository{exec: db}
+	return &ClusterRepository{exec: executor(db)}
 }
 
 func NewClusterRepositoryTx(tx *sql.Tx) *ClusterRepository {
@@ -87,3 +87,26 @@ func (r *ClusterRepository) Count(ctx context.Context) (int, error) {
 	var n int
 	return n, row.Scan(&n)
 }
+
+func (r *ClusterRepository) List(ctx context.Context) ([]Cluster, error) {
+	rows, err := r.exec.QueryContext(ctx, `SELECT id, name, state, created_at, create_user_id, updated_at, update_user_id
+	FROM clusters ORDER BY created_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []Cluster
+	for rows.Next() {
+		var c Cluster
+		if err := rows.Scan(
+			&c.ID, &c.Name, &c.State,
+			&c.CreatedAt, &c.CreateUserID,
+			&c.UpdatedAt, &c.UpdateUserID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, c)
+	}
+	return items, nil
+}
@@ -4,132 +4,156 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"mcloud/internal/config"
-	"os"
-	"path/filepath"
-	"sort"
 
+	"github.com/uptrace/opentelemetry-go-extra/otelsql"
 	_ "modernc.org/sqlite"
 )
 
-// DefaultMigrationsDir is the default path to the folder containing SQL migration files
-const (
-	DefaultMigrationsDir = "internal/database/migrations"
-)
-
 // Database wraps the sql.DB connection and provides migration capabilities
 type Database struct {
 	db *sql.DB // underlying sql.DB connection
 }
 
-// Open creates a new Database instance with a connection to the given SQLite file
+// Open creates a new Database instance with a connection to the given SQLite file,
+// without migrating it (e.g. for `mcloudctl db status`).
 func Open(dbPath string) (*Database, error) {
-	db, err := sql.Open("sqlite", dbPath)
+	db, err := open(dsn(dbPath))
 	if err != nil {
 		return nil, err
 	}
 	return &Database{db: db}, nil
 }
 
+// SQL returns the underlying connection, for repositories over a database
+// opened without migrating it (e.g. by `mcloudctl doctor`).
+func (s *Database) SQL() *sql.DB {
+	return s.db
+}
+
+// Close closes the underlying connection.
+func (s *Database) Close() error {
+	return s.db.Close()
+}
+
+// open opens the SQLite database of dsn through a driver that traces every query
+// as a child of the caller's span (see package tracing).
+func open(dsn string) (*sql.DB, error) {
+	return otelsql.Open("sqlite", dsn, otelsql.WithDBSystem("sqlite"))
+}
+
+// dsn adds the connection settings every mcloud process uses: wait for locks held by
+// other processes instead of failing, WAL so readers do not block the writer, and
+// transactions that take the write lock when they begin, so two of them cannot
+// both read and then fail to upgrade to writing.
+func dsn(dbPath string) string {
+	return fmt.Sprintf("%s?_pragma=busy_timeout=5000&_pragma=journal_mode=WAL&_pragma=synchronous=NORMAL&_txlock=immediate", dbPath)
+}
+
 // ensureMigrationsTable creates the migrations tracking table if it doesn't exist
 func (s *Database) ensureMigrationsTable() error {
 	_, err := s.db.Exec(`
 		CREATE TABLE IF NOT EXISTS schema_migrations (
 			filename TEXT PRIMARY KEY,
+			checksum TEXT,
 			applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
 		)
 	`)
-	return err
-}
-
-// migrationApplied checks if a migration file has already been applied
-func (s *Database) migrationApplied(filename string) (bool, error) {
-	var count int
-	err := s.db.QueryRow("SELECT COUNT(*) FROM schema_migrations WHERE filename = ?", filename).Scan(&count)
 	if err != nil {
-		return false, err
+		return err
 	}
-	return count > 0, nil
+	return s.ensureChecksumColumn()
 }
 
-// recordMigration records a migration as applied
-func (s *Database) recordMigration(filename string) error {
-	_, err := s.db.Exec("INSERT INTO schema_migrations (filename) VALUES (?)", filename)
-	return err
+// applyMigration runs a migration and records it as applied in one transaction,
+// so a migration that fails halfway leaves neither a half-applied schema nor a
+// version row.
+func (s *Database) applyMigration(m migration) error {
+	return WithTx(context.Background(), s.db, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(m.sql); err != nil {
+			return err
+		}
+		_, err := tx.Exec("INSERT INTO schema_migrations (filename, checksum) VALUES (?, ?)", m.filename, m.checksum)
+		return err
+	})
 }
 
-// Migrate runs all SQL migration files in the migrations directory in order
-// It reads all .sql files, sorts them alphabetically, and executes each statement on the database
+// Migrate runs the SQL migrations embedded in the binary (see migrations.go) in order.
+// Applied migrations are skipped after checking that they still match the recorded
+// checksum; a mismatch fails with ErrMigrationChecksum before anything new is applied.
+// Only one process migrates at a time: others wait for the migration lock (see migration_lock.go)
+// and then find the migrations already applied, or fail with ErrMigrationLocked.
 func (s *Database) Migrate() error {
 	// Ensure migrations tracking table exists
 	if err := s.ensureMigrationsTable(); err != nil {
 		return err
 	}
 
-	files, err := os.ReadDir(DefaultMigrationsDir)
+	// Serialize migrations across processes sharing this database
+	if err := s.ensureMigrationLockTable(); err != nil {
+		return err
+	}
+	release, err := s.acquireMigrationLock()
 	if err != nil {
 		return err
 	}
+	defer release()
 
-	// Collect all .sql files from the migrations directory
-	var migrationFiles []string
-	for _, f := range files {
-		if !f.IsDir() && filepath.Ext(f.Name()) == ".sql" {
-			migrationFiles = append(migrationFiles, f.Name())
-		}
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
 	}
 
-	// Sort files to ensure migrations run in order (e.g., 001_init.sql, 002_add_users.sql)
-	sort.Strings(migrationFiles)
-	for _, fname := range migrationFiles {
-		// Check if migration file has already been applied
-		applied, err := s.migrationApplied(fname)
+	// Verify every applied migration first, so drift is reported before the schema changes
+	var pending []migration
+	for _, m := range migrations {
+		recorded, applied, err := s.appliedChecksum(m.filename)
 		if err != nil {
 			return err
 		}
-		if applied {
-			fmt.Printf("Skipping already applied migration: %s\n", fname)
+		if !applied {
+			pending = append(pending, m)
 			continue
 		}
-
-		// Read migration SQL file
-		path := filepath.Join(DefaultMigrationsDir, fname)
-		sqlBytes, err := os.ReadFile(path)
-		if err != nil {
-			return err
-		}
-		sqlStmt := string(sqlBytes)
-		// Execute migration SQL statement
-		if _, err := s.db.Exec(sqlStmt); err != nil {
+		if err := s.verifyMigration(m, recorded); err != nil {
 			return err
 		}
+	}
 
-		// Record migration as applied
-		if err := s.recordMigration(fname); err != nil {
-			return err
+	for _, m := range pending {
+		// Execute migration SQL statement and record it as applied
+		if err := s.applyMigration(m); err != nil {
+			return fmt.Errorf("migration %s failed: %w", m.filename, err)
 		}
 
 		// Log successful migration
-		fmt.Printf("Applied migration: %s\n", fname)
+		fmt.Printf("Applied migration: %s\n", m.filename)
+	}
+	if len(pending) > 0 {
+		if err := alignPartitions(context.Background(), s.db); err != nil {
+			return err
+		}
 	}
 	fmt.Printf("Migration completed successfully \n")
 
 	return nil
 }
 
-// Connect loads config, ensures the database file exists, opens the connection, and runs migrations
-// Returns a ready-to-use Database instance with all migrations applied
-func Connect() (*sql.DB, error) {
-	cfg, err := config.GetConfig()
+// Connect opens the database at dbPath (normally cfg.Database.DBPath), creating it
+// if needed, and runs migrations. A dbPath of ":memory:" opens a database that
+// lives as long as the returned connection. It has that one connection for
+// reads and writes, so a query issued while the rows of another are open, or
+// outside a transaction that is open, waits forever; tests that do either
+// use a file in t.TempDir() instead. Release it with Close.
+// Returns a ready-to-use connection with all migrations applied
+func Connect(dbPath string) (*sql.DB, error) {
+	db, err := open(dsn(dbPath))
 	if err != nil {
 		return nil, err
 	}
-
-	dbPath := cfg.Database.DBPath
-	dsn := fmt.Sprintf("%s?_pragma=busy_timeout=5000&_pragma=journal_mode=WAL&_pragma=synchronous=NORMAL", dbPath)
-	db, err := sql.Open("sqlite", dsn)
-	if err != nil {
-		return nil, err
+	if dbPath == memoryPath {
+		// Every connection to :memory: opens a database of its own
+		db.SetMaxOpenConns(1)
+		db.SetConnMaxIdleTime(0)
 	}
 
 	// Create Database instance
@@ -139,6 +163,12 @@ func Connect() (*sql.DB, error) {
 	if err := database.Migrate(); err != nil {
 		return nil, err
 	}
+
+	// One write connection and a read pool (see pool.go)
+	if err := openPools(db, dbPath); err != nil {
+		db.Close()
+		return nil, err
+	}
 	return db, nil
 }
 
@@ -0,0 +1,81 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type DNSRecord struct {
+	Owner      string    `json:"owner"`
+	FQDN       string    `json:"fqdn"`
+	Type       string    `json:"type"`
+	Value      string    `json:"value"`
+	Provider   string    `json:"provider"`
+	ProviderID string    `json:"provider_id,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type DNSRecordRepository struct {
+	exec sqlExecutor
+}
+
+func NewDNSRecordRepository(db *sql.DB) *DNSRecordRepository {
+	return &DNSRecordRepository{exec: executor(db)}
+}
+
+func NewDNSRecordRepositoryTx(tx *sql.Tx) *DNSRecordRepository {
+	return &DNSRecordRepository{exec: tx}
+}
+
+func (r *DNSRecordRepository) Upsert(ctx context.Context, rec *DNSRecord) error {
+	_, err := r.exec.ExecContext(ctx, `
+INSERT INTO dns_records (owner, fqdn, type, value, provider, provider_id)
+VALUES (?, ?, ?, ?, ?, ?)
+ON CONFLICT(owner) DO UPDATE SET
+fqdn = excluded.fqdn, type = excluded.type, value = excluded.value,
+provider = excluded.provider, provider_id = excluded.provider_id,
+updated_at = CURRENT_TIMESTAMP
+`, rec.Owner, rec.FQDN, rec.Type, rec.Value, rec.Provider, rec.ProviderID)
+	return err
+}
+
+func (r *DNSRecordRepository) GetByOwner(ctx context.Context, owner string) (*DNSRecord, error) {
+	row := r.exec.QueryRowContext(ctx, `
+SELECT owner, fqdn, type, value, provider, COALESCE(provider_id, ''), created_at, updated_at
+FROM dns_records WHERE owner = ?
+`, owner)
+
+	var rec DNSRecord
+	if err := row.Scan(&rec.Owner, &rec.FQDN, &rec.Type, &rec.Value, &rec.Provider, &rec.ProviderID, &rec.CreatedAt, &rec.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+func (r *DNSRecordRepository) List(ctx context.Context) ([]DNSRecord, error) {
+	rows, err := r.exec.QueryContext(ctx, `
+SELECT owner, fqdn, type, value, provider, COALESCE(provider_id, ''), created_at, updated_at
+FROM dns_records ORDER BY fqdn
+`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []DNSRecord
+	for rows.Next() {
+		var rec DNSRecord
+		if err := rows.Scan(&rec.Owner, &rec.FQDN, &rec.Type, &rec.Value, &rec.Provider, &rec.ProviderID, &rec.CreatedAt, &rec.UpdatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, rec)
+	}
+	return items, rows.Err()
+}
+
+func (r *DNSRecordRepository) Delete(ctx context.Context, owner string) error {
+	_, err := r.exec.ExecContext(ctx, `DELETE FROM dns_records WHERE owner = ?`, owner)
+	return err
+}
@@ -3,55 +3,282 @@ package database
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"strings"
 	"time"
 )
 
+// Event is an entry of the cluster event log. Type and Severity come from the
+// taxonomy in package event, whose constructors also fill Payload.
 type Event struct {
-	ID        int64
-	ClusterID *string
-	NodeID    *string
-	Type      string
-	Message   string
-	CreatedAt time.Time
+	ID        int64           `json:"id"`
+	ClusterID *string         `json:"cluster_id"`
+	NodeID    *string         `json:"node_id"`
+	Type      string          `json:"type"`
+	Severity  string          `json:"severity"`          // info, warning, error or critical
+	Message   string          `json:"message"`
+	Payload   json.RawMessage `json:"payload,omitempty"` // JSON object with the details of the type
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Event severities, least severe first.
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityError    = "error"
+	SeverityCritical = "critical"
+)
+
+// Severities lists the event severities, least severe first.
+var Severities = []string{SeverityInfo, SeverityWarning, SeverityError, SeverityCritical}
+
+// SeveritiesFrom returns min and every more severe severity, or nil if min is
+// not a severity.
+//
+// Example:
+//   SeveritiesFrom("error")  =>  ["error", "critical"]
+func SeveritiesFrom(min string) []string {
+	for i, s := range Severities {
+		if s == min {
+			return Severities[i:]
+		}
+	}
+	return nil
 }
 
 type EventRepository struct {
-	db *sql.DB
+	db sqlExecutor
 }
 
 func NewEventRepository(db *sql.DB) *EventRepository {
-	return &EventRepository{db: db}
+	return &EventRepository{db: executor(db)}
+}
+
+func NewEventRepositoryTx(tx *sql.Tx) *EventRepository {
+	return &EventRepository{db: tx}
 }
 
+// Create records e; an empty Severity is recorded as info.
 func (r *EventRepository) Create(ctx context.Context, e *Event) error {
+	if e.Severity == "" {
+		e.Severity = SeverityInfo
+	}
+	var payload *string
+	if len(e.Payload) > 0 {
+		p := string(e.Payload)
+		payload = &p
+	}
 	_, err := r.db.ExecContext(ctx, `
-INSERT INTO events (cluster_id, node_id, type, message)
-VALUES (?, ?, ?, ?)
-`, e.ClusterID, e.NodeID, e.Type, e.Message)
+INSERT INTO events (cluster_id, node_id, type, severity, message, payload)
+VALUES (?, ?, ?, ?, ?, ?)
+`, e.ClusterID, e.NodeID, e.Type, e.Severity, e.Message, payload)
 	return err
 }
 
-func (r *EventRepository) ListByCluster(ctx context.Context, clusterID string, limit int) ([]Event, error) {
-	rows, err := r.db.QueryContext(ctx, `
-SELECT id, cluster_id, node_id, type, message, created_at
-FROM events WHERE cluster_id = ?
-ORDER BY created_at DESC LIMIT ?
-`, clusterID, limit)
+// ListByCluster returns the events of a cluster, newest first by default.
+// Like every read of events it includes the monthly partitions still in the
+// database (see partition.go).
+//
+// Example Input:
+//   ListByCluster(ctx, "c1", ListOptions{Limit: 50, Filter: map[string]string{"type": "node.drained"}})
+func (r *EventRepository) ListByCluster(ctx context.Context, clusterID string, opts ListOptions) ([]Event, error) {
+	list, err := r.list(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	query, args, err := list.build(opts, []string{"cluster_id = ?"}, clusterID)
+	if err != nil {
+		return nil, err
+	}
+	return collect(ctx, r.db, query, args, scanEvent)
+}
+
+const eventColumns = `id, cluster_id, node_id, type, severity, message, payload, created_at`
 
+var eventList = listQuery{
+	selectSQL:   `SELECT ` + eventColumns + ` FROM events`,
+	defaultSort: "id DESC",
+	sortable:    map[string]string{"created_at": "created_at", "type": "type"},
+	filterable:  map[string]string{"node": "node_id", "type": "type", "severity": "severity"},
+}
+
+// list returns eventList reading events and its live partitions.
+func (r *EventRepository) list(ctx context.Context) (listQuery, error) {
+	src, err := partitionSource(ctx, r.db, "events", eventColumns, time.Time{}, time.Time{})
+	list := eventList
+	list.selectSQL = `SELECT ` + eventColumns + ` FROM ` + src
+	return list, err
+}
+
+// ListRecent returns the newest events across all clusters, newest first.
+func (r *EventRepository) ListRecent(ctx context.Context, limit int) ([]Event, error) {
 	var items []Event
+	err := r.EachRecent(ctx, limit, func(e *Event) error {
+		items = append(items, *e)
+		return nil
+	})
+	return items, err
+}
+
+// EachRecent is ListRecent calling fn for every event as it is read from the cursor.
+func (r *EventRepository) EachRecent(ctx context.Context, limit int, fn func(*Event) error) error {
+	list, err := r.list(ctx)
+	if err != nil {
+		return err
+	}
+	rows, err := r.db.QueryContext(ctx, list.selectSQL+` ORDER BY id DESC LIMIT ?`, limit)
+	if err != nil {
+		return err
+	}
+	return eachEvent(rows, fn)
+}
+
+// ListAfter returns events with an ID greater than afterID, oldest first.
+func (r *EventRepository) ListAfter(ctx context.Context, afterID int64, limit int) ([]Event, error) {
+	list, err := r.list(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return collect(ctx, r.db, list.selectSQL+` WHERE id > ? ORDER BY id LIMIT ?`, []any{afterID, limit}, scanEvent)
+}
+
+// LatestID returns the ID of the newest event, or 0 if there are none. It is
+// read from the AUTOINCREMENT counter, which partitioning does not reset.
+func (r *EventRepository) LatestID(ctx context.Context) (int64, error) {
+	var id int64
+	err := r.db.QueryRowContext(ctx, `SELECT COALESCE((SELECT seq FROM sqlite_sequence WHERE name = 'events'), 0)`).Scan(&id)
+	return id, err
+}
+
+// EventFilter selects events for Search. Zero values mean "no filter".
+// Query is an FTS5 match expression over message and type. Type ending in ".*"
+// matches a family of types (e.g. "node.*"); Severity is the least severe
+// severity to include.
+type EventFilter struct {
+	Query     string
+	ClusterID string
+	NodeID    string
+	Type      string
+	Severity  string
+	Since     time.Time
+	Until     time.Time
+	Limit     int
+	Offset    int
+}
+
+// Search returns events matching the filter, newest first.
+// With a full-text query, matches are looked up in the events_fts index, and
+// in the index of each partition overlapping Since and Until.
+func (r *EventRepository) Search(ctx context.Context, f EventFilter) ([]Event, error) {
+	var items []Event
+	err := r.SearchEach(ctx, f, func(e *Event) error {
+		items = append(items, *e)
+		return nil
+	})
+	return items, err
+}
+
+// SearchEach is Search calling fn for every event as it is read from the cursor.
+func (r *EventRepository) SearchEach(ctx context.Context, f EventFilter, fn func(*Event) error) error {
+	partitions, err := partitionTables(ctx, r.db, "events", f.Since, f.Until)
+	if err != nil {
+		return err
+	}
+
+	// One SELECT per table, each with its own full-text index
+	var branches []string
+	var args []any
+	for _, table := range append([]string{"events"}, partitions...) {
+		branch, branchArgs := searchBranch(table, f)
+		branches = append(branches, branch)
+		args = append(args, branchArgs...)
+	}
+	query := strings.Join(branches, "UNION ALL\n") + "ORDER BY id DESC LIMIT ? OFFSET ?"
+	args = append(args, f.Limit, f.Offset)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	return eachEvent(rows, fn)
+}
+
+// likeEscaper escapes the LIKE wildcards of a literal, for ESCAPE '\'.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// searchBranch returns the SELECT of Search over one table of events.
+func searchBranch(table string, f EventFilter) (string, []any) {
+	query := `
+SELECT e.id, e.cluster_id, e.node_id, e.type, e.severity, e.message, e.payload, e.created_at
+FROM ` + table + ` e
+`
+	var where []string
+	var args []any
+	if f.Query != "" {
+		query += "JOIN " + table + "_fts ON " + table + "_fts.rowid = e.id\n"
+		where = append(where, table+"_fts MATCH ?")
+		args = append(args, f.Query)
+	}
+	if f.ClusterID != "" {
+		where = append(where, "e.cluster_id = ?")
+		args = append(args, f.ClusterID)
+	}
+	if f.NodeID != "" {
+		where = append(where, "e.node_id = ?")
+		args = append(args, f.NodeID)
+	}
+	if family, ok := strings.CutSuffix(f.Type, ".*"); ok {
+		where = append(where, "e.type LIKE ? ESCAPE '\\'")
+		args = append(args, likeEscaper.Replace(family)+".%")
+	} else if f.Type != "" {
+		where = append(where, "e.type = ?")
+		args = append(args, f.Type)
+	}
+	if f.Severity != "" {
+		severities := SeveritiesFrom(f.Severity)
+		where = append(where, "e.severity IN (?"+strings.Repeat(", ?", max(len(severities)-1, 0))+")")
+		for _, sev := range severities {
+			args = append(args, sev)
+		}
+	}
+	if !f.Since.IsZero() {
+		where = append(where, "e.created_at >= ?")
+		args = append(args, f.Since.UTC().Format(time.DateTime))
+	}
+	if !f.Until.IsZero() {
+		where = append(where, "e.created_at < ?")
+		args = append(args, f.Until.UTC().Format(time.DateTime))
+	}
+	if len(where) > 0 {
+		query += "WHERE " + strings.Join(where, " AND ") + "\n"
+	}
+	return query, args
+}
+
+// eachEvent scans event rows into fn and closes them.
+func eachEvent(rows *sql.Rows, fn func(*Event) error) error {
+	defer rows.Close()
+
 	for rows.Next() {
 		var e Event
-		if err := rows.Scan(
-			&e.ID, &e.ClusterID, &e.NodeID,
-			&e.Type, &e.Message, &e.CreatedAt,
-		); err != nil {
-			return nil, err
+		if err := scanEvent(rows, &e); err != nil {
+			return err
+		}
+		if err := fn(&e); err != nil {
+			return err
 		}
-		items = append(items, e)
 	}
-	return items, nil
+	return rows.Err()
+}
+
+func scanEvent(row rowScanner, e *Event) error {
+	var payload sql.NullString
+	err := row.Scan(
+		&e.ID, &e.ClusterID, &e.NodeID,
+		&e.Type, &e.Severity, &e.Message, &payload, &e.CreatedAt,
+	)
+	if payload.Valid {
+		e.Payload = json.RawMessage(payload.String)
+	}
+	return err
 }
@@ -0,0 +1,118 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestEventRepository(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	c := testCluster(t, db, "prod")
+	n := testNode(t, db, c, "node1")
+	repo := NewEventRepository(db)
+
+	if id, err := repo.LatestID(ctx); err != nil || id != 0 {
+		t.Errorf("LatestID without events = %d, %v; want 0", id, err)
+	}
+	for _, e := range []*Event{
+		{ClusterID: &c.ID, Type: "cluster.created", Message: "Cluster prod created"},
+		{ClusterID: &c.ID, NodeID: &n.ID, Type: "node.offline", Severity: SeverityCritical, Message: "Node node1 went offline",
+			Payload: json.RawMessage(`{"hostname":"node1"}`)},
+		{ClusterID: &c.ID, NodeID: &n.ID, Type: "node.online", Message: "Node node1 is back online"},
+		{Type: "alert.fired", Severity: SeverityWarning, Message: "Ceph is HEALTH_WARN"},
+	} {
+		if err := repo.Create(ctx, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	events, err := repo.ListByCluster(ctx, c.ID, ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if types := eventTypes(events); !slices.Equal(types, []string{"node.online", "node.offline", "cluster.created"}) {
+		t.Errorf("ListByCluster = %v, want newest first", types)
+	}
+	offline := events[1]
+	if offline.Severity != SeverityCritical || string(offline.Payload) != `{"hostname":"node1"}` || *offline.NodeID != n.ID {
+		t.Errorf("node.offline event = %+v", offline)
+	}
+	if events[0].Severity != SeverityInfo || events[0].Payload != nil {
+		t.Errorf("node.online event = %+v, want info without payload", events[0])
+	}
+
+	events, err = repo.ListByCluster(ctx, c.ID, ListOptions{Filter: map[string]string{"node": n.ID, "severity": SeverityCritical}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if types := eventTypes(events); !slices.Equal(types, []string{"node.offline"}) {
+		t.Errorf("ListByCluster(node, critical) = %v", types)
+	}
+
+	recent, err := repo.ListRecent(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if types := eventTypes(recent); !slices.Equal(types, []string{"alert.fired", "node.online"}) {
+		t.Errorf("ListRecent(2) = %v", types)
+	}
+	after, err := repo.ListAfter(ctx, recent[1].ID-2, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if types := eventTypes(after); !slices.Equal(types, []string{"node.offline", "node.online", "alert.fired"}) {
+		t.Errorf("ListAfter = %v, want oldest first", types)
+	}
+	if id, err := repo.LatestID(ctx); err != nil || id != recent[0].ID {
+		t.Errorf("LatestID = %d, %v; want %d", id, err, recent[0].ID)
+	}
+}
+
+func TestEventRepositorySearch(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	c := testCluster(t, db, "prod")
+	repo := NewEventRepository(db)
+	for _, e := range []*Event{
+		{ClusterID: &c.ID, Type: "node.offline", Severity: SeverityCritical, Message: "Node node1 went offline"},
+		{ClusterID: &c.ID, Type: "node.drained", Message: "Node node2 drained for maintenance"},
+		{ClusterID: &c.ID, Type: "ceph.health", Severity: SeverityWarning, Message: "Ceph health changed to HEALTH_WARN"},
+	} {
+		if err := repo.Create(ctx, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		filter EventFilter
+		want   []string
+	}{
+		{EventFilter{Query: "maintenance"}, []string{"node.drained"}},
+		{EventFilter{Type: "node.*"}, []string{"node.drained", "node.offline"}},
+		{EventFilter{Severity: SeverityWarning}, []string{"ceph.health", "node.offline"}},
+		{EventFilter{ClusterID: c.ID, Limit: 1, Offset: 1}, []string{"node.drained"}},
+	}
+	for _, tt := range tests {
+		if tt.filter.Limit == 0 {
+			tt.filter.Limit = 10
+		}
+		events, err := repo.Search(ctx, tt.filter)
+		if err != nil {
+			t.Fatalf("Search(%+v): %v", tt.filter, err)
+		}
+		if types := eventTypes(events); !slices.Equal(types, tt.want) {
+			t.Errorf("Search(%+v) = %v, want %v", tt.filter, types, tt.want)
+		}
+	}
+}
+
+func eventTypes(events []Event) []string {
+	var types []string
+	for _, e := range events {
+		types = append(types, e.Type)
+	}
+	return types
+}
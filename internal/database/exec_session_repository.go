@@ -0,0 +1,127 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// StringList is a list of strings stored as a JSON array, for values that may
+// contain commas (unlike AddressList), such as command arguments.
+type StringList []string
+
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		l = StringList{}
+	}
+	b, err := json.Marshal([]string(l))
+	return string(b), err
+}
+
+func (l *StringList) Scan(src any) error {
+	switch src := src.(type) {
+	case string:
+		return json.Unmarshal([]byte(src), (*[]string)(l))
+	case []byte:
+		return json.Unmarshal(src, (*[]string)(l))
+	case nil:
+		*l = StringList{}
+		return nil
+	}
+	return fmt.Errorf("cannot scan %T into StringList", src)
+}
+
+// ExecSession is one exec session into an instance (see migration 020).
+// The transcript itself is read with GetTranscript.
+type ExecSession struct {
+	ID              string     `json:"id"`
+	Target          string     `json:"target"`
+	Instance        string     `json:"instance"`
+	Actor           string     `json:"actor"`
+	Command         StringList `json:"command"`
+	StartedAt       time.Time  `json:"started_at"`
+	EndedAt         *time.Time `json:"ended_at"`
+	ExitCode        *int       `json:"exit_code"`
+	Error           *string    `json:"error"`
+	Recorded        bool       `json:"recorded"`
+	TranscriptBytes int64      `json:"transcript_bytes"`
+	Truncated       bool       `json:"truncated"`
+}
+
+type ExecSessionRepository struct {
+	exec sqlExecutor
+}
+
+func NewExecSessionRepository(db *sql.DB) *ExecSessionRepository {
+	return &ExecSessionRepository{exec: executor(db)}
+}
+
+func (r *ExecSessionRepository) Create(ctx context.Context, s *ExecSession) error {
+	_, err := r.exec.ExecContext(ctx, `
+INSERT INTO exec_sessions (id, target, instance, actor, command, started_at)
+VALUES (?, ?, ?, ?, ?, ?)
+`, s.ID, s.Target, s.Instance, s.Actor, s.Command, s.StartedAt.UTC())
+	return err
+}
+
+// Finish records how a session ended and its transcript (nil when not recorded).
+func (r *ExecSessionRepository) Finish(ctx context.Context, id string, exitCode *int, errMsg *string, transcript []byte, truncated bool) error {
+	_, err := r.exec.ExecContext(ctx, `
+UPDATE exec_sessions
+SET ended_at = ?, exit_code = ?, error = ?, transcript = ?, transcript_bytes = ?, truncated = ?
+WHERE id = ?
+`, time.Now().UTC(), exitCode, errMsg, transcript, len(transcript), truncated, id)
+	return err
+}
+
+func (r *ExecSessionRepository) GetByID(ctx context.Context, id string) (*ExecSession, error) {
+	var s ExecSession
+	if err := scanExecSession(r.exec.QueryRowContext(ctx, execSessionList.selectSQL+` WHERE id = ?`, id), &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// GetTranscript returns the recorded transcript of a session, or nil if it was not recorded.
+func (r *ExecSessionRepository) GetTranscript(ctx context.Context, id string) ([]byte, error) {
+	var transcript []byte
+	err := r.exec.QueryRowContext(ctx, `SELECT transcript FROM exec_sessions WHERE id = ?`, id).Scan(&transcript)
+	return transcript, err
+}
+
+// List returns sessions, newest first by default.
+//
+// Example Input:
+//   List(ctx, ListOptions{Limit: 50, Filter: map[string]string{"actor": "admin"}})
+func (r *ExecSessionRepository) List(ctx context.Context, opts ListOptions) ([]ExecSession, error) {
+	query, args, err := execSessionList.build(opts, nil)
+	if err != nil {
+		return nil, err
+	}
+	return collect(ctx, r.exec, query, args, scanExecSession)
+}
+
+// DeleteStartedBefore removes sessions (and their transcripts) started before cutoff.
+func (r *ExecSessionRepository) DeleteStartedBefore(ctx context.Context, cutoff time.Time) error {
+	_, err := r.exec.ExecContext(ctx, `DELETE FROM exec_sessions WHERE started_at < ?`, cutoff.UTC())
+	return err
+}
+
+var execSessionList = listQuery{
+	selectSQL: `SELECT id, target, instance, actor, command, started_at, ended_at, exit_code, error,
+transcript IS NOT NULL, transcript_bytes, truncated
+FROM exec_sessions`,
+	defaultSort: "started_at DESC, id",
+	sortable:    map[string]string{"started_at": "started_at", "actor": "actor"},
+	filterable:  map[string]string{"target": "target", "instance": "instance", "actor": "actor"},
+}
+
+func scanExecSession(row rowScanner, s *ExecSession) error {
+	return row.Scan(
+		&s.ID, &s.Target, &s.Instance, &s.Actor, &s.Command, &s.StartedAt, &s.EndedAt, &s.ExitCode, &s.Error,
+		&s.Recorded, &s.TranscriptBytes, &s.Truncated,
+	)
+}
@@ -0,0 +1,61 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Flavor is a named workload size, see migration 025.
+type Flavor struct {
+	Name         string    `json:"name"`
+	CPUs         int       `json:"cpus"`
+	MemoryMB     int       `json:"memory_mb"`
+	DiskGB       int       `json:"disk_gb"`
+	CreatedAt    time.Time `json:"created_at"`
+	CreateUserID *string   `json:"create_user_id"`
+}
+
+type FlavorRepository struct {
+	exec sqlExecutor
+}
+
+func NewFlavorRepository(db *sql.DB) *FlavorRepository {
+	return &FlavorRepository{exec: executor(db)}
+}
+
+func NewFlavorRepositoryTx(tx *sql.Tx) *FlavorRepository {
+	return &FlavorRepository{exec: tx}
+}
+
+func (r *FlavorRepository) Create(ctx context.Context, f *Flavor) error {
+	_, err := r.exec.ExecContext(ctx, `
+INSERT INTO flavors (name, cpus, memory_mb, disk_gb, create_user_id)
+VALUES (?, ?, ?, ?, ?)
+`, f.Name, f.CPUs, f.MemoryMB, f.DiskGB, f.CreateUserID)
+	return err
+}
+
+func (r *FlavorRepository) Get(ctx context.Context, name string) (*Flavor, error) {
+	var f Flavor
+	if err := scanFlavor(r.exec.QueryRowContext(ctx, flavorSelect+` WHERE name = ?`, name), &f); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// List returns all flavors, smallest first.
+func (r *FlavorRepository) List(ctx context.Context) ([]Flavor, error) {
+	return collect(ctx, r.exec, flavorSelect+` ORDER BY cpus, memory_mb, disk_gb, name`, nil, scanFlavor)
+}
+
+func (r *FlavorRepository) Delete(ctx context.Context, name string) error {
+	_, err := r.exec.ExecContext(ctx, `DELETE FROM flavors WHERE name = ?`, name)
+	return err
+}
+
+const flavorSelect = `SELECT name, cpus, memory_mb, disk_gb, created_at, create_user_id FROM flavors`
+
+func scanFlavor(row rowScanner, f *Flavor) error {
+	return row.Scan(&f.Name, &f.CPUs, &f.MemoryMB, &f.DiskGB, &f.CreatedAt, &f.CreateUserID)
+}
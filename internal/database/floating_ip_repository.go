@@ -0,0 +1,66 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// FloatingIP is an external address forwarded to a workload, see migration 027.
+type FloatingIP struct {
+	Address       string    `json:"address"`
+	WorkloadID    string    `json:"workload_id"`
+	Network       string    `json:"network"`
+	TargetAddress string    `json:"target_address"`
+	CreatedAt     time.Time `json:"created_at"`
+	CreateUserID  *string   `json:"create_user_id"`
+}
+
+type FloatingIPRepository struct {
+	exec sqlExecutor
+}
+
+func NewFloatingIPRepository(db *sql.DB) *FloatingIPRepository {
+	return &FloatingIPRepository{exec: executor(db)}
+}
+
+func NewFloatingIPRepositoryTx(tx *sql.Tx) *FloatingIPRepository {
+	return &FloatingIPRepository{exec: tx}
+}
+
+func (r *FloatingIPRepository) Create(ctx context.Context, f *FloatingIP) error {
+	_, err := r.exec.ExecContext(ctx, `
+INSERT INTO floating_ips (address, workload_id, network, target_address, create_user_id)
+VALUES (?, ?, ?, ?, ?)
+`, f.Address, f.WorkloadID, f.Network, f.TargetAddress, f.CreateUserID)
+	return err
+}
+
+func (r *FloatingIPRepository) Get(ctx context.Context, address string) (*FloatingIP, error) {
+	var f FloatingIP
+	if err := scanFloatingIP(r.exec.QueryRowContext(ctx, floatingIPSelect+` WHERE address = ?`, address), &f); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// List returns all allocated floating IPs by address.
+func (r *FloatingIPRepository) List(ctx context.Context) ([]FloatingIP, error) {
+	return collect(ctx, r.exec, floatingIPSelect+` ORDER BY address`, nil, scanFloatingIP)
+}
+
+// ListByWorkload returns the floating IPs forwarded to a workload.
+func (r *FloatingIPRepository) ListByWorkload(ctx context.Context, workloadID string) ([]FloatingIP, error) {
+	return collect(ctx, r.exec, floatingIPSelect+` WHERE workload_id = ? ORDER BY address`, []any{workloadID}, scanFloatingIP)
+}
+
+func (r *FloatingIPRepository) Delete(ctx context.Context, address string) error {
+	_, err := r.exec.ExecContext(ctx, `DELETE FROM floating_ips WHERE address = ?`, address)
+	return err
+}
+
+const floatingIPSelect = `SELECT address, workload_id, network, target_address, created_at, create_user_id FROM floating_ips`
+
+func scanFloatingIP(row rowScanner, f *FloatingIP) error {
+	return row.Scan(&f.Address, &f.WorkloadID, &f.Network, &f.TargetAddress, &f.CreatedAt, &f.CreateUserID)
+}
@@ -0,0 +1,72 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// HookDeadLetter is a hook webhook an agent gave up delivering (see migration 021).
+type HookDeadLetter struct {
+	ID         string          `json:"id"`
+	HookID     string          `json:"hook_id"`
+	WorkloadID string          `json:"workload_id"`
+	Node       string          `json:"node"`
+	Event      string          `json:"event"`
+	URL        string          `json:"url"`
+	Payload    json.RawMessage `json:"payload"`
+	Attempts   int             `json:"attempts"`
+	Error      string          `json:"error"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+type HookDeadLetterRepository struct {
+	exec sqlExecutor
+}
+
+func NewHookDeadLetterRepository(db *sql.DB) *HookDeadLetterRepository {
+	return &HookDeadLetterRepository{exec: executor(db)}
+}
+
+func (r *HookDeadLetterRepository) Create(ctx context.Context, d *HookDeadLetter) error {
+	_, err := r.exec.ExecContext(ctx, `
+INSERT INTO hook_dead_letters (id, hook_id, workload_id, node, event, url, payload, attempts, error)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+`, d.ID, d.HookID, d.WorkloadID, d.Node, d.Event, d.URL, string(d.Payload), d.Attempts, d.Error)
+	return err
+}
+
+// List returns dead letters, newest first by default.
+//
+// Example Input:
+//   List(ctx, ListOptions{Limit: 50, Filter: map[string]string{"workload": "550e8400-..."}})
+func (r *HookDeadLetterRepository) List(ctx context.Context, opts ListOptions) ([]HookDeadLetter, error) {
+	query, args, err := hookDeadLetterList.build(opts, nil)
+	if err != nil {
+		return nil, err
+	}
+	return collect(ctx, r.exec, query, args, scanHookDeadLetter)
+}
+
+// DeleteBefore removes dead letters recorded before cutoff.
+func (r *HookDeadLetterRepository) DeleteBefore(ctx context.Context, cutoff time.Time) error {
+	_, err := r.exec.ExecContext(ctx, `DELETE FROM hook_dead_letters WHERE created_at < ?`, cutoff.UTC())
+	return err
+}
+
+var hookDeadLetterList = listQuery{
+	selectSQL:   `SELECT id, hook_id, workload_id, node, event, url, payload, attempts, error, created_at FROM hook_dead_letters`,
+	defaultSort: "created_at DESC, id",
+	sortable:    map[string]string{"created_at": "created_at", "attempts": "attempts"},
+	filterable:  map[string]string{"workload": "workload_id", "hook": "hook_id", "node": "node", "event": "event"},
+}
+
+func scanHookDeadLetter(row rowScanner, d *HookDeadLetter) error {
+	var payload string
+	if err := row.Scan(&d.ID, &d.HookID, &d.WorkloadID, &d.Node, &d.Event, &d.URL, &payload, &d.Attempts, &d.Error, &d.CreatedAt); err != nil {
+		return err
+	}
+	d.Payload = json.RawMessage(payload)
+	return nil
+}
@@ -0,0 +1,108 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// ImageBuild is an image built from a base image and a provisioning script,
+// and its provenance record (see migration 019).
+type ImageBuild struct {
+	ID           string     `json:"id"`
+	Alias        string     `json:"alias"`
+	BaseImage    string     `json:"base_image"`
+	Script       string     `json:"script"`
+	ScriptSHA256 string     `json:"script_sha256"`
+	VM           bool       `json:"vm"`
+	NodeID       string     `json:"node_id"`
+	Status       string     `json:"status"`
+	OperationID  *string    `json:"operation_id"`
+	Fingerprint  *string    `json:"fingerprint"`
+	Log          *string    `json:"log"`
+	Error        *string    `json:"error"`
+	CreatedAt    time.Time  `json:"created_at"`
+	CreateUserID *string    `json:"create_user_id"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	FinishedAt   *time.Time `json:"finished_at"`
+}
+
+type ImageBuildRepository struct {
+	exec sqlExecutor
+}
+
+func NewImageBuildRepository(db *sql.DB) *ImageBuildRepository {
+	return &ImageBuildRepository{exec: executor(db)}
+}
+
+func (r *ImageBuildRepository) Create(ctx context.Context, b *ImageBuild) error {
+	_, err := r.exec.ExecContext(ctx, `
+INSERT INTO image_builds (id, alias, base_image, script, script_sha256, vm, node_id, status, create_user_id)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+`, b.ID, b.Alias, b.BaseImage, b.Script, b.ScriptSHA256, b.VM, b.NodeID, b.Status, b.CreateUserID)
+	return err
+}
+
+func (r *ImageBuildRepository) SetOperation(ctx context.Context, id string, operationID string) error {
+	_, err := r.exec.ExecContext(ctx, `
+UPDATE image_builds SET operation_id = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?
+`, operationID, id)
+	return err
+}
+
+func (r *ImageBuildRepository) UpdateStatus(ctx context.Context, id string, status string) error {
+	_, err := r.exec.ExecContext(ctx, `
+UPDATE image_builds SET status = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?
+`, status, id)
+	return err
+}
+
+// Finish records the outcome of a build: status completed with the published
+// image's fingerprint, or failed with errMsg. log is the tail of the script output.
+func (r *ImageBuildRepository) Finish(ctx context.Context, id string, status string, fingerprint *string, log *string, errMsg *string) error {
+	_, err := r.exec.ExecContext(ctx, `
+UPDATE image_builds
+SET status = ?, fingerprint = ?, log = ?, error = ?,
+updated_at = CURRENT_TIMESTAMP, finished_at = CURRENT_TIMESTAMP
+WHERE id = ?
+`, status, fingerprint, log, errMsg, id)
+	return err
+}
+
+func (r *ImageBuildRepository) GetByID(ctx context.Context, id string) (*ImageBuild, error) {
+	var b ImageBuild
+	if err := scanImageBuild(r.exec.QueryRowContext(ctx, imageBuildList.selectSQL+` WHERE id = ?`, id), &b); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// List returns image builds, newest first by default.
+//
+// Example Input:
+//   List(ctx, ListOptions{Limit: 20, Filter: map[string]string{"alias": "web-golden"}})
+func (r *ImageBuildRepository) List(ctx context.Context, opts ListOptions) ([]ImageBuild, error) {
+	query, args, err := imageBuildList.build(opts, nil)
+	if err != nil {
+		return nil, err
+	}
+	return collect(ctx, r.exec, query, args, scanImageBuild)
+}
+
+var imageBuildList = listQuery{
+	selectSQL: `SELECT id, alias, base_image, script, script_sha256, vm, node_id, status,
+operation_id, fingerprint, log, error,
+created_at, create_user_id, updated_at, finished_at
+FROM image_builds`,
+	defaultSort: "created_at DESC, id",
+	sortable:    map[string]string{"alias": "alias", "status": "status", "created_at": "created_at"},
+	filterable:  map[string]string{"alias": "alias", "status": "status", "node": "node_id"},
+}
+
+func scanImageBuild(row rowScanner, b *ImageBuild) error {
+	return row.Scan(
+		&b.ID, &b.Alias, &b.BaseImage, &b.Script, &b.ScriptSHA256, &b.VM, &b.NodeID, &b.Status,
+		&b.OperationID, &b.Fingerprint, &b.Log, &b.Error,
+		&b.CreatedAt, &b.CreateUserID, &b.UpdatedAt, &b.FinishedAt,
+	)
+}
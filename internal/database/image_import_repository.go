@@ -0,0 +1,102 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type ImageImport struct {
+	ID             string    `json:"id"`
+	URL            string    `json:"url"`
+	Alias          string    `json:"alias"`
+	SHA256         *string   `json:"sha256"`
+	MaxBytesPerSec int64     `json:"max_bytes_per_sec"`
+	Status         string    `json:"status"`
+	BytesDone      int64     `json:"bytes_done"`
+	BytesTotal     int64     `json:"bytes_total"`
+	Error          *string   `json:"error"`
+	CreatedAt      time.Time `json:"created_at"`
+	CreateUserID   *string   `json:"create_user_id"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type ImageImportRepository struct {
+	exec sqlExecutor
+}
+
+func NewImageImportRepository(db *sql.DB) *ImageImportRepository {
+	return &ImageImportRepository{exec: executor(db)}
+}
+
+func (r *ImageImportRepository) Create(ctx context.Context, i *ImageImport) error {
+	_, err := r.exec.ExecContext(ctx, `
+INSERT INTO image_imports (id, url, alias, sha256, max_bytes_per_sec, status, create_user_id)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+`, i.ID, i.URL, i.Alias, i.SHA256, i.MaxBytesPerSec, i.Status, i.CreateUserID)
+	return err
+}
+
+func (r *ImageImportRepository) UpdateProgress(ctx context.Context, id string, done int64, total int64) error {
+	_, err := r.exec.ExecContext(ctx, `
+UPDATE image_imports
+SET bytes_done = ?, bytes_total = ?, updated_at = CURRENT_TIMESTAMP
+WHERE id = ?
+`, done, total, id)
+	return err
+}
+
+func (r *ImageImportRepository) UpdateStatus(ctx context.Context, id string, status string, errMsg *string) error {
+	_, err := r.exec.ExecContext(ctx, `
+UPDATE image_imports
+SET status = ?, error = ?, updated_at = CURRENT_TIMESTAMP
+WHERE id = ?
+`, status, errMsg, id)
+	return err
+}
+
+func (r *ImageImportRepository) GetByID(ctx context.Context, id string) (*ImageImport, error) {
+	row := r.exec.QueryRowContext(ctx, `
+SELECT id, url, alias, sha256, max_bytes_per_sec, status, bytes_done, bytes_total, error,
+created_at, create_user_id, updated_at
+FROM image_imports WHERE id = ?
+`, id)
+
+	var i ImageImport
+	if err := row.Scan(
+		&i.ID, &i.URL, &i.Alias, &i.SHA256, &i.MaxBytesPerSec,
+		&i.Status, &i.BytesDone, &i.BytesTotal, &i.Error,
+		&i.CreatedAt, &i.CreateUserID, &i.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+// ListUnfinished returns imports that have not completed or failed, oldest first.
+func (r *ImageImportRepository) ListUnfinished(ctx context.Context) ([]ImageImport, error) {
+	rows, err := r.exec.QueryContext(ctx, `
+SELECT id, url, alias, sha256, max_bytes_per_sec, status, bytes_done, bytes_total, error,
+created_at, create_user_id, updated_at
+FROM image_imports WHERE status NOT IN ('completed', 'failed')
+ORDER BY created_at
+`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []ImageImport
+	for rows.Next() {
+		var i ImageImport
+		if err := rows.Scan(
+			&i.ID, &i.URL, &i.Alias, &i.SHA256, &i.MaxBytesPerSec,
+			&i.Status, &i.BytesDone, &i.BytesTotal, &i.Error,
+			&i.CreatedAt, &i.CreateUserID, &i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	return items, nil
+}
@@ -0,0 +1,104 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type JobLease struct {
+	Name          string
+	Holder        string
+	ExpiresAt     time.Time
+	LastRunAt     *time.Time
+	LastSuccessAt *time.Time
+	LastError     *string
+	UpdatedAt     time.Time
+}
+
+type JobLeaseRepository struct {
+	exec sqlExecutor
+}
+
+func NewJobLeaseRepository(db *sql.DB) *JobLeaseRepository {
+	return &JobLeaseRepository{exec: executor(db)}
+}
+
+// TryAcquire takes (or renews) the lease for a job.
+// The lease is granted when nobody holds it, the caller already holds it,
+// or the previous holder let it expire. Returns true if the caller now holds the lease.
+func (r *JobLeaseRepository) TryAcquire(ctx context.Context, name string, holder string, ttl time.Duration) (bool, error) {
+	now := time.Now().UTC()
+	res, err := r.exec.ExecContext(ctx, `
+INSERT INTO job_leases (name, holder, expires_at)
+VALUES (?, ?, ?)
+ON CONFLICT(name) DO UPDATE SET
+holder = excluded.holder, expires_at = excluded.expires_at, updated_at = CURRENT_TIMESTAMP
+WHERE job_leases.holder = excluded.holder OR job_leases.expires_at < ?
+`, name, holder, now.Add(ttl), now)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// Release gives up the lease so another manager can take over immediately.
+func (r *JobLeaseRepository) Release(ctx context.Context, name string, holder string) error {
+	_, err := r.exec.ExecContext(ctx, `
+UPDATE job_leases SET expires_at = ?, updated_at = CURRENT_TIMESTAMP
+WHERE name = ? AND holder = ?
+`, time.Now().UTC(), name, holder)
+	return err
+}
+
+// RecordRun stores the outcome of a job run by holder, creating the job's row on
+// its first run. A nil runErr updates last_success_at.
+func (r *JobLeaseRepository) RecordRun(ctx context.Context, name string, holder string, runErr error) error {
+	now := time.Now().UTC()
+	var lastSuccess *time.Time
+	var lastError *string
+	if runErr == nil {
+		lastSuccess = &now
+	} else {
+		msg := runErr.Error()
+		lastError = &msg
+	}
+
+	_, err := r.exec.ExecContext(ctx, `
+INSERT INTO job_leases (name, holder, expires_at, last_run_at, last_success_at, last_error)
+VALUES (?, ?, ?, ?, ?, ?)
+ON CONFLICT(name) DO UPDATE SET
+holder = excluded.holder, expires_at = excluded.expires_at, last_run_at = excluded.last_run_at,
+last_success_at = COALESCE(excluded.last_success_at, job_leases.last_success_at),
+last_error = excluded.last_error, updated_at = CURRENT_TIMESTAMP
+`, name, holder, now, now, lastSuccess, lastError)
+	return err
+}
+
+func (r *JobLeaseRepository) List(ctx context.Context) ([]JobLease, error) {
+	rows, err := r.exec.QueryContext(ctx, `
+SELECT name, holder, expires_at, last_run_at, last_success_at, last_error, updated_at
+FROM job_leases ORDER BY name
+`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []JobLease
+	for rows.Next() {
+		var l JobLease
+		if err := rows.Scan(
+			&l.Name, &l.Holder, &l.ExpiresAt,
+			&l.LastRunAt, &l.LastSuccessAt, &l.LastError, &l.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, l)
+	}
+	return items, nil
+}
@@ -9,15 +9,26 @@ import (
 type KV struct {
 	Key       string
 	Value     string
+	Version   int64 // starts at 1, incremented on every write
 	UpdatedAt time.Time
 }
 
+// KVChange is one entry of the kv_changes log written by CompareAndSet and CompareAndDelete.
+type KVChange struct {
+	Revision  int64
+	Key       string
+	Value     *string // nil when deleted
+	Version   int64
+	Deleted   bool
+	ChangedAt time.Time
+}
+
 type KVStoreRepository struct {
 	exec sqlExecutor
 }
 
 func NewKVStoreRepository(db *sql.DB) *KVStoreRepository {
-	return &KVStoreRepository{exec: db}
+	return &KVStoreRepository{exec: executor(db)}
 }
 
 func NewKVStoreRepositoryTx(tx *sql.Tx) *KVStoreRepository {
@@ -28,18 +39,18 @@ func (r *KVStoreRepository) Set(ctx context.Context, key, value string) error {
 	_, err := r.exec.ExecContext(ctx, `
 INSERT INTO kv_store (key, value)
 VALUES (?, ?)
-ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = CURRENT_TIMESTAMP
+ON CONFLICT(key) DO UPDATE SET value = excluded.value, version = kv_store.version + 1, updated_at = CURRENT_TIMESTAMP
 `, key, value)
 	return err
 }
 
 func (r *KVStoreRepository) Get(ctx context.Context, key string) (*KV, error) {
 	row := r.exec.QueryRowContext(ctx, `
-SELECT key, value, updated_at FROM kv_store WHERE key = ?
+SELECT key, value, version, updated_at FROM kv_store WHERE key = ?
 `, key)
 
 	var kv KV
-	if err := row.Scan(&kv.Key, &kv.Value, &kv.UpdatedAt); err != nil {
+	if err := row.Scan(&kv.Key, &kv.Value, &kv.Version, &kv.UpdatedAt); err != nil {
 		return nil, err
 	}
 	return &kv, nil
@@ -51,9 +62,21 @@ func (r *KVStoreRepository) Delete(ctx context.Context, key string) error {
 }
 
 func (r *KVStoreRepository) List(ctx context.Context) ([]KV, error) {
-	rows, err := r.exec.QueryContext(ctx, `
-SELECT key, value, updated_at FROM kv_store
+	return r.list(ctx, `
+SELECT key, value, version, updated_at FROM kv_store
 `)
+}
+
+// ListPrefix returns the keys starting with prefix, ordered by key.
+func (r *KVStoreRepository) ListPrefix(ctx context.Context, prefix string) ([]KV, error) {
+	return r.list(ctx, `
+SELECT key, value, version, updated_at FROM kv_store
+WHERE substr(key, 1, length(?)) = ? ORDER BY key
+`, prefix, prefix)
+}
+
+func (r *KVStoreRepository) list(ctx context.Context, query string, args ...any) ([]KV, error) {
+	rows, err := r.exec.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -62,10 +85,94 @@ SELECT key, value, updated_at FROM kv_store
 	var items []KV
 	for rows.Next() {
 		var kv KV
-		if err := rows.Scan(&kv.Key, &kv.Value, &kv.UpdatedAt); err != nil {
+		if err := rows.Scan(&kv.Key, &kv.Value, &kv.Version, &kv.UpdatedAt); err != nil {
 			return nil, err
 		}
 		items = append(items, kv)
 	}
-	return items, nil
+	return items, rows.Err()
+}
+
+// CompareAndSet writes key only if its current version is expected (0: the key
+// must not exist yet). It returns false without writing when another writer got
+// there first. The change is appended to kv_changes; call it inside a transaction.
+func (r *KVStoreRepository) CompareAndSet(ctx context.Context, key, value string, expected int64) (bool, error) {
+	var res sql.Result
+	var err error
+	if expected == 0 {
+		res, err = r.exec.ExecContext(ctx, `
+INSERT INTO kv_store (key, value, version) VALUES (?, ?, 1)
+ON CONFLICT(key) DO NOTHING
+`, key, value)
+	} else {
+		res, err = r.exec.ExecContext(ctx, `
+UPDATE kv_store SET value = ?, version = version + 1, updated_at = CURRENT_TIMESTAMP
+WHERE key = ? AND version = ?
+`, value, key, expected)
+	}
+	if err != nil {
+		return false, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return false, nil
+	}
+
+	_, err = r.exec.ExecContext(ctx, `
+INSERT INTO kv_changes (key, value, version) VALUES (?, ?, ?)
+`, key, value, expected+1)
+	return err == nil, err
+}
+
+// CompareAndDelete deletes key only if its current version is expected and
+// appends the deletion to kv_changes; call it inside a transaction.
+func (r *KVStoreRepository) CompareAndDelete(ctx context.Context, key string, expected int64) (bool, error) {
+	res, err := r.exec.ExecContext(ctx, `DELETE FROM kv_store WHERE key = ? AND version = ?`, key, expected)
+	if err != nil {
+		return false, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return false, nil
+	}
+
+	_, err = r.exec.ExecContext(ctx, `
+INSERT INTO kv_changes (key, version, deleted) VALUES (?, ?, 1)
+`, key, expected+1)
+	return err == nil, err
+}
+
+// ListChangesAfter returns up to limit changes to keys starting with prefix
+// with a revision above after, oldest first.
+func (r *KVStoreRepository) ListChangesAfter(ctx context.Context, prefix string, after int64, limit int) ([]KVChange, error) {
+	rows, err := r.exec.QueryContext(ctx, `
+SELECT revision, key, value, version, deleted, changed_at FROM kv_changes
+WHERE revision > ? AND substr(key, 1, length(?)) = ?
+ORDER BY revision LIMIT ?
+`, after, prefix, prefix, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []KVChange
+	for rows.Next() {
+		var c KVChange
+		if err := rows.Scan(&c.Revision, &c.Key, &c.Value, &c.Version, &c.Deleted, &c.ChangedAt); err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
+
+// LatestRevision returns the newest kv_changes revision (0 when there is none).
+func (r *KVStoreRepository) LatestRevision(ctx context.Context) (int64, error) {
+	var rev int64
+	err := r.exec.QueryRowContext(ctx, `SELECT COALESCE(MAX(revision), 0) FROM kv_changes`).Scan(&rev)
+	return rev, err
+}
+
+// DeleteChangesBefore prunes the change log; watchers further behind re-read the current values.
+func (r *KVStoreRepository) DeleteChangesBefore(ctx context.Context, before time.Time) error {
+	_, err := r.exec.ExecContext(ctx, `DELETE FROM kv_changes WHERE changed_at < ?`, before.UTC())
+	return err
 }
@@ -0,0 +1,114 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestKVStoreRepository(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	repo := NewKVStoreRepository(db)
+
+	if _, err := repo.Get(ctx, "missing"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Get(missing) error = %v, want sql.ErrNoRows", err)
+	}
+	for _, kv := range [][2]string{{"config/b", "1"}, {"config/a", "1"}, {"other", "x"}, {"config/a", "2"}} {
+		if err := repo.Set(ctx, kv[0], kv[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := repo.Get(ctx, "config/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Value != "2" || got.Version != 2 {
+		t.Errorf("Get(config/a) = %+v, want value 2 at version 2", got)
+	}
+
+	items, err := repo.ListPrefix(ctx, "config/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].Key != "config/a" || items[1].Key != "config/b" {
+		t.Errorf("ListPrefix(config/) = %+v", items)
+	}
+	if items, err := repo.List(ctx); err != nil || len(items) != 3 {
+		t.Errorf("List = %d keys, %v; want 3", len(items), err)
+	}
+
+	if err := repo.Delete(ctx, "other"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Get(ctx, "other"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Get(other) after Delete error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestKVStoreCompareAndSet(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	repo := NewKVStoreRepository(db)
+
+	steps := []struct {
+		name string
+		do   func() (bool, error)
+		want bool
+	}{
+		{"create", func() (bool, error) { return repo.CompareAndSet(ctx, "lock/leader", "node1", 0) }, true},
+		{"create again", func() (bool, error) { return repo.CompareAndSet(ctx, "lock/leader", "node2", 0) }, false},
+		{"update stale version", func() (bool, error) { return repo.CompareAndSet(ctx, "lock/leader", "node2", 2) }, false},
+		{"update", func() (bool, error) { return repo.CompareAndSet(ctx, "lock/leader", "node2", 1) }, true},
+		{"delete stale version", func() (bool, error) { return repo.CompareAndDelete(ctx, "lock/leader", 1) }, false},
+		{"delete", func() (bool, error) { return repo.CompareAndDelete(ctx, "lock/leader", 2) }, true},
+		{"other prefix", func() (bool, error) { return repo.CompareAndSet(ctx, "config/x", "1", 0) }, true},
+	}
+	for _, s := range steps {
+		ok, err := s.do()
+		if err != nil {
+			t.Fatalf("%s: %v", s.name, err)
+		}
+		if ok != s.want {
+			t.Errorf("%s = %v, want %v", s.name, ok, s.want)
+		}
+	}
+
+	// Only successful writes are logged
+	changes, err := repo.ListChangesAfter(ctx, "lock/", 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 3 {
+		t.Fatalf("ListChangesAfter(lock/) = %+v, want 3 changes", changes)
+	}
+	if c := changes[0]; c.Value == nil || *c.Value != "node1" || c.Version != 1 || c.Deleted {
+		t.Errorf("first change = %+v", c)
+	}
+	if c := changes[2]; c.Value != nil || c.Version != 3 || !c.Deleted {
+		t.Errorf("deletion = %+v", c)
+	}
+	after, err := repo.ListChangesAfter(ctx, "lock/", changes[0].Revision, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(after) != 1 || after[0].Revision != changes[1].Revision {
+		t.Errorf("ListChangesAfter(first, limit 1) = %+v", after)
+	}
+
+	latest, err := repo.LatestRevision(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest != changes[2].Revision+1 {
+		t.Errorf("LatestRevision = %d, want %d", latest, changes[2].Revision+1)
+	}
+	if err := repo.DeleteChangesBefore(ctx, time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if changes, err := repo.ListChangesAfter(ctx, "", 0, 10); err != nil || len(changes) != 0 {
+		t.Errorf("changes after pruning = %+v, %v", changes, err)
+	}
+}
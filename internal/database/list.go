@@ -0,0 +1,135 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"mcloud/pkg/reason"
+)
+
+// ErrInvalidListOption is returned when ListOptions sorts or filters by a field
+// the list does not support.
+var ErrInvalidListOption = reason.New(reason.InvalidRequest, "invalid list option")
+
+// ListOptions page, sort and filter a list query. The zero value returns every
+// row in the list's default order.
+//
+// Sort and Filter use the field names of the REST API (e.g. "node", not
+// "node_id"); each list declares which fields it supports.
+//
+// Example Input:
+//   ListOptions{Limit: 50, Offset: 100, Sort: "-created_at", Filter: map[string]string{"status": "running"}}
+type ListOptions struct {
+	Limit  int               // maximum rows; 0 for no limit
+	Offset int               // rows to skip
+	Sort   string            // field to sort by, "-" prefix for descending; "" for the default order
+	Filter map[string]string // field -> exact value
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows, so one scan function
+// per table serves single-row and list queries alike.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// listQuery describes the list queries of one table: the SELECT they share and
+// the fields ListOptions may sort and filter by.
+type listQuery struct {
+	selectSQL   string            // "SELECT <columns> FROM <table>"
+	defaultSort string            // ORDER BY clause without Sort, e.g. "created_at, id"
+	sortable    map[string]string // API field -> column
+	filterable  map[string]string // API field -> column
+}
+
+// build returns the SQL and arguments of a list query. where and args are the
+// conditions the caller always applies (e.g. "cluster_id = ?"); ListOptions
+// filters are ANDed to them. Sorting always ends on rowid so pages are stable.
+//
+// Example Input:
+//   nodeList.build(ListOptions{Limit: 10, Sort: "-hostname", Filter: {"status": "ready"}}, []string{"cluster_id = ?"}, "c1")
+//
+// Example Output:
+//   "SELECT ... FROM nodes WHERE cluster_id = ? AND status = ? ORDER BY hostname DESC, rowid LIMIT ? OFFSET ?",
+//   ["c1", "ready", 10, 0]
+//
+// Example Output (Error):
+//   Sort: "ip"  =>  ErrInvalidListOption: cannot sort by "ip"
+func (q listQuery) build(opts ListOptions, where []string, args ...any) (string, []any, error) {
+	var b strings.Builder
+	b.WriteString(q.selectSQL)
+
+	// Filters are applied in field order so the same options give the same SQL
+	fields := make([]string, 0, len(opts.Filter))
+	for field := range opts.Filter {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		column, ok := q.filterable[field]
+		if !ok {
+			return "", nil, fmt.Errorf("%w: cannot filter by %q", ErrInvalidListOption, field)
+		}
+		where = append(where, column+" = ?")
+		args = append(args, opts.Filter[field])
+	}
+	if len(where) > 0 {
+		b.WriteString(" WHERE " + strings.Join(where, " AND "))
+	}
+
+	order := q.defaultSort
+	if opts.Sort != "" {
+		field, desc := strings.CutPrefix(opts.Sort, "-")
+		column, ok := q.sortable[field]
+		if !ok {
+			return "", nil, fmt.Errorf("%w: cannot sort by %q", ErrInvalidListOption, field)
+		}
+		if desc {
+			column += " DESC"
+		}
+		order = column + ", rowid"
+	}
+	b.WriteString(" ORDER BY " + order)
+
+	if opts.Limit > 0 || opts.Offset > 0 {
+		// SQLite needs a LIMIT before OFFSET; -1 means no limit
+		limit := opts.Limit
+		if limit <= 0 {
+			limit = -1
+		}
+		b.WriteString(" LIMIT ? OFFSET ?")
+		args = append(args, limit, opts.Offset)
+	}
+	return b.String(), args, nil
+}
+
+// each runs a list query and calls fn for every row as it is read from the cursor.
+func each[T any](ctx context.Context, exec sqlExecutor, query string, args []any, scan func(rowScanner, *T) error, fn func(*T) error) error {
+	rows, err := exec.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item T
+		if err := scan(rows, &item); err != nil {
+			return err
+		}
+		if err := fn(&item); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// collect runs a list query and returns all of its rows.
+func collect[T any](ctx context.Context, exec sqlExecutor, query string, args []any, scan func(rowScanner, *T) error) ([]T, error) {
+	var items []T
+	err := each(ctx, exec, query, args, scan, func(item *T) error {
+		items = append(items, *item)
+		return nil
+	})
+	return items, err
+}
@@ -0,0 +1,107 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestListQueryBuild(t *testing.T) {
+	q := listQuery{
+		selectSQL:   "SELECT id FROM nodes",
+		defaultSort: "created_at, id",
+		sortable:    map[string]string{"hostname": "hostname"},
+		filterable:  map[string]string{"status": "status", "cluster": "cluster_id"},
+	}
+
+	tests := []struct {
+		name     string
+		opts     ListOptions
+		where    []string
+		args     []any
+		wantSQL  string
+		wantArgs []any
+		wantErr  bool
+	}{
+		{"zero value lists everything", ListOptions{}, nil, nil,
+			"SELECT id FROM nodes ORDER BY created_at, id", nil, false},
+		{"page", ListOptions{Limit: 10, Offset: 20}, nil, nil,
+			"SELECT id FROM nodes ORDER BY created_at, id LIMIT ? OFFSET ?", []any{10, 20}, false},
+		{"offset without limit", ListOptions{Offset: 5}, nil, nil,
+			"SELECT id FROM nodes ORDER BY created_at, id LIMIT ? OFFSET ?", []any{-1, 5}, false},
+		{"sort descending", ListOptions{Sort: "-hostname"}, nil, nil,
+			"SELECT id FROM nodes ORDER BY hostname DESC, rowid", nil, false},
+		{"filters after caller conditions, in field order", ListOptions{Filter: map[string]string{"status": "ready", "cluster": "c1"}},
+			[]string{"role = ?"}, []any{"manager"},
+			"SELECT id FROM nodes WHERE role = ? AND cluster_id = ? AND status = ? ORDER BY created_at, id", []any{"manager", "c1", "ready"}, false},
+		{"unknown sort", ListOptions{Sort: "ip"}, nil, nil, "", nil, true},
+		{"unknown filter", ListOptions{Filter: map[string]string{"ip": "10.0.0.1"}}, nil, nil, "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotSQL, gotArgs, err := q.build(tt.opts, tt.where, tt.args...)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidListOption) {
+					t.Fatalf("err = %v, want ErrInvalidListOption", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if gotSQL != tt.wantSQL {
+				t.Errorf("sql = %q, want %q", gotSQL, tt.wantSQL)
+			}
+			if !reflect.DeepEqual(gotArgs, tt.wantArgs) {
+				t.Errorf("args = %v, want %v", gotArgs, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestNodeListByCluster(t *testing.T) {
+	d := openTestDatabase(t, "mcloud.db")
+	if err := d.Migrate(); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	ctx := context.Background()
+
+	if _, err := d.db.Exec(`INSERT INTO clusters (id, name, state) VALUES ('c1', 'prod', 'active'), ('c2', 'dev', 'active')`); err != nil {
+		t.Fatal(err)
+	}
+	repo := NewNodeRepository(d.db)
+	for i, status := range []string{"online", "offline", "online", "online"} {
+		n := &Node{ID: fmt.Sprintf("n%d", i), ClusterID: "c1", Hostname: fmt.Sprintf("node%d", i), IP: fmt.Sprintf("10.0.0.%d", i+1), Role: "worker", Status: status}
+		if err := repo.Create(ctx, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.Create(ctx, &Node{ID: "other", ClusterID: "c2", Hostname: "dev1", IP: "10.0.1.1", Role: "worker", Status: "online"}); err != nil {
+		t.Fatal(err)
+	}
+
+	hostnames := func(opts ListOptions) []string {
+		t.Helper()
+		nodes, err := repo.ListByCluster(ctx, "c1", opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, n := range nodes {
+			names = append(names, n.Hostname)
+		}
+		return names
+	}
+
+	if got, want := hostnames(ListOptions{}), []string{"node0", "node1", "node2", "node3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("all = %v, want %v", got, want)
+	}
+	if got, want := hostnames(ListOptions{Sort: "-hostname", Limit: 2, Offset: 1}), []string{"node2", "node1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("page = %v, want %v", got, want)
+	}
+	if got, want := hostnames(ListOptions{Sort: "hostname", Filter: map[string]string{"status": "online"}}), []string{"node0", "node2", "node3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("online = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,141 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// LoadBalancer balances a VIP and port over the workloads its selectors
+// match, see migration 037. Backends are the workload addresses last
+// programmed into OVN.
+type LoadBalancer struct {
+	Name         string                 `json:"name"`
+	VIP          string                 `json:"vip"`
+	Network      string                 `json:"network"`
+	Protocol     string                 `json:"protocol"`
+	Port         int                    `json:"port"`
+	TargetPort   int                    `json:"target_port"`
+	Selectors    []LoadBalancerSelector `json:"selectors"`
+	Backends     AddressList            `json:"backends"`
+	CreatedAt    time.Time              `json:"created_at"`
+	CreateUserID *string                `json:"create_user_id"`
+	UpdatedAt    time.Time              `json:"updated_at"`
+}
+
+// LoadBalancerSelector matches the workloads of Project whose name matches
+// Name, a glob (path.Match) such as web-*.
+//
+// Example JSON:
+//   {"project": "default", "name": "web-*"}
+type LoadBalancerSelector struct {
+	Project string `json:"project"`
+	Name    string `json:"name"`
+}
+
+type LoadBalancerRepository struct {
+	exec sqlExecutor
+}
+
+func NewLoadBalancerRepository(db *sql.DB) *LoadBalancerRepository {
+	return &LoadBalancerRepository{exec: executor(db)}
+}
+
+func NewLoadBalancerRepositoryTx(tx *sql.Tx) *LoadBalancerRepository {
+	return &LoadBalancerRepository{exec: tx}
+}
+
+// Create inserts a load balancer and its selectors; use it in a transaction.
+func (r *LoadBalancerRepository) Create(ctx context.Context, lb *LoadBalancer) error {
+	_, err := r.exec.ExecContext(ctx, `
+INSERT INTO load_balancers (name, vip, network, protocol, port, target_port, backends, create_user_id)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+`, lb.Name, lb.VIP, lb.Network, lb.Protocol, lb.Port, lb.TargetPort, lb.Backends, lb.CreateUserID)
+	if err != nil {
+		return err
+	}
+	for i, sel := range lb.Selectors {
+		_, err := r.exec.ExecContext(ctx, `
+INSERT INTO load_balancer_selectors (load_balancer, position, project, name)
+VALUES (?, ?, ?, ?)
+`, lb.Name, i, sel.Project, sel.Name)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get returns a load balancer with its selectors.
+func (r *LoadBalancerRepository) Get(ctx context.Context, name string) (*LoadBalancer, error) {
+	var lb LoadBalancer
+	if err := scanLoadBalancer(r.exec.QueryRowContext(ctx, loadBalancerSelect+` WHERE name = ?`, name), &lb); err != nil {
+		return nil, err
+	}
+	var err error
+	lb.Selectors, err = r.selectors(ctx, name)
+	return &lb, err
+}
+
+func (r *LoadBalancerRepository) selectors(ctx context.Context, name string) ([]LoadBalancerSelector, error) {
+	selectors, err := collect(ctx, r.exec, `
+SELECT project, name FROM load_balancer_selectors
+WHERE load_balancer = ? ORDER BY position
+`, []any{name}, scanLoadBalancerSelector)
+	if selectors == nil {
+		selectors = []LoadBalancerSelector{}
+	}
+	return selectors, err
+}
+
+// GetByVIP returns the load balancer of a VIP, without its selectors.
+func (r *LoadBalancerRepository) GetByVIP(ctx context.Context, vip string) (*LoadBalancer, error) {
+	var lb LoadBalancer
+	if err := scanLoadBalancer(r.exec.QueryRowContext(ctx, loadBalancerSelect+` WHERE vip = ?`, vip), &lb); err != nil {
+		return nil, err
+	}
+	return &lb, nil
+}
+
+// List returns all load balancers by name, with their selectors.
+func (r *LoadBalancerRepository) List(ctx context.Context) ([]LoadBalancer, error) {
+	lbs, err := collect(ctx, r.exec, loadBalancerSelect+` ORDER BY name`, nil, scanLoadBalancer)
+	if err != nil {
+		return nil, err
+	}
+	for i := range lbs {
+		if lbs[i].Selectors, err = r.selectors(ctx, lbs[i].Name); err != nil {
+			return nil, err
+		}
+	}
+	return lbs, nil
+}
+
+// UpdateBackends records the backends programmed into OVN.
+func (r *LoadBalancerRepository) UpdateBackends(ctx context.Context, name string, backends AddressList) error {
+	_, err := r.exec.ExecContext(ctx, `
+UPDATE load_balancers
+SET backends = ?, updated_at = CURRENT_TIMESTAMP
+WHERE name = ?
+`, backends, name)
+	return err
+}
+
+// Delete removes a load balancer and its selectors; use it in a transaction.
+func (r *LoadBalancerRepository) Delete(ctx context.Context, name string) error {
+	if _, err := r.exec.ExecContext(ctx, `DELETE FROM load_balancer_selectors WHERE load_balancer = ?`, name); err != nil {
+		return err
+	}
+	_, err := r.exec.ExecContext(ctx, `DELETE FROM load_balancers WHERE name = ?`, name)
+	return err
+}
+
+const loadBalancerSelect = `SELECT name, vip, network, protocol, port, target_port, backends, created_at, create_user_id, updated_at FROM load_balancers`
+
+func scanLoadBalancer(row rowScanner, lb *LoadBalancer) error {
+	return row.Scan(&lb.Name, &lb.VIP, &lb.Network, &lb.Protocol, &lb.Port, &lb.TargetPort, &lb.Backends, &lb.CreatedAt, &lb.CreateUserID, &lb.UpdatedAt)
+}
+
+func scanLoadBalancerSelector(row rowScanner, sel *LoadBalancerSelector) error {
+	return row.Scan(&sel.Project, &sel.Name)
+}
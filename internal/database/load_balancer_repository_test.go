@@ -0,0 +1,65 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestLoadBalancerRepository(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	repo := NewLoadBalancerRepository(db)
+
+	for _, lb := range []*LoadBalancer{
+		{Name: "web", VIP: "203.0.113.10", Network: "default", Protocol: "tcp", Port: 80, TargetPort: 8080,
+			Selectors: []LoadBalancerSelector{{Project: "default", Name: "web-*"}, {Project: "team-a", Name: "api"}}},
+		{Name: "dns", VIP: "203.0.113.11", Network: "default", Protocol: "udp", Port: 53, TargetPort: 53,
+			Selectors: []LoadBalancerSelector{{Project: "default", Name: "*"}}},
+	} {
+		if err := repo.Create(ctx, lb); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.Create(ctx, &LoadBalancer{Name: "web2", VIP: "203.0.113.10", Network: "default", Protocol: "tcp", Port: 443, TargetPort: 443}); err == nil {
+		t.Error("second load balancer on VIP 203.0.113.10 was stored")
+	}
+	if err := repo.Create(ctx, &LoadBalancer{Name: "sctp", VIP: "203.0.113.12", Network: "default", Protocol: "sctp", Port: 1, TargetPort: 1}); err == nil {
+		t.Error("load balancer with protocol sctp was stored")
+	}
+
+	if err := repo.UpdateBackends(ctx, "web", AddressList{"10.0.0.5", "10.0.0.6"}); err != nil {
+		t.Fatal(err)
+	}
+	lb, err := repo.Get(ctx, "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(lb.Backends, AddressList{"10.0.0.5", "10.0.0.6"}) || len(lb.Selectors) != 2 || lb.Selectors[1].Project != "team-a" {
+		t.Errorf("Get(web) = %+v", lb)
+	}
+	if lb, err := repo.GetByVIP(ctx, "203.0.113.11"); err != nil || lb.Name != "dns" {
+		t.Errorf("GetByVIP(203.0.113.11) = %+v, %v", lb, err)
+	}
+
+	lbs, err := repo.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lbs) != 2 || lbs[0].Name != "dns" || len(lbs[0].Selectors) != 1 || len(lbs[0].Backends) != 0 {
+		t.Errorf("List() = %+v", lbs)
+	}
+
+	if err := repo.Delete(ctx, "web"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Get(ctx, "web"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Get(deleted web) = %v, want sql.ErrNoRows", err)
+	}
+	var selectors int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM load_balancer_selectors WHERE load_balancer = 'web'`).Scan(&selectors); err != nil || selectors != 0 {
+		t.Errorf("%d selectors of deleted web left, %v", selectors, err)
+	}
+}
@@ -0,0 +1,86 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// MaintenanceWindow opens every week on Day ("sun") at StartAt ("HH:MM", local
+// time) for DurationMinutes; Snaps are refreshed during it, one node at a time.
+type MaintenanceWindow struct {
+	ClusterID       string     `json:"cluster_id"`
+	Day             string     `json:"day"`
+	StartAt         string     `json:"start_at"`
+	DurationMinutes int        `json:"duration_minutes"`
+	Snaps           string     `json:"snaps"` // e.g. "lxd,microceph,microovn"
+	LastRunAt       *time.Time `json:"last_run_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+type MaintenanceWindowRepository struct {
+	exec sqlExecutor
+}
+
+func NewMaintenanceWindowRepository(db *sql.DB) *MaintenanceWindowRepository {
+	return &MaintenanceWindowRepository{exec: executor(db)}
+}
+
+func NewMaintenanceWindowRepositoryTx(tx *sql.Tx) *MaintenanceWindowRepository {
+	return &MaintenanceWindowRepository{exec: tx}
+}
+
+const maintenanceWindowColumns = `cluster_id, day, start_at, duration_minutes, snaps, last_run_at, created_at, updated_at`
+
+func scanMaintenanceWindow(row rowScanner, w *MaintenanceWindow) error {
+	return row.Scan(&w.ClusterID, &w.Day, &w.StartAt, &w.DurationMinutes, &w.Snaps, &w.LastRunAt, &w.CreatedAt, &w.UpdatedAt)
+}
+
+// Upsert creates or replaces a cluster's window; windows opening up to
+// lastRunAt are treated as already handled.
+func (r *MaintenanceWindowRepository) Upsert(ctx context.Context, w *MaintenanceWindow, lastRunAt time.Time) error {
+	_, err := r.exec.ExecContext(ctx, `
+INSERT INTO maintenance_windows (cluster_id, day, start_at, duration_minutes, snaps, last_run_at)
+VALUES (?, ?, ?, ?, ?, ?)
+ON CONFLICT(cluster_id) DO UPDATE SET
+day = excluded.day, start_at = excluded.start_at, duration_minutes = excluded.duration_minutes,
+snaps = excluded.snaps, last_run_at = excluded.last_run_at, updated_at = CURRENT_TIMESTAMP
+`, w.ClusterID, w.Day, w.StartAt, w.DurationMinutes, w.Snaps, lastRunAt.UTC())
+	return err
+}
+
+func (r *MaintenanceWindowRepository) GetByCluster(ctx context.Context, clusterID string) (*MaintenanceWindow, error) {
+	row := r.exec.QueryRowContext(ctx, `SELECT `+maintenanceWindowColumns+` FROM maintenance_windows WHERE cluster_id = ?`, clusterID)
+	var w MaintenanceWindow
+	if err := scanMaintenanceWindow(row, &w); err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+// List returns the windows of every cluster.
+func (r *MaintenanceWindowRepository) List(ctx context.Context) ([]MaintenanceWindow, error) {
+	return collect(ctx, r.exec, `SELECT `+maintenanceWindowColumns+` FROM maintenance_windows ORDER BY cluster_id`, nil, scanMaintenanceWindow)
+}
+
+// DeleteByCluster removes a cluster's window. Returns sql.ErrNoRows if it had none.
+func (r *MaintenanceWindowRepository) DeleteByCluster(ctx context.Context, clusterID string) error {
+	res, err := r.exec.ExecContext(ctx, `DELETE FROM maintenance_windows WHERE cluster_id = ?`, clusterID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// SetLastRunAt records the opening of the window a rollout was started in.
+func (r *MaintenanceWindowRepository) SetLastRunAt(ctx context.Context, clusterID string, at time.Time) error {
+	_, err := r.exec.ExecContext(ctx, `
+UPDATE maintenance_windows SET last_run_at = ?
+WHERE cluster_id = ?
+`, at.UTC(), clusterID)
+	return err
+}
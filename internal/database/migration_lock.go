@@ -0,0 +1,116 @@
+package database
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"mcloud/pkg/reason"
+)
+
+const (
+	// migrationLockTTL is how long the migration lock is valid without renewal.
+	// A process that dies while migrating blocks others for at most this long.
+	migrationLockTTL = time.Minute
+
+	// migrationLockWait is how long Migrate waits for another process to finish
+	// its migrations before giving up with ErrMigrationLocked.
+	migrationLockWait = 5 * time.Minute
+
+	// migrationLockPoll is the delay between attempts to take the lock.
+	migrationLockPoll = time.Second
+)
+
+// ErrMigrationLocked is returned by Migrate when another process kept the
+// migration lock for longer than migrationLockWait.
+var ErrMigrationLocked = reason.New(reason.MigrationLocked, "database migrations are being applied by another process")
+
+// ensureMigrationLockTable creates the single-row migration lock table.
+// It is created outside the numbered migrations because it guards them.
+func (s *Database) ensureMigrationLockTable() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migration_lock (
+			id INTEGER PRIMARY KEY CHECK (id = 1),
+			holder TEXT NOT NULL,
+			expires_at DATETIME NOT NULL,
+			acquired_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	return err
+}
+
+// tryMigrationLock takes (or renews) the lease on the migration lock.
+// It succeeds when nobody holds it, the caller already holds it, or the lease expired.
+func (s *Database) tryMigrationLock(holder string) (bool, error) {
+	now := time.Now().UTC()
+	res, err := s.db.Exec(`
+INSERT INTO schema_migration_lock (id, holder, expires_at)
+VALUES (1, ?, ?)
+ON CONFLICT(id) DO UPDATE SET
+holder = excluded.holder, expires_at = excluded.expires_at
+WHERE schema_migration_lock.holder = excluded.holder OR schema_migration_lock.expires_at < ?
+`, holder, now.Add(migrationLockTTL), now)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// acquireMigrationLock waits up to migrationLockWait for the migration lock.
+// While held, the lease is renewed in the background; call the returned
+// function to stop renewing and release it.
+func (s *Database) acquireMigrationLock() (release func(), err error) {
+	hostname, _ := os.Hostname()
+	holder := fmt.Sprintf("%s:%d:%d", hostname, os.Getpid(), time.Now().UnixNano())
+
+	deadline := time.Now().Add(migrationLockWait)
+	waiting := false
+	for {
+		ok, err := s.tryMigrationLock(holder)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			return nil, ErrMigrationLocked
+		}
+		if !waiting {
+			fmt.Printf("Waiting for another process to finish database migrations\n")
+			waiting = true
+		}
+		time.Sleep(migrationLockPoll)
+	}
+
+	// Keep the lease alive during long migrations
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(migrationLockTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if _, err := s.tryMigrationLock(holder); err != nil {
+					fmt.Printf("Failed to renew migration lock: %v\n", err)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+		if _, err := s.db.Exec(`DELETE FROM schema_migration_lock WHERE id = 1 AND holder = ?`, holder); err != nil {
+			fmt.Printf("Failed to release migration lock: %v\n", err)
+		}
+	}, nil
+}
@@ -0,0 +1,275 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"mcloud/pkg/reason"
+)
+
+// migrationFiles holds the SQL migrations compiled into the binary, so mcloudd
+// and mcloudctl can migrate the database from any working directory.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+const (
+	// migrationsDir is the directory of migrationFiles holding the .sql files.
+	migrationsDir = "migrations"
+
+	// downSuffix marks the file that reverts a migration: 005_node_metrics.down.sql
+	// reverts 005_node_metrics.sql. The first migration has none, so a rollback can
+	// never drop the base schema.
+	downSuffix = ".down.sql"
+)
+
+// ErrMigrationChecksum is returned by Migrate when an applied migration no longer
+// matches the file embedded in the binary. Applied migrations must never be
+// edited; add a new migration instead.
+var ErrMigrationChecksum = reason.New(reason.MigrationModified, "applied migration was modified")
+
+// ErrIrreversible is returned by Rollback when a migration to revert has no down file.
+var ErrIrreversible = reason.New(reason.MigrationIrreversible, "migration cannot be rolled back")
+
+// migration is one embedded .sql file with its down file, if any.
+type migration struct {
+	filename string
+	sql      string
+	checksum string // hex SHA-256 of the file
+	down     string // SQL that reverts it; empty if irreversible
+}
+
+// loadMigrations returns the embedded migrations sorted by filename
+// (e.g. 001_init.sql, 002_job_leases.sql).
+func loadMigrations() ([]migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, migrationsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []migration
+	for _, e := range entries {
+		if e.IsDir() || path.Ext(e.Name()) != ".sql" || strings.HasSuffix(e.Name(), downSuffix) {
+			continue
+		}
+		data, err := migrationFiles.ReadFile(path.Join(migrationsDir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		down, err := migrationFiles.ReadFile(path.Join(migrationsDir, strings.TrimSuffix(e.Name(), ".sql")+downSuffix))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		sum := sha256.Sum256(data)
+		migrations = append(migrations, migration{
+			filename: e.Name(),
+			sql:      string(data),
+			checksum: hex.EncodeToString(sum[:]),
+			down:     string(down),
+		})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].filename < migrations[j].filename })
+	return migrations, nil
+}
+
+// ensureChecksumColumn adds schema_migrations.checksum to databases created
+// before checksums were recorded.
+func (s *Database) ensureChecksumColumn() error {
+	var count int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('schema_migrations') WHERE name = 'checksum'`).Scan(&count)
+	if err != nil || count > 0 {
+		return err
+	}
+	_, err = s.db.Exec(`ALTER TABLE schema_migrations ADD COLUMN checksum TEXT`)
+	return err
+}
+
+// appliedChecksum returns the recorded checksum of a migration and whether it
+// was applied at all. The checksum is empty for migrations applied before
+// checksums were recorded.
+func (s *Database) appliedChecksum(filename string) (string, bool, error) {
+	var checksum sql.NullString
+	err := s.db.QueryRow("SELECT checksum FROM schema_migrations WHERE filename = ?", filename).Scan(&checksum)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return checksum.String, true, nil
+}
+
+// verifyMigration checks an applied migration against the embedded file. A
+// migration applied before checksums were recorded gets the current checksum.
+//
+// Example Output (Error - Drift):
+//   Returns: error("applied migration was modified: 003_audit_log.sql (recorded sha256 9f2c..., embedded 41ab...)")
+func (s *Database) verifyMigration(m migration, recorded string) error {
+	if recorded == "" {
+		_, err := s.db.Exec("UPDATE schema_migrations SET checksum = ? WHERE filename = ?", m.checksum, m.filename)
+		return err
+	}
+	if recorded != m.checksum {
+		return fmt.Errorf("%w: %s (recorded sha256 %s, embedded %s)", ErrMigrationChecksum, m.filename, recorded, m.checksum)
+	}
+	return nil
+}
+
+// MigrationStatus is one migration as seen by `mcloudctl db status`.
+//
+// Example JSON:
+//   {"filename": "017_workload_priority.sql", "state": "applied",
+//    "applied_at": "2026-01-03T10:30:45Z", "reversible": true}
+type MigrationStatus struct {
+	Filename   string     `json:"filename"`
+	State      string     `json:"state"` // pending, applied, modified (checksum drift) or unknown (applied, not in this binary)
+	AppliedAt  *time.Time `json:"applied_at,omitempty"`
+	Reversible bool       `json:"reversible"`
+}
+
+// Status lists every embedded migration and every applied one, sorted by filename.
+func (s *Database) Status() ([]MigrationStatus, error) {
+	if err := s.ensureMigrationsTable(); err != nil {
+		return nil, err
+	}
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	type applied struct {
+		checksum  string
+		appliedAt time.Time
+	}
+	rows, err := s.db.Query("SELECT filename, COALESCE(checksum, ''), applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	done := map[string]applied{}
+	for rows.Next() {
+		var name string
+		var a applied
+		if err := rows.Scan(&name, &a.checksum, &a.appliedAt); err != nil {
+			return nil, err
+		}
+		done[name] = a
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var out []MigrationStatus
+	for _, m := range migrations {
+		st := MigrationStatus{Filename: m.filename, State: "pending", Reversible: m.down != ""}
+		if a, ok := done[m.filename]; ok {
+			st.State = "applied"
+			if a.checksum != "" && a.checksum != m.checksum {
+				st.State = "modified"
+			}
+			st.AppliedAt = &a.appliedAt
+			delete(done, m.filename)
+		}
+		out = append(out, st)
+	}
+	for name, a := range done {
+		out = append(out, MigrationStatus{Filename: name, State: "unknown", AppliedAt: &a.appliedAt})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Filename < out[j].Filename })
+	return out, nil
+}
+
+// Rollback reverts the last n applied migrations, newest first, with their down
+// files. Each one runs in a transaction together with the removal of its
+// schema_migrations row, so a failed down migration leaves it applied. Nothing is
+// reverted unless all n have a down file in this binary.
+//
+// mcloudd applies pending migrations when it starts, so after rolling back run the
+// binary that matches the schema, or the migrations are applied again.
+//
+// Example Output:
+//   []string{"017_workload_priority.sql", "016_kv_store_versions.sql"}
+//
+// Example Output (Error - No Down File):
+//   Returns: error("migration cannot be rolled back: 001_init.sql has no down migration")
+func (s *Database) Rollback(n int) ([]string, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	if err := s.ensureMigrationsTable(); err != nil {
+		return nil, err
+	}
+	if err := s.ensureMigrationLockTable(); err != nil {
+		return nil, err
+	}
+	release, err := s.acquireMigrationLock()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	byName := map[string]migration{}
+	for _, m := range migrations {
+		byName[m.filename] = m
+	}
+
+	rows, err := s.db.Query("SELECT filename FROM schema_migrations ORDER BY filename DESC LIMIT ?", n)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Check the whole batch first, so it is not left half reverted
+	for _, name := range names {
+		m, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s is not known to this binary", ErrIrreversible, name)
+		}
+		if m.down == "" {
+			return nil, fmt.Errorf("%w: %s has no down migration", ErrIrreversible, name)
+		}
+	}
+
+	var reverted []string
+	for _, name := range names {
+		err := WithTx(context.Background(), s.db, func(tx *sql.Tx) error {
+			if _, err := tx.Exec(byName[name].down); err != nil {
+				return err
+			}
+			_, err := tx.Exec("DELETE FROM schema_migrations WHERE filename = ?", name)
+			return err
+		})
+		if err != nil {
+			return reverted, fmt.Errorf("rollback of %s failed: %w", name, err)
+		}
+		reverted = append(reverted, name)
+		fmt.Printf("Rolled back migration: %s\n", name)
+	}
+	return reverted, nil
+}
@@ -0,0 +1,2 @@
+-- Reverts 002_job_leases.sql
+DROP TABLE IF EXISTS job_leases;
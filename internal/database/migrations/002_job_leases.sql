@@ -0,0 +1,11 @@
+-- 10. Background job leases (only the lease holder runs a job)
+CREATE TABLE IF NOT EXISTS job_leases (
+  name TEXT PRIMARY KEY,
+  holder TEXT NOT NULL,
+  expires_at DATETIME NOT NULL,
+  last_run_at DATETIME,
+  last_success_at DATETIME,
+  last_error TEXT,
+
+  updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
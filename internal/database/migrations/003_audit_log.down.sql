@@ -0,0 +1,3 @@
+-- Reverts 003_audit_log.sql
+DROP INDEX IF EXISTS idx_audit_log_created_at;
+DROP TABLE IF EXISTS audit_log;
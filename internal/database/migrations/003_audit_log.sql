@@ -0,0 +1,12 @@
+-- 11. Audit log of mutating API calls
+CREATE TABLE IF NOT EXISTS audit_log (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  actor TEXT NOT NULL,
+  action TEXT NOT NULL,
+  target TEXT NOT NULL,
+  payload_hash TEXT,
+  status_code INTEGER NOT NULL,
+  result TEXT NOT NULL CHECK(result IN ('success', 'failure')),
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log(created_at);
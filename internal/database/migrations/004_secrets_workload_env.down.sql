@@ -0,0 +1,4 @@
+-- Reverts 004_secrets_workload_env.sql
+DROP TABLE IF EXISTS workload_env;
+ALTER TABLE workloads DROP COLUMN image;
+DROP TABLE IF EXISTS secrets;
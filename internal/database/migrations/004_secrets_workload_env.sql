@@ -0,0 +1,27 @@
+-- 12. Secrets (values are encrypted at rest, never returned by the API)
+CREATE TABLE IF NOT EXISTS secrets (
+  name TEXT PRIMARY KEY,
+  value_encrypted TEXT NOT NULL,
+
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  create_user_id TEXT,
+  updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  update_user_id TEXT
+);
+
+-- 13. Workload image
+ALTER TABLE workloads ADD COLUMN image TEXT NOT NULL DEFAULT '';
+
+-- 14. Workload environment variables
+-- Either value (plain variable) or secret_name (reference into secrets) is set, never both.
+CREATE TABLE IF NOT EXISTS workload_env (
+  workload_id TEXT NOT NULL,
+  key TEXT NOT NULL,
+  value TEXT,
+  secret_name TEXT,
+
+  PRIMARY KEY (workload_id, key),
+  FOREIGN KEY (workload_id) REFERENCES workloads(id) ON DELETE CASCADE,
+  FOREIGN KEY (secret_name) REFERENCES secrets(name),
+  CHECK ((value IS NULL) != (secret_name IS NULL))
+);
@@ -0,0 +1,3 @@
+-- Reverts 005_node_metrics.sql
+DROP INDEX IF EXISTS idx_node_metrics_node_collected;
+DROP TABLE IF EXISTS node_metrics;
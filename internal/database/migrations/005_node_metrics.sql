@@ -0,0 +1,19 @@
+-- 15. Node host metrics (rolling window, older samples are pruned on insert)
+CREATE TABLE IF NOT EXISTS node_metrics (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  node_id TEXT NOT NULL,
+  cpu_count INTEGER NOT NULL,
+  load1 REAL NOT NULL,
+  load5 REAL NOT NULL,
+  load15 REAL NOT NULL,
+  memory_used_mb INTEGER NOT NULL,
+  memory_total_mb INTEGER NOT NULL,
+  disk_used_bytes INTEGER NOT NULL,
+  disk_total_bytes INTEGER NOT NULL,
+  net_rx_bytes_per_sec INTEGER NOT NULL,
+  net_tx_bytes_per_sec INTEGER NOT NULL,
+  collected_at DATETIME NOT NULL,
+
+  FOREIGN KEY (node_id) REFERENCES nodes(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_node_metrics_node_collected ON node_metrics(node_id, collected_at);
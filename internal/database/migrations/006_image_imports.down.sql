@@ -0,0 +1,3 @@
+-- Reverts 006_image_imports.sql
+DROP INDEX IF EXISTS idx_image_imports_status;
+DROP TABLE IF EXISTS image_imports;
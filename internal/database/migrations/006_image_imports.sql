@@ -0,0 +1,17 @@
+-- 16. Image imports from URLs (downloads resume from bytes_done after interruptions)
+CREATE TABLE IF NOT EXISTS image_imports (
+  id TEXT PRIMARY KEY,
+  url TEXT NOT NULL,
+  alias TEXT NOT NULL,
+  sha256 TEXT,
+  max_bytes_per_sec INTEGER NOT NULL DEFAULT 0,
+  status TEXT NOT NULL CHECK(status IN ('pending', 'downloading', 'verifying', 'importing', 'completed', 'failed')),
+  bytes_done INTEGER NOT NULL DEFAULT 0,
+  bytes_total INTEGER NOT NULL DEFAULT 0,
+  error TEXT,
+
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  create_user_id TEXT,
+  updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_image_imports_status ON image_imports(status);
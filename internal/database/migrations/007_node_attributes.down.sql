@@ -0,0 +1,2 @@
+-- Reverts 007_node_attributes.sql
+DROP TABLE IF EXISTS node_attributes;
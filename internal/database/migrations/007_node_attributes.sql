@@ -0,0 +1,10 @@
+-- 17. Node attributes (e.g. benchmark scores used for placement)
+CREATE TABLE IF NOT EXISTS node_attributes (
+  node_id TEXT NOT NULL,
+  key TEXT NOT NULL,
+  value TEXT NOT NULL,
+  updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (node_id, key),
+  FOREIGN KEY (node_id) REFERENCES nodes(id) ON DELETE CASCADE
+);
@@ -0,0 +1,2 @@
+-- Reverts 008_workload_addresses.sql
+ALTER TABLE workloads DROP COLUMN addresses;
@@ -0,0 +1,2 @@
+-- 18. Workload IP addresses (refreshed from LXD instance state)
+ALTER TABLE workloads ADD COLUMN addresses TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+-- Reverts 009_usage.sql
+DROP TABLE IF EXISTS usage_daily;
@@ -0,0 +1,11 @@
+-- 19. Usage metering (daily rollups per identity or project)
+CREATE TABLE IF NOT EXISTS usage_daily (
+  day TEXT NOT NULL,          -- UTC date, YYYY-MM-DD
+  subject_type TEXT NOT NULL CHECK(subject_type IN ('identity', 'project')),
+  subject TEXT NOT NULL,      -- client certificate CN or LXD project name
+  metric TEXT NOT NULL,       -- api_calls, instance_hours, memory_gb_hours, disk_gb_hours
+  value REAL NOT NULL DEFAULT 0,
+  updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (day, subject_type, subject, metric)
+);
@@ -0,0 +1,2 @@
+-- Reverts 010_dns_records.sql
+DROP TABLE IF EXISTS dns_records;
@@ -0,0 +1,11 @@
+-- 20. DNS records published for floating IPs (removed again when the IP is released)
+CREATE TABLE IF NOT EXISTS dns_records (
+  owner TEXT PRIMARY KEY,     -- floating IP address the record points to
+  fqdn TEXT NOT NULL,         -- e.g. web.apps.example.com
+  type TEXT NOT NULL CHECK(type IN ('A', 'AAAA')),
+  value TEXT NOT NULL,
+  provider TEXT NOT NULL,     -- cloudflare or rfc2136
+  provider_id TEXT,           -- provider's record ID, if it has one
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,3 @@
+-- Reverts 011_operations.sql
+DROP INDEX IF EXISTS idx_operations_status;
+DROP TABLE IF EXISTS operations;
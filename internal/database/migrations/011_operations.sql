@@ -0,0 +1,17 @@
+-- 21. Long-running operations (mutating endpoints return one and run in the background)
+CREATE TABLE IF NOT EXISTS operations (
+  id TEXT PRIMARY KEY,
+  type TEXT NOT NULL,          -- e.g. workload.create, workload.clone
+  status TEXT NOT NULL CHECK(status IN ('pending', 'running', 'succeeded', 'failed')),
+  progress INTEGER NOT NULL DEFAULT 0 CHECK(progress BETWEEN 0 AND 100),
+  message TEXT,                -- current step, e.g. "Launching instance"
+  resource TEXT,               -- affected resource, e.g. /workloads/<id>
+  result TEXT,                 -- JSON result once succeeded
+  error TEXT,
+  manager TEXT NOT NULL,       -- hostname of the mcloudd running it
+
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  create_user_id TEXT,
+  updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_operations_status ON operations(status);
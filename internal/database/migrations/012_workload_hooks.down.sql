@@ -0,0 +1,3 @@
+-- Reverts 012_workload_hooks.sql
+DROP INDEX IF EXISTS idx_workload_hooks_workload;
+DROP TABLE IF EXISTS workload_hooks;
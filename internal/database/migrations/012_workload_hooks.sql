@@ -0,0 +1,15 @@
+-- 22. Workload lifecycle hooks, run by the agent on the instance's node
+-- Either webhook_url (POSTed a JSON payload) or command (name in the hooks.commands allowlist) is set, never both.
+CREATE TABLE IF NOT EXISTS workload_hooks (
+  id TEXT PRIMARY KEY,
+  workload_id TEXT NOT NULL,
+  event TEXT NOT NULL CHECK(event IN ('on-start', 'on-stop', 'on-failure')),
+  webhook_url TEXT,
+  command TEXT,
+
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+
+  FOREIGN KEY (workload_id) REFERENCES workloads(id) ON DELETE CASCADE,
+  CHECK ((webhook_url IS NULL) != (command IS NULL))
+);
+CREATE INDEX IF NOT EXISTS idx_workload_hooks_workload ON workload_hooks(workload_id);
@@ -0,0 +1,6 @@
+-- Reverts 013_events_fts.sql
+DROP INDEX IF EXISTS idx_events_node_id;
+DROP TRIGGER IF EXISTS events_fts_update;
+DROP TRIGGER IF EXISTS events_fts_delete;
+DROP TRIGGER IF EXISTS events_fts_insert;
+DROP TABLE IF EXISTS events_fts;
@@ -0,0 +1,26 @@
+-- 23. Full-text index over events, kept in sync by triggers
+-- External-content table: the text lives in events, events_fts only holds the index.
+CREATE VIRTUAL TABLE IF NOT EXISTS events_fts USING fts5(
+  message,
+  type,
+  content='events',
+  content_rowid='id'
+);
+
+CREATE TRIGGER IF NOT EXISTS events_fts_insert AFTER INSERT ON events BEGIN
+  INSERT INTO events_fts(rowid, message, type) VALUES (new.id, new.message, new.type);
+END;
+
+CREATE TRIGGER IF NOT EXISTS events_fts_delete AFTER DELETE ON events BEGIN
+  INSERT INTO events_fts(events_fts, rowid, message, type) VALUES ('delete', old.id, old.message, old.type);
+END;
+
+CREATE TRIGGER IF NOT EXISTS events_fts_update AFTER UPDATE ON events BEGIN
+  INSERT INTO events_fts(events_fts, rowid, message, type) VALUES ('delete', old.id, old.message, old.type);
+  INSERT INTO events_fts(rowid, message, type) VALUES (new.id, new.message, new.type);
+END;
+
+-- Index the events written before this migration
+INSERT INTO events_fts(events_fts) VALUES ('rebuild');
+
+CREATE INDEX IF NOT EXISTS idx_events_node_id ON events(node_id);
@@ -0,0 +1,2 @@
+-- Reverts 014_node_rejoin_credentials.sql
+DROP TABLE IF EXISTS node_rejoin_credentials;
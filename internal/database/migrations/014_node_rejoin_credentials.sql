@@ -0,0 +1,10 @@
+-- 24. Re-join credentials: one per node. The node keeps the secret in its encrypted
+-- re-join bundle; only a hash is stored here and it is replaced on every re-join.
+CREATE TABLE IF NOT EXISTS node_rejoin_credentials (
+  node_id TEXT PRIMARY KEY,
+  secret_hash TEXT NOT NULL,
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  used_at DATETIME,
+
+  FOREIGN KEY (node_id) REFERENCES nodes(id) ON DELETE CASCADE
+);
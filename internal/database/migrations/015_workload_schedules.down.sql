@@ -0,0 +1,2 @@
+-- Reverts 015_workload_schedules.sql
+DROP TABLE IF EXISTS workload_schedules;
@@ -0,0 +1,18 @@
+-- 25. Workload power schedules: stop and/or start a workload at fixed local times
+-- (HH:MM), optionally only on some weekdays (days, e.g. "mon,tue,wed,thu,fri"; empty = every day).
+-- Actions at or before skip_until are skipped; last_action_at is the last scheduled
+-- action handled, so each action runs once even if the job misses its minute.
+CREATE TABLE IF NOT EXISTS workload_schedules (
+  workload_id TEXT PRIMARY KEY,
+  stop_at TEXT,
+  start_at TEXT,
+  days TEXT NOT NULL DEFAULT '',
+  skip_until DATETIME,
+  last_action_at DATETIME,
+
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+
+  FOREIGN KEY (workload_id) REFERENCES workloads(id) ON DELETE CASCADE,
+  CHECK (stop_at IS NOT NULL OR start_at IS NOT NULL)
+);
@@ -0,0 +1,4 @@
+-- Reverts 016_kv_store_versions.sql
+DROP INDEX IF EXISTS idx_kv_changes_changed_at;
+DROP TABLE IF EXISTS kv_changes;
+ALTER TABLE kv_store DROP COLUMN version;
@@ -0,0 +1,14 @@
+-- 26. Cluster config on top of kv_store: every key carries a version for
+-- optimistic concurrency, and changes made through the config service are
+-- appended to kv_changes so watchers can resume from the last revision they saw.
+ALTER TABLE kv_store ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+CREATE TABLE IF NOT EXISTS kv_changes (
+  revision INTEGER PRIMARY KEY AUTOINCREMENT,
+  key TEXT NOT NULL,
+  value TEXT,
+  version INTEGER NOT NULL,
+  deleted INTEGER NOT NULL DEFAULT 0,
+  changed_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_kv_changes_changed_at ON kv_changes(changed_at);
@@ -0,0 +1,3 @@
+-- Reverts 017_workload_priority.sql
+ALTER TABLE workloads DROP COLUMN preempted_at;
+ALTER TABLE workloads DROP COLUMN priority;
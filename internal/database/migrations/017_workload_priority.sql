@@ -0,0 +1,7 @@
+-- 27. Workload priority classes: low-priority workloads are stopped first when a node
+-- is drained or runs out of memory; high-priority workloads are always migrated.
+-- preempted_at is set while a workload is stopped for that reason, so restoring the
+-- node can start it again.
+ALTER TABLE workloads ADD COLUMN priority TEXT NOT NULL DEFAULT 'normal'
+  CHECK (priority IN ('low', 'normal', 'high'));
+ALTER TABLE workloads ADD COLUMN preempted_at DATETIME;
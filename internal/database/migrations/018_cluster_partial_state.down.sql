@@ -0,0 +1,16 @@
+-- Reverts 018_cluster_partial_state.sql; partial clusters go back to "init"
+CREATE TABLE clusters_old (
+  id TEXT PRIMARY KEY,
+  name TEXT NOT NULL,
+  state TEXT NOT NULL CHECK(state IN ('init', 'active', 'degraded')),
+
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  create_user_id TEXT,
+  updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  update_user_id TEXT
+);
+INSERT INTO clusters_old (id, name, state, created_at, create_user_id, updated_at, update_user_id)
+  SELECT id, name, CASE state WHEN 'partial' THEN 'init' ELSE state END,
+         created_at, create_user_id, updated_at, update_user_id FROM clusters;
+DROP TABLE clusters;
+ALTER TABLE clusters_old RENAME TO clusters;
@@ -0,0 +1,17 @@
+-- 28. Clusters can be "partial": init failed after some bootstrap steps completed
+-- and can be resumed with `mcloudctl init --resume`. SQLite cannot change a CHECK
+-- constraint in place, so the table is rebuilt.
+CREATE TABLE clusters_new (
+  id TEXT PRIMARY KEY,
+  name TEXT NOT NULL,
+  state TEXT NOT NULL CHECK(state IN ('init', 'active', 'degraded', 'partial')),
+
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  create_user_id TEXT,
+  updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  update_user_id TEXT
+);
+INSERT INTO clusters_new (id, name, state, created_at, create_user_id, updated_at, update_user_id)
+  SELECT id, name, state, created_at, create_user_id, updated_at, update_user_id FROM clusters;
+DROP TABLE clusters;
+ALTER TABLE clusters_new RENAME TO clusters;
@@ -0,0 +1,2 @@
+-- Reverts 019_image_builds.sql
+DROP TABLE IF EXISTS image_builds;
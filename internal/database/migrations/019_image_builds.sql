@@ -0,0 +1,26 @@
+-- 29. Image builds: a base image provisioned by a script on a build node and
+-- published to the cluster image store. The row is the build's provenance record:
+-- what went in (base image, script and its hash), where it ran, who asked for it
+-- and which image fingerprint came out.
+CREATE TABLE IF NOT EXISTS image_builds (
+  id TEXT PRIMARY KEY,
+  alias TEXT NOT NULL,
+  base_image TEXT NOT NULL,        -- e.g. ubuntu:24.04
+  script TEXT NOT NULL,            -- provisioning script, run with sh -e
+  script_sha256 TEXT NOT NULL,
+  vm INTEGER NOT NULL DEFAULT 0,
+  node_id TEXT NOT NULL,           -- build node
+  status TEXT NOT NULL CHECK(status IN ('pending', 'building', 'publishing', 'completed', 'failed')),
+  operation_id TEXT,
+  fingerprint TEXT,                -- LXD image fingerprint once published
+  log TEXT,                        -- tail of the script output
+  error TEXT,
+
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  create_user_id TEXT,
+  updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  finished_at DATETIME,
+
+  FOREIGN KEY (node_id) REFERENCES nodes(id)
+);
+CREATE INDEX IF NOT EXISTS idx_image_builds_alias ON image_builds(alias);
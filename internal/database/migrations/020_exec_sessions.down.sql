@@ -0,0 +1,2 @@
+-- Reverts 020_exec_sessions.sql
+DROP TABLE IF EXISTS exec_sessions;
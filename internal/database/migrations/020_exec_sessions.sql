@@ -0,0 +1,20 @@
+-- 30. Exec sessions: every `mcloudctl workload exec` is recorded with who ran what
+-- where, how long it ran and how it ended. With audit.exec_recording enabled the
+-- transcript (asciicast v2, input and output with timings) is stored as well,
+-- up to a size limit. The session's audit_log entry has target /audit/exec-sessions/<id>.
+CREATE TABLE IF NOT EXISTS exec_sessions (
+  id TEXT PRIMARY KEY,
+  target TEXT NOT NULL,            -- e.g. /workloads/<id>
+  instance TEXT NOT NULL,          -- LXD instance name at the time of the session
+  actor TEXT NOT NULL,
+  command TEXT NOT NULL,           -- JSON array
+  started_at DATETIME NOT NULL,
+  ended_at DATETIME,
+  exit_code INTEGER,
+  error TEXT,
+  transcript BLOB,                 -- NULL when recording is disabled
+  transcript_bytes INTEGER NOT NULL DEFAULT 0,
+  truncated INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_exec_sessions_started_at ON exec_sessions(started_at);
+CREATE INDEX IF NOT EXISTS idx_exec_sessions_target ON exec_sessions(target);
@@ -0,0 +1,4 @@
+-- Reverts 021_hook_dead_letters.sql
+ALTER TABLE node_metrics DROP COLUMN hook_queue_depth;
+DROP INDEX IF EXISTS idx_hook_dead_letters_created_at;
+DROP TABLE IF EXISTS hook_dead_letters;
@@ -0,0 +1,18 @@
+-- 31. Webhook delivery: agents queue hook webhooks per endpoint and retry them;
+-- deliveries that keep failing (or find the queue full) are reported as dead
+-- letters and kept here with their URL and payload. node_metrics records how
+-- many deliveries were waiting on the node when the sample was taken.
+CREATE TABLE IF NOT EXISTS hook_dead_letters (
+  id TEXT PRIMARY KEY,
+  hook_id TEXT NOT NULL,
+  workload_id TEXT NOT NULL,
+  node TEXT NOT NULL,
+  event TEXT NOT NULL,
+  url TEXT NOT NULL,
+  payload TEXT NOT NULL,           -- JSON body that was to be POSTed
+  attempts INTEGER NOT NULL,
+  error TEXT NOT NULL,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_hook_dead_letters_created_at ON hook_dead_letters(created_at);
+ALTER TABLE node_metrics ADD COLUMN hook_queue_depth INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+-- Reverts 022_table_partitions.sql
+DROP TABLE IF EXISTS table_partitions;
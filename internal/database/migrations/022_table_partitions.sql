@@ -0,0 +1,16 @@
+-- 32. Monthly partitions of the high-volume tables (events, audit_log). Finished
+-- months are moved out of the base table into <table>_YYYY_MM tables, which
+-- queries read together with the base table; months past the retention are
+-- copied to standalone archive files and dropped. Archived partitions keep
+-- their row here with the archive file. The partition tables themselves are
+-- created at run time, so reverting this migration leaves them in place.
+CREATE TABLE IF NOT EXISTS table_partitions (
+  name TEXT PRIMARY KEY,           -- e.g. events_2026_09
+  base TEXT NOT NULL,              -- events or audit_log
+  month TEXT NOT NULL,             -- e.g. 2026-09
+  row_count INTEGER NOT NULL,
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  archive_path TEXT,
+  archived_at DATETIME,
+  UNIQUE (base, month)
+);
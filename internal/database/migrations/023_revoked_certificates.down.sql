@@ -0,0 +1,2 @@
+-- Reverts 023_revoked_certificates.sql
+DROP TABLE IF EXISTS revoked_certificates;
@@ -0,0 +1,8 @@
+-- 33. Client certificates revoked by node removal, by common name (the node's
+-- hostname). Certificates with that name issued before revoked_at are refused,
+-- so a host added back under the same name needs a new certificate.
+CREATE TABLE IF NOT EXISTS revoked_certificates (
+  common_name TEXT PRIMARY KEY,
+  reason TEXT NOT NULL,
+  revoked_at DATETIME NOT NULL
+);
@@ -0,0 +1,2 @@
+-- Reverts 024_workload_cloud_init.sql
+ALTER TABLE workloads DROP COLUMN cloud_init_hash;
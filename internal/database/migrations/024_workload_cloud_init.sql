@@ -0,0 +1,4 @@
+-- 34. cloud-init user-data and network-config given at launch are applied as
+-- the instance's user.user-data / user.network-config; only their SHA-256 is
+-- kept here, to tell which configuration a workload was launched with.
+ALTER TABLE workloads ADD COLUMN cloud_init_hash TEXT;
@@ -0,0 +1,3 @@
+-- Reverts 025_flavors.sql
+ALTER TABLE workloads DROP COLUMN flavor;
+DROP TABLE IF EXISTS flavors;
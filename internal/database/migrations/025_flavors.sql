@@ -0,0 +1,18 @@
+-- 35. Flavors: named workload sizes (CPUs, memory, root disk), applied as the
+-- LXD profile flavor-<name>. workloads.flavor is the flavor a workload was
+-- launched with, if any.
+CREATE TABLE IF NOT EXISTS flavors (
+  name TEXT PRIMARY KEY,
+  cpus INTEGER NOT NULL CHECK (cpus > 0),
+  memory_mb INTEGER NOT NULL CHECK (memory_mb > 0),
+  disk_gb INTEGER NOT NULL CHECK (disk_gb > 0),
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  create_user_id TEXT
+);
+
+INSERT OR IGNORE INTO flavors (name, cpus, memory_mb, disk_gb) VALUES
+  ('small', 1, 1024, 10),
+  ('medium', 2, 4096, 20),
+  ('large', 4, 8192, 40);
+
+ALTER TABLE workloads ADD COLUMN flavor TEXT;
@@ -0,0 +1,3 @@
+-- Reverts 026_volumes.sql
+DROP INDEX IF EXISTS idx_volumes_workload_id;
+DROP TABLE IF EXISTS volumes;
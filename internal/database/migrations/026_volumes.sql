@@ -0,0 +1,20 @@
+-- 36. Volumes: Ceph RBD-backed LXD custom storage volumes. A volume is
+-- attached to at most one workload at a time, mounted at mount_path.
+CREATE TABLE IF NOT EXISTS volumes (
+  id TEXT PRIMARY KEY,
+  name TEXT NOT NULL,
+  pool TEXT NOT NULL,
+  size_gb INTEGER NOT NULL CHECK (size_gb > 0),
+  workload_id TEXT,
+  mount_path TEXT,
+  attached_at DATETIME,
+
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  create_user_id TEXT,
+  updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  update_user_id TEXT,
+
+  FOREIGN KEY (workload_id) REFERENCES workloads(id) ON DELETE SET NULL,
+  UNIQUE (pool, name)
+);
+CREATE INDEX IF NOT EXISTS idx_volumes_workload_id ON volumes(workload_id);
@@ -0,0 +1,3 @@
+-- Reverts 027_floating_ips.sql
+DROP INDEX IF EXISTS idx_floating_ips_workload_id;
+DROP TABLE IF EXISTS floating_ips;
@@ -0,0 +1,15 @@
+-- 37. Floating IPs: addresses from network.external_pool, each forwarded to
+-- one workload by an LXD network forward (an OVN load balancer) on the
+-- workload's OVN network.
+CREATE TABLE IF NOT EXISTS floating_ips (
+  address TEXT PRIMARY KEY,
+  workload_id TEXT NOT NULL,
+  network TEXT NOT NULL,         -- LXD OVN network holding the forward
+  target_address TEXT NOT NULL,  -- workload address the traffic is forwarded to
+
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  create_user_id TEXT,
+
+  FOREIGN KEY (workload_id) REFERENCES workloads(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_floating_ips_workload_id ON floating_ips(workload_id);
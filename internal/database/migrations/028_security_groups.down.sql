@@ -0,0 +1,5 @@
+-- Reverts 028_security_groups.sql
+DROP INDEX IF EXISTS idx_workload_security_groups_group;
+DROP TABLE IF EXISTS workload_security_groups;
+DROP TABLE IF EXISTS security_group_rules;
+DROP TABLE IF EXISTS security_groups;
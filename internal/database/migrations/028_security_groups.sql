@@ -0,0 +1,36 @@
+-- 38. Security groups: named sets of ingress/egress allow rules, applied as
+-- the LXD network ACL sg-<name> (OVN ACLs) on the OVN NICs of the workloads
+-- attached to them. Traffic no attached group allows is rejected.
+CREATE TABLE IF NOT EXISTS security_groups (
+  name TEXT PRIMARY KEY,
+  description TEXT NOT NULL DEFAULT '',
+
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  create_user_id TEXT,
+  updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  update_user_id TEXT
+);
+
+CREATE TABLE IF NOT EXISTS security_group_rules (
+  group_name TEXT NOT NULL,
+  position INTEGER NOT NULL,
+  direction TEXT NOT NULL CHECK(direction IN ('ingress', 'egress')),
+  protocol TEXT NOT NULL DEFAULT '',  -- tcp, udp, icmp4, icmp6; '' = any
+  port TEXT NOT NULL DEFAULT '',      -- destination port or range, e.g. 443 or 8000-8100
+  cidr TEXT NOT NULL DEFAULT '',      -- source of ingress, destination of egress; '' = anywhere
+  description TEXT NOT NULL DEFAULT '',
+
+  PRIMARY KEY (group_name, position),
+  FOREIGN KEY (group_name) REFERENCES security_groups(name) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS workload_security_groups (
+  workload_id TEXT NOT NULL,
+  group_name TEXT NOT NULL,
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (workload_id, group_name),
+  FOREIGN KEY (workload_id) REFERENCES workloads(id) ON DELETE CASCADE,
+  FOREIGN KEY (group_name) REFERENCES security_groups(name)
+);
+CREATE INDEX IF NOT EXISTS idx_workload_security_groups_group ON workload_security_groups(group_name);
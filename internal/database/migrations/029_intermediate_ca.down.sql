@@ -0,0 +1,4 @@
+-- Reverts 029_intermediate_ca.sql
+DELETE FROM certificate_authorities WHERE kind = 'intermediate';
+ALTER TABLE certificate_authorities DROP COLUMN retired_at;
+ALTER TABLE certificate_authorities DROP COLUMN kind;
@@ -0,0 +1,8 @@
+-- 39. Intermediate CAs: the root CA (kind 'root') signs a cluster intermediate
+-- (kind 'intermediate') that issues the server, client and node certificates.
+-- The root's key stays in security.ca_key_path on the leader and is stored
+-- empty here. Rotating the intermediate retires the one in use and adds a new
+-- one; the certificates it issued chain to the root and stay valid until they
+-- expire.
+ALTER TABLE certificate_authorities ADD COLUMN kind TEXT NOT NULL DEFAULT 'root'; -- root, intermediate
+ALTER TABLE certificate_authorities ADD COLUMN retired_at DATETIME;
@@ -0,0 +1,2 @@
+-- Reverts 030_revoked_certificate_serials.sql
+DROP TABLE IF EXISTS revoked_certificate_serials;
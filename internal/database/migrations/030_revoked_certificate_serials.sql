@@ -0,0 +1,11 @@
+-- 40. Certificates revoked one by one, by serial number (lower-case hex), e.g.
+-- a node certificate that leaked. mcloudd refuses them on the HTTP and gRPC
+-- APIs and lists them in the CRL of GET /certs/crl. Unlike revoked_certificates,
+-- certificates issued later under the same common name are unaffected.
+CREATE TABLE IF NOT EXISTS revoked_certificate_serials (
+  serial TEXT PRIMARY KEY,
+  common_name TEXT NOT NULL,
+  reason TEXT NOT NULL,
+  revoked_by TEXT,
+  revoked_at DATETIME NOT NULL
+);
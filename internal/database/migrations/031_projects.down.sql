@@ -0,0 +1,3 @@
+-- Reverts 031_projects.sql
+ALTER TABLE workloads DROP COLUMN project;
+DROP TABLE IF EXISTS projects;
@@ -0,0 +1,19 @@
+-- 41. Projects (tenants) and their quotas. A NULL limit is unlimited. Every
+-- workload belongs to one project; existing workloads go to 'default', which
+-- has no limits. Usage is counted from the flavors of a project's workloads.
+CREATE TABLE IF NOT EXISTS projects (
+  name TEXT PRIMARY KEY,
+  description TEXT NOT NULL DEFAULT '',
+  max_cpus INTEGER CHECK (max_cpus >= 0),
+  max_memory_mb INTEGER CHECK (max_memory_mb >= 0),
+  max_instances INTEGER CHECK (max_instances >= 0),
+  max_disk_gb INTEGER CHECK (max_disk_gb >= 0),
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  create_user_id TEXT,
+  updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  update_user_id TEXT
+);
+
+INSERT OR IGNORE INTO projects (name, description) VALUES ('default', 'Default project');
+
+ALTER TABLE workloads ADD COLUMN project TEXT NOT NULL DEFAULT 'default';
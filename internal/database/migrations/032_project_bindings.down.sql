@@ -0,0 +1,2 @@
+-- Reverts 032_project_bindings.sql
+DROP TABLE IF EXISTS project_bindings;
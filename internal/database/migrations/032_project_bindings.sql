@@ -0,0 +1,11 @@
+-- 42. Per-project role bindings: which client identities (certificate common
+-- names) may manage a project's workloads (member) or also its quota and
+-- bindings (admin). A project without bindings is open to every identity.
+CREATE TABLE IF NOT EXISTS project_bindings (
+  project TEXT NOT NULL,
+  identity TEXT NOT NULL,
+  role TEXT NOT NULL CHECK (role IN ('admin', 'member')),
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  create_user_id TEXT,
+  PRIMARY KEY (project, identity)
+);
@@ -0,0 +1,2 @@
+-- Reverts 033_maintenance_windows.sql
+DROP TABLE IF EXISTS maintenance_windows;
@@ -0,0 +1,18 @@
+-- 43. Weekly maintenance windows: at most one per cluster, opening every week
+-- on day (e.g. "sun") at start_at (HH:MM, the manager's local time) for
+-- duration_minutes. During the window the listed snaps are refreshed one node
+-- at a time. last_run_at is the opening of the last window a rollout was
+-- started in, so each window starts one rollout even if the job misses its minute.
+CREATE TABLE IF NOT EXISTS maintenance_windows (
+  cluster_id TEXT PRIMARY KEY,
+  day TEXT NOT NULL,
+  start_at TEXT NOT NULL,
+  duration_minutes INTEGER NOT NULL CHECK (duration_minutes > 0),
+  snaps TEXT NOT NULL DEFAULT 'lxd,microceph,microovn',
+  last_run_at DATETIME,
+
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+
+  FOREIGN KEY (cluster_id) REFERENCES clusters(id) ON DELETE CASCADE
+);
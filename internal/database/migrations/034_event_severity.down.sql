@@ -0,0 +1,4 @@
+-- Reverts 034_event_severity.sql
+DROP INDEX IF EXISTS idx_events_severity;
+ALTER TABLE events DROP COLUMN payload;
+ALTER TABLE events DROP COLUMN severity;
@@ -0,0 +1,19 @@
+-- 44. Structured events: every event has a severity (info, warning, error or
+-- critical; see package event for the taxonomy of types) and an optional JSON
+-- payload with the details of its type, e.g. {"workload": "web-1", "status": "failed"}.
+-- Partitions of events get the new columns when mcloudd next migrates; their
+-- rows keep the default severity.
+ALTER TABLE events ADD COLUMN severity TEXT NOT NULL DEFAULT 'info';
+ALTER TABLE events ADD COLUMN payload TEXT;
+
+-- Events written before this migration get the severity of their type
+UPDATE events SET severity = 'warning'
+WHERE type IN ('node.offline', 'node.condition_set', 'node.clock_skew', 'node.clock_unsynchronized',
+               'node.rejoin_failed', 'workload.preempted', 'workload.hook.failed',
+               'workload.schedule_failed', 'maintenance.deferred')
+   OR (type LIKE 'drift.%' AND type != 'drift.healed');
+UPDATE events SET severity = 'error'
+WHERE type IN ('node.drain_failed', 'node.remove_failed', 'workload.failed', 'workload.hook.dead_lettered',
+               'image.build.failed', 'image.import.failed', 'maintenance.failed');
+
+CREATE INDEX IF NOT EXISTS idx_events_severity ON events(severity);
@@ -0,0 +1,2 @@
+-- Reverts 035_alerts.sql
+DROP TABLE IF EXISTS alerts;
@@ -0,0 +1,20 @@
+-- 45. Alerts raised by the alert rules (see package alert). A row is one
+-- stretch of time a rule fired for a subject (a node, a certificate, the Ceph
+-- cluster): it is firing until the condition clears, then resolved. At most one
+-- alert per rule and subject fires at a time. notified_at is the last mail sent
+-- about the rule and subject, which the cooldown between mails counts from.
+CREATE TABLE IF NOT EXISTS alerts (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  rule TEXT NOT NULL,
+  subject TEXT NOT NULL,
+  severity TEXT NOT NULL,
+  message TEXT NOT NULL,
+  status TEXT NOT NULL DEFAULT 'firing' CHECK (status IN ('firing', 'resolved')),
+  notifications INTEGER NOT NULL DEFAULT 0,
+  notified_at DATETIME,
+  fired_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  resolved_at DATETIME
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_alerts_firing ON alerts(rule, subject) WHERE status = 'firing';
+CREATE INDEX IF NOT EXISTS idx_alerts_fired_at ON alerts(fired_at);
@@ -0,0 +1,2 @@
+-- Reverts 036_node_labels.sql
+DROP TABLE IF EXISTS node_labels;
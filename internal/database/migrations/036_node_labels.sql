@@ -0,0 +1,15 @@
+-- 46. Node labels and taints used by the scheduler (see node.Labels). A label
+-- is a key/value pair a workload's node_selector can require; a taint keeps
+-- workloads without a matching toleration off the node (NoSchedule) or off it
+-- unless no other node fits (PreferNoSchedule). effect is only set on taints.
+CREATE TABLE IF NOT EXISTS node_labels (
+  node_id TEXT NOT NULL,
+  kind TEXT NOT NULL CHECK (kind IN ('label', 'taint')),
+  key TEXT NOT NULL,
+  value TEXT NOT NULL DEFAULT '',
+  effect TEXT NOT NULL DEFAULT '' CHECK (effect IN ('', 'NoSchedule', 'PreferNoSchedule')),
+  updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+
+  PRIMARY KEY (node_id, kind, key),
+  FOREIGN KEY (node_id) REFERENCES nodes(id) ON DELETE CASCADE
+);
@@ -0,0 +1,3 @@
+-- Reverts 037_load_balancers.sql
+DROP TABLE IF EXISTS load_balancer_selectors;
+DROP TABLE IF EXISTS load_balancers;
@@ -0,0 +1,29 @@
+-- 47. Load balancers: a VIP and port on the workloads' OVN network, balanced
+-- by an LXD network load balancer (an OVN load balancer) over the running
+-- workloads its selectors match. backends is the comma separated list of
+-- workload addresses last programmed, kept in sync by loadbalancer.Sync.
+CREATE TABLE IF NOT EXISTS load_balancers (
+  name TEXT PRIMARY KEY,
+  vip TEXT NOT NULL UNIQUE,
+  network TEXT NOT NULL,       -- LXD OVN network holding the load balancer
+  protocol TEXT NOT NULL CHECK (protocol IN ('tcp', 'udp')),
+  port INTEGER NOT NULL,
+  target_port INTEGER NOT NULL,
+  backends TEXT NOT NULL DEFAULT '',
+
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  create_user_id TEXT,
+  updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- A workload is a backend when it matches any selector: it is in project and
+-- its name matches name, a glob such as web-*.
+CREATE TABLE IF NOT EXISTS load_balancer_selectors (
+  load_balancer TEXT NOT NULL,
+  position INTEGER NOT NULL,
+  project TEXT NOT NULL,
+  name TEXT NOT NULL DEFAULT '*',
+
+  PRIMARY KEY (load_balancer, position),
+  FOREIGN KEY (load_balancer) REFERENCES load_balancers(name) ON DELETE CASCADE
+);
@@ -0,0 +1,2 @@
+-- Reverts 038_sealed_rejoin_secrets.sql
+ALTER TABLE node_rejoin_credentials DROP COLUMN secret_sealed;
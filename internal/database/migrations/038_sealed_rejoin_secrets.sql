@@ -0,0 +1,6 @@
+-- 48. Re-join requests are signed with the secret itself, which the manager
+-- keeps sealed with its secrets key (security.secrets_key_path) so the
+-- database alone cannot forge them. secret_hash stays to detect concurrent
+-- re-joins. Credentials issued before have no sealed secret and must be
+-- re-issued.
+ALTER TABLE node_rejoin_credentials ADD COLUMN secret_sealed TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+-- Reverts 039_intermediate_ca_key_off_database.sql
+-- The dropped keys are not restored; they are read from
+-- security.intermediate_key_path.
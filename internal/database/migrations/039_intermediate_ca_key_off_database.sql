@@ -0,0 +1,5 @@
+-- 49. The intermediate CA's key stays on the leader's filesystem
+-- (security.intermediate_key_path, mode 0600) like the root's, so a copy of
+-- the database cannot issue cluster certificates. Keys recorded before are
+-- dropped; the leader already has them on disk.
+UPDATE certificate_authorities SET key_pem = '' WHERE kind = 'intermediate';
@@ -0,0 +1,197 @@
+package database
+
+import (
+	"database/sql"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// schemaObject is one entry of sqlite_master, with its SQL normalized so that
+// equivalent definitions compare equal (see normalizeSQL).
+type schemaObject struct {
+	typ  string
+	name string
+	sql  string
+}
+
+var (
+	spaceRegexp = regexp.MustCompile(`\s+`)
+	punctRegexp = regexp.MustCompile(`\s*([(),])\s*`)
+)
+
+// normalizeSQL strips the differences SQLite introduces when it rewrites a
+// stored CREATE statement (ALTER TABLE ... RENAME quotes the name, DROP COLUMN
+// leaves different whitespace) and the IF NOT EXISTS of the migration files.
+func normalizeSQL(s string) string {
+	s = strings.ToLower(s)
+	s = strings.ReplaceAll(s, `"`, "")
+	s = strings.ReplaceAll(s, "if not exists ", "")
+	s = spaceRegexp.ReplaceAllString(s, " ")
+	s = punctRegexp.ReplaceAllString(s, "$1")
+	return strings.TrimSpace(s)
+}
+
+// schema returns every table, index, trigger and view except the migration
+// bookkeeping tables, sorted by type and name.
+func schema(t *testing.T, db *sql.DB) []schemaObject {
+	t.Helper()
+	rows, err := db.Query(`
+SELECT type, name, COALESCE(sql, '') FROM sqlite_master
+WHERE name NOT LIKE 'schema_migration%' AND name NOT LIKE 'sqlite_%'
+ORDER BY type, name`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var objects []schemaObject
+	for rows.Next() {
+		var o schemaObject
+		if err := rows.Scan(&o.typ, &o.name, &o.sql); err != nil {
+			t.Fatal(err)
+		}
+		o.sql = normalizeSQL(o.sql)
+		objects = append(objects, o)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return objects
+}
+
+func diffSchema(t *testing.T, label string, got []schemaObject, want []schemaObject) {
+	t.Helper()
+	index := func(objects []schemaObject) map[string]schemaObject {
+		m := map[string]schemaObject{}
+		for _, o := range objects {
+			m[o.typ+" "+o.name] = o
+		}
+		return m
+	}
+	g, w := index(got), index(want)
+	for key, o := range w {
+		if other, ok := g[key]; !ok {
+			t.Errorf("%s: missing %s", label, key)
+		} else if other.sql != o.sql {
+			t.Errorf("%s: %s differs\n got: %s\nwant: %s", label, key, other.sql, o.sql)
+		}
+	}
+	for key := range g {
+		if _, ok := w[key]; !ok {
+			t.Errorf("%s: unexpected %s", label, key)
+		}
+	}
+}
+
+func openTestDatabase(t *testing.T, name string) *Database {
+	t.Helper()
+	d, err := Open(filepath.Join(t.TempDir(), name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { d.Close() })
+	return d
+}
+
+// TestMigrationsRoundTrip migrates a database, rolls the migrations back one at a
+// time and migrates it again. After reverting migration k the schema must equal
+// that of a database with only migrations 1..k-1 applied, so every down file
+// exactly reverses its up file (tables, columns, indexes and triggers).
+func TestMigrationsRoundTrip(t *testing.T) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// expected[k] is the schema with the first k migrations applied
+	expected := make([][]schemaObject, len(migrations)+1)
+	ref := openTestDatabase(t, "reference.db")
+	expected[0] = schema(t, ref.db)
+	for k, m := range migrations {
+		if _, err := ref.db.Exec(m.sql); err != nil {
+			t.Fatalf("apply %s: %v", m.filename, err)
+		}
+		expected[k+1] = schema(t, ref.db)
+	}
+
+	d := openTestDatabase(t, "mcloud.db")
+	if err := d.Migrate(); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	diffSchema(t, "after Migrate", schema(t, d.db), expected[len(migrations)])
+
+	// Every migration but the base schema has a down file
+	for k := len(migrations) - 1; k >= 1; k-- {
+		if migrations[k].down == "" {
+			t.Fatalf("%s has no down migration", migrations[k].filename)
+		}
+		reverted, err := d.Rollback(1)
+		if err != nil {
+			t.Fatalf("Rollback of %s: %v", migrations[k].filename, err)
+		}
+		if len(reverted) != 1 || reverted[0] != migrations[k].filename {
+			t.Fatalf("Rollback(1) reverted %v, want [%s]", reverted, migrations[k].filename)
+		}
+		diffSchema(t, "after reverting "+migrations[k].filename, schema(t, d.db), expected[k])
+	}
+
+	// The base schema cannot be rolled back
+	if _, err := d.Rollback(1); err == nil {
+		t.Fatalf("Rollback of %s succeeded, want ErrIrreversible", migrations[0].filename)
+	}
+
+	if err := d.Migrate(); err != nil {
+		t.Fatalf("Migrate after rollback: %v", err)
+	}
+	diffSchema(t, "after re-Migrate", schema(t, d.db), expected[len(migrations)])
+}
+
+// TestRollbackKeepsData checks that the down migrations that rebuild or alter
+// populated tables keep their rows.
+func TestRollbackKeepsData(t *testing.T) {
+	d := openTestDatabase(t, "mcloud.db")
+	if err := d.Migrate(); err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+
+	stmts := []string{
+		`INSERT INTO clusters (id, name, state) VALUES ('c1', 'prod', 'partial')`,
+		`INSERT INTO workloads (id, cluster_id, name, kind, status, image, priority) VALUES ('w1', 'c1', 'web', 'container', 'running', 'ubuntu/24.04', 'high')`,
+		`INSERT INTO events (type, message) VALUES ('node.offline', 'node2 stopped reporting')`,
+	}
+	for _, stmt := range stmts {
+		if _, err := d.db.Exec(stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Rollback(len(migrations) - 1); err != nil {
+		t.Fatalf("Rollback: %v", err)
+	}
+
+	var state string
+	if err := d.db.QueryRow(`SELECT state FROM clusters WHERE id = 'c1'`).Scan(&state); err != nil {
+		t.Fatal(err)
+	}
+	if state != "init" {
+		t.Errorf("cluster state after rollback = %q, want init", state)
+	}
+	var n int
+	if err := d.db.QueryRow(`SELECT COUNT(*) FROM workloads WHERE id = 'w1'`).Scan(&n); err != nil || n != 1 {
+		t.Errorf("workload after rollback: count %d, err %v", n, err)
+	}
+
+	// Migrating again rebuilds the full-text index from the existing events
+	if err := d.Migrate(); err != nil {
+		t.Fatalf("Migrate after rollback: %v", err)
+	}
+	if err := d.db.QueryRow(`SELECT COUNT(*) FROM events_fts WHERE events_fts MATCH 'stopped'`).Scan(&n); err != nil || n != 1 {
+		t.Errorf("events_fts match after re-Migrate: count %d, err %v", n, err)
+	}
+}
@@ -0,0 +1,94 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type NodeAttribute struct {
+	NodeID    string    `json:"node_id"`
+	Key       string    `json:"key"`
+	Value     string    `json:"value"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type NodeAttributeRepository struct {
+	exec sqlExecutor
+}
+
+func NewNodeAttributeRepository(db *sql.DB) *NodeAttributeRepository {
+	return &NodeAttributeRepository{exec: executor(db)}
+}
+
+func NewNodeAttributeRepositoryTx(tx *sql.Tx) *NodeAttributeRepository {
+	return &NodeAttributeRepository{exec: tx}
+}
+
+// Set creates the attribute or replaces its value.
+func (r *NodeAttributeRepository) Set(ctx context.Context, nodeID string, key string, value string) error {
+	_, err := r.exec.ExecContext(ctx, `
+INSERT INTO node_attributes (node_id, key, value)
+VALUES (?, ?, ?)
+ON CONFLICT(node_id, key) DO UPDATE SET
+value = excluded.value, updated_at = CURRENT_TIMESTAMP
+`, nodeID, key, value)
+	return err
+}
+
+// Delete removes an attribute. It reports whether the attribute existed.
+func (r *NodeAttributeRepository) Delete(ctx context.Context, nodeID string, key string) (bool, error) {
+	res, err := r.exec.ExecContext(ctx, `DELETE FROM node_attributes WHERE node_id = ? AND key = ?`, nodeID, key)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (r *NodeAttributeRepository) ListByNode(ctx context.Context, nodeID string) ([]NodeAttribute, error) {
+	rows, err := r.exec.QueryContext(ctx, `
+SELECT node_id, key, value, updated_at
+FROM node_attributes WHERE node_id = ?
+ORDER BY key
+`, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []NodeAttribute
+	for rows.Next() {
+		var a NodeAttribute
+		if err := rows.Scan(&a.NodeID, &a.Key, &a.Value, &a.UpdatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, a)
+	}
+	return items, nil
+}
+
+// ListByKey returns the given attribute for every node that has it.
+func (r *NodeAttributeRepository) ListByKey(ctx context.Context, key string) ([]NodeAttribute, error) {
+	rows, err := r.exec.QueryContext(ctx, `
+SELECT node_id, key, value, updated_at
+FROM node_attributes WHERE key = ?
+`, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []NodeAttribute
+	for rows.Next() {
+		var a NodeAttribute
+		if err := rows.Scan(&a.NodeID, &a.Key, &a.Value, &a.UpdatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, a)
+	}
+	return items, nil
+}
@@ -19,11 +19,11 @@ type NodeCertificate struct {
 }
 
 type NodeCertificateRepository struct {
-	db *sql.DB
+	db sqlExecutor
 }
 
 func NewNodeCertificateRepository(db *sql.DB) *NodeCertificateRepository {
-	return &NodeCertificateRepository{db: db}
+	return &NodeCertificateRepository{db: executor(db)}
 }
 
 func (r *NodeCertificateRepository) Create(ctx context.Context, c *NodeCertificate) error {
@@ -0,0 +1,78 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Kinds of NodeLabel.
+const (
+	NodeLabelKindLabel = "label"
+	NodeLabelKindTaint = "taint"
+)
+
+// NodeLabel is a label or a taint of a node. Effect is only set on taints.
+type NodeLabel struct {
+	NodeID    string    `json:"node_id"`
+	Kind      string    `json:"kind"`
+	Key       string    `json:"key"`
+	Value     string    `json:"value"`
+	Effect    string    `json:"effect,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type NodeLabelRepository struct {
+	exec sqlExecutor
+}
+
+func NewNodeLabelRepository(db *sql.DB) *NodeLabelRepository {
+	return &NodeLabelRepository{exec: executor(db)}
+}
+
+func NewNodeLabelRepositoryTx(tx *sql.Tx) *NodeLabelRepository {
+	return &NodeLabelRepository{exec: tx}
+}
+
+// Set creates the label or taint or replaces its value and effect.
+func (r *NodeLabelRepository) Set(ctx context.Context, l *NodeLabel) error {
+	_, err := r.exec.ExecContext(ctx, `
+INSERT INTO node_labels (node_id, kind, key, value, effect)
+VALUES (?, ?, ?, ?, ?)
+ON CONFLICT(node_id, kind, key) DO UPDATE SET
+value = excluded.value, effect = excluded.effect, updated_at = CURRENT_TIMESTAMP
+`, l.NodeID, l.Kind, l.Key, l.Value, l.Effect)
+	return err
+}
+
+// Delete removes a label or taint. It reports whether it existed.
+func (r *NodeLabelRepository) Delete(ctx context.Context, nodeID string, kind string, key string) (bool, error) {
+	res, err := r.exec.ExecContext(ctx, `DELETE FROM node_labels WHERE node_id = ? AND kind = ? AND key = ?`, nodeID, kind, key)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+const nodeLabelSelect = `SELECT l.node_id, l.kind, l.key, l.value, l.effect, l.updated_at FROM node_labels l`
+
+// ListByNode returns the labels, then the taints, of a node, each by key.
+func (r *NodeLabelRepository) ListByNode(ctx context.Context, nodeID string) ([]NodeLabel, error) {
+	return collect(ctx, r.exec, nodeLabelSelect+` WHERE l.node_id = ? ORDER BY l.kind, l.key`, []any{nodeID}, scanNodeLabel)
+}
+
+// ListByCluster returns the labels and taints of every node of a cluster.
+func (r *NodeLabelRepository) ListByCluster(ctx context.Context, clusterID string) ([]NodeLabel, error) {
+	return collect(ctx, r.exec, nodeLabelSelect+`
+JOIN nodes n ON n.id = l.node_id
+WHERE n.cluster_id = ?
+ORDER BY l.node_id, l.kind, l.key`, []any{clusterID}, scanNodeLabel)
+}
+
+func scanNodeLabel(row rowScanner, l *NodeLabel) error {
+	return row.Scan(&l.NodeID, &l.Kind, &l.Key, &l.Value, &l.Effect, &l.UpdatedAt)
+}
@@ -0,0 +1,83 @@
+package database
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestNodeLabelRepository(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	c := testCluster(t, db, "prod")
+	other := testCluster(t, db, "staging")
+	n1 := testNode(t, db, c, "node1")
+	n2 := testNode(t, db, c, "node2")
+	n3 := testNode(t, db, other, "node3")
+	repo := NewNodeLabelRepository(db)
+
+	for _, l := range []NodeLabel{
+		{NodeID: n1.ID, Kind: NodeLabelKindLabel, Key: "zone", Value: "a"},
+		{NodeID: n1.ID, Kind: NodeLabelKindLabel, Key: "disk", Value: "hdd"},
+		{NodeID: n1.ID, Kind: NodeLabelKindTaint, Key: "gpu", Value: "true", Effect: "NoSchedule"},
+		{NodeID: n2.ID, Kind: NodeLabelKindLabel, Key: "zone", Value: "b"},
+		{NodeID: n3.ID, Kind: NodeLabelKindLabel, Key: "zone", Value: "c"},
+		// A label and a taint may share a key
+		{NodeID: n1.ID, Kind: NodeLabelKindTaint, Key: "zone", Effect: "PreferNoSchedule"},
+	} {
+		if err := repo.Set(ctx, &l); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.Set(ctx, &NodeLabel{NodeID: n1.ID, Kind: NodeLabelKindLabel, Key: "disk", Value: "ssd"}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := repo.ListByNode(ctx, n1.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"label disk=ssd", "label zone=a", "taint gpu=true:NoSchedule", "taint zone=:PreferNoSchedule"}
+	if s := labelStrings(got); !slices.Equal(s, want) {
+		t.Errorf("ListByNode(node1) = %v, want %v", s, want)
+	}
+
+	got, err = repo.ListByCluster(ctx, c.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 5 {
+		t.Errorf("ListByCluster(prod) = %v, want the 4 of node1 and 1 of node2", labelStrings(got))
+	}
+
+	if _, err := db.ExecContext(ctx, `INSERT INTO node_labels (node_id, kind, key, effect) VALUES (?, 'taint', 'x', 'Evict')`, n2.ID); err == nil {
+		t.Error("taint with effect Evict was stored")
+	}
+
+	if ok, err := repo.Delete(ctx, n1.ID, NodeLabelKindTaint, "gpu"); err != nil || !ok {
+		t.Errorf("Delete(gpu taint) = %v, %v, want true", ok, err)
+	}
+	if ok, err := repo.Delete(ctx, n1.ID, NodeLabelKindTaint, "gpu"); err != nil || ok {
+		t.Errorf("second Delete(gpu taint) = %v, %v, want false", ok, err)
+	}
+
+	// Labels go with their node
+	if err := NewNodeRepository(db).Purge(ctx, n2.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := repo.ListByNode(ctx, n2.ID); err != nil || len(got) != 0 {
+		t.Errorf("ListByNode(deleted node2) = %v, %v", labelStrings(got), err)
+	}
+}
+
+func labelStrings(labels []NodeLabel) []string {
+	var s []string
+	for _, l := range labels {
+		v := l.Kind + " " + l.Key + "=" + l.Value
+		if l.Effect != "" {
+			v += ":" + l.Effect
+		}
+		s = append(s, v)
+	}
+	return s
+}
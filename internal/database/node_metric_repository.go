@@ -0,0 +1,86 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type NodeMetric struct {
+	ID               int64     `json:"-"`
+	NodeID           string    `json:"node_id"`
+	CPUCount         int       `json:"cpu_count"`
+	Load1            float64   `json:"load1"`
+	Load5            float64   `json:"load5"`
+	Load15           float64   `json:"load15"`
+	MemoryUsedMB     int       `json:"memory_used_mb"`
+	MemoryTotalMB    int       `json:"memory_total_mb"`
+	DiskUsedBytes    uint64    `json:"disk_used_bytes"`
+	DiskTotalBytes   uint64    `json:"disk_total_bytes"`
+	NetRxBytesPerSec uint64    `json:"net_rx_bytes_per_sec"`
+	NetTxBytesPerSec uint64    `json:"net_tx_bytes_per_sec"`
+	CollectedAt      time.Time `json:"collected_at"`
+	HookQueueDepth   int       `json:"hook_queue_depth"`
+}
+
+type NodeMetricRepository struct {
+	exec sqlExecutor
+}
+
+func NewNodeMetricRepository(db *sql.DB) *NodeMetricRepository {
+	return &NodeMetricRepository{exec: executor(db)}
+}
+
+func NewNodeMetricRepositoryTx(tx *sql.Tx) *NodeMetricRepository {
+	return &NodeMetricRepository{exec: tx}
+}
+
+func (r *NodeMetricRepository) Create(ctx context.Context, m *NodeMetric) error {
+	_, err := r.exec.ExecContext(ctx, `
+INSERT INTO node_metrics (
+node_id, cpu_count, load1, load5, load15,
+memory_used_mb, memory_total_mb, disk_used_bytes, disk_total_bytes,
+net_rx_bytes_per_sec, net_tx_bytes_per_sec, collected_at, hook_queue_depth
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+`, m.NodeID, m.CPUCount, m.Load1, m.Load5, m.Load15,
+		m.MemoryUsedMB, m.MemoryTotalMB, m.DiskUsedBytes, m.DiskTotalBytes,
+		m.NetRxBytesPerSec, m.NetTxBytesPerSec, m.CollectedAt.UTC(), m.HookQueueDepth)
+	return err
+}
+
+// DeleteBefore removes samples of a node collected before the cutoff.
+func (r *NodeMetricRepository) DeleteBefore(ctx context.Context, nodeID string, cutoff time.Time) error {
+	_, err := r.exec.ExecContext(ctx, `
+DELETE FROM node_metrics WHERE node_id = ? AND collected_at < ?
+`, nodeID, cutoff.UTC())
+	return err
+}
+
+// ListSince returns samples of a node collected at or after since, oldest first.
+func (r *NodeMetricRepository) ListSince(ctx context.Context, nodeID string, since time.Time) ([]NodeMetric, error) {
+	rows, err := r.exec.QueryContext(ctx, `
+SELECT id, node_id, cpu_count, load1, load5, load15,
+memory_used_mb, memory_total_mb, disk_used_bytes, disk_total_bytes,
+net_rx_bytes_per_sec, net_tx_bytes_per_sec, collected_at, hook_queue_depth
+FROM node_metrics WHERE node_id = ? AND collected_at >= ?
+ORDER BY collected_at
+`, nodeID, since.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []NodeMetric
+	for rows.Next() {
+		var m NodeMetric
+		if err := rows.Scan(
+			&m.ID, &m.NodeID, &m.CPUCount, &m.Load1, &m.Load5, &m.Load15,
+			&m.MemoryUsedMB, &m.MemoryTotalMB, &m.DiskUsedBytes, &m.DiskTotalBytes,
+			&m.NetRxBytesPerSec, &m.NetTxBytesPerSec, &m.CollectedAt, &m.HookQueueDepth,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, m)
+	}
+	return items, nil
+}
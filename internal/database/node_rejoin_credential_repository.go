@@ -0,0 +1,68 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// NodeRejoinCredential is the secret a node re-joins with: its hash, and the
+// secret sealed with the manager's secrets key (see secret.Seal).
+type NodeRejoinCredential struct {
+	NodeID       string     `json:"node_id"`
+	SecretHash   string     `json:"-"`
+	SecretSealed string     `json:"-"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UsedAt       *time.Time `json:"used_at"`
+}
+
+type NodeRejoinCredentialRepository struct {
+	exec sqlExecutor
+}
+
+func NewNodeRejoinCredentialRepository(db *sql.DB) *NodeRejoinCredentialRepository {
+	return &NodeRejoinCredentialRepository{exec: executor(db)}
+}
+
+func NewNodeRejoinCredentialRepositoryTx(tx *sql.Tx) *NodeRejoinCredentialRepository {
+	return &NodeRejoinCredentialRepository{exec: tx}
+}
+
+// Upsert issues (or re-issues) the credential of a node.
+func (r *NodeRejoinCredentialRepository) Upsert(ctx context.Context, nodeID string, secretHash string, secretSealed string) error {
+	_, err := r.exec.ExecContext(ctx, `
+INSERT INTO node_rejoin_credentials (node_id, secret_hash, secret_sealed)
+VALUES (?, ?, ?)
+ON CONFLICT(node_id) DO UPDATE SET
+secret_hash = excluded.secret_hash, secret_sealed = excluded.secret_sealed, created_at = CURRENT_TIMESTAMP, used_at = NULL
+`, nodeID, secretHash, secretSealed)
+	return err
+}
+
+func (r *NodeRejoinCredentialRepository) Get(ctx context.Context, nodeID string) (*NodeRejoinCredential, error) {
+	row := r.exec.QueryRowContext(ctx, `
+SELECT node_id, secret_hash, secret_sealed, created_at, used_at
+FROM node_rejoin_credentials WHERE node_id = ?
+`, nodeID)
+
+	var c NodeRejoinCredential
+	if err := row.Scan(&c.NodeID, &c.SecretHash, &c.SecretSealed, &c.CreatedAt, &c.UsedAt); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Rotate replaces the secret if its hash is still oldHash, so two re-joins
+// with the same secret cannot both succeed. It reports whether it did.
+func (r *NodeRejoinCredentialRepository) Rotate(ctx context.Context, nodeID string, oldHash string, newHash string, newSealed string) (bool, error) {
+	res, err := r.exec.ExecContext(ctx, `
+UPDATE node_rejoin_credentials
+SET secret_hash = ?, secret_sealed = ?, used_at = CURRENT_TIMESTAMP
+WHERE node_id = ? AND secret_hash = ?
+`, newHash, newSealed, nodeID, oldHash)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
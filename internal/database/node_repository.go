@@ -7,19 +7,19 @@ import (
 )
 
 type Node struct {
-	ID            string
-	ClusterID     string
-	Hostname      string
-	IP            string
-	Role          string
-	Status        string
-	JoinedAt      time.Time
-	LastHeartbeat *time.Time
+	ID            string     `json:"id"`
+	ClusterID     string     `json:"cluster_id"`
+	Hostname      string     `json:"hostname"`
+	IP            string     `json:"ip"`
+	Role          string     `json:"role"`
+	Status        string     `json:"status"`
+	JoinedAt      time.Time  `json:"joined_at"`
+	LastHeartbeat *time.Time `json:"last_heartbeat"`
 
-	CreatedAt    time.Time
-	CreateUserID *string
-	UpdatedAt    time.Time
-	UpdateUserID *string
+	CreatedAt    time.Time `json:"created_at"`
+	CreateUserID *string   `json:"create_user_id"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	UpdateUserID *string   `json:"update_user_id"`
 }
 
 type NodeRepository struct {
@@ -27,7 +27,7 @@ type NodeRepository struct {
 }
 
 func NewNodeRepository(db *sql.DB) *NodeRepository {
-	return &NodeRepository{exec: db}
+	return &NodeRepository{exec: executor(db)}
 }
 
 func NewNodeRepositoryTx(tx *sql.Tx) *NodeRepository {
@@ -53,6 +53,13 @@ WHERE id = ?
 	return err
 }
 
+func (r *NodeRepository) UpdateStatus(ctx context.Context, id string, status string) error {
+	_, err := r.exec.ExecContext(ctx, `
+UPDATE nodes SET status = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?
+`, status, id)
+	return err
+}
+
 func (r *NodeRepository) UpdateHeartbeat(ctx context.Context, nodeID string) error {
 	_, err := r.exec.ExecContext(ctx, `
 UPDATE nodes SET last_heartbeat = CURRENT_TIMESTAMP WHERE id = ?
@@ -65,48 +72,85 @@ func (r *NodeRepository) DeleteByID(ctx context.Context, id string) error {
 	return err
 }
 
+// Purge deletes a node with its certificates, health, metrics, attributes,
+// labels and re-join credential, and detaches the workloads placed on it. Foreign keys
+// are not enforced, so the cascades of the schema are done here; events and
+// image builds keep the node's ID as history.
+func (r *NodeRepository) Purge(ctx context.Context, id string) error {
+	stmts := []string{
+		`DELETE FROM node_certificates WHERE node_id = ?`,
+		`DELETE FROM node_health WHERE node_id = ?`,
+		`DELETE FROM node_metrics WHERE node_id = ?`,
+		`DELETE FROM node_attributes WHERE node_id = ?`,
+		`DELETE FROM node_labels WHERE node_id = ?`,
+		`DELETE FROM node_rejoin_credentials WHERE node_id = ?`,
+		`UPDATE workloads SET node_id = NULL, updated_at = CURRENT_TIMESTAMP WHERE node_id = ?`,
+		`DELETE FROM nodes WHERE id = ?`,
+	}
+	for _, stmt := range stmts {
+		if _, err := r.exec.ExecContext(ctx, stmt, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (r *NodeRepository) GetByID(ctx context.Context, id string) (*Node, error) {
-	row := r.exec.QueryRowContext(ctx, `
-SELECT id, cluster_id, hostname, ip, role, status,
-joined_at, last_heartbeat,
-created_at, create_user_id, updated_at, update_user_id
-FROM nodes WHERE id = ?
-`, id)
+	var n Node
+	if err := scanNode(r.exec.QueryRowContext(ctx, nodeList.selectSQL+` WHERE id = ?`, id), &n); err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
 
+func (r *NodeRepository) GetByHostname(ctx context.Context, hostname string) (*Node, error) {
 	var n Node
-	if err := row.Scan(
-		&n.ID, &n.ClusterID, &n.Hostname, &n.IP,
-		&n.Role, &n.Status, &n.JoinedAt, &n.LastHeartbeat,
-		&n.CreatedAt, &n.CreateUserID, &n.UpdatedAt, &n.UpdateUserID,
-	); err != nil {
+	if err := scanNode(r.exec.QueryRowContext(ctx, nodeList.selectSQL+` WHERE hostname = ?`, hostname), &n); err != nil {
 		return nil, err
 	}
 	return &n, nil
 }
 
-func (r *NodeRepository) ListByCluster(ctx context.Context, clusterID string) ([]Node, error) {
-	rows, err := r.exec.QueryContext(ctx, `
-SELECT id, cluster_id, hostname, ip, role, status,
-joined_at, last_heartbeat,
-created_at, create_user_id, updated_at, update_user_id
-FROM nodes WHERE cluster_id = ?
-`, clusterID)
+// ListByCluster returns the nodes of a cluster; ListOptions{} returns all of them.
+func (r *NodeRepository) ListByCluster(ctx context.Context, clusterID string, opts ListOptions) ([]Node, error) {
+	query, args, err := nodeList.build(opts, []string{"cluster_id = ?"}, clusterID)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var items []Node
-	for rows.Next() {
-		var n Node
-		if err := rows.Scan(
-			&n.ID, &n.ClusterID, &n.Hostname, &n.IP,
-			&n.Role, &n.Status, &n.JoinedAt, &n.LastHeartbeat,
-			&n.CreatedAt, &n.CreateUserID, &n.UpdatedAt, &n.UpdateUserID,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, n)
+	return collect(ctx, r.exec, query, args, scanNode)
+}
+
+// List returns nodes across all clusters.
+//
+// Example Input:
+//   List(ctx, ListOptions{Limit: 100, Sort: "hostname", Filter: map[string]string{"status": "ready"}})
+func (r *NodeRepository) List(ctx context.Context, opts ListOptions) ([]Node, error) {
+	query, args, err := nodeList.build(opts, nil)
+	if err != nil {
+		return nil, err
 	}
-	return items, nil
+	return collect(ctx, r.exec, query, args, scanNode)
+}
+
+var nodeList = listQuery{
+	selectSQL: `SELECT id, cluster_id, hostname, ip, role, status,
+joined_at, last_heartbeat,
+created_at, create_user_id, updated_at, update_user_id
+FROM nodes`,
+	defaultSort: "created_at, id",
+	sortable: map[string]string{
+		"hostname": "hostname", "status": "status", "role": "role",
+		"joined_at": "joined_at", "last_heartbeat": "last_heartbeat", "created_at": "created_at",
+	},
+	filterable: map[string]string{
+		"cluster": "cluster_id", "hostname": "hostname", "status": "status", "role": "role",
+	},
+}
+
+func scanNode(row rowScanner, n *Node) error {
+	return row.Scan(
+		&n.ID, &n.ClusterID, &n.Hostname, &n.IP,
+		&n.Role, &n.Status, &n.JoinedAt, &n.LastHeartbeat,
+		&n.CreatedAt, &n.CreateUserID, &n.UpdatedAt, &n.UpdateUserID,
+	)
 }
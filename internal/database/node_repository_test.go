@@ -0,0 +1,121 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNodeRepository(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	c := testCluster(t, db, "prod")
+	other := testCluster(t, db, "staging")
+	n1 := testNode(t, db, c, "node1")
+	testNode(t, db, c, "node2")
+	testNode(t, db, other, "node3")
+	repo := NewNodeRepository(db)
+
+	got, err := repo.GetByHostname(ctx, "node1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != n1.ID || got.ClusterID != c.ID || got.Status != "online" || got.JoinedAt.IsZero() || got.LastHeartbeat != nil {
+		t.Errorf("GetByHostname(node1) = %+v", got)
+	}
+	if _, err := repo.GetByID(ctx, "missing"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetByID(missing) error = %v, want sql.ErrNoRows", err)
+	}
+
+	n1.IP, n1.Role = "10.0.0.9", "leader"
+	if err := repo.UpdateByID(ctx, n1); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.UpdateStatus(ctx, n1.ID, "offline"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.UpdateHeartbeat(ctx, n1.ID); err != nil {
+		t.Fatal(err)
+	}
+	got, err = repo.GetByID(ctx, n1.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.IP != "10.0.0.9" || got.Role != "leader" || got.Status != "offline" || got.LastHeartbeat == nil {
+		t.Errorf("after updates node1 = %+v", got)
+	}
+	if time.Since(*got.LastHeartbeat) > time.Minute {
+		t.Errorf("last heartbeat %s, want about now", got.LastHeartbeat)
+	}
+
+	nodes, err := repo.ListByCluster(ctx, c.ID, ListOptions{Sort: "-hostname"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 2 || nodes[0].Hostname != "node2" || nodes[1].Hostname != "node1" {
+		t.Errorf("ListByCluster(prod, -hostname) = %v", hostnames(nodes))
+	}
+	nodes, err = repo.List(ctx, ListOptions{Filter: map[string]string{"status": "online"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 2 || nodes[0].Hostname != "node2" || nodes[1].Hostname != "node3" {
+		t.Errorf("List(status=online) = %v", hostnames(nodes))
+	}
+	nodes, err = repo.List(ctx, ListOptions{Limit: 1, Offset: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 1 || nodes[0].Hostname != "node2" {
+		t.Errorf("List(limit 1, offset 1) = %v", hostnames(nodes))
+	}
+	if _, err := repo.List(ctx, ListOptions{Sort: "ip"}); !errors.Is(err, ErrInvalidListOption) {
+		t.Errorf("List(sort ip) error = %v, want ErrInvalidListOption", err)
+	}
+}
+
+func TestNodeRepositoryPurge(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	c := testCluster(t, db, "prod")
+	n := testNode(t, db, c, "node1")
+	w := testWorkload(t, db, c, n, "web")
+
+	if err := NewNodeAttributeRepository(db).Set(ctx, n.ID, "rack", "r1"); err != nil {
+		t.Fatal(err)
+	}
+	cert := &NodeCertificate{ID: "cert-1", NodeID: n.ID, CertPEM: "PEM", IssuedAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour)}
+	if err := NewNodeCertificateRepository(db).Create(ctx, cert); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := NewNodeRepository(db).Purge(ctx, n.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewNodeRepository(db).GetByID(ctx, n.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetByID after Purge error = %v, want sql.ErrNoRows", err)
+	}
+	if attrs, err := NewNodeAttributeRepository(db).ListByNode(ctx, n.ID); err != nil || len(attrs) != 0 {
+		t.Errorf("attributes after Purge = %v, %v", attrs, err)
+	}
+	if certs, err := NewNodeCertificateRepository(db).GetByNode(ctx, n.ID); err != nil || len(certs) != 0 {
+		t.Errorf("certificates after Purge = %v, %v", certs, err)
+	}
+	got, err := NewWorkloadRepository(db).GetByID(ctx, w.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.NodeID != nil {
+		t.Errorf("workload still placed on %s after Purge", *got.NodeID)
+	}
+}
+
+func hostnames(nodes []Node) []string {
+	var names []string
+	for _, n := range nodes {
+		names = append(names, n.Hostname)
+	}
+	return names
+}
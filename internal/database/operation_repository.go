@@ -0,0 +1,154 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+type Operation struct {
+	ID           string          `json:"id"`
+	Type         string          `json:"type"`
+	Status       string          `json:"status"`
+	Progress     int             `json:"progress"`
+	Message      *string         `json:"message"`
+	Resource     *string         `json:"resource"`
+	Result       json.RawMessage `json:"result,omitempty"`
+	Error        *string         `json:"error"`
+	Manager      string          `json:"manager"`
+	CreatedAt    time.Time       `json:"created_at"`
+	CreateUserID *string         `json:"create_user_id"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+}
+
+type OperationRepository struct {
+	exec sqlExecutor
+}
+
+func NewOperationRepository(db *sql.DB) *OperationRepository {
+	return &OperationRepository{exec: executor(db)}
+}
+
+func NewOperationRepositoryTx(tx *sql.Tx) *OperationRepository {
+	return &OperationRepository{exec: tx}
+}
+
+func (r *OperationRepository) Create(ctx context.Context, o *Operation) error {
+	_, err := r.exec.ExecContext(ctx, `
+INSERT INTO operations (id, type, status, progress, message, resource, manager, create_user_id)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+`, o.ID, o.Type, o.Status, o.Progress, o.Message, o.Resource, o.Manager, o.CreateUserID)
+	return err
+}
+
+// UpdateProgress sets the status, percentage and current step of an operation.
+func (r *OperationRepository) UpdateProgress(ctx context.Context, id string, status string, progress int, message string) error {
+	_, err := r.exec.ExecContext(ctx, `
+UPDATE operations
+SET status = ?, progress = ?, message = ?, updated_at = CURRENT_TIMESTAMP
+WHERE id = ?
+`, status, progress, message, id)
+	return err
+}
+
+// Finish records the final status with either a JSON result or an error.
+func (r *OperationRepository) Finish(ctx context.Context, id string, status string, result []byte, errMsg *string) error {
+	var res *string
+	if result != nil {
+		s := string(result)
+		res = &s
+	}
+	_, err := r.exec.ExecContext(ctx, `
+UPDATE operations
+SET status = ?, progress = CASE WHEN ? = 'succeeded' THEN 100 ELSE progress END,
+result = ?, error = ?, updated_at = CURRENT_TIMESTAMP
+WHERE id = ?
+`, status, status, res, errMsg, id)
+	return err
+}
+
+// FailUnfinished marks the pending and running operations of a manager as failed,
+// e.g. after it restarted and lost the goroutines running them.
+func (r *OperationRepository) FailUnfinished(ctx context.Context, manager string, errMsg string) (int64, error) {
+	res, err := r.exec.ExecContext(ctx, `
+UPDATE operations
+SET status = 'failed', error = ?, updated_at = CURRENT_TIMESTAMP
+WHERE manager = ? AND status IN ('pending', 'running')
+`, errMsg, manager)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// DeleteFinishedBefore removes succeeded and failed operations last updated before cutoff.
+func (r *OperationRepository) DeleteFinishedBefore(ctx context.Context, cutoff time.Time) error {
+	_, err := r.exec.ExecContext(ctx, `
+DELETE FROM operations
+WHERE status IN ('succeeded', 'failed') AND updated_at < ?
+`, cutoff.UTC())
+	return err
+}
+
+const operationColumns = `id, type, status, progress, message, resource, result, error, manager,
+created_at, create_user_id, updated_at`
+
+func scanOperation(scan func(dest ...any) error) (*Operation, error) {
+	var o Operation
+	var result *string
+	if err := scan(
+		&o.ID, &o.Type, &o.Status, &o.Progress, &o.Message, &o.Resource, &result, &o.Error, &o.Manager,
+		&o.CreatedAt, &o.CreateUserID, &o.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	if result != nil {
+		o.Result = json.RawMessage(*result)
+	}
+	return &o, nil
+}
+
+func (r *OperationRepository) GetByID(ctx context.Context, id string) (*Operation, error) {
+	row := r.exec.QueryRowContext(ctx, `
+SELECT `+operationColumns+`
+FROM operations WHERE id = ?
+`, id)
+	return scanOperation(row.Scan)
+}
+
+// List returns operations newest first, optionally only those with the given status.
+func (r *OperationRepository) List(ctx context.Context, status string, limit int, offset int) ([]Operation, error) {
+	var items []Operation
+	err := r.Each(ctx, status, limit, offset, func(o *Operation) error {
+		items = append(items, *o)
+		return nil
+	})
+	return items, err
+}
+
+// Each is List calling fn for every operation as it is read from the cursor.
+func (r *OperationRepository) Each(ctx context.Context, status string, limit int, offset int, fn func(*Operation) error) error {
+	rows, err := r.exec.QueryContext(ctx, `
+SELECT `+operationColumns+`
+FROM operations
+WHERE (? = '' OR status = ?)
+ORDER BY created_at DESC, id
+LIMIT ? OFFSET ?
+`, status, status, limit, offset)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		o, err := scanOperation(rows.Scan)
+		if err != nil {
+			return err
+		}
+		if err := fn(o); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,432 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// partitionSpec describes a table split into monthly partitions (see migration 022).
+type partitionSpec struct {
+	fts string // columns indexed with FTS5 in every partition, like <table>_fts; "" for none
+}
+
+// partitionedTables are the high-volume tables whose finished months are moved
+// into <table>_YYYY_MM partitions.
+var partitionedTables = map[string]partitionSpec{
+	"events":    {fts: "message, type"},
+	"audit_log": {},
+}
+
+// PartitionedTables returns the names of the tables split into monthly partitions.
+func PartitionedTables() []string {
+	tables := make([]string, 0, len(partitionedTables))
+	for base := range partitionedTables {
+		tables = append(tables, base)
+	}
+	sort.Strings(tables)
+	return tables
+}
+
+// ErrPartitionArchived is returned by Partition for a month whose partition has
+// already been archived; rows of it found in the base table are left there.
+var ErrPartitionArchived = errors.New("partition already archived")
+
+var (
+	monthRegexp         = regexp.MustCompile(`^\d{4}-\d{2}$`)
+	partitionNameRegexp = regexp.MustCompile(`^[a-z_]+_\d{4}_\d{2}$`)
+	createTableRegexp   = regexp.MustCompile(`(?i)^CREATE TABLE\s+(IF NOT EXISTS\s+)?["` + "`" + `]?\w+["` + "`" + `]?`)
+)
+
+// TablePartition is one month of a partitioned table. ArchivedAt is set once
+// the partition was copied to ArchivePath and dropped.
+type TablePartition struct {
+	Name        string     `json:"name"`
+	Base        string     `json:"base"`
+	Month       string     `json:"month"`
+	RowCount    int64      `json:"row_count"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ArchivePath *string    `json:"archive_path"`
+	ArchivedAt  *time.Time `json:"archived_at"`
+}
+
+// Live reports whether the partition is still in the database.
+func (p *TablePartition) Live() bool {
+	return p.ArchivedAt == nil
+}
+
+type PartitionRepository struct {
+	db *sql.DB
+}
+
+func NewPartitionRepository(db *sql.DB) *PartitionRepository {
+	return &PartitionRepository{db: db}
+}
+
+// PartitionName returns the table holding a month of base.
+//
+// Example:
+//   PartitionName("events", "2026-09")  ->  "events_2026_09"
+func PartitionName(base, month string) string {
+	return base + "_" + strings.ReplaceAll(month, "-", "_")
+}
+
+// monthRange returns the first instant of a month ("2026-09") and of the next,
+// formatted like the created_at columns.
+func monthRange(month string) (string, string, error) {
+	start, err := time.Parse("2006-01", month)
+	if !monthRegexp.MatchString(month) || err != nil {
+		return "", "", fmt.Errorf("invalid partition month %q", month)
+	}
+	return start.Format(time.DateTime), start.AddDate(0, 1, 0).Format(time.DateTime), nil
+}
+
+// List returns the partitions of base, archived ones included, oldest first.
+func (r *PartitionRepository) List(ctx context.Context, base string) ([]TablePartition, error) {
+	return collect(ctx, r.db, partitionSelect+` WHERE base = ? ORDER BY month`, []any{base}, scanTablePartition)
+}
+
+// UnpartitionedMonths returns the months before cutoff that still have rows in
+// base, oldest first.
+func (r *PartitionRepository) UnpartitionedMonths(ctx context.Context, base string, cutoff time.Time) ([]string, error) {
+	if _, ok := partitionedTables[base]; !ok {
+		return nil, fmt.Errorf("table %s is not partitioned", base)
+	}
+	rows, err := r.db.QueryContext(ctx, `SELECT DISTINCT substr(created_at, 1, 7) FROM `+base+` WHERE created_at < ? ORDER BY 1`,
+		cutoff.UTC().Format(time.DateTime))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var months []string
+	for rows.Next() {
+		var month string
+		if err := rows.Scan(&month); err != nil {
+			return nil, err
+		}
+		months = append(months, month)
+	}
+	return months, rows.Err()
+}
+
+// Partition moves the rows of one month of base into its partition table,
+// creating the table (with the base table's columns) if needed, and returns
+// how many rows were moved.
+//
+// Example Input:
+//   Partition(ctx, "events", "2026-09")
+//
+// Example Output:
+//   Returns: (18234, nil); events_2026_09 and events_2026_09_fts created, the rows deleted from events
+func (r *PartitionRepository) Partition(ctx context.Context, base, month string) (int64, error) {
+	spec, ok := partitionedTables[base]
+	if !ok {
+		return 0, fmt.Errorf("table %s is not partitioned", base)
+	}
+	start, end, err := monthRange(month)
+	if err != nil {
+		return 0, err
+	}
+	name := PartitionName(base, month)
+
+	var moved int64
+	err = WithTx(ctx, r.db, func(tx *sql.Tx) error {
+		var archived bool
+		err := tx.QueryRowContext(ctx, `SELECT archived_at IS NOT NULL FROM table_partitions WHERE name = ?`, name).Scan(&archived)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if archived {
+			return fmt.Errorf("%w: %s", ErrPartitionArchived, name)
+		}
+
+		ddl, err := tableDDL(ctx, tx, "main", base, name)
+		if err != nil {
+			return err
+		}
+		stmts := []string{
+			ddl,
+			`CREATE INDEX IF NOT EXISTS idx_` + name + `_created_at ON ` + name + `(created_at)`,
+		}
+		for _, stmt := range stmts {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return err
+			}
+		}
+		// A partition created before base gained columns needs them before the copy
+		if err := alignPartition(ctx, tx, base, name); err != nil {
+			return err
+		}
+
+		res, err := tx.ExecContext(ctx, `INSERT INTO `+name+` SELECT * FROM `+base+` WHERE created_at >= ? AND created_at < ?`, start, end)
+		if err != nil {
+			return err
+		}
+		if moved, err = res.RowsAffected(); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM `+base+` WHERE created_at >= ? AND created_at < ?`, start, end); err != nil {
+			return err
+		}
+
+		if spec.fts != "" {
+			fts := name + "_fts"
+			stmts := []string{
+				`CREATE VIRTUAL TABLE IF NOT EXISTS ` + fts + ` USING fts5(` + spec.fts + `, content='` + name + `', content_rowid='id')`,
+				`INSERT INTO ` + fts + `(` + fts + `) VALUES ('rebuild')`,
+			}
+			for _, stmt := range stmts {
+				if _, err := tx.ExecContext(ctx, stmt); err != nil {
+					return err
+				}
+			}
+		}
+
+		_, err = tx.ExecContext(ctx, `
+INSERT INTO table_partitions (name, base, month, row_count) VALUES (?, ?, ?, ?)
+ON CONFLICT (name) DO UPDATE SET row_count = row_count + excluded.row_count
+`, name, base, month, moved)
+		return err
+	})
+	return moved, err
+}
+
+// Archive copies a live partition to a standalone SQLite file at path (holding
+// one table named like the base table) and drops it from the database. The
+// file is written next to path first, so a failed archive leaves no file at path.
+//
+// Example Input:
+//   Archive(ctx, "events_2025_09", "/var/lib/mcloud/archive/events-2025-09.db")
+//
+// Example Output (Error):
+//   The file exists  =>  error("archive /var/lib/mcloud/archive/events-2025-09.db already exists")
+func (r *PartitionRepository) Archive(ctx context.Context, name, path string) error {
+	p, err := r.get(ctx, name)
+	if err != nil {
+		return err
+	}
+	if !p.Live() {
+		return fmt.Errorf("%w: %s", ErrPartitionArchived, name)
+	}
+	if _, ok := partitionedTables[p.Base]; !ok || !partitionNameRegexp.MatchString(p.Name) {
+		return fmt.Errorf("invalid partition %s of %s", p.Name, p.Base)
+	}
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("archive %s already exists", path)
+	}
+
+	tmp := path + ".tmp"
+	os.Remove(tmp)
+	if err := r.copyToFile(ctx, p, tmp); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+
+	return WithTx(ctx, r.db, func(tx *sql.Tx) error {
+		if partitionedTables[p.Base].fts != "" {
+			if _, err := tx.ExecContext(ctx, `DROP TABLE IF EXISTS `+name+`_fts`); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.ExecContext(ctx, `DROP TABLE IF EXISTS `+name); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, `UPDATE table_partitions SET archive_path = ?, archived_at = ? WHERE name = ?`,
+			path, time.Now().UTC(), name)
+		return err
+	})
+}
+
+// copyToFile writes the rows of a partition to a new SQLite file. ATTACH is
+// per connection, so it runs on a connection of its own.
+func (r *PartitionRepository) copyToFile(ctx context.Context, p *TablePartition, path string) error {
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `ATTACH DATABASE ? AS archive`, path); err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.Background(), `DETACH DATABASE archive`)
+
+	ddl, err := tableDDL(ctx, conn, "main", p.Name, "archive."+p.Base)
+	if err != nil {
+		return err
+	}
+	if _, err := conn.ExecContext(ctx, ddl); err != nil {
+		return err
+	}
+	_, err = conn.ExecContext(ctx, `INSERT INTO archive.`+p.Base+` SELECT * FROM `+p.Name)
+	return err
+}
+
+// tableColumn is a column as listed by pragma_table_info.
+type tableColumn struct {
+	name      string
+	typ       string
+	notNull   bool
+	dfltValue sql.NullString
+}
+
+func tableColumns(ctx context.Context, exec sqlExecutor, table string) ([]tableColumn, error) {
+	rows, err := exec.QueryContext(ctx, `SELECT name, type, "notnull", dflt_value FROM pragma_table_info(?) ORDER BY cid`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cols []tableColumn
+	for rows.Next() {
+		var c tableColumn
+		if err := rows.Scan(&c.name, &c.typ, &c.notNull, &c.dfltValue); err != nil {
+			return nil, err
+		}
+		cols = append(cols, c)
+	}
+	return cols, rows.Err()
+}
+
+// alignPartition adds the columns base gained after partition name was created
+// (e.g. events.severity, see migration 034), so partitions keep exactly the
+// columns of their base table, in the same order. A migration only alters the
+// base table; Migrate aligns the live partitions afterwards.
+func alignPartition(ctx context.Context, exec sqlExecutor, base, name string) error {
+	baseCols, err := tableColumns(ctx, exec, base)
+	if err != nil {
+		return err
+	}
+	partCols, err := tableColumns(ctx, exec, name)
+	if err != nil {
+		return err
+	}
+	have := make(map[string]bool, len(partCols))
+	for _, c := range partCols {
+		have[c.name] = true
+	}
+
+	for _, c := range baseCols {
+		if have[c.name] {
+			continue
+		}
+		stmt := `ALTER TABLE ` + name + ` ADD COLUMN ` + c.name + ` ` + c.typ
+		if c.notNull {
+			stmt += ` NOT NULL`
+		}
+		if c.dfltValue.Valid {
+			stmt += ` DEFAULT ` + c.dfltValue.String
+		}
+		if _, err := exec.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("align partition %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// alignPartitions runs alignPartition on every live partition.
+func alignPartitions(ctx context.Context, exec sqlExecutor) error {
+	for _, base := range PartitionedTables() {
+		tables, err := partitionTables(ctx, exec, base, time.Time{}, time.Time{})
+		if err != nil {
+			return err
+		}
+		for _, name := range tables {
+			if err := alignPartition(ctx, exec, base, name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// tableDDL returns the CREATE TABLE statement of table with the table renamed
+// to name, so a partition gets exactly the columns of its base table.
+func tableDDL(ctx context.Context, exec sqlExecutor, schema, table, name string) (string, error) {
+	var ddl string
+	err := exec.QueryRowContext(ctx, `SELECT sql FROM `+schema+`.sqlite_master WHERE type = 'table' AND name = ?`, table).Scan(&ddl)
+	if err != nil {
+		return "", fmt.Errorf("schema of %s: %w", table, err)
+	}
+	if !createTableRegexp.MatchString(ddl) {
+		return "", fmt.Errorf("unexpected schema of %s", table)
+	}
+	return createTableRegexp.ReplaceAllLiteralString(ddl, "CREATE TABLE IF NOT EXISTS "+name), nil
+}
+
+func (r *PartitionRepository) get(ctx context.Context, name string) (*TablePartition, error) {
+	var p TablePartition
+	if err := scanTablePartition(r.db.QueryRowContext(ctx, partitionSelect+` WHERE name = ?`, name), &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// partitionSource returns what a query of base reads FROM: base itself, or,
+// once it has live partitions overlapping [since, until), base and those
+// partitions as one subquery named like base. columns must include id; the
+// subquery also has it as rowid, which list queries order by. Zero times
+// leave that end of the range open.
+//
+// Example Output:
+//   "(SELECT id, type, id AS rowid FROM events UNION ALL SELECT id, type, id AS rowid FROM events_2026_09) AS events"
+func partitionSource(ctx context.Context, exec sqlExecutor, base, columns string, since, until time.Time) (string, error) {
+	tables, err := partitionTables(ctx, exec, base, since, until)
+	if err != nil || len(tables) == 0 {
+		return base, err
+	}
+
+	branches := []string{`SELECT ` + columns + `, id AS rowid FROM ` + base}
+	for _, t := range tables {
+		branches = append(branches, `SELECT `+columns+`, id AS rowid FROM `+t)
+	}
+	return "(" + strings.Join(branches, " UNION ALL ") + ") AS " + base, nil
+}
+
+// partitionTables returns the live partitions of base overlapping [since, until), newest first.
+func partitionTables(ctx context.Context, exec sqlExecutor, base string, since, until time.Time) ([]string, error) {
+	query := `SELECT name FROM table_partitions WHERE base = ? AND archived_at IS NULL`
+	args := []any{base}
+	if !since.IsZero() {
+		query += ` AND month >= ?`
+		args = append(args, since.UTC().Format("2006-01"))
+	}
+	if !until.IsZero() {
+		query += ` AND month <= ?`
+		args = append(args, until.UTC().Format("2006-01"))
+	}
+	rows, err := exec.QueryContext(ctx, query+` ORDER BY month DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		if !partitionNameRegexp.MatchString(name) {
+			return nil, fmt.Errorf("invalid partition name %q", name)
+		}
+		tables = append(tables, name)
+	}
+	return tables, rows.Err()
+}
+
+const partitionSelect = `SELECT name, base, month, row_count, created_at, archive_path, archived_at FROM table_partitions`
+
+func scanTablePartition(row rowScanner, p *TablePartition) error {
+	return row.Scan(&p.Name, &p.Base, &p.Month, &p.RowCount, &p.CreatedAt, &p.ArchivePath, &p.ArchivedAt)
+}
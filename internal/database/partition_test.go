@@ -0,0 +1,158 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPartitions(t *testing.T) {
+	ctx := context.Background()
+	db, err := Connect(filepath.Join(t.TempDir(), "mcloud.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(db)
+
+	now := time.Now().UTC()
+	exec := func(query string, args ...any) {
+		t.Helper()
+		if _, err := db.ExecContext(ctx, query, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	exec(`INSERT INTO clusters (id, name, state) VALUES ('c1', 'prod', 'active')`)
+	for _, e := range []struct{ typ, msg, at string }{
+		{"node.drained", "node2 drained for maintenance", "2025-08-03 10:00:00"},
+		{"ceph.health", "Ceph health changed to HEALTH_WARN", "2025-09-14 08:30:00"},
+		{"ceph.health", "Ceph health changed to HEALTH_OK", now.Format(time.DateTime)},
+	} {
+		exec(`INSERT INTO events (cluster_id, type, message, created_at) VALUES ('c1', ?, ?, ?)`, e.typ, e.msg, e.at)
+	}
+	exec(`INSERT INTO audit_log (actor, action, target, status_code, result, created_at) VALUES ('admin', 'POST', '/workloads', 201, 'success', '2025-09-14 08:31:00')`)
+	exec(`INSERT INTO audit_log (actor, action, target, status_code, result, created_at) VALUES ('admin', 'DELETE', '/workloads/w1', 204, 'success', ?)`, now)
+
+	repo := NewPartitionRepository(db)
+	thisMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for _, base := range PartitionedTables() {
+		months, err := repo.UnpartitionedMonths(ctx, base, thisMonth)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, month := range months {
+			if _, err := repo.Partition(ctx, base, month); err != nil {
+				t.Fatalf("Partition(%s, %s): %v", base, month, err)
+			}
+		}
+	}
+
+	var left int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM events`).Scan(&left); err != nil || left != 1 {
+		t.Fatalf("events left in the base table = %d, %v; want 1", left, err)
+	}
+	parts, err := repo.List(ctx, "events")
+	if err != nil || len(parts) != 2 || parts[0].Name != "events_2025_08" || parts[1].RowCount != 1 {
+		t.Fatalf("events partitions = %+v, %v", parts, err)
+	}
+
+	// Reads see the partitions as if the rows never moved
+	events := NewEventRepository(db)
+	list, err := events.ListByCluster(ctx, "c1", ListOptions{Sort: "created_at"})
+	if err != nil || len(list) != 3 || list[0].Type != "node.drained" {
+		t.Fatalf("ListByCluster = %+v, %v", list, err)
+	}
+	found, err := events.Search(ctx, EventFilter{Query: `"health"`, Limit: 10})
+	if err != nil || len(found) != 2 || found[0].Message != "Ceph health changed to HEALTH_OK" {
+		t.Fatalf("Search = %+v, %v", found, err)
+	}
+	found, err = events.Search(ctx, EventFilter{Query: `"health"`, Until: thisMonth, Limit: 10})
+	if err != nil || len(found) != 1 {
+		t.Fatalf("Search until this month = %+v, %v", found, err)
+	}
+	after, err := events.ListAfter(ctx, 1, 10)
+	if err != nil || len(after) != 2 || after[0].ID != 2 {
+		t.Fatalf("ListAfter = %+v, %v", after, err)
+	}
+	if id, err := events.LatestID(ctx); err != nil || id != 3 {
+		t.Fatalf("LatestID = %d, %v; want 3", id, err)
+	}
+	audit, err := NewAuditRepository(db).ListSince(ctx, time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC), 10)
+	if err != nil || len(audit) != 2 || audit[1].Target != "/workloads" {
+		t.Fatalf("audit ListSince = %+v, %v", audit, err)
+	}
+
+	// Archiving copies the partition to its own file and drops it
+	path := filepath.Join(t.TempDir(), "events-2025-08.db")
+	if err := repo.Archive(ctx, "events_2025_08", path); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Archive(ctx, "events_2025_08", path); !errors.Is(err, ErrPartitionArchived) {
+		t.Errorf("archiving twice: %v, want ErrPartitionArchived", err)
+	}
+	if list, err := events.ListByCluster(ctx, "c1", ListOptions{}); err != nil || len(list) != 2 {
+		t.Errorf("ListByCluster after archive = %+v, %v", list, err)
+	}
+
+	archive, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+	var e Event
+	if err := scanEvent(archive.QueryRowContext(ctx, `SELECT `+eventColumns+` FROM events`), &e); err != nil || e.Message != "node2 drained for maintenance" {
+		t.Errorf("archived event = %+v, %v", e, err)
+	}
+}
+
+// A partition created before events gained severity and payload is read (and
+// filled) like one created after.
+func TestAlignPartition(t *testing.T) {
+	ctx := context.Background()
+	db, err := Connect(filepath.Join(t.TempDir(), "mcloud.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(db)
+
+	for _, stmt := range []string{
+		`CREATE TABLE events_2024_01 (id INTEGER PRIMARY KEY AUTOINCREMENT, cluster_id TEXT, node_id TEXT, type TEXT NOT NULL, message TEXT NOT NULL, created_at DATETIME DEFAULT CURRENT_TIMESTAMP)`,
+		`INSERT INTO events_2024_01 (id, type, message, created_at) VALUES (1, 'node.drain_failed', 'node2 did not drain', '2024-01-05 10:00:00')`,
+		`INSERT INTO sqlite_sequence (name, seq) VALUES ('events', 1)`,
+		`INSERT INTO table_partitions (name, base, month, row_count) VALUES ('events_2024_01', 'events', '2024-01', 1)`,
+	} {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := alignPartitions(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+
+	events := NewEventRepository(db)
+	if err := events.Create(ctx, &Event{Type: "node.offline", Severity: SeverityWarning, Message: "node3 is offline", Payload: []byte(`{"node":"node3"}`)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := events.Create(ctx, &Event{Type: "workload.created", Message: "web-1 created"}); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := events.Search(ctx, EventFilter{Type: "node.*", Severity: SeverityWarning, Limit: 10})
+	if err != nil || len(found) != 1 || string(found[0].Payload) != `{"node":"node3"}` {
+		t.Fatalf("Search node.* from warning = %+v, %v", found, err)
+	}
+	found, err = events.Search(ctx, EventFilter{Type: "node.*", Limit: 10})
+	if err != nil || len(found) != 2 || found[1].ID != 1 || found[1].Severity != SeverityInfo || found[1].Payload != nil {
+		t.Fatalf("Search node.* = %+v, %v", found, err)
+	}
+
+	// The next partition of the month copies the new columns too
+	if _, err := db.ExecContext(ctx, `INSERT INTO events (type, message, created_at) VALUES ('node.removed', 'node4 removed', '2024-01-20 09:00:00')`); err != nil {
+		t.Fatal(err)
+	}
+	if moved, err := NewPartitionRepository(db).Partition(ctx, "events", "2024-01"); err != nil || moved != 1 {
+		t.Fatalf("Partition = %d, %v", moved, err)
+	}
+}
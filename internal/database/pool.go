@@ -0,0 +1,208 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// SQLite allows one writer at a time. A database opened by Connect therefore
+// has a single write connection, which writes and transactions queue for in
+// this process instead of contending for the file lock (and sleeping in the
+// busy handler), and a pool of read-only connections, which repositories send
+// their queries to: WAL lets them read while a write is in progress.
+const (
+	// stmtCacheSize bounds the prepared statements kept per pool; list queries
+	// are built from filters and sort keys, so their variety is unbounded.
+	stmtCacheSize = 256
+)
+
+// memoryPath opens a database in memory rather than in a file.
+const memoryPath = ":memory:"
+
+// readPoolSize is the number of read connections of a database opened by Connect.
+var readPoolSize = max(4, runtime.NumCPU())
+
+// pool is the read pool and the statement caches of a database opened by Connect.
+type pool struct {
+	write stmtCache
+	read  stmtCache
+}
+
+// pools maps the write pool returned by Connect to the rest of its pool.
+var pools sync.Map // *sql.DB -> *pool
+
+// openPools limits db to the single write connection and opens its read pool.
+// Databases in memory exist once per connection, so they are read through the
+// write connection.
+func openPools(db *sql.DB, dbPath string) error {
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+	db.SetConnMaxIdleTime(0)
+
+	read := db
+	if dbPath != memoryPath {
+		var err error
+		if read, err = open(dsn(dbPath) + "&_pragma=query_only=1"); err != nil {
+			return err
+		}
+		read.SetMaxOpenConns(readPoolSize)
+		read.SetMaxIdleConns(readPoolSize)
+		read.SetConnMaxIdleTime(5 * time.Minute)
+	}
+	pools.Store(db, &pool{write: stmtCache{db: db}, read: stmtCache{db: read}})
+	return nil
+}
+
+// Close closes a database opened by Connect with its read pool and cached
+// statements.
+func Close(db *sql.DB) error {
+	if v, ok := pools.LoadAndDelete(db); ok {
+		p := v.(*pool)
+		p.write.close()
+		p.read.close()
+		if p.read.db != db {
+			p.read.db.Close()
+		}
+	}
+	return db.Close()
+}
+
+// executor is what repositories over db run their statements on: for a
+// database opened by Connect, writes go to the write connection and queries to
+// the read pool, both through cached prepared statements. Other databases
+// (e.g. opened by Open) are used as they are.
+func executor(db *sql.DB) sqlExecutor {
+	if v, ok := pools.Load(db); ok {
+		return v.(*pool)
+	}
+	return db
+}
+
+func (p *pool) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	if stmt := p.write.get(ctx, query); stmt != nil {
+		return stmt.ExecContext(ctx, args...)
+	}
+	return p.write.db.ExecContext(ctx, query, args...)
+}
+
+func (p *pool) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	if stmt := p.read.get(ctx, query); stmt != nil {
+		return stmt.QueryContext(ctx, args...)
+	}
+	return p.read.db.QueryContext(ctx, query, args...)
+}
+
+func (p *pool) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	if stmt := p.read.get(ctx, query); stmt != nil {
+		return stmt.QueryRowContext(ctx, args...)
+	}
+	return p.read.db.QueryRowContext(ctx, query, args...)
+}
+
+// stmtCache keeps the prepared statements of one pool by query. database/sql
+// prepares a cached statement again on each connection it runs on.
+type stmtCache struct {
+	db *sql.DB
+
+	mu    sync.Mutex
+	stmts map[string]*sql.Stmt
+}
+
+// get returns the prepared statement of query, or nil when it cannot be
+// prepared or the cache is full; the caller then runs query unprepared, which
+// reports any error in it.
+func (c *stmtCache) get(ctx context.Context, query string) *sql.Stmt {
+	c.mu.Lock()
+	stmt, ok := c.stmts[query]
+	full := len(c.stmts) >= stmtCacheSize
+	c.mu.Unlock()
+	if ok {
+		return stmt
+	}
+	if full {
+		return nil
+	}
+
+	// Prepare outside the lock: it waits for a connection
+	stmt, err := c.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cached, ok := c.stmts[query]; ok {
+		stmt.Close()
+		return cached
+	}
+	if c.stmts == nil {
+		c.stmts = map[string]*sql.Stmt{}
+	}
+	c.stmts[query] = stmt
+	return stmt
+}
+
+func (c *stmtCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.stmts)
+}
+
+func (c *stmtCache) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, stmt := range c.stmts {
+		stmt.Close()
+	}
+	c.stmts = nil
+}
+
+// PoolStats describes the connection pools of a database opened by Connect.
+//
+// Example JSON:
+//   {"write": {"max_open": 1, "open": 1, "in_use": 0, "idle": 1, "wait_count": 12, "wait_duration_ms": 48, "statements": 31},
+//    "read": {"max_open": 8, "open": 3, "in_use": 1, "idle": 2, "wait_count": 0, "wait_duration_ms": 0, "statements": 57}}
+type PoolStats struct {
+	Write PoolStat `json:"write"`
+	Read  PoolStat `json:"read"`
+}
+
+// PoolStat describes one connection pool.
+type PoolStat struct {
+	MaxOpen        int   `json:"max_open"`
+	Open           int   `json:"open"`
+	InUse          int   `json:"in_use"`
+	Idle           int   `json:"idle"`
+	WaitCount      int64 `json:"wait_count"`       // requests that waited for a connection
+	WaitDurationMS int64 `json:"wait_duration_ms"` // total time spent waiting
+	Statements     int   `json:"statements"`       // cached prepared statements
+}
+
+// Stats returns the pool statistics of db. A database not opened by Connect
+// reports its single pool as both.
+func Stats(db *sql.DB) PoolStats {
+	v, ok := pools.Load(db)
+	if !ok {
+		s := poolStat(db.Stats(), 0)
+		return PoolStats{Write: s, Read: s}
+	}
+	p := v.(*pool)
+	return PoolStats{
+		Write: poolStat(p.write.db.Stats(), p.write.len()),
+		Read:  poolStat(p.read.db.Stats(), p.read.len()),
+	}
+}
+
+func poolStat(s sql.DBStats, statements int) PoolStat {
+	return PoolStat{
+		MaxOpen:        s.MaxOpenConnections,
+		Open:           s.OpenConnections,
+		InUse:          s.InUse,
+		Idle:           s.Idle,
+		WaitCount:      s.WaitCount,
+		WaitDurationMS: s.WaitDuration.Milliseconds(),
+		Statements:     statements,
+	}
+}
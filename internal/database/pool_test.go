@@ -0,0 +1,88 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestPools(t *testing.T) {
+	ctx := context.Background()
+	db, err := Connect(filepath.Join(t.TempDir(), "mcloud.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(db)
+
+	// Concurrent writers queue for the write connection instead of failing busy,
+	// while readers run alongside them
+	var wg sync.WaitGroup
+	errs := make(chan error, 40)
+	for i := range 20 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			errs <- NewEventRepository(db).Create(ctx, &Event{Type: "test", Message: fmt.Sprintf("event %d", i)})
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := NewEventRepository(db).ListRecent(ctx, 10)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	events, err := NewEventRepository(db).ListRecent(ctx, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 20 {
+		t.Errorf("got %d events, want 20", len(events))
+	}
+
+	// The read pool refuses writes
+	if _, err := executor(db).(*pool).read.db.ExecContext(ctx, `DELETE FROM events`); err == nil {
+		t.Error("read pool accepted a write")
+	}
+
+	stats := Stats(db)
+	if stats.Write.MaxOpen != 1 || stats.Read.MaxOpen != readPoolSize {
+		t.Errorf("max open = %d write, %d read; want 1, %d", stats.Write.MaxOpen, stats.Read.MaxOpen, readPoolSize)
+	}
+	if stats.Write.Statements != 1 || stats.Read.Statements == 0 {
+		t.Errorf("cached statements = %d write, %d read; want the insert and the queries", stats.Write.Statements, stats.Read.Statements)
+	}
+}
+
+func TestStmtCacheIsBounded(t *testing.T) {
+	ctx := context.Background()
+	db, err := Connect(filepath.Join(t.TempDir(), "mcloud.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(db)
+
+	exec := executor(db)
+	for i := range stmtCacheSize + 10 {
+		var n int
+		if err := exec.QueryRowContext(ctx, fmt.Sprintf(`SELECT %d`, i)).Scan(&n); err != nil || n != i {
+			t.Fatalf("SELECT %d = %d, %v", i, n, err)
+		}
+	}
+	if got := Stats(db).Read.Statements; got != stmtCacheSize {
+		t.Errorf("cached statements = %d, want %d", got, stmtCacheSize)
+	}
+
+	// A statement that does not prepare reports its error when run
+	var n int
+	if err := exec.QueryRowContext(ctx, `SELECT FROM`).Scan(&n); err == nil {
+		t.Error("invalid query succeeded")
+	}
+}
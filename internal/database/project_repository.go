@@ -0,0 +1,165 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Project is a tenant owning workloads, with optional quotas, see migration 031.
+// A nil limit is unlimited.
+type Project struct {
+	Name         string    `json:"name"`
+	Description  string    `json:"description"`
+	MaxCPUs      *int      `json:"max_cpus"`
+	MaxMemoryMB  *int      `json:"max_memory_mb"`
+	MaxInstances *int      `json:"max_instances"`
+	MaxDiskGB    *int      `json:"max_disk_gb"`
+	CreatedAt    time.Time `json:"created_at"`
+	CreateUserID *string   `json:"create_user_id"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	UpdateUserID *string   `json:"update_user_id"`
+}
+
+// ProjectUsage is what a project's workloads take up. Resources come from
+// the workloads' flavors; workloads without a flavor only count as instances.
+type ProjectUsage struct {
+	CPUs      int `json:"cpus"`
+	MemoryMB  int `json:"memory_mb"`
+	Instances int `json:"instances"`
+	DiskGB    int `json:"disk_gb"`
+}
+
+// DefaultProject is the project of workloads launched without one.
+const DefaultProject = "default"
+
+type ProjectRepository struct {
+	exec sqlExecutor
+}
+
+func NewProjectRepository(db *sql.DB) *ProjectRepository {
+	return &ProjectRepository{exec: executor(db)}
+}
+
+func NewProjectRepositoryTx(tx *sql.Tx) *ProjectRepository {
+	return &ProjectRepository{exec: tx}
+}
+
+func (r *ProjectRepository) Create(ctx context.Context, p *Project) error {
+	_, err := r.exec.ExecContext(ctx, `
+INSERT INTO projects (name, description, max_cpus, max_memory_mb, max_instances, max_disk_gb, create_user_id)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+`, p.Name, p.Description, p.MaxCPUs, p.MaxMemoryMB, p.MaxInstances, p.MaxDiskGB, p.CreateUserID)
+	return err
+}
+
+// UpdateQuota replaces all four limits of a project.
+func (r *ProjectRepository) UpdateQuota(ctx context.Context, p *Project) error {
+	_, err := r.exec.ExecContext(ctx, `
+UPDATE projects
+SET max_cpus = ?, max_memory_mb = ?, max_instances = ?, max_disk_gb = ?, updated_at = CURRENT_TIMESTAMP, update_user_id = ?
+WHERE name = ?
+`, p.MaxCPUs, p.MaxMemoryMB, p.MaxInstances, p.MaxDiskGB, p.UpdateUserID, p.Name)
+	return err
+}
+
+func (r *ProjectRepository) Get(ctx context.Context, name string) (*Project, error) {
+	var p Project
+	if err := scanProject(r.exec.QueryRowContext(ctx, projectSelect+` WHERE name = ?`, name), &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// List returns all projects by name.
+func (r *ProjectRepository) List(ctx context.Context) ([]Project, error) {
+	return collect(ctx, r.exec, projectSelect+` ORDER BY name`, nil, scanProject)
+}
+
+func (r *ProjectRepository) Delete(ctx context.Context, name string) error {
+	_, err := r.exec.ExecContext(ctx, `DELETE FROM projects WHERE name = ?`, name)
+	return err
+}
+
+// Usage sums the flavors of every workload in a project, whatever its status:
+// a stopped instance still holds its disk and can be started again.
+func (r *ProjectRepository) Usage(ctx context.Context, name string) (*ProjectUsage, error) {
+	var u ProjectUsage
+	row := r.exec.QueryRowContext(ctx, `
+SELECT COUNT(*), COALESCE(SUM(f.cpus), 0), COALESCE(SUM(f.memory_mb), 0), COALESCE(SUM(f.disk_gb), 0)
+FROM workloads w
+LEFT JOIN flavors f ON f.name = w.flavor
+WHERE w.project = ?
+`, name)
+	if err := row.Scan(&u.Instances, &u.CPUs, &u.MemoryMB, &u.DiskGB); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+const projectSelect = `SELECT name, description, max_cpus, max_memory_mb, max_instances, max_disk_gb,
+created_at, create_user_id, updated_at, update_user_id
+FROM projects`
+
+func scanProject(row rowScanner, p *Project) error {
+	return row.Scan(
+		&p.Name, &p.Description, &p.MaxCPUs, &p.MaxMemoryMB, &p.MaxInstances, &p.MaxDiskGB,
+		&p.CreatedAt, &p.CreateUserID, &p.UpdatedAt, &p.UpdateUserID,
+	)
+}
+
+// ProjectBinding gives a client identity a role in a project, see migration 032.
+type ProjectBinding struct {
+	Project      string    `json:"project"`
+	Identity     string    `json:"identity"` // client certificate common name
+	Role         string    `json:"role"`
+	CreatedAt    time.Time `json:"created_at"`
+	CreateUserID *string   `json:"create_user_id"`
+}
+
+// Project roles, see migration 032.
+const (
+	ProjectRoleAdmin  = "admin"
+	ProjectRoleMember = "member"
+)
+
+// PutBinding creates or replaces the binding of an identity in a project.
+func (r *ProjectRepository) PutBinding(ctx context.Context, b *ProjectBinding) error {
+	_, err := r.exec.ExecContext(ctx, `
+INSERT INTO project_bindings (project, identity, role, create_user_id)
+VALUES (?, ?, ?, ?)
+ON CONFLICT (project, identity) DO UPDATE SET role = excluded.role
+`, b.Project, b.Identity, b.Role, b.CreateUserID)
+	return err
+}
+
+func (r *ProjectRepository) GetBinding(ctx context.Context, project string, identity string) (*ProjectBinding, error) {
+	var b ProjectBinding
+	row := r.exec.QueryRowContext(ctx, projectBindingSelect+` WHERE project = ? AND identity = ?`, project, identity)
+	if err := scanProjectBinding(row, &b); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// ListBindings returns the bindings of a project by identity.
+func (r *ProjectRepository) ListBindings(ctx context.Context, project string) ([]ProjectBinding, error) {
+	return collect(ctx, r.exec, projectBindingSelect+` WHERE project = ? ORDER BY identity`, []any{project}, scanProjectBinding)
+}
+
+func (r *ProjectRepository) DeleteBinding(ctx context.Context, project string, identity string) error {
+	_, err := r.exec.ExecContext(ctx, `DELETE FROM project_bindings WHERE project = ? AND identity = ?`, project, identity)
+	return err
+}
+
+// DeleteBindings removes every binding of a project.
+func (r *ProjectRepository) DeleteBindings(ctx context.Context, project string) error {
+	_, err := r.exec.ExecContext(ctx, `DELETE FROM project_bindings WHERE project = ?`, project)
+	return err
+}
+
+const projectBindingSelect = `SELECT project, identity, role, created_at, create_user_id FROM project_bindings`
+
+func scanProjectBinding(row rowScanner, b *ProjectBinding) error {
+	return row.Scan(&b.Project, &b.Identity, &b.Role, &b.CreatedAt, &b.CreateUserID)
+}
@@ -0,0 +1,91 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// RevokedCertificate revokes the client certificates of a common name issued
+// before RevokedAt (see migration 023).
+type RevokedCertificate struct {
+	CommonName string    `json:"common_name"`
+	Reason     string    `json:"reason"`
+	RevokedAt  time.Time `json:"revoked_at"`
+}
+
+type RevokedCertificateRepository struct {
+	exec sqlExecutor
+}
+
+func NewRevokedCertificateRepository(db *sql.DB) *RevokedCertificateRepository {
+	return &RevokedCertificateRepository{exec: executor(db)}
+}
+
+func NewRevokedCertificateRepositoryTx(tx *sql.Tx) *RevokedCertificateRepository {
+	return &RevokedCertificateRepository{exec: tx}
+}
+
+// Revoke revokes the certificates of commonName issued until now; revoking a
+// name again moves the cutoff forward.
+func (r *RevokedCertificateRepository) Revoke(ctx context.Context, commonName string, reason string) error {
+	_, err := r.exec.ExecContext(ctx, `
+INSERT INTO revoked_certificates (common_name, reason, revoked_at) VALUES (?, ?, ?)
+ON CONFLICT (common_name) DO UPDATE SET reason = excluded.reason, revoked_at = excluded.revoked_at
+`, commonName, reason, time.Now().UTC())
+	return err
+}
+
+func (r *RevokedCertificateRepository) Get(ctx context.Context, commonName string) (*RevokedCertificate, error) {
+	var c RevokedCertificate
+	err := r.exec.QueryRowContext(ctx, `
+SELECT common_name, reason, revoked_at FROM revoked_certificates WHERE common_name = ?
+`, commonName).Scan(&c.CommonName, &c.Reason, &c.RevokedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// RevokedSerial revokes one certificate by its serial number (see migration
+// 030).
+type RevokedSerial struct {
+	Serial     string    `json:"serial"` // lower-case hex
+	CommonName string    `json:"common_name"`
+	Reason     string    `json:"reason"`
+	RevokedBy  *string   `json:"revoked_by"`
+	RevokedAt  time.Time `json:"revoked_at"`
+}
+
+func scanRevokedSerial(row rowScanner, s *RevokedSerial) error {
+	return row.Scan(&s.Serial, &s.CommonName, &s.Reason, &s.RevokedBy, &s.RevokedAt)
+}
+
+// RevokeSerial revokes one certificate. Revoking it again keeps the first
+// revocation.
+func (r *RevokedCertificateRepository) RevokeSerial(ctx context.Context, s *RevokedSerial) error {
+	s.RevokedAt = time.Now().UTC()
+	_, err := r.exec.ExecContext(ctx, `
+INSERT INTO revoked_certificate_serials (serial, common_name, reason, revoked_by, revoked_at) VALUES (?, ?, ?, ?, ?)
+ON CONFLICT (serial) DO NOTHING
+`, s.Serial, s.CommonName, s.Reason, s.RevokedBy, s.RevokedAt)
+	return err
+}
+
+func (r *RevokedCertificateRepository) GetSerial(ctx context.Context, serial string) (*RevokedSerial, error) {
+	var s RevokedSerial
+	err := scanRevokedSerial(r.exec.QueryRowContext(ctx, `
+SELECT serial, common_name, reason, revoked_by, revoked_at FROM revoked_certificate_serials WHERE serial = ?
+`, serial), &s)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// ListSerials returns the certificates revoked by serial, oldest first.
+func (r *RevokedCertificateRepository) ListSerials(ctx context.Context) ([]RevokedSerial, error) {
+	return collect(ctx, r.exec, `
+SELECT serial, common_name, reason, revoked_by, revoked_at FROM revoked_certificate_serials ORDER BY revoked_at, serial
+`, nil, scanRevokedSerial)
+}
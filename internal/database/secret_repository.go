@@ -0,0 +1,85 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type Secret struct {
+	Name           string
+	ValueEncrypted string
+	CreatedAt      time.Time
+	CreateUserID   *string
+	UpdatedAt      time.Time
+	UpdateUserID   *string
+}
+
+type SecretRepository struct {
+	exec sqlExecutor
+}
+
+func NewSecretRepository(db *sql.DB) *SecretRepository {
+	return &SecretRepository{exec: executor(db)}
+}
+
+func NewSecretRepositoryTx(tx *sql.Tx) *SecretRepository {
+	return &SecretRepository{exec: tx}
+}
+
+// Upsert creates the secret or replaces its value.
+func (r *SecretRepository) Upsert(ctx context.Context, s *Secret) error {
+	_, err := r.exec.ExecContext(ctx, `
+INSERT INTO secrets (name, value_encrypted, create_user_id)
+VALUES (?, ?, ?)
+ON CONFLICT(name) DO UPDATE SET
+value_encrypted = excluded.value_encrypted, updated_at = CURRENT_TIMESTAMP, update_user_id = excluded.create_user_id
+`, s.Name, s.ValueEncrypted, s.CreateUserID)
+	return err
+}
+
+func (r *SecretRepository) Get(ctx context.Context, name string) (*Secret, error) {
+	row := r.exec.QueryRowContext(ctx, `
+SELECT name, value_encrypted, created_at, create_user_id, updated_at, update_user_id
+FROM secrets WHERE name = ?
+`, name)
+
+	var s Secret
+	if err := row.Scan(
+		&s.Name, &s.ValueEncrypted,
+		&s.CreatedAt, &s.CreateUserID, &s.UpdatedAt, &s.UpdateUserID,
+	); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *SecretRepository) Delete(ctx context.Context, name string) error {
+	_, err := r.exec.ExecContext(ctx, `DELETE FROM secrets WHERE name = ?`, name)
+	return err
+}
+
+// List returns all secrets. Callers must not expose ValueEncrypted.
+func (r *SecretRepository) List(ctx context.Context) ([]Secret, error) {
+	rows, err := r.exec.QueryContext(ctx, `
+SELECT name, value_encrypted, created_at, create_user_id, updated_at, update_user_id
+FROM secrets ORDER BY name
+`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []Secret
+	for rows.Next() {
+		var s Secret
+		if err := rows.Scan(
+			&s.Name, &s.ValueEncrypted,
+			&s.CreatedAt, &s.CreateUserID, &s.UpdatedAt, &s.UpdateUserID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, s)
+	}
+	return items, nil
+}
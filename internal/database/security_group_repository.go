@@ -0,0 +1,159 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// SecurityGroup is a named set of allow rules, see migration 028. Rules and
+// Workloads (the IDs of the workloads it is attached to) are filled in by Get.
+type SecurityGroup struct {
+	Name         string              `json:"name"`
+	Description  string              `json:"description"`
+	Rules        []SecurityGroupRule `json:"rules"`
+	Workloads    []string            `json:"workloads"`
+	CreatedAt    time.Time           `json:"created_at"`
+	CreateUserID *string             `json:"create_user_id"`
+	UpdatedAt    time.Time           `json:"updated_at"`
+	UpdateUserID *string             `json:"update_user_id"`
+}
+
+// SecurityGroupRule allows traffic in one direction. Empty fields match anything.
+//
+// Example JSON:
+//   {"direction": "ingress", "protocol": "tcp", "port": "443", "cidr": "0.0.0.0/0"}
+type SecurityGroupRule struct {
+	Direction   string `json:"direction"`          // ingress or egress
+	Protocol    string `json:"protocol,omitempty"` // tcp, udp, icmp4 or icmp6
+	Port        string `json:"port,omitempty"`     // destination port or range, e.g. 8000-8100 (tcp and udp only)
+	CIDR        string `json:"cidr,omitempty"`     // source of ingress, destination of egress
+	Description string `json:"description,omitempty"`
+}
+
+type SecurityGroupRepository struct {
+	exec sqlExecutor
+}
+
+func NewSecurityGroupRepository(db *sql.DB) *SecurityGroupRepository {
+	return &SecurityGroupRepository{exec: executor(db)}
+}
+
+func NewSecurityGroupRepositoryTx(tx *sql.Tx) *SecurityGroupRepository {
+	return &SecurityGroupRepository{exec: tx}
+}
+
+func (r *SecurityGroupRepository) Create(ctx context.Context, g *SecurityGroup) error {
+	_, err := r.exec.ExecContext(ctx, `
+INSERT INTO security_groups (name, description, create_user_id)
+VALUES (?, ?, ?)
+`, g.Name, g.Description, g.CreateUserID)
+	return err
+}
+
+// Get returns a group with its rules and attached workloads.
+func (r *SecurityGroupRepository) Get(ctx context.Context, name string) (*SecurityGroup, error) {
+	var g SecurityGroup
+	if err := scanSecurityGroup(r.exec.QueryRowContext(ctx, securityGroupSelect+` WHERE name = ?`, name), &g); err != nil {
+		return nil, err
+	}
+	var err error
+	if g.Rules, err = r.Rules(ctx, name); err != nil {
+		return nil, err
+	}
+	if g.Workloads, err = r.Workloads(ctx, name); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// List returns all groups by name, without rules and workloads.
+func (r *SecurityGroupRepository) List(ctx context.Context) ([]SecurityGroup, error) {
+	return collect(ctx, r.exec, securityGroupSelect+` ORDER BY name`, nil, scanSecurityGroup)
+}
+
+// Rules returns a group's rules in order.
+func (r *SecurityGroupRepository) Rules(ctx context.Context, name string) ([]SecurityGroupRule, error) {
+	return collect(ctx, r.exec, `
+SELECT direction, protocol, port, cidr, description FROM security_group_rules
+WHERE group_name = ? ORDER BY position
+`, []any{name}, scanSecurityGroupRule)
+}
+
+// ReplaceRules replaces a group's rules; use it in a transaction.
+func (r *SecurityGroupRepository) ReplaceRules(ctx context.Context, name string, rules []SecurityGroupRule, actor *string) error {
+	if _, err := r.exec.ExecContext(ctx, `DELETE FROM security_group_rules WHERE group_name = ?`, name); err != nil {
+		return err
+	}
+	for i, rule := range rules {
+		_, err := r.exec.ExecContext(ctx, `
+INSERT INTO security_group_rules (group_name, position, direction, protocol, port, cidr, description)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+`, name, i, rule.Direction, rule.Protocol, rule.Port, rule.CIDR, rule.Description)
+		if err != nil {
+			return err
+		}
+	}
+	_, err := r.exec.ExecContext(ctx, `
+UPDATE security_groups
+SET updated_at = CURRENT_TIMESTAMP, update_user_id = ?
+WHERE name = ?
+`, actor, name)
+	return err
+}
+
+func (r *SecurityGroupRepository) Delete(ctx context.Context, name string) error {
+	_, err := r.exec.ExecContext(ctx, `DELETE FROM security_groups WHERE name = ?`, name)
+	return err
+}
+
+// Attach attaches a group to a workload; attaching it again changes nothing.
+func (r *SecurityGroupRepository) Attach(ctx context.Context, workloadID string, name string) error {
+	_, err := r.exec.ExecContext(ctx, `
+INSERT OR IGNORE INTO workload_security_groups (workload_id, group_name)
+VALUES (?, ?)
+`, workloadID, name)
+	return err
+}
+
+func (r *SecurityGroupRepository) Detach(ctx context.Context, workloadID string, name string) error {
+	_, err := r.exec.ExecContext(ctx, `DELETE FROM workload_security_groups WHERE workload_id = ? AND group_name = ?`, workloadID, name)
+	return err
+}
+
+// Workloads returns the IDs of the workloads a group is attached to.
+func (r *SecurityGroupRepository) Workloads(ctx context.Context, name string) ([]string, error) {
+	ids, err := collect(ctx, r.exec, `SELECT workload_id FROM workload_security_groups WHERE group_name = ? ORDER BY workload_id`, []any{name}, scanText)
+	if ids == nil {
+		ids = []string{}
+	}
+	return ids, err
+}
+
+// GroupsOf returns the names of the groups attached to a workload.
+func (r *SecurityGroupRepository) GroupsOf(ctx context.Context, workloadID string) ([]string, error) {
+	names, err := collect(ctx, r.exec, `SELECT group_name FROM workload_security_groups WHERE workload_id = ? ORDER BY group_name`, []any{workloadID}, scanText)
+	if names == nil {
+		names = []string{}
+	}
+	return names, err
+}
+
+// AttachedWorkloads returns the IDs of all workloads with a group attached.
+func (r *SecurityGroupRepository) AttachedWorkloads(ctx context.Context) ([]string, error) {
+	return collect(ctx, r.exec, `SELECT DISTINCT workload_id FROM workload_security_groups ORDER BY workload_id`, nil, scanText)
+}
+
+const securityGroupSelect = `SELECT name, description, created_at, create_user_id, updated_at, update_user_id FROM security_groups`
+
+func scanSecurityGroup(row rowScanner, g *SecurityGroup) error {
+	return row.Scan(&g.Name, &g.Description, &g.CreatedAt, &g.CreateUserID, &g.UpdatedAt, &g.UpdateUserID)
+}
+
+func scanSecurityGroupRule(row rowScanner, rule *SecurityGroupRule) error {
+	return row.Scan(&rule.Direction, &rule.Protocol, &rule.Port, &rule.CIDR, &rule.Description)
+}
+
+func scanText(row rowScanner, s *string) error {
+	return row.Scan(s)
+}
@@ -0,0 +1,66 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// UsageDayFormat is the layout of UsageRecord.Day.
+const UsageDayFormat = "2006-01-02"
+
+type UsageRecord struct {
+	Day         string    `json:"day"`
+	SubjectType string    `json:"subject_type"`
+	Subject     string    `json:"subject"`
+	Metric      string    `json:"metric"`
+	Value       float64   `json:"value"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type UsageRepository struct {
+	exec sqlExecutor
+}
+
+func NewUsageRepository(db *sql.DB) *UsageRepository {
+	return &UsageRepository{exec: executor(db)}
+}
+
+func NewUsageRepositoryTx(tx *sql.Tx) *UsageRepository {
+	return &UsageRepository{exec: tx}
+}
+
+// Add increments the day's rollup for a subject and metric, creating it if needed.
+func (r *UsageRepository) Add(ctx context.Context, day string, subjectType string, subject string, metric string, delta float64) error {
+	_, err := r.exec.ExecContext(ctx, `
+INSERT INTO usage_daily (day, subject_type, subject, metric, value)
+VALUES (?, ?, ?, ?, ?)
+ON CONFLICT(day, subject_type, subject, metric) DO UPDATE SET
+value = value + excluded.value, updated_at = CURRENT_TIMESTAMP
+`, day, subjectType, subject, metric, delta)
+	return err
+}
+
+// List returns the rollups between from and to (inclusive days), optionally for one subject type.
+func (r *UsageRepository) List(ctx context.Context, from string, to string, subjectType string) ([]UsageRecord, error) {
+	rows, err := r.exec.QueryContext(ctx, `
+SELECT day, subject_type, subject, metric, value, updated_at
+FROM usage_daily
+WHERE day >= ? AND day <= ? AND (? = '' OR subject_type = ?)
+ORDER BY day, subject_type, subject, metric
+`, from, to, subjectType, subjectType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []UsageRecord
+	for rows.Next() {
+		var u UsageRecord
+		if err := rows.Scan(&u.Day, &u.SubjectType, &u.Subject, &u.Metric, &u.Value, &u.UpdatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, u)
+	}
+	return items, rows.Err()
+}
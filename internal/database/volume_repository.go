@@ -0,0 +1,93 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Volume is a Ceph RBD-backed LXD custom volume, see migration 026.
+// WorkloadID and MountPath are set while it is attached.
+type Volume struct {
+	ID           string     `json:"id"`
+	Name         string     `json:"name"`
+	Pool         string     `json:"pool"`
+	SizeGB       int        `json:"size_gb"`
+	WorkloadID   *string    `json:"workload_id"`
+	MountPath    *string    `json:"mount_path,omitempty"`
+	AttachedAt   *time.Time `json:"attached_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	CreateUserID *string    `json:"create_user_id"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	UpdateUserID *string    `json:"update_user_id"`
+}
+
+type VolumeRepository struct {
+	exec sqlExecutor
+}
+
+func NewVolumeRepository(db *sql.DB) *VolumeRepository {
+	return &VolumeRepository{exec: executor(db)}
+}
+
+func NewVolumeRepositoryTx(tx *sql.Tx) *VolumeRepository {
+	return &VolumeRepository{exec: tx}
+}
+
+func (r *VolumeRepository) Create(ctx context.Context, v *Volume) error {
+	_, err := r.exec.ExecContext(ctx, `
+INSERT INTO volumes (id, name, pool, size_gb, create_user_id)
+VALUES (?, ?, ?, ?, ?)
+`, v.ID, v.Name, v.Pool, v.SizeGB, v.CreateUserID)
+	return err
+}
+
+func (r *VolumeRepository) GetByID(ctx context.Context, id string) (*Volume, error) {
+	var v Volume
+	if err := scanVolume(r.exec.QueryRowContext(ctx, volumeSelect+` WHERE id = ?`, id), &v); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (r *VolumeRepository) GetByName(ctx context.Context, pool string, name string) (*Volume, error) {
+	var v Volume
+	if err := scanVolume(r.exec.QueryRowContext(ctx, volumeSelect+` WHERE pool = ? AND name = ?`, pool, name), &v); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// List returns all volumes by pool and name.
+func (r *VolumeRepository) List(ctx context.Context) ([]Volume, error) {
+	return collect(ctx, r.exec, volumeSelect+` ORDER BY pool, name`, nil, scanVolume)
+}
+
+// ListByWorkload returns the volumes attached to a workload.
+func (r *VolumeRepository) ListByWorkload(ctx context.Context, workloadID string) ([]Volume, error) {
+	return collect(ctx, r.exec, volumeSelect+` WHERE workload_id = ? ORDER BY pool, name`, []any{workloadID}, scanVolume)
+}
+
+// SetAttachment records that a volume is attached to a workload at mountPath,
+// or detached (workloadID nil).
+func (r *VolumeRepository) SetAttachment(ctx context.Context, id string, workloadID *string, mountPath *string, actor *string) error {
+	_, err := r.exec.ExecContext(ctx, `
+UPDATE volumes
+SET workload_id = ?, mount_path = ?, attached_at = CASE WHEN ? IS NULL THEN NULL ELSE CURRENT_TIMESTAMP END,
+    updated_at = CURRENT_TIMESTAMP, update_user_id = ?
+WHERE id = ?
+`, workloadID, mountPath, workloadID, actor, id)
+	return err
+}
+
+func (r *VolumeRepository) DeleteByID(ctx context.Context, id string) error {
+	_, err := r.exec.ExecContext(ctx, `DELETE FROM volumes WHERE id = ?`, id)
+	return err
+}
+
+const volumeSelect = `SELECT id, name, pool, size_gb, workload_id, mount_path, attached_at, created_at, create_user_id, updated_at, update_user_id FROM volumes`
+
+func scanVolume(row rowScanner, v *Volume) error {
+	return row.Scan(&v.ID, &v.Name, &v.Pool, &v.SizeGB, &v.WorkloadID, &v.MountPath, &v.AttachedAt,
+		&v.CreatedAt, &v.CreateUserID, &v.UpdatedAt, &v.UpdateUserID)
+}
@@ -0,0 +1,68 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+)
+
+// WorkloadEnv is one environment variable of a workload.
+// Exactly one of Value (plain variable) or SecretName (reference to a secret) is set.
+type WorkloadEnv struct {
+	WorkloadID string
+	Key        string
+	Value      *string
+	SecretName *string
+}
+
+type WorkloadEnvRepository struct {
+	exec sqlExecutor
+}
+
+func NewWorkloadEnvRepository(db *sql.DB) *WorkloadEnvRepository {
+	return &WorkloadEnvRepository{exec: executor(db)}
+}
+
+func NewWorkloadEnvRepositoryTx(tx *sql.Tx) *WorkloadEnvRepository {
+	return &WorkloadEnvRepository{exec: tx}
+}
+
+func (r *WorkloadEnvRepository) Create(ctx context.Context, e *WorkloadEnv) error {
+	_, err := r.exec.ExecContext(ctx, `
+INSERT INTO workload_env (workload_id, key, value, secret_name)
+VALUES (?, ?, ?, ?)
+`, e.WorkloadID, e.Key, e.Value, e.SecretName)
+	return err
+}
+
+func (r *WorkloadEnvRepository) ListByWorkload(ctx context.Context, workloadID string) ([]WorkloadEnv, error) {
+	rows, err := r.exec.QueryContext(ctx, `
+SELECT workload_id, key, value, secret_name
+FROM workload_env WHERE workload_id = ? ORDER BY key
+`, workloadID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []WorkloadEnv
+	for rows.Next() {
+		var e WorkloadEnv
+		if err := rows.Scan(&e.WorkloadID, &e.Key, &e.Value, &e.SecretName); err != nil {
+			return nil, err
+		}
+		items = append(items, e)
+	}
+	return items, nil
+}
+
+func (r *WorkloadEnvRepository) DeleteByWorkload(ctx context.Context, workloadID string) error {
+	_, err := r.exec.ExecContext(ctx, `DELETE FROM workload_env WHERE workload_id = ?`, workloadID)
+	return err
+}
+
+// CountBySecret returns how many workload variables reference the secret.
+func (r *WorkloadEnvRepository) CountBySecret(ctx context.Context, secretName string) (int, error) {
+	row := r.exec.QueryRowContext(ctx, `SELECT COUNT(*) FROM workload_env WHERE secret_name = ?`, secretName)
+	var n int
+	return n, row.Scan(&n)
+}
@@ -0,0 +1,90 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// WorkloadHook is a lifecycle hook of a workload.
+// Exactly one of WebhookURL or Command (an allowlisted command name) is set.
+type WorkloadHook struct {
+	ID         string    `json:"id"`
+	WorkloadID string    `json:"workload_id"`
+	Event      string    `json:"event"`
+	WebhookURL *string   `json:"webhook_url,omitempty"`
+	Command    *string   `json:"command,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type WorkloadHookRepository struct {
+	exec sqlExecutor
+}
+
+func NewWorkloadHookRepository(db *sql.DB) *WorkloadHookRepository {
+	return &WorkloadHookRepository{exec: executor(db)}
+}
+
+func NewWorkloadHookRepositoryTx(tx *sql.Tx) *WorkloadHookRepository {
+	return &WorkloadHookRepository{exec: tx}
+}
+
+func (r *WorkloadHookRepository) Create(ctx context.Context, h *WorkloadHook) error {
+	_, err := r.exec.ExecContext(ctx, `
+INSERT INTO workload_hooks (id, workload_id, event, webhook_url, command)
+VALUES (?, ?, ?, ?, ?)
+`, h.ID, h.WorkloadID, h.Event, h.WebhookURL, h.Command)
+	return err
+}
+
+func (r *WorkloadHookRepository) GetByID(ctx context.Context, id string) (*WorkloadHook, error) {
+	row := r.exec.QueryRowContext(ctx, `
+SELECT id, workload_id, event, webhook_url, command, created_at
+FROM workload_hooks WHERE id = ?
+`, id)
+
+	var h WorkloadHook
+	if err := row.Scan(&h.ID, &h.WorkloadID, &h.Event, &h.WebhookURL, &h.Command, &h.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+func (r *WorkloadHookRepository) ListByWorkload(ctx context.Context, workloadID string) ([]WorkloadHook, error) {
+	return r.list(ctx, `
+SELECT id, workload_id, event, webhook_url, command, created_at
+FROM workload_hooks WHERE workload_id = ? ORDER BY event, created_at
+`, workloadID)
+}
+
+// ListByInstance returns the hooks for an event of the workload backed by the named LXD instance.
+func (r *WorkloadHookRepository) ListByInstance(ctx context.Context, instance string, event string) ([]WorkloadHook, error) {
+	return r.list(ctx, `
+SELECT h.id, h.workload_id, h.event, h.webhook_url, h.command, h.created_at
+FROM workload_hooks h JOIN workloads w ON w.id = h.workload_id
+WHERE w.name = ? AND h.event = ? ORDER BY h.created_at
+`, instance, event)
+}
+
+func (r *WorkloadHookRepository) DeleteByWorkload(ctx context.Context, workloadID string) error {
+	_, err := r.exec.ExecContext(ctx, `DELETE FROM workload_hooks WHERE workload_id = ?`, workloadID)
+	return err
+}
+
+func (r *WorkloadHookRepository) list(ctx context.Context, query string, args ...any) ([]WorkloadHook, error) {
+	rows, err := r.exec.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []WorkloadHook
+	for rows.Next() {
+		var h WorkloadHook
+		if err := rows.Scan(&h.ID, &h.WorkloadID, &h.Event, &h.WebhookURL, &h.Command, &h.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, h)
+	}
+	return items, rows.Err()
+}
@@ -3,40 +3,91 @@ package database
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
 	"time"
 )
 
 type Workload struct {
-	ID           string
-	ClusterID    string
-	NodeID       *string
-	Name         string
-	Kind         string
-	Status       string
-	CreatedAt    time.Time
-	CreateUserID *string
-	UpdatedAt    time.Time
-	UpdateUserID *string
+	ID            string      `json:"id"`
+	ClusterID     string      `json:"cluster_id"`
+	NodeID        *string     `json:"node_id"`
+	Name          string      `json:"name"`
+	Kind          string      `json:"kind"`
+	Status        string      `json:"status"`
+	Image         string      `json:"image"`
+	Priority      string      `json:"priority"`
+	Flavor        *string     `json:"flavor,omitempty"` // see FlavorRepository
+	Project       string      `json:"project"`          // see ProjectRepository
+	PreemptedAt   *time.Time  `json:"preempted_at,omitempty"`
+	Addresses     AddressList `json:"addresses"`
+	CloudInitHash *string     `json:"cloud_init_hash,omitempty"` // SHA-256 of the cloud-init config it was launched with
+	CreatedAt     time.Time   `json:"created_at"`
+	CreateUserID  *string     `json:"create_user_id"`
+	UpdatedAt     time.Time   `json:"updated_at"`
+	UpdateUserID  *string     `json:"update_user_id"`
 }
 
+// AddressList is a workload's IP addresses, stored comma separated.
+type AddressList []string
+
+func (a AddressList) Value() (driver.Value, error) {
+	return strings.Join(a, ","), nil
+}
+
+func (a *AddressList) Scan(src any) error {
+	var v string
+	switch src := src.(type) {
+	case string:
+		v = src
+	case []byte:
+		v = string(src)
+	case nil:
+	default:
+		return fmt.Errorf("cannot scan %T into AddressList", src)
+	}
+
+	*a = AddressList{}
+	if v != "" {
+		*a = strings.Split(v, ",")
+	}
+	return nil
+}
+
+// Workload priority classes, see migration 017.
+const (
+	PriorityLow    = "low"
+	PriorityNormal = "normal"
+	PriorityHigh   = "high"
+)
+
 type WorkloadRepository struct {
-	db *sql.DB
+	exec sqlExecutor
 }
 
 func NewWorkloadRepository(db *sql.DB) *WorkloadRepository {
-	return &WorkloadRepository{db: db}
+	return &WorkloadRepository{exec: executor(db)}
+}
+
+func NewWorkloadRepositoryTx(tx *sql.Tx) *WorkloadRepository {
+	return &WorkloadRepository{exec: tx}
 }
 
+// Create inserts a workload; one without a project goes to DefaultProject.
 func (r *WorkloadRepository) Create(ctx context.Context, w *Workload) error {
-	_, err := r.db.ExecContext(ctx, `
-INSERT INTO workloads (id, cluster_id, node_id, name, kind, status, create_user_id)
-VALUES (?, ?, ?, ?, ?, ?, ?)
-`, w.ID, w.ClusterID, w.NodeID, w.Name, w.Kind, w.Status, w.CreateUserID)
+	if w.Project == "" {
+		w.Project = DefaultProject
+	}
+	_, err := r.exec.ExecContext(ctx, `
+INSERT INTO workloads (id, cluster_id, node_id, name, kind, status, image, priority, flavor, project, cloud_init_hash, create_user_id)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+`, w.ID, w.ClusterID, w.NodeID, w.Name, w.Kind, w.Status, w.Image, w.Priority, w.Flavor, w.Project, w.CloudInitHash, w.CreateUserID)
 	return err
 }
 
 func (r *WorkloadRepository) UpdateStatus(ctx context.Context, id string, status string) error {
-	_, err := r.db.ExecContext(ctx, `
+	_, err := r.exec.ExecContext(ctx, `
 UPDATE workloads
 SET status = ?, updated_at = CURRENT_TIMESTAMP
 WHERE id = ?
@@ -44,74 +95,156 @@ WHERE id = ?
 	return err
 }
 
+func (r *WorkloadRepository) UpdatePriority(ctx context.Context, id string, priority string) error {
+	_, err := r.exec.ExecContext(ctx, `
+UPDATE workloads
+SET priority = ?, updated_at = CURRENT_TIMESTAMP
+WHERE id = ?
+`, priority, id)
+	return err
+}
+
+// SetPreempted records that a workload was stopped to make room (at), or clears it (nil).
+func (r *WorkloadRepository) SetPreempted(ctx context.Context, id string, at *time.Time) error {
+	_, err := r.exec.ExecContext(ctx, `
+UPDATE workloads
+SET preempted_at = ?
+WHERE id = ?
+`, at, id)
+	return err
+}
+
+func (r *WorkloadRepository) UpdateNode(ctx context.Context, id string, nodeID *string) error {
+	_, err := r.exec.ExecContext(ctx, `
+UPDATE workloads
+SET node_id = ?, updated_at = CURRENT_TIMESTAMP
+WHERE id = ?
+`, nodeID, id)
+	return err
+}
+
+// UpdateAddresses replaces the workload's IP addresses. updated_at is left alone,
+// since addresses are refreshed periodically rather than changed by a user.
+func (r *WorkloadRepository) UpdateAddresses(ctx context.Context, id string, addresses AddressList) error {
+	_, err := r.exec.ExecContext(ctx, `
+UPDATE workloads
+SET addresses = ?
+WHERE id = ?
+`, addresses, id)
+	return err
+}
+
 func (r *WorkloadRepository) DeleteByID(ctx context.Context, id string) error {
-	_, err := r.db.ExecContext(ctx, `DELETE FROM workloads WHERE id = ?`, id)
+	_, err := r.exec.ExecContext(ctx, `DELETE FROM workloads WHERE id = ?`, id)
 	return err
 }
 
 func (r *WorkloadRepository) GetByID(ctx context.Context, id string) (*Workload, error) {
-	row := r.db.QueryRowContext(ctx, `
-SELECT id, cluster_id, node_id, name, kind, status,
-created_at, create_user_id, updated_at, update_user_id
-FROM workloads WHERE id = ?
-`, id)
+	var w Workload
+	if err := scanWorkload(r.exec.QueryRowContext(ctx, workloadList.selectSQL+` WHERE id = ?`, id), &w); err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
 
+func (r *WorkloadRepository) GetByName(ctx context.Context, clusterID string, name string) (*Workload, error) {
 	var w Workload
-	if err := row.Scan(
-		&w.ID, &w.ClusterID, &w.NodeID, &w.Name, &w.Kind, &w.Status,
-		&w.CreatedAt, &w.CreateUserID, &w.UpdatedAt, &w.UpdateUserID,
-	); err != nil {
+	row := r.exec.QueryRowContext(ctx, workloadList.selectSQL+` WHERE cluster_id = ? AND name = ?`, clusterID, name)
+	if err := scanWorkload(row, &w); err != nil {
 		return nil, err
 	}
 	return &w, nil
 }
 
-func (r *WorkloadRepository) ListByCluster(ctx context.Context, clusterID string) ([]Workload, error) {
-	rows, err := r.db.QueryContext(ctx, `
-SELECT id, cluster_id, node_id, name, kind, status,
-created_at, create_user_id, updated_at, update_user_id
-FROM workloads WHERE cluster_id = ?
-`, clusterID)
+// ListByCluster returns the workloads of a cluster; ListOptions{} returns all of them.
+func (r *WorkloadRepository) ListByCluster(ctx context.Context, clusterID string, opts ListOptions) ([]Workload, error) {
+	query, args, err := workloadList.build(opts, []string{"cluster_id = ?"}, clusterID)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	return collect(ctx, r.exec, query, args, scanWorkload)
+}
 
-	var items []Workload
-	for rows.Next() {
-		var w Workload
-		if err := rows.Scan(
-			&w.ID, &w.ClusterID, &w.NodeID, &w.Name, &w.Kind, &w.Status,
-			&w.CreatedAt, &w.CreateUserID, &w.UpdatedAt, &w.UpdateUserID,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, w)
-	}
-	return items, nil
+// CountByFlavor returns how many workloads were launched with a flavor.
+func (r *WorkloadRepository) CountByFlavor(ctx context.Context, flavor string) (int, error) {
+	row := r.exec.QueryRowContext(ctx, `SELECT COUNT(*) FROM workloads WHERE flavor = ?`, flavor)
+	var n int
+	return n, row.Scan(&n)
 }
 
+// CountByProject returns how many workloads belong to a project.
+func (r *WorkloadRepository) CountByProject(ctx context.Context, project string) (int, error) {
+	row := r.exec.QueryRowContext(ctx, `SELECT COUNT(*) FROM workloads WHERE project = ?`, project)
+	var n int
+	return n, row.Scan(&n)
+}
+
+// ListByNode returns the workloads on a node in evacuation order: lowest
+// priority first, and within a priority the newest first.
 func (r *WorkloadRepository) ListByNode(ctx context.Context, nodeID string) ([]Workload, error) {
-	rows, err := r.db.QueryContext(ctx, `
-SELECT id, cluster_id, node_id, name, kind, status,
-created_at, create_user_id, updated_at, update_user_id
-FROM workloads WHERE node_id = ?
-`, nodeID)
+	return collect(ctx, r.exec, workloadList.selectSQL+`
+WHERE node_id = ?
+ORDER BY CASE priority WHEN 'low' THEN 0 WHEN 'normal' THEN 1 ELSE 2 END, created_at DESC
+`, []any{nodeID}, scanWorkload)
+}
+
+func (r *WorkloadRepository) List(ctx context.Context, opts ListOptions) ([]Workload, error) {
+	var items []Workload
+	err := r.Each(ctx, opts, func(w *Workload) error {
+		items = append(items, *w)
+		return nil
+	})
+	return items, err
+}
+
+// Each calls fn for one page of workloads as they are read from the cursor,
+// so large pages can be streamed without holding them in memory.
+//
+// Example Input:
+//   Each(ctx, ListOptions{Limit: 100, Sort: "-created_at", Filter: map[string]string{"node": "660e8400-..."}}, fn)
+func (r *WorkloadRepository) Each(ctx context.Context, opts ListOptions, fn func(*Workload) error) error {
+	query, args, err := workloadList.build(opts, nil)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer rows.Close()
+	return each(ctx, r.exec, query, args, scanWorkload, fn)
+}
 
-	var items []Workload
-	for rows.Next() {
-		var w Workload
-		if err := rows.Scan(
-			&w.ID, &w.ClusterID, &w.NodeID, &w.Name, &w.Kind, &w.Status,
-			&w.CreatedAt, &w.CreateUserID, &w.UpdatedAt, &w.UpdateUserID,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, w)
+// EachInProjects is Each restricted to the workloads of projects; no projects
+// means no workloads.
+func (r *WorkloadRepository) EachInProjects(ctx context.Context, projects []string, opts ListOptions, fn func(*Workload) error) error {
+	if len(projects) == 0 {
+		return nil
+	}
+	args := make([]any, len(projects))
+	for i, p := range projects {
+		args[i] = p
 	}
-	return items, nil
+	in := "project IN (?" + strings.Repeat(", ?", len(projects)-1) + ")"
+	query, args, err := workloadList.build(opts, []string{in}, args...)
+	if err != nil {
+		return err
+	}
+	return each(ctx, r.exec, query, args, scanWorkload, fn)
+}
+
+var workloadList = listQuery{
+	selectSQL: `SELECT id, cluster_id, node_id, name, kind, status, image, priority, flavor, project, preempted_at, addresses, cloud_init_hash,
+created_at, create_user_id, updated_at, update_user_id
+FROM workloads`,
+	defaultSort: "created_at, id",
+	sortable: map[string]string{
+		"name": "name", "status": "status", "kind": "kind", "priority": "priority", "created_at": "created_at",
+	},
+	filterable: map[string]string{
+		"cluster": "cluster_id", "node": "node_id", "status": "status", "kind": "kind", "priority": "priority",
+		"flavor": "flavor", "project": "project", "name": "name",
+	},
+}
+
+func scanWorkload(row rowScanner, w *Workload) error {
+	return row.Scan(
+		&w.ID, &w.ClusterID, &w.NodeID, &w.Name, &w.Kind, &w.Status, &w.Image, &w.Priority, &w.Flavor, &w.Project, &w.PreemptedAt, &w.Addresses, &w.CloudInitHash,
+		&w.CreatedAt, &w.CreateUserID, &w.UpdatedAt, &w.UpdateUserID,
+	)
 }
@@ -0,0 +1,144 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestWorkloadRepository(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	c := testCluster(t, db, "prod")
+	n1 := testNode(t, db, c, "node1")
+	n2 := testNode(t, db, c, "node2")
+	web := testWorkload(t, db, c, n1, "web")
+	testWorkload(t, db, c, n2, "db")
+	testWorkload(t, db, c, nil, "batch")
+	repo := NewWorkloadRepository(db)
+
+	got, err := repo.GetByName(ctx, c.ID, "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != web.ID || got.NodeID == nil || *got.NodeID != n1.ID || got.Project != DefaultProject || len(got.Addresses) != 0 {
+		t.Errorf("GetByName(web) = %+v", got)
+	}
+	if _, err := repo.GetByName(ctx, "other-cluster", "web"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetByName in another cluster error = %v, want sql.ErrNoRows", err)
+	}
+
+	if err := repo.UpdateStatus(ctx, web.ID, "stopped"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.UpdatePriority(ctx, web.ID, PriorityHigh); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.UpdateNode(ctx, web.ID, &n2.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.UpdateAddresses(ctx, web.ID, AddressList{"10.10.0.5", "fd42::5"}); err != nil {
+		t.Fatal(err)
+	}
+	preempted := time.Now().UTC().Truncate(time.Second)
+	if err := repo.SetPreempted(ctx, web.ID, &preempted); err != nil {
+		t.Fatal(err)
+	}
+	got, err = repo.GetByID(ctx, web.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != "stopped" || got.Priority != PriorityHigh || *got.NodeID != n2.ID ||
+		!slices.Equal(got.Addresses, AddressList{"10.10.0.5", "fd42::5"}) || got.PreemptedAt == nil || !got.PreemptedAt.Equal(preempted) {
+		t.Errorf("after updates web = %+v", got)
+	}
+	if err := repo.SetPreempted(ctx, web.ID, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = repo.GetByID(ctx, web.ID); err != nil || got.PreemptedAt != nil {
+		t.Errorf("preempted_at after clearing = %v, %v", got.PreemptedAt, err)
+	}
+
+	items, err := repo.List(ctx, ListOptions{Filter: map[string]string{"node": n2.ID}, Sort: "name"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := workloadNames(items); !slices.Equal(names, []string{"db", "web"}) {
+		t.Errorf("List(node=node2, name) = %v", names)
+	}
+	if n, err := repo.CountByProject(ctx, DefaultProject); err != nil || n != 3 {
+		t.Errorf("CountByProject(default) = %d, %v; want 3", n, err)
+	}
+	for _, projects := range [][]string{{"team-a"}, nil} {
+		var seen int
+		err := repo.EachInProjects(ctx, projects, ListOptions{}, func(*Workload) error {
+			seen++
+			return nil
+		})
+		if err != nil || seen != 0 {
+			t.Errorf("EachInProjects(%v) = %d workloads, %v; want none", projects, seen, err)
+		}
+	}
+	var inDefault []string
+	err = repo.EachInProjects(ctx, []string{"team-a", DefaultProject}, ListOptions{Sort: "name"}, func(w *Workload) error {
+		inDefault = append(inDefault, w.Name)
+		return nil
+	})
+	if err != nil || len(inDefault) != 3 {
+		t.Errorf("EachInProjects(team-a, default) = %v, %v", inDefault, err)
+	}
+
+	// Each stops at the first error of fn
+	stop := errors.New("stop")
+	var seen int
+	err = repo.Each(ctx, ListOptions{}, func(*Workload) error {
+		seen++
+		return stop
+	})
+	if !errors.Is(err, stop) || seen != 1 {
+		t.Errorf("Each = %v after %d workloads, want stop after 1", err, seen)
+	}
+
+	if err := repo.DeleteByID(ctx, web.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.GetByID(ctx, web.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("GetByID after delete error = %v, want sql.ErrNoRows", err)
+	}
+}
+
+func TestWorkloadRepositoryListByNode(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	c := testCluster(t, db, "prod")
+	n := testNode(t, db, c, "node1")
+	repo := NewWorkloadRepository(db)
+	for _, w := range []struct{ name, priority string }{
+		{"critical", PriorityHigh}, {"scratch", PriorityLow}, {"web", PriorityNormal},
+	} {
+		testWorkload(t, db, c, n, w.name)
+		if err := repo.UpdatePriority(ctx, "workload-"+w.name, w.priority); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Evacuation order: lowest priority first
+	items, err := repo.ListByNode(ctx, n.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := workloadNames(items); !slices.Equal(names, []string{"scratch", "web", "critical"}) {
+		t.Errorf("ListByNode = %v, want scratch, web, critical", names)
+	}
+}
+
+func workloadNames(items []Workload) []string {
+	var names []string
+	for _, w := range items {
+		names = append(names, w.Name)
+	}
+	return names
+}
@@ -0,0 +1,127 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// WorkloadSchedule stops and/or starts a workload at fixed local times.
+// StopAt and StartAt are "HH:MM"; Days lists weekdays ("mon,tue,...", empty = every day).
+type WorkloadSchedule struct {
+	WorkloadID   string     `json:"workload_id"`
+	StopAt       *string    `json:"stop_at,omitempty"`
+	StartAt      *string    `json:"start_at,omitempty"`
+	Days         string     `json:"days,omitempty"`
+	SkipUntil    *time.Time `json:"skip_until,omitempty"`
+	LastActionAt *time.Time `json:"last_action_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// ScheduledWorkload is a schedule with the workload fields the schedule job needs.
+type ScheduledWorkload struct {
+	WorkloadSchedule
+	Name      string
+	ClusterID string
+	NodeID    *string
+	Status    string
+	Project   string
+}
+
+type WorkloadScheduleRepository struct {
+	exec sqlExecutor
+}
+
+func NewWorkloadScheduleRepository(db *sql.DB) *WorkloadScheduleRepository {
+	return &WorkloadScheduleRepository{exec: executor(db)}
+}
+
+func NewWorkloadScheduleRepositoryTx(tx *sql.Tx) *WorkloadScheduleRepository {
+	return &WorkloadScheduleRepository{exec: tx}
+}
+
+// Upsert creates or replaces a workload's schedule. A replaced schedule loses
+// its skip; actions up to lastActionAt are treated as already handled.
+func (r *WorkloadScheduleRepository) Upsert(ctx context.Context, s *WorkloadSchedule, lastActionAt time.Time) error {
+	_, err := r.exec.ExecContext(ctx, `
+INSERT INTO workload_schedules (workload_id, stop_at, start_at, days, last_action_at)
+VALUES (?, ?, ?, ?, ?)
+ON CONFLICT(workload_id) DO UPDATE SET
+stop_at = excluded.stop_at, start_at = excluded.start_at, days = excluded.days,
+skip_until = NULL, last_action_at = excluded.last_action_at, updated_at = CURRENT_TIMESTAMP
+`, s.WorkloadID, s.StopAt, s.StartAt, s.Days, lastActionAt.UTC())
+	return err
+}
+
+func (r *WorkloadScheduleRepository) GetByWorkload(ctx context.Context, workloadID string) (*WorkloadSchedule, error) {
+	row := r.exec.QueryRowContext(ctx, `
+SELECT workload_id, stop_at, start_at, days, skip_until, last_action_at, created_at, updated_at
+FROM workload_schedules WHERE workload_id = ?
+`, workloadID)
+
+	var s WorkloadSchedule
+	if err := row.Scan(&s.WorkloadID, &s.StopAt, &s.StartAt, &s.Days, &s.SkipUntil, &s.LastActionAt, &s.CreatedAt, &s.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// DeleteByWorkload removes a workload's schedule. Returns sql.ErrNoRows if it had none.
+func (r *WorkloadScheduleRepository) DeleteByWorkload(ctx context.Context, workloadID string) error {
+	res, err := r.exec.ExecContext(ctx, `DELETE FROM workload_schedules WHERE workload_id = ?`, workloadID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// SetSkipUntil skips every scheduled action at or before until (nil clears the skip).
+func (r *WorkloadScheduleRepository) SetSkipUntil(ctx context.Context, workloadID string, until *time.Time) error {
+	if until != nil {
+		t := until.UTC()
+		until = &t
+	}
+	_, err := r.exec.ExecContext(ctx, `
+UPDATE workload_schedules SET skip_until = ?, updated_at = CURRENT_TIMESTAMP
+WHERE workload_id = ?
+`, until, workloadID)
+	return err
+}
+
+// SetLastActionAt records the scheduled action handled last.
+func (r *WorkloadScheduleRepository) SetLastActionAt(ctx context.Context, workloadID string, at time.Time) error {
+	_, err := r.exec.ExecContext(ctx, `
+UPDATE workload_schedules SET last_action_at = ?
+WHERE workload_id = ?
+`, at.UTC(), workloadID)
+	return err
+}
+
+// ListWithWorkloads returns every schedule with its workload's name, location, status and project.
+func (r *WorkloadScheduleRepository) ListWithWorkloads(ctx context.Context) ([]ScheduledWorkload, error) {
+	rows, err := r.exec.QueryContext(ctx, `
+SELECT s.workload_id, s.stop_at, s.start_at, s.days, s.skip_until, s.last_action_at, s.created_at, s.updated_at,
+       w.name, w.cluster_id, w.node_id, w.status, w.project
+FROM workload_schedules s JOIN workloads w ON w.id = s.workload_id
+ORDER BY w.name
+`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []ScheduledWorkload
+	for rows.Next() {
+		var s ScheduledWorkload
+		if err := rows.Scan(&s.WorkloadID, &s.StopAt, &s.StartAt, &s.Days, &s.SkipUntil, &s.LastActionAt, &s.CreatedAt, &s.UpdatedAt,
+			&s.Name, &s.ClusterID, &s.NodeID, &s.Status, &s.Project); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,145 @@
+package dns
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"mcloud/internal/config"
+)
+
+const cloudflareAPI = "https://api.cloudflare.com/client/v4"
+
+// cloudflareProvider manages records through the Cloudflare v4 API.
+type cloudflareProvider struct {
+	zoneID string
+	token  string
+	client *http.Client
+}
+
+func newCloudflareProvider(cfg config.DNSCloudflare) (*cloudflareProvider, error) {
+	if cfg.ZoneID == "" || cfg.APITokenPath == "" {
+		return nil, fmt.Errorf("dns.cloudflare.zone_id and dns.cloudflare.api_token_path are required")
+	}
+	token, err := os.ReadFile(cfg.APITokenPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cloudflare api token: %w", err)
+	}
+
+	return &cloudflareProvider{
+		zoneID: cfg.ZoneID,
+		token:  strings.TrimSpace(string(token)),
+		client: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+func (p *cloudflareProvider) Name() string {
+	return "cloudflare"
+}
+
+type cloudflareRecord struct {
+	ID      string `json:"id,omitempty"`
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Content string `json:"content"`
+	TTL     int    `json:"ttl"`
+	Proxied bool   `json:"proxied"`
+}
+
+// do calls the API and decodes the "result" field of the response into out.
+func (p *cloudflareProvider) do(ctx context.Context, method string, path string, body any, out any) error {
+	var reader *bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, cloudflareAPI+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Success bool `json:"success"`
+		Errors  []struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"errors"`
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("cloudflare: %s: invalid response: %w", resp.Status, err)
+	}
+	if !result.Success {
+		msgs := []string{}
+		for _, e := range result.Errors {
+			msgs = append(msgs, fmt.Sprintf("%d %s", e.Code, e.Message))
+		}
+		return fmt.Errorf("cloudflare: %s: %s", resp.Status, strings.Join(msgs, "; "))
+	}
+	if out != nil {
+		return json.Unmarshal(result.Result, out)
+	}
+	return nil
+}
+
+// findID returns the ID of the record with rec's name and type, or "" if there is none.
+func (p *cloudflareProvider) findID(ctx context.Context, rec Record) (string, error) {
+	var found []cloudflareRecord
+	query := url.Values{"type": {rec.Type}, "name": {rec.FQDN}}
+	if err := p.do(ctx, http.MethodGet, "/zones/"+p.zoneID+"/dns_records?"+query.Encode(), nil, &found); err != nil {
+		return "", err
+	}
+	if len(found) == 0 {
+		return "", nil
+	}
+	return found[0].ID, nil
+}
+
+func (p *cloudflareProvider) Upsert(ctx context.Context, rec Record) (string, error) {
+	id := rec.ProviderID
+	if id == "" {
+		var err error
+		if id, err = p.findID(ctx, rec); err != nil {
+			return "", err
+		}
+	}
+
+	body := cloudflareRecord{Type: rec.Type, Name: rec.FQDN, Content: rec.Value, TTL: rec.TTL}
+	var saved cloudflareRecord
+	if id == "" {
+		err := p.do(ctx, http.MethodPost, "/zones/"+p.zoneID+"/dns_records", body, &saved)
+		return saved.ID, err
+	}
+	err := p.do(ctx, http.MethodPut, "/zones/"+p.zoneID+"/dns_records/"+id, body, &saved)
+	return saved.ID, err
+}
+
+func (p *cloudflareProvider) Delete(ctx context.Context, rec Record) error {
+	// Look the record up instead of trusting ProviderID, so a record removed
+	// by hand does not make the delete fail
+	id, err := p.findID(ctx, rec)
+	if err != nil || id == "" {
+		return err
+	}
+	return p.do(ctx, http.MethodDelete, "/zones/"+p.zoneID+"/dns_records/"+id, nil, nil)
+}
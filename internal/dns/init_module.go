@@ -0,0 +1,12 @@
+package dns
+
+import (
+	"net/http"
+)
+
+// InitModule registers the dns routes served by handler.
+func InitModule(mux *http.ServeMux, handler *Handler) {
+	mux.HandleFunc("GET /dns/records", handler.ListRecords)
+	mux.HandleFunc("POST /dns/records", handler.PublishRecord)
+	mux.HandleFunc("DELETE /dns/records/{address}", handler.ReleaseRecord)
+}
@@ -0,0 +1,49 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+
+	"mcloud/internal/config"
+)
+
+// Record is a DNS record as sent to a provider.
+type Record struct {
+	FQDN       string // e.g. web.apps.example.com
+	Type       string // A or AAAA
+	Value      string // IP address
+	TTL        int
+	ProviderID string // provider's ID of an existing record, if known
+}
+
+// Provider creates and removes records in an external DNS service.
+type Provider interface {
+	Name() string
+
+	// Upsert creates the record or replaces an existing one with the same name
+	// and type, returning the provider's ID for it (empty if it has none).
+	Upsert(ctx context.Context, rec Record) (string, error)
+
+	// Delete removes the record. Deleting a record that no longer exists is not an error.
+	Delete(ctx context.Context, rec Record) error
+}
+
+// NewProvider returns the provider configured in cfg, or nil if DNS integration is disabled.
+//
+// Example Input:
+//   NewProvider(config.DNS{Provider: "rfc2136", RFC2136: config.DNSRFC2136{Server: "10.0.0.53", KeyFile: "/etc/mcloud/dns.key"}})
+//
+// Example Output:
+//   &rfc2136Provider{server: "10.0.0.53", keyFile: "/etc/mcloud/dns.key"}, nil
+func NewProvider(cfg config.DNS) (Provider, error) {
+	switch cfg.Provider {
+	case "":
+		return nil, nil
+	case "cloudflare":
+		return newCloudflareProvider(cfg.Cloudflare)
+	case "rfc2136":
+		return newRFC2136Provider(cfg.RFC2136)
+	default:
+		return nil, fmt.Errorf("unknown dns provider %q (want cloudflare or rfc2136)", cfg.Provider)
+	}
+}
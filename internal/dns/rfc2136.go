@@ -0,0 +1,42 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+
+	"mcloud/internal/config"
+	"mcloud/services/nsupdate"
+)
+
+// rfc2136Provider updates an authoritative server (BIND, Knot, PowerDNS, ...) with
+// TSIG-signed dynamic updates. Such records have no IDs.
+type rfc2136Provider struct {
+	server  string
+	keyFile string
+}
+
+func newRFC2136Provider(cfg config.DNSRFC2136) (*rfc2136Provider, error) {
+	if cfg.Server == "" {
+		return nil, fmt.Errorf("dns.rfc2136.server is required")
+	}
+	return &rfc2136Provider{server: cfg.Server, keyFile: cfg.KeyFile}, nil
+}
+
+func (p *rfc2136Provider) Name() string {
+	return "rfc2136"
+}
+
+func (p *rfc2136Provider) Upsert(ctx context.Context, rec Record) (string, error) {
+	// Delete and add in one update, so the name never resolves to nothing
+	err := nsupdate.Update(ctx, p.server, p.keyFile, []string{
+		fmt.Sprintf("update delete %s %s", rec.FQDN, rec.Type),
+		fmt.Sprintf("update add %s %d %s %s", rec.FQDN, rec.TTL, rec.Type, rec.Value),
+	})
+	return "", err
+}
+
+func (p *rfc2136Provider) Delete(ctx context.Context, rec Record) error {
+	return nsupdate.Update(ctx, p.server, p.keyFile, []string{
+		fmt.Sprintf("update delete %s %s %s", rec.FQDN, rec.Type, rec.Value),
+	})
+}
@@ -0,0 +1,512 @@
+package dns
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"strings"
+	"time"
+
+	"mcloud/internal/config"
+	"mcloud/internal/database"
+	"mcloud/services/lxd"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	defaultClusterDomain = "mcloud.local"
+	defaultClusterTTL    = 30
+
+	// forwardTimeout bounds one exchange with an upstream resolver.
+	forwardTimeout = 2 * time.Second
+
+	// maxUDPSize is the largest answer sent over UDP; larger ones are truncated
+	// so the client retries over TCP.
+	maxUDPSize = 512
+
+	// maxInflight bounds the queries handled at once over UDP, and separately
+	// the TCP connections served at once. UDP queries beyond it are dropped
+	// (clients retry); TCP connections beyond it are closed.
+	maxInflight = 256
+)
+
+// resolvConfs are read in order for the host's resolvers when
+// cluster_dns.upstreams is empty. Under systemd-resolved the first lists the
+// real upstreams rather than its 127.0.0.53 stub.
+var resolvConfs = []string{"/run/systemd/resolve/resolv.conf", "/etc/resolv.conf"}
+
+// Server is the cluster DNS server mcloudd runs for workloads (see
+// config.ClusterDNS), so they can address each other by name. It answers
+// <workload>.<project>.<domain> with the addresses of the workload, as synced
+// from LXD, and forwards every other name to the upstream resolvers. The OVN
+// network of the workloads hands its address to them over DHCP. Only clients
+// in cluster_dns.clients, by default those of that network, may query it;
+// others are refused.
+//
+// Example:
+//   $ dig +short web-1.default.mcloud.local @10.0.0.5
+//   10.10.0.5
+type Server struct {
+	db        *sql.DB
+	domain    string // lower case, fully qualified, e.g. "mcloud.local."
+	listen    string
+	upstreams []string // host:port
+	ttl       uint32
+	network   string // LXD OVN network of the workloads
+
+	clients  []netip.Prefix // allowed to query; set before serving
+	inflight chan struct{}  // one slot per UDP query being handled
+	conns    chan struct{}  // one slot per TCP connection being served
+}
+
+// NewServer returns the cluster DNS server configured in cfg, or nil if it is disabled.
+func NewServer(db *sql.DB, cfg *config.Config) *Server {
+	c := cfg.ClusterDNS
+	if !c.Enabled {
+		return nil
+	}
+
+	s := &Server{
+		db:      db,
+		domain:  strings.ToLower(strings.Trim(c.Domain, ".")) + ".",
+		listen:  c.Listen,
+		ttl:      uint32(c.TTL),
+		network:  cfg.Network.OVNNetwork,
+		inflight: make(chan struct{}, maxInflight),
+		conns:    make(chan struct{}, maxInflight),
+	}
+	if s.domain == "." {
+		s.domain = defaultClusterDomain + "."
+	}
+	if s.listen == "" {
+		s.listen = net.JoinHostPort(cfg.Manager.HttpHost, "53")
+	}
+	if c.TTL <= 0 {
+		s.ttl = defaultClusterTTL
+	}
+	if s.network == "" {
+		s.network = "default"
+	}
+
+	upstreams := c.Upstreams
+	if len(upstreams) == 0 {
+		upstreams = hostResolvers()
+	}
+	for _, u := range upstreams {
+		if _, _, err := net.SplitHostPort(u); err != nil {
+			u = net.JoinHostPort(u, "53")
+		}
+		if u != s.listen {
+			s.upstreams = append(s.upstreams, u)
+		}
+	}
+	if len(s.upstreams) == 0 {
+		log.Warn("Cluster DNS has no upstream resolvers; only names under %s resolve", s.domain)
+	}
+
+	for _, client := range c.Clients {
+		p, err := parseClient(client)
+		if err != nil {
+			log.Warn("Ignoring cluster_dns.clients entry %q: %v", client, err)
+			continue
+		}
+		s.clients = append(s.clients, p)
+	}
+	return s
+}
+
+// parseClient parses a CIDR or a single address into a prefix.
+func parseClient(s string) (netip.Prefix, error) {
+	if addr, err := netip.ParseAddr(s); err == nil {
+		return netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()), nil
+	}
+	p, err := netip.ParsePrefix(s)
+	return p.Masked(), err
+}
+
+// networkClients returns the clients of an OVN network from its LXD config:
+// its subnets, and its address on the uplink, which egress from the network
+// is NATed to.
+//
+// Example:
+//   networkClients({"ipv4.address": "10.10.0.1/24", "volatile.network.ipv4.address": "192.168.1.200"})
+//     // [10.10.0.0/24 192.168.1.200/32]
+func networkClients(config map[string]string) []netip.Prefix {
+	var clients []netip.Prefix
+	for _, key := range []string{"ipv4.address", "ipv6.address", "volatile.network.ipv4.address", "volatile.network.ipv6.address"} {
+		if p, err := parseClient(config[key]); err == nil {
+			clients = append(clients, p)
+		}
+	}
+	return clients
+}
+
+// allowed reports whether client may query the server: loopback and the
+// configured clients may.
+func (s *Server) allowed(client netip.Addr) bool {
+	client = client.Unmap()
+	if client.IsLoopback() {
+		return true
+	}
+	for _, p := range s.clients {
+		if p.Contains(client) {
+			return true
+		}
+	}
+	return false
+}
+
+// hostResolvers returns the nameservers of the first resolv.conf found.
+func hostResolvers() []string {
+	for _, path := range resolvConfs {
+		f, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		var servers []string
+		sc := bufio.NewScanner(f)
+		for sc.Scan() {
+			if fields := strings.Fields(sc.Text()); len(fields) >= 2 && fields[0] == "nameserver" {
+				servers = append(servers, fields[1])
+			}
+		}
+		f.Close()
+		if len(servers) > 0 {
+			return servers
+		}
+	}
+	return nil
+}
+
+// Domain returns the cluster domain, e.g. "mcloud.local".
+func (s *Server) Domain() string {
+	return strings.TrimSuffix(s.domain, ".")
+}
+
+// Serve answers DNS queries over UDP and TCP on the listen address until ctx
+// is cancelled. Without cluster_dns.clients it first reads the clients from
+// the workloads' OVN network. Once listening, it sets the server as
+// dns.nameservers of that network; a failure there is logged, not returned.
+func (s *Server) Serve(ctx context.Context) error {
+	if len(s.clients) == 0 {
+		config, err := lxd.NetworkConfig(ctx, s.network)
+		if err != nil {
+			log.Warn("Cluster DNS only answers loopback clients: %v", err)
+		}
+		s.clients = networkClients(config)
+		log.Info("Cluster DNS answers clients in %v", s.clients)
+	}
+
+	pc, err := net.ListenPacket("udp", s.listen)
+	if err != nil {
+		return fmt.Errorf("cluster DNS: %w", err)
+	}
+	ln, err := net.Listen("tcp", s.listen)
+	if err != nil {
+		pc.Close()
+		return fmt.Errorf("cluster DNS: %w", err)
+	}
+	log.Info("Serving cluster DNS for %s on %s", s.domain, s.listen)
+
+	go func() {
+		<-ctx.Done()
+		pc.Close()
+		ln.Close()
+	}()
+	go s.advertise(ctx)
+	go s.serveTCP(ctx, ln)
+	s.serveUDP(ctx, pc)
+	return nil
+}
+
+// advertise makes the DHCP server of the workloads' OVN network hand out the
+// address of this server.
+func (s *Server) advertise(ctx context.Context) {
+	host, _, _ := net.SplitHostPort(s.listen)
+	if ip := net.ParseIP(host); ip == nil || ip.IsUnspecified() {
+		log.Warn("Cluster DNS listens on %s; set cluster_dns.listen to an address to hand it to workloads on network %s", s.listen, s.network)
+		return
+	}
+	if err := lxd.SetNetworkConfig(ctx, s.network, map[string]string{"dns.nameservers": host}); err != nil {
+		log.Warn("Workloads on network %s will not use the cluster DNS: %v", s.network, err)
+	}
+}
+
+func (s *Server) serveUDP(ctx context.Context, pc net.PacketConn) {
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := pc.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Error("Cluster DNS stopped: %v", err)
+			}
+			return
+		}
+		select {
+		case s.inflight <- struct{}{}:
+		default:
+			continue // busy; the client retries
+		}
+		query := append([]byte(nil), buf[:n]...)
+		go func() {
+			defer func() { <-s.inflight }()
+			if resp := s.handle(ctx, query, "udp", clientAddr(addr)); resp != nil {
+				pc.WriteTo(resp, addr)
+			}
+		}()
+	}
+}
+
+func (s *Server) serveTCP(ctx context.Context, ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Error("Cluster DNS over TCP stopped: %v", err)
+			}
+			return
+		}
+		select {
+		case s.conns <- struct{}{}:
+		default:
+			conn.Close()
+			continue
+		}
+		go func() {
+			defer func() { <-s.conns }()
+			defer conn.Close()
+			client := clientAddr(conn.RemoteAddr())
+			for {
+				conn.SetDeadline(time.Now().Add(10 * time.Second))
+				query, err := readTCPMessage(conn)
+				if err != nil {
+					return
+				}
+				resp := s.handle(ctx, query, "tcp", client)
+				if resp == nil || writeTCPMessage(conn, resp) != nil {
+					return
+				}
+			}
+		}()
+	}
+}
+
+// clientAddr returns the IP address of a UDP or TCP peer.
+func clientAddr(addr net.Addr) netip.Addr {
+	var ip net.IP
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		ip = a.IP
+	case *net.TCPAddr:
+		ip = a.IP
+	}
+	client, _ := netip.AddrFromSlice(ip)
+	return client.Unmap()
+}
+
+// readTCPMessage and writeTCPMessage frame a message with its 2-byte length, as DNS over TCP does.
+func readTCPMessage(r io.Reader) ([]byte, error) {
+	var size uint16
+	if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+		return nil, err
+	}
+	msg := make([]byte, size)
+	_, err := io.ReadFull(r, msg)
+	return msg, err
+}
+
+func writeTCPMessage(w io.Writer, msg []byte) error {
+	_, err := w.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(msg))), msg...))
+	return err
+}
+
+// handle returns the response to a query received over network (udp or tcp)
+// from client, or nil if the query cannot be parsed.
+func (s *Server) handle(ctx context.Context, query []byte, network string, client netip.Addr) []byte {
+	var req dnsmessage.Message
+	if err := req.Unpack(query); err != nil || req.Header.Response {
+		return nil
+	}
+	resp := dnsmessage.Message{
+		Header: dnsmessage.Header{
+			ID:                 req.Header.ID,
+			Response:           true,
+			OpCode:             req.Header.OpCode,
+			RecursionDesired:   req.Header.RecursionDesired,
+			RecursionAvailable: true,
+		},
+		Questions: req.Questions,
+	}
+
+	switch {
+	case !s.allowed(client):
+		resp.Header.RecursionAvailable = false
+		resp.Header.RCode = dnsmessage.RCodeRefused
+	case req.Header.OpCode != 0:
+		resp.Header.RCode = dnsmessage.RCodeNotImplemented
+	case len(req.Questions) != 1:
+		resp.Header.RCode = dnsmessage.RCodeFormatError
+	case s.inDomain(req.Questions[0].Name.String()):
+		if err := s.answer(ctx, req.Questions[0], &resp); err != nil {
+			log.Warn("Cluster DNS lookup of %s: %v", req.Questions[0].Name, err)
+			resp.Header.RCode = dnsmessage.RCodeServerFailure
+		}
+	default:
+		fwd, err := s.forward(ctx, network, query)
+		if err == nil {
+			return fwd
+		}
+		log.Debug("Forwarding %s: %v", req.Questions[0].Name, err)
+		resp.Header.RCode = dnsmessage.RCodeServerFailure
+	}
+
+	out, err := resp.Pack()
+	if err != nil {
+		return nil
+	}
+	if network == "udp" && len(out) > maxUDPSize {
+		resp.Header.Truncated = true
+		resp.Answers = nil
+		if out, err = resp.Pack(); err != nil {
+			return nil
+		}
+	}
+	return out
+}
+
+func (s *Server) inDomain(name string) bool {
+	name = strings.ToLower(name)
+	return name == s.domain || strings.HasSuffix(name, "."+s.domain)
+}
+
+// answer fills in the answer to q, a question for a name in the cluster
+// domain. Only <workload>.<project>.<domain> names have records; the domain
+// and its project names exist without records.
+func (s *Server) answer(ctx context.Context, q dnsmessage.Question, resp *dnsmessage.Message) error {
+	resp.Header.Authoritative = true
+	// Negative answers carry the SOA so resolvers cache them for ttl
+	resp.Authorities = []dnsmessage.Resource{s.soa()}
+
+	rel := strings.TrimSuffix(strings.ToLower(q.Name.String()), s.domain)
+	labels := strings.Split(strings.TrimSuffix(rel, "."), ".")
+	switch {
+	case rel == "" || len(labels) == 1:
+		return nil
+	case len(labels) > 2:
+		resp.Header.RCode = dnsmessage.RCodeNameError
+		return nil
+	}
+
+	addrs, found, err := s.lookup(ctx, labels[1], labels[0])
+	if err != nil {
+		return err
+	}
+	if !found {
+		resp.Header.RCode = dnsmessage.RCodeNameError
+		return nil
+	}
+	hdr := dnsmessage.ResourceHeader{Name: q.Name, Class: dnsmessage.ClassINET, TTL: s.ttl}
+	for _, a := range addrs {
+		switch {
+		case a.Is4() && (q.Type == dnsmessage.TypeA || q.Type == dnsmessage.TypeALL):
+			resp.Answers = append(resp.Answers, dnsmessage.Resource{Header: hdr, Body: &dnsmessage.AResource{A: a.As4()}})
+		case a.Is6() && (q.Type == dnsmessage.TypeAAAA || q.Type == dnsmessage.TypeALL):
+			resp.Answers = append(resp.Answers, dnsmessage.Resource{Header: hdr, Body: &dnsmessage.AAAAResource{AAAA: a.As16()}})
+		}
+	}
+	if len(resp.Answers) > 0 {
+		resp.Authorities = nil
+	}
+	return nil
+}
+
+func (s *Server) soa() dnsmessage.Resource {
+	zone := dnsmessage.MustNewName(s.domain)
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: zone, Class: dnsmessage.ClassINET, TTL: s.ttl},
+		Body: &dnsmessage.SOAResource{
+			NS:      dnsmessage.MustNewName("ns." + s.domain),
+			MBox:    dnsmessage.MustNewName("hostmaster." + s.domain),
+			Serial:  1,
+			Refresh: 3600,
+			Retry:   600,
+			Expire:  86400,
+			MinTTL:  s.ttl,
+		},
+	}
+}
+
+// lookup returns the global addresses of the workload name in project, and
+// whether the workload exists.
+func (s *Server) lookup(ctx context.Context, project string, name string) ([]netip.Addr, bool, error) {
+	workloads, err := database.NewWorkloadRepository(s.db).List(ctx, database.ListOptions{
+		Filter: map[string]string{"project": project, "name": name},
+	})
+	if err != nil || len(workloads) == 0 {
+		return nil, false, err
+	}
+	var addrs []netip.Addr
+	for _, w := range workloads {
+		for _, a := range w.Addresses {
+			if ip, err := netip.ParseAddr(a); err == nil && !ip.IsLinkLocalUnicast() {
+				addrs = append(addrs, ip)
+			}
+		}
+	}
+	return addrs, true, nil
+}
+
+// forward relays a query to the upstream resolvers in turn over network and
+// returns the first response.
+func (s *Server) forward(ctx context.Context, network string, query []byte) ([]byte, error) {
+	if len(s.upstreams) == 0 {
+		return nil, errors.New("no upstream resolvers")
+	}
+	var errs []error
+	for _, upstream := range s.upstreams {
+		resp, err := exchange(ctx, network, upstream, query)
+		if err == nil {
+			return resp, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, errors.Join(errs...)
+}
+
+func exchange(ctx context.Context, network string, upstream string, query []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, forwardTimeout)
+	defer cancel()
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, upstream)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+
+	if network == "tcp" {
+		if err := writeTCPMessage(conn, query); err != nil {
+			return nil, err
+		}
+		return readTCPMessage(conn)
+	}
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 65535)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
@@ -0,0 +1,155 @@
+package dns
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"mcloud/internal/config"
+	"mcloud/internal/database"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestServer(t *testing.T) {
+	db, err := database.Connect(filepath.Join(t.TempDir(), "mcloud.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close(db)
+	ctx := context.Background()
+
+	repo := database.NewWorkloadRepository(db)
+	for _, w := range []*database.Workload{
+		{ID: "w-1", ClusterID: "c-1", Name: "web-1", Project: "default"},
+		{ID: "w-2", ClusterID: "c-1", Name: "db-1", Project: "team-a"},
+		{ID: "w-3", ClusterID: "c-1", Name: "new-1", Project: "default"},
+	} {
+		w.Kind, w.Status, w.Image, w.Priority = "container", "running", "ubuntu:24.04", "normal"
+		if err := repo.Create(ctx, w); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.UpdateAddresses(ctx, "w-1", database.AddressList{"10.10.0.5", "fd42::5", "fe80::1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.UpdateAddresses(ctx, "w-2", database.AddressList{"10.10.0.7"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// An upstream resolver answering every query with 192.0.2.1
+	upstream, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer upstream.Close()
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := upstream.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var m dnsmessage.Message
+			if m.Unpack(buf[:n]) != nil {
+				continue
+			}
+			m.Header.Response = true
+			m.Answers = []dnsmessage.Resource{{
+				Header: dnsmessage.ResourceHeader{Name: m.Questions[0].Name, Class: dnsmessage.ClassINET, TTL: 60},
+				Body:   &dnsmessage.AResource{A: [4]byte{192, 0, 2, 1}},
+			}}
+			out, _ := m.Pack()
+			upstream.WriteTo(out, addr)
+		}
+	}()
+
+	cfg := &config.Config{ClusterDNS: config.ClusterDNS{Enabled: true, Domain: "Mcloud.Local.", Upstreams: []string{upstream.LocalAddr().String()},
+		Clients: []string{"10.10.0.0/24", "192.168.1.200", "bogus"}}}
+	s := NewServer(db, cfg)
+	if s.Domain() != "mcloud.local" || s.ttl != defaultClusterTTL {
+		t.Fatalf("domain %q, ttl %d", s.Domain(), s.ttl)
+	}
+
+	tests := []struct {
+		name   string
+		qtype  dnsmessage.Type
+		client string
+		rcode  dnsmessage.RCode
+		want   []string
+	}{
+		{"web-1.default.mcloud.local.", dnsmessage.TypeA, "", dnsmessage.RCodeSuccess, []string{"10.10.0.5"}},
+		{"WEB-1.Default.mcloud.local.", dnsmessage.TypeAAAA, "", dnsmessage.RCodeSuccess, []string{"fd42::5"}},
+		{"db-1.team-a.mcloud.local.", dnsmessage.TypeA, "", dnsmessage.RCodeSuccess, []string{"10.10.0.7"}},
+		// Wrong project
+		{"db-1.default.mcloud.local.", dnsmessage.TypeA, "", dnsmessage.RCodeNameError, nil},
+		// No address synced yet
+		{"new-1.default.mcloud.local.", dnsmessage.TypeA, "", dnsmessage.RCodeSuccess, nil},
+		{"default.mcloud.local.", dnsmessage.TypeA, "", dnsmessage.RCodeSuccess, nil},
+		{"x.web-1.default.mcloud.local.", dnsmessage.TypeA, "", dnsmessage.RCodeNameError, nil},
+		// Forwarded
+		{"example.com.", dnsmessage.TypeA, "", dnsmessage.RCodeSuccess, []string{"192.0.2.1"}},
+		// From a workload, the network's uplink address and elsewhere
+		{"example.com.", dnsmessage.TypeA, "10.10.0.5", dnsmessage.RCodeSuccess, []string{"192.0.2.1"}},
+		{"example.com.", dnsmessage.TypeA, "192.168.1.200", dnsmessage.RCodeSuccess, []string{"192.0.2.1"}},
+		{"example.com.", dnsmessage.TypeA, "198.51.100.7", dnsmessage.RCodeRefused, nil},
+		{"web-1.default.mcloud.local.", dnsmessage.TypeA, "198.51.100.7", dnsmessage.RCodeRefused, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name+" "+tt.client, func(t *testing.T) {
+			client := netip.MustParseAddr("127.0.0.1")
+			if tt.client != "" {
+				client = netip.MustParseAddr(tt.client)
+			}
+
+			q := dnsmessage.Message{
+				Header:    dnsmessage.Header{ID: 42, RecursionDesired: true},
+				Questions: []dnsmessage.Question{{Name: dnsmessage.MustNewName(tt.name), Type: tt.qtype, Class: dnsmessage.ClassINET}},
+			}
+			query, err := q.Pack()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var resp dnsmessage.Message
+			if err := resp.Unpack(s.handle(ctx, query, "udp", client)); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Header.ID != 42 || resp.Header.RCode != tt.rcode {
+				t.Fatalf("id %d, rcode %v, want 42, %v", resp.Header.ID, resp.Header.RCode, tt.rcode)
+			}
+			var got []string
+			for _, a := range resp.Answers {
+				switch b := a.Body.(type) {
+				case *dnsmessage.AResource:
+					got = append(got, net.IP(b.A[:]).String())
+				case *dnsmessage.AAAAResource:
+					got = append(got, net.IP(b.AAAA[:]).String())
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("answers %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNetworkClients(t *testing.T) {
+	got := networkClients(map[string]string{
+		"ipv4.address":                  "10.10.0.1/24",
+		"ipv6.address":                  "none",
+		"volatile.network.ipv4.address": "192.168.1.200",
+		"volatile.network.ipv6.address": "2001:db8::200",
+	})
+	want := []netip.Prefix{
+		netip.MustParsePrefix("10.10.0.0/24"),
+		netip.MustParsePrefix("192.168.1.200/32"),
+		netip.MustParsePrefix("2001:db8::200/128"),
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("networkClients = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,183 @@
+// Package dns publishes DNS records for floating IPs through a pluggable
+// provider (Cloudflare or RFC 2136 dynamic updates) and tracks them in the
+// database, so they can be removed when the IP is released. Server is the
+// cluster DNS server resolving workload names inside the cluster.
+package dns
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net"
+	"regexp"
+	"strings"
+
+	"mcloud/internal/config"
+	"mcloud/internal/database"
+	"mcloud/pkg/logger"
+	"mcloud/pkg/reason"
+)
+
+var log = logger.Named("dns")
+
+// defaultTTL is used when dns.ttl is not set.
+const defaultTTL = 300
+
+var (
+	ErrDisabled       = reason.New(reason.DNSDisabled, "dns integration is not configured")
+	ErrInvalidName    = reason.New(reason.InvalidDNSName, "dns name must be one or more labels of [a-z0-9-]")
+	ErrInvalidAddress = reason.New(reason.InvalidIPAddress, "invalid ip address")
+)
+
+var nameRegexp = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)*$`)
+
+type PublishRequest struct {
+	Address string `json:"address"` // floating IP, e.g. 203.0.113.10
+	Name    string `json:"name"`    // name within the zone, e.g. web
+}
+
+type Service struct {
+	db       *sql.DB
+	provider Provider // nil when disabled
+	zone     string
+	ttl      int
+}
+
+func NewService(db *sql.DB, cfg config.DNS) *Service {
+	provider, err := NewProvider(cfg)
+	if err != nil {
+		log.Error("DNS integration disabled: %v", err)
+	}
+	if provider != nil && cfg.Zone == "" {
+		log.Error("DNS integration disabled: dns.zone is required")
+		provider = nil
+	}
+
+	ttl := cfg.TTL
+	if ttl <= 0 {
+		ttl = defaultTTL
+	}
+	return &Service{
+		db:       db,
+		provider: provider,
+		zone:     strings.TrimSuffix(cfg.Zone, "."),
+		ttl:      ttl,
+	}
+}
+
+// Enabled reports whether a provider is configured.
+func (s *Service) Enabled() bool {
+	return s.provider != nil
+}
+
+// Publish points <name>.<zone> at a floating IP. An IP has at most one name:
+// publishing a new name for it removes the old record first.
+//
+// Example Input:
+//   Publish(ctx, &PublishRequest{Address: "203.0.113.10", Name: "web"})  // zone apps.example.com
+//
+// Example Output:
+//   &database.DNSRecord{Owner: "203.0.113.10", FQDN: "web.apps.example.com", Type: "A",
+//     Value: "203.0.113.10", Provider: "cloudflare", ProviderID: "372e6795..."}, nil
+func (s *Service) Publish(ctx context.Context, req *PublishRequest) (*database.DNSRecord, error) {
+	if s.provider == nil {
+		return nil, ErrDisabled
+	}
+
+	name := strings.ToLower(strings.TrimSuffix(req.Name, "."))
+	if !nameRegexp.MatchString(name) {
+		return nil, ErrInvalidName
+	}
+	ip := net.ParseIP(req.Address)
+	if ip == nil {
+		return nil, ErrInvalidAddress
+	}
+
+	rec := Record{FQDN: name + "." + s.zone, Type: "A", Value: ip.String(), TTL: s.ttl}
+	if ip.To4() == nil {
+		rec.Type = "AAAA"
+	}
+
+	repo := database.NewDNSRecordRepository(s.db)
+	existing, err := repo.GetByOwner(ctx, rec.Value)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if existing != nil {
+		if existing.FQDN == rec.FQDN && existing.Provider == s.provider.Name() {
+			rec.ProviderID = existing.ProviderID
+		} else if err := s.deleteRecord(ctx, existing); err != nil {
+			return nil, err
+		}
+	}
+
+	id, err := s.provider.Upsert(ctx, rec)
+	if err != nil {
+		return nil, err
+	}
+
+	saved := &database.DNSRecord{
+		Owner:      rec.Value,
+		FQDN:       rec.FQDN,
+		Type:       rec.Type,
+		Value:      rec.Value,
+		Provider:   s.provider.Name(),
+		ProviderID: id,
+	}
+	if err := repo.Upsert(ctx, saved); err != nil {
+		return nil, err
+	}
+	log.Info("Published %s %s -> %s", rec.Type, rec.FQDN, rec.Value)
+	return repo.GetByOwner(ctx, rec.Value)
+}
+
+// Release removes the record published for a floating IP, if any. It is meant to
+// be called whenever the IP is released, so it is a no-op when nothing was published.
+func (s *Service) Release(ctx context.Context, address string) error {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return ErrInvalidAddress
+	}
+
+	existing, err := database.NewDNSRecordRepository(s.db).GetByOwner(ctx, ip.String())
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return s.deleteRecord(ctx, existing)
+}
+
+// deleteRecord removes a tracked record from its provider and stops tracking it.
+func (s *Service) deleteRecord(ctx context.Context, existing *database.DNSRecord) error {
+	if s.provider == nil {
+		return ErrDisabled
+	}
+	if existing.Provider != s.provider.Name() {
+		// Created with a provider that is no longer configured: only forget it
+		log.Warn("Not removing %s from %s: provider is now %s", existing.FQDN, existing.Provider, s.provider.Name())
+	} else {
+		rec := Record{FQDN: existing.FQDN, Type: existing.Type, Value: existing.Value, ProviderID: existing.ProviderID}
+		if err := s.provider.Delete(ctx, rec); err != nil {
+			return err
+		}
+	}
+
+	if err := database.NewDNSRecordRepository(s.db).Delete(ctx, existing.Owner); err != nil {
+		return err
+	}
+	log.Info("Removed %s %s -> %s", existing.Type, existing.FQDN, existing.Value)
+	return nil
+}
+
+func (s *Service) List(ctx context.Context) ([]database.DNSRecord, error) {
+	items, err := database.NewDNSRecordRepository(s.db).List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if items == nil {
+		items = []database.DNSRecord{}
+	}
+	return items, nil
+}
@@ -0,0 +1,435 @@
+// Package doctor cross-checks what a node keeps about itself (state.yaml, the
+// manager database and the certificates on disk) against each other and
+// against the live LXD, Ceph and OVN clusters, for `mcloudctl doctor`. Every
+// inconsistency is reported with a remedy; the few that can be repaired
+// without risk (state.yaml behind the database, private keys readable by
+// others) are fixed when asked to.
+package doctor
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"mcloud/internal/cert"
+	"mcloud/internal/config"
+	"mcloud/internal/database"
+	"mcloud/internal/state"
+	"mcloud/services/lxd"
+	"mcloud/services/microceph"
+	"mcloud/services/microovn"
+)
+
+// Finding severities.
+const (
+	SeverityOK    = "ok"
+	SeverityWarn  = "warn"
+	SeverityError = "error"
+)
+
+// certExpiryWarning is how long before expiry a certificate is reported.
+const certExpiryWarning = 30 * 24 * time.Hour
+
+// Finding is the result of one check.
+//
+// Example JSON:
+//   {"check": "state", "severity": "error", "message": "state.yaml says initialized but the database has no cluster 660e8400...",
+//    "remedy": "finish init with mcloudctl init --resume, or start over with mcloudctl reset --force"}
+type Finding struct {
+	Check    string `json:"check"` // e.g. state, database, cert.server, lxd
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	Remedy   string `json:"remedy,omitempty"`
+	Fixable  bool   `json:"fixable,omitempty"` // --fix applies the remedy
+	Fixed    bool   `json:"fixed,omitempty"`
+
+	fix func() error
+}
+
+// Report is the outcome of Run. Errors and Warnings count the findings not
+// fixed.
+type Report struct {
+	Findings []Finding `json:"findings"`
+	Errors   int       `json:"errors"`
+	Warnings int       `json:"warnings"`
+}
+
+// Options selects what Run does besides the local checks.
+type Options struct {
+	Fix  bool // apply the safe fixes of fixable findings
+	Live bool // also query LXD, Ceph and OVN
+}
+
+type doctor struct {
+	cfg      *config.Config
+	st       *state.State // nil without state.yaml
+	db       *sql.DB      // nil without a database
+	hostname string
+	findings []Finding
+}
+
+func (d *doctor) add(f Finding) {
+	d.findings = append(d.findings, f)
+}
+
+func (d *doctor) ok(check, format string, args ...any) {
+	d.add(Finding{Check: check, Severity: SeverityOK, Message: fmt.Sprintf(format, args...)})
+}
+
+// Run runs every check on this node and, with opts.Fix, the fixes of the
+// fixable findings.
+func Run(ctx context.Context, cfg *config.Config, opts Options) *Report {
+	d := &doctor{cfg: cfg}
+	d.hostname, _ = os.Hostname()
+
+	st, err := state.LoadState(cfg.StatePath)
+	switch {
+	case err == nil:
+		d.st = st
+		if st.Node.Hostname != "" {
+			d.hostname = st.Node.Hostname
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		d.add(Finding{Check: "state", Severity: SeverityError, Message: fmt.Sprintf("cannot read %s: %v", cfg.StatePath, err),
+			Remedy: "restore the file from a backup, or recreate it with mcloudctl rejoin"})
+	}
+
+	if _, err := os.Stat(cfg.Database.DBPath); err == nil {
+		db, err := database.Open(cfg.Database.DBPath)
+		if err != nil {
+			d.add(Finding{Check: "database", Severity: SeverityError, Message: fmt.Sprintf("cannot open %s: %v", cfg.Database.DBPath, err)})
+		} else {
+			defer db.Close()
+			d.db = db.SQL()
+			d.checkDatabase(ctx, db)
+		}
+	}
+
+	d.checkState(ctx)
+	d.checkCerts()
+	if opts.Live {
+		d.checkLive(ctx)
+	}
+
+	report := &Report{Findings: d.findings}
+	for i := range report.Findings {
+		f := &report.Findings[i]
+		if opts.Fix && f.fix != nil {
+			if err := f.fix(); err != nil {
+				f.Message += fmt.Sprintf(" (fix failed: %v)", err)
+			} else {
+				f.Fixed = true
+			}
+		}
+		if f.Fixed {
+			continue
+		}
+		switch f.Severity {
+		case SeverityError:
+			report.Errors++
+		case SeverityWarn:
+			report.Warnings++
+		}
+	}
+	return report
+}
+
+// checkDatabase checks the database file itself and its migrations.
+func (d *doctor) checkDatabase(ctx context.Context, db *database.Database) {
+	var integrity string
+	if err := d.db.QueryRowContext(ctx, `PRAGMA integrity_check`).Scan(&integrity); err != nil || integrity != "ok" {
+		if err != nil {
+			integrity = err.Error()
+		}
+		d.add(Finding{Check: "database", Severity: SeverityError, Message: "integrity check failed: " + integrity,
+			Remedy: "stop mcloudd and restore the database from a backup or the standby copy"})
+		return
+	}
+
+	migrations, err := db.Status()
+	if err != nil {
+		d.add(Finding{Check: "database", Severity: SeverityError, Message: fmt.Sprintf("cannot read migrations: %v", err)})
+		return
+	}
+	var pending, modified []string
+	for _, m := range migrations {
+		switch m.State {
+		case "pending":
+			pending = append(pending, m.Filename)
+		case "modified":
+			modified = append(modified, m.Filename)
+		}
+	}
+	switch {
+	case len(modified) > 0:
+		d.add(Finding{Check: "database", Severity: SeverityError, Message: "applied migrations differ from this binary: " + strings.Join(modified, ", "),
+			Remedy: "run the mcloud version that applied them"})
+	case len(pending) > 0:
+		d.add(Finding{Check: "database", Severity: SeverityWarn, Message: fmt.Sprintf("%d migrations pending, first %s", len(pending), pending[0]),
+			Remedy: "restart mcloudd, or run mcloudctl db migrate"})
+	default:
+		d.ok("database", "%s is intact and migrated", d.cfg.Database.DBPath)
+	}
+}
+
+// checkState compares state.yaml with the cluster and node rows of the
+// database. The database is the source of truth: state.yaml is what init
+// wrote about it, so a state.yaml behind the database is safe to rewrite.
+func (d *doctor) checkState(ctx context.Context) {
+	if d.db == nil {
+		switch {
+		case d.st != nil && d.st.Flags.Initialized:
+			d.add(Finding{Check: "state", Severity: SeverityError,
+				Message: fmt.Sprintf("state.yaml says cluster %s is initialized but %s does not exist", d.st.Cluster.ID, d.cfg.Database.DBPath),
+				Remedy:  "restore the database from a backup, or re-join with mcloudctl rejoin"})
+		case d.st == nil:
+			d.ok("state", "no state.yaml or database: this node is not a manager")
+		}
+		return
+	}
+
+	clusters, err := database.NewClusterRepository(d.db).List(ctx)
+	if err != nil {
+		d.add(Finding{Check: "state", Severity: SeverityError, Message: fmt.Sprintf("cannot list clusters: %v", err)})
+		return
+	}
+
+	if d.st == nil || !d.st.Flags.Initialized {
+		if len(clusters) == 0 {
+			d.ok("state", "not initialized")
+			return
+		}
+		f := Finding{Check: "state", Severity: SeverityError,
+			Message: fmt.Sprintf("the database holds cluster %s but state.yaml does not say it is initialized", clusters[0].Name),
+			Remedy:  "rewrite state.yaml from the database"}
+		if len(clusters) == 1 {
+			f.Fixable = true
+			f.fix = func() error { return d.rewriteState(ctx, &clusters[0]) }
+		}
+		d.add(f)
+		return
+	}
+
+	var cluster *database.Cluster
+	for i := range clusters {
+		if clusters[i].ID == d.st.Cluster.ID {
+			cluster = &clusters[i]
+		}
+	}
+	if cluster == nil {
+		d.add(Finding{Check: "state", Severity: SeverityError,
+			Message: fmt.Sprintf("state.yaml says cluster %s is initialized but the database has no such cluster", d.st.Cluster.ID),
+			Remedy:  "finish init with mcloudctl init --resume, or start over with mcloudctl reset --force"})
+		return
+	}
+
+	node, err := database.NewNodeRepository(d.db).GetByHostname(ctx, d.hostname)
+	if errors.Is(err, sql.ErrNoRows) {
+		d.add(Finding{Check: "state", Severity: SeverityError,
+			Message: fmt.Sprintf("the database has no node %s, the node state.yaml describes", d.hostname),
+			Remedy:  "re-join this node with mcloudctl rejoin"})
+		return
+	}
+	if err != nil {
+		d.add(Finding{Check: "state", Severity: SeverityError, Message: fmt.Sprintf("cannot read node %s: %v", d.hostname, err)})
+		return
+	}
+
+	var drift []string
+	if d.st.Cluster.Name != cluster.Name {
+		drift = append(drift, fmt.Sprintf("cluster name %q (database %q)", d.st.Cluster.Name, cluster.Name))
+	}
+	if d.st.Node.ID != node.ID {
+		drift = append(drift, fmt.Sprintf("node id %s (database %s)", d.st.Node.ID, node.ID))
+	}
+	if d.st.Node.Role != node.Role {
+		drift = append(drift, fmt.Sprintf("role %s (database %s)", d.st.Node.Role, node.Role))
+	}
+	if len(drift) > 0 {
+		d.add(Finding{Check: "state", Severity: SeverityWarn, Message: "state.yaml is behind the database: " + strings.Join(drift, ", "),
+			Remedy: "rewrite state.yaml from the database", Fixable: true,
+			fix: func() error { return d.rewriteState(ctx, cluster) }})
+		return
+	}
+	if cluster.State != "active" {
+		d.add(Finding{Check: "state", Severity: SeverityWarn, Message: fmt.Sprintf("cluster %s is %s", cluster.Name, cluster.State),
+			Remedy: "finish init with mcloudctl init --resume"})
+		return
+	}
+	d.ok("state", "state.yaml matches cluster %s and node %s", cluster.Name, node.Hostname)
+}
+
+// rewriteState writes state.yaml from the cluster row and the row of this
+// node, keeping what the database does not hold (advertise address, init time).
+func (d *doctor) rewriteState(ctx context.Context, cluster *database.Cluster) error {
+	node, err := database.NewNodeRepository(d.db).GetByHostname(ctx, d.hostname)
+	if err != nil {
+		return fmt.Errorf("node %s: %w", d.hostname, err)
+	}
+	st := state.NewState()
+	if d.st != nil {
+		st = d.st
+	}
+	st.Cluster.ID = cluster.ID
+	st.Cluster.Name = cluster.Name
+	st.Node.ID = node.ID
+	st.Node.Hostname = node.Hostname
+	st.Node.IP = node.IP
+	st.Node.Role = node.Role
+	st.Flags.Initialized = true
+	return state.SaveState(d.cfg.StatePath, *st)
+}
+
+// checkCerts checks every certificate of the config: present, matching its
+// key, issued by the cluster CA and not about to expire.
+func (d *doctor) checkCerts() {
+	sec := d.cfg.Security
+	ca, err := cert.ParseCertFile(sec.CACertPath)
+	if err != nil {
+		d.add(Finding{Check: "cert.ca", Severity: SeverityError, Message: fmt.Sprintf("cannot read the cluster CA: %v", err),
+			Remedy: "restore " + sec.CACertPath + " from another node or the re-join bundle"})
+		return
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	d.checkExpiry("cert.ca", ca, "the root CA cannot be renewed; plan a new cluster")
+	intermediates := x509.NewCertPool()
+	if sec.IntermediateCertPath != "" {
+		if ic, err := cert.ParseCertFile(sec.IntermediateCertPath); err == nil {
+			intermediates.AddCert(ic)
+			d.checkExpiry("cert.intermediate", ic, "rotate it with mcloudctl ca rotate")
+		}
+	}
+
+	for _, c := range []struct{ name, certPath, keyPath string }{
+		{"server", sec.ServerCertPath, sec.ServerKeyPath},
+		{"client", sec.ClientCertPath, sec.ClientKeyPath},
+		{"node", sec.NodeCertPath, sec.NodeKeyPath},
+	} {
+		if c.certPath == "" {
+			continue
+		}
+		check := "cert." + c.name
+		renew := "renew it with mcloudctl cert renew " + c.name
+
+		pair, err := tls.LoadX509KeyPair(c.certPath, c.keyPath)
+		if err != nil {
+			d.add(Finding{Check: check, Severity: SeverityError, Message: fmt.Sprintf("cannot load %s: %v", c.certPath, err), Remedy: renew})
+			continue
+		}
+		leaf, err := x509.ParseCertificate(pair.Certificate[0])
+		if err != nil {
+			d.add(Finding{Check: check, Severity: SeverityError, Message: fmt.Sprintf("cannot parse %s: %v", c.certPath, err), Remedy: renew})
+			continue
+		}
+		chain := intermediates.Clone()
+		for _, der := range pair.Certificate[1:] {
+			if ic, err := x509.ParseCertificate(der); err == nil {
+				chain.AddCert(ic)
+			}
+		}
+		if _, err := leaf.Verify(x509.VerifyOptions{Roots: roots, Intermediates: chain, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}}); err != nil {
+			d.add(Finding{Check: check, Severity: SeverityError, Message: fmt.Sprintf("%s is not issued by the cluster CA: %v", c.certPath, err), Remedy: renew})
+			continue
+		}
+		if !d.checkExpiry(check, leaf, renew) {
+			continue
+		}
+
+		if info, err := os.Stat(c.keyPath); err == nil && info.Mode().Perm()&0o077 != 0 {
+			keyPath := c.keyPath
+			d.add(Finding{Check: check, Severity: SeverityWarn, Message: fmt.Sprintf("%s is readable by other users (mode %s)", keyPath, info.Mode().Perm()),
+				Remedy: "chmod 600 " + keyPath, Fixable: true,
+				fix: func() error { return os.Chmod(keyPath, 0o600) }})
+			continue
+		}
+		d.ok(check, "%s is valid until %s", c.certPath, leaf.NotAfter.Local().Format(time.DateTime))
+	}
+}
+
+// checkExpiry reports a certificate that expired or expires soon, and
+// returns whether it is still valid.
+func (d *doctor) checkExpiry(check string, c *x509.Certificate, remedy string) bool {
+	left := time.Until(c.NotAfter)
+	switch {
+	case left <= 0:
+		d.add(Finding{Check: check, Severity: SeverityError, Message: fmt.Sprintf("%s expired on %s", c.Subject.CommonName, c.NotAfter.Local().Format(time.DateTime)), Remedy: remedy})
+		return false
+	case left < certExpiryWarning:
+		d.add(Finding{Check: check, Severity: SeverityWarn, Message: fmt.Sprintf("%s expires on %s", c.Subject.CommonName, c.NotAfter.Local().Format(time.DateTime)), Remedy: remedy})
+	}
+	return true
+}
+
+// checkLive compares the nodes of the database with the LXD cluster members,
+// and checks that Ceph is healthy and this node is in the OVN cluster.
+func (d *doctor) checkLive(ctx context.Context) {
+	members, err := lxd.ClusterMembers(ctx)
+	if err != nil {
+		d.add(Finding{Check: "lxd", Severity: SeverityError, Message: err.Error(), Remedy: "check snap services lxd and journalctl -u snap.lxd.daemon"})
+	} else if d.db != nil && d.st != nil {
+		d.checkMembers(ctx, members)
+	} else {
+		d.ok("lxd", "%d cluster members", len(members))
+	}
+
+	switch status, err := microceph.Status(); {
+	case err != nil:
+		d.add(Finding{Check: "ceph", Severity: SeverityError, Message: err.Error(), Remedy: "check snap services microceph"})
+	case status.Health != microceph.HealthOK:
+		d.add(Finding{Check: "ceph", Severity: SeverityWarn, Message: fmt.Sprintf("%s: %s", status.Health, strings.Join(status.Checks, "; ")),
+			Remedy: "see microceph.ceph health detail"})
+	default:
+		d.ok("ceph", "HEALTH_OK, %d/%d OSDs up", status.OSDsUp, status.OSDCount)
+	}
+
+	if _, err := microovn.Status(ctx); err != nil {
+		d.add(Finding{Check: "ovn", Severity: SeverityError, Message: err.Error(), Remedy: "check snap services microovn"})
+	} else {
+		d.ok("ovn", "this node is in the MicroOVN cluster")
+	}
+}
+
+func (d *doctor) checkMembers(ctx context.Context, members []lxd.ClusterMember) {
+	nodes, err := database.NewNodeRepository(d.db).ListByCluster(ctx, d.st.Cluster.ID, database.ListOptions{})
+	if err != nil {
+		d.add(Finding{Check: "lxd", Severity: SeverityError, Message: fmt.Sprintf("cannot list nodes: %v", err)})
+		return
+	}
+	inLXD := make(map[string]lxd.ClusterMember, len(members))
+	for _, m := range members {
+		inLXD[m.ServerName] = m
+	}
+	inDB := make(map[string]bool, len(nodes))
+	problems := 0
+	for _, n := range nodes {
+		inDB[n.Hostname] = true
+		m, ok := inLXD[n.Hostname]
+		switch {
+		case !ok:
+			problems++
+			d.add(Finding{Check: "lxd", Severity: SeverityError, Message: fmt.Sprintf("node %s is not an LXD cluster member", n.Hostname),
+				Remedy: "re-join it with mcloudctl rejoin, or remove it with mcloudctl node remove " + n.Hostname})
+		case m.Status != "Online" && m.Status != "Evacuated":
+			problems++
+			d.add(Finding{Check: "lxd", Severity: SeverityWarn, Message: fmt.Sprintf("member %s is %s: %s", m.ServerName, m.Status, m.Message),
+				Remedy: "check lxd on " + m.ServerName})
+		}
+	}
+	for _, m := range members {
+		if !inDB[m.ServerName] {
+			problems++
+			d.add(Finding{Check: "lxd", Severity: SeverityWarn, Message: fmt.Sprintf("LXD member %s is not a node of the cluster", m.ServerName),
+				Remedy: "join it with mcloudctl join, or remove it with lxc cluster remove " + m.ServerName})
+		}
+	}
+	if problems == 0 {
+		d.ok("lxd", "%d cluster members match the nodes", len(members))
+	}
+}
@@ -0,0 +1,110 @@
+package doctor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"mcloud/internal/cert"
+	"mcloud/internal/config"
+	"mcloud/internal/database"
+	"mcloud/internal/state"
+)
+
+// finding returns the first finding of a check, failing the test without one.
+func finding(t *testing.T, r *Report, check string) Finding {
+	t.Helper()
+	for _, f := range r.Findings {
+		if f.Check == check {
+			return f
+		}
+	}
+	t.Fatalf("no %s finding in %+v", check, r.Findings)
+	return Finding{}
+}
+
+func TestState(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.Config{StatePath: filepath.Join(dir, "state.yaml"), Database: config.Database{DBPath: filepath.Join(dir, "mcloud.db")}}
+	ctx := context.Background()
+
+	// A state.yaml without its database
+	st := state.State{Node: state.Node{ID: "n1", Hostname: "node1", Role: "leader"}, Cluster: state.Cluster{ID: "c1", Name: "prod"}, Flags: state.Flags{Initialized: true}}
+	if err := state.SaveState(cfg.StatePath, st); err != nil {
+		t.Fatal(err)
+	}
+	if f := finding(t, Run(ctx, cfg, Options{}), "state"); f.Severity != SeverityError {
+		t.Fatalf("state without database: got %+v", f)
+	}
+
+	db, err := database.Connect(cfg.Database.DBPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close(db)
+	if f := finding(t, Run(ctx, cfg, Options{}), "state"); f.Severity != SeverityError || f.Fixable {
+		t.Fatalf("initialized without cluster row: got %+v", f)
+	}
+
+	if _, err := db.Exec(`INSERT INTO clusters (id, name, state) VALUES ('c1', 'production', 'active')`); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.NewNodeRepository(db).Create(ctx, &database.Node{ID: "n1", ClusterID: "c1", Hostname: "node1", IP: "10.0.0.1", Role: "leader", Status: "online"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// state.yaml lost its flag and has an old cluster name: rewritten by --fix
+	st.Flags.Initialized = false
+	if err := state.SaveState(cfg.StatePath, st); err != nil {
+		t.Fatal(err)
+	}
+	r := Run(ctx, cfg, Options{Fix: true})
+	if f := finding(t, r, "state"); !f.Fixed {
+		t.Fatalf("fix: got %+v", f)
+	}
+	got, err := state.LoadState(cfg.StatePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Flags.Initialized || got.Cluster.Name != "production" || got.Node.IP != "10.0.0.1" {
+		t.Fatalf("rewritten state: got %+v", got)
+	}
+	if f := finding(t, Run(ctx, cfg, Options{}), "state"); f.Severity != SeverityOK {
+		t.Fatalf("after fix: got %+v", f)
+	}
+	if f := finding(t, r, "database"); f.Severity != SeverityOK {
+		t.Fatalf("database: got %+v", f)
+	}
+}
+
+func TestCerts(t *testing.T) {
+	dir := t.TempDir()
+	cfg := &config.Config{
+		StatePath: filepath.Join(dir, "state.yaml"),
+		Database:  config.Database{DBPath: filepath.Join(dir, "mcloud.db")},
+		Security: config.Security{
+			CACertPath:     filepath.Join(dir, "ca.crt"),
+			ClientCertPath: filepath.Join(dir, "client.crt"),
+			ClientKeyPath:  filepath.Join(dir, "client.key"),
+		},
+	}
+	ca, caKey, err := cert.GenerateCAV2(cfg.Security.CACertPath, filepath.Join(dir, "ca.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cert.GenerateClientCert(ca, caKey, "mcloud-admin", cfg.Security.ClientCertPath, cfg.Security.ClientKeyPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(cfg.Security.ClientKeyPath, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := Run(context.Background(), cfg, Options{Fix: true})
+	if f := finding(t, r, "cert.client"); !f.Fixed || r.Warnings != 0 || r.Errors != 0 {
+		t.Fatalf("key mode: got %+v, report %+v", f, r)
+	}
+	if info, err := os.Stat(cfg.Security.ClientKeyPath); err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("key mode after fix: %v %v", info.Mode(), err)
+	}
+}
@@ -0,0 +1,191 @@
+package event
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"mcloud/internal/audit"
+	"mcloud/internal/database"
+	"mcloud/pkg/reason"
+	"mcloud/pkg/utils"
+)
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 500
+
+	// pollInterval is how often the stream checks the database for new events.
+	// Events are written by every manager and background job, so polling the
+	// shared table is the one place that sees all of them.
+	pollInterval = time.Second
+
+	// keepAliveInterval keeps idle streams open through proxies and load balancers.
+	keepAliveInterval = 15 * time.Second
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(s *Service) *Handler {
+	return &Handler{service: s}
+}
+
+// ListEvents handles GET /events?limit=50&offset=0. Optional filters turn it into a search:
+//   q        full-text query over message and type, e.g. q=ceph+degraded
+//   cluster  cluster ID
+//   node     node ID or hostname
+//   type     event type, e.g. node.drained, or a family of them, e.g. node.*
+//   severity minimum severity: info, warning, error or critical
+//   since    Go duration (relative to now) or RFC 3339 time; until likewise
+func (h *Handler) ListEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	query := r.URL.Query()
+
+	limit := defaultListLimit
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			reason.HTTPErrorf(w, 400, "invalid limit")
+			return
+		}
+		limit = min(n, maxListLimit)
+	}
+	offset := 0
+	if v := query.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			reason.HTTPErrorf(w, 400, "invalid offset")
+			return
+		}
+		offset = n
+	}
+
+	filter := database.EventFilter{
+		Query:     query.Get("q"),
+		ClusterID: query.Get("cluster"),
+		NodeID:    query.Get("node"),
+		Type:      query.Get("type"),
+		Limit:     limit,
+		Offset:    offset,
+	}
+	if v := query.Get("severity"); v != "" {
+		if database.SeveritiesFrom(v) == nil {
+			reason.HTTPErrorf(w, 400, "invalid severity")
+			return
+		}
+		filter.Severity = v
+	}
+	var err error
+	if filter.Since, err = audit.ParseSince(query.Get("since")); err != nil {
+		reason.HTTPErrorf(w, 400, "invalid since")
+		return
+	}
+	if filter.Until, err = audit.ParseSince(query.Get("until")); err != nil {
+		reason.HTTPErrorf(w, 400, "invalid until")
+		return
+	}
+
+	// Rows are encoded as they are read, so large limits don't buffer the whole list
+	stream := utils.NewJSONStream(w, "")
+	write := func(e *database.Event) error { return stream.Write(e) }
+	if filter != (database.EventFilter{Limit: limit}) {
+		err = h.service.Search(r.Context(), filter, write)
+	} else {
+		err = h.service.EachRecent(r.Context(), limit, write)
+	}
+	if err != nil {
+		if !stream.Started() {
+			reason.HTTPError(w, err, 500)
+		}
+		return
+	}
+	stream.Close(nil)
+}
+
+// StreamEvents pushes new events as Server-Sent Events until the client disconnects.
+// Clients resuming after a disconnect send Last-Event-ID and receive every event
+// after it; new clients only receive events created after they connected.
+func (h *Handler) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	ctx := r.Context()
+
+	var lastID int64
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id < 0 {
+			reason.HTTPErrorf(w, 400, "invalid Last-Event-ID")
+			return
+		}
+		lastID = id
+	} else {
+		id, err := h.service.LatestID(ctx)
+		if err != nil {
+			reason.HTTPError(w, err, 500)
+			return
+		}
+		lastID = id
+	}
+
+	// The server's write timeout is meant for regular requests, not streams
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		reason.HTTPError(w, err, 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	poll := time.NewTicker(pollInterval)
+	defer poll.Stop()
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case <-poll.C:
+			events, err := h.service.ListAfter(ctx, lastID)
+			if err != nil {
+				return
+			}
+			for _, e := range events {
+				data, err := json.Marshal(e)
+				if err != nil {
+					return
+				}
+				if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data); err != nil {
+					return
+				}
+				lastID = e.ID
+			}
+			if len(events) == 0 {
+				continue
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,11 @@
+package event
+
+import (
+	"net/http"
+)
+
+// InitModule registers the event routes served by handler.
+func InitModule(mux *http.ServeMux, handler *Handler) {
+	mux.HandleFunc("/events", handler.ListEvents)
+	mux.HandleFunc("/events/stream", handler.StreamEvents)
+}
@@ -0,0 +1,84 @@
+// Package event exposes the cluster event log, both as a list and as a live
+// Server-Sent Events stream.
+package event
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"mcloud/internal/database"
+)
+
+// streamBatchSize is the maximum number of events read per poll while streaming.
+const streamBatchSize = 100
+
+type Service struct {
+	db *sql.DB
+}
+
+func NewService(db *sql.DB) *Service {
+	return &Service{db: db}
+}
+
+// EachRecent calls fn for the newest events, newest first.
+func (s *Service) EachRecent(ctx context.Context, limit int, fn func(*database.Event) error) error {
+	return database.NewEventRepository(s.db).EachRecent(ctx, limit, fn)
+}
+
+// ListAfter returns up to streamBatchSize events newer than afterID, oldest first.
+func (s *Service) ListAfter(ctx context.Context, afterID int64) ([]database.Event, error) {
+	return database.NewEventRepository(s.db).ListAfter(ctx, afterID, streamBatchSize)
+}
+
+// LatestID returns the ID of the newest event, so a stream can start with new events only.
+func (s *Service) LatestID(ctx context.Context) (int64, error) {
+	return database.NewEventRepository(s.db).LatestID(ctx)
+}
+
+// Search calls fn for events matching a full-text query and field filters, newest first.
+// f.Query is plain words, all of which must appear in the message or type; a word
+// ending in * matches as a prefix. f.NodeID may also be a node's hostname.
+//
+// Example Input:
+//   Search(ctx, database.EventFilter{Query: "ceph degrad*", Since: time.Now().Add(-24 * time.Hour), Limit: 50}, fn)
+//
+// Example Output:
+//   fn(&database.Event{ID: 812, Type: "ceph.health", Message: "Ceph health changed to HEALTH_WARN: 1 pg degraded"})
+func (s *Service) Search(ctx context.Context, f database.EventFilter, fn func(*database.Event) error) error {
+	f.Query = ftsQuery(f.Query)
+
+	if f.NodeID != "" {
+		node, err := database.NewNodeRepository(s.db).GetByHostname(ctx, f.NodeID)
+		if err == nil {
+			f.NodeID = node.ID
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+	}
+
+	return database.NewEventRepository(s.db).SearchEach(ctx, f, fn)
+}
+
+// ftsQuery turns user input into an FTS5 expression that cannot be a syntax
+// error: every word is quoted, so operators and punctuation match literally.
+//
+// Example:
+//   ceph "degraded" osd.1*  ->  "ceph" "degraded" "osd.1"*
+func ftsQuery(q string) string {
+	var terms []string
+	for _, word := range strings.Fields(q) {
+		prefix := strings.HasSuffix(word, "*")
+		word = strings.Trim(word, `*"`)
+		if word == "" {
+			continue
+		}
+		term := `"` + strings.ReplaceAll(word, `"`, `""`) + `"`
+		if prefix {
+			term += "*"
+		}
+		terms = append(terms, term)
+	}
+	return strings.Join(terms, " ")
+}
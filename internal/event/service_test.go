@@ -0,0 +1,32 @@
+package event
+
+import "testing"
+
+func TestFTSQuery(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"empty", "", ""},
+		{"only spaces", "   \t ", ""},
+		{"single word", "ceph", `"ceph"`},
+		{"several words", "ceph  degraded", `"ceph" "degraded"`},
+		{"quoted word", `"degraded"`, `"degraded"`},
+		{"prefix", "osd.1*", `"osd.1"*`},
+		{"prefix inside quotes", `"osd*"`, `"osd"`},
+		{"lone star", "*", ""},
+		{"lone quotes", `"" ceph`, `"ceph"`},
+		{"operators match literally", "ceph OR NOT node-1", `"ceph" "OR" "NOT" "node-1"`},
+		{"inner quote escaped", `it"s`, `"it""s"`},
+		{"parentheses", "(lxd)", `"(lxd)"`},
+		{"example from doc", `ceph "degraded" osd.1*`, `"ceph" "degraded" "osd.1"*`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ftsQuery(tt.in); got != tt.want {
+				t.Errorf("ftsQuery(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
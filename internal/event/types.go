@@ -0,0 +1,191 @@
+package event
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"mcloud/internal/database"
+)
+
+// Event types, named <subject>.<what happened>. The API and CLI filter on a
+// type or on a family of them, e.g. type=node.* (see database.EventFilter).
+const (
+	NodeJoined               = "node.joined"
+	NodeOnline               = "node.online"  // its agent connected
+	NodeOffline              = "node.offline" // its agent disconnected
+	NodeDrainStarted         = "node.drain_started"
+	NodeDrained              = "node.drained"
+	NodeDrainFailed          = "node.drain_failed"
+	NodeRestored             = "node.restored"
+	NodeRemoveStarted        = "node.remove_started"
+	NodeRemoved              = "node.removed"
+	NodeRemoveFailed         = "node.remove_failed"
+	NodeRejoinFailed         = "node.rejoin_failed"
+	NodeConditionSet         = "node.condition_set"
+	NodeConditionCleared     = "node.condition_cleared"
+	NodeClockSkew            = "node.clock_skew"
+	NodeClockUnsynchronized  = "node.clock_unsynchronized"
+	NodeTimeSyncConfigured   = "node.timesync_configured"
+	WorkloadCreated          = "workload.created"
+	WorkloadStarted          = "workload.started"
+	WorkloadStopped          = "workload.stopped"
+	WorkloadFailed           = "workload.failed"
+	WorkloadPreempted        = "workload.preempted"
+	WorkloadResumed          = "workload.resumed"
+	WorkloadScheduledStart   = "workload.scheduled_start"
+	WorkloadScheduledStop    = "workload.scheduled_stop"
+	WorkloadScheduleSkipped  = "workload.schedule_skipped"
+	WorkloadScheduleFailed   = "workload.schedule_failed"
+	WorkloadHookFailed       = "workload.hook.failed"
+	WorkloadHookDeadLettered = "workload.hook.dead_lettered"
+	ImageBuildStarted        = "image.build.started"
+	ImageBuildCompleted      = "image.build.completed"
+	ImageBuildFailed         = "image.build.failed"
+	ImageImportStarted       = "image.import.started"
+	ImageImportProgress      = "image.import.progress"
+	ImageImportCompleted     = "image.import.completed"
+	ImageImportFailed        = "image.import.failed"
+	MaintenanceStarted       = "maintenance.started"
+	MaintenanceCompleted     = "maintenance.completed"
+	MaintenanceDeferred      = "maintenance.deferred"
+	MaintenanceFailed        = "maintenance.failed"
+	CertRenewed              = "cert.renewed"
+	AlertFired               = "alert.fired" // with the severity of the alert
+	AlertResolved            = "alert.resolved"
+	DriftHealed              = "drift.healed" // other drift.* types are reported drift (see package reconcile)
+)
+
+// severities is the severity of every type that is not info.
+var severities = map[string]string{
+	NodeOffline:              database.SeverityWarning,
+	NodeDrainFailed:          database.SeverityError,
+	NodeRemoveFailed:         database.SeverityError,
+	NodeRejoinFailed:         database.SeverityWarning,
+	NodeConditionSet:         database.SeverityWarning,
+	NodeClockSkew:            database.SeverityWarning,
+	NodeClockUnsynchronized:  database.SeverityWarning,
+	WorkloadFailed:           database.SeverityError,
+	WorkloadPreempted:        database.SeverityWarning,
+	WorkloadScheduleFailed:   database.SeverityWarning,
+	WorkloadHookFailed:       database.SeverityWarning,
+	WorkloadHookDeadLettered: database.SeverityError,
+	ImageBuildFailed:         database.SeverityError,
+	ImageImportFailed:        database.SeverityError,
+	MaintenanceDeferred:      database.SeverityWarning,
+	MaintenanceFailed:        database.SeverityError,
+}
+
+// SeverityOf returns the severity of an event type: from the taxonomy above,
+// warning for reported drift, info for anything else.
+func SeverityOf(eventType string) string {
+	if s, ok := severities[eventType]; ok {
+		return s
+	}
+	if strings.HasPrefix(eventType, "drift.") && eventType != DriftHealed {
+		return database.SeverityWarning
+	}
+	return database.SeverityInfo
+}
+
+// New returns an event of eventType with the severity of its type. payload,
+// unless nil, is stored as the event's JSON payload.
+//
+// Example Input:
+//   New(WorkloadFailed, "Workload web-1 is failed (was running): exited", map[string]string{"workload": "web-1"})
+//
+// Example Output:
+//   &database.Event{Type: "workload.failed", Severity: "error", Message: "...", Payload: `{"workload":"web-1"}`}
+func New(eventType string, message string, payload any) *database.Event {
+	e := &database.Event{Type: eventType, Severity: SeverityOf(eventType), Message: message}
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err == nil {
+			e.Payload = data
+		}
+	}
+	return e
+}
+
+// ForNode returns New for an event about node n.
+func ForNode(n *database.Node, eventType string, message string, payload any) *database.Event {
+	e := New(eventType, message, payload)
+	e.ClusterID, e.NodeID = &n.ClusterID, &n.ID
+	return e
+}
+
+// NodePayload is the payload of node.joined, node.online and node.offline.
+type NodePayload struct {
+	Node string `json:"node"`
+	IP   string `json:"ip,omitempty"`
+	Role string `json:"role,omitempty"`
+}
+
+// NodeJoinedEvent records that n joined its cluster.
+func NodeJoinedEvent(n *database.Node) *database.Event {
+	return ForNode(n, NodeJoined, fmt.Sprintf("Node %s (%s) joined the cluster as %s", n.Hostname, n.IP, n.Role),
+		NodePayload{Node: n.Hostname, IP: n.IP, Role: n.Role})
+}
+
+// NodeOnlineEvent records that the agent of n connected.
+func NodeOnlineEvent(n *database.Node) *database.Event {
+	return ForNode(n, NodeOnline, fmt.Sprintf("Node %s is online", n.Hostname), NodePayload{Node: n.Hostname})
+}
+
+// NodeOfflineEvent records that the agent of n disconnected.
+func NodeOfflineEvent(n *database.Node) *database.Event {
+	return ForNode(n, NodeOffline, fmt.Sprintf("Node %s is offline: its agent disconnected", n.Hostname), NodePayload{Node: n.Hostname})
+}
+
+// WorkloadPayload is the payload of workload events.
+type WorkloadPayload struct {
+	Workload string `json:"workload"`
+	ID       string `json:"id"`
+	Kind     string `json:"kind,omitempty"`
+	Image    string `json:"image,omitempty"`
+	Status   string `json:"status,omitempty"`
+	Previous string `json:"previous,omitempty"` // status before a status change
+}
+
+// WorkloadCreatedEvent records that w was created.
+func WorkloadCreatedEvent(w *database.Workload) *database.Event {
+	e := New(WorkloadCreated, fmt.Sprintf("Workload %s created (%s from %s)", w.Name, w.Kind, w.Image),
+		WorkloadPayload{Workload: w.Name, ID: w.ID, Kind: w.Kind, Image: w.Image})
+	e.ClusterID, e.NodeID = &w.ClusterID, w.NodeID
+	return e
+}
+
+// CertPayload is the payload of cert.renewed.
+type CertPayload struct {
+	Certificate string    `json:"certificate"`
+	Serial      string    `json:"serial"`
+	NotAfter    time.Time `json:"not_after"`
+}
+
+// CertRenewedEvent records that the certificate id was renewed.
+func CertRenewedEvent(id string, serial string, notAfter time.Time) *database.Event {
+	return New(CertRenewed, fmt.Sprintf("Certificate %s renewed (serial %s, valid until %s)", id, serial, notAfter.UTC().Format(time.DateTime)),
+		CertPayload{Certificate: id, Serial: serial, NotAfter: notAfter})
+}
+
+// AlertPayload is the payload of alert.fired and alert.resolved.
+type AlertPayload struct {
+	Alert   int64  `json:"alert"`
+	Rule    string `json:"rule"`
+	Subject string `json:"subject"`
+}
+
+// AlertFiredEvent records that alert a started firing.
+func AlertFiredEvent(a *database.Alert) *database.Event {
+	e := New(AlertFired, fmt.Sprintf("Alert %q fired for %s: %s", a.Rule, a.Subject, a.Message),
+		AlertPayload{Alert: a.ID, Rule: a.Rule, Subject: a.Subject})
+	e.Severity = a.Severity
+	return e
+}
+
+// AlertResolvedEvent records that alert a stopped firing.
+func AlertResolvedEvent(a *database.Alert) *database.Event {
+	return New(AlertResolved, fmt.Sprintf("Alert %q resolved for %s", a.Rule, a.Subject),
+		AlertPayload{Alert: a.ID, Rule: a.Rule, Subject: a.Subject})
+}
@@ -0,0 +1,76 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"mcloud/internal/database"
+)
+
+func TestSeverityOf(t *testing.T) {
+	tests := map[string]string{
+		NodeJoined:             database.SeverityInfo,
+		NodeOffline:            database.SeverityWarning,
+		WorkloadFailed:         database.SeverityError,
+		"drift.lxd_instance":   database.SeverityWarning,
+		DriftHealed:            database.SeverityInfo,
+		"something.unheard_of": database.SeverityInfo,
+	}
+	for eventType, want := range tests {
+		if got := SeverityOf(eventType); got != want {
+			t.Errorf("SeverityOf(%q) = %q, want %q", eventType, got, want)
+		}
+	}
+}
+
+func TestFilterByTypeAndSeverity(t *testing.T) {
+	ctx := context.Background()
+	db, err := database.Connect(filepath.Join(t.TempDir(), "mcloud.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close(db)
+
+	repo := database.NewEventRepository(db)
+	for _, e := range []*database.Event{
+		New(NodeOnline, "Node node-1 is online", NodePayload{Node: "node-1"}),
+		New(NodeOffline, "Node node-1 is offline", NodePayload{Node: "node-1"}),
+		New(WorkloadFailed, "Workload web-1 is failed", nil),
+		{Type: "custom", Message: "no severity given"},
+	} {
+		if err := repo.Create(ctx, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	search := func(f database.EventFilter) []database.Event {
+		t.Helper()
+		f.Limit = 10
+		events, err := repo.Search(ctx, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return events
+	}
+
+	node := search(database.EventFilter{Type: "node.*"})
+	if len(node) != 2 {
+		t.Fatalf("type node.*: got %d events, want 2", len(node))
+	}
+	var payload NodePayload
+	if err := json.Unmarshal(node[1].Payload, &payload); err != nil || payload.Node != "node-1" {
+		t.Errorf("payload of %s: got %s, %v", node[1].Type, node[1].Payload, err)
+	}
+
+	if got := search(database.EventFilter{Severity: database.SeverityWarning}); len(got) != 2 {
+		t.Errorf("severity warning: got %d events, want 2", len(got))
+	}
+	if got := search(database.EventFilter{Type: "node.*", Severity: database.SeverityError}); len(got) != 0 {
+		t.Errorf("type node.* and severity error: got %d events, want 0", len(got))
+	}
+	if got := search(database.EventFilter{Type: "custom"}); len(got) != 1 || got[0].Severity != database.SeverityInfo {
+		t.Errorf("type custom: got %+v", got)
+	}
+}
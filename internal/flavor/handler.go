@@ -0,0 +1,86 @@
+package flavor
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"mcloud/internal/auth"
+	"mcloud/pkg/reason"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(s *Service) *Handler {
+	return &Handler{service: s}
+}
+
+func (h *Handler) CreateFlavor(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req CreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		reason.HTTPError(w, err, 400)
+		return
+	}
+
+	actor := auth.ClientIdentity(r)
+	f, err := h.service.Create(r.Context(), &req, &actor)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidFlavor):
+			reason.HTTPError(w, err, 400)
+		case errors.Is(err, ErrFlavorExists):
+			reason.HTTPError(w, err, 409)
+		default:
+			reason.HTTPError(w, err, 500)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(f)
+}
+
+func (h *Handler) ListFlavors(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	flavors, err := h.service.List(r.Context())
+	if err != nil {
+		reason.HTTPError(w, err, 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(flavors)
+}
+
+func (h *Handler) DeleteFlavor(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := h.service.Delete(r.Context(), r.PathValue("name")); err != nil {
+		switch {
+		case errors.Is(err, ErrFlavorNotFound):
+			reason.HTTPError(w, err, 404)
+		case errors.Is(err, ErrFlavorInUse):
+			reason.HTTPError(w, err, 409)
+		default:
+			reason.HTTPError(w, err, 500)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,12 @@
+package flavor
+
+import (
+	"net/http"
+)
+
+// InitModule registers the flavor routes served by handler.
+func InitModule(mux *http.ServeMux, handler *Handler) {
+	mux.HandleFunc("GET /flavors", handler.ListFlavors)
+	mux.HandleFunc("POST /flavors", handler.CreateFlavor)
+	mux.HandleFunc("DELETE /flavors/{name}", handler.DeleteFlavor)
+}
@@ -0,0 +1,148 @@
+// Package flavor manages flavors: named workload sizes (CPUs, memory, root
+// disk) stored in the database. A workload launched with a flavor gets the
+// LXD profile flavor-<name>, which carries the flavor's limits, so every
+// workload of a flavor is sized the same.
+package flavor
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"mcloud/internal/database"
+	"mcloud/pkg/reason"
+	"mcloud/services/lxd"
+)
+
+var (
+	ErrFlavorNotFound = reason.New(reason.FlavorNotFound, "flavor not found")
+	ErrFlavorExists   = reason.New(reason.FlavorExists, "a flavor with this name already exists")
+	ErrFlavorInUse    = reason.New(reason.FlavorInUse, "flavor is used by workloads")
+	ErrInvalidFlavor  = reason.New(reason.InvalidFlavor, "invalid flavor")
+)
+
+var nameRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]*$`)
+
+type Service struct {
+	db *sql.DB
+}
+
+// CreateRequest defines a new flavor.
+//
+// Example JSON:
+//   {"name": "xlarge", "cpus": 8, "memory_mb": 16384, "disk_gb": 80}
+type CreateRequest struct {
+	Name     string `json:"name"`
+	CPUs     int    `json:"cpus"`
+	MemoryMB int    `json:"memory_mb"`
+	DiskGB   int    `json:"disk_gb"`
+}
+
+func NewService(db *sql.DB) *Service {
+	return &Service{db: db}
+}
+
+// ProfileName is the LXD profile of a flavor, e.g. flavor-small.
+func ProfileName(name string) string {
+	return "flavor-" + name
+}
+
+// Profile returns the LXD profile of a flavor. Its root disk is on the default
+// profile's pool; launches on another pool set the size on the instance.
+//
+// Example Output:
+//   lxd.ProfileConfig{Name: "flavor-small", Config: {"limits.cpu": "1", "limits.memory": "1024MiB"}, RootPool: "remote", RootSize: "10GiB"}
+func Profile(f *database.Flavor, pool string) lxd.ProfileConfig {
+	return lxd.ProfileConfig{
+		Name:        ProfileName(f.Name),
+		Description: fmt.Sprintf("mcloud flavor %s", f.Name),
+		Config: map[string]string{
+			"limits.cpu":    strconv.Itoa(f.CPUs),
+			"limits.memory": fmt.Sprintf("%dMiB", f.MemoryMB),
+		},
+		RootPool: pool,
+		RootSize: RootSize(f),
+	}
+}
+
+// RootSize is the root disk size of a flavor in LXD notation, e.g. 10GiB.
+func RootSize(f *database.Flavor) string {
+	return fmt.Sprintf("%dGiB", f.DiskGB)
+}
+
+// Create records a new flavor. Its LXD profile is created when the first
+// workload is launched with it.
+//
+// Example Output (Error):
+//   {"name": "small", ...}  =>  ErrFlavorExists: small
+//   {"cpus": 0, ...}        =>  ErrInvalidFlavor: cpus, memory_mb and disk_gb must be positive
+func (s *Service) Create(ctx context.Context, req *CreateRequest, actor *string) (*database.Flavor, error) {
+	if !nameRegexp.MatchString(req.Name) {
+		return nil, fmt.Errorf("%w: name must match [a-z0-9][a-z0-9.-]*", ErrInvalidFlavor)
+	}
+	if req.CPUs <= 0 || req.MemoryMB <= 0 || req.DiskGB <= 0 {
+		return nil, fmt.Errorf("%w: cpus, memory_mb and disk_gb must be positive", ErrInvalidFlavor)
+	}
+
+	repo := database.NewFlavorRepository(s.db)
+	if _, err := repo.Get(ctx, req.Name); err == nil {
+		return nil, fmt.Errorf("%w: %s", ErrFlavorExists, req.Name)
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	f := &database.Flavor{Name: req.Name, CPUs: req.CPUs, MemoryMB: req.MemoryMB, DiskGB: req.DiskGB, CreateUserID: actor}
+	if err := repo.Create(ctx, f); err != nil {
+		return nil, err
+	}
+	return repo.Get(ctx, f.Name)
+}
+
+// List returns all flavors, smallest first.
+func (s *Service) List(ctx context.Context) ([]database.Flavor, error) {
+	flavors, err := database.NewFlavorRepository(s.db).List(ctx)
+	if flavors == nil {
+		flavors = []database.Flavor{}
+	}
+	return flavors, err
+}
+
+// Get returns a flavor.
+func (s *Service) Get(ctx context.Context, name string) (*database.Flavor, error) {
+	f, err := database.NewFlavorRepository(s.db).Get(ctx, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrFlavorNotFound, name)
+	}
+	return f, err
+}
+
+// Delete removes a flavor that no workload uses, and its LXD profile in
+// every project.
+func (s *Service) Delete(ctx context.Context, name string) error {
+	if _, err := s.Get(ctx, name); err != nil {
+		return err
+	}
+	inUse, err := database.NewWorkloadRepository(s.db).CountByFlavor(ctx, name)
+	if err != nil {
+		return err
+	}
+	if inUse > 0 {
+		return fmt.Errorf("%w: %s is used by %d workloads", ErrFlavorInUse, name, inUse)
+	}
+
+	// Launches apply the profile in the workload's project, so every project
+	// may have a copy
+	projects, err := database.NewProjectRepository(s.db).List(ctx)
+	if err != nil {
+		return err
+	}
+	for _, p := range projects {
+		if err := lxd.DeleteProfile(lxd.WithProject(ctx, p.Name), ProfileName(name)); err != nil {
+			return err
+		}
+	}
+	return database.NewFlavorRepository(s.db).Delete(ctx, name)
+}
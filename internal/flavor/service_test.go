@@ -0,0 +1,62 @@
+package flavor
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"mcloud/internal/database"
+)
+
+func TestCreateAndList(t *testing.T) {
+	db, err := database.Connect(filepath.Join(t.TempDir(), "mcloud.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close(db)
+	s := NewService(db)
+	ctx := context.Background()
+
+	// Seeded by the migration
+	flavors, err := s.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range flavors {
+		names = append(names, f.Name)
+	}
+	if len(names) != 3 || names[0] != "small" || names[1] != "medium" || names[2] != "large" {
+		t.Fatalf("seeded flavors: got %v", names)
+	}
+
+	f, err := s.Create(ctx, &CreateRequest{Name: "xlarge", CPUs: 8, MemoryMB: 16384, DiskGB: 80}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.CPUs != 8 || f.MemoryMB != 16384 || f.DiskGB != 80 {
+		t.Fatalf("created: got %+v", f)
+	}
+
+	if _, err := s.Create(ctx, &CreateRequest{Name: "small", CPUs: 1, MemoryMB: 512, DiskGB: 5}, nil); !errors.Is(err, ErrFlavorExists) {
+		t.Fatalf("duplicate: got %v, want ErrFlavorExists", err)
+	}
+	for _, req := range []CreateRequest{
+		{Name: "Bad Name", CPUs: 1, MemoryMB: 512, DiskGB: 5},
+		{Name: "tiny", CPUs: 0, MemoryMB: 512, DiskGB: 5},
+		{Name: "tiny", CPUs: 1, MemoryMB: 512, DiskGB: -1},
+	} {
+		if _, err := s.Create(ctx, &req, nil); !errors.Is(err, ErrInvalidFlavor) {
+			t.Errorf("%+v: got %v, want ErrInvalidFlavor", req, err)
+		}
+	}
+	if _, err := s.Get(ctx, "tiny"); !errors.Is(err, ErrFlavorNotFound) {
+		t.Fatalf("missing: got %v, want ErrFlavorNotFound", err)
+	}
+
+	p := Profile(f, "remote")
+	if p.Name != "flavor-xlarge" || p.Config["limits.cpu"] != "8" || p.Config["limits.memory"] != "16384MiB" || p.RootSize != "80GiB" || p.RootPool != "remote" {
+		t.Fatalf("profile: got %+v", p)
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"net"
 	"os"
 
+	"mcloud/internal/tracing"
+
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 )
@@ -18,10 +20,13 @@ import (
 //   caCert     - Path to the CA certificate file (PEM format)
 //   serverCert - Path to the server certificate file (PEM format)
 //   serverKey  - Path to the server private key file (PEM format)
+//   register   - Registers the services to serve (e.g., the agent command hub)
+//   checkPeer  - Checks the verified client certificate of each connection,
+//                e.g. that it is not revoked; nil accepts every certificate
 //
 // Returns:
 //   error - If any error occurs during setup or serving
-func StartGRPCServer(addr string, caCert string, serverCert string, serverKey string) error {
+func StartGRPCServer(addr string, caCert string, serverCert string, serverKey string, register func(*grpc.Server), checkPeer func(*x509.Certificate) error) error {
 	// Load the server's certificate and private key
 	cert, _ := tls.LoadX509KeyPair(serverCert, serverKey)
 
@@ -36,6 +41,12 @@ func StartGRPCServer(addr string, caCert string, serverCert string, serverKey st
 		ClientAuth:   tls.RequireAndVerifyClientCert,             // require and verify client certs
 		ClientCAs:    caPool,                                    // trusted CA pool
 	}
+	if checkPeer != nil {
+		// Runs after chain verification, so a refused client fails the handshake
+		tlsConfig.VerifyConnection = func(cs tls.ConnectionState) error {
+			return checkPeer(cs.VerifiedChains[0][0])
+		}
+	}
 
 	// Listen on the specified TCP address
 	lis, err := net.Listen("tcp", addr)
@@ -46,7 +57,9 @@ func StartGRPCServer(addr string, caCert string, serverCert string, serverKey st
 	// Create a new gRPC server with TLS credentials
 	grpcServer := grpc.NewServer(
 		grpc.Creds(credentials.NewTLS(tlsConfig)),
+		tracing.ServerOption(),
 	)
+	register(grpcServer)
 
 	fmt.Println("gRPC server listening on", addr)
 	// Start serving incoming gRPC connections
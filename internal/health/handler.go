@@ -0,0 +1,69 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"mcloud/internal/constant"
+	"mcloud/internal/version"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(s *Service) *Handler {
+	return &Handler{service: s}
+}
+
+// Healthz answers 200 as long as mcloudd is up and serving requests, with the
+// version it runs, which mcloudctl upgrade waits for after a restart.
+func (h *Handler) Healthz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok", "version": constant.AppVersion})
+}
+
+// Version answers the build of mcloudd (see version.Info). Nodes joining the
+// cluster check it before installing their agent.
+func (h *Handler) Version(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(version.Current())
+}
+
+// DatabasePool answers the connection pool statistics of the database: how
+// many connections are open and in use, how often and how long requests waited
+// for one, and how many prepared statements are cached.
+//
+// Example Output:
+//   {"write": {"max_open": 1, "open": 1, "in_use": 0, "idle": 1, "wait_count": 12, "wait_duration_ms": 48, "statements": 31},
+//    "read": {"max_open": 8, "open": 3, "in_use": 1, "idle": 2, "wait_count": 0, "wait_duration_ms": 0, "statements": 57}}
+func (h *Handler) DatabasePool(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.service.DatabasePool())
+}
+
+// Readyz runs the readiness checks and answers 200 when all pass, 503 when
+// any fails, with the result of every check.
+func (h *Handler) Readyz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	report := h.service.Ready(r.Context())
+	w.Header().Set("Content-Type", "application/json")
+	if !report.Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
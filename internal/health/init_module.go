@@ -0,0 +1,13 @@
+package health
+
+import (
+	"net/http"
+)
+
+// InitModule registers the health routes served by handler.
+func InitModule(mux *http.ServeMux, handler *Handler) {
+	mux.HandleFunc("/healthz", handler.Healthz)
+	mux.HandleFunc("/readyz", handler.Readyz)
+	mux.HandleFunc("/version", handler.Version)
+	mux.HandleFunc("GET /database/pool", handler.DatabasePool)
+}
@@ -0,0 +1,120 @@
+// Package health serves mcloudd's liveness (/healthz) and readiness (/readyz)
+// endpoints for the systemd watchdog and external monitors.
+package health
+
+import (
+	"context"
+	"database/sql"
+	"net"
+	"sync"
+	"time"
+
+	"mcloud/internal/database"
+	"mcloud/internal/state"
+	"mcloud/pkg/logger"
+	"mcloud/services/lxd"
+)
+
+var log = logger.Named("health")
+
+// CheckTimeout bounds each readiness check, so a hung dependency fails its
+// check instead of the probe.
+const CheckTimeout = 2 * time.Second
+
+// Readiness checks.
+const (
+	CheckDatabase = "database" // the database answers a query
+	CheckState    = "state"    // the state file can be read and parsed
+	CheckLXD      = "lxd"      // the local LXD socket accepts connections
+)
+
+// Check is the result of one readiness check.
+//
+// Example JSON:
+//   {"name": "lxd", "ok": false, "error": "dial unix /var/snap/lxd/common/lxd/unix.socket: connect: no such file or directory", "duration_ms": 0}
+type Check struct {
+	Name       string `json:"name"`
+	OK         bool   `json:"ok"`
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// Report is the answer of /readyz.
+//
+// Example JSON:
+//   {"ready": true, "checks": [{"name": "database", "ok": true, "duration_ms": 1}, ...]}
+type Report struct {
+	Ready  bool    `json:"ready"`
+	Checks []Check `json:"checks"`
+}
+
+type Service struct {
+	db        *sql.DB
+	state     *state.StateManager
+	lxdSocket string
+}
+
+func NewService(db *sql.DB, st *state.StateManager) *Service {
+	return &Service{db: db, state: st, lxdSocket: lxd.SocketPath}
+}
+
+// Ready runs the readiness checks concurrently; mcloudd is ready when all of
+// them pass.
+func (s *Service) Ready(ctx context.Context) *Report {
+	checks := []struct {
+		name string
+		fn   func(ctx context.Context) error
+	}{
+		{CheckDatabase, s.checkDatabase},
+		{CheckState, s.checkState},
+		{CheckLXD, s.checkLXD},
+	}
+
+	report := &Report{Ready: true, Checks: make([]Check, len(checks))}
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, CheckTimeout)
+			defer cancel()
+			start := time.Now()
+			err := c.fn(ctx)
+			report.Checks[i] = Check{Name: c.name, OK: err == nil, DurationMS: time.Since(start).Milliseconds()}
+			if err != nil {
+				report.Checks[i].Error = err.Error()
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, c := range report.Checks {
+		report.Ready = report.Ready && c.OK
+	}
+	return report
+}
+
+// DatabasePool returns the statistics of the database's write connection and
+// read pool (see database.Connect), e.g. to tell whether writes queue.
+func (s *Service) DatabasePool() database.PoolStats {
+	return database.Stats(s.db)
+}
+
+func (s *Service) checkDatabase(ctx context.Context) error {
+	var one int
+	return s.db.QueryRowContext(ctx, "SELECT 1").Scan(&one)
+}
+
+func (s *Service) checkState(ctx context.Context) error {
+	_, err := s.state.Get()
+	return err
+}
+
+func (s *Service) checkLXD(ctx context.Context) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "unix", s.lxdSocket)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
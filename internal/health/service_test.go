@@ -0,0 +1,55 @@
+package health
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"mcloud/internal/database"
+	"mcloud/internal/state"
+)
+
+func TestReady(t *testing.T) {
+	dir := t.TempDir()
+	db, err := database.Connect(filepath.Join(dir, "mcloud.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close(db)
+
+	statePath := filepath.Join(dir, "state.yaml")
+	s := NewService(db, state.NewStateManager(state.NewFileStore(statePath)))
+	s.lxdSocket = filepath.Join(dir, "lxd.socket")
+	failed := func(r *Report) []string {
+		names := []string{}
+		for _, c := range r.Checks {
+			if !c.OK {
+				names = append(names, c.Name)
+			}
+		}
+		return names
+	}
+
+	if r := s.Ready(context.Background()); r.Ready || len(failed(r)) != 2 {
+		t.Errorf("without state and LXD: got ready %v, failed %v", r.Ready, failed(r))
+	}
+
+	if err := os.WriteFile(statePath, []byte("version: 1.0.0\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	lis, err := net.Listen("unix", s.lxdSocket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+	if r := s.Ready(context.Background()); !r.Ready || len(r.Checks) != 3 {
+		t.Errorf("got ready %v, failed %v", r.Ready, failed(r))
+	}
+
+	database.Close(db)
+	if r := s.Ready(context.Background()); r.Ready || len(failed(r)) != 1 || failed(r)[0] != CheckDatabase {
+		t.Errorf("with the database closed: got ready %v, failed %v", r.Ready, failed(r))
+	}
+}
@@ -0,0 +1,58 @@
+package health
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"mcloud/pkg/sdnotify"
+)
+
+// Watchdog pings the systemd watchdog every half WatchdogSec until ctx is
+// cancelled, as long as the database check passes: a daemon that can no
+// longer query its database is hung and restarting it is the remedy. The
+// state file and LXD checks only show in `systemctl status`, since a restart
+// of mcloudd does not bring LXD back. Without WatchdogSec it returns at once.
+func (s *Service) Watchdog(ctx context.Context) {
+	interval := sdnotify.WatchdogInterval()
+	if interval == 0 {
+		return
+	}
+	log.Info("Pinging the systemd watchdog every %s", interval/2)
+
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		report := s.Ready(ctx)
+		status, alive := "ready", true
+		var failed []string
+		for _, c := range report.Checks {
+			if c.OK {
+				continue
+			}
+			failed = append(failed, c.Name+": "+c.Error)
+			if c.Name == CheckDatabase {
+				alive = false
+			}
+		}
+		if len(failed) > 0 {
+			status = "degraded: " + strings.Join(failed, "; ")
+		}
+		if _, err := sdnotify.Notify(sdnotify.Status(status)); err != nil {
+			log.Warn("Failed to notify systemd: %v", err)
+		}
+		if !alive {
+			log.Error("Withholding the watchdog ping: %s", status)
+			continue
+		}
+		if _, err := sdnotify.Notify(sdnotify.Watchdog); err != nil {
+			log.Warn("Failed to ping the systemd watchdog: %v", err)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package image
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"mcloud/internal/database"
+	"mcloud/internal/event"
+	"mcloud/internal/operation"
+	"mcloud/pkg/reason"
+	"mcloud/pkg/utils"
+	"mcloud/services/lxd"
+)
+
+var (
+	ErrBuildNotFound       = reason.New(reason.ImageBuildNotFound, "image build not found")
+	ErrInvalidBuildRequest = reason.New(reason.InvalidImageBuild, "invalid image build request")
+)
+
+const (
+	BuildStatusPending    = "pending"
+	BuildStatusBuilding   = "building"
+	BuildStatusPublishing = "publishing"
+	BuildStatusCompleted  = "completed"
+	BuildStatusFailed     = "failed"
+)
+
+const (
+	// maxScriptBytes bounds a provisioning script; it is passed to sh -c as one argument
+	maxScriptBytes = 64 << 10
+
+	// maxBuildLogBytes is how much script output is kept on the build record
+	maxBuildLogBytes = 64 << 10
+)
+
+var aliasRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._/-]*$`)
+
+// BuildRequest describes an image build: the base image is launched on the
+// build node, the script provisions it, and the result is published to the
+// cluster image store under Alias (replacing an existing image of that alias).
+//
+// Example JSON:
+//   {"alias": "web-golden", "base_image": "ubuntu:24.04", "node": "node2",
+//    "script": "apt-get update\napt-get install -y nginx\n"}
+type BuildRequest struct {
+	Alias     string `json:"alias"`
+	BaseImage string `json:"base_image"`
+	Script    string `json:"script"`
+	Node      string `json:"node"` // build node ID or hostname
+	VM        bool   `json:"vm"`   // build a virtual machine image instead of a container image
+}
+
+// BuildResult is returned when a build starts; the operation tracks its progress.
+type BuildResult struct {
+	Operation *database.Operation  `json:"operation"`
+	Build     *database.ImageBuild `json:"build"`
+}
+
+func validateBuildRequest(req *BuildRequest) error {
+	if !aliasRegexp.MatchString(req.Alias) {
+		return fmt.Errorf("%w: alias must match %s", ErrInvalidBuildRequest, aliasRegexp)
+	}
+	if req.BaseImage == "" {
+		return fmt.Errorf("%w: base_image is required", ErrInvalidBuildRequest)
+	}
+	if req.Script == "" {
+		return fmt.Errorf("%w: script is required", ErrInvalidBuildRequest)
+	}
+	if len(req.Script) > maxScriptBytes {
+		return fmt.Errorf("%w: script is larger than %d bytes", ErrInvalidBuildRequest, maxScriptBytes)
+	}
+	if req.Node == "" {
+		return fmt.Errorf("%w: node is required", ErrInvalidBuildRequest)
+	}
+	return nil
+}
+
+// Build records an image build and runs it in a background "image.build"
+// operation whose result is the finished build record.
+//
+// Steps of the operation:
+//  1. Launch a temporary builder instance from the base image on the build node
+//  2. Run the script in it with sh -e (any failing command fails the build)
+//  3. Stop the builder and publish it to the image store under the alias, with
+//     mcloud.build_id, mcloud.base_image and mcloud.script_sha256 as image properties
+//  4. Record the fingerprint on the build record and delete the builder
+//
+// Example Output:
+//   &BuildResult{Operation: {ID: "7f1c...", Type: "image.build", Status: "pending"},
+//     Build: {ID: "3e9a...", Alias: "web-golden", Status: "pending", ScriptSHA256: "9f86d0..."}}
+//
+// Example Output (Error):
+//   Node "node9" unknown  =>  ErrInvalidBuildRequest: build node node9 not found
+func (s *Service) Build(ctx context.Context, req *BuildRequest, actor *string) (*BuildResult, error) {
+	if err := validateBuildRequest(req); err != nil {
+		return nil, err
+	}
+
+	node, err := s.buildNode(ctx, req.Node)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256([]byte(req.Script))
+	b := &database.ImageBuild{
+		ID:           utils.GenerateUUID(),
+		Alias:        req.Alias,
+		BaseImage:    req.BaseImage,
+		Script:       req.Script,
+		ScriptSHA256: hex.EncodeToString(sum[:]),
+		VM:           req.VM,
+		NodeID:       node.ID,
+		Status:       BuildStatusPending,
+		CreateUserID: actor,
+	}
+	repo := database.NewImageBuildRepository(s.db)
+	if err := repo.Create(ctx, b); err != nil {
+		return nil, err
+	}
+
+	op, err := s.operations.Start(ctx, "image.build", "/images/builds/"+b.ID, actor, func(ctx context.Context, report operation.Reporter) (any, error) {
+		return s.runBuild(ctx, b, node.Hostname, report)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := repo.SetOperation(ctx, b.ID, op.ID); err != nil {
+		return nil, err
+	}
+
+	build, err := repo.GetByID(ctx, b.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &BuildResult{Operation: op, Build: build}, nil
+}
+
+// buildNode resolves the build node by ID or hostname. It must be online.
+func (s *Service) buildNode(ctx context.Context, ref string) (*database.Node, error) {
+	repo := database.NewNodeRepository(s.db)
+	node, err := repo.GetByID(ctx, ref)
+	if errors.Is(err, sql.ErrNoRows) {
+		node, err = repo.GetByHostname(ctx, ref)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: build node %s not found", ErrInvalidBuildRequest, ref)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if node.Status != "online" {
+		return nil, fmt.Errorf("%w: build node %s is %s", ErrInvalidBuildRequest, node.Hostname, node.Status)
+	}
+	return node, nil
+}
+
+// runBuild is the work of an image.build operation (see Build).
+func (s *Service) runBuild(ctx context.Context, b *database.ImageBuild, nodeHostname string, report operation.Reporter) (*database.ImageBuild, error) {
+	repo := database.NewImageBuildRepository(s.db)
+	builder := "mcloud-build-" + b.ID[:8]
+
+	var output string
+	fail := func(err error) (*database.ImageBuild, error) {
+		// The operation context may be what failed (timeout), so record with a fresh one
+		msg := err.Error()
+		if ferr := repo.Finish(context.Background(), b.ID, BuildStatusFailed, nil, logTail(output), &msg); ferr != nil {
+			log.Error("Failed to update image build %s: %v", b.ID, ferr)
+		}
+		s.event(context.Background(), event.ImageBuildFailed, fmt.Sprintf("Image build %s (%s) failed: %s", b.ID, b.Alias, msg))
+		return nil, err
+	}
+
+	// 1. Launch the builder
+	if err := repo.UpdateStatus(ctx, b.ID, BuildStatusBuilding); err != nil {
+		return fail(err)
+	}
+	s.event(ctx, event.ImageBuildStarted, fmt.Sprintf("Building image %s from %s on %s", b.Alias, b.BaseImage, nodeHostname))
+	report(10, "Launching builder "+builder+" from "+b.BaseImage)
+	if err := lxd.LaunchInstance(ctx, lxd.LaunchConfig{Name: builder, Image: b.BaseImage, VM: b.VM, TargetNode: nodeHostname}); err != nil {
+		return fail(err)
+	}
+	defer func() {
+		if err := lxd.DeleteInstance(context.Background(), builder); err != nil {
+			log.Warn("Failed to delete builder instance %s: %v", builder, err)
+		}
+	}()
+
+	// 2. Provision
+	report(30, "Running provisioning script")
+	var err error
+	if output, err = lxd.RunScript(ctx, builder, b.Script, maxBuildLogBytes); err != nil {
+		return fail(err)
+	}
+
+	// 3. Publish
+	report(70, "Stopping builder")
+	if err := lxd.StopInstance(ctx, builder); err != nil {
+		return fail(err)
+	}
+	if err := repo.UpdateStatus(ctx, b.ID, BuildStatusPublishing); err != nil {
+		return fail(err)
+	}
+	report(80, "Publishing image "+b.Alias)
+	fingerprint, err := lxd.PublishInstance(ctx, builder, b.Alias, map[string]string{
+		"description":          fmt.Sprintf("mcloud build %s of %s", b.ID, b.BaseImage),
+		"mcloud.build_id":      b.ID,
+		"mcloud.base_image":    b.BaseImage,
+		"mcloud.script_sha256": b.ScriptSHA256,
+	})
+	if err != nil {
+		return fail(err)
+	}
+
+	// 4. Record provenance
+	report(95, "Recording build")
+	if err := repo.Finish(ctx, b.ID, BuildStatusCompleted, &fingerprint, logTail(output), nil); err != nil {
+		return fail(err)
+	}
+	s.event(ctx, event.ImageBuildCompleted, fmt.Sprintf("Image %s built from %s (fingerprint %s)", b.Alias, b.BaseImage, fingerprint))
+
+	return repo.GetByID(ctx, b.ID)
+}
+
+// GetBuild returns an image build and its provenance.
+func (s *Service) GetBuild(ctx context.Context, id string) (*database.ImageBuild, error) {
+	b, err := database.NewImageBuildRepository(s.db).GetByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrBuildNotFound
+	}
+	return b, err
+}
+
+// ListBuilds returns image builds, newest first unless opts sorts otherwise.
+func (s *Service) ListBuilds(ctx context.Context, opts database.ListOptions) ([]database.ImageBuild, error) {
+	return database.NewImageBuildRepository(s.db).List(ctx, opts)
+}
+
+// logTail returns the last maxBuildLogBytes of a script's output, or nil if it printed nothing.
+func logTail(output string) *string {
+	if output == "" {
+		return nil
+	}
+	if len(output) > maxBuildLogBytes {
+		output = output[len(output)-maxBuildLogBytes:]
+	}
+	return &output
+}
@@ -0,0 +1,54 @@
+package image
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateBuildRequest(t *testing.T) {
+	valid := BuildRequest{Alias: "web-golden", BaseImage: "ubuntu:24.04", Script: "apt-get update\n", Node: "node2"}
+
+	tests := []struct {
+		name    string
+		modify  func(r *BuildRequest)
+		wantErr bool
+	}{
+		{"valid", func(r *BuildRequest) {}, false},
+		{"alias with path", func(r *BuildRequest) { r.Alias = "golden/web-1.2" }, false},
+		{"missing alias", func(r *BuildRequest) { r.Alias = "" }, true},
+		{"alias with space", func(r *BuildRequest) { r.Alias = "web golden" }, true},
+		{"alias starting with dash", func(r *BuildRequest) { r.Alias = "-web" }, true},
+		{"missing base image", func(r *BuildRequest) { r.BaseImage = "" }, true},
+		{"missing script", func(r *BuildRequest) { r.Script = "" }, true},
+		{"script at limit", func(r *BuildRequest) { r.Script = strings.Repeat("#", maxScriptBytes) }, false},
+		{"script too large", func(r *BuildRequest) { r.Script = strings.Repeat("#", maxScriptBytes+1) }, true},
+		{"missing node", func(r *BuildRequest) { r.Node = "" }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := valid
+			tt.modify(&req)
+			err := validateBuildRequest(&req)
+			if tt.wantErr && !errors.Is(err, ErrInvalidBuildRequest) {
+				t.Errorf("err = %v, want ErrInvalidBuildRequest", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("err = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestLogTail(t *testing.T) {
+	if got := logTail(""); got != nil {
+		t.Errorf("logTail(\"\") = %q, want nil", *got)
+	}
+	if got := logTail("done\n"); got == nil || *got != "done\n" {
+		t.Errorf("logTail(short) = %v, want \"done\\n\"", got)
+	}
+	long := strings.Repeat("a", maxBuildLogBytes) + "tail"
+	if got := logTail(long); got == nil || len(*got) != maxBuildLogBytes || !strings.HasSuffix(*got, "tail") {
+		t.Errorf("logTail(long) kept %d bytes, want the last %d", len(*got), maxBuildLogBytes)
+	}
+}
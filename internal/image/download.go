@@ -0,0 +1,194 @@
+package image
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// downloadAttempts is how many times a download is (re)started before giving up.
+	// Every attempt resumes from the bytes already on disk.
+	downloadAttempts = 5
+
+	// progressInterval limits how often progress is reported while downloading.
+	progressInterval = 2 * time.Second
+)
+
+// errNotRetryable marks failures where retrying the download cannot help (e.g. 404).
+var errNotRetryable = errors.New("not retryable")
+
+// progressFunc receives the number of bytes on disk and the expected total (0 if unknown).
+type progressFunc func(done int64, total int64)
+
+// throttledReader limits reads from r to about maxBytesPerSec on average.
+type throttledReader struct {
+	ctx            context.Context
+	r              io.Reader
+	maxBytesPerSec int64
+	start          time.Time
+	read           int64
+}
+
+func (t *throttledReader) Read(p []byte) (int, error) {
+	// Read in small chunks so the rate stays smooth instead of bursting
+	if chunk := int(t.maxBytesPerSec / 10); chunk > 0 && len(p) > chunk {
+		p = p[:chunk]
+	}
+
+	n, err := t.r.Read(p)
+	t.read += int64(n)
+
+	// Sleep until the average rate since start drops back to the limit
+	expected := time.Duration(float64(t.read) / float64(t.maxBytesPerSec) * float64(time.Second))
+	if wait := expected - time.Since(t.start); wait > 0 {
+		select {
+		case <-t.ctx.Done():
+			return n, t.ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+	return n, err
+}
+
+// download fetches url into path, resuming from the existing file size via HTTP Range
+// requests and retrying transient failures with a linear backoff.
+// A maxBytesPerSec of 0 means unlimited.
+func download(ctx context.Context, url string, path string, maxBytesPerSec int64, progress progressFunc) error {
+	var err error
+	for attempt := 1; attempt <= downloadAttempts; attempt++ {
+		if err = downloadOnce(ctx, url, path, maxBytesPerSec, progress); err == nil {
+			return nil
+		}
+		if errors.Is(err, errNotRetryable) || ctx.Err() != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt) * 5 * time.Second):
+		}
+	}
+	return fmt.Errorf("download failed after %d attempts: %w", downloadAttempts, err)
+}
+
+func downloadOnce(ctx context.Context, url string, path string, maxBytesPerSec int64, progress progressFunc) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errNotRetryable, err)
+	}
+	defer f.Close()
+
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errNotRetryable, err)
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var total int64
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		total = offset + resp.ContentLength
+		if cr := resp.Header.Get("Content-Range"); cr != "" {
+			// Content-Range: bytes 100-199/2000
+			if _, size, ok := strings.Cut(cr, "/"); ok {
+				if n, err := strconv.ParseInt(size, 10, 64); err == nil {
+					total = n
+				}
+			}
+		}
+	case http.StatusOK:
+		// Server ignored the Range header: start over
+		if err := f.Truncate(0); err != nil {
+			return err
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		offset = 0
+		total = resp.ContentLength
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The file on disk is already complete
+		return nil
+	default:
+		err := fmt.Errorf("GET %s: %s", url, resp.Status)
+		if resp.StatusCode >= 400 && resp.StatusCode < 500 {
+			return fmt.Errorf("%w: %v", errNotRetryable, err)
+		}
+		return err
+	}
+	if total < 0 {
+		total = 0
+	}
+
+	var body io.Reader = resp.Body
+	if maxBytesPerSec > 0 {
+		body = &throttledReader{ctx: ctx, r: resp.Body, maxBytesPerSec: maxBytesPerSec, start: time.Now()}
+	}
+
+	done := offset
+	lastReport := time.Time{}
+	buf := make([]byte, 64*1024)
+	for {
+		n, rerr := body.Read(buf)
+		if n > 0 {
+			if _, err := f.Write(buf[:n]); err != nil {
+				return fmt.Errorf("%w: %v", errNotRetryable, err)
+			}
+			done += int64(n)
+			if time.Since(lastReport) >= progressInterval {
+				progress(done, total)
+				lastReport = time.Now()
+			}
+		}
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			return rerr
+		}
+	}
+	progress(done, total)
+
+	if total > 0 && done != total {
+		return fmt.Errorf("short download: got %d of %d bytes", done, total)
+	}
+	return nil
+}
+
+// fileSHA256 returns the hex encoded SHA-256 digest of the file at path.
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,151 @@
+package image
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"mcloud/internal/auth"
+	"mcloud/internal/database"
+	"mcloud/pkg/reason"
+	"mcloud/pkg/utils"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(s *Service) *Handler {
+	return &Handler{service: s}
+}
+
+// ImportImage starts an image import and returns 202 with the import record.
+// Poll GET /images/imports/{id} (or watch events) for progress.
+func (h *Handler) ImportImage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ImportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		reason.HTTPError(w, err, 400)
+		return
+	}
+
+	actor := auth.ClientIdentity(r)
+	result, err := h.service.Import(r.Context(), &req, &actor)
+	if err != nil {
+		if errors.Is(err, ErrInvalidRequest) {
+			reason.HTTPError(w, err, 400)
+			return
+		}
+		reason.HTTPError(w, err, 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(result)
+}
+
+func (h *Handler) GetImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	result, err := h.service.Get(r.Context(), r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, ErrImportNotFound) {
+			reason.HTTPError(w, err, 404)
+			return
+		}
+		reason.HTTPError(w, err, 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// StartBuild starts an image build and returns 202 with the build record and
+// its operation. Poll GET /operations/{id} or GET /images/builds/{id} for progress.
+func (h *Handler) StartBuild(w http.ResponseWriter, r *http.Request) {
+	var req BuildRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		reason.HTTPError(w, err, 400)
+		return
+	}
+
+	actor := auth.ClientIdentity(r)
+	result, err := h.service.Build(r.Context(), &req, &actor)
+	if err != nil {
+		if errors.Is(err, ErrInvalidBuildRequest) {
+			reason.HTTPError(w, err, 400)
+			return
+		}
+		reason.HTTPError(w, err, 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(result)
+}
+
+// GetBuild returns an image build with its provenance (base image, script hash,
+// build node, requester and published fingerprint).
+func (h *Handler) GetBuild(w http.ResponseWriter, r *http.Request) {
+	result, err := h.service.GetBuild(r.Context(), r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, ErrBuildNotFound) {
+			reason.HTTPError(w, err, 404)
+			return
+		}
+		reason.HTTPError(w, err, 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// ListBuildsResponse is one page of GET /images/builds.
+type ListBuildsResponse struct {
+	Items      []database.ImageBuild `json:"items"`
+	NextOffset int                   `json:"next_offset,omitempty"` // 0 when there are no more pages
+}
+
+// ListBuilds handles GET /images/builds?limit=100&offset=0, newest first. Optional parameters:
+//   sort                 alias, status or created_at; "-" prefix for descending
+//   alias, status, node  exact-match filters
+func (h *Handler) ListBuilds(w http.ResponseWriter, r *http.Request) {
+	limit, offset, err := utils.ParsePage(r)
+	if err != nil {
+		reason.HTTPError(w, err, 400)
+		return
+	}
+
+	sort, filter := utils.ParseListQuery(r, "alias", "status", "node")
+	builds, err := h.service.ListBuilds(r.Context(), database.ListOptions{Limit: limit, Offset: offset, Sort: sort, Filter: filter})
+	if errors.Is(err, database.ErrInvalidListOption) {
+		reason.HTTPError(w, err, 400)
+		return
+	}
+	if err != nil {
+		reason.HTTPError(w, err, 500)
+		return
+	}
+
+	resp := ListBuildsResponse{Items: builds}
+	if resp.Items == nil {
+		resp.Items = []database.ImageBuild{}
+	}
+	if len(builds) == limit {
+		resp.NextOffset = offset + limit
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
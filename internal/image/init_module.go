@@ -0,0 +1,14 @@
+package image
+
+import (
+	"net/http"
+)
+
+// InitModule registers the image routes served by handler.
+func InitModule(mux *http.ServeMux, handler *Handler) {
+	mux.HandleFunc("/images/import", handler.ImportImage)
+	mux.HandleFunc("/images/imports/{id}", handler.GetImport)
+	mux.HandleFunc("GET /images/builds", handler.ListBuilds)
+	mux.HandleFunc("POST /images/builds", handler.StartBuild)
+	mux.HandleFunc("GET /images/builds/{id}", handler.GetBuild)
+}
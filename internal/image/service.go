@@ -0,0 +1,247 @@
+// Package image imports VM and container images from URLs into LXD.
+// Downloads run in the background, resume after interruptions, can be bandwidth
+// limited, are verified against a SHA-256 checksum and report progress as events.
+// Images can also be built in the cluster from a base image and a provisioning
+// script (see build.go).
+package image
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"mcloud/internal/constant"
+	"mcloud/internal/database"
+	"mcloud/internal/event"
+	"mcloud/internal/operation"
+	"mcloud/pkg/logger"
+	"mcloud/pkg/reason"
+	"mcloud/pkg/utils"
+	"mcloud/services/lxd"
+)
+
+var log = logger.Named("image")
+
+var (
+	ErrImportNotFound = reason.New(reason.ImageImportNotFound, "image import not found")
+	ErrInvalidRequest = reason.New(reason.InvalidImageImport, "invalid image import request")
+	ErrChecksum       = reason.New(reason.ImageChecksumMismatch, "checksum mismatch")
+)
+
+var sha256Regexp = regexp.MustCompile(`^[a-f0-9]{64}$`)
+
+const (
+	StatusPending     = "pending"
+	StatusDownloading = "downloading"
+	StatusVerifying   = "verifying"
+	StatusImporting   = "importing"
+	StatusCompleted   = "completed"
+	StatusFailed      = "failed"
+)
+
+type ImportRequest struct {
+	URL            string `json:"url"`
+	Alias          string `json:"alias"`
+	SHA256         string `json:"sha256"`            // optional, hex encoded
+	MaxBytesPerSec int64  `json:"max_bytes_per_sec"` // optional, 0 = unlimited
+}
+
+type Service struct {
+	db         *sql.DB
+	dir        string
+	operations *operation.Service
+
+	mu      sync.Mutex
+	running map[string]bool
+}
+
+func NewService(db *sql.DB, operations *operation.Service) *Service {
+	return &Service{
+		db:         db,
+		dir:        constant.DefaultImageDownloadDir,
+		operations: operations,
+		running:    map[string]bool{},
+	}
+}
+
+// Import records a new image import and starts downloading it in the background.
+func (s *Service) Import(ctx context.Context, req *ImportRequest, actor *string) (*database.ImageImport, error) {
+	u, err := url.Parse(req.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%w: url must be http(s)", ErrInvalidRequest)
+	}
+	if req.Alias == "" {
+		return nil, fmt.Errorf("%w: alias is required", ErrInvalidRequest)
+	}
+	if req.MaxBytesPerSec < 0 {
+		return nil, fmt.Errorf("%w: max_bytes_per_sec must not be negative", ErrInvalidRequest)
+	}
+
+	var checksum *string
+	if req.SHA256 != "" {
+		sum := strings.ToLower(req.SHA256)
+		if !sha256Regexp.MatchString(sum) {
+			return nil, fmt.Errorf("%w: sha256 must be 64 hex characters", ErrInvalidRequest)
+		}
+		checksum = &sum
+	}
+
+	imp := &database.ImageImport{
+		ID:             utils.GenerateUUID(),
+		URL:            req.URL,
+		Alias:          req.Alias,
+		SHA256:         checksum,
+		MaxBytesPerSec: req.MaxBytesPerSec,
+		Status:         StatusPending,
+		CreateUserID:   actor,
+	}
+	repo := database.NewImageImportRepository(s.db)
+	if err := repo.Create(ctx, imp); err != nil {
+		return nil, err
+	}
+
+	s.start(imp)
+
+	return repo.GetByID(ctx, imp.ID)
+}
+
+// Get returns the current state of an image import.
+func (s *Service) Get(ctx context.Context, id string) (*database.ImageImport, error) {
+	imp, err := database.NewImageImportRepository(s.db).GetByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrImportNotFound
+	}
+	return imp, err
+}
+
+// ResumeUnfinished restarts imports that were interrupted (e.g. by a daemon restart).
+// Downloads continue from the partial file left on disk.
+func (s *Service) ResumeUnfinished(ctx context.Context) error {
+	imports, err := database.NewImageImportRepository(s.db).ListUnfinished(ctx)
+	if err != nil {
+		return err
+	}
+	for i := range imports {
+		log.Info("Resuming image import %s (%s)", imports[i].ID, imports[i].Alias)
+		s.start(&imports[i])
+	}
+	return nil
+}
+
+// start runs the import in a goroutine unless it is already running.
+func (s *Service) start(imp *database.ImageImport) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running[imp.ID] {
+		return
+	}
+	s.running[imp.ID] = true
+
+	go func() {
+		defer func() {
+			s.mu.Lock()
+			delete(s.running, imp.ID)
+			s.mu.Unlock()
+		}()
+		s.run(context.Background(), imp)
+	}()
+}
+
+func (s *Service) run(ctx context.Context, imp *database.ImageImport) {
+	repo := database.NewImageImportRepository(s.db)
+
+	fail := func(err error) {
+		msg := err.Error()
+		if uerr := repo.UpdateStatus(ctx, imp.ID, StatusFailed, &msg); uerr != nil {
+			log.Error("Failed to update image import %s: %v", imp.ID, uerr)
+		}
+		s.event(ctx, event.ImageImportFailed, fmt.Sprintf("Image import %s (%s) failed: %s", imp.ID, imp.Alias, msg))
+	}
+
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		fail(err)
+		return
+	}
+	path := filepath.Join(s.dir, imp.ID+".part")
+
+	// 1. Download (resumes from the partial file if present)
+	if err := repo.UpdateStatus(ctx, imp.ID, StatusDownloading, nil); err != nil {
+		fail(err)
+		return
+	}
+	s.event(ctx, event.ImageImportStarted, fmt.Sprintf("Downloading image %s from %s", imp.Alias, imp.URL))
+
+	lastPercent := int64(-1)
+	err := download(ctx, imp.URL, path, imp.MaxBytesPerSec, func(done int64, total int64) {
+		if err := repo.UpdateProgress(ctx, imp.ID, done, total); err != nil {
+			log.Error("Failed to update image import %s: %v", imp.ID, err)
+		}
+		// Emit an event every 10%
+		if total > 0 {
+			if percent := done * 100 / total / 10 * 10; percent > lastPercent {
+				lastPercent = percent
+				s.event(ctx, event.ImageImportProgress, fmt.Sprintf("Image %s: %d%% (%d/%d bytes)", imp.Alias, percent, done, total))
+			}
+		}
+	})
+	if err != nil {
+		fail(err)
+		return
+	}
+
+	// 2. Verify checksum over the whole file (covers resumed downloads)
+	if imp.SHA256 != nil {
+		if err := repo.UpdateStatus(ctx, imp.ID, StatusVerifying, nil); err != nil {
+			fail(err)
+			return
+		}
+		sum, err := fileSHA256(path)
+		if err != nil {
+			fail(err)
+			return
+		}
+		if sum != *imp.SHA256 {
+			// The partial file is corrupt: drop it so a retry starts fresh
+			os.Remove(path)
+			fail(fmt.Errorf("%w: expected %s, got %s", ErrChecksum, *imp.SHA256, sum))
+			return
+		}
+	}
+
+	// 3. Import into LXD
+	if err := repo.UpdateStatus(ctx, imp.ID, StatusImporting, nil); err != nil {
+		fail(err)
+		return
+	}
+	if err := lxd.ImportImage(path, imp.Alias); err != nil {
+		fail(err)
+		return
+	}
+	os.Remove(path)
+
+	if err := repo.UpdateStatus(ctx, imp.ID, StatusCompleted, nil); err != nil {
+		log.Error("Failed to update image import %s: %v", imp.ID, err)
+	}
+	s.event(ctx, event.ImageImportCompleted, fmt.Sprintf("Image %s imported", imp.Alias))
+}
+
+// event records an image import event for the first cluster (if any).
+func (s *Service) event(ctx context.Context, eventType string, message string) {
+	var clusterID *string
+	if clusters, err := database.NewClusterRepository(s.db).List(ctx); err == nil && len(clusters) > 0 {
+		clusterID = &clusters[0].ID
+	}
+
+	e := event.New(eventType, message, nil)
+	e.ClusterID = clusterID
+	if err := database.NewEventRepository(s.db).Create(ctx, e); err != nil {
+		log.Error("Failed to record event %s: %v", eventType, err)
+	}
+}
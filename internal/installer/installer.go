@@ -1,6 +1,8 @@
-// Package installer provides system-level installation and setup for the mcloudd daemon.
-// It handles copying the mcloudd binary to the system path, creating systemd service units,
-// and managing the daemon lifecycle (enable/start).
+// Package installer provides system-level installation and setup for the mcloudd daemon
+// and the mcloud-agent. It handles copying the binaries to the system path, registering
+// them with the host's init system (systemd, OpenRC, or a pidfile fallback; see
+// ServiceManager) and managing their lifecycle (enable/start), as well as uninstalling
+// and resetting them.
 package installer
 
 import (
@@ -9,40 +11,57 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+
+	"mcloud/internal/config"
+	"mcloud/internal/constant"
 )
 
 // Installation constants defining paths and service names
 const (
-	binaryName = "mcloudd"                           // Systemd service name
-	binaryDst  = "/usr/local/bin/mcloudd"            // Destination path for the mcloudd binary
-	unitPath   = "/etc/systemd/system/mcloudd.service" // Systemd unit file location
+	binaryName = "mcloudd"                // Service name under every init system
+	binaryDst  = "/usr/local/bin/mcloudd" // Destination path for the mcloudd binary
+
+	agentBinaryName = "mcloud-agent"                // Agent service name under every init system
+	agentBinaryDst  = "/usr/local/bin/mcloud-agent" // Destination path for the agent binary
 )
 
-// Init installs the mcloudd daemon as a systemd service and starts it.
+// out is where the installer reports each thing it did, e.g. "✔ copied ...".
+var out io.Writer = os.Stdout
+
+// SetOutput sends the installer's reports to w instead of os.Stdout, e.g.
+// through a CLI progress display that redraws its status line around them.
+func SetOutput(w io.Writer) {
+	out = w
+}
+
+// Init installs the mcloudd daemon as a service of the detected init system and starts it.
 // This is the main entry point for daemon installation during cluster initialization.
 //
 // The function performs the following steps:
 //   1. Check for root privileges (required for system-level installation)
 //   2. Copy the mcloudd binary to /usr/local/bin/
-//   3. Create systemd unit file at /etc/systemd/system/mcloudd.service
-//   4. Reload systemd daemon to recognize the new service
-//   5. Enable mcloudd to start on boot
-//   6. Start the mcloudd service immediately
+//   3. Detect the init system (systemd, OpenRC, or the pidfile fallback)
+//   4. Install the service definition and enable mcloudd to start on boot
+//   5. Start the mcloudd service immediately
+//
+// Parameters:
+//   configPath - Config file written by init; passed to mcloudd via --config
 //
 // Returns:
 //   - nil if installation succeeds
-//   - error if any step fails (insufficient permissions, file I/O errors, systemd errors)
+//   - error if any step fails (insufficient permissions, file I/O errors, init system errors)
 //
 // Example Input:
 //   Called during: mcloudctl init --name my-cluster
+//   configPath: /etc/mcloud/config.yaml
 //   Process UID: 0 (root)
 //   Current executable: /home/user/mcloud/mcloudd
 //
 // Example Output (Success):
 //   Console output:
 //     ✔ copied mcloudd → /usr/local/bin/mcloudd
-//     ✅ mcloudd installed and started
-//   Side effects:
+//     ✅ mcloudd installed and started (systemd)
+//   Side effects (systemd host):
 //     - Binary copied to /usr/local/bin/mcloudd with mode 0755
 //     - Unit file created at /etc/systemd/system/mcloudd.service
 //     - Service enabled: systemctl enable mcloudd
@@ -55,7 +74,7 @@ const (
 //
 // Example Output (Error - Binary Copy Failed):
 //   Returns: error("open /usr/local/bin/mcloudd: permission denied")
-func Init() error {
+func Init(configPath string) error {
 	// Step 1: Verify root privileges (UID 0 required)
 	if os.Geteuid() != 0 {
 		return fmt.Errorf("must run as root")
@@ -66,27 +85,207 @@ func Init() error {
 		return err
 	}
 
-	// Step 3: Create systemd unit file
-	if err := writeUnitFile(); err != nil {
+	// Step 3: Detect the init system
+	sm := DetectServiceManager()
+
+	// Step 4: Install the service definition and enable it on boot
+	if err := sm.Install(configPath); err != nil {
+		return err
+	}
+
+	// Step 5: Start service immediately
+	if err := sm.Start(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "✅ mcloudd installed and started (%s)\n", sm.Name())
+	return nil
+}
+
+// InstallAgent installs mcloud-agent as a service of the detected init system,
+// pointed at the manager, and starts it. This is what `mcloudctl join` does on
+// a new node, mirroring Init for mcloudd.
+//
+// The agent binary is taken from next to the running mcloudctl, or else from PATH.
+//
+// Example Input:
+//   InstallAgent("/etc/mcloud/config.yaml", "https://192.168.1.10:9028")
+//
+// Example Output (Success):
+//   Console output:
+//     ✔ copied mcloud-agent → /usr/local/bin/mcloud-agent
+//     ✅ mcloud-agent installed and started (systemd)
+//   Unit file: /etc/systemd/system/mcloud-agent.service with
+//     ExecStart=/usr/local/bin/mcloud-agent --config /etc/mcloud/config.yaml --manager-url https://192.168.1.10:9028
+//
+// Example Output (Error - No Binary):
+//   Returns: error("mcloud-agent binary not found next to /usr/local/bin/mcloudctl or in PATH")
+func InstallAgent(configPath, managerURL string) error {
+	if os.Geteuid() != 0 {
+		return fmt.Errorf("must run as root")
+	}
+	if managerURL == "" {
+		return fmt.Errorf("manager URL is required to install mcloud-agent")
+	}
+
+	src, err := agentBinarySource()
+	if err != nil {
+		return err
+	}
+	if err := copyBinary(agentBinaryName, src, agentBinaryDst); err != nil {
+		return err
+	}
+
+	sm := detectServiceManager(agentService(managerURL))
+	if err := sm.Install(configPath); err != nil {
 		return err
 	}
+	if err := sm.Start(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "✅ mcloud-agent installed and started (%s)\n", sm.Name())
+	return nil
+}
+
+// UninstallAgent stops and disables mcloud-agent and removes its service
+// definition and binary. It is safe to call when the agent is not installed.
+func UninstallAgent() error {
+	if os.Geteuid() != 0 {
+		return fmt.Errorf("must run as root")
+	}
+
+	sm := detectServiceManager(agentService(""))
+	if err := sm.Stop(); err != nil {
+		return err
+	}
+	if err := sm.Remove(); err != nil {
+		return err
+	}
+	return removePath(agentBinaryDst)
+}
+
+// agentBinarySource finds the mcloud-agent binary shipped with mcloudctl.
+func agentBinarySource() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	exe, _ = filepath.EvalSymlinks(exe)
+
+	candidate := filepath.Join(filepath.Dir(exe), agentBinaryName)
+	if _, err := os.Stat(candidate); err == nil {
+		return candidate, nil
+	}
+	if path, err := exec.LookPath(agentBinaryName); err == nil {
+		return path, nil
+	}
+	return "", fmt.Errorf("%s binary not found next to %s or in PATH", agentBinaryName, exe)
+}
 
-	// Step 4: Reload systemd to recognize new service
-	if err := run("systemctl", "daemon-reload"); err != nil {
+// UninstallOptions selects what Uninstall removes besides the service.
+type UninstallOptions struct {
+	KeepBinary bool   // leave /usr/local/bin/mcloudd (init rollback)
+	Purge      bool   // also delete DataDir: state, database, certificates, images
+	DataDir    string // default constant.DefaultDataDir
+}
+
+// Uninstall stops and disables mcloudd, removes its service definition and, unless
+// KeepBinary is set, the binary. It is safe to call when mcloudd is not installed.
+//
+// Example Input:
+//   Uninstall(UninstallOptions{Purge: true})
+//
+// Example Output:
+//   Console output:
+//     ✔ removed /etc/systemd/system/mcloudd.service
+//     ✔ removed /usr/local/bin/mcloudd
+//     ✔ removed /var/lib/mcloud
+func Uninstall(opts UninstallOptions) error {
+	if os.Geteuid() != 0 {
+		return fmt.Errorf("must run as root")
+	}
+
+	if err := removeService(); err != nil {
 		return err
 	}
+	if !opts.KeepBinary {
+		if err := removePath(binaryDst); err != nil {
+			return err
+		}
+	}
+	if opts.Purge {
+		dataDir := opts.DataDir
+		if dataDir == "" {
+			dataDir = constant.DefaultDataDir
+		}
+		if err := removePath(dataDir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reset returns the node to its state before `mcloudctl init`: mcloudd is
+// stopped and its service removed, and the state file, database, certificates and
+// re-join bundle are deleted. Binaries and the config file are kept, so init
+// can run again straight away. LXD, MicroCeph and MicroOVN are not touched.
+//
+// Example Output:
+//   Console output:
+//     ✔ removed /etc/systemd/system/mcloudd.service
+//     ✔ removed /var/lib/mcloud/state.yaml
+//     ✔ removed /var/lib/mcloud/mcloud.db
+//     ✔ removed /var/lib/mcloud/certs
+//     ✔ removed /etc/mcloud/rejoin.bundle
+func Reset(cfg *config.Config) error {
+	if os.Geteuid() != 0 {
+		return fmt.Errorf("must run as root")
+	}
 
-	// Step 5: Enable service to start on boot
-	if err := run("systemctl", "enable", binaryName); err != nil {
+	// The daemon holds the database open; stop it before deleting anything
+	if err := removeService(); err != nil {
 		return err
 	}
 
-	// Step 6: Start service immediately
-	if err := run("systemctl", "start", binaryName); err != nil {
+	paths := []string{
+		cfg.StatePath,
+		cfg.Database.DBPath,
+		cfg.Database.DBPath + "-wal",
+		cfg.Database.DBPath + "-shm",
+		cfg.Security.CertDir,
+		constant.DefaultRejoinBundlePath,
+	}
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		if err := removePath(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeService stops and disables mcloudd and removes its service definition.
+func removeService() error {
+	sm := DetectServiceManager()
+	if err := sm.Stop(); err != nil {
 		return err
 	}
+	return sm.Remove()
+}
 
-	fmt.Println("✅ mcloudd installed and started")
+// removePath deletes a file or directory tree, reporting what it removed.
+// A path that does not exist is not an error.
+func removePath(path string) error {
+	if _, err := os.Lstat(path); os.IsNotExist(err) {
+		return nil
+	}
+	if err := os.RemoveAll(path); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "✔ removed %s\n", path)
 	return nil
 }
 
@@ -138,122 +337,47 @@ func installBinary() error {
 	// Step 2: Resolve symlinks to get real binary path
 	src, _ = filepath.EvalSymlinks(src)
 
-	// Step 3: Check if binary is already installed at destination
-	if src == binaryDst {
-		fmt.Println("binary already installed")
+	// Steps 3-5: Copy it into place
+	return copyBinary(binaryName, src, binaryDst)
+}
+
+// copyBinary copies the executable src to dst with mode 0755, unless src
+// already is dst.
+func copyBinary(name, src, dst string) error {
+	// Check if binary is already installed at destination
+	if src == dst {
+		fmt.Fprintln(out, "binary already installed")
 		return nil
 	}
 
-	// Step 4a: Open source binary for reading
+	// Open source binary for reading
 	in, err := os.Open(src)
 	if err != nil {
 		return err
 	}
 	defer in.Close()
 
-	// Step 4b: Create destination file
-	out, err := os.Create(binaryDst)
+	// Create destination file
+	out, err := os.Create(dst)
 	if err != nil {
 		return err
 	}
 	defer out.Close()
 
-	// Step 4c: Copy binary content from source to destination
+	// Copy binary content from source to destination
 	if _, err := io.Copy(out, in); err != nil {
 		return err
 	}
 
-	// Step 5: Set executable permissions (rwxr-xr-x)
+	// Set executable permissions (rwxr-xr-x)
 	if err := out.Chmod(0755); err != nil {
 		return err
 	}
 
-	fmt.Println("✔ copied mcloudd →", binaryDst)
+	fmt.Fprintf(out, "✔ copied %s → %s\n", name, dst)
 	return nil
 }
 
-// writeUnitFile creates a systemd unit file for the mcloudd daemon.
-// The unit file configures the daemon to start after network is available,
-// restart automatically on failure, and start on boot.
-//
-// Unit file configuration:
-//   [Unit] section:
-//     - Description: Human-readable service description
-//     - After: Wait for network.target before starting
-//     - Wants: Prefer network-online.target (non-blocking)
-//
-//   [Service] section:
-//     - Type: simple (process runs in foreground)
-//     - ExecStart: Command to execute (/usr/local/bin/mcloudd)
-//     - Restart: always (restart on any exit, including success)
-//     - RestartSec: 5 seconds delay before restart
-//
-//   [Install] section:
-//     - WantedBy: multi-user.target (start during normal boot)
-//
-// Returns:
-//   - nil if file is created successfully
-//   - error if write fails (permissions, disk full, etc.)
-//
-// Example Input:
-//   Unit path: /etc/systemd/system/mcloudd.service
-//   File exists: false (or will be overwritten)
-//   User: root (UID 0)
-//
-// Example Output (Success):
-//   File created: /etc/systemd/system/mcloudd.service
-//   File mode: 0644 (rw-r--r--)
-//   File content:
-//     [Unit]
-//     Description=mcloud daemon
-//     After=network.target
-//     Wants=network-online.target
-//     
-//     [Service]
-//     Type=simple
-//     ExecStart=/usr/local/bin/mcloudd
-//     Restart=always
-//     RestartSec=5
-//     LimitNOFILE=1048576
-//     
-//     # Security (optional but should have)
-//     NoNewPrivileges=true
-//     PrivateTmp=true
-//     
-//     [Install]
-//     WantedBy=multi-user.target
-//
-// Example Output (Error):
-//   Returns: error("open /etc/systemd/system/mcloudd.service: permission denied")
-//   Cause: Non-root user or /etc/systemd/system not writable
-func writeUnitFile() error {
-	// Define systemd unit file content
-	// [Unit]: Service metadata and dependencies
-	// [Service]: Execution configuration and restart policy
-	// [Install]: Boot-time behavior
-	content := `[Unit]
-Description=mcloud daemon
-After=network.target
-Wants=network-online.target
-
-[Service]
-Type=simple
-ExecStart=/usr/local/bin/mcloudd
-Restart=always
-RestartSec=5
-LimitNOFILE=1048576
-
-# Security (optional but should have)
-NoNewPrivileges=true
-PrivateTmp=true
-
-[Install]
-WantedBy=multi-user.target
-`
-	// Write unit file with mode 0644 (readable by all, writable by owner)
-	return os.WriteFile(unitPath, []byte(content), 0644)
-}
-
 // run executes a system command and streams its output to the current process's stdout/stderr.
 // This is a helper function for executing systemctl and other system commands during installation.
 //
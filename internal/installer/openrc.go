@@ -0,0 +1,83 @@
+package installer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const initScriptDir = "/etc/init.d" // OpenRC init script location
+
+// openrcScript runs a service under supervise-daemon, which restarts it when it
+// exits like Restart=always does under systemd.
+const openrcScript = `#!/sbin/openrc-run
+
+name="{{name}}"
+description="{{description}}"
+supervisor=supervise-daemon
+command="{{binary}}"
+command_args="{{args}}"
+pidfile="/run/{{name}}.pid"
+respawn_delay=5
+output_log="/var/log/mcloud/{{name}}.out"
+error_log="/var/log/mcloud/{{name}}.out"
+rc_ulimit="-n 1048576"
+
+depend() {
+	need net
+	after firewall
+}
+
+start_pre() {
+	checkpath --directory --mode 0755 /var/log/mcloud
+}
+`
+
+// openrcManager runs a service as an OpenRC service (Alpine, Gentoo).
+type openrcManager struct {
+	svc *service
+}
+
+func (m *openrcManager) scriptPath() string {
+	return filepath.Join(initScriptDir, m.svc.name)
+}
+
+func (m *openrcManager) Name() string { return "openrc" }
+
+// Install writes /etc/init.d/<name> and adds it to the default runlevel.
+//
+// Example Output:
+//   File created: /etc/init.d/mcloudd (mode 0755)
+//   Command executed: rc-update add mcloudd default
+func (m *openrcManager) Install(configPath string) error {
+	args := strings.TrimPrefix(m.svc.command(configPath), m.svc.binary+" ")
+	script := strings.NewReplacer(
+		"{{name}}", m.svc.name,
+		"{{description}}", m.svc.description,
+		"{{binary}}", m.svc.binary,
+		"{{args}}", args,
+	).Replace(openrcScript)
+	if err := os.WriteFile(m.scriptPath(), []byte(script), 0755); err != nil {
+		return err
+	}
+	return run("rc-update", "add", m.svc.name, "default")
+}
+
+func (m *openrcManager) Start() error {
+	return run("rc-service", m.svc.name, "start")
+}
+
+func (m *openrcManager) Restart() error {
+	return run("rc-service", m.svc.name, "restart")
+}
+
+func (m *openrcManager) Stop() error {
+	// Ignore errors: the service may not exist yet
+	_ = run("rc-service", m.svc.name, "stop")
+	_ = run("rc-update", "del", m.svc.name, "default")
+	return nil
+}
+
+func (m *openrcManager) Remove() error {
+	return removePath(m.scriptPath())
+}
@@ -0,0 +1,136 @@
+package installer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// pidfileManager is the fallback for hosts without systemd or OpenRC: the
+// service is started detached (like nohup) and tracked by a pidfile. Nothing
+// restarts it on exit or on boot.
+//
+// Files for mcloudd (mcloud-agent alike):
+//   /run/mcloudd.pid              PID of the running process
+//   /var/log/mcloud/mcloudd.out   stdout and stderr
+//   /etc/mcloud/mcloudd.args      arguments remembered for Start, one per line
+type pidfileManager struct {
+	svc *service
+}
+
+func (m *pidfileManager) pidfilePath() string { return "/run/" + m.svc.name + ".pid" }
+func (m *pidfileManager) logPath() string     { return "/var/log/mcloud/" + m.svc.name + ".out" }
+func (m *pidfileManager) argsPath() string    { return "/etc/mcloud/" + m.svc.name + ".args" }
+
+func (m *pidfileManager) Name() string { return "pidfile" }
+
+// Install remembers the arguments for Start. There is no boot integration.
+func (m *pidfileManager) Install(configPath string) error {
+	if err := os.MkdirAll(filepath.Dir(m.argsPath()), 0755); err != nil {
+		return err
+	}
+	args := append([]string{"--config", configPath}, m.svc.args...)
+	if err := os.WriteFile(m.argsPath(), []byte(strings.Join(args, "\n")+"\n"), 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "⚠ no systemd or OpenRC found; %s will not be restarted on exit or on boot\n", m.svc.name)
+	return nil
+}
+
+// Start runs the service in its own session with output appended to
+// /var/log/mcloud/<name>.out and writes its PID to /run/<name>.pid.
+func (m *pidfileManager) Start() error {
+	if pid, err := m.readPidfile(); err == nil && processAlive(pid) {
+		return nil
+	}
+
+	data, err := os.ReadFile(m.argsPath())
+	if err != nil {
+		return fmt.Errorf("%s is not installed: %w", m.svc.name, err)
+	}
+	args := strings.Fields(string(data))
+
+	if err := os.MkdirAll(filepath.Dir(m.logPath()), 0755); err != nil {
+		return err
+	}
+	logFile, err := os.OpenFile(m.logPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+
+	cmd := exec.Command(m.svc.binary, args...)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true} // survive mcloudctl exiting
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	if err := os.WriteFile(m.pidfilePath(), []byte(strconv.Itoa(cmd.Process.Pid)+"\n"), 0644); err != nil {
+		return err
+	}
+	return cmd.Process.Release()
+}
+
+// Restart stops the process in the pidfile, waits up to 30 seconds for it to
+// exit so the new one can bind its ports, and starts the service again.
+func (m *pidfileManager) Restart() error {
+	pid, err := m.readPidfile()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := m.Stop(); err != nil {
+		return err
+	}
+	for deadline := time.Now().Add(30 * time.Second); pid > 0 && processAlive(pid); {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%s (pid %d) did not exit after SIGTERM", m.svc.name, pid)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	return m.Start()
+}
+
+// Stop sends SIGTERM to the process in the pidfile and removes the pidfile.
+func (m *pidfileManager) Stop() error {
+	pid, err := m.readPidfile()
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if processAlive(pid) {
+		if err := syscall.Kill(pid, syscall.SIGTERM); err != nil {
+			return fmt.Errorf("stop %s (pid %d): %w", m.svc.name, pid, err)
+		}
+	}
+	return removePath(m.pidfilePath())
+}
+
+func (m *pidfileManager) Remove() error {
+	return removePath(m.argsPath())
+}
+
+func (m *pidfileManager) readPidfile() (int, error) {
+	data, err := os.ReadFile(m.pidfilePath())
+	if err != nil {
+		return 0, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("invalid pidfile %s: %w", m.pidfilePath(), err)
+	}
+	return pid, nil
+}
+
+// processAlive reports whether a process with this PID exists.
+func processAlive(pid int) bool {
+	return syscall.Kill(pid, 0) == nil
+}
@@ -0,0 +1,76 @@
+package installer
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// service describes a binary the installer runs under the host's init system.
+type service struct {
+	name        string   // service name under every init system, e.g. "mcloudd"
+	description string   // e.g. "mcloud daemon"
+	binary      string   // installed binary, e.g. /usr/local/bin/mcloudd
+	args        []string // passed after --config <configPath>
+	notify      bool     // reports readiness and pings the watchdog via sd_notify (see pkg/sdnotify)
+}
+
+// command returns the full command line for the service.
+//
+// Example Output:
+//   "/usr/local/bin/mcloud-agent --config /etc/mcloud/config.yaml --manager-url https://192.168.1.10:9028"
+func (s *service) command(configPath string) string {
+	return strings.Join(append([]string{s.binary, "--config", configPath}, s.args...), " ")
+}
+
+// daemonService is mcloudd, installed by `mcloudctl init`.
+var daemonService = &service{
+	name:        binaryName,
+	description: "mcloud daemon",
+	binary:      binaryDst,
+	notify:      true,
+}
+
+// agentService is mcloud-agent pointed at the manager, installed by `mcloudctl join`.
+func agentService(managerURL string) *service {
+	return &service{
+		name:        agentBinaryName,
+		description: "mcloud node agent",
+		binary:      agentBinaryDst,
+		args:        []string{"--manager-url", managerURL},
+	}
+}
+
+// ServiceManager runs an mcloud service under the host's init system.
+type ServiceManager interface {
+	// Name identifies the init system, e.g. "systemd".
+	Name() string
+	// Install writes the service definition for the binary with --config configPath
+	// and enables it on boot where the init system supports that.
+	Install(configPath string) error
+	// Start starts the service now.
+	Start() error
+	// Restart stops the service and starts it again, e.g. on a new binary.
+	Restart() error
+	// Stop stops the service and disables it on boot. Not being installed is not an error.
+	Stop() error
+	// Remove deletes the service definition written by Install.
+	Remove() error
+}
+
+// DetectServiceManager returns the ServiceManager for mcloudd under the running
+// init system: systemd if it is PID 1, else OpenRC if installed (Alpine, Gentoo),
+// else the pidfile fallback, which starts mcloudd in the background but not on boot.
+func DetectServiceManager() ServiceManager {
+	return detectServiceManager(daemonService)
+}
+
+func detectServiceManager(svc *service) ServiceManager {
+	if _, err := os.Stat("/run/systemd/system"); err == nil {
+		return &systemdManager{svc: svc}
+	}
+	if _, err := exec.LookPath("openrc-run"); err == nil {
+		return &openrcManager{svc: svc}
+	}
+	return &pidfileManager{svc: svc}
+}
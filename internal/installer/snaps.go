@@ -0,0 +1,76 @@
+package installer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"mcloud/pkg/commander"
+)
+
+// DefaultSnapChannels are the channels dependencies are installed from when the
+// preseed, flags and config do not pin one.
+var DefaultSnapChannels = map[string]string{
+	"lxd":       "5.21/stable",
+	"microceph": "squid/stable",
+	"microovn":  "24.03/stable",
+}
+
+// snapInstallOptions gives each attempt room for a slow download; snapd being
+// busy with an auto-refresh is retried.
+var snapInstallOptions = commander.RetryOptions{
+	Attempts:       3,
+	Timeout:        15 * time.Minute,
+	InitialBackoff: 5 * time.Second,
+	MaxBackoff:     time.Minute,
+	MaxOutputBytes: 1 << 20,
+}
+
+// SnapChannel returns the channel snap is pinned to: channels[snap] if set,
+// else DefaultSnapChannels[snap], else "" (the snap's default track).
+func SnapChannel(snap string, channels map[string]string) string {
+	if ch := channels[snap]; ch != "" {
+		return ch
+	}
+	return DefaultSnapChannels[snap]
+}
+
+// InstallSnaps installs snaps from their pinned channels, in order, reporting
+// each one through progress. It stops at the first failure.
+//
+// Example Input:
+//   InstallSnaps(ctx, []string{"microceph", "microovn"}, map[string]string{"microovn": "24.03/edge"}, logProgress)
+//
+// Example Output (progress):
+//   [1/2] Installing microceph (squid/stable)...
+//   [1/2] microceph installed
+//   [2/2] Installing microovn (24.03/edge)...
+//   [2/2] microovn installed
+//
+// Example Output (Error):
+//   Returns: error("install microovn: command execution failed: exit status 1: error: requested channel ...")
+func InstallSnaps(ctx context.Context, snaps []string, channels map[string]string, progress func(format string, args ...any)) error {
+	if len(snaps) == 0 {
+		return nil
+	}
+	if err := commander.CheckCommandExists("snap"); err != nil {
+		return fmt.Errorf("cannot install %v: snapd is not installed", snaps)
+	}
+
+	for i, snap := range snaps {
+		args := []string{"install", snap}
+		channel := SnapChannel(snap, channels)
+		label := "default channel"
+		if channel != "" {
+			args = append(args, "--channel="+channel)
+			label = channel
+		}
+
+		progress("[%d/%d] Installing %s (%s)...", i+1, len(snaps), snap, label)
+		if _, err := commander.ExecCommandWithRetry(ctx, snapInstallOptions, "snap", args...); err != nil {
+			return fmt.Errorf("install %s: %w", snap, err)
+		}
+		progress("[%d/%d] %s installed", i+1, len(snaps), snap)
+	}
+	return nil
+}
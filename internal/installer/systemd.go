@@ -0,0 +1,151 @@
+package installer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const unitDir = "/etc/systemd/system" // Systemd unit file location
+
+// watchdogSec is WatchdogSec of notify services: systemd restarts one that
+// has not pinged the watchdog for this long.
+const watchdogSec = 30
+
+// systemdManager runs a service as a systemd unit.
+type systemdManager struct {
+	svc *service
+}
+
+func (m *systemdManager) unitPath() string {
+	return filepath.Join(unitDir, m.svc.name+".service")
+}
+
+func (m *systemdManager) Name() string { return "systemd" }
+
+// Install writes the unit file, reloads systemd and enables the service on boot.
+func (m *systemdManager) Install(configPath string) error {
+	if err := writeUnitFile(m.unitPath(), m.svc, configPath); err != nil {
+		return err
+	}
+	if err := run("systemctl", "daemon-reload"); err != nil {
+		return err
+	}
+	return run("systemctl", "enable", m.svc.name)
+}
+
+func (m *systemdManager) Start() error {
+	return run("systemctl", "start", m.svc.name)
+}
+
+func (m *systemdManager) Restart() error {
+	return run("systemctl", "restart", m.svc.name)
+}
+
+func (m *systemdManager) Stop() error {
+	// Ignore errors: the unit may not exist yet
+	_ = run("systemctl", "disable", "--now", m.svc.name)
+	return nil
+}
+
+func (m *systemdManager) Remove() error {
+	if _, err := os.Stat(m.unitPath()); os.IsNotExist(err) {
+		return nil
+	}
+	if err := removePath(m.unitPath()); err != nil {
+		return err
+	}
+	return run("systemctl", "daemon-reload")
+}
+
+// writeUnitFile creates the systemd unit file for svc (mcloudd or mcloud-agent).
+// The unit file configures the daemon to start after network is available,
+// restart automatically on failure, and start on boot. A notify service
+// (mcloudd) is only started once it reports READY=1 and is restarted when
+// it stops pinging the watchdog.
+//
+// Unit file configuration:
+//   [Unit] section:
+//     - Description: Human-readable service description (svc.description)
+//     - After: Wait for network.target before starting
+//     - Wants: Prefer network-online.target (non-blocking)
+//
+//   [Service] section:
+//     - Type: notify for svc.notify, else simple (process runs in foreground)
+//     - WatchdogSec, NotifyAccess: for svc.notify, restart if no ping for 30 seconds
+//     - ExecStart: Command to execute (svc.binary --config <configPath> svc.args...)
+//     - Restart: always (restart on any exit, including success)
+//     - RestartSec: 5 seconds delay before restart
+//
+//   [Install] section:
+//     - WantedBy: multi-user.target (start during normal boot)
+//
+// Returns:
+//   - nil if file is created successfully
+//   - error if write fails (permissions, disk full, etc.)
+//
+// Example Input:
+//   Unit path: /etc/systemd/system/mcloudd.service
+//   svc: daemonService
+//   configPath: /etc/mcloud/config.yaml
+//   File exists: false (or will be overwritten)
+//   User: root (UID 0)
+//
+// Example Output (Success):
+//   File created: /etc/systemd/system/mcloudd.service
+//   File mode: 0644 (rw-r--r--)
+//   File content:
+//     [Unit]
+//     Description=mcloud daemon
+//     After=network.target
+//     Wants=network-online.target
+//     
+//     [Service]
+//     Type=notify
+//     NotifyAccess=main
+//     WatchdogSec=30
+//     ExecStart=/usr/local/bin/mcloudd --config /etc/mcloud/config.yaml
+//     Restart=always
+//     RestartSec=5
+//     LimitNOFILE=1048576
+//     
+//     # Security (optional but should have)
+//     NoNewPrivileges=true
+//     PrivateTmp=true
+//     
+//     [Install]
+//     WantedBy=multi-user.target
+//
+// Example Output (Error):
+//   Returns: error("open /etc/systemd/system/mcloudd.service: permission denied")
+//   Cause: Non-root user or /etc/systemd/system not writable
+func writeUnitFile(path string, svc *service, configPath string) error {
+	// Define systemd unit file content
+	// [Unit]: Service metadata and dependencies
+	// [Service]: Execution configuration and restart policy
+	// [Install]: Boot-time behavior
+	content := `[Unit]
+Description=%s
+After=network.target
+Wants=network-online.target
+
+[Service]
+%sExecStart=%s
+Restart=always
+RestartSec=5
+LimitNOFILE=1048576
+
+# Security (optional but should have)
+NoNewPrivileges=true
+PrivateTmp=true
+
+[Install]
+WantedBy=multi-user.target
+`
+	serviceType := "Type=simple\n"
+	if svc.notify {
+		serviceType = fmt.Sprintf("Type=notify\nNotifyAccess=main\nWatchdogSec=%d\n", watchdogSec)
+	}
+	// Write unit file with mode 0644 (readable by all, writable by owner)
+	return os.WriteFile(path, []byte(fmt.Sprintf(content, svc.description, serviceType, svc.command(configPath))), 0644)
+}
@@ -0,0 +1,114 @@
+package installer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// installedService returns the service of an installed binary by name
+// (mcloudd or mcloud-agent), for the upgrade steps below.
+func installedService(name string) (*service, error) {
+	switch name {
+	case binaryName:
+		return daemonService, nil
+	case agentBinaryName:
+		// Restart does not rewrite the service definition, so the manager URL is not needed
+		return agentService(""), nil
+	}
+	return nil, fmt.Errorf("unknown binary %q: must be %s or %s", name, binaryName, agentBinaryName)
+}
+
+// IsInstalled reports whether the binary name (mcloudd or mcloud-agent) is
+// installed on this host.
+func IsInstalled(name string) bool {
+	svc, err := installedService(name)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(svc.binary)
+	return err == nil
+}
+
+// ReplaceBinary installs src as the binary name (mcloudd or mcloud-agent)
+// without a moment where the binary is missing or half-written: src is copied
+// next to it as <binary>.new, the installed binary is kept as <binary>.prev
+// for RollbackBinary, and <binary>.new is renamed over it. The running
+// service keeps its old binary until RestartService.
+//
+// Example Input:
+//   ReplaceBinary("mcloudd", "/tmp/mcloud-upgrade-123/mcloudd-linux-amd64")
+//
+// Example Output:
+//   Console output:
+//     ✔ copied mcloudd → /usr/local/bin/mcloudd.new
+//     ✔ replaced /usr/local/bin/mcloudd (previous kept as /usr/local/bin/mcloudd.prev)
+func ReplaceBinary(name, src string) error {
+	if os.Geteuid() != 0 {
+		return fmt.Errorf("must run as root")
+	}
+	svc, err := installedService(name)
+	if err != nil {
+		return err
+	}
+	dst := svc.binary
+	if _, err := os.Stat(dst); err != nil {
+		return fmt.Errorf("%s is not installed: %w", name, err)
+	}
+
+	if err := copyBinary(name, src, dst+".new"); err != nil {
+		return err
+	}
+	// A hard link keeps the installed binary as it is; the rename below only
+	// points dst at the new file
+	if err := os.Remove(dst + ".prev"); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := os.Link(dst, dst+".prev"); err != nil {
+		return fmt.Errorf("keep previous %s: %w", name, err)
+	}
+	if err := os.Rename(dst+".new", dst); err != nil {
+		return fmt.Errorf("replace %s: %w", dst, err)
+	}
+
+	fmt.Fprintf(out, "✔ replaced %s (previous kept as %s.prev)\n", dst, dst)
+	return nil
+}
+
+// RollbackBinary puts back the binary ReplaceBinary replaced. The service
+// must be restarted to run it again.
+//
+// Example Output (Error):
+//   Returns: error("no previous mcloudd to roll back to: stat /usr/local/bin/mcloudd.prev: no such file or directory")
+func RollbackBinary(name string) error {
+	if os.Geteuid() != 0 {
+		return fmt.Errorf("must run as root")
+	}
+	svc, err := installedService(name)
+	if err != nil {
+		return err
+	}
+	prev := svc.binary + ".prev"
+	if _, err := os.Stat(prev); err != nil {
+		return fmt.Errorf("no previous %s to roll back to: %w", name, err)
+	}
+	if err := os.Rename(prev, svc.binary); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "✔ restored %s from %s\n", svc.binary, prev)
+	return nil
+}
+
+// RestartService restarts the service of the binary name (mcloudd or
+// mcloud-agent) under the detected init system.
+func RestartService(name string) error {
+	if os.Geteuid() != 0 {
+		return fmt.Errorf("must run as root")
+	}
+	svc, err := installedService(name)
+	if err != nil {
+		return err
+	}
+	return detectServiceManager(svc).Restart()
+}
@@ -0,0 +1,43 @@
+package job
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"mcloud/internal/database"
+)
+
+const (
+	// operationRetention is how long finished operations stay queryable.
+	operationRetention = 7 * 24 * time.Hour
+
+	// deadLetterRetention is how long undelivered hook webhooks are kept.
+	deadLetterRetention = 30 * 24 * time.Hour
+
+	// alertRetention is how long resolved alerts stay in the alert history.
+	alertRetention = 90 * 24 * time.Hour
+)
+
+// GarbageCollect returns a job that removes expired bootstrap tokens and node certificates,
+// finished operations older than operationRetention, hook dead letters older
+// than deadLetterRetention and alerts resolved more than alertRetention ago.
+func GarbageCollect(db *sql.DB) Func {
+	return func(ctx context.Context) error {
+		now := time.Now()
+
+		if err := database.NewBootstrapTokenRepository(db).DeleteExpired(ctx, now); err != nil {
+			return err
+		}
+		if err := database.NewNodeCertificateRepository(db).DeleteExpired(ctx, now); err != nil {
+			return err
+		}
+		if err := database.NewOperationRepository(db).DeleteFinishedBefore(ctx, now.Add(-operationRetention)); err != nil {
+			return err
+		}
+		if err := database.NewHookDeadLetterRepository(db).DeleteBefore(ctx, now.Add(-deadLetterRetention)); err != nil {
+			return err
+		}
+		return database.NewAlertRepository(db).DeleteResolvedBefore(ctx, now.Add(-alertRetention))
+	}
+}
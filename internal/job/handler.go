@@ -0,0 +1,59 @@
+package job
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"mcloud/internal/database"
+	"mcloud/pkg/reason"
+)
+
+type Handler struct {
+	db *sql.DB
+}
+
+// JobStatus is the API view of a job_leases row, used to monitor job health.
+// The "leader" entry is the leadership lease (Holder is the manager running jobs);
+// the others are jobs, with Holder the manager that ran them last.
+type JobStatus struct {
+	Name          string     `json:"name"`
+	Holder        string     `json:"holder"`
+	LeaseExpires  time.Time  `json:"lease_expires_at"`
+	LastRunAt     *time.Time `json:"last_run_at"`
+	LastSuccessAt *time.Time `json:"last_success_at"`
+	LastError     *string    `json:"last_error"`
+}
+
+func NewHandler(db *sql.DB) *Handler {
+	return &Handler{db: db}
+}
+
+func (h *Handler) ListJobs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	leases, err := database.NewJobLeaseRepository(h.db).List(r.Context())
+	if err != nil {
+		reason.HTTPError(w, err, 500)
+		return
+	}
+
+	items := make([]JobStatus, 0, len(leases))
+	for _, l := range leases {
+		items = append(items, JobStatus{
+			Name:          l.Name,
+			Holder:        l.Holder,
+			LeaseExpires:  l.ExpiresAt,
+			LastRunAt:     l.LastRunAt,
+			LastSuccessAt: l.LastSuccessAt,
+			LastError:     l.LastError,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(items)
+}
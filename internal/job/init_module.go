@@ -0,0 +1,10 @@
+package job
+
+import (
+	"net/http"
+)
+
+// InitModule registers the job routes served by handler.
+func InitModule(mux *http.ServeMux, handler *Handler) {
+	mux.HandleFunc("/jobs", handler.ListJobs)
+}
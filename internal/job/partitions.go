@@ -0,0 +1,86 @@
+package job
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"mcloud/internal/config"
+	"mcloud/internal/database"
+)
+
+// defaultKeepMonths applies when database.partitions.keep_months is not set.
+const defaultKeepMonths = 12
+
+// CompactPartitions returns a job that keeps the events and audit log tables
+// small: rows of finished months are moved into monthly partitions, which
+// queries still read, and partitions older than keep_months are written to
+// archive files (<archive_dir>/<table>-YYYY-MM.db) and dropped.
+//
+// Example (2026-10-16, keep_months: 12):
+//   events rows of 2026-09  ->  events_2026_09
+//   events_2025_09          ->  /var/lib/mcloud/archive/events-2025-09.db, dropped
+func CompactPartitions(db *sql.DB, cfg config.Database) Func {
+	keep := cfg.Partitions.KeepMonths
+	if keep <= 0 {
+		keep = defaultKeepMonths
+	}
+	dir := cfg.Partitions.ArchiveDir
+	if dir == "" {
+		dir = filepath.Join(filepath.Dir(cfg.DBPath), "archive")
+	}
+
+	return func(ctx context.Context) error {
+		now := time.Now().UTC()
+		return compactPartitions(ctx, db, now, keep, dir)
+	}
+}
+
+func compactPartitions(ctx context.Context, db *sql.DB, now time.Time, keep int, dir string) error {
+	repo := database.NewPartitionRepository(db)
+	thisMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	oldest := thisMonth.AddDate(0, -keep, 0).Format("2006-01")
+
+	for _, base := range database.PartitionedTables() {
+		// 1. Move finished months out of the base table
+		months, err := repo.UnpartitionedMonths(ctx, base, thisMonth)
+		if err != nil {
+			return err
+		}
+		for _, month := range months {
+			moved, err := repo.Partition(ctx, base, month)
+			if errors.Is(err, database.ErrPartitionArchived) {
+				log.Warn("Rows of %s %s arrived after the month was archived; leaving them in %s", base, month, base)
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("partition %s %s: %w", base, month, err)
+			}
+			log.Info("Moved %d rows of %s %s to %s", moved, base, month, database.PartitionName(base, month))
+		}
+
+		// 2. Archive and drop the partitions past the retention
+		partitions, err := repo.List(ctx, base)
+		if err != nil {
+			return err
+		}
+		for _, p := range partitions {
+			if !p.Live() || p.Month >= oldest {
+				continue
+			}
+			if err := os.MkdirAll(dir, 0700); err != nil {
+				return err
+			}
+			path := filepath.Join(dir, base+"-"+p.Month+".db")
+			if err := repo.Archive(ctx, p.Name, path); err != nil {
+				return fmt.Errorf("archive %s: %w", p.Name, err)
+			}
+			log.Info("Archived %s (%d rows) to %s", p.Name, p.RowCount, path)
+		}
+	}
+	return nil
+}
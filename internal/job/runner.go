@@ -0,0 +1,183 @@
+// Package job runs periodic background jobs (reconciler, GC, cron, cert renewal)
+// on exactly one manager at a time: the leader.
+//
+// Leadership is a single row ("leader") in the job_leases table with a short TTL.
+// Every manager tries to take or renew it every leaderRenewInterval, independently
+// of the job intervals, and only the holder runs jobs. If the leader dies, its lease
+// expires within leaderLeaseTTL and another manager takes over every job at once.
+// Each job's last run is recorded in a job_leases row of its own name.
+package job
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"mcloud/internal/database"
+	"mcloud/pkg/logger"
+)
+
+var log = logger.Named("job")
+
+const (
+	// leaderLease is the job_leases row holding the leadership.
+	leaderLease = "leader"
+
+	// leaderLeaseTTL is how long the leadership is valid without renewal: how long
+	// jobs stop running after the leader dies.
+	leaderLeaseTTL = 15 * time.Second
+
+	// leaderRenewInterval is how often managers take or renew the leadership.
+	// It is well below leaderLeaseTTL so one slow renewal does not lose it.
+	leaderRenewInterval = 5 * time.Second
+)
+
+// Func is the work performed by a job on each run.
+type Func func(ctx context.Context) error
+
+type job struct {
+	name     string
+	interval time.Duration
+	fn       Func
+}
+
+// Runner schedules registered jobs and runs them while this manager is the leader.
+type Runner struct {
+	db     *sql.DB
+	holder string // identity of this manager process in job_leases.holder
+
+	mu          sync.Mutex
+	jobs        []job
+	leaderUntil time.Time // when this manager's leadership lapses unless renewed; zero if not the leader
+}
+
+// NewRunner creates a Runner identified by hostname and PID, so two daemons on
+// the same host (e.g. during an upgrade overlap) never share the leadership.
+func NewRunner(db *sql.DB) *Runner {
+	hostname, _ := os.Hostname()
+	return &Runner{
+		db:     db,
+		holder: fmt.Sprintf("%s/%d", hostname, os.Getpid()),
+	}
+}
+
+// Register adds a job that should run every interval on the leader.
+// Must be called before Start.
+func (r *Runner) Register(name string, interval time.Duration, fn Func) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.jobs = append(r.jobs, job{name: name, interval: interval, fn: fn})
+}
+
+// IsLeader reports whether this manager currently holds the leadership.
+func (r *Runner) IsLeader() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return time.Now().Before(r.leaderUntil)
+}
+
+// Start takes part in the leader election and runs every registered job in its
+// own goroutine until ctx is cancelled. The leadership is released on shutdown
+// for fast takeover.
+func (r *Runner) Start(ctx context.Context) {
+	r.mu.Lock()
+	jobs := append([]job(nil), r.jobs...)
+	r.mu.Unlock()
+
+	leaseRepo := database.NewJobLeaseRepository(r.db)
+
+	// Settle leadership before the first job tick
+	r.renewLeadership(ctx, leaseRepo)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		r.lead(ctx, leaseRepo)
+	}()
+	for _, j := range jobs {
+		wg.Add(1)
+		go func(j job) {
+			defer wg.Done()
+			r.loop(ctx, leaseRepo, j)
+		}(j)
+	}
+	wg.Wait()
+}
+
+// lead renews (or tries to take) the leadership every leaderRenewInterval.
+func (r *Runner) lead(ctx context.Context, leaseRepo *database.JobLeaseRepository) {
+	ticker := time.NewTicker(leaderRenewInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			_ = leaseRepo.Release(context.Background(), leaderLease, r.holder)
+			return
+		case <-ticker.C:
+		}
+		r.renewLeadership(ctx, leaseRepo)
+	}
+}
+
+func (r *Runner) renewLeadership(ctx context.Context, leaseRepo *database.JobLeaseRepository) {
+	// The lease is counted from before the write, so this manager never believes
+	// it leads for longer than the row says
+	start := time.Now()
+	acquired, err := leaseRepo.TryAcquire(ctx, leaderLease, r.holder, leaderLeaseTTL)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		// Keep the current lease; it lapses on its own if renewals keep failing
+		log.Error("failed to renew job leadership: %v", err)
+		return
+	}
+	wasLeader := start.Before(r.leaderUntil)
+	switch {
+	case acquired:
+		r.leaderUntil = start.Add(leaderLeaseTTL)
+		if !wasLeader {
+			log.Info("%s is now the job leader", r.holder)
+		}
+	default:
+		r.leaderUntil = time.Time{}
+		if wasLeader {
+			log.Warn("%s lost the job leadership", r.holder)
+		}
+	}
+}
+
+func (r *Runner) loop(ctx context.Context, leaseRepo *database.JobLeaseRepository, j job) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		r.tick(ctx, leaseRepo, j)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Runner) tick(ctx context.Context, leaseRepo *database.JobLeaseRepository, j job) {
+	if !r.IsLeader() {
+		// Another manager is the leader
+		return
+	}
+
+	runErr := j.fn(ctx)
+	if runErr != nil {
+		log.Error("job %s failed: %v", j.name, runErr)
+	}
+	if err := leaseRepo.RecordRun(ctx, j.name, r.holder, runErr); err != nil {
+		log.Error("job %s: failed to record run: %v", j.name, err)
+	}
+}
@@ -0,0 +1,111 @@
+package loadbalancer
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"mcloud/internal/auth"
+	"mcloud/internal/project"
+	"mcloud/pkg/reason"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(s *Service) *Handler {
+	return &Handler{service: s}
+}
+
+// writeError maps service errors to HTTP status codes.
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrInvalid):
+		reason.HTTPError(w, err, 400)
+	case errors.Is(err, project.ErrProjectDenied):
+		reason.HTTPError(w, err, 403)
+	case errors.Is(err, ErrNotFound):
+		reason.HTTPError(w, err, 404)
+	case errors.Is(err, ErrExists):
+		reason.HTTPError(w, err, 409)
+	default:
+		reason.HTTPError(w, err, 500)
+	}
+}
+
+// ListLoadBalancers lists the load balancers whose selectors only match
+// projects the caller is a member of.
+func (h *Handler) ListLoadBalancers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	items, err := h.service.List(r.Context(), auth.ClientIdentity(r))
+	if err != nil {
+		reason.HTTPError(w, err, 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(items)
+}
+
+func (h *Handler) CreateLoadBalancer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req CreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		reason.HTTPError(w, err, 400)
+		return
+	}
+
+	req.Project = project.FromRequest(r, req.Project)
+	actor := auth.ClientIdentity(r)
+	lb, err := h.service.Create(r.Context(), &req, &actor)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(lb)
+}
+
+func (h *Handler) GetLoadBalancer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	lb, err := h.service.Get(r.Context(), r.PathValue("name"))
+	if err == nil {
+		err = h.service.Authorize(r.Context(), lb, auth.ClientIdentity(r))
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(lb)
+}
+
+func (h *Handler) DeleteLoadBalancer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	actor := auth.ClientIdentity(r)
+	if err := h.service.Delete(r.Context(), r.PathValue("name"), &actor); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,13 @@
+package loadbalancer
+
+import (
+	"net/http"
+)
+
+// InitModule registers the load balancer routes served by handler.
+func InitModule(mux *http.ServeMux, handler *Handler) {
+	mux.HandleFunc("GET /load-balancers", handler.ListLoadBalancers)
+	mux.HandleFunc("POST /load-balancers", handler.CreateLoadBalancer)
+	mux.HandleFunc("GET /load-balancers/{name}", handler.GetLoadBalancer)
+	mux.HandleFunc("DELETE /load-balancers/{name}", handler.DeleteLoadBalancer)
+}
@@ -0,0 +1,365 @@
+// Package loadbalancer balances a VIP over workloads: each load balancer is a
+// VIP, protocol and port on the workloads' OVN network, programmed as an LXD
+// network load balancer (an OVN load balancer) whose backends are the running
+// workloads its selectors match. Load balancers are stored in the
+// load_balancers table; Sync keeps their backends in step as workloads are
+// created, stopped and deleted.
+package loadbalancer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/netip"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"mcloud/internal/config"
+	"mcloud/internal/database"
+	"mcloud/internal/network"
+	"mcloud/internal/project"
+	"mcloud/pkg/logger"
+	"mcloud/pkg/reason"
+	"mcloud/services/lxd"
+)
+
+var log = logger.Named("loadbalancer")
+
+var (
+	ErrNotFound = reason.New(reason.LoadBalancerNotFound, "load balancer not found")
+	ErrExists   = reason.New(reason.LoadBalancerExists, "load balancer already exists")
+	ErrInvalid  = reason.New(reason.InvalidLoadBalancer, "invalid load balancer")
+)
+
+var nameRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+type Service struct {
+	db       *sql.DB
+	network  string
+	projects *project.Service
+
+	mu sync.Mutex // serializes changes to the LXD load balancers
+}
+
+// CreateRequest defines a new load balancer. Protocol defaults to tcp,
+// TargetPort to Port, Project to default, a selector's project to Project and
+// its name to *.
+//
+// Example JSON:
+//   {"name": "web", "vip": "203.0.113.10", "protocol": "tcp", "port": 80, "target_port": 8080,
+//    "selectors": [{"project": "default", "name": "web-*"}]}
+type CreateRequest struct {
+	Name       string                          `json:"name"`
+	Project    string                          `json:"project,omitempty"`
+	VIP        string                          `json:"vip"`
+	Protocol   string                          `json:"protocol,omitempty"`
+	Port       int                             `json:"port"`
+	TargetPort int                             `json:"target_port,omitempty"`
+	Selectors  []database.LoadBalancerSelector `json:"selectors"`
+}
+
+func NewService(db *sql.DB, cfg config.Network, projects *project.Service) *Service {
+	return &Service{db: db, network: network.OVNNetwork(cfg), projects: projects}
+}
+
+// ParseSelector parses the command-line form of a selector, [project/]name,
+// where name may be a glob.
+//
+// Example:
+//   ParseSelector("web-*")        // {Project: "", Name: "web-*"}, the request's project
+//   ParseSelector("team-a/api-1") // {Project: "team-a", Name: "api-1"}
+func ParseSelector(s string) database.LoadBalancerSelector {
+	project, name, ok := strings.Cut(s, "/")
+	if !ok {
+		project, name = "", s
+	}
+	return database.LoadBalancerSelector{Project: project, Name: name}
+}
+
+// normalize validates req, applying its defaults, and returns its VIP.
+func normalize(req *CreateRequest) (netip.Addr, error) {
+	if !nameRegexp.MatchString(req.Name) {
+		return netip.Addr{}, fmt.Errorf("%w: name must match [a-z0-9][a-z0-9-]* (at most 63 characters)", ErrInvalid)
+	}
+	vip, err := netip.ParseAddr(req.VIP)
+	if err != nil || vip.Zone() != "" {
+		return netip.Addr{}, fmt.Errorf("%w: vip %q is not an IP address", ErrInvalid, req.VIP)
+	}
+	vip = vip.Unmap()
+	if req.Protocol == "" {
+		req.Protocol = "tcp"
+	}
+	if req.Protocol != "tcp" && req.Protocol != "udp" {
+		return netip.Addr{}, fmt.Errorf("%w: protocol must be tcp or udp, got %q", ErrInvalid, req.Protocol)
+	}
+	if req.TargetPort == 0 {
+		req.TargetPort = req.Port
+	}
+	if req.Port < 1 || req.Port > 65535 || req.TargetPort < 1 || req.TargetPort > 65535 {
+		return netip.Addr{}, fmt.Errorf("%w: port and target_port must be 1-65535", ErrInvalid)
+	}
+	if len(req.Selectors) == 0 {
+		return netip.Addr{}, fmt.Errorf("%w: at least one selector is required", ErrInvalid)
+	}
+	if req.Project == "" {
+		req.Project = database.DefaultProject
+	}
+	for i := range req.Selectors {
+		sel := &req.Selectors[i]
+		if sel.Project == "" {
+			sel.Project = req.Project
+		}
+		if sel.Name == "" {
+			sel.Name = "*"
+		}
+		if _, err := path.Match(sel.Name, ""); err != nil {
+			return netip.Addr{}, fmt.Errorf("%w: selector %d: name %q is not a valid pattern", ErrInvalid, i+1, sel.Name)
+		}
+	}
+	return vip, nil
+}
+
+// Authorize returns project.ErrProjectDenied unless identity is a member of
+// the project of every selector of lb, so a load balancer only reaches
+// workloads its caller may manage.
+func (s *Service) Authorize(ctx context.Context, lb *database.LoadBalancer, identity string) error {
+	seen := map[string]bool{}
+	for _, sel := range lb.Selectors {
+		if seen[sel.Project] {
+			continue
+		}
+		seen[sel.Project] = true
+		if err := s.projects.Authorize(ctx, sel.Project, identity, database.ProjectRoleMember); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Create programs the load balancer with the workloads its selectors match
+// now and records it. The actor must be a member of the project of every
+// selector.
+//
+// Example Output (Error):
+//   {"name": "web", ...}                    =>  ErrExists: web
+//   {"vip": "203.0.113.17", ...}            =>  ErrExists: 203.0.113.17 is a floating ip
+//   {"selectors": [{"name": "web-["}], ...} =>  ErrInvalid: selector 1: name "web-[" is not a valid pattern
+//   {"selectors": [{"project": "team-b"}]}  =>  ErrProjectDenied: ci-runner has no role in team-b
+func (s *Service) Create(ctx context.Context, req *CreateRequest, actor *string) (*database.LoadBalancer, error) {
+	vip, err := normalize(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Authorize(ctx, &database.LoadBalancer{Selectors: req.Selectors}, deref(actor)); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo := database.NewLoadBalancerRepository(s.db)
+	if _, err := repo.Get(ctx, req.Name); err == nil {
+		return nil, fmt.Errorf("%w: %s", ErrExists, req.Name)
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if lb, err := repo.GetByVIP(ctx, vip.String()); err == nil {
+		return nil, fmt.Errorf("%w: %s is the vip of %s", ErrExists, vip, lb.Name)
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if _, err := database.NewFloatingIPRepository(s.db).Get(ctx, vip.String()); err == nil {
+		return nil, fmt.Errorf("%w: %s is a floating ip", ErrExists, vip)
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	lb := &database.LoadBalancer{
+		Name: req.Name, VIP: vip.String(), Network: s.network, Protocol: req.Protocol,
+		Port: req.Port, TargetPort: req.TargetPort, Selectors: req.Selectors, CreateUserID: actor,
+	}
+	workloads, err := runningWorkloads(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+	backends := Backends(lb, workloads)
+	if err := lxd.ApplyLoadBalancer(ctx, lb.Network, lbConfig(lb, backends)); err != nil {
+		return nil, err
+	}
+	lb.Backends = addresses(backends)
+	err = database.WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		return database.NewLoadBalancerRepositoryTx(tx).Create(ctx, lb)
+	})
+	if err != nil {
+		if delErr := lxd.DeleteLoadBalancer(ctx, lb.Network, lb.VIP); delErr != nil {
+			log.Warn("Removing load balancer %s after failed insert: %v", lb.VIP, delErr)
+		}
+		return nil, err
+	}
+	log.Info("Load balancer %s created on %s %s/%d with %d backends", lb.Name, lb.VIP, lb.Protocol, lb.Port, len(backends))
+	return repo.Get(ctx, lb.Name)
+}
+
+// List returns the load balancers identity may read (see Authorize) with
+// their selectors and backends.
+func (s *Service) List(ctx context.Context, identity string) ([]database.LoadBalancer, error) {
+	lbs, err := database.NewLoadBalancerRepository(s.db).List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	items := []database.LoadBalancer{}
+	for _, lb := range lbs {
+		err := s.Authorize(ctx, &lb, identity)
+		if errors.Is(err, project.ErrProjectDenied) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, lb)
+	}
+	return items, nil
+}
+
+// Get returns a load balancer with its selectors and backends.
+func (s *Service) Get(ctx context.Context, name string) (*database.LoadBalancer, error) {
+	lb, err := database.NewLoadBalancerRepository(s.db).Get(ctx, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return lb, err
+}
+
+// Delete removes the LXD load balancer and the record of a load balancer.
+// The actor must be a member of the project of every selector.
+func (s *Service) Delete(ctx context.Context, name string, actor *string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lb, err := s.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+	if err := s.Authorize(ctx, lb, deref(actor)); err != nil {
+		return err
+	}
+	if err := lxd.DeleteLoadBalancer(ctx, lb.Network, lb.VIP); err != nil {
+		return err
+	}
+	err = database.WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		return database.NewLoadBalancerRepositoryTx(tx).Delete(ctx, name)
+	})
+	if err != nil {
+		return err
+	}
+	log.Info("Load balancer %s on %s deleted", lb.Name, lb.VIP)
+	return nil
+}
+
+// Sync is the periodic job that reapplies every load balancer with the
+// running workloads its selectors match, so backends follow workloads as they
+// are created, stopped and deleted, and LXD changes made by hand are undone.
+func (s *Service) Sync(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo := database.NewLoadBalancerRepository(s.db)
+	lbs, err := repo.List(ctx)
+	if err != nil || len(lbs) == 0 {
+		return err
+	}
+	workloads, err := runningWorkloads(ctx, s.db)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for i := range lbs {
+		lb := &lbs[i]
+		backends := Backends(lb, workloads)
+		if err := lxd.ApplyLoadBalancer(ctx, lb.Network, lbConfig(lb, backends)); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if current := addresses(backends); !slices.Equal(current, lb.Backends) {
+			if err := repo.UpdateBackends(ctx, lb.Name, current); err != nil {
+				return err
+			}
+			log.Info("Load balancer %s backends: %v", lb.Name, current)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func runningWorkloads(ctx context.Context, db *sql.DB) ([]database.Workload, error) {
+	return database.NewWorkloadRepository(db).List(ctx, database.ListOptions{Filter: map[string]string{"status": "running"}})
+}
+
+// Backends returns the backends of lb among workloads, by address: every
+// running workload matching one of its selectors, at its first address of the
+// VIP's family (see workload.SyncAddresses). Workloads without such an address
+// are left out until they have one.
+func Backends(lb *database.LoadBalancer, workloads []database.Workload) []lxd.LoadBalancerBackend {
+	vip, err := netip.ParseAddr(lb.VIP)
+	if err != nil {
+		return nil
+	}
+	var backends []lxd.LoadBalancerBackend
+	for _, w := range workloads {
+		if w.Status != "running" || !selected(lb.Selectors, &w) {
+			continue
+		}
+		for _, a := range w.Addresses {
+			addr, err := netip.ParseAddr(a)
+			if err != nil || addr.Is4() != vip.Is4() || addr.IsLinkLocalUnicast() {
+				continue
+			}
+			backends = append(backends, lxd.LoadBalancerBackend{Name: w.ID, Description: w.Project + "/" + w.Name, TargetAddress: addr.String()})
+			break
+		}
+	}
+	slices.SortFunc(backends, func(a, b lxd.LoadBalancerBackend) int {
+		return netip.MustParseAddr(a.TargetAddress).Compare(netip.MustParseAddr(b.TargetAddress))
+	})
+	return backends
+}
+
+// selected reports whether w matches any of selectors.
+func selected(selectors []database.LoadBalancerSelector, w *database.Workload) bool {
+	for _, sel := range selectors {
+		if ok, _ := path.Match(sel.Name, w.Name); ok && sel.Project == w.Project {
+			return true
+		}
+	}
+	return false
+}
+
+func lbConfig(lb *database.LoadBalancer, backends []lxd.LoadBalancerBackend) lxd.LoadBalancerConfig {
+	return lxd.LoadBalancerConfig{
+		ListenAddress: lb.VIP,
+		Description:   "mcloud load balancer " + lb.Name,
+		Protocol:      lb.Protocol,
+		ListenPort:    lb.Port,
+		TargetPort:    lb.TargetPort,
+		Backends:      backends,
+	}
+}
+
+func addresses(backends []lxd.LoadBalancerBackend) database.AddressList {
+	list := database.AddressList{}
+	for _, b := range backends {
+		list = append(list, b.TargetAddress)
+	}
+	return list
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
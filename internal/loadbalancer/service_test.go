@@ -0,0 +1,170 @@
+package loadbalancer
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"mcloud/internal/config"
+	"mcloud/internal/database"
+	"mcloud/internal/project"
+)
+
+func TestBackends(t *testing.T) {
+	workloads := []database.Workload{
+		{ID: "w-1", Name: "web-1", Project: "default", Status: "running", Addresses: database.AddressList{"10.0.0.10", "fd42::10"}},
+		{ID: "w-2", Name: "web-2", Project: "default", Status: "running", Addresses: database.AddressList{"fe80::2", "fd42::2", "10.0.0.2"}},
+		// Stopped
+		{ID: "w-3", Name: "web-3", Project: "default", Status: "stopped", Addresses: database.AddressList{"10.0.0.3"}},
+		// No address yet
+		{ID: "w-4", Name: "web-4", Project: "default", Status: "running"},
+		// Another project
+		{ID: "w-5", Name: "web-5", Project: "team-a", Status: "running", Addresses: database.AddressList{"10.0.0.5"}},
+		{ID: "w-6", Name: "api", Project: "team-a", Status: "running", Addresses: database.AddressList{"10.0.0.6"}},
+		{ID: "w-7", Name: "db-1", Project: "default", Status: "running", Addresses: database.AddressList{"10.0.0.7"}},
+	}
+	selectors := []database.LoadBalancerSelector{{Project: "default", Name: "web-*"}, {Project: "team-a", Name: "api"}}
+
+	tests := []struct {
+		vip  string
+		want []string
+	}{
+		// Sorted by address, not by name
+		{"203.0.113.10", []string{"w-2 10.0.0.2", "w-6 10.0.0.6", "w-1 10.0.0.10"}},
+		{"2001:db8::10", []string{"w-2 fd42::2", "w-1 fd42::10"}},
+	}
+	for _, tt := range tests {
+		lb := &database.LoadBalancer{Name: "web", VIP: tt.vip, Selectors: selectors}
+		var got []string
+		for _, b := range Backends(lb, workloads) {
+			got = append(got, b.Name+" "+b.TargetAddress)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Backends(%s) = %v, want %v", tt.vip, got, tt.want)
+		}
+	}
+}
+
+func TestParseSelector(t *testing.T) {
+	if sel := ParseSelector("team-a/web-*"); sel.Project != "team-a" || sel.Name != "web-*" {
+		t.Errorf("ParseSelector(team-a/web-*) = %+v", sel)
+	}
+	if sel := ParseSelector("web-*"); sel.Project != "" || sel.Name != "web-*" {
+		t.Errorf("ParseSelector(web-*) = %+v", sel)
+	}
+}
+
+func TestCreateErrors(t *testing.T) {
+	db, err := database.Connect(filepath.Join(t.TempDir(), "mcloud.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close(db)
+	ctx := context.Background()
+	s := NewService(db, config.Network{}, project.NewService(db, &config.Config{}))
+
+	valid := func() *CreateRequest {
+		return &CreateRequest{Name: "web", VIP: "203.0.113.10", Port: 80, Selectors: []database.LoadBalancerSelector{{Name: "web-*"}}}
+	}
+	for name, change := range map[string]func(*CreateRequest){
+		"name":      func(r *CreateRequest) { r.Name = "Web" },
+		"vip":       func(r *CreateRequest) { r.VIP = "web.example.com" },
+		"protocol":  func(r *CreateRequest) { r.Protocol = "sctp" },
+		"port":      func(r *CreateRequest) { r.Port = 0 },
+		"target":    func(r *CreateRequest) { r.TargetPort = 70000 },
+		"selectors": func(r *CreateRequest) { r.Selectors = nil },
+		"pattern":   func(r *CreateRequest) { r.Selectors[0].Name = "web-[" },
+	} {
+		req := valid()
+		change(req)
+		if _, err := s.Create(ctx, req, nil); !errors.Is(err, ErrInvalid) {
+			t.Errorf("invalid %s: got %v, want ErrInvalid", name, err)
+		}
+	}
+
+	req := valid()
+	if _, err := normalize(req); err != nil {
+		t.Fatal(err)
+	}
+	if req.Protocol != "tcp" || req.TargetPort != 80 || req.Selectors[0].Project != "default" {
+		t.Errorf("defaults not applied: %+v", req)
+	}
+	req = valid()
+	req.Project = "team-a"
+	if _, err := normalize(req); err != nil || req.Selectors[0].Project != "team-a" {
+		t.Errorf("selector project = %q, %v; want the request's team-a", req.Selectors[0].Project, err)
+	}
+
+	if err := database.NewLoadBalancerRepository(db).Create(ctx, &database.LoadBalancer{Name: "web", VIP: "203.0.113.9", Network: "default", Protocol: "tcp", Port: 80, TargetPort: 80}); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.NewFloatingIPRepository(db).Create(ctx, &database.FloatingIP{Address: "203.0.113.17", WorkloadID: "w-1", Network: "default", TargetAddress: "10.0.0.5"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, req := range []*CreateRequest{
+		valid(),
+		{Name: "web2", VIP: "203.0.113.9", Port: 80, Selectors: []database.LoadBalancerSelector{{}}},
+		{Name: "web2", VIP: "203.0.113.17", Port: 80, Selectors: []database.LoadBalancerSelector{{}}},
+	} {
+		if _, err := s.Create(ctx, req, nil); !errors.Is(err, ErrExists) {
+			t.Errorf("create %s on %s: got %v, want ErrExists", req.Name, req.VIP, err)
+		}
+	}
+
+	if err := s.Delete(ctx, "missing", nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("delete missing: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	db, err := database.Connect(filepath.Join(t.TempDir(), "mcloud.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close(db)
+	ctx := context.Background()
+	s := NewService(db, config.Network{}, project.NewService(db, &config.Config{}))
+
+	projects := database.NewProjectRepository(db)
+	if err := projects.Create(ctx, &database.Project{Name: "team-a"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := projects.PutBinding(ctx, &database.ProjectBinding{Project: "team-a", Identity: "alice", Role: database.ProjectRoleMember}); err != nil {
+		t.Fatal(err)
+	}
+	repo := database.NewLoadBalancerRepository(db)
+	for _, lb := range []*database.LoadBalancer{
+		{Name: "web", VIP: "203.0.113.10", Network: "default", Protocol: "tcp", Port: 80, TargetPort: 80,
+			Selectors: []database.LoadBalancerSelector{{Project: "default", Name: "web-*"}, {Project: "team-a", Name: "api"}}},
+		{Name: "dns", VIP: "203.0.113.11", Network: "default", Protocol: "udp", Port: 53, TargetPort: 53,
+			Selectors: []database.LoadBalancerSelector{{Project: "default", Name: "dns-*"}}},
+	} {
+		if err := repo.Create(ctx, lb); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var names []string
+	lbs, err := s.List(ctx, "mallory")
+	for _, lb := range lbs {
+		names = append(names, lb.Name)
+	}
+	if err != nil || !slices.Equal(names, []string{"dns"}) {
+		t.Errorf("List(mallory) = %v, %v; want dns only", names, err)
+	}
+	if lbs, err := s.List(ctx, "alice"); err != nil || len(lbs) != 2 {
+		t.Errorf("List(alice) = %d load balancers, %v; want 2", len(lbs), err)
+	}
+
+	// Selectors may not reach into projects the caller has no role in
+	mallory := "mallory"
+	req := &CreateRequest{Name: "api", VIP: "203.0.113.12", Port: 80, Project: "team-a", Selectors: []database.LoadBalancerSelector{{Name: "api"}}}
+	if _, err := s.Create(ctx, req, &mallory); !errors.Is(err, project.ErrProjectDenied) {
+		t.Errorf("create by mallory in team-a: got %v, want ErrProjectDenied", err)
+	}
+	if err := s.Delete(ctx, "web", &mallory); !errors.Is(err, project.ErrProjectDenied) {
+		t.Errorf("delete web by mallory: got %v, want ErrProjectDenied", err)
+	}
+}
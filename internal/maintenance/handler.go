@@ -0,0 +1,114 @@
+package maintenance
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"mcloud/internal/audit"
+	"mcloud/internal/auth"
+	"mcloud/internal/command"
+	"mcloud/internal/operation"
+	"mcloud/pkg/reason"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(s *Service) *Handler {
+	return &Handler{service: s}
+}
+
+// writeError maps service errors to HTTP status codes.
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidWindow):
+		reason.HTTPError(w, err, 400)
+	case errors.Is(err, ErrWindowNotFound):
+		reason.HTTPError(w, err, 404)
+	case errors.Is(err, ErrRunning), errors.Is(err, ErrNoCluster), errors.Is(err, command.ErrNotConnected):
+		reason.HTTPError(w, err, 409)
+	default:
+		reason.HTTPError(w, err, 500)
+	}
+}
+
+// GetWindow returns the cluster's maintenance window with its next opening.
+func (h *Handler) GetWindow(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	window, err := h.service.GetWindow(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(window)
+}
+
+// SetWindow creates or replaces the cluster's maintenance window (see WindowRequest).
+func (h *Handler) SetWindow(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req WindowRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		reason.HTTPError(w, err, 400)
+		return
+	}
+
+	window, err := h.service.SetWindow(r.Context(), &req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(window)
+}
+
+func (h *Handler) DeleteWindow(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := h.service.DeleteWindow(r.Context()); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Run starts a rollout now, outside the window, and answers 202 with the
+// operation that tracks it. The body (a RunRequest) is optional.
+func (h *Handler) Run(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req RunRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			reason.HTTPError(w, err, 400)
+			return
+		}
+	}
+
+	actor := auth.ClientIdentity(r)
+	op, err := h.service.Run(r.Context(), &req, &actor)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	audit.SetTarget(r, "/operations/"+op.ID)
+	operation.WriteAccepted(w, op, op)
+}
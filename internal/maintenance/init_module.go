@@ -0,0 +1,13 @@
+package maintenance
+
+import (
+	"net/http"
+)
+
+// InitModule registers the maintenance routes served by handler.
+func InitModule(mux *http.ServeMux, handler *Handler) {
+	mux.HandleFunc("GET /cluster/maintenance", handler.GetWindow)
+	mux.HandleFunc("PUT /cluster/maintenance", handler.SetWindow)
+	mux.HandleFunc("DELETE /cluster/maintenance", handler.DeleteWindow)
+	mux.HandleFunc("POST /cluster/maintenance/run", handler.Run)
+}
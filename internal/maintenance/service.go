@@ -0,0 +1,461 @@
+// Package maintenance upgrades the cluster's snaps (lxd, microceph, microovn)
+// in a weekly maintenance window. A rollout refreshes one node at a time:
+// cordon (evacuate its instances), refresh, health-check, uncordon. A node
+// that fails stays cordoned and the rollout stops, so at most one node is
+// ever out of service; nodes not reached before the window closes wait for
+// the next one.
+package maintenance
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"mcloud/internal/agent"
+	"mcloud/internal/command"
+	"mcloud/internal/constant"
+	"mcloud/internal/database"
+	"mcloud/internal/event"
+	"mcloud/internal/node"
+	"mcloud/internal/operation"
+	"mcloud/pkg/logger"
+	"mcloud/pkg/reason"
+)
+
+var log = logger.Named("maintenance")
+
+// Interval is how often the maintenance job checks for opened windows.
+const Interval = time.Minute
+
+// nodeTimeout bounds the refresh of one node; a rollout's operation times out
+// after nodeTimeout per node.
+const nodeTimeout = 30 * time.Minute
+
+// maxDuration keeps a window shorter than the week it repeats in.
+const maxDuration = 24 * time.Hour
+
+// DefaultSnaps are refreshed when a window or run names none, in this order.
+var DefaultSnaps = []string{"lxd", "microceph", "microovn"}
+
+var (
+	ErrWindowNotFound = reason.New(reason.MaintenanceWindowNotFound, "cluster has no maintenance window")
+	ErrInvalidWindow  = reason.New(reason.InvalidMaintenanceWindow, "invalid maintenance window")
+	ErrRunning        = reason.New(reason.MaintenanceRunning, "maintenance is already running")
+	ErrNoCluster      = reason.New(reason.ClusterNotInitialized, "cluster is not initialized")
+)
+
+var weekdays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// WindowRequest sets the cluster's weekly maintenance window. Start is "HH:MM"
+// in the manager's local time.
+//
+// Example JSON:
+//   {"day": "sun", "start": "02:00", "duration_minutes": 240, "snaps": ["lxd", "microovn"]}
+type WindowRequest struct {
+	Day             string   `json:"day"`
+	Start           string   `json:"start"`
+	DurationMinutes int      `json:"duration_minutes"`
+	Snaps           []string `json:"snaps,omitempty"` // default DefaultSnaps
+}
+
+// RunRequest starts a rollout now, outside the window.
+//
+// Example JSON:
+//   {"snaps": ["lxd"]}
+type RunRequest struct {
+	Snaps []string `json:"snaps,omitempty"` // default the window's snaps, else DefaultSnaps
+}
+
+// Window is a maintenance window with its next opening.
+type Window struct {
+	database.MaintenanceWindow
+	NextOpeningAt time.Time `json:"next_opening_at"`
+}
+
+// NodeResult is what a rollout did on one node.
+type NodeResult struct {
+	NodeID   string                    `json:"node_id"`
+	Hostname string                    `json:"hostname"`
+	Snaps    []agent.SnapRefreshResult `json:"snaps"`
+}
+
+// Result is the result of a "maintenance.refresh" operation.
+//
+// Example JSON:
+//   {"cluster_id": "c1", "refreshed": [{"node_id": "n2", "hostname": "node2", "snaps": [...]}],
+//    "skipped": ["node4"], "deferred": ["node3"]}
+type Result struct {
+	ClusterID string       `json:"cluster_id"`
+	Refreshed []NodeResult `json:"refreshed"`
+	Skipped   []string     `json:"skipped"`  // nodes that were already drained
+	Deferred  []string     `json:"deferred"` // nodes left for the next window
+}
+
+type Service struct {
+	db         *sql.DB
+	nodes      *node.Service
+	commands   *command.Hub
+	operations *operation.Service
+
+	mu      sync.Mutex
+	running map[string]bool // clusters with a rollout in progress
+}
+
+func NewService(db *sql.DB, nodes *node.Service, commands *command.Hub, operations *operation.Service) *Service {
+	return &Service{
+		db:         db,
+		nodes:      nodes,
+		commands:   commands,
+		operations: operations,
+		running:    map[string]bool{},
+	}
+}
+
+// lastOpening returns the most recent opening of w at or before now.
+func lastOpening(w *database.MaintenanceWindow, now time.Time) (time.Time, error) {
+	day := slices.Index(weekdays, w.Day)
+	start, err := time.Parse("15:04", w.StartAt)
+	if day < 0 || err != nil {
+		return time.Time{}, fmt.Errorf("%w: %s %s", ErrInvalidWindow, w.Day, w.StartAt)
+	}
+	now = now.Local()
+	for i := 0; ; i++ {
+		d := now.AddDate(0, 0, -i)
+		if int(d.Weekday()) != day {
+			continue
+		}
+		opens := time.Date(d.Year(), d.Month(), d.Day(), start.Hour(), start.Minute(), 0, 0, time.Local)
+		if !opens.After(now) {
+			return opens, nil
+		}
+	}
+}
+
+// parseSnaps checks that every snap can be refreshed through the agents.
+func parseSnaps(snaps []string) ([]string, error) {
+	if len(snaps) == 0 {
+		return DefaultSnaps, nil
+	}
+	for _, snap := range snaps {
+		if !agent.IsRefreshable(snap) {
+			return nil, fmt.Errorf("%w: snap %q must be one of %s", ErrInvalidWindow, snap, strings.Join(DefaultSnaps, ", "))
+		}
+	}
+	return snaps, nil
+}
+
+// clusterID returns the ID of the cluster this manager runs.
+func (s *Service) clusterID(ctx context.Context) (string, error) {
+	clusters, err := database.NewClusterRepository(s.db).List(ctx)
+	if err != nil {
+		return "", err
+	}
+	if len(clusters) == 0 {
+		return "", ErrNoCluster
+	}
+	return clusters[0].ID, nil
+}
+
+// SetWindow creates or replaces the cluster's maintenance window. Only
+// openings after now start a rollout, so a window that is open right now
+// first runs next week.
+//
+// Example Input:
+//   SetWindow(ctx, &WindowRequest{Day: "sun", Start: "02:00", DurationMinutes: 240})
+//
+// Example Output:
+//   Window{Day: "sun", StartAt: "02:00", DurationMinutes: 240, Snaps: "lxd,microceph,microovn",
+//     NextOpeningAt: 2026-10-18T02:00:00+02:00}
+//
+// Example Output (Error):
+//   {Day: "sunday"}  =>  ErrInvalidWindow: day must be one of sun, mon, tue, wed, thu, fri, sat
+func (s *Service) SetWindow(ctx context.Context, req *WindowRequest) (*Window, error) {
+	day := strings.ToLower(strings.TrimSpace(req.Day))
+	if !slices.Contains(weekdays, day) {
+		return nil, fmt.Errorf("%w: day must be one of %s", ErrInvalidWindow, strings.Join(weekdays, ", "))
+	}
+	if _, err := time.Parse("15:04", req.Start); err != nil {
+		return nil, fmt.Errorf("%w: start %q must be HH:MM", ErrInvalidWindow, req.Start)
+	}
+	if req.DurationMinutes <= 0 || time.Duration(req.DurationMinutes)*time.Minute > maxDuration {
+		return nil, fmt.Errorf("%w: duration_minutes must be between 1 and %d", ErrInvalidWindow, int(maxDuration.Minutes()))
+	}
+	snaps, err := parseSnaps(req.Snaps)
+	if err != nil {
+		return nil, err
+	}
+
+	clusterID, err := s.clusterID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	w := &database.MaintenanceWindow{
+		ClusterID:       clusterID,
+		Day:             day,
+		StartAt:         req.Start,
+		DurationMinutes: req.DurationMinutes,
+		Snaps:           strings.Join(snaps, ","),
+	}
+	opens, err := lastOpening(w, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if err := database.NewMaintenanceWindowRepository(s.db).Upsert(ctx, w, opens); err != nil {
+		return nil, err
+	}
+	return s.GetWindow(ctx)
+}
+
+// GetWindow returns the cluster's maintenance window.
+func (s *Service) GetWindow(ctx context.Context) (*Window, error) {
+	clusterID, err := s.clusterID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	w, err := database.NewMaintenanceWindowRepository(s.db).GetByCluster(ctx, clusterID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWindowNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	opens, err := lastOpening(w, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return &Window{MaintenanceWindow: *w, NextOpeningAt: opens.AddDate(0, 0, 7)}, nil
+}
+
+// DeleteWindow removes the cluster's maintenance window; a rollout in
+// progress keeps running.
+func (s *Service) DeleteWindow(ctx context.Context) error {
+	clusterID, err := s.clusterID(ctx)
+	if err != nil {
+		return err
+	}
+	err = database.NewMaintenanceWindowRepository(s.db).DeleteByCluster(ctx, clusterID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrWindowNotFound
+	}
+	return err
+}
+
+// Run starts a rollout now, regardless of the window, in a
+// "maintenance.refresh" operation whose result is a Result.
+//
+// Example Output (Error):
+//   A rollout is already running  =>  ErrRunning: cluster c1
+func (s *Service) Run(ctx context.Context, req *RunRequest, actor *string) (*database.Operation, error) {
+	clusterID, err := s.clusterID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	snaps := req.Snaps
+	if len(snaps) == 0 {
+		w, err := database.NewMaintenanceWindowRepository(s.db).GetByCluster(ctx, clusterID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		if w != nil {
+			snaps = strings.Split(w.Snaps, ",")
+		}
+	}
+	if snaps, err = parseSnaps(snaps); err != nil {
+		return nil, err
+	}
+	return s.start(ctx, clusterID, snaps, time.Time{}, actor)
+}
+
+// ApplyWindows starts a rollout in every window that has opened since its
+// last run and is still open. Run by the "maintenance" job every Interval.
+func (s *Service) ApplyWindows(ctx context.Context) error {
+	repo := database.NewMaintenanceWindowRepository(s.db)
+	windows, err := repo.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for i := range windows {
+		w := &windows[i]
+		opens, err := lastOpening(w, now)
+		if err != nil {
+			log.Warn("Skipping maintenance window of cluster %s: %v", w.ClusterID, err)
+			continue
+		}
+		closes := opens.Add(time.Duration(w.DurationMinutes) * time.Minute)
+		if !now.Before(closes) || (w.LastRunAt != nil && !w.LastRunAt.Before(opens)) {
+			continue
+		}
+
+		if err := repo.SetLastRunAt(ctx, w.ClusterID, opens); err != nil {
+			return err
+		}
+		if _, err := s.start(ctx, w.ClusterID, strings.Split(w.Snaps, ","), closes, nil); err != nil {
+			log.Warn("Maintenance window of cluster %s opened but no rollout started: %v", w.ClusterID, err)
+		}
+	}
+	return nil
+}
+
+// start runs a rollout in the background unless one is running already. With
+// a non-zero closes no node is started after that time.
+func (s *Service) start(ctx context.Context, clusterID string, snaps []string, closes time.Time, actor *string) (*database.Operation, error) {
+	nodes, err := s.rolloutOrder(ctx, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	if s.running[clusterID] {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("%w: cluster %s", ErrRunning, clusterID)
+	}
+	s.running[clusterID] = true
+	s.mu.Unlock()
+	finished := func() {
+		s.mu.Lock()
+		delete(s.running, clusterID)
+		s.mu.Unlock()
+	}
+
+	timeout := time.Duration(max(len(nodes), 1)) * nodeTimeout
+	op, err := s.operations.StartWithTimeout(ctx, "maintenance.refresh", "/cluster/maintenance", actor, timeout,
+		func(ctx context.Context, report operation.Reporter) (any, error) {
+			defer finished()
+			return s.rollout(ctx, clusterID, nodes, snaps, closes, report)
+		})
+	if err != nil {
+		finished()
+		return nil, err
+	}
+	return op, nil
+}
+
+// rolloutOrder returns the cluster's nodes by hostname, the leader last: its
+// cordon briefly takes the manager's own LXD away.
+func (s *Service) rolloutOrder(ctx context.Context, clusterID string) ([]database.Node, error) {
+	nodes, err := database.NewNodeRepository(s.db).ListByCluster(ctx, clusterID, database.ListOptions{Sort: "hostname"})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(nodes, func(a, b database.Node) int {
+		aLeader, bLeader := a.Role == string(constant.RoleLeader), b.Role == string(constant.RoleLeader)
+		switch {
+		case aLeader == bLeader:
+			return 0
+		case aLeader:
+			return 1
+		default:
+			return -1
+		}
+	})
+	return nodes, nil
+}
+
+// rollout refreshes snaps on nodes one at a time and stops at the first node
+// that fails, leaving it cordoned for an operator to look at.
+func (s *Service) rollout(ctx context.Context, clusterID string, nodes []database.Node, snaps []string, closes time.Time, report operation.Reporter) (*Result, error) {
+	// Every agent must be there before the first node is cordoned
+	connected := map[string]bool{}
+	for _, a := range s.commands.Agents() {
+		connected[a.Node] = true
+	}
+	for _, n := range nodes {
+		if n.Status != "offline" && !connected[n.Hostname] {
+			s.event(ctx, clusterID, &n.ID, event.MaintenanceFailed, fmt.Sprintf("Maintenance not started: the agent of %s is not connected", n.Hostname))
+			return nil, fmt.Errorf("%w: %s", command.ErrNotConnected, n.Hostname)
+		}
+	}
+
+	result := &Result{ClusterID: clusterID, Refreshed: []NodeResult{}, Skipped: []string{}, Deferred: []string{}}
+	s.event(ctx, clusterID, nil, event.MaintenanceStarted, fmt.Sprintf("Refreshing %s on %d nodes, one at a time", strings.Join(snaps, ", "), len(nodes)))
+
+	for i, n := range nodes {
+		if !closes.IsZero() && time.Now().After(closes) {
+			for _, m := range nodes[i:] {
+				result.Deferred = append(result.Deferred, m.Hostname)
+			}
+			s.event(ctx, clusterID, nil, event.MaintenanceDeferred, fmt.Sprintf("Maintenance window closed; %s wait for the next one", strings.Join(result.Deferred, ", ")))
+			break
+		}
+		if n.Status == "offline" {
+			// Drained by an operator; uncordoning it would undo that
+			result.Skipped = append(result.Skipped, n.Hostname)
+			continue
+		}
+
+		progress := func(step int, message string) {
+			report(100*(4*i+step)/(4*len(nodes)), n.Hostname+": "+message)
+		}
+		nr, err := s.refreshNode(ctx, &n, snaps, progress)
+		if err != nil {
+			s.event(ctx, clusterID, &n.ID, event.MaintenanceFailed, fmt.Sprintf("Refreshing %s failed, the node stays cordoned: %v", n.Hostname, err))
+			return nil, fmt.Errorf("%s: %w", n.Hostname, err)
+		}
+		result.Refreshed = append(result.Refreshed, *nr)
+	}
+
+	s.event(ctx, clusterID, nil, event.MaintenanceCompleted, fmt.Sprintf("Maintenance done: %d nodes refreshed, %d skipped, %d deferred",
+		len(result.Refreshed), len(result.Skipped), len(result.Deferred)))
+	return result, nil
+}
+
+// refreshNode cordons n, refreshes snaps on it through its agent, checks it
+// and uncordons it. progress gets the step (0-3) and what is being done.
+func (s *Service) refreshNode(ctx context.Context, n *database.Node, snaps []string, progress func(step int, message string)) (*NodeResult, error) {
+	// 1. Cordon: evacuate the node's instances and keep new ones off it
+	progress(0, "Cordoning")
+	if _, err := s.nodes.Drain(ctx, n.ID, &node.DrainRequest{}); err != nil {
+		return nil, fmt.Errorf("cordon: %w", err)
+	}
+
+	// 2. Refresh; the agent health-checks each service after its refresh
+	nr := &NodeResult{NodeID: n.ID, Hostname: n.Hostname, Snaps: []agent.SnapRefreshResult{}}
+	for _, snap := range snaps {
+		progress(1, "Refreshing "+snap)
+		payload, err := json.Marshal(agent.ServiceCommand{Service: snap})
+		if err != nil {
+			return nil, err
+		}
+		var r agent.SnapRefreshResult
+		req := &command.Request{Type: command.TypeRefreshSnap, Payload: payload}
+		if err := s.commands.Run(ctx, n.Hostname, req, func(int, string) {}, &r); err != nil {
+			return nil, fmt.Errorf("refresh %s: %w", snap, err)
+		}
+		nr.Snaps = append(nr.Snaps, r)
+	}
+
+	// 3. Health-check: no refreshed component may be reported unavailable
+	progress(2, "Checking health")
+	conditions, err := s.nodes.Conditions(ctx, n.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, snap := range snaps {
+		if why, ok := conditions[snap+"_unavailable"]; ok {
+			return nil, fmt.Errorf("%w: %s: %s", agent.ErrServiceUnhealthy, snap, why)
+		}
+	}
+
+	// 4. Uncordon
+	progress(3, "Uncordoning")
+	if err := s.nodes.Restore(ctx, n.ID, ""); err != nil {
+		return nil, fmt.Errorf("uncordon: %w", err)
+	}
+	return nr, nil
+}
+
+func (s *Service) event(ctx context.Context, clusterID string, nodeID *string, eventType string, message string) {
+	e := event.New(eventType, message, nil)
+	e.ClusterID, e.NodeID = &clusterID, nodeID
+	if err := database.NewEventRepository(s.db).Create(context.WithoutCancel(ctx), e); err != nil {
+		log.Error("Failed to record event %s: %v", eventType, err)
+	}
+}
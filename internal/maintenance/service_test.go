@@ -0,0 +1,104 @@
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"mcloud/internal/command"
+	"mcloud/internal/config"
+	"mcloud/internal/database"
+	"mcloud/internal/node"
+	"mcloud/internal/operation"
+)
+
+func TestLastOpening(t *testing.T) {
+	w := &database.MaintenanceWindow{Day: "sun", StartAt: "02:00"}
+	// 2026-10-18 is a Sunday
+	tests := []struct {
+		now  time.Time
+		want time.Time
+	}{
+		{time.Date(2026, 10, 18, 2, 0, 0, 0, time.Local), time.Date(2026, 10, 18, 2, 0, 0, 0, time.Local)},
+		{time.Date(2026, 10, 18, 5, 30, 0, 0, time.Local), time.Date(2026, 10, 18, 2, 0, 0, 0, time.Local)},
+		{time.Date(2026, 10, 18, 1, 59, 0, 0, time.Local), time.Date(2026, 10, 11, 2, 0, 0, 0, time.Local)},
+		{time.Date(2026, 10, 21, 12, 0, 0, 0, time.Local), time.Date(2026, 10, 18, 2, 0, 0, 0, time.Local)},
+	}
+	for _, tt := range tests {
+		got, err := lastOpening(w, tt.now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("lastOpening(%s) = %s, want %s", tt.now, got, tt.want)
+		}
+	}
+}
+
+func TestWindow(t *testing.T) {
+	db, err := database.Connect(filepath.Join(t.TempDir(), "mcloud.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close(db)
+	cfg := &config.Config{}
+	ops := operation.NewService(db, cfg)
+	s := NewService(db, node.NewService(db, cfg), command.NewHub(db, ops), ops)
+	ctx := context.Background()
+
+	if _, err := s.SetWindow(ctx, &WindowRequest{Day: "sun", Start: "02:00", DurationMinutes: 60}); !errors.Is(err, ErrNoCluster) {
+		t.Fatalf("no cluster: got %v, want ErrNoCluster", err)
+	}
+	if _, err := db.Exec(`INSERT INTO clusters (id, name, state) VALUES ('c1', 'test', 'active')`); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, req := range []WindowRequest{
+		{Day: "sunday", Start: "02:00", DurationMinutes: 60},
+		{Day: "sun", Start: "2am", DurationMinutes: 60},
+		{Day: "sun", Start: "02:00"},
+		{Day: "sun", Start: "02:00", DurationMinutes: 25 * 60},
+		{Day: "sun", Start: "02:00", DurationMinutes: 60, Snaps: []string{"docker"}},
+	} {
+		if _, err := s.SetWindow(ctx, &req); !errors.Is(err, ErrInvalidWindow) {
+			t.Errorf("SetWindow(%+v): got %v, want ErrInvalidWindow", req, err)
+		}
+	}
+	if _, err := s.GetWindow(ctx); !errors.Is(err, ErrWindowNotFound) {
+		t.Fatalf("no window: got %v, want ErrWindowNotFound", err)
+	}
+
+	// A window that is open right now first runs next week
+	now := time.Now()
+	start := now.Add(-time.Minute).Format("15:04")
+	day := weekdays[now.Add(-time.Minute).Weekday()]
+	window, err := s.SetWindow(ctx, &WindowRequest{Day: day, Start: start, DurationMinutes: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if window.Snaps != "lxd,microceph,microovn" {
+		t.Errorf("snaps: got %q", window.Snaps)
+	}
+	if d := window.NextOpeningAt.Sub(now); d < 6*24*time.Hour || d > 7*24*time.Hour {
+		t.Errorf("next opening: got %s, %s from now", window.NextOpeningAt, d)
+	}
+	if err := s.ApplyWindows(ctx); err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM operations WHERE type = 'maintenance.refresh'`).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Fatalf("ApplyWindows started %d rollouts in a window that opened before it was set", n)
+	}
+
+	if err := s.DeleteWindow(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteWindow(ctx); !errors.Is(err, ErrWindowNotFound) {
+		t.Fatalf("second delete: got %v, want ErrWindowNotFound", err)
+	}
+}
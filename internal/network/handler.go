@@ -0,0 +1,89 @@
+package network
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"mcloud/internal/auth"
+	"mcloud/internal/dns"
+	"mcloud/pkg/reason"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(s *Service) *Handler {
+	return &Handler{service: s}
+}
+
+// writeError maps service errors to HTTP status codes.
+func writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrInvalid), errors.Is(err, dns.ErrInvalidName):
+		reason.HTTPError(w, err, 400)
+	case errors.Is(err, ErrNotFound), errors.Is(err, ErrWorkloadNotFound):
+		reason.HTTPError(w, err, 404)
+	case errors.Is(err, ErrInUse), errors.Is(err, ErrExhausted):
+		reason.HTTPError(w, err, 409)
+	case errors.Is(err, ErrDisabled), errors.Is(err, dns.ErrDisabled):
+		reason.HTTPError(w, err, 503)
+	default:
+		reason.HTTPError(w, err, 500)
+	}
+}
+
+func (h *Handler) ListFloatingIPs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	items, err := h.service.List(r.Context())
+	if err != nil {
+		reason.HTTPError(w, err, 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(items)
+}
+
+func (h *Handler) ExposeWorkload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ExposeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		reason.HTTPError(w, err, 400)
+		return
+	}
+
+	actor := auth.ClientIdentity(r)
+	exposure, err := h.service.Expose(r.Context(), &req, &actor)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(exposure)
+}
+
+func (h *Handler) ReleaseFloatingIP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := h.service.Release(r.Context(), r.PathValue("address")); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,12 @@
+package network
+
+import (
+	"net/http"
+)
+
+// InitModule registers the floating IP routes served by handler.
+func InitModule(mux *http.ServeMux, handler *Handler) {
+	mux.HandleFunc("GET /network/floating-ips", handler.ListFloatingIPs)
+	mux.HandleFunc("POST /network/floating-ips", handler.ExposeWorkload)
+	mux.HandleFunc("DELETE /network/floating-ips/{address}", handler.ReleaseFloatingIP)
+}
@@ -0,0 +1,298 @@
+// Package network exposes workloads outside the cluster with floating IPs:
+// addresses allocated from network.external_pool, each forwarded to one
+// workload by an LXD network forward (an OVN load balancer) on the workloads'
+// OVN network. Allocations are tracked in the floating_ips table; a floating
+// IP can also get a DNS name through the dns package.
+package network
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+	"sync"
+
+	"mcloud/internal/config"
+	"mcloud/internal/database"
+	"mcloud/internal/dns"
+	"mcloud/pkg/logger"
+	"mcloud/pkg/reason"
+	"mcloud/services/lxd"
+)
+
+var log = logger.Named("network")
+
+// defaultOVNNetwork applies when network.ovn_network is not set.
+const defaultOVNNetwork = "default"
+
+var (
+	ErrDisabled         = reason.New(reason.FloatingIPsDisabled, "floating ips are not configured (network.external_pool)")
+	ErrNotFound         = reason.New(reason.FloatingIPNotFound, "floating ip not allocated")
+	ErrInUse            = reason.New(reason.FloatingIPInUse, "floating ip is already allocated")
+	ErrExhausted        = reason.New(reason.FloatingIPsExhausted, "no free address in the external pool")
+	ErrInvalid          = reason.New(reason.InvalidFloatingIP, "invalid floating ip request")
+	ErrWorkloadNotFound = reason.New(reason.WorkloadNotFound, "workload not found")
+)
+
+type Service struct {
+	db      *sql.DB
+	dns     *dns.Service
+	network string
+	pool    []netip.Prefix // nil when disabled
+
+	mu sync.Mutex // serializes allocations
+}
+
+// ExposeRequest forwards a floating IP to a workload.
+//
+// Example JSON:
+//   {"workload_id": "550e8400-e29b-41d4-a716-446655440000", "dns_name": "web"}
+type ExposeRequest struct {
+	WorkloadID string `json:"workload_id"`
+	Address    string `json:"address,omitempty"`  // a specific address of the pool; empty = the first free one
+	DNSName    string `json:"dns_name,omitempty"` // also publish <dns_name>.<dns.zone> for the address
+}
+
+// Exposure is a floating IP and the DNS name published for it, if any.
+//
+// Example JSON:
+//   {"address": "203.0.113.17", "workload_id": "550e8400-...", "network": "default",
+//    "target_address": "10.154.12.5", ..., "fqdn": "web.apps.example.com"}
+type Exposure struct {
+	database.FloatingIP
+	FQDN string `json:"fqdn,omitempty"`
+}
+
+// OVNNetwork returns the LXD OVN network of the workloads (network.ovn_network).
+func OVNNetwork(cfg config.Network) string {
+	if cfg.OVNNetwork == "" {
+		return defaultOVNNetwork
+	}
+	return cfg.OVNNetwork
+}
+
+func NewService(db *sql.DB, cfg config.Network, dnsService *dns.Service) *Service {
+	network := OVNNetwork(cfg)
+	pool, err := ParsePool(cfg.ExternalPool)
+	if err != nil {
+		log.Error("Floating IPs disabled: %v", err)
+		pool = nil
+	}
+	return &Service{db: db, dns: dnsService, network: network, pool: pool}
+}
+
+// ParsePool parses the CIDRs of network.external_pool; a bare address is a
+// pool of one.
+func ParsePool(entries []string) ([]netip.Prefix, error) {
+	var pool []netip.Prefix
+	for _, e := range entries {
+		if !strings.Contains(e, "/") {
+			addr, err := netip.ParseAddr(e)
+			if err != nil {
+				return nil, fmt.Errorf("invalid external_pool entry %q: %w", e, err)
+			}
+			pool = append(pool, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		p, err := netip.ParsePrefix(e)
+		if err != nil {
+			return nil, fmt.Errorf("invalid external_pool entry %q: %w", e, err)
+		}
+		pool = append(pool, p.Masked())
+	}
+	return pool, nil
+}
+
+// usable reports whether a can be handed out from p: every address except the
+// network and broadcast addresses of IPv4 prefixes larger than /31.
+func usable(p netip.Prefix, a netip.Addr) bool {
+	if !p.Contains(a) {
+		return false
+	}
+	if !a.Is4() || p.Bits() >= 31 {
+		return true
+	}
+	return a != p.Addr() && p.Contains(a.Next())
+}
+
+// nextFree returns the first usable address of the pool that is not allocated.
+func nextFree(pool []netip.Prefix, allocated map[netip.Addr]bool) (netip.Addr, bool) {
+	for _, p := range pool {
+		for a := p.Addr(); p.Contains(a); a = a.Next() {
+			if usable(p, a) && !allocated[a] {
+				return a, true
+			}
+		}
+	}
+	return netip.Addr{}, false
+}
+
+// Enabled reports whether an external pool is configured.
+func (s *Service) Enabled() bool {
+	return s.pool != nil
+}
+
+// Expose allocates a floating IP (req.Address, or the first free one of the
+// pool) and forwards it to the workload's address of the same family. With
+// req.DNSName the address is also published in DNS; if that fails the
+// floating IP is released again.
+//
+// Example Output (Error):
+//   Every pool address taken  =>  ErrExhausted
+//   Workload without an IPv4  =>  ErrInvalid: workload web-1 has no IPv4 address yet
+func (s *Service) Expose(ctx context.Context, req *ExposeRequest, actor *string) (*Exposure, error) {
+	if !s.Enabled() {
+		return nil, ErrDisabled
+	}
+	if req.DNSName != "" && !s.dns.Enabled() {
+		return nil, dns.ErrDisabled
+	}
+	w, err := database.NewWorkloadRepository(s.db).GetByID(ctx, req.WorkloadID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrWorkloadNotFound, req.WorkloadID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := s.allocate(ctx, w, req.Address, actor)
+	if err != nil {
+		return nil, err
+	}
+	exposure := &Exposure{FloatingIP: *f}
+	log.Info("Floating IP %s forwarded to %s (%s)", f.Address, w.Name, f.TargetAddress)
+
+	if req.DNSName != "" {
+		rec, err := s.dns.Publish(ctx, &dns.PublishRequest{Address: f.Address, Name: req.DNSName})
+		if err != nil {
+			if releaseErr := s.Release(ctx, f.Address); releaseErr != nil {
+				log.Warn("Releasing floating IP %s after failed DNS publish: %v", f.Address, releaseErr)
+			}
+			return nil, err
+		}
+		exposure.FQDN = rec.FQDN
+	}
+	return exposure, nil
+}
+
+// allocate picks the address, creates the forward and records it.
+func (s *Service) allocate(ctx context.Context, w *database.Workload, requested string, actor *string) (*database.FloatingIP, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	repo := database.NewFloatingIPRepository(s.db)
+	existing, err := repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	allocated := make(map[netip.Addr]bool, len(existing))
+	for _, f := range existing {
+		if a, err := netip.ParseAddr(f.Address); err == nil {
+			allocated[a] = true
+		}
+	}
+	// The VIPs of load balancers are taken as well
+	lbs, err := database.NewLoadBalancerRepository(s.db).List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, lb := range lbs {
+		if a, err := netip.ParseAddr(lb.VIP); err == nil {
+			allocated[a] = true
+		}
+	}
+
+	var addr netip.Addr
+	if requested != "" {
+		if addr, err = netip.ParseAddr(requested); err != nil {
+			return nil, fmt.Errorf("%w: %q is not an IP address", ErrInvalid, requested)
+		}
+		inPool := false
+		for _, p := range s.pool {
+			inPool = inPool || usable(p, addr)
+		}
+		if !inPool {
+			return nil, fmt.Errorf("%w: %s is not in the external pool", ErrInvalid, addr)
+		}
+		if allocated[addr] {
+			return nil, fmt.Errorf("%w: %s", ErrInUse, addr)
+		}
+	} else {
+		var ok bool
+		if addr, ok = nextFree(s.pool, allocated); !ok {
+			return nil, ErrExhausted
+		}
+	}
+
+	target, err := targetAddress(w, addr)
+	if err != nil {
+		return nil, err
+	}
+	if err := lxd.CreateForward(ctx, s.network, addr.String(), target); err != nil {
+		return nil, err
+	}
+	f := &database.FloatingIP{Address: addr.String(), WorkloadID: w.ID, Network: s.network, TargetAddress: target, CreateUserID: actor}
+	if err := repo.Create(ctx, f); err != nil {
+		if delErr := lxd.DeleteForward(ctx, s.network, f.Address); delErr != nil {
+			log.Warn("Removing forward %s after failed insert: %v", f.Address, delErr)
+		}
+		return nil, err
+	}
+	return repo.Get(ctx, f.Address)
+}
+
+// targetAddress returns the workload's first address of the same family as
+// addr (see workload.SyncAddresses).
+func targetAddress(w *database.Workload, addr netip.Addr) (string, error) {
+	for _, a := range w.Addresses {
+		candidate, err := netip.ParseAddr(a)
+		if err == nil && candidate.Is4() == addr.Is4() {
+			return candidate.String(), nil
+		}
+	}
+	family := "IPv4"
+	if !addr.Is4() {
+		family = "IPv6"
+	}
+	return "", fmt.Errorf("%w: workload %s has no %s address yet", ErrInvalid, w.Name, family)
+}
+
+// List returns all allocated floating IPs.
+func (s *Service) List(ctx context.Context) ([]database.FloatingIP, error) {
+	items, err := database.NewFloatingIPRepository(s.db).List(ctx)
+	if items == nil {
+		items = []database.FloatingIP{}
+	}
+	return items, err
+}
+
+// Release removes a floating IP's DNS record, if any, and its forward, and
+// returns the address to the pool.
+func (s *Service) Release(ctx context.Context, address string) error {
+	addr, err := netip.ParseAddr(address)
+	if err != nil {
+		return fmt.Errorf("%w: %q is not an IP address", ErrInvalid, address)
+	}
+	repo := database.NewFloatingIPRepository(s.db)
+	f, err := repo.Get(ctx, addr.String())
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %s", ErrNotFound, addr)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := s.dns.Release(ctx, f.Address); err != nil {
+		return err
+	}
+	if err := lxd.DeleteForward(ctx, f.Network, f.Address); err != nil {
+		return err
+	}
+	if err := repo.Delete(ctx, f.Address); err != nil {
+		return err
+	}
+	log.Info("Floating IP %s released", f.Address)
+	return nil
+}
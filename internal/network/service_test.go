@@ -0,0 +1,101 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"path/filepath"
+	"testing"
+
+	"mcloud/internal/config"
+	"mcloud/internal/database"
+	"mcloud/internal/dns"
+)
+
+func TestNextFree(t *testing.T) {
+	pool, err := ParsePool([]string{"203.0.113.16/30", "198.51.100.7"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	allocated := map[netip.Addr]bool{}
+	var got []string
+	for {
+		a, ok := nextFree(pool, allocated)
+		if !ok {
+			break
+		}
+		allocated[a] = true
+		got = append(got, a.String())
+	}
+	// .16 and .19 are the network and broadcast addresses of the /30
+	want := []string{"203.0.113.17", "203.0.113.18", "198.51.100.7"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+
+	if _, err := ParsePool([]string{"203.0.113.0/33"}); err == nil {
+		t.Fatal("invalid prefix: want an error")
+	}
+}
+
+func TestExposeErrors(t *testing.T) {
+	db, err := database.Connect(filepath.Join(t.TempDir(), "mcloud.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close(db)
+	ctx := context.Background()
+	dnsService := dns.NewService(db, config.DNS{})
+
+	disabled := NewService(db, config.Network{}, dnsService)
+	if _, err := disabled.Expose(ctx, &ExposeRequest{WorkloadID: "w-1"}, nil); !errors.Is(err, ErrDisabled) {
+		t.Fatalf("no pool: got %v, want ErrDisabled", err)
+	}
+
+	s := NewService(db, config.Network{ExternalPool: []string{"203.0.113.16/30"}}, dnsService)
+	if _, err := s.Expose(ctx, &ExposeRequest{WorkloadID: "w-1"}, nil); !errors.Is(err, ErrWorkloadNotFound) {
+		t.Fatalf("missing workload: got %v, want ErrWorkloadNotFound", err)
+	}
+	if _, err := s.Expose(ctx, &ExposeRequest{WorkloadID: "w-1", DNSName: "web"}, nil); !errors.Is(err, dns.ErrDisabled) {
+		t.Fatalf("dns name without dns: got %v, want dns.ErrDisabled", err)
+	}
+
+	w := &database.Workload{ID: "w-1", ClusterID: "c-1", Name: "web-1", Kind: "container", Status: "running", Image: "ubuntu:24.04", Priority: "normal"}
+	if err := database.NewWorkloadRepository(db).Create(ctx, w); err != nil {
+		t.Fatal(err)
+	}
+	for _, address := range []string{"203.0.113.16", "203.0.113.40", "not-an-ip"} {
+		if _, err := s.Expose(ctx, &ExposeRequest{WorkloadID: "w-1", Address: address}, nil); !errors.Is(err, ErrInvalid) {
+			t.Errorf("address %s: got %v, want ErrInvalid", address, err)
+		}
+	}
+	// No addresses synced from LXD yet
+	if _, err := s.Expose(ctx, &ExposeRequest{WorkloadID: "w-1"}, nil); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("no target: got %v, want ErrInvalid", err)
+	}
+
+	if err := database.NewFloatingIPRepository(db).Create(ctx, &database.FloatingIP{Address: "203.0.113.17", WorkloadID: "w-1", Network: "default", TargetAddress: "10.0.0.5"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Expose(ctx, &ExposeRequest{WorkloadID: "w-1", Address: "203.0.113.17"}, nil); !errors.Is(err, ErrInUse) {
+		t.Fatalf("taken address: got %v, want ErrInUse", err)
+	}
+	if err := database.NewLoadBalancerRepository(db).Create(ctx, &database.LoadBalancer{Name: "web", VIP: "203.0.113.18", Network: "default", Protocol: "tcp", Port: 80, TargetPort: 80}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Expose(ctx, &ExposeRequest{WorkloadID: "w-1", Address: "203.0.113.18"}, nil); !errors.Is(err, ErrInUse) {
+		t.Fatalf("load balancer vip: got %v, want ErrInUse", err)
+	}
+	if _, err := s.Expose(ctx, &ExposeRequest{WorkloadID: "w-1"}, nil); !errors.Is(err, ErrExhausted) {
+		t.Fatalf("pool of floating ip and vip: got %v, want ErrExhausted", err)
+	}
+	if err := s.Release(ctx, "203.0.113.18"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("release unallocated: got %v, want ErrNotFound", err)
+	}
+}
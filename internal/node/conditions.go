@@ -0,0 +1,88 @@
+package node
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"mcloud/internal/database"
+	"mcloud/internal/event"
+	"mcloud/pkg/commander"
+)
+
+// ConditionPrefix starts the node attribute keys of node conditions. A condition
+// is present while it holds, e.g. condition.microceph_unavailable, and its value
+// describes why.
+const ConditionPrefix = "condition."
+
+// unavailableCondition is the condition set while a component's circuit breaker is open.
+func unavailableCondition(component string) string {
+	return ConditionPrefix + component + "_unavailable"
+}
+
+// ApplyBreakers turns the circuit breaker states reported for a node (by its
+// agent, or by mcloudd for its own node) into node conditions: an open breaker
+// sets <component>_unavailable, a closed one clears it. Changes are recorded as
+// node.condition_set and node.condition_cleared events.
+//
+// Example Input:
+//   ApplyBreakers(ctx, "node-2", []commander.BreakerStatus{{Component: "microceph", State: "open", ...}})
+//
+// Example Output:
+//   node_attributes: ("node-2's ID", "condition.microceph_unavailable", "5 consecutive failures, last: ...")
+func (s *Service) ApplyBreakers(ctx context.Context, hostname string, breakers []commander.BreakerStatus) error {
+	n, err := database.NewNodeRepository(s.db).GetByHostname(ctx, hostname)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNodeNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	attrRepo := database.NewNodeAttributeRepository(s.db)
+	current, err := s.Conditions(ctx, n.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, b := range breakers {
+		key := unavailableCondition(b.Component)
+		if b.State == commander.BreakerClosed {
+			removed, err := attrRepo.Delete(ctx, n.ID, key)
+			if err != nil {
+				return err
+			}
+			if removed {
+				s.event(ctx, n, event.NodeConditionCleared, fmt.Sprintf("%s on node %s is available again", b.Component, n.Hostname))
+			}
+			continue
+		}
+
+		reason := fmt.Sprintf("%d consecutive failures, last: %s", b.Failures, b.LastError)
+		if err := attrRepo.Set(ctx, n.ID, key, reason); err != nil {
+			return err
+		}
+		if _, ok := current[strings.TrimPrefix(key, ConditionPrefix)]; !ok {
+			s.event(ctx, n, event.NodeConditionSet, fmt.Sprintf("%s on node %s is unavailable: %s", b.Component, n.Hostname, reason))
+		}
+	}
+	return nil
+}
+
+// Conditions returns the conditions currently set on a node, keyed by name
+// without ConditionPrefix (e.g. "microceph_unavailable").
+func (s *Service) Conditions(ctx context.Context, nodeID string) (map[string]string, error) {
+	attrs, err := database.NewNodeAttributeRepository(s.db).ListByNode(ctx, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	conditions := map[string]string{}
+	for _, a := range attrs {
+		if name, ok := strings.CutPrefix(a.Key, ConditionPrefix); ok {
+			conditions[name] = a.Value
+		}
+	}
+	return conditions, nil
+}
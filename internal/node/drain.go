@@ -0,0 +1,197 @@
+package node
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"mcloud/internal/database"
+	"mcloud/internal/event"
+	"mcloud/pkg/logger"
+	"mcloud/pkg/reason"
+	"mcloud/services/lxd"
+)
+
+var log = logger.Named("node")
+
+var ErrDrainTimeout = reason.New(reason.DrainTimeout, "drain did not finish in time")
+
+const (
+	// DefaultDrainTimeout bounds a drain when the caller does not set one.
+	DefaultDrainTimeout = 5 * time.Minute
+
+	// maxDrainTimeout caps caller supplied timeouts.
+	maxDrainTimeout = 30 * time.Minute
+)
+
+// DrainRequest is sent by an agent about to shut down, or by an operator.
+// Agents identify themselves by hostname; operators use the node ID in the URL.
+type DrainRequest struct {
+	Hostname       string `json:"hostname,omitempty"`
+	TimeoutSeconds int    `json:"timeout_seconds,omitempty"`
+}
+
+// DrainResult reports where the node's workloads ended up.
+type DrainResult struct {
+	NodeID    string            `json:"node_id"`
+	Moved     map[string]string `json:"moved"`     // workload name → new node hostname
+	Remaining []string          `json:"remaining"` // workloads still on the node (stopped in place)
+	Preempted []string          `json:"preempted"` // low-priority workloads stopped before the evacuation
+}
+
+// resolveNode finds a node by ID, or by hostname when the ID is empty.
+func (s *Service) resolveNode(ctx context.Context, nodeID string, hostname string) (*database.Node, error) {
+	repo := database.NewNodeRepository(s.db)
+
+	var n *database.Node
+	var err error
+	if nodeID != "" {
+		n, err = repo.GetByID(ctx, nodeID)
+	} else {
+		n, err = repo.GetByHostname(ctx, hostname)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNodeNotFound
+	}
+	return n, err
+}
+
+// Drain evacuates all workloads from a node through LXD, records their new
+// locations and marks the node offline. Low-priority workloads are stopped
+// first, so the remaining members have room for the ones that are migrated. If the timeout expires first, ErrDrainTimeout
+// is returned; the evacuation keeps running in the background and the caller (e.g. a
+// shutting down agent) may proceed anyway.
+func (s *Service) Drain(ctx context.Context, nodeID string, req *DrainRequest) (*DrainResult, error) {
+	n, err := s.resolveNode(ctx, nodeID, req.Hostname)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := DefaultDrainTimeout
+	if req.TimeoutSeconds > 0 {
+		timeout = min(time.Duration(req.TimeoutSeconds)*time.Second, maxDrainTimeout)
+	}
+
+	s.event(ctx, n, event.NodeDrainStarted, fmt.Sprintf("Draining node %s (timeout %s)", n.Hostname, timeout))
+
+	preempted, err := s.preempt(ctx, n, "node is being drained", 0)
+	if err != nil {
+		// The evacuation stops whatever is left (cluster.evacuate=stop)
+		log.Warn("Draining node %s: %v", n.Hostname, err)
+	}
+
+	// lxc has no cancellation, so wait for it in the background
+	done := make(chan error, 1)
+	go func() { done <- lxd.EvacuateMember(n.Hostname) }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			s.event(ctx, n, event.NodeDrainFailed, fmt.Sprintf("Draining node %s failed: %v", n.Hostname, err))
+			return nil, err
+		}
+	case <-time.After(timeout):
+		s.event(ctx, n, event.NodeDrainFailed, fmt.Sprintf("Draining node %s timed out after %s", n.Hostname, timeout))
+		return nil, ErrDrainTimeout
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	result, err := s.syncWorkloadLocations(ctx, n)
+	if err != nil {
+		return nil, err
+	}
+	result.Preempted = preempted
+
+	if err := database.NewNodeRepository(s.db).UpdateStatus(ctx, n.ID, "offline"); err != nil {
+		return nil, err
+	}
+	s.event(ctx, n, event.NodeDrained, fmt.Sprintf("Node %s drained: %d workloads moved, %d stopped in place",
+		n.Hostname, len(result.Moved), len(result.Remaining)))
+
+	return result, nil
+}
+
+// Restore brings a drained node back: LXD moves evacuated instances back, the
+// workloads preempted by the drain are started again and the node is marked online.
+func (s *Service) Restore(ctx context.Context, nodeID string, hostname string) error {
+	n, err := s.resolveNode(ctx, nodeID, hostname)
+	if err != nil {
+		return err
+	}
+
+	if err := lxd.RestoreMember(n.Hostname); err != nil {
+		return err
+	}
+	if _, err := s.syncWorkloadLocations(ctx, n); err != nil {
+		return err
+	}
+	if err := s.resumePreempted(ctx, n); err != nil {
+		log.Warn("Restoring node %s: %v", n.Hostname, err)
+	}
+	if err := database.NewNodeRepository(s.db).UpdateStatus(ctx, n.ID, "online"); err != nil {
+		return err
+	}
+
+	s.event(ctx, n, event.NodeRestored, fmt.Sprintf("Node %s restored", n.Hostname))
+	return nil
+}
+
+// syncWorkloadLocations updates node_id of the cluster's workloads from LXD and
+// reports which workloads left node n and which are still on it.
+func (s *Service) syncWorkloadLocations(ctx context.Context, n *database.Node) (*DrainResult, error) {
+	locations, err := lxd.InstanceLocations()
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := database.NewNodeRepository(s.db).ListByCluster(ctx, n.ClusterID, database.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	nodeIDs := map[string]string{} // hostname → node ID
+	for _, m := range nodes {
+		nodeIDs[m.Hostname] = m.ID
+	}
+
+	workloadRepo := database.NewWorkloadRepository(s.db)
+	workloads, err := workloadRepo.ListByCluster(ctx, n.ClusterID, database.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &DrainResult{NodeID: n.ID, Moved: map[string]string{}, Remaining: []string{}}
+	for _, w := range workloads {
+		location, ok := locations[w.Name]
+		if !ok {
+			continue
+		}
+		newID, ok := nodeIDs[location]
+		if !ok {
+			continue
+		}
+
+		wasHere := w.NodeID != nil && *w.NodeID == n.ID
+		switch {
+		case newID == n.ID && wasHere:
+			result.Remaining = append(result.Remaining, w.Name)
+		case wasHere:
+			result.Moved[w.Name] = location
+		}
+
+		if w.NodeID == nil || *w.NodeID != newID {
+			if err := workloadRepo.UpdateNode(ctx, w.ID, &newID); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return result, nil
+}
+
+func (s *Service) event(ctx context.Context, n *database.Node, eventType string, message string) {
+	if err := database.NewEventRepository(s.db).Create(ctx, event.ForNode(n, eventType, message, nil)); err != nil {
+		log.Error("Failed to record event %s: %v", eventType, err)
+	}
+}
@@ -0,0 +1,497 @@
+package node
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"mcloud/internal/agent"
+	"mcloud/internal/auth"
+	"mcloud/internal/database"
+	"mcloud/pkg/reason"
+	"mcloud/pkg/utils"
+)
+
+const (
+	// defaultMetricsWindow is used when GET /nodes/{id}/metrics has no since parameter
+	defaultMetricsWindow = time.Hour
+
+	benchmarkTimeout = 2 * time.Minute
+
+	// restartTimeout covers the restart itself plus the agent's post-restart
+	// health checks (about 30s)
+	restartTimeout = 2 * time.Minute
+
+	// removeTimeout covers moving the data of the node's OSDs to the rest of Ceph
+	removeTimeout = 30 * time.Minute
+
+	// Re-join attempts allowed per client address: rejoinRate a minute, and
+	// none for rejoinLockout after rejoinMaxFailures failures in a row
+	rejoinRate        = 10
+	rejoinMaxFailures = 5
+	rejoinLockout     = 15 * time.Minute
+)
+
+type ListNodesResponse struct {
+	Items      []database.Node `json:"items"`
+	NextOffset int             `json:"next_offset,omitempty"` // 0 when there are no more pages
+}
+
+type Handler struct {
+	service     *Service
+	rejoinGuard *auth.AttemptGuard
+}
+
+func NewHandler(s *Service) *Handler {
+	return &Handler{
+		service:     s,
+		rejoinGuard: auth.NewAttemptGuard(rejoinRate, rejoinMaxFailures, rejoinLockout),
+	}
+}
+
+// ListNodes handles GET /nodes?limit=100&offset=0. Optional parameters:
+//   sort                           hostname, status, role, joined_at, last_heartbeat or created_at; "-" prefix for descending
+//   cluster, hostname, status, role  exact-match filters
+func (h *Handler) ListNodes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit, offset, err := utils.ParsePage(r)
+	if err != nil {
+		reason.HTTPError(w, err, 400)
+		return
+	}
+
+	sort, filter := utils.ParseListQuery(r, "cluster", "hostname", "status", "role")
+	nodes, err := h.service.ListNodes(r.Context(), database.ListOptions{Limit: limit, Offset: offset, Sort: sort, Filter: filter})
+	if errors.Is(err, database.ErrInvalidListOption) {
+		reason.HTTPError(w, err, 400)
+		return
+	}
+	if err != nil {
+		reason.HTTPError(w, err, 500)
+		return
+	}
+
+	resp := ListNodesResponse{Items: nodes}
+	if resp.Items == nil {
+		resp.Items = []database.Node{}
+	}
+	if len(nodes) == limit {
+		resp.NextOffset = offset + limit
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (h *Handler) RestartService(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Waiting for the service to come back healthy takes longer than the server's
+	// default write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Now().Add(restartTimeout))
+
+	result, err := h.service.RestartService(r.Context(), r.PathValue("id"), r.PathValue("service"))
+	if err != nil && result == nil {
+		switch {
+		case errors.Is(err, ErrServiceNotAllowed):
+			reason.HTTPError(w, err, 400)
+		case errors.Is(err, ErrNodeNotFound):
+			reason.HTTPError(w, err, 404)
+		default:
+			reason.HTTPError(w, err, 502)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		// Restart ran but the service did not come back healthy
+		w.WriteHeader(http.StatusBadGateway)
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+func (h *Handler) RecordMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var report agent.MetricsReport
+	if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+		reason.HTTPError(w, err, 400)
+		return
+	}
+
+	// Samples drive memory-pressure preemption, so a node may only report for itself:
+	// the agent presents its node certificate, whose CN is the node's hostname
+	if identity := auth.ClientIdentity(r); identity == "" || identity != report.Hostname {
+		reason.HTTPErrorf(w, http.StatusForbidden, "client certificate %q may not report metrics for node %q", identity, report.Hostname)
+		return
+	}
+
+	if err := h.service.RecordMetrics(r.Context(), &report); err != nil {
+		if errors.Is(err, ErrNodeNotFound) {
+			reason.HTTPError(w, err, 404)
+			return
+		}
+		reason.HTTPError(w, err, 500)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetMetrics returns the node's metrics samples, oldest first.
+// The optional since query parameter is a duration (e.g. 15m, 6h) looking back from now.
+func (h *Handler) GetMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	window := defaultMetricsWindow
+	if v := r.URL.Query().Get("since"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			reason.HTTPErrorf(w, 400, "invalid since")
+			return
+		}
+		window = d
+	}
+
+	metrics, err := h.service.GetMetrics(r.Context(), r.PathValue("id"), time.Now().Add(-window))
+	if err != nil {
+		if errors.Is(err, ErrNodeNotFound) {
+			reason.HTTPError(w, err, 404)
+			return
+		}
+		reason.HTTPError(w, err, 500)
+		return
+	}
+	if metrics == nil {
+		metrics = []database.NodeMetric{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(metrics)
+}
+
+func (h *Handler) Benchmark(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Benchmarks take longer than the server's default write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Now().Add(benchmarkTimeout))
+
+	result, err := h.service.Benchmark(r.Context(), r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, ErrNodeNotFound) {
+			reason.HTTPError(w, err, 404)
+			return
+		}
+		reason.HTTPError(w, err, 502)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func (h *Handler) GetAttributes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	attrs, err := h.service.GetAttributes(r.Context(), r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, ErrNodeNotFound) {
+			reason.HTTPError(w, err, 404)
+			return
+		}
+		reason.HTTPError(w, err, 500)
+		return
+	}
+	if attrs == nil {
+		attrs = []database.NodeAttribute{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(attrs)
+}
+
+// GetLabels handles GET /nodes/{id}/labels.
+func (h *Handler) GetLabels(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	labels, err := h.service.GetLabels(r.Context(), r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, ErrNodeNotFound) {
+			reason.HTTPError(w, err, 404)
+			return
+		}
+		reason.HTTPError(w, err, 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(labels)
+}
+
+// SetLabel handles PUT /nodes/{id}/labels/{key} with a SetLabelRequest and
+// returns the node's Labels.
+func (h *Handler) SetLabel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req SetLabelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		reason.HTTPError(w, err, 400)
+		return
+	}
+
+	labels, err := h.service.SetLabel(r.Context(), r.PathValue("id"), r.PathValue("key"), &req)
+	h.writeLabels(w, labels, err)
+}
+
+// SetTaint handles PUT /nodes/{id}/taints/{key} with a SetTaintRequest and
+// returns the node's Labels.
+func (h *Handler) SetTaint(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req SetTaintRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		reason.HTTPError(w, err, 400)
+		return
+	}
+
+	labels, err := h.service.SetTaint(r.Context(), r.PathValue("id"), r.PathValue("key"), &req)
+	h.writeLabels(w, labels, err)
+}
+
+func (h *Handler) writeLabels(w http.ResponseWriter, labels *Labels, err error) {
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidLabel):
+			reason.HTTPError(w, err, 400)
+		case errors.Is(err, ErrNodeNotFound):
+			reason.HTTPError(w, err, 404)
+		default:
+			reason.HTTPError(w, err, 500)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(labels)
+}
+
+// DeleteLabel handles DELETE /nodes/{id}/labels/{key}.
+func (h *Handler) DeleteLabel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	h.writeDeleted(w, h.service.DeleteLabel(r.Context(), r.PathValue("id"), r.PathValue("key")))
+}
+
+// DeleteTaint handles DELETE /nodes/{id}/taints/{key}.
+func (h *Handler) DeleteTaint(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	h.writeDeleted(w, h.service.DeleteTaint(r.Context(), r.PathValue("id"), r.PathValue("key")))
+}
+
+func (h *Handler) writeDeleted(w http.ResponseWriter, err error) {
+	if err != nil {
+		if errors.Is(err, ErrNodeNotFound) {
+			reason.HTTPError(w, err, 404)
+			return
+		}
+		reason.HTTPError(w, err, 500)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Drain handles both POST /nodes/{id}/drain (operators) and POST /nodes/drain
+// (agents of nodes about to shut down, identified by hostname in the body).
+func (h *Handler) Drain(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req DrainRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			reason.HTTPError(w, err, 400)
+			return
+		}
+	}
+	nodeID := r.PathValue("id")
+	if nodeID == "" && req.Hostname == "" {
+		reason.HTTPErrorf(w, 400, "hostname is required")
+		return
+	}
+
+	// Evacuating workloads takes longer than the server's default write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Now().Add(maxDrainTimeout + time.Minute))
+
+	result, err := h.service.Drain(r.Context(), nodeID, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrNodeNotFound):
+			reason.HTTPError(w, err, 404)
+		case errors.Is(err, ErrDrainTimeout):
+			reason.HTTPError(w, err, 504)
+		default:
+			reason.HTTPError(w, err, 500)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// RemoveNode handles DELETE /nodes/{id}?force=true. Without force the node must
+// have been drained; the result lists the removal steps.
+func (h *Handler) RemoveNode(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	force := false
+	if v := r.URL.Query().Get("force"); v != "" {
+		var err error
+		if force, err = strconv.ParseBool(v); err != nil {
+			reason.HTTPErrorf(w, 400, "invalid force")
+			return
+		}
+	}
+
+	// Removing OSDs waits for Ceph to move their data
+	http.NewResponseController(w).SetWriteDeadline(time.Now().Add(removeTimeout))
+
+	result, err := h.service.Remove(r.Context(), r.PathValue("id"), force)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrNodeNotFound):
+			reason.HTTPError(w, err, 404)
+		case errors.Is(err, ErrRemoveRefused):
+			reason.HTTPError(w, err, 409)
+		default:
+			reason.HTTPError(w, err, 502)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// Restore handles both POST /nodes/{id}/restore and POST /nodes/restore (agents after boot).
+func (h *Handler) Restore(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req DrainRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			reason.HTTPError(w, err, 400)
+			return
+		}
+	}
+	nodeID := r.PathValue("id")
+	if nodeID == "" && req.Hostname == "" {
+		reason.HTTPErrorf(w, 400, "hostname is required")
+		return
+	}
+
+	http.NewResponseController(w).SetWriteDeadline(time.Now().Add(maxDrainTimeout + time.Minute))
+
+	if err := h.service.Restore(r.Context(), nodeID, req.Hostname); err != nil {
+		if errors.Is(err, ErrNodeNotFound) {
+			reason.HTTPError(w, err, 404)
+			return
+		}
+		reason.HTTPError(w, err, 500)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Rejoin handles POST /nodes/rejoin from `mcloudctl rejoin` on a node that lost
+// its state file or local database. Each client address may try 10 times a
+// minute and is locked out for 15 minutes after 5 rejected attempts in a row
+// (429 with Retry-After).
+func (h *Handler) Rejoin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	from := auth.ClientAddr(r)
+	if wait, err := h.rejoinGuard.Allow(from); err != nil {
+		w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+		reason.HTTPError(w, err, http.StatusTooManyRequests)
+		return
+	}
+
+	var req RejoinRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		reason.HTTPError(w, err, 400)
+		return
+	}
+	if req.NodeID == "" || req.Hostname == "" || req.Nonce == "" || req.MAC == "" {
+		reason.HTTPErrorf(w, 400, "node_id, hostname, nonce and mac are required")
+		return
+	}
+
+	resp, err := h.service.Rejoin(r.Context(), &req, from)
+	if err != nil {
+		if errors.Is(err, ErrRejoinDenied) {
+			if h.rejoinGuard.Fail(from) {
+				log.Warn("Locked out re-joins from %s for %s after %d rejected attempts", from, rejoinLockout, rejoinMaxFailures)
+			}
+			reason.HTTPError(w, err, 403)
+			return
+		}
+		reason.HTTPError(w, err, 500)
+		return
+	}
+	h.rejoinGuard.Succeed(from)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
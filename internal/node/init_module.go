@@ -0,0 +1,29 @@
+package node
+
+import (
+	"net/http"
+)
+
+// InitModule registers the node routes served by handler.
+func InitModule(mux *http.ServeMux, handler *Handler) {
+	mux.HandleFunc("/nodes", handler.ListNodes)
+	mux.HandleFunc("/nodes/{id}", handler.RemoveNode)
+	mux.HandleFunc("/nodes/{id}/services/{service}/restart", handler.RestartService)
+	mux.HandleFunc("/nodes/metrics", handler.RecordMetrics)
+	mux.HandleFunc("/nodes/{id}/metrics", handler.GetMetrics)
+	mux.HandleFunc("/nodes/{id}/benchmark", handler.Benchmark)
+	mux.HandleFunc("/nodes/{id}/attributes", handler.GetAttributes)
+
+	// Labels and taints used by the workload scheduler
+	mux.HandleFunc("GET /nodes/{id}/labels", handler.GetLabels)
+	mux.HandleFunc("PUT /nodes/{id}/labels/{key}", handler.SetLabel)
+	mux.HandleFunc("DELETE /nodes/{id}/labels/{key}", handler.DeleteLabel)
+	mux.HandleFunc("PUT /nodes/{id}/taints/{key}", handler.SetTaint)
+	mux.HandleFunc("DELETE /nodes/{id}/taints/{key}", handler.DeleteTaint)
+
+	mux.HandleFunc("/nodes/drain", handler.Drain)
+	mux.HandleFunc("/nodes/{id}/drain", handler.Drain)
+	mux.HandleFunc("/nodes/restore", handler.Restore)
+	mux.HandleFunc("/nodes/{id}/restore", handler.Restore)
+	mux.HandleFunc("/nodes/rejoin", handler.Rejoin)
+}
@@ -0,0 +1,196 @@
+package node
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"mcloud/internal/database"
+	"mcloud/pkg/reason"
+)
+
+var ErrInvalidLabel = reason.New(reason.InvalidNodeLabel, "invalid node label or taint")
+
+// Taint effects. A NoSchedule taint keeps workloads that do not tolerate it off
+// the node; a PreferNoSchedule taint only when another node fits.
+const (
+	EffectNoSchedule       = "NoSchedule"
+	EffectPreferNoSchedule = "PreferNoSchedule"
+)
+
+var (
+	// labelKeyRegexp allows keys such as zone or example.com/gpu.
+	labelKeyRegexp   = regexp.MustCompile(`^[a-z0-9]([a-z0-9._/-]{0,61}[a-z0-9])?$`)
+	labelValueRegexp = regexp.MustCompile(`^([A-Za-z0-9]([A-Za-z0-9._-]{0,61}[A-Za-z0-9])?)?$`)
+)
+
+// Labels are the labels and taints of a node, used by the workload scheduler:
+// a workload's node_selector must match the labels of the node it is placed
+// on, and its tolerations must tolerate the node's taints.
+//
+// Example JSON:
+//   {"labels": {"zone": "a", "disk": "ssd"}, "taints": [{"key": "gpu", "value": "true", "effect": "NoSchedule"}]}
+type Labels struct {
+	Labels map[string]string `json:"labels"`
+	Taints []Taint           `json:"taints"`
+}
+
+type Taint struct {
+	Key    string `json:"key"`
+	Value  string `json:"value,omitempty"`
+	Effect string `json:"effect"`
+}
+
+func (t Taint) String() string {
+	if t.Value == "" {
+		return t.Key + ":" + t.Effect
+	}
+	return t.Key + "=" + t.Value + ":" + t.Effect
+}
+
+// Toleration lets a workload on nodes with a matching taint. An empty Value
+// tolerates the taint whatever its value, an empty Effect both effects.
+type Toleration struct {
+	Key    string `json:"key"`
+	Value  string `json:"value,omitempty"`
+	Effect string `json:"effect,omitempty"`
+}
+
+// Tolerates reports whether the toleration matches t.
+func (tol Toleration) Tolerates(t Taint) bool {
+	return tol.Key == t.Key && (tol.Value == "" || tol.Value == t.Value) && (tol.Effect == "" || tol.Effect == t.Effect)
+}
+
+// SetLabelRequest is the body of PUT /nodes/{id}/labels/{key}.
+type SetLabelRequest struct {
+	Value string `json:"value"`
+}
+
+// SetTaintRequest is the body of PUT /nodes/{id}/taints/{key}. Effect
+// defaults to NoSchedule.
+type SetTaintRequest struct {
+	Value  string `json:"value,omitempty"`
+	Effect string `json:"effect,omitempty"`
+}
+
+// ValidateLabel checks a label key and value; taints and node selectors use the same syntax.
+func ValidateLabel(key string, value string) error {
+	if !labelKeyRegexp.MatchString(key) {
+		return fmt.Errorf("%w: key %q must be at most 63 lower-case letters, digits and . _ / -, starting and ending with a letter or digit", ErrInvalidLabel, key)
+	}
+	if !labelValueRegexp.MatchString(value) {
+		return fmt.Errorf("%w: value %q of %s must be at most 63 letters, digits and . _ -, starting and ending with a letter or digit", ErrInvalidLabel, value, key)
+	}
+	return nil
+}
+
+// ValidateEffect checks a taint effect.
+func ValidateEffect(effect string) error {
+	if effect != EffectNoSchedule && effect != EffectPreferNoSchedule {
+		return fmt.Errorf("%w: effect %q must be NoSchedule or PreferNoSchedule", ErrInvalidLabel, effect)
+	}
+	return nil
+}
+
+// GroupLabels groups label rows by node ID.
+func GroupLabels(rows []database.NodeLabel) map[string]*Labels {
+	nodes := map[string]*Labels{}
+	for _, row := range rows {
+		l := nodes[row.NodeID]
+		if l == nil {
+			l = &Labels{Labels: map[string]string{}, Taints: []Taint{}}
+			nodes[row.NodeID] = l
+		}
+		switch row.Kind {
+		case database.NodeLabelKindLabel:
+			l.Labels[row.Key] = row.Value
+		case database.NodeLabelKindTaint:
+			l.Taints = append(l.Taints, Taint{Key: row.Key, Value: row.Value, Effect: row.Effect})
+		}
+	}
+	return nodes
+}
+
+// GetLabels returns the labels and taints of a node.
+func (s *Service) GetLabels(ctx context.Context, nodeID string) (*Labels, error) {
+	if err := s.checkNode(ctx, nodeID); err != nil {
+		return nil, err
+	}
+	rows, err := database.NewNodeLabelRepository(s.db).ListByNode(ctx, nodeID)
+	if err != nil {
+		return nil, err
+	}
+	if l := GroupLabels(rows)[nodeID]; l != nil {
+		return l, nil
+	}
+	return &Labels{Labels: map[string]string{}, Taints: []Taint{}}, nil
+}
+
+// SetLabel sets a label of a node and returns its labels and taints.
+func (s *Service) SetLabel(ctx context.Context, nodeID string, key string, req *SetLabelRequest) (*Labels, error) {
+	if err := ValidateLabel(key, req.Value); err != nil {
+		return nil, err
+	}
+	return s.setLabel(ctx, &database.NodeLabel{NodeID: nodeID, Kind: database.NodeLabelKindLabel, Key: key, Value: req.Value})
+}
+
+// SetTaint sets a taint of a node and returns its labels and taints. Workloads
+// already on the node stay there.
+func (s *Service) SetTaint(ctx context.Context, nodeID string, key string, req *SetTaintRequest) (*Labels, error) {
+	if req.Effect == "" {
+		req.Effect = EffectNoSchedule
+	}
+	if err := ValidateLabel(key, req.Value); err != nil {
+		return nil, err
+	}
+	if err := ValidateEffect(req.Effect); err != nil {
+		return nil, err
+	}
+	return s.setLabel(ctx, &database.NodeLabel{NodeID: nodeID, Kind: database.NodeLabelKindTaint, Key: key, Value: req.Value, Effect: req.Effect})
+}
+
+func (s *Service) setLabel(ctx context.Context, l *database.NodeLabel) (*Labels, error) {
+	if err := s.checkNode(ctx, l.NodeID); err != nil {
+		return nil, err
+	}
+	if err := database.NewNodeLabelRepository(s.db).Set(ctx, l); err != nil {
+		return nil, err
+	}
+	log.Info("Set %s %s=%s on node %s", l.Kind, l.Key, l.Value, l.NodeID)
+	return s.GetLabels(ctx, l.NodeID)
+}
+
+// DeleteLabel removes a label of a node; removing a missing label succeeds.
+func (s *Service) DeleteLabel(ctx context.Context, nodeID string, key string) error {
+	return s.deleteLabel(ctx, nodeID, database.NodeLabelKindLabel, key)
+}
+
+// DeleteTaint removes a taint of a node; removing a missing taint succeeds.
+func (s *Service) DeleteTaint(ctx context.Context, nodeID string, key string) error {
+	return s.deleteLabel(ctx, nodeID, database.NodeLabelKindTaint, key)
+}
+
+func (s *Service) deleteLabel(ctx context.Context, nodeID string, kind string, key string) error {
+	if err := s.checkNode(ctx, nodeID); err != nil {
+		return err
+	}
+	deleted, err := database.NewNodeLabelRepository(s.db).Delete(ctx, nodeID, kind, key)
+	if err != nil {
+		return err
+	}
+	if deleted {
+		log.Info("Removed %s %s from node %s", kind, key, nodeID)
+	}
+	return nil
+}
+
+// checkNode returns ErrNodeNotFound unless the node exists.
+func (s *Service) checkNode(ctx context.Context, nodeID string) error {
+	_, err := database.NewNodeRepository(s.db).GetByID(ctx, nodeID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNodeNotFound
+	}
+	return err
+}
@@ -0,0 +1,116 @@
+package node
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"mcloud/internal/database"
+	"mcloud/internal/event"
+	"mcloud/services/lxd"
+)
+
+// DefaultPreemptMemoryPercent is the node memory use at which low-priority
+// workloads are stopped when scheduler.preempt_memory_percent is not set.
+const DefaultPreemptMemoryPercent = 95
+
+// preemptMemoryPercent returns the configured memory pressure threshold; a
+// negative value disables preemption under memory pressure.
+func (s *Service) preemptMemoryPercent() int {
+	if s.cfg == nil || s.cfg.Scheduler.PreemptMemoryPercent == 0 {
+		return DefaultPreemptMemoryPercent
+	}
+	return s.cfg.Scheduler.PreemptMemoryPercent
+}
+
+// preempt stops running low-priority workloads on node n, newest first, and
+// records a workload.preempted event with the reason for each. At most limit
+// workloads are stopped (0 = all of them). Normal and high priority workloads are
+// never preempted.
+//
+// Example Output:
+//   []string{"batch-7", "batch-3"}
+func (s *Service) preempt(ctx context.Context, n *database.Node, reason string, limit int) ([]string, error) {
+	workloadRepo := database.NewWorkloadRepository(s.db)
+	workloads, err := workloadRepo.ListByNode(ctx, n.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	stopped := []string{}
+	var errs []error
+	for _, w := range workloads {
+		if w.Priority != database.PriorityLow {
+			// ListByNode returns the lowest priority first
+			break
+		}
+		if w.Status != "running" {
+			continue
+		}
+		if limit > 0 && len(stopped) == limit {
+			break
+		}
+
+		if err := lxd.StopInstance(lxd.WithProject(ctx, w.Project), w.Name); err != nil {
+			errs = append(errs, fmt.Errorf("failed to preempt workload %s: %w", w.Name, err))
+			continue
+		}
+		now := time.Now()
+		if err := workloadRepo.UpdateStatus(ctx, w.ID, "stopped"); err != nil {
+			return stopped, err
+		}
+		if err := workloadRepo.SetPreempted(ctx, w.ID, &now); err != nil {
+			return stopped, err
+		}
+		stopped = append(stopped, w.Name)
+		s.event(ctx, n, event.WorkloadPreempted, fmt.Sprintf("Stopped low-priority workload %s on node %s: %s", w.Name, n.Hostname, reason))
+	}
+	return stopped, errors.Join(errs...)
+}
+
+// resumePreempted starts the workloads on node n that were preempted and are
+// still stopped, once the node has room again.
+func (s *Service) resumePreempted(ctx context.Context, n *database.Node) error {
+	workloadRepo := database.NewWorkloadRepository(s.db)
+	workloads, err := workloadRepo.ListByNode(ctx, n.ID)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, w := range workloads {
+		if w.PreemptedAt == nil {
+			continue
+		}
+		if w.Status == "stopped" {
+			if err := lxd.StartInstance(lxd.WithProject(ctx, w.Project), w.Name); err != nil {
+				errs = append(errs, fmt.Errorf("failed to restart preempted workload %s: %w", w.Name, err))
+				continue
+			}
+			if err := workloadRepo.UpdateStatus(ctx, w.ID, "running"); err != nil {
+				return err
+			}
+			s.event(ctx, n, event.WorkloadResumed, fmt.Sprintf("Restarted preempted workload %s on node %s", w.Name, n.Hostname))
+		}
+		if err := workloadRepo.SetPreempted(ctx, w.ID, nil); err != nil {
+			return err
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkMemoryPressure stops one low-priority workload on node n when its memory
+// use reaches the preemption threshold. One per metrics report gives the host
+// time to reclaim the memory before deciding whether another must go.
+func (s *Service) checkMemoryPressure(ctx context.Context, n *database.Node, usedMB int, totalMB int) {
+	threshold := s.preemptMemoryPercent()
+	if threshold < 0 || totalMB <= 0 || usedMB*100 < totalMB*threshold {
+		return
+	}
+
+	reason := fmt.Sprintf("memory use %d%% reached the %d%% limit", usedMB*100/totalMB, threshold)
+	if _, err := s.preempt(ctx, n, reason, 1); err != nil {
+		log.Error("Failed to preempt workloads on node %s: %v", n.Hostname, err)
+	}
+}
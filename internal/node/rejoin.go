@@ -0,0 +1,165 @@
+package node
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"mcloud/internal/auth"
+	"mcloud/internal/database"
+	"mcloud/internal/event"
+	"mcloud/internal/secret"
+	"mcloud/pkg/reason"
+)
+
+var ErrRejoinDenied = reason.New(reason.RejoinDenied, "re-join credential rejected")
+
+// rejoinClockSkew is how far the timestamp of a signed re-join request may be
+// from the manager's clock; its nonce is remembered that long.
+const rejoinClockSkew = 5 * time.Minute
+
+// RejoinRequest is sent by `mcloudctl rejoin`, signed with the secret from the
+// node's bundle (see auth.RejoinMAC).
+//
+// Example JSON:
+//   {"node_id": "660e8400-...", "hostname": "node2", "nonce": "Hq1...", "timestamp": 1792141200, "mac": "5b0c..."}
+type RejoinRequest struct {
+	NodeID    string `json:"node_id"`
+	Hostname  string `json:"hostname"`
+	Nonce     string `json:"nonce"`
+	Timestamp int64  `json:"timestamp"` // Unix seconds
+	MAC       string `json:"mac"`
+}
+
+// SignRejoinRequest returns the request of a node re-joining with secret,
+// signed with a fresh nonce.
+func SignRejoinRequest(nodeID, hostname, secret string) (*RejoinRequest, error) {
+	nonce, err := auth.GenerateRejoinSecret()
+	if err != nil {
+		return nil, err
+	}
+	ts := time.Now().Unix()
+	return &RejoinRequest{
+		NodeID:    nodeID,
+		Hostname:  hostname,
+		Nonce:     nonce,
+		Timestamp: ts,
+		MAC:       auth.RejoinMAC(secret, nodeID, nonce, ts),
+	}, nil
+}
+
+// RejoinResponse is what the node needs to rebuild its state file, plus the
+// secret that replaces the one it just used.
+type RejoinResponse struct {
+	Node        database.Node `json:"node"`
+	ClusterName string        `json:"cluster_name"`
+	Secret      string        `json:"secret"`
+}
+
+// IssueRejoinSecret creates (or replaces) a node's re-join credential and returns
+// the secret, which the manager stores hashed and sealed with the secrets key
+// at keyPath (see secret.Seal), never in the clear.
+//
+// Example Output:
+//   Returns: ("q3Xk2...", nil); node_rejoin_credentials row with secret_hash sha256("q3Xk2...") and secret_sealed
+func IssueRejoinSecret(ctx context.Context, db *sql.DB, keyPath string, nodeID string) (string, error) {
+	s, err := auth.GenerateRejoinSecret()
+	if err != nil {
+		return "", err
+	}
+	sealed, err := secret.Seal(keyPath, s)
+	if err != nil {
+		return "", err
+	}
+	if err := database.NewNodeRejoinCredentialRepository(db).Upsert(ctx, nodeID, auth.HashRejoinSecret(s), sealed); err != nil {
+		return "", err
+	}
+	return s, nil
+}
+
+// Rejoin checks a node's signed re-join request and hostname and,
+// if they match, returns the node's membership and a rotated secret. Every
+// failure is reported as ErrRejoinDenied so callers cannot probe which node
+// IDs exist; the reason is recorded in a node.rejoin_failed event with the
+// caller's address from.
+//
+// Example Input:
+//   RejoinRequest{NodeID: "660e8400-...", Hostname: "node2", Nonce: "Hq1...", Timestamp: 1792141200, MAC: "5b0c..."}
+//
+// Example Output:
+//   RejoinResponse{Node: {ID: "660e8400-...", Hostname: "node2", Role: "member", ...}, ClusterName: "prod", Secret: "Zp91..."}
+func (s *Service) Rejoin(ctx context.Context, req *RejoinRequest, from string) (*RejoinResponse, error) {
+	n, err := database.NewNodeRepository(s.db).GetByID(ctx, req.NodeID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, s.denyRejoin(ctx, nil, from, fmt.Sprintf("unknown node %q", req.NodeID))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if n.Hostname != req.Hostname {
+		return nil, s.denyRejoin(ctx, n, from, fmt.Sprintf("hostname %q does not match", req.Hostname))
+	}
+
+	credRepo := database.NewNodeRejoinCredentialRepository(s.db)
+	cred, err := credRepo.Get(ctx, n.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, s.denyRejoin(ctx, n, from, "no re-join credential")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if cred.SecretSealed == "" {
+		return nil, s.denyRejoin(ctx, n, from, "re-join credential predates signed re-joins; issue a new bundle")
+	}
+	current, err := secret.Unseal(s.cfg.Security.SecretsKeyPath, cred.SecretSealed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unseal the re-join credential of node %s: %w", n.ID, err)
+	}
+	if !auth.VerifyRejoinMAC(current, n.ID, req.Nonce, req.Timestamp, req.MAC) {
+		return nil, s.denyRejoin(ctx, n, from, "wrong signature")
+	}
+	if !s.nonces.Use(req.Nonce, time.Unix(req.Timestamp, 0)) {
+		return nil, s.denyRejoin(ctx, n, from, "stale timestamp or replayed nonce")
+	}
+
+	cluster, err := database.NewClusterRepository(s.db).GetByID(ctx, n.ClusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	next, err := auth.GenerateRejoinSecret()
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := secret.Seal(s.cfg.Security.SecretsKeyPath, next)
+	if err != nil {
+		return nil, err
+	}
+	rotated, err := credRepo.Rotate(ctx, n.ID, cred.SecretHash, auth.HashRejoinSecret(next), sealed)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		// Another re-join with the same secret won the race
+		return nil, ErrRejoinDenied
+	}
+
+	log.Info("Node %s (%s) re-joined with its re-join credential", n.Hostname, n.ID)
+	return &RejoinResponse{Node: *n, ClusterName: cluster.Name, Secret: next}, nil
+}
+
+// denyRejoin records a failed re-join attempt as a node.rejoin_failed event,
+// on the node when it exists, and returns ErrRejoinDenied.
+func (s *Service) denyRejoin(ctx context.Context, n *database.Node, from string, why string) error {
+	log.Warn("Re-join from %s rejected: %s", from, why)
+	e := event.New(event.NodeRejoinFailed, fmt.Sprintf("Re-join from %s rejected: %s", from, why), nil)
+	if n != nil {
+		e = event.ForNode(n, event.NodeRejoinFailed, fmt.Sprintf("Re-join of node %s from %s rejected: %s", n.Hostname, from, why), nil)
+	}
+	if err := database.NewEventRepository(s.db).Create(ctx, e); err != nil {
+		log.Error("Failed to record event %s: %v", e.Type, err)
+	}
+	return ErrRejoinDenied
+}
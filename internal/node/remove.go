@@ -0,0 +1,138 @@
+package node
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"mcloud/internal/database"
+	"mcloud/internal/event"
+	"mcloud/pkg/reason"
+	"mcloud/services/lxd"
+	"mcloud/services/microceph"
+	"mcloud/services/microovn"
+)
+
+var ErrRemoveRefused = reason.New(reason.NodeRemoveRefused, "node cannot be removed")
+
+// Statuses of a RemoveStep.
+const (
+	RemoveStepDone   = "done"
+	RemoveStepFailed = "failed" // only with force; otherwise the removal stops
+)
+
+// RemoveResult reports what removing a node did.
+//
+// Example JSON:
+//   {"node_id": "660e8400-...", "hostname": "node3", "forced": false,
+//    "steps": [{"name": "lxd", "status": "done"}, {"name": "ceph", "status": "done", "detail": "removed OSDs [4 5]"}, ...],
+//    "detached_workloads": []}
+type RemoveResult struct {
+	NodeID            string       `json:"node_id"`
+	Hostname          string       `json:"hostname"`
+	Forced            bool         `json:"forced"`
+	Steps             []RemoveStep `json:"steps"`
+	DetachedWorkloads []string     `json:"detached_workloads"` // workloads that were still on the node (force only), now failed
+}
+
+type RemoveStep struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Remove takes a node out of the cluster for good:
+//  1. Remove it from the LXD cluster
+//  2. Remove its OSDs and then the member from MicroCeph
+//  3. Remove it from MicroOVN
+//  4. Revoke its client certificates (see auth.RejectRevoked), delete its rows
+//     and record a node.removed event
+//
+// The leader cannot be removed, and a node with workloads on it must be
+// drained first. force is for dead nodes: workloads still on the node are
+// marked failed, the steps run with their own force options, and a step that
+// fails is reported in the result instead of stopping the removal.
+//
+// Example Output (Error):
+//   Workloads on the node  =>  ErrRemoveRefused: node node3 still has workloads web-1, db-2; drain it first or use force
+func (s *Service) Remove(ctx context.Context, nodeID string, force bool) (*RemoveResult, error) {
+	n, err := s.resolveNode(ctx, nodeID, "")
+	if err != nil {
+		return nil, err
+	}
+	if n.Role == "leader" {
+		return nil, fmt.Errorf("%w: node %s is the leader", ErrRemoveRefused, n.Hostname)
+	}
+
+	workloads, err := database.NewWorkloadRepository(s.db).ListByNode(ctx, n.ID)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, w := range workloads {
+		names = append(names, w.Name)
+	}
+	if len(names) > 0 && !force {
+		return nil, fmt.Errorf("%w: node %s still has workloads %s; drain it first or use force", ErrRemoveRefused, n.Hostname, strings.Join(names, ", "))
+	}
+
+	result := &RemoveResult{NodeID: n.ID, Hostname: n.Hostname, Forced: force, DetachedWorkloads: []string{}}
+	s.event(ctx, n, event.NodeRemoveStarted, fmt.Sprintf("Removing node %s (force: %t)", n.Hostname, force))
+
+	// 1-3. Leave LXD, Ceph and OVN
+	steps := []struct {
+		name string
+		run  func() (string, error)
+	}{
+		{"lxd", func() (string, error) { return "", lxd.RemoveMember(ctx, n.Hostname, force) }},
+		{"ceph", func() (string, error) {
+			osds, err := microceph.RemoveMember(ctx, n.Hostname, force)
+			if len(osds) == 0 {
+				return "", err
+			}
+			return fmt.Sprintf("removed OSDs %v", osds), err
+		}},
+		{"ovn", func() (string, error) { return "", microovn.RemoveMember(ctx, n.Hostname, force) }},
+	}
+	for _, step := range steps {
+		detail, err := step.run()
+		if err != nil {
+			if !force {
+				s.event(ctx, n, event.NodeRemoveFailed, fmt.Sprintf("Removing node %s failed at %s: %v", n.Hostname, step.name, err))
+				return nil, fmt.Errorf("remove node %s from %s: %w", n.Hostname, step.name, err)
+			}
+			log.Warn("Removing node %s from %s failed, continuing (force): %v", n.Hostname, step.name, err)
+			result.Steps = append(result.Steps, RemoveStep{Name: step.name, Status: RemoveStepFailed, Detail: err.Error()})
+			continue
+		}
+		result.Steps = append(result.Steps, RemoveStep{Name: step.name, Status: RemoveStepDone, Detail: detail})
+	}
+
+	// 4. Forget the node
+	err = database.WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		workloadRepo := database.NewWorkloadRepositoryTx(tx)
+		for _, w := range workloads {
+			if err := workloadRepo.UpdateStatus(ctx, w.ID, "failed"); err != nil {
+				return err
+			}
+		}
+		if err := database.NewRevokedCertificateRepositoryTx(tx).Revoke(ctx, n.Hostname, "node "+n.ID+" removed"); err != nil {
+			return err
+		}
+		return database.NewNodeRepositoryTx(tx).Purge(ctx, n.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.DetachedWorkloads = append(result.DetachedWorkloads, names...)
+	result.Steps = append(result.Steps, RemoveStep{Name: "database", Status: RemoveStepDone, Detail: "certificates revoked, rows deleted"})
+
+	message := fmt.Sprintf("Node %s removed", n.Hostname)
+	if len(names) > 0 {
+		message += fmt.Sprintf("; workloads %s marked failed", strings.Join(names, ", "))
+	}
+	s.event(ctx, n, event.NodeRemoved, message)
+	log.Info("%s", message)
+	return result, nil
+}
@@ -0,0 +1,201 @@
+package node
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"mcloud/internal/agent"
+	"mcloud/internal/auth"
+	"mcloud/internal/config"
+	"mcloud/internal/database"
+	"mcloud/pkg/reason"
+)
+
+var (
+	ErrNodeNotFound      = reason.New(reason.NodeNotFound, "node not found")
+	ErrServiceNotAllowed = reason.New(reason.ServiceNotAllowed, "service cannot be restarted")
+)
+
+// Node attribute keys written by Benchmark.
+const (
+	AttrBenchmarkCPU     = "benchmark.cpu_score"
+	AttrBenchmarkDisk    = "benchmark.disk_write_mbps"
+	AttrBenchmarkNetwork = "benchmark.network_mbps"
+	AttrBenchmarkAt      = "benchmark.at"
+)
+
+// networkBenchmarkSize is the payload uploaded to the agent to measure throughput.
+const networkBenchmarkSize = 64 << 20
+
+// MetricsRetention is how long host metric samples are kept per node.
+const MetricsRetention = 24 * time.Hour
+
+type Service struct {
+	db     *sql.DB
+	cfg    *config.Config
+	nonces *auth.NonceCache // of signed re-join requests
+}
+
+func NewService(db *sql.DB, cfg *config.Config) *Service {
+	return &Service{
+		db:     db,
+		cfg:    cfg,
+		nonces: auth.NewNonceCache(rejoinClockSkew),
+	}
+}
+
+// RestartService restarts lxd, microceph, or microovn on the given node.
+// The request is forwarded to the node's agent, which only runs allowlisted commands
+// and performs health checks before and after the restart.
+func (s *Service) RestartService(ctx context.Context, nodeID string, service string) (*agent.ServiceRestartResult, error) {
+	// 1. Validate service name before reaching out to the node
+	if !agent.IsRestartable(service) {
+		return nil, fmt.Errorf("%w: %s", ErrServiceNotAllowed, service)
+	}
+
+	// 2. Resolve node address
+	nodeRepo := database.NewNodeRepository(s.db)
+	n, err := nodeRepo.GetByID(ctx, nodeID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNodeNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. Forward to the agent
+	client, err := agent.NewClient(s.cfg, n.IP)
+	if err != nil {
+		return nil, err
+	}
+	return client.RestartService(ctx, service)
+}
+
+// ListNodes returns one page of nodes, by default ordered by creation time.
+func (s *Service) ListNodes(ctx context.Context, opts database.ListOptions) ([]database.Node, error) {
+	return database.NewNodeRepository(s.db).List(ctx, opts)
+}
+
+// RecordMetrics stores a host metrics sample reported by a node's agent and
+// prunes samples that fell out of the retention window. A node under memory
+// pressure has a low-priority workload preempted (see checkMemoryPressure), and
+// the agent's circuit breakers update the node's conditions (see ApplyBreakers).
+func (s *Service) RecordMetrics(ctx context.Context, report *agent.MetricsReport) error {
+	n, err := database.NewNodeRepository(s.db).GetByHostname(ctx, report.Hostname)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNodeNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	collectedAt := report.CollectedAt
+	if collectedAt.IsZero() {
+		collectedAt = time.Now()
+	}
+
+	err = database.WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		metricRepo := database.NewNodeMetricRepositoryTx(tx)
+		if err := metricRepo.Create(ctx, &database.NodeMetric{
+			NodeID:           n.ID,
+			CPUCount:         report.CPUCount,
+			Load1:            report.Load1,
+			Load5:            report.Load5,
+			Load15:           report.Load15,
+			MemoryUsedMB:     report.MemoryUsedMB,
+			MemoryTotalMB:    report.MemoryTotalMB,
+			DiskUsedBytes:    report.DiskUsedBytes,
+			DiskTotalBytes:   report.DiskTotalBytes,
+			NetRxBytesPerSec: report.NetRxBytesPerSec,
+			NetTxBytesPerSec: report.NetTxBytesPerSec,
+			CollectedAt:      collectedAt,
+			HookQueueDepth:   report.HookQueueDepth,
+		}); err != nil {
+			return err
+		}
+		return metricRepo.DeleteBefore(ctx, n.ID, time.Now().Add(-MetricsRetention))
+	})
+	if err != nil {
+		return err
+	}
+
+	s.checkMemoryPressure(ctx, n, report.MemoryUsedMB, report.MemoryTotalMB)
+	if len(report.Breakers) > 0 {
+		if err := s.ApplyBreakers(ctx, n.Hostname, report.Breakers); err != nil {
+			log.Error("Failed to update conditions of node %s: %v", n.Hostname, err)
+		}
+	}
+	return nil
+}
+
+// GetMetrics returns the metrics samples of a node collected at or after since.
+func (s *Service) GetMetrics(ctx context.Context, nodeID string, since time.Time) ([]database.NodeMetric, error) {
+	if _, err := database.NewNodeRepository(s.db).GetByID(ctx, nodeID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNodeNotFound
+		}
+		return nil, err
+	}
+	return database.NewNodeMetricRepository(s.db).ListSince(ctx, nodeID, since)
+}
+
+// Benchmark runs the CPU/disk benchmark on the node's agent, measures network
+// throughput from the manager to the node, and stores the scores as node attributes.
+func (s *Service) Benchmark(ctx context.Context, nodeID string) (*agent.BenchmarkResult, error) {
+	n, err := database.NewNodeRepository(s.db).GetByID(ctx, nodeID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNodeNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := agent.NewClient(s.cfg, n.IP)
+	if err != nil {
+		return nil, err
+	}
+	result, err := client.RunBenchmark(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if result.NetworkMBps, err = client.MeasureNetwork(ctx, networkBenchmarkSize); err != nil {
+		return nil, err
+	}
+
+	formatScore := func(v float64) string { return strconv.FormatFloat(v, 'f', 1, 64) }
+	attrs := map[string]string{
+		AttrBenchmarkCPU:     formatScore(result.CPUScore),
+		AttrBenchmarkDisk:    formatScore(result.DiskWriteMBps),
+		AttrBenchmarkNetwork: formatScore(result.NetworkMBps),
+		AttrBenchmarkAt:      time.Now().UTC().Format(time.RFC3339),
+	}
+	err = database.WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		attrRepo := database.NewNodeAttributeRepositoryTx(tx)
+		for k, v := range attrs {
+			if err := attrRepo.Set(ctx, n.ID, k, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// GetAttributes returns all attributes of a node.
+func (s *Service) GetAttributes(ctx context.Context, nodeID string) ([]database.NodeAttribute, error) {
+	if _, err := database.NewNodeRepository(s.db).GetByID(ctx, nodeID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNodeNotFound
+		}
+		return nil, err
+	}
+	return database.NewNodeAttributeRepository(s.db).ListByNode(ctx, nodeID)
+}
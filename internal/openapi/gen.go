@@ -0,0 +1,26 @@
+//go:build ignore
+
+// gen writes openapi.json from the route table; run by go generate.
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+
+	"mcloud/internal/openapi"
+)
+
+func main() {
+	doc, err := openapi.Generate("../..")
+	if err != nil {
+		log.Fatal(err)
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile("openapi.json", append(data, '\n'), 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
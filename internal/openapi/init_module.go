@@ -0,0 +1,10 @@
+package openapi
+
+import (
+	"net/http"
+)
+
+// InitModule registers the OpenAPI document route served by handler.
+func InitModule(mux *http.ServeMux, handler *Handler) {
+	mux.HandleFunc("GET /openapi.json", handler.GetSpec)
+}
@@ -0,0 +1,20 @@
+// Package openapi serves the OpenAPI 3 document of the mcloudd REST API at
+// /v1/openapi.json. The document is generated from the route table (the
+// InitModule of every module) and committed as openapi.json; regenerate it
+// after changing routes with:
+//   go generate ./internal/openapi
+package openapi
+
+import (
+	_ "embed"
+)
+
+//go:generate go run gen.go
+
+//go:embed openapi.json
+var spec []byte
+
+// Spec returns the generated OpenAPI document.
+func Spec() []byte {
+	return spec
+}
@@ -0,0 +1,328 @@
+package openapi
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"mcloud/pkg/api"
+)
+
+// Document is the subset of an OpenAPI 3 document the generator fills in.
+type Document struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       Info                            `json:"info"`
+	Servers    []Server                        `json:"servers"`
+	Paths      map[string]map[string]Operation `json:"paths"` // path -> lower-case method -> operation
+	Components Components                      `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description"`
+}
+
+type Server struct {
+	URL string `json:"url"`
+}
+
+type Operation struct {
+	OperationID string              `json:"operationId"`
+	Summary     string              `json:"summary,omitempty"`
+	Description string              `json:"description,omitempty"`
+	Tags        []string            `json:"tags"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name     string `json:"name"`
+	In       string `json:"in"`
+	Required bool   `json:"required"`
+	Schema   Schema `json:"schema"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema Schema `json:"schema"`
+}
+
+type Schema struct {
+	Ref        string            `json:"$ref,omitempty"`
+	Type       string            `json:"type,omitempty"`
+	Properties map[string]Schema `json:"properties,omitempty"`
+}
+
+type Components struct {
+	Schemas map[string]Schema `json:"schemas"`
+}
+
+// errorResponse is the body of every error (see reason.HTTPError).
+var errorResponse = Response{
+	Description: "Error with a stable reason code (see docs/reason-codes.md)",
+	Content:     map[string]MediaType{"application/json": {Schema: Schema{Ref: "#/components/schemas/Error"}}},
+}
+
+// pathParam matches the wildcards of a route pattern, e.g. {id} or {path...}.
+var pathParam = regexp.MustCompile(`\{([A-Za-z_]+)(\.\.\.)?\}`)
+
+// route is one mux.HandleFunc call of an InitModule.
+type route struct {
+	pkg     string
+	method  string // "" when the pattern has none
+	path    string
+	handler *ast.FuncDecl
+}
+
+// Generate builds the document from the routes every internal/*/init_module.go
+// under root registers. Methods come from the route pattern or, when it has
+// none, from the http.Method* constants the handler compares r.Method with;
+// summaries come from the handler doc comments.
+func Generate(root string) (*Document, error) {
+	files, err := filepath.Glob(filepath.Join(root, "internal", "*", "init_module.go"))
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(files)
+
+	var routes []route
+	for _, file := range files {
+		r, err := packageRoutes(filepath.Dir(file))
+		if err != nil {
+			return nil, err
+		}
+		routes = append(routes, r...)
+	}
+
+	doc := &Document{
+		OpenAPI: "3.0.3",
+		Info: Info{
+			Title:       "mcloudd REST API",
+			Version:     api.Version,
+			Description: fmt.Sprintf("Generated from the mcloudd route table. Clients may send %s: %s.", api.VersionHeader, api.Version),
+		},
+		Servers: []Server{{URL: api.Prefix}},
+		Paths:   map[string]map[string]Operation{},
+		Components: Components{Schemas: map[string]Schema{
+			"Error": {Type: "object", Properties: map[string]Schema{
+				"code":    {Type: "string"},
+				"reason":  {Type: "string"},
+				"message": {Type: "string"},
+			}},
+		}},
+	}
+	ids := map[string]int{}
+	for _, r := range routes {
+		methods := []string{r.method}
+		if r.method == "" {
+			methods = handlerMethods(r.handler)
+		}
+		for _, method := range methods {
+			id := r.handler.Name.Name
+			if ids[id]++; ids[id] > 1 {
+				id += strconv.Itoa(ids[id])
+			}
+			summary, description := docText(r.handler)
+			op := Operation{
+				OperationID: id,
+				Summary:     summary,
+				Description: description,
+				Tags:        []string{r.pkg},
+				Responses:   map[string]Response{"default": errorResponse},
+			}
+			for _, m := range pathParam.FindAllStringSubmatch(r.path, -1) {
+				op.Parameters = append(op.Parameters, Parameter{Name: m[1], In: "path", Required: true, Schema: Schema{Type: "string"}})
+			}
+			for _, status := range successStatuses(r.handler) {
+				op.Responses[strconv.Itoa(status)] = Response{Description: http.StatusText(status)}
+			}
+
+			path := pathParam.ReplaceAllString(r.path, "{$1}")
+			if doc.Paths[path] == nil {
+				doc.Paths[path] = map[string]Operation{}
+			}
+			doc.Paths[path][strings.ToLower(method)] = op
+		}
+	}
+	return doc, nil
+}
+
+// packageRoutes parses the package in dir and returns the routes its
+// init_module.go registers.
+func packageRoutes(dir string) ([]route, error) {
+	fset := token.NewFileSet()
+	names, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+	funcs := map[string]*ast.FuncDecl{}
+	var initModule *ast.File
+	for _, name := range names {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, name, nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		for _, decl := range f.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && isHandlerMethod(fn) {
+				funcs[fn.Name.Name] = fn
+			}
+		}
+		if filepath.Base(name) == "init_module.go" {
+			initModule = f
+		}
+	}
+
+	var routes []route
+	var err2 error
+	ast.Inspect(initModule, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) != 2 {
+			return true
+		}
+		if sel, ok := call.Fun.(*ast.SelectorExpr); !ok || sel.Sel.Name != "HandleFunc" {
+			return true
+		}
+		lit, ok := call.Args[0].(*ast.BasicLit)
+		handler, ok2 := call.Args[1].(*ast.SelectorExpr)
+		if !ok || !ok2 {
+			err2 = fmt.Errorf("%s: route at %s is not a literal pattern and handler method", dir, fset.Position(call.Pos()))
+			return false
+		}
+		pattern, _ := strconv.Unquote(lit.Value)
+		r := route{pkg: initModule.Name.Name, path: pattern, handler: funcs[handler.Sel.Name]}
+		if method, path, ok := strings.Cut(pattern, " "); ok {
+			r.method, r.path = method, path
+		}
+		if r.handler == nil {
+			err2 = fmt.Errorf("%s: handler %s of %s not found", dir, handler.Sel.Name, pattern)
+			return false
+		}
+		routes = append(routes, r)
+		return true
+	})
+	return routes, err2
+}
+
+// isHandlerMethod reports whether fn is a method of the package's *Handler.
+func isHandlerMethod(fn *ast.FuncDecl) bool {
+	if fn.Recv == nil || len(fn.Recv.List) != 1 {
+		return false
+	}
+	star, ok := fn.Recv.List[0].Type.(*ast.StarExpr)
+	if !ok {
+		return false
+	}
+	ident, ok := star.X.(*ast.Ident)
+	return ok && ident.Name == "Handler"
+}
+
+// handlerMethods returns the methods a handler serves: the http.Method*
+// constants it refers to, or GET.
+func handlerMethods(fn *ast.FuncDecl) []string {
+	var methods []string
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if x, ok := sel.X.(*ast.Ident); ok && x.Name == "http" && strings.HasPrefix(sel.Sel.Name, "Method") {
+				method := strings.ToUpper(strings.TrimPrefix(sel.Sel.Name, "Method"))
+				if !slices.Contains(methods, method) {
+					methods = append(methods, method)
+				}
+			}
+		}
+		return true
+	})
+	if len(methods) == 0 {
+		return []string{http.MethodGet}
+	}
+	return methods
+}
+
+// successStatuses returns the 2xx statuses a handler answers with: those it
+// passes to WriteHeader, 202 if it starts an operation, else 200.
+func successStatuses(fn *ast.FuncDecl) []int {
+	var statuses []int
+	add := func(status int) {
+		if !slices.Contains(statuses, status) {
+			statuses = append(statuses, status)
+		}
+	}
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		switch {
+		case sel.Sel.Name == "WriteAccepted":
+			add(http.StatusAccepted)
+		case sel.Sel.Name == "WriteHeader" && len(call.Args) == 1:
+			if status, ok := constStatus(call.Args[0]); ok && status >= 200 && status < 300 {
+				add(status)
+			}
+		}
+		return true
+	})
+	if len(statuses) == 0 {
+		add(http.StatusOK)
+	}
+	slices.Sort(statuses)
+	return statuses
+}
+
+// statusCodes maps the http.Status* constants handlers use to their codes.
+var statusCodes = map[string]int{
+	"StatusOK":                 http.StatusOK,
+	"StatusCreated":            http.StatusCreated,
+	"StatusAccepted":           http.StatusAccepted,
+	"StatusNoContent":          http.StatusNoContent,
+	"StatusSwitchingProtocols": http.StatusSwitchingProtocols,
+}
+
+func constStatus(e ast.Expr) (int, bool) {
+	switch e := e.(type) {
+	case *ast.BasicLit:
+		status, err := strconv.Atoi(e.Value)
+		return status, err == nil
+	case *ast.SelectorExpr:
+		status, ok := statusCodes[e.Sel.Name]
+		return status, ok
+	}
+	return 0, false
+}
+
+// docText splits a handler's doc comment into its first sentence and the rest.
+func docText(fn *ast.FuncDecl) (summary, description string) {
+	text := strings.TrimSpace(fn.Doc.Text())
+	if text == "" {
+		return "", ""
+	}
+	first, _, _ := strings.Cut(text, "\n\n")
+	first = strings.Join(strings.Fields(first), " ")
+	if i := strings.Index(first, ". "); i >= 0 {
+		first = first[:i+1]
+	}
+	// Handler comments start with the handler name, e.g. "ListAgents returns ..."
+	first = strings.TrimPrefix(first, fn.Name.Name+" ")
+	if first != "" {
+		first = strings.ToUpper(first[:1]) + first[1:]
+	}
+	return first, text
+}
@@ -0,0 +1,53 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+// TestSpecUpToDate fails when routes changed without `go generate ./internal/openapi`.
+func TestSpecUpToDate(t *testing.T) {
+	doc, err := Generate("../..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(append(data, '\n'), Spec()) {
+		t.Error("openapi.json is stale; run go generate ./internal/openapi")
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	doc, err := Generate("../..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path, method, id, status string
+	}{
+		{"/workloads/{id}", "patch", "UpdateWorkload", "200"},
+		{"/workloads/{id}/clone", "post", "CloneWorkload", "202"}, // method from the handler
+		{"/flavors", "post", "CreateFlavor", "201"},
+		{"/nodes/{id}/services/{service}/restart", "post", "RestartService", "200"},
+	}
+	for _, tt := range tests {
+		op, ok := doc.Paths[tt.path][tt.method]
+		if !ok {
+			t.Errorf("%s %s missing", tt.method, tt.path)
+			continue
+		}
+		if op.OperationID != tt.id {
+			t.Errorf("%s %s: operationId %s, want %s", tt.method, tt.path, op.OperationID, tt.id)
+		}
+		if _, ok := op.Responses[tt.status]; !ok {
+			t.Errorf("%s %s: responses %v, want %s", tt.method, tt.path, op.Responses, tt.status)
+		}
+	}
+	if params := doc.Paths["/nodes/{id}/services/{service}/restart"]["post"].Parameters; len(params) != 2 || params[1].Name != "service" {
+		t.Errorf("path parameters: got %+v", params)
+	}
+}
@@ -0,0 +1,90 @@
+package operation
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"mcloud/internal/database"
+	"mcloud/pkg/api"
+	"mcloud/pkg/reason"
+	"mcloud/pkg/utils"
+)
+
+type ListOperationsResponse struct {
+	Items      []database.Operation `json:"items"`
+	NextOffset int                  `json:"next_offset,omitempty"` // 0 when there are no more pages
+}
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(s *Service) *Handler {
+	return &Handler{service: s}
+}
+
+// ListOperations handles GET /operations?status=running&limit=100&offset=0.
+func (h *Handler) ListOperations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit, offset, err := utils.ParsePage(r)
+	if err != nil {
+		reason.HTTPError(w, err, 400)
+		return
+	}
+
+	// Streamed in the shape of ListOperationsResponse without buffering the page
+	stream := utils.NewJSONStream(w, "items")
+	err = h.service.List(r.Context(), r.URL.Query().Get("status"), limit, offset, func(op *database.Operation) error {
+		return stream.Write(op)
+	})
+	if err != nil {
+		switch {
+		case stream.Started():
+		case errors.Is(err, ErrInvalidStatus):
+			reason.HTTPError(w, err, 400)
+		default:
+			reason.HTTPError(w, err, 500)
+		}
+		return
+	}
+
+	var extra map[string]any
+	if stream.Count() == limit {
+		extra = map[string]any{"next_offset": offset + limit}
+	}
+	stream.Close(extra)
+}
+
+func (h *Handler) GetOperation(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	result, err := h.service.Get(r.Context(), r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, ErrOperationNotFound) {
+			reason.HTTPError(w, err, 404)
+			return
+		}
+		reason.HTTPError(w, err, 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// WriteAccepted answers a request that started an operation: 202 with a Location
+// header pointing at the operation and body as the response.
+func WriteAccepted(w http.ResponseWriter, op *database.Operation, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", api.Path("/operations/"+op.ID))
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(body)
+}
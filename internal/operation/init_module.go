@@ -0,0 +1,11 @@
+package operation
+
+import (
+	"net/http"
+)
+
+// InitModule registers the operation routes served by handler.
+func InitModule(mux *http.ServeMux, handler *Handler) {
+	mux.HandleFunc("GET /operations", handler.ListOperations)
+	mux.HandleFunc("GET /operations/{id}", handler.GetOperation)
+}
@@ -0,0 +1,210 @@
+// Package operation runs long-running work (e.g. launching a workload) in the
+// background. The request that starts it gets an operation ID immediately;
+// status and progress are stored in the operations table for clients to poll.
+package operation
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"mcloud/internal/config"
+	"mcloud/internal/database"
+	"mcloud/pkg/logger"
+	"mcloud/pkg/reason"
+	"mcloud/pkg/utils"
+)
+
+var log = logger.Named("operation")
+
+// DefaultTimeout applies when manager.operation_timeout_seconds is not set.
+const DefaultTimeout = 30 * time.Minute
+
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+var (
+	ErrOperationNotFound = reason.New(reason.OperationNotFound, "operation not found")
+	ErrInvalidStatus     = reason.New(reason.InvalidOperationStatus, "status must be pending, running, succeeded or failed")
+)
+
+// Reporter updates the progress (0-100) and current step of a running operation.
+type Reporter func(progress int, message string)
+
+// Func is the work of an operation. Its result is stored as JSON when it succeeds.
+type Func func(ctx context.Context, report Reporter) (any, error)
+
+type Service struct {
+	db      *sql.DB
+	manager string        // hostname of this mcloudd, recorded on every operation
+	timeout time.Duration // an operation's context is cancelled after this long
+}
+
+func NewService(db *sql.DB, cfg *config.Config) *Service {
+	hostname, _ := os.Hostname()
+	timeout := DefaultTimeout
+	if cfg.Manager.OperationTimeoutSeconds > 0 {
+		timeout = time.Duration(cfg.Manager.OperationTimeoutSeconds) * time.Second
+	}
+	return &Service{db: db, manager: hostname, timeout: timeout}
+}
+
+// Done reports whether an operation status is final.
+func Done(status string) bool {
+	return status == StatusSucceeded || status == StatusFailed
+}
+
+// Start records a pending operation and runs fn in the background, detached from
+// ctx so it outlives the request. resource names what the operation affects.
+// fn's context is cancelled after the operation timeout, which kills any
+// command it is still running.
+//
+// Example Input:
+//   Start(ctx, "workload.create", "/workloads/550e8400-...", &actor, launch)
+//
+// Example Output:
+//   &database.Operation{ID: "7f1c...", Type: "workload.create", Status: "pending",
+//     Resource: "/workloads/550e8400-...", Manager: "node1"}, nil
+//   GET /operations/7f1c... later returns Status "running" (Progress 40, Message
+//   "Launching instance"), then "succeeded" with the result of fn.
+func (s *Service) Start(ctx context.Context, opType string, resource string, actor *string, fn Func) (*database.Operation, error) {
+	return s.StartWithTimeout(ctx, opType, resource, actor, s.timeout, fn)
+}
+
+// StartWithTimeout is Start with a timeout of its own instead of the
+// manager's operation timeout, for work known to take longer (e.g. a rolling
+// refresh of every node).
+func (s *Service) StartWithTimeout(ctx context.Context, opType string, resource string, actor *string, timeout time.Duration, fn Func) (*database.Operation, error) {
+	op := &database.Operation{
+		ID:           utils.GenerateUUID(),
+		Type:         opType,
+		Status:       StatusPending,
+		Manager:      s.manager,
+		CreateUserID: actor,
+	}
+	if resource != "" {
+		op.Resource = &resource
+	}
+
+	repo := database.NewOperationRepository(s.db)
+	if err := repo.Create(ctx, op); err != nil {
+		return nil, err
+	}
+
+	go s.run(context.Background(), op, timeout, fn)
+
+	return repo.GetByID(ctx, op.ID)
+}
+
+func (s *Service) run(ctx context.Context, op *database.Operation, timeout time.Duration, fn Func) {
+	repo := database.NewOperationRepository(s.db)
+
+	finish := func(result any, err error) {
+		var data []byte
+		var errMsg *string
+		status := StatusSucceeded
+		if err == nil && result != nil {
+			if data, err = json.Marshal(result); err != nil {
+				err = fmt.Errorf("failed to encode result: %w", err)
+			}
+		}
+		if err != nil {
+			status = StatusFailed
+			msg := err.Error()
+			errMsg = &msg
+			data = nil
+			log.Warn("Operation %s (%s) failed: %v", op.ID, op.Type, err)
+		}
+		if uerr := repo.Finish(ctx, op.ID, status, data, errMsg); uerr != nil {
+			log.Error("Failed to update operation %s: %v", op.ID, uerr)
+		}
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			finish(nil, fmt.Errorf("operation panicked: %v", p))
+		}
+	}()
+
+	report := func(progress int, message string) {
+		progress = max(0, min(progress, 100))
+		if err := repo.UpdateProgress(ctx, op.ID, StatusRunning, progress, message); err != nil {
+			log.Error("Failed to update operation %s: %v", op.ID, err)
+		}
+	}
+	report(0, "Started")
+
+	fnCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	result, err := fn(fnCtx, report)
+	if err != nil && errors.Is(fnCtx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("operation timed out after %s: %w", timeout, err)
+	}
+	finish(result, err)
+}
+
+func (s *Service) Get(ctx context.Context, id string) (*database.Operation, error) {
+	op, err := database.NewOperationRepository(s.db).GetByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrOperationNotFound
+	}
+	return op, err
+}
+
+// waitInterval is how often Wait polls an operation.
+const waitInterval = time.Second
+
+// Wait blocks until an operation finishes and returns it; a failed operation
+// is returned with its error.
+func (s *Service) Wait(ctx context.Context, id string) (*database.Operation, error) {
+	for {
+		op, err := s.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if op.Status == StatusFailed {
+			msg := "unknown error"
+			if op.Error != nil {
+				msg = *op.Error
+			}
+			return op, fmt.Errorf("operation %s (%s) failed: %s", op.ID, op.Type, msg)
+		}
+		if Done(op.Status) {
+			return op, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(waitInterval):
+		}
+	}
+}
+
+// List calls fn for operations newest first, optionally filtered by status.
+func (s *Service) List(ctx context.Context, status string, limit int, offset int, fn func(*database.Operation) error) error {
+	if status != "" && status != StatusPending && status != StatusRunning && !Done(status) {
+		return ErrInvalidStatus
+	}
+	return database.NewOperationRepository(s.db).Each(ctx, status, limit, offset, fn)
+}
+
+// FailInterrupted marks operations this manager was running before it restarted
+// as failed; their goroutines are gone.
+func (s *Service) FailInterrupted(ctx context.Context) error {
+	n, err := database.NewOperationRepository(s.db).FailUnfinished(ctx, s.manager, "interrupted by mcloudd restart")
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		log.Warn("Marked %d interrupted operations as failed", n)
+	}
+	return nil
+}
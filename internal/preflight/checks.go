@@ -0,0 +1,225 @@
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	"mcloud/pkg/commander"
+	"mcloud/pkg/utils"
+	"mcloud/services/microceph"
+)
+
+const (
+	// LXDPort is the LXD cluster API port, which init always needs.
+	LXDPort = 8443
+
+	defaultMinCPU      = 2
+	defaultMinMemoryMB = 4096
+)
+
+// RequiredSnaps are the snaps mcloud drives, keyed by snap name with the
+// commands each provides.
+var RequiredSnaps = map[string][]string{
+	"lxd":       {"lxd", "lxc"},
+	"microceph": {"microceph"},
+	"microovn":  {"microovn"},
+}
+
+// requiredSnapOrder keeps reports stable.
+var requiredSnapOrder = []string{"lxd", "microceph", "microovn"}
+
+// RecommendedModules are kernel modules OVN and Ceph RBD use. They are usually
+// loaded on demand, so a missing one is only a warning.
+var RecommendedModules = []string{"openvswitch", "rbd"}
+
+// Options selects what Checks verifies.
+type Options struct {
+	Ports       []int    // TCP ports that must be free, in addition to LXDPort
+	Disks       []string // Ceph disks that must exist; none means "look for unused disks"
+	LoopOSDs    bool     // loop-file OSDs are allowed when there are no disks
+	MinCPU      int      // 0 means defaultMinCPU
+	MinMemoryMB int      // 0 means defaultMinMemoryMB
+}
+
+// Checks returns the standard preflight checks for a node about to init or join.
+//
+// Example Input:
+//   Checks(Options{Ports: []int{9028, 9030}, Disks: []string{"/dev/sdb"}})
+//
+// Example Output (as run by Run):
+//   snap lxd        pass  lxd, lxc found
+//   kernel rbd      warn  module rbd is not loaded
+//   port 8443       pass  free
+//   disk /dev/sdb   pass  present
+//   time sync       pass  system clock is synchronized
+//   cpu             pass  4 cores (minimum 2)
+//   memory          fail  2048 MB (minimum 4096 MB)
+func Checks(opts Options) []Check {
+	var checks []Check
+	for _, snap := range requiredSnapOrder {
+		checks = append(checks, snapCheck(snap))
+	}
+	for _, module := range RecommendedModules {
+		checks = append(checks, moduleCheck(module))
+	}
+	for _, port := range append([]int{LXDPort}, opts.Ports...) {
+		checks = append(checks, portCheck(port))
+	}
+	if len(opts.Disks) > 0 {
+		for _, disk := range opts.Disks {
+			checks = append(checks, diskCheck(disk))
+		}
+	} else {
+		checks = append(checks, availableDisksCheck(opts.LoopOSDs))
+	}
+	checks = append(checks, timeSyncCheck(), cpuCheck(opts.MinCPU), memoryCheck(opts.MinMemoryMB))
+	return checks
+}
+
+// SnapCheckName is the name of the check for a required snap, e.g. "snap lxd".
+func SnapCheckName(snap string) string {
+	return "snap " + snap
+}
+
+// MissingSnaps returns the required snaps whose check failed, in check order.
+//
+// Example Output:
+//   []string{"microceph", "microovn"}
+func (r *Report) MissingSnaps() []string {
+	var missing []string
+	for _, snap := range requiredSnapOrder {
+		for _, res := range r.Results {
+			if res.Check == SnapCheckName(snap) && res.Status == StatusFail {
+				missing = append(missing, snap)
+			}
+		}
+	}
+	return missing
+}
+
+func snapCheck(snap string) Check {
+	return Check{
+		Name: SnapCheckName(snap),
+		Run: func(ctx context.Context) (Status, string) {
+			commands := RequiredSnaps[snap]
+			for _, cmd := range commands {
+				if err := commander.CheckCommandExists(cmd); err != nil {
+					return StatusFail, fmt.Sprintf("%s is not installed (%s not found)", snap, cmd)
+				}
+			}
+			return StatusPass, strings.Join(commands, ", ") + " found"
+		},
+	}
+}
+
+func moduleCheck(module string) Check {
+	return Check{
+		Name: "kernel " + module,
+		Run: func(ctx context.Context) (Status, string) {
+			if _, err := os.Stat("/sys/module/" + module); err != nil {
+				return StatusWarn, fmt.Sprintf("module %s is not loaded", module)
+			}
+			return StatusPass, "loaded"
+		},
+	}
+}
+
+func portCheck(port int) Check {
+	return Check{
+		Name: fmt.Sprintf("port %d", port),
+		Run: func(ctx context.Context) (Status, string) {
+			if err := commander.CheckPortAvailable(port); err != nil {
+				return StatusFail, err.Error()
+			}
+			return StatusPass, "free"
+		},
+	}
+}
+
+func diskCheck(disk string) Check {
+	return Check{
+		Name: "disk " + disk,
+		Run: func(ctx context.Context) (Status, string) {
+			if err := commander.CheckDiskExists(disk); err != nil {
+				return StatusFail, err.Error()
+			}
+			return StatusPass, "present"
+		},
+	}
+}
+
+func availableDisksCheck(loopOSDs bool) Check {
+	return Check{
+		Name: "ceph disks",
+		Run: func(ctx context.Context) (Status, string) {
+			disks, err := microceph.AvailableDisks()
+			if err != nil {
+				return StatusWarn, fmt.Sprintf("cannot list disks: %v", err)
+			}
+			if len(disks) > 0 {
+				paths := make([]string, 0, len(disks))
+				for _, d := range disks {
+					paths = append(paths, d.Path)
+				}
+				return StatusPass, "unused: " + strings.Join(paths, ", ")
+			}
+			if loopOSDs {
+				return StatusWarn, "no unused disks; loop-file OSDs will be used (not for production)"
+			}
+			return StatusFail, "no unused disks; pass --ceph-disk or enable loop-file OSDs"
+		},
+	}
+}
+
+func timeSyncCheck() Check {
+	return Check{
+		Name: "time sync",
+		Run: func(ctx context.Context) (Status, string) {
+			output, err := commander.ExecCommandContext(ctx, "timedatectl", "show", "--property=NTPSynchronized", "--value")
+			if err != nil {
+				return StatusWarn, fmt.Sprintf("cannot read time sync status: %s", strings.TrimSpace(err.Error()))
+			}
+			if strings.TrimSpace(output) != "yes" {
+				return StatusWarn, "system clock is not synchronized; Ceph and certificate checks need accurate time"
+			}
+			return StatusPass, "system clock is synchronized"
+		},
+	}
+}
+
+func cpuCheck(minCPU int) Check {
+	if minCPU <= 0 {
+		minCPU = defaultMinCPU
+	}
+	return Check{
+		Name: "cpu",
+		Run: func(ctx context.Context) (Status, string) {
+			cpu := runtime.NumCPU()
+			msg := fmt.Sprintf("%d cores (minimum %d)", cpu, minCPU)
+			if cpu < minCPU {
+				return StatusFail, msg
+			}
+			return StatusPass, msg
+		},
+	}
+}
+
+func memoryCheck(minMemoryMB int) Check {
+	if minMemoryMB <= 0 {
+		minMemoryMB = defaultMinMemoryMB
+	}
+	return Check{
+		Name: "memory",
+		Run: func(ctx context.Context) (Status, string) {
+			memory := utils.GetTotalMemoryMB()
+			msg := fmt.Sprintf("%d MB (minimum %d MB)", memory, minMemoryMB)
+			if memory < minMemoryMB {
+				return StatusFail, msg
+			}
+			return StatusPass, msg
+		},
+	}
+}
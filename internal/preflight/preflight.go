@@ -0,0 +1,86 @@
+// Package preflight checks whether a host can run mcloud before init or join
+// changes anything: required snaps, kernel modules, free ports, disks, time
+// sync and minimum CPU/RAM. Every check reports pass, warn or fail; only
+// failures block init.
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+type Status string
+
+const (
+	StatusPass Status = "pass"
+	StatusWarn Status = "warn" // works, but not recommended (e.g. loop-file OSDs)
+	StatusFail Status = "fail" // init or join would fail
+)
+
+// Result is the outcome of one check.
+type Result struct {
+	Check   string `json:"check"` // e.g. "snap lxd", "port 8443"
+	Status  Status `json:"status"`
+	Message string `json:"message"`
+}
+
+// Report is the outcome of a preflight run, in check order.
+type Report struct {
+	Results []Result `json:"results"`
+}
+
+// Failed returns the failed checks.
+func (r *Report) Failed() []Result {
+	var failed []Result
+	for _, res := range r.Results {
+		if res.Status == StatusFail {
+			failed = append(failed, res)
+		}
+	}
+	return failed
+}
+
+// Err returns an error listing the failed checks, or nil if none failed.
+//
+// Example Output:
+//   preflight failed: snap microovn: microovn is not installed; port 8443: port 8443 is not available
+func (r *Report) Err() error {
+	failed := r.Failed()
+	if len(failed) == 0 {
+		return nil
+	}
+	msgs := make([]string, 0, len(failed))
+	for _, res := range failed {
+		msgs = append(msgs, res.Check+": "+res.Message)
+	}
+	return fmt.Errorf("preflight failed: %s", strings.Join(msgs, "; "))
+}
+
+// Check is one named preflight check.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) (Status, string)
+}
+
+// Run runs the checks in order and collects their results. A check that panics
+// is reported as failed rather than aborting the run.
+func Run(ctx context.Context, checks []Check) *Report {
+	report := &Report{Results: make([]Result, 0, len(checks))}
+	for _, c := range checks {
+		report.Results = append(report.Results, runCheck(ctx, c))
+	}
+	return report
+}
+
+func runCheck(ctx context.Context, c Check) (res Result) {
+	res.Check = c.Name
+	defer func() {
+		if p := recover(); p != nil {
+			res.Status = StatusFail
+			res.Message = fmt.Sprintf("check panicked: %v", p)
+		}
+	}()
+	res.Status, res.Message = c.Run(ctx)
+	return res
+}
@@ -0,0 +1,237 @@
+package project
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"mcloud/internal/auth"
+	"mcloud/internal/database"
+	"mcloud/pkg/reason"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(s *Service) *Handler {
+	return &Handler{service: s}
+}
+
+// authorize checks that the caller is an admin of the project in the path,
+// replying with an error if not.
+func (h *Handler) authorize(w http.ResponseWriter, r *http.Request) bool {
+	err := h.service.Authorize(r.Context(), r.PathValue("name"), auth.ClientIdentity(r), database.ProjectRoleAdmin)
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, ErrProjectDenied):
+		reason.HTTPError(w, err, 403)
+	default:
+		reason.HTTPError(w, err, 500)
+	}
+	return false
+}
+
+func (h *Handler) CreateProject(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req CreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		reason.HTTPError(w, err, 400)
+		return
+	}
+
+	actor := auth.ClientIdentity(r)
+	p, err := h.service.Create(r.Context(), &req, &actor)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidProject):
+			reason.HTTPError(w, err, 400)
+		case errors.Is(err, ErrProjectExists):
+			reason.HTTPError(w, err, 409)
+		default:
+			reason.HTTPError(w, err, 500)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(p)
+}
+
+func (h *Handler) ListProjects(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	projects, err := h.service.List(r.Context())
+	if err != nil {
+		reason.HTTPError(w, err, 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(projects)
+}
+
+func (h *Handler) GetProject(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	p, err := h.service.Get(r.Context(), r.PathValue("name"))
+	if err != nil {
+		if errors.Is(err, ErrProjectNotFound) {
+			reason.HTTPError(w, err, 404)
+			return
+		}
+		reason.HTTPError(w, err, 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p)
+}
+
+// SetQuota replaces a project's limits with the Quota in the body; omitted
+// or null limits become unlimited.
+func (h *Handler) SetQuota(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var q Quota
+	if err := json.NewDecoder(r.Body).Decode(&q); err != nil {
+		reason.HTTPError(w, err, 400)
+		return
+	}
+	if !h.authorize(w, r) {
+		return
+	}
+
+	actor := auth.ClientIdentity(r)
+	p, err := h.service.SetQuota(r.Context(), r.PathValue("name"), &q, &actor)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidProject):
+			reason.HTTPError(w, err, 400)
+		case errors.Is(err, ErrProjectNotFound):
+			reason.HTTPError(w, err, 404)
+		default:
+			reason.HTTPError(w, err, 500)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p)
+}
+
+func (h *Handler) DeleteProject(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !h.authorize(w, r) {
+		return
+	}
+	if err := h.service.Delete(r.Context(), r.PathValue("name")); err != nil {
+		switch {
+		case errors.Is(err, ErrProjectNotFound):
+			reason.HTTPError(w, err, 404)
+		case errors.Is(err, ErrProjectInUse):
+			reason.HTTPError(w, err, 409)
+		default:
+			reason.HTTPError(w, err, 500)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListBindings handles GET /projects/{name}/bindings.
+func (h *Handler) ListBindings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	bindings, err := h.service.ListBindings(r.Context(), r.PathValue("name"))
+	if err != nil {
+		if errors.Is(err, ErrProjectNotFound) {
+			reason.HTTPError(w, err, 404)
+			return
+		}
+		reason.HTTPError(w, err, 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bindings)
+}
+
+// PutBinding gives the identity in the path the role in the body (a
+// BindingRequest). Only admins of the project may change its bindings.
+func (h *Handler) PutBinding(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req BindingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		reason.HTTPError(w, err, 400)
+		return
+	}
+	if !h.authorize(w, r) {
+		return
+	}
+
+	actor := auth.ClientIdentity(r)
+	b, err := h.service.Bind(r.Context(), r.PathValue("name"), r.PathValue("identity"), &req, &actor)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidProject):
+			reason.HTTPError(w, err, 400)
+		case errors.Is(err, ErrProjectNotFound):
+			reason.HTTPError(w, err, 404)
+		default:
+			reason.HTTPError(w, err, 500)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(b)
+}
+
+func (h *Handler) DeleteBinding(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !h.authorize(w, r) {
+		return
+	}
+	if err := h.service.Unbind(r.Context(), r.PathValue("name"), r.PathValue("identity")); err != nil {
+		if errors.Is(err, ErrProjectNotFound) {
+			reason.HTTPError(w, err, 404)
+			return
+		}
+		reason.HTTPError(w, err, 500)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,19 @@
+package project
+
+import (
+	"net/http"
+)
+
+// InitModule registers the project routes served by handler.
+func InitModule(mux *http.ServeMux, handler *Handler) {
+	mux.HandleFunc("GET /projects", handler.ListProjects)
+	mux.HandleFunc("POST /projects", handler.CreateProject)
+	mux.HandleFunc("GET /projects/{name}", handler.GetProject)
+	mux.HandleFunc("PUT /projects/{name}/quota", handler.SetQuota)
+	mux.HandleFunc("DELETE /projects/{name}", handler.DeleteProject)
+
+	// Role bindings of client identities
+	mux.HandleFunc("GET /projects/{name}/bindings", handler.ListBindings)
+	mux.HandleFunc("PUT /projects/{name}/bindings/{identity}", handler.PutBinding)
+	mux.HandleFunc("DELETE /projects/{name}/bindings/{identity}", handler.DeleteBinding)
+}
@@ -0,0 +1,439 @@
+// Package project manages projects: tenants that own workloads, each with
+// optional quotas on CPUs, memory, instance count and root disk. A workload
+// launch is refused when its flavor would take its project over a quota.
+//
+// Every project but "default" is backed by an LXD project of the same name,
+// which keeps its instances, images and profiles apart from other tenants
+// (see lxd.CreateProject). Role bindings decide which client identities may
+// manage a project; a project without bindings is open to every identity.
+package project
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"mcloud/internal/config"
+	"mcloud/internal/database"
+	"mcloud/internal/network"
+	"mcloud/pkg/logger"
+	"mcloud/pkg/reason"
+	"mcloud/services/lxd"
+)
+
+var log = logger.Named("project")
+
+var (
+	ErrProjectNotFound = reason.New(reason.ProjectNotFound, "project not found")
+	ErrProjectExists   = reason.New(reason.ProjectExists, "a project with this name already exists")
+	ErrProjectInUse    = reason.New(reason.ProjectInUse, "project is in use")
+	ErrInvalidProject  = reason.New(reason.InvalidProject, "invalid project")
+	ErrQuotaExceeded   = reason.New(reason.QuotaExceeded, "project quota exceeded")
+	ErrProjectDenied   = reason.New(reason.ProjectDenied, "not allowed in this project")
+)
+
+// Header selects the project of a request when its body has no project field,
+// e.g. for POST /workloads, or filters GET /workloads.
+const Header = "X-MCloud-Project"
+
+// FromRequest returns the project named by field, or else by the Header of r.
+//
+// Example:
+//   FromRequest(r, req.Project)  // "team-a" for {"project": "team-a"} or X-MCloud-Project: team-a
+func FromRequest(r *http.Request, field string) string {
+	if field != "" {
+		return field
+	}
+	return r.Header.Get(Header)
+}
+
+// roleRank orders the roles: an admin may do everything a member may.
+var roleRank = map[string]int{database.ProjectRoleMember: 1, database.ProjectRoleAdmin: 2}
+
+var nameRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]*$`)
+
+type Service struct {
+	db  *sql.DB
+	cfg *config.Config
+}
+
+// Quota holds the limits of a project; a nil limit is unlimited.
+//
+// Example JSON:
+//   {"cpus": 16, "memory_mb": 32768, "instances": 10, "disk_gb": 500}
+type Quota struct {
+	CPUs      *int `json:"cpus"`
+	MemoryMB  *int `json:"memory_mb"`
+	Instances *int `json:"instances"`
+	DiskGB    *int `json:"disk_gb"`
+}
+
+// CreateRequest defines a new project. The identity creating it becomes its
+// first admin.
+//
+// Example JSON:
+//   {"name": "team-a", "description": "Team A", "quota": {"cpus": 16, "instances": 10}}
+type CreateRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Quota       Quota  `json:"quota"`
+}
+
+// ProjectDetail is a project together with what its workloads take up.
+type ProjectDetail struct {
+	database.Project
+	Usage database.ProjectUsage `json:"usage"`
+}
+
+// QuotaError is returned (wrapping ErrQuotaExceeded) when a launch would take
+// a project over one of its limits. Its fields are sent to API clients as the
+// error's details.
+//
+// Example Output:
+//   project quota exceeded: project team-a: cpus 6 used + 4 requested exceeds the quota of 8
+type QuotaError struct {
+	Project   string
+	Resource  string // cpus, memory_mb, instances or disk_gb
+	Limit     int
+	Used      int
+	Requested int
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("%v: project %s: %s %d used + %d requested exceeds the quota of %d",
+		ErrQuotaExceeded, e.Project, e.Resource, e.Used, e.Requested, e.Limit)
+}
+
+func (e *QuotaError) Unwrap() error {
+	return ErrQuotaExceeded
+}
+
+// ReasonDetails implements reason.Detailer.
+func (e *QuotaError) ReasonDetails() map[string]any {
+	return map[string]any{
+		"project":   e.Project,
+		"resource":  e.Resource,
+		"limit":     e.Limit,
+		"used":      e.Used,
+		"requested": e.Requested,
+	}
+}
+
+// BindingRequest gives an identity a role in a project.
+//
+// Example JSON:
+//   {"role": "member"}
+type BindingRequest struct {
+	Role string `json:"role"` // admin or member
+}
+
+func NewService(db *sql.DB, cfg *config.Config) *Service {
+	return &Service{db: db, cfg: cfg}
+}
+
+func validateQuota(q *Quota) error {
+	for _, v := range []*int{q.CPUs, q.MemoryMB, q.Instances, q.DiskGB} {
+		if v != nil && *v < 0 {
+			return fmt.Errorf("%w: quota limits must not be negative", ErrInvalidProject)
+		}
+	}
+	return nil
+}
+
+// Create creates the LXD project and records the project, with actor as its
+// admin.
+//
+// Example Output (Error):
+//   {"name": "default"}                   =>  ErrProjectExists: default
+//   {"name": "a", "quota": {"cpus": -1}}  =>  ErrInvalidProject: quota limits must not be negative
+func (s *Service) Create(ctx context.Context, req *CreateRequest, actor *string) (*ProjectDetail, error) {
+	if !nameRegexp.MatchString(req.Name) {
+		return nil, fmt.Errorf("%w: name must match [a-z0-9][a-z0-9.-]*", ErrInvalidProject)
+	}
+	if err := validateQuota(&req.Quota); err != nil {
+		return nil, err
+	}
+
+	repo := database.NewProjectRepository(s.db)
+	if _, err := repo.Get(ctx, req.Name); err == nil {
+		return nil, fmt.Errorf("%w: %s", ErrProjectExists, req.Name)
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	pool, err := lxd.DefaultStoragePool()
+	if err != nil {
+		return nil, err
+	}
+	err = lxd.CreateProject(ctx, lxd.ProjectConfig{
+		Name:        req.Name,
+		Description: req.Description,
+		Network:     network.OVNNetwork(s.cfg.Network),
+		Pool:        pool,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	p := &database.Project{
+		Name:         req.Name,
+		Description:  req.Description,
+		MaxCPUs:      req.Quota.CPUs,
+		MaxMemoryMB:  req.Quota.MemoryMB,
+		MaxInstances: req.Quota.Instances,
+		MaxDiskGB:    req.Quota.DiskGB,
+		CreateUserID: actor,
+	}
+	err = database.WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		repo := database.NewProjectRepositoryTx(tx)
+		if err := repo.Create(ctx, p); err != nil {
+			return err
+		}
+		if actor == nil || *actor == "" {
+			return nil
+		}
+		return repo.PutBinding(ctx, &database.ProjectBinding{Project: p.Name, Identity: *actor, Role: database.ProjectRoleAdmin, CreateUserID: actor})
+	})
+	if err != nil {
+		if delErr := lxd.DeleteProject(context.WithoutCancel(ctx), p.Name); delErr != nil {
+			log.Warn("Failed to remove LXD project %s after a failed create: %v", p.Name, delErr)
+		}
+		return nil, err
+	}
+	return s.Get(ctx, p.Name)
+}
+
+// List returns all projects with their usage, by name.
+func (s *Service) List(ctx context.Context) ([]ProjectDetail, error) {
+	repo := database.NewProjectRepository(s.db)
+	projects, err := repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	details := make([]ProjectDetail, 0, len(projects))
+	for _, p := range projects {
+		usage, err := repo.Usage(ctx, p.Name)
+		if err != nil {
+			return nil, err
+		}
+		details = append(details, ProjectDetail{Project: p, Usage: *usage})
+	}
+	return details, nil
+}
+
+// Get returns a project with its usage.
+func (s *Service) Get(ctx context.Context, name string) (*ProjectDetail, error) {
+	repo := database.NewProjectRepository(s.db)
+	p, err := repo.Get(ctx, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrProjectNotFound, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	usage, err := repo.Usage(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return &ProjectDetail{Project: *p, Usage: *usage}, nil
+}
+
+// SetQuota replaces the limits of a project. Lowering a limit below the
+// current usage is allowed; it only blocks further launches.
+func (s *Service) SetQuota(ctx context.Context, name string, q *Quota, actor *string) (*ProjectDetail, error) {
+	if err := validateQuota(q); err != nil {
+		return nil, err
+	}
+	current, err := s.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	p := current.Project
+	p.MaxCPUs, p.MaxMemoryMB, p.MaxInstances, p.MaxDiskGB = q.CPUs, q.MemoryMB, q.Instances, q.DiskGB
+	p.UpdateUserID = actor
+	if err := database.NewProjectRepository(s.db).UpdateQuota(ctx, &p); err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, name)
+}
+
+// Delete removes a project that owns no workloads, its LXD project and its
+// bindings. The default project cannot be deleted.
+func (s *Service) Delete(ctx context.Context, name string) error {
+	if name == database.DefaultProject {
+		return fmt.Errorf("%w: the default project cannot be deleted", ErrProjectInUse)
+	}
+	if _, err := s.Get(ctx, name); err != nil {
+		return err
+	}
+	inUse, err := database.NewWorkloadRepository(s.db).CountByProject(ctx, name)
+	if err != nil {
+		return err
+	}
+	if inUse > 0 {
+		return fmt.Errorf("%w: %s has %d workloads", ErrProjectInUse, name, inUse)
+	}
+
+	if err := lxd.DeleteProject(ctx, name); err != nil {
+		return err
+	}
+	return database.WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		repo := database.NewProjectRepositoryTx(tx)
+		if err := repo.DeleteBindings(ctx, name); err != nil {
+			return err
+		}
+		return repo.Delete(ctx, name)
+	})
+}
+
+// Authorize returns ErrProjectDenied unless identity has at least role in the
+// project. A project without bindings allows every identity, so clusters keep
+// working as before until someone binds a role.
+//
+// Example Output (Error):
+//   Authorize(ctx, "team-a", "ci-runner", "admin")  =>  ErrProjectDenied: ci-runner is member of team-a, admin required
+func (s *Service) Authorize(ctx context.Context, name string, identity string, role string) error {
+	repo := database.NewProjectRepository(s.db)
+	bindings, err := repo.ListBindings(ctx, name)
+	if err != nil || len(bindings) == 0 {
+		return err
+	}
+	for _, b := range bindings {
+		if b.Identity != identity {
+			continue
+		}
+		if roleRank[b.Role] >= roleRank[role] {
+			return nil
+		}
+		return fmt.Errorf("%w: %s is %s of %s, %s required", ErrProjectDenied, identity, b.Role, name, role)
+	}
+	if identity == "" {
+		return fmt.Errorf("%w: a client certificate with a role in %s is required", ErrProjectDenied, name)
+	}
+	return fmt.Errorf("%w: %s has no role in %s", ErrProjectDenied, identity, name)
+}
+
+// Readable returns the names of the projects in which identity has at least
+// the member role, which includes every project without bindings.
+func (s *Service) Readable(ctx context.Context, identity string) ([]string, error) {
+	projects, err := database.NewProjectRepository(s.db).List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, p := range projects {
+		err := s.Authorize(ctx, p.Name, identity, database.ProjectRoleMember)
+		if errors.Is(err, ErrProjectDenied) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, p.Name)
+	}
+	return names, nil
+}
+
+// ListBindings returns the role bindings of a project by identity.
+func (s *Service) ListBindings(ctx context.Context, name string) ([]database.ProjectBinding, error) {
+	if _, err := s.Get(ctx, name); err != nil {
+		return nil, err
+	}
+	bindings, err := database.NewProjectRepository(s.db).ListBindings(ctx, name)
+	if bindings == nil {
+		bindings = []database.ProjectBinding{}
+	}
+	return bindings, err
+}
+
+// Bind gives identity a role in a project, replacing its previous role.
+func (s *Service) Bind(ctx context.Context, name string, identity string, req *BindingRequest, actor *string) (*database.ProjectBinding, error) {
+	if _, ok := roleRank[req.Role]; !ok {
+		return nil, fmt.Errorf("%w: role must be admin or member", ErrInvalidProject)
+	}
+	if identity == "" {
+		return nil, fmt.Errorf("%w: identity is required", ErrInvalidProject)
+	}
+	if _, err := s.Get(ctx, name); err != nil {
+		return nil, err
+	}
+
+	repo := database.NewProjectRepository(s.db)
+	b := &database.ProjectBinding{Project: name, Identity: identity, Role: req.Role, CreateUserID: actor}
+	if err := repo.PutBinding(ctx, b); err != nil {
+		return nil, err
+	}
+	return repo.GetBinding(ctx, name, identity)
+}
+
+// Unbind removes the role of identity in a project. Removing a binding that
+// does not exist changes nothing.
+func (s *Service) Unbind(ctx context.Context, name string, identity string) error {
+	if _, err := s.Get(ctx, name); err != nil {
+		return err
+	}
+	return database.NewProjectRepository(s.db).DeleteBinding(ctx, name, identity)
+}
+
+// CheckQuota returns a *QuotaError if adding demand to the project's current
+// usage would exceed one of its limits, checked in the order cpus, memory_mb,
+// instances, disk_gb. Instances without CPUs and memory (launched without a
+// flavor) do not count against cpus and memory_mb, so they are refused in a
+// project limiting either.
+//
+// The check reads the usage outside of any transaction; a launch records its
+// workload with CheckQuotaTx in the transaction that inserts it.
+//
+// Example Output (Error):
+//   CheckQuota(ctx, "team-a", {CPUs: 4, MemoryMB: 8192, Instances: 1, DiskGB: 40})
+//     =>  &QuotaError{Project: "team-a", Resource: "cpus", Limit: 8, Used: 6, Requested: 4}
+//   CheckQuota(ctx, "team-a", {Instances: 1})
+//     =>  ErrQuotaExceeded: project team-a limits cpus and memory_mb; launch with a flavor
+func (s *Service) CheckQuota(ctx context.Context, name string, demand database.ProjectUsage) error {
+	return checkQuota(ctx, database.NewProjectRepository(s.db), name, demand)
+}
+
+// CheckQuotaTx is CheckQuota reading the usage in tx, so that a launch
+// inserting its workload in the same write transaction cannot race another.
+func (s *Service) CheckQuotaTx(ctx context.Context, tx *sql.Tx, name string, demand database.ProjectUsage) error {
+	return checkQuota(ctx, database.NewProjectRepositoryTx(tx), name, demand)
+}
+
+func checkQuota(ctx context.Context, repo *database.ProjectRepository, name string, demand database.ProjectUsage) error {
+	p, err := repo.Get(ctx, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %s", ErrProjectNotFound, name)
+	}
+	if err != nil {
+		return err
+	}
+	unsized := demand.Instances > 0 && demand.CPUs == 0 && demand.MemoryMB == 0
+	if unsized && (p.MaxCPUs != nil || p.MaxMemoryMB != nil) {
+		return fmt.Errorf("%w: project %s limits cpus and memory_mb; launch with a flavor", ErrQuotaExceeded, name)
+	}
+	usage, err := repo.Usage(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	checks := []struct {
+		resource        string
+		limit           *int
+		used, requested int
+	}{
+		{"cpus", p.MaxCPUs, usage.CPUs, demand.CPUs},
+		{"memory_mb", p.MaxMemoryMB, usage.MemoryMB, demand.MemoryMB},
+		{"instances", p.MaxInstances, usage.Instances, demand.Instances},
+		{"disk_gb", p.MaxDiskGB, usage.DiskGB, demand.DiskGB},
+	}
+	for _, c := range checks {
+		if c.limit != nil && c.used+c.requested > *c.limit {
+			return &QuotaError{Project: name, Resource: c.resource, Limit: *c.limit, Used: c.used, Requested: c.requested}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package project
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"mcloud/internal/config"
+	"mcloud/internal/database"
+)
+
+func TestQuota(t *testing.T) {
+	db, err := database.Connect(filepath.Join(t.TempDir(), "mcloud.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close(db)
+	s := NewService(db, &config.Config{})
+	ctx := context.Background()
+
+	// Create also creates the LXD project; record the row directly
+	two, eight := 2, 8
+	if err := database.NewProjectRepository(db).Create(ctx, &database.Project{Name: "team-a", MaxCPUs: &eight, MaxInstances: &two}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Create(ctx, &CreateRequest{Name: "default"}, nil); !errors.Is(err, ErrProjectExists) {
+		t.Fatalf("duplicate: got %v, want ErrProjectExists", err)
+	}
+
+	// One medium (2 CPUs) workload in team-a
+	if _, err := db.Exec(`INSERT INTO clusters (id, name, state) VALUES ('c1', 'test', 'active')`); err != nil {
+		t.Fatal(err)
+	}
+	medium := "medium"
+	w := &database.Workload{ID: "w1", ClusterID: "c1", Name: "web-1", Kind: "container", Status: "running",
+		Image: "ubuntu:24.04", Priority: "normal", Flavor: &medium, Project: "team-a"}
+	if err := database.NewWorkloadRepository(db).Create(ctx, w); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := s.Get(ctx, "team-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Usage != (database.ProjectUsage{CPUs: 2, MemoryMB: 4096, Instances: 1, DiskGB: 20}) {
+		t.Fatalf("usage: got %+v", p.Usage)
+	}
+
+	if err := s.CheckQuota(ctx, "team-a", database.ProjectUsage{CPUs: 4, Instances: 1}); err != nil {
+		t.Fatalf("within quota: got %v", err)
+	}
+	err = s.CheckQuota(ctx, "team-a", database.ProjectUsage{CPUs: 8, Instances: 1})
+	var qe *QuotaError
+	if !errors.As(err, &qe) || !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("over quota: got %v, want *QuotaError", err)
+	}
+	if qe.Resource != "cpus" || qe.Limit != 8 || qe.Used != 2 || qe.Requested != 8 {
+		t.Fatalf("over quota: got %+v", qe)
+	}
+
+	// A launch without a flavor would not count against the CPU limit
+	if err := s.CheckQuota(ctx, "team-a", database.ProjectUsage{Instances: 1}); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("without flavor: got %v, want ErrQuotaExceeded", err)
+	}
+
+	// The usage read in a transaction includes the workloads it inserted
+	err = database.WithTx(ctx, db, func(tx *sql.Tx) error {
+		w2 := &database.Workload{ID: "w2", ClusterID: "c1", Name: "web-2", Kind: "container", Status: "pending",
+			Image: "ubuntu:24.04", Priority: "normal", Flavor: &medium, Project: "team-a"}
+		if err := database.NewWorkloadRepositoryTx(tx).Create(ctx, w2); err != nil {
+			return err
+		}
+		return s.CheckQuotaTx(ctx, tx, "team-a", database.ProjectUsage{CPUs: 2, Instances: 1})
+	})
+	if !errors.As(err, &qe) || qe.Resource != "instances" || qe.Used != 2 {
+		t.Fatalf("in transaction: got %v, want instances quota with 2 used", err)
+	}
+
+	// Lifting the CPU limit leaves the instance limit
+	if _, err := s.SetQuota(ctx, "team-a", &Quota{Instances: &two}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CheckQuota(ctx, "team-a", database.ProjectUsage{CPUs: 8, Instances: 2}); !errors.As(err, &qe) || qe.Resource != "instances" {
+		t.Fatalf("instances: got %v", err)
+	}
+
+	if err := s.Delete(ctx, "team-a"); !errors.Is(err, ErrProjectInUse) {
+		t.Fatalf("delete in use: got %v, want ErrProjectInUse", err)
+	}
+	if err := s.Delete(ctx, "default"); !errors.Is(err, ErrProjectInUse) {
+		t.Fatalf("delete default: got %v, want ErrProjectInUse", err)
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	db, err := database.Connect(filepath.Join(t.TempDir(), "mcloud.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close(db)
+	s := NewService(db, &config.Config{})
+	ctx := context.Background()
+
+	if err := database.NewProjectRepository(db).Create(ctx, &database.Project{Name: "team-a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without bindings the project is open
+	if err := s.Authorize(ctx, "team-a", "anyone", database.ProjectRoleAdmin); err != nil {
+		t.Fatalf("unbound project: got %v", err)
+	}
+
+	if _, err := s.Bind(ctx, "team-a", "alice", &BindingRequest{Role: "admin"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Bind(ctx, "team-a", "ci-runner", &BindingRequest{Role: "member"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Bind(ctx, "team-a", "bob", &BindingRequest{Role: "owner"}, nil); !errors.Is(err, ErrInvalidProject) {
+		t.Fatalf("bad role: got %v, want ErrInvalidProject", err)
+	}
+
+	tests := []struct {
+		identity, role string
+		allowed        bool
+	}{
+		{"alice", database.ProjectRoleAdmin, true},
+		{"alice", database.ProjectRoleMember, true},
+		{"ci-runner", database.ProjectRoleMember, true},
+		{"ci-runner", database.ProjectRoleAdmin, false},
+		{"mallory", database.ProjectRoleMember, false},
+		{"", database.ProjectRoleMember, false},
+	}
+	for _, tt := range tests {
+		err := s.Authorize(ctx, "team-a", tt.identity, tt.role)
+		if tt.allowed && err != nil || !tt.allowed && !errors.Is(err, ErrProjectDenied) {
+			t.Errorf("Authorize(%q, %s) = %v, allowed %v", tt.identity, tt.role, err, tt.allowed)
+		}
+	}
+
+	// Other projects are unaffected
+	if err := s.Authorize(ctx, database.DefaultProject, "mallory", database.ProjectRoleAdmin); err != nil {
+		t.Fatalf("default project: got %v", err)
+	}
+	if got, err := s.Readable(ctx, "mallory"); err != nil || !slices.Equal(got, []string{database.DefaultProject}) {
+		t.Errorf("Readable(mallory) = %v, %v", got, err)
+	}
+	if got, err := s.Readable(ctx, "ci-runner"); err != nil || !slices.Equal(got, []string{database.DefaultProject, "team-a"}) {
+		t.Errorf("Readable(ci-runner) = %v, %v", got, err)
+	}
+
+	if err := s.Unbind(ctx, "team-a", "ci-runner"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Authorize(ctx, "team-a", "ci-runner", database.ProjectRoleMember); !errors.Is(err, ErrProjectDenied) {
+		t.Fatalf("after unbind: got %v, want ErrProjectDenied", err)
+	}
+}
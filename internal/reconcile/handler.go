@@ -0,0 +1,41 @@
+package reconcile
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(s *Service) *Handler {
+	return &Handler{service: s}
+}
+
+// GetDrift returns the outcome of the last reconciler run.
+func (h *Handler) GetDrift(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.service.Status())
+}
+
+// CheckDrift runs the reconciler now and returns its outcome. Checks that
+// failed are listed in the status rather than failing the request.
+func (h *Handler) CheckDrift(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := h.service.Run(r.Context()); err != nil {
+		log.Warn("Drift check: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.service.Status())
+}
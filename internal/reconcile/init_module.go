@@ -0,0 +1,11 @@
+package reconcile
+
+import (
+	"net/http"
+)
+
+// InitModule registers the drift routes served by handler.
+func InitModule(mux *http.ServeMux, handler *Handler) {
+	mux.HandleFunc("GET /drift", handler.GetDrift)
+	mux.HandleFunc("POST /drift/check", handler.CheckDrift)
+}
@@ -0,0 +1,523 @@
+// Package reconcile detects drift between what the database records and what
+// LXD, OVN and Ceph actually have: instances that are gone or in the wrong
+// power state, volumes that are missing or no longer attached, floating IP
+// forwards that are missing or point elsewhere. New drift is recorded as a
+// drift.* event; depending on the per-resource policy (reconcile.workloads,
+// reconcile.volumes, reconcile.network) it is also healed. Healing only ever
+// recreates or restarts what the database records - resources mcloud did not
+// create (unmanaged instances, orphan volumes and forwards) are reported,
+// never deleted.
+package reconcile
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"mcloud/internal/config"
+	"mcloud/internal/database"
+	"mcloud/internal/event"
+	"mcloud/internal/network"
+	"mcloud/pkg/logger"
+	"mcloud/services/lxd"
+)
+
+var log = logger.Named("reconcile")
+
+const (
+	PolicyReport = "report" // record drift as events only
+	PolicyHeal   = "heal"   // also repair it
+
+	DefaultInterval = 5 * time.Minute
+)
+
+// Resource kinds, each with its own policy.
+const (
+	KindWorkload = "workload"
+	KindVolume   = "volume"
+	KindNetwork  = "network"
+)
+
+// Interval returns how often the reconciler runs (reconcile.interval_seconds).
+func Interval(cfg *config.Config) time.Duration {
+	if cfg.Reconcile.IntervalSeconds <= 0 {
+		return DefaultInterval
+	}
+	return time.Duration(cfg.Reconcile.IntervalSeconds) * time.Second
+}
+
+// Drift is one difference between the database and the actual state.
+//
+// Example JSON:
+//   {"kind": "workload", "type": "drift.instance_state", "resource": "web-1",
+//    "message": "instance web-1 is Stopped, recorded as running", "healable": true,
+//    "policy": "report", "detected_at": "2026-10-16T09:00:00Z"}
+type Drift struct {
+	Kind       string    `json:"kind"`
+	Type       string    `json:"type"` // also the type of the event recorded for it
+	Resource   string    `json:"resource"`
+	Message    string    `json:"message"`
+	Healable   bool      `json:"healable"`
+	Policy     string    `json:"policy"`
+	DetectedAt time.Time `json:"detected_at"` // first run that saw it
+
+	clusterID *string
+	heal      func(ctx context.Context) error
+}
+
+func (d *Drift) key() string {
+	return d.Type + " " + d.Resource
+}
+
+// Status is the outcome of the last run: the drift still present after
+// healing, and the checks that could not be made.
+type Status struct {
+	CheckedAt *time.Time `json:"checked_at"`
+	Drift     []Drift    `json:"drift"`
+	Errors    []string   `json:"errors,omitempty"`
+}
+
+type Service struct {
+	db       *sql.DB
+	cfg      config.Network
+	policies map[string]string
+
+	run   sync.Mutex           // serializes runs
+	known map[string]time.Time // drift key -> first seen, guarded by run
+
+	mu     sync.Mutex
+	status Status
+}
+
+func NewService(db *sql.DB, cfg *config.Config) *Service {
+	policies := map[string]string{
+		KindWorkload: cfg.Reconcile.Workloads,
+		KindVolume:   cfg.Reconcile.Volumes,
+		KindNetwork:  cfg.Reconcile.Network,
+	}
+	for kind, p := range policies {
+		switch p {
+		case PolicyReport, PolicyHeal:
+		case "":
+			policies[kind] = PolicyReport
+		default:
+			log.Warn("Unknown reconcile policy %q for %ss, only reporting drift", p, kind)
+			policies[kind] = PolicyReport
+		}
+	}
+	return &Service{
+		db:       db,
+		cfg:      cfg.Network,
+		policies: policies,
+		known:    map[string]time.Time{},
+		status:   Status{Drift: []Drift{}},
+	}
+}
+
+// Status returns the outcome of the last run.
+func (s *Service) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+// Run is the periodic job: it compares the database with LXD, records drift
+// not seen by the previous run as events and heals what the policy allows.
+// Checks that fail (e.g. LXD unreachable) are skipped and returned.
+func (s *Service) Run(ctx context.Context) error {
+	s.run.Lock()
+	defer s.run.Unlock()
+
+	drift, errs := s.detect(ctx)
+	now := time.Now().UTC()
+	seen := make(map[string]time.Time, len(drift))
+	remaining := []Drift{}
+	for _, d := range drift {
+		key := d.key()
+		first, ok := s.known[key]
+		if !ok {
+			first = now
+			log.Warn("Drift detected: %s", d.Message)
+			s.event(ctx, d.clusterID, d.Type, d.Message)
+		}
+		d.DetectedAt = first
+		d.Policy = s.policies[d.Kind]
+
+		if d.Policy == PolicyHeal && d.heal != nil {
+			if err := d.heal(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("healing %s: %w", key, err))
+			} else {
+				log.Info("Drift healed: %s", d.Message)
+				s.event(ctx, d.clusterID, event.DriftHealed, "Healed: "+d.Message)
+				continue
+			}
+		}
+		seen[key] = first
+		remaining = append(remaining, d)
+	}
+	for key := range s.known {
+		if _, ok := seen[key]; !ok {
+			log.Info("Drift resolved: %s", key)
+		}
+	}
+	s.known = seen
+
+	status := Status{CheckedAt: &now, Drift: remaining}
+	for _, err := range errs {
+		status.Errors = append(status.Errors, err.Error())
+	}
+	s.mu.Lock()
+	s.status = status
+	s.mu.Unlock()
+	return errors.Join(errs...)
+}
+
+// snapshot is the recorded and the actual state a run compares.
+type snapshot struct {
+	workloads []database.Workload
+	instances map[string]lxd.InstanceState            // nil when LXD could not be listed
+	devices   map[string]map[string]map[string]string // instance -> device -> config, for instances with attached volumes
+
+	volumes     []database.Volume
+	pools       map[string]string   // storage pool -> driver; nil when the pools could not be listed
+	poolVolumes map[string][]string // ceph pool -> custom volumes
+
+	floatingIPs []database.FloatingIP
+	forwards    map[string]map[string]string // network -> listen address -> target address
+}
+
+// detect takes a snapshot and compares it. Sources that cannot be read leave
+// their part of the snapshot nil, which skips the checks that need it.
+func (s *Service) detect(ctx context.Context) ([]Drift, []error) {
+	var sn snapshot
+	var errs []error
+	var err error
+
+	if sn.workloads, err = database.NewWorkloadRepository(s.db).List(ctx, database.ListOptions{}); err != nil {
+		return nil, []error{err}
+	}
+	if sn.volumes, err = database.NewVolumeRepository(s.db).List(ctx); err != nil {
+		return nil, []error{err}
+	}
+	if sn.floatingIPs, err = database.NewFloatingIPRepository(s.db).List(ctx); err != nil {
+		return nil, []error{err}
+	}
+
+	if sn.instances, err = lxd.InstanceStates(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if sn.pools, err = lxd.StoragePools(ctx); err != nil {
+		errs = append(errs, err)
+	} else {
+		sn.poolVolumes = map[string][]string{}
+		for pool, driver := range sn.pools {
+			if driver != "ceph" {
+				continue
+			}
+			names, err := lxd.ListVolumes(ctx, pool)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			sn.poolVolumes[pool] = names
+		}
+	}
+
+	if sn.instances != nil {
+		names := map[string]string{}
+		for _, w := range sn.workloads {
+			names[w.ID] = w.Name
+		}
+		sn.devices = map[string]map[string]map[string]string{}
+		for _, v := range sn.volumes {
+			name, ok := names[deref(v.WorkloadID)]
+			if !ok || sn.devices[name] != nil {
+				continue
+			}
+			st, exists := sn.instances[name]
+			if !exists {
+				continue
+			}
+			devices, err := lxd.InstanceDevices(lxd.WithProject(ctx, st.Project), name)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			sn.devices[name] = devices
+		}
+	}
+
+	// Without an external pool and floating IPs there are no forwards to check
+	if len(s.cfg.ExternalPool) > 0 || len(sn.floatingIPs) > 0 {
+		sn.forwards = map[string]map[string]string{}
+		networks := map[string]bool{network.OVNNetwork(s.cfg): true}
+		for _, f := range sn.floatingIPs {
+			networks[f.Network] = true
+		}
+		for n := range networks {
+			forwards, err := lxd.ListForwards(ctx, n)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			sn.forwards[n] = forwards
+		}
+	}
+
+	drift := append(sn.workloadDrift(), sn.volumeDrift()...)
+	drift = append(drift, sn.networkDrift()...)
+	sort.SliceStable(drift, func(i, j int) bool {
+		if drift[i].Kind != drift[j].Kind {
+			return drift[i].Kind < drift[j].Kind
+		}
+		return drift[i].key() < drift[j].key()
+	})
+	return drift, errs
+}
+
+// workloadDrift finds instances that are missing, in another power state than
+// recorded, or not recorded at all. Pending and failed workloads have no
+// settled state and are left alone.
+func (sn *snapshot) workloadDrift() []Drift {
+	if sn.instances == nil {
+		return nil
+	}
+	var drift []Drift
+	recorded := make(map[string]bool, len(sn.workloads))
+	for _, w := range sn.workloads {
+		recorded[w.Name] = true
+		clusterID := w.ClusterID
+		var want string
+		switch w.Status {
+		case "running":
+			want = "Running"
+		case "stopped":
+			want = "Stopped"
+		default:
+			continue
+		}
+
+		st, ok := sn.instances[w.Name]
+		switch {
+		case !ok:
+			drift = append(drift, Drift{
+				Kind:      KindWorkload,
+				Type:      "drift.instance_missing",
+				Resource:  w.Name,
+				Message:   fmt.Sprintf("instance %s of workload %s does not exist", w.Name, w.ID),
+				clusterID: &clusterID,
+			})
+		case st.Status != want && (st.Status == "Running" || st.Status == "Stopped"):
+			name, project := w.Name, w.Project
+			heal := func(ctx context.Context) error { return lxd.StartInstance(lxd.WithProject(ctx, project), name) }
+			if want == "Stopped" {
+				heal = func(ctx context.Context) error { return lxd.StopInstance(lxd.WithProject(ctx, project), name) }
+			}
+			drift = append(drift, Drift{
+				Kind:      KindWorkload,
+				Type:      "drift.instance_state",
+				Resource:  w.Name,
+				Message:   fmt.Sprintf("instance %s is %s, recorded as %s", w.Name, st.Status, w.Status),
+				Healable:  true,
+				clusterID: &clusterID,
+				heal:      heal,
+			})
+		}
+	}
+
+	for name := range sn.instances {
+		if !recorded[name] {
+			drift = append(drift, Drift{
+				Kind:     KindWorkload,
+				Type:     "drift.instance_unmanaged",
+				Resource: name,
+				Message:  fmt.Sprintf("instance %s is not a recorded workload", name),
+			})
+		}
+	}
+	return drift
+}
+
+// volumeDrift finds volumes missing from their Ceph pool, attachments LXD no
+// longer has, and custom volumes on Ceph pools that are not recorded.
+func (sn *snapshot) volumeDrift() []Drift {
+	var drift []Drift
+	workloads := make(map[string]*database.Workload, len(sn.workloads))
+	for i := range sn.workloads {
+		workloads[sn.workloads[i].ID] = &sn.workloads[i]
+	}
+
+	recorded := map[string]bool{}
+	for _, v := range sn.volumes {
+		recorded[v.Pool+"/"+v.Name] = true
+		resource := v.Pool + "/" + v.Name
+		pool, name, size := v.Pool, v.Name, fmt.Sprintf("%dGiB", v.SizeGB)
+
+		var clusterID *string
+		var instance, mountPath, project string
+		if w, ok := workloads[deref(v.WorkloadID)]; ok && v.MountPath != nil {
+			clusterID = &w.ClusterID
+			instance, mountPath, project = w.Name, *v.MountPath, w.Project
+		}
+		attach := func(ctx context.Context) error {
+			return lxd.AttachVolume(lxd.WithProject(ctx, project), pool, name, instance, mountPath)
+		}
+
+		// Volumes of pools that are not Ceph are not listed, nor checked
+		_, poolExists := sn.pools[v.Pool]
+		names, listed := sn.poolVolumes[v.Pool]
+		if sn.pools != nil && (!poolExists || listed && !slices.Contains(names, v.Name)) {
+			drift = append(drift, Drift{
+				Kind:      KindVolume,
+				Type:      "drift.volume_missing",
+				Resource:  resource,
+				Message:   fmt.Sprintf("volume %s does not exist", resource),
+				Healable:  poolExists,
+				clusterID: clusterID,
+				heal: func(ctx context.Context) error {
+					if !poolExists {
+						return fmt.Errorf("storage pool %s does not exist", pool)
+					}
+					if err := lxd.CreateVolume(ctx, pool, name, size); err != nil {
+						return err
+					}
+					if instance == "" {
+						return nil
+					}
+					return attach(ctx)
+				},
+			})
+			continue
+		}
+		devices, ok := sn.devices[instance]
+		if instance == "" || !ok || hasDisk(devices, v.Pool, v.Name) {
+			continue
+		}
+		drift = append(drift, Drift{
+			Kind:      KindVolume,
+			Type:      "drift.volume_detached",
+			Resource:  resource,
+			Message:   fmt.Sprintf("volume %s is not attached to instance %s at %s", resource, instance, mountPath),
+			Healable:  true,
+			clusterID: clusterID,
+			heal:      attach,
+		})
+	}
+
+	for pool, names := range sn.poolVolumes {
+		for _, name := range names {
+			if !recorded[pool+"/"+name] {
+				drift = append(drift, Drift{
+					Kind:     KindVolume,
+					Type:     "drift.volume_orphan",
+					Resource: pool + "/" + name,
+					Message:  fmt.Sprintf("volume %s/%s is not recorded", pool, name),
+				})
+			}
+		}
+	}
+	return drift
+}
+
+// networkDrift finds floating IP forwards that are missing or target another
+// address than recorded, and forwards that are not recorded.
+func (sn *snapshot) networkDrift() []Drift {
+	if sn.forwards == nil {
+		return nil
+	}
+	var drift []Drift
+	clusters := make(map[string]string, len(sn.workloads))
+	for _, w := range sn.workloads {
+		clusters[w.ID] = w.ClusterID
+	}
+
+	recorded := map[string]bool{}
+	for _, f := range sn.floatingIPs {
+		recorded[f.Network+"/"+f.Address] = true
+		forwards, ok := sn.forwards[f.Network]
+		if !ok {
+			continue // listing failed
+		}
+		var clusterID *string
+		if id, ok := clusters[f.WorkloadID]; ok {
+			clusterID = &id
+		}
+		net, listen, target := f.Network, f.Address, f.TargetAddress
+
+		actual, ok := forwards[f.Address]
+		switch {
+		case !ok:
+			drift = append(drift, Drift{
+				Kind:      KindNetwork,
+				Type:      "drift.forward_missing",
+				Resource:  f.Address,
+				Message:   fmt.Sprintf("floating ip %s has no forward on network %s", f.Address, f.Network),
+				Healable:  true,
+				clusterID: clusterID,
+				heal: func(ctx context.Context) error {
+					return lxd.CreateForward(ctx, net, listen, target)
+				},
+			})
+		case actual != f.TargetAddress:
+			drift = append(drift, Drift{
+				Kind:      KindNetwork,
+				Type:      "drift.forward_target",
+				Resource:  f.Address,
+				Message:   fmt.Sprintf("floating ip %s forwards to %s, recorded as %s", f.Address, actual, f.TargetAddress),
+				Healable:  true,
+				clusterID: clusterID,
+				heal: func(ctx context.Context) error {
+					if err := lxd.DeleteForward(ctx, net, listen); err != nil {
+						return err
+					}
+					return lxd.CreateForward(ctx, net, listen, target)
+				},
+			})
+		}
+	}
+
+	for net, forwards := range sn.forwards {
+		for listen, target := range forwards {
+			if !recorded[net+"/"+listen] {
+				drift = append(drift, Drift{
+					Kind:     KindNetwork,
+					Type:     "drift.forward_orphan",
+					Resource: listen,
+					Message:  fmt.Sprintf("forward %s -> %s on network %s is not a recorded floating ip", listen, target, net),
+				})
+			}
+		}
+	}
+	return drift
+}
+
+// hasDisk reports whether devices include the custom volume pool/name.
+func hasDisk(devices map[string]map[string]string, pool string, name string) bool {
+	for _, d := range devices {
+		if d["type"] == "disk" && d["pool"] == pool && d["source"] == name {
+			return true
+		}
+	}
+	return false
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func (s *Service) event(ctx context.Context, clusterID *string, eventType string, message string) {
+	e := event.New(eventType, message, nil)
+	e.ClusterID = clusterID
+	if err := database.NewEventRepository(s.db).Create(ctx, e); err != nil {
+		log.Error("Failed to record event %s: %v", eventType, err)
+	}
+}
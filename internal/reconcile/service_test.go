@@ -0,0 +1,156 @@
+package reconcile
+
+import (
+	"slices"
+	"sort"
+	"testing"
+
+	"mcloud/internal/config"
+	"mcloud/internal/database"
+	"mcloud/services/lxd"
+)
+
+func ptr(s string) *string { return &s }
+
+// found returns "type resource" of each drift, sorted, plus which are healable.
+func found(t *testing.T, drift []Drift) ([]string, map[string]bool) {
+	t.Helper()
+	keys := []string{}
+	healable := map[string]bool{}
+	for _, d := range drift {
+		keys = append(keys, d.key())
+		healable[d.key()] = d.Healable
+		if d.Healable && d.heal == nil {
+			t.Errorf("%s is healable without a heal func", d.key())
+		}
+	}
+	sort.Strings(keys)
+	return keys, healable
+}
+
+func TestWorkloadDrift(t *testing.T) {
+	sn := snapshot{
+		workloads: []database.Workload{
+			{ID: "1", Name: "web-1", Status: "running"},
+			{ID: "2", Name: "web-2", Status: "running"},
+			{ID: "3", Name: "db-1", Status: "stopped"},
+			{ID: "4", Name: "new-1", Status: "pending"},
+			{ID: "5", Name: "batch-1", Status: "stopped"},
+		},
+		instances: map[string]lxd.InstanceState{
+			"web-1":   {Status: "Running"},
+			"db-1":    {Status: "Running"},
+			"batch-1": {Status: "Frozen"},
+			"manual":  {Status: "Running"},
+		},
+	}
+	keys, healable := found(t, sn.workloadDrift())
+	want := []string{
+		"drift.instance_missing web-2",
+		"drift.instance_state db-1",
+		"drift.instance_unmanaged manual",
+	}
+	if !slices.Equal(keys, want) {
+		t.Fatalf("got %v, want %v", keys, want)
+	}
+	if !healable["drift.instance_state db-1"] || healable["drift.instance_missing web-2"] || healable["drift.instance_unmanaged manual"] {
+		t.Errorf("healable = %v", healable)
+	}
+
+	sn.instances = nil // LXD unreachable
+	if drift := sn.workloadDrift(); len(drift) != 0 {
+		t.Errorf("got %d drifts without instances, want none", len(drift))
+	}
+}
+
+func TestVolumeDrift(t *testing.T) {
+	sn := snapshot{
+		workloads: []database.Workload{{ID: "w1", Name: "pg-1", Status: "running"}},
+		volumes: []database.Volume{
+			{Name: "pgdata", Pool: "remote", SizeGB: 50, WorkloadID: ptr("w1"), MountPath: ptr("/var/lib/postgresql")},
+			{Name: "logs", Pool: "remote", SizeGB: 10, WorkloadID: ptr("w1"), MountPath: ptr("/var/log/pg")},
+			{Name: "gone", Pool: "remote", SizeGB: 10},
+			{Name: "lost", Pool: "nopool", SizeGB: 10},
+			{Name: "local", Pool: "default", SizeGB: 10},
+		},
+		pools: map[string]string{"remote": "ceph", "default": "zfs"},
+		poolVolumes: map[string][]string{
+			"remote": {"pgdata", "logs", "stray"},
+		},
+		devices: map[string]map[string]map[string]string{
+			"pg-1": {
+				"root":   {"type": "disk", "pool": "remote", "path": "/"},
+				"pgdata": {"type": "disk", "pool": "remote", "source": "pgdata", "path": "/var/lib/postgresql"},
+			},
+		},
+	}
+	keys, healable := found(t, sn.volumeDrift())
+	want := []string{
+		"drift.volume_detached remote/logs",
+		"drift.volume_missing nopool/lost",
+		"drift.volume_missing remote/gone",
+		"drift.volume_orphan remote/stray",
+	}
+	if !slices.Equal(keys, want) {
+		t.Fatalf("got %v, want %v", keys, want)
+	}
+	if !healable["drift.volume_detached remote/logs"] || !healable["drift.volume_missing remote/gone"] || healable["drift.volume_missing nopool/lost"] {
+		t.Errorf("healable = %v", healable)
+	}
+
+	sn.pools, sn.poolVolumes = nil, nil // pools could not be listed
+	keys, _ = found(t, sn.volumeDrift())
+	if !slices.Equal(keys, []string{"drift.volume_detached remote/logs"}) {
+		t.Errorf("without pools got %v", keys)
+	}
+}
+
+func TestNetworkDrift(t *testing.T) {
+	sn := snapshot{
+		workloads: []database.Workload{{ID: "w1", ClusterID: "c1", Name: "web-1"}},
+		floatingIPs: []database.FloatingIP{
+			{Address: "203.0.113.10", WorkloadID: "w1", Network: "default", TargetAddress: "10.0.0.5"},
+			{Address: "203.0.113.11", WorkloadID: "w1", Network: "default", TargetAddress: "10.0.0.5"},
+			{Address: "203.0.113.12", WorkloadID: "w1", Network: "default", TargetAddress: "10.0.0.5"},
+		},
+		forwards: map[string]map[string]string{
+			"default": {
+				"203.0.113.10": "10.0.0.5",
+				"203.0.113.11": "10.0.0.9",
+				"203.0.113.99": "10.0.0.7",
+			},
+		},
+	}
+	drift := sn.networkDrift()
+	keys, healable := found(t, drift)
+	want := []string{
+		"drift.forward_missing 203.0.113.12",
+		"drift.forward_orphan 203.0.113.99",
+		"drift.forward_target 203.0.113.11",
+	}
+	if !slices.Equal(keys, want) {
+		t.Fatalf("got %v, want %v", keys, want)
+	}
+	if healable["drift.forward_orphan 203.0.113.99"] || !healable["drift.forward_target 203.0.113.11"] {
+		t.Errorf("healable = %v", healable)
+	}
+	for _, d := range drift {
+		if d.Type == "drift.forward_missing" && (d.clusterID == nil || *d.clusterID != "c1") {
+			t.Errorf("forward_missing cluster = %v, want c1", d.clusterID)
+		}
+	}
+}
+
+func TestPolicies(t *testing.T) {
+	cfg := &config.Config{Reconcile: config.Reconcile{Volumes: PolicyHeal, Network: "fix"}}
+	s := NewService(nil, cfg)
+	want := map[string]string{KindWorkload: PolicyReport, KindVolume: PolicyHeal, KindNetwork: PolicyReport}
+	for kind, p := range want {
+		if s.policies[kind] != p {
+			t.Errorf("%s policy = %q, want %q", kind, s.policies[kind], p)
+		}
+	}
+	if Interval(cfg) != DefaultInterval {
+		t.Errorf("Interval = %v, want %v", Interval(cfg), DefaultInterval)
+	}
+}
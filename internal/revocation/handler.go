@@ -0,0 +1,84 @@
+package revocation
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"mcloud/internal/auth"
+	"mcloud/pkg/reason"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(s *Service) *Handler {
+	return &Handler{service: s}
+}
+
+// RevokeCertificate handles POST /certs/revoke: revokes a compromised client
+// certificate, given as PEM or by serial number. mcloudd refuses it on the
+// HTTP and gRPC APIs from then on.
+func (h *Handler) RevokeCertificate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req RevokeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		reason.HTTPError(w, err, 400)
+		return
+	}
+
+	actor := auth.ClientIdentity(r)
+	revoked, err := h.service.Revoke(r.Context(), &req, &actor)
+	if err != nil {
+		if errors.Is(err, ErrInvalidRevocation) {
+			reason.HTTPError(w, err, 400)
+			return
+		}
+		reason.HTTPError(w, err, 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(revoked)
+}
+
+// ListRevoked handles GET /certs/revoked: the certificates revoked by serial
+// number, oldest first.
+func (h *Handler) ListRevoked(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	revoked, err := h.service.List(r.Context())
+	if err != nil {
+		reason.HTTPError(w, err, 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(revoked)
+}
+
+// GetCRL handles GET /certs/crl: the revoked certificates as a DER CRL signed
+// by the issuing CA, e.g. for `openssl crl -inform DER`.
+func (h *Handler) GetCRL(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	crl, err := h.service.CRL(r.Context())
+	if err != nil {
+		reason.HTTPError(w, err, 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pkix-crl")
+	w.Write(crl)
+}
@@ -0,0 +1,12 @@
+package revocation
+
+import (
+	"net/http"
+)
+
+// InitModule registers the certificate revocation routes served by handler.
+func InitModule(mux *http.ServeMux, handler *Handler) {
+	mux.HandleFunc("POST /certs/revoke", handler.RevokeCertificate)
+	mux.HandleFunc("GET /certs/revoked", handler.ListRevoked)
+	mux.HandleFunc("GET /certs/crl", handler.GetCRL)
+}
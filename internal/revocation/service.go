@@ -0,0 +1,126 @@
+// Package revocation revokes client certificates of the cluster one by one,
+// e.g. a node certificate that leaked, and publishes the revoked serials as a
+// CRL signed by the issuing CA. mcloudd itself refuses revoked certificates on
+// the HTTP and gRPC APIs from the database (see auth.CheckRevoked); the CRL is
+// for other services that trust the cluster CA.
+package revocation
+
+import (
+	"context"
+	"crypto/x509"
+	"database/sql"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+
+	"mcloud/internal/cert"
+	"mcloud/internal/config"
+	"mcloud/internal/database"
+	"mcloud/pkg/reason"
+)
+
+var ErrInvalidRevocation = reason.New(reason.InvalidRevocation, "invalid revocation")
+
+var serialRegexp = regexp.MustCompile(`^[0-9a-f]+$`)
+
+type Service struct {
+	db  *sql.DB
+	cfg *config.Config
+}
+
+// RevokeRequest names the certificate to revoke: its PEM, or its serial
+// number in hex as 'mcloudctl ca list' and openssl print it (colons allowed).
+//
+// Example JSON:
+//   {"serial": "3a9f0c12d4e5b6a7", "reason": "key of node3 leaked"}
+type RevokeRequest struct {
+	Serial      string `json:"serial,omitempty"`
+	Certificate string `json:"certificate,omitempty"` // PEM
+	Reason      string `json:"reason"`
+}
+
+func NewService(db *sql.DB, cfg *config.Config) *Service {
+	return &Service{db: db, cfg: cfg}
+}
+
+// Revoke revokes one certificate by serial number. From then on mcloudd
+// refuses it on the HTTP and gRPC APIs and lists it in the CRL. Revoking a
+// certificate again returns the first revocation.
+//
+// Example Output (Error):
+//   {"serial": "xyz"}  =>  ErrInvalidRevocation: serial "xyz" is not hex
+//   {"certificate": <the intermediate CA>}  =>  ErrInvalidRevocation: ... is a CA certificate
+func (s *Service) Revoke(ctx context.Context, req *RevokeRequest, actor *string) (*database.RevokedSerial, error) {
+	rs := &database.RevokedSerial{Reason: req.Reason, RevokedBy: actor}
+	switch {
+	case req.Certificate != "":
+		block, _ := pem.Decode([]byte(req.Certificate))
+		if block == nil {
+			return nil, fmt.Errorf("%w: certificate is not PEM", ErrInvalidRevocation)
+		}
+		c, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRevocation, err)
+		}
+		if c.IsCA {
+			return nil, fmt.Errorf("%w: %s is a CA certificate; rotate it with mcloudctl ca rotate", ErrInvalidRevocation, c.Subject.CommonName)
+		}
+		rs.Serial, rs.CommonName = fmt.Sprintf("%x", c.SerialNumber), c.Subject.CommonName
+	case req.Serial != "":
+		serial := strings.ToLower(strings.ReplaceAll(req.Serial, ":", ""))
+		serial = strings.TrimLeft(serial, "0")
+		if !serialRegexp.MatchString(serial) {
+			return nil, fmt.Errorf("%w: serial %q is not hex", ErrInvalidRevocation, req.Serial)
+		}
+		rs.Serial = serial
+	default:
+		return nil, fmt.Errorf("%w: serial or certificate is required", ErrInvalidRevocation)
+	}
+	if rs.Reason == "" {
+		rs.Reason = "revoked"
+	}
+
+	repo := database.NewRevokedCertificateRepository(s.db)
+	if err := repo.RevokeSerial(ctx, rs); err != nil {
+		return nil, err
+	}
+	return repo.GetSerial(ctx, rs.Serial)
+}
+
+// List returns the certificates revoked by serial, oldest first.
+func (s *Service) List(ctx context.Context) ([]database.RevokedSerial, error) {
+	return database.NewRevokedCertificateRepository(s.db).ListSerials(ctx)
+}
+
+// CRL returns the revoked serials as a DER CRL, signed by the CA that issues
+// the cluster's certificates: the intermediate when one is configured, else
+// the root. Certificates revoked by removing their node are refused by common
+// name and have no serial, so they are not listed.
+func (s *Service) CRL(ctx context.Context) ([]byte, error) {
+	certPath, keyPath := s.cfg.Security.CACertPath, s.cfg.Security.CAKeyPath
+	if s.cfg.Security.IntermediateCertPath != "" {
+		certPath, keyPath = s.cfg.Security.IntermediateCertPath, s.cfg.Security.IntermediateKeyPath
+	}
+	ca, caKey, err := cert.LoadCA(certPath, keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	revoked, err := s.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]x509.RevocationListEntry, 0, len(revoked))
+	for _, r := range revoked {
+		serial, ok := new(big.Int).SetString(r.Serial, 16)
+		if !ok {
+			return nil, fmt.Errorf("revoked serial %q is not hex", r.Serial)
+		}
+		entries = append(entries, x509.RevocationListEntry{SerialNumber: serial, RevocationTime: r.RevokedAt})
+	}
+	// A fresh CRL is signed on each request; its number grows with time
+	return cert.CreateCRL(ca, caKey, entries, big.NewInt(time.Now().UnixNano()))
+}
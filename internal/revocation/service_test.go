@@ -0,0 +1,91 @@
+package revocation
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"mcloud/internal/auth"
+	"mcloud/internal/cert"
+	"mcloud/internal/config"
+	"mcloud/internal/database"
+)
+
+func TestRevokeAndCRL(t *testing.T) {
+	dir := t.TempDir()
+	path := func(name string) string { return filepath.Join(dir, name) }
+	db, err := database.Connect(path("mcloud.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close(db)
+	ctx := context.Background()
+
+	root, rootKey, err := cert.GenerateCAV2(path("ca.crt"), path("ca.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	inter, interKey, err := cert.GenerateIntermediateCA(root, rootKey, path("inter.crt"), path("inter.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var leaves []*x509.Certificate
+	for _, name := range []string{"node1", "node2"} {
+		if err := cert.GenerateClientCert(inter, interKey, name, path(name+".crt"), path(name+".key")); err != nil {
+			t.Fatal(err)
+		}
+		data, _ := os.ReadFile(path(name + ".crt"))
+		block, _ := pem.Decode(data)
+		leaf, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		leaves = append(leaves, leaf)
+	}
+
+	cfg := &config.Config{}
+	cfg.Security.CACertPath, cfg.Security.CAKeyPath = path("ca.crt"), path("ca.key")
+	cfg.Security.IntermediateCertPath, cfg.Security.IntermediateKeyPath = path("inter.crt"), path("inter.key")
+	s := NewService(db, cfg)
+
+	for _, req := range []*RevokeRequest{{}, {Serial: "xyz"}, {Certificate: cert.EncodeCertPEM(inter)}} {
+		if _, err := s.Revoke(ctx, req, nil); !errors.Is(err, ErrInvalidRevocation) {
+			t.Errorf("revoke %+v: got %v, want ErrInvalidRevocation", req, err)
+		}
+	}
+
+	node1PEM, _ := os.ReadFile(path("node1.crt"))
+	revoked, err := s.Revoke(ctx, &RevokeRequest{Certificate: string(node1PEM), Reason: "key leaked"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revoked.CommonName != "node1" || revoked.Reason != "key leaked" {
+		t.Errorf("revoked: got %+v", revoked)
+	}
+
+	if err := auth.CheckRevoked(ctx, db, leaves[0]); !errors.Is(err, auth.ErrCertRevoked) {
+		t.Errorf("node1: got %v, want ErrCertRevoked", err)
+	}
+	if err := auth.CheckRevoked(ctx, db, leaves[1]); err != nil {
+		t.Errorf("node2: got %v, want accepted", err)
+	}
+
+	der, err := s.CRL(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	crl, err := x509.ParseRevocationList(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := crl.CheckSignatureFrom(inter); err != nil {
+		t.Errorf("CRL signature: %v", err)
+	}
+	if len(crl.RevokedCertificateEntries) != 1 || crl.RevokedCertificateEntries[0].SerialNumber.Cmp(leaves[0].SerialNumber) != 0 {
+		t.Errorf("CRL entries: got %+v, want the serial of node1", crl.RevokedCertificateEntries)
+	}
+}
@@ -0,0 +1,12 @@
+package secret
+
+import (
+	"net/http"
+)
+
+// InitModule registers the secret routes served by handler.
+func InitModule(mux *http.ServeMux, handler *Handler) {
+	mux.HandleFunc("GET /secrets", handler.ListSecrets)
+	mux.HandleFunc("POST /secrets", handler.PutSecret)
+	mux.HandleFunc("DELETE /secrets/{name}", handler.DeleteSecret)
+}
@@ -0,0 +1,114 @@
+// Package secret is the secrets backend: named values encrypted at rest in the database.
+// Secret values can be written and consumed by other subsystems (e.g. workload
+// environment injection) but are never returned by the API.
+package secret
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"mcloud/internal/database"
+	"mcloud/pkg/reason"
+)
+
+var (
+	ErrSecretNotFound = reason.New(reason.SecretNotFound, "secret not found")
+	ErrSecretInUse    = reason.New(reason.SecretInUse, "secret is referenced by workloads")
+	ErrInvalidName    = reason.New(reason.InvalidSecretName, "secret name must match [a-zA-Z0-9][a-zA-Z0-9._-]*")
+)
+
+var nameRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+type Service struct {
+	db      *sql.DB
+	keyPath string
+}
+
+// Info is the public view of a secret (no value).
+type Info struct {
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type PutRequest struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+func NewService(db *sql.DB, keyPath string) *Service {
+	return &Service{
+		db:      db,
+		keyPath: keyPath,
+	}
+}
+
+// Put creates or replaces a secret.
+func (s *Service) Put(ctx context.Context, req *PutRequest, actor *string) error {
+	if !nameRegexp.MatchString(req.Name) {
+		return ErrInvalidName
+	}
+
+	key, err := loadOrCreateKey(s.keyPath)
+	if err != nil {
+		return err
+	}
+	encrypted, err := encrypt(key, req.Value)
+	if err != nil {
+		return err
+	}
+
+	return database.NewSecretRepository(s.db).Upsert(ctx, &database.Secret{
+		Name:           req.Name,
+		ValueEncrypted: encrypted,
+		CreateUserID:   actor,
+	})
+}
+
+// List returns secret names and timestamps only.
+func (s *Service) List(ctx context.Context) ([]Info, error) {
+	secrets, err := database.NewSecretRepository(s.db).List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]Info, 0, len(secrets))
+	for _, sec := range secrets {
+		items = append(items, Info{Name: sec.Name, CreatedAt: sec.CreatedAt, UpdatedAt: sec.UpdatedAt})
+	}
+	return items, nil
+}
+
+// Delete removes a secret that is no longer referenced by any workload.
+func (s *Service) Delete(ctx context.Context, name string) error {
+	inUse, err := database.NewWorkloadEnvRepository(s.db).CountBySecret(ctx, name)
+	if err != nil {
+		return err
+	}
+	if inUse > 0 {
+		return fmt.Errorf("%w: %s", ErrSecretInUse, name)
+	}
+	return database.NewSecretRepository(s.db).Delete(ctx, name)
+}
+
+// Resolve returns the plaintext value of a secret for injection into a workload.
+// It must only be used server-side; the value must never be persisted or returned.
+func (s *Service) Resolve(ctx context.Context, name string) (string, error) {
+	sec, err := database.NewSecretRepository(s.db).Get(ctx, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("%w: %s", ErrSecretNotFound, name)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	key, err := loadOrCreateKey(s.keyPath)
+	if err != nil {
+		return "", err
+	}
+	return decrypt(key, sec.ValueEncrypted)
+}
@@ -0,0 +1,17 @@
+package securitygroup
+
+import (
+	"net/http"
+)
+
+// InitModule registers the security group routes served by handler.
+func InitModule(mux *http.ServeMux, handler *Handler) {
+	mux.HandleFunc("GET /security-groups", handler.ListGroups)
+	mux.HandleFunc("POST /security-groups", handler.CreateGroup)
+	mux.HandleFunc("GET /security-groups/{name}", handler.GetGroup)
+	mux.HandleFunc("DELETE /security-groups/{name}", handler.DeleteGroup)
+	mux.HandleFunc("PUT /security-groups/{name}/rules", handler.ReplaceRules)
+	mux.HandleFunc("GET /workloads/{id}/security-groups", handler.ListWorkloadGroups)
+	mux.HandleFunc("POST /workloads/{id}/security-groups", handler.AttachGroup)
+	mux.HandleFunc("DELETE /workloads/{id}/security-groups/{name}", handler.DetachGroup)
+}
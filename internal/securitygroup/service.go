@@ -0,0 +1,465 @@
+// Package securitygroup is the firewall of workloads: named security groups
+// of ingress and egress allow rules, attached to workloads. Groups and
+// attachments are stored in the database and reconciled to OVN through LXD
+// network ACLs: each group is the ACL sg-<name>, and a workload's NICs on the
+// OVN network carry the ACLs of its groups. Once a workload has a group,
+// traffic that none of its groups allows is rejected.
+package securitygroup
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"mcloud/internal/config"
+	"mcloud/internal/database"
+	"mcloud/internal/network"
+	"mcloud/internal/project"
+	"mcloud/pkg/logger"
+	"mcloud/pkg/reason"
+	"mcloud/services/lxd"
+)
+
+var log = logger.Named("securitygroup")
+
+var (
+	ErrNotFound         = reason.New(reason.SecurityGroupNotFound, "security group not found")
+	ErrExists           = reason.New(reason.SecurityGroupExists, "a security group with this name already exists")
+	ErrInUse            = reason.New(reason.SecurityGroupInUse, "security group is attached to workloads")
+	ErrInvalid          = reason.New(reason.InvalidSecurityGroup, "invalid security group")
+	ErrWorkloadNotFound = reason.New(reason.WorkloadNotFound, "workload not found")
+)
+
+// Names become LXD ACL names (sg-<name>), which are limited to 63 characters.
+var nameRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,59}$`)
+
+var protocols = map[string]bool{"": true, "tcp": true, "udp": true, "icmp4": true, "icmp6": true}
+
+type Service struct {
+	db       *sql.DB
+	network  string
+	projects *project.Service
+}
+
+// CreateRequest defines a new security group.
+//
+// Example JSON:
+//   {"name": "web", "description": "HTTP(S) from anywhere",
+//    "rules": [{"direction": "ingress", "protocol": "tcp", "port": "443"},
+//              {"direction": "egress"}]}
+type CreateRequest struct {
+	Name        string                       `json:"name"`
+	Description string                       `json:"description,omitempty"`
+	Rules       []database.SecurityGroupRule `json:"rules"`
+}
+
+// RulesRequest replaces the rules of a security group.
+type RulesRequest struct {
+	Rules []database.SecurityGroupRule `json:"rules"`
+}
+
+func NewService(db *sql.DB, cfg config.Network, projects *project.Service) *Service {
+	return &Service{db: db, network: network.OVNNetwork(cfg), projects: projects}
+}
+
+// ACLName is the LXD network ACL of a group, e.g. sg-web.
+func ACLName(name string) string {
+	return "sg-" + name
+}
+
+// ParseRule parses the command-line form of a rule,
+// direction[:protocol[:port[:cidr]]], where protocol may be "any".
+//
+// Example:
+//   ParseRule("ingress:tcp:443:0.0.0.0/0")   // {Direction: "ingress", Protocol: "tcp", Port: "443", CIDR: "0.0.0.0/0"}
+//   ParseRule("egress")                      // any traffic out
+//   ParseRule("ingress:icmp6::2001:db8::/32")
+func ParseRule(s string) (database.SecurityGroupRule, error) {
+	parts := strings.SplitN(s, ":", 4)
+	rule := database.SecurityGroupRule{Direction: parts[0]}
+	if len(parts) > 1 && parts[1] != "any" {
+		rule.Protocol = parts[1]
+	}
+	if len(parts) > 2 {
+		rule.Port = parts[2]
+	}
+	if len(parts) > 3 {
+		rule.CIDR = parts[3]
+	}
+	return normalizeRule(rule)
+}
+
+// normalizeRule validates a rule and returns it with its CIDR in canonical form.
+func normalizeRule(rule database.SecurityGroupRule) (database.SecurityGroupRule, error) {
+	if rule.Direction != "ingress" && rule.Direction != "egress" {
+		return rule, fmt.Errorf("%w: direction must be ingress or egress, got %q", ErrInvalid, rule.Direction)
+	}
+	if !protocols[rule.Protocol] {
+		return rule, fmt.Errorf("%w: protocol must be tcp, udp, icmp4 or icmp6, got %q", ErrInvalid, rule.Protocol)
+	}
+	if rule.Port != "" {
+		if rule.Protocol != "tcp" && rule.Protocol != "udp" {
+			return rule, fmt.Errorf("%w: port %s needs protocol tcp or udp", ErrInvalid, rule.Port)
+		}
+		if !validPortRange(rule.Port) {
+			return rule, fmt.Errorf("%w: port must be a port or range of 1-65535, got %q", ErrInvalid, rule.Port)
+		}
+	}
+	if rule.CIDR != "" {
+		if p, err := netip.ParsePrefix(rule.CIDR); err == nil {
+			rule.CIDR = p.Masked().String()
+		} else if a, err := netip.ParseAddr(rule.CIDR); err == nil {
+			rule.CIDR = a.String()
+		} else {
+			return rule, fmt.Errorf("%w: cidr must be an address or prefix, got %q", ErrInvalid, rule.CIDR)
+		}
+	}
+	return rule, nil
+}
+
+// validPortRange accepts a port (443) or an inclusive range (8000-8100).
+func validPortRange(s string) bool {
+	low, high, isRange := strings.Cut(s, "-")
+	if !isRange {
+		high = low
+	}
+	lo, err1 := strconv.Atoi(low)
+	hi, err2 := strconv.Atoi(high)
+	return err1 == nil && err2 == nil && lo >= 1 && hi <= 65535 && lo <= hi
+}
+
+// NormalizeRules validates rules and returns them in the form they are stored,
+// so that rule lists can be compared.
+func NormalizeRules(rules []database.SecurityGroupRule) ([]database.SecurityGroupRule, error) {
+	out := make([]database.SecurityGroupRule, len(rules))
+	for i, r := range rules {
+		var err error
+		if out[i], err = normalizeRule(r); err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+	}
+	return out, nil
+}
+
+// acl returns the LXD network ACL of a group: every rule allows.
+func acl(name string, description string, rules []database.SecurityGroupRule) lxd.ACLConfig {
+	cfg := lxd.ACLConfig{Name: ACLName(name), Description: description}
+	for _, r := range rules {
+		rule := lxd.ACLRule{Action: "allow", Protocol: r.Protocol, DestinationPort: r.Port, Description: r.Description}
+		if r.Direction == "ingress" {
+			rule.Source = r.CIDR
+			cfg.Ingress = append(cfg.Ingress, rule)
+		} else {
+			rule.Destination = r.CIDR
+			cfg.Egress = append(cfg.Egress, rule)
+		}
+	}
+	return cfg
+}
+
+// Create applies the group's ACL and records the group.
+//
+// Example Output (Error):
+//   {"name": "web", ...}                                =>  ErrExists: web
+//   {"rules": [{"direction": "in"}]}                    =>  ErrInvalid: rule 1: direction must be ingress or egress, got "in"
+//   {"rules": [{"direction": "ingress", "port": "22"}]} =>  ErrInvalid: rule 1: port 22 needs protocol tcp or udp
+func (s *Service) Create(ctx context.Context, req *CreateRequest, actor *string) (*database.SecurityGroup, error) {
+	if !nameRegexp.MatchString(req.Name) {
+		return nil, fmt.Errorf("%w: name must match [a-z0-9][a-z0-9-]* (at most 60 characters)", ErrInvalid)
+	}
+	rules, err := NormalizeRules(req.Rules)
+	if err != nil {
+		return nil, err
+	}
+
+	repo := database.NewSecurityGroupRepository(s.db)
+	if _, err := repo.Get(ctx, req.Name); err == nil {
+		return nil, fmt.Errorf("%w: %s", ErrExists, req.Name)
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	if err := lxd.ApplyACL(ctx, acl(req.Name, req.Description, rules)); err != nil {
+		return nil, err
+	}
+	err = database.WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		txRepo := database.NewSecurityGroupRepositoryTx(tx)
+		if err := txRepo.Create(ctx, &database.SecurityGroup{Name: req.Name, Description: req.Description, CreateUserID: actor}); err != nil {
+			return err
+		}
+		return txRepo.ReplaceRules(ctx, req.Name, rules, actor)
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Info("Security group %s created with %d rules", req.Name, len(rules))
+	return repo.Get(ctx, req.Name)
+}
+
+// List returns all groups with their rules and workloads.
+func (s *Service) List(ctx context.Context) ([]database.SecurityGroup, error) {
+	repo := database.NewSecurityGroupRepository(s.db)
+	groups, err := repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	items := []database.SecurityGroup{}
+	for _, g := range groups {
+		full, err := repo.Get(ctx, g.Name)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, *full)
+	}
+	return items, nil
+}
+
+// Get returns a group with its rules and workloads.
+func (s *Service) Get(ctx context.Context, name string) (*database.SecurityGroup, error) {
+	g, err := database.NewSecurityGroupRepository(s.db).Get(ctx, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return g, err
+}
+
+// ListAs is List with the workloads of each group limited to those of
+// projects identity is a member of.
+func (s *Service) ListAs(ctx context.Context, identity string) ([]database.SecurityGroup, error) {
+	groups, err := s.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return groups, s.hideWorkloads(ctx, groups, identity)
+}
+
+// GetAs is Get with the workloads of the group limited to those of projects
+// identity is a member of.
+func (s *Service) GetAs(ctx context.Context, name string, identity string) (*database.SecurityGroup, error) {
+	g, err := s.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	groups := []database.SecurityGroup{*g}
+	if err := s.hideWorkloads(ctx, groups, identity); err != nil {
+		return nil, err
+	}
+	return &groups[0], nil
+}
+
+// hideWorkloads removes from groups the workloads of projects identity may
+// not read. Groups themselves belong to no project.
+func (s *Service) hideWorkloads(ctx context.Context, groups []database.SecurityGroup, identity string) error {
+	names, err := s.projects.Readable(ctx, identity)
+	if err != nil {
+		return err
+	}
+	readable := map[string]bool{}
+	for _, p := range names {
+		readable[p] = true
+	}
+	repo := database.NewWorkloadRepository(s.db)
+	for i := range groups {
+		visible := []string{}
+		for _, id := range groups[i].Workloads {
+			w, err := repo.GetByID(ctx, id)
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if readable[w.Project] {
+				visible = append(visible, id)
+			}
+		}
+		groups[i].Workloads = visible
+	}
+	return nil
+}
+
+// ReplaceRules replaces a group's rules. Workloads in the group get the new
+// rules at once, since their NICs reference the group's ACL.
+func (s *Service) ReplaceRules(ctx context.Context, name string, req *RulesRequest, actor *string) (*database.SecurityGroup, error) {
+	g, err := s.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := NormalizeRules(req.Rules)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := lxd.ApplyACL(ctx, acl(g.Name, g.Description, rules)); err != nil {
+		return nil, err
+	}
+	err = database.WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		return database.NewSecurityGroupRepositoryTx(tx).ReplaceRules(ctx, name, rules, actor)
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Info("Security group %s now has %d rules", name, len(rules))
+	return s.Get(ctx, name)
+}
+
+// Delete deletes a group that no workload uses, and its ACL.
+func (s *Service) Delete(ctx context.Context, name string) error {
+	g, err := s.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+	if len(g.Workloads) > 0 {
+		return fmt.Errorf("%w: %s is attached to %d workloads", ErrInUse, name, len(g.Workloads))
+	}
+
+	if err := lxd.DeleteACL(ctx, ACLName(name)); err != nil {
+		return err
+	}
+	return database.WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		repo := database.NewSecurityGroupRepositoryTx(tx)
+		if err := repo.ReplaceRules(ctx, name, nil, nil); err != nil {
+			return err
+		}
+		return repo.Delete(ctx, name)
+	})
+}
+
+// Attach attaches a group to a workload and updates the ACLs of its NICs.
+// If LXD rejects the change the attachment is not recorded.
+func (s *Service) Attach(ctx context.Context, workloadID string, name string) ([]string, error) {
+	return s.changeAttachment(ctx, workloadID, name, true)
+}
+
+// Detach detaches a group from a workload and updates the ACLs of its NICs.
+func (s *Service) Detach(ctx context.Context, workloadID string, name string) ([]string, error) {
+	return s.changeAttachment(ctx, workloadID, name, false)
+}
+
+// Authorize returns project.ErrProjectDenied unless identity is a member of
+// the workload's project.
+func (s *Service) Authorize(ctx context.Context, workloadID string, identity string) error {
+	w, err := s.workload(ctx, workloadID)
+	if err != nil {
+		return err
+	}
+	return s.projects.Authorize(ctx, w.Project, identity, database.ProjectRoleMember)
+}
+
+// GroupsOf returns the names of the groups attached to a workload.
+func (s *Service) GroupsOf(ctx context.Context, workloadID string) ([]string, error) {
+	if _, err := s.workload(ctx, workloadID); err != nil {
+		return nil, err
+	}
+	return database.NewSecurityGroupRepository(s.db).GroupsOf(ctx, workloadID)
+}
+
+func (s *Service) changeAttachment(ctx context.Context, workloadID string, name string, attach bool) ([]string, error) {
+	w, err := s.workload(ctx, workloadID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.Get(ctx, name); err != nil {
+		return nil, err
+	}
+
+	repo := database.NewSecurityGroupRepository(s.db)
+	before, err := repo.GroupsOf(ctx, w.ID)
+	if err != nil {
+		return nil, err
+	}
+	if attach {
+		err = repo.Attach(ctx, w.ID, name)
+	} else {
+		err = repo.Detach(ctx, w.ID, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := s.reconcileWorkload(ctx, w)
+	if err != nil {
+		// Put the attachments back the way LXD still has them
+		if attach && !contains(before, name) {
+			repo.Detach(ctx, w.ID, name)
+		} else if !attach && contains(before, name) {
+			repo.Attach(ctx, w.ID, name)
+		}
+		return nil, err
+	}
+	return groups, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Service) workload(ctx context.Context, id string) (*database.Workload, error) {
+	w, err := database.NewWorkloadRepository(s.db).GetByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrWorkloadNotFound, id)
+	}
+	return w, err
+}
+
+// reconcileWorkload sets the ACLs of a workload's NICs to those of its groups.
+func (s *Service) reconcileWorkload(ctx context.Context, w *database.Workload) ([]string, error) {
+	groups, err := database.NewSecurityGroupRepository(s.db).GroupsOf(ctx, w.ID)
+	if err != nil {
+		return nil, err
+	}
+	acls := make([]string, len(groups))
+	for i, g := range groups {
+		acls[i] = ACLName(g)
+	}
+	if err := lxd.SetInstanceACLs(lxd.WithProject(ctx, w.Project), w.Name, s.network, acls); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// Reconcile is the periodic job that re-applies every group's ACL and the ACLs
+// of every workload with groups, repairing changes made behind mcloud's back
+// (or instances recreated without them).
+func (s *Service) Reconcile(ctx context.Context) error {
+	repo := database.NewSecurityGroupRepository(s.db)
+	groups, err := repo.List(ctx)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, g := range groups {
+		rules, err := repo.Rules(ctx, g.Name)
+		if err != nil {
+			return err
+		}
+		if err := lxd.ApplyACL(ctx, acl(g.Name, g.Description, rules)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	ids, err := repo.AttachedWorkloads(ctx)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		w, err := s.workload(ctx, id)
+		if err != nil {
+			continue // the workload is gone; its attachments go with it
+		}
+		if _, err := s.reconcileWorkload(ctx, w); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
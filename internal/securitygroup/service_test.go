@@ -0,0 +1,125 @@
+package securitygroup
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"mcloud/internal/config"
+	"mcloud/internal/database"
+	"mcloud/internal/project"
+)
+
+func TestParseRule(t *testing.T) {
+	cases := []struct {
+		in   string
+		want database.SecurityGroupRule
+	}{
+		{"egress", database.SecurityGroupRule{Direction: "egress"}},
+		{"ingress:tcp:443", database.SecurityGroupRule{Direction: "ingress", Protocol: "tcp", Port: "443"}},
+		{"ingress:any::10.1.2.3/8", database.SecurityGroupRule{Direction: "ingress", CIDR: "10.0.0.0/8"}},
+		{"ingress:udp:8000-8100:192.0.2.1", database.SecurityGroupRule{Direction: "ingress", Protocol: "udp", Port: "8000-8100", CIDR: "192.0.2.1"}},
+		{"egress:icmp6::2001:db8::/32", database.SecurityGroupRule{Direction: "egress", Protocol: "icmp6", CIDR: "2001:db8::/32"}},
+	}
+	for _, c := range cases {
+		got, err := ParseRule(c.in)
+		if err != nil {
+			t.Errorf("%s: %v", c.in, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("%s: got %+v, want %+v", c.in, got, c.want)
+		}
+	}
+
+	for _, in := range []string{"in", "ingress:gre", "ingress:icmp4:22", "ingress:tcp:0", "ingress:tcp:90-80", "ingress:tcp:http", "ingress:tcp:22:10.0.0.0/33"} {
+		if _, err := ParseRule(in); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: got %v, want ErrInvalid", in, err)
+		}
+	}
+}
+
+func TestACL(t *testing.T) {
+	cfg := acl("web", "", []database.SecurityGroupRule{
+		{Direction: "ingress", Protocol: "tcp", Port: "443", CIDR: "0.0.0.0/0"},
+		{Direction: "egress", CIDR: "10.0.0.0/8"},
+	})
+	if cfg.Name != "sg-web" || len(cfg.Ingress) != 1 || len(cfg.Egress) != 1 {
+		t.Fatalf("got %+v", cfg)
+	}
+	if in := cfg.Ingress[0]; in.Action != "allow" || in.Source != "0.0.0.0/0" || in.DestinationPort != "443" || in.Destination != "" {
+		t.Errorf("ingress rule: got %+v", in)
+	}
+	if out := cfg.Egress[0]; out.Destination != "10.0.0.0/8" || out.Source != "" {
+		t.Errorf("egress rule: got %+v", out)
+	}
+}
+
+func TestServiceErrors(t *testing.T) {
+	db, err := database.Connect(filepath.Join(t.TempDir(), "mcloud.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close(db)
+	ctx := context.Background()
+	s := NewService(db, config.Network{}, project.NewService(db, &config.Config{}))
+
+	for _, name := range []string{"", "Web", "-web", "web_1"} {
+		if _, err := s.Create(ctx, &CreateRequest{Name: name}, nil); !errors.Is(err, ErrInvalid) {
+			t.Errorf("name %q: got %v, want ErrInvalid", name, err)
+		}
+	}
+	if _, err := s.Create(ctx, &CreateRequest{Name: "web", Rules: []database.SecurityGroupRule{{Direction: "ingress", Port: "22"}}}, nil); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("port without protocol: got %v, want ErrInvalid", err)
+	}
+
+	repo := database.NewSecurityGroupRepository(db)
+	if err := repo.Create(ctx, &database.SecurityGroup{Name: "web"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Create(ctx, &CreateRequest{Name: "web"}, nil); !errors.Is(err, ErrExists) {
+		t.Fatalf("duplicate: got %v, want ErrExists", err)
+	}
+	if _, err := s.Get(ctx, "db"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("get missing: got %v, want ErrNotFound", err)
+	}
+	if _, err := s.ReplaceRules(ctx, "db", &RulesRequest{}, nil); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("rules of missing: got %v, want ErrNotFound", err)
+	}
+	if _, err := s.Attach(ctx, "w-1", "web"); !errors.Is(err, ErrWorkloadNotFound) {
+		t.Fatalf("attach to missing workload: got %v, want ErrWorkloadNotFound", err)
+	}
+
+	w := &database.Workload{ID: "w-1", ClusterID: "c-1", Name: "web-1", Kind: "container", Status: "running", Image: "ubuntu:24.04", Priority: "normal"}
+	if err := database.NewWorkloadRepository(db).Create(ctx, w); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Attach(ctx, "w-1", "db"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("attach missing group: got %v, want ErrNotFound", err)
+	}
+	if err := repo.Attach(ctx, "w-1", "web"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(ctx, "web"); !errors.Is(err, ErrInUse) {
+		t.Fatalf("delete attached: got %v, want ErrInUse", err)
+	}
+	groups, err := s.GroupsOf(ctx, "w-1")
+	if err != nil || len(groups) != 1 || groups[0] != "web" {
+		t.Fatalf("groups of w-1: got %v, %v", groups, err)
+	}
+
+	// Once default has bindings, others no longer see its workloads
+	if err := database.NewProjectRepository(db).PutBinding(ctx, &database.ProjectBinding{Project: database.DefaultProject, Identity: "alice", Role: database.ProjectRoleMember}); err != nil {
+		t.Fatal(err)
+	}
+	if g, err := s.GetAs(ctx, "web", "alice"); err != nil || len(g.Workloads) != 1 {
+		t.Errorf("GetAs(web, alice) = %+v, %v", g, err)
+	}
+	if g, err := s.GetAs(ctx, "web", "mallory"); err != nil || len(g.Workloads) != 0 {
+		t.Errorf("GetAs(web, mallory) = %+v, %v; want no workloads", g, err)
+	}
+	if err := s.Authorize(ctx, "w-1", "mallory"); !errors.Is(err, project.ErrProjectDenied) {
+		t.Errorf("Authorize(w-1, mallory) = %v, want ErrProjectDenied", err)
+	}
+}
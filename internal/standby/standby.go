@@ -0,0 +1,79 @@
+// Package standby keeps a warm copy of the manager database on a follower node.
+// The leader takes a consistent SQLite snapshot (VACUUM INTO) every interval and
+// ships it to the standby node's agent, so losing the leader's disk costs at most
+// one interval of changes instead of the whole cluster state.
+package standby
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"time"
+
+	"mcloud/internal/agent"
+	"mcloud/internal/config"
+	"mcloud/internal/database"
+	"mcloud/pkg/logger"
+)
+
+var log = logger.Named("standby")
+
+// DefaultInterval is used when standby.interval_seconds is not set.
+const DefaultInterval = 10 * time.Second
+
+// Interval returns how often snapshots are shipped to the standby node.
+func Interval(cfg *config.Config) time.Duration {
+	if cfg.Standby.IntervalSeconds > 0 {
+		return time.Duration(cfg.Standby.IntervalSeconds) * time.Second
+	}
+	return DefaultInterval
+}
+
+type Shipper struct {
+	db  *sql.DB
+	cfg *config.Config
+}
+
+func NewShipper(db *sql.DB, cfg *config.Config) *Shipper {
+	return &Shipper{
+		db:  db,
+		cfg: cfg,
+	}
+}
+
+// Ship snapshots the database and sends it to the configured standby node.
+// It is registered as the "standby" background job.
+//
+// Example Output:
+//   Returns: nil; node2 holds /var/lib/mcloud/standby/mcloud.db as of now
+//
+// Example Output (Error - Unknown Node):
+//   Returns: error("standby node node9 is not a cluster member: sql: no rows in result set")
+func (s *Shipper) Ship(ctx context.Context) error {
+	n, err := database.NewNodeRepository(s.db).GetByHostname(ctx, s.cfg.Standby.Node)
+	if err != nil {
+		return fmt.Errorf("standby node %s is not a cluster member: %w", s.cfg.Standby.Node, err)
+	}
+
+	snapshot := s.cfg.Database.DBPath + ".standby"
+	// VACUUM INTO refuses to overwrite an existing file
+	os.Remove(snapshot)
+	defer os.Remove(snapshot)
+
+	if _, err := s.db.ExecContext(ctx, "VACUUM INTO ?", snapshot); err != nil {
+		return fmt.Errorf("snapshot database: %w", err)
+	}
+
+	client, err := agent.NewClient(s.cfg, n.IP)
+	if err != nil {
+		return err
+	}
+	status, err := client.ShipStandbySnapshot(ctx, snapshot)
+	if err != nil {
+		return fmt.Errorf("ship snapshot to %s: %w", n.Hostname, err)
+	}
+
+	log.Debug("Shipped %d byte snapshot to %s (%s)", status.Size, n.Hostname, status.SHA256)
+	return nil
+}
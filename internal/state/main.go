@@ -5,8 +5,6 @@ import (
 	"os"
 	"time"
 
-	"mcloud/internal/config"
-
 	"gopkg.in/yaml.v3"
 )
 
@@ -68,6 +66,7 @@ func NewState() *State {
 // This function should only be called once when the node joins a cluster for the first time.
 //
 // Parameters:
+//   path  - The state file, normally cfg.StatePath
 //   initS - The initial state to persist (contains cluster ID, node info, etc.)
 //
 // Returns:
@@ -103,7 +102,7 @@ func NewState() *State {
 //   err = "node already initialized"
 //
 // Side Effect:
-//   Creates a YAML file at cfg.StatePath containing:
+//   Creates a YAML file at path containing:
 //   version: 1.0.0
 //   node:
 //     id: node-123
@@ -117,23 +116,17 @@ func NewState() *State {
 //     advertise_addr: 192.168.1.10:8443
 //   flags:
 //     initialized: true
-func (s *State) Initialize(initS *State) (current *State, err error) {
-	// Load configuration to get the state file path
-	cfg, err := config.Load()
-	if err != nil {
-		return nil, err
-	}
-
+func Initialize(path string, initS *State) (current *State, err error) {
 	// Check if state file already exists (node already initialized)
 	// os.Stat returns an error if file doesn't exist, which is what we want
-	_, err = os.Stat(cfg.StatePath)
+	_, err = os.Stat(path)
 	if err == nil {
 		// File exists - node is already initialized
 		return nil, errors.New("node already initialized")
 	}
 
 	// Create new state file
-	file, err := os.Create(cfg.StatePath)
+	file, err := os.Create(path)
 	if err != nil {
 		return nil, err
 	}
@@ -157,6 +150,9 @@ func (s *State) Initialize(initS *State) (current *State, err error) {
 // LoadState reads and deserializes the node's state from disk.
 // This function is used to restore the node's state after a restart.
 //
+// Parameters:
+//   path - The state file, normally cfg.StatePath
+//
 // Returns:
 //   - A pointer to the loaded State
 //   - An error if the file doesn't exist, can't be read, or contains invalid YAML
@@ -201,15 +197,9 @@ func (s *State) Initialize(initS *State) (current *State, err error) {
 // Example Output (File Not Found):
 //   state = nil
 //   err = "open /path/to/state.yaml: no such file or directory"
-func LoadState() (*State, error) {
-	// Load configuration to get the state file path
-	cfg, err := config.Load()
-	if err != nil {
-		return nil, err
-	}
-
+func LoadState(path string) (*State, error) {
 	// Read the entire state file into memory
-	data, err := os.ReadFile(cfg.StatePath)
+	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
@@ -225,14 +215,16 @@ func LoadState() (*State, error) {
 
 // SaveState updates the state file on disk with the provided state data.
 // This function is used to persist state changes after modifications (e.g., status updates, role changes).
-// Unlike Initialize, this function overwrites an existing state file.
+// Unlike Initialize, this function overwrites an existing state file. A running
+// daemon changes its state through a StateManager instead.
 //
 // Parameters:
+//   path - The state file, normally cfg.StatePath
 //   data - The updated state to persist
 //
 // Returns:
-//   - true if the state was successfully saved
-//   - false if any error occurred during the save operation
+//   - nil if the state was successfully saved
+//   - error if the file cannot be written
 //
 // Example Input:
 //   data = State{
@@ -255,16 +247,13 @@ func LoadState() (*State, error) {
 //   }
 //
 // Example Output (Success):
-//   return true
-//
-// Example Output (Error - Config Load Failed):
-//   return false
+//   return nil
 //
 // Example Output (Error - File Write Failed):
-//   return false
+//   return error("open /var/lib/mcloud/state.yaml: permission denied")
 //
 // Side Effect:
-//   Overwrites the YAML file at cfg.StatePath with:
+//   Overwrites the YAML file at path with:
 //   version: 0.1.0
 //   node:
 //     id: node-123
@@ -278,31 +267,7 @@ func LoadState() (*State, error) {
 //     advertise_addr: 192.168.1.10:8443
 //   flags:
 //     initialized: true
-func (s *State) SaveState(data State) (success bool, err error) {
-	// Load configuration to get the state file path
-	cfg, err := config.Load()
-	if err != nil {
-		return false, err
-	}
-
-	// Create or overwrite the state file
-	file, err := os.Create(cfg.StatePath)
-	if err != nil {
-		return false, err
-	}
-	defer file.Close()
-
-	// Serialize state to YAML format
-	yamlData, err := yaml.Marshal(data)
-	if err != nil {
-		return false, err
-	}
-
-	// Write YAML data to file
-	_, err = file.Write(yamlData)
-	if err != nil {
-		return false, err
-	}
-
-	return true, nil
+func SaveState(path string, data State) error {
+	// Replace the state file atomically, so a crash never leaves half of it
+	return writeAtomic(path, data)
 }
\ No newline at end of file
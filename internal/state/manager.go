@@ -0,0 +1,144 @@
+package state
+
+import (
+	"errors"
+	"os"
+	"sync"
+)
+
+// ErrNotInitialized is returned by the StateManager mutations when there is no
+// state yet, i.e. the node was never initialized or joined.
+var ErrNotInitialized = errors.New("node is not initialized")
+
+// StateManager is the state of a running daemon: it caches the state of its
+// Store in memory, serializes mutations and tells registered components about
+// changes. It is safe for concurrent use. The store is read once, on first use
+// or Reload; edits made to it behind the manager's back are not seen until then.
+type StateManager struct {
+	store Store
+
+	mu       sync.RWMutex
+	state    *State // nil until loaded
+	onChange []func(old, new State)
+}
+
+// NewStateManager returns a manager for the state in store, normally
+// NewFileStore(cfg.StatePath). Nothing is read until the first call.
+func NewStateManager(store Store) *StateManager {
+	return &StateManager{store: store}
+}
+
+// Get returns a copy of the cached state, loading it first if needed.
+//
+// Example Output (Not Initialized):
+//   Returns: (State{}, error("open /var/lib/mcloud/state.yaml: no such file or directory"))
+func (m *StateManager) Get() (State, error) {
+	m.mu.RLock()
+	if m.state != nil {
+		defer m.mu.RUnlock()
+		return *m.state, nil
+	}
+	m.mu.RUnlock()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.load(); err != nil {
+		return State{}, err
+	}
+	return *m.state, nil
+}
+
+// Reload re-reads the store, e.g. after mcloudctl rewrote the state file, and notifies
+// the listeners if it changed.
+func (m *StateManager) Reload() error {
+	m.mu.Lock()
+	old := m.state
+	m.state = nil
+	if err := m.load(); err != nil {
+		m.state = old
+		m.mu.Unlock()
+		return err
+	}
+	st := *m.state
+	listeners := m.onChange
+	m.mu.Unlock()
+
+	if old != nil && *old != st {
+		for _, fn := range listeners {
+			fn(*old, st)
+		}
+	}
+	return nil
+}
+
+// SetStatus records the node's status (e.g. "online", "maintenance").
+func (m *StateManager) SetStatus(status string) error {
+	return m.Update(func(s *State) { s.Node.Status = status })
+}
+
+// SetRole records the node's role (e.g. "leader", "worker").
+func (m *StateManager) SetRole(role string) error {
+	return m.Update(func(s *State) { s.Node.Role = role })
+}
+
+// Update applies fn to a copy of the state and saves the result to the store
+// (atomically, for a FileStore). The cache is only updated once the state is
+// saved; listeners are called afterwards, outside the lock, and only if fn
+// changed something.
+//
+// Example Input:
+//   m.Update(func(s *State) { s.Cluster.AdvertiseAddr = "10.0.0.5:9028" })
+//
+// Example Output (Error - Not Initialized):
+//   Returns: ErrNotInitialized
+func (m *StateManager) Update(fn func(s *State)) error {
+	m.mu.Lock()
+	if err := m.load(); err != nil {
+		m.mu.Unlock()
+		if errors.Is(err, os.ErrNotExist) {
+			return ErrNotInitialized
+		}
+		return err
+	}
+	old := *m.state
+	st := old
+	fn(&st)
+	if st == old {
+		m.mu.Unlock()
+		return nil
+	}
+	if err := m.store.Save(st); err != nil {
+		m.mu.Unlock()
+		return err
+	}
+	m.state = &st
+	listeners := m.onChange
+	m.mu.Unlock()
+
+	for _, fn := range listeners {
+		fn(old, st)
+	}
+	return nil
+}
+
+// OnChange registers fn to be called with the old and new state after every
+// change made through the manager (or noticed by Reload). fn runs on the
+// goroutine that made the change and must not block.
+func (m *StateManager) OnChange(fn func(old, new State)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onChange = append(m.onChange, fn)
+}
+
+// load fills the cache from the store if it is empty. m.mu must be held for writing.
+func (m *StateManager) load() error {
+	if m.state != nil {
+		return nil
+	}
+	st, err := m.store.Load()
+	if err != nil {
+		return err
+	}
+	m.state = st
+	return nil
+}
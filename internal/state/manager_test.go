@@ -0,0 +1,96 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestStateManager(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.yaml")
+	m := NewStateManager(NewFileStore(path))
+	if err := m.SetStatus("online"); !errors.Is(err, ErrNotInitialized) {
+		t.Fatalf("SetStatus without a state file: got %v", err)
+	}
+
+	if err := SaveState(path, State{Version: "1.0.0", Node: Node{ID: "n1", Role: "worker", Status: "online"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	changes := 0
+	m.OnChange(func(old, new State) {
+		mu.Lock()
+		changes++
+		mu.Unlock()
+	})
+
+	// Concurrent mutations are serialized and each one is persisted
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := m.SetStatus(fmt.Sprintf("status-%d", i)); err != nil {
+				t.Error(err)
+			}
+			if _, err := m.Get(); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if err := m.SetRole("leader"); err != nil {
+		t.Fatal(err)
+	}
+	if changes != 11 {
+		t.Errorf("got %d change notifications, want 11", changes)
+	}
+
+	cached, _ := m.Get()
+	onDisk, err := LoadState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *onDisk != cached || onDisk.Node.Role != "leader" {
+		t.Errorf("cached %+v, on disk %+v", cached, *onDisk)
+	}
+
+	// An unchanged value is neither written nor reported
+	if err := m.SetRole("leader"); err != nil || changes != 11 {
+		t.Errorf("no-op SetRole: %v, %d changes", err, changes)
+	}
+
+	// Reload picks up a file rewritten behind the manager's back
+	onDisk.Node.Status = "maintenance"
+	if err := SaveState(path, *onDisk); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if st, _ := m.Get(); st.Node.Status != "maintenance" || changes != 12 {
+		t.Errorf("after reload: got %+v, %d changes", st.Node, changes)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	m := NewStateManager(NewMemoryStore(nil))
+	if _, err := m.Get(); err == nil {
+		t.Fatal("Get from an empty store: want error")
+	}
+	if err := m.SetRole("leader"); !errors.Is(err, ErrNotInitialized) {
+		t.Fatalf("SetRole on an empty store: got %v", err)
+	}
+
+	store := NewMemoryStore(&State{Node: Node{ID: "n1", Role: "worker"}})
+	m = NewStateManager(store)
+	if err := m.SetRole("leader"); err != nil {
+		t.Fatal(err)
+	}
+	if st, err := store.Load(); err != nil || st.Node.Role != "leader" {
+		t.Errorf("saved state: got %+v, %v", st, err)
+	}
+}
@@ -0,0 +1,127 @@
+package state
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// machineIDPath identifies the installed OS; it survives losing mcloud's own
+// state but not a reinstall, which is what binds a bundle to this node.
+var machineIDPath = "/etc/machine-id"
+
+// RejoinBundle is everything a node needs to re-join its cluster after losing
+// state.yaml or its local database: where the manager is, how to reach it
+// (cluster CA, client and node certificates) and the node's re-join secret.
+// It is stored encrypted with a key derived from the machine ID.
+type RejoinBundle struct {
+	ClusterID   string    `json:"cluster_id"`
+	ClusterName string    `json:"cluster_name"`
+	NodeID      string    `json:"node_id"`
+	Hostname    string    `json:"hostname"`
+	ManagerURL  string    `json:"manager_url"`
+	CACert      string    `json:"ca_cert"`             // PEM
+	ClientCert  string    `json:"client_cert"`         // PEM
+	ClientKey   string    `json:"client_key"`          // PEM
+	NodeCert    string    `json:"node_cert,omitempty"` // PEM; empty in bundles written before node certificates
+	NodeKey     string    `json:"node_key,omitempty"`  // PEM
+	Secret      string    `json:"secret"`
+	IssuedAt    time.Time `json:"issued_at"`
+}
+
+// bundleKey derives the bundle encryption key from the machine ID.
+func bundleKey() ([]byte, error) {
+	id, err := os.ReadFile(machineIDPath)
+	if err != nil {
+		return nil, fmt.Errorf("read machine id: %w", err)
+	}
+	machineID := strings.TrimSpace(string(id))
+	if machineID == "" {
+		return nil, fmt.Errorf("machine id %s is empty", machineIDPath)
+	}
+	key := sha256.Sum256([]byte("mcloud-rejoin-bundle:" + machineID))
+	return key[:], nil
+}
+
+func bundleCipher() (cipher.AEAD, error) {
+	key, err := bundleKey()
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// SaveRejoinBundle encrypts b and writes it to path (root-only), replacing any
+// previous bundle atomically.
+//
+// Example Input:
+//   SaveRejoinBundle("/etc/mcloud/rejoin.bundle", &RejoinBundle{NodeID: "660e8400-...", Secret: "q3X...", ...})
+//
+// Example Output:
+//   File written: /etc/mcloud/rejoin.bundle (mode 0600, nonce || AES-GCM ciphertext)
+func SaveRejoinBundle(path string, b *RejoinBundle) error {
+	gcm, err := bundleCipher()
+	if err != nil {
+		return err
+	}
+	plaintext, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	sealed := gcm.Seal(nonce, nonce, plaintext, nil)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, sealed, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// LoadRejoinBundle reads and decrypts the bundle at path. It fails if the bundle
+// was written on another machine (or before the OS was reinstalled).
+//
+// Example Output (Error - Other Machine):
+//   Returns: error("cannot decrypt re-join bundle /etc/mcloud/rejoin.bundle: it was not created on this machine")
+func LoadRejoinBundle(path string) (*RejoinBundle, error) {
+	sealed, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := bundleCipher()
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("invalid re-join bundle")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decrypt re-join bundle %s: it was not created on this machine", path)
+	}
+
+	var b RejoinBundle
+	if err := json.Unmarshal(plaintext, &b); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
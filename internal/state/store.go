@@ -0,0 +1,98 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Store is where a StateManager keeps the state. Load fails with an error
+// wrapping os.ErrNotExist when there is no state yet.
+type Store interface {
+	Load() (*State, error)
+	Save(st State) error
+}
+
+// FileStore keeps the state in a YAML file, normally cfg.StatePath.
+type FileStore struct {
+	path string
+}
+
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+func (f *FileStore) Load() (*State, error) {
+	return LoadState(f.path)
+}
+
+func (f *FileStore) Save(st State) error {
+	return writeAtomic(f.path, st)
+}
+
+// MemoryStore keeps the state in memory, for tests and harnesses that run
+// without a state file.
+type MemoryStore struct {
+	mu    sync.Mutex
+	state *State
+}
+
+// NewMemoryStore returns a store holding st, or no state (an uninitialized node) if st is nil.
+func NewMemoryStore(st *State) *MemoryStore {
+	m := &MemoryStore{}
+	if st != nil {
+		copied := *st
+		m.state = &copied
+	}
+	return m
+}
+
+func (m *MemoryStore) Load() (*State, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.state == nil {
+		return nil, fmt.Errorf("no state in memory: %w", os.ErrNotExist)
+	}
+	copied := *m.state
+	return &copied, nil
+}
+
+func (m *MemoryStore) Save(st State) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.state = &st
+	return nil
+}
+
+// writeAtomic writes data to a temporary file next to path and renames it over
+// path, so readers never see a partly written state file.
+func writeAtomic(path string, data State) error {
+	yamlData, err := yaml.Marshal(data)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(yamlData); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
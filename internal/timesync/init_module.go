@@ -0,0 +1,10 @@
+package timesync
+
+import (
+	"net/http"
+)
+
+// InitModule registers the timesync routes served by handler.
+func InitModule(mux *http.ServeMux, handler *Handler) {
+	mux.HandleFunc("/timesync", handler.GetStatus)
+}
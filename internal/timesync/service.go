@@ -0,0 +1,184 @@
+// Package timesync manages chrony on all cluster nodes and watches for clock skew.
+// Members sync from the leader (or from a configured upstream), and every node's
+// offset is checked periodically; skewed or unsynchronised nodes raise events.
+package timesync
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+
+	"mcloud/internal/agent"
+	"mcloud/internal/config"
+	"mcloud/internal/constant"
+	"mcloud/internal/database"
+	"mcloud/internal/event"
+	"mcloud/pkg/logger"
+)
+
+var log = logger.Named("timesync")
+
+// defaultMaxOffsetMs is used when time_sync.max_offset_ms is not set.
+const defaultMaxOffsetMs = 500
+
+// NodeStatus is the clock state of one node.
+type NodeStatus struct {
+	NodeID   string                `json:"node_id"`
+	Hostname string                `json:"hostname"`
+	Status   *agent.TimeSyncStatus `json:"status,omitempty"`
+	Skewed   bool                  `json:"skewed"`
+	Error    string                `json:"error,omitempty"`
+}
+
+type Service struct {
+	db  *sql.DB
+	cfg *config.Config
+}
+
+func NewService(db *sql.DB, cfg *config.Config) *Service {
+	return &Service{
+		db:  db,
+		cfg: cfg,
+	}
+}
+
+func (s *Service) maxOffsetSeconds() float64 {
+	ms := s.cfg.TimeSync.MaxOffsetMs
+	if ms <= 0 {
+		ms = defaultMaxOffsetMs
+	}
+	return float64(ms) / 1000
+}
+
+// desiredConfig returns the chrony configuration for node n of a cluster.
+// With an upstream every node syncs from it; otherwise members sync from the leader,
+// which keeps its distribution pools and serves time to the members.
+func desiredConfig(n *database.Node, nodes []database.Node, upstream string) *agent.TimeSyncConfig {
+	cfg := &agent.TimeSyncConfig{}
+	if upstream != "" {
+		cfg.Servers = []string{upstream}
+	}
+
+	if n.Role == string(constant.RoleLeader) {
+		for _, m := range nodes {
+			if m.ID != n.ID {
+				cfg.Allow = append(cfg.Allow, m.IP)
+			}
+		}
+		cfg.LocalStratum = len(cfg.Allow) > 0
+		return cfg
+	}
+
+	if upstream == "" {
+		for _, m := range nodes {
+			if m.Role == string(constant.RoleLeader) {
+				cfg.Servers = []string{m.IP}
+			}
+		}
+	}
+	return cfg
+}
+
+// Status queries every node's agent for its clock state.
+func (s *Service) Status(ctx context.Context) ([]NodeStatus, error) {
+	clusters, err := database.NewClusterRepository(s.db).List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	items := []NodeStatus{}
+	for _, c := range clusters {
+		nodes, err := database.NewNodeRepository(s.db).ListByCluster(ctx, c.ID, database.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for i := range nodes {
+			items = append(items, s.nodeStatus(ctx, &nodes[i]))
+		}
+	}
+	return items, nil
+}
+
+func (s *Service) nodeStatus(ctx context.Context, n *database.Node) NodeStatus {
+	ns := NodeStatus{NodeID: n.ID, Hostname: n.Hostname}
+
+	client, err := agent.NewClient(s.cfg, n.IP)
+	if err != nil {
+		ns.Error = err.Error()
+		return ns
+	}
+	status, err := client.GetTimeSyncStatus(ctx)
+	if err != nil {
+		ns.Error = err.Error()
+		return ns
+	}
+
+	ns.Status = status
+	ns.Skewed = !status.Synchronized || math.Abs(status.OffsetSeconds) > s.maxOffsetSeconds()
+	return ns
+}
+
+// Reconcile is the periodic time sync job. When time sync is managed it pushes
+// the chrony configuration to every node; in all cases it checks clock skew and
+// records an event for each node that is unsynchronised or beyond max_offset_ms.
+func (s *Service) Reconcile(ctx context.Context) error {
+	clusters, err := database.NewClusterRepository(s.db).List(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range clusters {
+		nodes, err := database.NewNodeRepository(s.db).ListByCluster(ctx, c.ID, database.ListOptions{})
+		if err != nil {
+			return err
+		}
+
+		for i := range nodes {
+			n := &nodes[i]
+
+			if s.cfg.TimeSync.Managed {
+				if err := s.apply(ctx, n, desiredConfig(n, nodes, s.cfg.TimeSync.Upstream)); err != nil {
+					log.Error("Failed to configure time sync on node %s: %v", n.Hostname, err)
+				}
+			}
+
+			ns := s.nodeStatus(ctx, n)
+			switch {
+			case ns.Error != "":
+				log.Error("Failed to check clock on node %s: %s", n.Hostname, ns.Error)
+			case !ns.Status.Synchronized:
+				s.event(ctx, c.ID, n.ID, event.NodeClockUnsynchronized,
+					fmt.Sprintf("Node %s clock is not synchronised", n.Hostname))
+			case ns.Skewed:
+				s.event(ctx, c.ID, n.ID, event.NodeClockSkew,
+					fmt.Sprintf("Node %s clock is off by %.3fs (max %.3fs)", n.Hostname, ns.Status.OffsetSeconds, s.maxOffsetSeconds()))
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Service) apply(ctx context.Context, n *database.Node, cfg *agent.TimeSyncConfig) error {
+	client, err := agent.NewClient(s.cfg, n.IP)
+	if err != nil {
+		return err
+	}
+	changed, err := client.ApplyTimeSync(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	if changed {
+		s.event(ctx, n.ClusterID, n.ID, event.NodeTimeSyncConfigured,
+			fmt.Sprintf("Configured time sync on node %s", n.Hostname))
+	}
+	return nil
+}
+
+func (s *Service) event(ctx context.Context, clusterID string, nodeID string, eventType string, message string) {
+	e := event.New(eventType, message, nil)
+	e.ClusterID, e.NodeID = &clusterID, &nodeID
+	if err := database.NewEventRepository(s.db).Create(ctx, e); err != nil {
+		log.Error("Failed to record event %s: %v", eventType, err)
+	}
+}
@@ -0,0 +1,117 @@
+// Package tracing sets up OpenTelemetry for the mcloud binaries. Setup installs
+// a tracer provider exporting over OTLP/gRPC (see config.Tracing); the helpers
+// below instrument the HTTP servers and clients and the gRPC command stream so
+// a trace follows a request from mcloudctl through mcloudd to the agents.
+// Database queries (see database.Connect) and shell-outs (see package
+// commander) record their spans through the global provider as well.
+//
+// Without an endpoint nothing is exported, but trace context is still passed
+// on, so a traced caller's trace continues through an untraced process.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"mcloud/internal/config"
+	"mcloud/internal/constant"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"google.golang.org/grpc"
+)
+
+// Setup installs the global tracer provider of service (e.g. "mcloudd") and
+// returns the function that flushes and stops it at exit. Tracing is off, and
+// shutdown a no-op, when neither cfg.Endpoint nor OTEL_EXPORTER_OTLP_ENDPOINT
+// is set.
+//
+// Example Input:
+//   Setup(ctx, "mcloudd", config.Tracing{Endpoint: "otel-collector:4317", Insecure: true})
+//
+// Example Output (Error - Bad Endpoint):
+//   Returns: (nil, error("create OTLP exporter: ..."))
+func Setup(ctx context.Context, service string, cfg config.Tracing) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if cfg.Endpoint == "" && os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	var opts []otlptracegrpc.Option
+	if cfg.Endpoint != "" {
+		opts = append(opts, otlptracegrpc.WithEndpoint(cfg.Endpoint))
+	}
+	if cfg.Insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+	exporter, err := otlptracegrpc.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("create OTLP exporter: %w", err)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithSchemaURL(semconv.SchemaURL),
+		resource.WithAttributes(semconv.ServiceName(service), semconv.ServiceVersion(constant.AppVersion)),
+		resource.WithHost(),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("describe %s for tracing: %w", service, err)
+	}
+
+	ratio := cfg.SampleRatio
+	if ratio <= 0 || ratio > 1 {
+		ratio = 1
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
+
+// Handler traces every request served by h, a mux of the operation (e.g.
+// "mcloudd"). Spans are named after the matched route, e.g. "GET /nodes/{id}",
+// so they group by endpoint rather than by URL.
+func Handler(h http.Handler, operation string) http.Handler {
+	return otelhttp.NewHandler(h, operation, otelhttp.WithSpanNameFormatter(spanName))
+}
+
+// spanName names the span of an HTTP request after its route, once the mux
+// has matched one, and after its method until then.
+func spanName(operation string, r *http.Request) string {
+	switch {
+	case r.Pattern == "":
+		return r.Method
+	case strings.Contains(r.Pattern, " "):
+		return r.Pattern
+	}
+	return r.Method + " " + r.Pattern
+}
+
+// Transport traces the requests sent through base and passes the trace
+// context on to the server.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	return otelhttp.NewTransport(base)
+}
+
+// ServerOption traces the calls and streams a gRPC server serves.
+func ServerOption() grpc.ServerOption {
+	return grpc.StatsHandler(otelgrpc.NewServerHandler())
+}
+
+// DialOption traces the calls and streams of a gRPC client connection.
+func DialOption() grpc.DialOption {
+	return grpc.WithStatsHandler(otelgrpc.NewClientHandler())
+}
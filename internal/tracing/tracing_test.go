@@ -0,0 +1,63 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"mcloud/internal/config"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestHandlerNamesSpansAfterRoutes(t *testing.T) {
+	if _, err := Setup(context.Background(), "test", config.Tracing{}); err != nil {
+		t.Fatal(err)
+	}
+	rec := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	defer otel.SetTracerProvider(prev)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /nodes/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/join", func(w http.ResponseWriter, r *http.Request) {})
+	srv := httptest.NewServer(Handler(mux, "test"))
+	defer srv.Close()
+
+	// A traced client: the server spans must join its trace
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "caller")
+	client := &http.Client{Transport: Transport(http.DefaultTransport)}
+	for _, path := range []string{"/nodes/node-1", "/join", "/missing"} {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+path, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	parent.End()
+
+	var names []string
+	for _, s := range rec.Ended() {
+		if s.SpanKind().String() != "server" {
+			continue
+		}
+		names = append(names, s.Name())
+		if s.Parent().TraceID() != parent.SpanContext().TraceID() {
+			t.Errorf("span %q is not part of the caller's trace", s.Name())
+		}
+	}
+	want := []string{"GET /nodes/{id}", "GET /join", "GET"}
+	if len(names) != len(want) {
+		t.Fatalf("server spans = %q, want %q", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("server span %d = %q, want %q", i, names[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,256 @@
+// Package upgrade fetches and verifies the release binaries `mcloudctl upgrade`
+// swaps in: mcloudd and mcloud-agent are published with a SHA256SUMS file
+// signed with ed25519 (SHA256SUMS.sig) under <release_url>/<version>/.
+//
+// Release layout:
+//   https://releases.example.com/mcloud/v0.2.0/mcloudd-linux-amd64
+//   https://releases.example.com/mcloud/v0.2.0/mcloud-agent-linux-amd64
+//   https://releases.example.com/mcloud/v0.2.0/SHA256SUMS        "<sha256>  mcloudd-linux-amd64" per line
+//   https://releases.example.com/mcloud/v0.2.0/SHA256SUMS.sig    ed25519 signature of SHA256SUMS (raw or base64)
+package upgrade
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"mcloud/internal/config"
+	"mcloud/pkg/reason"
+)
+
+var (
+	ErrChecksumMismatch = reason.New(reason.ChecksumMismatch, "checksum mismatch")
+	ErrNoChecksum       = reason.New(reason.NoChecksum, "no checksum for binary")
+	ErrBadSignature     = reason.New(reason.BadReleaseSignature, "invalid signature of SHA256SUMS")
+	ErrUnsigned         = reason.New(reason.ReleaseUnsigned, "release signature cannot be verified")
+	ErrVersionMismatch  = reason.New(reason.BinaryVersionMismatch, "binary reports a different version")
+)
+
+// maxSumsSize bounds SHA256SUMS and its signature, which are a few lines.
+const maxSumsSize = 1 << 20
+
+// Asset returns the release file name of a binary for this host's
+// architecture, e.g. mcloudd-linux-amd64.
+func Asset(binary string) string {
+	return binary + "-linux-" + runtime.GOARCH
+}
+
+// Tag returns version as a release tag: "0.2.0" and "v0.2.0" are both "v0.2.0".
+func Tag(version string) string {
+	return "v" + strings.TrimPrefix(version, "v")
+}
+
+// Download fetches the binaries of a release into dir and verifies each
+// against SHA256SUMS, itself verified against SHA256SUMS.sig with
+// cfg.PublicKeyPath. Without a public key the release is refused unless
+// insecure is set, e.g. by `mcloudctl upgrade --insecure-skip-signature`.
+// Returns the downloaded file of each binary.
+//
+// Example Input:
+//   Download(ctx, config.Upgrade{ReleaseURL: "https://releases.example.com/mcloud", PublicKeyPath: "/etc/mcloud/release.pub"}, "0.2.0", []string{"mcloudd"}, "/tmp/mcloud-upgrade-123", false)
+//
+// Example Output:
+//   {"mcloudd": "/tmp/mcloud-upgrade-123/mcloudd-linux-amd64"}
+//
+// Example Output (Error):
+//   checksum mismatch: mcloudd-linux-amd64 has sha256 9f2c..., SHA256SUMS lists 41ab...
+//   release signature cannot be verified: upgrade.public_key_path is not set in /etc/mcloud/config.yaml; ...
+func Download(ctx context.Context, cfg config.Upgrade, version string, binaries []string, dir string, insecure bool) (map[string]string, error) {
+	if cfg.ReleaseURL == "" {
+		return nil, fmt.Errorf("upgrade.release_url is not set in %s; pass a local binary instead", config.Path())
+	}
+	if cfg.PublicKeyPath == "" && !insecure {
+		return nil, fmt.Errorf("%w: upgrade.public_key_path is not set in %s; set it, pass a local binary with its --sha256, or --insecure-skip-signature", ErrUnsigned, config.Path())
+	}
+	base := strings.TrimSuffix(cfg.ReleaseURL, "/") + "/" + Tag(version) + "/"
+
+	sums, err := fetch(ctx, base+"SHA256SUMS", maxSumsSize)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.PublicKeyPath != "" {
+		sig, err := fetch(ctx, base+"SHA256SUMS.sig", maxSumsSize)
+		if err != nil {
+			return nil, err
+		}
+		if err := VerifySignature(sums, sig, cfg.PublicKeyPath); err != nil {
+			return nil, err
+		}
+	}
+	checksums, err := ParseChecksums(sums)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make(map[string]string, len(binaries))
+	for _, binary := range binaries {
+		asset := Asset(binary)
+		want, ok := checksums[asset]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s is not in SHA256SUMS of %s", ErrNoChecksum, asset, Tag(version))
+		}
+		path := filepath.Join(dir, asset)
+		if err := fetchFile(ctx, base+asset, path); err != nil {
+			return nil, err
+		}
+		if err := VerifyFile(path, want); err != nil {
+			return nil, err
+		}
+		paths[binary] = path
+	}
+	return paths, nil
+}
+
+// fetch returns the body of a GET, up to limit bytes.
+func fetch(ctx context.Context, url string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, limit))
+}
+
+// fetchFile writes the body of a GET to path with mode 0755.
+func fetchFile(ctx context.Context, url, path string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0755)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ParseChecksums reads a SHA256SUMS file as written by sha256sum: one
+// "<hex digest>  <file>" per line, the file possibly marked binary with '*'.
+//
+// Example Input:
+//   9f2c...e1  mcloudd-linux-amd64
+//   41ab...07 *mcloud-agent-linux-amd64
+//
+// Example Output:
+//   {"mcloudd-linux-amd64": "9f2c...e1", "mcloud-agent-linux-amd64": "41ab...07"}
+func ParseChecksums(data []byte) (map[string]string, error) {
+	sums := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		digest, file, ok := strings.Cut(line, " ")
+		file = strings.TrimPrefix(strings.TrimSpace(file), "*")
+		if _, err := hex.DecodeString(digest); !ok || err != nil || len(digest) != sha256.Size*2 || file == "" {
+			return nil, fmt.Errorf("invalid SHA256SUMS line %d: %q", n, line)
+		}
+		sums[file] = strings.ToLower(digest)
+	}
+	return sums, scanner.Err()
+}
+
+// VerifyFile checks that the SHA-256 digest of the file at path is want (hex).
+func VerifyFile(path, want string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != strings.ToLower(want) {
+		return fmt.Errorf("%w: %s has sha256 %s, expected %s", ErrChecksumMismatch, filepath.Base(path), got, want)
+	}
+	return nil
+}
+
+// VerifySignature checks that sig, raw or base64, is an ed25519 signature of
+// data by the PEM public key at publicKeyPath (as written by
+// `openssl pkey -pubout`).
+func VerifySignature(data, sig []byte, publicKeyPath string) error {
+	keyPEM, err := os.ReadFile(publicKeyPath)
+	if err != nil {
+		return err
+	}
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return fmt.Errorf("no PEM public key in %s", publicKeyPath)
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("invalid public key %s: %w", publicKeyPath, err)
+	}
+	key, ok := parsed.(ed25519.PublicKey)
+	if !ok {
+		return fmt.Errorf("public key %s is not ed25519", publicKeyPath)
+	}
+
+	if len(sig) != ed25519.SignatureSize {
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+		if err != nil {
+			return fmt.Errorf("%w: neither raw nor base64", ErrBadSignature)
+		}
+		sig = decoded
+	}
+	if !ed25519.Verify(key, data, sig) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// CheckVersion runs `<path> --version` and checks that the binary reports
+// version, so a binary of the wrong release (or that does not run on this
+// host) is never swapped in.
+//
+// Example Output (Error):
+//   binary reports a different version: mcloudd-linux-amd64 is 0.1.9, expected 0.2.0
+func CheckVersion(ctx context.Context, path, version string) error {
+	out, err := exec.CommandContext(ctx, path, "--version").Output()
+	if err != nil {
+		return fmt.Errorf("run %s --version: %w", filepath.Base(path), err)
+	}
+	got := strings.TrimPrefix(strings.TrimSpace(string(out)), "v")
+	if want := strings.TrimPrefix(version, "v"); got != want {
+		return fmt.Errorf("%w: %s is %s, expected %s", ErrVersionMismatch, filepath.Base(path), got, want)
+	}
+	return nil
+}
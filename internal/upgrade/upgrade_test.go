@@ -0,0 +1,129 @@
+package upgrade
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"mcloud/internal/config"
+)
+
+func TestParseChecksums(t *testing.T) {
+	digest := hex.EncodeToString(make([]byte, sha256.Size))
+	sums, err := ParseChecksums([]byte(digest + "  mcloudd-linux-amd64\n\n" + digest + " *mcloud-agent-linux-amd64\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sums) != 2 || sums["mcloudd-linux-amd64"] != digest || sums["mcloud-agent-linux-amd64"] != digest {
+		t.Fatalf("got %v", sums)
+	}
+
+	for _, bad := range []string{"mcloudd-linux-amd64", "abc  mcloudd-linux-amd64", digest} {
+		if _, err := ParseChecksums([]byte(bad)); err == nil {
+			t.Errorf("ParseChecksums(%q): want error", bad)
+		}
+	}
+}
+
+// testKey writes a new ed25519 public key to a temporary file and returns its
+// path and the private key.
+func testKey(t *testing.T) (string, ed25519.PrivateKey) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPath := filepath.Join(t.TempDir(), "release.pub")
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return keyPath, priv
+}
+
+func TestVerifySignature(t *testing.T) {
+	keyPath, priv := testKey(t)
+
+	data := []byte("sums")
+	sig := ed25519.Sign(priv, data)
+	if err := VerifySignature(data, sig, keyPath); err != nil {
+		t.Fatalf("raw signature: %v", err)
+	}
+	if err := VerifySignature(data, []byte(base64.StdEncoding.EncodeToString(sig)+"\n"), keyPath); err != nil {
+		t.Fatalf("base64 signature: %v", err)
+	}
+	if err := VerifySignature([]byte("tampered"), sig, keyPath); !errors.Is(err, ErrBadSignature) {
+		t.Fatalf("tampered data: got %v, want ErrBadSignature", err)
+	}
+}
+
+func TestDownload(t *testing.T) {
+	keyPath, priv := testKey(t)
+	binary := []byte("#!/bin/sh\necho 0.2.0\n")
+	sum := sha256.Sum256(binary)
+	sums := hex.EncodeToString(sum[:]) + "  " + Asset("mcloudd") + "\n"
+	sig := ed25519.Sign(priv, []byte(sums))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v0.2.0/SHA256SUMS", "/v0.3.0/SHA256SUMS", "/v0.4.0/SHA256SUMS":
+			w.Write([]byte(sums))
+		case "/v0.2.0/SHA256SUMS.sig", "/v0.3.0/SHA256SUMS.sig":
+			w.Write(sig)
+		case "/v0.4.0/SHA256SUMS.sig":
+			w.Write(ed25519.Sign(priv, []byte("other sums")))
+		case "/v0.2.0/" + Asset("mcloudd"), "/v0.4.0/" + Asset("mcloudd"):
+			w.Write(binary)
+		case "/v0.3.0/" + Asset("mcloudd"):
+			w.Write([]byte("tampered"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	cfg := config.Upgrade{ReleaseURL: srv.URL + "/", PublicKeyPath: keyPath}
+	ctx := context.Background()
+
+	paths, err := Download(ctx, cfg, "0.2.0", []string{"mcloudd"}, t.TempDir(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckVersion(ctx, paths["mcloudd"], "v0.2.0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckVersion(ctx, paths["mcloudd"], "0.3.0"); !errors.Is(err, ErrVersionMismatch) {
+		t.Fatalf("CheckVersion: got %v, want ErrVersionMismatch", err)
+	}
+
+	if _, err := Download(ctx, cfg, "v0.3.0", []string{"mcloudd"}, t.TempDir(), false); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("tampered binary: got %v, want ErrChecksumMismatch", err)
+	}
+	if _, err := Download(ctx, cfg, "v0.2.0", []string{"mcloud-agent"}, t.TempDir(), false); !errors.Is(err, ErrNoChecksum) {
+		t.Fatalf("unlisted binary: got %v, want ErrNoChecksum", err)
+	}
+	if _, err := Download(ctx, cfg, "v0.4.0", []string{"mcloudd"}, t.TempDir(), false); !errors.Is(err, ErrBadSignature) {
+		t.Fatalf("bad signature: got %v, want ErrBadSignature", err)
+	}
+
+	// Without a public key the release is refused unless the caller opts out
+	unsigned := config.Upgrade{ReleaseURL: srv.URL + "/"}
+	if _, err := Download(ctx, unsigned, "v0.2.0", []string{"mcloudd"}, t.TempDir(), false); !errors.Is(err, ErrUnsigned) {
+		t.Fatalf("no public key: got %v, want ErrUnsigned", err)
+	}
+	if _, err := Download(ctx, unsigned, "v0.2.0", []string{"mcloudd"}, t.TempDir(), true); err != nil {
+		t.Fatalf("insecure: got %v", err)
+	}
+}
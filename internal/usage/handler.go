@@ -0,0 +1,73 @@
+package usage
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"mcloud/internal/database"
+	"mcloud/pkg/reason"
+)
+
+// defaultRangeDays is the number of days returned when from is not given.
+const defaultRangeDays = 30
+
+type ListUsageResponse struct {
+	From  string                 `json:"from"`
+	To    string                 `json:"to"`
+	Items []database.UsageRecord `json:"items"`
+}
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(s *Service) *Handler {
+	return &Handler{service: s}
+}
+
+// ListUsage handles GET /usage?from=2026-01-01&to=2026-01-31&subject_type=project.
+// Days are UTC dates; the default range is the last 30 days including today.
+func (h *Handler) ListUsage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	to := time.Now().UTC()
+	if v := r.URL.Query().Get("to"); v != "" {
+		t, err := time.Parse(database.UsageDayFormat, v)
+		if err != nil {
+			reason.HTTPErrorf(w, 400, "invalid to: want YYYY-MM-DD")
+			return
+		}
+		to = t
+	}
+	from := to.AddDate(0, 0, -(defaultRangeDays - 1))
+	if v := r.URL.Query().Get("from"); v != "" {
+		t, err := time.Parse(database.UsageDayFormat, v)
+		if err != nil {
+			reason.HTTPErrorf(w, 400, "invalid from: want YYYY-MM-DD")
+			return
+		}
+		from = t
+	}
+
+	items, err := h.service.List(r.Context(), from, to, r.URL.Query().Get("subject_type"))
+	if err != nil {
+		if errors.Is(err, ErrInvalidSubjectType) {
+			reason.HTTPError(w, err, 400)
+			return
+		}
+		reason.HTTPError(w, err, 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ListUsageResponse{
+		From:  from.Format(database.UsageDayFormat),
+		To:    to.Format(database.UsageDayFormat),
+		Items: items,
+	})
+}
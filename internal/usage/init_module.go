@@ -0,0 +1,10 @@
+package usage
+
+import (
+	"net/http"
+)
+
+// InitModule registers the usage routes served by handler.
+func InitModule(mux *http.ServeMux, handler *Handler) {
+	mux.HandleFunc("/usage", handler.ListUsage)
+}
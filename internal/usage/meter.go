@@ -0,0 +1,111 @@
+package usage
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"sync"
+	"time"
+
+	"mcloud/internal/auth"
+	"mcloud/internal/database"
+)
+
+// FlushInterval is how often counted API calls are written to the daily rollups.
+const FlushInterval = time.Minute
+
+// anonymousIdentity is counted for callers without a client certificate.
+const anonymousIdentity = "anonymous"
+
+type meterKey struct {
+	day      string
+	identity string
+}
+
+// Meter counts API calls per client identity in memory and periodically adds
+// them to the daily rollups, so requests never wait on a usage write.
+// Every manager runs its own Meter; the rollups are additive.
+type Meter struct {
+	db *sql.DB
+
+	mu     sync.Mutex
+	counts map[meterKey]int
+}
+
+func NewMeter(db *sql.DB) *Meter {
+	return &Meter{
+		db:     db,
+		counts: map[meterKey]int{},
+	}
+}
+
+// Middleware counts every request, including rejected ones, against the caller's identity.
+func (m *Meter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity := auth.ClientIdentity(r)
+		if identity == "" {
+			identity = anonymousIdentity
+		}
+		key := meterKey{day: time.Now().UTC().Format(database.UsageDayFormat), identity: identity}
+
+		m.mu.Lock()
+		m.counts[key]++
+		m.mu.Unlock()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Run flushes the counters every FlushInterval until ctx is cancelled, then flushes once more.
+func (m *Meter) Run(ctx context.Context) {
+	ticker := time.NewTicker(FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// The request context is gone; use a short one for the final write
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := m.Flush(flushCtx); err != nil {
+				log.Error("Failed to flush API usage: %v", err)
+			}
+			return
+		case <-ticker.C:
+			if err := m.Flush(ctx); err != nil {
+				log.Error("Failed to flush API usage: %v", err)
+			}
+		}
+	}
+}
+
+// Flush adds the counted calls to the daily rollups. On failure the counts are
+// kept and retried on the next flush.
+func (m *Meter) Flush(ctx context.Context) error {
+	m.mu.Lock()
+	counts := m.counts
+	m.counts = map[meterKey]int{}
+	m.mu.Unlock()
+
+	if len(counts) == 0 {
+		return nil
+	}
+
+	err := database.WithTx(ctx, m.db, func(tx *sql.Tx) error {
+		repo := database.NewUsageRepositoryTx(tx)
+		for k, n := range counts {
+			if err := repo.Add(ctx, k.day, SubjectIdentity, k.identity, MetricAPICalls, float64(n)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		m.mu.Lock()
+		for k, n := range counts {
+			m.counts[k] += n
+		}
+		m.mu.Unlock()
+	}
+	return err
+}
@@ -0,0 +1,76 @@
+// Package version describes the build of the running binary and decides
+// whether two mcloud builds can work together: mcloudd, mcloud-agent and
+// mcloudctl of the same major version are compatible, across major versions
+// they are not.
+package version
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"mcloud/internal/constant"
+	"mcloud/pkg/api"
+	"mcloud/pkg/reason"
+)
+
+// ErrIncompatible is returned when two builds have different major versions.
+var ErrIncompatible = reason.New(reason.IncompatibleVersion, "incompatible versions")
+
+// Info is the build of a binary, served by mcloudd at GET /version.
+//
+// Example JSON:
+//   {"version": "0.2.0", "commit": "3f9c2ab", "build_date": "2026-10-16T08:12:00Z", "go_version": "go1.24.4", "api_version": "1"}
+type Info struct {
+	Version    string `json:"version"`
+	Commit     string `json:"commit"`
+	BuildDate  string `json:"build_date"`
+	GoVersion  string `json:"go_version"`
+	APIVersion string `json:"api_version"`
+}
+
+// Current returns the build of the running binary.
+func Current() Info {
+	return Info{
+		Version:    constant.AppVersion,
+		Commit:     constant.AppCommit,
+		BuildDate:  constant.AppBuildDate,
+		GoVersion:  runtime.Version(),
+		APIVersion: api.Version,
+	}
+}
+
+// Major returns the major version of v, e.g. 1 for "v1.4.2".
+func Major(v string) (int, error) {
+	major, _, _ := strings.Cut(strings.TrimPrefix(strings.TrimSpace(v), "v"), ".")
+	n, err := strconv.Atoi(major)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid version %q", v)
+	}
+	return n, nil
+}
+
+// Compatible checks that a peer of the given version can work with this
+// binary. A peer too old to report its version ("") is let through.
+//
+// Example Output (Error):
+//   Compatible("manager", "2.0.0")  =>  ErrIncompatible: manager runs 2.0.0, this binary is 1.4.2 (major versions 2 and 1 differ)
+func Compatible(peer, peerVersion string) error {
+	if peerVersion == "" {
+		return nil
+	}
+	want, err := Major(constant.AppVersion)
+	if err != nil {
+		return err
+	}
+	got, err := Major(peerVersion)
+	if err != nil {
+		return fmt.Errorf("%w: %s reports %v", ErrIncompatible, peer, err)
+	}
+	if got != want {
+		return fmt.Errorf("%w: %s runs %s, this binary is %s (major versions %d and %d differ)",
+			ErrIncompatible, peer, peerVersion, constant.AppVersion, got, want)
+	}
+	return nil
+}
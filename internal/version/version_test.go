@@ -0,0 +1,34 @@
+package version
+
+import (
+	"errors"
+	"testing"
+
+	"mcloud/internal/constant"
+)
+
+func TestCompatible(t *testing.T) {
+	defer func(v string) { constant.AppVersion = v }(constant.AppVersion)
+	constant.AppVersion = "1.4.2"
+
+	tests := []struct {
+		peer string
+		ok   bool
+	}{
+		{"", true},
+		{"1.0.0", true},
+		{"v1.9.3", true},
+		{"2.0.0", false},
+		{"0.9.0", false},
+		{"dev", false},
+	}
+	for _, tt := range tests {
+		err := Compatible("agent", tt.peer)
+		if tt.ok && err != nil {
+			t.Errorf("Compatible(%q) = %v, want nil", tt.peer, err)
+		}
+		if !tt.ok && !errors.Is(err, ErrIncompatible) {
+			t.Errorf("Compatible(%q) = %v, want ErrIncompatible", tt.peer, err)
+		}
+	}
+}
@@ -0,0 +1,15 @@
+package volume
+
+import (
+	"net/http"
+)
+
+// InitModule registers the volume routes served by handler.
+func InitModule(mux *http.ServeMux, handler *Handler) {
+	mux.HandleFunc("GET /volumes", handler.ListVolumes)
+	mux.HandleFunc("POST /volumes", handler.CreateVolume)
+	mux.HandleFunc("GET /volumes/{id}", handler.GetVolume)
+	mux.HandleFunc("DELETE /volumes/{id}", handler.DeleteVolume)
+	mux.HandleFunc("POST /volumes/{id}/attach", handler.AttachVolume)
+	mux.HandleFunc("POST /volumes/{id}/detach", handler.DetachVolume)
+}
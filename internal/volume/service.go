@@ -0,0 +1,259 @@
+// Package volume manages volumes: Ceph RBD-backed LXD custom volumes that
+// outlive the workloads they are attached to. Each volume is recorded in the
+// database with its pool, size and the workload it is attached to, if any;
+// Ceph lets a volume follow its workload to any node, but only one workload
+// may use it at a time.
+package volume
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+
+	"mcloud/internal/database"
+	"mcloud/internal/project"
+	"mcloud/pkg/logger"
+	"mcloud/pkg/reason"
+	"mcloud/pkg/utils"
+	"mcloud/services/lxd"
+)
+
+var log = logger.Named("volume")
+
+var (
+	ErrVolumeNotFound   = reason.New(reason.VolumeNotFound, "volume not found")
+	ErrVolumeExists     = reason.New(reason.VolumeExists, "a volume with this name already exists in the pool")
+	ErrVolumeInUse      = reason.New(reason.VolumeInUse, "volume is attached to a workload")
+	ErrInvalidVolume    = reason.New(reason.InvalidVolume, "invalid volume request")
+	ErrWorkloadNotFound = reason.New(reason.WorkloadNotFound, "workload not found")
+)
+
+var nameRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+type Service struct {
+	db       *sql.DB
+	projects *project.Service
+}
+
+// CreateRequest defines a new volume. Pool defaults to the pool of the
+// default profile's root disk and must be Ceph-backed.
+//
+// Example JSON:
+//   {"name": "pgdata", "pool": "remote", "size_gb": 50}
+type CreateRequest struct {
+	Name   string `json:"name"`
+	Pool   string `json:"pool,omitempty"`
+	SizeGB int    `json:"size_gb"`
+}
+
+// AttachRequest attaches a volume to a workload, mounted at Path.
+//
+// Example JSON:
+//   {"workload_id": "550e8400-e29b-41d4-a716-446655440000", "path": "/var/lib/postgresql"}
+type AttachRequest struct {
+	WorkloadID string `json:"workload_id"`
+	Path       string `json:"path"`
+}
+
+func NewService(db *sql.DB, projects *project.Service) *Service {
+	return &Service{db: db, projects: projects}
+}
+
+// Create creates the volume in LXD and records it.
+//
+// Example Output (Error):
+//   {"name": "pgdata", "pool": "remote", ...}  =>  ErrVolumeExists: remote/pgdata
+//   {"pool": "local", ...}                     =>  ErrInvalidVolume: pool local is not Ceph-backed (driver zfs)
+func (s *Service) Create(ctx context.Context, req *CreateRequest, actor *string) (*database.Volume, error) {
+	if !nameRegexp.MatchString(req.Name) {
+		return nil, fmt.Errorf("%w: name must match [a-z0-9][a-z0-9-]*", ErrInvalidVolume)
+	}
+	if req.SizeGB <= 0 {
+		return nil, fmt.Errorf("%w: size_gb must be positive", ErrInvalidVolume)
+	}
+
+	pool := req.Pool
+	if pool == "" {
+		var err error
+		if pool, err = lxd.DefaultStoragePool(); err != nil {
+			return nil, err
+		}
+	}
+	repo := database.NewVolumeRepository(s.db)
+	if _, err := repo.GetByName(ctx, pool, req.Name); err == nil {
+		return nil, fmt.Errorf("%w: %s/%s", ErrVolumeExists, pool, req.Name)
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	driver, err := lxd.StoragePoolDriver(pool)
+	if err != nil {
+		return nil, err
+	}
+	if driver != "ceph" {
+		return nil, fmt.Errorf("%w: pool %s is not Ceph-backed (driver %s)", ErrInvalidVolume, pool, driver)
+	}
+
+	if err := lxd.CreateVolume(ctx, pool, req.Name, fmt.Sprintf("%dGiB", req.SizeGB)); err != nil {
+		return nil, err
+	}
+	v := &database.Volume{ID: utils.GenerateUUID(), Name: req.Name, Pool: pool, SizeGB: req.SizeGB, CreateUserID: actor}
+	if err := repo.Create(ctx, v); err != nil {
+		return nil, err
+	}
+	log.Info("Volume %s/%s created (%d GiB)", pool, req.Name, req.SizeGB)
+	return repo.GetByID(ctx, v.ID)
+}
+
+// List returns all volumes.
+func (s *Service) List(ctx context.Context) ([]database.Volume, error) {
+	volumes, err := database.NewVolumeRepository(s.db).List(ctx)
+	if volumes == nil {
+		volumes = []database.Volume{}
+	}
+	return volumes, err
+}
+
+// Get returns a volume.
+func (s *Service) Get(ctx context.Context, id string) (*database.Volume, error) {
+	v, err := database.NewVolumeRepository(s.db).GetByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrVolumeNotFound, id)
+	}
+	return v, err
+}
+
+// Authorize returns project.ErrProjectDenied unless identity is a member of
+// the project of the workload v is attached to. A detached volume belongs to
+// no project, so every identity may read it.
+func (s *Service) Authorize(ctx context.Context, v *database.Volume, identity string) error {
+	if v.WorkloadID == nil {
+		return nil
+	}
+	w, err := database.NewWorkloadRepository(s.db).GetByID(ctx, *v.WorkloadID)
+	if errors.Is(err, sql.ErrNoRows) {
+		// The workload is gone; Detach clears the attachment
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return s.projects.Authorize(ctx, w.Project, identity, database.ProjectRoleMember)
+}
+
+// Readable returns the volumes identity may read (see Authorize).
+func (s *Service) Readable(ctx context.Context, identity string) ([]database.Volume, error) {
+	volumes, err := s.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	readable := []database.Volume{}
+	for _, v := range volumes {
+		err := s.Authorize(ctx, &v, identity)
+		if errors.Is(err, project.ErrProjectDenied) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		readable = append(readable, v)
+	}
+	return readable, nil
+}
+
+// Attach adds a volume to a workload's instance, mounted at req.Path. The
+// actor must be a member of the workload's project.
+//
+// Example Output (Error):
+//   Volume attached elsewhere  =>  ErrVolumeInUse: pgdata is attached to workload 660e8400-...
+//   {"path": "data"}           =>  ErrInvalidVolume: path must be absolute
+func (s *Service) Attach(ctx context.Context, id string, req *AttachRequest, actor *string) (*database.Volume, error) {
+	if !path.IsAbs(req.Path) || path.Clean(req.Path) == "/" {
+		return nil, fmt.Errorf("%w: path must be absolute and not /", ErrInvalidVolume)
+	}
+	v, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if v.WorkloadID != nil {
+		return nil, fmt.Errorf("%w: %s is attached to workload %s", ErrVolumeInUse, v.Name, *v.WorkloadID)
+	}
+	w, err := database.NewWorkloadRepository(s.db).GetByID(ctx, req.WorkloadID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrWorkloadNotFound, req.WorkloadID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.projects.Authorize(ctx, w.Project, deref(actor), database.ProjectRoleMember); err != nil {
+		return nil, err
+	}
+
+	mountPath := path.Clean(req.Path)
+	if err := lxd.AttachVolume(lxd.WithProject(ctx, w.Project), v.Pool, v.Name, w.Name, mountPath); err != nil {
+		return nil, err
+	}
+	repo := database.NewVolumeRepository(s.db)
+	if err := repo.SetAttachment(ctx, v.ID, &w.ID, &mountPath, actor); err != nil {
+		return nil, err
+	}
+	log.Info("Volume %s/%s attached to %s at %s", v.Pool, v.Name, w.Name, mountPath)
+	return repo.GetByID(ctx, v.ID)
+}
+
+// Detach removes a volume from the workload it is attached to; the actor must
+// be a member of the workload's project. Detaching a volume that is not
+// attached changes nothing.
+func (s *Service) Detach(ctx context.Context, id string, actor *string) (*database.Volume, error) {
+	v, err := s.Get(ctx, id)
+	if err != nil || v.WorkloadID == nil {
+		return v, err
+	}
+
+	repo := database.NewVolumeRepository(s.db)
+	w, err := database.NewWorkloadRepository(s.db).GetByID(ctx, *v.WorkloadID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		// The workload is gone, and its instance with it
+	case err != nil:
+		return nil, err
+	default:
+		if err := s.projects.Authorize(ctx, w.Project, deref(actor), database.ProjectRoleMember); err != nil {
+			return nil, err
+		}
+		if err := lxd.DetachVolume(lxd.WithProject(ctx, w.Project), v.Pool, v.Name, w.Name); err != nil {
+			return nil, err
+		}
+		log.Info("Volume %s/%s detached from %s", v.Pool, v.Name, w.Name)
+	}
+	if err := repo.SetAttachment(ctx, v.ID, nil, nil, actor); err != nil {
+		return nil, err
+	}
+	return repo.GetByID(ctx, v.ID)
+}
+
+// Delete deletes a detached volume and its data.
+func (s *Service) Delete(ctx context.Context, id string) error {
+	v, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if v.WorkloadID != nil {
+		return fmt.Errorf("%w: %s is attached to workload %s; detach it first", ErrVolumeInUse, v.Name, *v.WorkloadID)
+	}
+
+	if err := lxd.DeleteVolume(ctx, v.Pool, v.Name); err != nil {
+		return err
+	}
+	log.Info("Volume %s/%s deleted", v.Pool, v.Name)
+	return database.NewVolumeRepository(s.db).DeleteByID(ctx, v.ID)
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
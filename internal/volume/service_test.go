@@ -0,0 +1,133 @@
+package volume
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"mcloud/internal/config"
+	"mcloud/internal/database"
+	"mcloud/internal/project"
+)
+
+func TestValidationAndAttachment(t *testing.T) {
+	db, err := database.Connect(filepath.Join(t.TempDir(), "mcloud.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close(db)
+	s := NewService(db, project.NewService(db, &config.Config{}))
+	ctx := context.Background()
+
+	for _, req := range []CreateRequest{
+		{Name: "Bad Name", Pool: "remote", SizeGB: 10},
+		{Name: "pgdata", Pool: "remote", SizeGB: 0},
+	} {
+		if _, err := s.Create(ctx, &req, nil); !errors.Is(err, ErrInvalidVolume) {
+			t.Errorf("%+v: got %v, want ErrInvalidVolume", req, err)
+		}
+	}
+
+	repo := database.NewVolumeRepository(db)
+	if err := repo.Create(ctx, &database.Volume{ID: "vol-1", Name: "pgdata", Pool: "remote", SizeGB: 50}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Create(ctx, &CreateRequest{Name: "pgdata", Pool: "remote", SizeGB: 10}, nil); !errors.Is(err, ErrVolumeExists) {
+		t.Fatalf("duplicate: got %v, want ErrVolumeExists", err)
+	}
+	if _, err := s.Get(ctx, "vol-2"); !errors.Is(err, ErrVolumeNotFound) {
+		t.Fatalf("missing: got %v, want ErrVolumeNotFound", err)
+	}
+
+	for _, p := range []string{"", "data", "/", "/.."} {
+		if _, err := s.Attach(ctx, "vol-1", &AttachRequest{WorkloadID: "w-1", Path: p}, nil); !errors.Is(err, ErrInvalidVolume) {
+			t.Errorf("path %q: got %v, want ErrInvalidVolume", p, err)
+		}
+	}
+	if _, err := s.Attach(ctx, "vol-1", &AttachRequest{WorkloadID: "w-1", Path: "/data"}, nil); !errors.Is(err, ErrWorkloadNotFound) {
+		t.Fatalf("missing workload: got %v, want ErrWorkloadNotFound", err)
+	}
+
+	// Attached to a workload that no longer exists
+	workloadID, mountPath := "w-1", "/data"
+	if err := repo.SetAttachment(ctx, "vol-1", &workloadID, &mountPath, nil); err != nil {
+		t.Fatal(err)
+	}
+	v, err := s.Get(ctx, "vol-1")
+	if err != nil || v.WorkloadID == nil || *v.WorkloadID != "w-1" || v.AttachedAt == nil {
+		t.Fatalf("attached: got %+v, %v", v, err)
+	}
+	if _, err := s.Attach(ctx, "vol-1", &AttachRequest{WorkloadID: "w-2", Path: "/data"}, nil); !errors.Is(err, ErrVolumeInUse) {
+		t.Fatalf("attach twice: got %v, want ErrVolumeInUse", err)
+	}
+	if err := s.Delete(ctx, "vol-1"); !errors.Is(err, ErrVolumeInUse) {
+		t.Fatalf("delete attached: got %v, want ErrVolumeInUse", err)
+	}
+
+	v, err = s.Detach(ctx, "vol-1", nil)
+	if err != nil || v.WorkloadID != nil || v.MountPath != nil || v.AttachedAt != nil {
+		t.Fatalf("detached: got %+v, %v", v, err)
+	}
+	if v, err = s.Detach(ctx, "vol-1", nil); err != nil || v.WorkloadID != nil {
+		t.Fatalf("detach twice: got %+v, %v", v, err)
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	db, err := database.Connect(filepath.Join(t.TempDir(), "mcloud.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close(db)
+	s := NewService(db, project.NewService(db, &config.Config{}))
+	ctx := context.Background()
+
+	projects := database.NewProjectRepository(db)
+	if err := projects.Create(ctx, &database.Project{Name: "team-a"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := projects.PutBinding(ctx, &database.ProjectBinding{Project: "team-a", Identity: "alice", Role: database.ProjectRoleMember}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO clusters (id, name, state) VALUES ('c1', 'test', 'active')`); err != nil {
+		t.Fatal(err)
+	}
+	w := &database.Workload{ID: "w-1", ClusterID: "c1", Name: "db-1", Kind: "container", Status: "running",
+		Image: "ubuntu:24.04", Priority: "normal", Project: "team-a"}
+	if err := database.NewWorkloadRepository(db).Create(ctx, w); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := database.NewVolumeRepository(db)
+	for _, id := range []string{"vol-1", "vol-2"} {
+		if err := repo.Create(ctx, &database.Volume{ID: id, Name: "data-" + id, Pool: "remote", SizeGB: 10}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mountPath := "/data"
+	if err := repo.SetAttachment(ctx, "vol-1", &w.ID, &mountPath, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := s.Get(ctx, "vol-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Authorize(ctx, v, "alice"); err != nil {
+		t.Errorf("Authorize(alice) = %v", err)
+	}
+	if err := s.Authorize(ctx, v, "mallory"); !errors.Is(err, project.ErrProjectDenied) {
+		t.Errorf("Authorize(mallory) = %v, want ErrProjectDenied", err)
+	}
+	if vs, err := s.Readable(ctx, "mallory"); err != nil || len(vs) != 1 || vs[0].ID != "vol-2" {
+		t.Errorf("Readable(mallory) = %+v, %v; want vol-2 only", vs, err)
+	}
+	mallory := "mallory"
+	if _, err := s.Detach(ctx, "vol-1", &mallory); !errors.Is(err, project.ErrProjectDenied) {
+		t.Errorf("Detach by mallory = %v, want ErrProjectDenied", err)
+	}
+	if _, err := s.Attach(ctx, "vol-2", &AttachRequest{WorkloadID: "w-1", Path: "/logs"}, &mallory); !errors.Is(err, project.ErrProjectDenied) {
+		t.Errorf("Attach by mallory = %v, want ErrProjectDenied", err)
+	}
+}
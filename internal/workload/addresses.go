@@ -0,0 +1,43 @@
+package workload
+
+import (
+	"context"
+	"slices"
+
+	"mcloud/internal/database"
+	"mcloud/services/lxd"
+)
+
+// SyncAddresses is the periodic job that copies each workload's IP addresses
+// from LXD instance state into the database, so list/get answer "what IP did
+// my VM get" without asking LXD. Only changed rows are written.
+func (s *Service) SyncAddresses(ctx context.Context) error {
+	states, err := lxd.InstanceStates()
+	if err != nil {
+		return err
+	}
+
+	clusters, err := database.NewClusterRepository(s.db).List(ctx)
+	if err != nil {
+		return err
+	}
+
+	workloadRepo := database.NewWorkloadRepository(s.db)
+	for _, c := range clusters {
+		workloads, err := workloadRepo.ListByCluster(ctx, c.ID, database.ListOptions{})
+		if err != nil {
+			return err
+		}
+
+		for _, w := range workloads {
+			st, ok := states[w.Name]
+			if !ok || slices.Equal(w.Addresses, st.Addresses) {
+				continue
+			}
+			if err := workloadRepo.UpdateAddresses(ctx, w.ID, st.Addresses); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
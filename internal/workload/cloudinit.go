@@ -0,0 +1,77 @@
+package workload
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxCloudInitSize bounds user-data and network-config each; LXD stores them
+// as instance config in its database.
+const maxCloudInitSize = 256 * 1024
+
+// validateCloudInit checks the cloud-init config of a create request. User-data
+// is either "#cloud-config" YAML or anything else cloud-init runs as is (a
+// "#!" script, "#include" list, MIME multipart); only the YAML is parsed.
+// Network-config must be a YAML mapping (network config version 1 or 2).
+//
+// Example Output (Error):
+//   user_data "#cloud-config\npackages: [nginx"  =>  ErrInvalidRequest: user_data is not valid YAML: ...
+func validateCloudInit(userData string, networkConfig string) error {
+	if len(userData) > maxCloudInitSize || len(networkConfig) > maxCloudInitSize {
+		return fmt.Errorf("%w: user_data and network_config are limited to %d KiB each", ErrInvalidRequest, maxCloudInitSize/1024)
+	}
+	if strings.HasPrefix(userData, "#cloud-config") {
+		var doc map[string]any
+		if err := yaml.Unmarshal([]byte(userData), &doc); err != nil {
+			return fmt.Errorf("%w: user_data is not valid YAML: %v", ErrInvalidRequest, err)
+		}
+	} else if userData != "" && !strings.HasPrefix(userData, "#") && !strings.HasPrefix(userData, "Content-Type:") {
+		return fmt.Errorf("%w: user_data must start with #cloud-config, a #! script line or another cloud-init header", ErrInvalidRequest)
+	}
+	if networkConfig != "" {
+		var doc map[string]any
+		if err := yaml.Unmarshal([]byte(networkConfig), &doc); err != nil || doc == nil {
+			return fmt.Errorf("%w: network_config must be a YAML mapping", ErrInvalidRequest)
+		}
+	}
+	return nil
+}
+
+// cloudInitConfig returns the LXD instance config applying the cloud-init
+// config, and its hash for the workload row; both are empty without any.
+//
+// Example Output:
+//   {"user.user-data": "#cloud-config\n..."}, "5f2a0c..."
+func cloudInitConfig(userData string, networkConfig string) (map[string]string, *string) {
+	if userData == "" && networkConfig == "" {
+		return nil, nil
+	}
+
+	config := map[string]string{}
+	if userData != "" {
+		config["user.user-data"] = userData
+	}
+	if networkConfig != "" {
+		config["user.network-config"] = networkConfig
+	}
+
+	// Both documents, each prefixed by its key, so that moving content from one
+	// to the other changes the hash
+	sum := sha256.New()
+	for _, k := range []string{"user.user-data", "user.network-config"} {
+		fmt.Fprintf(sum, "%s\x00%s\x00", k, config[k])
+	}
+	hash := hex.EncodeToString(sum.Sum(nil))
+	return config, &hash
+}
+
+// CloudInitHash returns the cloud_init_hash of a workload launched with this
+// cloud-init config, nil without any.
+func CloudInitHash(userData string, networkConfig string) *string {
+	_, hash := cloudInitConfig(userData, networkConfig)
+	return hash
+}
@@ -0,0 +1,52 @@
+package workload
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateCloudInit(t *testing.T) {
+	tests := []struct {
+		name          string
+		userData      string
+		networkConfig string
+		wantErr       bool
+	}{
+		{name: "none"},
+		{name: "cloud-config", userData: "#cloud-config\npackages: [nginx]\n"},
+		{name: "script", userData: "#!/bin/sh\necho hello > /tmp/hello\n"},
+		{name: "network v2", networkConfig: "version: 2\nethernets:\n  eth0:\n    dhcp4: true\n"},
+		{name: "bad cloud-config", userData: "#cloud-config\npackages: [nginx\n", wantErr: true},
+		{name: "no header", userData: "packages: [nginx]\n", wantErr: true},
+		{name: "network not a mapping", networkConfig: "- eth0\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		err := validateCloudInit(tt.userData, tt.networkConfig)
+		if tt.wantErr != (err != nil) {
+			t.Errorf("%s: got %v, want error %t", tt.name, err, tt.wantErr)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidRequest) {
+			t.Errorf("%s: got %v, want ErrInvalidRequest", tt.name, err)
+		}
+	}
+}
+
+func TestCloudInitConfig(t *testing.T) {
+	if config, hash := cloudInitConfig("", ""); config != nil || hash != nil {
+		t.Fatalf("no cloud-init: got %v, %v", config, hash)
+	}
+
+	config, hash := cloudInitConfig("#cloud-config\n", "version: 2\n")
+	if config["user.user-data"] != "#cloud-config\n" || config["user.network-config"] != "version: 2\n" {
+		t.Fatalf("config: got %v", config)
+	}
+	if hash == nil || len(*hash) != 64 {
+		t.Fatalf("hash: got %v", hash)
+	}
+
+	// The same content under the other key is a different configuration
+	_, swapped := cloudInitConfig("version: 2\n", "#cloud-config\n")
+	if *swapped == *hash {
+		t.Fatal("swapped documents hash the same")
+	}
+}
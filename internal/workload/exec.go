@@ -0,0 +1,213 @@
+package workload
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+
+	"mcloud/internal/audit"
+	"mcloud/internal/database"
+	"mcloud/pkg/reason"
+	"mcloud/services/lxd"
+)
+
+var ErrNotRunning = reason.New(reason.WorkloadNotRunning, "workload is not running")
+
+// ExecRequest runs a command in a workload's instance. With tty the command
+// gets a pseudo-terminal of width x height characters.
+//
+// Example JSON:
+//   {"command": ["bash", "-l"], "env": {"TERM": "xterm-256color"}, "tty": true, "width": 120, "height": 40}
+type ExecRequest struct {
+	Command []string          `json:"command"`
+	Env     map[string]string `json:"env,omitempty"`
+	TTY     bool              `json:"tty,omitempty"`
+	Width   int               `json:"width,omitempty"`
+	Height  int               `json:"height,omitempty"`
+}
+
+// ConsoleRequest attaches to a workload's console.
+//
+// Example JSON:
+//   {"width": 120, "height": 40}
+type ConsoleRequest struct {
+	Width  int `json:"width,omitempty"`
+	Height int `json:"height,omitempty"`
+}
+
+// consoleCommand is the command recorded for console sessions in the audit log.
+var consoleCommand = []string{"console"}
+
+// ExecSession is an exec into a workload that has been authorized and recorded
+// but not yet run.
+type ExecSession struct {
+	*audit.Session
+
+	workload *database.Workload
+	env      map[string]string
+	tty      bool
+	console  bool
+	width    int
+	height   int
+}
+
+// StartExec checks that a command can be run in a workload and records the
+// start of the session in the audit log (see audit.Sessions); the caller then
+// runs it with Run.
+//
+// Example Output (Error):
+//   Workload stopped  =>  ErrNotRunning
+func (s *Service) StartExec(ctx context.Context, id string, req *ExecRequest, actor string) (*ExecSession, error) {
+	if len(req.Command) == 0 || req.Command[0] == "" {
+		return nil, fmt.Errorf("%w: command is required", ErrInvalidRequest)
+	}
+	for k := range req.Env {
+		if !envKeyRegexp.MatchString(k) {
+			return nil, fmt.Errorf("%w: invalid environment variable name %q", ErrInvalidRequest, k)
+		}
+	}
+	if err := validateTerminalSize(req.Width, req.Height); err != nil {
+		return nil, err
+	}
+
+	session, err := s.startSession(ctx, id, actor, req.Command)
+	if err != nil {
+		return nil, err
+	}
+	session.env = req.Env
+	session.tty = req.TTY
+	session.width, session.height = req.Width, req.Height
+	return session, nil
+}
+
+// StartConsole checks that a workload's console can be attached and records
+// the start of the session in the audit log with the command "console"; the
+// caller then runs it with RunTerminal.
+//
+// Example Output (Error):
+//   Workload stopped  =>  ErrNotRunning
+func (s *Service) StartConsole(ctx context.Context, id string, req *ConsoleRequest, actor string) (*ExecSession, error) {
+	if err := validateTerminalSize(req.Width, req.Height); err != nil {
+		return nil, err
+	}
+
+	session, err := s.startSession(ctx, id, actor, consoleCommand)
+	if err != nil {
+		return nil, err
+	}
+	session.console = true
+	session.width, session.height = req.Width, req.Height
+	return session, nil
+}
+
+// validateTerminalSize checks a requested terminal size; 0 leaves it to LXD.
+func validateTerminalSize(width int, height int) error {
+	if width < 0 || width > math.MaxUint16 || height < 0 || height > math.MaxUint16 {
+		return fmt.Errorf("%w: invalid terminal size %dx%d", ErrInvalidRequest, width, height)
+	}
+	return nil
+}
+
+// startSession looks up a running workload and records the start of a session in it.
+func (s *Service) startSession(ctx context.Context, id string, actor string, command []string) (*ExecSession, error) {
+	w, err := database.NewWorkloadRepository(s.db).GetByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWorkloadNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if w.Status != "running" {
+		return nil, fmt.Errorf("%w: workload %s is %s", ErrNotRunning, w.Name, w.Status)
+	}
+
+	session, err := s.sessions.Start(ctx, "/workloads/"+w.ID, w.Name, actor, command)
+	if err != nil {
+		return nil, err
+	}
+	return &ExecSession{Session: session, workload: w}, nil
+}
+
+// Terminal reports whether the session runs on a terminal (an exec with a TTY
+// or a console) and must be run with RunTerminal.
+func (e *ExecSession) Terminal() bool {
+	return e.tty || e.console
+}
+
+// Run runs the command, recording its input and output when transcripts are
+// enabled, and returns its exit code. The end of the session is recorded
+// whatever the outcome.
+func (e *ExecSession) Run(ctx context.Context, stdin io.Reader, stdout io.Writer, stderr io.Writer) (int, error) {
+	log.Info("Exec session %s: %s runs %q in %s", e.ID, e.Actor, e.Command, e.workload.Name)
+
+	code, err := lxd.ExecInstance(lxd.WithProject(ctx, e.workload.Project), lxd.ExecConfig{
+		Name:    e.workload.Name,
+		Command: e.Command,
+		Env:     e.env,
+		Stdin:   e.Input(stdin),
+		Stdout:  e.Output(stdout),
+		Stderr:  e.Output(stderr),
+	})
+	e.End(code, err)
+	return code, err
+}
+
+// TerminalSize is a new size of a session's terminal, in characters.
+type TerminalSize struct {
+	Width  int
+	Height int
+}
+
+// RunTerminal runs the session on a terminal through LXD's websocket API,
+// resizing it whenever a size arrives on resize, and returns the command's
+// exit code (0 for a console). The session ends when the command exits, when
+// ctx is cancelled or, for a console, when stdin ends. The end of the session
+// is recorded whatever the outcome.
+func (e *ExecSession) RunTerminal(ctx context.Context, stdin io.Reader, stdout io.Writer, resize <-chan TerminalSize) (int, error) {
+	if e.console {
+		log.Info("Console session %s: %s attaches to %s", e.ID, e.Actor, e.workload.Name)
+	} else {
+		log.Info("Exec session %s: %s runs %q in %s on a terminal", e.ID, e.Actor, e.Command, e.workload.Name)
+	}
+
+	cfg := lxd.TerminalConfig{Name: e.workload.Name, Env: e.env, Width: e.width, Height: e.height}
+	if !e.console {
+		cfg.Command = e.Command
+	}
+	terminal, err := lxd.OpenTerminal(lxd.WithProject(ctx, e.workload.Project), cfg)
+	if err != nil {
+		e.End(-1, err)
+		return -1, err
+	}
+	var closeOnce sync.Once
+	hangUp := func() { closeOnce.Do(func() { terminal.Close() }) }
+	defer hangUp()
+	stop := context.AfterFunc(ctx, hangUp)
+	defer stop()
+
+	go func() {
+		for size := range resize {
+			if err := terminal.Resize(size.Width, size.Height); err != nil {
+				log.Debug("Resizing terminal of session %s: %v", e.ID, err)
+			}
+		}
+	}()
+	go func() {
+		io.Copy(terminal, e.Input(stdin))
+		if e.console {
+			hangUp() // the client detached
+		}
+	}()
+	io.Copy(e.Output(stdout), terminal)
+
+	code, err := terminal.Wait(ctx)
+	if ctx.Err() != nil {
+		code, err = -1, ctx.Err()
+	}
+	e.End(code, err)
+	return code, err
+}
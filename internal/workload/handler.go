@@ -0,0 +1,568 @@
+package workload
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"mcloud/internal/agent"
+	"mcloud/internal/audit"
+	"mcloud/internal/auth"
+	"mcloud/internal/database"
+	"mcloud/internal/operation"
+	"mcloud/internal/project"
+	"mcloud/internal/secret"
+	"mcloud/pkg/execstream"
+	"mcloud/pkg/reason"
+	"mcloud/pkg/utils"
+)
+
+type ListWorkloadsResponse struct {
+	Items      []database.Workload `json:"items"`
+	NextOffset int                 `json:"next_offset,omitempty"` // 0 when there are no more pages
+}
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(s *Service) *Handler {
+	return &Handler{service: s}
+}
+
+// authorize checks that the caller is a member of the project of the workload
+// in the path, replying with an error if not.
+func (h *Handler) authorize(w http.ResponseWriter, r *http.Request) bool {
+	err := h.service.Authorize(r.Context(), r.PathValue("id"), auth.ClientIdentity(r))
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, ErrWorkloadNotFound):
+		reason.HTTPError(w, err, 404)
+	case errors.Is(err, project.ErrProjectDenied):
+		reason.HTTPError(w, err, 403)
+	default:
+		reason.HTTPError(w, err, 500)
+	}
+	return false
+}
+
+// ListWorkloads handles GET /workloads?limit=100&offset=0. Optional parameters:
+//   sort                                   name, status, kind, priority or created_at; "-" prefix for descending
+//   cluster, node, status, kind, priority  exact-match filters
+//   project                                exact-match filter, else the X-MCloud-Project header
+// Only workloads of projects the caller is a member of are listed.
+func (h *Handler) ListWorkloads(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit, offset, err := utils.ParsePage(r)
+	if err != nil {
+		reason.HTTPError(w, err, 400)
+		return
+	}
+
+	// Streamed in the shape of ListWorkloadsResponse without buffering the page
+	sort, filter := utils.ParseListQuery(r, "cluster", "node", "status", "kind", "priority", "project")
+	if p := project.FromRequest(r, filter["project"]); p != "" {
+		if filter == nil {
+			filter = map[string]string{}
+		}
+		filter["project"] = p
+	}
+	opts := database.ListOptions{Limit: limit, Offset: offset, Sort: sort, Filter: filter}
+	stream := utils.NewJSONStream(w, "items")
+	err = h.service.ListWorkloads(r.Context(), opts, auth.ClientIdentity(r), func(wl *database.Workload) error {
+		return stream.Write(wl)
+	})
+	if errors.Is(err, database.ErrInvalidListOption) {
+		reason.HTTPError(w, err, 400)
+		return
+	}
+	if errors.Is(err, project.ErrProjectDenied) {
+		reason.HTTPError(w, err, 403)
+		return
+	}
+	if err != nil {
+		if !stream.Started() {
+			reason.HTTPError(w, err, 500)
+		}
+		return
+	}
+
+	var extra map[string]any
+	if stream.Count() == limit {
+		extra = map[string]any{"next_offset": offset + limit}
+	}
+	stream.Close(extra)
+}
+
+func (h *Handler) CreateWorkload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req CreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		reason.HTTPError(w, err, 400)
+		return
+	}
+	req.Project = project.FromRequest(r, req.Project)
+
+	actor := auth.ClientIdentity(r)
+	result, err := h.service.CreateWorkload(r.Context(), &req, &actor)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrNameRequired), errors.Is(err, ErrInvalidRequest), errors.Is(err, secret.ErrSecretNotFound):
+			reason.HTTPError(w, err, 400)
+		case errors.Is(err, ErrNodeNotFound):
+			reason.HTTPError(w, err, 404)
+		case errors.Is(err, project.ErrQuotaExceeded), errors.Is(err, project.ErrProjectDenied):
+			reason.HTTPError(w, err, 403)
+		case errors.Is(err, ErrNameExists), errors.Is(err, ErrNoCluster), errors.Is(err, ErrNoEligibleNode):
+			reason.HTTPError(w, err, 409)
+		case errors.Is(err, ErrStorageUnhealthy):
+			reason.HTTPError(w, err, 503)
+		default:
+			reason.HTTPError(w, err, 500)
+		}
+		return
+	}
+
+	operation.WriteAccepted(w, result.Operation, result)
+}
+
+func (h *Handler) GetWorkload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !h.authorize(w, r) {
+		return
+	}
+
+	result, err := h.service.GetWorkload(r.Context(), r.PathValue("id"))
+	if err != nil {
+		if errors.Is(err, ErrWorkloadNotFound) {
+			reason.HTTPError(w, err, 404)
+			return
+		}
+		reason.HTTPError(w, err, 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// UpdateWorkload applies a JSON merge patch (see UpdateRequest) to a workload.
+func (h *Handler) UpdateWorkload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPatch {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req UpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		reason.HTTPError(w, err, 400)
+		return
+	}
+
+	if !h.authorize(w, r) {
+		return
+	}
+	result, err := h.service.UpdateWorkload(r.Context(), r.PathValue("id"), &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidRequest), errors.Is(err, secret.ErrSecretNotFound):
+			reason.HTTPError(w, err, 400)
+		case errors.Is(err, ErrWorkloadNotFound):
+			reason.HTTPError(w, err, 404)
+		default:
+			reason.HTTPError(w, err, 500)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+func (h *Handler) CloneWorkload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req CloneRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		reason.HTTPError(w, err, 400)
+		return
+	}
+
+	actor := auth.ClientIdentity(r)
+	result, err := h.service.CloneWorkload(r.Context(), r.PathValue("id"), &req, &actor)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrNameRequired), errors.Is(err, ErrInvalidRequest):
+			reason.HTTPError(w, err, 400)
+		case errors.Is(err, ErrWorkloadNotFound), errors.Is(err, ErrNodeNotFound):
+			reason.HTTPError(w, err, 404)
+		case errors.Is(err, project.ErrQuotaExceeded), errors.Is(err, project.ErrProjectDenied):
+			reason.HTTPError(w, err, 403)
+		case errors.Is(err, ErrNameExists):
+			reason.HTTPError(w, err, 409)
+		default:
+			reason.HTTPError(w, err, 500)
+		}
+		return
+	}
+
+	operation.WriteAccepted(w, result.Operation, result)
+}
+
+// ListInstanceHooks is called by agents with ?instance=<name>&event=<event>
+// when an instance on their node changes state.
+func (h *Handler) ListInstanceHooks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	instance := r.URL.Query().Get("instance")
+	event := r.URL.Query().Get("event")
+	if instance == "" || event == "" {
+		reason.HTTPErrorf(w, 400, "instance and event are required")
+		return
+	}
+
+	hooks, err := h.service.ListInstanceHooks(r.Context(), instance, event)
+	if err != nil {
+		reason.HTTPError(w, err, 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(hooks)
+}
+
+func (h *Handler) RecordHookResult(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var result agent.HookResult
+	if err := json.NewDecoder(r.Body).Decode(&result); err != nil {
+		reason.HTTPError(w, err, 400)
+		return
+	}
+
+	if err := h.service.RecordHookResult(r.Context(), &result); err != nil {
+		if errors.Is(err, ErrWorkloadNotFound) {
+			reason.HTTPError(w, err, 404)
+			return
+		}
+		reason.HTTPError(w, err, 500)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListDeadLettersResponse is one page of GET /workloads/hooks/dead-letters.
+type ListDeadLettersResponse struct {
+	Items      []database.HookDeadLetter `json:"items"`
+	NextOffset int                       `json:"next_offset,omitempty"` // 0 when there are no more pages
+}
+
+// ListDeadLetters handles GET /workloads/hooks/dead-letters?limit=100&offset=0,
+// the hook webhooks agents gave up delivering, newest first. Optional parameters:
+//   sort                         created_at or attempts; "-" prefix for descending
+//   workload, hook, node, event  exact-match filters
+func (h *Handler) ListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	limit, offset, err := utils.ParsePage(r)
+	if err != nil {
+		reason.HTTPError(w, err, 400)
+		return
+	}
+
+	sort, filter := utils.ParseListQuery(r, "workload", "hook", "node", "event")
+	letters, err := h.service.ListDeadLetters(r.Context(), database.ListOptions{Limit: limit, Offset: offset, Sort: sort, Filter: filter})
+	if errors.Is(err, database.ErrInvalidListOption) {
+		reason.HTTPError(w, err, 400)
+		return
+	}
+	if err != nil {
+		reason.HTTPError(w, err, 500)
+		return
+	}
+
+	resp := ListDeadLettersResponse{Items: letters}
+	if resp.Items == nil {
+		resp.Items = []database.HookDeadLetter{}
+	}
+	if len(letters) == limit {
+		resp.NextOffset = offset + limit
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// writeScheduleError maps schedule errors to status codes.
+func writeScheduleError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidRequest):
+		reason.HTTPError(w, err, 400)
+	case errors.Is(err, ErrWorkloadNotFound), errors.Is(err, ErrScheduleNotFound):
+		reason.HTTPError(w, err, 404)
+	default:
+		reason.HTTPError(w, err, 500)
+	}
+}
+
+// SetSchedule handles PUT /workloads/{id}/schedule (see ScheduleRequest).
+func (h *Handler) SetSchedule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		reason.HTTPError(w, err, 400)
+		return
+	}
+
+	if !h.authorize(w, r) {
+		return
+	}
+	result, err := h.service.SetSchedule(r.Context(), r.PathValue("id"), &req)
+	if err != nil {
+		writeScheduleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// DeleteSchedule handles DELETE /workloads/{id}/schedule.
+func (h *Handler) DeleteSchedule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !h.authorize(w, r) {
+		return
+	}
+	if err := h.service.DeleteSchedule(r.Context(), r.PathValue("id")); err != nil {
+		writeScheduleError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SkipSchedule handles POST /workloads/{id}/schedule/skip: the next scheduled action is skipped.
+func (h *Handler) SkipSchedule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !h.authorize(w, r) {
+		return
+	}
+	result, err := h.service.SkipSchedule(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeScheduleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// OverrideSchedule handles POST /workloads/{id}/schedule/override (see OverrideRequest).
+func (h *Handler) OverrideSchedule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req OverrideRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		reason.HTTPError(w, err, 400)
+		return
+	}
+
+	if !h.authorize(w, r) {
+		return
+	}
+	result, err := h.service.OverrideSchedule(r.Context(), r.PathValue("id"), &req)
+	if err != nil {
+		writeScheduleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// ExecWorkload handles POST /workloads/{id}/exec with an ExecRequest body and
+// "Connection: Upgrade", "Upgrade: mcloud-exec" headers. Once the session is
+// recorded the connection switches to the execstream protocol: the client sends
+// Stdin frames (and StdinEOF), the server sends Stdout and Stderr frames and
+// finally an Exit frame with the command's exit code. With "tty": true the
+// command runs on a terminal: Stdout carries everything it writes and the
+// client sends Resize frames when its window changes size.
+//
+// The audit entry of the request points at the session, /audit/exec-sessions/<id>,
+// whose ID is also returned in the X-Mcloud-Exec-Session header of the 101 response.
+func (h *Handler) ExecWorkload(w http.ResponseWriter, r *http.Request) {
+	if !strings.EqualFold(r.Header.Get("Upgrade"), execstream.Protocol) {
+		reason.HTTPErrorf(w, 400, "exec requires Upgrade: %s", execstream.Protocol)
+		return
+	}
+
+	var req ExecRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		reason.HTTPError(w, err, 400)
+		return
+	}
+
+	if !h.authorize(w, r) {
+		return
+	}
+	session, err := h.service.StartExec(r.Context(), r.PathValue("id"), &req, auth.ClientIdentity(r))
+	if err != nil {
+		writeSessionError(w, err)
+		return
+	}
+	serveSession(w, r, session)
+}
+
+// ConsoleWorkload handles POST /workloads/{id}/console with a ConsoleRequest
+// body and the same Upgrade headers as ExecWorkload, attaching to the console
+// of the workload's instance. The session speaks execstream like an exec on a
+// terminal; it ends with an Exit frame of 0 once the client sends StdinEOF or
+// the instance closes the console.
+func (h *Handler) ConsoleWorkload(w http.ResponseWriter, r *http.Request) {
+	if !strings.EqualFold(r.Header.Get("Upgrade"), execstream.Protocol) {
+		reason.HTTPErrorf(w, 400, "console requires Upgrade: %s", execstream.Protocol)
+		return
+	}
+
+	var req ConsoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		reason.HTTPError(w, err, 400)
+		return
+	}
+
+	if !h.authorize(w, r) {
+		return
+	}
+	session, err := h.service.StartConsole(r.Context(), r.PathValue("id"), &req, auth.ClientIdentity(r))
+	if err != nil {
+		writeSessionError(w, err)
+		return
+	}
+	serveSession(w, r, session)
+}
+
+func writeSessionError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidRequest):
+		reason.HTTPError(w, err, 400)
+	case errors.Is(err, ErrWorkloadNotFound):
+		reason.HTTPError(w, err, 404)
+	case errors.Is(err, ErrNotRunning):
+		reason.HTTPError(w, err, 409)
+	default:
+		reason.HTTPError(w, err, 500)
+	}
+}
+
+// serveSession switches the connection to the execstream protocol and runs a
+// recorded session over it.
+func serveSession(w http.ResponseWriter, r *http.Request, session *ExecSession) {
+	audit.SetTarget(r, "/audit/exec-sessions/"+session.ID)
+
+	conn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		session.End(-1, err)
+		reason.HTTPError(w, err, 500)
+		return
+	}
+	defer conn.Close()
+	// Sessions are interactive; the server's read and write timeouts do not apply
+	conn.SetDeadline(time.Time{})
+
+	fmt.Fprintf(brw, "HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: %s\r\nX-Mcloud-Exec-Session: %s\r\n\r\n",
+		execstream.Protocol, session.ID)
+	if err := brw.Flush(); err != nil {
+		session.End(-1, err)
+		return
+	}
+
+	// The request context no longer ends with the connection; losing the client kills the command
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	stdin, stdinWriter := io.Pipe()
+	defer stdin.Close()
+	resize := make(chan TerminalSize, 1)
+	go func() {
+		defer close(resize)
+		for {
+			typ, payload, err := execstream.ReadFrame(brw)
+			if err != nil {
+				stdinWriter.CloseWithError(err)
+				cancel()
+				return
+			}
+			switch typ {
+			case execstream.Stdin:
+				if _, err := stdinWriter.Write(payload); err != nil {
+					return // the command exited
+				}
+			case execstream.StdinEOF:
+				stdinWriter.Close()
+			case execstream.Resize:
+				width, height, err := execstream.Size(payload)
+				if err != nil {
+					continue
+				}
+				// Only the latest size matters; replace one not yet applied
+				select {
+				case <-resize:
+				default:
+				}
+				resize <- TerminalSize{Width: width, Height: height}
+			}
+		}
+	}()
+
+	out := execstream.NewWriters(conn, execstream.Stdout, execstream.Stderr)
+	var code int
+	if session.Terminal() {
+		code, err = session.RunTerminal(ctx, stdin, out[0], resize)
+	} else {
+		code, err = session.Run(ctx, stdin, out[0], out[1])
+	}
+	if err != nil {
+		out[1].Write([]byte(err.Error() + "\n"))
+	}
+	out[0].WriteExit(code)
+}
@@ -0,0 +1,146 @@
+package workload
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"mcloud/internal/agent"
+	"mcloud/internal/database"
+	"mcloud/internal/event"
+	"mcloud/pkg/utils"
+)
+
+// HookSpec is a lifecycle hook in a workload spec. Exactly one of Webhook or
+// Command is set; Command names an entry of hooks.commands in the agent config.
+//
+// Example JSON:
+//   {"event": "on-start", "command": "warm-cache"}
+//   {"event": "on-failure", "webhook": "https://alerts.example.com/mcloud"}
+type HookSpec struct {
+	Event   string `json:"event"`
+	Webhook string `json:"webhook,omitempty"`
+	Command string `json:"command,omitempty"`
+}
+
+// validateHooks checks hook specs against the command allowlist.
+// Commands run inside the instance, so they are only accepted for on-start.
+func (s *Service) validateHooks(hooks []HookSpec) error {
+	for _, h := range hooks {
+		switch h.Event {
+		case agent.HookOnStart, agent.HookOnStop, agent.HookOnFailure:
+		default:
+			return fmt.Errorf("%w: hook event must be %s, %s or %s", ErrInvalidRequest, agent.HookOnStart, agent.HookOnStop, agent.HookOnFailure)
+		}
+		if (h.Webhook == "") == (h.Command == "") {
+			return fmt.Errorf("%w: %s hook needs exactly one of webhook or command", ErrInvalidRequest, h.Event)
+		}
+		if h.Webhook != "" {
+			u, err := url.Parse(h.Webhook)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("%w: hook webhook must be an http(s) URL", ErrInvalidRequest)
+			}
+			continue
+		}
+		if h.Event != agent.HookOnStart {
+			return fmt.Errorf("%w: command hooks are only supported for %s", ErrInvalidRequest, agent.HookOnStart)
+		}
+		if _, ok := s.cfg.Hooks.Commands[h.Command]; !ok {
+			return fmt.Errorf("%w: hook command %q is not allowed", ErrInvalidRequest, h.Command)
+		}
+	}
+	return nil
+}
+
+func createHooks(ctx context.Context, tx *sql.Tx, workloadID string, hooks []HookSpec) error {
+	repo := database.NewWorkloadHookRepositoryTx(tx)
+	for _, h := range hooks {
+		hook := &database.WorkloadHook{
+			ID:         utils.GenerateUUID(),
+			WorkloadID: workloadID,
+			Event:      h.Event,
+		}
+		if h.Webhook != "" {
+			hook.WebhookURL = &h.Webhook
+		} else {
+			hook.Command = &h.Command
+		}
+		if err := repo.Create(ctx, hook); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListInstanceHooks returns the hooks the agent should run for an event of an LXD instance.
+func (s *Service) ListInstanceHooks(ctx context.Context, instance string, event string) ([]database.WorkloadHook, error) {
+	hooks, err := database.NewWorkloadHookRepository(s.db).ListByInstance(ctx, instance, event)
+	if err != nil {
+		return nil, err
+	}
+	if hooks == nil {
+		hooks = []database.WorkloadHook{}
+	}
+	return hooks, nil
+}
+
+// RecordHookResult stores a hook run reported by an agent as a
+// workload.hook.succeeded or workload.hook.failed event. A webhook the agent
+// gave up delivering is also kept as a dead letter (see ListDeadLetters) and
+// recorded as workload.hook.dead_lettered.
+//
+// Example message:
+//   on-start hook 7c9e... of web-1 failed on node-2 after 1200ms: exit status 1
+func (s *Service) RecordHookResult(ctx context.Context, result *agent.HookResult) error {
+	w, err := database.NewWorkloadRepository(s.db).GetByID(ctx, result.WorkloadID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrWorkloadNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	eventType := "workload.hook.succeeded"
+	message := fmt.Sprintf("%s hook %s of %s succeeded on %s after %dms", result.Event, result.HookID, w.Name, result.Node, result.DurationMs)
+	switch {
+	case result.DeadLetter:
+		if err := s.storeDeadLetter(ctx, result); err != nil {
+			return err
+		}
+		eventType = event.WorkloadHookDeadLettered
+		message = fmt.Sprintf("%s hook %s of %s was not delivered by %s after %d attempts: %s", result.Event, result.HookID, w.Name, result.Node, result.Attempts, result.Error)
+	case !result.Success:
+		eventType = event.WorkloadHookFailed
+		message = fmt.Sprintf("%s hook %s of %s failed on %s after %dms: %s", result.Event, result.HookID, w.Name, result.Node, result.DurationMs, result.Error)
+	}
+
+	e := event.New(eventType, message, nil)
+	e.ClusterID, e.NodeID = &w.ClusterID, w.NodeID
+	return database.NewEventRepository(s.db).Create(ctx, e)
+}
+
+func (s *Service) storeDeadLetter(ctx context.Context, result *agent.HookResult) error {
+	payload, err := json.Marshal(result.Payload)
+	if err != nil {
+		return err
+	}
+	return database.NewHookDeadLetterRepository(s.db).Create(ctx, &database.HookDeadLetter{
+		ID:         utils.GenerateUUID(),
+		HookID:     result.HookID,
+		WorkloadID: result.WorkloadID,
+		Node:       result.Node,
+		Event:      result.Event,
+		URL:        result.WebhookURL,
+		Payload:    payload,
+		Attempts:   result.Attempts,
+		Error:      result.Error,
+	})
+}
+
+// ListDeadLetters returns undelivered hook webhooks, newest first unless opts sorts otherwise.
+func (s *Service) ListDeadLetters(ctx context.Context, opts database.ListOptions) ([]database.HookDeadLetter, error) {
+	return database.NewHookDeadLetterRepository(s.db).List(ctx, opts)
+}
@@ -0,0 +1,31 @@
+package workload
+
+import (
+	"net/http"
+)
+
+// InitModule registers the workload routes served by handler.
+func InitModule(mux *http.ServeMux, handler *Handler) {
+	mux.HandleFunc("GET /workloads", handler.ListWorkloads)
+	mux.HandleFunc("POST /workloads", handler.CreateWorkload)
+	mux.HandleFunc("GET /workloads/{id}", handler.GetWorkload)
+	mux.HandleFunc("PATCH /workloads/{id}", handler.UpdateWorkload)
+	mux.HandleFunc("/workloads/{id}/clone", handler.CloneWorkload)
+
+	// Interactive commands; the connection is upgraded to the execstream protocol
+	mux.HandleFunc("POST /workloads/{id}/exec", handler.ExecWorkload)
+	mux.HandleFunc("POST /workloads/{id}/console", handler.ConsoleWorkload)
+
+	// Power schedules (stop/start at fixed times)
+	mux.HandleFunc("PUT /workloads/{id}/schedule", handler.SetSchedule)
+	mux.HandleFunc("DELETE /workloads/{id}/schedule", handler.DeleteSchedule)
+	mux.HandleFunc("POST /workloads/{id}/schedule/skip", handler.SkipSchedule)
+	mux.HandleFunc("POST /workloads/{id}/schedule/override", handler.OverrideSchedule)
+
+	// Called by agents when instances start or stop
+	mux.HandleFunc("GET /workloads/hooks", handler.ListInstanceHooks)
+	mux.HandleFunc("POST /workloads/hooks/results", handler.RecordHookResult)
+
+	// Hook webhooks agents could not deliver
+	mux.HandleFunc("GET /workloads/hooks/dead-letters", handler.ListDeadLetters)
+}
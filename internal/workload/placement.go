@@ -0,0 +1,196 @@
+package workload
+
+import (
+	"context"
+	"strconv"
+
+	"mcloud/internal/database"
+	"mcloud/internal/node"
+)
+
+// benchmarkKeys are the node attributes combined into a placement score.
+var benchmarkKeys = []string{node.AttrBenchmarkCPU, node.AttrBenchmarkDisk, node.AttrBenchmarkNetwork}
+
+// placeNode picks the node of the cluster an unpinned workload is launched on,
+// or returns nil to let LXD place it.
+//
+// Nodes that do not match req.NodeSelector or have a NoSchedule taint req does
+// not tolerate are never picked, nor are nodes with an untolerated
+// PreferNoSchedule taint while another node fits. Among the nodes left,
+// scheduler.weight_benchmark picks the fastest benchmarked one. LXD places the
+// workload itself only when no node has a label constraint to honour: with a
+// node selector or any taint in the cluster, the node with the fewest
+// workloads is picked instead, and ErrNoEligibleNode returned if none fits.
+func (s *Service) placeNode(ctx context.Context, clusterID string, req *CreateRequest) (*database.Node, error) {
+	nodes, err := database.NewNodeRepository(s.db).ListByCluster(ctx, clusterID, database.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	rows, err := database.NewNodeLabelRepository(s.db).ListByCluster(ctx, clusterID)
+	if err != nil {
+		return nil, err
+	}
+	labels := node.GroupLabels(rows)
+
+	constrained := len(req.NodeSelector) > 0 || hasTaints(labels)
+	if constrained {
+		if nodes = eligibleNodes(nodes, labels, req.NodeSelector, req.Tolerations); len(nodes) == 0 {
+			return nil, ErrNoEligibleNode
+		}
+	}
+
+	if s.cfg.Scheduler.WeightBenchmark {
+		attrRepo := database.NewNodeAttributeRepository(s.db)
+		attrs := map[string][]database.NodeAttribute{}
+		for _, key := range benchmarkKeys {
+			if attrs[key], err = attrRepo.ListByKey(ctx, key); err != nil {
+				return nil, err
+			}
+		}
+		if n := fastestNode(nodes, attrs); n != nil {
+			return n, nil
+		}
+	}
+	if !constrained {
+		return nil, nil
+	}
+
+	workloads, err := database.NewWorkloadRepository(s.db).ListByCluster(ctx, clusterID, database.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return leastLoadedNode(nodes, workloads), nil
+}
+
+func hasTaints(labels map[string]*node.Labels) bool {
+	for _, l := range labels {
+		if len(l.Taints) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// eligibleNodes returns the online nodes whose labels match every key and
+// value of selector and whose NoSchedule taints are all tolerated. Of those,
+// the nodes whose PreferNoSchedule taints are tolerated too are returned if
+// there are any.
+func eligibleNodes(nodes []database.Node, labels map[string]*node.Labels, selector map[string]string, tolerations []node.Toleration) []database.Node {
+	var eligible, preferred []database.Node
+	for _, n := range nodes {
+		if n.Status != "online" {
+			continue
+		}
+		l := labels[n.ID]
+		if l == nil {
+			l = &node.Labels{}
+		}
+		if !matchesSelector(l.Labels, selector) {
+			continue
+		}
+		hard, soft := untolerated(l.Taints, tolerations)
+		if hard {
+			continue
+		}
+		eligible = append(eligible, n)
+		if !soft {
+			preferred = append(preferred, n)
+		}
+	}
+	if len(preferred) > 0 {
+		return preferred
+	}
+	return eligible
+}
+
+func matchesSelector(labels map[string]string, selector map[string]string) bool {
+	for k, v := range selector {
+		if got, ok := labels[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
+
+// untolerated reports whether taints has a NoSchedule (hard) or a
+// PreferNoSchedule (soft) taint none of tolerations tolerates.
+func untolerated(taints []node.Taint, tolerations []node.Toleration) (hard bool, soft bool) {
+	for _, t := range taints {
+		tolerated := false
+		for _, tol := range tolerations {
+			if tol.Tolerates(t) {
+				tolerated = true
+				break
+			}
+		}
+		switch {
+		case tolerated:
+		case t.Effect == node.EffectPreferNoSchedule:
+			soft = true
+		default:
+			hard = true
+		}
+	}
+	return hard, soft
+}
+
+// leastLoadedNode returns the node running the fewest of workloads, the first
+// one on a tie.
+func leastLoadedNode(nodes []database.Node, workloads []database.Workload) *database.Node {
+	count := map[string]int{}
+	for _, w := range workloads {
+		if w.NodeID != nil {
+			count[*w.NodeID]++
+		}
+	}
+	var picked *database.Node
+	for i := range nodes {
+		if picked == nil || count[nodes[i].ID] < count[picked.ID] {
+			picked = &nodes[i]
+		}
+	}
+	return picked
+}
+
+// fastestNode picks the online node with the highest combined benchmark score from
+// the benchmark attributes of every node, keyed by attribute key. Unparseable or
+// non-positive scores are ignored; nil means no online node has a score.
+//
+// Each score is normalised against the best node for that score, so CPU, disk and
+// network weigh equally regardless of their units.
+func fastestNode(nodes []database.Node, attrs map[string][]database.NodeAttribute) *database.Node {
+	// scores[key][nodeID]
+	scores := map[string]map[string]float64{}
+	best := map[string]float64{}
+	for _, key := range benchmarkKeys {
+		scores[key] = map[string]float64{}
+		for _, a := range attrs[key] {
+			v, err := strconv.ParseFloat(a.Value, 64)
+			if err != nil || v <= 0 {
+				continue
+			}
+			scores[key][a.NodeID] = v
+			best[key] = max(best[key], v)
+		}
+	}
+
+	var picked *database.Node
+	var pickedScore float64
+	for i := range nodes {
+		n := &nodes[i]
+		if n.Status != "online" {
+			continue
+		}
+
+		total := 0.0
+		for _, key := range benchmarkKeys {
+			if v, ok := scores[key][n.ID]; ok {
+				total += v / best[key]
+			}
+		}
+		if total > pickedScore {
+			picked, pickedScore = n, total
+		}
+	}
+	return picked
+}
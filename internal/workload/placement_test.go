@@ -0,0 +1,173 @@
+package workload
+
+import (
+	"slices"
+	"testing"
+
+	"mcloud/internal/database"
+	"mcloud/internal/node"
+)
+
+func TestFastestNode(t *testing.T) {
+	nodes := []database.Node{
+		{ID: "n1", Hostname: "node1", Status: "online"},
+		{ID: "n2", Hostname: "node2", Status: "online"},
+		{ID: "n3", Hostname: "node3", Status: "offline"},
+	}
+	attr := func(nodeID string, value string) database.NodeAttribute {
+		return database.NodeAttribute{NodeID: nodeID, Value: value}
+	}
+
+	tests := []struct {
+		name  string
+		attrs map[string][]database.NodeAttribute
+		want  string // hostname; "" for nil
+	}{
+		{
+			name:  "no benchmarks",
+			attrs: nil,
+			want:  "",
+		},
+		{
+			name: "single score",
+			attrs: map[string][]database.NodeAttribute{
+				node.AttrBenchmarkCPU: {attr("n1", "100"), attr("n2", "200")},
+			},
+			want: "node2",
+		},
+		{
+			name: "scores normalised per key",
+			// node1: 1.0 + 0.1 + 1.0 = 2.1; node2: 0.5 + 1.0 + 0.5 = 2.0
+			attrs: map[string][]database.NodeAttribute{
+				node.AttrBenchmarkCPU:     {attr("n1", "1000"), attr("n2", "500")},
+				node.AttrBenchmarkDisk:    {attr("n1", "50"), attr("n2", "500")},
+				node.AttrBenchmarkNetwork: {attr("n1", "10000"), attr("n2", "5000")},
+			},
+			want: "node1",
+		},
+		{
+			name: "missing score counts as zero",
+			attrs: map[string][]database.NodeAttribute{
+				node.AttrBenchmarkCPU:  {attr("n1", "100"), attr("n2", "90")},
+				node.AttrBenchmarkDisk: {attr("n2", "10")},
+			},
+			want: "node2",
+		},
+		{
+			name: "offline node is never picked",
+			attrs: map[string][]database.NodeAttribute{
+				node.AttrBenchmarkCPU: {attr("n1", "100"), attr("n3", "1000")},
+			},
+			want: "node1",
+		},
+		{
+			name: "invalid and non-positive scores are ignored",
+			attrs: map[string][]database.NodeAttribute{
+				node.AttrBenchmarkCPU: {attr("n1", "fast"), attr("n2", "-5")},
+			},
+			want: "",
+		},
+		{
+			name: "tie keeps the first node",
+			attrs: map[string][]database.NodeAttribute{
+				node.AttrBenchmarkCPU: {attr("n1", "100"), attr("n2", "100")},
+			},
+			want: "node1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fastestNode(nodes, tt.attrs)
+			switch {
+			case got == nil && tt.want != "":
+				t.Errorf("fastestNode = nil, want %s", tt.want)
+			case got != nil && got.Hostname != tt.want:
+				t.Errorf("fastestNode = %s, want %q", got.Hostname, tt.want)
+			}
+		})
+	}
+}
+
+func TestEligibleNodes(t *testing.T) {
+	nodes := []database.Node{
+		{ID: "n1", Hostname: "node1", Status: "online"},
+		{ID: "n2", Hostname: "node2", Status: "online"},
+		{ID: "n3", Hostname: "node3", Status: "online"},
+		{ID: "n4", Hostname: "node4", Status: "offline"},
+	}
+	labels := node.GroupLabels([]database.NodeLabel{
+		{NodeID: "n1", Kind: database.NodeLabelKindLabel, Key: "zone", Value: "a"},
+		{NodeID: "n2", Kind: database.NodeLabelKindLabel, Key: "zone", Value: "a"},
+		{NodeID: "n2", Kind: database.NodeLabelKindTaint, Key: "gpu", Value: "true", Effect: node.EffectNoSchedule},
+		{NodeID: "n3", Kind: database.NodeLabelKindLabel, Key: "zone", Value: "b"},
+		{NodeID: "n3", Kind: database.NodeLabelKindTaint, Key: "spot", Effect: node.EffectPreferNoSchedule},
+		{NodeID: "n4", Kind: database.NodeLabelKindLabel, Key: "zone", Value: "b"},
+	})
+
+	tests := []struct {
+		name        string
+		selector    map[string]string
+		tolerations []node.Toleration
+		want        []string
+	}{
+		{
+			name: "tainted nodes are avoided",
+			want: []string{"node1"},
+		},
+		{
+			name:     "selector",
+			selector: map[string]string{"zone": "a"},
+			want:     []string{"node1"},
+		},
+		{
+			name:        "toleration of any value",
+			selector:    map[string]string{"zone": "a"},
+			tolerations: []node.Toleration{{Key: "gpu"}},
+			want:        []string{"node1", "node2"},
+		},
+		{
+			name:        "toleration of another value",
+			tolerations: []node.Toleration{{Key: "gpu", Value: "false"}},
+			want:        []string{"node1"},
+		},
+		{
+			name:        "toleration of another effect",
+			tolerations: []node.Toleration{{Key: "gpu", Effect: node.EffectPreferNoSchedule}},
+			want:        []string{"node1"},
+		},
+		{
+			name:     "preferred taint when no other node fits",
+			selector: map[string]string{"zone": "b"},
+			want:     []string{"node3"},
+		},
+		{
+			name:     "no node matches",
+			selector: map[string]string{"zone": "c"},
+			want:     nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, n := range eligibleNodes(nodes, labels, tt.selector, tt.tolerations) {
+				got = append(got, n.Hostname)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("eligibleNodes = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLeastLoadedNode(t *testing.T) {
+	nodes := []database.Node{{ID: "n1", Hostname: "node1"}, {ID: "n2", Hostname: "node2"}, {ID: "n3", Hostname: "node3"}}
+	n1, n2 := "n1", "n2"
+	workloads := []database.Workload{{NodeID: &n1}, {NodeID: &n1}, {NodeID: &n2}, {}}
+
+	if got := leastLoadedNode(nodes, workloads); got.Hostname != "node3" {
+		t.Errorf("leastLoadedNode = %s, want node3", got.Hostname)
+	}
+	if got := leastLoadedNode(nodes[:2], workloads); got.Hostname != "node2" {
+		t.Errorf("leastLoadedNode(node1, node2) = %s, want node2", got.Hostname)
+	}
+}
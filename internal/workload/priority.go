@@ -0,0 +1,18 @@
+package workload
+
+import "mcloud/internal/database"
+
+// evacuateModes maps a priority class to the LXD cluster.evacuate setting of the
+// instance, which decides what 'lxc cluster evacuate' does with it when its node
+// is drained:
+//   low    → stop: stopped in place (the node service stops these first)
+//   normal → auto: migrated if its storage allows, otherwise stopped
+//   high   → migrate: always migrated to another member
+//
+// Low-priority workloads are also the ones stopped when a node runs out of
+// memory; see node.Service.RecordMetrics.
+var evacuateModes = map[string]string{
+	database.PriorityLow:    "stop",
+	database.PriorityNormal: "auto",
+	database.PriorityHigh:   "migrate",
+}
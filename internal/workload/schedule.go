@@ -0,0 +1,347 @@
+package workload
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"mcloud/internal/database"
+	"mcloud/internal/event"
+	"mcloud/pkg/reason"
+	"mcloud/services/lxd"
+)
+
+// ScheduleInterval is how often the workload-schedules job checks for due actions.
+const ScheduleInterval = time.Minute
+
+var ErrScheduleNotFound = reason.New(reason.ScheduleNotFound, "workload has no schedule")
+
+const (
+	scheduleStop  = "stop"
+	scheduleStart = "start"
+)
+
+var weekdays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// ScheduleRequest sets a workload's power schedule. Times are "HH:MM" in the
+// manager's local time; at least one of Stop or Start is required. Days limits
+// the schedule to some weekdays, as a list or range ("mon-fri", "sat,sun").
+//
+// Example JSON:
+//   {"stop": "01:00", "start": "07:00", "days": "mon-fri"}
+type ScheduleRequest struct {
+	Stop  string `json:"stop,omitempty"`
+	Start string `json:"start,omitempty"`
+	Days  string `json:"days,omitempty"`
+}
+
+// OverrideRequest pauses a workload's schedule for a while, e.g. to keep a lab
+// VM running through the night. A zero duration lifts an earlier skip or override.
+type OverrideRequest struct {
+	DurationSeconds int `json:"duration_seconds"`
+}
+
+// parseClock parses "HH:MM" into minutes after midnight.
+func parseClock(v string) (int, error) {
+	t, err := time.Parse("15:04", v)
+	if err != nil {
+		return 0, fmt.Errorf("%w: time %q must be HH:MM", ErrInvalidRequest, v)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// parseDays normalises a weekday list such as "mon-fri" or "sat, sun" to
+// "mon,tue,wed,thu,fri". An empty list means every day.
+func parseDays(v string) (string, error) {
+	if strings.TrimSpace(v) == "" {
+		return "", nil
+	}
+
+	index := func(d string) (int, error) {
+		d = strings.ToLower(strings.TrimSpace(d))
+		for i, w := range weekdays {
+			if d == w {
+				return i, nil
+			}
+		}
+		return 0, fmt.Errorf("%w: unknown weekday %q (use mon, tue, ... sun)", ErrInvalidRequest, d)
+	}
+
+	selected := make([]bool, 7)
+	for _, part := range strings.Split(v, ",") {
+		from, to, isRange := strings.Cut(part, "-")
+		first, err := index(from)
+		if err != nil {
+			return "", err
+		}
+		last := first
+		if isRange {
+			if last, err = index(to); err != nil {
+				return "", err
+			}
+		}
+		// Ranges may wrap around the week, e.g. fri-mon
+		for d := first; ; d = (d + 1) % 7 {
+			selected[d] = true
+			if d == last {
+				break
+			}
+		}
+	}
+
+	// Monday first, the way people write weekday lists
+	var out []string
+	for i := 1; i <= 7; i++ {
+		if selected[i%7] {
+			out = append(out, weekdays[i%7])
+		}
+	}
+	return strings.Join(out, ","), nil
+}
+
+// scheduledAction is one stop or start due at a point in time.
+type scheduledAction struct {
+	action string
+	at     time.Time
+}
+
+// actionsOn returns the schedule's actions on the day of t (local time), if the
+// schedule applies to that weekday.
+func actionsOn(s *database.WorkloadSchedule, t time.Time) []scheduledAction {
+	if s.Days != "" && !strings.Contains(s.Days, weekdays[t.Weekday()]) {
+		return nil
+	}
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+
+	var out []scheduledAction
+	for action, clock := range map[string]*string{scheduleStop: s.StopAt, scheduleStart: s.StartAt} {
+		if clock == nil {
+			continue
+		}
+		minutes, err := parseClock(*clock)
+		if err != nil {
+			continue
+		}
+		out = append(out, scheduledAction{action: action, at: midnight.Add(time.Duration(minutes) * time.Minute)})
+	}
+	return out
+}
+
+// lastAction returns the most recent action due at or before now, looking back a week.
+func lastAction(s *database.WorkloadSchedule, now time.Time) (scheduledAction, bool) {
+	var best scheduledAction
+	found := false
+	for i := 0; i <= 7; i++ {
+		for _, a := range actionsOn(s, now.Local().AddDate(0, 0, -i)) {
+			if !a.at.After(now) && (!found || a.at.After(best.at)) {
+				best, found = a, true
+			}
+		}
+		if found {
+			break
+		}
+	}
+	return best, found
+}
+
+// nextAction returns the first action due after now, looking ahead a week.
+func nextAction(s *database.WorkloadSchedule, now time.Time) (scheduledAction, bool) {
+	var best scheduledAction
+	found := false
+	for i := 0; i <= 7; i++ {
+		for _, a := range actionsOn(s, now.Local().AddDate(0, 0, i)) {
+			if a.at.After(now) && (!found || a.at.Before(best.at)) {
+				best, found = a, true
+			}
+		}
+		if found {
+			break
+		}
+	}
+	return best, found
+}
+
+// SetSchedule creates or replaces a workload's power schedule. Only actions due
+// after now are run, so setting a stop time that has already passed today does
+// not stop the workload straight away.
+//
+// Example Input:
+//   SetSchedule(ctx, "550e8400-...", &ScheduleRequest{Stop: "01:00", Start: "07:00", Days: "mon-fri"})
+//
+// Example Output:
+//   WorkloadSchedule{WorkloadID: "550e8400-...", StopAt: "01:00", StartAt: "07:00", Days: "mon,tue,wed,thu,fri"}
+//
+// Example Output (Error - Bad Time):
+//   Returns: error(`invalid workload request: time "25:00" must be HH:MM`)
+func (s *Service) SetSchedule(ctx context.Context, id string, req *ScheduleRequest) (*database.WorkloadSchedule, error) {
+	if req.Stop == "" && req.Start == "" {
+		return nil, fmt.Errorf("%w: schedule needs a stop or start time", ErrInvalidRequest)
+	}
+	sched := &database.WorkloadSchedule{WorkloadID: id}
+	if req.Stop != "" {
+		if _, err := parseClock(req.Stop); err != nil {
+			return nil, err
+		}
+		sched.StopAt = &req.Stop
+	}
+	if req.Start != "" {
+		if _, err := parseClock(req.Start); err != nil {
+			return nil, err
+		}
+		sched.StartAt = &req.Start
+	}
+	if req.Stop == req.Start {
+		return nil, fmt.Errorf("%w: stop and start times must differ", ErrInvalidRequest)
+	}
+	days, err := parseDays(req.Days)
+	if err != nil {
+		return nil, err
+	}
+	sched.Days = days
+
+	if _, err := s.GetWorkload(ctx, id); err != nil {
+		return nil, err
+	}
+
+	repo := database.NewWorkloadScheduleRepository(s.db)
+	if err := repo.Upsert(ctx, sched, time.Now()); err != nil {
+		return nil, err
+	}
+	return repo.GetByWorkload(ctx, id)
+}
+
+// DeleteSchedule removes a workload's power schedule; the workload keeps its current state.
+func (s *Service) DeleteSchedule(ctx context.Context, id string) error {
+	err := database.NewWorkloadScheduleRepository(s.db).DeleteByWorkload(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrScheduleNotFound
+	}
+	return err
+}
+
+// SkipSchedule skips the next scheduled action of a workload, e.g. tonight's
+// stop while an experiment runs overnight. Later actions run as usual.
+//
+// Example Output:
+//   WorkloadSchedule{..., SkipUntil: "2026-01-04T01:00:00+07:00"}
+func (s *Service) SkipSchedule(ctx context.Context, id string) (*database.WorkloadSchedule, error) {
+	repo := database.NewWorkloadScheduleRepository(s.db)
+	sched, err := s.schedule(ctx, repo, id)
+	if err != nil {
+		return nil, err
+	}
+
+	next, ok := nextAction(sched, time.Now())
+	if !ok {
+		return nil, fmt.Errorf("%w: schedule has no upcoming action", ErrInvalidRequest)
+	}
+	if err := repo.SetSkipUntil(ctx, id, &next.at); err != nil {
+		return nil, err
+	}
+	return repo.GetByWorkload(ctx, id)
+}
+
+// OverrideSchedule suspends a workload's schedule for req.DurationSeconds, so it
+// can be started or stopped by hand without the schedule undoing it. Actions due
+// during the override are skipped, not run afterwards.
+func (s *Service) OverrideSchedule(ctx context.Context, id string, req *OverrideRequest) (*database.WorkloadSchedule, error) {
+	if req.DurationSeconds < 0 {
+		return nil, fmt.Errorf("%w: duration must not be negative", ErrInvalidRequest)
+	}
+	repo := database.NewWorkloadScheduleRepository(s.db)
+	if _, err := s.schedule(ctx, repo, id); err != nil {
+		return nil, err
+	}
+
+	var until *time.Time
+	if req.DurationSeconds > 0 {
+		t := time.Now().Add(time.Duration(req.DurationSeconds) * time.Second)
+		until = &t
+	}
+	if err := repo.SetSkipUntil(ctx, id, until); err != nil {
+		return nil, err
+	}
+	return repo.GetByWorkload(ctx, id)
+}
+
+func (s *Service) schedule(ctx context.Context, repo *database.WorkloadScheduleRepository, id string) (*database.WorkloadSchedule, error) {
+	sched, err := repo.GetByWorkload(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrScheduleNotFound
+	}
+	return sched, err
+}
+
+// ApplySchedules is the periodic job that stops and starts workloads according
+// to their schedules. Each action runs once: an action missed while no manager
+// was running is applied on the next run, unless a later action is already due.
+func (s *Service) ApplySchedules(ctx context.Context) error {
+	repo := database.NewWorkloadScheduleRepository(s.db)
+	schedules, err := repo.ListWithWorkloads(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var errs []error
+	for _, sw := range schedules {
+		due, ok := lastAction(&sw.WorkloadSchedule, now)
+		if !ok || (sw.LastActionAt != nil && !due.at.After(*sw.LastActionAt)) {
+			continue
+		}
+
+		if sw.SkipUntil != nil && !due.at.After(*sw.SkipUntil) {
+			s.scheduleEvent(ctx, &sw, event.WorkloadScheduleSkipped,
+				fmt.Sprintf("Skipped scheduled %s of workload %s", due.action, sw.Name))
+		} else if err := s.applyScheduledAction(ctx, &sw, due.action); err != nil {
+			// Retried on the next run
+			errs = append(errs, err)
+			continue
+		}
+
+		if err := repo.SetLastActionAt(ctx, sw.WorkloadID, due.at); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// applyScheduledAction stops or starts the workload's instance if it is not
+// already in that state. Workloads still being created or failed are left alone.
+func (s *Service) applyScheduledAction(ctx context.Context, sw *database.ScheduledWorkload, action string) error {
+	workloadRepo := database.NewWorkloadRepository(s.db)
+	ctx = lxd.WithProject(ctx, sw.Project)
+	switch {
+	case action == scheduleStop && sw.Status == "running":
+		if err := lxd.StopInstance(ctx, sw.Name); err != nil {
+			s.scheduleEvent(ctx, sw, event.WorkloadScheduleFailed, fmt.Sprintf("Scheduled stop of workload %s failed: %v", sw.Name, err))
+			return err
+		}
+		if err := workloadRepo.UpdateStatus(ctx, sw.WorkloadID, "stopped"); err != nil {
+			return err
+		}
+		s.scheduleEvent(ctx, sw, event.WorkloadScheduledStop, fmt.Sprintf("Stopped workload %s on schedule", sw.Name))
+
+	case action == scheduleStart && sw.Status == "stopped":
+		if err := lxd.StartInstance(ctx, sw.Name); err != nil {
+			s.scheduleEvent(ctx, sw, event.WorkloadScheduleFailed, fmt.Sprintf("Scheduled start of workload %s failed: %v", sw.Name, err))
+			return err
+		}
+		if err := workloadRepo.UpdateStatus(ctx, sw.WorkloadID, "running"); err != nil {
+			return err
+		}
+		s.scheduleEvent(ctx, sw, event.WorkloadScheduledStart, fmt.Sprintf("Started workload %s on schedule", sw.Name))
+	}
+	return nil
+}
+
+func (s *Service) scheduleEvent(ctx context.Context, sw *database.ScheduledWorkload, eventType string, message string) {
+	e := event.New(eventType, message, nil)
+	e.ClusterID, e.NodeID = &sw.ClusterID, sw.NodeID
+	if err := database.NewEventRepository(s.db).Create(ctx, e); err != nil {
+		log.Error("Failed to record event %s: %v", eventType, err)
+	}
+}
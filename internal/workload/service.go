@@ -0,0 +1,636 @@
+package workload
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+
+	"mcloud/internal/audit"
+	"mcloud/internal/config"
+	"mcloud/internal/database"
+	"mcloud/internal/event"
+	"mcloud/internal/flavor"
+	"mcloud/internal/node"
+	"mcloud/internal/operation"
+	"mcloud/internal/project"
+	"mcloud/internal/secret"
+	"mcloud/pkg/logger"
+	"mcloud/pkg/reason"
+	"mcloud/pkg/utils"
+	"mcloud/services/lxd"
+)
+
+var log = logger.Named("workload")
+
+var (
+	ErrWorkloadNotFound = reason.New(reason.WorkloadNotFound, "workload not found")
+	ErrNodeNotFound     = reason.New(reason.TargetNodeNotFound, "target node not found")
+	ErrNameExists       = reason.New(reason.WorkloadNameExists, "a workload with this name already exists")
+	ErrNameRequired     = reason.New(reason.WorkloadNameRequired, "workload name is required")
+	ErrInvalidRequest   = reason.New(reason.InvalidWorkload, "invalid workload request")
+	ErrNoCluster        = reason.New(reason.ClusterNotInitialized, "cluster is not initialized")
+	ErrStorageUnhealthy = reason.New(reason.StorageUnhealthy, "ceph storage is unhealthy (HEALTH_ERR)")
+	ErrNoEligibleNode   = reason.New(reason.NoEligibleNode, "no online node matches the node selector and tolerates its taints")
+)
+
+var envKeyRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+type Service struct {
+	db         *sql.DB
+	cfg        *config.Config
+	secrets    *secret.Service
+	flavors    *flavor.Service
+	projects   *project.Service
+	operations *operation.Service
+	sessions   *audit.Sessions
+}
+
+// CreateRequest launches a new workload.
+//
+// Example JSON:
+//   {
+//     "name": "web-1",
+//     "kind": "container",
+//     "image": "ubuntu:24.04",
+//     "node_id": "550e8400-e29b-41d4-a716-446655440000",
+//     "storage_pool": "ceph-default",
+//     "priority": "high",
+//     "flavor": "medium",
+//     "project": "team-a",
+//     "env": {"APP_ENV": "production"},
+//     "secrets": {"DB_PASSWORD": "db-password"},
+//     "hooks": [{"event": "on-failure", "webhook": "https://alerts.example.com/mcloud"}],
+//     "user_data": "#cloud-config\npackages: [nginx]\n"
+//   }
+//
+// Secrets maps an environment variable name to the name of a secret in the secrets backend.
+// Only the reference is stored; the value is resolved at launch time.
+// Hooks are run by the agent on the instance's node; see HookSpec.
+// Priority is low, normal (the default) or high; see evacuateModes.
+// Flavor sizes the instance through the flavor's LXD profile.
+// Project is the tenant the workload belongs to (default "default"); the
+// launch is refused if the flavor would take it over its quota.
+// UserData and NetworkConfig are handed to cloud-init in the instance; only
+// their hash is stored (see cloudInitConfig).
+// Without a node_id, the workload goes to a node whose labels match every key
+// of NodeSelector (e.g. {"zone": "a"}) and whose taints it tolerates (e.g.
+// [{"key": "gpu", "effect": "NoSchedule"}]); see placeNode. A node_id is used
+// as given.
+type CreateRequest struct {
+	Name        string            `json:"name"`
+	Kind        string            `json:"kind"`
+	Image       string            `json:"image"`
+	NodeID      string            `json:"node_id,omitempty"`
+	StoragePool string            `json:"storage_pool,omitempty"` // root disk pool; default profile pool when empty
+	Priority    string            `json:"priority,omitempty"`
+	Flavor      string            `json:"flavor,omitempty"`  // see flavor.Service
+	Project     string            `json:"project,omitempty"` // see project.Service
+	Env         map[string]string `json:"env,omitempty"`
+	Secrets     map[string]string `json:"secrets,omitempty"`
+	Hooks       []HookSpec        `json:"hooks,omitempty"`
+
+	NodeSelector map[string]string `json:"node_selector,omitempty"`
+	Tolerations  []node.Toleration `json:"tolerations,omitempty"`
+
+	// cloud-init config, applied as user.user-data / user.network-config
+	UserData      string `json:"user_data,omitempty"`
+	NetworkConfig string `json:"network_config,omitempty"`
+}
+
+// WorkloadDetail is a workload together with its environment, hooks and power schedule.
+// Secret-backed variables expose only the secret name, never the value.
+type WorkloadDetail struct {
+	database.Workload
+	Env      map[string]string          `json:"env"`
+	Secrets  map[string]string          `json:"secrets"`
+	Hooks    []database.WorkloadHook    `json:"hooks"`
+	Schedule *database.WorkloadSchedule `json:"schedule,omitempty"`
+}
+
+type CloneRequest struct {
+	Name         string `json:"name"`
+	Snapshot     string `json:"snapshot,omitempty"`
+	TargetNodeID string `json:"target_node_id,omitempty"`
+}
+
+func NewService(db *sql.DB, cfg *config.Config, secrets *secret.Service, flavors *flavor.Service, projects *project.Service, operations *operation.Service, sessions *audit.Sessions) *Service {
+	return &Service{
+		db:         db,
+		cfg:        cfg,
+		secrets:    secrets,
+		flavors:    flavors,
+		projects:   projects,
+		operations: operations,
+		sessions:   sessions,
+	}
+}
+
+// AsyncResult is returned by requests that finish in a background operation.
+// Workload is the record as created before the operation ran (status "pending");
+// the operation's result holds the final state.
+type AsyncResult struct {
+	Operation *database.Operation `json:"operation"`
+	Workload  *database.Workload  `json:"workload"`
+}
+
+func validateCreateRequest(req *CreateRequest) error {
+	if req.Name == "" {
+		return ErrNameRequired
+	}
+	if req.Image == "" {
+		return fmt.Errorf("%w: image is required", ErrInvalidRequest)
+	}
+	if req.Kind == "" {
+		req.Kind = "container"
+	}
+	if req.Kind != "container" && req.Kind != "vm" {
+		return fmt.Errorf("%w: kind must be container or vm", ErrInvalidRequest)
+	}
+	if req.Priority == "" {
+		req.Priority = database.PriorityNormal
+	}
+	if _, ok := evacuateModes[req.Priority]; !ok {
+		return fmt.Errorf("%w: priority must be low, normal or high", ErrInvalidRequest)
+	}
+	if req.Project == "" {
+		req.Project = database.DefaultProject
+	}
+	for k := range req.Env {
+		if !envKeyRegexp.MatchString(k) {
+			return fmt.Errorf("%w: invalid environment variable name %q", ErrInvalidRequest, k)
+		}
+	}
+	for k := range req.Secrets {
+		if !envKeyRegexp.MatchString(k) {
+			return fmt.Errorf("%w: invalid environment variable name %q", ErrInvalidRequest, k)
+		}
+		if _, ok := req.Env[k]; ok {
+			return fmt.Errorf("%w: %q is set both as env and secret", ErrInvalidRequest, k)
+		}
+	}
+	for k, v := range req.NodeSelector {
+		if err := node.ValidateLabel(k, v); err != nil {
+			return fmt.Errorf("%w: node_selector: %v", ErrInvalidRequest, err)
+		}
+	}
+	for _, tol := range req.Tolerations {
+		if err := node.ValidateLabel(tol.Key, tol.Value); err != nil {
+			return fmt.Errorf("%w: tolerations: %v", ErrInvalidRequest, err)
+		}
+		if tol.Effect != "" {
+			if err := node.ValidateEffect(tol.Effect); err != nil {
+				return fmt.Errorf("%w: tolerations: %v", ErrInvalidRequest, err)
+			}
+		}
+	}
+	return validateCloudInit(req.UserData, req.NetworkConfig)
+}
+
+func validateCloneRequest(req *CloneRequest) error {
+	if req.Name == "" {
+		return ErrNameRequired
+	}
+	return nil
+}
+
+// CloneWorkload duplicates an existing workload under a new name using LXD copy.
+// The copy can be taken from a snapshot of the source and placed on a different node;
+// when no target node is given it stays on the same node as the source.
+// The clone gets the source's environment variables, secret references and hooks.
+// The copy runs in a background "workload.clone" operation.
+func (s *Service) CloneWorkload(ctx context.Context, id string, req *CloneRequest, actor *string) (*AsyncResult, error) {
+	// 1. Validate
+	if err := validateCloneRequest(req); err != nil {
+		return nil, err
+	}
+
+	workloadRepo := database.NewWorkloadRepository(s.db)
+	nodeRepo := database.NewNodeRepository(s.db)
+
+	// 2. Load source workload
+	source, err := workloadRepo.GetByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWorkloadNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. Name must be unique in the cluster
+	_, err = workloadRepo.GetByName(ctx, source.ClusterID, req.Name)
+	if err == nil {
+		return nil, ErrNameExists
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	// 4. Resolve target node (defaults to the source node)
+	nodeID := source.NodeID
+	if req.TargetNodeID != "" {
+		nodeID = &req.TargetNodeID
+	}
+	var targetHost string
+	if nodeID != nil {
+		node, err := nodeRepo.GetByID(ctx, *nodeID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNodeNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		targetHost = node.Hostname
+	}
+
+	// The clone counts against the source's project like a new launch
+	var fl *database.Flavor
+	if source.Flavor != nil {
+		fl, err = s.flavors.Get(ctx, *source.Flavor)
+		if err != nil && !errors.Is(err, flavor.ErrFlavorNotFound) {
+			return nil, err
+		}
+	}
+	if err := s.projects.Authorize(ctx, source.Project, deref(actor), database.ProjectRoleMember); err != nil {
+		return nil, err
+	}
+
+	// lxc copy carries the source's environment.* config, resolved secrets included,
+	// so the clone gets the same env references and hooks; secret rotation and the
+	// secret in-use check then cover it too
+	env, err := database.NewWorkloadEnvRepository(s.db).ListByWorkload(ctx, source.ID)
+	if err != nil {
+		return nil, err
+	}
+	hooks, err := database.NewWorkloadHookRepository(s.db).ListByWorkload(ctx, source.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 5. Record the clone as pending before touching LXD (TRANSACTION ONLY)
+	clone := &database.Workload{
+		ID:            utils.GenerateUUID(),
+		ClusterID:     source.ClusterID,
+		NodeID:        nodeID,
+		Name:          req.Name,
+		Kind:          source.Kind,
+		Status:        "pending",
+		Image:         source.Image,
+		Priority:      source.Priority, // lxc copy keeps cluster.evacuate too
+		Addresses:     database.AddressList{},
+		Flavor:        source.Flavor,        // and its profiles
+		Project:       source.Project,
+		CloudInitHash: source.CloudInitHash, // and user.user-data / user.network-config
+	}
+	err = database.WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		if err := s.checkQuota(ctx, tx, source.Project, fl); err != nil {
+			return err
+		}
+		if err := database.NewWorkloadRepositoryTx(tx).Create(ctx, clone); err != nil {
+			return err
+		}
+		envRepo := database.NewWorkloadEnvRepositoryTx(tx)
+		for _, e := range env {
+			e.WorkloadID = clone.ID
+			if err := envRepo.Create(ctx, &e); err != nil {
+				return err
+			}
+		}
+		hookRepo := database.NewWorkloadHookRepositoryTx(tx)
+		for _, h := range hooks {
+			h.ID = utils.GenerateUUID()
+			h.WorkloadID = clone.ID
+			if err := hookRepo.Create(ctx, &h); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 6. LXD COPY (SIDE EFFECT, in the background)
+	snapshot := req.Snapshot
+	op, err := s.operations.Start(ctx, "workload.clone", "/workloads/"+clone.ID, actor,
+		func(ctx context.Context, report operation.Reporter) (any, error) {
+			ctx = lxd.WithProject(ctx, source.Project)
+			report(10, "Copying instance "+source.Name)
+			copyErr := lxd.CopyInstance(ctx, lxd.CopyConfig{
+				Source:     source.Name,
+				Snapshot:   snapshot,
+				Name:       clone.Name,
+				TargetNode: targetHost,
+			})
+			if copyErr != nil {
+				// ctx may be the one that timed out; the status must still be recorded
+				_ = workloadRepo.UpdateStatus(context.WithoutCancel(ctx), clone.ID, "failed")
+				return nil, fmt.Errorf("failed to clone workload %s: %w", source.Name, copyErr)
+			}
+
+			// Copies are created stopped
+			if err := workloadRepo.UpdateStatus(ctx, clone.ID, "stopped"); err != nil {
+				return nil, err
+			}
+			return workloadRepo.GetByID(ctx, clone.ID)
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return &AsyncResult{Operation: op, Workload: clone}, nil
+}
+
+// checkQuota refuses a launch of flavor fl (nil for none) in a project that
+// would go over its quota; the error wraps project.ErrQuotaExceeded. It runs
+// in the write transaction recording the workload, so concurrent launches
+// cannot both take the last of a quota.
+func (s *Service) checkQuota(ctx context.Context, tx *sql.Tx, name string, fl *database.Flavor) error {
+	demand := database.ProjectUsage{Instances: 1}
+	if fl != nil {
+		demand.CPUs, demand.MemoryMB, demand.DiskGB = fl.CPUs, fl.MemoryMB, fl.DiskGB
+	}
+	err := s.projects.CheckQuotaTx(ctx, tx, name, demand)
+	if errors.Is(err, project.ErrProjectNotFound) {
+		return fmt.Errorf("%w: project %s does not exist", ErrInvalidRequest, name)
+	}
+	return err
+}
+
+// Authorize returns project.ErrProjectDenied unless identity is a member of
+// the workload's project.
+func (s *Service) Authorize(ctx context.Context, id string, identity string) error {
+	w, err := database.NewWorkloadRepository(s.db).GetByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrWorkloadNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return s.projects.Authorize(ctx, w.Project, identity, database.ProjectRoleMember)
+}
+
+// ListWorkloads calls fn for one page of the workloads identity may read, by
+// default ordered by creation time. Filtering by a project the identity is
+// not a member of returns project.ErrProjectDenied; without a project filter
+// the page only holds workloads of projects it is a member of.
+func (s *Service) ListWorkloads(ctx context.Context, opts database.ListOptions, identity string, fn func(*database.Workload) error) error {
+	repo := database.NewWorkloadRepository(s.db)
+	if p := opts.Filter["project"]; p != "" {
+		if err := s.projects.Authorize(ctx, p, identity, database.ProjectRoleMember); err != nil {
+			return err
+		}
+		return repo.Each(ctx, opts, fn)
+	}
+	projects, err := s.projects.Readable(ctx, identity)
+	if err != nil {
+		return err
+	}
+	return repo.EachInProjects(ctx, projects, opts, fn)
+}
+
+// CreateWorkload launches a new instance in LXD and records it.
+// Environment variables and resolved secrets are injected as LXD environment.* config
+// at creation; only plain variables and secret references are stored in the database.
+// Without a node_id, placeNode picks the node from the request's node selector and
+// tolerations and, with scheduler.weight_benchmark, the benchmark scores.
+// Validation and the database record happen before returning; the LXD launch runs in a
+// background "workload.create" operation whose result is the WorkloadDetail.
+func (s *Service) CreateWorkload(ctx context.Context, req *CreateRequest, actor *string) (*AsyncResult, error) {
+	// 1. Validate
+	if err := validateCreateRequest(req); err != nil {
+		return nil, err
+	}
+	if err := s.validateHooks(req.Hooks); err != nil {
+		return nil, err
+	}
+
+	workloadRepo := database.NewWorkloadRepository(s.db)
+
+	clusters, err := database.NewClusterRepository(s.db).List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(clusters) == 0 {
+		return nil, ErrNoCluster
+	}
+	clusterID := clusters[0].ID
+
+	_, err = workloadRepo.GetByName(ctx, clusterID, req.Name)
+	if err == nil {
+		return nil, ErrNameExists
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	// 2. Resolve target node
+	var nodeID *string
+	var targetHost string
+	if req.NodeID != "" {
+		node, err := database.NewNodeRepository(s.db).GetByID(ctx, req.NodeID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNodeNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		nodeID = &node.ID
+		targetHost = node.Hostname
+	} else {
+		node, err := s.placeNode(ctx, clusterID, req)
+		if err != nil {
+			return nil, err
+		}
+		if node != nil {
+			nodeID = &node.ID
+			targetHost = node.Hostname
+		}
+	}
+
+	// Refuse to place new disks on a Ceph cluster that is in HEALTH_ERR
+	if err := checkStoragePool(req.StoragePool); err != nil {
+		return nil, err
+	}
+
+	var fl *database.Flavor
+	if req.Flavor != "" {
+		fl, err = s.flavors.Get(ctx, req.Flavor)
+		if errors.Is(err, flavor.ErrFlavorNotFound) {
+			return nil, fmt.Errorf("%w: flavor %s does not exist", ErrInvalidRequest, req.Flavor)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := s.projects.Authorize(ctx, req.Project, deref(actor), database.ProjectRoleMember); err != nil {
+		return nil, err
+	}
+
+	// 3. Build instance config (secret values only live in memory here)
+	instanceConfig := map[string]string{"cluster.evacuate": evacuateModes[req.Priority]}
+	for k, v := range req.Env {
+		instanceConfig["environment."+k] = v
+	}
+	for k, secretName := range req.Secrets {
+		value, err := s.secrets.Resolve(ctx, secretName)
+		if err != nil {
+			return nil, err
+		}
+		instanceConfig["environment."+k] = value
+	}
+	cloudInit, cloudInitHash := cloudInitConfig(req.UserData, req.NetworkConfig)
+	maps.Copy(instanceConfig, cloudInit)
+
+	// 4. Persist workload and env references (TRANSACTION ONLY)
+	w := &database.Workload{
+		ID:            utils.GenerateUUID(),
+		ClusterID:     clusterID,
+		NodeID:        nodeID,
+		Name:          req.Name,
+		Kind:          req.Kind,
+		Status:        "pending",
+		Image:         req.Image,
+		Priority:      req.Priority,
+		Project:       req.Project,
+		CloudInitHash: cloudInitHash,
+	}
+	if fl != nil {
+		w.Flavor = &fl.Name
+	}
+	err = database.WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		if err := s.checkQuota(ctx, tx, req.Project, fl); err != nil {
+			return err
+		}
+		if err := database.NewWorkloadRepositoryTx(tx).Create(ctx, w); err != nil {
+			return err
+		}
+		envRepo := database.NewWorkloadEnvRepositoryTx(tx)
+		for k, v := range req.Env {
+			value := v
+			if err := envRepo.Create(ctx, &database.WorkloadEnv{WorkloadID: w.ID, Key: k, Value: &value}); err != nil {
+				return err
+			}
+		}
+		for k, secretName := range req.Secrets {
+			name := secretName
+			if err := envRepo.Create(ctx, &database.WorkloadEnv{WorkloadID: w.ID, Key: k, SecretName: &name}); err != nil {
+				return err
+			}
+		}
+		if err := createHooks(ctx, tx, w.ID, req.Hooks); err != nil {
+			return err
+		}
+		return database.NewEventRepositoryTx(tx).Create(ctx, event.WorkloadCreatedEvent(w))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 5. LXD LAUNCH (SIDE EFFECT, in the background)
+	launch := lxd.LaunchConfig{
+		Name:       w.Name,
+		Image:      w.Image,
+		VM:         w.Kind == "vm",
+		TargetNode: targetHost,
+		Pool:       req.StoragePool,
+		Config:     instanceConfig,
+	}
+	if fl != nil {
+		launch.Profiles = []string{"default", flavor.ProfileName(fl.Name)}
+		if req.StoragePool != "" {
+			launch.RootSize = flavor.RootSize(fl)
+		}
+	}
+	op, err := s.operations.Start(ctx, "workload.create", "/workloads/"+w.ID, actor,
+		func(ctx context.Context, report operation.Reporter) (any, error) {
+			ctx = lxd.WithProject(ctx, w.Project)
+
+			// The profile is (re)applied at every launch, in the workload's
+			// project, so that it always matches the flavor's row
+			if fl != nil {
+				report(5, "Applying flavor "+fl.Name)
+				if err := applyFlavor(ctx, fl); err != nil {
+					_ = workloadRepo.UpdateStatus(context.WithoutCancel(ctx), w.ID, "failed")
+					return nil, err
+				}
+			}
+
+			report(10, "Launching instance "+w.Name)
+			if launchErr := lxd.LaunchInstance(ctx, launch); launchErr != nil {
+				_ = workloadRepo.UpdateStatus(context.WithoutCancel(ctx), w.ID, "failed")
+				return nil, launchErr
+			}
+
+			report(90, "Recording workload status")
+			if err := workloadRepo.UpdateStatus(ctx, w.ID, "running"); err != nil {
+				return nil, err
+			}
+			return s.GetWorkload(ctx, w.ID)
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return &AsyncResult{Operation: op, Workload: w}, nil
+}
+
+// GetWorkload returns a workload with its environment (secret values excluded).
+func (s *Service) GetWorkload(ctx context.Context, id string) (*WorkloadDetail, error) {
+	w, err := database.NewWorkloadRepository(s.db).GetByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrWorkloadNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.detail(ctx, w)
+}
+
+func (s *Service) detail(ctx context.Context, w *database.Workload) (*WorkloadDetail, error) {
+	env, err := database.NewWorkloadEnvRepository(s.db).ListByWorkload(ctx, w.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	hooks, err := database.NewWorkloadHookRepository(s.db).ListByWorkload(ctx, w.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule, err := database.NewWorkloadScheduleRepository(s.db).GetByWorkload(ctx, w.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	d := &WorkloadDetail{
+		Workload: *w,
+		Env:      map[string]string{},
+		Secrets:  map[string]string{},
+		Hooks:    hooks,
+		Schedule: schedule,
+	}
+	if d.Hooks == nil {
+		d.Hooks = []database.WorkloadHook{}
+	}
+	if d.Addresses == nil {
+		d.Addresses = database.AddressList{} // not known until the next address sync
+	}
+	for _, e := range env {
+		if e.SecretName != nil {
+			d.Secrets[e.Key] = *e.SecretName
+		} else if e.Value != nil {
+			d.Env[e.Key] = *e.Value
+		}
+	}
+	return d, nil
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,144 @@
+package workload
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"mcloud/internal/database"
+	"mcloud/internal/event"
+	"mcloud/services/lxd"
+)
+
+// statusRetry is how long WatchStatus waits before reconnecting to LXD's
+// event stream.
+const statusRetry = 10 * time.Second
+
+// statusEvents are the event types recorded when a workload's status changes.
+var statusEvents = map[string]string{
+	"running": event.WorkloadStarted,
+	"stopped": event.WorkloadStopped,
+	"failed":  event.WorkloadFailed,
+}
+
+// WatchStatus keeps workload.status in step with LXD until ctx is cancelled:
+// it follows LXD's lifecycle events and, on every (re)connect, resyncs all
+// workloads from `lxc list` to catch up on events it missed. Each change is
+// recorded as a workload.started, workload.stopped or workload.failed event.
+func (s *Service) WatchStatus(ctx context.Context) {
+	for {
+		if err := s.SyncStatuses(ctx); err != nil {
+			log.Warn("Failed to sync workload statuses: %v", err)
+		}
+		err := lxd.WatchLifecycle(ctx, func(e lxd.LifecycleEvent) {
+			if err := s.onLifecycle(ctx, e); err != nil {
+				log.Warn("Failed to apply LXD event %s of %s: %v", e.Action, e.Instance, err)
+			}
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		log.Warn("Lost the LXD event stream, reconnecting in %s: %v", statusRetry, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(statusRetry):
+		}
+	}
+}
+
+// SyncStatuses sets the status of every workload from its instance's state.
+func (s *Service) SyncStatuses(ctx context.Context) error {
+	states, err := lxd.InstanceStates()
+	if err != nil {
+		return err
+	}
+	clusters, err := database.NewClusterRepository(s.db).List(ctx)
+	if err != nil {
+		return err
+	}
+	workloadRepo := database.NewWorkloadRepository(s.db)
+	for _, c := range clusters {
+		workloads, err := workloadRepo.ListByCluster(ctx, c.ID, database.ListOptions{})
+		if err != nil {
+			return err
+		}
+		for i := range workloads {
+			st, ok := states[workloads[i].Name]
+			if !ok {
+				continue
+			}
+			if err := s.applyStatus(ctx, &workloads[i], st.Status, "found "+st.Status+" on sync"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// onLifecycle applies an LXD lifecycle event to the workload of its instance,
+// reading the instance's status after the event.
+func (s *Service) onLifecycle(ctx context.Context, e lxd.LifecycleEvent) error {
+	if e.Action == "instance-deleted" {
+		return nil // a workload deleted outside mcloud is drift (see internal/reconcile)
+	}
+	clusters, err := database.NewClusterRepository(s.db).List(ctx)
+	if err != nil {
+		return err
+	}
+	for _, c := range clusters {
+		w, err := database.NewWorkloadRepository(s.db).GetByName(ctx, c.ID, e.Instance)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue // not a workload of this cluster
+		}
+		if err != nil {
+			return err
+		}
+		status, err := lxd.InstanceStatus(lxd.WithProject(ctx, e.Project), e.Instance)
+		if err != nil {
+			return err
+		}
+		return s.applyStatus(ctx, w, status, fmt.Sprintf("LXD %s on %s", e.Action, e.Location))
+	}
+	return nil
+}
+
+// applyStatus records lxdStatus (e.g. Running) as the workload's status if it
+// changed. Workloads still being created are left to the launch, and
+// transitional statuses such as Frozen are ignored.
+//
+// Example Output:
+//   event workload.stopped: "Workload web-1 is stopped (was running): LXD instance-stopped on node2"
+func (s *Service) applyStatus(ctx context.Context, w *database.Workload, lxdStatus string, cause string) error {
+	status, ok := workloadStatus(lxdStatus)
+	if !ok || w.Status == status || w.Status == "pending" {
+		return nil
+	}
+	if err := database.NewWorkloadRepository(s.db).UpdateStatus(ctx, w.ID, status); err != nil {
+		return err
+	}
+	log.Info("Workload %s is %s (was %s): %s", w.Name, status, w.Status, cause)
+	e := event.New(statusEvents[status], fmt.Sprintf("Workload %s is %s (was %s): %s", w.Name, status, w.Status, cause),
+		event.WorkloadPayload{Workload: w.Name, ID: w.ID, Status: status, Previous: w.Status})
+	e.ClusterID, e.NodeID = &w.ClusterID, w.NodeID
+	if err := database.NewEventRepository(s.db).Create(ctx, e); err != nil {
+		log.Error("Failed to record event %s: %v", statusEvents[status], err)
+	}
+	return nil
+}
+
+// workloadStatus maps an LXD instance status to a workload status; an
+// instance LXD reports in error is a failed workload.
+func workloadStatus(lxdStatus string) (string, bool) {
+	switch lxdStatus {
+	case "Running":
+		return "running", true
+	case "Stopped":
+		return "stopped", true
+	case "Error":
+		return "failed", true
+	}
+	return "", false
+}
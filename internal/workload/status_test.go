@@ -0,0 +1,60 @@
+package workload
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"mcloud/internal/database"
+)
+
+func TestApplyStatus(t *testing.T) {
+	db, err := database.Connect(filepath.Join(t.TempDir(), "mcloud.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close(db)
+	ctx := context.Background()
+	s := &Service{db: db}
+
+	if err := database.NewClusterRepository(db).Create(ctx, &database.Cluster{ID: "c1", Name: "test", State: "active"}); err != nil {
+		t.Fatal(err)
+	}
+	repo := database.NewWorkloadRepository(db)
+	for _, w := range []*database.Workload{
+		{ID: "w1", ClusterID: "c1", Name: "web-1", Kind: "container", Status: "running", Image: "ubuntu:24.04", Priority: "normal"},
+		{ID: "w2", ClusterID: "c1", Name: "web-2", Kind: "container", Status: "pending", Image: "ubuntu:24.04", Priority: "normal"},
+	} {
+		if err := repo.Create(ctx, w); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tt := range []struct {
+		id, lxdStatus, want string
+	}{
+		{"w1", "Stopped", "stopped"},
+		{"w1", "Frozen", "stopped"}, // transitional, ignored
+		{"w1", "Error", "failed"},
+		{"w2", "Running", "pending"}, // left to the launch
+	} {
+		w, err := repo.GetByID(ctx, tt.id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.applyStatus(ctx, w, tt.lxdStatus, "test"); err != nil {
+			t.Fatal(err)
+		}
+		if w, _ = repo.GetByID(ctx, tt.id); w.Status != tt.want {
+			t.Errorf("%s after %s: status %s, want %s", tt.id, tt.lxdStatus, w.Status, tt.want)
+		}
+	}
+
+	events, err := database.NewEventRepository(db).ListByCluster(ctx, "c1", database.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].Type != "workload.failed" || events[1].Type != "workload.stopped" {
+		t.Errorf("events: got %+v, want workload.failed and before it workload.stopped", events)
+	}
+}
@@ -0,0 +1,50 @@
+package workload
+
+import (
+	"context"
+	"fmt"
+
+	"mcloud/internal/database"
+	"mcloud/internal/flavor"
+	"mcloud/services/lxd"
+	"mcloud/services/microceph"
+)
+
+// checkStoragePool returns ErrStorageUnhealthy when pool (or the default profile's
+// pool if empty) is backed by Ceph and Ceph reports HEALTH_ERR. Non-Ceph pools are
+// not checked.
+func checkStoragePool(pool string) error {
+	if pool == "" {
+		var err error
+		if pool, err = lxd.DefaultStoragePool(); err != nil {
+			return err
+		}
+	}
+
+	driver, err := lxd.StoragePoolDriver(pool)
+	if err != nil {
+		return err
+	}
+	if driver != "ceph" {
+		return nil
+	}
+
+	status, err := microceph.Status()
+	if err != nil {
+		return err
+	}
+	if status.Health == microceph.HealthErr {
+		return fmt.Errorf("%w: %v", ErrStorageUnhealthy, status.Checks)
+	}
+	return nil
+}
+
+// applyFlavor creates or updates the LXD profile of a flavor, with its root
+// disk on the default profile's pool.
+func applyFlavor(ctx context.Context, f *database.Flavor) error {
+	pool, err := lxd.DefaultStoragePool()
+	if err != nil {
+		return err
+	}
+	return lxd.ApplyProfile(ctx, flavor.Profile(f, pool))
+}
@@ -0,0 +1,143 @@
+package workload
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"mcloud/internal/database"
+	"mcloud/services/lxd"
+)
+
+// UpdateRequest is a JSON merge patch (RFC 7386) of a workload's editable fields.
+// A null value removes a variable; hooks, when present, replace the whole list.
+//
+// Example JSON:
+//   {
+//     "priority": "low",
+//     "env": {"APP_ENV": "staging", "DEBUG": null},
+//     "secrets": {"DB_PASSWORD": "db-password-v2"},
+//     "hooks": [{"event": "on-failure", "webhook": "https://alerts.example.com/mcloud"}]
+//   }
+type UpdateRequest struct {
+	Env      map[string]*string `json:"env,omitempty"`
+	Secrets  map[string]*string `json:"secrets,omitempty"`
+	Hooks    *[]HookSpec        `json:"hooks,omitempty"`
+	Priority *string            `json:"priority,omitempty"`
+}
+
+// applyPatch applies a merge patch to a set of variables in place.
+func applyPatch(vars map[string]string, patch map[string]*string) {
+	for k, v := range patch {
+		if v == nil {
+			delete(vars, k)
+		} else {
+			vars[k] = *v
+		}
+	}
+}
+
+// UpdateWorkload applies a merge patch to a workload's environment, secret
+// references, hooks and priority. Changed variables and the priority's
+// cluster.evacuate mode are written to the LXD instance config first (variables
+// take effect on the next start), then to the database.
+func (s *Service) UpdateWorkload(ctx context.Context, id string, req *UpdateRequest) (*WorkloadDetail, error) {
+	current, err := s.GetWorkload(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// 1. Compute and validate the result of the patch
+	next := CreateRequest{
+		Name:     current.Name,
+		Kind:     current.Kind,
+		Image:    current.Image,
+		Env:      current.Env,
+		Secrets:  current.Secrets,
+		Priority: current.Priority,
+	}
+	if req.Priority != nil {
+		next.Priority = *req.Priority
+	}
+	applyPatch(next.Env, req.Env)
+	applyPatch(next.Secrets, req.Secrets)
+	if err := validateCreateRequest(&next); err != nil {
+		return nil, err
+	}
+	if req.Hooks != nil {
+		if err := s.validateHooks(*req.Hooks); err != nil {
+			return nil, err
+		}
+	}
+
+	// 2. Update the instance config (secret values only live in memory here)
+	set := map[string]string{}
+	var unset []string
+	for k, v := range req.Env {
+		if v != nil {
+			set["environment."+k] = *v
+		} else if _, ok := next.Secrets[k]; !ok {
+			unset = append(unset, "environment."+k)
+		}
+	}
+	for k, secretName := range req.Secrets {
+		if secretName == nil {
+			if _, ok := next.Env[k]; !ok {
+				unset = append(unset, "environment."+k)
+			}
+			continue
+		}
+		value, err := s.secrets.Resolve(ctx, *secretName)
+		if err != nil {
+			return nil, err
+		}
+		set["environment."+k] = value
+	}
+	if next.Priority != current.Priority {
+		set["cluster.evacuate"] = evacuateModes[next.Priority]
+	}
+	if len(set) > 0 || len(unset) > 0 {
+		if err := lxd.SetInstanceConfig(lxd.WithProject(ctx, current.Project), current.Name, set, unset); err != nil {
+			return nil, err
+		}
+	}
+
+	// 3. Replace env references, priority and hooks (TRANSACTION ONLY)
+	err = database.WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		envRepo := database.NewWorkloadEnvRepositoryTx(tx)
+		if err := envRepo.DeleteByWorkload(ctx, id); err != nil {
+			return err
+		}
+		for k, v := range next.Env {
+			value := v
+			if err := envRepo.Create(ctx, &database.WorkloadEnv{WorkloadID: id, Key: k, Value: &value}); err != nil {
+				return err
+			}
+		}
+		for k, secretName := range next.Secrets {
+			name := secretName
+			if err := envRepo.Create(ctx, &database.WorkloadEnv{WorkloadID: id, Key: k, SecretName: &name}); err != nil {
+				return err
+			}
+		}
+
+		if next.Priority != current.Priority {
+			if err := database.NewWorkloadRepositoryTx(tx).UpdatePriority(ctx, id, next.Priority); err != nil {
+				return err
+			}
+		}
+
+		if req.Hooks == nil {
+			return nil
+		}
+		if err := database.NewWorkloadHookRepositoryTx(tx).DeleteByWorkload(ctx, id); err != nil {
+			return err
+		}
+		return createHooks(ctx, tx, id, *req.Hooks)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("instance %s was updated but the workload record was not: %w", current.Name, err)
+	}
+
+	return s.GetWorkload(ctx, id)
+}
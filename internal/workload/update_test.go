@@ -0,0 +1,68 @@
+package workload
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApplyPatch(t *testing.T) {
+	str := func(s string) *string { return &s }
+
+	tests := []struct {
+		name  string
+		vars  map[string]string
+		patch map[string]*string
+		want  map[string]string
+	}{
+		{
+			name:  "nil patch",
+			vars:  map[string]string{"A": "1"},
+			patch: nil,
+			want:  map[string]string{"A": "1"},
+		},
+		{
+			name:  "add",
+			vars:  map[string]string{"A": "1"},
+			patch: map[string]*string{"B": str("2")},
+			want:  map[string]string{"A": "1", "B": "2"},
+		},
+		{
+			name:  "replace",
+			vars:  map[string]string{"A": "1"},
+			patch: map[string]*string{"A": str("3")},
+			want:  map[string]string{"A": "3"},
+		},
+		{
+			name:  "null removes",
+			vars:  map[string]string{"A": "1", "B": "2"},
+			patch: map[string]*string{"A": nil},
+			want:  map[string]string{"B": "2"},
+		},
+		{
+			name:  "null for missing key",
+			vars:  map[string]string{"A": "1"},
+			patch: map[string]*string{"C": nil},
+			want:  map[string]string{"A": "1"},
+		},
+		{
+			name:  "empty string is a value",
+			vars:  map[string]string{"A": "1"},
+			patch: map[string]*string{"A": str("")},
+			want:  map[string]string{"A": ""},
+		},
+		{
+			name:  "mixed",
+			vars:  map[string]string{"APP_ENV": "prod", "DEBUG": "1"},
+			patch: map[string]*string{"APP_ENV": str("staging"), "DEBUG": nil, "LOG": str("info")},
+			want:  map[string]string{"APP_ENV": "staging", "LOG": "info"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			applyPatch(tt.vars, tt.patch)
+			if !reflect.DeepEqual(tt.vars, tt.want) {
+				t.Errorf("applyPatch = %v, want %v", tt.vars, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+// Package api is the version contract of the mcloudd REST API, shared by the
+// server and its clients. Every route is served under Prefix (e.g.
+// /v1/workloads); a client may name the version it was written against in
+// VersionHeader and every response carries the version that served it. The
+// OpenAPI document of the version is served at Prefix + "/openapi.json".
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"mcloud/pkg/reason"
+)
+
+const (
+	Version       = "1"
+	Prefix        = "/v" + Version
+	VersionHeader = "X-Mcloud-API-Version"
+)
+
+// ErrUnsupportedVersion is returned for a request naming another version in
+// VersionHeader.
+var ErrUnsupportedVersion = reason.New(reason.UnsupportedAPIVersion, "unsupported API version")
+
+// Unversioned are routes that are also served without Prefix and are not
+// deprecated there: probes configured outside mcloud (systemd, monitors).
+var Unversioned = []string{"/healthz", "/readyz"}
+
+// Path returns the versioned path of a route.
+//
+// Example:
+//   Path("/workloads/abc")  =>  "/v1/workloads/abc"
+func Path(route string) string {
+	return Prefix + route
+}
+
+// Versioned serves next, whose routes are registered without Prefix, under
+// Prefix. Requests without Prefix are still served for clients older than
+// the versioned API, marked deprecated with a link to their versioned path.
+//
+// Example Output (Error):
+//   X-Mcloud-API-Version: 2  =>  400 ErrUnsupportedVersion
+func Versioned(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(VersionHeader, Version)
+		if v := r.Header.Get(VersionHeader); v != "" && v != Version {
+			reason.HTTPError(w, fmt.Errorf("%w %q, this server speaks %s", ErrUnsupportedVersion, v, Version), http.StatusBadRequest)
+			return
+		}
+
+		route, versioned := strings.CutPrefix(r.URL.Path, Prefix)
+		if !versioned || (route != "" && route[0] != '/') {
+			if !slices.Contains(Unversioned, r.URL.Path) {
+				w.Header().Set("Deprecation", "true")
+				w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", Path(r.URL.Path)))
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		r2 := r.Clone(r.Context())
+		r2.URL.Path = route
+		r2.URL.RawPath = ""
+		next.ServeHTTP(w, r2)
+	})
+}
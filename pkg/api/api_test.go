@@ -0,0 +1,48 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVersioned(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /workloads/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.PathValue("id")))
+	})
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {})
+	h := Versioned(mux)
+
+	tests := []struct {
+		path, version string
+		status        int
+		body          string
+		deprecated    bool
+	}{
+		{"/v1/workloads/abc", "", 200, "abc", false},
+		{"/v1/workloads/abc", "1", 200, "abc", false},
+		{"/v1/workloads/abc", "2", 400, "", false},
+		{"/workloads/abc", "", 200, "abc", true},
+		{"/v10/workloads/abc", "", 404, "", true},
+		{"/healthz", "", 200, "", false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		if tt.version != "" {
+			req.Header.Set(VersionHeader, tt.version)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		if rec.Code != tt.status || (tt.body != "" && rec.Body.String() != tt.body) {
+			t.Errorf("%s (version %q): got %d %q", tt.path, tt.version, rec.Code, rec.Body.String())
+		}
+		if rec.Header().Get(VersionHeader) != Version {
+			t.Errorf("%s: %s = %q", tt.path, VersionHeader, rec.Header().Get(VersionHeader))
+		}
+		if deprecated := rec.Header().Get("Deprecation") != ""; deprecated != tt.deprecated {
+			t.Errorf("%s: deprecated %v, want %v", tt.path, deprecated, tt.deprecated)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+// Package client is a Go client for the mcloudd REST API.
+//
+// It handles mutual TLS against the cluster CA (or the CA pinned by a join
+// string, see Join), retries of idempotent calls, transparent pagination of
+// list endpoints (ForEachNode, ForEachWorkload), typed calls for common tasks
+// (InitCluster, LaunchWorkload, WaitOperation) and long-lived event and config
+// streams with automatic reconnect (WatchEvents, WatchConfig), so integrators
+// don't have to re-implement them. Endpoints without a typed method are
+// reached with Do. mcloudctl and mcloud-agent call mcloudd through it.
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"mcloud/pkg/api"
+	"mcloud/pkg/reason"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// DefaultPageSize is the number of items requested per page by the iterators.
+const DefaultPageSize = 100
+
+// DefaultMaxRetries is how often an idempotent call is retried by default.
+const DefaultMaxRetries = 3
+
+// retryDelay is the delay before the first retry; it doubles with each one.
+const retryDelay = 500 * time.Millisecond
+
+// Config holds the connection settings for a Client.
+type Config struct {
+	BaseURL    string // e.g. https://192.168.1.10:9028
+	CACertPath string // cluster CA used to verify the server
+	CertPath   string // client certificate presented to the server
+	KeyPath    string // client private key
+
+	// HTTPClient, when set, is used instead of one built from the paths
+	// above, e.g. for certificates held in memory. Its Timeout applies to
+	// request/response calls; streams do not time out.
+	HTTPClient *http.Client
+
+	// MaxRetries is how often GET, HEAD, PUT and DELETE calls are retried
+	// after a network error or a 502, 503 or 504 answer (0 means
+	// DefaultMaxRetries, negative disables retries).
+	MaxRetries int
+}
+
+// Client is a typed client for the mcloudd REST API.
+type Client struct {
+	baseURL    string
+	http       *http.Client // request/response calls
+	streaming  *http.Client // long-lived streams (no overall timeout)
+	maxRetries int
+}
+
+// New creates a Client from the given configuration.
+func New(cfg Config) (*Client, error) {
+	c := &Client{baseURL: strings.TrimRight(cfg.BaseURL, "/"), maxRetries: cfg.MaxRetries}
+	if c.maxRetries == 0 {
+		c.maxRetries = DefaultMaxRetries
+	}
+
+	if cfg.HTTPClient != nil {
+		streaming := *cfg.HTTPClient
+		streaming.Timeout = 0
+		c.http, c.streaming = cfg.HTTPClient, &streaming
+		return c, nil
+	}
+
+	caBytes, err := os.ReadFile(cfg.CACertPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+	}
+	caPool := x509.NewCertPool()
+	if !caPool.AppendCertsFromPEM(caBytes) {
+		return nil, fmt.Errorf("invalid CA certificate: %s", cfg.CACertPath)
+	}
+
+	tlsConfig := &tls.Config{
+		RootCAs:    caPool,
+		MinVersion: tls.VersionTLS12,
+	}
+	if cfg.CertPath != "" {
+		clientCert, err := tls.LoadX509KeyPair(cfg.CertPath, cfg.KeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{clientCert}
+	}
+
+	// Pass the caller's trace context on, so its trace continues in mcloudd
+	transport := otelhttp.NewTransport(&http.Transport{TLSClientConfig: tlsConfig})
+	c.http = &http.Client{Timeout: 30 * time.Second, Transport: transport}
+	c.streaming = &http.Client{Transport: transport}
+	return c, nil
+}
+
+// APIError is returned when the server answers with a non-2xx status. Code is
+// the stable reason code of the error (see package reason); branch on it rather
+// than on Message.
+type APIError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Code       reason.Code
+	Reason     string
+	Message    string
+}
+
+// Error renders the call and the server's message.
+//
+// Example Output:
+//   GET /workloads/abc: 404 Not Found: workload not found
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s %s: %d %s: %s", e.Method, e.Path, e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// Unwrap exposes the reason code to reason.CodeOf and errors.As.
+func (e *APIError) Unwrap() error {
+	return &reason.Error{Code: e.Code, Reason: e.Reason, Message: e.Message}
+}
+
+// newAPIError builds an APIError from an error response.
+func newAPIError(req *http.Request, resp *http.Response, body []byte) *APIError {
+	e := reason.FromResponse(resp.StatusCode, resp.Header, body)
+	path := strings.TrimPrefix(req.URL.Path, api.Prefix)
+	return &APIError{Method: req.Method, Path: path, StatusCode: resp.StatusCode, Code: e.Code, Reason: e.Reason, Message: e.Message}
+}
+
+// Do sends body (if non-nil) as JSON to path, a route without the version
+// prefix such as /workloads, and decodes the JSON response into out (if
+// non-nil). Non-2xx answers are returned as *APIError.
+func (c *Client) Do(ctx context.Context, method string, path string, body any, out any) error {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	resp, err := c.send(ctx, method, path, data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// Download copies the body of GET path to w, for responses that are not
+// JSON (e.g. exec transcripts, logs). The client's timeout does not apply;
+// the copy runs until the body ends or ctx is cancelled.
+func (c *Client) Download(ctx context.Context, path string, w io.Writer) error {
+	req, err := c.newRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.streaming.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(resp.Body)
+		return newAPIError(req, resp, msg)
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+func (c *Client) newRequest(ctx context.Context, method string, path string, data []byte) (*http.Request, error) {
+	var reader io.Reader
+	if data != nil {
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+api.Path(path), reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(api.VersionHeader, api.Version)
+	if data != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+// send makes the call, retrying idempotent ones, and returns a 2xx response.
+func (c *Client) send(ctx context.Context, method string, path string, data []byte) (*http.Response, error) {
+	retries := 0
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		retries = max(c.maxRetries, 0)
+	}
+
+	delay := retryDelay
+	for attempt := 0; ; attempt++ {
+		req, err := c.newRequest(ctx, method, path, data)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := c.http.Do(req)
+		if err == nil && resp.StatusCode < 300 {
+			return resp, nil
+		}
+		if err == nil {
+			msg, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			err = newAPIError(req, resp, msg)
+			switch resp.StatusCode {
+			case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			default:
+				return nil, err
+			}
+		}
+		if attempt >= retries || ctx.Err() != nil {
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
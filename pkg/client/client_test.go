@@ -0,0 +1,69 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"mcloud/pkg/api"
+	"mcloud/pkg/reason"
+)
+
+func TestDo(t *testing.T) {
+	calls := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls[r.Method+" "+r.URL.Path]++
+		if r.Header.Get(api.VersionHeader) != api.Version {
+			t.Errorf("%s %s: version header %q", r.Method, r.URL.Path, r.Header.Get(api.VersionHeader))
+		}
+		switch r.URL.Path {
+		case "/v1/nodes":
+			if calls["GET /v1/nodes"] == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte(`{"nodes":[{"id":"n1"}]}`))
+		case "/v1/workloads":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			reason.HTTPError(w, reason.New(reason.WorkloadNotFound, "workload not found"), http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	c, err := New(Config{BaseURL: srv.URL + "/", HTTPClient: srv.Client(), MaxRetries: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	var out struct {
+		Nodes []struct{ ID string } `json:"nodes"`
+	}
+	if err := c.Do(ctx, http.MethodGet, "/nodes", nil, &out); err != nil || len(out.Nodes) != 1 {
+		t.Errorf("GET /nodes: got %+v, %v", out, err)
+	}
+	if calls["GET /v1/nodes"] != 2 {
+		t.Errorf("GET /nodes: %d calls, want a retry after 503", calls["GET /v1/nodes"])
+	}
+
+	// POST is not idempotent, so it is not retried
+	err = c.Do(ctx, http.MethodPost, "/workloads", map[string]string{"name": "web-1"}, nil)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("POST /workloads: got %v, want a 503 APIError", err)
+	}
+	if calls["POST /v1/workloads"] != 1 {
+		t.Errorf("POST /workloads: %d calls, want 1", calls["POST /v1/workloads"])
+	}
+
+	err = c.Do(ctx, http.MethodDelete, "/workloads/abc", nil, nil)
+	if err == nil || err.Error() != "DELETE /workloads/abc: 404 Not Found: workload not found" {
+		t.Errorf("DELETE /workloads/abc: got %v", err)
+	}
+	if reason.CodeOf(err, reason.Internal) != reason.WorkloadNotFound {
+		t.Errorf("DELETE /workloads/abc: code %s, want %s", reason.CodeOf(err, reason.Internal), reason.WorkloadNotFound)
+	}
+}
@@ -0,0 +1,64 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"time"
+
+	"mcloud/internal/auth"
+)
+
+// InitCluster asks the manager to initialise a cluster.
+func (c *Client) InitCluster(ctx context.Context, req InitClusterRequest) error {
+	return c.Do(ctx, http.MethodPost, "/cluster/init", req, nil)
+}
+
+// Version returns the build of the manager. It needs no client certificate.
+func (c *Client) Version(ctx context.Context) (*VersionInfo, error) {
+	var info VersionInfo
+	if err := c.Do(ctx, http.MethodGet, "/version", nil, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// Join connects to the manager named by a join string (printed by `mcloudctl
+// init`), trusting only the cluster CA the string pins: the CA the manager
+// presents is checked against the string's fingerprint before any call. cert
+// is the client certificate presented to the manager.
+//
+// Setting up the node itself (agent, LXD, Ceph) is done on the node by
+// `mcloudctl join`.
+//
+// Example Output (Error):
+//   Expired join string  =>  MC1021 TokenExpired: join token has expired at 2026-01-03 10:30:45
+func Join(ctx context.Context, joinString string, cert tls.Certificate) (*Client, error) {
+	token, err := auth.ParseJoinToken(joinString)
+	if err != nil {
+		return nil, err
+	}
+	if token.Expired(time.Now()) {
+		return nil, fmt.Errorf("%w at %s", auth.ErrJoinTokenExpired, token.ExpiresAt.Local().Format(time.DateTime))
+	}
+	caPEM, err := auth.FetchPinnedCA(ctx, token.Address, token.CAFingerprint)
+	if err != nil {
+		return nil, err
+	}
+
+	caPool := x509.NewCertPool()
+	caPool.AppendCertsFromPEM(caPEM)
+	return New(Config{
+		BaseURL: token.Address,
+		HTTPClient: &http.Client{
+			Timeout: 30 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{
+				RootCAs:      caPool,
+				Certificates: []tls.Certificate{cert},
+				MinVersion:   tls.VersionTLS12,
+			}},
+		},
+	})
+}
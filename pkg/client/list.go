@@ -0,0 +1,97 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+func pageQuery(opts ListOptions) string {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(limit))
+	query.Set("offset", strconv.Itoa(opts.Offset))
+	if opts.Sort != "" {
+		query.Set("sort", opts.Sort)
+	}
+	for k, v := range opts.Filter {
+		query.Set(k, v)
+	}
+	return "?" + query.Encode()
+}
+
+// ListNodes returns a single page of nodes.
+func (c *Client) ListNodes(ctx context.Context, opts ListOptions) (*NodePage, error) {
+	var page NodePage
+	if err := c.Do(ctx, http.MethodGet, "/nodes"+pageQuery(opts), nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// ForEachNode calls fn for every node in the cluster, fetching pages as needed.
+// Iteration stops at the first error returned by fn or by the API.
+func (c *Client) ForEachNode(ctx context.Context, fn func(Node) error) error {
+	return c.ForEachNodeMatching(ctx, ListOptions{}, fn)
+}
+
+// ForEachNodeMatching is ForEachNode with the sort and filters of opts.
+func (c *Client) ForEachNodeMatching(ctx context.Context, opts ListOptions, fn func(Node) error) error {
+	opts.Limit = DefaultPageSize
+	opts.Offset = 0
+	for {
+		page, err := c.ListNodes(ctx, opts)
+		if err != nil {
+			return err
+		}
+		for _, n := range page.Items {
+			if err := fn(n); err != nil {
+				return err
+			}
+		}
+		if page.NextOffset == 0 {
+			return nil
+		}
+		opts.Offset = page.NextOffset
+	}
+}
+
+// ListWorkloads returns a single page of workloads.
+func (c *Client) ListWorkloads(ctx context.Context, opts ListOptions) (*WorkloadPage, error) {
+	var page WorkloadPage
+	if err := c.Do(ctx, http.MethodGet, "/workloads"+pageQuery(opts), nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// ForEachWorkload calls fn for every workload in the cluster, fetching pages as needed.
+// Iteration stops at the first error returned by fn or by the API.
+func (c *Client) ForEachWorkload(ctx context.Context, fn func(Workload) error) error {
+	return c.ForEachWorkloadMatching(ctx, ListOptions{}, fn)
+}
+
+// ForEachWorkloadMatching is ForEachWorkload with the sort and filters of opts.
+func (c *Client) ForEachWorkloadMatching(ctx context.Context, opts ListOptions, fn func(Workload) error) error {
+	opts.Limit = DefaultPageSize
+	opts.Offset = 0
+	for {
+		page, err := c.ListWorkloads(ctx, opts)
+		if err != nil {
+			return err
+		}
+		for _, w := range page.Items {
+			if err := fn(w); err != nil {
+				return err
+			}
+		}
+		if page.NextOffset == 0 {
+			return nil
+		}
+		opts.Offset = page.NextOffset
+	}
+}
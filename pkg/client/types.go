@@ -0,0 +1,136 @@
+package client
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ListOptions selects one page of a list endpoint. Sort is a field name with
+// an optional "-" prefix for descending; Filter holds exact-match query
+// parameters such as {"status": "running"}. See the handler of each endpoint
+// for the fields it supports.
+type ListOptions struct {
+	Limit  int
+	Offset int
+	Sort   string
+	Filter map[string]string
+}
+
+type Node struct {
+	ID            string     `json:"id"`
+	ClusterID     string     `json:"cluster_id"`
+	Hostname      string     `json:"hostname"`
+	IP            string     `json:"ip"`
+	Role          string     `json:"role"`
+	Status        string     `json:"status"`
+	JoinedAt      time.Time  `json:"joined_at"`
+	LastHeartbeat *time.Time `json:"last_heartbeat"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+type NodePage struct {
+	Items      []Node `json:"items"`
+	NextOffset int    `json:"next_offset"`
+}
+
+type Workload struct {
+	ID        string    `json:"id"`
+	ClusterID string    `json:"cluster_id"`
+	NodeID    *string   `json:"node_id"`
+	Name      string    `json:"name"`
+	Kind      string    `json:"kind"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type WorkloadPage struct {
+	Items      []Workload `json:"items"`
+	NextOffset int        `json:"next_offset"`
+}
+
+type Event struct {
+	ID        int64           `json:"id"`
+	ClusterID *string         `json:"cluster_id"`
+	NodeID    *string         `json:"node_id"`
+	Type      string          `json:"type"`
+	Severity  string          `json:"severity"` // info, warning, error or critical
+	Message   string          `json:"message"`
+	Payload   json.RawMessage `json:"payload,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// ConfigChange is a write or deletion of a cluster config key.
+type ConfigChange struct {
+	Revision  int64     `json:"revision"`
+	Namespace string    `json:"namespace"`
+	Key       string    `json:"key"`
+	Value     *string   `json:"value,omitempty"` // nil when deleted
+	Version   int64     `json:"version"`
+	Deleted   bool      `json:"deleted"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// InitClusterRequest is the body of POST /cluster/init.
+type InitClusterRequest struct {
+	Name             string `json:"name"`
+	AdvertiseAddress string `json:"advertise_address"`
+}
+
+// LaunchRequest is the body of POST /workloads. Image is an LXD image alias
+// or fingerprint; Kind is "container" or "vm".
+//
+// Example JSON:
+//   {"name": "web-1", "kind": "container", "image": "ubuntu/24.04", "flavor": "small"}
+type LaunchRequest struct {
+	Name        string            `json:"name"`
+	Kind        string            `json:"kind"`
+	Image       string            `json:"image"`
+	NodeID      string            `json:"node_id,omitempty"`
+	StoragePool string            `json:"storage_pool,omitempty"`
+	Priority    string            `json:"priority,omitempty"`
+	Flavor      string            `json:"flavor,omitempty"`
+	Env         map[string]string `json:"env,omitempty"`
+	Secrets     map[string]string `json:"secrets,omitempty"` // variable -> secret name
+
+	UserData      string `json:"user_data,omitempty"`
+	NetworkConfig string `json:"network_config,omitempty"`
+}
+
+// LaunchResult is the workload being launched and the operation launching it.
+type LaunchResult struct {
+	Operation Operation `json:"operation"`
+	Workload  Workload  `json:"workload"`
+}
+
+// Operation statuses.
+const (
+	OperationPending   = "pending"
+	OperationRunning   = "running"
+	OperationSucceeded = "succeeded"
+	OperationFailed    = "failed"
+)
+
+// Operation is a long-running task on the manager, e.g. a launch.
+type Operation struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	Status    string          `json:"status"`
+	Progress  int             `json:"progress"`
+	Message   *string         `json:"message"`
+	Resource  *string         `json:"resource"`
+	Result    json.RawMessage `json:"result,omitempty"`
+	Error     *string         `json:"error"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// VersionInfo is the build of mcloudd, from GET /version.
+type VersionInfo struct {
+	Version    string `json:"version"`
+	Commit     string `json:"commit"`
+	BuildDate  string `json:"build_date"`
+	GoVersion  string `json:"go_version"`
+	APIVersion string `json:"api_version"`
+}
@@ -0,0 +1,178 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"mcloud/pkg/reason"
+)
+
+const (
+	minReconnectDelay = time.Second
+	maxReconnectDelay = 30 * time.Second
+)
+
+// ErrWatchUnsupported is returned by WatchEvents and WatchConfig when the server
+// has no such stream endpoint, e.g. an mcloudd older than the client.
+var ErrWatchUnsupported = reason.New(reason.NotFound, "mcloudd does not serve this watch stream")
+
+// errStopWatch wraps errors returned by the caller's callback so they end the watch
+// instead of triggering a reconnect.
+type errStopWatch struct{ err error }
+
+func (e errStopWatch) Error() string { return e.err.Error() }
+
+// WatchEvents streams cluster events from GET /events/stream (Server-Sent Events) and
+// calls fn for each one until ctx is cancelled or fn returns an error.
+//
+// Dropped connections are re-established with exponential backoff. The ID of the last
+// delivered event is sent back as Last-Event-ID, so no events are missed or repeated
+// across reconnects. A server without the endpoint fails with ErrWatchUnsupported.
+func (c *Client) WatchEvents(ctx context.Context, fn func(Event) error) error {
+	return c.watch(ctx, "/events/stream", func(data []byte) (int64, error) {
+		var e Event
+		if err := json.Unmarshal(data, &e); err != nil {
+			return 0, fmt.Errorf("invalid event payload: %w", err)
+		}
+		if err := fn(e); err != nil {
+			return 0, errStopWatch{err}
+		}
+		return e.ID, nil
+	})
+}
+
+// WatchConfig streams cluster config changes from GET /config/watch and calls fn
+// for each one until ctx is cancelled or fn returns an error. With a namespace
+// only that namespace's keys are watched. Reconnects work like WatchEvents.
+//
+// Example:
+//   c.WatchConfig(ctx, "agent", func(ch client.ConfigChange) error {
+//       log.Printf("%s/%s is now %v", ch.Namespace, ch.Key, ch.Value)
+//       return nil
+//   })
+func (c *Client) WatchConfig(ctx context.Context, namespace string, fn func(ConfigChange) error) error {
+	path := "/config/watch"
+	if namespace != "" {
+		path += "?namespace=" + url.QueryEscape(namespace)
+	}
+	return c.watch(ctx, path, func(data []byte) (int64, error) {
+		var ch ConfigChange
+		if err := json.Unmarshal(data, &ch); err != nil {
+			return 0, fmt.Errorf("invalid config change payload: %w", err)
+		}
+		if err := fn(ch); err != nil {
+			return 0, errStopWatch{err}
+		}
+		return ch.Revision, nil
+	})
+}
+
+// watch follows an SSE endpoint, reconnecting with exponential backoff and
+// resuming from the last ID handle returned. Errors wrapped in errStopWatch end
+// the watch; others reconnect.
+func (c *Client) watch(ctx context.Context, path string, handle func(data []byte) (int64, error)) error {
+	var lastID int64
+	delay := minReconnectDelay
+
+	for {
+		connected, err := c.stream(ctx, path, &lastID, handle)
+
+		var stop errStopWatch
+		if errors.As(err, &stop) {
+			return stop.err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if errors.Is(err, ErrWatchUnsupported) {
+			return err
+		}
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode < 500 {
+			// Client errors (bad request, unauthorized) won't fix themselves
+			return err
+		}
+
+		if connected {
+			delay = minReconnectDelay
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxReconnectDelay)
+	}
+}
+
+// stream runs one SSE connection. connected reports whether the stream was
+// established, which resets the reconnect backoff.
+func (c *Client) stream(ctx context.Context, path string, lastID *int64, handle func(data []byte) (int64, error)) (connected bool, err error) {
+	req, err := c.newRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if *lastID > 0 {
+		req.Header.Set("Last-Event-ID", strconv.FormatInt(*lastID, 10))
+	}
+
+	resp, err := c.streaming.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return false, fmt.Errorf("%w: GET %s", ErrWatchUnsupported, path)
+	}
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		return false, newAPIError(req, resp, msg)
+	}
+
+	// Parse the SSE wire format: "field: value" lines, events separated by a blank line
+	var data strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		switch {
+		case line == "":
+			if data.Len() == 0 {
+				continue
+			}
+			id, err := handle([]byte(data.String()))
+			data.Reset()
+			if err != nil {
+				return true, err
+			}
+			if id > *lastID {
+				*lastID = id
+			}
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		default:
+			// "id:", "event:", "retry:" and ":" keep-alive comments need no handling;
+			// the event ID is also carried in the JSON payload.
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return true, err
+	}
+	return true, errors.New("stream closed")
+}
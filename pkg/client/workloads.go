@@ -0,0 +1,57 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// OperationPollInterval is how often WaitOperation polls an operation.
+const OperationPollInterval = time.Second
+
+// LaunchWorkload creates a workload. The launch continues in the background;
+// wait for it with WaitOperation(ctx, result.Operation.ID).
+func (c *Client) LaunchWorkload(ctx context.Context, req LaunchRequest) (*LaunchResult, error) {
+	var result LaunchResult
+	if err := c.Do(ctx, http.MethodPost, "/workloads", req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetOperation returns an operation by ID.
+func (c *Client) GetOperation(ctx context.Context, id string) (*Operation, error) {
+	var op Operation
+	if err := c.Do(ctx, http.MethodGet, "/operations/"+url.PathEscape(id), nil, &op); err != nil {
+		return nil, err
+	}
+	return &op, nil
+}
+
+// WaitOperation polls an operation until it finishes or ctx is done. A failed
+// operation is returned together with an error carrying its message.
+func (c *Client) WaitOperation(ctx context.Context, id string) (*Operation, error) {
+	for {
+		op, err := c.GetOperation(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		switch op.Status {
+		case OperationSucceeded:
+			return op, nil
+		case OperationFailed:
+			if op.Error != nil {
+				return op, fmt.Errorf("operation %s failed: %s", op.ID, *op.Error)
+			}
+			return op, fmt.Errorf("operation %s failed", op.ID)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(OperationPollInterval):
+		}
+	}
+}
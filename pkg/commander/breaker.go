@@ -0,0 +1,213 @@
+package commander
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"mcloud/pkg/reason"
+)
+
+const (
+	// BreakerThreshold is the number of consecutive failures of a component after
+	// which its breaker opens and further commands fail fast.
+	BreakerThreshold = 5
+
+	// BreakerCooldown is how long an open breaker rejects commands before it
+	// lets one through as a probe.
+	BreakerCooldown = 30 * time.Second
+)
+
+// ErrCircuitOpen is returned instead of running a command while the breaker of
+// its component is open.
+var ErrCircuitOpen = reason.New(reason.CircuitOpen, "circuit breaker is open")
+
+// BreakerState is the state of a component's circuit breaker.
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"    // commands run normally
+	BreakerOpen     BreakerState = "open"      // commands fail fast with ErrCircuitOpen
+	BreakerHalfOpen BreakerState = "half-open" // one probe is running; its result closes or reopens the breaker
+)
+
+// breakerComponents maps the commands guarded by a breaker to their component.
+// Other commands (snap, lsblk, ...) always run.
+var breakerComponents = map[string]string{
+	"lxc":       "lxd",
+	"lxd":       "lxd",
+	"microceph": "microceph",
+	"microovn":  "microovn",
+}
+
+// breakerProbes are the cheap read-only commands ProbeBreakers uses to check
+// whether a component is back.
+var breakerProbes = map[string][]string{
+	"lxd":       {"lxc", "info"},
+	"microceph": {"microceph", "status"},
+	"microovn":  {"microovn", "status"},
+}
+
+// BreakerStatus is a snapshot of one component's breaker, as shown in health output.
+//
+// Example JSON:
+//   {"component": "microceph", "state": "open", "failures": 5,
+//    "last_error": "command execution failed: exit status 1: cannot communicate with server",
+//    "opened_at": "2026-01-03T10:30:45Z", "retry_at": "2026-01-03T10:31:15Z"}
+type BreakerStatus struct {
+	Component string       `json:"component"`
+	State     BreakerState `json:"state"`
+	Failures  int          `json:"failures"`
+	LastError string       `json:"last_error,omitempty"`
+	OpenedAt  *time.Time   `json:"opened_at,omitempty"`
+	RetryAt   *time.Time   `json:"retry_at,omitempty"`
+}
+
+type breaker struct {
+	component string
+	state     BreakerState
+	failures  int
+	lastError string
+	openedAt  time.Time
+}
+
+func (b *breaker) status() BreakerStatus {
+	st := BreakerStatus{Component: b.component, State: b.state, Failures: b.failures, LastError: b.lastError}
+	if b.state != BreakerClosed {
+		opened := b.openedAt
+		retry := opened.Add(BreakerCooldown)
+		st.OpenedAt, st.RetryAt = &opened, &retry
+	}
+	return st
+}
+
+var (
+	breakersMu sync.Mutex
+	breakers   = map[string]*breaker{}
+	onChange   []func(BreakerStatus)
+)
+
+// OnBreakerChange registers fn to be called (outside any lock) whenever a
+// breaker opens or closes, e.g. to set a node condition.
+func OnBreakerChange(fn func(BreakerStatus)) {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+	onChange = append(onChange, fn)
+}
+
+// Breakers returns the breakers of every guarded component sorted by name.
+// Components that never ran a command are reported closed.
+func Breakers() []BreakerStatus {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+
+	out := make([]BreakerStatus, 0, len(breakerProbes))
+	for component := range breakerProbes {
+		out = append(out, breakerFor(component).status())
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Component < out[j].Component })
+	return out
+}
+
+// breakerFor returns the breaker of a component. breakersMu must be held.
+func breakerFor(component string) *breaker {
+	b, ok := breakers[component]
+	if !ok {
+		b = &breaker{component: component, state: BreakerClosed}
+		breakers[component] = b
+	}
+	return b
+}
+
+// allowCommand decides whether a command of the named program may run now. An
+// open breaker past its cooldown turns half-open and lets this one command through
+// as the probe. component is empty for commands without a breaker.
+func allowCommand(name string) (component string, err error) {
+	component, ok := breakerComponents[name]
+	if !ok {
+		return "", nil
+	}
+
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+	b := breakerFor(component)
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.openedAt) < BreakerCooldown {
+			return component, fmt.Errorf("%w for %s (%d failures, last: %s)", ErrCircuitOpen, component, b.failures, b.lastError)
+		}
+		b.state = BreakerHalfOpen
+	case BreakerHalfOpen:
+		return component, fmt.Errorf("%w for %s (probe in progress)", ErrCircuitOpen, component)
+	}
+	return component, nil
+}
+
+// countsAsFailure reports whether a failed command says something about the
+// health of its component. Ordinary errors such as "instance not found" do not;
+// timeouts, daemons that cannot be reached and commands that could not run do.
+func countsAsFailure(err *CommandError) bool {
+	return err.ExitCode == -1 || IsTransient(err)
+}
+
+// recordResult updates a component's breaker with the outcome of a command.
+func recordResult(callerCtx context.Context, component string, cmdErr *CommandError) {
+	if component == "" {
+		return
+	}
+
+	breakersMu.Lock()
+	b := breakerFor(component)
+	before := b.state
+	switch {
+	case cmdErr == nil:
+		b.state, b.failures, b.lastError = BreakerClosed, 0, ""
+	case errors.Is(callerCtx.Err(), context.Canceled):
+		// The caller gave up, which says nothing about the component; a cancelled
+		// probe leaves the breaker open so the next command probes again
+		if b.state == BreakerHalfOpen {
+			b.state = BreakerOpen
+		}
+	case countsAsFailure(cmdErr):
+		b.failures++
+		b.lastError = cmdErr.Error()
+		if b.state == BreakerHalfOpen || b.failures >= BreakerThreshold {
+			b.state, b.openedAt = BreakerOpen, time.Now()
+		}
+	default:
+		// The component answered, even if the command itself was refused
+		b.state, b.failures, b.lastError = BreakerClosed, 0, ""
+	}
+	st := b.status()
+	listeners := onChange
+	breakersMu.Unlock()
+
+	// Half-open is a transition of its own; only report open ↔ closed
+	if (before == BreakerClosed) != (st.State == BreakerClosed) {
+		for _, fn := range listeners {
+			fn(st)
+		}
+	}
+}
+
+// ProbeBreakers runs the probe command of every component whose breaker is open
+// and past its cooldown, so a component that came back is noticed even when no
+// other command of it runs. Call it periodically.
+func ProbeBreakers(ctx context.Context) {
+	breakersMu.Lock()
+	var due []string
+	for component, b := range breakers {
+		if b.state == BreakerOpen && time.Since(b.openedAt) >= BreakerCooldown {
+			due = append(due, component)
+		}
+	}
+	breakersMu.Unlock()
+
+	for _, component := range due {
+		probe := breakerProbes[component]
+		_, _ = runCommand(ctx, BreakerCooldown, 0, probe[0], probe[1:]...)
+	}
+}
@@ -1,30 +1,22 @@
 package commander
 
 import (
-	"bytes"
+	"context"
 	"fmt"
 	"net"
 	"os/exec"
 )
 
-// ExecCommand runs an external command and returns its output or an error
+// ExecCommand runs an external command and returns its output or a *CommandError
 func ExecCommand(name string, args ...string) (string, error) {
-	// define command and arguments
-	cmd := exec.Command(name, args...)
-
-	// capture output and error
-	var out bytes.Buffer
-	var stderr bytes.Buffer
-	cmd.Stdout = &out
-	cmd.Stderr = &stderr
-
-	// run command
-	err := cmd.Run()
-	if err != nil {
-		return "", fmt.Errorf("command execution failed: %s: %s", err.Error(), stderr.String())
-	}
+	return ExecCommandContext(context.Background(), name, args...)
+}
 
-	return out.String(), nil
+// ExecCommandContext is like ExecCommand but kills the command when ctx is done,
+// e.g. because the request that started it was cancelled or its deadline passed.
+// The returned *CommandError then wraps the kill and ctx.Err() is set.
+func ExecCommandContext(ctx context.Context, name string, args ...string) (string, error) {
+	return runCommand(ctx, 0, 0, name, args...)
 }
 
 func CheckCommandExists(cmd string) error {
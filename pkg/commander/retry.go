@@ -0,0 +1,218 @@
+package commander
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer records a span per command run, a child of the caller's span.
+var tracer = otel.Tracer("mcloud/pkg/commander")
+
+// CommandError is returned when an external command fails.
+// Stdout and Stderr hold the (possibly truncated) output of the failed run.
+type CommandError struct {
+	Name     string
+	Args     []string
+	ExitCode int // -1 when the command did not exit normally (not found, killed, timed out)
+	Stdout   string
+	Stderr   string
+	TimedOut bool
+	Err      error
+}
+
+func (e *CommandError) Error() string {
+	return fmt.Sprintf("command execution failed: %s: %s", e.Err.Error(), e.Stderr)
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// RetryOptions controls ExecCommandWithRetry.
+type RetryOptions struct {
+	Attempts       int                      // total attempts including the first (default 3)
+	Timeout        time.Duration            // limit for each attempt; 0 means no limit
+	InitialBackoff time.Duration            // wait before the first retry, doubled after each (default 1s)
+	MaxBackoff     time.Duration            // upper bound for the wait between attempts (default 30s)
+	MaxOutputBytes int                      // stdout and stderr are each truncated to this size; 0 means no limit
+	Retryable      func(*CommandError) bool // decides whether a failure is retried (default IsTransient)
+}
+
+// DefaultRetryOptions retries transient failures three times with 1s, 2s backoff and
+// keeps up to 1 MiB of output. No per-attempt timeout is set, since launching or
+// copying an instance may legitimately take minutes.
+var DefaultRetryOptions = RetryOptions{
+	Attempts:       3,
+	InitialBackoff: time.Second,
+	MaxBackoff:     30 * time.Second,
+	MaxOutputBytes: 1 << 20,
+}
+
+// transientMessages are stderr fragments of failures where the command did not get
+// to do its work (snapd busy with a refresh, daemon restarting) and retrying is safe.
+var transientMessages = []string{
+	"change in progress",
+	"cannot communicate with server",
+	"connection refused",
+	"resource temporarily unavailable",
+	"database is locked",
+	"try again",
+}
+
+// IsTransient reports whether a command failure looks temporary: the attempt timed
+// out, or stderr matches a known "busy" message from snapd, LXD or the micro* daemons.
+func IsTransient(err *CommandError) bool {
+	if err.TimedOut {
+		return true
+	}
+	stderr := strings.ToLower(err.Stderr)
+	for _, msg := range transientMessages {
+		if strings.Contains(stderr, msg) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExecCommandWithRetry runs an external command, retrying transient failures with
+// exponential backoff. It stops early when ctx is cancelled and returns ctx's error
+// wrapped together with the last failure. Failures are returned as *CommandError.
+//
+// Example:
+//   out, err := ExecCommandWithRetry(ctx, DefaultRetryOptions, "lxc", "list", "--format", "json")
+//   var cmdErr *CommandError
+//   if errors.As(err, &cmdErr) { log.Printf("exit %d: %s", cmdErr.ExitCode, cmdErr.Stderr) }
+func ExecCommandWithRetry(ctx context.Context, opts RetryOptions, name string, args ...string) (string, error) {
+	if opts.Attempts <= 0 {
+		opts.Attempts = DefaultRetryOptions.Attempts
+	}
+	if opts.InitialBackoff <= 0 {
+		opts.InitialBackoff = DefaultRetryOptions.InitialBackoff
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = DefaultRetryOptions.MaxBackoff
+	}
+	if opts.Retryable == nil {
+		opts.Retryable = IsTransient
+	}
+
+	backoff := opts.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		out, err := runCommand(ctx, opts.Timeout, opts.MaxOutputBytes, name, args...)
+		if err == nil {
+			return out, nil
+		}
+
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("%w: %w", ctx.Err(), err)
+		}
+		var cmdErr *CommandError
+		if attempt >= opts.Attempts || !errors.As(err, &cmdErr) || !opts.Retryable(cmdErr) {
+			return "", err
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("%w: %w", ctx.Err(), err)
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, opts.MaxBackoff)
+	}
+}
+
+// runCommand runs one attempt of a command, killing it when ctx is done or timeout
+// elapses, and keeping at most limit bytes of stdout and stderr (0 = everything).
+// Commands of lxd, microceph and microovn go through their component's circuit
+// breaker (see breaker.go) and fail with ErrCircuitOpen while it is open.
+// Each run is traced as a span named after the command and its subcommand.
+func runCommand(ctx context.Context, timeout time.Duration, limit int, name string, args ...string) (string, error) {
+	component, err := allowCommand(name)
+	if err != nil {
+		return "", err
+	}
+	callerCtx := ctx
+
+	// Only the subcommand is recorded: arguments may carry secrets
+	spanName := "exec " + name
+	if len(args) > 0 {
+		spanName += " " + args[0]
+	}
+	ctx, span := tracer.Start(ctx, spanName, trace.WithAttributes(attribute.String("process.executable.name", name)))
+	defer span.End()
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, name, args...)
+
+	stdout := &limitedBuffer{limit: limit}
+	stderr := &limitedBuffer{limit: limit}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	if err := cmd.Run(); err != nil {
+		cmdErr := &CommandError{
+			Name:     name,
+			Args:     args,
+			ExitCode: -1,
+			Stdout:   stdout.String(),
+			Stderr:   stderr.String(),
+			TimedOut: errors.Is(ctx.Err(), context.DeadlineExceeded),
+			Err:      err,
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.Exited() {
+			cmdErr.ExitCode = exitErr.ExitCode()
+		}
+		span.SetAttributes(attribute.Int("process.exit.code", cmdErr.ExitCode))
+		span.SetStatus(codes.Error, err.Error())
+		recordResult(callerCtx, component, cmdErr)
+		return "", cmdErr
+	}
+
+	span.SetAttributes(attribute.Int("process.exit.code", 0))
+	recordResult(callerCtx, component, nil)
+	return stdout.String(), nil
+}
+
+// limitedBuffer keeps the first limit bytes written to it and discards the rest,
+// so a runaway command cannot exhaust memory. A limit of 0 keeps everything.
+// The buffer is not embedded: its ReadFrom would let io.Copy bypass the limit.
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if b.limit > 0 {
+		room := b.limit - b.buf.Len()
+		if room < len(p) {
+			b.truncated = true
+			p = p[:max(room, 0)]
+		}
+	}
+	b.buf.Write(p)
+	return n, nil // report everything as written so the command is not interrupted
+}
+
+func (b *limitedBuffer) String() string {
+	if b.truncated {
+		return b.buf.String() + "\n[output truncated]"
+	}
+	return b.buf.String()
+}
@@ -0,0 +1,156 @@
+// Package execstream is the wire format of an exec session after the HTTP
+// connection is upgraded (Upgrade: mcloud-exec). Both directions carry frames of
+// a 1-byte type, a 4-byte big-endian payload length and the payload:
+//
+//   client -> server: Stdin, StdinEOF, Resize
+//   server -> client: Stdout, Stderr, then one Exit frame with the exit code
+//
+// Framing keeps stdout and stderr apart and lets the exit code and end of input
+// travel over a connection that has no half-close. On a terminal (an exec with
+// a TTY, or a console) Stdin and Stdout carry raw terminal bytes, there is no
+// Stderr, and the client sends Resize whenever its window changes size.
+package execstream
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Protocol is the value of the Upgrade header of an exec request.
+const Protocol = "mcloud-exec"
+
+// Frame types.
+const (
+	Stdin    byte = 0
+	Stdout   byte = 1
+	Stderr   byte = 2
+	Exit     byte = 3 // payload: 4-byte big-endian exit code
+	StdinEOF byte = 4 // no payload
+	Resize   byte = 5 // payload: 2-byte big-endian width, then height, in characters
+)
+
+// MaxPayload bounds a frame so a corrupt length cannot allocate unbounded memory.
+const MaxPayload = 1 << 20
+
+// ErrFrameTooLarge is returned by ReadFrame for a frame above MaxPayload.
+var ErrFrameTooLarge = errors.New("exec frame too large")
+
+// WriteFrame writes one frame.
+func WriteFrame(w io.Writer, typ byte, payload []byte) error {
+	if len(payload) > MaxPayload {
+		return ErrFrameTooLarge
+	}
+	header := make([]byte, 5, 5+len(payload))
+	header[0] = typ
+	binary.BigEndian.PutUint32(header[1:], uint32(len(payload)))
+	_, err := w.Write(append(header, payload...))
+	return err
+}
+
+// ReadFrame reads one frame.
+func ReadFrame(r io.Reader) (typ byte, payload []byte, err error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	n := binary.BigEndian.Uint32(header[1:])
+	if n > MaxPayload {
+		return 0, nil, fmt.Errorf("%w: %d bytes", ErrFrameTooLarge, n)
+	}
+	payload = make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	return header[0], payload, nil
+}
+
+// WriteExit writes the Exit frame that ends a session.
+func WriteExit(w io.Writer, code int) error {
+	var payload [4]byte
+	binary.BigEndian.PutUint32(payload[:], uint32(int32(code)))
+	return WriteFrame(w, Exit, payload[:])
+}
+
+// ExitCode decodes the payload of an Exit frame.
+func ExitCode(payload []byte) (int, error) {
+	if len(payload) != 4 {
+		return 0, fmt.Errorf("exit frame has %d bytes, want 4", len(payload))
+	}
+	return int(int32(binary.BigEndian.Uint32(payload))), nil
+}
+
+// WriteResize writes a Resize frame.
+func WriteResize(w io.Writer, width int, height int) error {
+	var payload [4]byte
+	binary.BigEndian.PutUint16(payload[:2], uint16(width))
+	binary.BigEndian.PutUint16(payload[2:], uint16(height))
+	return WriteFrame(w, Resize, payload[:])
+}
+
+// Size decodes the payload of a Resize frame.
+func Size(payload []byte) (width int, height int, err error) {
+	if len(payload) != 4 {
+		return 0, 0, fmt.Errorf("resize frame has %d bytes, want 4", len(payload))
+	}
+	return int(binary.BigEndian.Uint16(payload[:2])), int(binary.BigEndian.Uint16(payload[2:])), nil
+}
+
+// Writer writes everything written to it as frames of one type. Writers of
+// different types may share a connection; frames are never interleaved.
+type Writer struct {
+	mu  *sync.Mutex
+	w   io.Writer
+	typ byte
+}
+
+// NewWriters returns frame writers of the given types sharing w and one lock.
+//
+// Example:
+//   ws := execstream.NewWriters(conn, execstream.Stdout, execstream.Stderr)
+//   cmd.Stdout, cmd.Stderr = ws[0], ws[1]
+func NewWriters(w io.Writer, types ...byte) []*Writer {
+	mu := &sync.Mutex{}
+	writers := make([]*Writer, len(types))
+	for i, typ := range types {
+		writers[i] = &Writer{mu: mu, w: w, typ: typ}
+	}
+	return writers
+}
+
+func (f *Writer) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for written := 0; written < len(p); {
+		n := min(len(p)-written, MaxPayload)
+		if err := WriteFrame(f.w, f.typ, p[written:written+n]); err != nil {
+			return written, err
+		}
+		written += n
+	}
+	return len(p), nil
+}
+
+// WriteExit writes the Exit frame under the writers' lock.
+func (f *Writer) WriteExit(code int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return WriteExit(f.w, code)
+}
+
+// WriteResize writes a Resize frame under the writers' lock.
+func (f *Writer) WriteResize(width int, height int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return WriteResize(f.w, width, height)
+}
+
+// WriteEOF writes a StdinEOF frame under the writers' lock.
+func (f *Writer) WriteEOF() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return WriteFrame(f.w, StdinEOF, nil)
+}
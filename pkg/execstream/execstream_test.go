@@ -0,0 +1,81 @@
+package execstream
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestFrames(t *testing.T) {
+	var buf bytes.Buffer
+	ws := NewWriters(&buf, Stdout, Stderr)
+	ws[0].Write([]byte("hello\n"))
+	ws[1].Write([]byte("oops\n"))
+	ws[0].Write(nil)
+	ws[0].WriteExit(-2)
+
+	want := []struct {
+		typ     byte
+		payload string
+	}{
+		{Stdout, "hello\n"},
+		{Stderr, "oops\n"},
+	}
+	for _, w := range want {
+		typ, payload, err := ReadFrame(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if typ != w.typ || string(payload) != w.payload {
+			t.Errorf("frame = %d %q, want %d %q", typ, payload, w.typ, w.payload)
+		}
+	}
+
+	typ, payload, err := ReadFrame(&buf)
+	if err != nil || typ != Exit {
+		t.Fatalf("frame = %d, %v; want exit frame", typ, err)
+	}
+	if code, err := ExitCode(payload); err != nil || code != -2 {
+		t.Errorf("ExitCode = %d, %v; want -2", code, err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("%d bytes left after exit frame", buf.Len())
+	}
+}
+
+func TestFrameTooLarge(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteFrame(&buf, Stdin, make([]byte, MaxPayload+1)); !errors.Is(err, ErrFrameTooLarge) {
+		t.Errorf("WriteFrame = %v, want ErrFrameTooLarge", err)
+	}
+
+	// A large write is split into frames of at most MaxPayload
+	w := NewWriters(&buf, Stdout)[0]
+	if n, err := w.Write(make([]byte, MaxPayload+10)); err != nil || n != MaxPayload+10 {
+		t.Fatalf("Write = %d, %v", n, err)
+	}
+	for _, want := range []int{MaxPayload, 10} {
+		_, payload, err := ReadFrame(&buf)
+		if err != nil || len(payload) != want {
+			t.Errorf("frame of %d bytes, %v; want %d", len(payload), err, want)
+		}
+	}
+
+	header := []byte{Stdout, 0xff, 0xff, 0xff, 0xff}
+	if _, _, err := ReadFrame(bytes.NewReader(header)); !errors.Is(err, ErrFrameTooLarge) {
+		t.Errorf("ReadFrame = %v, want ErrFrameTooLarge", err)
+	}
+}
+
+func TestResize(t *testing.T) {
+	var buf bytes.Buffer
+	NewWriters(&buf, Stdin)[0].WriteResize(132, 43)
+
+	typ, payload, err := ReadFrame(&buf)
+	if err != nil || typ != Resize {
+		t.Fatalf("frame = %d, %v; want resize frame", typ, err)
+	}
+	if w, h, err := Size(payload); err != nil || w != 132 || h != 43 {
+		t.Errorf("Size = %d, %d, %v; want 132, 43", w, h, err)
+	}
+}
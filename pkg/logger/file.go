@@ -0,0 +1,207 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+	========================
+	Rotating File Sink
+	========================
+*/
+
+const (
+	// DefaultMaxSizeMB is the file size that triggers rotation when none is configured.
+	DefaultMaxSizeMB = 50
+
+	// DefaultMaxBackups is how many rotated files are kept when none is configured.
+	DefaultMaxBackups = 5
+)
+
+// FileOptions configures the log file sink.
+type FileOptions struct {
+	Path       string        // e.g. /var/log/mcloud/mcloudd.log
+	MaxSizeMB  int           // rotate when the file would grow past this size (default 50)
+	MaxAge     time.Duration // rotate when the file was opened longer ago than this (0 = size only)
+	MaxBackups int           // rotated files to keep as <path>.1 (newest) … <path>.N (default 5)
+}
+
+// RotatingFile is an io.Writer that appends to a file and rotates it by size or age.
+// Rotated files are renamed <path>.1, <path>.2, …; the oldest beyond MaxBackups is removed.
+type RotatingFile struct {
+	mu       sync.Mutex
+	opts     FileOptions
+	file     *os.File
+	size     int64
+	openedAt time.Time
+}
+
+// NewRotatingFile opens (or creates) the log file, appending to any existing content.
+//
+// Example Input:
+//   NewRotatingFile(FileOptions{Path: "/var/log/mcloud/mcloudd.log", MaxSizeMB: 50, MaxBackups: 5})
+//
+// Example Output:
+//   Files over time:
+//     /var/log/mcloud/mcloudd.log    (current, < 50MB)
+//     /var/log/mcloud/mcloudd.log.1  (previous)
+//     ...
+//     /var/log/mcloud/mcloudd.log.5  (oldest kept)
+func NewRotatingFile(opts FileOptions) (*RotatingFile, error) {
+	if opts.Path == "" {
+		return nil, fmt.Errorf("log file path is required")
+	}
+	if opts.MaxSizeMB <= 0 {
+		opts.MaxSizeMB = DefaultMaxSizeMB
+	}
+	if opts.MaxBackups <= 0 {
+		opts.MaxBackups = DefaultMaxBackups
+	}
+
+	f := &RotatingFile{opts: opts}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *RotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(f.opts.Path), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(f.opts.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	f.file = file
+	f.size = info.Size()
+	f.openedAt = time.Now()
+	return nil
+}
+
+// Write appends p, rotating first if it would exceed the size limit or the file is too old.
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	tooBig := f.size > 0 && f.size+int64(len(p)) > int64(f.opts.MaxSizeMB)*1024*1024
+	tooOld := f.opts.MaxAge > 0 && time.Since(f.openedAt) > f.opts.MaxAge
+	if tooBig || tooOld {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// rotate shifts <path>.i to <path>.i+1, drops the oldest and starts a new file.
+func (f *RotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+
+	backup := func(i int) string { return fmt.Sprintf("%s.%d", f.opts.Path, i) }
+
+	os.Remove(backup(f.opts.MaxBackups))
+	for i := f.opts.MaxBackups - 1; i >= 1; i-- {
+		if err := os.Rename(backup(i), backup(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Rename(f.opts.Path, backup(1)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return f.open()
+}
+
+// Close closes the current file. Further writes fail.
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+var (
+	fileMu   sync.RWMutex
+	fileSink io.Closer            // the open sink, closed when replaced
+	fileLogs map[Level]*log.Logger // per-level loggers without colors, nil when no file is set
+)
+
+// SetFile writes every printed message to a rotating log file in addition to stdout/stderr.
+// Calling it again replaces the previous file; an empty path disables the file sink.
+//
+// Example Input:
+//   logger.SetFile(logger.FileOptions{Path: "/var/log/mcloud/mcloudd.log"})
+//   logger.Info("Starting HTTPS server on %s", addr)
+//
+// Example Output (/var/log/mcloud/mcloudd.log):
+//   [INFO] 2026-01-02 10:30:45 Starting HTTPS server on 192.168.1.10:9028
+func SetFile(opts FileOptions) error {
+	var sink *RotatingFile
+	var logs map[Level]*log.Logger
+
+	if opts.Path != "" {
+		var err error
+		if sink, err = NewRotatingFile(opts); err != nil {
+			return err
+		}
+
+		flags := log.LstdFlags | log.Lmsgprefix
+		logs = map[Level]*log.Logger{}
+		for _, l := range []Level{LevelDebug, LevelInfo, LevelWarn, LevelError} {
+			logs[l] = log.New(sink, "["+strings.ToUpper(l.String())+"] ", flags)
+		}
+	}
+
+	fileMu.Lock()
+	old := fileSink
+	fileLogs = logs
+	fileSink = nil
+	if sink != nil { // avoid storing a typed nil
+		fileSink = sink
+	}
+	fileMu.Unlock()
+
+	if old != nil {
+		old.Close()
+	}
+	return nil
+}
+
+// writeFile sends an already filtered message to the file sink, if one is set.
+func writeFile(level Level, msg string, v ...any) {
+	fileMu.RLock()
+	defer fileMu.RUnlock()
+
+	if out, ok := fileLogs[level]; ok {
+		out.Printf(msg, v...)
+	}
+}
@@ -0,0 +1,179 @@
+package logger
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+)
+
+/*
+	========================
+	Levels
+	========================
+*/
+
+// Level is the severity of a log message. Messages below the configured
+// minimum level are dropped.
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// EnvLevel is the environment variable that overrides the configured minimum level,
+// e.g. MCLOUD_LOG_LEVEL=debug mcloudd
+const EnvLevel = "MCLOUD_LOG_LEVEL"
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	}
+	return fmt.Sprintf("level(%d)", int(l))
+}
+
+// ParseLevel converts a level name (debug, info, warn/warning, error) to a Level.
+//
+// Example Input:
+//   ParseLevel("WARN")
+//
+// Example Output:
+//   LevelWarn, nil
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	}
+	return LevelInfo, fmt.Errorf("unknown log level %q (want debug, info, warn or error)", s)
+}
+
+var (
+	levelMu      sync.RWMutex
+	minLevel     = LevelInfo          // minimum level for the root logger and unconfigured modules
+	moduleLevels = map[string]Level{} // per-module overrides, keyed by Named() name
+)
+
+// SetLevel sets the minimum level for the root logger and all modules without an override.
+func SetLevel(l Level) {
+	levelMu.Lock()
+	defer levelMu.Unlock()
+	minLevel = l
+}
+
+// SetModuleLevel overrides the minimum level for one named logger.
+func SetModuleLevel(name string, l Level) {
+	levelMu.Lock()
+	defer levelMu.Unlock()
+	moduleLevels[name] = l
+}
+
+// Configure applies the log settings from the config file.
+// The MCLOUD_LOG_LEVEL environment variable, if set, takes precedence over level.
+//
+// Parameters:
+//   level   - Minimum level name; empty keeps the current level
+//   modules - Per-module level names, e.g. {"grpc": "debug", "lxd": "warn"}
+//
+// Returns:
+//   An error naming the first invalid level; valid settings are still applied
+func Configure(level string, modules map[string]string) error {
+	var firstErr error
+
+	if env := os.Getenv(EnvLevel); env != "" {
+		level = env
+	}
+	if level != "" {
+		l, err := ParseLevel(level)
+		if err != nil {
+			firstErr = err
+		} else {
+			SetLevel(l)
+		}
+	}
+
+	for name, v := range modules {
+		l, err := ParseLevel(v)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("module %s: %w", name, err)
+			}
+			continue
+		}
+		SetModuleLevel(name, l)
+	}
+
+	return firstErr
+}
+
+// enabled reports whether a message at level l from the named logger is printed.
+func enabled(name string, l Level) bool {
+	levelMu.RLock()
+	defer levelMu.RUnlock()
+
+	if name != "" {
+		if min, ok := moduleLevels[name]; ok {
+			return l >= min
+		}
+	}
+	return l >= minLevel
+}
+
+/*
+	========================
+	Named Loggers
+	========================
+*/
+
+// Logger is a named sub-logger. Its messages are prefixed with the name and
+// filtered by the module's level override (or the global level if none is set).
+type Logger struct {
+	name string
+}
+
+// std is the unnamed root logger behind the package-level functions.
+var std = &Logger{}
+
+// Named returns a sub-logger for a module.
+//
+// Example Input:
+//   log := logger.Named("grpc")
+//   log.Info("Listening on %s", addr)
+//
+// Example Output:
+//   [INFO] 2026-01-02 10:30:45 grpc: Listening on 0.0.0.0:9030
+func Named(name string) *Logger {
+	return &Logger{name: name}
+}
+
+func (l *Logger) logf(level Level, out *log.Logger, msg string, v ...any) {
+	if !enabled(l.name, level) {
+		return
+	}
+	if l.name != "" {
+		msg = l.name + ": " + msg
+	}
+	out.Printf(msg, v...)
+	writeFile(level, msg, v...)
+}
+
+func (l *Logger) Debug(msg string, v ...any) { l.logf(LevelDebug, debugLog, msg, v...) }
+func (l *Logger) Info(msg string, v ...any)  { l.logf(LevelInfo, infoLog, msg, v...) }
+func (l *Logger) Warn(msg string, v ...any)  { l.logf(LevelWarn, warnLog, msg, v...) }
+func (l *Logger) Error(msg string, v ...any) { l.logf(LevelError, errorLog, msg, v...) }
@@ -1,6 +1,7 @@
 package logger
 
 import (
+	"io"
 	"log"
 	"os"
 
@@ -92,7 +93,8 @@ var (
 //   - INFO and DEBUG: stdout (standard output)
 //   - WARN and ERROR: stderr (standard error)
 //
-// This function must be called before using any logging functions (Info, Warn, Error, Debug).
+// It runs automatically when the package is loaded, so logging works without calling it;
+// the minimum level starts at info unless MCLOUD_LOG_LEVEL says otherwise.
 // Colors are automatically disabled if output is not a terminal.
 //
 // Side Effect:
@@ -147,6 +149,28 @@ func InitLogger() {
 		cyan+"[DEBUG] "+reset,
 		flags,
 	)
+
+	// Allow the level to be raised or lowered without a config file
+	if l, err := ParseLevel(os.Getenv(EnvLevel)); err == nil {
+		SetLevel(l)
+	}
+}
+
+func init() {
+	InitLogger()
+}
+
+// SetOutput sends INFO and DEBUG lines to stdout and WARN and ERROR lines to
+// stderr instead of os.Stdout and os.Stderr, e.g. through a CLI progress
+// display that redraws its status line around them.
+//
+// Example Input:
+//   logger.SetOutput(os.Stdout, os.Stderr)   // restore the defaults
+func SetOutput(stdout, stderr io.Writer) {
+	infoLog.SetOutput(stdout)
+	debugLog.SetOutput(stdout)
+	warnLog.SetOutput(stderr)
+	errorLog.SetOutput(stderr)
 }
 
 /*
@@ -157,7 +181,7 @@ func InitLogger() {
 
 // Info logs an informational message to stdout with a green [INFO] prefix.
 // Automatically formats the message using fmt.Sprintf if format specifiers are present.
-// Uses the global infoLog logger which must be initialized via InitLogger() function.
+// Dropped when the minimum level is above info (see SetLevel and Configure).
 //
 // Parameters:
 //   msg string - Format string (as in fmt.Printf)
@@ -181,7 +205,7 @@ func InitLogger() {
 // Example Output 2:
 //   [INFO] 2026-01-02 10:30:45 Server listening on 127.0.0.1:9028
 func Info(msg string, v ...any) {
-	infoLog.Printf(msg, v...)
+	std.logf(LevelInfo, infoLog, msg, v...)
 }
 
 // Warn logs a warning message to stderr with a yellow [WARN] prefix.
@@ -210,7 +234,7 @@ func Info(msg string, v ...any) {
 // Example Output 2:
 //   [WARN] 2026-01-02 10:30:45 Failed to detect LAN interface, falling back to 127.0.0.1
 func Warn(msg string, v ...any) {
-	warnLog.Printf(msg, v...)
+	std.logf(LevelWarn, warnLog, msg, v...)
 }
 
 // Error logs an error message to stderr with a red [ERROR] prefix.
@@ -240,11 +264,12 @@ func Warn(msg string, v ...any) {
 // Example Output 2:
 //   [ERROR] 2026-01-02 10:30:45 Connection refused on 127.0.0.1:9028
 func Error(msg string, v ...any) {
-	errorLog.Printf(msg, v...)
+	std.logf(LevelError, errorLog, msg, v...)
 }
 
 // Debug logs a debug message to stdout with a cyan [DEBUG] prefix.
 // Use for detailed diagnostic information during development and troubleshooting.
+// Only printed when the minimum level is debug (e.g. MCLOUD_LOG_LEVEL=debug).
 // Automatically formats the message using fmt.Sprintf if format specifiers are present.
 //
 // Parameters:
@@ -269,5 +294,5 @@ func Error(msg string, v ...any) {
 // Example Output 2:
 //   [DEBUG] 2026-01-02 10:30:45 Transaction started, isolation level: READ COMMITTED
 func Debug(msg string, v ...any) {
-	debugLog.Printf(msg, v...)
+	std.logf(LevelDebug, debugLog, msg, v...)
 }
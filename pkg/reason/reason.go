@@ -0,0 +1,491 @@
+// Package reason gives every mcloud error a stable, machine-readable reason code
+// (e.g. MC1021 TokenExpired). The REST API returns it with each error and
+// mcloudctl prints it with each failure, so automation can branch on the code
+// while the human-readable message is free to change or be translated.
+//
+// Codes are never reused or renumbered. Ranges:
+//   MC10xx  generic errors and cluster membership
+//   MC11xx  nodes and agents
+//   MC12xx  workloads
+//   MC13xx  operations
+//   MC14xx  secrets
+//   MC15xx  cluster config
+//   MC16xx  images
+//   MC17xx  DNS
+//   MC18xx  usage
+//   MC19xx  audit
+//   MC20xx  flavors
+//   MC21xx  volumes
+//   MC22xx  floating IPs
+//   MC23xx  security groups
+//   MC24xx  manifests (apply)
+//   MC25xx  projects and quotas
+//   MC26xx  maintenance windows
+//   MC27xx  versions
+//   MC28xx  load balancers
+package reason
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Code is a stable reason code such as "MC1021".
+type Code string
+
+// Generic errors, used when an error has no more specific code.
+const (
+	Internal         Code = "MC1000"
+	InvalidRequest   Code = "MC1001"
+	Unauthenticated  Code = "MC1002"
+	Forbidden        Code = "MC1003"
+	NotFound         Code = "MC1004"
+	Conflict         Code = "MC1005"
+	MethodNotAllowed Code = "MC1006"
+	Unavailable      Code = "MC1007"
+	UpstreamFailed   Code = "MC1008"
+	Timeout          Code = "MC1009"
+	RateLimited      Code = "MC1010"
+
+	UnsupportedAPIVersion Code = "MC1011"
+)
+
+// Cluster membership and the database.
+const (
+	TokenInvalid          Code = "MC1020"
+	TokenExpired          Code = "MC1021"
+	RejoinDenied          Code = "MC1022"
+	ClusterNotInitialized Code = "MC1023"
+	CAPinMismatch         Code = "MC1024"
+	MigrationLocked       Code = "MC1030"
+	MigrationModified     Code = "MC1031"
+	MigrationIrreversible Code = "MC1032"
+)
+
+// Nodes and agents.
+const (
+	NodeNotFound      Code = "MC1100"
+	ServiceNotAllowed Code = "MC1101"
+	DrainTimeout      Code = "MC1102"
+	ActionNotAllowed  Code = "MC1103"
+	ServiceUnhealthy  Code = "MC1104"
+	InvalidTimeSync   Code = "MC1105"
+	CircuitOpen       Code = "MC1106"
+	NodeRemoveRefused Code = "MC1107"
+	CertRevoked       Code = "MC1108"
+	AgentNotConnected Code = "MC1109"
+	InvalidCommand    Code = "MC1110"
+	InvalidRevocation Code = "MC1111"
+	CertNotFound      Code = "MC1112"
+	CertNotRenewable  Code = "MC1113"
+	InvalidNodeLabel  Code = "MC1114"
+)
+
+// Workloads.
+const (
+	WorkloadNotFound     Code = "MC1200"
+	WorkloadNameExists   Code = "MC1201"
+	WorkloadNameRequired Code = "MC1202"
+	InvalidWorkload      Code = "MC1203"
+	TargetNodeNotFound   Code = "MC1204"
+	StorageUnhealthy     Code = "MC1205"
+	ScheduleNotFound     Code = "MC1206"
+	WorkloadNotRunning   Code = "MC1207"
+	NoEligibleNode       Code = "MC1208"
+)
+
+// Operations.
+const (
+	OperationNotFound      Code = "MC1300"
+	InvalidOperationStatus Code = "MC1301"
+)
+
+// Secrets.
+const (
+	SecretNotFound    Code = "MC1400"
+	SecretInUse       Code = "MC1401"
+	InvalidSecretName Code = "MC1402"
+)
+
+// Cluster config.
+const (
+	ConfigKeyNotFound     Code = "MC1500"
+	ConfigVersionConflict Code = "MC1501"
+	InvalidConfigKey      Code = "MC1502"
+)
+
+// Images.
+const (
+	ImageImportNotFound   Code = "MC1600"
+	InvalidImageImport    Code = "MC1601"
+	ImageChecksumMismatch Code = "MC1602"
+	ImageBuildNotFound    Code = "MC1603"
+	InvalidImageBuild     Code = "MC1604"
+)
+
+// DNS.
+const (
+	DNSDisabled      Code = "MC1700"
+	InvalidDNSName   Code = "MC1701"
+	InvalidIPAddress Code = "MC1702"
+)
+
+// Usage.
+const (
+	InvalidUsageSubject Code = "MC1800"
+)
+
+// Audit.
+const (
+	ExecSessionNotFound Code = "MC1900"
+)
+
+// Flavors.
+const (
+	FlavorNotFound Code = "MC2000"
+	FlavorExists   Code = "MC2001"
+	FlavorInUse    Code = "MC2002"
+	InvalidFlavor  Code = "MC2003"
+)
+
+// Volumes.
+const (
+	VolumeNotFound Code = "MC2100"
+	VolumeExists   Code = "MC2101"
+	VolumeInUse    Code = "MC2102"
+	InvalidVolume  Code = "MC2103"
+)
+
+// Floating IPs.
+const (
+	FloatingIPsDisabled  Code = "MC2200"
+	FloatingIPNotFound   Code = "MC2201"
+	FloatingIPInUse      Code = "MC2202"
+	FloatingIPsExhausted Code = "MC2203"
+	InvalidFloatingIP    Code = "MC2204"
+)
+
+// Security groups.
+const (
+	SecurityGroupNotFound Code = "MC2300"
+	SecurityGroupExists   Code = "MC2301"
+	SecurityGroupInUse    Code = "MC2302"
+	InvalidSecurityGroup  Code = "MC2303"
+)
+
+// Manifests.
+const (
+	InvalidManifest  Code = "MC2400"
+	ManifestConflict Code = "MC2401"
+)
+
+// Projects and quotas.
+const (
+	ProjectNotFound Code = "MC2500"
+	ProjectExists   Code = "MC2501"
+	ProjectInUse    Code = "MC2502"
+	InvalidProject  Code = "MC2503"
+	QuotaExceeded   Code = "MC2504"
+	ProjectDenied   Code = "MC2505"
+)
+
+// Maintenance windows.
+const (
+	MaintenanceWindowNotFound Code = "MC2600"
+	InvalidMaintenanceWindow  Code = "MC2601"
+	MaintenanceRunning        Code = "MC2602"
+)
+
+// Versions.
+const (
+	IncompatibleVersion   Code = "MC2700"
+	ChecksumMismatch      Code = "MC2701"
+	NoChecksum            Code = "MC2702"
+	BadReleaseSignature   Code = "MC2703"
+	ReleaseUnsigned       Code = "MC2704"
+	BinaryVersionMismatch Code = "MC2705"
+)
+
+// Load balancers.
+const (
+	LoadBalancerNotFound Code = "MC2800"
+	LoadBalancerExists   Code = "MC2801"
+	InvalidLoadBalancer  Code = "MC2802"
+)
+
+// names maps every code to its reason name, the stable identifier shown next to it.
+var names = map[Code]string{
+	Internal:         "Internal",
+	InvalidRequest:   "InvalidRequest",
+	Unauthenticated:  "Unauthenticated",
+	Forbidden:        "Forbidden",
+	NotFound:         "NotFound",
+	Conflict:         "Conflict",
+	MethodNotAllowed: "MethodNotAllowed",
+	Unavailable:      "Unavailable",
+	UpstreamFailed:   "UpstreamFailed",
+	Timeout:          "Timeout",
+	RateLimited:      "RateLimited",
+
+	UnsupportedAPIVersion: "UnsupportedAPIVersion",
+
+	TokenInvalid:          "TokenInvalid",
+	TokenExpired:          "TokenExpired",
+	RejoinDenied:          "RejoinDenied",
+	ClusterNotInitialized: "ClusterNotInitialized",
+	CAPinMismatch:         "CAPinMismatch",
+	MigrationLocked:       "MigrationLocked",
+	MigrationModified:     "MigrationModified",
+	MigrationIrreversible: "MigrationIrreversible",
+
+	NodeNotFound:      "NodeNotFound",
+	ServiceNotAllowed: "ServiceNotAllowed",
+	DrainTimeout:      "DrainTimeout",
+	ActionNotAllowed:  "ActionNotAllowed",
+	ServiceUnhealthy:  "ServiceUnhealthy",
+	InvalidTimeSync:   "InvalidTimeSync",
+	CircuitOpen:       "CircuitOpen",
+	NodeRemoveRefused: "NodeRemoveRefused",
+	CertRevoked:       "CertRevoked",
+	AgentNotConnected: "AgentNotConnected",
+	InvalidCommand:    "InvalidCommand",
+	InvalidRevocation: "InvalidRevocation",
+	CertNotFound:      "CertNotFound",
+	CertNotRenewable:  "CertNotRenewable",
+	InvalidNodeLabel:  "InvalidNodeLabel",
+
+	WorkloadNotFound:     "WorkloadNotFound",
+	WorkloadNameExists:   "WorkloadNameExists",
+	WorkloadNameRequired: "WorkloadNameRequired",
+	InvalidWorkload:      "InvalidWorkload",
+	TargetNodeNotFound:   "TargetNodeNotFound",
+	StorageUnhealthy:     "StorageUnhealthy",
+	ScheduleNotFound:     "ScheduleNotFound",
+	WorkloadNotRunning:   "WorkloadNotRunning",
+	NoEligibleNode:       "NoEligibleNode",
+
+	OperationNotFound:      "OperationNotFound",
+	InvalidOperationStatus: "InvalidOperationStatus",
+
+	SecretNotFound:    "SecretNotFound",
+	SecretInUse:       "SecretInUse",
+	InvalidSecretName: "InvalidSecretName",
+
+	ConfigKeyNotFound:     "ConfigKeyNotFound",
+	ConfigVersionConflict: "ConfigVersionConflict",
+	InvalidConfigKey:      "InvalidConfigKey",
+
+	ImageImportNotFound:   "ImageImportNotFound",
+	InvalidImageImport:    "InvalidImageImport",
+	ImageChecksumMismatch: "ImageChecksumMismatch",
+	ImageBuildNotFound:    "ImageBuildNotFound",
+	InvalidImageBuild:     "InvalidImageBuild",
+
+	DNSDisabled:      "DNSDisabled",
+	InvalidDNSName:   "InvalidDNSName",
+	InvalidIPAddress: "InvalidIPAddress",
+
+	InvalidUsageSubject: "InvalidUsageSubject",
+
+	ExecSessionNotFound: "ExecSessionNotFound",
+
+	FlavorNotFound: "FlavorNotFound",
+	FlavorExists:   "FlavorExists",
+	FlavorInUse:    "FlavorInUse",
+	InvalidFlavor:  "InvalidFlavor",
+
+	VolumeNotFound: "VolumeNotFound",
+	VolumeExists:   "VolumeExists",
+	VolumeInUse:    "VolumeInUse",
+	InvalidVolume:  "InvalidVolume",
+
+	FloatingIPsDisabled:  "FloatingIPsDisabled",
+	FloatingIPNotFound:   "FloatingIPNotFound",
+	FloatingIPInUse:      "FloatingIPInUse",
+	FloatingIPsExhausted: "FloatingIPsExhausted",
+	InvalidFloatingIP:    "InvalidFloatingIP",
+
+	SecurityGroupNotFound: "SecurityGroupNotFound",
+	SecurityGroupExists:   "SecurityGroupExists",
+	SecurityGroupInUse:    "SecurityGroupInUse",
+	InvalidSecurityGroup:  "InvalidSecurityGroup",
+
+	InvalidManifest:  "InvalidManifest",
+	ManifestConflict: "ManifestConflict",
+
+	ProjectNotFound: "ProjectNotFound",
+	ProjectExists:   "ProjectExists",
+	ProjectInUse:    "ProjectInUse",
+	InvalidProject:  "InvalidProject",
+	QuotaExceeded:   "QuotaExceeded",
+	ProjectDenied:   "ProjectDenied",
+
+	MaintenanceWindowNotFound: "MaintenanceWindowNotFound",
+	InvalidMaintenanceWindow:  "InvalidMaintenanceWindow",
+	MaintenanceRunning:        "MaintenanceRunning",
+
+	IncompatibleVersion:   "IncompatibleVersion",
+	ChecksumMismatch:      "ChecksumMismatch",
+	NoChecksum:            "NoChecksum",
+	BadReleaseSignature:   "BadReleaseSignature",
+	ReleaseUnsigned:       "ReleaseUnsigned",
+	BinaryVersionMismatch: "BinaryVersionMismatch",
+
+	LoadBalancerNotFound: "LoadBalancerNotFound",
+	LoadBalancerExists:   "LoadBalancerExists",
+	InvalidLoadBalancer:  "InvalidLoadBalancer",
+}
+
+// Name returns the reason name of a code, e.g. "TokenExpired" for MC1021.
+// Unknown codes (e.g. from a newer server) return "".
+func (c Code) Name() string {
+	return names[c]
+}
+
+// Error is an error with a reason code. Packages declare their sentinel errors
+// with New, so errors.Is keeps working and the code travels with every error
+// that wraps them.
+//
+// Example JSON (REST API error body):
+//   {"code": "MC1200", "reason": "WorkloadNotFound", "message": "workload not found"}
+type Error struct {
+	Code    Code           `json:"code"`
+	Reason  string         `json:"reason"`
+	Message string         `json:"message"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// Detailer is implemented by errors that carry structured fields for clients,
+// e.g. which quota a launch exceeded. HTTPError sends them as "details".
+//
+// Example JSON:
+//   {"code": "MC2504", "reason": "QuotaExceeded", "message": "...", "details": {"resource": "cpus", "limit": 8, "used": 6, "requested": 4}}
+type Detailer interface {
+	ReasonDetails() map[string]any
+}
+
+// New creates an error with a reason code.
+//
+// Example:
+//   var ErrWorkloadNotFound = reason.New(reason.WorkloadNotFound, "workload not found")
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Reason: code.Name(), Message: message}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// CodeOf returns the reason code of err: the code of the first *Error in its
+// chain, or fallback if it has none.
+func CodeOf(err error, fallback Code) Code {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return fallback
+}
+
+// ForStatus is the generic code of an HTTP error status, for errors without a
+// code of their own.
+func ForStatus(status int) Code {
+	switch status {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity, http.StatusRequestEntityTooLarge:
+		return InvalidRequest
+	case http.StatusUnauthorized:
+		return Unauthenticated
+	case http.StatusForbidden:
+		return Forbidden
+	case http.StatusNotFound:
+		return NotFound
+	case http.StatusConflict, http.StatusPreconditionFailed:
+		return Conflict
+	case http.StatusMethodNotAllowed:
+		return MethodNotAllowed
+	case http.StatusTooManyRequests:
+		return RateLimited
+	case http.StatusBadGateway:
+		return UpstreamFailed
+	case http.StatusServiceUnavailable:
+		return Unavailable
+	case http.StatusGatewayTimeout:
+		return Timeout
+	}
+	return Internal
+}
+
+// HTTPError replies to the request with err as a JSON error body and the given
+// status, like http.Error. The code is err's own, or the generic one for status.
+//
+// Example Output:
+//   HTTP/1.1 404 Not Found
+//   Content-Type: application/json
+//   X-Mcloud-Reason: MC1200
+//
+//   {"code":"MC1200","reason":"WorkloadNotFound","message":"workload not found"}
+func HTTPError(w http.ResponseWriter, err error, status int) {
+	code := CodeOf(err, ForStatus(status))
+	e := &Error{Code: code, Reason: code.Name(), Message: err.Error()}
+	var d Detailer
+	if errors.As(err, &d) {
+		e.Details = d.ReasonDetails()
+	}
+	body, _ := json.Marshal(e)
+
+	h := w.Header()
+	h.Del("Content-Length")
+	h.Set("Content-Type", "application/json")
+	h.Set("X-Content-Type-Options", "nosniff")
+	h.Set(Header, string(code))
+	w.WriteHeader(status)
+	w.Write(append(body, '\n'))
+}
+
+// HTTPErrorf is HTTPError with a formatted message and the generic code for status.
+func HTTPErrorf(w http.ResponseWriter, status int, format string, args ...any) {
+	HTTPError(w, fmt.Errorf(format, args...), status)
+}
+
+// Header carries the reason code of an error response, so clients can read it
+// without parsing the body.
+const Header = "X-Mcloud-Reason"
+
+// FromResponse turns the body of an error response into an *Error. Bodies that
+// are not a JSON error (e.g. from a proxy or an older server) become the message,
+// with the code from the X-Mcloud-Reason header or the generic one for status.
+func FromResponse(status int, header http.Header, body []byte) *Error {
+	var e Error
+	if err := json.Unmarshal(body, &e); err == nil && e.Code != "" {
+		if e.Reason == "" {
+			e.Reason = e.Code.Name()
+		}
+		return &e
+	}
+
+	code := Code(header.Get(Header))
+	if code == "" {
+		code = ForStatus(status)
+	}
+	msg := strings.TrimSpace(string(body))
+	if msg == "" {
+		msg = http.StatusText(status)
+	}
+	return New(code, msg)
+}
+
+// Format renders err for people and scripts alike, with the code first.
+//
+// Example Output:
+//   MC1200 WorkloadNotFound: GET /workloads/abc: workload not found
+//   MC1000 Internal: open /etc/mcloud/config.yaml: permission denied
+func Format(err error) string {
+	code := CodeOf(err, Internal)
+	name := code.Name()
+	if name == "" {
+		return fmt.Sprintf("%s: %v", code, err)
+	}
+	return fmt.Sprintf("%s %s: %v", code, name, err)
+}
@@ -0,0 +1,132 @@
+package reason
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func TestCodesHaveNames(t *testing.T) {
+	format := regexp.MustCompile(`^MC\d{4}$`)
+	seen := map[string]Code{}
+	for code, name := range names {
+		if !format.MatchString(string(code)) {
+			t.Errorf("code %q does not look like MCnnnn", code)
+		}
+		if name == "" {
+			t.Errorf("code %s has no name", code)
+		}
+		if other, ok := seen[name]; ok {
+			t.Errorf("name %s used by both %s and %s", name, code, other)
+		}
+		seen[name] = code
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	errNotFound := New(WorkloadNotFound, "workload not found")
+
+	tests := []struct {
+		name     string
+		err      error
+		status   int
+		wantCode Code
+		wantMsg  string
+	}{
+		{"sentinel", errNotFound, 404, WorkloadNotFound, "workload not found"},
+		{"wrapped sentinel", fmt.Errorf("clone web: %w", errNotFound), 404, WorkloadNotFound, "clone web: workload not found"},
+		{"plain error takes status code", errors.New("bad json"), 400, InvalidRequest, "bad json"},
+		{"plain error 500", errors.New("disk full"), 500, Internal, "disk full"},
+		{"plain error unknown status", errors.New("teapot"), 418, Internal, "teapot"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			HTTPError(rec, tt.err, tt.status)
+
+			resp := rec.Result()
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if got := resp.Header.Get(Header); got != string(tt.wantCode) {
+				t.Errorf("%s = %q, want %q", Header, got, tt.wantCode)
+			}
+
+			got := FromResponse(resp.StatusCode, resp.Header, body)
+			if got.Code != tt.wantCode || got.Reason != tt.wantCode.Name() || got.Message != tt.wantMsg {
+				t.Errorf("FromResponse = %+v, want code %s message %q", got, tt.wantCode, tt.wantMsg)
+			}
+		})
+	}
+}
+
+type limitError struct{ limit int }
+
+func (e *limitError) Error() string                 { return "over the limit" }
+func (e *limitError) Unwrap() error                 { return New(QuotaExceeded, "project quota exceeded") }
+func (e *limitError) ReasonDetails() map[string]any { return map[string]any{"limit": e.limit} }
+
+func TestDetails(t *testing.T) {
+	rec := httptest.NewRecorder()
+	HTTPError(rec, fmt.Errorf("launch web-1: %w", &limitError{limit: 8}), 403)
+
+	resp := rec.Result()
+	body, _ := io.ReadAll(resp.Body)
+	got := FromResponse(resp.StatusCode, resp.Header, body)
+	if got.Code != QuotaExceeded || got.Message != "launch web-1: over the limit" {
+		t.Fatalf("FromResponse = %+v", got)
+	}
+	// JSON numbers decode as float64
+	if got.Details["limit"] != float64(8) {
+		t.Errorf("details = %v, want limit 8", got.Details)
+	}
+}
+
+func TestFromResponseText(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		header   http.Header
+		body     string
+		wantCode Code
+		wantMsg  string
+	}{
+		{"proxy text", 502, http.Header{}, "upstream connect error\n", UpstreamFailed, "upstream connect error"},
+		{"empty body", 503, http.Header{}, "", Unavailable, "Service Unavailable"},
+		{"header code", 404, http.Header{Header: {"MC1300"}}, "operation not found", OperationNotFound, "operation not found"},
+		{"json without code", 409, http.Header{}, `{"error":"conflict"}`, Conflict, `{"error":"conflict"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FromResponse(tt.status, tt.header, []byte(tt.body))
+			if got.Code != tt.wantCode || got.Message != tt.wantMsg {
+				t.Errorf("FromResponse = %+v, want code %s message %q", got, tt.wantCode, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"coded", fmt.Errorf("GET /workloads/abc: %w", New(WorkloadNotFound, "workload not found")),
+			"MC1200 WorkloadNotFound: GET /workloads/abc: workload not found"},
+		{"uncoded", errors.New("permission denied"), "MC1000 Internal: permission denied"},
+		{"code from a newer server", &Error{Code: "MC9999", Message: "new thing"}, "MC9999: new thing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Format(tt.err); got != tt.want {
+				t.Errorf("Format() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
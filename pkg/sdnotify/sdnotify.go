@@ -0,0 +1,62 @@
+// Package sdnotify implements the sd_notify protocol a Type=notify systemd
+// service uses to report that it is ready and to ping the watchdog, without
+// linking libsystemd.
+package sdnotify
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// States sent with Notify.
+const (
+	Ready    = "READY=1"
+	Stopping = "STOPPING=1"
+	Watchdog = "WATCHDOG=1"
+)
+
+// Status is a free-form state shown by `systemctl status`.
+func Status(s string) string {
+	return "STATUS=" + s
+}
+
+// Notify sends state to systemd over $NOTIFY_SOCKET. It reports false, and
+// no error, when the process was not started by systemd as a notify service.
+//
+// Example Input:
+//   Notify(sdnotify.Ready)
+func Notify(state string) (bool, error) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return false, nil
+	}
+	if socket[0] == '@' {
+		socket = "\x00" + socket[1:] // abstract namespace
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// WatchdogInterval returns WatchdogSec of the service, from $WATCHDOG_USEC,
+// or 0 when the watchdog is not enabled for this process. Pings must be sent
+// more often, conventionally every half interval.
+func WatchdogInterval() time.Duration {
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0 // meant for another process
+	}
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
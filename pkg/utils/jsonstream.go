@@ -0,0 +1,124 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// jsonStreamFlushEvery is how many elements are written between flushes,
+// so clients start receiving large lists before the last row is read.
+const jsonStreamFlushEvery = 100
+
+// JSONStream writes a JSON array to an HTTP response one element at a time,
+// so list handlers can encode rows straight from a database cursor instead of
+// collecting them into a slice first. With a field name the array is wrapped in
+// an object, {"items": [...], ...extra fields}.
+//
+// Nothing is written until the first element (or Close), so a handler can still
+// reply with http.Error if the query fails before any row was read. An error
+// after that can only cut the response short; clients see invalid JSON.
+//
+// Example:
+//   stream := utils.NewJSONStream(w, "items")
+//   err := repo.Each(ctx, func(e *Event) error { return stream.Write(e) })
+//   if err != nil && !stream.Started() { http.Error(w, err.Error(), 500); return }
+//   stream.Close(map[string]any{"next_offset": 100})
+//   => {"items":[{...},{...}],"next_offset":100}
+type JSONStream struct {
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	enc     *json.Encoder
+	field   string
+	count   int
+	started bool
+}
+
+// NewJSONStream creates a stream writing to w. field is "" for a bare array.
+func NewJSONStream(w http.ResponseWriter, field string) *JSONStream {
+	return &JSONStream{
+		w:     w,
+		rc:    http.NewResponseController(w),
+		enc:   json.NewEncoder(w),
+		field: field,
+	}
+}
+
+// Started reports whether any part of the response has been written.
+func (s *JSONStream) Started() bool {
+	return s.started
+}
+
+// Count returns the number of elements written so far.
+func (s *JSONStream) Count() int {
+	return s.count
+}
+
+func (s *JSONStream) start() error {
+	if s.started {
+		return nil
+	}
+	s.started = true
+	s.w.Header().Set("Content-Type", "application/json")
+
+	open := "["
+	if s.field != "" {
+		key, err := json.Marshal(s.field)
+		if err != nil {
+			return err
+		}
+		open = "{" + string(key) + ":["
+	}
+	_, err := s.w.Write([]byte(open))
+	return err
+}
+
+// Write appends one element to the array.
+func (s *JSONStream) Write(v any) error {
+	if err := s.start(); err != nil {
+		return err
+	}
+	if s.count > 0 {
+		if _, err := s.w.Write([]byte(",")); err != nil {
+			return err
+		}
+	}
+	// Encode appends a newline, which is valid whitespace between elements
+	if err := s.enc.Encode(v); err != nil {
+		return err
+	}
+	s.count++
+
+	if s.count%jsonStreamFlushEvery == 0 {
+		if err := s.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close ends the array and, for an object stream, adds the extra fields after it.
+// extra is ignored for a bare array.
+func (s *JSONStream) Close(extra map[string]any) error {
+	if err := s.start(); err != nil {
+		return err
+	}
+
+	end := "]"
+	if s.field != "" {
+		for k, v := range extra {
+			key, err := json.Marshal(k)
+			if err != nil {
+				return err
+			}
+			value, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			end += "," + string(key) + ":" + string(value)
+		}
+		end += "}"
+	}
+	_, err := s.w.Write([]byte(end + "\n"))
+	return err
+}
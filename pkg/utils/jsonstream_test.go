@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestJSONStream(t *testing.T) {
+	type item struct {
+		ID int `json:"id"`
+	}
+
+	tests := []struct {
+		name  string
+		field string
+		items []item
+		extra map[string]any
+		want  any
+	}{
+		{
+			name: "empty bare array",
+			want: []any{},
+		},
+		{
+			name:  "bare array ignores extra",
+			items: []item{{1}, {2}},
+			extra: map[string]any{"next_offset": 2},
+			want:  []any{map[string]any{"id": 1.0}, map[string]any{"id": 2.0}},
+		},
+		{
+			name:  "empty object stream",
+			field: "items",
+			want:  map[string]any{"items": []any{}},
+		},
+		{
+			name:  "object stream with extra fields",
+			field: "items",
+			items: []item{{1}, {2}, {3}},
+			extra: map[string]any{"next_offset": 3, "truncated": false},
+			want: map[string]any{
+				"items":       []any{map[string]any{"id": 1.0}, map[string]any{"id": 2.0}, map[string]any{"id": 3.0}},
+				"next_offset": 3.0,
+				"truncated":   false,
+			},
+		},
+		{
+			name:  "more than one flush",
+			field: "items",
+			items: make([]item, jsonStreamFlushEvery*2+1),
+			want: map[string]any{"items": func() []any {
+				out := make([]any, jsonStreamFlushEvery*2+1)
+				for i := range out {
+					out[i] = map[string]any{"id": 0.0}
+				}
+				return out
+			}()},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			stream := NewJSONStream(rec, tt.field)
+			if stream.Started() {
+				t.Fatal("Started before the first write")
+			}
+			for _, it := range tt.items {
+				if err := stream.Write(it); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := stream.Close(tt.extra); err != nil {
+				t.Fatal(err)
+			}
+
+			if !stream.Started() {
+				t.Error("not Started after Close")
+			}
+			if stream.Count() != len(tt.items) {
+				t.Errorf("Count = %d, want %d", stream.Count(), len(tt.items))
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %q", ct)
+			}
+			var got any
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("invalid JSON %q: %v", rec.Body.String(), err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("body = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONStreamNothingWrittenBeforeFirstElement(t *testing.T) {
+	rec := httptest.NewRecorder()
+	stream := NewJSONStream(rec, "items")
+	if rec.Body.Len() != 0 || stream.Started() {
+		t.Fatal("stream wrote before the first element")
+	}
+}
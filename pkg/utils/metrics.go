@@ -0,0 +1,161 @@
+package utils
+
+import (
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// HostMetrics contains a point-in-time sample of host resource usage.
+// Network counters are cumulative; use NetworkThroughput to turn two samples into rates.
+type HostMetrics struct {
+	CPU            int       // Number of CPU cores
+	Load1          float64   // 1 minute load average
+	Load5          float64   // 5 minute load average
+	Load15         float64   // 15 minute load average
+	MemoryUsedMB   int       // MemTotal - MemAvailable in megabytes
+	MemoryTotalMB  int       // Total system memory in megabytes
+	DiskUsedBytes  uint64    // Used bytes on the sampled filesystem
+	DiskTotalBytes uint64    // Size of the sampled filesystem in bytes
+	NetRxBytes     uint64    // Total bytes received on all non-loopback interfaces
+	NetTxBytes     uint64    // Total bytes sent on all non-loopback interfaces
+	SampledAt      time.Time // When the sample was taken
+}
+
+// readMeminfo parses /proc/meminfo into a map of field name to kilobytes.
+func readMeminfo() map[string]int {
+	values := map[string]int{}
+
+	data, err := os.ReadFile("/proc/meminfo")
+	if err != nil {
+		return values
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		// Lines look like "MemAvailable:  8192000 kB"
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		kb, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+		values[strings.TrimSuffix(fields[0], ":")] = kb
+	}
+	return values
+}
+
+// GetLoadAverage reads the 1, 5 and 15 minute load averages from /proc/loadavg.
+//
+// Returns:
+//   The three load averages, or zeros if unable to read or parse the file
+func GetLoadAverage() (load1, load5, load15 float64) {
+	data, err := os.ReadFile("/proc/loadavg")
+	if err != nil {
+		return 0, 0, 0
+	}
+
+	// Format: "0.52 0.58 0.59 1/467 12345"
+	fields := strings.Fields(string(data))
+	if len(fields) < 3 {
+		return 0, 0, 0
+	}
+	load1, _ = strconv.ParseFloat(fields[0], 64)
+	load5, _ = strconv.ParseFloat(fields[1], 64)
+	load15, _ = strconv.ParseFloat(fields[2], 64)
+	return load1, load5, load15
+}
+
+// GetDiskUsage returns used and total bytes of the filesystem containing path.
+func GetDiskUsage(path string) (used uint64, total uint64, err error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, 0, err
+	}
+
+	total = st.Blocks * uint64(st.Bsize)
+	free := st.Bfree * uint64(st.Bsize)
+	return total - free, total, nil
+}
+
+// GetNetworkCounters sums received and transmitted bytes of all interfaces
+// listed in /proc/net/dev, excluding the loopback interface.
+func GetNetworkCounters() (rx uint64, tx uint64) {
+	data, err := os.ReadFile("/proc/net/dev")
+	if err != nil {
+		return 0, 0
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		// Lines look like "  eth0: 1234 12 0 0 0 0 0 0 5678 34 0 0 0 0 0 0"
+		name, counters, ok := strings.Cut(line, ":")
+		if !ok || strings.TrimSpace(name) == "lo" {
+			continue
+		}
+		fields := strings.Fields(counters)
+		if len(fields) < 9 {
+			continue
+		}
+		r, _ := strconv.ParseUint(fields[0], 10, 64)
+		t, _ := strconv.ParseUint(fields[8], 10, 64)
+		rx += r
+		tx += t
+	}
+	return rx, tx
+}
+
+// SampleHostMetrics takes a snapshot of CPU load, memory, disk and network usage.
+//
+// Parameters:
+//   diskPath - Mount point whose filesystem usage is reported (e.g. "/")
+//
+// Returns:
+//   - A pointer to HostMetrics with the current values
+//   - An error if the disk usage cannot be read
+func SampleHostMetrics(diskPath string) (*HostMetrics, error) {
+	m := &HostMetrics{
+		CPU:       runtime.NumCPU(),
+		SampledAt: time.Now(),
+	}
+
+	m.Load1, m.Load5, m.Load15 = GetLoadAverage()
+
+	mem := readMeminfo()
+	m.MemoryTotalMB = mem["MemTotal"] / 1024
+	m.MemoryUsedMB = (mem["MemTotal"] - mem["MemAvailable"]) / 1024
+
+	used, total, err := GetDiskUsage(diskPath)
+	if err != nil {
+		return nil, err
+	}
+	m.DiskUsedBytes = used
+	m.DiskTotalBytes = total
+
+	m.NetRxBytes, m.NetTxBytes = GetNetworkCounters()
+
+	return m, nil
+}
+
+// NetworkThroughput computes receive and transmit rates in bytes per second
+// between two samples. Counter resets (e.g. an interface going away) yield zero.
+func NetworkThroughput(prev *HostMetrics, cur *HostMetrics) (rxPerSec uint64, txPerSec uint64) {
+	if prev == nil {
+		return 0, 0
+	}
+	elapsed := cur.SampledAt.Sub(prev.SampledAt).Seconds()
+	if elapsed <= 0 {
+		return 0, 0
+	}
+
+	if cur.NetRxBytes >= prev.NetRxBytes {
+		rxPerSec = uint64(float64(cur.NetRxBytes-prev.NetRxBytes) / elapsed)
+	}
+	if cur.NetTxBytes >= prev.NetTxBytes {
+		txPerSec = uint64(float64(cur.NetTxBytes-prev.NetTxBytes) / elapsed)
+	}
+	return rxPerSec, txPerSec
+}
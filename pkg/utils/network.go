@@ -3,6 +3,8 @@ package utils
 import (
 	"fmt"
 	"net"
+	"strconv"
+	"time"
 )
 
 // IsLANInterface checks if the given network interface name is a common LAN interface.
@@ -29,10 +31,12 @@ func IsLANInterface(name string) bool {
 }
 
 // IsPrivateIP checks if the given IP address is in a private network range.
-// Private IP ranges are defined by RFC 1918:
+// Private IPv4 ranges are defined by RFC 1918, and IPv6 unique local addresses
+// (ULA) by RFC 4193:
 //   - 10.0.0.0/8        (10.0.0.0 - 10.255.255.255)
 //   - 172.16.0.0/12     (172.16.0.0 - 172.31.255.255)
 //   - 192.168.0.0/16    (192.168.0.0 - 192.168.255.255)
+//   - fc00::/7          (fc00:: - fdff:ffff:...)
 //
 // Parameters:
 //   ip - The IP address to check
@@ -44,6 +48,7 @@ func IsPrivateIP(ip net.IP) bool {
 		"10.0.0.0/8",      // Class A private network
 		"172.16.0.0/12",   // Class B private network
 		"192.168.0.0/16",  // Class C private network
+		"fc00::/7",        // IPv6 unique local addresses
 	}
 	// Check if the IP falls within any of the private ranges
 	for _, cidr := range privateRanges {
@@ -55,6 +60,26 @@ func IsPrivateIP(ip net.IP) bool {
 	return false
 }
 
+// IsULA reports whether ip is an IPv6 unique local address (fc00::/7).
+func IsULA(ip net.IP) bool {
+	return ip.To4() == nil && len(ip) == net.IPv6len && ip[0]&0xfe == 0xfc
+}
+
+// isUsableIPv6 reports whether ip is an IPv6 address other nodes can reach:
+// global unicast or ULA, not link-local (which needs a zone) or loopback.
+func isUsableIPv6(ip net.IP) bool {
+	return ip.To4() == nil && ip.To16() != nil && ip.IsGlobalUnicast()
+}
+
+// HostPort joins a host and port into an address, bracketing IPv6 literals.
+//
+// Example Output:
+//   HostPort("10.0.0.5", 9028)     => "10.0.0.5:9028"
+//   HostPort("fd00::5", 9028)      => "[fd00::5]:9028"
+func HostPort(host string, port int) string {
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
 // GetLocalIPv4 returns the local IPv4 address with a priority system for selecting the best address.
 // 
 // Priority order:
@@ -127,8 +152,59 @@ func GetLocalIPv4() (string, error) {
 	return "", fmt.Errorf("no IPv4 address found")
 }
 
-// GetAllIPs returns a list of all IPv4 addresses from active network interfaces on the system.
-// This function excludes loopback interfaces and only returns IPv4 addresses (not IPv6).
+// GetLocalIPv6 returns the local IPv6 address with the same priority system as
+// GetLocalIPv4: a unique local address (ULA) on a LAN interface, else any
+// address on a LAN interface, else any ULA. Link-local addresses are skipped.
+//
+// Returns:
+//   - The selected IPv6 address as a string
+//   - An error if no suitable IPv6 address is found
+func GetLocalIPv6() (string, error) {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return "", err
+	}
+
+	var lanIP, otherIP string
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok || !isUsableIPv6(ipNet.IP) {
+				continue
+			}
+			ipStr := ipNet.IP.String()
+			if IsLANInterface(iface.Name) && IsULA(ipNet.IP) {
+				return ipStr, nil
+			}
+			if lanIP == "" && IsLANInterface(iface.Name) {
+				lanIP = ipStr
+			}
+			if otherIP == "" && IsULA(ipNet.IP) {
+				otherIP = ipStr
+			}
+		}
+	}
+
+	if lanIP != "" {
+		return lanIP, nil
+	}
+	if otherIP != "" {
+		return otherIP, nil
+	}
+	return "", fmt.Errorf("no IPv6 address found")
+}
+
+// GetAllIPs returns the addresses of the active network interfaces on the
+// system: every IPv4 address first, then every global or unique local IPv6
+// address. Loopback interfaces and link-local IPv6 addresses are excluded, so
+// the first address stays IPv4 on dual-stack hosts.
 //
 // Useful for discovering all available IP addresses on the machine, such as for:
 //   - Network diagnostics
@@ -136,9 +212,9 @@ func GetLocalIPv4() (string, error) {
 //   - Cluster node discovery
 //
 // Returns:
-//   A slice of net.IP containing all IPv4 addresses found, or an empty slice if none or error occurs
+//   A slice of net.IP containing all addresses found, or an empty slice if none or error occurs
 func GetAllIPs() []net.IP {
-	var ips []net.IP
+	var ips, ipv6 []net.IP
 
 	// Get all network interfaces on the system
 	ifaces, err := net.Interfaces()
@@ -151,7 +227,7 @@ func GetAllIPs() []net.IP {
 		if iface.Flags&net.FlagUp == 0 {
 			continue
 		}
-		// Skip loopback interfaces (127.0.0.1)
+		// Skip loopback interfaces (127.0.0.1, ::1)
 		if iface.Flags&net.FlagLoopback != 0 {
 			continue
 		}
@@ -177,16 +253,106 @@ func GetAllIPs() []net.IP {
 				continue
 			}
 
-			// Convert to IPv4 only (returns nil if it's IPv6)
-			ip = ip.To4()
-			if ip == nil {
-				continue
+			if ip4 := ip.To4(); ip4 != nil {
+				ips = append(ips, ip4)
+			} else if isUsableIPv6(ip) {
+				ipv6 = append(ipv6, ip)
 			}
+		}
+	}
+
+	return append(ips, ipv6...)
+}
+
+// InterfaceIPv4 returns the first IPv4 address of the named network interface.
+//
+// Parameters:
+//   name - The interface name, e.g. "eth0"
+//
+// Returns:
+//   - The interface's IPv4 address
+//   - An error if the interface does not exist or has no IPv4 address
+func InterfaceIPv4(name string) (net.IP, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, err
+	}
 
-			// Add the IPv4 address to the result list
-			ips = append(ips, ip)
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, err
+	}
+	for _, addr := range addrs {
+		if v, ok := addr.(*net.IPNet); ok && v.IP.To4() != nil {
+			return v.IP.To4(), nil
 		}
 	}
+	return nil, fmt.Errorf("interface %s has no IPv4 address", name)
+}
 
-	return ips
+// InterfaceIP returns the first IPv4 address of the named network interface,
+// else its first global or unique local IPv6 address, for IPv6-only networks.
+//
+// Example Output (Error):
+//   Returns: error("interface eth1 has no usable IP address")
+func InterfaceIP(name string) (net.IP, error) {
+	if ip, err := InterfaceIPv4(name); err == nil {
+		return ip, nil
+	}
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, err
+	}
+	for _, addr := range addrs {
+		if v, ok := addr.(*net.IPNet); ok && isUsableIPv6(v.IP) {
+			return v.IP, nil
+		}
+	}
+	return nil, fmt.Errorf("interface %s has no usable IP address", name)
+}
+
+// CheckAdvertiseAddress verifies that ip belongs to an active interface of this
+// host and, when port is not 0, that peers can reach ip:port: either something
+// already answers there or a listener can be bound to it.
+//
+// Parameters:
+//   ip   - The address to advertise
+//   port - The port other nodes will dial on ip, e.g. 8443 for LXD; 0 skips the check
+//
+// Returns:
+//   An error if the address is not on this host or the port cannot be served on it
+//
+// Example Output (Error):
+//   Returns: error("address 10.9.9.9 is not on any active interface of this host (have [10.0.0.5 192.168.1.20])")
+func CheckAdvertiseAddress(ip net.IP, port int) error {
+	ips := GetAllIPs()
+	found := false
+	for _, local := range ips {
+		if local.Equal(ip) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("address %s is not on any active interface of this host (have %v)", ip, ips)
+	}
+	if port == 0 {
+		return nil
+	}
+
+	addr := HostPort(ip.String(), port)
+	if conn, err := net.DialTimeout("tcp", addr, 2*time.Second); err == nil {
+		conn.Close()
+		return nil
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("port %d cannot be served on %s: %w", port, ip, err)
+	}
+	ln.Close()
+	return nil
 }
@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"net"
+	"testing"
+)
+
+func TestCheckAdvertiseAddress(t *testing.T) {
+	if err := CheckAdvertiseAddress(net.ParseIP("192.0.2.1"), 0); err == nil {
+		t.Error("192.0.2.1: got nil, want an error for an address not on this host")
+	}
+
+	ips := GetAllIPs()
+	if len(ips) == 0 {
+		t.Skip("no active non-loopback IPv4 interface")
+	}
+	if err := CheckAdvertiseAddress(ips[0], 0); err != nil {
+		t.Errorf("%s: %v", ips[0], err)
+	}
+
+	// A port already served on the address is reachable
+	ln, err := net.Listen("tcp", net.JoinHostPort(ips[0].String(), "0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	port := ln.Addr().(*net.TCPAddr).Port
+	if err := CheckAdvertiseAddress(ips[0], port); err != nil {
+		t.Errorf("%s:%d: %v", ips[0], port, err)
+	}
+}
+
+func TestIPv6Helpers(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"10.1.2.3":    true,
+		"172.20.0.1":  true,
+		"8.8.8.8":     false,
+		"fd00::10":    true,
+		"fc12::1":     true,
+		"2001:db8::1": false,
+		"fe80::1":     false,
+	} {
+		if got := IsPrivateIP(net.ParseIP(addr)); got != want {
+			t.Errorf("IsPrivateIP(%s): got %v, want %v", addr, got, want)
+		}
+	}
+	if !IsULA(net.ParseIP("fd00::10")) || IsULA(net.ParseIP("10.0.0.1")) || IsULA(net.ParseIP("2001:db8::1")) {
+		t.Error("IsULA: wrong result")
+	}
+
+	for _, tt := range []struct {
+		host string
+		want string
+	}{
+		{"10.0.0.5", "10.0.0.5:9028"},
+		{"fd00::5", "[fd00::5]:9028"},
+	} {
+		if got := HostPort(tt.host, 9028); got != tt.want {
+			t.Errorf("HostPort(%s): got %s, want %s", tt.host, got, tt.want)
+		}
+	}
+
+	// IPv4 addresses come first, and no link-local IPv6 address is listed
+	ips := GetAllIPs()
+	seen6 := false
+	for _, ip := range ips {
+		if ip.To4() == nil {
+			seen6 = true
+			if ip.IsLinkLocalUnicast() {
+				t.Errorf("GetAllIPs: link-local %s", ip)
+			}
+		} else if seen6 {
+			t.Errorf("GetAllIPs: IPv4 %s after an IPv6 address in %v", ip, ips)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+const (
+	// DefaultPageLimit is the page size of list endpoints without a limit parameter.
+	DefaultPageLimit = 100
+
+	// MaxPageLimit caps the limit parameter of list endpoints.
+	MaxPageLimit = 500
+)
+
+// ParsePage reads the limit/offset query parameters of a list endpoint, with
+// DefaultPageLimit when no limit is given and limits above MaxPageLimit capped.
+//
+// Example Input:
+//   GET /workloads?limit=1000&offset=200
+//
+// Example Output:
+//   (500, 200, nil)
+//
+// Example Output (Error):
+//   GET /workloads?limit=-1  =>  (0, 0, error("invalid limit"))
+func ParsePage(r *http.Request) (limit int, offset int, err error) {
+	limit = DefaultPageLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 {
+			return 0, 0, errors.New("invalid limit")
+		}
+	}
+	if limit > MaxPageLimit {
+		limit = MaxPageLimit
+	}
+	if v := r.URL.Query().Get("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil || offset < 0 {
+			return 0, 0, errors.New("invalid offset")
+		}
+	}
+	return limit, offset, nil
+}
+
+// ParseListQuery reads the sort parameter of a list endpoint and those of the
+// given filter parameters that are set. Which fields may be sorted by is
+// checked by the repository.
+//
+// Example Input:
+//   GET /nodes?sort=-hostname&status=ready&role=  with filters "status", "role"
+//
+// Example Output:
+//   ("-hostname", map[string]string{"status": "ready"})
+func ParseListQuery(r *http.Request, filters ...string) (sort string, filter map[string]string) {
+	query := r.URL.Query()
+	for _, name := range filters {
+		if v := query.Get(name); v != "" {
+			if filter == nil {
+				filter = map[string]string{}
+			}
+			filter[name] = v
+		}
+	}
+	return query.Get("sort"), filter
+}
@@ -0,0 +1,154 @@
+package lxd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"mcloud/pkg/commander"
+)
+
+// ACLConfig is an LXD network ACL managed by mcloud. On OVN networks LXD
+// programs it as OVN ACLs on the ports of the NICs it is assigned to; traffic
+// no rule allows is rejected.
+type ACLConfig struct {
+	Name        string
+	Description string
+	Ingress     []ACLRule
+	Egress      []ACLRule
+}
+
+// ACLRule is one rule of a network ACL, in LXD's field names. Empty fields
+// match anything.
+//
+// Example:
+//   ACLRule{Action: "allow", Source: "10.0.0.0/8", Protocol: "tcp", DestinationPort: "22"}
+type ACLRule struct {
+	Action          string `json:"action"`
+	State           string `json:"state"`
+	Description     string `json:"description,omitempty"`
+	Source          string `json:"source,omitempty"`
+	Destination     string `json:"destination,omitempty"`
+	Protocol        string `json:"protocol,omitempty"`
+	DestinationPort string `json:"destination_port,omitempty"`
+}
+
+// aclExists reports whether a network ACL exists.
+func aclExists(ctx context.Context, name string) (bool, error) {
+	output, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "lxc", "query", "/1.0/network-acls")
+	if err != nil {
+		return false, fmt.Errorf("failed to list network ACLs: %w", err)
+	}
+	var urls []string
+	if err := json.Unmarshal([]byte(output), &urls); err != nil {
+		return false, fmt.Errorf("failed to parse network ACLs: %w", err)
+	}
+	for _, u := range urls {
+		if u == "/1.0/network-acls/"+name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ApplyACL creates the network ACL if it does not exist and replaces its
+// rules, so applying the same ACLConfig again changes nothing. NICs using the
+// ACL pick up the new rules at once.
+func ApplyACL(ctx context.Context, cfg ACLConfig) error {
+	exists, err := aclExists(ctx, cfg.Name)
+	if err != nil {
+		return err
+	}
+	body := map[string]any{
+		"description": cfg.Description,
+		"ingress":     enabledRules(cfg.Ingress),
+		"egress":      enabledRules(cfg.Egress),
+	}
+
+	method, path := "PUT", "/1.0/network-acls/"+cfg.Name
+	if !exists {
+		log.Debug("Creating network ACL %s", cfg.Name)
+		method, path = "POST", "/1.0/network-acls"
+		body["name"] = cfg.Name
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	if _, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "lxc", "query", "-X", method, path, "--data", string(data)); err != nil {
+		return fmt.Errorf("failed to apply network ACL %s: %w", cfg.Name, err)
+	}
+	return nil
+}
+
+// enabledRules copies rules, enabling those without a state.
+func enabledRules(rules []ACLRule) []ACLRule {
+	out := make([]ACLRule, len(rules))
+	for i, r := range rules {
+		if r.State == "" {
+			r.State = "enabled"
+		}
+		out[i] = r
+	}
+	return out
+}
+
+// DeleteACL deletes a network ACL. An ACL that does not exist is not an
+// error; LXD refuses to delete one that NICs still use.
+func DeleteACL(ctx context.Context, name string) error {
+	exists, err := aclExists(ctx, name)
+	if err != nil || !exists {
+		return err
+	}
+	log.Debug("Deleting network ACL %s", name)
+	if _, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "lxc", "network", "acl", "delete", name); err != nil {
+		return fmt.Errorf("failed to delete network ACL %s: %w", name, err)
+	}
+	return nil
+}
+
+// SetInstanceACLs assigns network ACLs to the instance's NICs on network,
+// replacing the ACLs they had; no ACLs removes them. A NIC inherited from a
+// profile is overridden on the instance first.
+func SetInstanceACLs(ctx context.Context, name string, network string, acls []string) error {
+	output, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "lxc", "query", projectPath(ctx, "/1.0/instances/"+name))
+	if err != nil {
+		return fmt.Errorf("failed to get instance %s: %w", name, err)
+	}
+	var inst struct {
+		Devices         map[string]map[string]string `json:"devices"`
+		ExpandedDevices map[string]map[string]string `json:"expanded_devices"`
+	}
+	if err := json.Unmarshal([]byte(output), &inst); err != nil {
+		return fmt.Errorf("failed to parse instance %s: %w", name, err)
+	}
+
+	var nics []string
+	for dev, d := range inst.ExpandedDevices {
+		if d["type"] == "nic" && d["network"] == network {
+			nics = append(nics, dev)
+		}
+	}
+	if len(nics) == 0 {
+		return fmt.Errorf("instance %s has no NIC on network %s", name, network)
+	}
+	sort.Strings(nics)
+
+	value := "security.acls=" + strings.Join(acls, ",")
+	for _, dev := range nics {
+		if inst.ExpandedDevices[dev]["security.acls"] == strings.Join(acls, ",") {
+			continue
+		}
+		verb := "set"
+		if _, local := inst.Devices[dev]; !local {
+			verb = "override"
+		}
+		log.Debug("Setting ACLs of %s/%s to %v", name, dev, acls)
+		if _, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "lxc", projectArgs(ctx, "config", "device", verb, name, dev, value)...); err != nil {
+			return fmt.Errorf("failed to set ACLs of instance %s: %w", name, err)
+		}
+	}
+	return nil
+}
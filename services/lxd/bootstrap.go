@@ -2,6 +2,7 @@ package lxd
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"os/exec"
 
@@ -40,21 +41,44 @@ func generateInitConfig(nodeName string, address string) (*InitConfigYaml, error
 	}, nil
 }
 
+// InitPreseed returns the preseed Bootstrap feeds to `lxd init --preseed`.
+//
+// Example Output:
+//   config:
+//     core.https_address: 10.0.0.10:8443
+//   cluster:
+//     enabled: true
+//     server_name: prod
+//     cluster_address: 10.0.0.10:8443
+func InitPreseed(cfg BootstrapConfig) ([]byte, error) {
+	initCfg, err := generateInitConfig(cfg.ClusterName, cfg.Address)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(initCfg)
+}
+
 // RunInit executes the 'lxd init' command with the provided preseed configuration
-func RunInit(initCfg *InitConfigYaml) error {
+func RunInit(ctx context.Context, initCfg *InitConfigYaml) error {
 	data, err := yaml.Marshal(initCfg)
 	if err != nil {
 		return err
 	}
 
-	cmd := exec.Command("lxd", "init", "--preseed")
+	cmd := exec.CommandContext(ctx, "lxd", "init", "--preseed")
 	cmd.Stdin = bytes.NewReader(data)
 
 	return cmd.Run()
 }
 
-// Bootstrap initializes a new LXD cluster with the given configuration
-func Bootstrap(cfg BootstrapConfig) error {
+// Bootstrap initializes a new LXD cluster with the given configuration.
+// It does nothing when LXD is already clustered, e.g. by an interrupted init.
+func Bootstrap(ctx context.Context, cfg BootstrapConfig) error {
+	if clustered, err := Clustered(ctx); err == nil && clustered {
+		log.Info("LXD is already clustered, skipping lxd init")
+		return nil
+	}
+
 	// generate init config
 	data, err := generateInitConfig(cfg.ClusterName, cfg.Address)
 	if err != nil {
@@ -62,7 +86,7 @@ func Bootstrap(cfg BootstrapConfig) error {
 	}
 
 	// run lxd init with "preseed"
-	initErr := RunInit(data)
+	initErr := RunInit(ctx, data)
 	if initErr != nil {
 		return fmt.Errorf("failed to bootstrap LXD cluster: %w", initErr)
 	}
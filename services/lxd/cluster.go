@@ -0,0 +1,97 @@
+package lxd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"mcloud/pkg/commander"
+)
+
+// EvacuateMember moves (or stops, per each instance's cluster.evacuate setting)
+// all instances off a cluster member and stops new instances being placed on it.
+func EvacuateMember(member string) error {
+	log.Debug("Evacuating cluster member %s", member)
+
+	if _, err := commander.ExecCommandWithRetry(context.Background(), commander.DefaultRetryOptions, "lxc", "cluster", "evacuate", member, "--force"); err != nil {
+		return fmt.Errorf("failed to evacuate member %s: %w", member, err)
+	}
+	return nil
+}
+
+// RestoreMember brings an evacuated member back and restarts the instances that were moved off it.
+func RestoreMember(member string) error {
+	log.Debug("Restoring cluster member %s", member)
+
+	if _, err := commander.ExecCommandWithRetry(context.Background(), commander.DefaultRetryOptions, "lxc", "cluster", "restore", member, "--force"); err != nil {
+		return fmt.Errorf("failed to restore member %s: %w", member, err)
+	}
+	return nil
+}
+
+// InstanceLocations returns the cluster member each instance currently runs on, keyed by instance name.
+func InstanceLocations() (map[string]string, error) {
+	states, err := InstanceStates()
+	if err != nil {
+		return nil, err
+	}
+
+	locations := make(map[string]string, len(states))
+	for name, st := range states {
+		locations[name] = st.Location
+	}
+	return locations, nil
+}
+
+// RemoveMember removes a member from the LXD cluster. With force the member is
+// removed even if it is unreachable; instances still on it are lost.
+func RemoveMember(ctx context.Context, member string, force bool) error {
+	log.Debug("Removing cluster member %s", member)
+
+	args := []string{"cluster", "remove", member}
+	if force {
+		args = append(args, "--force", "--yes")
+	}
+	if _, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "lxc", args...); err != nil {
+		return fmt.Errorf("failed to remove member %s: %w", member, err)
+	}
+	return nil
+}
+
+// ClusterMember is a member of the LXD cluster as listed by /1.0/cluster/members.
+type ClusterMember struct {
+	ServerName string `json:"server_name"`
+	Status     string `json:"status"` // Online, Offline, Evacuated, Blocked
+	Message    string `json:"message"`
+}
+
+// ClusterMembers lists the members of the LXD cluster.
+//
+// Example Output:
+//   [{ServerName: "node1", Status: "Online", Message: "Fully operational"}, {ServerName: "node2", Status: "Offline", ...}]
+func ClusterMembers(ctx context.Context) ([]ClusterMember, error) {
+	output, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "lxc", "query", "/1.0/cluster/members?recursion=1")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cluster members: %w", err)
+	}
+	var members []ClusterMember
+	if err := json.Unmarshal([]byte(output), &members); err != nil {
+		return nil, fmt.Errorf("failed to parse cluster members: %w", err)
+	}
+	return members, nil
+}
+
+// Clustered reports whether clustering is enabled on the local LXD server.
+func Clustered(ctx context.Context) (bool, error) {
+	output, err := commander.ExecCommandContext(ctx, "lxc", "query", "/1.0/cluster")
+	if err != nil {
+		return false, fmt.Errorf("failed to get cluster: %w", err)
+	}
+	var cluster struct {
+		Enabled bool `json:"enabled"`
+	}
+	if err := json.Unmarshal([]byte(output), &cluster); err != nil {
+		return false, fmt.Errorf("failed to parse cluster: %w", err)
+	}
+	return cluster.Enabled, nil
+}
@@ -0,0 +1,107 @@
+package lxd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"mcloud/pkg/commander"
+
+	"golang.org/x/net/websocket"
+)
+
+// LifecycleEvent is an instance lifecycle event from LXD's event stream, e.g.
+// an instance started, stopped or shut down on any cluster member.
+//
+// Example:
+//   LifecycleEvent{Action: "instance-stopped", Instance: "web-1", Location: "node2", Time: 2026-10-16 09:00:00}
+type LifecycleEvent struct {
+	Action   string // e.g. instance-started, instance-stopped, instance-shutdown, instance-deleted
+	Instance string
+	Project  string // LXD project of the instance
+	Location string // cluster member the event happened on
+	Time     time.Time
+}
+
+// WatchLifecycle follows /1.0/events?type=lifecycle on the local LXD socket
+// and calls handle for every instance event, in all projects, until ctx is
+// cancelled or the stream breaks. LXD relays the events of every cluster
+// member, so one listener sees the whole cluster. It returns nil once ctx is cancelled.
+func WatchLifecycle(ctx context.Context, handle func(LifecycleEvent)) error {
+	config, err := websocket.NewConfig("ws://lxd/1.0/events?type=lifecycle&all-projects=true", "http://lxd/")
+	if err != nil {
+		return err
+	}
+	conn, err := dialSocket(ctx, "", "")
+	if err != nil {
+		return fmt.Errorf("failed to connect to LXD events: %w", err)
+	}
+	ws, err := websocket.NewClient(config, conn)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to connect to LXD events: %w", err)
+	}
+	defer ws.Close()
+
+	// Receive blocks; closing the socket unblocks it when ctx ends
+	stop := context.AfterFunc(ctx, func() { ws.Close() })
+	defer stop()
+
+	for {
+		var msg struct {
+			Type      string    `json:"type"`
+			Timestamp time.Time `json:"timestamp"`
+			Location  string    `json:"location"`
+			Metadata  struct {
+				Action string `json:"action"`
+				Source string `json:"source"` // e.g. /1.0/instances/web-1?project=default
+			} `json:"metadata"`
+		}
+		if err := websocket.JSON.Receive(ws, &msg); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("LXD event stream: %w", err)
+		}
+		name, project, ok := instanceFromSource(msg.Metadata.Source)
+		if msg.Type != "lifecycle" || !ok || !strings.HasPrefix(msg.Metadata.Action, "instance-") {
+			continue
+		}
+		handle(LifecycleEvent{Action: msg.Metadata.Action, Instance: name, Project: project, Location: msg.Location, Time: msg.Timestamp})
+	}
+}
+
+// instanceFromSource returns the instance name and project of an event source,
+// e.g. web-1 and team-a of /1.0/instances/web-1?project=team-a. Sources below
+// an instance, such as its snapshots, are not the instance's own events.
+func instanceFromSource(source string) (string, string, bool) {
+	path, query, _ := strings.Cut(source, "?")
+	name, ok := strings.CutPrefix(path, "/1.0/instances/")
+	if !ok || name == "" || strings.Contains(name, "/") {
+		return "", "", false
+	}
+	project := DefaultProject
+	if q, err := url.ParseQuery(query); err == nil && q.Get("project") != "" {
+		project = q.Get("project")
+	}
+	return name, project, true
+}
+
+// InstanceStatus returns the status of one instance, e.g. Running, Stopped,
+// Frozen or Error.
+func InstanceStatus(ctx context.Context, name string) (string, error) {
+	output, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "lxc", "query", projectPath(ctx, "/1.0/instances/"+name))
+	if err != nil {
+		return "", fmt.Errorf("failed to get instance %s: %w", name, err)
+	}
+	var inst struct {
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal([]byte(output), &inst); err != nil {
+		return "", fmt.Errorf("failed to parse instance %s: %w", name, err)
+	}
+	return inst.Status, nil
+}
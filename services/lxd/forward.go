@@ -0,0 +1,52 @@
+package lxd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"mcloud/pkg/commander"
+)
+
+// CreateForward forwards all traffic to listen, an external address, to
+// target on an OVN network. LXD programs it as an OVN load balancer, so it
+// follows the instance to whichever member hosts it. listen must be routed to
+// the network's uplink (its ipv4.routes / ipv6.routes).
+func CreateForward(ctx context.Context, network string, listen string, target string) error {
+	log.Debug("Forwarding %s to %s on network %s", listen, target, network)
+	if _, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "lxc", "network", "forward", "create", network, listen, "target_address="+target); err != nil {
+		return fmt.Errorf("failed to create forward %s on network %s: %w", listen, network, err)
+	}
+	return nil
+}
+
+// DeleteForward removes the forward of listen from an OVN network.
+func DeleteForward(ctx context.Context, network string, listen string) error {
+	log.Debug("Removing forward %s from network %s", listen, network)
+	if _, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "lxc", "network", "forward", "delete", network, listen); err != nil {
+		return fmt.Errorf("failed to delete forward %s on network %s: %w", listen, network, err)
+	}
+	return nil
+}
+
+// ListForwards returns the forwards of a network: the target address of each
+// listen address.
+func ListForwards(ctx context.Context, network string) (map[string]string, error) {
+	output, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "lxc", "query", "/1.0/networks/"+network+"/forwards?recursion=1")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list forwards of network %s: %w", network, err)
+	}
+
+	var forwards []struct {
+		ListenAddress string            `json:"listen_address"`
+		Config        map[string]string `json:"config"`
+	}
+	if err := json.Unmarshal([]byte(output), &forwards); err != nil {
+		return nil, fmt.Errorf("failed to parse forwards of network %s: %w", network, err)
+	}
+	targets := make(map[string]string, len(forwards))
+	for _, f := range forwards {
+		targets[f.ListenAddress] = f.Config["target_address"]
+	}
+	return targets, nil
+}
@@ -0,0 +1,71 @@
+package lxd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"mcloud/pkg/commander"
+)
+
+// ImportImage adds a local image file (unified tarball or qcow2-based VM image)
+// to the LXD image store under the given alias.
+func ImportImage(path string, alias string) error {
+	log.Debug("Importing image %s from %s", alias, path)
+
+	if _, err := commander.ExecCommandWithRetry(context.Background(), commander.DefaultRetryOptions, "lxc", "image", "import", path, "--alias", alias); err != nil {
+		return fmt.Errorf("failed to import image %s: %w", alias, err)
+	}
+
+	return nil
+}
+
+// PublishInstance publishes a stopped instance as an image in the cluster image
+// store under alias, replacing the alias if it exists. properties are stored on
+// the image (e.g. mcloud.build_id) and shown by `lxc image info`. Returns the
+// fingerprint of the new image.
+func PublishInstance(ctx context.Context, name string, alias string, properties map[string]string) (string, error) {
+	log.Debug("Publishing instance %s as image %s", name, alias)
+
+	args := []string{"publish", name, "--alias", alias, "--reuse"}
+	keys := make([]string, 0, len(properties))
+	for k := range properties {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		args = append(args, k+"="+properties[k])
+	}
+
+	// Publishing is not idempotent (a retry would create a second image), so run once
+	opts := commander.DefaultRetryOptions
+	opts.Attempts = 1
+	if _, err := commander.ExecCommandWithRetry(ctx, opts, "lxc", args...); err != nil {
+		return "", fmt.Errorf("failed to publish instance %s: %w", name, err)
+	}
+
+	return ImageFingerprint(ctx, alias)
+}
+
+// ImageFingerprint returns the fingerprint of the image an alias points to.
+func ImageFingerprint(ctx context.Context, alias string) (string, error) {
+	output, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "lxc", "image", "alias", "list", "--format", "json")
+	if err != nil {
+		return "", fmt.Errorf("failed to list image aliases: %w", err)
+	}
+
+	var aliases []struct {
+		Name   string `json:"name"`
+		Target string `json:"target"`
+	}
+	if err := json.Unmarshal([]byte(output), &aliases); err != nil {
+		return "", fmt.Errorf("failed to parse image aliases: %w", err)
+	}
+	for _, a := range aliases {
+		if a.Name == alias {
+			return a.Target, nil
+		}
+	}
+	return "", fmt.Errorf("image alias %s not found", alias)
+}
@@ -0,0 +1,361 @@
+package lxd
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sort"
+	"time"
+
+	"mcloud/pkg/commander"
+	"mcloud/pkg/logger"
+)
+
+var log = logger.Named("lxd")
+
+type CopyConfig struct {
+	Source     string // name of the instance to copy
+	Snapshot   string // optional snapshot of the source to copy from
+	Name       string // name of the new instance
+	TargetNode string // optional cluster member to place the copy on
+}
+
+// CopyInstance creates a new instance from an existing instance (or one of its snapshots).
+// LXD regenerates volatile keys such as MAC addresses on copy, so the new instance
+// receives fresh MACs and obtains its own addresses when it first boots.
+func CopyInstance(ctx context.Context, cfg CopyConfig) error {
+	source := cfg.Source
+	if cfg.Snapshot != "" {
+		source = source + "/" + cfg.Snapshot
+	}
+
+	log.Debug("Copying instance %s to %s", source, cfg.Name)
+
+	args := []string{"copy", source, cfg.Name}
+	if cfg.TargetNode != "" {
+		args = append(args, "--target", cfg.TargetNode)
+	}
+
+	if _, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "lxc", projectArgs(ctx, args...)...); err != nil {
+		return fmt.Errorf("failed to copy instance %s: %w", source, err)
+	}
+
+	return nil
+}
+
+type LaunchConfig struct {
+	Name       string            // instance name
+	Image      string            // image alias or remote:alias, e.g. ubuntu:24.04
+	VM         bool              // launch a virtual machine instead of a container
+	TargetNode string            // optional cluster member to place the instance on
+	Pool       string            // optional storage pool for the root disk (default profile pool otherwise)
+	Profiles   []string          // profiles to apply in order; only "default" when empty
+	RootSize   string            // optional root disk size, e.g. 20GiB
+	Config     map[string]string // instance config keys, e.g. environment.FOO
+}
+
+// LaunchInstance creates and starts a new instance.
+// Config values are passed with -c and may contain secrets, so they are never logged.
+func LaunchInstance(ctx context.Context, cfg LaunchConfig) error {
+	log.Debug("Launching instance %s from %s", cfg.Name, cfg.Image)
+
+	args := []string{"launch", cfg.Image, cfg.Name}
+	if cfg.VM {
+		args = append(args, "--vm")
+	}
+	if cfg.TargetNode != "" {
+		args = append(args, "--target", cfg.TargetNode)
+	}
+	for _, p := range cfg.Profiles {
+		args = append(args, "--profile", p)
+	}
+	if cfg.Pool != "" {
+		args = append(args, "--storage", cfg.Pool)
+	}
+	// Given again on the instance: its root disk replaces the profiles' when
+	// --storage picks another pool
+	if cfg.RootSize != "" {
+		args = append(args, "--device", "root,size="+cfg.RootSize)
+	}
+
+	// Sort keys so the command line is deterministic
+	keys := make([]string, 0, len(cfg.Config))
+	for k := range cfg.Config {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		args = append(args, "-c", k+"="+cfg.Config[k])
+	}
+
+	if _, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "lxc", projectArgs(ctx, args...)...); err != nil {
+		return fmt.Errorf("failed to launch instance %s: %w", cfg.Name, err)
+	}
+
+	return nil
+}
+
+// SetInstanceConfig sets config keys on an existing instance and unsets the keys in unset.
+// Values may contain secrets, so they are never logged.
+func SetInstanceConfig(ctx context.Context, name string, set map[string]string, unset []string) error {
+	log.Debug("Updating config of instance %s", name)
+
+	if len(set) > 0 {
+		keys := make([]string, 0, len(set))
+		for k := range set {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		args := []string{"config", "set", name}
+		for _, k := range keys {
+			args = append(args, k+"="+set[k])
+		}
+		if _, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "lxc", projectArgs(ctx, args...)...); err != nil {
+			return fmt.Errorf("failed to set config of instance %s: %w", name, err)
+		}
+	}
+
+	for _, k := range unset {
+		if _, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "lxc", projectArgs(ctx, "config", "unset", name, k)...); err != nil {
+			return fmt.Errorf("failed to unset %s on instance %s: %w", k, name, err)
+		}
+	}
+
+	return nil
+}
+
+// InstanceState is the runtime state of an instance as reported by `lxc list`.
+type InstanceState struct {
+	Project   string   // LXD project of the instance
+	Location  string   // cluster member the instance runs on
+	Status    string   // e.g. Running, Stopped
+	Addresses []string // global IPv4 and IPv6 addresses on all NICs except loopback
+}
+
+// InstanceStates returns the state of every instance in the cluster, in all
+// projects, keyed by instance name (mcloud keeps names unique across projects).
+// Addresses come from the instance's network state, so they include addresses handed
+// out on OVN networks; stopped instances (and VMs without the LXD agent) have none.
+func InstanceStates() (map[string]InstanceState, error) {
+	output, err := commander.ExecCommandWithRetry(context.Background(), commander.DefaultRetryOptions, "lxc", "list", "--all-projects", "--format", "json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list instances: %w", err)
+	}
+
+	var instances []struct {
+		Project  string `json:"project"`
+		Name     string `json:"name"`
+		Location string `json:"location"`
+		Status   string `json:"status"`
+		State    *struct {
+			Network map[string]struct {
+				Addresses []struct {
+					Family  string `json:"family"`
+					Address string `json:"address"`
+					Scope   string `json:"scope"`
+				} `json:"addresses"`
+			} `json:"network"`
+		} `json:"state"`
+	}
+	if err := json.Unmarshal([]byte(output), &instances); err != nil {
+		return nil, fmt.Errorf("failed to parse instance list: %w", err)
+	}
+
+	states := make(map[string]InstanceState, len(instances))
+	for _, inst := range instances {
+		st := InstanceState{Project: cmp.Or(inst.Project, DefaultProject), Location: inst.Location, Status: inst.Status, Addresses: []string{}}
+		if inst.State != nil {
+			// Sort NIC names so the address order is stable between refreshes
+			nics := make([]string, 0, len(inst.State.Network))
+			for nic := range inst.State.Network {
+				nics = append(nics, nic)
+			}
+			sort.Strings(nics)
+
+			for _, nic := range nics {
+				if nic == "lo" {
+					continue
+				}
+				for _, a := range inst.State.Network[nic].Addresses {
+					if a.Scope == "global" && (a.Family == "inet" || a.Family == "inet6") {
+						st.Addresses = append(st.Addresses, a.Address)
+					}
+				}
+			}
+		}
+		states[inst.Name] = st
+	}
+	return states, nil
+}
+
+// InstanceDevices returns the devices of an instance including those of its
+// profiles, keyed by device name.
+//
+// Example Output:
+//   {"eth0": {"type": "nic", "network": "default"}, "pgdata": {"type": "disk", "pool": "remote", "source": "pgdata", "path": "/var/lib/postgresql"}}
+func InstanceDevices(ctx context.Context, name string) (map[string]map[string]string, error) {
+	output, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "lxc", "query", projectPath(ctx, "/1.0/instances/"+name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get instance %s: %w", name, err)
+	}
+	var inst struct {
+		ExpandedDevices map[string]map[string]string `json:"expanded_devices"`
+	}
+	if err := json.Unmarshal([]byte(output), &inst); err != nil {
+		return nil, fmt.Errorf("failed to parse instance %s: %w", name, err)
+	}
+	return inst.ExpandedDevices, nil
+}
+
+// InstanceUsage is the resource footprint of one instance, in any LXD project.
+type InstanceUsage struct {
+	Project     string
+	Name        string
+	Running     bool
+	MemoryBytes uint64 // current memory usage
+	DiskBytes   uint64 // root disk usage (0 if the storage driver does not report it)
+}
+
+// ListInstanceUsage returns the usage of every instance in all projects.
+func ListInstanceUsage() ([]InstanceUsage, error) {
+	output, err := commander.ExecCommandWithRetry(context.Background(), commander.DefaultRetryOptions, "lxc", "list", "--all-projects", "--format", "json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list instances: %w", err)
+	}
+
+	var instances []struct {
+		Project string `json:"project"`
+		Name    string `json:"name"`
+		Status  string `json:"status"`
+		State   *struct {
+			Memory struct {
+				Usage uint64 `json:"usage"`
+			} `json:"memory"`
+			Disk map[string]struct {
+				Usage uint64 `json:"usage"`
+			} `json:"disk"`
+		} `json:"state"`
+	}
+	if err := json.Unmarshal([]byte(output), &instances); err != nil {
+		return nil, fmt.Errorf("failed to parse instance list: %w", err)
+	}
+
+	items := make([]InstanceUsage, 0, len(instances))
+	for _, inst := range instances {
+		u := InstanceUsage{Project: inst.Project, Name: inst.Name, Running: inst.Status == "Running"}
+		if inst.State != nil {
+			u.MemoryBytes = inst.State.Memory.Usage
+			u.DiskBytes = inst.State.Disk["root"].Usage
+		}
+		if u.Project == "" {
+			u.Project = "default"
+		}
+		items = append(items, u)
+	}
+	return items, nil
+}
+
+// StartInstance starts a stopped instance.
+func StartInstance(ctx context.Context, name string) error {
+	log.Debug("Starting instance %s", name)
+
+	if _, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "lxc", projectArgs(ctx, "start", name)...); err != nil {
+		return fmt.Errorf("failed to start instance %s: %w", name, err)
+	}
+	return nil
+}
+
+// StopInstance stops a running instance, giving it the default LXD timeout to shut down cleanly.
+func StopInstance(ctx context.Context, name string) error {
+	log.Debug("Stopping instance %s", name)
+
+	if _, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "lxc", projectArgs(ctx, "stop", name)...); err != nil {
+		return fmt.Errorf("failed to stop instance %s: %w", name, err)
+	}
+	return nil
+}
+
+// DeleteInstance deletes an instance, stopping it first if it is running.
+func DeleteInstance(ctx context.Context, name string) error {
+	log.Debug("Deleting instance %s", name)
+
+	if _, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "lxc", projectArgs(ctx, "delete", name, "--force")...); err != nil {
+		return fmt.Errorf("failed to delete instance %s: %w", name, err)
+	}
+	return nil
+}
+
+// RunScript runs a shell script inside an instance with `sh -ec` as root and
+// returns its combined output, truncated to maxOutput bytes. A failing script
+// returns its output along with the error. Scripts are not idempotent, so they
+// are never retried.
+func RunScript(ctx context.Context, name string, script string, maxOutput int) (string, error) {
+	log.Debug("Running script in instance %s", name)
+
+	opts := commander.DefaultRetryOptions
+	opts.Attempts = 1
+	opts.MaxOutputBytes = maxOutput
+	output, err := commander.ExecCommandWithRetry(ctx, opts, "lxc", projectArgs(ctx, "exec", name, "--", "sh", "-ec", script)...)
+	if err != nil {
+		var cmdErr *commander.CommandError
+		if errors.As(err, &cmdErr) {
+			output = cmdErr.Stdout + cmdErr.Stderr
+		}
+		return output, fmt.Errorf("script failed in instance %s: %w", name, err)
+	}
+	return output, nil
+}
+
+type ExecConfig struct {
+	Name    string            // instance name
+	Command []string          // command and arguments
+	Env     map[string]string // extra environment variables
+	Stdin   io.Reader
+	Stdout  io.Writer
+	Stderr  io.Writer
+}
+
+// ExecInstance runs a command in an instance, streaming its input and output,
+// and returns its exit code. A command that ran and exited non-zero is not an
+// error. It bypasses the commander (and its circuit breaker): the exit status
+// belongs to the user's command, not to LXD. Cancelling ctx kills the session.
+func ExecInstance(ctx context.Context, cfg ExecConfig) (int, error) {
+	log.Debug("Running command in instance %s", cfg.Name)
+
+	args := []string{"exec", cfg.Name, "--mode=non-interactive"}
+	keys := make([]string, 0, len(cfg.Env))
+	for k := range cfg.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		args = append(args, "--env", k+"="+cfg.Env[k])
+	}
+	args = append(args, "--")
+	args = append(args, cfg.Command...)
+
+	cmd := exec.CommandContext(ctx, "lxc", projectArgs(ctx, args...)...)
+	cmd.Stdin = cfg.Stdin
+	cmd.Stdout = cfg.Stdout
+	cmd.Stderr = cfg.Stderr
+	// Stdin may be a network stream that never ends; stop waiting for it once the command exits
+	cmd.WaitDelay = time.Second
+
+	err := cmd.Run()
+	if errors.Is(err, exec.ErrWaitDelay) {
+		err = nil // exited 0 with input still open
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && ctx.Err() == nil {
+		return exitErr.ExitCode(), nil
+	}
+	if err != nil {
+		return -1, fmt.Errorf("failed to exec in instance %s: %w", cfg.Name, err)
+	}
+	return 0, nil
+}
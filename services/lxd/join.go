@@ -1,6 +1,7 @@
 package lxd
 
 import (
+	"context"
 	"fmt"
 )
 
@@ -35,7 +36,7 @@ func generateJoinConfig(
 }
 
 // JoinCluster joins an existing LXD cluster with the given configuration
-func JoinCluster(cfg JoinConfig) (string, error) {
+func JoinCluster(ctx context.Context, cfg JoinConfig) (string, error) {
 	// generate init config
 	data, err := generateJoinConfig(cfg.nodeName, cfg.nodeAddress, cfg.clusterAddress, cfg.clusterCertificate, cfg.clusterToken)
 	if err != nil {
@@ -43,7 +44,7 @@ func JoinCluster(cfg JoinConfig) (string, error) {
 	}
 
 	// run lxd init with preseed
-	initErr := RunInit(data)
+	initErr := RunInit(ctx, data)
 	if initErr != nil {
 		return "", fmt.Errorf("failed to join LXD cluster: %w", initErr)
 	}
@@ -0,0 +1,116 @@
+package lxd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"mcloud/pkg/commander"
+)
+
+// LoadBalancerConfig is an LXD network load balancer managed by mcloud. On
+// OVN networks LXD programs it as an OVN load balancer spreading connections
+// to ListenAddress:ListenPort over the backends. ListenAddress must be routed
+// to the network's uplink (its ipv4.routes / ipv6.routes).
+type LoadBalancerConfig struct {
+	ListenAddress string
+	Description   string
+	Protocol      string // tcp or udp
+	ListenPort    int
+	TargetPort    int
+	Backends      []LoadBalancerBackend
+}
+
+// LoadBalancerBackend is one target of a load balancer.
+//
+// Example:
+//   LoadBalancerBackend{Name: "550e8400-e29b-41d4-a716-446655440000", Description: "default/web-1", TargetAddress: "10.154.12.5"}
+type LoadBalancerBackend struct {
+	Name          string
+	Description   string
+	TargetAddress string
+}
+
+// loadBalancerExists reports whether network has a load balancer on listen.
+func loadBalancerExists(ctx context.Context, network string, listen string) (bool, error) {
+	output, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "lxc", "query", "/1.0/networks/"+network+"/load-balancers")
+	if err != nil {
+		return false, fmt.Errorf("failed to list load balancers of network %s: %w", network, err)
+	}
+	var urls []string
+	if err := json.Unmarshal([]byte(output), &urls); err != nil {
+		return false, fmt.Errorf("failed to parse load balancers of network %s: %w", network, err)
+	}
+	for _, u := range urls {
+		if u == "/1.0/networks/"+network+"/load-balancers/"+listen {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ApplyLoadBalancer creates the load balancer of cfg.ListenAddress on an OVN
+// network if it does not exist and replaces its backends and port, so
+// applying the same LoadBalancerConfig again changes nothing. Without
+// backends the port is left out: LXD rejects a port with no target.
+func ApplyLoadBalancer(ctx context.Context, network string, cfg LoadBalancerConfig) error {
+	exists, err := loadBalancerExists(ctx, network, cfg.ListenAddress)
+	if err != nil {
+		return err
+	}
+
+	backends := make([]map[string]string, 0, len(cfg.Backends))
+	names := make([]string, 0, len(cfg.Backends))
+	for _, b := range cfg.Backends {
+		backends = append(backends, map[string]string{
+			"name":           b.Name,
+			"description":    b.Description,
+			"target_address": b.TargetAddress,
+			"target_port":    strconv.Itoa(cfg.TargetPort),
+		})
+		names = append(names, b.Name)
+	}
+	ports := []map[string]any{}
+	if len(names) > 0 {
+		ports = append(ports, map[string]any{
+			"protocol":       cfg.Protocol,
+			"listen_port":    strconv.Itoa(cfg.ListenPort),
+			"target_backend": names,
+		})
+	}
+	body := map[string]any{
+		"description": cfg.Description,
+		"backends":    backends,
+		"ports":       ports,
+	}
+
+	method, path := "PUT", "/1.0/networks/"+network+"/load-balancers/"+cfg.ListenAddress
+	if !exists {
+		log.Debug("Creating load balancer %s on network %s", cfg.ListenAddress, network)
+		method, path = "POST", "/1.0/networks/"+network+"/load-balancers"
+		body["listen_address"] = cfg.ListenAddress
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	if _, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "lxc", "query", "-X", method, path, "--data", string(data)); err != nil {
+		return fmt.Errorf("failed to apply load balancer %s on network %s: %w", cfg.ListenAddress, network, err)
+	}
+	return nil
+}
+
+// DeleteLoadBalancer removes the load balancer of listen from an OVN network.
+// A load balancer that does not exist is not an error.
+func DeleteLoadBalancer(ctx context.Context, network string, listen string) error {
+	exists, err := loadBalancerExists(ctx, network, listen)
+	if err != nil || !exists {
+		return err
+	}
+	log.Debug("Removing load balancer %s from network %s", listen, network)
+	if _, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "lxc", "network", "load-balancer", "delete", network, listen); err != nil {
+		return fmt.Errorf("failed to delete load balancer %s on network %s: %w", listen, network, err)
+	}
+	return nil
+}
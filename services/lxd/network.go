@@ -0,0 +1,42 @@
+package lxd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+
+	"mcloud/pkg/commander"
+)
+
+// SetNetworkConfig sets config keys of a network, e.g. dns.nameservers of an
+// OVN network, which its DHCP server then hands to the instances.
+func SetNetworkConfig(ctx context.Context, network string, config map[string]string) error {
+	args := []string{"network", "set", network}
+	for _, k := range slices.Sorted(maps.Keys(config)) {
+		args = append(args, k+"="+config[k])
+	}
+	log.Debug("Setting %v on network %s", config, network)
+	if _, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "lxc", args...); err != nil {
+		return fmt.Errorf("failed to configure network %s: %w", network, err)
+	}
+	return nil
+}
+
+// NetworkConfig returns the config keys of a network, e.g. ipv4.address of
+// an OVN network and, once LXD allocated it, volatile.network.ipv4.address,
+// its address on the uplink.
+func NetworkConfig(ctx context.Context, network string) (map[string]string, error) {
+	output, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "lxc", "query", "/1.0/networks/"+network)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get network %s: %w", network, err)
+	}
+	var n struct {
+		Config map[string]string `json:"config"`
+	}
+	if err := json.Unmarshal([]byte(output), &n); err != nil {
+		return nil, fmt.Errorf("failed to parse network %s: %w", network, err)
+	}
+	return n.Config, nil
+}
@@ -0,0 +1,131 @@
+package lxd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"mcloud/pkg/commander"
+)
+
+// ProfileConfig is an LXD profile managed by mcloud.
+//
+// Example:
+//   ProfileConfig{Name: "flavor-small", Config: map[string]string{"limits.cpu": "1", "limits.memory": "1024MiB"},
+//     RootPool: "remote", RootSize: "10GiB"}
+type ProfileConfig struct {
+	Name        string
+	Description string
+	Config      map[string]string // profile config keys, e.g. limits.cpu
+	RootPool    string            // with RootSize, the pool of the profile's root disk
+	RootSize    string            // optional root disk size, e.g. 10GiB
+}
+
+type profile struct {
+	Config  map[string]string            `json:"config"`
+	Devices map[string]map[string]string `json:"devices"`
+}
+
+// getProfile returns a profile, or nil if it does not exist.
+func getProfile(ctx context.Context, name string) (*profile, error) {
+	output, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "lxc", "query", projectPath(ctx, "/1.0/profiles"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list profiles: %w", err)
+	}
+	var urls []string
+	if err := json.Unmarshal([]byte(output), &urls); err != nil {
+		return nil, fmt.Errorf("failed to parse profiles: %w", err)
+	}
+	found := false
+	for _, u := range urls {
+		if u == "/1.0/profiles/"+name {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, nil
+	}
+
+	output, err = commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "lxc", "query", projectPath(ctx, "/1.0/profiles/"+name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get profile %s: %w", name, err)
+	}
+	var p profile
+	if err := json.Unmarshal([]byte(output), &p); err != nil {
+		return nil, fmt.Errorf("failed to parse profile %s: %w", name, err)
+	}
+	return &p, nil
+}
+
+// ApplyProfile creates the profile if it does not exist and sets its config
+// and root disk, so applying the same ProfileConfig again changes nothing.
+// Instances using the profile pick up the changes.
+func ApplyProfile(ctx context.Context, cfg ProfileConfig) error {
+	current, err := getProfile(ctx, cfg.Name)
+	if err != nil {
+		return err
+	}
+	if current == nil {
+		log.Debug("Creating profile %s", cfg.Name)
+		if _, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "lxc", projectArgs(ctx, "profile", "create", cfg.Name)...); err != nil {
+			return fmt.Errorf("failed to create profile %s: %w", cfg.Name, err)
+		}
+		current = &profile{}
+	}
+
+	if cfg.Description != "" {
+		if _, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "lxc", projectArgs(ctx, "profile", "set", cfg.Name, "--property", "description="+cfg.Description)...); err != nil {
+			return fmt.Errorf("failed to set description of profile %s: %w", cfg.Name, err)
+		}
+	}
+
+	keys := make([]string, 0, len(cfg.Config))
+	for k, v := range cfg.Config {
+		if current.Config[k] != v {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) > 0 {
+		sort.Strings(keys)
+		args := []string{"profile", "set", cfg.Name}
+		for _, k := range keys {
+			args = append(args, k+"="+cfg.Config[k])
+		}
+		if _, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "lxc", projectArgs(ctx, args...)...); err != nil {
+			return fmt.Errorf("failed to set config of profile %s: %w", cfg.Name, err)
+		}
+	}
+
+	if cfg.RootSize == "" {
+		return nil
+	}
+	root, ok := current.Devices["root"]
+	switch {
+	case !ok:
+		_, err = commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "lxc", projectArgs(ctx, "profile", "device", "add", cfg.Name, "root", "disk",
+			"path=/", "pool="+cfg.RootPool, "size="+cfg.RootSize)...)
+	case root["size"] != cfg.RootSize || root["pool"] != cfg.RootPool:
+		_, err = commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "lxc", projectArgs(ctx, "profile", "device", "set", cfg.Name, "root",
+			"pool="+cfg.RootPool, "size="+cfg.RootSize)...)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set root disk of profile %s: %w", cfg.Name, err)
+	}
+	return nil
+}
+
+// DeleteProfile deletes a profile. A profile that does not exist is not an
+// error; LXD refuses to delete one that instances still use.
+func DeleteProfile(ctx context.Context, name string) error {
+	current, err := getProfile(ctx, name)
+	if err != nil || current == nil {
+		return err
+	}
+	log.Debug("Deleting profile %s", name)
+	if _, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "lxc", projectArgs(ctx, "profile", "delete", name)...); err != nil {
+		return fmt.Errorf("failed to delete profile %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package lxd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"mcloud/pkg/commander"
+)
+
+// DefaultProject is LXD's built-in project. Instance calls without a project
+// in their context go there, as they always did.
+const DefaultProject = "default"
+
+type projectKey struct{}
+
+// WithProject returns a context whose instance calls (launch, start, exec,
+// profiles, ...) run in an LXD project. Networks, network ACLs and custom
+// volumes are shared from the default project (see CreateProject), so calls
+// about them ignore it.
+//
+// Example:
+//   ctx = lxd.WithProject(ctx, "team-a")
+//   lxd.StartInstance(ctx, "web-1")  // lxc start web-1 --project team-a
+func WithProject(ctx context.Context, project string) context.Context {
+	return context.WithValue(ctx, projectKey{}, project)
+}
+
+// ProjectFrom returns the LXD project of ctx, DefaultProject if none is set.
+func ProjectFrom(ctx context.Context) string {
+	if p, _ := ctx.Value(projectKey{}).(string); p != "" {
+		return p
+	}
+	return DefaultProject
+}
+
+// projectArgs appends --project to an lxc command line when ctx names a
+// project other than the default one, so default-project command lines are
+// unchanged.
+func projectArgs(ctx context.Context, args ...string) []string {
+	if p := ProjectFrom(ctx); p != DefaultProject {
+		args = append(args, "--project", p)
+	}
+	return args
+}
+
+// projectPath adds the project of ctx to an API path for `lxc query` and the
+// socket client, e.g. /1.0/instances/web-1?project=team-a.
+func projectPath(ctx context.Context, apiPath string) string {
+	p := ProjectFrom(ctx)
+	if p == DefaultProject {
+		return apiPath
+	}
+	sep := "?"
+	if strings.Contains(apiPath, "?") {
+		sep = "&"
+	}
+	return apiPath + sep + "project=" + url.QueryEscape(p)
+}
+
+// ProjectConfig is an LXD project backing an mcloud project.
+type ProjectConfig struct {
+	Name        string
+	Description string
+	Network     string // network the project's instances are restricted to, e.g. the OVN network
+	Pool        string // storage pool of the root disk in the project's default profile
+}
+
+// CreateProject creates an LXD project with its own images and profiles, and
+// gives its default profile a root disk on cfg.Pool and an eth0 on cfg.Network.
+// Networks (with their ACLs and forwards) and custom volumes stay shared from
+// the default project, so security groups, floating IPs and volumes work the
+// same for every project; the project is restricted to cfg.Network and to
+// managed disks and NICs. Instances may still be placed with --target.
+func CreateProject(ctx context.Context, cfg ProjectConfig) error {
+	log.Debug("Creating project %s", cfg.Name)
+
+	args := []string{"project", "create", cfg.Name,
+		"-c", "features.images=true",
+		"-c", "features.profiles=true",
+		"-c", "features.networks=false",
+		"-c", "features.storage.volumes=false",
+		"-c", "restricted=true",
+		"-c", "restricted.cluster.target=allow",
+		"-c", "restricted.networks.access=" + cfg.Network,
+	}
+	if _, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "lxc", args...); err != nil {
+		return fmt.Errorf("failed to create project %s: %w", cfg.Name, err)
+	}
+	if cfg.Description != "" {
+		if _, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "lxc", "project", "set", cfg.Name, "--property", "description="+cfg.Description); err != nil {
+			return fmt.Errorf("failed to set description of project %s: %w", cfg.Name, err)
+		}
+	}
+
+	pctx := WithProject(ctx, cfg.Name)
+	devices := [][]string{
+		{"root", "disk", "path=/", "pool=" + cfg.Pool},
+		{"eth0", "nic", "network=" + cfg.Network, "name=eth0"},
+	}
+	for _, dev := range devices {
+		args := projectArgs(pctx, append([]string{"profile", "device", "add", "default"}, dev...)...)
+		if _, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "lxc", args...); err != nil {
+			return fmt.Errorf("failed to add %s to the default profile of project %s: %w", dev[0], cfg.Name, err)
+		}
+	}
+	return nil
+}
+
+// DeleteProject deletes an LXD project together with the profiles and cached
+// images left in it. A project that does not exist is not an error; LXD
+// refuses to delete one that still has instances.
+func DeleteProject(ctx context.Context, name string) error {
+	output, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "lxc", "query", "/1.0/projects")
+	if err != nil {
+		return fmt.Errorf("failed to list projects: %w", err)
+	}
+	var projects []string
+	if err := json.Unmarshal([]byte(output), &projects); err != nil {
+		return fmt.Errorf("failed to parse projects: %w", err)
+	}
+	found := false
+	for _, u := range projects {
+		if path.Base(u) == name {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil
+	}
+
+	pctx := WithProject(ctx, name)
+	for _, kind := range []string{"profiles", "images"} {
+		output, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "lxc", "query", projectPath(pctx, "/1.0/"+kind))
+		if err != nil {
+			return fmt.Errorf("failed to list %s of project %s: %w", kind, name, err)
+		}
+		var urls []string
+		if err := json.Unmarshal([]byte(output), &urls); err != nil {
+			return fmt.Errorf("failed to parse %s of project %s: %w", kind, name, err)
+		}
+		for _, u := range urls {
+			u, _, _ = strings.Cut(u, "?")
+			item := path.Base(u)
+			if kind == "profiles" && item == "default" {
+				continue
+			}
+			verb := strings.TrimSuffix(kind, "s")
+			if _, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "lxc", projectArgs(pctx, verb, "delete", item)...); err != nil {
+				return fmt.Errorf("failed to delete %s %s of project %s: %w", verb, item, name, err)
+			}
+		}
+	}
+
+	log.Debug("Deleting project %s", name)
+	if _, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "lxc", "project", "delete", name); err != nil {
+		return fmt.Errorf("failed to delete project %s: %w", name, err)
+	}
+	return nil
+}
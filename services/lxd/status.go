@@ -1,8 +1,12 @@
 package lxd
 
-import "mcloud/pkg/commander"
+import (
+	"context"
+
+	"mcloud/pkg/commander"
+)
 
 // ClusterStatus retrieves the status of the LXD cluster
 func ClusterStatus() (string, error) {
-	return commander.ExecCommand("lxc", "cluster", "list")
+	return commander.ExecCommandWithRetry(context.Background(), commander.DefaultRetryOptions, "lxc", "cluster", "list")
 }
@@ -0,0 +1,57 @@
+package lxd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"mcloud/pkg/commander"
+)
+
+// DefaultStoragePool returns the pool of the root disk in the default profile,
+// i.e. where instances launched without -s are stored.
+func DefaultStoragePool() (string, error) {
+	output, err := commander.ExecCommandWithRetry(context.Background(), commander.DefaultRetryOptions, "lxc", "profile", "device", "get", "default", "root", "pool")
+	if err != nil {
+		return "", fmt.Errorf("failed to get default storage pool: %w", err)
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// StoragePoolDriver returns the driver of a storage pool, e.g. ceph, zfs or dir.
+func StoragePoolDriver(pool string) (string, error) {
+	output, err := commander.ExecCommandWithRetry(context.Background(), commander.DefaultRetryOptions, "lxc", "query", "/1.0/storage-pools/"+pool)
+	if err != nil {
+		return "", fmt.Errorf("failed to get storage pool %s: %w", pool, err)
+	}
+
+	var p struct {
+		Driver string `json:"driver"`
+	}
+	if err := json.Unmarshal([]byte(output), &p); err != nil {
+		return "", fmt.Errorf("failed to parse storage pool %s: %w", pool, err)
+	}
+	return p.Driver, nil
+}
+
+// StoragePools returns the driver of every storage pool, keyed by pool name.
+func StoragePools(ctx context.Context) (map[string]string, error) {
+	output, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "lxc", "query", "/1.0/storage-pools?recursion=1")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list storage pools: %w", err)
+	}
+
+	var pools []struct {
+		Name   string `json:"name"`
+		Driver string `json:"driver"`
+	}
+	if err := json.Unmarshal([]byte(output), &pools); err != nil {
+		return nil, fmt.Errorf("failed to parse storage pools: %w", err)
+	}
+	drivers := make(map[string]string, len(pools))
+	for _, p := range pools {
+		drivers[p.Name] = p.Driver
+	}
+	return drivers, nil
+}
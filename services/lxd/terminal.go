@@ -0,0 +1,210 @@
+package lxd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+
+	"golang.org/x/net/websocket"
+)
+
+// SocketPath is the local LXD API. In a cluster LXD forwards requests about an
+// instance to the member hosting it, so terminals work from any node.
+const SocketPath = "/var/snap/lxd/common/lxd/unix.socket"
+
+// socketClient talks HTTP to the local LXD API.
+var socketClient = &http.Client{
+	Transport: &http.Transport{
+		DialContext: dialSocket,
+	},
+}
+
+func dialSocket(ctx context.Context, _, _ string) (net.Conn, error) {
+	var d net.Dialer
+	return d.DialContext(ctx, "unix", SocketPath)
+}
+
+type TerminalConfig struct {
+	Name    string            // instance name
+	Command []string          // command and arguments; empty attaches to the instance's console
+	Env     map[string]string // extra environment variables (exec only)
+	Width   int               // initial size in characters; 0 lets LXD pick
+	Height  int
+}
+
+// Terminal is an interactive session in an instance over LXD's websocket API:
+// an exec with a pseudo-terminal, or the instance's console. Read and Write
+// carry the raw terminal bytes.
+type Terminal struct {
+	name      string
+	operation string // e.g. "/1.0/operations/7c3d..."
+	console   bool
+	data      *websocket.Conn
+	control   *websocket.Conn
+
+	mu sync.Mutex // serializes control messages
+}
+
+// operationResponse is the part of an LXD response that describes an operation.
+type operationResponse struct {
+	Type      string `json:"type"`
+	Error     string `json:"error"`
+	Operation string `json:"operation"`
+	Metadata  struct {
+		ID       string `json:"id"`
+		Status   string `json:"status"`
+		Err      string `json:"err"`
+		Metadata struct {
+			FDs    map[string]string `json:"fds"`
+			Return int               `json:"return"`
+		} `json:"metadata"`
+	} `json:"metadata"`
+}
+
+// OpenTerminal starts an exec with a pseudo-terminal (or attaches to the
+// console when cfg.Command is empty) and connects its websockets. The caller
+// must Close the terminal.
+func OpenTerminal(ctx context.Context, cfg TerminalConfig) (*Terminal, error) {
+	path := "/1.0/instances/" + url.PathEscape(cfg.Name)
+	var body map[string]any
+	if len(cfg.Command) == 0 {
+		log.Debug("Attaching to the console of instance %s", cfg.Name)
+		path += "/console"
+		body = map[string]any{"type": "console", "width": cfg.Width, "height": cfg.Height}
+	} else {
+		log.Debug("Opening a terminal in instance %s", cfg.Name)
+		path += "/exec"
+		env := cfg.Env
+		if env == nil {
+			env = map[string]string{}
+		}
+		body = map[string]any{
+			"command":            cfg.Command,
+			"environment":        env,
+			"interactive":        true,
+			"wait-for-websocket": true,
+			"width":              cfg.Width,
+			"height":             cfg.Height,
+		}
+	}
+
+	op, err := request(ctx, http.MethodPost, projectPath(ctx, path), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open terminal in instance %s: %w", cfg.Name, err)
+	}
+	t := &Terminal{name: cfg.Name, operation: op.Operation, console: len(cfg.Command) == 0}
+
+	fds := op.Metadata.Metadata.FDs
+	if t.data, err = connectWebsocket(ctx, t.operation, fds["0"]); err != nil {
+		return nil, fmt.Errorf("failed to open terminal in instance %s: %w", cfg.Name, err)
+	}
+	if t.control, err = connectWebsocket(ctx, t.operation, fds["control"]); err != nil {
+		t.data.Close()
+		return nil, fmt.Errorf("failed to open terminal in instance %s: %w", cfg.Name, err)
+	}
+	return t, nil
+}
+
+// request calls the LXD API and decodes an operation response.
+func request(ctx context.Context, method string, path string, body any) (*operationResponse, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, "http://lxd"+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := socketClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var op operationResponse
+	if err := json.NewDecoder(resp.Body).Decode(&op); err != nil {
+		return nil, fmt.Errorf("decode LXD response: %w", err)
+	}
+	if op.Type == "error" {
+		return nil, fmt.Errorf("LXD: %s", op.Error)
+	}
+	return &op, nil
+}
+
+// connectWebsocket connects to one of an operation's websockets.
+func connectWebsocket(ctx context.Context, operation string, secret string) (*websocket.Conn, error) {
+	if secret == "" {
+		return nil, fmt.Errorf("operation %s has no websocket", operation)
+	}
+	config, err := websocket.NewConfig("ws://lxd"+operation+"/websocket?secret="+url.QueryEscape(secret), "http://lxd/")
+	if err != nil {
+		return nil, err
+	}
+	conn, err := dialSocket(ctx, "", "")
+	if err != nil {
+		return nil, err
+	}
+	ws, err := websocket.NewClient(config, conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	ws.PayloadType = websocket.BinaryFrame
+	return ws, nil
+}
+
+func (t *Terminal) Read(p []byte) (int, error) {
+	return t.data.Read(p)
+}
+
+func (t *Terminal) Write(p []byte) (int, error) {
+	return t.data.Write(p)
+}
+
+// Resize changes the size of the terminal, in characters.
+func (t *Terminal) Resize(width int, height int) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	msg := map[string]any{
+		"command": "window-resize",
+		"args":    map[string]string{"width": strconv.Itoa(width), "height": strconv.Itoa(height)},
+	}
+	return websocket.JSON.Send(t.control, msg)
+}
+
+// Wait waits for the command to exit and returns its exit code. A console has
+// no exit code; Wait returns 0 once it is detached.
+func (t *Terminal) Wait(ctx context.Context) (int, error) {
+	op, err := request(ctx, http.MethodGet, t.operation+"/wait?timeout=-1", nil)
+	if err != nil {
+		return -1, fmt.Errorf("failed to wait for terminal in instance %s: %w", t.name, err)
+	}
+	if t.console {
+		return 0, nil
+	}
+	if op.Metadata.Status == "Failure" {
+		return -1, fmt.Errorf("terminal in instance %s failed: %s", t.name, op.Metadata.Err)
+	}
+	return op.Metadata.Metadata.Return, nil
+}
+
+// Close disconnects the terminal. Closing the control websocket detaches a
+// console and hangs up an exec.
+func (t *Terminal) Close() error {
+	t.control.Close()
+	return t.data.Close()
+}
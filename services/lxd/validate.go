@@ -1,6 +1,7 @@
 package lxd
 
 import (
+	"context"
 	"fmt"
 	"mcloud/pkg/commander"
 )
@@ -12,7 +13,9 @@ type ValidateConfig struct {
 
 // Validate checks if the LXD cluster can be initialized with the given configuration
 func Validate(cfg ValidateConfig) (bool, error) {
-	_, err := commander.ExecCommand(
+	_, err := commander.ExecCommandWithRetry(
+		context.Background(),
+		commander.DefaultRetryOptions,
 		"lxd", "init", 
 		"--cluster",
 		"--cluster-name", cfg.clusterName,
@@ -0,0 +1,76 @@
+package lxd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"mcloud/pkg/commander"
+)
+
+// CreateVolume creates a custom filesystem volume of size (e.g. 10GiB) in a
+// storage pool. On a Ceph pool it is an RBD image, so it can be attached on
+// any cluster member.
+func CreateVolume(ctx context.Context, pool string, name string, size string) error {
+	log.Debug("Creating volume %s/%s (%s)", pool, name, size)
+	if _, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "lxc", "storage", "volume", "create", pool, name, "size="+size); err != nil {
+		return fmt.Errorf("failed to create volume %s/%s: %w", pool, name, err)
+	}
+	return nil
+}
+
+// DeleteVolume deletes a custom volume and its data. LXD refuses to delete a
+// volume that is still attached.
+func DeleteVolume(ctx context.Context, pool string, name string) error {
+	log.Debug("Deleting volume %s/%s", pool, name)
+	if _, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "lxc", "storage", "volume", "delete", pool, name); err != nil {
+		return fmt.Errorf("failed to delete volume %s/%s: %w", pool, name, err)
+	}
+	return nil
+}
+
+// AttachVolume adds a custom volume to an instance as a disk device named
+// after the volume, mounted at path. Running instances get it at once.
+func AttachVolume(ctx context.Context, pool string, name string, instance string, path string) error {
+	log.Debug("Attaching volume %s/%s to instance %s at %s", pool, name, instance, path)
+	if _, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "lxc", projectArgs(ctx, "storage", "volume", "attach", pool, name, instance, name, path)...); err != nil {
+		return fmt.Errorf("failed to attach volume %s/%s to instance %s: %w", pool, name, instance, err)
+	}
+	return nil
+}
+
+// DetachVolume removes a custom volume's disk device from an instance.
+func DetachVolume(ctx context.Context, pool string, name string, instance string) error {
+	log.Debug("Detaching volume %s/%s from instance %s", pool, name, instance)
+	if _, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "lxc", projectArgs(ctx, "storage", "volume", "detach", pool, name, instance, name)...); err != nil {
+		return fmt.Errorf("failed to detach volume %s/%s from instance %s: %w", pool, name, instance, err)
+	}
+	return nil
+}
+
+// ListVolumes returns the names of the custom volumes of a storage pool.
+func ListVolumes(ctx context.Context, pool string) ([]string, error) {
+	output, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "lxc", "query", "/1.0/storage-pools/"+pool+"/volumes/custom")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list volumes of pool %s: %w", pool, err)
+	}
+
+	// e.g. "/1.0/storage-pools/remote/volumes/custom/pgdata" (with ?target=<member> on local pools)
+	var urls []string
+	if err := json.Unmarshal([]byte(output), &urls); err != nil {
+		return nil, fmt.Errorf("failed to parse volumes of pool %s: %w", pool, err)
+	}
+	names := make([]string, 0, len(urls))
+	for _, u := range urls {
+		u, _, _ = strings.Cut(u, "?")
+		name, err := url.PathUnescape(path.Base(u))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse volume %q: %w", u, err)
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
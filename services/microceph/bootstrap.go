@@ -1,31 +1,37 @@
 package microceph
 
 import (
+	"context"
+
 	"mcloud/pkg/commander"
 	"mcloud/pkg/logger"
 )
 
+var log = logger.Named("microceph")
+
 type BootstrapConfig struct {
-	Disk string // example: /dev/sdb
+	Disks DiskConfig
 }
 
-// Bootstrap initializes the microceph service with the given configuration
-func Bootstrap(cfg BootstrapConfig) error {
+// Bootstrap initializes the microceph service with the given configuration.
+// `microceph init` is skipped when MicroCeph is already bootstrapped, e.g. by an
+// interrupted init that failed adding disks.
+func Bootstrap(ctx context.Context, cfg BootstrapConfig) error {
 	// Initialize microceph
-	if _, err := commander.ExecCommand("microceph", "init"); 
-	err != nil {
-		logger.Error("failed to init microceph: %v", err)
+	if Bootstrapped(ctx) {
+		log.Info("MicroCeph is already bootstrapped, skipping microceph init")
+	} else if _, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "microceph", "init"); err != nil {
+		log.Error("failed to init microceph: %v", err)
 		return err
 	}
 
-	// Add disk to microceph
-	if _, err := commander.ExecCommand(
-		"microceph", "disk", "add", cfg.Disk,
-	); 
-	err != nil {
-		logger.Error("failed to add disk: %v", err)
-		return err
-	}
+	// Add disks to microceph
+	return AddDisks(ctx, cfg.Disks)
+}
 
-	return nil
+// Bootstrapped reports whether MicroCeph is already initialized on this node,
+// i.e. `microceph status` succeeds.
+func Bootstrapped(ctx context.Context) bool {
+	_, err := commander.ExecCommandContext(ctx, "microceph", "status")
+	return err == nil
 }
@@ -0,0 +1,129 @@
+package microceph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"mcloud/pkg/commander"
+)
+
+// DiskConfig describes the OSDs to create on a node.
+// With no Paths, every available block device is used; if there are none and
+// LoopCount is set, loop-file OSDs are created instead (for test clusters).
+type DiskConfig struct {
+	Paths      []string // block devices, e.g. /dev/sdb, /dev/sdc
+	Wipe       bool     // wipe existing data/partitions before adding
+	Encrypt    bool     // encrypt the OSDs with LUKS
+	LoopSizeGB int      // size of each loop-file OSD (default 4)
+	LoopCount  int      // number of loop-file OSDs to create when no disk is available
+}
+
+// BlockDevice is a local disk as reported by lsblk.
+type BlockDevice struct {
+	Path       string `json:"path"`
+	SizeBytes  uint64 `json:"size"`
+	Model      string `json:"model"`
+	Rotational bool   `json:"rota"`
+}
+
+// defaultLoopSizeGB is used when DiskConfig.LoopSizeGB is not set.
+const defaultLoopSizeGB = 4
+
+// AvailableDisks returns whole disks that are safe to hand to Ceph: not read-only,
+// not mounted, without partitions and without a filesystem or other signature.
+func AvailableDisks() ([]BlockDevice, error) {
+	output, err := commander.ExecCommandWithRetry(context.Background(), commander.DefaultRetryOptions, "lsblk", "--json", "--bytes", "--output", "PATH,SIZE,MODEL,ROTA,TYPE,RO,MOUNTPOINT,FSTYPE")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list block devices: %w", err)
+	}
+
+	var result struct {
+		BlockDevices []struct {
+			BlockDevice
+			Type       string            `json:"type"`
+			ReadOnly   bool              `json:"ro"`
+			Mountpoint *string           `json:"mountpoint"`
+			FSType     *string           `json:"fstype"`
+			Children   []json.RawMessage `json:"children"`
+		} `json:"blockdevices"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse lsblk output: %w", err)
+	}
+
+	disks := []BlockDevice{}
+	for _, d := range result.BlockDevices {
+		if d.Type != "disk" || d.ReadOnly || d.Mountpoint != nil || d.FSType != nil || len(d.Children) > 0 {
+			continue
+		}
+		disks = append(disks, d.BlockDevice)
+	}
+	return disks, nil
+}
+
+// AddDisks adds OSDs to the local MicroCeph member according to cfg.
+//
+// Example Input:
+//   AddDisks(ctx, DiskConfig{Paths: []string{"/dev/sdb", "/dev/sdc"}, Wipe: true})
+//
+// Example Output:
+//   Runs: microceph disk add /dev/sdb /dev/sdc --wipe
+//
+// Example Input (test cluster without spare disks):
+//   AddDisks(ctx, DiskConfig{LoopCount: 3})
+//
+// Example Output:
+//   Runs: microceph disk add loop,4G,3
+func AddDisks(ctx context.Context, cfg DiskConfig) error {
+	args, err := DiskAddArgs(cfg)
+	if err != nil {
+		return err
+	}
+
+	log.Debug("Adding disks %v", args[2:])
+	if _, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "microceph", args...); err != nil {
+		log.Error("failed to add disks %v: %v", args[2:], err)
+		return err
+	}
+	return nil
+}
+
+// DiskAddArgs returns the microceph arguments AddDisks runs for cfg, after
+// discovering the available disks when cfg names none.
+//
+// Example Output:
+//   ["disk", "add", "/dev/sdb", "/dev/sdc", "--wipe"]
+func DiskAddArgs(cfg DiskConfig) ([]string, error) {
+	paths := cfg.Paths
+	if len(paths) == 0 {
+		disks, err := AvailableDisks()
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range disks {
+			paths = append(paths, d.Path)
+		}
+	}
+
+	if len(paths) == 0 {
+		if cfg.LoopCount <= 0 {
+			return nil, fmt.Errorf("no available disks for Ceph; pass disks explicitly or enable loop-file OSDs")
+		}
+		size := cfg.LoopSizeGB
+		if size <= 0 {
+			size = defaultLoopSizeGB
+		}
+		paths = []string{fmt.Sprintf("loop,%dG,%d", size, cfg.LoopCount)}
+		log.Warn("No available disks, adding %d loop-file OSDs of %dG (not for production)", cfg.LoopCount, size)
+	}
+
+	args := append([]string{"disk", "add"}, paths...)
+	if cfg.Wipe {
+		args = append(args, "--wipe")
+	}
+	if cfg.Encrypt {
+		args = append(args, "--encrypt")
+	}
+	return args, nil
+}
@@ -1,32 +1,34 @@
 package microceph
 
 import (
+	"context"
 	"fmt"
 	"mcloud/pkg/commander"
 )
 
+// JoinConfig is how a node joins MicroCeph: Token comes from
+// `microceph cluster add <hostname>` on a member, Disks are the node's OSDs.
 type JoinConfig struct {
-	joinToken string
-	disk      string
+	Token string
+	Disks DiskConfig
 }
 
 // Join makes the node join an existing microceph cluster
-func Join(cfg JoinConfig) error {
+func Join(ctx context.Context, cfg JoinConfig) error {
 	// Join microceph cluster
-	if _, err := commander.ExecCommand(
-		"microceph", "join", cfg.joinToken,
+	if _, err := commander.ExecCommandWithRetry(
+		ctx,
+		commander.DefaultRetryOptions,
+		"microceph", "join", cfg.Token,
 	); 
 	err != nil {
 		return fmt.Errorf("failed to join microceph cluster: %w", err)
 	}
 
-	// Add disk to microceph
-	if _, err := commander.ExecCommand(
-		"microceph", "disk", "add", cfg.disk,
-	); 
-	err != nil {
-		return fmt.Errorf("failed to add disk: %w", err)
+	// Add disks to microceph
+	if err := AddDisks(ctx, cfg.Disks); err != nil {
+		return fmt.Errorf("failed to add disks: %w", err)
 	}
 
 	return nil
-}	
+}
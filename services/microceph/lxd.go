@@ -1,10 +1,16 @@
 package microceph
 
-import "mcloud/pkg/commander"
+import (
+	"context"
+
+	"mcloud/pkg/commander"
+)
 
 // RegisterToLXD registers the given Ceph pool to LXD
 func RegisterToLXD(pool string) (string, error) {
-	output, err := commander.ExecCommand(
+	output, err := commander.ExecCommandWithRetry(
+		context.Background(),
+		commander.DefaultRetryOptions,
 		"lxc", "storage", "create",
 		"ceph-"+pool,
 		"ceph",
@@ -0,0 +1,75 @@
+package microceph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"mcloud/pkg/commander"
+)
+
+// OSD is an OSD configured in MicroCeph, as listed by `microceph disk list`.
+type OSD struct {
+	ID       int    `json:"osd"`
+	Location string `json:"location"` // hostname of the member holding it
+	Path     string `json:"path"`
+}
+
+// OSDs returns the OSDs of the cluster.
+func OSDs(ctx context.Context) ([]OSD, error) {
+	output, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "microceph", "disk", "list", "--json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list disks: %w", err)
+	}
+
+	var result struct {
+		ConfiguredDisks []OSD `json:"ConfiguredDisks"`
+	}
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse disk list: %w", err)
+	}
+	return result.ConfiguredDisks, nil
+}
+
+// RemoveMember removes a member's OSDs and then the member from MicroCeph,
+// returning the IDs of the OSDs removed. Removing an OSD waits for its data to
+// be moved to the other OSDs; with force (for dead members) the safety checks
+// are bypassed and the member is removed even if unreachable.
+//
+// Example Input:
+//   RemoveMember(ctx, "node3", false)
+//
+// Example Output:
+//   Runs: microceph disk remove osd.4, microceph disk remove osd.5, microceph cluster remove node3
+//   Returns: ([]int{4, 5}, nil)
+func RemoveMember(ctx context.Context, member string, force bool) ([]int, error) {
+	osds, err := OSDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []int
+	for _, osd := range osds {
+		if osd.Location != member {
+			continue
+		}
+		args := []string{"disk", "remove", fmt.Sprintf("osd.%d", osd.ID)}
+		if force {
+			args = append(args, "--bypass-safety-checks")
+		}
+		log.Debug("Removing OSD %d (%s) of %s", osd.ID, osd.Path, member)
+		if _, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "microceph", args...); err != nil {
+			return removed, fmt.Errorf("failed to remove osd.%d: %w", osd.ID, err)
+		}
+		removed = append(removed, osd.ID)
+	}
+
+	args := []string{"cluster", "remove", member}
+	if force {
+		args = append(args, "--force")
+	}
+	if _, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "microceph", args...); err != nil {
+		return removed, fmt.Errorf("failed to remove member %s: %w", member, err)
+	}
+	return removed, nil
+}
@@ -0,0 +1,89 @@
+package microceph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"mcloud/pkg/commander"
+)
+
+// Ceph health states as reported by `ceph -s`.
+const (
+	HealthOK   = "HEALTH_OK"
+	HealthWarn = "HEALTH_WARN"
+	HealthErr  = "HEALTH_ERR"
+)
+
+// ClusterStatus is a summary of the Ceph cluster state.
+type ClusterStatus struct {
+	Health     string   `json:"health"` // HEALTH_OK, HEALTH_WARN or HEALTH_ERR
+	Checks     []string `json:"checks"` // active health checks, e.g. "OSD_DOWN: 1 osds down"
+	MonCount   int      `json:"mon_count"`
+	OSDCount   int      `json:"osd_count"`
+	OSDsUp     int      `json:"osds_up"`
+	OSDsIn     int      `json:"osds_in"`
+	BytesUsed  uint64   `json:"bytes_used"`
+	BytesAvail uint64   `json:"bytes_avail"`
+	BytesTotal uint64   `json:"bytes_total"`
+}
+
+// Status runs `ceph -s` through MicroCeph and parses its JSON output.
+//
+// Example Output:
+//   &ClusterStatus{Health: "HEALTH_WARN", Checks: ["OSD_DOWN: 1 osds down"], MonCount: 3,
+//     OSDCount: 3, OSDsUp: 2, OSDsIn: 3, BytesUsed: 3221225472, ...}
+func Status() (*ClusterStatus, error) {
+	output, err := commander.ExecCommandWithRetry(context.Background(), commander.DefaultRetryOptions, "microceph.ceph", "status", "--format", "json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ceph status: %w", err)
+	}
+	return parseStatus([]byte(output))
+}
+
+func parseStatus(data []byte) (*ClusterStatus, error) {
+	var raw struct {
+		Health struct {
+			Status string `json:"status"`
+			Checks map[string]struct {
+				Summary struct {
+					Message string `json:"message"`
+				} `json:"summary"`
+			} `json:"checks"`
+		} `json:"health"`
+		MonMap struct {
+			NumMons int `json:"num_mons"`
+		} `json:"monmap"`
+		OSDMap struct {
+			NumOSDs   int `json:"num_osds"`
+			NumUpOSDs int `json:"num_up_osds"`
+			NumInOSDs int `json:"num_in_osds"`
+		} `json:"osdmap"`
+		PGMap struct {
+			BytesUsed  uint64 `json:"bytes_used"`
+			BytesAvail uint64 `json:"bytes_avail"`
+			BytesTotal uint64 `json:"bytes_total"`
+		} `json:"pgmap"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse ceph status: %w", err)
+	}
+
+	st := &ClusterStatus{
+		Health:     raw.Health.Status,
+		Checks:     []string{},
+		MonCount:   raw.MonMap.NumMons,
+		OSDCount:   raw.OSDMap.NumOSDs,
+		OSDsUp:     raw.OSDMap.NumUpOSDs,
+		OSDsIn:     raw.OSDMap.NumInOSDs,
+		BytesUsed:  raw.PGMap.BytesUsed,
+		BytesAvail: raw.PGMap.BytesAvail,
+		BytesTotal: raw.PGMap.BytesTotal,
+	}
+	for name, check := range raw.Health.Checks {
+		st.Checks = append(st.Checks, name+": "+check.Summary.Message)
+	}
+	sort.Strings(st.Checks)
+	return st, nil
+}
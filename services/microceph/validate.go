@@ -1,6 +1,7 @@
 package microceph
 
 import (
+	"context"
 	"fmt"
 	"mcloud/pkg/commander"
 )
@@ -12,7 +13,9 @@ type ValidateConfig struct {
 
 // validate checks if the microceph cluster can be initialized with the given configuration
 func Validate(cfg ValidateConfig) (bool, error) {
-	_, err := commander.ExecCommand(
+	_, err := commander.ExecCommandWithRetry(
+		context.Background(),
+		commander.DefaultRetryOptions,
 		"microceph", "init", 
 		"--cluster",
 		"--cluster-name", cfg.ClusterName,
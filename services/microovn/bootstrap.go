@@ -1,14 +1,18 @@
 package microovn
 
 import (
+	"context"
+
 	"mcloud/pkg/commander"
 	"mcloud/pkg/logger"
 )
 
-func Bootstrap() error {
-	_, err := commander.ExecCommand("microovn", "init")
+var log = logger.Named("microovn")
+
+func Bootstrap(ctx context.Context) error {
+	_, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "microovn", "init")
 	if err != nil {
-		logger.Error("failed to init microovn: %v", err)
+		log.Error("failed to init microovn: %v", err)
 	}
 	
 	return nil
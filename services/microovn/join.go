@@ -1,9 +1,13 @@
 package microovn
 
-import "mcloud/pkg/commander"
+import (
+	"context"
+
+	"mcloud/pkg/commander"
+)
 
 // Join makes the node join an existing microovn cluster
-func Join(token string) (string, error) {
-	output, err := commander.ExecCommand("microovn", "join", token)
+func Join(ctx context.Context, token string) (string, error) {
+	output, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "microovn", "join", token)
 	return output, err
 }
\ No newline at end of file
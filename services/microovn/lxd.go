@@ -1,10 +1,16 @@
 package microovn
 
-import "mcloud/pkg/commander"
+import (
+	"context"
+
+	"mcloud/pkg/commander"
+)
 
 // RegisterToLXD registers the given OVN network to LXD
 func RegisterToLXD(network string) (string, error) {
-	output, err := commander.ExecCommand(
+	output, err := commander.ExecCommandWithRetry(
+		context.Background(),
+		commander.DefaultRetryOptions,
 		"lxc", "network", "create",
 		network,
 		"--type=ovn",
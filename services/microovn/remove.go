@@ -0,0 +1,21 @@
+package microovn
+
+import (
+	"context"
+	"fmt"
+
+	"mcloud/pkg/commander"
+)
+
+// RemoveMember removes a member from the MicroOVN cluster. With force the
+// member is removed even if it is unreachable.
+func RemoveMember(ctx context.Context, member string, force bool) error {
+	args := []string{"cluster", "remove", member}
+	if force {
+		args = append(args, "--force")
+	}
+	if _, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "microovn", args...); err != nil {
+		return fmt.Errorf("failed to remove member %s: %w", member, err)
+	}
+	return nil
+}
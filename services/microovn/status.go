@@ -0,0 +1,18 @@
+package microovn
+
+import (
+	"context"
+	"fmt"
+
+	"mcloud/pkg/commander"
+)
+
+// Status runs `microovn status`, which fails when this node is not a member of
+// the MicroOVN cluster or its daemon is down, and returns its output.
+func Status(ctx context.Context) (string, error) {
+	output, err := commander.ExecCommandWithRetry(ctx, commander.DefaultRetryOptions, "microovn", "status")
+	if err != nil {
+		return "", fmt.Errorf("failed to get microovn status: %w", err)
+	}
+	return output, nil
+}
@@ -0,0 +1,54 @@
+package nsupdate
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"mcloud/pkg/commander"
+)
+
+// Update sends a dynamic DNS update (RFC 2136) to server, signed with the TSIG
+// key in keyFile. Each command is one nsupdate line.
+//
+// Example Input:
+//   Update(ctx, "10.0.0.53", "/etc/mcloud/dns.key", []string{
+//     "update delete web.apps.example.com A",
+//     "update add web.apps.example.com 300 A 203.0.113.10",
+//   })
+//
+// Example Output:
+//   Runs: nsupdate -k /etc/mcloud/dns.key <script>
+//   where <script> is:
+//     server 10.0.0.53
+//     update delete web.apps.example.com A
+//     update add web.apps.example.com 300 A 203.0.113.10
+//     send
+func Update(ctx context.Context, server string, keyFile string, commands []string) error {
+	// nsupdate reads commands from a file when not attached to a terminal
+	script, err := os.CreateTemp("", "mcloud-nsupdate-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(script.Name())
+
+	lines := append([]string{"server " + server}, commands...)
+	lines = append(lines, "send")
+	if _, err := script.WriteString(strings.Join(lines, "\n") + "\n"); err != nil {
+		script.Close()
+		return err
+	}
+	if err := script.Close(); err != nil {
+		return err
+	}
+
+	args := []string{script.Name()}
+	if keyFile != "" {
+		args = append([]string{"-k", keyFile}, args...)
+	}
+	if _, err := commander.ExecCommandContext(ctx, "nsupdate", args...); err != nil {
+		return fmt.Errorf("dns update failed: %w", err)
+	}
+	return nil
+}